@@ -0,0 +1,219 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth_test implements RT-1.9: BGP Authentication and TTL
+// Security.
+package auth_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpauth"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var ateTTL = flag.Uint("ate_bgp_ttl", 64, "TTL of the BGP packets sent by the ATE, which cannot be configured through the ATE API.")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 with an eBGP session
+// between them.
+//
+//   - ate:port1 (AS 64501) -> dut:port1 (AS 64500) subnet 192.0.2.0/30
+const (
+	dutAS    = 64500
+	ateAS    = 64501
+	plenIPv4 = 30
+	// gtsmHops is the number of hops GTSM is configured to accept out of
+	// band.
+	gtsmHops = 1
+	keychain = "BGP-AO"
+	// establishTimeout is how long to wait for a session to establish,
+	// and rejectTime how long a rejected session is checked to stay down.
+	establishTimeout = 2 * time.Minute
+	rejectTime       = time.Minute
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "DUT to ATE port1", IPv4: "192.0.2.1", IPv4Len: plenIPv4}
+	atePort1 = attrs.Attributes{Name: "port1", IPv4: "192.0.2.2", IPv4Len: plenIPv4}
+)
+
+// configureDUT configures the port and the BGP session of the DUT, with
+// the TCP MD5 password if the key is not empty.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice, key string) {
+	dc := dut.Config()
+	intf := dutPort1.NewInterface(dut.Port(t, "port1").Name())
+	dc.Interface(intf.GetName()).Replace(t, intf)
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String(dutPort1.IPv4)
+	nbr := bgp.GetOrCreateNeighbor(atePort1.IPv4)
+	nbr.PeerAs = ygot.Uint32(ateAS)
+	nbr.Enabled = ygot.Bool(true)
+	nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+	bgpauth.ConfigureMD5(nbr, key)
+	dc.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Replace(t, bgp)
+}
+
+// configureATE configures the port and the BGP session of the ATE, with
+// the TCP MD5 key if it is not empty, and starts the protocols.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice, key string) {
+	top := ate.Topology().New()
+	intf := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	peer := intf.BGP().AddPeer().WithPeerAddress(dutPort1.IPv4).WithLocalASN(ateAS).WithTypeExternal()
+	if key != "" {
+		peer.WithMD5Key(key)
+	}
+	top.Push(t).StartProtocols(t)
+}
+
+// session is the wanted outcome of a BGP session: one of the states,
+// and whether a NOTIFICATION is received while it is checked.
+type session struct {
+	states       []telemetry.E_Bgp_Neighbor_SessionState
+	notification bool
+}
+
+var (
+	// established is a session that authenticates.
+	established = session{
+		states: []telemetry.E_Bgp_Neighbor_SessionState{telemetry.Bgp_Neighbor_SessionState_ESTABLISHED},
+	}
+	// rejected is a session whose TCP connection is rejected, so it
+	// never exchanges a BGP message.
+	rejected = session{
+		states: []telemetry.E_Bgp_Neighbor_SessionState{
+			telemetry.Bgp_Neighbor_SessionState_IDLE,
+			telemetry.Bgp_Neighbor_SessionState_ACTIVE,
+		},
+	}
+)
+
+// hasState returns whether the state is one of the wanted states.
+func (s session) hasState(state telemetry.E_Bgp_Neighbor_SessionState) bool {
+	for _, st := range s.states {
+		if st == state {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSession checks that the session with the ATE establishes, or
+// that its TCP connection is rejected, and that it ends in one of the
+// wanted states with a NOTIFICATION received only if wanted.
+func checkSession(t *testing.T, dut *ondatra.DUTDevice, want session) {
+	t.Helper()
+	before := bgpauth.ReadReason(t, dut, atePort1.IPv4)
+	if want.hasState(telemetry.Bgp_Neighbor_SessionState_ESTABLISHED) {
+		if err := bgpauth.AwaitEstablished(t, dut, atePort1.IPv4, establishTimeout); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		if err := bgpauth.AwaitDown(t, dut, atePort1.IPv4, establishTimeout); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bgpauth.CheckRejected(t, dut, atePort1.IPv4, rejectTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := bgpauth.ReadReason(t, dut, atePort1.IPv4)
+	t.Logf("BGP session with %s: %v", atePort1.IPv4, got)
+	if !want.hasState(got.State) {
+		t.Errorf("BGP session with %s got state %v, want one of %v", atePort1.IPv4, got.State, want.states)
+	}
+	if notified := got.NotificationTime != before.NotificationTime; notified != want.notification {
+		t.Errorf("BGP session with %s got NOTIFICATION received %t, want %t", atePort1.IPv4, notified, want.notification)
+	}
+}
+
+func TestMD5(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+
+	for _, c := range []struct {
+		desc   string
+		dutKey string
+		ateKey string
+		want   session
+	}{
+		{"no authentication", "", "", established},
+		{"matching keys", "featureprofiles", "featureprofiles", established},
+		{"mismatched keys", "featureprofiles", "mismatch", rejected},
+		{"key on the DUT only", "featureprofiles", "", rejected},
+		{"key on the ATE only", "", "featureprofiles", rejected},
+		{"keys matched again", "featureprofiles", "featureprofiles", established},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			configureDUT(t, dut, c.dutKey)
+			configureATE(t, ate, c.ateKey)
+			checkSession(t, dut, c.want)
+		})
+	}
+}
+
+func TestTCPAO(t *testing.T) {
+	if !*deviations.BGPTCPAO {
+		t.Skip("TCP-AO is not configured, see -deviation_bgp_tcp_ao")
+	}
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+
+	kc := bgpauth.Keychain(keychain, 1, telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_SHA_1_96, "featureprofiles")
+	dut.Config().Keychain(keychain).Replace(t, kc)
+	if got := dut.Telemetry().Keychain(keychain).Key(1).CryptoAlgorithm().Get(t); got != telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_SHA_1_96 {
+		t.Errorf("Keychain %s key 1 algorithm got %v, want %v", keychain, got, telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_SHA_1_96)
+	}
+
+	// The ATE does not support TCP-AO, so the DUT must reject it.
+	configureDUT(t, dut, "")
+	configureATE(t, ate, "")
+	checkSession(t, dut, rejected)
+}
+
+func TestGTSM(t *testing.T) {
+	if !*deviations.BGPGTSM {
+		t.Skip("GTSM is not configured, see -deviation_bgp_gtsm")
+	}
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+
+	minTTL, err := bgpauth.MinTTL(gtsmHops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rejected
+	if bgpauth.Accepts(gtsmHops, uint8(*ateTTL)) {
+		want = established
+	}
+	t.Logf("GTSM accepts TTL %d or more, the ATE sends TTL %d", minTTL, *ateTTL)
+
+	configureDUT(t, dut, "")
+	configureATE(t, ate, "")
+	checkSession(t, dut, want)
+}
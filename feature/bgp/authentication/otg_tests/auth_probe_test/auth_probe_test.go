@@ -0,0 +1,223 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth_probe_test implements RT-1.16: BGP Authentication and TTL
+// Security Negative Paths.
+package auth_probe_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpauth"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1.  The DUT has a BGP
+// neighbor at the address of ate:port1, which runs no BGP: the ATE only
+// sends TCP SYNs to the BGP port of the DUT, with the TTL of each probe
+// and no TCP options, and captures the replies.
+//
+//   - ate:port1 (AS 64497) -> dut:port1 (AS 64496) subnet 192.0.2.0/30
+const (
+	dutAS = 64496
+	ateAS = 64497
+	plen  = 30
+	// gtsmHops is the number of hops GTSM is configured to accept out of
+	// band.
+	gtsmHops = 1
+	md5Key   = "featureprofiles"
+	// probePPS and probePackets are the rate and number of the SYNs of
+	// each probe, as retransmissions of a connection attempt.
+	probePPS     = 1
+	probePackets = 10
+	probeTime    = probePackets * time.Second / probePPS
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen}
+)
+
+// configureDUT configures the port and the BGP neighbor of the DUT, with
+// the TCP MD5 password if the key is not empty.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice, key string) {
+	dc := dut.Config()
+	intf := dutPort1.NewInterface(dut.Port(t, "port1").Name())
+	dc.Interface(intf.GetName()).Replace(t, intf)
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String(dutPort1.IPv4)
+	nbr := bgp.GetOrCreateNeighbor(atePort1.IPv4)
+	nbr.PeerAs = ygot.Uint32(ateAS)
+	nbr.Enabled = ygot.Bool(true)
+	nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+	bgpauth.ConfigureMD5(nbr, key)
+	dc.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Replace(t, bgp)
+}
+
+// configureOTG returns the OTG configuration of the ATE interface, with
+// no BGP, capturing on ate:port1.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	id := ate.Port(t, "port1").ID()
+	config.Ports().Add().SetName(id)
+	config.Devices().Add().SetName(atePort1.Name).Ethernets().Add().
+		SetName(atePort1.Name + ".eth").
+		SetPortName(id).
+		SetMac(atePort1.MAC).
+		Ipv4Addresses().Add().
+		SetName(atePort1.Name + ".IPv4").
+		SetAddress(atePort1.IPv4).
+		SetGateway(dutPort1.IPv4).
+		SetPrefix(int32(atePort1.IPv4Len))
+	capture.Enable(config, id)
+	return config
+}
+
+// check is a probe and whether the DUT should accept its connection.
+type check struct {
+	probe  *bgpauth.Probe
+	accept bool
+}
+
+// newProbe returns a probe of the neighbor to the DUT with the TTL, from
+// its own source port.
+func newProbe(t *testing.T, dut *ondatra.DUTDevice, name string, srcPort uint16, ttl uint8) *bgpauth.Probe {
+	return &bgpauth.Probe{
+		Name:    name,
+		SrcMAC:  atePort1.MAC,
+		DstMAC:  dut.Telemetry().Interface(dut.Port(t, "port1").Name()).Ethernet().MacAddress().Get(t),
+		Src:     atePort1.IPv4,
+		Dst:     dutPort1.IPv4,
+		SrcPort: srcPort,
+		TTL:     ttl,
+	}
+}
+
+// sendProbes sends the probes and verifies from the captured replies of
+// the DUT that it accepts or rejects the connection of each.  While the
+// probes are sent, it verifies that the session of the DUT with the
+// neighbor never connects and receives no NOTIFICATION.
+func sendProbes(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, api gosnappi.GosnappiApi, config gosnappi.Config, checks []check) {
+	id := ate.Port(t, "port1").ID()
+	config.Flows().Clear()
+	for _, c := range checks {
+		if err := c.probe.AddFlow(config, id, probePPS, probePackets); err != nil {
+			t.Fatalf("Cannot add the probe: %v", err)
+		}
+	}
+	otg := ate.OTG()
+	otg.PushConfig(t, config)
+	otg.StartProtocols(t)
+
+	capture.Start(t, api, id)
+	otg.StartTraffic(t)
+	r, err := bgpauth.CheckRejected(t, dut, atePort1.IPv4, probeTime)
+	otg.StopTraffic(t)
+	capture.Stop(t, api, id)
+	t.Logf("BGP session with %s: %v", atePort1.IPv4, r)
+	if err != nil {
+		t.Error(err)
+	}
+
+	pkts := capture.Fetch(t, api, id)
+	for _, c := range checks {
+		replies := c.probe.Replies(pkts)
+		t.Logf("Probe %s with TTL %d: %d SYN-ACKs, %d RSTs", c.probe.Name, c.probe.TTL, replies.SYNACK, replies.RST)
+		if err := c.probe.Check(replies, c.accept); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestMD5Absent verifies that the DUT, with a TCP MD5 password for the
+// neighbor, rejects the connections of SYNs from the neighbor without an
+// MD5 signature, and accepts them once the password is removed.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/config/auth-password
+// telemetry_path:/interfaces/interface/ethernet/state/mac-address
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/session-state
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/messages/received/last-notification-time
+func TestMD5Absent(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	config := configureOTG(t, ate)
+	api := fptest.DialOTG(t, ate)
+
+	for _, c := range []struct {
+		desc    string
+		key     string
+		srcPort uint16
+		accept  bool
+	}{
+		{"password", md5Key, 50000, false},
+		{"no password", "", 50001, true},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			configureDUT(t, dut, c.key)
+			p := newProbe(t, dut, "no md5", c.srcPort, 255)
+			sendProbes(t, dut, ate, api, config, []check{{p, c.accept}})
+		})
+	}
+}
+
+// TestGTSMTTL verifies that the DUT, configured out of band with GTSM for
+// the neighbor, accepts the connections of SYNs from the neighbor with
+// the TTL of a peer within the hops of GTSM, and rejects those with a
+// lower TTL.
+//
+// telemetry_path:/interfaces/interface/ethernet/state/mac-address
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/session-state
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/messages/received/last-notification-time
+func TestGTSMTTL(t *testing.T) {
+	if !*deviations.BGPGTSM {
+		t.Skip("GTSM is not configured, see -deviation_bgp_gtsm")
+	}
+	r := fptest.Requirements{PortIDs: []string{"port1"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	config := configureOTG(t, ate)
+	api := fptest.DialOTG(t, ate)
+	configureDUT(t, dut, "")
+
+	minTTL, err := bgpauth.MinTTL(gtsmHops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("GTSM accepts TTL %d or more", minTTL)
+	var checks []check
+	for i, ttl := range []uint8{255, 254, 64, 1} {
+		p := newProbe(t, dut, fmt.Sprintf("ttl %d", ttl), 50100+uint16(i), ttl)
+		checks = append(checks, check{p, bgpauth.Accepts(gtsmHops, ttl)})
+	}
+	sendProbes(t, dut, ate, api, config, checks)
+}
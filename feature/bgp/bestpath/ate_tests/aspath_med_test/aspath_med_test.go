@@ -0,0 +1,277 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aspath_med_test implements RT-1.7: BGP AS Path Prepend and MED.
+package aspath_med_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpattr"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, ate:port2 ->
+// dut:port2 and dut:port3 -> ate:port3.  The ATE advertises the same
+// prefix from port1 and port2 in the same AS, the DUT selects its best
+// path according to its import policies, and advertises it to port3
+// according to its export policy.
+//
+//   - ate:port1 (AS 64501) -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 (AS 64501) -> dut:port2 subnet 192.0.2.4/30
+//   - dut:port3 -> ate:port3 (AS 64503) subnet 192.0.2.8/30
+const (
+	dutAS        = 64500
+	ateAS        = 64501
+	ateDstAS     = 64503
+	plenIPv4     = 30
+	prefix       = "198.51.100.0/24"
+	ribTimeout   = 2 * time.Minute
+	pollInterval = 5 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "DUT to ATE port1", IPv4: "192.0.2.1", IPv4Len: plenIPv4}
+	atePort1 = attrs.Attributes{Name: "port1", IPv4: "192.0.2.2", IPv4Len: plenIPv4}
+	dutPort2 = attrs.Attributes{Desc: "DUT to ATE port2", IPv4: "192.0.2.5", IPv4Len: plenIPv4}
+	atePort2 = attrs.Attributes{Name: "port2", IPv4: "192.0.2.6", IPv4Len: plenIPv4}
+	dutPort3 = attrs.Attributes{Desc: "DUT to ATE port3", IPv4: "192.0.2.9", IPv4Len: plenIPv4}
+	atePort3 = attrs.Attributes{Name: "port3", IPv4: "192.0.2.10", IPv4Len: plenIPv4}
+)
+
+// sessions are the BGP sessions of the DUT, with the name of the policy
+// applied to each of them.
+var sessions = []struct {
+	dut, ate *attrs.Attributes
+	as       uint32
+	policy   string
+	export   bool
+}{
+	{&dutPort1, &atePort1, ateAS, "IMPORT-1", false},
+	{&dutPort2, &atePort2, ateAS, "IMPORT-2", false},
+	{&dutPort3, &atePort3, ateDstAS, "EXPORT-3", true},
+}
+
+// action is the manipulation of the routes by a policy.
+type action struct {
+	prepend uint8
+	med     uint32
+}
+
+// configurePolicy replaces the routing policy of the DUT, with the
+// actions of each policy applied to all routes.
+func configurePolicy(t *testing.T, dut *ondatra.DUTDevice, actions map[string]action) {
+	rp := &telemetry.RoutingPolicy{}
+	for _, s := range sessions {
+		a := rp.GetOrCreatePolicyDefinition(s.policy).GetOrCreateStatement("10").GetOrCreateActions()
+		a.PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+		act := actions[s.policy]
+		if act.prepend > 0 {
+			asn := uint32(dutAS)
+			if !s.export {
+				asn = s.as
+			}
+			bgpattr.Prepend(a, asn, act.prepend)
+		}
+		if act.med > 0 {
+			bgpattr.SetMED(a, act.med)
+		}
+	}
+	dut.Config().RoutingPolicy().Replace(t, rp)
+}
+
+// configureDUT configures the ports, the BGP sessions and the default
+// policies of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	dc := dut.Config()
+	for i, s := range sessions {
+		intf := s.dut.NewInterface(dut.Port(t, fmt.Sprintf("port%d", i+1)).Name())
+		dc.Interface(intf.GetName()).Replace(t, intf)
+	}
+	configurePolicy(t, dut, nil)
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String(dutPort1.IPv4)
+	for _, s := range sessions {
+		nbr := bgp.GetOrCreateNeighbor(s.ate.IPv4)
+		nbr.PeerAs = ygot.Uint32(s.as)
+		nbr.Enabled = ygot.Bool(true)
+		af := nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST)
+		af.Enabled = ygot.Bool(true)
+		if s.export {
+			af.GetOrCreateApplyPolicy().ExportPolicy = []string{s.policy}
+		} else {
+			af.GetOrCreateApplyPolicy().ImportPolicy = []string{s.policy}
+		}
+	}
+	dc.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Replace(t, bgp)
+
+	state := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	for _, s := range sessions {
+		_, ok := state.Neighbor(s.ate.IPv4).SessionState().Watch(t, time.Minute, func(val *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+			return val.IsPresent() && val.Val(t) == telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+		}).Await(t)
+		if !ok {
+			t.Fatalf("BGP session with %s is not established", s.ate.IPv4)
+		}
+	}
+}
+
+// configureATE configures the BGP sessions of the ATE, and advertises
+// the prefix from port1 and port2.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) {
+	top := ate.Topology().New()
+	for i, s := range sessions {
+		intf := top.AddInterface(s.ate.Name).WithPort(ate.Port(t, fmt.Sprintf("port%d", i+1)))
+		intf.IPv4().WithAddress(s.ate.IPv4CIDR()).WithDefaultGateway(s.dut.IPv4)
+		intf.BGP().AddPeer().WithPeerAddress(s.dut.IPv4).WithLocalASN(s.as).WithTypeExternal()
+		if !s.export {
+			net := intf.AddNetwork("net-" + s.ate.Name)
+			net.IPv4().WithAddress(prefix).WithCount(1)
+			net.BGP().WithNextHopAddress(s.ate.IPv4).WithOriginIGP()
+		}
+	}
+	top.Push(t).StartProtocols(t)
+}
+
+// await calls check until it returns nil or ribTimeout elapses, to let
+// the DUT apply a new policy, and returns the last error.
+func await(check func() error) error {
+	deadline := time.Now().Add(ribTimeout)
+	for {
+		err := check()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func TestExportAttributes(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	configureATE(t, ate)
+
+	for _, c := range []struct {
+		desc string
+		act  action
+	}{
+		{"Prepend AS path", action{prepend: 3}},
+		{"Set MED", action{med: 50}},
+		{"Prepend AS path and set MED", action{prepend: 2, med: 150}},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			configurePolicy(t, dut, map[string]action{"EXPORT-3": c.act})
+
+			// The ATE does not report the AS path, so it is read from
+			// the route advertised by the DUT.
+			err := await(func() error {
+				as, err := bgpattr.ReadAdvertised(t, dut, atePort3.IPv4, prefix, pollInterval)
+				if err != nil {
+					return err
+				}
+				return bgpattr.CheckPrepended(bgpattr.ASPath(as.AsSegment), dutAS, int(c.act.prepend), []uint32{ateAS})
+			})
+			if err != nil {
+				t.Errorf("Advertised route to %s: %v", prefix, err)
+			}
+
+			err = await(func() error {
+				r, err := bgpattr.ReadATE(t, ate, atePort3.Name, fmt.Sprint(ateDstAS), dutPort3.IPv4, prefix, pollInterval)
+				switch {
+				case err != nil:
+					return err
+				case c.act.med > 0 && (!r.HasMED || r.MED != c.act.med):
+					return fmt.Errorf("MED got %d (present %t), want %d", r.MED, r.HasMED, c.act.med)
+				case r.NextHop != dutPort3.IPv4:
+					return fmt.Errorf("next hop got %s, want %s", r.NextHop, dutPort3.IPv4)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Errorf("ATE received route to %s: %v", prefix, err)
+			}
+		})
+	}
+}
+
+func TestBestPath(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	configureATE(t, ate)
+
+	for _, c := range []struct {
+		desc          string
+		first, second action
+	}{
+		{"Prepend on first session", action{prepend: 2}, action{}},
+		{"Prepend on second session", action{}, action{prepend: 2}},
+		{"Lower MED on second session", action{med: 100}, action{med: 50}},
+		{"Lower MED on first session", action{med: 50}, action{med: 100}},
+		{"AS path before MED", action{prepend: 1, med: 10}, action{med: 100}},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			configurePolicy(t, dut, map[string]action{"IMPORT-1": c.first, "IMPORT-2": c.second})
+
+			var paths []*bgpattr.Path
+			for i, act := range []action{c.first, c.second} {
+				paths = append(paths, &bgpattr.Path{
+					Neighbor:   sessions[i].ate.IPv4,
+					NeighborAS: ateAS,
+					ASPathLen:  1 + int(act.prepend),
+					Origin:     telemetry.BgpTypes_BgpOriginAttrType_IGP,
+					MED:        act.med,
+				})
+			}
+			want := bgpattr.Best(paths)
+			if want == nil {
+				t.Fatalf("Test case does not select a single best path")
+			}
+
+			err := await(func() error {
+				for _, p := range paths {
+					best, err := bgpattr.IsBest(t, dut, p.Neighbor, prefix)
+					if err != nil {
+						return err
+					}
+					if best != (p == want) {
+						return fmt.Errorf("route from %s is best %t, want %t", p.Neighbor, best, p == want)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Best path to %s: %v", prefix, err)
+			}
+		})
+	}
+}
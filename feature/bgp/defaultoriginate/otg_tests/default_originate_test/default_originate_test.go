@@ -0,0 +1,223 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package default_originate_test implements RT-1.18: BGP Default Route
+// Origination.
+package default_originate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpdefault"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, which advertises the
+// condition route, and dut:port2 -> ate:port2, to which the DUT sends
+// the default route.  Each ATE port has an eBGP session with the DUT.
+//
+//   - ate:port1 (AS 64497) -> dut:port1 (AS 64496) subnet 192.0.2.0/30
+//   - dut:port2 -> ate:port2 (AS 64498) subnet 192.0.2.4/30
+//   - condition route 198.51.100.0/24, advertised by ate:port1
+const (
+	dutAS        = 64496
+	ate1AS       = 64497
+	ate2AS       = 64498
+	plen         = 30
+	conditionNet = "198.51.100.0"
+	conditionLen = 24
+	// conditionRoutes is the name of the OTG route range of the condition
+	// route.
+	conditionRoutes = "condition"
+	routeTimeout    = 2 * time.Minute
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv4Len: plen}
+
+	condition = &bgpdefault.Condition{
+		Policy:    "DEFAULT-CONDITION",
+		PrefixSet: "DEFAULT-CONDITION",
+		Prefix:    "198.51.100.0/24",
+	}
+)
+
+// peerName returns the name of the BGP peer of the ATE port.
+func peerName(a *attrs.Attributes) string {
+	return a.Name + ".BGP4.peer"
+}
+
+// configureDUT configures the ports of the DUT and its eBGP sessions with
+// the ATE ports, accepting all the routes, and sending the default route
+// to ate:port2 if send is set.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice, send bool) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String(dutPort1.IPv4)
+	for _, p := range []struct {
+		ate *attrs.Attributes
+		as  uint32
+	}{{&atePort1, ate1AS}, {&atePort2, ate2AS}} {
+		nbr := bgp.GetOrCreateNeighbor(p.ate.IPv4)
+		nbr.PeerAs = ygot.Uint32(p.as)
+		nbr.Enabled = ygot.Bool(true)
+		nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+		ap := nbr.GetOrCreateApplyPolicy()
+		ap.DefaultImportPolicy = telemetry.RoutingPolicy_DefaultPolicyType_ACCEPT_ROUTE
+		ap.DefaultExportPolicy = telemetry.RoutingPolicy_DefaultPolicyType_ACCEPT_ROUTE
+	}
+	bgpdefault.Send(bgp.GetNeighbor(atePort2.IPv4), send)
+	bgpPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	fptest.LogYgot(t, "DUT BGP", bgpPath, bgp)
+	bgpPath.Replace(t, bgp)
+}
+
+// configureOTG returns the OTG configuration of the ATE ports and their
+// eBGP sessions, with the condition route advertised by ate:port1.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	for _, p := range []struct {
+		port      string
+		ate, peer *attrs.Attributes
+		as        int32
+	}{{"port1", &atePort1, &dutPort1, ate1AS}, {"port2", &atePort2, &dutPort2, ate2AS}} {
+		id := ate.Port(t, p.port).ID()
+		config.Ports().Add().SetName(id)
+		dev := config.Devices().Add().SetName(p.ate.Name)
+		ip := dev.Ethernets().Add().
+			SetName(p.ate.Name + ".eth").
+			SetPortName(id).
+			SetMac(p.ate.MAC).
+			Ipv4Addresses().Add().
+			SetName(p.ate.Name + ".IPv4").
+			SetAddress(p.ate.IPv4).
+			SetGateway(p.peer.IPv4).
+			SetPrefix(int32(p.ate.IPv4Len))
+		peer := dev.Bgp().SetRouterId(p.ate.IPv4).
+			Ipv4Interfaces().Add().SetIpv4Name(ip.Name()).
+			Peers().Add().SetName(peerName(p.ate)).
+			SetPeerAddress(p.peer.IPv4).
+			SetAsNumber(p.as).
+			SetAsType(gosnappi.BgpV4PeerAsType.EBGP)
+		if p.ate == &atePort1 {
+			peer.V4Routes().Add().SetName(conditionRoutes).
+				SetNextHopIpv4Address(p.ate.IPv4).
+				SetNextHopAddressType(gosnappi.BgpV4RouteRangeNextHopAddressType.IPV4).
+				SetNextHopMode(gosnappi.BgpV4RouteRangeNextHopMode.MANUAL).
+				Addresses().Add().SetAddress(conditionNet).SetPrefix(conditionLen).SetCount(1)
+		}
+	}
+	return config
+}
+
+// checkDefault verifies that the DUT advertises the default route to
+// ate:port2, and that ate:port2 receives it, or not.
+func checkDefault(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, want bool) {
+	t.Helper()
+	if err := bgpdefault.AwaitAdvertised(t, dut, atePort2.IPv4, want, routeTimeout); err != nil {
+		t.Error(err)
+	}
+	if err := bgpdefault.AwaitReceived(t, ate.OTG(), peerName(&atePort2), want, routeTimeout); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestSendDefault verifies that the DUT sends the default route to the
+// neighbor with send-default-route, and withdraws it once it is unset.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/afi-safis/afi-safi/ipv4-unicast/config/send-default-route
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/rib/afi-safis/afi-safi/ipv4-unicast/neighbors/neighbor/adj-rib-out-post/routes/route/state/prefix
+func TestSendDefault(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut, false)
+	otg := ate.OTG()
+	otg.PushConfig(t, configureOTG(t, ate))
+	otg.StartProtocols(t)
+	defer otg.StopProtocols(t)
+
+	for _, send := range []bool{true, false} {
+		configureDUT(t, dut, send)
+		checkDefault(t, dut, ate, send)
+	}
+}
+
+// TestConditionalDefault verifies that the DUT, configured out of band to
+// condition the default route of the neighbor with send-default-route on
+// the policy matching the condition route, sends the default route only
+// while it has the condition route.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/afi-safis/afi-safi/ipv4-unicast/config/send-default-route
+// config_path:/routing-policy/defined-sets/prefix-sets/prefix-set/prefixes/prefix/config/ip-prefix
+// config_path:/routing-policy/policy-definitions/policy-definition/statements/statement/conditions/match-prefix-set/config/prefix-set
+// config_path:/routing-policy/policy-definitions/policy-definition/statements/statement/actions/config/policy-result
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/rib/afi-safis/afi-safi/ipv4-unicast/neighbors/neighbor/adj-rib-out-post/routes/route/state/prefix
+func TestConditionalDefault(t *testing.T) {
+	bgpdefault.Require(t, condition)
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+
+	rp := &telemetry.RoutingPolicy{}
+	if err := condition.Configure(rp); err != nil {
+		t.Fatalf("Cannot configure the condition: %v", err)
+	}
+	rpPath := dut.Config().RoutingPolicy()
+	fptest.LogYgot(t, "DUT routing policy", rpPath, rp)
+	rpPath.Update(t, rp)
+	configureDUT(t, dut, true)
+	otg := ate.OTG()
+	otg.PushConfig(t, configureOTG(t, ate))
+	otg.StartProtocols(t)
+	defer otg.StopProtocols(t)
+
+	t.Run("injected", func(t *testing.T) {
+		checkDefault(t, dut, ate, true)
+	})
+	t.Run("withdrawn", func(t *testing.T) {
+		otg.WithdrawRoutes(t, []string{conditionRoutes})
+		checkDefault(t, dut, ate, false)
+	})
+	t.Run("reinjected", func(t *testing.T) {
+		otg.AdvertiseRoutes(t, []string{conditionRoutes})
+		checkDefault(t, dut, ate, true)
+	})
+}
@@ -0,0 +1,231 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flowspec_test implements RT-1.11: BGP Flowspec.
+package flowspec_test
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/flowspec"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	dutAddr     = flag.String("flowspec_dut_addr", "", "host:port of the BGP listener of the DUT, reachable from the test in its default network instance")
+	speakerAddr = flag.String("flowspec_speaker_addr", "", "IPv4 address of the test as seen by the DUT, the source of the flowspec session")
+	tolerance   = flag.Float64("flowspec_tolerance", 10, "tolerance in percent of the traffic received for each rule from the expected")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The test advertises the flowspec rules to the DUT from
+// its own BGP speaker, and the ATE sends UDP traffic from port1 to
+// port2, one flow per rule and one matching no rule.
+//
+//   - Source: ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - Destination: dut:port2 -> ate:port2 subnet 192.0.2.4/30
+const (
+	plen4        = 30
+	dutAS        = 64500
+	speakerAS    = 64510
+	policy       = "ALLOW"
+	fps          = 1000
+	frameSize    = 512
+	trafficTime  = 30 * time.Second
+	rulesTimeout = 2 * time.Minute
+	// rateLimit is the rate of the rate-limiting rule, a quarter of that
+	// of its flow.
+	rateLimit = fps * frameSize / 4
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "DUT to ATE port1", IPv4: "192.0.2.1", IPv4Len: plen4}
+	atePort1 = attrs.Attributes{Name: "port1", IPv4: "192.0.2.2", IPv4Len: plen4}
+	dutPort2 = attrs.Attributes{Desc: "DUT to ATE port2", IPv4: "192.0.2.5", IPv4Len: plen4}
+	atePort2 = attrs.Attributes{Name: "port2", IPv4: "192.0.2.6", IPv4Len: plen4}
+)
+
+// udp returns the values matching UDP.
+func udp() []flowspec.Range {
+	return []flowspec.Range{flowspec.Value(17)}
+}
+
+// rules are the flowspec rules advertised to the DUT, matching the
+// traffic to ate:port2 by UDP destination port.
+var rules = []*flowspec.Rule{{
+	Dst:       "192.0.2.4/30",
+	Protocols: udp(),
+	DstPorts:  []flowspec.Range{flowspec.Value(5001)},
+	Action:    flowspec.Action{Type: flowspec.Drop, AS: speakerAS},
+}, {
+	Dst:       "192.0.2.4/30",
+	Protocols: udp(),
+	DstPorts:  []flowspec.Range{flowspec.Value(5002)},
+	Action:    flowspec.Action{Type: flowspec.RateLimit, AS: speakerAS, Rate: rateLimit},
+}, {
+	Dst:       "192.0.2.4/30",
+	Protocols: udp(),
+	DstPorts:  []flowspec.Range{flowspec.Value(5003)},
+	Action:    flowspec.Action{Type: flowspec.Mark, DSCP: 46},
+}}
+
+// dstPorts are the UDP destination ports of the flows: those of the
+// rules, and one matching none.
+var dstPorts = []uint16{5001, 5002, 5003, 5004}
+
+// configureDUT configures the ports, and the flowspec session with the
+// speaker of the test, accepting all the rules.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	dc := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		intf := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		dc.Interface(intf.GetName()).Replace(t, intf)
+	}
+
+	rp := &telemetry.RoutingPolicy{}
+	rp.GetOrCreatePolicyDefinition(policy).GetOrCreateStatement("10").GetOrCreateActions().
+		PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+	dc.RoutingPolicy().Replace(t, rp)
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String(dutPort1.IPv4)
+	nbr := bgp.GetOrCreateNeighbor(*speakerAddr)
+	nbr.PeerAs = ygot.Uint32(speakerAS)
+	nbr.Enabled = ygot.Bool(true)
+	mh := nbr.GetOrCreateEbgpMultihop()
+	mh.Enabled = ygot.Bool(true)
+	mh.MultihopTtl = ygot.Uint8(255)
+	flowspec.Enable(nbr)
+	nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_FLOWSPEC).GetOrCreateApplyPolicy().
+		ImportPolicy = []string{policy}
+	dc.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Replace(t, bgp)
+}
+
+// configureATE configures the ports and returns a flow to each of the
+// destination ports.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) []*ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	top.Push(t).StartProtocols(t)
+
+	var flows []*ondatra.Flow
+	for _, port := range dstPorts {
+		flows = append(flows, ate.Traffic().NewFlow(fmt.Sprintf("udp%d", port)).
+			WithSrcEndpoints(src).
+			WithDstEndpoints(dst).
+			WithHeaders(
+				ondatra.NewEthernetHeader(),
+				ondatra.NewIPv4Header(),
+				ondatra.NewUDPHeader().WithDstPort(port)).
+			WithFrameRateFPS(fps).
+			WithFrameSize(frameSize))
+	}
+	return flows
+}
+
+// awaitRules waits for the DUT to receive the number of rules from the
+// speaker.
+func awaitRules(t *testing.T, dut *ondatra.DUTDevice, want uint32) {
+	received := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().
+		Neighbor(*speakerAddr).AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_FLOWSPEC).Prefixes().Received()
+	_, ok := received.Watch(t, rulesTimeout, func(v *telemetry.QualifiedUint32) bool {
+		return v.IsPresent() && v.Val(t) == want
+	}).Await(t)
+	if !ok {
+		t.Fatalf("DUT received %v flowspec rules from %s, want %d", received.Lookup(t), *speakerAddr, want)
+	}
+}
+
+// checkTraffic sends the flows and checks that the outcome of each
+// matches the action of the rules for its packets.
+func checkTraffic(t *testing.T, ate *ondatra.ATEDevice, flows []*ondatra.Flow, rules []*flowspec.Rule) {
+	ate.Traffic().Start(t, flows...)
+	time.Sleep(trafficTime)
+	ate.Traffic().Stop(t)
+
+	for i, flow := range flows {
+		p := &flowspec.Packet{
+			Src:      net.ParseIP(atePort1.IPv4),
+			Dst:      net.ParseIP(atePort2.IPv4),
+			Protocol: 17,
+			DstPort:  dstPorts[i],
+		}
+		a := flowspec.ActionFor(rules, p)
+		o := flowspec.ReadOutcome(t, ate, flow.Name(), trafficTime)
+		if err := a.Check(o, *tolerance); err != nil {
+			t.Errorf("Flow %s with action %d: %v", flow.Name(), a.Type, err)
+		}
+	}
+}
+
+// TestFlowspec advertises the rules to the DUT and checks that it
+// applies them to the traffic, then that it forwards all the traffic
+// once they are withdrawn.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/afi-safis/afi-safi/config/enabled
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/ebgp-multihop/config/multihop-ttl
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/afi-safis/afi-safi/state/prefixes/received
+func TestFlowspec(t *testing.T) {
+	if *dutAddr == "" || *speakerAddr == "" {
+		t.Skip("The flowspec speaker is not reachable, see -flowspec_dut_addr and -flowspec_speaker_addr")
+	}
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	flows := configureATE(t, ate)
+
+	s := &flowspec.Speaker{AS: speakerAS, RouterID: net.ParseIP(*speakerAddr), HoldTime: 90 * time.Second}
+	if err := s.Dial(*dutAddr, rulesTimeout); err != nil {
+		t.Fatalf("Cannot establish the flowspec session with %s: %v", *dutAddr, err)
+	}
+	defer s.Close()
+
+	t.Run("Advertised", func(t *testing.T) {
+		if err := s.Advertise(rules); err != nil {
+			t.Fatalf("Cannot advertise the flowspec rules: %v", err)
+		}
+		awaitRules(t, dut, uint32(len(rules)))
+		checkTraffic(t, ate, flows, rules)
+	})
+
+	t.Run("Withdrawn", func(t *testing.T) {
+		if err := s.Withdraw(rules); err != nil {
+			t.Fatalf("Cannot withdraw the flowspec rules: %v", err)
+		}
+		awaitRules(t, dut, 0)
+		checkTraffic(t, ate, flows, nil)
+	})
+}
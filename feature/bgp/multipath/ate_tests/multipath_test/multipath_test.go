@@ -0,0 +1,340 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package multipath_test implements RT-1.8: BGP Multipath.
+package multipath_test
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpcomm"
+	"github.com/openconfig/featureprofiles/internal/bgpmultipath"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var tolerance = flag.Float64("multipath_tolerance", 10, "tolerance in percent of the packets received on each path from the expected count")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port{2-4} ->
+// ate:port{2-4}.  The ATE advertises the same prefix over BGP from port2
+// to port4, and sends traffic to it from port1.
+//
+//   - Source: ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - Paths: dut:port{2-4} -> ate:port{2-4} subnets 192.0.2.4/30,
+//     192.0.2.8/30 and 192.0.2.12/30
+//   - Destination network: 198.51.100.0/24
+const (
+	plen4       = 30
+	paths       = 3
+	prefix      = "198.51.100.0/24"
+	dstMin      = "198.51.100.1"
+	dstCount    = 254
+	dutAS       = 64500
+	ateAS       = 64501
+	fps         = 10000
+	trafficTime = 15 * time.Second
+	aftTimeout  = 2 * time.Minute
+)
+
+// dutPorts and atePorts are the attributes of the DUT and ATE ends of
+// port1, port2, and so on.
+var (
+	dutPorts = []attrs.Attributes{
+		{
+			Desc:    "DUT to ATE port1",
+			IPv4:    "192.0.2.1",
+			IPv4Len: plen4,
+		},
+		{
+			Desc:    "DUT to ATE port2",
+			IPv4:    "192.0.2.5",
+			IPv4Len: plen4,
+		},
+		{
+			Desc:    "DUT to ATE port3",
+			IPv4:    "192.0.2.9",
+			IPv4Len: plen4,
+		},
+		{
+			Desc:    "DUT to ATE port4",
+			IPv4:    "192.0.2.13",
+			IPv4Len: plen4,
+		},
+	}
+
+	atePorts = []attrs.Attributes{
+		{
+			Name:    "port1",
+			IPv4:    "192.0.2.2",
+			IPv4Len: plen4,
+		},
+		{
+			Name:    "port2",
+			IPv4:    "192.0.2.6",
+			IPv4Len: plen4,
+		},
+		{
+			Name:    "port3",
+			IPv4:    "192.0.2.10",
+			IPv4Len: plen4,
+		},
+		{
+			Name:    "port4",
+			IPv4:    "192.0.2.14",
+			IPv4Len: plen4,
+		},
+	}
+)
+
+// importPolicy returns the name of the import policy of port i.
+func importPolicy(i int) string {
+	return fmt.Sprintf("IMPORT-PORT%d", i)
+}
+
+// configurePorts configures the ports of the DUT.
+func configurePorts(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for i := 1; i <= paths+1; i++ {
+		dutAttrs := dutPorts[i-1]
+		p := dut.Port(t, fmt.Sprintf("port%d", i))
+		d.Interface(p.Name()).Replace(t, dutAttrs.NewInterface(p.Name()))
+	}
+}
+
+// session is the BGP configuration of a test case.
+type session struct {
+	peerAS     uint32
+	ebgp, ibgp uint32
+	// bandwidths are the link bandwidths the DUT attaches to the routes
+	// received from each path, if any.
+	bandwidths []uint64
+}
+
+// configureDUT configures the BGP sessions of the DUT with the ATE on
+// port2 to port4, and waits for them to be established.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice, s *session) {
+	d := dut.Config()
+	rp := &telemetry.RoutingPolicy{}
+	for i := 2; i <= paths+1; i++ {
+		a := rp.GetOrCreatePolicyDefinition(importPolicy(i)).GetOrCreateStatement("10").GetOrCreateActions()
+		a.PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+		if s.bandwidths != nil {
+			lb := bgpcomm.LinkBandwidth(uint16(s.peerAS), s.bandwidths[i-2])
+			if err := bgpcomm.SetExtCommunities(a, telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD, lb); err != nil {
+				t.Fatalf("Cannot build routing policy: %v", err)
+			}
+		}
+	}
+	d.RoutingPolicy().Replace(t, rp)
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String("192.0.2.1")
+	global.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+	bgpmultipath.Configure(bgp, s.ebgp, s.ibgp, false)
+	for i := 2; i <= paths+1; i++ {
+		ateAttrs := atePorts[i-1]
+		nbr := bgp.GetOrCreateNeighbor(ateAttrs.IPv4)
+		nbr.PeerAs = ygot.Uint32(s.peerAS)
+		nbr.Enabled = ygot.Bool(true)
+		nbr.SendCommunity = telemetry.BgpTypes_CommunityType_BOTH
+		af := nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST)
+		af.Enabled = ygot.Bool(true)
+		af.GetOrCreateApplyPolicy().ImportPolicy = []string{importPolicy(i)}
+	}
+	bgpPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	fptest.LogYgot(t, "DUT BGP", bgpPath, bgp)
+	bgpPath.Replace(t, bgp)
+
+	state := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	for i := 2; i <= paths+1; i++ {
+		ateAttrs := atePorts[i-1]
+		_, ok := state.Neighbor(ateAttrs.IPv4).SessionState().Watch(t, time.Minute, func(val *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+			return val.IsPresent() && val.Val(t) == telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+		}).Await(t)
+		if !ok {
+			t.Fatalf("BGP session with %s is not established", ateAttrs.IPv4)
+		}
+	}
+}
+
+// configureATE configures the ATE interfaces and the BGP sessions
+// advertising the prefix from port2 to port4, and returns the source and
+// the paths.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice, s *session) (*ondatra.Interface, []ondatra.Endpoint) {
+	top := ate.Topology().New()
+	var src *ondatra.Interface
+	var dsts []ondatra.Endpoint
+	for i := 1; i <= paths+1; i++ {
+		dutAttrs, ateAttrs := dutPorts[i-1], atePorts[i-1]
+		intf := ateAttrs.AddToATE(top, ate.Port(t, ateAttrs.Name), &dutAttrs)
+		if i == 1 {
+			src = intf
+			continue
+		}
+		peer := intf.BGP().AddPeer().WithPeerAddress(dutAttrs.IPv4).WithLocalASN(s.peerAS)
+		if s.peerAS == dutAS {
+			peer.WithTypeInternal()
+		} else {
+			peer.WithTypeExternal()
+		}
+		net := intf.AddNetwork("net-" + ateAttrs.Name)
+		net.IPv4().WithAddress(prefix).WithCount(1)
+		net.BGP().WithNextHopAddress(ateAttrs.IPv4).WithOriginIGP()
+		dsts = append(dsts, intf)
+	}
+	top.Push(t).StartProtocols(t)
+	return src, dsts
+}
+
+// awaitNextHops waits for the DUT to program n next hops for the prefix,
+// and returns their weights.
+func awaitNextHops(t *testing.T, dut *ondatra.DUTDevice, n int) map[string]uint64 {
+	deadline := time.Now().Add(aftTimeout)
+	for {
+		weights, err := bgpmultipath.ReadNextHops(t, dut, prefix)
+		switch {
+		case err == nil && len(weights) == n:
+			return weights
+		case time.Now().After(deadline):
+			t.Fatalf("DUT next hops for %s got %v (%v), want %d next hops", prefix, weights, err, n)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// sendTraffic sends traffic from the source to the prefix, and returns
+// the packets received on each path.
+func sendTraffic(t *testing.T, ate *ondatra.ATEDevice, src *ondatra.Interface, dsts []ondatra.Endpoint) []uint64 {
+	srcAttrs := atePorts[0]
+	ip := ondatra.NewIPv4Header().WithSrcAddress(srcAttrs.IPv4)
+	ip.DstAddressRange().WithMin(dstMin).WithCount(dstCount)
+	udp := ondatra.NewUDPHeader()
+	udp.SrcPortRange().WithMin(10000).WithCount(1000)
+	flow := ate.Traffic().NewFlow("multipath").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dsts...).
+		WithHeaders(ondatra.NewEthernetHeader(), ip, udp).
+		WithFrameRateFPS(fps)
+
+	received := func() []uint64 {
+		var pkts []uint64
+		for i := 2; i <= paths+1; i++ {
+			p := ate.Port(t, fmt.Sprintf("port%d", i))
+			pkts = append(pkts, ate.Telemetry().Interface(p.Name()).Counters().InPkts().Get(t))
+		}
+		return pkts
+	}
+	before := received()
+	ate.Traffic().Start(t, flow)
+	time.Sleep(trafficTime)
+	ate.Traffic().Stop(t)
+	// Let the counters settle.
+	time.Sleep(5 * time.Second)
+	after := received()
+
+	counts := make([]uint64, len(after))
+	for i := range after {
+		counts[i] = after[i] - before[i]
+	}
+	t.Logf("Packets received on each path: %v", counts)
+	return counts
+}
+
+func TestMultipath(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configurePorts(t, dut)
+
+	for _, c := range []struct {
+		desc string
+		s    session
+		want int
+	}{
+		{"eBGP single path", session{peerAS: ateAS, ebgp: 1}, 1},
+		{"eBGP 2 paths", session{peerAS: ateAS, ebgp: 2}, 2},
+		{"eBGP 3 paths", session{peerAS: ateAS, ebgp: 3}, 3},
+		{"iBGP single path", session{peerAS: dutAS, ibgp: 1}, 1},
+		{"iBGP 3 paths", session{peerAS: dutAS, ibgp: 3}, 3},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			src, dsts := configureATE(t, ate, &c.s)
+			configureDUT(t, dut, &c.s)
+			weights := awaitNextHops(t, dut, c.want)
+			t.Logf("DUT next hops for %s: %v", prefix, weights)
+
+			counts := sendTraffic(t, ate, src, dsts)
+			if err := bgpmultipath.CheckSpread(counts, c.want, *tolerance); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestLinkBandwidth(t *testing.T) {
+	if !*deviations.BGPLinkBandwidth {
+		t.Skip("Link bandwidth multipath is not configured, see -deviation_bgp_link_bandwidth")
+	}
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configurePorts(t, dut)
+
+	s := &session{peerAS: ateAS, ebgp: paths, bandwidths: []uint64{1e9, 3e9, 4e9}}
+	want, err := bgpmultipath.Weights(s.bandwidths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, dsts := configureATE(t, ate, s)
+	configureDUT(t, dut, s)
+
+	weights := awaitNextHops(t, dut, paths)
+	t.Logf("DUT next hops for %s: %v", prefix, weights)
+	var aft []uint64
+	for i := 2; i <= paths+1; i++ {
+		ateAttrs := atePorts[i-1]
+		aft = append(aft, weights[ateAttrs.IPv4])
+	}
+	got, err := bgpmultipath.Weights(aft)
+	if err != nil {
+		t.Fatalf("DUT next hop weights: %v", err)
+	}
+	for i := range want {
+		// The AFT weights are integers, so allow for rounding.
+		if math.Abs(got[i]-want[i]) > 0.02 {
+			t.Errorf("DUT next hop weights got %v, want in proportion to %v", aft, s.bandwidths)
+			break
+		}
+	}
+
+	counts := sendTraffic(t, ate, src, dsts)
+	if err := bgpmultipath.Check(counts, want, *tolerance); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,458 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package community_test implements RT-1.6: BGP Community Manipulation.
+package community_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpcomm"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The ATE advertises prefixes with communities from port1,
+// the DUT applies the policy under test on import, and the ATE checks
+// the communities of the routes received on port2.
+//
+//   - ate:port1 (AS 64501) -> dut:port1 subnet 192.0.2.0/30
+//   - dut:port2 -> ate:port2 (AS 64502) subnet 192.0.2.4/30
+const (
+	dutAS        = 64500
+	ateSrcAS     = 64501
+	ateDstAS     = 64502
+	plenIPv4     = 30
+	policyName   = "COMMUNITY-POLICY"
+	exportPolicy = "PERMIT-ALL"
+	matchSet     = "MATCH-SET"
+	largeImport  = "LARGE-COMMUNITY-IMPORT"
+	largeExport  = "LARGE-COMMUNITY-EXPORT"
+	prefixA      = "198.51.100.0/24"
+	prefixB      = "203.0.113.0/24"
+	ribTimeout   = 2 * time.Minute
+	pollInterval = 5 * time.Second
+)
+
+var (
+	dutSrc = attrs.Attributes{
+		Desc:    "DUT to ATE source",
+		IPv4:    "192.0.2.1",
+		IPv4Len: plenIPv4,
+	}
+	ateSrc = attrs.Attributes{
+		Name:    "atesrc",
+		IPv4:    "192.0.2.2",
+		IPv4Len: plenIPv4,
+	}
+	dutDst = attrs.Attributes{
+		Desc:    "DUT to ATE destination",
+		IPv4:    "192.0.2.5",
+		IPv4Len: plenIPv4,
+	}
+	ateDst = attrs.Attributes{
+		Name:    "atedst",
+		IPv4:    "192.0.2.6",
+		IPv4Len: plenIPv4,
+	}
+
+	// advertised are the communities advertised by the ATE for each
+	// prefix.
+	advertised = map[string][]string{
+		prefixA: {"64501:1", "64501:2"},
+		prefixB: {"64501:1"},
+	}
+
+	// The large community policies are configured out of band.  On
+	// import from ate:port1, the routes with community largeMatch get
+	// largeAdd, then all routes get largeRemove.  On export to
+	// ate:port2, the routes matching each large community set are
+	// tagged with a standard community the ATE reports.
+	largeMatch = "64501:2"
+	largeAdd   = &bgpcomm.LargeAction{
+		Option:      telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD,
+		Communities: []bgpcomm.Large{{64500, 1, 1}, {64500, 1, 2}},
+	}
+	largeRemove = &bgpcomm.LargeAction{
+		Option:      telemetry.BgpPolicy_BgpSetCommunityOptionType_REMOVE,
+		Communities: []bgpcomm.Large{{64500, 1, 2}},
+	}
+	largeTags = []struct {
+		set *bgpcomm.LargeSet
+		tag string
+	}{{
+		set: &bgpcomm.LargeSet{
+			Name:        "LARGE-1",
+			Match:       telemetry.PolicyTypes_MatchSetOptionsType_ANY,
+			Communities: []bgpcomm.Large{{64500, 1, 1}},
+		},
+		tag: "64500:301",
+	}, {
+		set: &bgpcomm.LargeSet{
+			Name:        "LARGE-2",
+			Match:       telemetry.PolicyTypes_MatchSetOptionsType_ANY,
+			Communities: []bgpcomm.Large{{64500, 1, 2}},
+		},
+		tag: "64500:302",
+	}}
+)
+
+// configureDUT configures the ports and the BGP sessions of the DUT,
+// with policyName applied on import from ate:port1.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := &telemetry.Device{}
+	rp := d.GetOrCreateRoutingPolicy()
+	rp.GetOrCreatePolicyDefinition(policyName).GetOrCreateStatement("20").
+		GetOrCreateActions().PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+	rp.GetOrCreatePolicyDefinition(exportPolicy).GetOrCreateStatement("20").
+		GetOrCreateActions().PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+	dut.Config().RoutingPolicy().Replace(t, rp)
+	configureBGP(t, dut, policyName, exportPolicy)
+}
+
+// configureBGP configures the ports and the BGP sessions of the DUT,
+// with importPolicy applied on import from ate:port1 and exportPolicy on
+// export to ate:port2.
+func configureBGP(t *testing.T, dut *ondatra.DUTDevice, importPolicy, exportPolicy string) {
+	dc := dut.Config()
+	i1 := dutSrc.NewInterface(dut.Port(t, "port1").Name())
+	dc.Interface(i1.GetName()).Replace(t, i1)
+	i2 := dutDst.NewInterface(dut.Port(t, "port2").Name())
+	dc.Interface(i2.GetName()).Replace(t, i2)
+
+	d := &telemetry.Device{}
+	ni := d.GetOrCreateNetworkInstance(*deviations.DefaultNetworkInstance)
+	bgp := ni.GetOrCreateProtocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").GetOrCreateBgp()
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String(dutSrc.IPv4)
+	for _, n := range []struct {
+		addr   string
+		as     uint32
+		policy *telemetry.NetworkInstance_Protocol_Bgp_Neighbor_AfiSafi_ApplyPolicy
+	}{
+		{ateSrc.IPv4, ateSrcAS, &telemetry.NetworkInstance_Protocol_Bgp_Neighbor_AfiSafi_ApplyPolicy{ImportPolicy: []string{importPolicy}}},
+		{ateDst.IPv4, ateDstAS, &telemetry.NetworkInstance_Protocol_Bgp_Neighbor_AfiSafi_ApplyPolicy{ExportPolicy: []string{exportPolicy}}},
+	} {
+		nbr := bgp.GetOrCreateNeighbor(n.addr)
+		nbr.PeerAs = ygot.Uint32(n.as)
+		nbr.Enabled = ygot.Bool(true)
+		nbr.SendCommunity = telemetry.BgpTypes_CommunityType_BOTH
+		af := nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST)
+		af.Enabled = ygot.Bool(true)
+		af.ApplyPolicy = n.policy
+	}
+	dc.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Replace(t, bgp)
+}
+
+// configureATE configures the BGP sessions of the ATE, and advertises
+// the prefixes with their communities from ate:port1.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) {
+	top := ate.Topology().New()
+	src := top.AddInterface(ateSrc.Name).WithPort(ate.Port(t, "port1"))
+	src.IPv4().WithAddress(ateSrc.IPv4CIDR()).WithDefaultGateway(dutSrc.IPv4)
+	src.BGP().AddPeer().WithPeerAddress(dutSrc.IPv4).WithLocalASN(ateSrcAS).WithTypeExternal()
+
+	dst := top.AddInterface(ateDst.Name).WithPort(ate.Port(t, "port2"))
+	dst.IPv4().WithAddress(ateDst.IPv4CIDR()).WithDefaultGateway(dutDst.IPv4)
+	dst.BGP().AddPeer().WithPeerAddress(dutDst.IPv4).WithLocalASN(ateDstAS).WithTypeExternal()
+
+	for i, prefix := range []string{prefixA, prefixB} {
+		net := src.AddNetwork(fmt.Sprintf("net%d", i))
+		net.IPv4().WithAddress(prefix).WithCount(1)
+		net.BGP().WithNextHopAddress(ateSrc.IPv4).Communities().WithPrivateCommunities(advertised[prefix]...)
+	}
+	top.Push(t).StartProtocols(t)
+}
+
+// awaitEstablished waits for the BGP sessions of the DUT to be
+// established.
+func awaitEstablished(t *testing.T, dut *ondatra.DUTDevice) {
+	bgp := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	for _, addr := range []string{ateSrc.IPv4, ateDst.IPv4} {
+		_, ok := bgp.Neighbor(addr).SessionState().Watch(t, time.Minute, func(val *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+			return val.IsPresent() && val.Val(t) == telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+		}).Await(t)
+		if !ok {
+			t.Fatalf("BGP session with %s is not established", addr)
+		}
+	}
+}
+
+// want lists the communities expected on a prefix received by the ATE,
+// or whether the prefix is rejected.
+type want struct {
+	present, absent []string
+	rejected        bool
+}
+
+// checkATE returns the differences between the communities of the
+// prefix received by the ATE and the communities wanted.
+func checkATE(t *testing.T, ate *ondatra.ATEDevice, prefix string, w want) []error {
+	got, err := bgpcomm.ReadATE(t, ate, ateDst.Name, fmt.Sprint(ateDstAS), dutDst.IPv4, prefix, pollInterval)
+	switch {
+	case w.rejected && err == nil:
+		return []error{fmt.Errorf("ATE received rejected prefix with communities %v", got)}
+	case w.rejected:
+		return nil
+	case err != nil:
+		return []error{err}
+	}
+	return bgpcomm.Check(got, w.present, w.absent)
+}
+
+// await calls check until it returns no error or ribTimeout elapses, to
+// let the DUT apply a new policy, and returns the last errors.
+func await(check func() []error) []error {
+	deadline := time.Now().Add(ribTimeout)
+	for {
+		errs := check()
+		if len(errs) == 0 || time.Now().After(deadline) {
+			return errs
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func TestCommunities(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	configureATE(t, ate)
+	awaitEstablished(t, dut)
+
+	accept := func(s *telemetry.RoutingPolicy_PolicyDefinition_Statement) *telemetry.RoutingPolicy_PolicyDefinition_Statement_Actions {
+		a := s.GetOrCreateActions()
+		a.PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+		return a
+	}
+
+	cases := []struct {
+		desc      string
+		configure func(rp *telemetry.RoutingPolicy, pd *telemetry.RoutingPolicy_PolicyDefinition) error
+		want      map[string]want
+	}{{
+		desc: "Add standard community",
+		configure: func(_ *telemetry.RoutingPolicy, pd *telemetry.RoutingPolicy_PolicyDefinition) error {
+			return bgpcomm.SetCommunities(accept(pd.GetOrCreateStatement("20")), telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD, "64500:100")
+		},
+		want: map[string]want{
+			prefixA: {present: []string{"64501:1", "64501:2", "64500:100"}},
+			prefixB: {present: []string{"64501:1", "64500:100"}},
+		},
+	}, {
+		desc: "Remove standard community",
+		configure: func(_ *telemetry.RoutingPolicy, pd *telemetry.RoutingPolicy_PolicyDefinition) error {
+			return bgpcomm.SetCommunities(accept(pd.GetOrCreateStatement("20")), telemetry.BgpPolicy_BgpSetCommunityOptionType_REMOVE, "64501:2")
+		},
+		want: map[string]want{
+			prefixA: {present: []string{"64501:1"}, absent: []string{"64501:2"}},
+			prefixB: {present: []string{"64501:1"}},
+		},
+	}, {
+		desc: "Replace standard communities",
+		configure: func(_ *telemetry.RoutingPolicy, pd *telemetry.RoutingPolicy_PolicyDefinition) error {
+			return bgpcomm.SetCommunities(accept(pd.GetOrCreateStatement("20")), telemetry.BgpPolicy_BgpSetCommunityOptionType_REPLACE, "64500:200")
+		},
+		want: map[string]want{
+			prefixA: {present: []string{"64500:200"}, absent: []string{"64501:1", "64501:2"}},
+			prefixB: {present: []string{"64500:200"}, absent: []string{"64501:1"}},
+		},
+	}, {
+		desc: "Match community set and add",
+		configure: func(rp *telemetry.RoutingPolicy, pd *telemetry.RoutingPolicy_PolicyDefinition) error {
+			if err := bgpcomm.ConfigureSet(rp, matchSet, telemetry.PolicyTypes_MatchSetOptionsType_ANY, "64501:2"); err != nil {
+				return err
+			}
+			s := pd.GetOrCreateStatement("10")
+			s.GetOrCreateConditions().GetOrCreateBgpConditions().CommunitySet = ygot.String(matchSet)
+			if err := bgpcomm.SetCommunities(accept(s), telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD, "64500:2"); err != nil {
+				return err
+			}
+			accept(pd.GetOrCreateStatement("20"))
+			return nil
+		},
+		want: map[string]want{
+			prefixA: {present: []string{"64501:1", "64501:2", "64500:2"}},
+			prefixB: {present: []string{"64501:1"}, absent: []string{"64500:2"}},
+		},
+	}, {
+		desc: "Match community set and reject",
+		configure: func(rp *telemetry.RoutingPolicy, pd *telemetry.RoutingPolicy_PolicyDefinition) error {
+			if err := bgpcomm.ConfigureSet(rp, matchSet, telemetry.PolicyTypes_MatchSetOptionsType_ANY, "64501:2"); err != nil {
+				return err
+			}
+			s := pd.GetOrCreateStatement("10")
+			s.GetOrCreateConditions().GetOrCreateBgpConditions().CommunitySet = ygot.String(matchSet)
+			s.GetOrCreateActions().PolicyResult = telemetry.RoutingPolicy_PolicyResultType_REJECT_ROUTE
+			accept(pd.GetOrCreateStatement("20"))
+			return nil
+		},
+		want: map[string]want{
+			prefixA: {rejected: true},
+			prefixB: {present: []string{"64501:1"}},
+		},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			rp := &telemetry.RoutingPolicy{}
+			rp.GetOrCreatePolicyDefinition(exportPolicy).GetOrCreateStatement("20").
+				GetOrCreateActions().PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+			if err := c.configure(rp, rp.GetOrCreatePolicyDefinition(policyName)); err != nil {
+				t.Fatalf("Cannot build routing policy: %v", err)
+			}
+			dut.Config().RoutingPolicy().Replace(t, rp)
+
+			for _, prefix := range []string{prefixA, prefixB} {
+				for _, err := range await(func() []error { return checkATE(t, ate, prefix, c.want[prefix]) }) {
+					t.Errorf("Prefix %s: %v", prefix, err)
+				}
+			}
+		})
+	}
+}
+
+func TestExtendedCommunities(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	configureATE(t, ate)
+	awaitEstablished(t, dut)
+
+	for _, c := range []struct {
+		desc            string
+		option          telemetry.E_BgpPolicy_BgpSetCommunityOptionType
+		communities     []string
+		present, absent []string
+	}{{
+		desc:        "Add extended communities",
+		option:      telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD,
+		communities: []string{"route-target:64500:1", "route-origin:64500:2"},
+		present:     []string{"route-target:64500:1", "route-origin:64500:2"},
+	}, {
+		desc:        "Replace extended communities",
+		option:      telemetry.BgpPolicy_BgpSetCommunityOptionType_REPLACE,
+		communities: []string{"route-target:64500:3"},
+		present:     []string{"route-target:64500:3"},
+		absent:      []string{"route-target:64500:1", "route-origin:64500:2"},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			rp := &telemetry.RoutingPolicy{}
+			rp.GetOrCreatePolicyDefinition(exportPolicy).GetOrCreateStatement("20").
+				GetOrCreateActions().PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+			a := rp.GetOrCreatePolicyDefinition(policyName).GetOrCreateStatement("20").GetOrCreateActions()
+			a.PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+			if err := bgpcomm.SetExtCommunities(a, c.option, c.communities...); err != nil {
+				t.Fatalf("Cannot build routing policy: %v", err)
+			}
+			dut.Config().RoutingPolicy().Replace(t, rp)
+
+			// The ATE does not report extended communities, so they
+			// are read from the routes advertised by the DUT.
+			for _, err := range await(func() []error {
+				got, err := bgpcomm.ReadDUTExtended(t, dut, ateDst.IPv4, prefixA, pollInterval)
+				if err != nil {
+					return []error{err}
+				}
+				return bgpcomm.CheckExtended(got, c.present, c.absent)
+			}) {
+				t.Errorf("Prefix %s: %v", prefixA, err)
+			}
+			// The standard communities must be left untouched.
+			for _, err := range checkATE(t, ate, prefixA, want{present: advertised[prefixA]}) {
+				t.Errorf("Prefix %s: %v", prefixA, err)
+			}
+		})
+	}
+}
+
+// largeConfig returns the large community policies the DUT is configured
+// with out of band.
+func largeConfig() []string {
+	config := []string{
+		fmt.Sprintf("policy %s on import from %s: if community %s then %v; then %v and accept route",
+			largeImport, ateSrc.IPv4, largeMatch, largeAdd, largeRemove),
+	}
+	for _, lt := range largeTags {
+		config = append(config, fmt.Sprintf("policy %s on export to %s: if large community set %v then add community %s",
+			largeExport, ateDst.IPv4, lt.set, lt.tag))
+	}
+	return append(config, fmt.Sprintf("policy %s on export to %s: accept route", largeExport, ateDst.IPv4))
+}
+
+// wantLarge returns the communities expected on a prefix received by the
+// ATE through the large community policies.
+func wantLarge(prefix string) (want, error) {
+	var large []bgpcomm.Large
+	var err error
+	for _, c := range advertised[prefix] {
+		if c == largeMatch {
+			if large, err = largeAdd.Apply(large); err != nil {
+				return want{}, err
+			}
+		}
+	}
+	if large, err = largeRemove.Apply(large); err != nil {
+		return want{}, err
+	}
+	w := want{present: append([]string{}, advertised[prefix]...)}
+	for _, lt := range largeTags {
+		ok, err := lt.set.Matches(large)
+		if err != nil {
+			return want{}, err
+		}
+		if ok {
+			w.present = append(w.present, lt.tag)
+		} else {
+			w.absent = append(w.absent, lt.tag)
+		}
+	}
+	return w, nil
+}
+
+func TestLargeCommunities(t *testing.T) {
+	bgpcomm.RequireLarge(t, largeConfig()...)
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureBGP(t, dut, largeImport, largeExport)
+	configureATE(t, ate)
+	awaitEstablished(t, dut)
+
+	// The ATE neither sends nor reports large communities, so they are
+	// verified through the standard communities tagged by the DUT.
+	for _, prefix := range []string{prefixA, prefixB} {
+		w, err := wantLarge(prefix)
+		if err != nil {
+			t.Fatalf("Prefix %s: %v", prefix, err)
+		}
+		for _, err := range await(func() []error { return checkATE(t, ate, prefix, w) }) {
+			t.Errorf("Prefix %s: %v", prefix, err)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package update_generation_test implements RT-1.10: BGP Update
+// Generation.
+package update_generation_test
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpupdate"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	routeCounts = flag.String("bgp_update_route_counts", "10000,100000",
+		"Comma separated numbers of routes the DUT advertises to the peers, one measurement each.")
+	peerCount = flag.Int("bgp_update_peers", 3,
+		"Number of ATE peers the DUT advertises the routes to, all on port2.")
+	updateTimeout = flag.Duration("bgp_update_timeout", 10*time.Minute,
+		"How long to wait for the DUT to receive or advertise all the routes.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The ATE advertises the routes from port1, and the DUT
+// advertises them to the peers emulated on port2.
+//
+//   - Source: ate:port1 (AS 64501) -> dut:port1 subnet 192.0.2.0/30
+//   - Peers: dut:port2 -> ate:port2 (AS 64502) subnet 198.18.0.0/16, the
+//     peers using the addresses from 198.18.0.2
+//   - Routes: /24 prefixes from 100.0.0.0/24
+const (
+	plenIPv4     = 30
+	plenPeers    = 16
+	dutAS        = 64500
+	ateAS        = 64501
+	peersAS      = 64502
+	routesStart  = "100.0.0.0/24"
+	policy       = "ALLOW"
+	pollInterval = 5 * time.Second
+	// maxPeers is the number of addresses of the peers subnet, less the
+	// network, DUT and broadcast addresses.
+	maxPeers = 1<<(32-plenPeers) - 3
+)
+
+var (
+	dutSrc = attrs.Attributes{
+		Desc:    "DUT to ATE source",
+		IPv4:    "192.0.2.1",
+		IPv4Len: plenIPv4,
+	}
+
+	ateSrc = attrs.Attributes{
+		Name:    "src",
+		IPv4:    "192.0.2.2",
+		IPv4Len: plenIPv4,
+	}
+
+	dutPeers = attrs.Attributes{
+		Desc:    "DUT to ATE peers",
+		IPv4:    "198.18.0.1",
+		IPv4Len: plenPeers,
+	}
+)
+
+// peerAttrs returns the attributes of the ATE peer i, counting from 0.
+func peerAttrs(i int) attrs.Attributes {
+	host := i + 2
+	return attrs.Attributes{
+		Name:    fmt.Sprintf("peer%d", i),
+		IPv4:    fmt.Sprintf("198.18.%d.%d", host>>8, host&0xff),
+		IPv4Len: plenPeers,
+	}
+}
+
+// peerAddrs returns the addresses of the ATE peers receiving the routes.
+func peerAddrs() []string {
+	var addrs []string
+	for i := 0; i < *peerCount; i++ {
+		addrs = append(addrs, peerAttrs(i).IPv4)
+	}
+	return addrs
+}
+
+// parseCounts parses the comma separated route counts.
+func parseCounts(s string) ([]uint32, error) {
+	var counts []uint32
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.ParseUint(strings.TrimSpace(f), 10, 32)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("bad route count %q", f)
+		}
+		counts = append(counts, uint32(n))
+	}
+	return counts, nil
+}
+
+// configureDUT configures the ports and the BGP sessions of the DUT,
+// accepting and advertising all the routes.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	dc := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutSrc}, {"port2", &dutPeers}} {
+		intf := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		dc.Interface(intf.GetName()).Replace(t, intf)
+	}
+
+	rp := &telemetry.RoutingPolicy{}
+	rp.GetOrCreatePolicyDefinition(policy).GetOrCreateStatement("10").GetOrCreateActions().
+		PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+	dc.RoutingPolicy().Replace(t, rp)
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String(dutSrc.IPv4)
+	addNeighbor := func(addr string, as uint32) {
+		nbr := bgp.GetOrCreateNeighbor(addr)
+		nbr.PeerAs = ygot.Uint32(as)
+		nbr.Enabled = ygot.Bool(true)
+		af := nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST)
+		af.Enabled = ygot.Bool(true)
+		ap := af.GetOrCreateApplyPolicy()
+		ap.ImportPolicy = []string{policy}
+		ap.ExportPolicy = []string{policy}
+	}
+	addNeighbor(ateSrc.IPv4, ateAS)
+	for _, addr := range peerAddrs() {
+		addNeighbor(addr, peersAS)
+	}
+	dc.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Replace(t, bgp)
+}
+
+// ateConfig is the ATE topology, with the network advertising the
+// routes from port1 and the peers receiving them.
+type ateConfig struct {
+	top   *ondatra.ATETopology
+	net   *ondatra.Network
+	peers []*ondatra.BGPPeer
+}
+
+// configureATE configures the ports and the BGP sessions of the ATE,
+// with the peers receiving the routes inactive, and starts the
+// protocols.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ateConfig {
+	c := &ateConfig{top: ate.Topology().New()}
+	src := ateSrc.AddToATE(c.top, ate.Port(t, "port1"), &dutSrc)
+	src.BGP().AddPeer().
+		WithPeerAddress(dutSrc.IPv4).
+		WithLocalASN(ateAS).
+		WithTypeExternal()
+	c.net = src.AddNetwork("routes")
+	c.net.IPv4().WithAddress(routesStart).WithCount(1)
+	c.net.BGP().WithNextHopAddress(ateSrc.IPv4)
+
+	p2 := ate.Port(t, "port2")
+	for i := 0; i < *peerCount; i++ {
+		a := peerAttrs(i)
+		peer := a.AddToATE(c.top, p2, &dutPeers).BGP().AddPeer().
+			WithPeerAddress(dutPeers.IPv4).
+			WithLocalASN(peersAS).
+			WithTypeExternal().
+			WithActive(false)
+		c.peers = append(c.peers, peer)
+	}
+	c.top.Push(t).StartProtocols(t)
+	return c
+}
+
+// setPeersActive activates or deactivates the ATE peers receiving the
+// routes.
+func (c *ateConfig) setPeersActive(t *testing.T, active bool) {
+	for _, p := range c.peers {
+		p.WithActive(active)
+	}
+	c.top.UpdateBGPPeerStates(t)
+}
+
+// awaitReceived waits for the DUT to receive the routes from ATE port1.
+func awaitReceived(t *testing.T, dut *ondatra.DUTDevice, routes uint32) {
+	received := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().
+		Neighbor(ateSrc.IPv4).AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Prefixes().Received()
+	_, ok := received.Watch(t, *updateTimeout, func(v *telemetry.QualifiedUint32) bool {
+		return v.IsPresent() && v.Val(t) == routes
+	}).Await(t)
+	if !ok {
+		t.Fatalf("DUT received %v routes from %s, want %d", received.Lookup(t), ateSrc.IPv4, routes)
+	}
+}
+
+// awaitDown waits for the sessions with the peers to go down.
+func awaitDown(t *testing.T, dut *ondatra.DUTDevice) {
+	bgp := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	for _, addr := range peerAddrs() {
+		_, ok := bgp.Neighbor(addr).SessionState().Watch(t, time.Minute, func(v *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+			return v.IsPresent() && v.Val(t) != telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+		}).Await(t)
+		if !ok {
+			t.Fatalf("BGP session with %s is still established", addr)
+		}
+	}
+}
+
+func TestUpdateGeneration(t *testing.T) {
+	counts, err := parseCounts(*routeCounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *peerCount < 1 || *peerCount > maxPeers {
+		t.Fatalf("-bgp_update_peers got %d, want 1 to %d", *peerCount, maxPeers)
+	}
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	c := configureATE(t, ate)
+
+	for _, routes := range counts {
+		t.Run(fmt.Sprintf("%d routes", routes), func(t *testing.T) {
+			c.net.IPv4().WithCount(routes)
+			c.top.UpdateNetworks(t)
+			awaitReceived(t, dut, routes)
+
+			r := &bgpupdate.Recorder{
+				DUT:       dut,
+				Neighbors: peerAddrs(),
+				Interval:  pollInterval,
+				Timeout:   *updateTimeout,
+			}
+			awaitDown(t, dut)
+			r.Start(t)
+			c.setPeersActive(t, true)
+			defer c.setPeersActive(t, false)
+
+			s := bgpupdate.Summarize(r.Wait(t, routes))
+			t.Logf("Advertising %d routes to %s", routes, s)
+			if len(s.Incomplete) > 0 {
+				t.Errorf("DUT did not advertise %d routes within %v to %v", routes, *updateTimeout, s.Incomplete)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package degraded_link_test implements RT-1.13: BGP Timers over a
+// Degraded Link.
+package degraded_link_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpauth"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/faults"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 with an eBGP session
+// between them, over a link the testbed can degrade.
+//
+//   - ate:port1 (AS 64501) -> dut:port1 (AS 64500) subnet 192.0.2.0/30
+const (
+	dutAS    = 64500
+	ateAS    = 64501
+	plenIPv4 = 30
+	// holdTime and keepalive are the BGP timers of both peers, in
+	// seconds.
+	holdTime  = 9
+	keepalive = 3
+	// establishTimeout is how long to wait for the session to establish.
+	establishTimeout = 2 * time.Minute
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "DUT to ATE port1", IPv4: "192.0.2.1", IPv4Len: plenIPv4}
+	atePort1 = attrs.Attributes{Name: "port1", IPv4: "192.0.2.2", IPv4Len: plenIPv4}
+)
+
+// configureDUT configures the port and the BGP session of the DUT, with
+// short timers.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	dc := dut.Config()
+	intf := dutPort1.NewInterface(dut.Port(t, "port1").Name())
+	dc.Interface(intf.GetName()).Replace(t, intf)
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String(dutPort1.IPv4)
+	nbr := bgp.GetOrCreateNeighbor(atePort1.IPv4)
+	nbr.PeerAs = ygot.Uint32(ateAS)
+	nbr.Enabled = ygot.Bool(true)
+	timers := nbr.GetOrCreateTimers()
+	timers.HoldTime = ygot.Uint16(holdTime)
+	timers.KeepaliveInterval = ygot.Uint16(keepalive)
+	nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+	dc.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Replace(t, bgp)
+}
+
+// configureATE configures the port and the BGP session of the ATE, and
+// starts the protocols.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) {
+	top := ate.Topology().New()
+	intf := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	intf.BGP().AddPeer().WithPeerAddress(dutPort1.IPv4).WithLocalASN(ateAS).WithTypeExternal().
+		WithHoldTime(holdTime).WithKeepaliveTime(keepalive)
+	top.Push(t).StartProtocols(t)
+}
+
+// transitions returns the number of times the session with the ATE was
+// established.
+func transitions(t *testing.T, dut *ondatra.DUTDevice) uint64 {
+	return dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().
+		Neighbor(atePort1.IPv4).EstablishedTransitions().Get(t)
+}
+
+// TestDegradedLink verifies that the BGP session with the ATE survives
+// latency, jitter and loss that still deliver a keepalive within the hold
+// time, and that it goes down once the hold time expires without any.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/timers/config/hold-time
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/timers/config/keepalive-interval
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/session-state
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/established-transitions
+func TestDegradedLink(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	dg := faults.RequireDegrader(t)
+	l := faults.LinkOf(t, dut, ate, "port1")
+
+	configureDUT(t, dut)
+	configureATE(t, ate)
+	if err := bgpauth.AwaitEstablished(t, dut, atePort1.IPv4, establishTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		desc string
+		d    faults.Degradation
+	}{
+		{"latency", faults.Degradation{Latency: 500 * time.Millisecond}},
+		{"latency and jitter", faults.Degradation{Latency: 500 * time.Millisecond, Jitter: 200 * time.Millisecond}},
+		{"loss", faults.Degradation{LossPct: 20}},
+		{"latency, jitter and loss", faults.Degradation{Latency: 200 * time.Millisecond, Jitter: 50 * time.Millisecond, LossPct: 10}},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			before := transitions(t, dut)
+			faults.Degrade(t, dg, l, c.d)
+			// The session must survive several hold times.
+			time.Sleep(args.Convergence(3 * holdTime * time.Second))
+			got := bgpauth.ReadReason(t, dut, atePort1.IPv4)
+			if got.State != telemetry.Bgp_Neighbor_SessionState_ESTABLISHED {
+				t.Errorf("BGP session with %s over a link with %v got %v, want ESTABLISHED", atePort1.IPv4, c.d, got)
+			}
+			if after := transitions(t, dut); after != before {
+				t.Errorf("BGP session with %s over a link with %v flapped: established transitions got %d, want %d", atePort1.IPv4, c.d, after, before)
+			}
+			faults.Restore(t, dg, l)
+		})
+	}
+
+	t.Run("total loss", func(t *testing.T) {
+		faults.Degrade(t, dg, l, faults.Degradation{LossPct: 100})
+		start := time.Now()
+		if err := bgpauth.AwaitDown(t, dut, atePort1.IPv4, args.Convergence(2*holdTime*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d < (holdTime-keepalive)*time.Second {
+			t.Errorf("BGP session with %s went down after %v, before its hold time of %ds", atePort1.IPv4, d, holdTime)
+		}
+		got := bgpauth.ReadReason(t, dut, atePort1.IPv4)
+		t.Logf("BGP session with %s after the hold time: %v", atePort1.IPv4, got)
+
+		faults.Restore(t, dg, l)
+		if err := bgpauth.AwaitEstablished(t, dut, atePort1.IPv4, establishTimeout); err != nil {
+			t.Error(err)
+		}
+	})
+}
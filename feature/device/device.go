@@ -18,8 +18,14 @@
 package device
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/openconfig/featureprofiles/yang/fpoc"
 	"github.com/openconfig/ygot/ygot"
+	"github.com/openconfig/ygot/ytypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
 )
@@ -71,6 +77,70 @@ func (d *Device) FullReplaceRequest() (*gnmipb.SetRequest, error) {
 	return r, nil
 }
 
+// ScopedReplaceRequest returns gNMI SetRequest replacing only the subtrees
+// of the config at the paths of containers or lists, such as /system or
+// /interfaces/interface[name=Ethernet1.1].  A path to a list without keys
+// selects all its entries.  Subtrees with no config are left out.
+func (d *Device) ScopedReplaceRequest(paths ...string) (*gnmipb.SetRequest, error) {
+	updates, err := d.scopedUpdates(paths)
+	if err != nil {
+		return nil, err
+	}
+	return &gnmipb.SetRequest{Replace: updates}, nil
+}
+
+// ScopedUpdateRequest returns gNMI SetRequest updating only the subtrees of
+// the config at the paths, as ScopedReplaceRequest selects them.
+func (d *Device) ScopedUpdateRequest(paths ...string) (*gnmipb.SetRequest, error) {
+	updates, err := d.scopedUpdates(paths)
+	if err != nil {
+		return nil, err
+	}
+	return &gnmipb.SetRequest{Update: updates}, nil
+}
+
+// scopedUpdates returns the updates of the subtrees of the config at the
+// paths.
+func (d *Device) scopedUpdates(paths []string) ([]*gnmipb.Update, error) {
+	if err := d.oc.Validate(); err != nil {
+		return nil, err
+	}
+	var updates []*gnmipb.Update
+	for _, p := range paths {
+		path, err := ygot.StringToStructuredPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %q: %w", p, err)
+		}
+		nodes, err := ytypes.GetNode(fpoc.SchemaTree["Device"], &d.oc, path, &ytypes.GetPartialKeyMatch{})
+		switch {
+		case status.Code(err) == codes.NotFound:
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("cannot select %q: %w", p, err)
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].Path.String() < nodes[j].Path.String()
+		})
+		for _, n := range nodes {
+			if n.Schema.IsLeaf() || n.Schema.IsLeafList() {
+				return nil, fmt.Errorf("%q is a leaf, not a subtree", p)
+			}
+			val, err := ygot.EncodeTypedValue(n.Data, gnmipb.Encoding_JSON_IETF)
+			if err != nil {
+				return nil, fmt.Errorf("cannot encode %q: %w", p, err)
+			}
+			if string(val.GetJsonIetfVal()) == "{}" {
+				continue
+			}
+			updates = append(updates, &gnmipb.Update{
+				Path: &gnmipb.Path{Origin: "openconfig", Elem: n.Path.GetElem()},
+				Val:  val,
+			})
+		}
+	}
+	return updates, nil
+}
+
 // Feature is a feature on the device.
 type Feature interface {
 	// AugmentDevice augments the device OC with this feature.
@@ -23,9 +23,11 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/openconfig/featureprofiles/feature/bgp"
+	intf "github.com/openconfig/featureprofiles/feature/interface/singleton"
 	"github.com/openconfig/featureprofiles/feature/lldp"
 	"github.com/openconfig/featureprofiles/feature/networkinstance"
 	"github.com/openconfig/featureprofiles/yang/fpoc"
+	"github.com/openconfig/ygot/ygot"
 	"google.golang.org/protobuf/testing/protocmp"
 
 	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
@@ -268,3 +270,120 @@ func TestWithFeature(t *testing.T) {
 		})
 	}
 }
+
+// newScopedDevice returns a device with two interfaces, LLDP on one of
+// them and BGP in the default network instance.
+func newScopedDevice(t *testing.T) *Device {
+	t.Helper()
+	d := New()
+	for _, name := range []string{"Ethernet1.1", "Ethernet1.2"} {
+		if err := d.WithFeature(intf.New(name, "port "+name, fpoc.IETFInterfaces_InterfaceType_ethernetCsmacd)); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if err := d.WithFeature(lldp.New().EnableInterface("Ethernet1.1")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	ni := networkinstance.New("DEFAULT", fpoc.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_DEFAULT_INSTANCE)
+	if err := ni.WithFeature(bgp.New().WithAS(12345)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := d.WithFeature(ni); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return d
+}
+
+// TestScopedReplaceRequest tests the ScopedReplaceRequest method.
+func TestScopedReplaceRequest(t *testing.T) {
+	d := newScopedDevice(t)
+	tests := []struct {
+		name      string
+		paths     []string
+		wantPaths []string
+		wantVals  []ygot.GoStruct
+	}{{
+		name:      "container",
+		paths:     []string{"/lldp"},
+		wantPaths: []string{"/lldp"},
+		wantVals:  []ygot.GoStruct{d.oc.GetLldp()},
+	}, {
+		name:      "list entry",
+		paths:     []string{"/interfaces/interface[name=Ethernet1.2]"},
+		wantPaths: []string{"/interfaces/interface[name=Ethernet1.2]"},
+		wantVals:  []ygot.GoStruct{d.oc.GetInterface("Ethernet1.2")},
+	}, {
+		name:      "all list entries",
+		paths:     []string{"/interfaces/interface"},
+		wantPaths: []string{"/interfaces/interface[name=Ethernet1.1]", "/interfaces/interface[name=Ethernet1.2]"},
+		wantVals:  []ygot.GoStruct{d.oc.GetInterface("Ethernet1.1"), d.oc.GetInterface("Ethernet1.2")},
+	}, {
+		name:  "subtree without config",
+		paths: []string{"/system", "/interfaces/interface[name=Ethernet1.3]"},
+	}, {
+		name:      "several subtrees",
+		paths:     []string{"/lldp", "/network-instances/network-instance[name=DEFAULT]"},
+		wantPaths: []string{"/lldp", "/network-instances/network-instance[name=DEFAULT]"},
+		wantVals:  []ygot.GoStruct{d.oc.GetLldp(), d.oc.GetNetworkInstance("DEFAULT")},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.ScopedReplaceRequest(tt.paths...)
+			if err != nil {
+				t.Fatalf("ScopedReplaceRequest(%v): got unexpected error: %v", tt.paths, err)
+			}
+			want := &gnmipb.SetRequest{}
+			for i, p := range tt.wantPaths {
+				path, err := ygot.StringToStructuredPath(p)
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				path.Origin = "openconfig"
+				val, err := ygot.EncodeTypedValue(tt.wantVals[i], gnmipb.Encoding_JSON_IETF)
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				want.Replace = append(want.Replace, &gnmipb.Update{Path: path, Val: val})
+			}
+			if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("ScopedReplaceRequest(%v): did not get expected request, diff(-want,+got):\n%s", tt.paths, diff)
+			}
+		})
+	}
+}
+
+// TestScopedUpdateRequest tests the ScopedUpdateRequest method.
+func TestScopedUpdateRequest(t *testing.T) {
+	got, err := newScopedDevice(t).ScopedUpdateRequest("/lldp")
+	if err != nil {
+		t.Fatalf("ScopedUpdateRequest: got unexpected error: %v", err)
+	}
+	if len(got.GetUpdate()) != 1 || len(got.GetReplace()) != 0 {
+		t.Errorf("ScopedUpdateRequest: got %v, want one update", got)
+	}
+}
+
+// TestScopedRequestErrors tests the errors of ScopedReplaceRequest.
+func TestScopedRequestErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		wantErrSubStr string
+	}{
+		{"invalid path", "/interfaces/interface[name=Ethernet1.1", "invalid path"},
+		{"not in the schema", "/foo", "cannot select"},
+		{"leaf", "/lldp/enabled", "is a leaf"},
+	}
+	d := newScopedDevice(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := d.ScopedReplaceRequest(tt.path)
+			if err == nil {
+				t.Fatalf("ScopedReplaceRequest(%s): error expected but got none", tt.path)
+			}
+			if !strings.Contains(err.Error(), tt.wantErrSubStr) {
+				t.Errorf("ScopedReplaceRequest(%s): error %v does not contain %q", tt.path, err, tt.wantErrSubStr)
+			}
+		})
+	}
+}
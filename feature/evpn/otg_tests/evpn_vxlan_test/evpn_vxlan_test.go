@@ -0,0 +1,249 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package evpn_vxlan_test implements EVPN-1.1: EVPN-VXLAN Layer 2
+// Baseline.
+package evpn_vxlan_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpauth"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/evpn"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/otgutils"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/netutil"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, a host in the MAC-VRF
+// of the DUT, and dut:port2 -> ate:port2, the underlay to a remote VTEP
+// emulated by the ATE.  The DUT sources its VXLAN tunnels from its
+// loopback, and peers with the ATE in eBGP with the L2VPN EVPN address
+// family.  The remote VTEP advertises the MAC/IP routes of its hosts.
+//
+//   - ate:port1 -> dut:port1 host subnet 198.51.100.0/24, layer 2
+//   - dut:port2 -> ate:port2 subnet 192.0.2.4/30
+const (
+	plen        = 30
+	dutAS       = 64496
+	ateAS       = 64497
+	dutLoopback = "203.0.113.1"
+	macVRF      = "MAC-VRF-100"
+	vni         = 10100
+	rt          = "64496:100"
+	pps         = 100
+	trafficTime = 15 * time.Second
+	bgpTimeout  = 2 * time.Minute
+	ribTimeout  = time.Minute
+	// minDeliveryPct is the share of the frames sent which must be
+	// received, as the first ones may be flooded before the DUT learns
+	// the MAC address of the host.
+	minDeliveryPct = 99
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1"}
+	atePort1 = attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "198.51.100.1", IPv4Len: 24}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv4Len: plen}
+
+	instance = &evpn.Instance{Name: macVRF, VNI: vni, RD: dutLoopback + ":100", RT: rt}
+	vtep     = &evpn.VTEP{RD: atePort2.IPv4 + ":100", MAC: "02:00:0a:00:00:01", IPv4: "198.51.100.101", Hosts: 4}
+)
+
+// configureDUT configures the ports and the loopback of the DUT, the
+// MAC-VRF with dut:port1, and the eBGP session with ate:port2.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+	lo := netutil.LoopbackInterface(t, dut, 0)
+	loIntf := &telemetry.Interface{
+		Name: ygot.String(lo),
+		Type: telemetry.IETFInterfaces_InterfaceType_softwareLoopback,
+	}
+	loIntf.GetOrCreateSubinterface(0).GetOrCreateIpv4().GetOrCreateAddress(dutLoopback).PrefixLength = ygot.Uint8(32)
+	d.Interface(lo).Replace(t, loIntf)
+
+	ni := instance.MACVRF(lo, dut.Port(t, "port1").Name())
+	fptest.LogYgot(t, "DUT MAC-VRF", d.NetworkInstance(macVRF), ni)
+	d.NetworkInstance(macVRF).Replace(t, ni)
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	bgp.GetOrCreateGlobal().As = ygot.Uint32(dutAS)
+	bgp.GetOrCreateGlobal().RouterId = ygot.String(dutLoopback)
+	nbr := bgp.GetOrCreateNeighbor(atePort2.IPv4)
+	nbr.PeerAs = ygot.Uint32(ateAS)
+	nbr.Enabled = ygot.Bool(true)
+	evpn.EnableBGP(bgp, atePort2.IPv4)
+	bgpPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	fptest.LogYgot(t, "DUT BGP", bgpPath, bgp)
+	bgpPath.Replace(t, bgp)
+}
+
+// configureOTG returns the OTG configuration of the host on ate:port1 and
+// of the remote VTEP on ate:port2, capturing on both ports.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	p1 := ate.Port(t, "port1").ID()
+	p2 := ate.Port(t, "port2").ID()
+	config.Ports().Add().SetName(p1)
+	config.Ports().Add().SetName(p2)
+
+	// The host has no gateway of its own in the MAC-VRF, so it takes a
+	// remote host as its gateway.
+	config.Devices().Add().SetName(atePort1.Name).Ethernets().Add().
+		SetName(atePort1.Name + ".eth").
+		SetPortName(p1).
+		SetMac(atePort1.MAC).
+		Ipv4Addresses().Add().
+		SetName(atePort1.Name + ".IPv4").
+		SetAddress(atePort1.IPv4).
+		SetGateway(vtep.IPv4).
+		SetPrefix(int32(atePort1.IPv4Len))
+
+	dev := config.Devices().Add().SetName(atePort2.Name)
+	ip := dev.Ethernets().Add().
+		SetName(atePort2.Name + ".eth").
+		SetPortName(p2).
+		SetMac(atePort2.MAC).
+		Ipv4Addresses().Add().
+		SetName(atePort2.Name + ".IPv4").
+		SetAddress(atePort2.IPv4).
+		SetGateway(dutPort2.IPv4).
+		SetPrefix(int32(atePort2.IPv4Len))
+	peer := dev.Bgp().SetRouterId(ip.Address()).
+		Ipv4Interfaces().Add().SetIpv4Name(ip.Name()).
+		Peers().Add().SetName(atePort2.Name + ".BGP4.peer").
+		SetPeerAddress(ip.Gateway()).
+		SetAsNumber(ateAS).
+		SetAsType(gosnappi.BgpV4PeerAsType.EBGP)
+	if err := instance.Advertise(peer, "vtep.hosts", vtep); err != nil {
+		t.Fatalf("Cannot advertise the remote VTEP: %v", err)
+	}
+	capture.Enable(config, p1, p2)
+	return config
+}
+
+// sendAndCapture sends the flow of the config, and returns the number of
+// packets sent and the packets captured on the port.
+func sendAndCapture(t *testing.T, ate *ondatra.ATEDevice, api gosnappi.GosnappiApi, config gosnappi.Config, flow, port string) (uint64, []*capture.Packet) {
+	otg := ate.OTG()
+	id := ate.Port(t, port).ID()
+	otg.PushConfig(t, config)
+	otg.StartProtocols(t)
+	capture.Start(t, api, id)
+	otg.StartTraffic(t)
+	time.Sleep(trafficTime)
+	otg.StopTraffic(t)
+	capture.Stop(t, api, id)
+	otgutils.LogFlowMetrics(t, otg, config)
+	sent := otg.Telemetry().Flow(flow).Get(t).GetCounters().GetOutPkts()
+	if sent == 0 {
+		t.Fatalf("No packets sent by flow %s", flow)
+	}
+	return sent, capture.Fetch(t, api, id)
+}
+
+// TestEVPNVXLAN verifies that the DUT learns the MAC addresses of the
+// hosts of a remote VTEP from its EVPN routes, encapsulates the frames of
+// its local host to them in VXLAN with the VNI of the MAC-VRF, and
+// decapsulates the VXLAN packets of the remote VTEP to its local host.
+//
+// config_path:/network-instances/network-instance/config/type
+// config_path:/network-instances/network-instance/evpn/evpn-instances/evpn-instance/config/encapsulation-type
+// config_path:/network-instances/network-instance/evpn/evpn-instances/evpn-instance/config/route-distinguisher
+// config_path:/network-instances/network-instance/evpn/evpn-instances/evpn-instance/import-export-policy/config/export-route-target
+// config_path:/network-instances/network-instance/evpn/evpn-instances/evpn-instance/import-export-policy/config/import-route-target
+// config_path:/network-instances/network-instance/evpn/evpn-instances/evpn-instance/vxlan/config/vni
+// config_path:/network-instances/network-instance/connection-points/connection-point/endpoints/endpoint/vxlan/config/source-interface
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/afi-safis/afi-safi/config/enabled
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/session-state
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/rib/afi-safis/afi-safi/l2vpn-evpn/loc-rib/route-distinguishers/route-distinguisher/type-two-routes/type-two-route/state/mac-address
+func TestEVPNVXLAN(t *testing.T) {
+	if err := instance.Validate(); err != nil {
+		t.Fatalf("Invalid EVPN instance: %v", err)
+	}
+	macs, err := vtep.HostMACs()
+	if err != nil {
+		t.Fatalf("Invalid remote VTEP: %v", err)
+	}
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	config := configureOTG(t, ate)
+	api := fptest.DialOTG(t, ate)
+	otg := ate.OTG()
+	otg.PushConfig(t, config)
+	otg.StartProtocols(t)
+
+	if err := bgpauth.AwaitEstablished(t, dut, atePort2.IPv4, bgpTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("RIB", func(t *testing.T) {
+		if err := evpn.AwaitMACs(t, dut, vtep.RD, macs, ribTimeout); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Encap", func(t *testing.T) {
+		f := evpn.Frame{SrcMAC: atePort1.MAC, DstMAC: macs[0], SrcIP: atePort1.IPv4, DstIP: vtep.IPv4}
+		config.Flows().Clear()
+		evpn.AddFlow(config, "encap", ate.Port(t, "port1").ID(), ate.Port(t, "port2").ID(), f, pps, int32(pps*trafficTime/time.Second))
+		sent, pkts := sendAndCapture(t, ate, api, config, "encap", "port2")
+		tn := evpn.Tunnel{Src: dutLoopback, Dst: atePort2.IPv4, VNI: vni}
+		n, errs := tn.Verify(pkts, f)
+		for _, err := range errs {
+			t.Error(err)
+		}
+		t.Logf("ate:port2 received %d of %d frames encapsulated in VXLAN", n, sent)
+		if uint64(n)*100 < sent*minDeliveryPct {
+			t.Errorf("ate:port2 received %d of %d frames encapsulated in VXLAN, want at least %d%%", n, sent, minDeliveryPct)
+		}
+	})
+
+	t.Run("Decap", func(t *testing.T) {
+		f := evpn.Frame{SrcMAC: macs[0], DstMAC: atePort1.MAC, SrcIP: vtep.IPv4, DstIP: atePort1.IPv4}
+		tn := evpn.Tunnel{Src: atePort2.IPv4, Dst: dutLoopback, VNI: vni}
+		config.Flows().Clear()
+		evpn.AddVXLANFlow(config, "decap", atePort2.Name+".IPv4", []string{atePort1.Name + ".IPv4"}, atePort2.MAC, tn, f, pps, int32(pps*trafficTime/time.Second))
+		sent, pkts := sendAndCapture(t, ate, api, config, "decap", "port1")
+		n := f.Count(pkts)
+		t.Logf("ate:port1 received %d of %d frames decapsulated", n, sent)
+		if uint64(n)*100 < sent*minDeliveryPct {
+			t.Errorf("ate:port1 received %d of %d frames decapsulated, want at least %d%%", n, sent, minDeliveryPct)
+		}
+	})
+}
@@ -17,6 +17,7 @@ package backup_nhg_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 
 	"github.com/openconfig/featureprofiles/internal/attrs"
 	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/faults"
 	"github.com/openconfig/featureprofiles/internal/fptest"
 )
 
@@ -143,42 +145,14 @@ func TestDirectBackupNexthopGroup(t *testing.T) {
 	baselineFlow := tcArgs.createFlow("Baseline Path Flow", &atePort2)
 	backupFlow := tcArgs.createFlow("Backup Path Flow", &atePort3)
 
-	cases := []struct {
-		desc               string
-		applyImpairmentFn  func()
-		removeImpairmentFn func()
-	}{
-		{
-			desc: "Disable ATE port-2",
-			applyImpairmentFn: func() {
-				ateP2 := ate.Port(t, "port2")
-				dutP2 := dut.Port(t, "port2")
-				ate.Actions().NewSetPortState().WithPort(ateP2).WithEnabled(false).Send(t)
-				dut.Telemetry().Interface(dutP2.Name()).OperStatus().Await(t, time.Minute, telemetry.Interface_OperStatus_DOWN)
-			},
-			removeImpairmentFn: func() {
-				ateP2 := ate.Port(t, "port2")
-				dutP2 := dut.Port(t, "port2")
-				ate.Actions().NewSetPortState().WithPort(ateP2).WithEnabled(true).Send(t)
-				dut.Telemetry().Interface(dutP2.Name()).OperStatus().Await(t, time.Minute, telemetry.Interface_OperStatus_UP)
-			},
-		},
-		{
-			desc: "Disable DUT port-2",
-			applyImpairmentFn: func() {
-				dutP2 := dut.Port(t, "port2")
-				dut.Config().Interface(dutP2.Name()).Enabled().Replace(t, false)
-				dut.Telemetry().Interface(dutP2.Name()).OperStatus().Await(t, time.Minute, telemetry.Interface_OperStatus_DOWN)
-			},
-			removeImpairmentFn: func() {
-				dutP2 := dut.Port(t, "port2")
-				dut.Config().Interface(dutP2.Name()).Enabled().Replace(t, true)
-				dut.Telemetry().Interface(dutP2.Name()).OperStatus().Await(t, time.Minute, telemetry.Interface_OperStatus_UP)
-			},
-		},
-	}
-	for _, tc := range cases {
-		t.Run(tc.desc, func(t *testing.T) {
+	l := faults.LinkOf(t, dut, ate, "port2")
+	for _, kind := range faults.Selected(faults.ATELaser, faults.AdminDown) {
+		t.Run(fmt.Sprintf("Fault %s of port-2", kind), func(t *testing.T) {
+			inj, err := faults.New(kind, &faults.Devices{DUT: dut, ATE: ate})
+			if err != nil {
+				t.Fatalf("Cannot inject %s faults: %v", kind, err)
+			}
+
 			t.Run("Validate Baseline AFT Telemetry", func(t *testing.T) {
 				tcArgs.validateAftTelemetry(t)
 			})
@@ -187,8 +161,8 @@ func TestDirectBackupNexthopGroup(t *testing.T) {
 				tcArgs.validateTrafficFlows(t, baselineFlow, backupFlow)
 			})
 
-			tc.applyImpairmentFn()
-			defer tc.removeImpairmentFn()
+			faults.Down(t, inj, dut, l, time.Minute)
+			defer faults.Up(t, inj, dut, l, time.Minute)
 
 			t.Run("Validate Backup Path Traffic Delivery", func(t *testing.T) {
 				tcArgs.validateTrafficFlows(t, backupFlow, baselineFlow)
@@ -0,0 +1,179 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package copp_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/copp"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	policers = flag.String("copp_policers", "",
+		"comma separated policers protecting the control plane for each traffic class, in the form class=interface:sequence, e.g. bgp=CPU:10,icmp=CPU:20.  Classes without a policer are not checked for drops.")
+	floodFPS = flag.Uint64("copp_flood_fps", 100000,
+		"frames per second of the punted traffic sent for each class")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The punted traffic is sent from ate:port1, which also
+// has an eBGP session with the DUT.
+//
+//   - Source: ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - Destination: dut:port2 -> ate:port2 subnet 192.0.2.4/30
+const (
+	plen4       = 30
+	dutAS       = 64500
+	ateAS       = 64501
+	peerGrpName = "COPP-PEERS"
+	floodTime   = 30 * time.Second
+)
+
+var (
+	dutSrc = attrs.Attributes{
+		Desc:    "DUT to ATE source",
+		IPv4:    "192.0.2.1",
+		IPv4Len: plen4,
+	}
+
+	ateSrc = attrs.Attributes{
+		Name:    "src",
+		IPv4:    "192.0.2.2",
+		IPv4Len: plen4,
+	}
+
+	dutDst = attrs.Attributes{
+		Desc:    "DUT to ATE destination",
+		IPv4:    "192.0.2.5",
+		IPv4Len: plen4,
+	}
+
+	ateDst = attrs.Attributes{
+		Name:    "dst",
+		IPv4:    "192.0.2.6",
+		IPv4Len: plen4,
+	}
+)
+
+// configureDUT configures the interfaces and the eBGP session with
+// ate:port1.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+
+	p1 := dut.Port(t, "port1")
+	d.Interface(p1.Name()).Replace(t, dutSrc.NewInterface(p1.Name()))
+	p2 := dut.Port(t, "port2")
+	d.Interface(p2.Name()).Replace(t, dutDst.NewInterface(p2.Name()))
+
+	dev := &telemetry.Device{}
+	ni := dev.GetOrCreateNetworkInstance(*deviations.DefaultNetworkInstance)
+	bgp := ni.GetOrCreateProtocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").GetOrCreateBgp()
+	bgp.GetOrCreateGlobal().As = ygot.Uint32(dutAS)
+	pg := bgp.GetOrCreatePeerGroup(peerGrpName)
+	pg.PeerAs = ygot.Uint32(ateAS)
+	pg.PeerGroupName = ygot.String(peerGrpName)
+	nbr := bgp.GetOrCreateNeighbor(ateSrc.IPv4)
+	nbr.PeerGroup = ygot.String(peerGrpName)
+	nbr.PeerAs = ygot.Uint32(ateAS)
+	nbr.Enabled = ygot.Bool(true)
+	nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+
+	bgpPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	fptest.LogYgot(t, "DUT BGP", bgpPath, bgp)
+	bgpPath.Replace(t, bgp)
+}
+
+// configureATE configures the ATE interfaces and the eBGP peer on
+// ate:port1, and returns them.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) (src, dst *ondatra.Interface) {
+	top := ate.Topology().New()
+	src = ateSrc.AddToATE(top, ate.Port(t, "port1"), &dutSrc)
+	dst = ateDst.AddToATE(top, ate.Port(t, "port2"), &dutDst)
+	src.BGP().AddPeer().WithPeerAddress(dutSrc.IPv4).WithLocalASN(ateAS).WithTypeExternal()
+	top.Push(t).StartProtocols(t)
+	return src, dst
+}
+
+// awaitBGP waits for the eBGP session with ate:port1 to establish.
+func awaitBGP(t *testing.T, dut *ondatra.DUTDevice) {
+	nbrPath := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Neighbor(ateSrc.IPv4)
+	_, ok := nbrPath.SessionState().Watch(t, time.Minute, func(val *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+		return val.IsPresent() && val.Val(t) == telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+	}).Await(t)
+	if !ok {
+		fptest.LogYgot(t, "BGP reported state", nbrPath, nbrPath.Get(t))
+		t.Fatal("No BGP neighbor formed")
+	}
+}
+
+func TestCoPP(t *testing.T) {
+	pols, err := copp.ParsePolicers(*policers)
+	if err != nil {
+		t.Fatalf("Invalid -copp_policers: %v", err)
+	}
+
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	src, dst := configureATE(t, ate)
+	awaitBGP(t, dut)
+
+	tgt := &copp.Target{DUT: &dutSrc, TransitIPv4: ateDst.IPv4}
+	for _, class := range copp.AllClasses {
+		t.Run(class.Name, func(t *testing.T) {
+			policer, checkPolicer := pols[class.Name]
+			var before copp.Counters
+			if checkPolicer {
+				before = policer.Read(t, dut)
+			} else {
+				t.Logf("No policer given for class %s, not checking drops", class.Name)
+			}
+
+			flow := class.Flow(ate, src, dst, tgt, *floodFPS)
+			ate.Traffic().Start(t, flow)
+			time.Sleep(floodTime)
+			established := copp.BGPEstablished(t, dut, ateSrc.IPv4)
+			ate.Traffic().Stop(t)
+
+			if !established {
+				t.Errorf("BGP session with %s went down while flooding %s traffic", ateSrc.IPv4, class.Name)
+			}
+			if checkPolicer {
+				diff := policer.Read(t, dut).Sub(before)
+				t.Logf("Policer %v counters increased by %+v", policer, diff)
+				if diff.Dropped() == 0 {
+					t.Errorf("Policer %v dropped no packets while flooding %s traffic at %d fps", policer, class.Name, *floodFPS)
+				}
+			}
+			// Let the DUT recover before the next class.
+			awaitBGP(t, dut)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icmp_errors_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/otgutils"
+	"github.com/openconfig/ondatra"
+
+	otgtelemetry "github.com/openconfig/ondatra/telemetry/otg"
+)
+
+var (
+	floodPPS  = flag.Int64("icmp_flood_pps", 10000, "packets per second sent to trigger ICMP errors")
+	rateLimit = flag.Float64("icmp_rate_limit", 1000,
+		"maximum ICMP errors per second the DUT is expected to generate for each error type")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The traffic triggering the ICMP errors is sent from
+// ate:port1, where the errors are captured.
+//
+//   - Source: ate:port1 -> dut:port1 subnets 192.0.2.0/30 and 2001:db8::/126
+//   - Destination: dut:port2 -> ate:port2 subnets 192.0.2.4/30 and 2001:db8::4/126
+//
+// Unreachable destinations are taken from 198.51.100.0/24 (TEST-NET-2)
+// and 2001:db8:ffff::/48, which are not routed by the DUT.
+const (
+	plen4       = 30
+	plen6       = 126
+	floodTime   = 10 * time.Second
+	unreachable = "198.51.100.1"
+	unreach6    = "2001:db8:ffff::1"
+)
+
+var (
+	dutSrc = attrs.Attributes{
+		Desc:    "DUT to ATE source",
+		IPv4:    "192.0.2.1",
+		IPv6:    "2001:db8::1",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+
+	ateSrc = attrs.Attributes{
+		Name:    "src",
+		MAC:     "02:00:01:01:01:01",
+		IPv4:    "192.0.2.2",
+		IPv6:    "2001:db8::2",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+
+	dutDst = attrs.Attributes{
+		Desc:    "DUT to ATE destination",
+		IPv4:    "192.0.2.5",
+		IPv6:    "2001:db8::5",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+
+	ateDst = attrs.Attributes{
+		Name:    "dst",
+		MAC:     "02:00:02:01:01:01",
+		IPv4:    "192.0.2.6",
+		IPv6:    "2001:db8::6",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+)
+
+// configureDUT configures port1 and port2 on the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	p1 := dut.Port(t, "port1")
+	d.Interface(p1.Name()).Replace(t, dutSrc.NewInterface(p1.Name()))
+	p2 := dut.Port(t, "port2")
+	d.Interface(p2.Name()).Replace(t, dutDst.NewInterface(p2.Name()))
+}
+
+// configureOTG returns the OTG configuration of the ATE interfaces,
+// capturing on ate:port1.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	for _, p := range []struct {
+		port      *ondatra.Port
+		ate, peer *attrs.Attributes
+	}{
+		{ate.Port(t, "port1"), &ateSrc, &dutSrc},
+		{ate.Port(t, "port2"), &ateDst, &dutDst},
+	} {
+		config.Ports().Add().SetName(p.port.ID())
+		eth := config.Devices().Add().SetName(p.ate.Name).Ethernets().Add().
+			SetName(p.ate.Name + ".eth").
+			SetPortName(p.port.ID()).
+			SetMac(p.ate.MAC)
+		eth.Ipv4Addresses().Add().
+			SetName(p.ate.Name + ".IPv4").
+			SetAddress(p.ate.IPv4).
+			SetGateway(p.peer.IPv4).
+			SetPrefix(int32(p.ate.IPv4Len))
+		eth.Ipv6Addresses().Add().
+			SetName(p.ate.Name + ".IPv6").
+			SetAddress(p.ate.IPv6).
+			SetGateway(p.peer.IPv6).
+			SetPrefix(int32(p.ate.IPv6Len))
+	}
+	capture.Enable(config, ate.Port(t, "port1").ID())
+	return config
+}
+
+// trigger describes traffic triggering an ICMP error on the DUT.
+type trigger struct {
+	desc      string
+	ipv6      bool
+	dst       string
+	ttl       int32
+	icmpType  uint8
+	icmpCode  uint8
+	wantSrcIP string
+}
+
+var triggers = []trigger{
+	{desc: "IPv4 TTL exceeded", dst: ateDst.IPv4, ttl: 1, icmpType: 11, wantSrcIP: dutSrc.IPv4},
+	{desc: "IPv4 net unreachable", dst: unreachable, ttl: 64, icmpType: 3, wantSrcIP: dutSrc.IPv4},
+	{desc: "IPv6 hop limit exceeded", ipv6: true, dst: ateDst.IPv6, ttl: 1, icmpType: 3, wantSrcIP: dutSrc.IPv6},
+	{desc: "IPv6 no route", ipv6: true, dst: unreach6, ttl: 64, icmpType: 1, wantSrcIP: dutSrc.IPv6},
+}
+
+// addFlow adds the flow sending the trigger traffic for floodTime.
+func (tr *trigger) addFlow(config gosnappi.Config) {
+	config.Flows().Clear()
+	flow := config.Flows().Add().SetName("icmp-trigger")
+	flow.Metrics().SetEnable(true)
+	if tr.ipv6 {
+		flow.TxRx().Device().
+			SetTxNames([]string{ateSrc.Name + ".IPv6"}).
+			SetRxNames([]string{ateDst.Name + ".IPv6"})
+	} else {
+		flow.TxRx().Device().
+			SetTxNames([]string{ateSrc.Name + ".IPv4"}).
+			SetRxNames([]string{ateDst.Name + ".IPv4"})
+	}
+	flow.Rate().SetPps(*floodPPS)
+	flow.Duration().FixedPackets().SetPackets(int32(*floodPPS * int64(floodTime/time.Second)))
+	flow.Size().SetFixed(128)
+	flow.Packet().Add().Ethernet().Src().SetValue(ateSrc.MAC)
+	if tr.ipv6 {
+		ip := flow.Packet().Add().Ipv6()
+		ip.Src().SetValue(ateSrc.IPv6)
+		ip.Dst().SetValue(tr.dst)
+		ip.HopLimit().SetValue(tr.ttl)
+	} else {
+		ip := flow.Packet().Add().Ipv4()
+		ip.Src().SetValue(ateSrc.IPv4)
+		ip.Dst().SetValue(tr.dst)
+		ip.TimeToLive().SetValue(tr.ttl)
+	}
+}
+
+// icmpErrors returns the ICMP errors of the trigger type in the
+// captured packets.
+func (tr *trigger) icmpErrors(pkts []*capture.Packet) []*capture.Packet {
+	proto := uint8(capture.ProtocolICMP)
+	if tr.ipv6 {
+		proto = capture.ProtocolICMPv6
+	}
+	var errs []*capture.Packet
+	for _, p := range pkts {
+		if p.IP == nil || p.ICMP == nil || p.IP.Protocol != proto {
+			continue
+		}
+		if p.ICMP.Type == tr.icmpType && p.ICMP.Code == tr.icmpCode {
+			errs = append(errs, p)
+		}
+	}
+	return errs
+}
+
+// waitNeighbors waits for the ATE to resolve the DUT addresses.
+func waitNeighbors(t *testing.T, ate *ondatra.ATEDevice) {
+	for _, a := range []*attrs.Attributes{&ateSrc, &ateDst} {
+		intf := ate.OTG().Telemetry().Interface(a.Name + ".eth")
+		intf.Ipv4NeighborAny().LinkLayerAddress().Watch(t, time.Minute, func(val *otgtelemetry.QualifiedString) bool {
+			return val.IsPresent()
+		}).Await(t)
+		intf.Ipv6NeighborAny().LinkLayerAddress().Watch(t, time.Minute, func(val *otgtelemetry.QualifiedString) bool {
+			return val.IsPresent()
+		}).Await(t)
+	}
+}
+
+func TestICMPErrors(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	config := configureOTG(t, ate)
+	otg := ate.OTG()
+	api := fptest.DialOTG(t, ate)
+	capPort := ate.Port(t, "port1").ID()
+
+	for _, tr := range triggers {
+		tr := tr
+		t.Run(tr.desc, func(t *testing.T) {
+			tr.addFlow(config)
+			otg.PushConfig(t, config)
+			otg.StartProtocols(t)
+			waitNeighbors(t, ate)
+
+			capture.Start(t, api, capPort)
+			otg.StartTraffic(t)
+			time.Sleep(floodTime)
+			otg.StopTraffic(t)
+			capture.Stop(t, api, capPort)
+			otgutils.LogFlowMetrics(t, otg, config)
+
+			errs := tr.icmpErrors(capture.Fetch(t, api, capPort))
+			if len(errs) == 0 {
+				t.Fatalf("No ICMP type %d code %d error received", tr.icmpType, tr.icmpCode)
+			}
+			rate := float64(len(errs)) / floodTime.Seconds()
+			t.Logf("Received %d ICMP errors, %.1f per second", len(errs), rate)
+			if rate > *rateLimit {
+				t.Errorf("ICMP error rate got %.1f per second, want <= %.1f", rate, *rateLimit)
+			}
+			for _, p := range errs {
+				if got := p.IP.Src.String(); got != tr.wantSrcIP {
+					t.Errorf("ICMP error source address got %s, want %s of the ingress interface", got, tr.wantSrcIP)
+					break
+				}
+			}
+		})
+	}
+}
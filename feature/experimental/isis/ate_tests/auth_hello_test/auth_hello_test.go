@@ -0,0 +1,187 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth_hello_test implements RT-2.4.
+package auth_hello_test
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/feature/experimental/isis/ate_tests/internal/session"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/isisauth"
+	"github.com/openconfig/featureprofiles/internal/isislsdb"
+	"github.com/openconfig/ondatra/ixnet"
+	"github.com/openconfig/ondatra/telemetry"
+)
+
+var holdTolerance = flag.Duration("isis_hold_tolerance", 2*time.Second, "Tolerance on the time the DUT takes to bring down an adjacency after its hold time expired.")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+const (
+	level    = 2
+	keychain = "ISIS-AUTH"
+	// rejectTime is how long an adjacency with a mismatched key is
+	// checked not to come up.
+	rejectTime = time.Minute
+)
+
+// configureHello configures the hello authentication of the DUT and the
+// ATE, disabling it when the key is empty, or authenticating the DUT
+// with a keychain.
+func configureHello(t *testing.T, ts *session.TestSession, dutKey, ateKey string, useKeychain bool) {
+	if useKeychain {
+		kc := isisauth.Keychain(keychain, 1, dutKey)
+		ts.DUT.Config().Keychain(keychain).Replace(t, kc)
+	}
+	ts.ConfigISIS(t, func(isis *telemetry.NetworkInstance_Protocol_Isis) {
+		for _, intf := range isis.Interface {
+			if useKeychain {
+				isisauth.HelloKeychain(intf, level, keychain)
+			} else {
+				isisauth.HelloMD5(intf, level, dutKey)
+			}
+		}
+	}, func(isis *ixnet.ISIS) {
+		if ateKey == "" {
+			isis.WithAuthDisabled()
+		} else {
+			isis.WithAuthMD5(ateKey)
+		}
+	})
+}
+
+// TestHelloAuthentication verifies that the adjacency only comes up when
+// the DUT and the ATE authenticate their hellos with the same key.
+func TestHelloAuthentication(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		dutKey      string
+		ateKey      string
+		useKeychain bool
+		up          bool
+	}{
+		{name: "matching keys", dutKey: "featureprofiles", ateKey: "featureprofiles", up: true},
+		{name: "matching keychain", dutKey: "featureprofiles", ateKey: "featureprofiles", useKeychain: true, up: true},
+		{name: "mismatched keys", dutKey: "featureprofiles", ateKey: "mismatch"},
+		{name: "mismatched keychain", dutKey: "featureprofiles", ateKey: "mismatch", useKeychain: true},
+		{name: "key on the DUT only", dutKey: "featureprofiles"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := session.NewWithISIS(t)
+			configureHello(t, ts, tc.dutKey, tc.ateKey, tc.useKeychain)
+			ts.PushAndStart(t)
+			defer ts.ATETop.StopProtocols(t)
+
+			intf := ts.DUT.Port(t, "port1").Name()
+			if tc.up {
+				ts.AwaitAdjacency(t)
+				return
+			}
+			before := isisauth.AuthFails(t, ts.DUT, session.ISISName, intf)
+			if err := isisauth.CheckNoAdjacency(t, ts.DUT, session.ISISName, intf, rejectTime); err != nil {
+				t.Fatal(err)
+			}
+			after := isisauth.AuthFails(t, ts.DUT, session.ISISName, intf)
+			t.Logf("Authentication failures on %s: %d before, %d after", intf, before, after)
+			if tc.ateKey != "" && after == before {
+				t.Errorf("Authentication failures on %s got %d, want more than %d", intf, after, before)
+			}
+		})
+	}
+}
+
+// TestLSPAuthentication verifies that the DUT rejects the LSPs of the
+// ATE when it authenticates its LSPs, since the ATE does not.
+func TestLSPAuthentication(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		dutKey string
+		accept bool
+	}{
+		{name: "disabled", accept: true},
+		{name: "enabled", dutKey: "featureprofiles"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := session.NewWithISIS(t)
+			ts.ConfigISIS(t, func(isis *telemetry.NetworkInstance_Protocol_Isis) {
+				isisauth.LSPMD5(isis, level, tc.dutKey)
+			}, func(isis *ixnet.ISIS) {})
+			ts.PushAndStart(t)
+			defer ts.ATETop.StopProtocols(t)
+			ts.AwaitAdjacency(t)
+
+			intf := ts.DUT.Port(t, "port1").Name()
+			sysID, err := isisauth.NeighborSystemID(t, ts.DUT, session.ISISName, intf, level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := isisauth.LSPAuthFails(t, ts.DUT, session.ISISName, level)
+			// Leave time for the ATE to flood its LSP.
+			time.Sleep(30 * time.Second)
+
+			db := isislsdb.Read(t, ts.DUT, session.ISISName, level)
+			got := db.System(sysID) != nil
+			if got != tc.accept {
+				t.Errorf("LSP of the ATE %s in the LSDB got %v, want %v", sysID, got, tc.accept)
+			}
+			if after := isisauth.LSPAuthFails(t, ts.DUT, session.ISISName, level); !tc.accept && after == before {
+				t.Errorf("Level %d authentication failures got %d, want more than %d", level, after, before)
+			}
+		})
+	}
+}
+
+// TestHoldTime verifies that the DUT brings down the adjacency when the
+// hold time advertised by the ATE expires after it stops sending hellos.
+func TestHoldTime(t *testing.T) {
+	const hello = 1
+	for _, hold := range []uint32{3, 10} {
+		t.Run(fmt.Sprintf("hold %ds", hold), func(t *testing.T) {
+			ts := session.NewWithISIS(t)
+			ts.ConfigISIS(t, func(isis *telemetry.NetworkInstance_Protocol_Isis) {
+				for _, intf := range isis.Interface {
+					isisauth.HelloTimers(intf, level, hello, 3)
+				}
+			}, func(isis *ixnet.ISIS) {
+				isis.WithHelloInterval(hello).WithDeadInterval(hold)
+			})
+			ts.PushAndStart(t)
+			ts.AwaitAdjacency(t)
+
+			intf := ts.DUT.Port(t, "port1").Name()
+			holdTime := time.Duration(hold) * time.Second
+			w := isisauth.WatchDown(t, ts.DUT, session.ISISName, intf, holdTime+time.Minute)
+			start := time.Now()
+			ts.ATETop.StopProtocols(t)
+			stopping := time.Since(start)
+			v, ok := w.Await(t)
+			if !ok {
+				t.Fatalf("IS-IS adjacency on %s still up %v after the ATE stopped", intf, holdTime+time.Minute)
+			}
+			elapsed := v.RecvTimestamp.Sub(start)
+			t.Logf("IS-IS adjacency on %s went down %v after stopping the ATE, which took %v", intf, elapsed, stopping)
+			// The ATE stops sending hellos at some point while stopping.
+			if err := isisauth.CheckHoldExpiry(elapsed, holdTime, hello*time.Second, *holdTolerance+stopping); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drain_test implements RT-2.5.
+package drain_test
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/feature/experimental/isis/ate_tests/internal/session"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/isisauth"
+	"github.com/openconfig/featureprofiles/internal/isisdrain"
+	"github.com/openconfig/featureprofiles/internal/isislsdb"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/telemetry"
+	"github.com/openconfig/ygot/ygot"
+)
+
+var (
+	lossBound   = flag.Duration("isis_drain_loss", 100*time.Millisecond, "Longest interruption of the traffic allowed while draining or undraining a link.")
+	convergence = flag.Duration("isis_drain_convergence", 30*time.Second, "Time given to the DUT to shift the traffic after draining or undraining a link.")
+	tolerance   = flag.Float64("isis_drain_tolerance", 1, "Tolerance in percent of the packets received on the drained path once converged.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port{2-3} ->
+// ate:port{2-3}.  The ATE forms an IS-IS adjacency with the DUT on port2
+// and port3, advertises the same prefix over both, and sends traffic to
+// it from port1.  The DUT prefers port2, whose metric is lower, until it
+// is drained.
+//
+//   - Source: ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - Primary path: dut:port2 -> ate:port2 subnet 192.0.2.4/30
+//   - Backup path: dut:port3 -> ate:port3 subnet 192.0.2.8/30
+//   - Destination network: 198.51.100.0/24
+const (
+	plen4       = 30
+	level       = 2
+	prefix      = "198.51.100.0/24"
+	dstMin      = "198.51.100.1"
+	dstCount    = 254
+	fps         = 10000
+	trafficTime = 15 * time.Second
+)
+
+// metrics are the metrics of the primary and the backup path on the DUT.
+var metrics = []uint32{10, 20}
+
+// dutPorts and atePorts are the attributes of the DUT and ATE ends of
+// port1, port2, and so on.
+var (
+	dutPorts = []attrs.Attributes{
+		{
+			Desc:    "DUT to ATE port1",
+			IPv4:    "192.0.2.1",
+			IPv4Len: plen4,
+		},
+		{
+			Desc:    "DUT to ATE port2",
+			IPv4:    "192.0.2.5",
+			IPv4Len: plen4,
+		},
+		{
+			Desc:    "DUT to ATE port3",
+			IPv4:    "192.0.2.9",
+			IPv4Len: plen4,
+		},
+	}
+
+	atePorts = []attrs.Attributes{
+		{
+			Name:    "port1",
+			IPv4:    "192.0.2.2",
+			IPv4Len: plen4,
+		},
+		{
+			Name:    "port2",
+			IPv4:    "192.0.2.6",
+			IPv4Len: plen4,
+		},
+		{
+			Name:    "port3",
+			IPv4:    "192.0.2.10",
+			IPv4Len: plen4,
+		},
+	}
+)
+
+// isisConfig returns the IS-IS configuration of the DUT on port2 and
+// port3.
+func isisConfig(t *testing.T, dut *ondatra.DUTDevice) *telemetry.NetworkInstance_Protocol_Isis {
+	isis := &telemetry.NetworkInstance_Protocol_Isis{}
+	glob := isis.GetOrCreateGlobal()
+	glob.Instance = ygot.String(session.ISISName)
+	glob.Net = []string{fmt.Sprintf("%v.%v.00", session.DUTAreaAddress, session.DUTSysID)}
+	glob.GetOrCreateAf(telemetry.IsisTypes_AFI_TYPE_IPV4, telemetry.IsisTypes_SAFI_TYPE_UNICAST).Enabled = ygot.Bool(true)
+	for i, metric := range metrics {
+		intf := isis.GetOrCreateInterface(dut.Port(t, fmt.Sprintf("port%d", i+2)).Name())
+		intf.CircuitType = telemetry.IsisTypes_CircuitType_POINT_TO_POINT
+		intf.Enabled = ygot.Bool(true)
+		intf.GetOrCreateLevel(level).Enabled = ygot.Bool(true)
+		isisdrain.SetMetric(intf, level, metric)
+	}
+	return isis
+}
+
+// pushISIS replaces the IS-IS configuration of the DUT.
+func pushISIS(t *testing.T, dut *ondatra.DUTDevice, isis *telemetry.NetworkInstance_Protocol_Isis) {
+	prot := &telemetry.NetworkInstance_Protocol{
+		Identifier: session.PTISIS,
+		Name:       ygot.String(session.ISISName),
+		Enabled:    ygot.Bool(true),
+		Isis:       isis,
+	}
+	dut.Config().NetworkInstance(*deviations.DefaultNetworkInstance).Protocol(session.PTISIS, session.ISISName).Replace(t, prot)
+}
+
+// configureDUT configures the ports and IS-IS on the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) *telemetry.NetworkInstance_Protocol_Isis {
+	d := dut.Config()
+	for i := 1; i <= len(metrics)+1; i++ {
+		dutAttrs := dutPorts[i-1]
+		p := dut.Port(t, fmt.Sprintf("port%d", i))
+		d.Interface(p.Name()).Replace(t, dutAttrs.NewInterface(p.Name()))
+	}
+	isis := isisConfig(t, dut)
+	pushISIS(t, dut, isis)
+	return isis
+}
+
+// configureATE configures the ATE interfaces and IS-IS on port2 and
+// port3 advertising the prefix, and returns the source and the paths.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) (*ondatra.ATETopology, *ondatra.Interface, []ondatra.Endpoint) {
+	top := ate.Topology().New()
+	var src *ondatra.Interface
+	var dsts []ondatra.Endpoint
+	for i := 1; i <= len(metrics)+1; i++ {
+		dutAttrs, ateAttrs := dutPorts[i-1], atePorts[i-1]
+		intf := ateAttrs.AddToATE(top, ate.Port(t, ateAttrs.Name), &dutAttrs)
+		if i == 1 {
+			src = intf
+			continue
+		}
+		intf.ISIS().
+			WithAreaID(session.ATEAreaAddress).
+			WithTERouterID("*").
+			WithNetworkTypePointToPoint().
+			WithWideMetricEnabled(true).
+			WithLevelL2()
+		net := intf.AddNetwork("net-" + ateAttrs.Name)
+		net.IPv4().WithAddress(prefix).WithCount(1)
+		net.ISIS().WithIPReachabilityExternal().WithIPReachabilityMetric(10)
+		dsts = append(dsts, intf)
+	}
+	top.Push(t).StartProtocols(t)
+	return top, src, dsts
+}
+
+// awaitAdjacencies waits for the IS-IS adjacencies of the DUT on port2
+// and port3 to come up.
+func awaitAdjacencies(t *testing.T, dut *ondatra.DUTDevice) {
+	for i := range metrics {
+		intf := dut.Port(t, fmt.Sprintf("port%d", i+2)).Name()
+		if _, err := isisauth.AwaitUp(t, dut, session.ISISName, intf, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// received returns the packets received on each path.
+func received(t *testing.T, ate *ondatra.ATEDevice) []uint64 {
+	var pkts []uint64
+	for i := range metrics {
+		p := ate.Port(t, fmt.Sprintf("port%d", i+2))
+		pkts = append(pkts, ate.Telemetry().Interface(p.Name()).Counters().InPkts().Get(t))
+	}
+	return pkts
+}
+
+// delta returns the packets received on each path between two reads.
+func delta(before, after []uint64) []uint64 {
+	d := make([]uint64, len(after))
+	for i := range after {
+		d[i] = after[i] - before[i]
+	}
+	return d
+}
+
+// drainCase is a change of the IS-IS configuration of the DUT while
+// traffic flows, which should move it from one path to another, or keep
+// it on the same path.
+type drainCase struct {
+	desc     string
+	apply    func(isis *telemetry.NetworkInstance_Protocol_Isis)
+	from, to int
+}
+
+// run sends traffic from the source to the prefix, applies the change
+// and pushes the IS-IS configuration, and checks that the traffic shifts
+// within the loss bound.
+func (c *drainCase) run(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, isis *telemetry.NetworkInstance_Protocol_Isis, src *ondatra.Interface, dsts []ondatra.Endpoint) {
+	srcAttrs := atePorts[0]
+	ip := ondatra.NewIPv4Header().WithSrcAddress(srcAttrs.IPv4)
+	ip.DstAddressRange().WithMin(dstMin).WithCount(dstCount)
+	flow := ate.Traffic().NewFlow("drain").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dsts...).
+		WithHeaders(ondatra.NewEthernetHeader(), ip).
+		WithFrameRateFPS(fps)
+
+	before := received(t, ate)
+	ate.Traffic().Start(t, flow)
+	time.Sleep(trafficTime)
+	start := received(t, ate)
+	if err := isisdrain.CheckPath(delta(before, start), c.from, *tolerance); err != nil {
+		ate.Traffic().Stop(t)
+		t.Fatalf("Before the change: %v", err)
+	}
+
+	c.apply(isis)
+	pushISIS(t, dut, isis)
+	time.Sleep(*convergence)
+	converged := received(t, ate)
+	time.Sleep(trafficTime)
+	end := received(t, ate)
+	ate.Traffic().Stop(t)
+	// Let the counters settle.
+	time.Sleep(5 * time.Second)
+
+	counts := delta(converged, end)
+	t.Logf("Packets received on each path once converged: %v", counts)
+	if err := isisdrain.CheckPath(counts, c.to, *tolerance); err != nil {
+		t.Error(err)
+	}
+	tx, rx := isisdrain.ReadLoss(t, ate, flow.Name())
+	t.Logf("Flow %s sent %d packets and received %d", flow.Name(), tx, rx)
+	if err := isisdrain.CheckLoss(tx, rx, fps, *lossBound); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestLinkDrain verifies that the traffic shifts to the backup path
+// within the loss bound when the DUT raises the metric of the primary
+// path to the maximum, and back when it restores it.
+func TestLinkDrain(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	isis := configureDUT(t, dut)
+	top, src, dsts := configureATE(t, ate)
+	defer top.StopProtocols(t)
+	awaitAdjacencies(t, dut)
+
+	primary := dut.Port(t, "port2").Name()
+	for _, c := range []drainCase{{
+		desc: "drain",
+		apply: func(isis *telemetry.NetworkInstance_Protocol_Isis) {
+			isisdrain.Drain(isis.GetInterface(primary), level)
+		},
+		from: 0,
+		to:   1,
+	}, {
+		desc: "undrain",
+		apply: func(isis *telemetry.NetworkInstance_Protocol_Isis) {
+			isisdrain.SetMetric(isis.GetInterface(primary), level, metrics[0])
+		},
+		from: 1,
+		to:   0,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			c.run(t, dut, ate, isis, src, dsts)
+		})
+	}
+}
+
+// TestOverload verifies that the DUT sets the overload bit and
+// advertises its links with the maximum metric, which only drains the
+// transit traffic of the other routers, so that its own traffic keeps
+// flowing on the primary path within the loss bound.
+func TestOverload(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	isis := configureDUT(t, dut)
+	top, src, dsts := configureATE(t, ate)
+	defer top.StopProtocols(t)
+	awaitAdjacencies(t, dut)
+
+	c := &drainCase{
+		apply: func(isis *telemetry.NetworkInstance_Protocol_Isis) {
+			isisdrain.SetOverload(isis, true, true)
+		},
+		from: 0,
+		to:   0,
+	}
+	c.run(t, dut, ate, isis, src, dsts)
+
+	telem := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).Protocol(session.PTISIS, session.ISISName).Isis()
+	if got := telem.Global().LspBit().OverloadBit().SetBit().Get(t); !got {
+		t.Errorf("Overload bit got %v, want true", got)
+	}
+	db := isislsdb.Read(t, dut, session.ISISName, level)
+	lsp := db.System(session.DUTSysID)
+	if lsp == nil {
+		t.Fatalf("No LSP of the DUT %s in its LSDB", session.DUTSysID)
+	}
+	if err := isisdrain.CheckHighMetric(lsp); err != nil {
+		t.Error(err)
+	}
+
+	isisdrain.SetOverload(isis, false, false)
+	pushISIS(t, dut, isis)
+}
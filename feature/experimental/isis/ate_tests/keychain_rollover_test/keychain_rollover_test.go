@@ -0,0 +1,117 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keychain_rollover_test implements RT-2.7: IS-IS Keychain Key
+// Rollover.
+package keychain_rollover_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/feature/experimental/isis/ate_tests/internal/session"
+	"github.com/openconfig/featureprofiles/internal/configdiff"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/isisauth"
+	"github.com/openconfig/featureprofiles/internal/keychain"
+	"github.com/openconfig/ondatra/ixnet"
+	"github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	rolloverDelay = flag.Duration("keychain_rollover_delay", 3*time.Minute,
+		"Time from configuring the keychain to the rollover of its key, long enough for the adjacency to come up with the first key.")
+	clockSkew = flag.Duration("keychain_clock_skew", 30*time.Second,
+		"Maximum difference between the clocks of the test and of the DUT, which the lifetimes of the keys overlap by.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+const (
+	level        = 2
+	keychainName = "ISIS-ROLLOVER"
+	secret       = "featureprofiles"
+	// holdMargin is how long after the rollover, and the skew of the
+	// clocks, the adjacency is watched, longer than its hold time.
+	holdMargin = time.Minute
+)
+
+// TestKeyRollover configures the DUT to authenticate its IS-IS hellos with
+// a keychain rolling its key over in a few minutes, and verifies that the
+// adjacency stays up through the rollover to a key of the same secret as
+// the ATE, and goes down after the rollover to another secret.
+//
+// config_path:/keychains/keychain/keys/key/config/secret-key
+// config_path:/keychains/keychain/keys/key/send-lifetime/config/start-time
+// config_path:/keychains/keychain/keys/key/send-lifetime/config/end-time
+// config_path:/keychains/keychain/keys/key/receive-lifetime/config/start-time
+// config_path:/keychains/keychain/keys/key/receive-lifetime/config/end-time
+// config_path:/network-instances/network-instance/protocols/protocol/isis/interfaces/interface/levels/level/hello-authentication/config/keychain
+// telemetry_path:/network-instances/network-instance/protocols/protocol/isis/interfaces/interface/levels/level/adjacencies/adjacency/state/adjacency-state
+func TestKeyRollover(t *testing.T) {
+	for _, c := range []struct {
+		desc      string
+		newSecret string
+		up        bool
+	}{
+		{desc: "same secret", newSecret: secret, up: true},
+		{desc: "new secret", newSecret: "rolled-over"},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			ts := session.NewWithISIS(t)
+			at := time.Now().Add(*rolloverDelay)
+			keys := keychain.Rollover(
+				keychain.Key{ID: 1, Algorithm: telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_MD5, Secret: secret},
+				keychain.Key{ID: 2, Algorithm: telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_MD5, Secret: c.newSecret},
+				at, *clockSkew)
+			if err := keychain.CheckHitless(keys, time.Now(), *clockSkew); err != nil {
+				t.Fatalf("Rollover is not hitless: %v", err)
+			}
+			kc := keychain.New(keychainName, keys...)
+			ts.DUT.Config().Keychain(keychainName).Replace(t, kc)
+			configdiff.VerifyReplace(t, ts.DUT, ts.DUT.Config().Keychain(keychainName), kc)
+
+			ts.ConfigISIS(t, func(isis *telemetry.NetworkInstance_Protocol_Isis) {
+				for _, intf := range isis.Interface {
+					keychain.BindISISHello(intf, level, keychainName)
+				}
+			}, func(isis *ixnet.ISIS) {
+				isis.WithAuthMD5(secret)
+			})
+			ts.PushAndStart(t)
+			defer ts.ATETop.StopProtocols(t)
+			ts.AwaitAdjacency(t)
+			if until := time.Until(at); until < *clockSkew {
+				t.Fatalf("IS-IS adjacency came up %v before the rollover, want at least %v, see --keychain_rollover_delay", until, *clockSkew)
+			}
+
+			intf := ts.DUT.Port(t, "port1").Name()
+			w := isisauth.WatchDown(t, ts.DUT, session.ISISName, intf, time.Until(at)+*clockSkew+holdMargin)
+			v, down := w.Await(t)
+			switch {
+			case c.up && down:
+				t.Errorf("IS-IS adjacency on %s went down at %v, rollover at %v", intf, v.RecvTimestamp, at)
+			case !c.up && !down:
+				t.Errorf("IS-IS adjacency on %s still up %v after the rollover to a secret the ATE does not send", intf, *clockSkew+holdMargin)
+			case !c.up && v.RecvTimestamp.Before(at.Add(-*clockSkew)):
+				t.Errorf("IS-IS adjacency on %s went down at %v, before the rollover at %v", intf, v.RecvTimestamp, at)
+			case !c.up:
+				t.Logf("IS-IS adjacency on %s went down %v after the rollover", intf, v.RecvTimestamp.Sub(at))
+			}
+		})
+	}
+}
@@ -23,6 +23,8 @@ import (
 	"github.com/openconfig/featureprofiles/feature/experimental/isis/ate_tests/internal/session"
 	"github.com/openconfig/featureprofiles/internal/deviations"
 	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/isislsdb"
+	"github.com/openconfig/ondatra/ixnet"
 	"github.com/openconfig/ondatra/telemetry"
 	"github.com/openconfig/ygot/ygot"
 )
@@ -79,3 +81,58 @@ func TestMetric(t *testing.T) {
 	// for _, nbr := range ateDB.Tlv(telemetry.IsisLsdbTypes_ISIS_TLV_TYPE_IS_NEIGHBOR_ATTRIBUTE).IsisNeighborAttribute().NeighborAny().Get(t) {
 	// }
 }
+
+// TestLSDB verifies the TLVs of the LSP advertised by the ATE in the DUT
+// LSDB: the extended IS reachability to the DUT, the reachability to
+// the ATE networks with their prefix SIDs, and the router capability
+// with the segment routing sub-TLVs.
+func TestLSDB(t *testing.T) {
+	const (
+		metric    = 10
+		v4Network = "198.51.100.0/24"
+		v6Network = "2001:db8:1::/64"
+		sidIndex  = 100
+		srgbStart = 16000
+		srgbSize  = 8000
+	)
+	ts := session.NewWithISIS(t)
+	ts.ConfigISIS(t, func(*telemetry.NetworkInstance_Protocol_Isis) {}, func(isis *ixnet.ISIS) {
+		isis.WithMetric(metric).WithCapabilityRouterID(session.ATEISISAttrs.IPv4)
+		isis.SegmentRouting().WithEnabled(true).AddSRGBRange().WithSIDStartLabel(srgbStart).WithSIDCount(srgbSize)
+	})
+	net := ts.ATEInterface(t, "port1").AddNetwork("lsdb")
+	net.IPv4().WithAddress(v4Network).WithCount(1)
+	net.IPv6().WithAddress(v6Network).WithCount(1)
+	net.ISIS().WithIPReachabilityMetric(metric).
+		WithSIDIndexLabelEnabled(true).WithIPReachabilitySIDIndexLabel(sidIndex).WithFlagNodeSID(true)
+	ts.PushAndStart(t)
+	defer ts.ATETop.StopProtocols(t)
+	ts.AwaitAdjacency(t)
+
+	ateSysID := ts.DUTISISTelemetry(t).Interface(ts.DUT.Port(t, "port1").Name()).Level(2).AdjacencyAny().SystemId().Get(t)[0]
+	want := &isislsdb.Advertisement{
+		SystemID:  ateSysID,
+		Neighbors: map[string]uint32{session.DUTSysID + ".00": metric},
+		Prefixes: map[string]*isislsdb.Prefix{
+			v4Network: {Metric: metric, SID: &isislsdb.PrefixSID{Value: sidIndex, Node: true}},
+			v6Network: {Metric: metric},
+		},
+		Capability: &isislsdb.Capability{
+			RouterID: session.ATEISISAttrs.IPv4,
+			SRGB:     []isislsdb.LabelRange{{Start: srgbStart, Size: srgbSize}},
+		},
+	}
+	// The ATE LSP may take a few seconds to be flooded after the
+	// adjacency comes up.
+	var errs []error
+	for i := 0; i < 10; i++ {
+		db := isislsdb.Read(t, ts.DUT, session.ISISName, 2)
+		if errs = db.Diff(want); len(errs) == 0 {
+			break
+		}
+		time.Sleep(3 * time.Second)
+	}
+	for _, err := range errs {
+		t.Error(err)
+	}
+}
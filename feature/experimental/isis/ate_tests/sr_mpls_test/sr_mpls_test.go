@@ -0,0 +1,191 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sr_mpls_test implements RT-2.3.
+package sr_mpls_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/feature/experimental/isis/ate_tests/internal/session"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/isislsdb"
+	"github.com/openconfig/featureprofiles/internal/srmpls"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/ixnet"
+	"github.com/openconfig/ondatra/netutil"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// In addition to the IS-IS adjacency of the session between dut:port1
+// and ate:port1, the DUT advertises its loopback with a node SID, and
+// the ATE advertises a network with a prefix SID.  Traffic is sent from
+// ate:port2 with the labels of the SIDs.
+const (
+	dutLoopback    = "192.0.2.255"
+	dutNodeIndex   = 1
+	ateNodeIndex   = 2
+	ateNetwork     = "198.51.100.0/24"
+	ateNetworkIP   = "198.51.100.1"
+	ateNetIndex    = 100
+	metric         = 10
+	adjacencyLabel = 15001
+	lossTolerance  = 1
+	labelTimeout   = time.Minute
+	trafficTime    = 15 * time.Second
+)
+
+var (
+	dutSRGB = &srmpls.Block{Name: "srgb", Start: 16000, End: 23999}
+	dutSRLB = &srmpls.Block{Name: "srlb", Start: 15000, End: 15999}
+	ateSRGB = &srmpls.Block{Name: "srgb", Start: 400000, End: 407999}
+)
+
+// newSession returns a session with segment routing configured on the
+// DUT and the ATE, pushed and with the adjacency up.
+func newSession(t *testing.T) *session.TestSession {
+	ts := session.NewWithISIS(t)
+	lo := netutil.LoopbackInterface(t, ts.DUT, 0)
+	intf := ts.DUTConf.GetOrCreateInterface(lo)
+	intf.Type = telemetry.IETFInterfaces_InterfaceType_softwareLoopback
+	intf.GetOrCreateSubinterface(0).GetOrCreateIpv4().GetOrCreateAddress(dutLoopback).PrefixLength = ygot.Uint8(32)
+
+	ni := ts.DUTConf.GetOrCreateNetworkInstance(*deviations.DefaultNetworkInstance)
+	dutSRGB.ConfigureSRGB(ni)
+	dutSRLB.ConfigureSRLB(ni)
+	ts.ConfigISIS(t, func(isis *telemetry.NetworkInstance_Protocol_Isis) {
+		srmpls.EnableISIS(isis, dutSRGB.Name)
+		loIntf := isis.GetOrCreateInterface(lo)
+		loIntf.Enabled = ygot.Bool(true)
+		loIntf.Passive = ygot.Bool(true)
+		srmpls.ConfigurePrefixSID(isis, lo, dutLoopback+"/32", dutNodeIndex)
+		srmpls.ConfigureAdjacencySID(isis, ts.DUT.Port(t, "port1").Name(), session.ATEISISAttrs.IPv4, adjacencyLabel)
+	}, func(isis *ixnet.ISIS) {
+		isis.WithMetric(metric)
+		srmpls.ConfigureATE(isis, ateSRGB, ateNodeIndex)
+	})
+	net := ts.ATEInterface(t, "port1").AddNetwork("sr")
+	net.IPv4().WithAddress(ateNetwork).WithCount(1)
+	srmpls.AdvertiseATEPrefix(net, metric, ateNetIndex)
+
+	// PushDUT only pushes the interfaces and IS-IS, so push the label
+	// blocks referenced by IS-IS first.
+	dutNI := ts.DUT.Config().NetworkInstance(*deviations.DefaultNetworkInstance)
+	dutNI.Mpls().Update(t, ni.GetMpls())
+	dutNI.SegmentRouting().Update(t, ni.GetSegmentRouting())
+	ts.PushDUT(t)
+	ts.PushAndStartATE(t)
+	ts.AwaitAdjacency(t)
+	return ts
+}
+
+// ateSystemID returns the system ID of the ATE, as seen by the DUT.
+func ateSystemID(t *testing.T, ts *session.TestSession) string {
+	return ts.DUTISISTelemetry(t).Interface(ts.DUT.Port(t, "port1").Name()).Level(2).AdjacencyAny().SystemId().Get(t)[0]
+}
+
+// sendTraffic sends the flow from ate:port2 to ate:port1 and verifies
+// that it is forwarded.
+func sendTraffic(t *testing.T, ts *session.TestSession, name string, labels []uint32, dstIP string) {
+	flow := srmpls.Flow(ts.ATE, name, ts.ATEInterface(t, "port2"), []ondatra.Endpoint{ts.ATEInterface(t, "port1")}, labels, dstIP)
+	ts.ATE.Traffic().Start(t, flow)
+	time.Sleep(trafficTime)
+	ts.ATE.Traffic().Stop(t)
+	if err := srmpls.VerifyFlow(t, ts.ATE, flow, lossTolerance); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSR(t *testing.T) {
+	ts := newSession(t)
+	defer ts.ATETop.StopProtocols(t)
+	ateSys := ateSystemID(t, ts)
+
+	t.Run("DUT learns ATE prefix SID", func(t *testing.T) {
+		var db *isislsdb.Database
+		var label uint32
+		var err error
+		for start := time.Now(); time.Since(start) < labelTimeout; time.Sleep(5 * time.Second) {
+			db = isislsdb.Read(t, ts.DUT, session.ISISName, 2)
+			dutLSP := db.System(session.DUTSysID)
+			if dutLSP == nil {
+				continue
+			}
+			if label, err = srmpls.PrefixLabel(db, dutLSP, ateSys, ateNetwork); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			t.Fatalf("Cannot find the DUT label of %s: %v", ateNetwork, err)
+		}
+		want, err := dutSRGB.Label(ateNetIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if label != want {
+			t.Errorf("DUT label of %s got %d, want %d", ateNetwork, label, want)
+		}
+		if srmpls.AwaitLabelEntry(t, ts.DUT, label, labelTimeout) == nil {
+			t.Fatalf("Label %d of %s not in the DUT MPLS AFT", label, ateNetwork)
+		}
+		sendTraffic(t, ts, "prefix-sid", []uint32{label}, ateNetworkIP)
+	})
+
+	t.Run("ATE learns DUT node SID", func(t *testing.T) {
+		var errs []error
+		want := &isislsdb.Advertisement{
+			SystemID: session.DUTSysID,
+			Prefixes: map[string]*isislsdb.Prefix{
+				dutLoopback + "/32": {SID: &isislsdb.PrefixSID{Value: dutNodeIndex, Node: true}},
+			},
+			Capability: &isislsdb.Capability{
+				SRGB: []isislsdb.LabelRange{{Start: dutSRGB.Start, Size: dutSRGB.Size()}},
+			},
+		}
+		for start := time.Now(); time.Since(start) < labelTimeout; time.Sleep(5 * time.Second) {
+			lsps := ts.ATEISISTelemetry(t).Level(2).LspAny().Get(t)
+			db, err := isislsdb.Decode(lsps)
+			if err != nil {
+				t.Fatalf("Cannot decode the ATE LSDB: %v", err)
+			}
+			// The metric of the loopback is not known, so copy it.
+			if lsp := db.System(session.DUTSysID); lsp != nil {
+				if p, ok := lsp.Prefixes[dutLoopback+"/32"]; ok {
+					want.Prefixes[dutLoopback+"/32"].Metric = p.Metric
+				}
+			}
+			if errs = db.Diff(want); len(errs) == 0 {
+				break
+			}
+		}
+		for _, err := range errs {
+			t.Error(err)
+		}
+	})
+
+	t.Run("DUT adjacency SID", func(t *testing.T) {
+		if srmpls.AwaitLabelEntry(t, ts.DUT, adjacencyLabel, labelTimeout) == nil {
+			t.Fatalf("Adjacency SID %d not in the DUT MPLS AFT", adjacencyLabel)
+		}
+		sendTraffic(t, ts, "adjacency-sid", []uint32{adjacencyLabel}, ateNetworkIP)
+	})
+}
@@ -0,0 +1,193 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package unnumbered_test implements RT-2.6.
+package unnumbered_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/feature/experimental/isis/ate_tests/internal/session"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/isisauth"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/netutil"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The IS-IS link between dut:port1 and ate:port1 is IPv4
+// unnumbered, borrowing the address of the DUT loopback, and IPv6
+// link-local only.  The ATE advertises a network of each family over
+// it, and sends traffic to them from ate:port2.
+//
+//   - IS-IS link: dut:port1 unnumbered to 192.0.2.1, fe80::1 -> ate:port1 192.0.2.2/30, fe80::2
+//   - Traffic source: ate:port2 -> dut:port2 subnet 192.0.2.4/30 2001:db8::4/126
+//   - Destination networks: 198.51.100.0/24 2001:db8:64::/64
+const (
+	level         = 2
+	dutLoopback   = "192.0.2.1"
+	ateNetwork4   = "198.51.100.0/24"
+	ateNetworkIP4 = "198.51.100.1"
+	ateNetwork6   = "2001:db8:64::/64"
+	ateNetworkIP6 = "2001:db8:64::1"
+	lossTolerance = 1
+	trafficTime   = 15 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{
+		Desc:          "DUT to ATE unnumbered",
+		IPv4:          dutLoopback,
+		IPv6LinkLocal: "fe80::1",
+	}
+
+	atePort1 = attrs.Attributes{
+		Name:          "port1",
+		IPv4:          "192.0.2.2",
+		IPv4Len:       30,
+		IPv6LinkLocal: "fe80::2",
+	}
+
+	dutPort2 = attrs.Attributes{
+		Desc:    "DUT to ATE source",
+		IPv4:    "192.0.2.5",
+		IPv6:    "2001:db8::5",
+		IPv4Len: 30,
+		IPv6Len: 126,
+	}
+
+	atePort2 = attrs.Attributes{
+		Name:    "port2",
+		IPv4:    "192.0.2.6",
+		IPv6:    "2001:db8::6",
+		IPv4Len: 30,
+		IPv6Len: 126,
+	}
+)
+
+// configureDUT configures the loopback, the ports and IS-IS on the
+// unnumbered port1 of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	lo := netutil.LoopbackInterface(t, dut, 0)
+	loIntf := &telemetry.Interface{Name: ygot.String(lo)}
+	loIntf.Type = telemetry.IETFInterfaces_InterfaceType_softwareLoopback
+	loIntf.GetOrCreateSubinterface(0).GetOrCreateIpv4().GetOrCreateAddress(dutLoopback).PrefixLength = ygot.Uint8(32)
+	d.Interface(lo).Replace(t, loIntf)
+
+	p1 := dut.Port(t, "port1")
+	unnumbered := dutPort1
+	unnumbered.IPv4Unnumbered = lo
+	d.Interface(p1.Name()).Replace(t, unnumbered.NewInterface(p1.Name()))
+	p2 := dut.Port(t, "port2")
+	d.Interface(p2.Name()).Replace(t, dutPort2.NewInterface(p2.Name()))
+
+	isis := &telemetry.NetworkInstance_Protocol_Isis{}
+	glob := isis.GetOrCreateGlobal()
+	glob.Instance = ygot.String(session.ISISName)
+	glob.Net = []string{fmt.Sprintf("%v.%v.00", session.DUTAreaAddress, session.DUTSysID)}
+	for _, afi := range []telemetry.E_IsisTypes_AFI_TYPE{telemetry.IsisTypes_AFI_TYPE_IPV4, telemetry.IsisTypes_AFI_TYPE_IPV6} {
+		glob.GetOrCreateAf(afi, telemetry.IsisTypes_SAFI_TYPE_UNICAST).Enabled = ygot.Bool(true)
+	}
+	intf := isis.GetOrCreateInterface(p1.Name())
+	intf.CircuitType = telemetry.IsisTypes_CircuitType_POINT_TO_POINT
+	intf.Enabled = ygot.Bool(true)
+	intf.GetOrCreateLevel(level).Enabled = ygot.Bool(true)
+	d.NetworkInstance(*deviations.DefaultNetworkInstance).Protocol(session.PTISIS, session.ISISName).Replace(t, &telemetry.NetworkInstance_Protocol{
+		Identifier: session.PTISIS,
+		Name:       ygot.String(session.ISISName),
+		Enabled:    ygot.Bool(true),
+		Isis:       isis,
+	})
+}
+
+// configureATE configures the ports and IS-IS on port1 of the ATE,
+// advertising the networks.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) (top *ondatra.ATETopology, dst, src *ondatra.Interface) {
+	top = ate.Topology().New()
+	dst = atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst.ISIS().
+		WithAreaID(session.ATEAreaAddress).
+		WithTERouterID("*").
+		WithNetworkTypePointToPoint().
+		WithWideMetricEnabled(true).
+		WithLevelL2()
+	net := dst.AddNetwork("unnumbered")
+	net.IPv4().WithAddress(ateNetwork4).WithCount(1)
+	net.IPv6().WithAddress(ateNetwork6).WithCount(1)
+	net.ISIS().WithIPReachabilityExternal().WithIPReachabilityMetric(10)
+	src = atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	top.Push(t).StartProtocols(t)
+	return top, dst, src
+}
+
+// TestUnnumbered verifies that IS-IS forms an adjacency over the
+// unnumbered link, learns the neighbor addresses, and forwards the
+// traffic to the networks advertised over it.
+func TestUnnumbered(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	top, dst, src := configureATE(t, ate)
+	defer top.StopProtocols(t)
+
+	p1 := dut.Port(t, "port1").Name()
+	if _, err := isisauth.AwaitUp(t, dut, session.ISISName, p1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Adjacency", func(t *testing.T) {
+		adj := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+			Protocol(session.PTISIS, session.ISISName).Isis().Interface(p1).Level(level).AdjacencyAny()
+		if got := adj.NeighborIpv4Address().Get(t); len(got) != 1 || got[0] != atePort1.IPv4 {
+			t.Errorf("Neighbor IPv4 addresses got %v, want [%s]", got, atePort1.IPv4)
+		}
+		if got := adj.NeighborIpv6Address().Get(t); len(got) != 1 || !strings.EqualFold(got[0], atePort1.IPv6LinkLocal) {
+			t.Errorf("Neighbor IPv6 addresses got %v, want [%s]", got, atePort1.IPv6LinkLocal)
+		}
+	})
+
+	for _, c := range []struct {
+		desc string
+		ip   ondatra.Header
+	}{
+		{"IPv4", ondatra.NewIPv4Header().WithSrcAddress(atePort2.IPv4).WithDstAddress(ateNetworkIP4)},
+		{"IPv6", ondatra.NewIPv6Header().WithSrcAddress(atePort2.IPv6).WithDstAddress(ateNetworkIP6)},
+	} {
+		t.Run(c.desc+" traffic", func(t *testing.T) {
+			flow := ate.Traffic().NewFlow(c.desc).
+				WithSrcEndpoints(src).
+				WithDstEndpoints(dst).
+				WithHeaders(ondatra.NewEthernetHeader(), c.ip)
+			ate.Traffic().Start(t, flow)
+			time.Sleep(trafficTime)
+			ate.Traffic().Stop(t)
+			if got := ate.Telemetry().Flow(flow.Name()).LossPct().Get(t); got > lossTolerance {
+				t.Errorf("Flow %s loss got %v%%, want at most %v%%", flow.Name(), got, lossTolerance)
+			}
+		})
+	}
+}
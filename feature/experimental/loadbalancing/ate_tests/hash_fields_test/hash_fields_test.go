@@ -0,0 +1,248 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hash_fields_test
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/lbhash"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	tolerance = flag.Float64("lb_tolerance_pct", 20,
+		"maximum deviation in percent of the packets received by each member from the mean, for hashed fields")
+	mplsLabel = flag.Uint("lb_mpls_label", 0,
+		"incoming MPLS label the DUT is configured to load-balance over port2 to port4, to vary the entropy label under; the mpls-entropy field is skipped if zero")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port{2-4} ->
+// ate:port{2-4}.  The DUT load-balances the destination networks over
+// port2 to port4 with equal cost static routes.
+//
+//   - Source: ate:port1 -> dut:port1 subnets 192.0.2.0/30 and 2001:db8::/126
+//   - Members: dut:port{2-4} -> ate:port{2-4} subnets 192.0.2.4/30,
+//     192.0.2.8/30, 192.0.2.12/30 and 2001:db8::4/126, ...
+//   - Destination networks: 203.0.113.0/24 (TEST-NET-3) and 2001:db8:1::/64
+const (
+	plen4       = 30
+	plen6       = 126
+	dstNet4     = "203.0.113.0/24"
+	dstNet6     = "2001:db8:1::/64"
+	dstIP4      = "203.0.113.1"
+	dstIP6      = "2001:db8:1::1"
+	srcIP4      = "198.51.100.1"
+	srcIP6      = "2001:db8:2::1"
+	members     = 3
+	valueCount  = 1024
+	fps         = 10000
+	trafficTime = 15 * time.Second
+	staticName  = "STATIC"
+)
+
+// dutPorts and atePorts are the attributes of the DUT and ATE ends of
+// port1, port2, and so on.
+var (
+	dutPorts = []attrs.Attributes{
+		{
+			Desc:    "DUT to ATE port1",
+			IPv4:    "192.0.2.1",
+			IPv6:    "2001:db8::1",
+			IPv4Len: plen4,
+			IPv6Len: plen6,
+		},
+		{
+			Desc:    "DUT to ATE port2",
+			IPv4:    "192.0.2.5",
+			IPv6:    "2001:db8::5",
+			IPv4Len: plen4,
+			IPv6Len: plen6,
+		},
+		{
+			Desc:    "DUT to ATE port3",
+			IPv4:    "192.0.2.9",
+			IPv6:    "2001:db8::9",
+			IPv4Len: plen4,
+			IPv6Len: plen6,
+		},
+		{
+			Desc:    "DUT to ATE port4",
+			IPv4:    "192.0.2.13",
+			IPv6:    "2001:db8::d",
+			IPv4Len: plen4,
+			IPv6Len: plen6,
+		},
+	}
+
+	atePorts = []attrs.Attributes{
+		{
+			Name:    "port1",
+			IPv4:    "192.0.2.2",
+			IPv6:    "2001:db8::2",
+			IPv4Len: plen4,
+			IPv6Len: plen6,
+		},
+		{
+			Name:    "port2",
+			IPv4:    "192.0.2.6",
+			IPv6:    "2001:db8::6",
+			IPv4Len: plen4,
+			IPv6Len: plen6,
+		},
+		{
+			Name:    "port3",
+			IPv4:    "192.0.2.10",
+			IPv6:    "2001:db8::a",
+			IPv4Len: plen4,
+			IPv6Len: plen6,
+		},
+		{
+			Name:    "port4",
+			IPv4:    "192.0.2.14",
+			IPv6:    "2001:db8::e",
+			IPv4Len: plen4,
+			IPv6Len: plen6,
+		},
+	}
+)
+
+// configureDUT configures the ports and the equal cost static routes.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	dev := &telemetry.Device{}
+	ni := dev.GetOrCreateNetworkInstance(*deviations.DefaultNetworkInstance)
+	static := ni.GetOrCreateProtocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName)
+	sr4 := static.GetOrCreateStatic(dstNet4)
+	sr6 := static.GetOrCreateStatic(dstNet6)
+	for i := 1; i <= members+1; i++ {
+		dutAttrs, ateAttrs := dutPorts[i-1], atePorts[i-1]
+		p := dut.Port(t, fmt.Sprintf("port%d", i))
+		d.Interface(p.Name()).Replace(t, dutAttrs.NewInterface(p.Name()))
+		if i == 1 {
+			continue
+		}
+		sr4.GetOrCreateNextHop(ateAttrs.Name).NextHop = telemetry.UnionString(ateAttrs.IPv4)
+		sr6.GetOrCreateNextHop(ateAttrs.Name).NextHop = telemetry.UnionString(ateAttrs.IPv6)
+	}
+	staticPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName)
+	fptest.LogYgot(t, "DUT static routes", staticPath, static)
+	staticPath.Replace(t, static)
+}
+
+// configureATE configures the ATE interfaces, and returns the source
+// and the members.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) (*ondatra.Interface, []*ondatra.Interface) {
+	top := ate.Topology().New()
+	var src *ondatra.Interface
+	var dsts []*ondatra.Interface
+	for i := 1; i <= members+1; i++ {
+		dutAttrs, ateAttrs := dutPorts[i-1], atePorts[i-1]
+		intf := ateAttrs.AddToATE(top, ate.Port(t, ateAttrs.Name), &dutAttrs)
+		if i == 1 {
+			src = intf
+		} else {
+			dsts = append(dsts, intf)
+		}
+	}
+	top.Push(t).StartProtocols(t)
+	return src, dsts
+}
+
+// receivedPkts returns the packets received by the member ports.
+func receivedPkts(t *testing.T, ate *ondatra.ATEDevice) []uint64 {
+	var pkts []uint64
+	for i := 2; i <= members+1; i++ {
+		p := ate.Port(t, fmt.Sprintf("port%d", i))
+		pkts = append(pkts, ate.Telemetry().Interface(p.Name()).Counters().InPkts().Get(t))
+	}
+	return pkts
+}
+
+func TestHashFields(t *testing.T) {
+	hashed, err := lbhash.Configured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("DUT hash fields: %s", lbhash.String(hashed))
+
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	src, dsts := configureATE(t, ate)
+	var dstEPs []ondatra.Endpoint
+	for _, d := range dsts {
+		dstEPs = append(dstEPs, d)
+	}
+
+	for _, ipv6 := range []bool{false, true} {
+		spec := &lbhash.Spec{
+			Src:             src,
+			Dsts:            dstEPs,
+			IPv6:            ipv6,
+			SrcIP:           srcIP4,
+			DstIP:           dstIP4,
+			MPLSLabel:       uint32(*mplsLabel),
+			Count:           valueCount,
+			FramesPerSecond: fps,
+		}
+		family := "IPv4"
+		if ipv6 {
+			spec.SrcIP, spec.DstIP, family = srcIP6, dstIP6, "IPv6"
+		}
+		for _, field := range lbhash.AllFields {
+			t.Run(fmt.Sprintf("%s %s", family, field), func(t *testing.T) {
+				switch {
+				case field == lbhash.FlowLabel && !ipv6, field == lbhash.Protocol && ipv6:
+					t.Skipf("%s not applicable to %s", field, family)
+				case field == lbhash.MPLSEntropy && *mplsLabel == 0:
+					t.Skip("No -lb_mpls_label given")
+				}
+				flows, err := spec.Flows(ate, field)
+				if err != nil {
+					t.Fatal(err)
+				}
+				before := receivedPkts(t, ate)
+				ate.Traffic().Start(t, flows...)
+				time.Sleep(trafficTime)
+				ate.Traffic().Stop(t)
+				// Let the counters settle.
+				time.Sleep(5 * time.Second)
+				after := receivedPkts(t, ate)
+
+				counts := make([]uint64, len(after))
+				for i := range after {
+					counts[i] = after[i] - before[i]
+				}
+				t.Logf("Packets received by each member: %v", counts)
+				if err := lbhash.Check(field, hashed[field], counts, *tolerance); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package static_nat_test implements RT-12.1: Static NAT.
+package static_nat_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/nat"
+	"github.com/openconfig/featureprofiles/internal/otgutils"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, the inside interface,
+// and dut:port2 -> ate:port2, the outside interface.  The inside hosts
+// are behind ate:port1, and the DUT translates their addresses to the
+// outside addresses, which it owns.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - dut:port2 -> ate:port2 subnet 192.0.2.4/30
+//   - inside hosts 198.51.100.1 to 198.51.100.4, routed to ate:port1
+//   - outside addresses 203.0.113.1 to 203.0.113.4
+const (
+	plen         = 30
+	staticName   = "STATIC"
+	insidePrefix = "198.51.100.0/24"
+	firstInside  = "198.51.100.1"
+	firstOutside = "203.0.113.1"
+	mappings     = 4
+	pps          = 100
+	trafficTime  = 10 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT and the static route to the
+// inside hosts.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	static := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC,
+		Name:       ygot.String(staticName),
+	}
+	s := static.GetOrCreateStatic(insidePrefix)
+	s.GetOrCreateNextHop("1").NextHop = telemetry.UnionString(atePort1.IPv4)
+	staticPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName)
+	fptest.LogYgot(t, "DUT static routes", staticPath, static)
+	staticPath.Replace(t, static)
+}
+
+// configureOTG returns the OTG configuration of the ATE interfaces,
+// capturing on both ports.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	for _, p := range []struct {
+		port      string
+		ate, peer *attrs.Attributes
+	}{{"port1", &atePort1, &dutPort1}, {"port2", &atePort2, &dutPort2}} {
+		id := ate.Port(t, p.port).ID()
+		config.Ports().Add().SetName(id)
+		config.Devices().Add().SetName(p.ate.Name).Ethernets().Add().
+			SetName(p.ate.Name + ".eth").
+			SetPortName(id).
+			SetMac(p.ate.MAC).
+			Ipv4Addresses().Add().
+			SetName(p.ate.Name + ".IPv4").
+			SetAddress(p.ate.IPv4).
+			SetGateway(p.peer.IPv4).
+			SetPrefix(int32(p.ate.IPv4Len))
+	}
+	capture.Enable(config, ate.Port(t, "port1").ID(), ate.Port(t, "port2").ID())
+	return config
+}
+
+// flowName returns the name of the flow of the mapping in the direction.
+func flowName(m nat.Mapping, d nat.Direction) string {
+	return fmt.Sprintf("%v %s", d, m.Inside)
+}
+
+// addFlows adds a flow for each mapping in the direction: outbound from
+// the inside address to ate:port2, and inbound from ate:port2 to the
+// outside address.
+func addFlows(config gosnappi.Config, tb nat.Table, d nat.Direction) {
+	config.Flows().Clear()
+	tx, rx := atePort1, atePort2
+	if d == nat.Inbound {
+		tx, rx = atePort2, atePort1
+	}
+	for _, m := range tb {
+		flow := config.Flows().Add().SetName(flowName(m, d))
+		flow.Metrics().SetEnable(true)
+		flow.TxRx().Device().
+			SetTxNames([]string{tx.Name + ".IPv4"}).
+			SetRxNames([]string{rx.Name + ".IPv4"})
+		flow.Rate().SetPps(pps)
+		flow.Duration().FixedPackets().SetPackets(int32(pps * trafficTime / time.Second))
+		flow.Size().SetFixed(256)
+		flow.Packet().Add().Ethernet().Src().SetValue(tx.MAC)
+		ip := flow.Packet().Add().Ipv4()
+		if d == nat.Outbound {
+			ip.Src().SetValue(m.Inside)
+			ip.Dst().SetValue(atePort2.IPv4)
+		} else {
+			ip.Src().SetValue(atePort2.IPv4)
+			ip.Dst().SetValue(m.Outside)
+		}
+		flow.Packet().Add().Udp()
+	}
+}
+
+// inPkts returns the unicast packets received by the DUT port.
+func inPkts(t testing.TB, dut *ondatra.DUTDevice, port string) uint64 {
+	return dut.Telemetry().Interface(dut.Port(t, port).Name()).Counters().InUnicastPkts().Get(t)
+}
+
+// outPkts returns the unicast packets sent out of the DUT port.
+func outPkts(t testing.TB, dut *ondatra.DUTDevice, port string) uint64 {
+	return dut.Telemetry().Interface(dut.Port(t, port).Name()).Counters().OutUnicastPkts().Get(t)
+}
+
+// checkTranslation sends the flows of the mappings in the direction, and
+// verifies from the flow counters, the captured packets and the counters
+// of the DUT ports that all the packets were forwarded translated.
+func checkTranslation(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, api gosnappi.GosnappiApi, config gosnappi.Config, tb nat.Table, d nat.Direction) {
+	dutIn, dutOut, ateRx := "port1", "port2", "port2"
+	if d == nat.Inbound {
+		dutIn, dutOut, ateRx = "port2", "port1", "port1"
+	}
+	rxID := ate.Port(t, ateRx).ID()
+	addFlows(config, tb, d)
+	otg := ate.OTG()
+	otg.PushConfig(t, config)
+	otg.StartProtocols(t)
+
+	in0, out0 := inPkts(t, dut, dutIn), outPkts(t, dut, dutOut)
+	capture.Start(t, api, rxID)
+	otg.StartTraffic(t)
+	time.Sleep(trafficTime)
+	otg.StopTraffic(t)
+	capture.Stop(t, api, rxID)
+	in1, out1 := inPkts(t, dut, dutIn), outPkts(t, dut, dutOut)
+	otgutils.LogFlowMetrics(t, otg, config)
+
+	sent := map[string]uint64{}
+	var total uint64
+	for _, m := range tb {
+		c := otg.Telemetry().Flow(flowName(m, d)).Get(t).GetCounters()
+		if tx, rx := c.GetOutPkts(), c.GetInPkts(); tx == 0 {
+			t.Errorf("Mapping %v: no packets sent", m)
+		} else if rx != tx {
+			t.Errorf("Mapping %v: %d of %d packets received", m, rx, tx)
+		}
+		sent[m.Inside] = c.GetOutPkts()
+		total += c.GetOutPkts()
+	}
+
+	counts := tb.Count(capture.Fetch(t, api, rxID), d)
+	for _, m := range tb {
+		t.Logf("Mapping %v: %d packets translated, %d not", m, counts[m.Inside].Translated, counts[m.Inside].Untranslated)
+	}
+	for _, err := range tb.Check(counts, sent) {
+		t.Error(err)
+	}
+
+	// The counters of the DUT ports also count control plane traffic.
+	if got := in1 - in0; got < total {
+		t.Errorf("dut:%s received %d unicast packets, want at least the %d sent", dutIn, got, total)
+	}
+	if got := out1 - out0; got < total {
+		t.Errorf("dut:%s sent %d unicast packets, want at least the %d translated", dutOut, got, total)
+	}
+}
+
+// TestStaticNAT verifies that the DUT, configured out of band with static
+// NAT mappings of inside hosts to outside addresses, translates the source
+// addresses of the traffic of the inside hosts to the outside addresses,
+// and the destination addresses of the traffic to the outside addresses
+// back to the inside hosts.
+//
+// telemetry_path:/interfaces/interface/state/counters/in-unicast-pkts
+// telemetry_path:/interfaces/interface/state/counters/out-unicast-pkts
+func TestStaticNAT(t *testing.T) {
+	tb, err := nat.Sequential(firstInside, firstOutside, mappings)
+	if err != nil {
+		t.Fatalf("Invalid NAT mappings: %v", err)
+	}
+	nat.Require(t, tb)
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	config := configureOTG(t, ate)
+	api := fptest.DialOTG(t, ate)
+
+	for _, d := range []nat.Direction{nat.Outbound, nat.Inbound} {
+		t.Run(d.String(), func(t *testing.T) {
+			checkTranslation(t, dut, ate, api, config, tb, d)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipv6_nd_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/ipv6nd"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	hostCount   = flag.Int("nd_host_count", 4, "number of IPv6 hosts emulated by the ATE")
+	raInterval  = flag.Uint("nd_ra_interval", 30, "router advertisement interval configured on the DUT, in seconds")
+	raLifetime  = flag.Uint("nd_ra_lifetime", 1800, "router lifetime advertised by the DUT, in seconds")
+	collectTime = flag.Duration("nd_collect_time", 2*time.Minute, "time the neighbor states of the hosts are collected for")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The hosts are emulated on ate:port2, and traffic is sent
+// to them from ate:port1 so that the DUT keeps resolving them.
+//
+//   - Source: ate:port1 -> dut:port1 subnet 2001:db8::/126
+//   - Hosts: dut:port2 -> ate:port2 subnet 2001:db8:1::/64
+//
+// dadAddress is used by an extra host on ate:port2, then configured on
+// dut:port2, which must detect it as a duplicate.
+const (
+	hostsLen6   = 64
+	firstHost6  = "2001:db8:1::2"
+	dadAddress  = "2001:db8:1::ffff"
+	resolveTime = 2 * time.Minute
+	dadTime     = time.Minute
+)
+
+var (
+	dutSrc = attrs.Attributes{
+		Desc:    "DUT to ATE source",
+		IPv6:    "2001:db8::1",
+		IPv6Len: 126,
+	}
+
+	ateSrc = attrs.Attributes{
+		Name:    "src",
+		IPv6:    "2001:db8::2",
+		IPv6Len: 126,
+	}
+
+	dutHosts = attrs.Attributes{
+		Desc:    "DUT to ATE hosts",
+		IPv6:    "2001:db8:1::1",
+		IPv6Len: hostsLen6,
+	}
+)
+
+// configureDUT configures the ports, with the router advertisements
+// on port2.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice, ra *ipv6nd.RouterAdvertisement) {
+	d := dut.Config()
+	p1 := dut.Port(t, "port1")
+	d.Interface(p1.Name()).Replace(t, dutSrc.NewInterface(p1.Name()))
+	p2 := dut.Port(t, "port2")
+	i2 := dutHosts.NewInterface(p2.Name())
+	ra.Apply(i2.GetOrCreateSubinterface(0).GetOrCreateIpv6())
+	fptest.LogYgot(t, p2.String(), d.Interface(p2.Name()), i2)
+	d.Interface(p2.Name()).Replace(t, i2)
+}
+
+// configureATE configures the source interface, the hosts and the
+// host using dadAddress.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice, hostAddrs []string) (*ondatra.Interface, []*ondatra.Interface) {
+	top := ate.Topology().New()
+	src := ateSrc.AddToATE(top, ate.Port(t, "port1"), &dutSrc)
+	p2 := ate.Port(t, "port2")
+	hosts := ipv6nd.AddHosts(top, p2, append(hostAddrs, dadAddress), hostsLen6, dutHosts.IPv6)
+	top.Push(t).StartProtocols(t)
+	return src, hosts[:len(hostAddrs)]
+}
+
+// TestIPv6ND verifies the router advertisement configuration of the
+// DUT, that the neighbor states of the ATE hosts follow the state
+// machine of RFC 4861, and that the DUT detects a duplicate address.
+//
+// config_path:/interfaces/interface/subinterfaces/subinterface/ipv6/router-advertisement/config/interval
+// config_path:/interfaces/interface/subinterfaces/subinterface/ipv6/router-advertisement/config/lifetime
+// config_path:/interfaces/interface/subinterfaces/subinterface/ipv6/router-advertisement/config/suppress
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/ipv6/router-advertisement/state/interval
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/ipv6/router-advertisement/state/lifetime
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/ipv6/router-advertisement/state/suppress
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/ipv6/neighbors/neighbor/state/neighbor-state
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/ipv6/addresses/address/state/status
+func TestIPv6ND(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	p2 := dut.Port(t, "port2")
+
+	hostAddrs, err := ipv6nd.HostAddresses(firstHost6, *hostCount)
+	if err != nil {
+		t.Fatalf("Cannot generate the host addresses: %v", err)
+	}
+	ra := &ipv6nd.RouterAdvertisement{
+		Interval: uint32(*raInterval),
+		Lifetime: uint32(*raLifetime),
+	}
+	configureDUT(t, dut, ra)
+	src, hosts := configureATE(t, ate, hostAddrs)
+
+	t.Run("RouterAdvertisement", func(t *testing.T) {
+		ra.Verify(t, dut, p2)
+	})
+
+	t.Run("RouterAdvertisementSuppress", func(t *testing.T) {
+		suppressed := *ra
+		suppressed.Suppress = true
+		raPath := dut.Config().Interface(p2.Name()).Subinterface(0).Ipv6().RouterAdvertisement()
+		raPath.Suppress().Replace(t, true)
+		defer raPath.Suppress().Replace(t, false)
+		suppressed.Verify(t, dut, p2)
+	})
+
+	t.Run("NeighborStates", func(t *testing.T) {
+		var dsts []ondatra.Endpoint
+		for _, h := range hosts {
+			dsts = append(dsts, h)
+		}
+		flow := ate.Traffic().NewFlow("hosts").
+			WithSrcEndpoints(src).
+			WithDstEndpoints(dsts...).
+			WithHeaders(ondatra.NewEthernetHeader(), ondatra.NewIPv6Header()).
+			WithFrameRatePct(1)
+		ate.Traffic().Start(t, flow)
+		defer ate.Traffic().Stop(t)
+
+		for _, addr := range hostAddrs {
+			if !ipv6nd.AwaitNeighborState(t, dut, p2, addr, resolveTime,
+				telemetry.Neighbor_NeighborState_REACHABLE, telemetry.Neighbor_NeighborState_STALE) {
+				t.Errorf("Neighbor %s not resolved in %v", addr, resolveTime)
+			}
+		}
+		// The states of the first host are representative of the others,
+		// which are resolved the same way.
+		states := ipv6nd.CollectNeighborStates(t, dut, p2, hostAddrs[0], *collectTime)
+		t.Logf("Neighbor %s went through states %v", hostAddrs[0], states)
+		for _, err := range ipv6nd.CheckTransitions(states) {
+			t.Errorf("Neighbor %s: %v", hostAddrs[0], err)
+		}
+	})
+
+	t.Run("DuplicateAddressDetection", func(t *testing.T) {
+		addrPath := dut.Config().Interface(p2.Name()).Subinterface(0).Ipv6().Address(dadAddress)
+		addrPath.Replace(t, &telemetry.Interface_Subinterface_Ipv6_Address{
+			Ip:           ygot.String(dadAddress),
+			PrefixLength: ygot.Uint8(hostsLen6),
+		})
+		defer addrPath.Delete(t)
+		if !ipv6nd.AwaitAddressStatus(t, dut, p2, dadAddress, dadTime, telemetry.Address_Status_DUPLICATE) {
+			t.Errorf("Address %s used by an ATE host not detected as duplicate in %v", dadAddress, dadTime)
+		}
+	})
+}
@@ -0,0 +1,220 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package neighbor_scale_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/neighbor"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	staticCount = flag.Int("neighbor_static_count", 4000, "number of static ARP and ND entries to configure")
+	hostCount   = flag.Int("neighbor_host_count", 1000, "number of hosts emulated by the ATE for each address family")
+	minRate     = flag.Float64("neighbor_min_rate", 100, "minimum number of neighbors the DUT should resolve per second")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The hosts are emulated on ate:port2, and resolved by the
+// DUT when forwarding the traffic sent to them from ate:port1.
+//
+//   - Source: ate:port1 -> dut:port1 subnets 192.0.2.0/30 and 2001:db8::/126
+//   - Hosts: dut:port2 -> ate:port2 subnets 198.18.0.0/16 and 2001:db8:1::/64
+//
+// The static neighbors are configured in the second half of the hosts
+// subnets, where no host is emulated.
+const (
+	hostsLen4    = 16
+	hostsLen6    = 64
+	firstHost4   = "198.18.0.2"
+	firstHost6   = "2001:db8:1::2"
+	firstStatic4 = "198.18.128.0"
+	firstStatic6 = "2001:db8:1::8000:0"
+	firstMAC     = "02:00:5e:00:00:01"
+	resolveTime  = 5 * time.Minute
+)
+
+var (
+	dutSrc = attrs.Attributes{
+		Desc:    "DUT to ATE source",
+		IPv4:    "192.0.2.1",
+		IPv6:    "2001:db8::1",
+		IPv4Len: 30,
+		IPv6Len: 126,
+	}
+
+	ateSrc = attrs.Attributes{
+		Name:    "src",
+		IPv4:    "192.0.2.2",
+		IPv6:    "2001:db8::2",
+		IPv4Len: 30,
+		IPv6Len: 126,
+	}
+
+	dutHosts = attrs.Attributes{
+		Desc:    "DUT to ATE hosts",
+		IPv4:    "198.18.0.1",
+		IPv6:    "2001:db8:1::1",
+		IPv4Len: hostsLen4,
+		IPv6Len: hostsLen6,
+	}
+)
+
+// staticEntries returns the static IPv4 and IPv6 neighbors.
+func staticEntries(t *testing.T) []neighbor.Entry {
+	var entries []neighbor.Entry
+	for _, first := range []string{firstStatic4, firstStatic6} {
+		e, err := neighbor.Entries(first, firstMAC, args.Scale(*staticCount))
+		if err != nil {
+			t.Fatalf("Cannot generate static neighbors: %v", err)
+		}
+		entries = append(entries, e...)
+	}
+	return entries
+}
+
+// configureDUT configures the ports, with the static neighbors on
+// port2.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice, static []neighbor.Entry) {
+	d := dut.Config()
+	p1 := dut.Port(t, "port1")
+	d.Interface(p1.Name()).Replace(t, dutSrc.NewInterface(p1.Name()))
+	p2 := dut.Port(t, "port2")
+	i2 := dutHosts.NewInterface(p2.Name())
+	neighbor.ConfigureStatic(i2.GetOrCreateSubinterface(0), static)
+	d.Interface(p2.Name()).Replace(t, i2)
+}
+
+// configureATE configures the source interface and the hosts, and
+// returns them.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) (*ondatra.Interface, []ondatra.Endpoint, []ondatra.Endpoint) {
+	top := ate.Topology().New()
+	src := ateSrc.AddToATE(top, ate.Port(t, "port1"), &dutSrc)
+	p2 := ate.Port(t, "port2")
+
+	var hosts4, hosts6 []ondatra.Endpoint
+	for _, h := range []struct {
+		first, gateway string
+		plen           uint8
+		eps            *[]ondatra.Endpoint
+	}{
+		{firstHost4, dutHosts.IPv4, hostsLen4, &hosts4},
+		{firstHost6, dutHosts.IPv6, hostsLen6, &hosts6},
+	} {
+		entries, err := neighbor.Entries(h.first, firstMAC, args.Scale(*hostCount))
+		if err != nil {
+			t.Fatalf("Cannot generate hosts: %v", err)
+		}
+		var addrs []string
+		for _, e := range entries {
+			addrs = append(addrs, e.IP)
+		}
+		for _, intf := range neighbor.AddHosts(top, p2, addrs, h.plen, h.gateway) {
+			*h.eps = append(*h.eps, intf)
+		}
+	}
+	top.Push(t).StartProtocols(t)
+	return src, hosts4, hosts6
+}
+
+func TestStaticNeighbors(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	static := staticEntries(t)
+	configureDUT(t, dut, static)
+	p2 := dut.Port(t, "port2")
+
+	for _, ipv6 := range []bool{false, true} {
+		got := neighbor.Read(t, dut, p2, ipv6)
+		want := static[:args.Scale(*staticCount)]
+		if ipv6 {
+			want = static[args.Scale(*staticCount):]
+		}
+		errs := neighbor.Diff(want, got, telemetry.IfIp_NeighborOrigin_STATIC)
+		for i, err := range errs {
+			if i == 10 {
+				t.Errorf("... and %d more static neighbor errors", len(errs)-i)
+				break
+			}
+			t.Error(err)
+		}
+	}
+}
+
+func TestResolutionScale(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut, nil)
+	src, hosts4, hosts6 := configureATE(t, ate)
+	p2 := dut.Port(t, "port2")
+
+	for _, c := range []struct {
+		desc  string
+		ipv6  bool
+		hosts []ondatra.Endpoint
+		ip    ondatra.Header
+	}{
+		{"IPv4", false, hosts4, ondatra.NewIPv4Header()},
+		{"IPv6", true, hosts6, ondatra.NewIPv6Header()},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			flow := ate.Traffic().NewFlow("hosts-"+c.desc).
+				WithSrcEndpoints(src).
+				WithDstEndpoints(c.hosts...).
+				WithHeaders(ondatra.NewEthernetHeader(), c.ip).
+				WithFrameRateFPS(uint64(10 * len(c.hosts)))
+			ate.Traffic().Start(t, flow)
+			defer ate.Traffic().Stop(t)
+
+			elapsed, ok := neighbor.AwaitResolved(t, dut, p2, c.ipv6, len(c.hosts), resolveTime)
+			if !ok {
+				t.Fatalf("DUT did not resolve %d %s hosts within %v", len(c.hosts), c.desc, resolveTime)
+			}
+			rate := neighbor.Rate(len(c.hosts), elapsed)
+			t.Logf("DUT resolved %d %s hosts in %v, %.1f per second", len(c.hosts), c.desc, elapsed, rate)
+			if rate < *minRate {
+				t.Errorf("Resolution rate got %.1f per second, want >= %.1f", rate, *minRate)
+			}
+
+			want := map[string]string{}
+			for _, s := range neighbor.Read(t, dut, p2, c.ipv6) {
+				if s.Origin == telemetry.IfIp_NeighborOrigin_DYNAMIC {
+					want[s.IP] = s.MAC
+				}
+			}
+			nhs := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).Afts().NextHopAny().Get(t)
+			errs := neighbor.CheckNextHopMACs(nhs, want)
+			for i, err := range errs {
+				if i == 10 {
+					t.Errorf("... and %d more AFT next hop errors", len(errs)-i)
+					break
+				}
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lldp_disabled_punt_test implements P4RT-6.1: LLDP Disabled with
+// P4RT Punt.
+package lldp_disabled_punt_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/p4rt"
+	"github.com/openconfig/ondatra"
+
+	p4pb "github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+var (
+	p4rtNodeName = flag.String("p4rt_node_name", "SwitchChip3/0", "component name for P4RT Node")
+	p4infoFile   = flag.String("p4info_file", "", "P4Info of the pipeline of the DUT, in text format")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  ate:port1 sends LLDP frames to the DUT, which has LLDP
+// disabled, and the test is the P4Runtime controller of the DUT.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+const (
+	plen       = 30
+	deviceID   = 1
+	electionID = 100
+	port1ID    = 10
+	port2ID    = 11
+	// lldpPPS is the rate of the LLDP frames, above the one of LLDP agents
+	// to count the punted frames over a short duration.
+	lldpPPS = 10
+	// trafficFor is how long the LLDP frames are sent for.
+	trafficFor = 10 * time.Second
+	// lldpMulticast is the nearest bridge group address of LLDP.
+	lldpMulticast = "01:80:c2:00:00:0e"
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+
+	// trap traps LLDP frames to the controller in the WBB pipeline.
+	trap = &p4rt.Trap{
+		Table:      "ingress.acl_ingress.acl_ingress_table",
+		MatchField: "ether_type",
+		Action:     "ingress.acl_ingress.acl_trap",
+		Params:     map[string][]byte{"qos_queue": {0x1}},
+		EtherType:  p4rt.LLDPEtherType,
+		Priority:   1,
+	}
+)
+
+// configureDUT configures the ports of the DUT and their P4Runtime IDs,
+// the P4Runtime node ID, and disables LLDP.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+	p4rt.Configure(t, dut, *p4rtNodeName, deviceID, map[string]uint32{
+		dut.Port(t, "port1").Name(): port1ID,
+		dut.Port(t, "port2").Name(): port2ID,
+	})
+	d.Lldp().Enabled().Replace(t, false)
+}
+
+// configureATE configures the ports of the ATE and returns the flow of
+// LLDP frames from ate:port1.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	top.Push(t).StartProtocols(t)
+
+	eth := ondatra.NewEthernetHeader().
+		WithSrcAddress(atePort1.MAC).
+		WithDstAddress(lldpMulticast).
+		WithEtherType(p4rt.LLDPEtherType)
+	return ate.Traffic().NewFlow("LLDP").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst).
+		WithHeaders(eth).
+		WithFrameRateFPS(lldpPPS)
+}
+
+// sendLLDP sends the LLDP frames, and returns the packet-ins the
+// controller received and the number of frames sent.
+func sendLLDP(t *testing.T, ate *ondatra.ATEDevice, flow *ondatra.Flow, c *p4rt.Client) ([]*p4pb.PacketIn, uint64) {
+	c.Drain()
+	ate.Traffic().Start(t, flow)
+	time.Sleep(args.TrafficDuration(trafficFor))
+	ate.Traffic().Stop(t)
+	packets := c.PacketIns(5 * time.Second)
+	return packets, ate.Telemetry().Flow(flow.Name()).Counters().OutPkts().Get(t)
+}
+
+// TestLLDPDisabledPunt verifies that with LLDP disabled in OpenConfig, the
+// DUT neither learns LLDP neighbors nor consumes the LLDP frames, which
+// P4Runtime punts to the controller while it traps them, and only then.
+//
+// config_path:/lldp/config/enabled
+// config_path:/interfaces/interface/config/id
+// config_path:/components/component/integrated-circuit/config/node-id
+// telemetry_path:/lldp/state/enabled
+// telemetry_path:/lldp/interfaces/interface/neighbors/neighbor/state/id
+func TestLLDPDisabledPunt(t *testing.T) {
+	if *p4infoFile == "" {
+		t.Skip("P4Info of the DUT not given by --p4info_file")
+	}
+	info, err := p4rt.LoadP4Info(*p4infoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := trap.Entry(info)
+	if err != nil {
+		t.Fatalf("Cannot trap LLDP with the P4Info: %v", err)
+	}
+
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	flow := configureATE(t, ate)
+	if got := dut.Telemetry().Lldp().Enabled().Get(t); got {
+		t.Errorf("LLDP enabled got %t, want false", got)
+	}
+
+	c, err := p4rt.Connect(t, dut, deviceID, electionID)
+	if err != nil {
+		t.Fatalf("P4Runtime connection can not be established: %v", err)
+	}
+	defer c.Close()
+	if err := c.SetPipeline(t, info); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Trapped", func(t *testing.T) {
+		if err := c.Write(t, p4pb.Update_INSERT, entry); err != nil {
+			t.Fatal(err)
+		}
+		packets, sent := sendLLDP(t, ate, flow, c)
+		got := p4rt.CountLLDP(packets)
+		t.Logf("Sent %d LLDP frames, %d punted to the controller", sent, got)
+		// Control plane policing may drop a few of them.
+		if want := sent * 9 / 10; uint64(got) < want {
+			t.Errorf("LLDP frames punted got %d, want at least %d of %d sent", got, want, sent)
+		}
+	})
+
+	t.Run("Not trapped", func(t *testing.T) {
+		if err := c.Write(t, p4pb.Update_DELETE, entry); err != nil {
+			t.Fatal(err)
+		}
+		packets, sent := sendLLDP(t, ate, flow, c)
+		if got := p4rt.CountLLDP(packets); got != 0 {
+			t.Errorf("LLDP frames punted got %d of %d sent, want none", got, sent)
+		}
+	})
+
+	// With LLDP disabled, the frames are punted to the controller, but not
+	// processed by the DUT.
+	port1 := dut.Port(t, "port1").Name()
+	if nbrs := dut.Telemetry().Lldp().Interface(port1).NeighborAny().Id().Lookup(t); len(nbrs) > 0 {
+		t.Errorf("LLDP neighbors of %s got %d, want none", port1, len(nbrs))
+	}
+}
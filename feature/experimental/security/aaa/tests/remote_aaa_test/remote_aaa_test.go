@@ -0,0 +1,266 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote_aaa_test
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/aaa"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	sshIP          = flag.String("ssh_ip", "", "External IP address of management interface.")
+	serverHost     = flag.String("aaa_server_host", "", "Address of the test host as reached by the DUT, where the TACACS+ and RADIUS servers listen.  TestRemoteAAA is skipped if empty.")
+	tacacsPort     = flag.Int("aaa_tacacs_port", aaa.TACACSPort, "Port on which the TACACS+ server listens.")
+	radiusAuthPort = flag.Int("aaa_radius_auth_port", aaa.RADIUSAuthPort, "Port on which the RADIUS server listens for authentication.")
+	radiusAcctPort = flag.Int("aaa_radius_acct_port", aaa.RADIUSAcctPort, "Port on which the RADIUS server listens for accounting.")
+	eventTimeout   = flag.Duration("aaa_event_timeout", 30*time.Second, "Time given to the DUT to send an AAA request to the server.")
+)
+
+const (
+	sshPort  = 22
+	gnmiPort = 6030
+	secret   = "aaa-secret"
+	group    = "remote-aaa"
+)
+
+// users are known to the servers only, so that the DUT must ask the
+// server to authenticate them.
+var users = map[string]*aaa.User{
+	"aaa-admin": {Password: "aaa-admin-pw"},
+	"aaa-ops":   {Password: "aaa-ops-pw", Denied: []string{"configure"}},
+}
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// server is the TACACS+ or RADIUS server of a test case.
+type server interface {
+	Events() []*aaa.Event
+	Await(skip int, timeout time.Duration, match func(*aaa.Event) bool) (*aaa.Event, error)
+	Close() error
+}
+
+func keyboardInteraction(password string) ssh.KeyboardInteractiveChallenge {
+	return func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+		if len(questions) == 0 {
+			return []string{}, nil
+		}
+		return []string{password}, nil
+	}
+}
+
+// sshLogin logs in as the user over SSH and runs the command.
+func sshLogin(user, password, command string) error {
+	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", *sshIP, sshPort), &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(password),
+			ssh.KeyboardInteractive(keyboardInteraction(password)),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         30 * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	// The command may fail if the user is not authorized to run it.
+	session.CombinedOutput(command)
+	return nil
+}
+
+// gnmiGet gets the hostname as the user over gNMI.
+func gnmiGet(user, password string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(
+		ctx,
+		fmt.Sprintf("%s:%d", *sshIP, gnmiPort),
+		grpc.WithTransportCredentials(
+			credentials.NewTLS(&tls.Config{
+				InsecureSkipVerify: true, // NOLINT
+			})),
+	)
+	if err != nil {
+		return fmt.Errorf("grpc.DialContext => unexpected failure dialing GNMI (should not require auth): %w", err)
+	}
+	defer conn.Close()
+	ctx = metadata.AppendToOutgoingContext(ctx, "username", user, "password", password)
+	_, err = gpb.NewGNMIClient(conn).Get(ctx, &gpb.GetRequest{
+		Path: []*gpb.Path{{
+			Elem: []*gpb.PathElem{
+				{Name: "system"}, {Name: "config"}, {Name: "hostname"}}},
+		},
+		Type: gpb.GetRequest_CONFIG,
+	})
+	return err
+}
+
+// awaitEvent waits for the event of the kind for the user, recorded
+// after the first skip events.
+func awaitEvent(t *testing.T, s server, skip int, kind aaa.Kind, user string, pass bool) *aaa.Event {
+	t.Helper()
+	e, err := s.Await(skip, *eventTimeout, func(e *aaa.Event) bool {
+		return e.Kind == kind && e.User == user && e.Pass == pass
+	})
+	if err != nil {
+		t.Errorf("No %v of %s with pass=%v: %v; events: %v", kind, user, pass, err, s.Events()[skip:])
+		return nil
+	}
+	t.Logf("Server recorded %v", e)
+	return e
+}
+
+// TestRemoteAAA verifies that the DUT authenticates the users logging in
+// over SSH and gNMI with a TACACS+ or RADIUS server embedded in the test,
+// authorizes their commands with TACACS+, and accounts their logins and
+// commands.
+//
+// config_path:/system/aaa/server-groups/server-group/config/type
+// config_path:/system/aaa/server-groups/server-group/servers/server/tacacs/config/port
+// config_path:/system/aaa/server-groups/server-group/servers/server/tacacs/config/secret-key
+// config_path:/system/aaa/server-groups/server-group/servers/server/radius/config/auth-port
+// config_path:/system/aaa/server-groups/server-group/servers/server/radius/config/acct-port
+// config_path:/system/aaa/server-groups/server-group/servers/server/radius/config/secret-key
+// config_path:/system/aaa/authentication/config/authentication-method
+// config_path:/system/aaa/authorization/config/authorization-method
+// config_path:/system/aaa/accounting/config/accounting-method
+// config_path:/system/aaa/accounting/events/event/config/record
+// telemetry_path:/system/aaa/server-groups/server-group/servers/server/state/connection-opens
+// telemetry_path:/system/aaa/server-groups/server-group/servers/server/state/connection-failures
+func TestRemoteAAA(t *testing.T) {
+	if *serverHost == "" {
+		t.Skip("No -aaa_server_host for the DUT to reach the AAA servers")
+	}
+	if *sshIP == "" {
+		t.Fatal("--ssh_ip flag must be set.")
+	}
+	dut := ondatra.DUT(t, "dut")
+	config := dut.Config().System().Aaa()
+
+	for _, tc := range []struct {
+		desc   string
+		typ    telemetry.E_AaaTypes_AAA_SERVER_TYPE
+		server func() (server, error)
+		config *telemetry.System_Aaa_ServerGroup_Server
+	}{{
+		desc: "TACACS+",
+		typ:  telemetry.AaaTypes_AAA_SERVER_TYPE_TACACS,
+		server: func() (server, error) {
+			return aaa.NewTACACS(fmt.Sprintf(":%d", *tacacsPort), secret, users)
+		},
+		config: aaa.TACACSServer(*serverHost, uint16(*tacacsPort), secret),
+	}, {
+		desc: "RADIUS",
+		typ:  telemetry.AaaTypes_AAA_SERVER_TYPE_RADIUS,
+		server: func() (server, error) {
+			return aaa.NewRADIUS(fmt.Sprintf(":%d", *radiusAuthPort), fmt.Sprintf(":%d", *radiusAcctPort), secret, users)
+		},
+		config: aaa.RADIUSServer(*serverHost, uint16(*radiusAuthPort), uint16(*radiusAcctPort), secret),
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := tc.server()
+			if err != nil {
+				t.Fatalf("Cannot start the %s server: %v", tc.desc, err)
+			}
+			defer s.Close()
+
+			config.Update(t, aaa.Config(group, tc.typ, tc.config))
+			defer func() {
+				config.Authentication().AuthenticationMethod().Delete(t)
+				config.Authorization().AuthorizationMethod().Delete(t)
+				config.Accounting().Delete(t)
+				config.ServerGroup(group).Delete(t)
+			}()
+
+			t.Run("SSH", func(t *testing.T) {
+				skip := len(s.Events())
+				if err := sshLogin("aaa-ops", "aaa-ops-pw", "show version"); err != nil {
+					t.Fatalf("ssh.Dial got error %v, want nil for user aaa-ops", err)
+				}
+				awaitEvent(t, s, skip, aaa.Authentication, "aaa-ops", true)
+				awaitEvent(t, s, skip, aaa.Accounting, "aaa-ops", true)
+				if tc.typ == telemetry.AaaTypes_AAA_SERVER_TYPE_TACACS {
+					if e := awaitEvent(t, s, skip, aaa.Authorization, "aaa-ops", true); e != nil && e.Command == "" {
+						t.Errorf("Authorization of aaa-ops got no command, want show version")
+					}
+				}
+			})
+
+			t.Run("SSH denied", func(t *testing.T) {
+				skip := len(s.Events())
+				if err := sshLogin("aaa-ops", "wrong", ""); err == nil {
+					t.Errorf("ssh.Dial got nil error, want error for user aaa-ops with a wrong password")
+				}
+				awaitEvent(t, s, skip, aaa.Authentication, "aaa-ops", false)
+			})
+
+			if tc.typ == telemetry.AaaTypes_AAA_SERVER_TYPE_TACACS {
+				t.Run("SSH unauthorized command", func(t *testing.T) {
+					skip := len(s.Events())
+					if err := sshLogin("aaa-ops", "aaa-ops-pw", "configure terminal"); err != nil {
+						t.Fatalf("ssh.Dial got error %v, want nil for user aaa-ops", err)
+					}
+					awaitEvent(t, s, skip, aaa.Authorization, "aaa-ops", false)
+				})
+			}
+
+			t.Run("gNMI", func(t *testing.T) {
+				skip := len(s.Events())
+				if err := gnmiGet("aaa-admin", "aaa-admin-pw"); err != nil {
+					t.Errorf("gnmi.Get unexpected error for user aaa-admin: %v", err)
+				}
+				awaitEvent(t, s, skip, aaa.Authentication, "aaa-admin", true)
+			})
+
+			t.Run("gNMI denied", func(t *testing.T) {
+				skip := len(s.Events())
+				if err := gnmiGet("aaa-admin", "wrong"); err == nil {
+					t.Errorf("gnmi.Get nil error when error expected for user aaa-admin with a wrong password")
+				}
+				awaitEvent(t, s, skip, aaa.Authentication, "aaa-admin", false)
+			})
+
+			counters := dut.Telemetry().System().Aaa().ServerGroup(group).Server(*serverHost)
+			if v := counters.ConnectionOpens().Lookup(t); v.IsPresent() {
+				t.Logf("DUT opened %d connections to the %s server", v.Val(t), tc.desc)
+			}
+			if v := counters.ConnectionFailures().Lookup(t); v.IsPresent() && v.Val(t) > 0 {
+				t.Errorf("DUT got %d connection failures to the %s server, want 0", v.Val(t), tc.desc)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mgmt_acl_test
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/mgmtacl"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	sshIP            = flag.String("ssh_ip", "", "External IP address of management interface.")
+	mgmtInterface    = flag.String("mgmt_acl_interface", "", "Name of the management interface of the DUT, to which the ACL is applied.")
+	permittedSources = flag.String("mgmt_acl_permitted_sources", "", "Comma separated addresses of the test host permitted by the ACL.  They must include the address the binding dials the DUT from, and may include secondary addresses of the same interface.  TestManagementACL is skipped if empty.")
+	blockedSource    = flag.String("mgmt_acl_blocked_source", "", "Secondary address of the test host not permitted by the ACL.  TestManagementACL is skipped if empty.")
+	sshPort          = flag.Uint("mgmt_acl_ssh_port", uint(mgmtacl.SSH.Port), "Port of the SSH server of the DUT.")
+	gnmiPort         = flag.Uint("mgmt_acl_gnmi_port", uint(mgmtacl.GNMI.Port), "Port of the gNMI server of the DUT.")
+	gnoiPort         = flag.Uint("mgmt_acl_gnoi_port", uint(mgmtacl.GNOI.Port), "Port of the gNOI server of the DUT.")
+	probeTimeout     = flag.Duration("mgmt_acl_probe_timeout", 10*time.Second, "Time given to a service to answer a probe.")
+)
+
+const aclName = "MGMT-ACL"
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// probe probes the service from the source, and reports an error if
+// its reachability is not the one wanted.
+func probe(t *testing.T, svc mgmtacl.Service, src string, wantReachable bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), *probeTimeout)
+	defer cancel()
+	d := &mgmtacl.Dialer{Source: src, Timeout: *probeTimeout}
+	err := svc.Probe(ctx, d, *sshIP)
+	switch {
+	case wantReachable && err != nil:
+		t.Errorf("Probe got error %v, want %s reachable from %s", err, svc.Name, src)
+	case !wantReachable && err == nil:
+		t.Errorf("Probe got nil error, want %s blocked from %s", svc.Name, src)
+	default:
+		t.Logf("Probe of %s from %s got error %v", svc.Name, src, err)
+	}
+}
+
+// TestManagementACL verifies that an ACL on the management interface
+// restricts the sources which can reach the SSH, gNMI and gNOI services
+// of the DUT.  The test host probes the services from the addresses
+// permitted by the ACL, and from a blocked address.
+//
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/ipv4/config/source-address
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/ipv4/config/protocol
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/transport/config/destination-port
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/actions/config/forwarding-action
+// config_path:/acl/interfaces/interface/ingress-acl-sets/ingress-acl-set/config/set-name
+// telemetry_path:/acl/interfaces/interface/ingress-acl-sets/ingress-acl-set/acl-entries/acl-entry/state/matched-packets
+func TestManagementACL(t *testing.T) {
+	if *permittedSources == "" || *blockedSource == "" {
+		t.Skip("No -mgmt_acl_permitted_sources and -mgmt_acl_blocked_source to probe the DUT from")
+	}
+	if *sshIP == "" {
+		t.Fatal("--ssh_ip flag must be set.")
+	}
+	if *mgmtInterface == "" {
+		t.Fatal("--mgmt_acl_interface flag must be set.")
+	}
+	dut := ondatra.DUT(t, "dut")
+	permitted := strings.Split(*permittedSources, ",")
+	services := []mgmtacl.Service{
+		mgmtacl.SSH.WithPort(uint16(*sshPort)),
+		mgmtacl.GNMI.WithPort(uint16(*gnmiPort)),
+		mgmtacl.GNOI.WithPort(uint16(*gnoiPort)),
+	}
+
+	t.Run("Before ACL", func(t *testing.T) {
+		for _, svc := range services {
+			for _, src := range append(permitted, *blockedSource) {
+				probe(t, svc, src, true)
+			}
+		}
+	})
+
+	acl := &mgmtacl.ACL{Name: aclName, Services: services}
+	for _, src := range permitted {
+		acl.Permitted = append(acl.Permitted, mgmtacl.HostPrefix(src))
+	}
+	set := acl.AclSet()
+	config := dut.Config().Acl()
+	fptest.LogYgot(t, "DUT ACL", config.AclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4), set)
+	config.AclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4).Replace(t, set)
+	config.Interface(*mgmtInterface).Replace(t, acl.Interface(*mgmtInterface))
+	defer func() {
+		config.Interface(*mgmtInterface).Delete(t)
+		config.AclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4).Delete(t)
+	}()
+
+	for _, svc := range services {
+		t.Run(svc.Name, func(t *testing.T) {
+			for _, src := range permitted {
+				probe(t, svc, src, true)
+			}
+			probe(t, svc, *blockedSource, false)
+		})
+	}
+
+	entries := dut.Telemetry().Acl().Interface(*mgmtInterface).IngressAclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4)
+	var dropped uint64
+	for seq, e := range set.AclEntry {
+		if e.GetActions().GetForwardingAction() != telemetry.Acl_FORWARDING_ACTION_DROP {
+			continue
+		}
+		if v := entries.AclEntry(seq).MatchedPackets().Lookup(t); v.IsPresent() {
+			dropped += v.Val(t)
+		}
+	}
+	t.Logf("Drop entries of %s matched %d packets", aclName, dropped)
+	if dropped == 0 {
+		t.Errorf("Drop entries of %s matched no packets, want the probes from %s", aclName, *blockedSource)
+	}
+}
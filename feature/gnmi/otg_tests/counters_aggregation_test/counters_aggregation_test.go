@@ -0,0 +1,274 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package counters_aggregation_test implements gNMI-1.18: Telemetry:
+// Subinterface and Aggregate Counters.
+package counters_aggregation_test
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/ifcounters"
+	"github.com/openconfig/featureprofiles/internal/otgutils"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/netutil"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+	otgtelemetry "github.com/openconfig/ondatra/telemetry/otg"
+)
+
+var (
+	tolerance   = flag.Float64("counters_tolerance", 1, "percent by which the counters may differ from those expected, for the control traffic of the DUT")
+	updateDelay = flag.Duration("counters_update_delay", 30*time.Second, "how long the DUT may take to update its counters after the traffic")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, and dut:port2 and
+// dut:port3 -> ate:port2 and ate:port3.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - LAG: dut:port2 and dut:port3 -> ate:port2 and ate:port3 subnet
+//     192.0.2.4/30
+//   - Subinterfaces: dut:port2 -> ate:port2 VLAN 10, 20 and 30 subnets
+//     192.0.2.8/30, 192.0.2.12/30 and 192.0.2.16/30
+const (
+	plen4   = 30
+	pps     = 1000
+	lagName = "lag"
+)
+
+var (
+	dutSrc = attrs.Attributes{Desc: "DUT to ATE source", IPv4: "192.0.2.1", IPv4Len: plen4}
+	ateSrc = attrs.Attributes{Name: "src", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen4}
+	dutLAG = attrs.Attributes{Desc: "DUT to ATE LAG", IPv4: "192.0.2.5", IPv4Len: plen4}
+	ateLAG = attrs.Attributes{Name: "lag", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv4Len: plen4}
+
+	// vlans are the subinterfaces of dut:port2, and the number of
+	// packets of the bursts sent to each, all different.
+	vlans = []struct {
+		id       uint16
+		dut, ate attrs.Attributes
+		packets  uint64
+	}{
+		{10, attrs.Attributes{IPv4: "192.0.2.9", IPv4Len: plen4}, attrs.Attributes{Name: "vlan10", MAC: "02:00:03:01:01:01", IPv4: "192.0.2.10", IPv4Len: plen4}, 1000},
+		{20, attrs.Attributes{IPv4: "192.0.2.13", IPv4Len: plen4}, attrs.Attributes{Name: "vlan20", MAC: "02:00:03:01:01:02", IPv4: "192.0.2.14", IPv4Len: plen4}, 2000},
+		{30, attrs.Attributes{IPv4: "192.0.2.17", IPv4Len: plen4}, attrs.Attributes{Name: "vlan30", MAC: "02:00:03:01:01:03", IPv4: "192.0.2.18", IPv4Len: plen4}, 3000},
+	}
+)
+
+// bursts returns bursts of small and large packets.
+func bursts(packets uint64) []ifcounters.Burst {
+	return []ifcounters.Burst{{Packets: packets, PacketSize: 100}, {Packets: packets, PacketSize: 1400}}
+}
+
+// addSrc adds to the OTG configuration the device of ate:port1.
+func addSrc(t *testing.T, ate *ondatra.ATEDevice, config gosnappi.Config) ifcounters.Endpoint {
+	config.Ports().Add().SetName(ate.Port(t, "port1").ID())
+	eth := config.Devices().Add().SetName(ateSrc.Name).Ethernets().Add().
+		SetName(ateSrc.Name + ".eth").
+		SetPortName(ate.Port(t, "port1").ID()).
+		SetMac(ateSrc.MAC)
+	eth.Ipv4Addresses().Add().
+		SetName(ateSrc.Name + ".IPv4").
+		SetAddress(ateSrc.IPv4).
+		SetGateway(dutSrc.IPv4).
+		SetPrefix(int32(ateSrc.IPv4Len))
+	return ifcounters.Endpoint{Device: ateSrc.Name + ".IPv4", MAC: ateSrc.MAC, IPv4: ateSrc.IPv4}
+}
+
+// waitNeighbors waits for the OTG to resolve the DUT addresses.
+func waitNeighbors(t *testing.T, ate *ondatra.ATEDevice, names ...string) {
+	for _, name := range names {
+		ate.OTG().Telemetry().Interface(name+".eth").Ipv4NeighborAny().LinkLayerAddress().Watch(t, time.Minute, func(val *otgtelemetry.QualifiedString) bool {
+			return val.IsPresent()
+		}).Await(t)
+	}
+}
+
+// sendBursts sends the flows, and checks that the OTG sent and received
+// exactly their packets.
+func sendBursts(t *testing.T, ate *ondatra.ATEDevice, config gosnappi.Config) {
+	otg := ate.OTG()
+	otg.PushConfig(t, config)
+	otg.StartProtocols(t)
+	otg.StartTraffic(t)
+	for _, f := range config.Flows().Items() {
+		want := uint64(f.Duration().FixedPackets().Packets())
+		otg.Telemetry().Flow(f.Name()).Counters().OutPkts().Watch(t, time.Duration(want/pps)*time.Second+time.Minute, func(val *otgtelemetry.QualifiedUint64) bool {
+			return val.IsPresent() && val.Val(t) >= want
+		}).Await(t)
+	}
+	otg.StopTraffic(t)
+	otgutils.LogFlowMetrics(t, otg, config)
+
+	for _, f := range config.Flows().Items() {
+		want := uint64(f.Duration().FixedPackets().Packets())
+		c := otg.Telemetry().Flow(f.Name()).Counters()
+		if tx, rx := c.OutPkts().Get(t), c.InPkts().Get(t); tx != want || rx != want {
+			t.Errorf("Flow %s sent %d and received %d packets, want %d", f.Name(), tx, rx, want)
+		}
+	}
+	time.Sleep(*updateDelay)
+}
+
+// TestLAGRollUp sends traffic both ways through a static LAG, and
+// checks that the counters of the LAG are the sum of those of its
+// members, and match the traffic.
+//
+// telemetry_path:/interfaces/interface/state/counters/in-unicast-pkts
+// telemetry_path:/interfaces/interface/state/counters/in-octets
+// telemetry_path:/interfaces/interface/state/counters/in-errors
+// telemetry_path:/interfaces/interface/state/counters/out-unicast-pkts
+// telemetry_path:/interfaces/interface/state/counters/out-octets
+// telemetry_path:/interfaces/interface/state/counters/out-errors
+func TestLAGRollUp(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	d := dut.Config()
+
+	p1 := dut.Port(t, "port1")
+	d.Interface(p1.Name()).Replace(t, dutSrc.NewInterface(p1.Name()))
+	aggID := netutil.NextBundleInterface(t, dut)
+	d.Interface(aggID).Replace(t, ifcounters.NewLAG(aggID, &dutLAG))
+	var members []ifcounters.Ref
+	for _, p := range []string{"port2", "port3"} {
+		name := dut.Port(t, p).Name()
+		d.Interface(name).Replace(t, ifcounters.NewMember(name, aggID))
+		members = append(members, ifcounters.Ref{Kind: ifcounters.Physical, Name: name})
+	}
+	lag := ifcounters.Ref{Kind: ifcounters.LAG, Name: aggID}
+	dut.Telemetry().Interface(aggID).OperStatus().Await(t, time.Minute, telemetry.Interface_OperStatus_UP)
+
+	config := ate.OTG().NewConfig(t)
+	src := addSrc(t, ate, config)
+	l := config.Lags().Add().SetName(lagName)
+	l.Protocol().Static().SetLagId(1)
+	for i, p := range []string{"port2", "port3"} {
+		config.Ports().Add().SetName(ate.Port(t, p).ID())
+		l.Ports().Add().SetPortName(ate.Port(t, p).ID()).Ethernet().
+			SetName(ateLAG.Name + "." + p).
+			SetMac(fmt.Sprintf("02:00:02:01:01:%02x", i+2))
+	}
+	eth := config.Devices().Add().SetName(ateLAG.Name).Ethernets().Add().
+		SetName(ateLAG.Name + ".eth").
+		SetMac(ateLAG.MAC)
+	eth.Connection().SetLagName(lagName)
+	eth.Ipv4Addresses().Add().
+		SetName(ateLAG.Name + ".IPv4").
+		SetAddress(ateLAG.IPv4).
+		SetGateway(dutLAG.IPv4).
+		SetPrefix(int32(ateLAG.IPv4Len))
+	dst := ifcounters.Endpoint{Device: ateLAG.Name + ".IPv4", MAC: ateLAG.MAC, IPv4: ateLAG.IPv4}
+	out, in := bursts(10000), bursts(5000)
+	ifcounters.AddFlows(config, "to-lag", src, dst, out, pps)
+	ifcounters.AddFlows(config, "from-lag", dst, src, in, pps)
+
+	lagBefore := ifcounters.Get(t, dut, lag)
+	var membersBefore []ifcounters.Counters
+	for _, m := range members {
+		membersBefore = append(membersBefore, ifcounters.Get(t, dut, m))
+	}
+	waitNeighbors(t, ate, ateSrc.Name, ateLAG.Name)
+	sendBursts(t, ate, config)
+	lagDelta := ifcounters.Get(t, dut, lag).Sub(lagBefore)
+	var memberDeltas []ifcounters.Counters
+	for i, m := range members {
+		delta := ifcounters.Get(t, dut, m).Sub(membersBefore[i])
+		t.Logf("Member %v counters increased by %+v", m, delta)
+		if delta.OutUnicastPkts == 0 {
+			t.Errorf("Member %v sent no packets: the traffic is not spread over the LAG", m)
+		}
+		memberDeltas = append(memberDeltas, delta)
+	}
+	t.Logf("LAG %v counters increased by %+v", lag, lagDelta)
+
+	for _, err := range ifcounters.CheckRollUp(lagDelta, memberDeltas, *tolerance) {
+		t.Errorf("LAG %v: %v", lag, err)
+	}
+	want := ifcounters.Sum([]ifcounters.Counters{
+		ifcounters.Expect(out, ifcounters.Physical, ifcounters.LAG).Out(),
+		ifcounters.Expect(in, ifcounters.LAG, ifcounters.Physical).In(),
+	})
+	for _, err := range ifcounters.Check(lagDelta, want, *tolerance) {
+		t.Errorf("LAG %v: %v", lag, err)
+	}
+}
+
+// TestSubinterfaceIndependence sends a different number of packets to
+// each of several VLAN subinterfaces of a port, and checks that the
+// counters of each only count its own.
+//
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/state/counters/out-unicast-pkts
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/state/counters/out-octets
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/state/counters/out-errors
+func TestSubinterfaceIndependence(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	d := dut.Config()
+
+	p1, p2 := dut.Port(t, "port1"), dut.Port(t, "port2")
+	d.Interface(p1.Name()).Replace(t, dutSrc.NewInterface(p1.Name()))
+	i := (&attrs.Attributes{Desc: "DUT to ATE VLANs"}).NewInterface(p2.Name())
+	for _, v := range vlans {
+		ifcounters.ConfigureSubinterface(i, uint32(v.id), v.id, &v.dut)
+	}
+	d.Interface(p2.Name()).Replace(t, i)
+	dut.Telemetry().Interface(p2.Name()).OperStatus().Await(t, time.Minute, telemetry.Interface_OperStatus_UP)
+
+	config := ate.OTG().NewConfig(t)
+	src := addSrc(t, ate, config)
+	config.Ports().Add().SetName(ate.Port(t, "port2").ID())
+	names := []string{ateSrc.Name}
+	var subs []ifcounters.Ref
+	var before []ifcounters.Counters
+	for _, v := range vlans {
+		eth := config.Devices().Add().SetName(v.ate.Name).Ethernets().Add().
+			SetName(v.ate.Name + ".eth").
+			SetPortName(ate.Port(t, "port2").ID()).
+			SetMac(v.ate.MAC)
+		eth.Vlans().Add().SetName(v.ate.Name + ".vlan").SetId(int32(v.id))
+		eth.Ipv4Addresses().Add().
+			SetName(v.ate.Name + ".IPv4").
+			SetAddress(v.ate.IPv4).
+			SetGateway(v.dut.IPv4).
+			SetPrefix(int32(v.ate.IPv4Len))
+		dst := ifcounters.Endpoint{Device: v.ate.Name + ".IPv4", MAC: v.ate.MAC, IPv4: v.ate.IPv4, VLAN: v.id}
+		ifcounters.AddFlows(config, v.ate.Name, src, dst, bursts(v.packets), pps)
+		names = append(names, v.ate.Name)
+
+		sub := ifcounters.Ref{Kind: ifcounters.Subinterface, Name: p2.Name(), Index: uint32(v.id)}
+		subs = append(subs, sub)
+		before = append(before, ifcounters.Get(t, dut, sub))
+	}
+	waitNeighbors(t, ate, names...)
+	sendBursts(t, ate, config)
+
+	for i, v := range vlans {
+		got := ifcounters.Get(t, dut, subs[i]).Sub(before[i]).Out()
+		want := ifcounters.Expect(bursts(v.packets), ifcounters.Physical, ifcounters.Subinterface).Out()
+		t.Logf("Subinterface %v counters increased by %+v, want %+v", subs[i], got, want)
+		for _, err := range ifcounters.Check(got, want, *tolerance) {
+			t.Errorf("Subinterface %v: %v", subs[i], err)
+		}
+	}
+}
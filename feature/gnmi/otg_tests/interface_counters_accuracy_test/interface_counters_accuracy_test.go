@@ -0,0 +1,262 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package interface_counters_accuracy_test implements gNMI-1.17:
+// Telemetry: Interface Counters Accuracy.
+package interface_counters_accuracy_test
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/ifcounters"
+	"github.com/openconfig/featureprofiles/internal/otgutils"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/netutil"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+	otgtelemetry "github.com/openconfig/ondatra/telemetry/otg"
+)
+
+var (
+	tolerance   = flag.Float64("counters_tolerance", 1, "percent by which the packet and octet counters may exceed those expected, for the control traffic of the DUT")
+	updateDelay = flag.Duration("counters_update_delay", 30*time.Second, "how long the DUT may take to update its counters after the traffic")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2, and dut:port3 -> ate:port3 which is bundled with port2 in
+// a static LAG for the LAG case.
+//
+//   - Ingress: ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - Egress: dut:port2 -> ate:port2 subnet 192.0.2.4/30
+//
+// The subinterface case uses the same subnets on subinterfaces tagged
+// with VLAN 10 and 20.
+const (
+	plen4       = 30
+	pps         = 1000
+	ingressVLAN = 10
+	egressVLAN  = 20
+	lagName     = "lag"
+)
+
+var (
+	dutSrc = attrs.Attributes{Desc: "DUT to ATE ingress", IPv4: "192.0.2.1", IPv4Len: plen4}
+	ateSrc = attrs.Attributes{Name: "src", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen4}
+	dutDst = attrs.Attributes{Desc: "DUT to ATE egress", IPv4: "192.0.2.5", IPv4Len: plen4}
+	ateDst = attrs.Attributes{Name: "dst", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv4Len: plen4}
+
+	// bursts are sent in every case: minimum, typical and maximum
+	// untagged frames.
+	bursts = []ifcounters.Burst{
+		{Packets: 10000, PacketSize: 46},
+		{Packets: 10000, PacketSize: 494},
+		{Packets: 10000, PacketSize: 1482},
+	}
+)
+
+// testCase routes the bursts from an ingress to an egress interface of
+// the given kinds.
+type testCase struct {
+	desc            string
+	ingress, egress ifcounters.Kind
+}
+
+var cases = []testCase{
+	{desc: "Physical", ingress: ifcounters.Physical, egress: ifcounters.Physical},
+	{desc: "Subinterface", ingress: ifcounters.Subinterface, egress: ifcounters.Subinterface},
+	{desc: "LAG", ingress: ifcounters.Physical, egress: ifcounters.LAG},
+}
+
+// configureDUT configures the ingress and egress interfaces of the
+// case, and returns them.
+func (tc *testCase) configureDUT(t *testing.T, dut *ondatra.DUTDevice) (ingress, egress ifcounters.Ref) {
+	d := dut.Config()
+	p1, p2 := dut.Port(t, "port1"), dut.Port(t, "port2")
+
+	ingress = ifcounters.Ref{Kind: tc.ingress, Name: p1.Name()}
+	if tc.ingress == ifcounters.Subinterface {
+		i := (&attrs.Attributes{Desc: dutSrc.Desc}).NewInterface(p1.Name())
+		ifcounters.ConfigureSubinterface(i, ingressVLAN, ingressVLAN, &dutSrc)
+		d.Interface(p1.Name()).Replace(t, i)
+		ingress.Index = ingressVLAN
+	} else {
+		d.Interface(p1.Name()).Replace(t, dutSrc.NewInterface(p1.Name()))
+	}
+
+	switch tc.egress {
+	case ifcounters.Physical:
+		egress = ifcounters.Ref{Kind: tc.egress, Name: p2.Name()}
+		d.Interface(p2.Name()).Replace(t, dutDst.NewInterface(p2.Name()))
+	case ifcounters.Subinterface:
+		egress = ifcounters.Ref{Kind: tc.egress, Name: p2.Name(), Index: egressVLAN}
+		i := (&attrs.Attributes{Desc: dutDst.Desc}).NewInterface(p2.Name())
+		ifcounters.ConfigureSubinterface(i, egressVLAN, egressVLAN, &dutDst)
+		d.Interface(p2.Name()).Replace(t, i)
+	case ifcounters.LAG:
+		aggID := netutil.NextBundleInterface(t, dut)
+		egress = ifcounters.Ref{Kind: tc.egress, Name: aggID}
+		d.Interface(aggID).Replace(t, ifcounters.NewLAG(aggID, &dutDst))
+		for _, p := range []string{"port2", "port3"} {
+			name := dut.Port(t, p).Name()
+			d.Interface(name).Replace(t, ifcounters.NewMember(name, aggID))
+		}
+	}
+	for _, r := range []ifcounters.Ref{ingress, egress} {
+		dut.Telemetry().Interface(r.Name).OperStatus().Await(t, time.Minute, telemetry.Interface_OperStatus_UP)
+	}
+	return ingress, egress
+}
+
+// configureOTG returns the OTG configuration of the case.
+func (tc *testCase) configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	ports := []string{"port1", "port2"}
+	if tc.egress == ifcounters.LAG {
+		ports = append(ports, "port3")
+	}
+	for _, p := range ports {
+		config.Ports().Add().SetName(ate.Port(t, p).ID())
+	}
+
+	src := config.Devices().Add().SetName(ateSrc.Name).Ethernets().Add().
+		SetName(ateSrc.Name + ".eth").
+		SetPortName(ate.Port(t, "port1").ID()).
+		SetMac(ateSrc.MAC)
+	if tc.ingress == ifcounters.Subinterface {
+		src.Vlans().Add().SetName(ateSrc.Name + ".vlan").SetId(ingressVLAN)
+	}
+	src.Ipv4Addresses().Add().
+		SetName(ateSrc.Name + ".IPv4").
+		SetAddress(ateSrc.IPv4).
+		SetGateway(dutSrc.IPv4).
+		SetPrefix(int32(ateSrc.IPv4Len))
+
+	dst := config.Devices().Add().SetName(ateDst.Name).Ethernets().Add().
+		SetName(ateDst.Name + ".eth").
+		SetMac(ateDst.MAC)
+	switch tc.egress {
+	case ifcounters.LAG:
+		lag := config.Lags().Add().SetName(lagName)
+		lag.Protocol().Static().SetLagId(1)
+		for i, p := range []string{"port2", "port3"} {
+			lag.Ports().Add().SetPortName(ate.Port(t, p).ID()).Ethernet().
+				SetName(ateDst.Name + "." + p).
+				SetMac(fmt.Sprintf("02:00:03:01:01:%02x", i+1))
+		}
+		dst.Connection().SetLagName(lagName)
+	case ifcounters.Subinterface:
+		dst.SetPortName(ate.Port(t, "port2").ID())
+		dst.Vlans().Add().SetName(ateDst.Name + ".vlan").SetId(egressVLAN)
+	default:
+		dst.SetPortName(ate.Port(t, "port2").ID())
+	}
+	dst.Ipv4Addresses().Add().
+		SetName(ateDst.Name + ".IPv4").
+		SetAddress(ateDst.IPv4).
+		SetGateway(dutDst.IPv4).
+		SetPrefix(int32(ateDst.IPv4Len))
+
+	srcEP := ifcounters.Endpoint{Device: ateSrc.Name + ".IPv4", MAC: ateSrc.MAC, IPv4: ateSrc.IPv4}
+	if tc.ingress == ifcounters.Subinterface {
+		srcEP.VLAN = ingressVLAN
+	}
+	dstEP := ifcounters.Endpoint{Device: ateDst.Name + ".IPv4", MAC: ateDst.MAC, IPv4: ateDst.IPv4}
+	ifcounters.AddFlows(config, "counters", srcEP, dstEP, bursts, pps)
+	return config
+}
+
+// waitNeighbors waits for the OTG to resolve the DUT addresses.
+func waitNeighbors(t *testing.T, ate *ondatra.ATEDevice) {
+	for _, a := range []*attrs.Attributes{&ateSrc, &ateDst} {
+		ate.OTG().Telemetry().Interface(a.Name+".eth").Ipv4NeighborAny().LinkLayerAddress().Watch(t, time.Minute, func(val *otgtelemetry.QualifiedString) bool {
+			return val.IsPresent()
+		}).Await(t)
+	}
+}
+
+// sendBursts sends the bursts, and checks that the OTG sent and
+// received exactly their packets.
+func sendBursts(t *testing.T, ate *ondatra.ATEDevice, config gosnappi.Config) {
+	otg := ate.OTG()
+	otg.StartTraffic(t)
+	var timeout time.Duration
+	for _, b := range bursts {
+		timeout += time.Duration(b.Packets/pps+1) * time.Second
+	}
+	for _, f := range config.Flows().Items() {
+		otg.Telemetry().Flow(f.Name()).Counters().OutPkts().Watch(t, timeout+time.Minute, func(val *otgtelemetry.QualifiedUint64) bool {
+			return val.IsPresent() && val.Val(t) >= uint64(f.Duration().FixedPackets().Packets())
+		}).Await(t)
+	}
+	otg.StopTraffic(t)
+	otgutils.LogFlowMetrics(t, otg, config)
+
+	for _, f := range config.Flows().Items() {
+		want := uint64(f.Duration().FixedPackets().Packets())
+		c := otg.Telemetry().Flow(f.Name()).Counters()
+		if tx, rx := c.OutPkts().Get(t), c.InPkts().Get(t); tx != want || rx != want {
+			t.Errorf("Flow %s sent %d and received %d packets, want %d", f.Name(), tx, rx, want)
+		}
+	}
+}
+
+// TestCountersAccuracy sends an exact number of packets of known sizes
+// through interfaces of every kind, and checks that their counters
+// increase by exactly as much.
+//
+// telemetry_path:/interfaces/interface/state/counters/in-unicast-pkts
+// telemetry_path:/interfaces/interface/state/counters/in-octets
+// telemetry_path:/interfaces/interface/state/counters/in-errors
+// telemetry_path:/interfaces/interface/state/counters/out-unicast-pkts
+// telemetry_path:/interfaces/interface/state/counters/out-octets
+// telemetry_path:/interfaces/interface/state/counters/out-errors
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/state/counters/in-unicast-pkts
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/state/counters/in-octets
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/state/counters/out-unicast-pkts
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/state/counters/out-octets
+func TestCountersAccuracy(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			ingress, egress := tc.configureDUT(t, dut)
+			config := tc.configureOTG(t, ate)
+			ate.OTG().PushConfig(t, config)
+			ate.OTG().StartProtocols(t)
+			waitNeighbors(t, ate)
+
+			before := ifcounters.Read(t, dut, ingress, egress)
+			sendBursts(t, ate, config)
+			time.Sleep(*updateDelay)
+			got := ifcounters.Read(t, dut, ingress, egress).Sub(before)
+			want := ifcounters.Expect(bursts, tc.ingress, tc.egress)
+			t.Logf("Counters of %v -> %v increased by %+v, want %+v", ingress, egress, got, want)
+			for _, err := range ifcounters.Check(got, want, *tolerance) {
+				t.Errorf("%v -> %v: %v", ingress, egress, err)
+			}
+		})
+	}
+}
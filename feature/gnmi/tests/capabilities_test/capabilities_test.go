@@ -0,0 +1,83 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package capabilities_test implements gNMI-1.25: Capabilities and Model
+// Versions.
+package capabilities_test
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gnmicap"
+	"github.com/openconfig/ondatra"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+var (
+	requiredModels = flag.String("required_models",
+		"openconfig-interfaces,openconfig-if-ip,openconfig-network-instance,openconfig-platform,openconfig-system,openconfig-routing-policy,openconfig-acl",
+		"Comma separated models the DUT must advertise, each optionally followed by @ and its minimum version, e.g. openconfig-interfaces@2.4.1.")
+	requiredEncodings = flag.String("required_encodings", "JSON_IETF",
+		"Comma separated gNMI encodings the DUT must advertise.")
+	minGNMIVersion = flag.String("min_gnmi_version", "0.7.0",
+		"Minimum gNMI version the DUT must advertise.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// requirements returns the capabilities required by the flags.
+func requirements(t *testing.T) gnmicap.Requirements {
+	t.Helper()
+	models, err := gnmicap.ParseModels(*requiredModels)
+	if err != nil {
+		t.Fatalf("Invalid -required_models: %v", err)
+	}
+	r := gnmicap.Requirements{Models: models, GNMIVersion: *minGNMIVersion}
+	for _, name := range strings.Split(*requiredEncodings, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		e, ok := gpb.Encoding_value[name]
+		if !ok {
+			t.Fatalf("Invalid -required_encodings: unknown encoding %s", name)
+		}
+		r.Encodings = append(r.Encodings, gpb.Encoding(e))
+	}
+	return r
+}
+
+// TestCapabilities verifies that the DUT advertises the required gNMI
+// version, encodings and models, at their minimum versions, and that each
+// OpenConfig model it advertises has a version.
+func TestCapabilities(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	r := requirements(t)
+	caps := gnmicap.Get(t, dut)
+	t.Logf("%s advertises gNMI %s, encodings %v and %d models", dut.Name(), caps.GetGNMIVersion(), caps.GetSupportedEncodings(), len(caps.GetSupportedModels()))
+
+	for _, msg := range r.Unmet(caps) {
+		t.Errorf("Capabilities of %s: %s", dut.Name(), msg)
+	}
+	for _, m := range caps.GetSupportedModels() {
+		if strings.HasPrefix(m.GetName(), "openconfig-") && m.GetVersion() == "" {
+			t.Errorf("Model %s advertised without version", m.GetName())
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dialout_test implements gNMI-1.12.
+package dialout_test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/dialout"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+var (
+	serverHost      = flag.String("dialout_server_host", "", "Address of the test host as reached by the DUT, where the tunnel server listens.  TestDialOut is skipped if empty.")
+	serverPort      = flag.Int("dialout_server_port", 4321, "Port on which the tunnel server listens.")
+	configFile      = flag.String("dialout_config_file", "", "Vendor configuration of the DUT to dial out to the tunnel server, with the variables tunnel_server, tunnel_port and tunnel_target.")
+	certFile        = flag.String("dialout_cert_file", "", "Certificate of the tunnel server.  The tunnel server serves plaintext if empty.")
+	keyFile         = flag.String("dialout_key_file", "", "Key of the certificate of the tunnel server.")
+	username        = flag.String("dialout_username", "", "Username of the gNMI requests to the DUT over the tunnel.")
+	password        = flag.String("dialout_password", "", "Password of the gNMI requests to the DUT over the tunnel.")
+	registerTimeout = flag.Duration("dialout_register_timeout", 2*time.Minute, "Time given to the DUT to register with the tunnel server.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+var hostnamePath = &gpb.Path{
+	Elem: []*gpb.PathElem{{Name: "system"}, {Name: "state"}, {Name: "hostname"}},
+}
+
+// hostname returns the hostname in the notifications.
+func hostname(notifs []*gpb.Notification) (string, error) {
+	for _, n := range notifs {
+		for _, u := range n.GetUpdate() {
+			elems := append(append([]*gpb.PathElem(nil), n.GetPrefix().GetElem()...), u.GetPath().GetElem()...)
+			if len(elems) == 0 || elems[len(elems)-1].GetName() != "hostname" {
+				continue
+			}
+			switch v := u.GetVal().GetValue().(type) {
+			case *gpb.TypedValue_StringVal:
+				return v.StringVal, nil
+			case *gpb.TypedValue_JsonIetfVal:
+				var s string
+				err := json.Unmarshal(v.JsonIetfVal, &s)
+				return s, err
+			case *gpb.TypedValue_JsonVal:
+				var s string
+				err := json.Unmarshal(v.JsonVal, &s)
+				return s, err
+			}
+			return "", fmt.Errorf("hostname of unexpected type: %v", u.GetVal())
+		}
+	}
+	return "", fmt.Errorf("no hostname among %d notifications", len(notifs))
+}
+
+// TestDialOut verifies that the DUT dials out to a tunnel server
+// embedded in the test, registers itself as a gNMI target, and serves
+// the gNMI subscriptions of the test over the tunnel.
+func TestDialOut(t *testing.T) {
+	if *serverHost == "" {
+		t.Skip("No -dialout_server_host for the DUT to dial out to")
+	}
+	if *configFile == "" {
+		t.Fatal("--dialout_config_file flag must be set.")
+	}
+	var opts []grpc.ServerOption
+	if *certFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(*certFile, *keyFile)
+		if err != nil {
+			t.Fatalf("Cannot load the tunnel server certificate: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	c, err := dialout.NewCollector(fmt.Sprintf(":%d", *serverPort), opts...)
+	if err != nil {
+		t.Fatalf("Cannot start the tunnel server: %v", err)
+	}
+	defer c.Close()
+
+	dut := ondatra.DUT(t, "dut")
+	target := dut.Name()
+	dialout.Configure(t, dut, *configFile, *serverHost, *serverPort, target)
+	if err := c.AwaitTarget(target, *registerTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	conn, err := c.Dial(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if *username != "" {
+		ctx = dialout.WithCredentials(ctx, *username, *password)
+	}
+
+	notifs, err := dialout.Collect(ctx, gpb.NewGNMIClient(conn), hostnamePath)
+	if err != nil {
+		t.Fatalf("Subscription over the tunnel got error: %v", err)
+	}
+	got, err := hostname(notifs)
+	if err != nil {
+		t.Fatalf("Subscription over the tunnel: %v", err)
+	}
+	if want := dut.Telemetry().System().Hostname().Get(t); got != want {
+		t.Errorf("Hostname over the tunnel got %q, want %q as dialed in", got, want)
+	}
+}
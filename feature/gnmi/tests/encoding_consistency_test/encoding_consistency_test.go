@@ -0,0 +1,63 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package encoding_consistency_test implements gNMI-1.26: Telemetry:
+// Encoding Consistency.
+package encoding_consistency_test
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/dualenc"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gnmicap"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+var paths = flag.String("encoding_consistency_paths", "/interfaces,/components,/system",
+	"Comma separated paths whose values are compared between encodings.")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// TestEncodingConsistency subscribes once to the paths in JSON_IETF, PROTO
+// and JSON_IETF again, and verifies that the values of the leaves are the
+// same in both encodings.  Values which change between the two JSON_IETF
+// samples, such as counters, are not compared.
+//
+// telemetry_path:/interfaces
+// telemetry_path:/components
+// telemetry_path:/system
+func TestEncodingConsistency(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	gnmicap.SkipUnless(t, dut, gnmicap.Requirements{
+		Encodings: []gpb.Encoding{gpb.Encoding_JSON_IETF, gpb.Encoding_PROTO},
+	})
+
+	var ps []*gpb.Path
+	for _, s := range strings.Split(*paths, ",") {
+		p, err := ygot.StringToStructuredPath(s)
+		if err != nil {
+			t.Fatalf("Invalid --encoding_consistency_paths %s: %v", s, err)
+		}
+		ps = append(ps, p)
+	}
+	dualenc.Check(t, dut, ps...)
+}
@@ -0,0 +1,283 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ordering_test implements gNMI-1.24: Leaf-list and Ordered List
+// Handling.
+package ordering_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gnmicap"
+	"github.com/openconfig/featureprofiles/internal/ordering"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+const (
+	communitySetName = "GNMI-1.24-COMMUNITIES"
+	policyName       = "GNMI-1.24-POLICY"
+)
+
+// requirements are the gNMI capabilities the tests use: the models of the
+// routing policies, set in JSON_IETF.
+var requirements = gnmicap.Requirements{
+	Models:    map[string]string{"openconfig-routing-policy": "", "openconfig-bgp-policy": ""},
+	Encodings: []gpb.Encoding{gpb.Encoding_JSON_IETF},
+}
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// set sends a SetRequest replacing or updating the path with the JSON
+// value, or deleting it if the value is nil.
+func set(t *testing.T, dut *ondatra.DUTDevice, replace bool, path *gpb.Path, v interface{}) {
+	t.Helper()
+	req := &gpb.SetRequest{}
+	if v == nil {
+		req.Delete = []*gpb.Path{path}
+	} else {
+		val, err := ordering.JSON(v)
+		if err != nil {
+			t.Fatalf("Cannot marshal %v: %v", v, err)
+		}
+		u := []*gpb.Update{{Path: path, Val: val}}
+		if replace {
+			req.Replace = u
+		} else {
+			req.Update = u
+		}
+	}
+	t.Logf("SetRequest: %v", req)
+	if _, err := dut.RawAPIs().GNMI().Default(t).Set(context.Background(), req); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+}
+
+// path returns the gNMI path of the string.
+func path(t *testing.T, format string, a ...interface{}) *gpb.Path {
+	t.Helper()
+	p, err := ygot.StringToStructuredPath(fmt.Sprintf(format, a...))
+	if err != nil {
+		t.Fatalf("Invalid path: %v", err)
+	}
+	return p
+}
+
+// quote returns the JSON text of the strings, as compared by ordering.
+func quote(values ...string) []string {
+	var q []string
+	for _, v := range values {
+		q = append(q, fmt.Sprintf("%q", v))
+	}
+	return q
+}
+
+// TestLeafList replaces, updates and deletes the members of a community
+// set, a leaf-list ordered by the system, and verifies its values after
+// each operation.
+//
+// config_path:/routing-policy/defined-sets/bgp-defined-sets/community-sets/community-set/config/community-member
+func TestLeafList(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	gnmicap.SkipUnless(t, dut, requirements)
+	ctx := context.Background()
+	setPath := path(t, "/routing-policy/defined-sets/bgp-defined-sets/community-sets/community-set[community-set-name=%s]", communitySetName)
+	configPath := path(t, "/routing-policy/defined-sets/bgp-defined-sets/community-sets/community-set[community-set-name=%s]/config", communitySetName)
+	membersPath := path(t, "/routing-policy/defined-sets/bgp-defined-sets/community-sets/community-set[community-set-name=%s]/config/community-member", communitySetName)
+	defer dut.Config().RoutingPolicy().DefinedSets().BgpDefinedSets().CommunitySet(communitySetName).Delete(t)
+
+	var want []string
+	for _, tc := range []struct {
+		desc    string
+		replace bool
+		path    *gpb.Path
+		value   func(members ...string) interface{}
+		members []string
+		want    func(cur, members []string) []string
+	}{{
+		desc:    "Replace of the community set",
+		replace: true,
+		path:    setPath,
+		value: func(members ...string) interface{} {
+			return map[string]interface{}{
+				"openconfig-bgp-policy:community-set-name": communitySetName,
+				"openconfig-bgp-policy:config": map[string]interface{}{
+					"community-set-name": communitySetName,
+					"community-member":   members,
+				},
+			}
+		},
+		members: []string{"64496:1", "64496:2"},
+		want:    func(_, members []string) []string { return members },
+	}, {
+		desc: "Update of the config of the community set",
+		path: configPath,
+		value: func(members ...string) interface{} {
+			return map[string]interface{}{"openconfig-bgp-policy:community-member": members}
+		},
+		members: []string{"64496:2", "64496:3"},
+		want:    updated,
+	}, {
+		desc:    "Update of the leaf-list",
+		path:    membersPath,
+		value:   func(members ...string) interface{} { return members },
+		members: []string{"64496:4"},
+		want:    updated,
+	}, {
+		desc:    "Replace of the leaf-list",
+		replace: true,
+		path:    membersPath,
+		value:   func(members ...string) interface{} { return members },
+		members: []string{"64496:5", "64496:1"},
+		want:    func(_, members []string) []string { return members },
+	}, {
+		desc: "Delete of the leaf-list",
+		path: membersPath,
+		want: func(_, _ []string) []string { return nil },
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var v interface{}
+			if tc.value != nil {
+				v = tc.value(tc.members...)
+			}
+			set(t, dut, tc.replace, tc.path, v)
+			want = tc.want(want, quote(tc.members...))
+
+			raw, err := ordering.Get(ctx, dut.RawAPIs().GNMI().Default(t), setPath)
+			if err != nil {
+				t.Fatalf("Cannot get the community set: %v", err)
+			}
+			got, err := ordering.LeafList(raw, "config", "community-member")
+			if err != nil {
+				t.Fatalf("Cannot read the community members: %v", err)
+			}
+			if err := ordering.CheckUnordered(got, want); err != nil {
+				t.Errorf("Community members: %v", err)
+			}
+			// The next operations start from the values of the DUT.
+			want = got
+		})
+	}
+}
+
+// updated returns the values of a leaf-list updated with members, merged
+// unless the DUT replaces leaf-lists on update.
+func updated(cur, members []string) []string {
+	if *deviations.LeafListUpdateReplaces {
+		return members
+	}
+	return ordering.Merge(cur, members)
+}
+
+// statement returns the JSON value of a policy statement with the result.
+func statement(name, result string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":    name,
+		"config":  map[string]interface{}{"name": name},
+		"actions": map[string]interface{}{"config": map[string]interface{}{"policy-result": result}},
+	}
+}
+
+// statements returns the JSON value of the statements container of a
+// policy with the statements in order, all accepting routes.
+func statements(names ...string) map[string]interface{} {
+	var list []interface{}
+	for _, n := range names {
+		list = append(list, statement(n, "ACCEPT_ROUTE"))
+	}
+	return map[string]interface{}{"openconfig-routing-policy:statement": list}
+}
+
+// TestOrderedList replaces, updates and deletes the statements of a
+// routing policy, a list ordered by the user, and verifies their order
+// after each operation.  The names of the statements do not sort in the
+// order they are configured in.
+//
+// config_path:/routing-policy/policy-definitions/policy-definition/statements/statement/config/name
+// config_path:/routing-policy/policy-definitions/policy-definition/statements/statement/actions/config/policy-result
+func TestOrderedList(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	gnmicap.SkipUnless(t, dut, requirements)
+	ctx := context.Background()
+	policyPath := path(t, "/routing-policy/policy-definitions/policy-definition[name=%s]", policyName)
+	statementsPath := path(t, "/routing-policy/policy-definitions/policy-definition[name=%s]/statements", policyName)
+	statementPath := func(name string) *gpb.Path {
+		return path(t, "/routing-policy/policy-definitions/policy-definition[name=%s]/statements/statement[name=%s]", policyName, name)
+	}
+	defer dut.Config().RoutingPolicy().PolicyDefinition(policyName).Delete(t)
+
+	for _, tc := range []struct {
+		desc    string
+		replace bool
+		path    *gpb.Path
+		value   interface{}
+		want    []string
+	}{{
+		desc:    "Replace of the policy",
+		replace: true,
+		path:    policyPath,
+		value: map[string]interface{}{
+			"openconfig-routing-policy:name":       policyName,
+			"openconfig-routing-policy:config":     map[string]interface{}{"name": policyName},
+			"openconfig-routing-policy:statements": statements("zeta", "alpha", "mu"),
+		},
+		want: []string{"zeta", "alpha", "mu"},
+	}, {
+		desc:    "Replace of the statements in another order",
+		replace: true,
+		path:    statementsPath,
+		value:   statements("alpha", "zeta", "mu"),
+		want:    []string{"alpha", "zeta", "mu"},
+	}, {
+		desc:  "Update with a new statement",
+		path:  statementsPath,
+		value: statements("beta"),
+		want:  []string{"alpha", "zeta", "mu", "beta"},
+	}, {
+		desc: "Update of an existing statement",
+		path: statementPath("zeta"),
+		value: map[string]interface{}{
+			"openconfig-routing-policy:actions": map[string]interface{}{"config": map[string]interface{}{"policy-result": "REJECT_ROUTE"}},
+		},
+		want: []string{"alpha", "zeta", "mu", "beta"},
+	}, {
+		desc: "Delete of a statement",
+		path: statementPath("zeta"),
+		want: []string{"alpha", "mu", "beta"},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			set(t, dut, tc.replace, tc.path, tc.value)
+
+			raw, err := ordering.Get(ctx, dut.RawAPIs().GNMI().Default(t), policyPath)
+			if err != nil {
+				t.Fatalf("Cannot get the policy: %v", err)
+			}
+			got, err := ordering.Keys(raw, "name", "statements", "statement")
+			if err != nil {
+				t.Fatalf("Cannot read the statements: %v", err)
+			}
+			if err := ordering.CheckOrdered(got, quote(tc.want...)); err != nil {
+				t.Errorf("Statements of %s: %v", policyName, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package replace_roundtrip_test implements gNMI-1.27: Set Replace Round
+// Trip.
+package replace_roundtrip_test
+
+import (
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/configdiff"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+const (
+	description = "gNMI-1.27 replace round trip"
+	aclName     = "GNMI-1.27-ACL"
+	prefixSet   = "GNMI-1.27-PREFIXES"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// TestReplaceRoundTrip replaces subtrees of the configuration, gets each
+// back, and verifies that the DUT kept every leaf sent, with the value
+// sent.  It restores the configuration after, and verifies that none is
+// left behind.
+//
+// config_path:/interfaces/interface/config/description
+// config_path:/interfaces/interface/config/type
+// config_path:/interfaces/interface/config/enabled
+// config_path:/interfaces/interface/config/mtu
+// config_path:/interfaces/interface/subinterfaces/subinterface/ipv4/addresses/address/config/prefix-length
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/config/description
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/ipv4/config/source-address
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/ipv4/config/destination-address
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/actions/config/forwarding-action
+// config_path:/routing-policy/defined-sets/prefix-sets/prefix-set/config/mode
+// config_path:/routing-policy/defined-sets/prefix-sets/prefix-set/prefixes/prefix/config/ip-prefix
+func TestReplaceRoundTrip(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	p1 := dut.Port(t, "port1").Name()
+	before := configdiff.Take(t, dut)
+	defer restore(t, dut, before, p1)
+
+	t.Run("Interface", func(t *testing.T) {
+		i := &telemetry.Interface{
+			Name:        ygot.String(p1),
+			Description: ygot.String(description),
+			Type:        telemetry.IETFInterfaces_InterfaceType_ethernetCsmacd,
+			Enabled:     ygot.Bool(true),
+			Mtu:         ygot.Uint16(1500),
+		}
+		i.GetOrCreateSubinterface(0).GetOrCreateIpv4().GetOrCreateAddress("192.0.2.1").PrefixLength = ygot.Uint8(30)
+		dut.Config().Interface(p1).Replace(t, i)
+		configdiff.VerifyReplace(t, dut, dut.Config().Interface(p1), i)
+	})
+
+	t.Run("ACL", func(t *testing.T) {
+		set := &telemetry.Acl_AclSet{
+			Name: ygot.String(aclName),
+			Type: telemetry.Acl_ACL_TYPE_ACL_IPV4,
+		}
+		for i, src := range []string{"198.51.100.0/24", "203.0.113.0/24"} {
+			e := set.GetOrCreateAclEntry(uint32(10 * (i + 1)))
+			e.Description = ygot.String(description)
+			e.GetOrCreateIpv4().SourceAddress = ygot.String(src)
+			e.GetOrCreateIpv4().DestinationAddress = ygot.String("192.0.2.0/30")
+			e.GetOrCreateActions().ForwardingAction = telemetry.Acl_FORWARDING_ACTION_ACCEPT
+		}
+		path := dut.Config().Acl().AclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4)
+		path.Replace(t, set)
+		configdiff.VerifyReplace(t, dut, path, set)
+	})
+
+	t.Run("PrefixSet", func(t *testing.T) {
+		set := &telemetry.RoutingPolicy_DefinedSets_PrefixSet{
+			Name: ygot.String(prefixSet),
+			Mode: telemetry.PrefixSet_Mode_IPV4,
+		}
+		set.GetOrCreatePrefix("198.51.100.0/24", "exact")
+		set.GetOrCreatePrefix("203.0.113.0/24", "24..32")
+		path := dut.Config().RoutingPolicy().DefinedSets().PrefixSet(prefixSet)
+		path.Replace(t, set)
+		configdiff.VerifyReplace(t, dut, path, set)
+	})
+}
+
+// restore restores the configuration of the interface from the snapshot,
+// deletes the ACL and prefix sets, and verifies that the configuration is
+// the one of the snapshot.
+func restore(t *testing.T, dut *ondatra.DUTDevice, before *telemetry.Device, name string) {
+	t.Helper()
+	if i := before.GetInterface(name); i != nil {
+		dut.Config().Interface(name).Replace(t, i)
+	} else {
+		dut.Config().Interface(name).Delete(t)
+	}
+	dut.Config().Acl().AclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4).Delete(t)
+	dut.Config().RoutingPolicy().DefinedSets().PrefixSet(prefixSet).Delete(t)
+	configdiff.CheckUnchanged(t, dut, before)
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package set_chunking_test implements gNMI-1.23: Set Payload Size Limits.
+package set_chunking_test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gnmicap"
+	"github.com/openconfig/featureprofiles/internal/setchunk"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var aclEntries = flag.Int("set_chunking_acl_entries", 20000,
+	"Number of entries of the ACL set, large enough for its SetRequest to exceed the limit of the device.")
+
+const aclName = "CHUNKED-ACL"
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// aclSet returns an ACL set with n entries, each accepting a distinct
+// pair of test addresses.
+func aclSet(n int) *telemetry.Acl_AclSet {
+	set := &telemetry.Acl_AclSet{
+		Name: ygot.String(aclName),
+		Type: telemetry.Acl_ACL_TYPE_ACL_IPV4,
+	}
+	for i := 0; i < n; i++ {
+		seq := uint32(i + 1)
+		e := set.GetOrCreateAclEntry(seq)
+		e.Description = ygot.String(fmt.Sprintf("gNMI-1.23 entry %d", seq))
+		ipv4 := e.GetOrCreateIpv4()
+		ipv4.SourceAddress = ygot.String(fmt.Sprintf("198.51.100.%d/32", i%256))
+		ipv4.DestinationAddress = ygot.String(fmt.Sprintf("203.0.113.%d/32", i/256%256))
+		e.GetOrCreateActions().ForwardingAction = telemetry.Acl_FORWARDING_ACTION_ACCEPT
+	}
+	return set
+}
+
+// replaceRequest returns the SetRequest replacing the ACL set.
+func replaceRequest(set *telemetry.Acl_AclSet) (*gpb.SetRequest, error) {
+	path, err := ygot.StringToStructuredPath(fmt.Sprintf("/acl/acl-sets/acl-set[name=%s][type=ACL_IPV4]", set.GetName()))
+	if err != nil {
+		return nil, err
+	}
+	v, err := ygot.ConstructIETFJSON(set, &ygot.RFC7951JSONConfig{AppendModuleName: true, PreferShadowPath: true})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal the ACL set: %w", err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal the ACL set: %w", err)
+	}
+	return &gpb.SetRequest{Replace: []*gpb.Update{{
+		Path: path,
+		Val:  &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: b}},
+	}}}, nil
+}
+
+// verifyEntries verifies that the DUT has the entries of the ACL set.
+func verifyEntries(t *testing.T, dut *ondatra.DUTDevice, want *telemetry.Acl_AclSet) {
+	t.Helper()
+	got := dut.Config().Acl().AclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4).Get(t)
+	if g, w := len(got.AclEntry), len(want.AclEntry); g != w {
+		t.Errorf("ACL set %s got %d entries, want %d", aclName, g, w)
+	}
+	for seq, w := range want.AclEntry {
+		e := got.GetAclEntry(seq)
+		if e == nil {
+			t.Errorf("ACL set %s has no entry %d", aclName, seq)
+			continue
+		}
+		if e.GetDescription() != w.GetDescription() || e.GetIpv4().GetSourceAddress() != w.GetIpv4().GetSourceAddress() {
+			t.Errorf("Entry %d of ACL set %s got %q from %s, want %q from %s", seq, aclName,
+				e.GetDescription(), e.GetIpv4().GetSourceAddress(), w.GetDescription(), w.GetIpv4().GetSourceAddress())
+		}
+	}
+}
+
+// TestSetChunking replaces an ACL set too large for a single SetRequest
+// within the limit of the DUT, first unsplit, then split by setchunk, and
+// verifies that the DUT either accepts or cleanly rejects the former, and
+// accepts the latter with all the entries.
+//
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/config/description
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/ipv4/config/source-address
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/ipv4/config/destination-address
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/actions/config/forwarding-action
+func TestSetChunking(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	gnmicap.SkipUnless(t, dut, gnmicap.Requirements{
+		Models:    map[string]string{"openconfig-acl": ""},
+		Encodings: []gpb.Encoding{gpb.Encoding_JSON_IETF},
+	})
+	gnmi := dut.RawAPIs().GNMI().Default(t)
+	ctx := context.Background()
+	config := dut.Config().Acl().AclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4)
+
+	set := aclSet(*aclEntries)
+	req, err := replaceRequest(set)
+	if err != nil {
+		t.Fatalf("Cannot build the SetRequest of the ACL set: %v", err)
+	}
+	limit := setchunk.Limit()
+	t.Logf("SetRequest of %d entries: %d bytes, limit %d bytes", len(set.AclEntry), proto.Size(req), limit)
+
+	t.Run("Unsplit", func(t *testing.T) {
+		_, err := gnmi.Set(ctx, req)
+		switch {
+		case err == nil:
+			t.Logf("The DUT accepted the SetRequest of %d bytes", proto.Size(req))
+			verifyEntries(t, dut, set)
+			config.Delete(t)
+		case status.Code(err) == codes.ResourceExhausted:
+			if lower, ok := setchunk.LimitFromError(err); ok {
+				t.Logf("The DUT rejected the SetRequest, advertising a limit of %d bytes: %v", lower, err)
+			} else {
+				t.Logf("The DUT rejected the SetRequest: %v", err)
+			}
+		default:
+			t.Errorf("Set got error %v, want success or %v", err, codes.ResourceExhausted)
+		}
+	})
+
+	t.Run("Split", func(t *testing.T) {
+		reqs, err := setchunk.Split(req, limit)
+		if err != nil {
+			t.Fatalf("Cannot split the SetRequest: %v", err)
+		}
+		for i, r := range reqs {
+			if n := proto.Size(r); n > limit {
+				t.Errorf("SetRequest %d got %d bytes, want at most %d", i, n, limit)
+			}
+		}
+		if proto.Size(req) > limit && len(reqs) < 2 {
+			t.Errorf("Split got %d SetRequests, want at least 2", len(reqs))
+		}
+
+		resps, err := setchunk.Set(ctx, gnmi, req, limit)
+		if err != nil {
+			t.Fatalf("Set of the split SetRequest failed: %v", err)
+		}
+		defer config.Delete(t)
+		t.Logf("The DUT accepted %d SetRequests", len(resps))
+		verifyEntries(t, dut, set)
+	})
+}
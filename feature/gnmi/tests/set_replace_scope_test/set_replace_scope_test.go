@@ -0,0 +1,134 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package set_replace_scope_test implements gNMI-1.21: Set Replace Scope.
+package set_replace_scope_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/feature/device"
+	"github.com/openconfig/featureprofiles/feature/lldp"
+	"github.com/openconfig/featureprofiles/internal/configdiff"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/yang/fpoc"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	intf "github.com/openconfig/featureprofiles/feature/interface/singleton"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+const description = "gNMI-1.21 replace scope"
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// TestReplaceScope replaces the configuration of an interface, and
+// verifies from snapshots of the full configuration that only the
+// interface changed, and that restoring it leaves no configuration behind.
+//
+// config_path:/interfaces/interface/config/description
+// config_path:/interfaces/interface/config/enabled
+// config_path:/interfaces/interface/config/type
+func TestReplaceScope(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	p1 := dut.Port(t, "port1").Name()
+	subtree := "/interfaces/interface[name=" + p1 + "]"
+	before := configdiff.Take(t, dut)
+
+	t.Run("Replace", func(t *testing.T) {
+		i := &telemetry.Interface{
+			Name:        ygot.String(p1),
+			Description: ygot.String(description),
+			Type:        telemetry.IETFInterfaces_InterfaceType_ethernetCsmacd,
+			Enabled:     ygot.Bool(true),
+		}
+		dut.Config().Interface(p1).Replace(t, i)
+		configdiff.VerifyReplace(t, dut, dut.Config().Interface(p1), i)
+
+		changes, err := configdiff.Diff(before, configdiff.Take(t, dut))
+		if err != nil {
+			t.Fatalf("Cannot diff the configuration: %v", err)
+		}
+		t.Logf("Configuration changes, -before, +after:\n%s", configdiff.Format(changes))
+		if out := configdiff.Outside(changes, subtree); len(out) > 0 {
+			t.Errorf("Replace of %s changed the configuration outside of it:\n%s", subtree, configdiff.Format(out))
+		}
+		if got := dut.Config().Interface(p1).Description().Get(t); got != description {
+			t.Errorf("Description got %q, want %q", got, description)
+		}
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		restore(t, dut, before, p1)
+	})
+}
+
+// restore restores the configuration of an interface from a snapshot, and
+// verifies that the configuration is the one of the snapshot.
+func restore(t *testing.T, dut *ondatra.DUTDevice, before *telemetry.Device, name string) {
+	t.Helper()
+	if i := before.GetInterface(name); i != nil {
+		dut.Config().Interface(name).Replace(t, i)
+	} else {
+		dut.Config().Interface(name).Delete(t)
+	}
+	configdiff.CheckUnchanged(t, dut, before)
+}
+
+// TestScopedReplace builds the configuration of the interface and of LLDP
+// at once, and replaces the interface only, from a SetRequest scoped to
+// its subtree.  It verifies from snapshots of the full configuration that
+// LLDP was not configured.
+//
+// config_path:/interfaces/interface/config/description
+// config_path:/lldp/config/enabled
+func TestScopedReplace(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	p1 := dut.Port(t, "port1").Name()
+	subtree := "/interfaces/interface[name=" + p1 + "]"
+	before := configdiff.Take(t, dut)
+
+	d := device.New()
+	if err := d.WithFeature(intf.New(p1, description, fpoc.IETFInterfaces_InterfaceType_ethernetCsmacd)); err != nil {
+		t.Fatalf("Cannot configure the interface: %v", err)
+	}
+	if err := d.WithFeature(lldp.New().EnableInterface(p1)); err != nil {
+		t.Fatalf("Cannot configure LLDP: %v", err)
+	}
+	req, err := d.ScopedReplaceRequest(subtree)
+	if err != nil {
+		t.Fatalf("Cannot build the SetRequest of %s: %v", subtree, err)
+	}
+	t.Logf("SetRequest: %v", req)
+	if _, err := dut.RawAPIs().GNMI().Default(t).Set(context.Background(), req); err != nil {
+		t.Fatalf("Set of %s failed: %v", subtree, err)
+	}
+	defer restore(t, dut, before, p1)
+
+	changes, err := configdiff.Diff(before, configdiff.Take(t, dut))
+	if err != nil {
+		t.Fatalf("Cannot diff the configuration: %v", err)
+	}
+	t.Logf("Configuration changes, -before, +after:\n%s", configdiff.Format(changes))
+	if out := configdiff.Outside(changes, subtree); len(out) > 0 {
+		t.Errorf("Scoped replace of %s changed the configuration outside of it:\n%s", subtree, configdiff.Format(out))
+	}
+	if got := dut.Config().Interface(p1).Description().Get(t); got != description {
+		t.Errorf("Description got %q, want %q", got, description)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package set_transaction_test implements gNMI-1.28: Set Transactionality.
+package set_transaction_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/configdiff"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+	"google.golang.org/grpc/status"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+const (
+	description = "gNMI-1.28 transaction"
+	aclName     = "GNMI-1.28-ACL"
+	prefixSet   = "GNMI-1.28-PREFIXES"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// mustPath returns the structured path of a string path.
+func mustPath(t *testing.T, s string) *gpb.Path {
+	t.Helper()
+	p, err := ygot.StringToStructuredPath(s)
+	if err != nil {
+		t.Fatalf("Invalid path %s: %v", s, err)
+	}
+	return p
+}
+
+// jsonUpdate returns the update of a path to a JSON_IETF value.
+func jsonUpdate(t *testing.T, path string, v interface{}) *gpb.Update {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Cannot marshal the value of %s: %v", path, err)
+	}
+	return &gpb.Update{
+		Path: mustPath(t, path),
+		Val:  &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: b}},
+	}
+}
+
+// structUpdate returns the update of a path to the JSON_IETF value of a
+// struct.
+func structUpdate(t *testing.T, path string, s ygot.GoStruct) *gpb.Update {
+	t.Helper()
+	v, err := ygot.ConstructIETFJSON(s, &ygot.RFC7951JSONConfig{AppendModuleName: true, PreferShadowPath: true})
+	if err != nil {
+		t.Fatalf("Cannot marshal the value of %s: %v", path, err)
+	}
+	return jsonUpdate(t, path, v)
+}
+
+// aclSet returns an IPv4 ACL set with an entry accepting 198.51.100.0/24.
+func aclSet() *telemetry.Acl_AclSet {
+	set := &telemetry.Acl_AclSet{
+		Name: ygot.String(aclName),
+		Type: telemetry.Acl_ACL_TYPE_ACL_IPV4,
+	}
+	e := set.GetOrCreateAclEntry(10)
+	e.Description = ygot.String(description)
+	e.GetOrCreateIpv4().SourceAddress = ygot.String("198.51.100.0/24")
+	e.GetOrCreateActions().ForwardingAction = telemetry.Acl_FORWARDING_ACTION_ACCEPT
+	return set
+}
+
+// TestSetTransaction sends SetRequests with valid operations and one
+// invalid operation, and verifies from snapshots of the full configuration
+// that the DUT rejects each request as a whole, without applying any of its
+// operations.
+//
+// config_path:/interfaces/interface/config/description
+// config_path:/interfaces/interface/config/mtu
+// config_path:/acl/acl-sets/acl-set/acl-entries/acl-entry/config/description
+// config_path:/routing-policy/defined-sets/prefix-sets/prefix-set/prefixes/prefix/config/ip-prefix
+func TestSetTransaction(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	p1 := dut.Port(t, "port1").Name()
+	intf := fmt.Sprintf("/interfaces/interface[name=%s]", p1)
+	acl := fmt.Sprintf("/acl/acl-sets/acl-set[name=%s][type=ACL_IPV4]", aclName)
+	ps := fmt.Sprintf("/routing-policy/defined-sets/prefix-sets/prefix-set[name=%s]", prefixSet)
+
+	// The prefix set is configured beforehand, for a request to delete it.
+	set := &telemetry.RoutingPolicy_DefinedSets_PrefixSet{
+		Name: ygot.String(prefixSet),
+		Mode: telemetry.PrefixSet_Mode_IPV4,
+	}
+	set.GetOrCreatePrefix("203.0.113.0/24", "exact")
+	dut.Config().RoutingPolicy().DefinedSets().PrefixSet(prefixSet).Replace(t, set)
+	defer dut.Config().RoutingPolicy().DefinedSets().PrefixSet(prefixSet).Delete(t)
+
+	// MTU is a uint16, so 70000 and a string are invalid values.
+	for _, c := range []struct {
+		desc string
+		req  *gpb.SetRequest
+	}{{
+		desc: "update with a value out of range",
+		req: &gpb.SetRequest{Update: []*gpb.Update{
+			jsonUpdate(t, intf+"/config/description", description),
+			jsonUpdate(t, intf+"/config/mtu", 70000),
+		}},
+	}, {
+		desc: "update with a value of the wrong type",
+		req: &gpb.SetRequest{Update: []*gpb.Update{
+			jsonUpdate(t, intf+"/config/description", description),
+			jsonUpdate(t, intf+"/config/mtu", "not-a-number"),
+		}},
+	}, {
+		desc: "update of a path not in the schema",
+		req: &gpb.SetRequest{Update: []*gpb.Update{
+			jsonUpdate(t, intf+"/config/description", description),
+			jsonUpdate(t, intf+"/config/gnmi-1-28-no-such-leaf", description),
+		}},
+	}, {
+		desc: "replace of a new subtree",
+		req: &gpb.SetRequest{
+			Replace: []*gpb.Update{structUpdate(t, acl, aclSet())},
+			Update:  []*gpb.Update{jsonUpdate(t, intf+"/config/mtu", 70000)},
+		},
+	}, {
+		desc: "delete of a subtree",
+		req: &gpb.SetRequest{
+			Delete: []*gpb.Path{mustPath(t, ps)},
+			Update: []*gpb.Update{jsonUpdate(t, intf+"/config/mtu", "not-a-number")},
+		},
+	}, {
+		desc: "invalid operation first",
+		req: &gpb.SetRequest{Update: []*gpb.Update{
+			jsonUpdate(t, intf+"/config/mtu", 70000),
+			jsonUpdate(t, intf+"/config/description", description),
+		}},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			before := configdiff.Take(t, dut)
+			t.Logf("SetRequest: %v", c.req)
+			_, err := dut.RawAPIs().GNMI().Default(t).Set(context.Background(), c.req)
+			if err == nil {
+				t.Errorf("Set with an invalid operation succeeded")
+			} else {
+				t.Logf("Set failed with %v: %s", status.Code(err), status.Convert(err).Message())
+			}
+			changes, err := configdiff.Diff(before, configdiff.Take(t, dut))
+			if err != nil {
+				t.Fatalf("Cannot diff the configuration: %v", err)
+			}
+			if len(changes) > 0 {
+				t.Errorf("Set partially applied, -before, +after:\n%s", configdiff.Format(changes))
+				restore(t, dut, before, p1)
+			}
+		})
+	}
+}
+
+// restore restores the configuration the SetRequests touch from a snapshot.
+func restore(t *testing.T, dut *ondatra.DUTDevice, before *telemetry.Device, name string) {
+	t.Helper()
+	if i := before.GetInterface(name); i != nil {
+		dut.Config().Interface(name).Replace(t, i)
+	} else {
+		dut.Config().Interface(name).Delete(t)
+	}
+	if set := before.GetAcl().GetAclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4); set != nil {
+		dut.Config().Acl().AclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4).Replace(t, set)
+	} else {
+		dut.Config().Acl().AclSet(aclName, telemetry.Acl_ACL_TYPE_ACL_IPV4).Delete(t)
+	}
+	if set := before.GetRoutingPolicy().GetDefinedSets().GetPrefixSet(prefixSet); set != nil {
+		dut.Config().RoutingPolicy().DefinedSets().PrefixSet(prefixSet).Replace(t, set)
+	}
+	configdiff.CheckUnchanged(t, dut, before)
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package subscribe_scale_test implements gNMI-1.19: Telemetry: Subscribe
+// Scale.
+package subscribe_scale_test
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/subscale"
+	"github.com/openconfig/ondatra"
+)
+
+var (
+	clients = flag.Int("subscribe_clients", 20,
+		"Number of concurrent Subscribe RPCs.")
+	paths = flag.String("subscribe_paths",
+		"/interfaces/interface/state/counters,/components/component/state,/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state",
+		"Comma separated paths each Subscribe RPC samples.")
+	sampleInterval = flag.Duration("subscribe_sample_interval", 10*time.Second,
+		"Sample interval of the subscriptions.")
+	duration = flag.Duration("subscribe_duration", 5*time.Minute,
+		"How long the subscriptions stream after their initial sync.")
+	maxDropPercent = flag.Float64("subscribe_max_drop_percent", 1,
+		"Largest percentage of the samples the DUT may drop.")
+	maxCPU = flag.Uint("subscribe_max_cpu_percent", 80,
+		"Largest CPU utilization of the DUT components while streaming.")
+	maxMemoryGrowth = flag.Float64("subscribe_max_memory_growth_percent", 10,
+		"Largest growth of the utilized memory of the DUT components while streaming.")
+)
+
+// syncTimeout is the time given to each client to complete its initial
+// sync.
+const syncTimeout = 2 * time.Minute
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// TestSubscribeScale opens many concurrent Subscribe RPCs sampling the
+// same paths, and verifies that each completes its initial sync and
+// receives its samples with few drops, without overloading the DUT.
+//
+// telemetry_path:/interfaces/interface/state/counters
+// telemetry_path:/components/component/state
+// telemetry_path:/components/component/cpu/utilization/state/avg
+// telemetry_path:/components/component/state/memory/utilized
+func TestSubscribeScale(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ps, err := subscale.ParsePaths(strings.Split(*paths, ","))
+	if err != nil {
+		t.Fatalf("Invalid --subscribe_paths: %v", err)
+	}
+	c := &subscale.Config{
+		Clients:        args.Scale(*clients),
+		Paths:          ps,
+		SampleInterval: *sampleInterval,
+		Duration:       args.TrafficDuration(*duration),
+	}
+
+	w := fptest.WatchResources(t, dut, 10*time.Second)
+	stats := subscale.Run(t, dut, c, args.Convergence(syncTimeout))
+	samples := w.Stop(t)
+
+	for _, s := range stats {
+		if s.Err != nil {
+			t.Errorf("Client %d: %v", s.Client, s.Err)
+		}
+		if !s.Synced {
+			t.Errorf("Client %d: no initial sync within %v", s.Client, args.Convergence(syncTimeout))
+		}
+		t.Logf("Client %d: %d leaves, %d updates, synced in %v", s.Client, s.Leaves(), s.Updates, s.SyncLatency)
+	}
+	sum := subscale.Summarize(stats, c)
+	t.Log(sum)
+	if sum.Expected == 0 {
+		t.Fatalf("No samples expected: the paths have no leaves or the duration is shorter than the sample interval")
+	}
+	if got := sum.DropPercent(); got > *maxDropPercent {
+		t.Errorf("Samples dropped got %.2f%%, want at most %g%%", got, *maxDropPercent)
+	}
+
+	if err := fptest.CheckPeakCPU(samples, uint8(*maxCPU)); err != nil {
+		t.Error(err)
+	}
+	if err := fptest.CheckPeakMemory(samples, *maxMemoryGrowth); err != nil {
+		t.Error(err)
+	}
+}
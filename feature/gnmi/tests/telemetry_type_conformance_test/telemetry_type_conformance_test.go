@@ -0,0 +1,99 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry_type_conformance_test implements gNMI-1.20: Telemetry:
+// Value Type Conformance.
+package telemetry_type_conformance_test
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/dualenc"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/typecheck"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+var (
+	paths = flag.String("type_conformance_paths", "/interfaces,/components,/system,/network-instances",
+		"Comma separated paths whose values are checked.")
+	collectTimeout = flag.Duration("type_conformance_timeout", 5*time.Minute,
+		"Time given to the DUT to send the values of the paths.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// TestTypeConformance subscribes once to the paths, and verifies that the
+// value of each leaf is of the type of the leaf in the OpenConfig schema.
+// The mismatches are reported once per schema path, as the leaves the DUT
+// deviates from the schema for.  With --dual_encoding, the values are
+// also compared between the JSON_IETF and PROTO encodings.
+//
+// telemetry_path:/interfaces
+// telemetry_path:/components
+// telemetry_path:/system
+// telemetry_path:/network-instances
+func TestTypeConformance(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	checker, err := typecheck.New()
+	if err != nil {
+		t.Fatalf("Cannot load the schema: %v", err)
+	}
+
+	var ps []*gpb.Path
+	for _, s := range strings.Split(*paths, ",") {
+		p, err := ygot.StringToStructuredPath(s)
+		if err != nil {
+			t.Fatalf("Invalid --type_conformance_paths %s: %v", s, err)
+		}
+		ps = append(ps, p)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), args.Convergence(*collectTimeout))
+	defer cancel()
+	notifs, err := typecheck.Collect(ctx, dut.RawAPIs().GNMI().Default(t), ps...)
+	if err != nil {
+		t.Fatalf("Cannot collect the values of %s: %v", *paths, err)
+	}
+
+	var ms []*typecheck.Mismatch
+	updates := 0
+	for _, n := range notifs {
+		updates += len(n.GetUpdate())
+		ms = append(ms, checker.Check(n)...)
+	}
+	if updates == 0 {
+		t.Fatalf("No values received for %s", *paths)
+	}
+	t.Logf("Checked %d values, %d mismatches", updates, len(ms))
+
+	schemaPaths, counts, first := typecheck.Summarize(ms)
+	for _, p := range schemaPaths {
+		t.Errorf("%s: %d values mismatch the schema, first %v", p, counts[p], first[p])
+	}
+
+	if *dualenc.Enabled {
+		dualenc.Check(t, dut, ps...)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/openconfig/featureprofiles/internal/components"
 	"github.com/openconfig/featureprofiles/internal/fptest"
 	closer "github.com/openconfig/gocloser"
 	"github.com/openconfig/ondatra"
@@ -246,7 +247,7 @@ func (tc *testCase) transferOS(ctx context.Context, t *testing.T, standby bool)
 }
 
 // verifyInstall validates the OS.Verify RPC returns no failures and version numbers match the
-// newly requested software version.
+// newly requested software version, also reported by the supervisors and linecards.
 func (tc *testCase) verifyInstall(ctx context.Context, t *testing.T) {
 	r, err := tc.osc.Verify(ctx, &ospb.VerifyRequest{})
 	if err != nil {
@@ -270,6 +271,10 @@ func (tc *testCase) verifyInstall(ctx context.Context, t *testing.T) {
 		}
 	}
 
+	for _, m := range components.CheckSoftwareVersions(tc.dut.Telemetry().ComponentAny().Get(t), *osVersion) {
+		t.Error(m)
+	}
+
 	t.Log("OS.Verify complete")
 }
 
@@ -0,0 +1,194 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package component_redundancy_test implements gNOI-3.4: Fabric and
+// Linecard Redundancy.
+package component_redundancy_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/components"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/tolerance"
+	"github.com/openconfig/ondatra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var rebootTimeout = flag.Duration("redundancy_reboot_timeout", 15*time.Minute, "how long a component may take to become active again")
+
+// policy bounds the outage of the traffic while a redundant component is
+// offline, unless the tolerance arguments override it.
+var policy = tolerance.TrafficPolicy{MaxConvergence: time.Second}
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2, which should be on another linecard than the one taken
+// offline.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+const (
+	plen4 = 30
+	fps   = 10000
+	// downTimeout is how long a rebooted component may take to leave the
+	// active state.
+	downTimeout = time.Minute
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "DUT to ATE port1", IPv4: "192.0.2.1", IPv4Len: plen4}
+	atePort1 = attrs.Attributes{Name: "port1", IPv4: "192.0.2.2", IPv4Len: plen4}
+	dutPort2 = attrs.Attributes{Desc: "DUT to ATE port2", IPv4: "192.0.2.5", IPv4Len: plen4}
+	atePort2 = attrs.Attributes{Name: "port2", IPv4: "192.0.2.6", IPv4Len: plen4}
+)
+
+// configure configures the ports of the DUT and the ATE, and returns a
+// flow from ate:port1 to ate:port2.
+func configure(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice) *ondatra.Flow {
+	dc := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		intf := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		dc.Interface(intf.GetName()).Replace(t, intf)
+	}
+
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	top.Push(t).StartProtocols(t)
+	return ate.Traffic().NewFlow("continuity").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst).
+		WithHeaders(ondatra.NewEthernetHeader(), ondatra.NewIPv4Header()).
+		WithFrameRateFPS(fps)
+}
+
+// rebootWithTraffic sends the flow while the component is rebooted
+// through gNOI, until it is active again, and checks its oper-status
+// and last reboot time, and that the outage of the flow is within the
+// policy.
+func rebootWithTraffic(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, flow *ondatra.Flow, name string) {
+	comp := dut.Telemetry().Component(name)
+	before := comp.LastRebootTime().Lookup(t)
+	var lastChanges []*telemetry.QualifiedUint64
+	for _, p := range []string{"port1", "port2"} {
+		lastChanges = append(lastChanges, dut.Telemetry().Interface(dut.Port(t, p).Name()).LastChange().Lookup(t))
+	}
+
+	ate.Traffic().Start(t, flow)
+	if err := components.Reboot(t, dut, name); err != nil {
+		ate.Traffic().Stop(t)
+		if status.Code(err) == codes.Unimplemented {
+			t.Skipf("DUT %s cannot reboot %s through gNOI: %v", dut.Model(), name, err)
+		}
+		t.Fatalf("Cannot reboot %s: %v", name, err)
+	}
+	down := components.AwaitOperStatus(t, dut, name, downTimeout, telemetry.PlatformTypes_COMPONENT_OPER_STATUS_INACTIVE)
+	up := components.AwaitOperStatus(t, dut, name, *rebootTimeout, telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE)
+	ate.Traffic().Stop(t)
+
+	if !down {
+		t.Errorf("Component %s did not become INACTIVE after its reboot", name)
+	}
+	if !up {
+		t.Fatalf("Component %s did not become ACTIVE in %v after its reboot", name, *rebootTimeout)
+	}
+	if after := comp.LastRebootTime().Lookup(t); !after.IsPresent() || (before.IsPresent() && after.Val(t) <= before.Val(t)) {
+		t.Errorf("Component %s last-reboot-time got %v, want after %v", name, after, before)
+	}
+	for i, p := range []string{"port1", "port2"} {
+		after := dut.Telemetry().Interface(dut.Port(t, p).Name()).LastChange().Lookup(t)
+		if lastChanges[i].IsPresent() && after.IsPresent() && after.Val(t) != lastChanges[i].Val(t) {
+			t.Errorf("DUT %s last-change got %d, want %d: the port flapped", p, after.Val(t), lastChanges[i].Val(t))
+		}
+	}
+
+	c := ate.Telemetry().Flow(flow.Name()).Counters()
+	tx, rx := c.OutPkts().Get(t), c.InPkts().Get(t)
+	if tx == 0 {
+		t.Fatalf("Flow %s sent no packets", flow.Name())
+	}
+	var lost uint64
+	if rx < tx {
+		lost = tx - rx
+	}
+	outage := components.Outage(lost, fps)
+	t.Logf("Flow %s lost %d of %d packets during the reboot of %s, an outage of %v", flow.Name(), lost, tx, name, outage)
+	if maxOutage := tolerance.New(policy).MaxConvergence; outage > maxOutage {
+		t.Errorf("Flow %s outage got %v during the reboot of %s, want at most %v", flow.Name(), outage, name, maxOutage)
+	}
+}
+
+// TestFabricRedundancy takes a fabric card offline while others are
+// active, and checks that the traffic continues within the threshold.
+//
+// telemetry_path:/components/component/state/oper-status
+// telemetry_path:/components/component/state/last-reboot-time
+// telemetry_path:/interfaces/interface/state/last-change
+func TestFabricRedundancy(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+
+	active := components.Active(components.ByType(t, dut, telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_FABRIC))
+	if len(active) < 2 {
+		t.Skipf("DUT %s has %d active fabric cards, want 2 or more", dut.Model(), len(active))
+	}
+	flow := configure(t, dut, ate)
+	fabric := active[len(active)-1]
+	t.Logf("Taking fabric card %s offline, leaving %v", fabric, active[:len(active)-1])
+	rebootWithTraffic(t, dut, ate, flow, fabric)
+}
+
+// TestLinecardRedundancy takes offline a linecard which does not hold
+// the ports of the traffic, and checks that the traffic continues
+// within the threshold.
+//
+// telemetry_path:/components/component/state/oper-status
+// telemetry_path:/components/component/state/last-reboot-time
+// telemetry_path:/interfaces/interface/state/hardware-port
+// telemetry_path:/interfaces/interface/state/last-change
+func TestLinecardRedundancy(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+
+	cs := components.Map(t, dut)
+	used := map[string]bool{}
+	for _, p := range []string{"port1", "port2"} {
+		used[components.Linecard(t, dut, cs, dut.Port(t, p))] = true
+	}
+	var linecard string
+	for _, c := range components.ByType(t, dut, telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_LINECARD) {
+		if c.GetRemovable() && c.GetOperStatus() == telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE && !used[c.GetName()] {
+			linecard = c.GetName()
+		}
+	}
+	if linecard == "" {
+		t.Skipf("DUT %s has no active removable linecard without port1 and port2", dut.Model())
+	}
+	flow := configure(t, dut, ate)
+	t.Logf("Taking linecard %s offline", linecard)
+	rebootWithTraffic(t, dut, ate, flow, linecard)
+}
@@ -0,0 +1,239 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package process_restart_test implements gNOI-3.5: Routing and gRIBI
+// Daemon Restart.
+package process_restart_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/daemon"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/featureprofiles/internal/tolerance"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	spb "github.com/openconfig/gnoi/system"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var reconvergenceTimeout = flag.Duration("reconvergence_timeout", 2*time.Minute,
+	"Maximum time for a daemon to restart and its routes to be installed again.")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The DUT has an iBGP session with ate:port2, which advertises
+// the BGP prefix, while the gRIBI prefix is programmed by the test.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+const (
+	plen        = 30
+	as          = 64500
+	bgpPrefix   = "203.0.113.0/24"
+	gribiPrefix = "198.51.100.0/24"
+	nhIndex     = 1
+	nhgIndex    = 42
+	pps         = 1000
+	settle      = 10 * time.Second
+)
+
+// policy bounds the traffic outage while a daemon restarts, unless the
+// tolerance arguments override it.
+var policy = tolerance.TrafficPolicy{MaxConvergence: time.Second}
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT and its iBGP session.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(as)
+	global.RouterId = ygot.String(dutPort2.IPv4)
+	global.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+	nbr := bgp.GetOrCreateNeighbor(atePort2.IPv4)
+	nbr.PeerAs = ygot.Uint32(as)
+	nbr.Enabled = ygot.Bool(true)
+	nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+	bgpPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	fptest.LogYgot(t, "DUT BGP", bgpPath, bgp)
+	bgpPath.Replace(t, bgp)
+}
+
+// configureATE configures the ports of the ATE and the iBGP session of
+// ate:port2, and returns the flows to the BGP and gRIBI prefixes.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) (*ondatra.Flow, *ondatra.Flow) {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	dst.BGP().AddPeer().WithPeerAddress(dutPort2.IPv4).WithLocalASN(as).WithTypeInternal()
+	net := dst.AddNetwork(bgpPrefix)
+	net.IPv4().WithAddress(bgpPrefix).WithCount(1)
+	net.BGP().WithNextHopAddress(atePort2.IPv4).WithOriginIGP()
+	top.Push(t).StartProtocols(t)
+
+	var flows []*ondatra.Flow
+	for _, f := range []struct{ name, min, max string }{
+		{"BGP", "203.0.113.1", "203.0.113.250"},
+		{"gRIBI", "198.51.100.1", "198.51.100.250"},
+	} {
+		ipv4 := ondatra.NewIPv4Header()
+		ipv4.DstAddressRange().WithMin(f.min).WithMax(f.max).WithCount(250)
+		flows = append(flows, ate.Traffic().NewFlow(f.name).
+			WithSrcEndpoints(src).
+			WithDstEndpoints(dst).
+			WithHeaders(ondatra.NewEthernetHeader(), ipv4).
+			WithFrameRateFPS(args.PPS(pps)))
+	}
+	return flows[0], flows[1]
+}
+
+// awaitSession waits for the iBGP session to be established, and returns
+// whether it was within the timeout.
+func awaitSession(t testing.TB, dut *ondatra.DUTDevice, timeout time.Duration) bool {
+	_, ok := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().
+		Neighbor(atePort2.IPv4).SessionState().
+		Watch(t, timeout, func(val *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+			return val.IsPresent() && val.Val(t) == telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+		}).Await(t)
+	return ok
+}
+
+// TestRoutingDaemonRestart restarts the routing daemon while traffic is
+// forwarded to the BGP prefix, and measures the traffic outage and the
+// time for the BGP prefix to be installed again.
+//
+// telemetry_path:/system/processes/process/state/name
+// telemetry_path:/system/processes/process/state/pid
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/session-state
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/global/config/as
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/config/peer-as
+func TestRoutingDaemonRestart(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	flow, _ := configureATE(t, ate)
+	if !awaitSession(t, dut, time.Minute) {
+		t.Fatalf("BGP session with %s is not established", atePort2.IPv4)
+	}
+	recursion.Await(t, dut, []string{bgpPrefix}, time.Minute)
+
+	var reconvergence time.Duration
+	w := gribi.MeasureOutage(t, ate, flow, args.PPS(pps), settle, func() {
+		start := time.Now()
+		pid := daemon.Kill(t, dut, daemon.Routing, spb.KillProcessRequest_SIGNAL_TERM, true)
+		if d, ok := daemon.AwaitRestart(t, dut, daemon.Routing, pid, args.Convergence(*reconvergenceTimeout)); !ok {
+			t.Fatalf("Routing daemon not restarted after %v", d)
+		}
+		if !awaitSession(t, dut, args.Convergence(*reconvergenceTimeout)-time.Since(start)) {
+			t.Fatalf("BGP session with %s not established again", atePort2.IPv4)
+		}
+		recursion.Await(t, dut, []string{bgpPrefix}, args.Convergence(*reconvergenceTimeout)-time.Since(start))
+		reconvergence = time.Since(start)
+	})
+	t.Logf("Routing daemon restart: reconverged after %v, with a traffic outage of %v", reconvergence, w)
+	if max := tolerance.New(policy).MaxConvergence; w > max {
+		t.Errorf("Traffic outage got %v, want at most %v", w, max)
+	}
+}
+
+// TestGRIBIDaemonRestart restarts the gRIBI daemon while traffic is
+// forwarded to a prefix programmed with PERSISTENCE set to PRESERVE, and
+// verifies that the prefix is retained, forwarding, and returned by the
+// Get RPC of a new connection.
+//
+// telemetry_path:/system/processes/process/state/name
+// telemetry_path:/system/processes/process/state/pid
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/prefix
+func TestGRIBIDaemonRestart(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	_, flow := configureATE(t, ate)
+	ni := *deviations.DefaultNetworkInstance
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		Persistence:          true,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+	c.AddNH(t, nhIndex, atePort2.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, nhgIndex, map[uint64]uint64{nhIndex: 1}, ni, fluent.InstalledInRIB)
+	c.AddIPv4(t, gribiPrefix, nhgIndex, ni, "", fluent.InstalledInRIB)
+
+	p := &gribi.Persistence{
+		Client:          c,
+		NetworkInstance: ni,
+		Prefixes:        []string{gribiPrefix},
+	}
+	var errs []error
+	w := gribi.MeasureOutage(t, ate, flow, args.PPS(pps), settle, func() {
+		pid := daemon.Kill(t, dut, daemon.GRIBI, spb.KillProcessRequest_SIGNAL_TERM, true)
+		p.Disconnect(t)
+		d, ok := daemon.AwaitRestart(t, dut, daemon.GRIBI, pid, args.Convergence(*reconvergenceTimeout))
+		if !ok {
+			t.Fatalf("gRIBI daemon not restarted after %v", d)
+		}
+		t.Logf("gRIBI daemon restarted after %v", d)
+		errs = p.CheckWindow(t, dut)
+	})
+	for _, err := range errs {
+		t.Error(err)
+	}
+	if max := tolerance.New(policy).MaxConvergence; w > max {
+		t.Errorf("Traffic outage got %v, want at most %v", w, max)
+	}
+
+	if err := p.Reconnect(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be re-established: %v", err)
+	}
+	if err := p.CheckGet(t); err != nil {
+		t.Error(err)
+	}
+	c.DeleteIPv4(t, gribiPrefix, ni, fluent.InstalledInRIB)
+	c.DeleteNHG(t, nhgIndex, ni, fluent.InstalledInRIB)
+	c.DeleteNH(t, nhIndex, ni, fluent.InstalledInRIB)
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package set_package_test implements gNOI-4.2: Software Package Transfer.
+package set_package_test
+
+import (
+	"context"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/swpkg"
+	"github.com/openconfig/ondatra"
+
+	spb "github.com/openconfig/gnoi/system"
+	tpb "github.com/openconfig/gnoi/types"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+var (
+	packageFile     = flag.String("package_file", "", "Path of the package transferred to the DUT.")
+	packageFilename = flag.String("package_filename", "", "Path the DUT stores the package at.")
+	packageVersion  = flag.String("package_version", "", "Version of the package.")
+	packageURL      = flag.String("package_remote_url", "", "URL the DUT downloads the package from, serving the same file as -package_file, with the scheme http, https, sftp or scp and optionally the credentials.")
+)
+
+// readPackage returns the package given by the flags, and its contents.
+func readPackage(t *testing.T) (*swpkg.Package, []byte) {
+	t.Helper()
+	if *packageFile == "" || *packageFilename == "" {
+		t.Skip("Missing -package_file or -package_filename")
+	}
+	contents, err := os.ReadFile(*packageFile)
+	if err != nil {
+		t.Fatalf("Cannot read the package: %v", err)
+	}
+	return &swpkg.Package{Filename: *packageFilename, Version: *packageVersion}, contents
+}
+
+// hash returns the SHA256 hash of the contents.
+func hash(t *testing.T, contents []byte) *tpb.HashType {
+	t.Helper()
+	h, err := swpkg.Hash(tpb.HashType_SHA256, contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// send sends the requests to the DUT, and checks whether it accepts them.
+func send(t *testing.T, c spb.SystemClient, reqs []*spb.SetPackageRequest, err error, accepted bool) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Invalid SetPackage requests: %v", err)
+	}
+	switch err := swpkg.Send(context.Background(), c, reqs); {
+	case accepted && err != nil:
+		t.Errorf("SetPackage got error %v, want the package stored", err)
+	case !accepted && err == nil:
+		t.Errorf("SetPackage got no error, want the package rejected for its hash")
+	case !accepted:
+		t.Logf("SetPackage rejected as expected: %v", err)
+	}
+}
+
+// system returns the gNOI System client of the DUT.
+func system(t *testing.T, dut *ondatra.DUTDevice) spb.SystemClient {
+	return dut.RawAPIs().GNOI().Default(t).System()
+}
+
+// TestDirectTransfer verifies that the DUT stores a package whose contents
+// are sent on the SetPackage stream when their hash matches, and rejects
+// it otherwise.
+func TestDirectTransfer(t *testing.T) {
+	dut := fptest.RequireDUT(t, "dut", fptest.Requirements{})
+	pkg, contents := readPackage(t)
+	h := hash(t, contents)
+	c := system(t, dut)
+
+	t.Run("Valid", func(t *testing.T) {
+		reqs, err := pkg.Requests(contents, h)
+		send(t, c, reqs, err, true)
+	})
+	t.Run("HashMismatch", func(t *testing.T) {
+		reqs, err := pkg.Requests(contents, swpkg.Corrupt(h))
+		send(t, c, reqs, err, false)
+	})
+}
+
+// TestRemoteDownload verifies that the DUT stores a package it downloads
+// from a remote location when its hash matches, and rejects it otherwise.
+func TestRemoteDownload(t *testing.T) {
+	dut := fptest.RequireDUT(t, "dut", fptest.Requirements{})
+	pkg, contents := readPackage(t)
+	if *packageURL == "" {
+		t.Skip("Missing -package_remote_url")
+	}
+	r, err := swpkg.ParseRemote(*packageURL)
+	if err != nil {
+		t.Fatalf("Invalid -package_remote_url: %v", err)
+	}
+	t.Logf("Downloading the package from %v", r)
+	h := hash(t, contents)
+	c := system(t, dut)
+
+	t.Run("Valid", func(t *testing.T) {
+		reqs, err := pkg.RemoteRequests(r, h)
+		send(t, c, reqs, err, true)
+	})
+	t.Run("HashMismatch", func(t *testing.T) {
+		reqs, err := pkg.RemoteRequests(r, swpkg.Corrupt(h))
+		send(t, c, reqs, err, false)
+	})
+}
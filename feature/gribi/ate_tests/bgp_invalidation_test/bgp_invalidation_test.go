@@ -0,0 +1,245 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bgp_invalidation_test implements TE-11.2: Backup NHG: BGP Resolved
+// Next Hop Withdrawn.
+package bgp_invalidation_test
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var maxLossWindow = flag.Duration("max_loss_window", time.Second,
+	"Maximum duration of the traffic outage while the BGP route resolving the next hop is withdrawn or restored.")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3.  The DUT has an iBGP session with ate:port2,
+// which advertises the via prefix with itself as the next hop.  The gRIBI
+// entry of the prefix is through the next hop address in the via prefix,
+// with a backup next hop group through ate:port3.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+//   - ate:port3 -> dut:port3 subnet 192.0.2.8/30
+const (
+	plen      = 30
+	as        = 64500
+	prefix    = "198.51.100.0/24"
+	via       = "203.0.113.0/30"
+	viaNH     = "203.0.113.1"
+	nh        = 1
+	backupNH  = 2
+	nhg       = 101
+	backupNHG = 102
+	pps       = 10000
+	// settle is how long traffic runs before and after each change.
+	settle     = 10 * time.Second
+	aftTimeout = 2 * time.Minute
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", IPv4: "192.0.2.10", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT and its iBGP session with
+// ate:port2.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(as)
+	global.RouterId = ygot.String(dutPort2.IPv4)
+	global.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+	nbr := bgp.GetOrCreateNeighbor(atePort2.IPv4)
+	nbr.PeerAs = ygot.Uint32(as)
+	nbr.Enabled = ygot.Bool(true)
+	nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+	bgpPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	fptest.LogYgot(t, "DUT BGP", bgpPath, bgp)
+	bgpPath.Replace(t, bgp)
+}
+
+// configureATE configures the ports of the ATE and the iBGP session of
+// ate:port2, and returns the flow to the prefix and the via network.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) (*ondatra.ATETopology, *ondatra.Flow, *ondatra.Network) {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst2 := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	dst3 := atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3)
+	dst2.BGP().AddPeer().WithPeerAddress(dutPort2.IPv4).WithLocalASN(as).WithTypeInternal()
+	viaNet := dst2.AddNetwork(via)
+	viaNet.IPv4().WithAddress(via).WithCount(1)
+	viaNet.BGP().WithNextHopAddress(atePort2.IPv4).WithOriginIGP()
+	top.Push(t).StartProtocols(t)
+
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("198.51.100.0").WithMax("198.51.100.254").WithCount(250)
+	flow := ate.Traffic().NewFlow("Invalidation").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst2, dst3).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4).
+		WithFrameRateFPS(args.PPS(pps))
+	return top, flow, viaNet
+}
+
+// awaitSession waits for the iBGP session to be established.
+func awaitSession(t *testing.T, dut *ondatra.DUTDevice) {
+	state := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	_, ok := state.Neighbor(atePort2.IPv4).SessionState().Watch(t, time.Minute, func(val *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+		return val.IsPresent() && val.Val(t) == telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+	}).Await(t)
+	if !ok {
+		t.Fatalf("BGP session with %s is not established", atePort2.IPv4)
+	}
+}
+
+// outPkts returns the unicast packets sent out of the DUT ports.
+func outPkts(t *testing.T, dut *ondatra.DUTDevice, ports ...string) map[string]uint64 {
+	pkts := map[string]uint64{}
+	for _, p := range ports {
+		pkts[p] = dut.Telemetry().Interface(dut.Port(t, p).Name()).Counters().OutUnicastPkts().Get(t)
+	}
+	return pkts
+}
+
+// checkShift runs traffic to the prefix across change, and verifies that
+// the resolution of the prefix reaches the wanted state, that the outage of
+// the traffic is within the allowed loss window, and that the traffic then
+// leaves the DUT through port only.
+func checkShift(t *testing.T, ate *ondatra.ATEDevice, dut *ondatra.DUTDevice, flow *ondatra.Flow, change func(), want recursion.Resolution, port string) {
+	t.Helper()
+	ate.Traffic().Start(t, flow)
+	time.Sleep(settle)
+	change()
+	if d, err := recursion.AwaitState(t, dut, prefix, via, want, aftTimeout); err != nil {
+		t.Error(err)
+	} else {
+		t.Logf("Resolution of %s is %s after %v", prefix, want, d)
+	}
+	time.Sleep(settle)
+	before := outPkts(t, dut, "port2", "port3")
+	time.Sleep(settle)
+	after := outPkts(t, dut, "port2", "port3")
+	ate.Traffic().Stop(t)
+
+	counters := ate.Telemetry().Flow(flow.Name()).Counters().Get(t)
+	if err := gribi.CheckLossWindow(counters.GetOutPkts(), counters.GetInPkts(), args.PPS(pps), args.Convergence(*maxLossWindow)); err != nil {
+		t.Error(err)
+	}
+	for p, n := range after {
+		switch delta := n - before[p]; {
+		case p == port && delta == 0:
+			t.Errorf("No traffic out of dut:%s", p)
+		case p != port && delta > args.PPS(pps)*uint64(settle/time.Second)/100:
+			t.Errorf("%d packets out of dut:%s, want them out of dut:%s", delta, p, port)
+		}
+	}
+}
+
+// TestBGPInvalidation programs a gRIBI entry whose next hop resolves over a
+// BGP route, with a backup next hop group, and verifies that when the BGP
+// route is withdrawn while traffic is forwarded, the entry becomes
+// unresolved in the AFTs and the traffic falls back to the backup within
+// the allowed loss window, and that it reverts once the route is
+// advertised again.
+//
+// telemetry_path:/interfaces/interface/state/counters/out-unicast-pkts
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group
+// telemetry_path:/network-instances/network-instance/afts/next-hop-groups/next-hop-group/state/backup-next-hop-group
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/session-state
+func TestBGPInvalidation(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	top, flow, viaNet := configureATE(t, ate)
+	awaitSession(t, dut)
+	recursion.Await(t, dut, []string{via}, aftTimeout)
+	ni := *deviations.DefaultNetworkInstance
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+
+	fc := c.Fluent(t)
+	fc.Modify().AddEntry(t,
+		fluent.NextHopEntry().WithNetworkInstance(ni).WithIndex(nh).WithIPAddress(viaNH),
+		fluent.NextHopEntry().WithNetworkInstance(ni).WithIndex(backupNH).WithIPAddress(atePort3.IPv4),
+		fluent.NextHopGroupEntry().WithNetworkInstance(ni).WithID(backupNHG).AddNextHop(backupNH, 1),
+		fluent.NextHopGroupEntry().WithNetworkInstance(ni).WithID(nhg).AddNextHop(nh, 1).WithBackupNHG(backupNHG),
+	)
+	if err := c.AwaitTimeout(context.Background(), t, time.Minute); err != nil {
+		t.Fatalf("Error waiting to add the next hops: %v", err)
+	}
+	c.AddIPv4(t, prefix, nhg, ni, "", fluent.InstalledInRIB)
+
+	t.Run("Resolved", func(t *testing.T) {
+		if _, err := recursion.AwaitState(t, dut, prefix, via, recursion.Resolved, aftTimeout); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Withdrawn", func(t *testing.T) {
+		checkShift(t, ate, dut, flow, func() {
+			viaNet.BGP().WithActive(false)
+			top.UpdateNetworks(t)
+		}, recursion.Unresolved, "port3")
+	})
+
+	t.Run("Restored", func(t *testing.T) {
+		checkShift(t, ate, dut, flow, func() {
+			viaNet.BGP().WithActive(true)
+			top.UpdateNetworks(t)
+		}, recursion.Resolved, "port2")
+	})
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package delete_integrity_test implements TE-3.9: Delete Ordering and
+// Referential Integrity.
+package delete_integrity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+const (
+	plen       = 30
+	dstPrefix  = "198.51.100.0/24"
+	indirectNH = "203.0.113.1"
+	indirect   = "203.0.113.1/32"
+	settle     = 10 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+}
+
+// configureATE configures the ports of the ATE and returns the flow to
+// the destination prefix.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	top.Push(t).StartProtocols(t)
+
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("198.51.100.0").WithMax("198.51.100.254").WithCount(250)
+	return ate.Traffic().NewFlow("Integrity").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4)
+}
+
+// lossDuring forwards traffic while f runs, and returns its loss in
+// percent.
+func lossDuring(t *testing.T, ate *ondatra.ATEDevice, flow *ondatra.Flow, f func()) float32 {
+	ate.Traffic().Start(t, flow)
+	time.Sleep(settle)
+	f()
+	time.Sleep(settle)
+	ate.Traffic().Stop(t)
+	return ate.Telemetry().Flow(flow.Name()).LossPct().Get(t)
+}
+
+// TestDeleteIntegrity tries to delete the next hop groups and next hops of
+// a recursive route while they are referenced, which must fail without
+// affecting forwarding, and then tears the route down in order.
+//
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group
+// telemetry_path:/network-instances/network-instance/afts/next-hop-groups/next-hop-group/state/programmed-id
+// telemetry_path:/network-instances/network-instance/afts/next-hops/next-hop/state/ip-address
+func TestDeleteIntegrity(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	flow := configureATE(t, ate)
+
+	h := &gribi.Hierarchy{
+		NetworkInstance:    *deviations.DefaultNetworkInstance,
+		NHGNetworkInstance: *deviations.DefaultNetworkInstance,
+		Outer: gribi.Entry{Prefix: dstPrefix, NHG: 42, NextHops: []gribi.NextHop{
+			{Index: 1, Address: indirectNH, Weight: 1},
+		}},
+		Inner: []gribi.Entry{{Prefix: indirect, NHG: 52, NextHops: []gribi.NextHop{
+			{Index: 2, Address: atePort2.IPv4, Weight: 1},
+		}}},
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatalf("Invalid hierarchy: %v", err)
+	}
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+	h.Program(t, c, fluent.InstalledInRIB)
+	if errs := h.Check(h.AFTs(t, dut)); len(errs) > 0 {
+		t.Fatalf("Entries not installed: %v", errs)
+	}
+
+	t.Run("DeleteReferenced", func(t *testing.T) {
+		loss := lossDuring(t, ate, flow, func() { h.DeleteReferenced(t, c) })
+		if loss > 0.5 {
+			t.Errorf("Loss while deleting referenced entries: got %g, want < 0.5", loss)
+		}
+		for _, err := range h.Check(h.AFTs(t, dut)) {
+			t.Errorf("After deleting referenced entries: %v", err)
+		}
+	})
+
+	t.Run("DeleteOrdered", func(t *testing.T) {
+		loss := lossDuring(t, ate, flow, func() { h.Delete(t, c, fluent.InstalledInRIB) })
+		if loss == 0 || loss == 100 {
+			t.Errorf("Loss while deleting in order: got %g, want traffic forwarded until the deletion", loss)
+		}
+		for _, err := range h.CheckRemoved(h.AFTs(t, dut)) {
+			t.Error(err)
+		}
+	})
+}
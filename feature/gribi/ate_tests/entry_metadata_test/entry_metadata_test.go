@@ -0,0 +1,169 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package entry_metadata_test implements TE-16.1: gRIBI Entry Metadata.
+package entry_metadata_test
+
+import (
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The gRIBI entries are all through ate:port2.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+const (
+	plen = 30
+	nh   = 1
+	// coloredNHG is the next hop group with a color, and plainNHG the one
+	// without.
+	coloredNHG = 11
+	plainNHG   = 12
+	color      = 100
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+
+	// entries are the IPv4 entries programmed, with their attributes.
+	entries = []*gribi.Attributes{
+		{Prefix: "198.51.100.0/24", NHG: coloredNHG, Metadata: []byte("controller-1/198.51.100.0/24"), Color: color},
+		{Prefix: "203.0.113.0/25", NHG: plainNHG, Metadata: []byte{0x00, 0x01, 0xfe, 0xff}},
+		{Prefix: "203.0.113.128/25", NHG: plainNHG},
+	}
+)
+
+// configureDUT configures the ports of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+}
+
+// configureATE configures the ports of the ATE.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.ATETopology {
+	top := ate.Topology().New()
+	atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	top.Push(t).StartProtocols(t)
+	return top
+}
+
+// program adds the IPv4 entries with their metadata.
+func program(t *testing.T, c *gribi.Client, ni string, entries []*gribi.Attributes) {
+	t.Helper()
+	for _, e := range entries {
+		c.AddIPv4WithMetadata(t, e.Prefix, e.NHG, ni, "", e.Metadata, fluent.InstalledInRIB)
+	}
+}
+
+// check verifies that the attributes of the entries round-trip through the
+// Get RPC and the AFTs of the DUT.
+func check(t *testing.T, c *gribi.Client, dut *ondatra.DUTDevice, ni string, want []*gribi.Attributes) {
+	t.Helper()
+	resp, err := c.Fluent(t).Get().WithNetworkInstance(ni).WithAFT(fluent.AllAFTs).Send()
+	if err != nil {
+		t.Fatalf("Cannot Get: %v", err)
+	}
+	for _, err := range gribi.CheckAttributes(want, gribi.GetAttributes(resp)) {
+		t.Errorf("Get: %v", err)
+	}
+	afts := &telemetry.NetworkInstance_Afts{}
+	if v := dut.Telemetry().NetworkInstance(ni).Afts().Lookup(t); v.IsPresent() {
+		afts = v.Val(t)
+	}
+	for _, err := range gribi.CheckAttributes(want, gribi.AFTAttributes(afts)) {
+		t.Errorf("AFT: %v", err)
+	}
+}
+
+// TestEntryMetadata programs IPv4 entries with opaque metadata, through
+// next hop groups with and without a color, and verifies that the
+// metadata and the colors are returned as is by the Get RPC and in the
+// AFTs, and that they follow the entries when these are replaced.
+//
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/entry-metadata
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/origin-protocol
+// telemetry_path:/network-instances/network-instance/afts/next-hop-groups/next-hop-group/state/color
+// telemetry_path:/network-instances/network-instance/afts/next-hop-groups/next-hop-group/state/programmed-id
+func TestEntryMetadata(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	top := configureATE(t, ate)
+	defer top.StopProtocols(t)
+	ni := *deviations.DefaultNetworkInstance
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+
+	c.AddNH(t, nh, atePort2.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNHGWithColor(t, coloredNHG, map[uint64]uint64{nh: 1}, color, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, plainNHG, map[uint64]uint64{nh: 1}, ni, fluent.InstalledInRIB)
+	defer func() {
+		for _, e := range entries {
+			c.DeleteIPv4(t, e.Prefix, ni, fluent.InstalledInRIB)
+		}
+		c.DeleteNHG(t, plainNHG, ni, fluent.InstalledInRIB)
+		c.DeleteNHG(t, coloredNHG, ni, fluent.InstalledInRIB)
+		c.DeleteNH(t, nh, ni, fluent.InstalledInRIB)
+	}()
+
+	t.Run("Added", func(t *testing.T) {
+		program(t, c, ni, entries)
+		check(t, c, dut, ni, entries)
+	})
+
+	t.Run("Replaced", func(t *testing.T) {
+		// Swap the next hop groups of the first two entries, and change
+		// their metadata.
+		replaced := []*gribi.Attributes{
+			{Prefix: entries[0].Prefix, NHG: plainNHG, Metadata: []byte("controller-2/198.51.100.0/24")},
+			{Prefix: entries[1].Prefix, NHG: coloredNHG, Metadata: []byte{0xff, 0xfe, 0x01, 0x00}, Color: color},
+			entries[2],
+		}
+		program(t, c, ni, replaced[:2])
+		check(t, c, dut, ni, replaced)
+	})
+}
@@ -0,0 +1,313 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gnmi_contention_test implements TE-3.10: gRIBI and gNMI Write
+// Contention.
+package gnmi_contention_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/contention"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	spb "github.com/openconfig/gribi/v1/proto/service"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	steps = flag.Int("contention_steps", 8, "Number of concurrent changes of the entries of the prefix.")
+	seed  = flag.Int64("contention_seed", 0, "Seed of the changes and of their timing, or 0 to seed from the clock.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3.  The static route of the prefix is through
+// ate:port2, and its gRIBI entry through ate:port3.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+//   - ate:port3 -> dut:port3 subnet 192.0.2.8/30
+const (
+	plen   = 30
+	prefix = "203.0.113.0/24"
+	nh     = 1
+	nhg    = 42
+	pps    = 1000
+	// raceTimeout is how long the operations of a step have to complete
+	// before their control plane is considered wedged.
+	raceTimeout = time.Minute
+	// stagger is the maximum delay between the starts of the operations.
+	stagger    = 500 * time.Millisecond
+	aftTimeout = time.Minute
+	trafficFor = 10 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", IPv4: "192.0.2.10", IPv4Len: plen}
+)
+
+// winnerPorts are the DUT ports the traffic to the prefix leaves through,
+// by the origin protocol of its entry.
+var winnerPorts = map[string]string{
+	contention.Static: "port2",
+	contention.GRIBI:  "port3",
+}
+
+// configureDUT configures the ports of the DUT, and the static protocol of
+// the default network instance.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	ni := *deviations.DefaultNetworkInstance
+	p := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC,
+		Name:       ygot.String(contention.Static),
+		Enabled:    ygot.Bool(true),
+	}
+	d.NetworkInstance(ni).Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, contention.Static).Update(t, p)
+}
+
+// configureATE configures the ports of the ATE and returns the flow to the
+// prefix.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst2 := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	dst3 := atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3)
+	top.Push(t).StartProtocols(t)
+
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("203.0.113.0").WithMax("203.0.113.254").WithCount(250)
+	return ate.Traffic().NewFlow("Contention").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst2, dst3).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4).
+		WithFrameRateFPS(args.PPS(pps))
+}
+
+// staticOp returns the operation installing or deleting the static route of
+// the prefix through gNMI.
+func staticOp(gnmiC gpb.GNMIClient, ni string, install bool) contention.Op {
+	if !install {
+		return contention.Op{Name: "gNMI delete", Do: func(ctx context.Context) error {
+			req, err := contention.DeleteStaticRouteRequest(ni, prefix)
+			if err != nil {
+				return err
+			}
+			_, err = gnmiC.Set(ctx, req)
+			return err
+		}}
+	}
+	return contention.Op{Name: "gNMI replace", Do: func(ctx context.Context) error {
+		req, err := contention.StaticRouteRequest(ni, prefix, atePort2.IPv4)
+		if err != nil {
+			return err
+		}
+		_, err = gnmiC.Set(ctx, req)
+		return err
+	}}
+}
+
+// gribiOp returns the operation adding or deleting the gRIBI entry of the
+// prefix.  The modify functions of the fluent client only fail the test on
+// entries they cannot build, which this one is not, so the operation can run
+// off the test goroutine.
+func gribiOp(t *testing.T, c *gribi.Client, ni string, install bool) contention.Op {
+	entry := fluent.IPv4Entry().WithPrefix(prefix).WithNetworkInstance(ni).WithNextHopGroup(nhg)
+	if !install {
+		return contention.Op{Name: "gRIBI delete", Do: func(ctx context.Context) error {
+			c.Fluent(t).Modify().DeleteEntry(t, entry)
+			return c.AwaitTimeout(ctx, t, raceTimeout)
+		}}
+	}
+	return contention.Op{Name: "gRIBI add", Do: func(ctx context.Context) error {
+		c.Fluent(t).Modify().AddEntry(t, entry)
+		return c.AwaitTimeout(ctx, t, raceTimeout)
+	}}
+}
+
+// awaitWinner waits for the AFT entry of the prefix to be from the origin
+// protocol want, or to be removed if want is empty.
+func awaitWinner(t *testing.T, dut *ondatra.DUTDevice, ni, want string) {
+	t.Helper()
+	aft := dut.Telemetry().NetworkInstance(ni).Afts().Ipv4Entry(prefix)
+	if want == "" {
+		if _, ok := aft.Prefix().Watch(t, aftTimeout, func(v *telemetry.QualifiedString) bool {
+			return !v.IsPresent()
+		}).Await(t); !ok {
+			t.Errorf("AFT entry of %s got %v, want it removed", prefix, aft.OriginProtocol().Lookup(t))
+		}
+		return
+	}
+	if _, ok := aft.OriginProtocol().Watch(t, aftTimeout, func(v *telemetry.QualifiedE_PolicyTypes_INSTALL_PROTOCOL_TYPE) bool {
+		return v.IsPresent() && v.Val(t).String() == want
+	}).Await(t); !ok {
+		t.Errorf("AFT entry of %s origin protocol got %v, want %s", prefix, aft.OriginProtocol().Lookup(t), want)
+	}
+}
+
+// outPkts returns the unicast packets sent out of the DUT ports.
+func outPkts(t *testing.T, dut *ondatra.DUTDevice, ports []string) map[string]uint64 {
+	pkts := map[string]uint64{}
+	for _, p := range ports {
+		pkts[p] = dut.Telemetry().Interface(dut.Port(t, p).Name()).Counters().OutUnicastPkts().Get(t)
+	}
+	return pkts
+}
+
+// checkTraffic sends traffic to the prefix, and verifies that it leaves
+// through the port of the winner, or is dropped if there is none.
+func checkTraffic(t *testing.T, ate *ondatra.ATEDevice, dut *ondatra.DUTDevice, flow *ondatra.Flow, winner string) {
+	t.Helper()
+	ports := []string{"port2", "port3"}
+	d := args.TrafficDuration(trafficFor)
+	before := outPkts(t, dut, ports)
+	ate.Traffic().Start(t, flow)
+	time.Sleep(d)
+	ate.Traffic().Stop(t)
+	after := outPkts(t, dut, ports)
+
+	loss := ate.Telemetry().Flow(flow.Name()).LossPct().Get(t)
+	want, ok := winnerPorts[winner]
+	if !ok {
+		if loss != 100 {
+			t.Errorf("Loss with no entry for %s got %g, want 100", prefix, loss)
+		}
+		return
+	}
+	if loss > 1 {
+		t.Errorf("Loss with the %s entry of %s got %g, want <= 1", winner, prefix, loss)
+	}
+	// A little control plane traffic leaves through the other port.
+	for _, p := range ports {
+		switch delta := after[p] - before[p]; {
+		case p == want && delta == 0:
+			t.Errorf("No traffic out of dut:%s with the %s entry of %s", p, winner, prefix)
+		case p != want && delta > args.PPS(pps)*uint64(d/time.Second)/100:
+			t.Errorf("%d packets out of dut:%s with the %s entry of %s, want none", delta, p, winner, prefix)
+		}
+	}
+}
+
+// TestContention installs and removes a static route through gNMI and a
+// gRIBI entry for the same prefix concurrently, in random order and with
+// random timing, and verifies after each change that neither control plane
+// wedged, that the gRIBI operations were acknowledged, and that the DUT
+// forwards the prefix with the preferred entry.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/next-hop
+// telemetry_path:/interfaces/interface/state/counters/out-unicast-pkts
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/origin-protocol
+func TestContention(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	flow := configureATE(t, ate)
+	ni := *deviations.DefaultNetworkInstance
+
+	s := *seed
+	if s == 0 {
+		s = time.Now().UnixNano()
+	}
+	t.Logf("Seed of the changes: %d", s)
+	rnd := rand.New(rand.NewSource(s))
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+	c.AddNH(t, nh, atePort3.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, nhg, map[uint64]uint64{nh: 1}, ni, fluent.InstalledInRIB)
+
+	gnmiC := dut.RawAPIs().GNMI().Default(t)
+	static := dut.Config().NetworkInstance(ni).Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, contention.Static).Static(prefix)
+	defer static.Delete(t)
+
+	var cur contention.Installed
+	wedged := false
+	for i, next := range contention.Steps(rnd, *steps) {
+		if wedged {
+			break
+		}
+		t.Run(fmt.Sprintf("%d: %v", i+1, next), func(t *testing.T) {
+			var ops []contention.Op
+			if next.Static != cur.Static {
+				ops = append(ops, staticOp(gnmiC, ni, next.Static))
+			}
+			if next.GRIBI != cur.GRIBI {
+				ops = append(ops, gribiOp(t, c, ni, next.GRIBI))
+			}
+			before := len(c.Fluent(t).Results(t))
+			results := contention.Race(context.Background(), raceTimeout, stagger, rnd, ops...)
+			for _, r := range results {
+				t.Log(r)
+			}
+			cur = next
+			for _, err := range contention.Errors(results) {
+				if errors.Is(err, contention.ErrWedged) {
+					wedged = true
+				}
+				t.Error(err)
+			}
+			if wedged {
+				t.Fatalf("A control plane wedged, the state of the entries of %s is unknown", prefix)
+			}
+
+			for _, r := range c.Fluent(t).Results(t)[before:] {
+				if r.ProgrammingResult != spb.AFTResult_UNSET && r.ProgrammingResult != spb.AFTResult_RIB_PROGRAMMED {
+					t.Errorf("gRIBI operation got %v, want RIB_PROGRAMMED", r)
+				}
+			}
+			winner := cur.Winner(*deviations.GRIBIPreferredOverStatic)
+			awaitWinner(t, dut, ni, winner)
+			checkTraffic(t, ate, dut, flow, winner)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hierarchical_vip_test implements TE-3.7: Hierarchical VIP Route
+// Installation.
+package hierarchical_vip_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3.  dut:port1 is in the VRF of the VIP, while
+// dut:port2 and dut:port3, the backends, are in the default network
+// instance.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+//   - ate:port3 -> dut:port3 subnet 192.0.2.8/30
+const (
+	plen       = 30
+	vrf        = "VRF-VIP"
+	vip        = "198.51.100.0/24"
+	vipNH      = "203.0.113.1"
+	backend    = "203.0.113.1/32"
+	trafficFor = 15 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", IPv4: "192.0.2.10", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT, and the VRF of the VIP
+// with dut:port1.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	p1 := dut.Port(t, "port1").Name()
+	dev := &telemetry.Device{}
+	ni := dev.GetOrCreateNetworkInstance(vrf)
+	ni.Type = telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L3VRF
+	ni.Enabled = ygot.Bool(true)
+	niIntf := ni.GetOrCreateInterface(p1)
+	niIntf.Interface = ygot.String(p1)
+	niIntf.Subinterface = ygot.Uint32(0)
+	fptest.LogYgot(t, "DUT VRF", d.NetworkInstance(vrf), ni)
+	d.NetworkInstance(vrf).Replace(t, ni)
+}
+
+// configureATE configures the ports of the ATE.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.ATETopology {
+	top := ate.Topology().New()
+	atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3)
+	top.Push(t).StartProtocols(t)
+	return top
+}
+
+// sendTraffic sends traffic from ate:port1 to the VIP, received by the
+// backends, and returns its loss in percent.
+func sendTraffic(t *testing.T, ate *ondatra.ATEDevice, top *ondatra.ATETopology) float32 {
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("198.51.100.0").WithMax("198.51.100.254").WithCount(250)
+	flow := ate.Traffic().NewFlow("VIP").
+		WithSrcEndpoints(top.Interfaces()[atePort1.Name]).
+		WithDstEndpoints(top.Interfaces()[atePort2.Name], top.Interfaces()[atePort3.Name]).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4)
+	ate.Traffic().Start(t, flow)
+	time.Sleep(args.TrafficDuration(trafficFor))
+	ate.Traffic().Stop(t)
+	return ate.Telemetry().Flow(flow.Name()).LossPct().Get(t)
+}
+
+// TestHierarchicalVIP programs a VIP in a VRF through a next hop in the
+// default network instance, resolved by a backend prefix over two
+// weighted next hops, and verifies it through the AFTs and traffic, before
+// and after it is deleted top down.
+//
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group-network-instance
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/origin-protocol
+// telemetry_path:/network-instances/network-instance/afts/next-hop-groups/next-hop-group/state/programmed-id
+// telemetry_path:/network-instances/network-instance/afts/next-hop-groups/next-hop-group/next-hops/next-hop/state/weight
+// telemetry_path:/network-instances/network-instance/afts/next-hops/next-hop/state/ip-address
+func TestHierarchicalVIP(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	top := configureATE(t, ate)
+
+	h := &gribi.Hierarchy{
+		NetworkInstance:    vrf,
+		NHGNetworkInstance: *deviations.DefaultNetworkInstance,
+		Outer: gribi.Entry{Prefix: vip, NHG: 10, NextHops: []gribi.NextHop{
+			{Index: 1, Address: vipNH, Weight: 1},
+		}},
+		Inner: []gribi.Entry{{Prefix: backend, NHG: 20, NextHops: []gribi.NextHop{
+			{Index: 2, Address: atePort2.IPv4, Weight: 1},
+			{Index: 3, Address: atePort3.IPv4, Weight: 3},
+		}}},
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatalf("Invalid hierarchy: %v", err)
+	}
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+
+	t.Run("Program", func(t *testing.T) {
+		h.Program(t, c, fluent.InstalledInRIB)
+		afts := h.AFTs(t, dut)
+		for ni, a := range afts {
+			fptest.LogYgot(t, "AFTs of "+ni, dut.Telemetry().NetworkInstance(ni).Afts(), a)
+		}
+		for _, err := range h.Check(afts) {
+			t.Error(err)
+		}
+		if loss := sendTraffic(t, ate, top); loss > 0.5 {
+			t.Errorf("Loss: got %g, want < 0.5", loss)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		h.Delete(t, c, fluent.InstalledInRIB)
+		for _, err := range h.CheckRemoved(h.AFTs(t, dut)) {
+			t.Error(err)
+		}
+		if loss := sendTraffic(t, ate, top); loss != 100 {
+			t.Errorf("Loss: got %g, want 100", loss)
+		}
+	})
+}
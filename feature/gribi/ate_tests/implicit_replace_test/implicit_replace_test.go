@@ -0,0 +1,258 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package implicit_replace_test implements TE-3.8: Entry Modification
+// (Implicit Replace).
+package implicit_replace_test
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/ippool"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/featureprofiles/internal/timeline"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+)
+
+var maxLossWindow = flag.Duration("max_loss_window", 50*time.Millisecond,
+	"Maximum duration of the traffic outage while an entry is modified.")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+//   - ate:port3 -> dut:port3 subnet 192.0.2.8/30
+const (
+	plen   = 30
+	prefix = "198.51.100.0/24"
+	nh2    = 1
+	nh3    = 2
+	nhg1   = 10
+	nhg2   = 20
+	pps    = 10000
+	// settle is how long traffic runs before and after each modification.
+	settle = 10 * time.Second
+)
+
+// The addresses of the ports are allocated by allocateLinks.
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1"}
+	atePort1 = attrs.Attributes{Name: "atePort1"}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2"}
+	atePort2 = attrs.Attributes{Name: "atePort2"}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3"}
+	atePort3 = attrs.Attributes{Name: "atePort3"}
+)
+
+// allocateLinks allocates the links of the ports, in order, from the
+// test addresses.
+func allocateLinks(t *testing.T) {
+	pool, err := ippool.New("192.0.2.0/24")
+	if err != nil {
+		t.Fatalf("Cannot create the address pool: %v", err)
+	}
+	ports := [][2]*attrs.Attributes{{&dutPort1, &atePort1}, {&dutPort2, &atePort2}, {&dutPort3, &atePort3}}
+	links, err := pool.Links(plen, len(ports))
+	if err != nil {
+		t.Fatalf("Cannot allocate the links: %v", err)
+	}
+	for i, l := range links {
+		dut, ate := ports[i][0], ports[i][1]
+		dut.IPv4, dut.IPv4Len = l.DUT, l.Len
+		ate.IPv4, ate.IPv4Len = l.ATE, l.Len
+	}
+}
+
+// configureDUT configures the ports of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+}
+
+// configureATE configures the ports of the ATE and returns the flow to
+// the prefix.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst2 := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	dst3 := atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3)
+	top.Push(t).StartProtocols(t)
+
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("198.51.100.0").WithMax("198.51.100.254").WithCount(250)
+	return ate.Traffic().NewFlow("Replace").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst2, dst3).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4).
+		WithFrameRateFPS(args.PPS(pps))
+}
+
+// outPkts returns the unicast packets sent out of the DUT ports.
+func outPkts(t *testing.T, dut *ondatra.DUTDevice, ports []string) map[string]uint64 {
+	pkts := map[string]uint64{}
+	for _, p := range ports {
+		pkts[p] = dut.Telemetry().Interface(dut.Port(t, p).Name()).Counters().OutUnicastPkts().Get(t)
+	}
+	return pkts
+}
+
+// TestImplicitReplace modifies the entries of a prefix while traffic is
+// forwarded to it, with ADD operations on existing entries and with
+// REPLACE operations, and verifies that the traffic shifts to the new next
+// hops within the allowed loss window.
+//
+// telemetry_path:/interfaces/interface/state/counters/out-unicast-pkts
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group
+// telemetry_path:/network-instances/network-instance/afts/next-hops/next-hop/state/ip-address
+func TestImplicitReplace(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	allocateLinks(t)
+	configureDUT(t, dut)
+	flow := configureATE(t, ate)
+	ni := *deviations.DefaultNetworkInstance
+
+	// The timeline traces each modification against the AFT entry of the
+	// prefix and the counters of the flow.
+	tl := timeline.New()
+	defer tl.Write(t)
+	ctx := context.Background()
+	if skew, err := timeline.EstimateGNMISkew(ctx, dut.RawAPIs().GNMI().Default(t), 5); err != nil {
+		t.Logf("Cannot estimate the clock skew of %s, its events are not corrected: %v", dut.Name(), err)
+	} else {
+		tl.SetSkew(dut.Name(), skew)
+	}
+	aft, err := ygot.StringToStructuredPath(fmt.Sprintf("/network-instances/network-instance[name=%s]/afts/ipv4-unicast/ipv4-entry[prefix=%s]", ni, prefix))
+	if err != nil {
+		t.Fatalf("Invalid AFT path: %v", err)
+	}
+	stop, err := tl.Watch(ctx, dut.RawAPIs().GNMI().New(t), dut.Name(), aft)
+	if err != nil {
+		t.Fatalf("Cannot watch the AFT entry of %s: %v", prefix, err)
+	}
+	defer stop()
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+
+	c.AddNH(t, nh2, atePort2.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNH(t, nh3, atePort3.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, nhg1, map[uint64]uint64{nh2: 1}, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, nhg2, map[uint64]uint64{nh3: 1}, ni, fluent.InstalledInRIB)
+	c.AddIPv4(t, prefix, nhg1, ni, "", fluent.InstalledInRIB)
+
+	for _, tc := range []struct {
+		desc   string
+		modify func(t *testing.T)
+		// wantPorts are the DUT ports the traffic shifts to.
+		wantPorts []string
+		wantNHs   []string
+	}{{
+		desc:      "Implicit replace of the NHG of the prefix",
+		modify:    func(t *testing.T) { c.AddIPv4(t, prefix, nhg2, ni, "", fluent.InstalledInRIB) },
+		wantPorts: []string{"port3"},
+		wantNHs:   []string{atePort3.IPv4},
+	}, {
+		desc:      "Implicit replace of the next hops of the NHG",
+		modify:    func(t *testing.T) { c.AddNHG(t, nhg2, map[uint64]uint64{nh2: 1, nh3: 1}, ni, fluent.InstalledInRIB) },
+		wantPorts: []string{"port2", "port3"},
+		wantNHs:   []string{atePort2.IPv4, atePort3.IPv4},
+	}, {
+		desc:      "Replace of the NHG of the prefix",
+		modify:    func(t *testing.T) { c.ReplaceIPv4(t, prefix, nhg1, ni, "", fluent.InstalledInRIB) },
+		wantPorts: []string{"port2"},
+		wantNHs:   []string{atePort2.IPv4},
+	}, {
+		desc: "Replace of the weights of the NHG",
+		modify: func(t *testing.T) {
+			c.ReplaceNHG(t, nhg1, map[uint64]uint64{nh2: 1, nh3: 3}, ni, fluent.InstalledInRIB)
+		},
+		wantPorts: []string{"port2", "port3"},
+		wantNHs:   []string{atePort2.IPv4, atePort3.IPv4},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ate.Traffic().Start(t, flow)
+			time.Sleep(settle)
+			tl.Mark("%s: modifying", tc.desc)
+			tc.modify(t)
+			tl.Mark("%s: modified", tc.desc)
+			time.Sleep(settle)
+			before := outPkts(t, dut, []string{"port2", "port3"})
+			time.Sleep(settle)
+			after := outPkts(t, dut, []string{"port2", "port3"})
+			ate.Traffic().Stop(t)
+			tl.AddFlowStats(t, ate, flow.Name())
+
+			counters := ate.Telemetry().Flow(flow.Name()).Counters().Get(t)
+			if err := gribi.CheckLossWindow(counters.GetOutPkts(), counters.GetInPkts(), args.PPS(pps), args.Convergence(*maxLossWindow)); err != nil {
+				t.Error(err)
+			}
+
+			e, err := recursion.Read(t, dut, prefix)
+			if err != nil {
+				t.Fatalf("Cannot read the AFT entry of %s: %v", prefix, err)
+			}
+			if diff := cmp.Diff(tc.wantNHs, e.NextHops, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("Next hops of %s -want, +got:\n%s", prefix, diff)
+			}
+
+			// Once modified, the traffic only leaves through the wanted
+			// ports, up to a little control plane traffic.
+			want := map[string]bool{}
+			for _, p := range tc.wantPorts {
+				want[p] = true
+			}
+			for p, n := range after {
+				switch delta := n - before[p]; {
+				case want[p] && delta == 0:
+					t.Errorf("No traffic out of dut:%s after the modification", p)
+				case !want[p] && delta > args.PPS(pps)*uint64(settle/time.Second)/100:
+					t.Errorf("%d packets out of dut:%s after the modification, want none", delta, p)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package leader_takeover_test implements TE-4.3: Leader Takeover with
+// Traffic Continuity.
+package leader_takeover_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/featureprofiles/internal/scenario"
+	"github.com/openconfig/featureprofiles/internal/tolerance"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+//   - ate:port3 -> dut:port3 subnet 192.0.2.8/30
+const (
+	plen   = 30
+	prefix = "203.0.113.0/24"
+	nhA    = 1
+	nhB    = 2
+	nhgA   = 10
+	nhgB   = 20
+	pps    = 10000
+	settle = 10 * time.Second
+)
+
+// policy bounds the traffic outage while the leader changes, unless the
+// tolerance arguments override it.
+var policy = tolerance.TrafficPolicy{MaxConvergence: 50 * time.Millisecond}
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", IPv4: "192.0.2.10", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+}
+
+// configureATE configures the ports of the ATE and returns the flow to
+// the prefix.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst2 := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	dst3 := atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3)
+	top.Push(t).StartProtocols(t)
+
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("203.0.113.1").WithMax("203.0.113.250").WithCount(250)
+	return ate.Traffic().NewFlow("Takeover").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst2, dst3).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4).
+		WithFrameRateFPS(pps)
+}
+
+// checkNextHops checks that the prefix is resolved to the address.
+func checkNextHops(t *testing.T, dut *ondatra.DUTDevice, addr string) {
+	t.Helper()
+	e, err := recursion.Read(t, dut, prefix)
+	if err != nil {
+		t.Fatalf("Cannot read the AFT entry of %s: %v", prefix, err)
+	}
+	if diff := cmp.Diff([]string{addr}, e.NextHops); diff != "" {
+		t.Errorf("Next hops of %s -want, +got:\n%s", prefix, diff)
+	}
+}
+
+// TestLeaderTakeover has client A program a route to ate:port2, then has
+// client B take over the mastership and move the route to ate:port3, and
+// measures the traffic outage during the transition.  The entries are
+// deleted even if programming them or the takeover fails.
+//
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group
+// telemetry_path:/network-instances/network-instance/afts/next-hops/next-hop/state/ip-address
+func TestLeaderTakeover(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	flow := configureATE(t, ate)
+	ni := *deviations.DefaultNetworkInstance
+
+	f := &gribi.Failover{
+		A: &gribi.Client{DUT: dut, Persistence: true, InitialElectionIDLow: 10},
+		B: &gribi.Client{DUT: dut, Persistence: true, InitialElectionIDLow: 10},
+	}
+	defer f.Close(t)
+	if err := f.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+
+	s := scenario.New(nil)
+	s.Configure("ProgramA", func(t *testing.T) {
+		f.A.AddNH(t, nhA, atePort2.IPv4, ni, fluent.InstalledInRIB)
+		f.A.AddNHG(t, nhgA, map[uint64]uint64{nhA: 1}, ni, fluent.InstalledInRIB)
+		f.A.AddIPv4(t, prefix, nhgA, ni, "", fluent.InstalledInRIB)
+		checkNextHops(t, dut, atePort2.IPv4)
+	})
+
+	s.Perturb("TakeoverB", func(t *testing.T) {
+		w := gribi.MeasureOutage(t, ate, flow, pps, settle, func() {
+			f.Takeover(t)
+			f.B.AddNH(t, nhB, atePort3.IPv4, ni, fluent.InstalledInRIB)
+			f.B.AddNHG(t, nhgB, map[uint64]uint64{nhB: 1}, ni, fluent.InstalledInRIB)
+			f.B.AddIPv4(t, prefix, nhgB, ni, "", fluent.InstalledInRIB)
+		})
+		t.Logf("Outage during the takeover: %v", w)
+		if max := tolerance.New(policy).MaxConvergence; w > max {
+			t.Errorf("Outage during the takeover got %v, want at most %v", w, max)
+		}
+		checkNextHops(t, dut, atePort3.IPv4)
+	})
+
+	s.Perturb("CloseA", func(t *testing.T) {
+		// The former leader disconnecting leaves the entries of the leader
+		// untouched.
+		w := gribi.MeasureOutage(t, ate, flow, pps, settle, func() { f.A.Close(t) })
+		if w > 0 {
+			t.Errorf("Outage after client A disconnects got %v, want none", w)
+		}
+		checkNextHops(t, dut, atePort3.IPv4)
+	})
+
+	s.Cleanup("CleanupB", func(t *testing.T) {
+		f.B.DeleteIPv4(t, prefix, ni, fluent.InstalledInRIB)
+		f.B.DeleteNHG(t, nhgA, ni, fluent.InstalledInRIB)
+		f.B.DeleteNHG(t, nhgB, ni, fluent.InstalledInRIB)
+		f.B.DeleteNH(t, nhA, ni, fluent.InstalledInRIB)
+		f.B.DeleteNH(t, nhB, ni, fluent.InstalledInRIB)
+	})
+	s.Run(t)
+}
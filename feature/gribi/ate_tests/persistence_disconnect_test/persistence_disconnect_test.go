@@ -0,0 +1,161 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package persistence_disconnect_test implements TE-4.4: Persistence Across
+// Disconnect.
+package persistence_disconnect_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+)
+
+var window = flag.Duration("persistence_window", time.Minute,
+	"How long the entries are checked to persist after the client disconnects.")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+const (
+	plen       = 30
+	dstPrefix  = "198.51.100.0/24"
+	indirectNH = "203.0.113.1"
+	indirect   = "203.0.113.1/32"
+	pps        = 1000
+	settle     = 5 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+}
+
+// configureATE configures the ports of the ATE and returns the flow to
+// the destination prefix.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	top.Push(t).StartProtocols(t)
+
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("198.51.100.0").WithMax("198.51.100.254").WithCount(250)
+	return ate.Traffic().NewFlow("Persistence").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4).
+		WithFrameRateFPS(pps)
+}
+
+// TestPersistenceAcrossDisconnect programs a recursive route with
+// PERSISTENCE set to PRESERVE, disconnects the client, and verifies that
+// the route remains installed and forwards traffic, and is returned by
+// the Get RPC once the client reconnects.
+//
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/prefix
+func TestPersistenceAcrossDisconnect(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	flow := configureATE(t, ate)
+	ni := *deviations.DefaultNetworkInstance
+
+	h := &gribi.Hierarchy{
+		NetworkInstance:    ni,
+		NHGNetworkInstance: ni,
+		Outer: gribi.Entry{Prefix: dstPrefix, NHG: 42, NextHops: []gribi.NextHop{
+			{Index: 1, Address: indirectNH, Weight: 1},
+		}},
+		Inner: []gribi.Entry{{Prefix: indirect, NHG: 52, NextHops: []gribi.NextHop{
+			{Index: 2, Address: atePort2.IPv4, Weight: 1},
+		}}},
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatalf("Invalid hierarchy: %v", err)
+	}
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		Persistence:          true,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+	h.Program(t, c, fluent.InstalledInRIB)
+
+	p := &gribi.Persistence{
+		Client:          c,
+		NetworkInstance: ni,
+		Prefixes:        []string{dstPrefix, indirect},
+		Window:          *window,
+	}
+
+	t.Run("Disconnect", func(t *testing.T) {
+		var errs []error
+		w := gribi.MeasureOutage(t, ate, flow, pps, settle, func() {
+			p.Disconnect(t)
+			errs = p.CheckWindow(t, dut)
+		})
+		for _, err := range errs {
+			t.Error(err)
+		}
+		if w > 0 {
+			t.Errorf("Outage after the client disconnected got %v, want none", w)
+		}
+	})
+
+	t.Run("Reconnect", func(t *testing.T) {
+		if err := p.Reconnect(t); err != nil {
+			t.Fatalf("gRIBI Connection can not be re-established: %v", err)
+		}
+		if err := p.CheckGet(t); err != nil {
+			t.Error(err)
+		}
+		for _, err := range h.Check(h.AFTs(t, dut)) {
+			t.Error(err)
+		}
+		h.Delete(t, c, fluent.InstalledInRIB)
+	})
+}
@@ -0,0 +1,229 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prefix_migration_test implements TE-3.12: Make-before-break Prefix
+// Migration.
+package prefix_migration_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fibscale"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+)
+
+var (
+	batchSize     = flag.Int("migration_batch_size", 8, "Number of prefixes migrated at once.")
+	maxLossWindow = flag.Duration("max_loss_window", 50*time.Millisecond,
+		"Maximum duration of the traffic outage while a batch of prefixes is migrated.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3.  The prefixes migrate from the next hop group
+// through ate:port2 to the one through ate:port3.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+//   - ate:port3 -> dut:port3 subnet 192.0.2.8/30
+const (
+	plen    = 30
+	start   = "198.51.100.0/32"
+	nh2     = 1
+	nh3     = 2
+	fromNHG = 10
+	toNHG   = 20
+	pps     = 10000
+	// settle is how long the traffic is measured after each batch, and
+	// before and after the migration.
+	settle = 5 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", IPv4: "192.0.2.10", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+}
+
+// configureATE configures the ports of the ATE and returns the flow to the
+// prefixes.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice, prefixes []string) *ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst2 := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	dst3 := atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3)
+	top.Push(t).StartProtocols(t)
+
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("198.51.100.0").WithCount(uint32(len(prefixes)))
+	return ate.Traffic().NewFlow("Migration").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst2, dst3).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4).
+		WithFrameRateFPS(args.PPS(pps))
+}
+
+// outPkts returns the unicast packets sent out of the DUT ports.
+func outPkts(t *testing.T, dut *ondatra.DUTDevice, ports ...string) map[string]uint64 {
+	pkts := map[string]uint64{}
+	for _, p := range ports {
+		pkts[p] = dut.Telemetry().Interface(dut.Port(t, p).Name()).Counters().OutUnicastPkts().Get(t)
+	}
+	return pkts
+}
+
+// checkForwarding sends traffic to the prefixes, and verifies that it
+// leaves the DUT through port only, up to a little control plane traffic.
+func checkForwarding(t *testing.T, ate *ondatra.ATEDevice, dut *ondatra.DUTDevice, flow *ondatra.Flow, port string) {
+	t.Helper()
+	before := outPkts(t, dut, "port2", "port3")
+	ate.Traffic().Start(t, flow)
+	time.Sleep(settle)
+	ate.Traffic().Stop(t)
+	after := outPkts(t, dut, "port2", "port3")
+	if loss := ate.Telemetry().Flow(flow.Name()).LossPct().Get(t); loss > 1 {
+		t.Errorf("Loss got %g, want <= 1", loss)
+	}
+	for p, n := range after {
+		switch delta := n - before[p]; {
+		case p == port && delta == 0:
+			t.Errorf("No traffic out of dut:%s", p)
+		case p != port && delta > args.PPS(pps)*uint64(settle/time.Second)/100:
+			t.Errorf("%d packets out of dut:%s, want them out of dut:%s", delta, p, port)
+		}
+	}
+}
+
+// checkNextHops verifies that the AFT entries of the prefixes resolve to
+// the next hop address only.
+func checkNextHops(t *testing.T, dut *ondatra.DUTDevice, prefixes []string, addr string) {
+	t.Helper()
+	for _, prefix := range prefixes {
+		e, err := recursion.Read(t, dut, prefix)
+		if err != nil {
+			t.Errorf("Cannot read the AFT entry of %s: %v", prefix, err)
+			continue
+		}
+		if diff := cmp.Diff([]string{addr}, e.NextHops); diff != "" {
+			t.Errorf("Next hops of %s -want, +got:\n%s", prefix, diff)
+		}
+	}
+}
+
+// TestMigration installs prefixes to a next hop group, then migrates them
+// make before break to another next hop group in batches while traffic is
+// forwarded to them, and verifies that the traffic shifts gracefully: each
+// batch causes at most the allowed loss window, and the traffic ends up on
+// the new next hop only.
+//
+// telemetry_path:/interfaces/interface/state/counters/out-unicast-pkts
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group
+// telemetry_path:/network-instances/network-instance/afts/next-hops/next-hop/state/ip-address
+func TestMigration(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	// The prefixes are host routes, all within 198.51.100.0/24.
+	n := args.Scale(64)
+	if n > 256 {
+		t.Fatalf("Cannot migrate %d prefixes, want at most 256", n)
+	}
+	prefixes, err := fibscale.Prefixes(start, n)
+	if err != nil {
+		t.Fatalf("Cannot generate the prefixes: %v", err)
+	}
+	configureDUT(t, dut)
+	flow := configureATE(t, ate, prefixes)
+	ni := *deviations.DefaultNetworkInstance
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+
+	// The next hop group the prefixes migrate to is installed before the
+	// migration, so that it is made before the old one is broken.
+	c.AddNH(t, nh2, atePort2.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNH(t, nh3, atePort3.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, fromNHG, map[uint64]uint64{nh2: 1}, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, toNHG, map[uint64]uint64{nh3: 1}, ni, fluent.InstalledInRIB)
+	c.AddIPv4Batch(t, prefixes, fromNHG, ni, "", fluent.InstalledInRIB)
+
+	t.Run("Before", func(t *testing.T) {
+		checkNextHops(t, dut, prefixes, atePort2.IPv4)
+		checkForwarding(t, ate, dut, flow, "port2")
+	})
+
+	t.Run("Migrate", func(t *testing.T) {
+		m := &gribi.Migration{
+			NetworkInstance: ni,
+			Prefixes:        prefixes,
+			ToNHG:           toNHG,
+			BatchSize:       *batchSize,
+			Settle:          settle,
+		}
+		counters := func(t testing.TB) (uint64, uint64) {
+			c := ate.Telemetry().Flow(flow.Name()).Counters().Get(t)
+			return c.GetOutPkts(), c.GetInPkts()
+		}
+		ate.Traffic().Start(t, flow)
+		time.Sleep(settle)
+		batches := m.Run(t, c, counters)
+		ate.Traffic().Stop(t)
+		for i, b := range batches {
+			t.Logf("Batch %d of %d prefixes from %s: sent %d packets, received %d", i+1, len(b.Prefixes), b.Prefixes[0], b.TxPkts, b.RxPkts)
+		}
+		for _, err := range gribi.CheckBatches(batches, args.PPS(pps), args.Convergence(*maxLossWindow)) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("After", func(t *testing.T) {
+		checkNextHops(t, dut, prefixes, atePort3.IPv4)
+		checkForwarding(t, ate, dut, flow, "port3")
+	})
+}
@@ -0,0 +1,233 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package static_gribi_ecmp_test implements TE-3.11: Static and gRIBI Routes
+// of Equal Preference.
+package static_gribi_ecmp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/contention"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/config/networkinstance"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3.  The static route of the prefix is through
+// ate:port2, and its gRIBI entry through ate:port3.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+//   - ate:port3 -> dut:port3 subnet 192.0.2.8/30
+const (
+	plen       = 30
+	prefix     = "203.0.113.0/24"
+	nh         = 1
+	nhg        = 42
+	pps        = 10000
+	trafficFor = 15 * time.Second
+	// balancePct is how far from the mean the traffic through each entry
+	// may deviate when it is balanced over both.
+	balancePct = 10
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", IPv4: "192.0.2.10", IPv4Len: plen}
+)
+
+// The DUT ports and next hops of the entries, by origin protocol.
+var (
+	entryPorts = map[string]string{
+		contention.Static: "port2",
+		contention.GRIBI:  "port3",
+	}
+	entryNHs = map[string]string{
+		contention.Static: atePort2.IPv4,
+		contention.GRIBI:  atePort3.IPv4,
+	}
+)
+
+// configureDUT configures the ports of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+}
+
+// configureATE configures the ports of the ATE and returns the flow to the
+// prefix, varying its addresses and ports so that it can be hashed.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst2 := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	dst3 := atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3)
+	top.Push(t).StartProtocols(t)
+
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("203.0.113.0").WithMax("203.0.113.254").WithCount(250)
+	udp := ondatra.NewUDPHeader()
+	udp.SrcPortRange().WithMin(1024).WithCount(1000)
+	return ate.Traffic().NewFlow("Coexistence").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst2, dst3).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4, udp).
+		WithFrameRateFPS(args.PPS(pps))
+}
+
+// configureStatic configures the static route of the prefix through
+// ate:port2, with the preference of the gRIBI entries.
+func configureStatic(t *testing.T, dut *ondatra.DUTDevice, ni string) {
+	s := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String(prefix)}
+	n := s.GetOrCreateNextHop("0")
+	n.NextHop = telemetry.UnionString(atePort2.IPv4)
+	n.Preference = ygot.Uint32(uint32(*deviations.GRIBIPreference))
+	path := staticPath(dut, ni)
+	fptest.LogYgot(t, "Static route", path, s)
+	path.Replace(t, s)
+}
+
+// staticPath returns the config path of the static route of the prefix.
+func staticPath(dut *ondatra.DUTDevice, ni string) *networkinstance.NetworkInstance_Protocol_StaticPath {
+	return dut.Config().NetworkInstance(ni).Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, contention.Static).Static(prefix)
+}
+
+// forwarded sends traffic to the prefix and returns the packets forwarded
+// through the next hop of each entry, and the loss of the flow.
+func forwarded(t *testing.T, ate *ondatra.ATEDevice, dut *ondatra.DUTDevice, flow *ondatra.Flow) (map[string]uint64, float32) {
+	t.Helper()
+	read := func() map[string]uint64 {
+		counts := map[string]uint64{}
+		for proto, p := range entryPorts {
+			counts[proto] = dut.Telemetry().Interface(dut.Port(t, p).Name()).Counters().OutUnicastPkts().Get(t)
+		}
+		return counts
+	}
+	before := read()
+	ate.Traffic().Start(t, flow)
+	time.Sleep(args.TrafficDuration(trafficFor))
+	ate.Traffic().Stop(t)
+	after := read()
+	for proto := range after {
+		after[proto] -= before[proto]
+	}
+	return after, ate.Telemetry().Flow(flow.Name()).LossPct().Get(t)
+}
+
+// TestEqualPreference installs a static route and a gRIBI entry for the
+// same prefix with equal preference, and verifies through the AFTs and the
+// distribution of the traffic that the DUT forwards with both, or with the
+// one it prefers, as given by its tie-break.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/next-hop
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/preference
+// telemetry_path:/interfaces/interface/state/counters/out-unicast-pkts
+// telemetry_path:/network-instances/network-instance/afts/next-hops/next-hop/state/ip-address
+func TestEqualPreference(t *testing.T) {
+	if *deviations.GRIBIPreference == 0 {
+		t.Skip("The preference of the gRIBI entries of the DUT is not given by -deviation_gribi_preference")
+	}
+	tb, err := contention.ConfiguredTieBreak()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Tie-break between the static route and the gRIBI entry: %s", tb)
+
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	flow := configureATE(t, ate)
+	ni := *deviations.DefaultNetworkInstance
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+	c.AddNH(t, nh, atePort3.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, nhg, map[uint64]uint64{nh: 1}, ni, fluent.InstalledInRIB)
+	defer staticPath(dut, ni).Delete(t)
+
+	for _, tc := range []struct {
+		installed contention.Installed
+		change    func(t *testing.T)
+	}{{
+		installed: contention.Installed{Static: true},
+		change:    func(t *testing.T) { configureStatic(t, dut, ni) },
+	}, {
+		installed: contention.Installed{Static: true, GRIBI: true},
+		change:    func(t *testing.T) { c.AddIPv4(t, prefix, nhg, ni, "", fluent.InstalledInRIB) },
+	}, {
+		installed: contention.Installed{GRIBI: true},
+		change:    func(t *testing.T) { staticPath(dut, ni).Delete(t) },
+	}} {
+		t.Run(tc.installed.String(), func(t *testing.T) {
+			tc.change(t)
+			forwarders := tc.installed.Forwarders(tb)
+
+			var wantNHs []string
+			for _, f := range forwarders {
+				wantNHs = append(wantNHs, entryNHs[f])
+			}
+			e, err := recursion.Read(t, dut, prefix)
+			if err != nil {
+				t.Fatalf("Cannot read the AFT entry of %s: %v", prefix, err)
+			}
+			if diff := cmp.Diff(wantNHs, e.NextHops, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("Next hops of %s -want, +got:\n%s", prefix, diff)
+			}
+
+			counts, loss := forwarded(t, ate, dut, flow)
+			t.Logf("Packets forwarded through each entry: %v", counts)
+			if loss > 1 {
+				t.Errorf("Loss got %g, want <= 1", loss)
+			}
+			if err := contention.CheckDistribution(forwarders, counts, balancePct); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package te_repair_test implements TE-11.3: Backup NHG: TE Service Repair
+// Path.
+package te_repair_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/faults"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/teservice"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	vips          = flag.Int("te_vips", 256, "Number of VIPs of the TE service, at most 256.")
+	tunnels       = flag.Int("te_tunnels", 16, "Number of tunnel endpoints of the TE service.")
+	maxLossWindow = flag.Duration("max_loss_window", 50*time.Millisecond,
+		"Maximum duration of the traffic outage when the primary path fails or is restored.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3.  dut:port1 is in the VRF of the VIPs.  The
+// primary path of the tunnels is through ate:port2, and their repair path
+// through ate:port3.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+//   - ate:port3 -> dut:port3 subnet 192.0.2.8/30
+const (
+	plen        = 30
+	vrf         = "VRF-TE"
+	firstVIP    = "198.51.100.0/32"
+	firstTunnel = "203.0.113.0/32"
+	// maxVIPs is the number of VIPs in 198.51.100.0/24.
+	maxVIPs = 256
+	pps     = 10000
+	// settle is how long traffic runs before and after each failure and
+	// restoration.
+	settle = 10 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", IPv4: "192.0.2.10", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT, and the VRF of the VIPs
+// with dut:port1.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	p1 := dut.Port(t, "port1").Name()
+	dev := &telemetry.Device{}
+	ni := dev.GetOrCreateNetworkInstance(vrf)
+	ni.Type = telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L3VRF
+	ni.Enabled = ygot.Bool(true)
+	niIntf := ni.GetOrCreateInterface(p1)
+	niIntf.Interface = ygot.String(p1)
+	niIntf.Subinterface = ygot.Uint32(0)
+	fptest.LogYgot(t, "DUT VRF", d.NetworkInstance(vrf), ni)
+	d.NetworkInstance(vrf).Replace(t, ni)
+}
+
+// configureATE configures the ports of the ATE and returns the flow to the
+// VIPs.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice, n int) *ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst2 := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	dst3 := atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3)
+	top.Push(t).StartProtocols(t)
+
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("198.51.100.0").WithCount(uint32(n))
+	return ate.Traffic().NewFlow("TE").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst2, dst3).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4).
+		WithFrameRateFPS(args.PPS(pps))
+}
+
+// outPkts returns the unicast packets sent out of the DUT ports.
+func outPkts(t testing.TB, dut *ondatra.DUTDevice, ports []string) map[string]uint64 {
+	pkts := map[string]uint64{}
+	for _, p := range ports {
+		pkts[p] = dut.Telemetry().Interface(dut.Port(t, p).Name()).Counters().OutUnicastPkts().Get(t)
+	}
+	return pkts
+}
+
+// checkSwitch runs the change of the primary path with traffic to the VIPs,
+// and checks its outage and that the traffic then leaves through the
+// wanted port only, up to a little control plane traffic.
+func checkSwitch(t *testing.T, ate *ondatra.ATEDevice, dut *ondatra.DUTDevice, flow *ondatra.Flow, change func(t testing.TB), want string) {
+	counters := func(t testing.TB) (uint64, uint64) {
+		c := ate.Telemetry().Flow(flow.Name()).Counters().Get(t)
+		return c.GetOutPkts(), c.GetInPkts()
+	}
+	ports := []string{"port2", "port3"}
+	ate.Traffic().Start(t, flow)
+	tx, rx := teservice.Measure(t, counters, settle, change)
+	before := outPkts(t, dut, ports)
+	time.Sleep(settle)
+	after := outPkts(t, dut, ports)
+	ate.Traffic().Stop(t)
+
+	if err := gribi.CheckLossWindow(tx, rx, args.PPS(pps), args.Convergence(*maxLossWindow)); err != nil {
+		t.Error(err)
+	}
+	for _, p := range ports {
+		switch delta := after[p] - before[p]; {
+		case p == want && delta == 0:
+			t.Errorf("No traffic out of dut:%s, want the traffic to the VIPs", p)
+		case p != want && delta > args.PPS(pps)*uint64(settle/time.Second)/100:
+			t.Errorf("%d packets out of dut:%s, want none", delta, p)
+		}
+	}
+}
+
+// TestTERepair programs a TE service of VIPs carried by tunnels whose next
+// hop groups are backed up by a repair path, and verifies that the traffic
+// to the VIPs switches over to the repair path and back within the loss
+// window, when the primary path fails by its link going down, and by a
+// blackhole only BFD detects where the testbed can degrade links.
+//
+// telemetry_path:/interfaces/interface/state/counters/out-unicast-pkts
+// telemetry_path:/interfaces/interface/state/oper-status
+func TestTERepair(t *testing.T) {
+	if *vips > maxVIPs {
+		t.Fatalf("Invalid -te_vips %d, want at most %d", *vips, maxVIPs)
+	}
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	flow := configureATE(t, ate, *vips)
+
+	s, err := (&teservice.Spec{
+		NetworkInstance:    vrf,
+		NHGNetworkInstance: *deviations.DefaultNetworkInstance,
+		VIPs:               *vips,
+		FirstVIP:           firstVIP,
+		Tunnels:            *tunnels,
+		FirstTunnel:        firstTunnel,
+		Primary:            atePort2.IPv4,
+		Repair:             atePort3.IPv4,
+	}).Generate()
+	if err != nil {
+		t.Fatalf("Invalid TE service: %v", err)
+	}
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+	s.Program(t, c, fluent.InstalledInRIB)
+	defer s.Delete(t, c, fluent.InstalledInRIB)
+
+	l := faults.LinkOf(t, dut, ate, "port2")
+	var triggers []teservice.Trigger
+	for _, kind := range faults.Selected(faults.ATELaser, faults.AdminDown) {
+		inj, err := faults.New(kind, &faults.Devices{DUT: dut, ATE: ate})
+		if err != nil {
+			t.Fatalf("Cannot inject %s faults: %v", kind, err)
+		}
+		triggers = append(triggers, &teservice.LinkDown{Injector: inj, DUT: dut, Link: l, Timeout: time.Minute})
+	}
+
+	for _, trig := range triggers {
+		t.Run(trig.String(), func(t *testing.T) {
+			t.Run("Fail", func(t *testing.T) { checkSwitch(t, ate, dut, flow, trig.Fail, "port3") })
+			t.Run("Restore", func(t *testing.T) { checkSwitch(t, ate, dut, flow, trig.Restore, "port2") })
+		})
+	}
+
+	t.Run("BFD", func(t *testing.T) {
+		trig := &teservice.BFDDown{Degrader: faults.RequireDegrader(t), Link: l}
+		t.Run("Fail", func(t *testing.T) { checkSwitch(t, ate, dut, flow, trig.Fail, "port3") })
+		t.Run("Restore", func(t *testing.T) { checkSwitch(t, ate, dut, flow, trig.Restore, "port2") })
+	})
+}
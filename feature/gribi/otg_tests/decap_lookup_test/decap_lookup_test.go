@@ -0,0 +1,257 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package decap_lookup_test implements TE-7.2: Decapsulate and Lookup in
+// VRF with Fallback.
+package decap_lookup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/otgutils"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3.  The traffic is sent from ate:port1 and
+// captured on ate:port2 and ate:port3.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - dut:port2 -> ate:port2 subnet 192.0.2.4/30
+//   - dut:port3 -> ate:port3 subnet 192.0.2.8/30
+//
+// Packets to decapAddress are decapsulated and the inner packets looked
+// up in vrf, where vrfPrefix is routed to ate:port2.  In the default
+// network instance, the fallback, defaultPrefix, which covers vrfPrefix,
+// is routed to ate:port3, so that the port receiving the inner packets
+// tells the table they were forwarded by.
+const (
+	plen          = 30
+	vrf           = "VRF-DECAP-LOOKUP"
+	vrfPrefix     = "198.51.100.0/25"
+	defaultPrefix = "198.51.100.0/24"
+	decapAddress  = "203.0.113.100"
+	trafficTime   = 10 * time.Second
+
+	nh2     = 1
+	nh3     = 2
+	nhg2    = 1
+	nhg3    = 2
+	decapNH = 10
+	// decapNHG is the next hop group of the decapsulating next hop, and
+	// decapNHG+1 that of the fallback.
+	decapNHG = 10
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", MAC: "02:00:03:01:01:01", IPv4: "192.0.2.10", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT in the default network
+// instance, and the lookup VRF.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	dev := &telemetry.Device{}
+	ni := dev.GetOrCreateNetworkInstance(vrf)
+	ni.Type = telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L3VRF
+	ni.Enabled = ygot.Bool(true)
+	fptest.LogYgot(t, "DUT VRF", d.NetworkInstance(vrf), ni)
+	d.NetworkInstance(vrf).Replace(t, ni)
+}
+
+// configureOTG returns the OTG configuration of the ATE interfaces,
+// capturing on ate:port2 and ate:port3.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	for _, p := range []struct {
+		port      string
+		ate, peer *attrs.Attributes
+	}{{"port1", &atePort1, &dutPort1}, {"port2", &atePort2, &dutPort2}, {"port3", &atePort3, &dutPort3}} {
+		id := ate.Port(t, p.port).ID()
+		config.Ports().Add().SetName(id)
+		config.Devices().Add().SetName(p.ate.Name).Ethernets().Add().
+			SetName(p.ate.Name + ".eth").
+			SetPortName(id).
+			SetMac(p.ate.MAC).
+			Ipv4Addresses().Add().
+			SetName(p.ate.Name + ".IPv4").
+			SetAddress(p.ate.IPv4).
+			SetGateway(p.peer.IPv4).
+			SetPrefix(int32(p.ate.IPv4Len))
+	}
+	capture.Enable(config, ate.Port(t, "port2").ID(), ate.Port(t, "port3").ID())
+	return config
+}
+
+// programRoutes programs vrfPrefix in the VRF to ate:port2, and
+// defaultPrefix in the default network instance to ate:port3.
+func programRoutes(t *testing.T, c *gribi.Client) {
+	ni := *deviations.DefaultNetworkInstance
+	c.AddNH(t, nh2, atePort2.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNH(t, nh3, atePort3.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, nhg2, map[uint64]uint64{nh2: 1}, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, nhg3, map[uint64]uint64{nh3: 1}, ni, fluent.InstalledInRIB)
+	c.AddIPv4(t, vrfPrefix, nhg2, vrf, ni, fluent.InstalledInRIB)
+	c.AddIPv4(t, defaultPrefix, nhg3, ni, "", fluent.InstalledInRIB)
+}
+
+// addFlow adds a flow from ate:port1 of IP-in-IP packets to decapAddress,
+// with inner packets to innerDst.
+func addFlow(config gosnappi.Config, innerDst string) {
+	config.Flows().Clear()
+	flow := config.Flows().Add().SetName("decap-lookup")
+	flow.Metrics().SetEnable(true)
+	flow.TxRx().Device().
+		SetTxNames([]string{atePort1.Name + ".IPv4"}).
+		SetRxNames([]string{atePort2.Name + ".IPv4", atePort3.Name + ".IPv4"})
+	flow.Rate().SetPps(100)
+	flow.Duration().FixedPackets().SetPackets(int32(100 * trafficTime / time.Second))
+	flow.Size().SetFixed(256)
+	flow.Packet().Add().Ethernet().Src().SetValue(atePort1.MAC)
+	outer := flow.Packet().Add().Ipv4()
+	outer.Src().SetValue(atePort1.IPv4)
+	outer.Dst().SetValue(decapAddress)
+	inner := flow.Packet().Add().Ipv4()
+	inner.Src().SetValue(atePort1.IPv4)
+	inner.Dst().SetValue(innerDst)
+}
+
+// sendAndCapture sends the flow of the config and returns the packets
+// captured on the given ports.
+func sendAndCapture(t *testing.T, ate *ondatra.ATEDevice, api gosnappi.GosnappiApi, config gosnappi.Config, ports []string) map[string][]*capture.Packet {
+	otg := ate.OTG()
+	var ids []string
+	for _, p := range ports {
+		ids = append(ids, ate.Port(t, p).ID())
+	}
+	otg.PushConfig(t, config)
+	otg.StartProtocols(t)
+	capture.Start(t, api, ids...)
+	otg.StartTraffic(t)
+	time.Sleep(trafficTime)
+	otg.StopTraffic(t)
+	capture.Stop(t, api, ids...)
+	otgutils.LogFlowMetrics(t, otg, config)
+	pkts := map[string][]*capture.Packet{}
+	for i, p := range ports {
+		pkts[p] = capture.Fetch(t, api, ids[i])
+	}
+	return pkts
+}
+
+// TestDecapLookup programs a decapsulating route whose inner packets are
+// looked up in a VRF, with and without falling back to the default
+// network instance, and verifies from captures that the inner packets are
+// forwarded decapsulated per the table of the VRF when it has a route to
+// them, else per the table of the fallback network instance, else
+// dropped.
+func TestDecapLookup(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	config := configureOTG(t, ate)
+	api := fptest.DialOTG(t, ate)
+	ni := *deviations.DefaultNetworkInstance
+
+	c := &gribi.Client{
+		DUT:                  dut,
+		InitialElectionIDLow: 10,
+	}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("gRIBI Connection can not be established: %v", err)
+	}
+	c.BecomeLeader(t)
+	programRoutes(t, c)
+
+	// wantPorts are the ATE ports receiving the packets forwarded by each
+	// network instance.
+	wantPorts := map[string]string{vrf: "port2", ni: "port3"}
+	ports := []string{"port2", "port3"}
+
+	for _, fallback := range []string{ni, ""} {
+		d := &gribi.DecapLookup{
+			NetworkInstance:         ni,
+			Prefix:                  decapAddress + "/32",
+			LookupNetworkInstance:   vrf,
+			FallbackNetworkInstance: fallback,
+			NH:                      decapNH,
+			NHG:                     decapNHG,
+		}
+		if err := d.Validate(); err != nil {
+			t.Fatalf("Invalid decap: %v", err)
+		}
+		d.Program(t, c, fluent.InstalledInRIB)
+
+		for _, innerDst := range []string{"198.51.100.1", "198.51.100.129"} {
+			forwarder, err := d.Forwarder(innerDst, []string{vrfPrefix})
+			if err != nil {
+				t.Fatalf("Cannot look up %s: %v", innerDst, err)
+			}
+			desc := "Inner " + innerDst + " with fallback " + fallback
+			if fallback == "" {
+				desc = "Inner " + innerDst + " without fallback"
+			}
+			t.Run(desc, func(t *testing.T) {
+				addFlow(config, innerDst)
+				pkts := sendAndCapture(t, ate, api, config, ports)
+				for _, p := range ports {
+					n, errs := capture.VerifyDecapsulated(pkts[p], innerDst)
+					for _, err := range errs {
+						t.Errorf("ate:%s: %v", p, err)
+					}
+					switch want := wantPorts[forwarder] == p; {
+					case want && n == 0:
+						t.Errorf("No decapsulated packet to %s captured on ate:%s, want the packets forwarded by %s", innerDst, p, forwarder)
+					case !want && n > 0:
+						t.Errorf("%d decapsulated packets to %s captured on ate:%s, want none", n, innerDst, p)
+					default:
+						t.Logf("Captured %d decapsulated packets to %s on ate:%s", n, innerDst, p)
+					}
+				}
+			})
+		}
+
+		d.Delete(t, c, fluent.InstalledInRIB)
+	}
+}
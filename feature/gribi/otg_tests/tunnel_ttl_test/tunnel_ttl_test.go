@@ -0,0 +1,307 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel_ttl_test
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/otgutils"
+	"github.com/openconfig/gribigo/chk"
+	"github.com/openconfig/gribigo/constants"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+)
+
+var (
+	ttlMode = flag.String("tunnel_ttl_mode", "uniform", "TTL mode of the IP-in-IP tunnels of the DUT, either uniform or pipe.  The DUT is expected to be configured with this mode, e.g. by the binding.")
+	pipeTTL = flag.Uint("tunnel_pipe_ttl", 255, "outer TTL the DUT sets when encapsulating in pipe mode")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The traffic is sent from ate:port1 and captured on
+// ate:port2.
+//
+//   - Source: ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - Destination: dut:port2 -> ate:port2 subnet 192.0.2.4/30
+//
+// Packets to encapPrefix are encapsulated in IP-in-IP from tunnelSrc
+// to tunnelDst, which is routed to ate:port2.  Packets to decapAddress
+// are decapsulated, and the inner packets are routed to ate:port2.
+const (
+	plen4        = 30
+	encapPrefix  = "198.51.100.0/24"
+	encapDst     = "198.51.100.1"
+	tunnelSrc    = "192.0.2.1"
+	tunnelDst    = "203.0.113.1"
+	decapAddress = "203.0.113.100"
+	// sentTTL is the TTL of the packets sent to be encapsulated, and
+	// the inner TTL of the packets sent to be decapsulated.
+	sentTTL = 64
+	// sentOuterTTL is the outer TTL of the packets sent to be
+	// decapsulated, distinct from sentTTL to tell the modes apart.
+	sentOuterTTL = 32
+	trafficTime  = 10 * time.Second
+
+	nhIndex       = 1
+	nhgIndex      = 1
+	encapNHIndex  = 2
+	encapNHGIndex = 2
+	decapNHIndex  = 3
+	decapNHGIndex = 3
+)
+
+var (
+	dutSrc = attrs.Attributes{
+		Desc:    "DUT to ATE source",
+		IPv4:    "192.0.2.1",
+		IPv4Len: plen4,
+	}
+
+	ateSrc = attrs.Attributes{
+		Name:    "src",
+		MAC:     "02:00:01:01:01:01",
+		IPv4:    "192.0.2.2",
+		IPv4Len: plen4,
+	}
+
+	dutDst = attrs.Attributes{
+		Desc:    "DUT to ATE destination",
+		IPv4:    "192.0.2.5",
+		IPv4Len: plen4,
+	}
+
+	ateDst = attrs.Attributes{
+		Name:    "dst",
+		MAC:     "02:00:02:01:01:01",
+		IPv4:    "192.0.2.6",
+		IPv4Len: plen4,
+	}
+)
+
+// configureDUT configures port1 and port2 on the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	p1 := dut.Port(t, "port1")
+	d.Interface(p1.Name()).Replace(t, dutSrc.NewInterface(p1.Name()))
+	p2 := dut.Port(t, "port2")
+	d.Interface(p2.Name()).Replace(t, dutDst.NewInterface(p2.Name()))
+}
+
+// configureOTG returns the OTG configuration of the ATE interfaces,
+// capturing on ate:port2.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	for _, p := range []struct {
+		port      *ondatra.Port
+		ate, peer *attrs.Attributes
+	}{
+		{ate.Port(t, "port1"), &ateSrc, &dutSrc},
+		{ate.Port(t, "port2"), &ateDst, &dutDst},
+	} {
+		config.Ports().Add().SetName(p.port.ID())
+		config.Devices().Add().SetName(p.ate.Name).Ethernets().Add().
+			SetName(p.ate.Name + ".eth").
+			SetPortName(p.port.ID()).
+			SetMac(p.ate.MAC).
+			Ipv4Addresses().Add().
+			SetName(p.ate.Name + ".IPv4").
+			SetAddress(p.ate.IPv4).
+			SetGateway(p.peer.IPv4).
+			SetPrefix(int32(p.ate.IPv4Len))
+	}
+	capture.Enable(config, ate.Port(t, "port2").ID())
+	return config
+}
+
+// addNH adds a next hop entry with the given index, for the next hops
+// the gribi.Client helpers cannot build, and checks it is programmed.
+func addNH(t *testing.T, c *gribi.Client, index uint64, entry fluent.GRIBIEntry) {
+	t.Helper()
+	c.Fluent(t).Modify().AddEntry(t, entry)
+	if err := c.AwaitTimeout(context.Background(), t, time.Minute); err != nil {
+		t.Fatalf("Error waiting to add NH: %v", err)
+	}
+	chk.HasResult(t, c.Fluent(t).Results(t),
+		fluent.OperationResult().
+			WithNextHopOperation(index).
+			WithOperationType(constants.Add).
+			WithProgrammingResult(fluent.InstalledInRIB).
+			AsResult(),
+		chk.IgnoreOperationID(),
+	)
+}
+
+// programTunnels programs the route to the tunnel destination, the
+// encapsulating route and the decapsulating route.
+func programTunnels(t *testing.T, c *gribi.Client) {
+	ni := *deviations.DefaultNetworkInstance
+	c.AddNH(t, nhIndex, ateDst.IPv4, ni, fluent.InstalledInRIB)
+	c.AddNHG(t, nhgIndex, map[uint64]uint64{nhIndex: 1}, ni, fluent.InstalledInRIB)
+	c.AddIPv4(t, tunnelDst+"/32", nhgIndex, ni, "", fluent.InstalledInRIB)
+
+	addNH(t, c, encapNHIndex, fluent.NextHopEntry().
+		WithNetworkInstance(ni).
+		WithIndex(encapNHIndex).
+		WithIPinIP(tunnelSrc, tunnelDst).
+		WithEncapsulateHeader(fluent.IPinIP))
+	c.AddNHG(t, encapNHGIndex, map[uint64]uint64{encapNHIndex: 1}, ni, fluent.InstalledInRIB)
+	c.AddIPv4(t, encapPrefix, encapNHGIndex, ni, "", fluent.InstalledInRIB)
+
+	addNH(t, c, decapNHIndex, fluent.NextHopEntry().
+		WithNetworkInstance(ni).
+		WithIndex(decapNHIndex).
+		WithDecapsulateHeader(fluent.IPinIP).
+		WithNextHopNetworkInstance(ni))
+	c.AddNHG(t, decapNHGIndex, map[uint64]uint64{decapNHIndex: 1}, ni, fluent.InstalledInRIB)
+	c.AddIPv4(t, decapAddress+"/32", decapNHGIndex, ni, "", fluent.InstalledInRIB)
+}
+
+// addFlow adds a flow from ate:port1 to ate:port2 of IPv4 packets to
+// dst with the given TTL.  When innerDst is set, the packets carry an
+// inner IPv4 header to innerDst with the inner TTL.
+func addFlow(config gosnappi.Config, dst string, ttl int32, innerDst string, innerTTL int32) {
+	config.Flows().Clear()
+	flow := config.Flows().Add().SetName("tunnel-ttl")
+	flow.Metrics().SetEnable(true)
+	flow.TxRx().Device().
+		SetTxNames([]string{ateSrc.Name + ".IPv4"}).
+		SetRxNames([]string{ateDst.Name + ".IPv4"})
+	flow.Rate().SetPps(100)
+	flow.Duration().FixedPackets().SetPackets(int32(100 * trafficTime / time.Second))
+	flow.Size().SetFixed(256)
+	flow.Packet().Add().Ethernet().Src().SetValue(ateSrc.MAC)
+	ip := flow.Packet().Add().Ipv4()
+	ip.Src().SetValue(ateSrc.IPv4)
+	ip.Dst().SetValue(dst)
+	ip.TimeToLive().SetValue(ttl)
+	if innerDst != "" {
+		inner := flow.Packet().Add().Ipv4()
+		inner.Src().SetValue(ateSrc.IPv4)
+		inner.Dst().SetValue(innerDst)
+		inner.TimeToLive().SetValue(innerTTL)
+	}
+}
+
+// sendAndCapture sends the flow of the config and returns the packets
+// captured on ate:port2.
+func sendAndCapture(t *testing.T, ate *ondatra.ATEDevice, api gosnappi.GosnappiApi, config gosnappi.Config) []*capture.Packet {
+	otg := ate.OTG()
+	capPort := ate.Port(t, "port2").ID()
+	otg.PushConfig(t, config)
+	otg.StartProtocols(t)
+	capture.Start(t, api, capPort)
+	otg.StartTraffic(t)
+	time.Sleep(trafficTime)
+	otg.StopTraffic(t)
+	capture.Stop(t, api, capPort)
+	otgutils.LogFlowMetrics(t, otg, config)
+	return capture.Fetch(t, api, capPort)
+}
+
+// maxErrors bounds the errors reported, as every packet of a flow
+// usually fails the same way.
+const maxErrors = 5
+
+// TestTunnelTTL verifies that the DUT sets the TTL of the packets it
+// encapsulates in and decapsulates from IP-in-IP tunnels programmed by
+// gRIBI according to its uniform or pipe TTL mode.
+func TestTunnelTTL(t *testing.T) {
+	var mode capture.TTLMode
+	switch *ttlMode {
+	case "uniform":
+		mode = capture.Uniform
+	case "pipe":
+		mode = capture.Pipe
+	default:
+		t.Fatalf("Invalid -tunnel_ttl_mode %q, want uniform or pipe", *ttlMode)
+	}
+
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	config := configureOTG(t, ate)
+	api := fptest.DialOTG(t, ate)
+
+	c := &gribi.Client{DUT: dut, Persistence: true}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("Could not initialize gRIBI: %v", err)
+	}
+	c.BecomeLeader(t)
+	programTunnels(t, c)
+
+	t.Run("Encap", func(t *testing.T) {
+		addFlow(config, encapDst, sentTTL, "", 0)
+		var n int
+		var errs []error
+		for _, p := range sendAndCapture(t, ate, api, config) {
+			if p.IP == nil || p.IP.Dst.String() != tunnelDst {
+				continue
+			}
+			n++
+			errs = append(errs, capture.VerifyEncapTTL(p, mode, sentTTL, uint8(*pipeTTL))...)
+		}
+		if n == 0 {
+			t.Fatalf("No packet encapsulated to %s captured", tunnelDst)
+		}
+		t.Logf("Captured %d encapsulated packets", n)
+		for i, err := range errs {
+			if i == maxErrors {
+				t.Errorf("... and %d more errors", len(errs)-maxErrors)
+				break
+			}
+			t.Error(err)
+		}
+	})
+
+	t.Run("Decap", func(t *testing.T) {
+		addFlow(config, decapAddress, sentOuterTTL, ateDst.IPv4, sentTTL)
+		var n int
+		var errs []error
+		for _, p := range sendAndCapture(t, ate, api, config) {
+			if p.IP == nil || p.Inner != nil || p.IP.Dst.String() != ateDst.IPv4 {
+				continue
+			}
+			n++
+			if err := capture.VerifyDecapTTL(p, mode, sentOuterTTL, sentTTL); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if n == 0 {
+			t.Fatalf("No packet decapsulated from %s captured", decapAddress)
+		}
+		t.Logf("Captured %d decapsulated packets", n)
+		for i, err := range errs {
+			if i == maxErrors {
+				t.Errorf("... and %d more errors", len(errs)-maxErrors)
+				break
+			}
+			t.Error(err)
+		}
+	})
+}
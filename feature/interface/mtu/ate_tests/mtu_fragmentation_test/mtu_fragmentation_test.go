@@ -0,0 +1,207 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtu_fragmentation_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/mtu"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	ingressMTU = flag.Uint("mtu_ingress", 9216, "interface MTU of dut:port1, large enough to receive every packet of the test")
+	egressMTU  = flag.Uint("mtu_egress_ip", 1500, "IPv4 and IPv6 MTU of dut:port2, which the packets of the test are routed to")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  Packets are sent from ate:port1 to ate:port2, and the
+// ICMP errors of the DUT are received back on ate:port1.
+//
+//   - Source: ate:port1 -> dut:port1 subnet 192.0.2.0/30 2001:db8::0/126
+//   - Destination: dut:port2 -> ate:port2 subnet 192.0.2.4/30 2001:db8::4/126
+const (
+	plen4       = 30
+	plen6       = 126
+	trafficTime = 15 * time.Second
+)
+
+var (
+	dutSrc = attrs.Attributes{
+		Desc:    "DUT to ATE source",
+		IPv4:    "192.0.2.1",
+		IPv6:    "2001:db8::1",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+
+	ateSrc = attrs.Attributes{
+		Name:    "src",
+		IPv4:    "192.0.2.2",
+		IPv6:    "2001:db8::2",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+
+	dutDst = attrs.Attributes{
+		Desc:    "DUT to ATE destination",
+		IPv4:    "192.0.2.5",
+		IPv6:    "2001:db8::5",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+
+	ateDst = attrs.Attributes{
+		Name:    "dst",
+		IPv4:    "192.0.2.6",
+		IPv6:    "2001:db8::6",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+)
+
+// configureDUT configures port1 with the ingress MTU and port2 with
+// the egress IP MTU.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port         *ondatra.Port
+		a            *attrs.Attributes
+		ifMTU, ipMTU uint16
+	}{
+		{dut.Port(t, "port1"), &dutSrc, uint16(*ingressMTU), 0},
+		{dut.Port(t, "port2"), &dutDst, 0, uint16(*egressMTU)},
+	} {
+		i := &telemetry.Interface{Name: ygot.String(p.port.Name())}
+		p.a.ConfigInterface(i)
+		mtu.Configure(i, p.ifMTU, p.ipMTU)
+		di := d.Interface(p.port.Name())
+		fptest.LogYgot(t, p.port.String(), di, i)
+		di.Replace(t, i)
+	}
+}
+
+// configureATE configures the ATE interfaces with an MTU large enough
+// to send packets exceeding the egress MTU of the DUT.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.ATETopology {
+	top := ate.Topology().New()
+	for _, p := range []struct {
+		port      *ondatra.Port
+		ate, peer *attrs.Attributes
+	}{
+		{ate.Port(t, "port1"), &ateSrc, &dutSrc},
+		{ate.Port(t, "port2"), &ateDst, &dutDst},
+	} {
+		i := p.ate.AddToATE(top, p.port, p.peer)
+		i.Ethernet().WithMTU(uint16(*ingressMTU))
+	}
+	top.Push(t).StartProtocols(t)
+	return top
+}
+
+// testCase is a flow of packets of a given size, relative to the
+// egress MTU.
+type testCase struct {
+	desc string
+	ipv6 bool
+	df   bool
+	// over is the number of bytes the packets exceed the egress MTU
+	// by, or fall short of it when negative.
+	over int
+}
+
+var testCases = []testCase{
+	{desc: "IPv4 at MTU with DF", df: true, over: 0},
+	{desc: "IPv4 over MTU with DF", df: true, over: 100},
+	{desc: "IPv4 over MTU without DF", df: false, over: 100},
+	{desc: "IPv6 at MTU", ipv6: true, over: 0},
+	{desc: "IPv6 over MTU", ipv6: true, over: 100},
+}
+
+// run sends the flow of the test case and verifies the counters
+// against the outcome expected for its packets.
+func (tc *testCase) run(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, top *ondatra.ATETopology) {
+	size := uint32(int(*egressMTU) + tc.over)
+	want := mtu.Expect(size, uint32(*egressMTU), tc.ipv6, tc.df)
+	t.Logf("Sending %d byte packets, expecting them to be %v", size, want)
+
+	dst := ateDst.IPv4
+	if tc.ipv6 {
+		dst = ateDst.IPv6
+	}
+	flow := mtu.Flow(ate, "mtu", top.Interfaces()[ateSrc.Name], top.Interfaces()[ateDst.Name], dst, tc.ipv6, size, tc.df)
+
+	egress := dut.Port(t, "port2")
+	src := ate.Port(t, "port1")
+	before := mtu.Read(t, dut, egress, ate, src, tc.ipv6)
+	ate.Traffic().Start(t, flow)
+	time.Sleep(trafficTime)
+	ate.Traffic().Stop(t)
+	// Let the counters settle.
+	time.Sleep(5 * time.Second)
+	diff := mtu.Read(t, dut, egress, ate, src, tc.ipv6).Sub(before)
+
+	fpc := ate.Telemetry().Flow(flow.Name()).Counters()
+	sent := fpc.OutPkts().Get(t)
+	if sent == 0 {
+		t.Fatal("Flow did not send any packet")
+	}
+	t.Logf("Sent %d packets, counters increased by %+v", sent, diff)
+	for _, err := range mtu.Verify(diff, sent, want) {
+		t.Error(err)
+	}
+	if want == mtu.Rejected {
+		if got := fpc.InPkts().Get(t); got != 0 {
+			t.Errorf("Flow received %d packets, want 0", got)
+		}
+	}
+}
+
+// TestMTUFragmentation verifies that packets exceeding the egress MTU
+// of the DUT are fragmented, or dropped with an ICMP Fragmentation
+// Needed or Packet Too Big error to the source when they cannot be
+// fragmented.
+//
+// config_path:/interfaces/interface/config/mtu
+// config_path:/interfaces/interface/subinterfaces/subinterface/ipv4/config/mtu
+// config_path:/interfaces/interface/subinterfaces/subinterface/ipv6/config/mtu
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/ipv4/state/counters/out-pkts
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/ipv4/state/counters/out-discarded-pkts
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/ipv6/state/counters/out-pkts
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/ipv6/state/counters/out-discarded-pkts
+func TestMTUFragmentation(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	top := configureATE(t, ate)
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.run(t, dut, ate, top)
+		})
+	}
+}
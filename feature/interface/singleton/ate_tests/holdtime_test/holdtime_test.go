@@ -0,0 +1,136 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package holdtime_test implements RT-5.4.
+package holdtime_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/holdtime"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var tolerance = flag.Duration("holdtime_tolerance", time.Second, "Tolerance of the delay of the oper-status changes of the DUT after the hold time.")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, whose link the ATE
+// flaps by disabling and enabling its port.
+//
+//   - dut:port1 -> ate:port1 subnet 192.0.2.0/30
+const (
+	plen4    = 30
+	holdUp   = 3 * time.Second
+	holdDown = 2 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{
+		Desc:    "DUT to ATE",
+		IPv4:    "192.0.2.1",
+		IPv4Len: plen4,
+	}
+
+	atePort1 = attrs.Attributes{
+		Name:    "port1",
+		IPv4:    "192.0.2.2",
+		IPv4Len: plen4,
+	}
+)
+
+// configureDUT configures port1 on the DUT with the hold time.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) string {
+	p := dut.Port(t, "port1")
+	i := dutPort1.NewInterface(p.Name())
+	holdtime.Set(i, holdUp, holdDown)
+	dut.Config().Interface(p.Name()).Replace(t, i)
+	return p.Name()
+}
+
+// configureATE configures port1 on the ATE.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.ATETopology {
+	top := ate.Topology().New()
+	atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	top.Push(t).StartProtocols(t)
+	return top
+}
+
+// TestHoldTime verifies that the DUT reports its hold time, that it
+// suppresses the flaps of the link shorter than the hold-time down, and
+// that it delays reporting the changes of longer flaps by the hold
+// time.
+func TestHoldTime(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	intf := configureDUT(t, dut)
+	top := configureATE(t, ate)
+	defer top.StopProtocols(t)
+	port := ate.Port(t, "port1")
+
+	dut.Telemetry().Interface(intf).OperStatus().Await(t, time.Minute, telemetry.Interface_OperStatus_UP)
+	if err := holdtime.Check(t, dut, intf, holdUp, holdDown); err != nil {
+		t.Error(err)
+	}
+
+	t.Run("short flap", func(t *testing.T) {
+		before := holdtime.CarrierTransitions(t, dut, intf)
+		c := holdtime.Collect(t, dut, intf, holdDown+holdUp+5*time.Second)
+		holdtime.Flap(t, ate, port, holdDown/4)
+		events := holdtime.Events(t, c.Await(t))
+		t.Logf("Oper-status changes of %s: %v", intf, events)
+		if got := holdtime.CountDown(events); got != 0 {
+			t.Errorf("A flap of %v went down %d times, want 0 for a hold-time down of %v", holdDown/4, got, holdDown)
+		}
+		if got := holdtime.CarrierTransitions(t, dut, intf) - before; got != 0 {
+			t.Errorf("A flap of %v counted %d carrier transitions, want 0", holdDown/4, got)
+		}
+	})
+
+	t.Run("long flap", func(t *testing.T) {
+		flap := 2 * holdDown
+		c := holdtime.Collect(t, dut, intf, flap+holdUp+*tolerance+5*time.Second)
+		disabled := time.Now()
+		holdtime.Flap(t, ate, port, flap)
+		enabled := time.Now()
+		events := holdtime.Events(t, c.Await(t))
+		t.Logf("Oper-status changes of %s: %v", intf, events)
+		if got := holdtime.CountDown(events); got != 1 {
+			t.Errorf("A flap of %v went down %d times, want 1", flap, got)
+		}
+
+		down, ok := holdtime.First(events, telemetry.Interface_OperStatus_DOWN, disabled)
+		if !ok {
+			t.Fatalf("%s did not go down after a flap of %v", intf, flap)
+		}
+		if err := holdtime.CheckDelay(down.Time.Sub(disabled), holdDown, *tolerance); err != nil {
+			t.Errorf("Going down: %v", err)
+		}
+		up, ok := holdtime.First(events, telemetry.Interface_OperStatus_UP, enabled)
+		if !ok {
+			t.Fatalf("%s did not come back up after a flap of %v", intf, flap)
+		}
+		if err := holdtime.CheckDelay(up.Time.Sub(enabled), holdUp, *tolerance); err != nil {
+			t.Errorf("Coming up: %v", err)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package port_settings_test implements RT-5.6: Port Speed, FEC and
+// Autonegotiation.
+package port_settings_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/ethernet"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, whose link retrains
+// each time the Ethernet settings of the DUT change.
+//
+//   - dut:port1 -> ate:port1 subnet 192.0.2.0/30
+const (
+	plen4 = 30
+	// linkTimeout is how long the link is given to come back up after the
+	// settings change.
+	linkTimeout = time.Minute
+)
+
+var (
+	dutPort1 = attrs.Attributes{
+		Desc:    "DUT to ATE",
+		IPv4:    "192.0.2.1",
+		IPv4Len: plen4,
+	}
+
+	atePort1 = attrs.Attributes{
+		Name:    "port1",
+		IPv4:    "192.0.2.2",
+		IPv4Len: plen4,
+	}
+)
+
+// fecModes are, for each port speed, the FEC mode the ATE uses by default
+// and a FEC mode not defined for the speed.
+var fecModes = map[telemetry.E_IfEthernet_ETHERNET_SPEED]struct {
+	valid, invalid telemetry.E_IfEthernet_INTERFACE_FEC
+}{
+	telemetry.IfEthernet_ETHERNET_SPEED_SPEED_10GB:  {telemetry.IfEthernet_INTERFACE_FEC_FEC_DISABLED, telemetry.IfEthernet_INTERFACE_FEC_FEC_RS544},
+	telemetry.IfEthernet_ETHERNET_SPEED_SPEED_100GB: {telemetry.IfEthernet_INTERFACE_FEC_FEC_RS528, telemetry.IfEthernet_INTERFACE_FEC_FEC_RS544_2X_INTERLEAVE},
+	telemetry.IfEthernet_ETHERNET_SPEED_SPEED_400GB: {telemetry.IfEthernet_INTERFACE_FEC_FEC_RS544_2X_INTERLEAVE, telemetry.IfEthernet_INTERFACE_FEC_FEC_FC},
+}
+
+// configureDUT configures port1 on the DUT with the settings.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice, s *ethernet.Settings) string {
+	p := dut.Port(t, "port1")
+	i := dutPort1.NewInterface(p.Name())
+	s.Set(i.GetOrCreateEthernet())
+	dut.Config().Interface(p.Name()).Replace(t, i)
+	return p.Name()
+}
+
+// configureATE configures port1 on the ATE.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.ATETopology {
+	top := ate.Topology().New()
+	atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	top.Push(t).StartProtocols(t)
+	return top
+}
+
+// TestPortSettings configures the port speed, FEC mode and
+// autonegotiation of a DUT port, and verifies that the link comes back up
+// with the settings reported in telemetry.  It then verifies that the DUT
+// rejects combinations it does not support, and that the link is still
+// up with the last supported settings.
+//
+// config_path:/interfaces/interface/ethernet/config/port-speed
+// config_path:/interfaces/interface/ethernet/config/fec-mode
+// config_path:/interfaces/interface/ethernet/config/auto-negotiate
+// config_path:/interfaces/interface/ethernet/config/duplex-mode
+// telemetry_path:/interfaces/interface/ethernet/state/port-speed
+// telemetry_path:/interfaces/interface/ethernet/state/fec-mode
+// telemetry_path:/interfaces/interface/ethernet/state/auto-negotiate
+// telemetry_path:/interfaces/interface/ethernet/state/negotiated-port-speed
+// telemetry_path:/interfaces/interface/state/oper-status
+func TestPortSettings(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	speed, ok := ethernet.Speed(dut.Port(t, "port1").Speed())
+	if !ok {
+		t.Skipf("Speed of %v unknown", dut.Port(t, "port1"))
+	}
+	fec, ok := fecModes[speed]
+	if !ok {
+		t.Skipf("FEC modes of %v unknown", speed)
+	}
+
+	forced := &ethernet.Settings{Speed: speed}
+	intf := configureDUT(t, dut, forced)
+	top := configureATE(t, ate)
+	defer top.StopProtocols(t)
+	timeout := args.Convergence(linkTimeout)
+	if !ethernet.AwaitUp(t, dut, intf, timeout) {
+		t.Fatalf("Interface %s not up with %v", intf, forced)
+	}
+
+	for _, s := range []*ethernet.Settings{
+		forced,
+		{Speed: speed, FEC: fec.valid},
+		{Speed: speed, AutoNegotiate: true},
+	} {
+		t.Run(s.String(), func(t *testing.T) {
+			ethernet.Configure(t, dut, intf, s)
+			if !ethernet.AwaitUp(t, dut, intf, timeout) {
+				t.Fatalf("Interface %s not up after %v", intf, timeout)
+			}
+			e := dut.Telemetry().Interface(intf).Ethernet().Get(t)
+			for _, err := range ethernet.Check(e, s) {
+				t.Errorf("Interface %s: %v", intf, err)
+			}
+		})
+	}
+
+	last := &ethernet.Settings{Speed: speed, AutoNegotiate: true}
+	for _, s := range []*ethernet.Settings{
+		{Speed: speed, FEC: fec.invalid},
+		{Speed: telemetry.IfEthernet_ETHERNET_SPEED_SPEED_800GB},
+	} {
+		t.Run("unsupported "+s.String(), func(t *testing.T) {
+			if err := ethernet.TryConfigure(t, dut, intf, s); err == nil {
+				t.Errorf("Interface %s accepted %v, want rejected", intf, s)
+				ethernet.Configure(t, dut, intf, last)
+			} else {
+				t.Logf("Rejected as expected: %v", err)
+			}
+			if !ethernet.AwaitUp(t, dut, intf, timeout) {
+				t.Fatalf("Interface %s not up with %v", intf, last)
+			}
+			e := dut.Telemetry().Interface(intf).Ethernet().Get(t)
+			for _, err := range ethernet.Check(e, last) {
+				t.Errorf("Interface %s: %v", intf, err)
+			}
+		})
+	}
+}
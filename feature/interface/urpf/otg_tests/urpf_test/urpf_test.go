@@ -0,0 +1,220 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package urpf_test implements RT-13.1: Unicast Reverse Path Forwarding.
+package urpf_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/otgutils"
+	"github.com/openconfig/featureprofiles/internal/urpf"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, on which the DUT checks
+// the sources of the traffic with uRPF, and dut:port2 -> ate:port2, the
+// destination.  The remote network is routed to ate:port2, and the
+// unroutable network is not routed at all.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - dut:port2 -> ate:port2 subnet 192.0.2.4/30
+//   - remote network 198.51.100.0/24, routed to ate:port2
+//   - unroutable network 203.0.113.0/24
+const (
+	plen          = 30
+	staticName    = "STATIC"
+	remotePrefix  = "198.51.100.0/24"
+	remoteSrc     = "198.51.100.1"
+	unroutableSrc = "203.0.113.1"
+	pps           = 100
+	trafficTime   = 10 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv4Len: plen}
+
+	// sources are the flows from ate:port1, by the source address they
+	// spoof, if any.
+	sources = []struct {
+		flow, src string
+		// remote is whether the source is routed to another interface,
+		// failing the strict check only.
+		remote bool
+	}{
+		{flow: "legitimate", src: atePort1.IPv4},
+		{flow: "remote", src: remoteSrc, remote: true},
+		{flow: "unroutable", src: unroutableSrc},
+	}
+)
+
+// configureDUT configures the ports of the DUT and the static route to
+// the remote network.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	static := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC,
+		Name:       ygot.String(staticName),
+	}
+	s := static.GetOrCreateStatic(remotePrefix)
+	s.GetOrCreateNextHop("1").NextHop = telemetry.UnionString(atePort2.IPv4)
+	staticPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName)
+	fptest.LogYgot(t, "DUT static routes", staticPath, static)
+	staticPath.Replace(t, static)
+}
+
+// configureOTG returns the OTG configuration of the ATE interfaces, with a
+// flow from each source of ate:port1 to ate:port2.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	for _, p := range []struct {
+		port      string
+		ate, peer *attrs.Attributes
+	}{{"port1", &atePort1, &dutPort1}, {"port2", &atePort2, &dutPort2}} {
+		id := ate.Port(t, p.port).ID()
+		config.Ports().Add().SetName(id)
+		config.Devices().Add().SetName(p.ate.Name).Ethernets().Add().
+			SetName(p.ate.Name + ".eth").
+			SetPortName(id).
+			SetMac(p.ate.MAC).
+			Ipv4Addresses().Add().
+			SetName(p.ate.Name + ".IPv4").
+			SetAddress(p.ate.IPv4).
+			SetGateway(p.peer.IPv4).
+			SetPrefix(int32(p.ate.IPv4Len))
+	}
+	for _, s := range sources {
+		flow := config.Flows().Add().SetName(s.flow)
+		flow.Metrics().SetEnable(true)
+		flow.TxRx().Device().
+			SetTxNames([]string{atePort1.Name + ".IPv4"}).
+			SetRxNames([]string{atePort2.Name + ".IPv4"})
+		flow.Rate().SetPps(pps)
+		flow.Duration().FixedPackets().SetPackets(int32(pps * trafficTime / time.Second))
+		flow.Size().SetFixed(256)
+		flow.Packet().Add().Ethernet().Src().SetValue(atePort1.MAC)
+		ip := flow.Packet().Add().Ipv4()
+		ip.Src().SetValue(s.src)
+		ip.Dst().SetValue(atePort2.IPv4)
+		flow.Packet().Add().Udp()
+	}
+	return config
+}
+
+// set applies the SetRequest to the DUT.
+func set(t *testing.T, dut *ondatra.DUTDevice, req *gpb.SetRequest, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Invalid uRPF configuration: %v", err)
+	}
+	if _, err := dut.RawAPIs().GNMI().Default(t).Set(context.Background(), req); err != nil {
+		t.Fatalf("Cannot configure uRPF on %s: %v", dut.Name(), err)
+	}
+}
+
+// checkTraffic sends the flows of the sources, and verifies from the flow
+// counters that the traffic of the spoofed sources is dropped and the
+// legitimate traffic forwarded, and from the discard counter of dut:port1
+// that the DUT dropped the spoofed traffic.
+func checkTraffic(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, config gosnappi.Config, c *urpf.Config) {
+	otg := ate.OTG()
+	before := urpf.ReadDiscards(t, dut, c)
+	otg.StartTraffic(t)
+	time.Sleep(trafficTime)
+	otg.StopTraffic(t)
+	after := urpf.ReadDiscards(t, dut, c)
+	otgutils.LogFlowMetrics(t, otg, config)
+
+	var traffic []urpf.Traffic
+	for _, s := range sources {
+		cs := otg.Telemetry().Flow(s.flow).Get(t).GetCounters()
+		traffic = append(traffic, urpf.Traffic{
+			Flow:     s.flow,
+			Spoofed:  s.src != atePort1.IPv4 && (!s.remote || c.Mode == urpf.Strict),
+			Sent:     cs.GetOutPkts(),
+			Received: cs.GetInPkts(),
+		})
+	}
+	for _, err := range urpf.Check(traffic) {
+		t.Error(err)
+	}
+	if !*deviations.SubInterfacePacketCountersSupported {
+		t.Log("Skipping the check of the discard counter, see -deviation_subinterface_packet_counters_supported")
+		return
+	}
+	t.Logf("dut:port1 discarded %d packets", after-before)
+	if err := urpf.CheckDiscards(traffic, after-before); err != nil {
+		t.Errorf("dut:port1: %v", err)
+	}
+}
+
+// TestURPF verifies that the DUT, with uRPF enabled on dut:port1, drops
+// the traffic of ate:port1 from sources failing the check of its mode,
+// counting it as discarded, and forwards the legitimate traffic.  Strict
+// mode drops the traffic from the remote network, routed to ate:port2,
+// and the unroutable network; loose mode drops only the traffic from the
+// unroutable network.
+//
+// config_path:/interfaces/interface/subinterfaces/subinterface/ipv4/urpf/config/enabled
+// config_path:/interfaces/interface/subinterfaces/subinterface/ipv4/urpf/config/mode
+// config_path:/interfaces/interface/subinterfaces/subinterface/ipv4/urpf/config/allow-default-route
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/ipv4/state/counters/in-discarded-pkts
+func TestURPF(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	config := configureOTG(t, ate)
+	otg := ate.OTG()
+	otg.PushConfig(t, config)
+	otg.StartProtocols(t)
+
+	for _, mode := range []urpf.Mode{urpf.Strict, urpf.Loose} {
+		t.Run(string(mode), func(t *testing.T) {
+			c := &urpf.Config{Interface: dut.Port(t, "port1").Name(), Mode: mode}
+			req, err := c.SetRequest()
+			set(t, dut, req, err)
+			defer func() {
+				req, err := c.DeleteRequest()
+				set(t, dut, req, err)
+			}()
+			checkTraffic(t, dut, ate, config, c)
+		})
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bgp_aggregate_test implements RT-1.17: BGP Route Aggregation.
+package bgp_aggregate_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpagg"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and ate:port2 ->
+// dut:port2, which advertise the contributing routes of the aggregates,
+// and dut:port3 -> ate:port3, which receives the routes advertised by
+// the DUT.  Each ATE port has an eBGP session with the DUT.
+//
+//   - ate:port1 (AS 64497) -> dut:port1 (AS 64496) subnet 192.0.2.0/30
+//   - ate:port2 (AS 64498) -> dut:port2 subnet 192.0.2.4/30
+//   - dut:port3 -> ate:port3 (AS 64499) subnet 192.0.2.8/30
+const (
+	dutAS        = 64496
+	plen         = 30
+	routeTimeout = 2 * time.Minute
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", MAC: "02:00:03:01:01:01", IPv4: "192.0.2.10", IPv4Len: plen}
+
+	// peers are the eBGP peers of the ATE, and the routes they advertise.
+	peers = []struct {
+		port     string
+		ate, dut *attrs.Attributes
+		as       uint32
+		routes   []string
+	}{
+		{"port1", &atePort1, &dutPort1, 64497, []string{"198.51.100.0/26", "203.0.113.0/26"}},
+		{"port2", &atePort2, &dutPort2, 64498, []string{"198.51.100.64/26", "203.0.113.64/26"}},
+		{"port3", &atePort3, &dutPort3, 64499, nil},
+	}
+
+	// plain is an aggregate advertised along its contributing routes, and
+	// summary an aggregate suppressing them, with an AS_SET of their ASes.
+	plain   = &bgpagg.Aggregate{Prefix: "198.51.100.0/24"}
+	summary = &bgpagg.Aggregate{Prefix: "203.0.113.0/24", SummaryOnly: true, ASSet: true}
+)
+
+// peerName returns the name of the BGP peer of the ATE port.
+func peerName(a *attrs.Attributes) string {
+	return a.Name + ".BGP4.peer"
+}
+
+// configureDUT configures the ports of the DUT, its eBGP sessions with
+// the ATE ports, accepting all the routes, and the aggregates.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice, aggs ...*bgpagg.Aggregate) {
+	d := dut.Config()
+	for _, p := range peers {
+		i := p.dut.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	niName := *deviations.DefaultNetworkInstance
+	ni := &telemetry.NetworkInstance{Name: ygot.String(niName)}
+	bgp := ni.GetOrCreateProtocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").GetOrCreateBgp()
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String(dutPort1.IPv4)
+	for _, p := range peers {
+		nbr := bgp.GetOrCreateNeighbor(p.ate.IPv4)
+		nbr.PeerAs = ygot.Uint32(p.as)
+		nbr.Enabled = ygot.Bool(true)
+		nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+		ap := nbr.GetOrCreateApplyPolicy()
+		ap.DefaultImportPolicy = telemetry.RoutingPolicy_DefaultPolicyType_ACCEPT_ROUTE
+		ap.DefaultExportPolicy = telemetry.RoutingPolicy_DefaultPolicyType_ACCEPT_ROUTE
+	}
+	if err := bgpagg.Configure(ni, aggs...); err != nil {
+		t.Fatalf("Cannot configure the aggregates: %v", err)
+	}
+
+	niPath := d.NetworkInstance(niName)
+	fptest.LogYgot(t, "DUT network instance", niPath, ni)
+	niPath.Update(t, ni)
+}
+
+// configureOTG returns the OTG configuration of the ATE ports, with their
+// eBGP sessions and routes.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	for _, p := range peers {
+		id := ate.Port(t, p.port).ID()
+		config.Ports().Add().SetName(id)
+		dev := config.Devices().Add().SetName(p.ate.Name)
+		ip := dev.Ethernets().Add().
+			SetName(p.ate.Name + ".eth").
+			SetPortName(id).
+			SetMac(p.ate.MAC).
+			Ipv4Addresses().Add().
+			SetName(p.ate.Name + ".IPv4").
+			SetAddress(p.ate.IPv4).
+			SetGateway(p.dut.IPv4).
+			SetPrefix(int32(p.ate.IPv4Len))
+		peer := dev.Bgp().SetRouterId(p.ate.IPv4).
+			Ipv4Interfaces().Add().SetIpv4Name(ip.Name()).
+			Peers().Add().SetName(peerName(p.ate)).
+			SetPeerAddress(p.dut.IPv4).
+			SetAsNumber(int32(p.as)).
+			SetAsType(gosnappi.BgpV4PeerAsType.EBGP)
+		for _, r := range p.routes {
+			_, n, err := net.ParseCIDR(r)
+			if err != nil {
+				t.Fatalf("Invalid route %q: %v", r, err)
+			}
+			l, _ := n.Mask.Size()
+			peer.V4Routes().Add().SetName(p.ate.Name + " " + r).
+				SetNextHopIpv4Address(p.ate.IPv4).
+				SetNextHopAddressType(gosnappi.BgpV4RouteRangeNextHopAddressType.IPV4).
+				SetNextHopMode(gosnappi.BgpV4RouteRangeNextHopMode.MANUAL).
+				Addresses().Add().SetAddress(n.IP.String()).SetPrefix(int32(l)).SetCount(1)
+		}
+	}
+	return config
+}
+
+// routes returns all the routes advertised by the ATE.
+func routes() []string {
+	var rs []string
+	for _, p := range peers {
+		rs = append(rs, p.routes...)
+	}
+	return rs
+}
+
+// checkAggregates configures the aggregates on the DUT, and verifies that
+// the DUT has them and the contributing routes in its local RIB, and that
+// ate:port3 receives the aggregates and the routes they do not suppress.
+func checkAggregates(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, aggs ...*bgpagg.Aggregate) {
+	configureDUT(t, dut, aggs...)
+	config := configureOTG(t, ate)
+	otg := ate.OTG()
+	otg.PushConfig(t, config)
+	otg.StartProtocols(t)
+	defer otg.StopProtocols(t)
+
+	var prefixes []string
+	for _, a := range aggs {
+		prefixes = append(prefixes, a.Prefix)
+	}
+	for _, err := range bgpagg.AwaitLocRib(t, dut, append(prefixes, routes()...), routeTimeout) {
+		t.Errorf("DUT local RIB: %v", err)
+	}
+
+	want, suppressed, err := bgpagg.Expect(aggs, routes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, errs := bgpagg.AwaitReceived(t, otg, peerName(&atePort3), want, suppressed, routeTimeout)
+	for _, err := range errs {
+		t.Errorf("ate:port3: %v", err)
+	}
+
+	for _, a := range aggs {
+		if !a.ASSet {
+			continue
+		}
+		r := got[a.Prefix]
+		if r == nil {
+			continue
+		}
+		var ases []uint32
+		for _, p := range peers {
+			for _, c := range p.routes {
+				if ok, _ := a.Contains(c); ok {
+					ases = append(ases, p.as)
+					break
+				}
+			}
+		}
+		t.Logf("ate:port3 received %s with AS_SET %v", a.Prefix, bgpagg.ASSet(r.AsPath))
+		if err := bgpagg.CheckASSet(r.AsPath, ases); err != nil {
+			t.Errorf("ate:port3: %s: %v", a.Prefix, err)
+		}
+	}
+}
+
+// TestAggregate verifies that the DUT advertises a local aggregate to
+// its BGP neighbors along its contributing routes.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/local-aggregates/aggregate/config/prefix
+// config_path:/network-instances/network-instance/protocols/protocol/local-aggregates/aggregate/config/discard
+// config_path:/network-instances/network-instance/table-connections/table-connection/config/src-protocol
+// config_path:/network-instances/network-instance/table-connections/table-connection/config/dst-protocol
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/rib/afi-safis/afi-safi/ipv4-unicast/loc-rib/routes/route/state/prefix
+func TestAggregate(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	checkAggregates(t, dut, ate, plain)
+}
+
+// TestSummaryOnlyASSet verifies that the DUT, configured out of band with
+// the summary-only and as-set options for an aggregate, advertises it
+// with an AS_SET of the ASes of its contributing routes and suppresses
+// them, while it still advertises the routes contributing to an
+// aggregate without the options.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/local-aggregates/aggregate/config/prefix
+// config_path:/network-instances/network-instance/protocols/protocol/local-aggregates/aggregate/config/discard
+// config_path:/network-instances/network-instance/table-connections/table-connection/config/src-protocol
+// config_path:/network-instances/network-instance/table-connections/table-connection/config/dst-protocol
+// telemetry_path:/network-instances/network-instance/protocols/protocol/bgp/rib/afi-safis/afi-safi/ipv4-unicast/loc-rib/routes/route/state/prefix
+func TestSummaryOnlyASSet(t *testing.T) {
+	bgpagg.Require(t, plain, summary)
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	checkAggregates(t, dut, ate, plain, summary)
+}
@@ -0,0 +1,207 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package macsec_test implements MACSEC-1.1: MACsec Link Encryption.
+package macsec_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/macsec"
+	"github.com/openconfig/ondatra"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of dut1:port1 <-> dut2:port1, secured with MACsec
+// keyed by MKA with a pre-shared key.  dut1 is the key server.
+//
+//   - dut1:port1 <-> dut2:port1 subnet 192.0.2.0/30
+const (
+	plen     = 30
+	policy   = "MACSEC"
+	keychain = "MKA"
+	cipher   = macsec.GCMAES256
+	pings    = 20
+	// mkaTimeout is how long MKA may take to secure the link, or to
+	// notice that the keys of its ends do not match.
+	mkaTimeout = 2 * time.Minute
+)
+
+var (
+	dut1Port1 = attrs.Attributes{Desc: "dut1Port1", IPv4: "192.0.2.1", IPv4Len: plen}
+	dut2Port1 = attrs.Attributes{Desc: "dut2Port1", IPv4: "192.0.2.2", IPv4Len: plen}
+
+	key = macsec.Key{
+		CKN: "0123456789abcdef0123456789abcdef",
+		CAK: "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff",
+	}
+	// mismatched is a key of the same name with another secret.
+	mismatched = macsec.Key{
+		CKN: key.CKN,
+		CAK: "ffeeddccbbaa99887766554433221100ffeeddccbbaa99887766554433221100",
+	}
+)
+
+// set applies the SetRequest to the DUT.
+func set(t *testing.T, dut *ondatra.DUTDevice, req *gpb.SetRequest, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Invalid MACsec configuration of %s: %v", dut.Name(), err)
+	}
+	if _, err := dut.RawAPIs().GNMI().Default(t).Set(context.Background(), req); err != nil {
+		t.Fatalf("Cannot configure MACsec on %s: %v", dut.Name(), err)
+	}
+}
+
+// configure configures port1 of the DUT, and MACsec on it with the key.
+func configure(t *testing.T, dut *ondatra.DUTDevice, a *attrs.Attributes, priority uint8) *macsec.Config {
+	p1 := dut.Port(t, "port1").Name()
+	dut.Config().Interface(p1).Replace(t, a.NewInterface(p1))
+	c := &macsec.Config{
+		Policy:            policy,
+		Keychain:          keychain,
+		Cipher:            cipher,
+		Key:               key,
+		KeyServerPriority: priority,
+		Interfaces:        []string{p1},
+	}
+	req, err := c.SetRequest()
+	set(t, dut, req, err)
+	return c
+}
+
+// awaitPing pings the address from the DUT until all the echo requests
+// are answered if reachable, or none otherwise, and returns an error if
+// they are not within the timeout.
+func awaitPing(t *testing.T, dut *ondatra.DUTDevice, addr string, reachable bool, timeout time.Duration) error {
+	t.Helper()
+	sys := dut.RawAPIs().GNOI().Default(t).System()
+	var sent, received int32
+	for deadline := time.Now().Add(timeout); ; time.Sleep(5 * time.Second) {
+		var err error
+		sent, received, err = macsec.Ping(context.Background(), sys, addr, pings)
+		if err != nil {
+			return err
+		}
+		if sent > 0 && (reachable && received == sent || !reachable && received == 0) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+	want := "none"
+	if reachable {
+		want = "all"
+	}
+	return fmt.Errorf("%s received %d of %d echo replies from %s after %v, want %s", dut.Name(), received, sent, addr, timeout, want)
+}
+
+// readCounters returns the MACsec counters of port1 of the DUT.
+func readCounters(t *testing.T, dut *ondatra.DUTDevice) *macsec.Counters {
+	t.Helper()
+	c, err := macsec.ReadCounters(context.Background(), dut.RawAPIs().GNMI().Default(t), dut.Port(t, "port1").Name())
+	if err != nil {
+		t.Fatalf("Cannot read the MACsec counters of %s: %v", dut.Name(), err)
+	}
+	return c
+}
+
+// TestMACsec verifies that MKA secures the link between the DUTs, that
+// the traffic over it is encrypted and passes the integrity checks, that
+// it stops when the keys of the DUTs do not match, and that it resumes
+// once they match again.
+//
+// config_path:/macsec/interfaces/interface/config/enable
+// config_path:/macsec/interfaces/interface/mka/config/mka-policy
+// config_path:/macsec/interfaces/interface/mka/config/key-chain
+// config_path:/macsec/mka/policies/policy/config/macsec-cipher-suite
+// config_path:/macsec/mka/policies/policy/config/key-server-priority
+// config_path:/macsec/mka/policies/policy/config/security-policy
+// config_path:/keychains/keychain/keys/key/config/key-id
+// config_path:/keychains/keychain/keys/key/config/secret-key
+// telemetry_path:/macsec/interfaces/interface/scsa-tx/scsa-tx/state/counters/sc-encrypted
+// telemetry_path:/macsec/interfaces/interface/scsa-rx/scsa-rx/state/counters/sc-valid
+// telemetry_path:/macsec/interfaces/interface/scsa-rx/scsa-rx/state/counters/sc-invalid
+func TestMACsec(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1"}}
+	dut1 := fptest.RequireDUT(t, "dut1", r)
+	dut2 := fptest.RequireDUT(t, "dut2", r)
+	c1 := configure(t, dut1, &dut1Port1, 0)
+	c2 := configure(t, dut2, &dut2Port1, 16)
+	defer func() {
+		req, err := c1.DeleteRequest()
+		set(t, dut1, req, err)
+		req, err = c2.DeleteRequest()
+		set(t, dut2, req, err)
+	}()
+
+	t.Run("Encrypted", func(t *testing.T) {
+		if err := awaitPing(t, dut1, dut2Port1.IPv4, true, mkaTimeout); err != nil {
+			t.Fatal(err)
+		}
+		before1, before2 := readCounters(t, dut1), readCounters(t, dut2)
+		sent, received, err := macsec.Ping(context.Background(), dut1.RawAPIs().GNOI().Default(t).System(), dut2Port1.IPv4, pings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("dut1 received %d of %d echo replies from dut2", received, sent)
+		after1, after2 := readCounters(t, dut1), readCounters(t, dut2)
+
+		for _, c := range []struct {
+			desc          string
+			before, after uint64
+		}{
+			{"dut1 packets encrypted", before1.Encrypted, after1.Encrypted},
+			{"dut2 packets valid", before2.Valid, after2.Valid},
+			{"dut2 packets encrypted", before2.Encrypted, after2.Encrypted},
+			{"dut1 packets valid", before1.Valid, after1.Valid},
+		} {
+			if delta := c.after - c.before; delta < pings {
+				t.Errorf("%s got %d more after %d pings, want at least %d", c.desc, delta, pings, pings)
+			}
+		}
+		if delta := after2.Invalid - before2.Invalid; delta > 0 {
+			t.Errorf("dut2 packets invalid got %d more, want none", delta)
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		c := *c2
+		c.Key = mismatched
+		req, err := c.KeychainRequest()
+		set(t, dut2, req, err)
+		if err := awaitPing(t, dut1, dut2Port1.IPv4, false, mkaTimeout); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		req, err := c2.KeychainRequest()
+		set(t, dut2, req, err)
+		if err := awaitPing(t, dut1, dut2Port1.IPv4, true, mkaTimeout); err != nil {
+			t.Error(err)
+		}
+	})
+}
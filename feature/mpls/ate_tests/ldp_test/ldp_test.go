@@ -0,0 +1,159 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ldp_test implements MPLS-1.1: LDP Label Distribution.
+package ldp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/ldp"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/featureprofiles/internal/srmpls"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The DUT has a static route to the destination prefix
+// through ate:port2, and an LDP session with ate:port2, whose LSR ID is
+// its address, which advertises a label for the prefix.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - dut:port2 -> ate:port2 subnet 192.0.2.4/30
+const (
+	plen           = 30
+	staticName     = "STATIC"
+	prefix         = "198.51.100.0/24"
+	dstIP          = "198.51.100.1"
+	ateLabel       = 100100
+	lossTolerance  = 1
+	sessionTimeout = 2 * time.Minute
+	aftTimeout     = time.Minute
+	trafficTime    = 15 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT, the static route to the
+// destination prefix, and LDP on dut:port2.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	niPath := d.NetworkInstance(*deviations.DefaultNetworkInstance)
+	static := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC,
+		Name:       ygot.String(staticName),
+	}
+	if err := static.AppendStatic(recursion.Static(prefix, atePort2.IPv4)); err != nil {
+		t.Fatalf("Cannot add static route: %v", err)
+	}
+	staticPath := niPath.Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName)
+	fptest.LogYgot(t, "DUT static routes", staticPath, static)
+	staticPath.Replace(t, static)
+
+	ni := &telemetry.NetworkInstance{Name: ygot.String(*deviations.DefaultNetworkInstance)}
+	ldp.Configure(ni, dutPort2.IPv4, dut.Port(t, "port2").Name())
+	fptest.LogYgot(t, "DUT MPLS", niPath.Mpls(), ni.GetMpls())
+	niPath.Mpls().Update(t, ni.GetMpls())
+}
+
+// configureATE configures the ports of the ATE and returns the interfaces
+// of ate:port1 and ate:port2.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) (*ondatra.Interface, *ondatra.Interface) {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	top.Push(t).StartProtocols(t)
+	return src, dst
+}
+
+// sendTraffic sends the flow from ate:port1 to ate:port2 and verifies
+// that it is forwarded.
+func sendTraffic(t *testing.T, ate *ondatra.ATEDevice, src, dst *ondatra.Interface, name string, labels []uint32) {
+	flow := srmpls.Flow(ate, name, src, []ondatra.Endpoint{dst}, labels, dstIP)
+	ate.Traffic().Start(t, flow)
+	time.Sleep(trafficTime)
+	ate.Traffic().Stop(t)
+	if err := srmpls.VerifyFlow(t, ate, flow, lossTolerance); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestLDP verifies that the DUT establishes an LDP session with the ATE,
+// installs the label the ATE advertises for the destination prefix in its
+// AFTs, and forwards the IP and labeled traffic to the prefix with it.
+//
+// config_path:/network-instances/network-instance/mpls/global/interface-attributes/interface/config/mpls-enabled
+// config_path:/network-instances/network-instance/mpls/signaling-protocols/ldp/global/config/lsr-id
+// config_path:/network-instances/network-instance/mpls/signaling-protocols/ldp/interface-attributes/interfaces/interface/address-families/address-family/config/enabled
+// telemetry_path:/network-instances/network-instance/mpls/signaling-protocols/ldp/neighbors/neighbor/state/session-state
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group
+// telemetry_path:/network-instances/network-instance/afts/next-hops/next-hop/state/pushed-mpls-label-stack
+// telemetry_path:/network-instances/network-instance/afts/mpls/label-entry/state/next-hop-group
+func TestLDP(t *testing.T) {
+	b := ldp.Binding{FEC: prefix, Label: ateLabel, NextHop: atePort2.IPv4}
+	ldp.Require(t, b)
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	src, dst := configureATE(t, ate)
+
+	if err := ldp.AwaitSession(t, dut, atePort2.IPv4, sessionTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	var locals []uint32
+	t.Run("Binding", func(t *testing.T) {
+		var err error
+		if locals, err = ldp.AwaitBinding(t, dut, b, aftTimeout); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("DUT swaps local labels %v to %d", locals, b.Label)
+	})
+
+	t.Run("IP traffic", func(t *testing.T) {
+		sendTraffic(t, ate, src, dst, "ldp-ip", nil)
+	})
+
+	t.Run("Labeled traffic", func(t *testing.T) {
+		if len(locals) == 0 {
+			t.Skip("No local label swapped to the ATE label")
+		}
+		sendTraffic(t, ate, src, dst, "ldp-labeled", locals[:1])
+	})
+}
@@ -0,0 +1,216 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pim_igmp_test implements MC-1.1: PIM-SM and IGMP Baseline.
+package pim_igmp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/multicast"
+	"github.com/openconfig/featureprofiles/internal/otgutils"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, the source, and
+// dut:port2 -> ate:port2 and dut:port3 -> ate:port3, the receivers.  The
+// DUT runs PIM-SM on all its ports, with itself as the rendezvous point,
+// and IGMPv2 on the ports of the receivers.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - dut:port2 -> ate:port2 subnet 192.0.2.4/30
+//   - dut:port3 -> ate:port3 subnet 192.0.2.8/30
+const (
+	plen         = 30
+	group        = "239.1.1.1"
+	groups       = "239.0.0.0/8"
+	igmpVersion  = 2
+	pps          = 100
+	trafficTime  = 30 * time.Second
+	stateTimeout = time.Minute
+	// minDeliveryPct is the share of the packets of the source each
+	// receiver must receive, as the first ones may be lost while the DUT
+	// builds its (S,G) state.
+	minDeliveryPct = 99
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", MAC: "02:00:03:01:01:01", IPv4: "192.0.2.10", IPv4Len: plen}
+
+	// receivers are the ports of the receivers and their ATE attributes.
+	receivers = []struct {
+		port string
+		ate  *attrs.Attributes
+	}{{"port2", &atePort2}, {"port3", &atePort3}}
+)
+
+// configureDUT configures the ports of the DUT, PIM-SM on all of them and
+// IGMP on the ports of the receivers.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	var intfs []string
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+		intfs = append(intfs, i.GetName())
+	}
+
+	ni := d.NetworkInstance(*deviations.DefaultNetworkInstance)
+	pim := multicast.PIM(dutPort1.IPv4, groups, intfs...)
+	pimPath := ni.Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_PIM, multicast.PIMName)
+	fptest.LogYgot(t, "DUT PIM", pimPath, pim)
+	pimPath.Replace(t, pim)
+
+	igmp := multicast.IGMP(igmpVersion, intfs[1:]...)
+	igmpPath := ni.Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_IGMP, multicast.IGMPName)
+	fptest.LogYgot(t, "DUT IGMP", igmpPath, igmp)
+	igmpPath.Replace(t, igmp)
+}
+
+// configureOTG returns the OTG configuration of the ATE interfaces,
+// capturing on the ports of the receivers.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	for _, p := range []struct {
+		port      string
+		ate, peer *attrs.Attributes
+	}{{"port1", &atePort1, &dutPort1}, {"port2", &atePort2, &dutPort2}, {"port3", &atePort3, &dutPort3}} {
+		id := ate.Port(t, p.port).ID()
+		config.Ports().Add().SetName(id)
+		config.Devices().Add().SetName(p.ate.Name).Ethernets().Add().
+			SetName(p.ate.Name + ".eth").
+			SetPortName(id).
+			SetMac(p.ate.MAC).
+			Ipv4Addresses().Add().
+			SetName(p.ate.Name + ".IPv4").
+			SetAddress(p.ate.IPv4).
+			SetGateway(p.peer.IPv4).
+			SetPrefix(int32(p.ate.IPv4Len))
+	}
+	capture.Enable(config, ate.Port(t, "port2").ID(), ate.Port(t, "port3").ID())
+	return config
+}
+
+// addReports adds the flows of the membership reports of the receivers.
+func addReports(t *testing.T, ate *ondatra.ATEDevice, config gosnappi.Config) {
+	for _, r := range receivers {
+		if err := multicast.AddReport(config, "join "+r.port, ate.Port(t, r.port).ID(), r.ate.MAC, r.ate.IPv4, group); err != nil {
+			t.Fatalf("Cannot add the membership reports of ate:%s: %v", r.port, err)
+		}
+	}
+}
+
+// TestPIMIGMP verifies that the DUT learns the IGMP memberships of the
+// receivers, builds the PIM (S,G) state of the source once it sends to the
+// group, and replicates the traffic of the source to each receiver.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/pim/global/rendezvous-points/rendezvous-point/config/multicast-groups
+// config_path:/network-instances/network-instance/protocols/protocol/pim/interfaces/interface/config/enabled
+// config_path:/network-instances/network-instance/protocols/protocol/pim/interfaces/interface/config/mode
+// config_path:/network-instances/network-instance/protocols/protocol/igmp/interfaces/interface/config/enabled
+// config_path:/network-instances/network-instance/protocols/protocol/igmp/interfaces/interface/config/version
+// telemetry_path:/network-instances/network-instance/protocols/protocol/igmp/interfaces/interface/membership-groups/group/state/reporter
+// telemetry_path:/network-instances/network-instance/protocols/protocol/pim/global/sources-joined/source/state/group
+// telemetry_path:/network-instances/network-instance/protocols/protocol/pim/global/sources-joined/source/state/upstream-interface-id
+func TestPIMIGMP(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	config := configureOTG(t, ate)
+	api := fptest.DialOTG(t, ate)
+	otg := ate.OTG()
+
+	t.Run("IGMP", func(t *testing.T) {
+		config.Flows().Clear()
+		addReports(t, ate, config)
+		otg.PushConfig(t, config)
+		otg.StartProtocols(t)
+		otg.StartTraffic(t)
+		defer otg.StopTraffic(t)
+		for _, r := range receivers {
+			intf := dut.Port(t, r.port).Name()
+			reporter, err := multicast.AwaitMembership(t, dut, intf, group, stateTimeout)
+			switch {
+			case err != nil:
+				t.Errorf("dut:%s: %v", r.port, err)
+			case reporter != r.ate.IPv4:
+				t.Errorf("dut:%s: membership of %s reported by %s, want %s", r.port, group, reporter, r.ate.IPv4)
+			}
+		}
+	})
+
+	t.Run("PIM", func(t *testing.T) {
+		var rx []string
+		var ids []string
+		for _, r := range receivers {
+			rx = append(rx, r.ate.Name+".IPv4")
+			ids = append(ids, ate.Port(t, r.port).ID())
+		}
+		config.Flows().Clear()
+		addReports(t, ate, config)
+		if err := multicast.AddSource(config, "source", atePort1.Name+".IPv4", rx, atePort1.MAC, atePort1.IPv4, group, pps, int32(pps*trafficTime/time.Second)); err != nil {
+			t.Fatalf("Cannot add the source: %v", err)
+		}
+		otg.PushConfig(t, config)
+		otg.StartProtocols(t)
+		capture.Start(t, api, ids...)
+		otg.StartTraffic(t)
+		start := time.Now()
+
+		s, err := multicast.AwaitSource(t, dut, atePort1.IPv4, group, stateTimeout)
+		if err != nil {
+			t.Error(err)
+		} else if got, want := s.GetUpstreamInterfaceId(), dut.Port(t, "port1").Name(); got != want {
+			t.Errorf("(%s, %s) upstream interface got %q, want %q", atePort1.IPv4, group, got, want)
+		}
+
+		time.Sleep(trafficTime - time.Since(start))
+		otg.StopTraffic(t)
+		capture.Stop(t, api, ids...)
+		otgutils.LogFlowMetrics(t, otg, config)
+
+		sent := otg.Telemetry().Flow("source").Get(t).GetCounters().GetOutPkts()
+		if sent == 0 {
+			t.Fatalf("No packets sent to %s", group)
+		}
+		for i, r := range receivers {
+			n := multicast.Count(capture.Fetch(t, api, ids[i]), atePort1.IPv4, group)
+			t.Logf("ate:%s received %d of %d packets to %s", r.port, n, sent)
+			if uint64(n)*100 < sent*minDeliveryPct {
+				t.Errorf("ate:%s received %d of %d packets to %s, want at least %d%%", r.port, n, sent, group, minDeliveryPct)
+			}
+		}
+	})
+}
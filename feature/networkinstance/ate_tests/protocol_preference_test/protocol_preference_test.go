@@ -0,0 +1,309 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protocol_preference_test implements RT-14.1: Protocol Preference.
+package protocol_preference_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/bgpauth"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/isisauth"
+	"github.com/openconfig/featureprofiles/internal/isisdrain"
+	"github.com/openconfig/featureprofiles/internal/routepref"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var tolerance = flag.Float64("protocol_preference_tolerance", 1,
+	"Tolerance in percent of the packets received on the paths of the routes not selected.")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port{2-4} ->
+// ate:port{2-4}.  The DUT has a route to the same prefix from another
+// protocol through each of ate:port2, ate:port3 and ate:port4, and the
+// ATE sends traffic to it from ate:port1.
+//
+//   - Source: ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - Static route: dut:port2 -> ate:port2 subnet 192.0.2.4/30
+//   - eBGP, ate:port3 (AS 64497) with the DUT (AS 64496): dut:port3 ->
+//     ate:port3 subnet 192.0.2.8/30
+//   - IS-IS level 2: dut:port4 -> ate:port4 subnet 192.0.2.12/30
+//   - Destination network: 198.51.100.0/24
+const (
+	plen        = 30
+	dutAS       = 64496
+	ateAS       = 64497
+	staticName  = "STATIC"
+	isisName    = "ISIS"
+	isisNET     = "49.0001.1920.0000.2001.00"
+	ateAreaID   = "49.0002"
+	level       = 2
+	prefix      = "198.51.100.0/24"
+	dstMin      = "198.51.100.1"
+	dstCount    = 254
+	fps         = 10000
+	trafficTime = 15 * time.Second
+	// ibgpDistance is the distance of the iBGP routes, of which the DUT
+	// has none.
+	ibgpDistance = 200
+	// protocolTimeout is how long the BGP session and the IS-IS adjacency
+	// may take to come up.
+	protocolTimeout = time.Minute
+	aftTimeout      = 2 * time.Minute
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", IPv4: "192.0.2.10", IPv4Len: plen}
+	dutPort4 = attrs.Attributes{Desc: "dutPort4", IPv4: "192.0.2.13", IPv4Len: plen}
+	atePort4 = attrs.Attributes{Name: "atePort4", IPv4: "192.0.2.14", IPv4Len: plen}
+)
+
+// preferences are the preferences of the static, eBGP and IS-IS routes of
+// the DUT.
+type preferences struct {
+	static, ebgp, isis uint32
+}
+
+// routes returns the routes of the DUT to the prefix, through ate:port2,
+// ate:port3 and ate:port4 in order.
+func (p *preferences) routes() []*routepref.Route {
+	return []*routepref.Route{
+		{Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, Preference: p.static, NextHop: atePort2.IPv4},
+		{Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, Preference: p.ebgp, NextHop: atePort3.IPv4},
+		{Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_ISIS, Preference: p.isis, NextHop: atePort4.IPv4},
+	}
+}
+
+// protocols returns the static route, and the BGP and IS-IS configuration
+// with only the preferences.
+func (p *preferences) protocols(t *testing.T) (*telemetry.NetworkInstance_Protocol_Static, *telemetry.NetworkInstance_Protocol_Bgp, *telemetry.NetworkInstance_Protocol_Isis) {
+	static := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String(prefix)}
+	static.GetOrCreateNextHop("1").NextHop = telemetry.UnionString(atePort2.IPv4)
+	routepref.SetStatic(static, p.static)
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	if err := routepref.SetBGP(bgp, p.ebgp, ibgpDistance); err != nil {
+		t.Fatalf("Cannot set the BGP distances: %v", err)
+	}
+	isis := &telemetry.NetworkInstance_Protocol_Isis{}
+	routepref.SetISIS(isis, level, p.isis, p.isis)
+	return static, bgp, isis
+}
+
+// configureDUT configures the ports of the DUT, its static route, its eBGP
+// session with ate:port3 and IS-IS on port4, with the preferences.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice, p *preferences) {
+	d := dut.Config()
+	for _, port := range []struct {
+		id    string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}, {"port4", &dutPort4}} {
+		i := port.attrs.NewInterface(dut.Port(t, port.id).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+	ni := d.NetworkInstance(*deviations.DefaultNetworkInstance)
+	s, bgp, isis := p.protocols(t)
+
+	static := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC,
+		Name:       ygot.String(staticName),
+	}
+	if err := static.AppendStatic(s); err != nil {
+		t.Fatalf("Cannot add the static route: %v", err)
+	}
+	staticPath := ni.Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName)
+	fptest.LogYgot(t, "DUT static routes", staticPath, static)
+	staticPath.Replace(t, static)
+
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(dutAS)
+	global.RouterId = ygot.String(dutPort3.IPv4)
+	nbr := bgp.GetOrCreateNeighbor(atePort3.IPv4)
+	nbr.PeerAs = ygot.Uint32(ateAS)
+	nbr.Enabled = ygot.Bool(true)
+	nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+	ap := nbr.GetOrCreateApplyPolicy()
+	ap.DefaultImportPolicy = telemetry.RoutingPolicy_DefaultPolicyType_ACCEPT_ROUTE
+	ap.DefaultExportPolicy = telemetry.RoutingPolicy_DefaultPolicyType_ACCEPT_ROUTE
+	bgpPath := ni.Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	fptest.LogYgot(t, "DUT BGP", bgpPath, bgp)
+	bgpPath.Replace(t, bgp)
+
+	glob := isis.GetOrCreateGlobal()
+	glob.Instance = ygot.String(isisName)
+	glob.Net = []string{isisNET}
+	glob.GetOrCreateAf(telemetry.IsisTypes_AFI_TYPE_IPV4, telemetry.IsisTypes_SAFI_TYPE_UNICAST).Enabled = ygot.Bool(true)
+	intf := isis.GetOrCreateInterface(dut.Port(t, "port4").Name())
+	intf.CircuitType = telemetry.IsisTypes_CircuitType_POINT_TO_POINT
+	intf.Enabled = ygot.Bool(true)
+	intf.GetOrCreateLevel(level).Enabled = ygot.Bool(true)
+	prot := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_ISIS,
+		Name:       ygot.String(isisName),
+		Enabled:    ygot.Bool(true),
+		Isis:       isis,
+	}
+	isisPath := ni.Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_ISIS, isisName)
+	fptest.LogYgot(t, "DUT IS-IS", isisPath, prot)
+	isisPath.Replace(t, prot)
+}
+
+// setPreferences updates the preferences of the static route, BGP and
+// IS-IS of the DUT.
+func setPreferences(t *testing.T, dut *ondatra.DUTDevice, p *preferences) {
+	ni := dut.Config().NetworkInstance(*deviations.DefaultNetworkInstance)
+	static, bgp, isis := p.protocols(t)
+	ni.Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName).Static(prefix).Update(t, static)
+	ni.Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Update(t, bgp)
+	ni.Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_ISIS, isisName).Isis().Update(t, isis)
+}
+
+// configureATE configures the ports of the ATE, the eBGP session of
+// ate:port3 and IS-IS on ate:port4, both advertising the prefix, and
+// returns the flow from ate:port1 to the prefix.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) (*ondatra.ATETopology, *ondatra.Flow) {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst2 := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	dst3 := atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3)
+	dst4 := atePort4.AddToATE(top, ate.Port(t, "port4"), &dutPort4)
+
+	dst3.BGP().AddPeer().WithPeerAddress(dutPort3.IPv4).WithLocalASN(ateAS).WithTypeExternal()
+	bgpNet := dst3.AddNetwork("bgp")
+	bgpNet.IPv4().WithAddress(prefix).WithCount(1)
+	bgpNet.BGP().WithNextHopAddress(atePort3.IPv4).WithOriginIGP()
+
+	dst4.ISIS().
+		WithAreaID(ateAreaID).
+		WithTERouterID("*").
+		WithNetworkTypePointToPoint().
+		WithWideMetricEnabled(true).
+		WithLevelL2()
+	isisNet := dst4.AddNetwork("isis")
+	isisNet.IPv4().WithAddress(prefix).WithCount(1)
+	isisNet.ISIS().WithIPReachabilityExternal().WithIPReachabilityMetric(10)
+	top.Push(t).StartProtocols(t)
+
+	ip := ondatra.NewIPv4Header().WithSrcAddress(atePort1.IPv4)
+	ip.DstAddressRange().WithMin(dstMin).WithCount(dstCount)
+	flow := ate.Traffic().NewFlow("Preference").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst2, dst3, dst4).
+		WithHeaders(ondatra.NewEthernetHeader(), ip).
+		WithFrameRateFPS(fps)
+	return top, flow
+}
+
+// received returns the packets received on ate:port2, ate:port3 and
+// ate:port4.
+func received(t *testing.T, ate *ondatra.ATEDevice) []uint64 {
+	var pkts []uint64
+	for _, port := range []string{"port2", "port3", "port4"} {
+		p := ate.Port(t, port)
+		pkts = append(pkts, ate.Telemetry().Interface(p.Name()).Counters().InPkts().Get(t))
+	}
+	return pkts
+}
+
+// checkTraffic runs the flow and checks that the traffic all goes through
+// the path of the route.
+func checkTraffic(t *testing.T, ate *ondatra.ATEDevice, flow *ondatra.Flow, path int) {
+	t.Helper()
+	before := received(t, ate)
+	ate.Traffic().Start(t, flow)
+	time.Sleep(trafficTime)
+	ate.Traffic().Stop(t)
+	// Let the counters settle.
+	time.Sleep(5 * time.Second)
+	after := received(t, ate)
+	counts := make([]uint64, len(after))
+	for i := range after {
+		counts[i] = after[i] - before[i]
+	}
+	t.Logf("Packets received on each path: %v", counts)
+	if err := isisdrain.CheckPath(counts, path, *tolerance); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestProtocolPreference verifies that the DUT installs the route of the
+// protocol of the lowest preference among its static, eBGP and IS-IS
+// routes to the same prefix, and forwards the traffic through it, as the
+// preferences change.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/preference
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/global/default-route-distance/config/external-route-distance
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/global/default-route-distance/config/internal-route-distance
+// config_path:/network-instances/network-instance/protocols/protocol/isis/levels/level/route-preference/config/internal-route-preference
+// config_path:/network-instances/network-instance/protocols/protocol/isis/levels/level/route-preference/config/external-route-preference
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/origin-protocol
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group
+// telemetry_path:/interfaces/interface/state/counters/in-pkts
+func TestProtocolPreference(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3", "port4"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	cases := []struct {
+		desc string
+		p    *preferences
+	}{
+		{"static", &preferences{static: 1, ebgp: 20, isis: 115}},
+		{"ebgp", &preferences{static: 200, ebgp: 20, isis: 115}},
+		{"isis", &preferences{static: 200, ebgp: 170, isis: 115}},
+		{"static restored", &preferences{static: 1, ebgp: 170, isis: 115}},
+	}
+	configureDUT(t, dut, cases[0].p)
+	top, flow := configureATE(t, ate)
+	defer top.StopProtocols(t)
+	if err := bgpauth.AwaitEstablished(t, dut, atePort3.IPv4, protocolTimeout); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := isisauth.AwaitUp(t, dut, isisName, dut.Port(t, "port4").Name(), protocolTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			setPreferences(t, dut, c.p)
+			routes := c.p.routes()
+			want, err := routepref.Select(routes...)
+			if err != nil {
+				t.Fatalf("Bad preferences %+v: %v", *c.p, err)
+			}
+			if err := routepref.AwaitSelected(t, dut, prefix, want, aftTimeout); err != nil {
+				t.Fatal(err)
+			}
+			for path, r := range routes {
+				if r == want {
+					checkTraffic(t, ate, flow, path)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vrf_scale_test implements RT-11.1: VRF Scale.
+package vrf_scale_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/tolerance"
+	"github.com/openconfig/featureprofiles/internal/vrfscale"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, carrying two VLAN
+// subinterfaces per VRF.  The ATE sends from the first subinterface of
+// each VRF to the prefixes routed behind its second subinterface, so
+// the traffic of every VRF hairpins through the DUT on its own VLANs.
+const (
+	vrfs       = 64
+	prefixes   = 2
+	pps        = 1000
+	trafficFor = 15 * time.Second
+)
+
+// newSpec returns the spec of the topology, with the number of VRFs
+// scaled to the target.
+func newSpec() *vrfscale.Spec {
+	return &vrfscale.Spec{
+		VRFs:       args.Scale(vrfs),
+		Interfaces: 2,
+		Prefixes:   prefixes,
+		IPv6Links:  "2001:db8::/126",
+		IPv6Routes: "2001:db8:2::/64",
+	}
+}
+
+// newFlow returns a flow from the first endpoint of the source VRF to
+// the routes behind the second endpoint of the destination VRF.
+func newFlow(ate *ondatra.ATEDevice, intfs map[string]*ondatra.Interface, src, dst *vrfscale.VRF) *ondatra.Flow {
+	from, to := src.Endpoints[0], dst.Endpoints[1]
+	var nets []ondatra.Endpoint
+	for _, r := range to.IPv6Routes {
+		nets = append(nets, intfs[to.ATE.Name].Networks()[to.NetworkName(r)])
+	}
+	return ate.Traffic().NewFlow(fmt.Sprintf("%s-to-%s", src.Name, dst.Name)).
+		WithSrcEndpoints(intfs[from.ATE.Name]).
+		WithDstEndpoints(nets...).
+		WithHeaders(ondatra.NewEthernetHeader(), ondatra.NewIPv6Header()).
+		WithFrameRateFPS(args.PPS(pps))
+}
+
+// policy is the default traffic policy of the flows within a VRF.
+var policy = tolerance.TrafficPolicy{MaxLossPct: 1, MinThroughputPct: 98}
+
+// sendTraffic runs the flows and returns how long they were sent for.
+func sendTraffic(t *testing.T, ate *ondatra.ATEDevice, flows []*ondatra.Flow) time.Duration {
+	d := args.TrafficDuration(trafficFor)
+	ate.Traffic().Start(t, flows...)
+	time.Sleep(d)
+	ate.Traffic().Stop(t)
+	return d
+}
+
+// TestVRFScale configures many VRFs with VLAN subinterfaces and static
+// routes, verifies that the DUT reports them all, that every VRF
+// forwards its own traffic, and that no VRF forwards the traffic of
+// another.
+//
+// config_path:/network-instances/network-instance/config/type
+// config_path:/network-instances/network-instance/interfaces/interface/config/subinterface
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/next-hop
+// config_path:/interfaces/interface/subinterfaces/subinterface/vlan/match/single-tagged/config/vlan-id
+// telemetry_path:/network-instances/network-instance/state/type
+// telemetry_path:/network-instances/network-instance/interfaces/interface/state/subinterface
+// telemetry_path:/interfaces/interface/subinterfaces/subinterface/state/oper-status
+func TestVRFScale(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+
+	tp, err := newSpec().Generate()
+	if err != nil {
+		t.Fatalf("Cannot generate the topology: %v", err)
+	}
+	port := dut.Port(t, "port1").Name()
+	tp.Configure(t, dut, port)
+	defer tp.Unconfigure(t, dut)
+	t.Logf("Configured %d VRFs with %d subinterfaces", len(tp.VRFs), len(tp.Endpoints()))
+
+	top := ate.Topology().New()
+	intfs := tp.AddToATE(top, ate.Port(t, "port1"))
+	top.Push(t).StartProtocols(t)
+
+	t.Run("Telemetry", func(t *testing.T) {
+		for _, v := range tp.VRFs {
+			ni := dut.Telemetry().NetworkInstance(v.Name)
+			if got := ni.Type().Get(t); got != telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L3VRF {
+				t.Errorf("Type of %s got %v, want L3VRF", v.Name, got)
+			}
+			for _, e := range v.Endpoints {
+				id := fmt.Sprintf("%s.%d", port, e.Subinterface())
+				if got := ni.Interface(id).Subinterface().Get(t); got != e.Subinterface() {
+					t.Errorf("Subinterface of %s in %s got %d, want %d", id, v.Name, got, e.Subinterface())
+				}
+				s := dut.Telemetry().Interface(port).Subinterface(e.Subinterface())
+				if got := s.OperStatus().Get(t); got != telemetry.Interface_OperStatus_UP {
+					t.Errorf("Oper status of %s got %v, want UP", id, got)
+				}
+			}
+		}
+	})
+
+	t.Run("Forwarding", func(t *testing.T) {
+		var flows []*ondatra.Flow
+		for _, v := range tp.VRFs {
+			flows = append(flows, newFlow(ate, intfs, v, v))
+		}
+		d := sendTraffic(t, ate, flows)
+		tolerance.New(policy).CheckFlows(t, ate, args.PPS(pps), d, flows...)
+	})
+
+	t.Run("Isolation", func(t *testing.T) {
+		if len(tp.VRFs) < 2 {
+			t.Skip("Isolation needs at least two VRFs")
+		}
+		var flows []*ondatra.Flow
+		for i, v := range tp.VRFs {
+			flows = append(flows, newFlow(ate, intfs, v, tp.VRFs[(i+1)%len(tp.VRFs)]))
+		}
+		sendTraffic(t, ate, flows)
+		for _, f := range flows {
+			if loss := ate.Telemetry().Flow(f.Name()).LossPct().Get(t); loss != 100 {
+				t.Errorf("Loss of %s got %g%%, want 100%%", f.Name(), loss)
+			}
+		}
+	})
+}
@@ -0,0 +1,266 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vrf_selection_test implements RT-11.2: Class-Based VRF
+// Selection.
+package vrf_selection_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/otgutils"
+	"github.com/openconfig/featureprofiles/internal/vrfscale"
+	"github.com/openconfig/featureprofiles/internal/vrfselect"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  dut:port1 applies the VRF selection policy.  dut:port2
+// carries the untagged interface of the default network instance, and a
+// VLAN subinterface for each VRF, generated by vrfscale.  Every network
+// instance routes dstPrefix to its ATE peer, so the VLAN of a packet
+// captured on ate:port2 tells the network instance which forwarded it.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - dut:port2 -> ate:port2 subnet 192.0.2.4/30, untagged
+//   - dut:port2 -> ate:port2 subnets from 192.0.2.64/30, VLANs from 10
+const (
+	plen        = 30
+	policyName  = "VRF-SELECTION"
+	dstPrefix   = "198.51.100.0/24"
+	dst         = "198.51.100.1"
+	pps         = 640
+	trafficTime = 10 * time.Second
+	// maxErrors bounds the errors reported, as every DSCP of a class
+	// usually fails the same way.
+	maxErrors = 16
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", MAC: "02:00:01:01:01:01", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", MAC: "02:00:02:01:01:01", IPv4: "192.0.2.6", IPv4Len: plen}
+)
+
+// newTopology returns the VRFs of dut:port2, one for each class of the
+// TE designs.
+func newTopology(t *testing.T) *vrfscale.Topology {
+	tp, err := (&vrfscale.Spec{VRFs: 6, Interfaces: 1, IPv4Links: "192.0.2.64/30"}).Generate()
+	if err != nil {
+		t.Fatalf("Cannot generate the VRFs: %v", err)
+	}
+	return tp
+}
+
+// staticRoute returns the static route of dstPrefix to the next hop.
+func staticRoute(nextHop string) *telemetry.NetworkInstance_Protocol_Static {
+	s := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String(dstPrefix)}
+	s.GetOrCreateNextHop("0").NextHop = telemetry.UnionString(nextHop)
+	return s
+}
+
+// configureDUT configures the ports and the network instances of the DUT,
+// and applies the VRF selection policy to dut:port1.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice, tp *vrfscale.Topology, p *vrfselect.Policy) {
+	d := dut.Config()
+	p1 := dut.Port(t, "port1").Name()
+	d.Interface(p1).Replace(t, dutPort1.NewInterface(p1))
+	p2 := dut.Port(t, "port2").Name()
+	i := tp.ConfigInterface(dutPort2.NewInterface(p2))
+	fptest.LogYgot(t, "DUT port2", d.Interface(p2), i)
+	d.Interface(p2).Replace(t, i)
+
+	// The network instances are in the order of the VRFs.
+	for i, ni := range tp.NetworkInstances(p2) {
+		nextHop := tp.VRFs[i].Endpoints[0].ATE.IPv4
+		static := ni.GetOrCreateProtocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, vrfscale.StaticName)
+		static.GetOrCreateStatic(dstPrefix).GetOrCreateNextHop("0").NextHop = telemetry.UnionString(nextHop)
+		d.NetworkInstance(ni.GetName()).Replace(t, ni)
+	}
+	ni := *deviations.DefaultNetworkInstance
+	d.NetworkInstance(ni).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, vrfscale.StaticName).
+		Static(dstPrefix).
+		Replace(t, staticRoute(atePort2.IPv4))
+
+	pf := p.Config(p1)
+	fptest.LogYgot(t, "VRF selection policy", d.NetworkInstance(ni).PolicyForwarding(), pf)
+	d.NetworkInstance(ni).PolicyForwarding().Replace(t, pf)
+}
+
+// configureOTG returns the OTG configuration of ate:port1, and of the
+// untagged interface and VLAN interfaces of ate:port2, capturing on
+// ate:port2.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice, tp *vrfscale.Topology) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	p1, p2 := ate.Port(t, "port1").ID(), ate.Port(t, "port2").ID()
+	config.Ports().Add().SetName(p1)
+	config.Ports().Add().SetName(p2)
+	addDevice := func(port string, ate, peer *attrs.Attributes, vlan uint16) {
+		eth := config.Devices().Add().SetName(ate.Name).Ethernets().Add().
+			SetName(ate.Name + ".eth").
+			SetPortName(port).
+			SetMac(ate.MAC)
+		if vlan != 0 {
+			eth.Vlans().Add().SetName(ate.Name + ".vlan").SetId(int32(vlan))
+		}
+		eth.Ipv4Addresses().Add().
+			SetName(ate.Name + ".IPv4").
+			SetAddress(ate.IPv4).
+			SetGateway(peer.IPv4).
+			SetPrefix(int32(ate.IPv4Len))
+	}
+	addDevice(p1, &atePort1, &dutPort1, 0)
+	addDevice(p2, &atePort2, &dutPort2, 0)
+	for i, e := range tp.Endpoints() {
+		a := e.ATE
+		a.MAC = fmt.Sprintf("02:00:02:02:01:%02x", i+1)
+		addDevice(p2, &a, &e.DUT, e.VLAN)
+	}
+	capture.Enable(config, p2)
+	return config
+}
+
+// addFlow adds a flow from ate:port1 to dst cycling through all the DSCPs.
+func addFlow(config gosnappi.Config, tp *vrfscale.Topology) {
+	rx := []string{atePort2.Name + ".IPv4"}
+	for _, e := range tp.Endpoints() {
+		rx = append(rx, e.ATE.Name+".IPv4")
+	}
+	var dscps []int32
+	for d := 0; d <= vrfselect.MaxDSCP; d++ {
+		dscps = append(dscps, int32(d))
+	}
+	config.Flows().Clear()
+	flow := config.Flows().Add().SetName("vrf-selection")
+	flow.Metrics().SetEnable(true)
+	flow.TxRx().Device().
+		SetTxNames([]string{atePort1.Name + ".IPv4"}).
+		SetRxNames(rx)
+	flow.Rate().SetPps(pps)
+	flow.Duration().FixedPackets().SetPackets(int32(pps * trafficTime / time.Second))
+	flow.Size().SetFixed(256)
+	flow.Packet().Add().Ethernet().Src().SetValue(atePort1.MAC)
+	ip := flow.Packet().Add().Ipv4()
+	ip.Src().SetValue(atePort1.IPv4)
+	ip.Dst().SetValue(dst)
+	ip.Priority().Dscp().Phb().SetValues(dscps)
+}
+
+// sendAndCapture sends the flow of the config and returns the packets
+// captured on ate:port2.
+func sendAndCapture(t *testing.T, ate *ondatra.ATEDevice, api gosnappi.GosnappiApi, config gosnappi.Config) []*capture.Packet {
+	otg := ate.OTG()
+	capPort := ate.Port(t, "port2").ID()
+	otg.PushConfig(t, config)
+	otg.StartProtocols(t)
+	capture.Start(t, api, capPort)
+	otg.StartTraffic(t)
+	time.Sleep(trafficTime)
+	otg.StopTraffic(t)
+	capture.Stop(t, api, capPort)
+	otgutils.LogFlowMetrics(t, otg, config)
+	return capture.Fetch(t, api, capPort)
+}
+
+// countByNetworkInstance returns the number of packets of the flow
+// captured for each DSCP and network instance, given by their VLAN.
+func countByNetworkInstance(pkts []*capture.Packet, tp *vrfscale.Topology) map[uint8]map[string]uint64 {
+	byVLAN := map[uint16]string{}
+	for _, e := range tp.Endpoints() {
+		byVLAN[e.VLAN] = e.VRF
+	}
+	counts := map[uint8]map[string]uint64{}
+	for _, p := range pkts {
+		if p.IP == nil || p.IP.Dst.String() != dst || p.IP.Src.String() != atePort1.IPv4 {
+			continue
+		}
+		ni := *deviations.DefaultNetworkInstance
+		if p.Ethernet != nil && len(p.Ethernet.VLANs) > 0 {
+			vlan := p.Ethernet.VLANs[0]
+			if ni = byVLAN[vlan]; ni == "" {
+				ni = fmt.Sprintf("VLAN %d", vlan)
+			}
+		}
+		if counts[p.IP.DSCP] == nil {
+			counts[p.IP.DSCP] = map[string]uint64{}
+		}
+		counts[p.IP.DSCP][ni]++
+	}
+	return counts
+}
+
+// TestVRFSelection applies the VRF selection policy of the TE designs to
+// dut:port1, sends packets of every DSCP, and verifies from captures that
+// the packets of each DSCP are forwarded by the network instance its class
+// selects.
+//
+// config_path:/network-instances/network-instance/policy-forwarding/policies/policy/config/type
+// config_path:/network-instances/network-instance/policy-forwarding/policies/policy/rules/rule/ipv4/config/dscp-set
+// config_path:/network-instances/network-instance/policy-forwarding/policies/policy/rules/rule/action/config/network-instance
+// config_path:/network-instances/network-instance/policy-forwarding/interfaces/interface/config/apply-vrf-selection-policy
+func TestVRFSelection(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+
+	tp := newTopology(t)
+	var vrfs []string
+	for _, v := range tp.VRFs {
+		vrfs = append(vrfs, v.Name)
+	}
+	p, err := vrfselect.TE(policyName, *deviations.DefaultNetworkInstance, vrfs)
+	if err != nil {
+		t.Fatalf("Cannot build the VRF selection policy: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Invalid VRF selection policy: %v", err)
+	}
+	for _, s := range p.Matrix() {
+		t.Logf("DSCP %d: class %s, network instance %s", s.DSCP, s.Class, s.NetworkInstance)
+	}
+
+	configureDUT(t, dut, tp, p)
+	// The policy is deleted before the VRFs it selects.
+	defer tp.Unconfigure(t, dut)
+	defer dut.Config().NetworkInstance(*deviations.DefaultNetworkInstance).PolicyForwarding().Delete(t)
+	config := configureOTG(t, ate, tp)
+	api := fptest.DialOTG(t, ate)
+
+	addFlow(config, tp)
+	counts := countByNetworkInstance(sendAndCapture(t, ate, api, config), tp)
+	errs := p.Verify(counts)
+	for i, err := range errs {
+		if i == maxErrors {
+			t.Errorf("... and %d more errors", len(errs)-maxErrors)
+			break
+		}
+		t.Error(err)
+	}
+}
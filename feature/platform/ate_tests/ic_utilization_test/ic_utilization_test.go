@@ -0,0 +1,190 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ic_utilization_test implements gNMI-1.16: Telemetry:
+// Integrated Circuit Utilization.
+package ic_utilization_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fibscale"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/icutil"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+)
+
+var (
+	resource     = flag.String("ic_resource", "fib", "name of the integrated circuit resource holding the IPv4 routes")
+	component    = flag.String("ic_component", "", "integrated circuit checked, the first one reporting the resource if empty")
+	stepRoutes   = flag.Int("ic_step_routes", 10000, "number of routes programmed per step")
+	maxRoutes    = flag.Int("ic_max_routes", 2000000, "maximum number of routes programmed to cross the threshold")
+	perRoute     = flag.Float64("ic_entries_per_route", 1, "number of entries of the resource used per /32 route")
+	tolerance    = flag.Float64("ic_tolerance", 10, "tolerance in percent of the entries used from the expected")
+	threshold    = flag.Float64("ic_threshold", 90, "utilization in percent at which the DUT raises an alarm")
+	rampTime     = flag.Duration("ic_ramp_time", 30*time.Minute, "how long the utilization is collected while routes are programmed up to the threshold")
+	alarmTimeout = flag.Duration("ic_alarm_timeout", 2*time.Minute, "how long to wait for the alarm once the threshold is crossed")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The routes are programmed through gRIBI to a next hop on
+// ate:port2, and no traffic is sent.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - ate:port2 -> dut:port2 subnet 192.0.2.4/30
+//
+// The routes of the scale step are /32 from 198.18.0.0, and those
+// programmed up to the threshold /32 from 100.0.0.0.
+const (
+	plen4         = 30
+	nhIndex       = 1
+	nhgIndex      = 1
+	routesStart   = "198.18.0.0/32"
+	crossingStart = "100.0.0.0/32"
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "DUT to ATE port1", IPv4: "192.0.2.1", IPv4Len: plen4}
+	atePort1 = attrs.Attributes{Name: "port1", IPv4: "192.0.2.2", IPv4Len: plen4}
+	dutPort2 = attrs.Attributes{Desc: "DUT to ATE port2", IPv4: "192.0.2.5", IPv4Len: plen4}
+	atePort2 = attrs.Attributes{Name: "port2", IPv4: "192.0.2.6", IPv4Len: plen4}
+)
+
+// configure configures the ports of the DUT and the ATE.
+func configure(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice) {
+	dc := dut.Config()
+	top := ate.Topology().New()
+	for _, p := range []struct {
+		port     string
+		dut, ate *attrs.Attributes
+	}{{"port1", &dutPort1, &atePort1}, {"port2", &dutPort2, &atePort2}} {
+		intf := p.dut.NewInterface(dut.Port(t, p.port).Name())
+		dc.Interface(intf.GetName()).Replace(t, intf)
+		p.ate.AddToATE(top, ate.Port(t, p.port), p.dut)
+	}
+	top.Push(t).StartProtocols(t)
+}
+
+// TestICUtilization checks the consistency of the utilization of the
+// resource, that it grows with the routes programmed, and that the DUT
+// streams the crossing of the threshold and raises an alarm.
+//
+// telemetry_path:/components/component/integrated-circuit/utilization/resources/resource/state/used
+// telemetry_path:/components/component/integrated-circuit/utilization/resources/resource/state/free
+// telemetry_path:/components/component/integrated-circuit/utilization/resources/resource/state/max-limit
+// telemetry_path:/components/component/integrated-circuit/utilization/resources/resource/state/high-watermark
+// telemetry_path:/system/alarms/alarm/state/resource
+func TestICUtilization(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+
+	ics := icutil.Components(t, dut, *resource)
+	if len(ics) == 0 {
+		t.Fatalf("No integrated circuit of DUT %s reports the utilization of %s", dut.Model(), *resource)
+	}
+	ic := *component
+	if ic == "" {
+		ic = ics[0]
+	}
+	t.Logf("Integrated circuits reporting %s: %v, checking %s", *resource, ics, ic)
+
+	t.Run("Consistency", func(t *testing.T) {
+		for _, name := range ics {
+			u := icutil.Read(t, dut, name, *resource)
+			t.Logf("%s %s: %v", name, *resource, u)
+			for _, err := range u.Check() {
+				t.Errorf("%s %s: %v", name, *resource, err)
+			}
+		}
+	})
+
+	configure(t, dut, ate)
+	c := &gribi.Client{DUT: dut, FibACK: true}
+	defer c.Close(t)
+	if err := c.Start(t); err != nil {
+		t.Fatalf("Could not initialize gRIBI: %v", err)
+	}
+	c.BecomeLeader(t)
+	ni := *deviations.DefaultNetworkInstance
+	c.AddNH(t, nhIndex, atePort2.IPv4, ni, fluent.InstalledInFIB)
+	c.AddNHG(t, nhgIndex, map[uint64]uint64{nhIndex: 1}, ni, fluent.InstalledInFIB)
+
+	t.Run("RouteScale", func(t *testing.T) {
+		before := icutil.Read(t, dut, ic, *resource)
+		r := &fibscale.Ramp{
+			Injector: &fibscale.GRIBIInjector{Client: c, NetworkInstance: ni, NHGIndex: nhgIndex},
+			Start:    routesStart,
+			Step:     *stepRoutes,
+			Max:      *stepRoutes,
+		}
+		res := r.Run(t)
+		if res.Installed != *stepRoutes {
+			t.Fatalf("DUT installed %d of %d routes", res.Installed, *stepRoutes)
+		}
+		// The telemetry may lag the programming.
+		time.Sleep(30 * time.Second)
+		after := icutil.Read(t, dut, ic, *resource)
+		t.Logf("%s %s before: %v, after %d routes: %v", ic, *resource, before, res.Installed, after)
+		if err := icutil.CheckGrowth(before, after, uint64(res.Installed), *perRoute, *tolerance); err != nil {
+			t.Errorf("%s %s: %v", ic, *resource, err)
+		}
+		for _, err := range after.Check() {
+			t.Errorf("%s %s: %v", ic, *resource, err)
+		}
+	})
+
+	t.Run("ThresholdCrossing", func(t *testing.T) {
+		if u := icutil.Read(t, dut, ic, *resource); u.Percent() >= *threshold {
+			t.Fatalf("%s %s is already over the threshold of %.0f%%: %v", ic, *resource, *threshold, u)
+		}
+		collection := icutil.Collect(t, dut, ic, *resource, *rampTime)
+		r := &fibscale.Ramp{
+			Injector: &fibscale.GRIBIInjector{Client: c, NetworkInstance: ni, NHGIndex: nhgIndex},
+			Start:    crossingStart,
+			Step:     *stepRoutes,
+			Max:      *maxRoutes,
+			Exhausted: func(t testing.TB) bool {
+				return icutil.Read(t, dut, ic, *resource).Percent() >= *threshold
+			},
+		}
+		res := r.Run(t)
+		final := icutil.Read(t, dut, ic, *resource)
+		if final.Percent() < *threshold {
+			t.Fatalf("%s %s did not reach the threshold of %.0f%% with %d routes: %v", ic, *resource, *threshold, res.Installed, final)
+		}
+
+		usages := icutil.Usages(t, collection.Await(t))
+		if crossings := icutil.Crossings(usages, *threshold); len(crossings) == 0 {
+			t.Errorf("%s %s: none of the %d updates streamed crosses %.0f%%", ic, *resource, len(usages), *threshold)
+		}
+		if a := icutil.AwaitAlarm(t, dut, ic, *alarmTimeout); a == nil {
+			t.Errorf("No alarm raised for %s at %.1f%% of %s", ic, final.Percent(), *resource)
+		} else {
+			t.Logf("Alarm raised for %s: %s (%v)", ic, a.GetText(), a.GetSeverity())
+		}
+		for _, err := range final.Check() {
+			t.Errorf("%s %s: %v", ic, *resource, err)
+		}
+	})
+}
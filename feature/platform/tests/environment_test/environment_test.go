@@ -0,0 +1,144 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package environment_test implements gNMI-1.15: Telemetry: Chassis
+// Environment.
+package environment_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/components"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	fanMinRPM      = flag.Uint("env_fan_min_rpm", 1, "lowest speed in RPM of a running fan")
+	fanMaxRPM      = flag.Uint("env_fan_max_rpm", 0, "highest speed in RPM of a fan, 0 for no bound")
+	eventComponent = flag.String("env_event_component", "", "component rebooted through gNOI to cause a component event, none if empty")
+	eventTimeout   = flag.Duration("env_event_timeout", 10*time.Minute, "how long the rebooted component may take to become active again")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// TestPowerSupplies checks that every power supply is active and within
+// its allocated power.
+//
+// telemetry_path:/components/component/state/oper-status
+// telemetry_path:/components/component/state/allocated-power
+// telemetry_path:/components/component/state/used-power
+func TestPowerSupplies(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	psus := components.ByType(t, dut, telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_POWER_SUPPLY)
+	if len(psus) == 0 {
+		t.Fatalf("DUT %s has no power supply", dut.Model())
+	}
+	for _, c := range psus {
+		for _, err := range components.CheckPowerSupply(c) {
+			t.Error(err)
+		}
+	}
+}
+
+// TestFans checks that every fan reports a speed within the bounds.
+//
+// telemetry_path:/components/component/fan/state/speed
+func TestFans(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	fans := components.ByType(t, dut, telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_FAN)
+	if len(fans) == 0 {
+		t.Skipf("DUT %s has no fan", dut.Model())
+	}
+	for _, c := range fans {
+		for _, err := range components.CheckFan(c, uint32(*fanMinRPM), uint32(*fanMaxRPM)) {
+			t.Error(err)
+		}
+	}
+}
+
+// TestTemperatures checks that every temperature sensor reports a
+// temperature consistent with its alarm, and that the system alarms
+// match the temperature alarms.
+//
+// telemetry_path:/components/component/state/temperature/instant
+// telemetry_path:/components/component/state/temperature/min
+// telemetry_path:/components/component/state/temperature/max
+// telemetry_path:/components/component/state/temperature/alarm-status
+// telemetry_path:/components/component/state/temperature/alarm-threshold
+// telemetry_path:/components/component/state/temperature/alarm-severity
+// telemetry_path:/system/alarms/alarm/state/resource
+// telemetry_path:/system/alarms/alarm/state/severity
+func TestTemperatures(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	sensors := components.ByType(t, dut, telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_SENSOR)
+	if len(sensors) == 0 {
+		t.Fatalf("DUT %s has no temperature sensor", dut.Model())
+	}
+	for _, c := range sensors {
+		t.Logf("Sensor %s: %.1f degrees", c.GetName(), c.GetTemperature().GetInstant())
+		for _, err := range components.CheckTemperature(c) {
+			t.Error(err)
+		}
+	}
+	alarms := components.Alarms(t, dut)
+	t.Logf("DUT has %d alarms", len(alarms))
+	for _, err := range components.CheckAlarms(sensors, alarms) {
+		t.Error(err)
+	}
+}
+
+// TestComponentEvent reboots a component through gNOI, and checks that
+// its oper-status goes down then back to active, that its last reboot
+// time is updated, and that the alarms stay consistent.
+//
+// telemetry_path:/components/component/state/oper-status
+// telemetry_path:/components/component/state/last-reboot-time
+func TestComponentEvent(t *testing.T) {
+	if *eventComponent == "" {
+		t.Skip("No component to reboot, see -env_event_component")
+	}
+	dut := ondatra.DUT(t, "dut")
+	comp := dut.Telemetry().Component(*eventComponent)
+	before := comp.LastRebootTime().Lookup(t)
+
+	if err := components.Reboot(t, dut, *eventComponent); err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			t.Skipf("DUT %s cannot reboot %s through gNOI: %v", dut.Model(), *eventComponent, err)
+		}
+		t.Fatalf("Cannot reboot %s: %v", *eventComponent, err)
+	}
+	if !components.AwaitOperStatus(t, dut, *eventComponent, time.Minute, telemetry.PlatformTypes_COMPONENT_OPER_STATUS_INACTIVE) {
+		t.Errorf("Component %s did not become INACTIVE after its reboot", *eventComponent)
+	}
+	if !components.AwaitOperStatus(t, dut, *eventComponent, *eventTimeout, telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE) {
+		t.Fatalf("Component %s did not become ACTIVE in %v after its reboot", *eventComponent, *eventTimeout)
+	}
+	if after := comp.LastRebootTime().Lookup(t); !after.IsPresent() || (before.IsPresent() && after.Val(t) <= before.Val(t)) {
+		t.Errorf("Component %s last-reboot-time got %v, want after %v", *eventComponent, after, before)
+	}
+
+	sensors := components.ByType(t, dut, telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_SENSOR)
+	for _, err := range components.CheckAlarms(sensors, components.Alarms(t, dut)) {
+		t.Error(err)
+	}
+}
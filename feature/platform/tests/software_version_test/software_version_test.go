@@ -0,0 +1,54 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package software_version_test implements gNMI-1.29: Telemetry: Software
+// Version Consistency.
+package software_version_test
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/components"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+)
+
+var softwareVersion = flag.String("software_version", "", "software version the supervisors and linecards are expected to run, as after an upgrade, or empty for the version most of them run")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// TestSoftwareVersions checks that the active supervisors and linecards
+// all report the same software version, the one expected if given.
+//
+// telemetry_path:/components/component/state/type
+// telemetry_path:/components/component/state/empty
+// telemetry_path:/components/component/state/oper-status
+// telemetry_path:/components/component/state/software-version
+func TestSoftwareVersions(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	cs := dut.Telemetry().ComponentAny().Get(t)
+	versions := components.SoftwareVersions(cs)
+	if len(versions) == 0 {
+		t.Fatalf("DUT %s has no active supervisor or linecard", dut.Model())
+	}
+	for name, v := range versions {
+		t.Logf("Component %s software-version %q", name, v)
+	}
+	for _, m := range components.CheckSoftwareVersions(cs, *softwareVersion) {
+		t.Error(m)
+	}
+}
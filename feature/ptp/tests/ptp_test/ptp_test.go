@@ -0,0 +1,132 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ptp_test implements PTP-1.1: PTP Boundary Clock Phase and
+// Frequency.
+package ptp_test
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/ptp"
+	"github.com/openconfig/ondatra"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+var (
+	maxOffset = flag.Duration("ptp_max_offset", 100*time.Nanosecond,
+		"Maximum absolute offset of the DUT from the grandmaster, the max|TE| of a class A boundary clock in ITU-T G.8271.1.")
+	maxMeanPathDelay = flag.Duration("ptp_max_mean_path_delay", 10*time.Microsecond,
+		"Maximum mean path delay from the DUT to the grandmaster, as expected of the cabling of the testbed.")
+	maxDrift = flag.Float64("ptp_max_drift_ppb", 1,
+		"Maximum drift of the offset of the DUT from the grandmaster while locked, in parts per billion.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of the PTP grandmaster registered by the binding,
+// an ATE port or a lab grandmaster, connected to a port of the DUT.  The
+// DUT is a boundary clock with a PTP port on that port.
+const (
+	instance = 1
+	// lockTimeout is how long the DUT may take to lock to the
+	// grandmaster, including the settling of its servo.
+	lockTimeout    = 10 * time.Minute
+	sampleInterval = 5 * time.Second
+	sampleTime     = 2 * time.Minute
+)
+
+// set applies the SetRequest to the DUT.
+func set(t *testing.T, dut *ondatra.DUTDevice, req *gpb.SetRequest, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Invalid PTP configuration: %v", err)
+	}
+	if _, err := dut.RawAPIs().GNMI().Default(t).Set(context.Background(), req); err != nil {
+		t.Fatalf("Cannot configure PTP: %v", err)
+	}
+}
+
+// TestPTP verifies that the DUT locks to the grandmaster, and that while
+// locked its offset from the grandmaster stays within bounds, bounding its
+// phase error, and does not drift, bounding its frequency error.
+//
+// config_path:/ptp/instance-list/default-ds/domain-number
+// config_path:/ptp/instance-list/port-ds-list/underlying-interface
+// config_path:/ptp/instance-list/port-ds-list/delay-mechanism
+// telemetry_path:/ptp/instance-list/default-ds/clock-quality/clock-class
+// telemetry_path:/ptp/instance-list/current-ds/steps-removed
+// telemetry_path:/ptp/instance-list/current-ds/offset-from-master
+// telemetry_path:/ptp/instance-list/current-ds/mean-path-delay
+// telemetry_path:/ptp/instance-list/parent-ds/parent-port-identity/clock-identity
+// telemetry_path:/ptp/instance-list/parent-ds/grandmaster-identity
+// telemetry_path:/ptp/instance-list/parent-ds/grandmaster-clock-quality/clock-class
+// telemetry_path:/ptp/instance-list/port-ds-list/port-state
+func TestPTP(t *testing.T) {
+	gm := ptp.RequireGrandmaster(t)
+	dut := fptest.RequireDUT(t, "dut", fptest.Requirements{PortIDs: []string{gm.Port}})
+	p := dut.Port(t, gm.Port).Name()
+	dut.Config().Interface(p).Replace(t, (&attrs.Attributes{Desc: "To grandmaster"}).NewInterface(p))
+
+	c := &ptp.Config{Instance: instance, Domain: gm.Domain, Interfaces: []string{p}}
+	req, err := c.SetRequest()
+	set(t, dut, req, err)
+	defer func() {
+		req, err := c.DeleteRequest()
+		set(t, dut, req, err)
+	}()
+
+	gnmi := dut.RawAPIs().GNMI().Default(t)
+	b := ptp.Bounds{MaxOffset: *maxOffset, MaxMeanPathDelay: *maxMeanPathDelay}
+	s, err := ptp.AwaitLocked(t, gnmi, c, gm, b, args.Convergence(lockTimeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Locked to %s: clock class %d, offset %v, mean path delay %v", s.Grandmaster, s.ClockClass, s.Offset, s.MeanPathDelay)
+
+	var samples []ptp.Sample
+	t.Run("Phase", func(t *testing.T) {
+		for start := time.Now(); time.Since(start) < sampleTime; time.Sleep(sampleInterval) {
+			at := time.Now()
+			s, err := ptp.ReadState(context.Background(), gnmi, instance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, err := range s.Check(gm, p, b) {
+				t.Errorf("At %v: %v", at.Format(time.RFC3339), err)
+			}
+			samples = append(samples, ptp.Sample{Time: at, Offset: s.Offset})
+		}
+	})
+
+	t.Run("Frequency", func(t *testing.T) {
+		drift, err := ptp.Drift(samples)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("Offset drift over %d samples: %.3f ppb", len(samples), drift)
+		if drift > *maxDrift || drift < -*maxDrift {
+			t.Errorf("Offset drift got %.3f ppb, want within ±%g ppb", drift, *maxDrift)
+		}
+	})
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package control_plane_marking_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	marking = flag.String("control_plane_marking", "bgp=48,gnmi=16,ntp=48,dns=48",
+		"comma separated DSCP the DUT marks the control plane traffic it originates with, in the form class=dscp.  The DUT is expected to be configured with this profile, e.g. by the binding.  Classes without a DSCP are not checked.")
+	captureTime = flag.Duration("control_plane_marking_capture_time", time.Minute,
+		"time the packets originated by the DUT are captured for")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1.  ate:port1 is the
+// eBGP peer, the NTP server and the DNS server of the DUT, and captures
+// the packets the DUT sends to it.  The ATE does not answer NTP and
+// DNS, so that the DUT keeps sending requests.
+//
+//   - dut:port1 -> ate:port1 subnet 192.0.2.0/30
+const (
+	plen4 = 30
+	dutAS = 64500
+	ateAS = 64501
+	// ntpName is resolved by the DUT through the DNS server, which
+	// makes it send DNS queries.
+	ntpName = "ntp.marking.test"
+)
+
+var (
+	dutPort1 = attrs.Attributes{
+		Desc:    "DUT to ATE",
+		IPv4:    "192.0.2.1",
+		IPv4Len: plen4,
+	}
+
+	atePort1 = attrs.Attributes{
+		Name:    "ate",
+		MAC:     "02:00:01:01:01:01",
+		IPv4:    "192.0.2.2",
+		IPv4Len: plen4,
+	}
+)
+
+// configureDUT configures port1, the eBGP session, and the NTP and DNS
+// servers of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	p1 := dut.Port(t, "port1")
+	d.Interface(p1.Name()).Replace(t, dutPort1.NewInterface(p1.Name()))
+
+	dev := &telemetry.Device{}
+	bgp := dev.GetOrCreateNetworkInstance(*deviations.DefaultNetworkInstance).
+		GetOrCreateProtocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").GetOrCreateBgp()
+	bgp.GetOrCreateGlobal().As = ygot.Uint32(dutAS)
+	bgp.GetOrCreateGlobal().RouterId = ygot.String(dutPort1.IPv4)
+	nbr := bgp.GetOrCreateNeighbor(atePort1.IPv4)
+	nbr.PeerAs = ygot.Uint32(ateAS)
+	nbr.Enabled = ygot.Bool(true)
+	nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Enabled = ygot.Bool(true)
+	bgpPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	fptest.LogYgot(t, "DUT BGP", bgpPath, bgp)
+	bgpPath.Replace(t, bgp)
+
+	ntp := &telemetry.System_Ntp{Enabled: ygot.Bool(true)}
+	for _, addr := range []string{atePort1.IPv4, ntpName} {
+		s := ntp.GetOrCreateServer(addr)
+		s.Iburst = ygot.Bool(true)
+	}
+	d.System().Ntp().Replace(t, ntp)
+
+	d.System().Dns().Server(atePort1.IPv4).Replace(t, &telemetry.System_Dns_Server{
+		Address: ygot.String(atePort1.IPv4),
+		Port:    ygot.Uint16(capture.ControlDNS.Port),
+	})
+}
+
+// unconfigureDUT removes the configuration of configureDUT, except the
+// interface.
+func unconfigureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	d.System().Dns().Server(atePort1.IPv4).Delete(t)
+	d.System().Ntp().Delete(t)
+	d.NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Delete(t)
+}
+
+// configureOTG returns the OTG configuration of ate:port1, with the
+// eBGP peer of the DUT, capturing on ate:port1.
+func configureOTG(t *testing.T, ate *ondatra.ATEDevice) gosnappi.Config {
+	config := ate.OTG().NewConfig(t)
+	port := ate.Port(t, "port1")
+	config.Ports().Add().SetName(port.ID())
+	dev := config.Devices().Add().SetName(atePort1.Name)
+	eth := dev.Ethernets().Add().
+		SetName(atePort1.Name + ".eth").
+		SetPortName(port.ID()).
+		SetMac(atePort1.MAC)
+	ip := eth.Ipv4Addresses().Add().
+		SetName(atePort1.Name + ".IPv4").
+		SetAddress(atePort1.IPv4).
+		SetGateway(dutPort1.IPv4).
+		SetPrefix(int32(atePort1.IPv4Len))
+	dev.Bgp().SetRouterId(ip.Address()).
+		Ipv4Interfaces().Add().SetIpv4Name(ip.Name()).
+		Peers().Add().SetName(atePort1.Name + ".BGP4.peer").
+		SetPeerAddress(ip.Gateway()).
+		SetAsNumber(ateAS).
+		SetAsType(gosnappi.BgpV4PeerAsType.EBGP)
+	capture.Enable(config, port.ID())
+	return config
+}
+
+// awaitBGP waits for the eBGP session with ate:port1 to establish.
+func awaitBGP(t *testing.T, dut *ondatra.DUTDevice) {
+	nbrPath := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Neighbor(atePort1.IPv4)
+	_, ok := nbrPath.SessionState().Watch(t, 2*time.Minute, func(val *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+		return val.IsPresent() && val.Val(t) == telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+	}).Await(t)
+	if !ok {
+		fptest.LogYgot(t, "BGP reported state", nbrPath, nbrPath.Get(t))
+		t.Fatal("No BGP neighbor formed")
+	}
+}
+
+// TestControlPlaneMarking verifies that the BGP, gNMI, NTP and DNS
+// packets the DUT originates are marked with the DSCP of the control
+// plane marking profile.  gNMI is only checked when the DUT reaches a
+// collector through ate:port1.
+//
+// config_path:/system/ntp/servers/server/config/address
+// config_path:/system/dns/servers/server/config/address
+// config_path:/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/config/peer-as
+func TestControlPlaneMarking(t *testing.T) {
+	profile, err := capture.ParseMarkingProfile(*marking)
+	if err != nil {
+		t.Fatalf("Invalid -control_plane_marking: %v", err)
+	}
+
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	defer unconfigureDUT(t, dut)
+
+	config := configureOTG(t, ate)
+	otg := ate.OTG()
+	api := fptest.DialOTG(t, ate)
+	capPort := ate.Port(t, "port1").ID()
+	otg.PushConfig(t, config)
+
+	// Capture from the start of the protocols, so that the BGP session
+	// establishment is captured too.
+	capture.Start(t, api, capPort)
+	otg.StartProtocols(t)
+	awaitBGP(t, dut)
+	time.Sleep(*captureTime)
+	capture.Stop(t, api, capPort)
+	pkts := capture.Fetch(t, api, capPort)
+	t.Logf("Captured %d packets on %s", len(pkts), capPort)
+
+	for _, class := range capture.ControlClasses {
+		t.Run(class.Name, func(t *testing.T) {
+			dscp, ok := profile[class.Name]
+			if !ok {
+				t.Skipf("No DSCP for class %s in -control_plane_marking", class.Name)
+			}
+			n, errs := capture.VerifyMarking(pkts, class, dutPort1.IPv4, dscp)
+			if n == 0 {
+				if class == capture.ControlGNMI {
+					t.Skipf("No %s packet from %s is visible to the ATE", class.Name, dutPort1.IPv4)
+				}
+				t.Fatalf("No %s packet from %s captured in %v", class.Name, dutPort1.IPv4, *captureTime)
+			}
+			t.Logf("Captured %d %s packets from %s", n, class.Name, dutPort1.IPv4)
+			for _, err := range errs {
+				t.Error(err)
+			}
+		})
+	}
+}
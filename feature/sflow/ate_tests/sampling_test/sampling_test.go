@@ -0,0 +1,216 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sampling_test implements SFLOW-1.1.
+package sampling_test
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/sampling"
+	"github.com/openconfig/ondatra"
+)
+
+var (
+	collectorHost = flag.String("sampling_collector_host", "", "Address of the test host as reached by the DUT, where the sampling collector listens.  TestSampling is skipped if empty.")
+	collectorPort = flag.Int("sampling_collector_port", sampling.SflowPort, "Port on which the sampling collector listens.")
+	sourceAddress = flag.String("sampling_source_address", "", "Address of the DUT from which it exports the samples, if any.")
+	tolerance     = flag.Float64("sampling_tolerance_pct", 20, "Tolerance, in percent, of the packets estimated from the samples of a flow to the packets sent.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and
+// dut:port2 -> ate:port2.  The DUT samples the packets received on
+// port1.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30 2001:db8::0/126
+//   - dut:port2 -> ate:port2 subnet 192.0.2.4/30 2001:db8::4/126
+const (
+	plen4 = 30
+	plen6 = 126
+
+	samplingRate = 1000
+	frameRate    = 10000
+	trafficTime  = 30 * time.Second
+	// exportDelay is the time given to the DUT to export its last
+	// samples after the traffic stops.
+	exportDelay = 10 * time.Second
+)
+
+var (
+	dutPort1 = attrs.Attributes{
+		Desc:    "dutPort1",
+		IPv4:    "192.0.2.1",
+		IPv6:    "2001:db8::1",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+
+	atePort1 = attrs.Attributes{
+		Name:    "atePort1",
+		IPv4:    "192.0.2.2",
+		IPv6:    "2001:db8::2",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+
+	dutPort2 = attrs.Attributes{
+		Desc:    "dutPort2",
+		IPv4:    "192.0.2.5",
+		IPv6:    "2001:db8::5",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+
+	atePort2 = attrs.Attributes{
+		Name:    "atePort2",
+		IPv4:    "192.0.2.6",
+		IPv6:    "2001:db8::6",
+		IPv4Len: plen4,
+		IPv6Len: plen6,
+	}
+)
+
+// configureDUT configures the ports of the DUT, and sFlow sampling of
+// the packets received on port1.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	p1 := dut.Port(t, "port1")
+	d.Interface(p1.Name()).Replace(t, dutPort1.NewInterface(p1.Name()))
+	p2 := dut.Port(t, "port2")
+	d.Interface(p2.Name()).Replace(t, dutPort2.NewInterface(p2.Name()))
+
+	d.Sampling().Sflow().Replace(t, sampling.Config(*collectorHost, uint16(*collectorPort), *sourceAddress, samplingRate, p1.Name()))
+}
+
+// configureATE configures the ports of the ATE.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.ATETopology {
+	top := ate.Topology().New()
+	atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	top.Push(t).StartProtocols(t)
+	return top
+}
+
+// TestSampling verifies that the DUT exports sFlow samples of the packets
+// it receives to a collector embedded in the test, and that the samples
+// of the IPv4 and IPv6 flows of the ATE carry their addresses, DSCP and
+// input interface, and stand for the packets the flows sent at the
+// sampling rate.
+//
+// config_path:/sampling/sflow/config/enabled
+// config_path:/sampling/sflow/config/ingress-sampling-rate
+// config_path:/sampling/sflow/collectors/collector/config/address
+// config_path:/sampling/sflow/collectors/collector/config/port
+// config_path:/sampling/sflow/collectors/collector/config/source-address
+// config_path:/sampling/sflow/interfaces/interface/config/enabled
+// telemetry_path:/sampling/sflow/collectors/collector/state/packets-sent
+// telemetry_path:/sampling/sflow/interfaces/interface/state/packets-sampled
+func TestSampling(t *testing.T) {
+	if *collectorHost == "" {
+		t.Skip("No -sampling_collector_host for the DUT to export the samples to")
+	}
+	c, err := sampling.NewCollector(fmt.Sprintf(":%d", *collectorPort))
+	if err != nil {
+		t.Fatalf("Cannot start the sampling collector: %v", err)
+	}
+	defer c.Close()
+
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	defer dut.Config().Sampling().Sflow().Delete(t)
+	top := configureATE(t, ate)
+	defer top.StopProtocols(t)
+
+	p1 := dut.Port(t, "port1").Name()
+	ifindex := dut.Telemetry().Interface(p1).Ifindex().Get(t)
+	src := top.Interfaces()[atePort1.Name]
+	dst := top.Interfaces()[atePort2.Name]
+	eth := ondatra.NewEthernetHeader()
+
+	for _, tc := range []struct {
+		desc             string
+		srcAddr, dstAddr string
+		dscp             uint8
+		header           ondatra.Header
+	}{{
+		desc:    "IPv4",
+		srcAddr: atePort1.IPv4,
+		dstAddr: atePort2.IPv4,
+		dscp:    46,
+		header:  ondatra.NewIPv4Header().WithSrcAddress(atePort1.IPv4).WithDstAddress(atePort2.IPv4).WithDSCP(46),
+	}, {
+		desc:    "IPv6",
+		srcAddr: atePort1.IPv6,
+		dstAddr: atePort2.IPv6,
+		dscp:    10,
+		header:  ondatra.NewIPv6Header().WithSrcAddress(atePort1.IPv6).WithDstAddress(atePort2.IPv6).WithDSCP(10),
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			c.Reset()
+			flow := ate.Traffic().NewFlow(tc.desc).
+				WithSrcEndpoints(src).
+				WithDstEndpoints(dst).
+				WithHeaders(eth, tc.header).
+				WithFrameRateFPS(frameRate)
+			ate.Traffic().Start(t, flow)
+			time.Sleep(trafficTime)
+			ate.Traffic().Stop(t)
+			time.Sleep(exportDelay)
+
+			sent := ate.Telemetry().Flow(flow.Name()).Counters().OutPkts().Get(t)
+			samples := sampling.Filter(c.Samples(), func(s *sampling.Sample) bool {
+				return s.Matches(tc.srcAddr, tc.dstAddr)
+			})
+			if len(samples) == 0 {
+				t.Fatalf("Collector got no samples of flow %s among %d; decoding errors: %v", flow.Name(), len(c.Samples()), c.Errors())
+			}
+			t.Logf("Collector got %d samples of flow %s, the first: %v", len(samples), flow.Name(), samples[0])
+			for _, s := range samples {
+				if s.Rate != samplingRate {
+					t.Errorf("Sample rate got %d, want %d: %v", s.Rate, samplingRate, s)
+					break
+				}
+				if s.DSCP != tc.dscp {
+					t.Errorf("Sample DSCP got %d, want %d: %v", s.DSCP, tc.dscp, s)
+					break
+				}
+				if s.Input != ifindex {
+					t.Errorf("Sample input ifindex got %d, want %d of %s: %v", s.Input, ifindex, p1, s)
+					break
+				}
+			}
+			if err := sampling.CheckEstimate(sampling.Estimate(samples), sent, *tolerance); err != nil {
+				t.Errorf("Flow %s: %v", flow.Name(), err)
+			}
+		})
+	}
+
+	sflow := dut.Telemetry().Sampling().Sflow()
+	if v := sflow.Collector(*collectorHost, uint16(*collectorPort)).PacketsSent().Lookup(t); v.IsPresent() && v.Val(t) == 0 {
+		t.Errorf("Collector packets-sent got 0, want the packets exported")
+	}
+	if v := sflow.Interface(p1).PacketsSampled().Lookup(t); v.IsPresent() && v.Val(t) == 0 {
+		t.Errorf("Interface %s packets-sampled got 0, want the packets sampled", p1)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bfd_tracking_test implements RT-1.14: Static Route BFD Tracking.
+package bfd_tracking_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/faults"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/staticbfd"
+	"github.com/openconfig/featureprofiles/internal/teservice"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var failoverTolerance = flag.Duration("bfd_failover_tolerance", 200*time.Millisecond,
+	"Time the traffic may take to fail over beyond the BFD detection time, for the DUT to reprogram its forwarding.")
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3.  The DUT has a static route to the
+// destination prefix through ate:port2, tracked by a BFD session, and a
+// backup through ate:port3 of a higher preference.
+//
+//   - ate:port1 -> dut:port1 subnet 192.0.2.0/30
+//   - dut:port2 -> ate:port2 subnet 192.0.2.4/30
+//   - dut:port3 -> ate:port3 subnet 192.0.2.8/30
+const (
+	plen       = 30
+	staticName = "STATIC"
+	prefix     = "198.51.100.0/24"
+	primary    = 10
+	backup     = 20
+	pps        = 10000
+	// settle is how long traffic runs before and after each failure and
+	// restoration.
+	settle     = 10 * time.Second
+	aftTimeout = time.Minute
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv4: "192.0.2.1", IPv4Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv4: "192.0.2.2", IPv4Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv4: "192.0.2.5", IPv4Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv4: "192.0.2.6", IPv4Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv4: "192.0.2.9", IPv4Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", IPv4: "192.0.2.10", IPv4Len: plen}
+)
+
+// configureDUT configures the ports of the DUT and the static route to the
+// destination prefix.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	static := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC,
+		Name:       ygot.String(staticName),
+	}
+	if err := static.AppendStatic(staticbfd.Static(prefix,
+		staticbfd.NextHop{Address: atePort2.IPv4, Preference: primary, BFD: true},
+		staticbfd.NextHop{Address: atePort3.IPv4, Preference: backup},
+	)); err != nil {
+		t.Fatalf("Cannot add static route: %v", err)
+	}
+	staticPath := d.NetworkInstance(*deviations.DefaultNetworkInstance).Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName)
+	fptest.LogYgot(t, "DUT static routes", staticPath, static)
+	staticPath.Replace(t, static)
+}
+
+// configureATE configures the ports of the ATE and returns the flow to the
+// destination prefix.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) *ondatra.Flow {
+	top := ate.Topology().New()
+	src := atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1)
+	dst2 := atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2)
+	dst3 := atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3)
+	top.Push(t).StartProtocols(t)
+
+	ipv4 := ondatra.NewIPv4Header()
+	ipv4.DstAddressRange().WithMin("198.51.100.1").WithCount(254)
+	return ate.Traffic().NewFlow("Static").
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst2, dst3).
+		WithHeaders(ondatra.NewEthernetHeader(), ipv4).
+		WithFrameRateFPS(args.PPS(pps))
+}
+
+// checkFailover runs the change of the next hop with traffic to the
+// destination prefix, checks that the traffic is interrupted for at most
+// the BFD detection time and that the AFT entry of the prefix is then
+// resolved to the wanted next hop.
+func checkFailover(t *testing.T, ate *ondatra.ATEDevice, dut *ondatra.DUTDevice, flow *ondatra.Flow, detection time.Duration, change func(t testing.TB), want string) {
+	counters := func(t testing.TB) (uint64, uint64) {
+		c := ate.Telemetry().Flow(flow.Name()).Counters().Get(t)
+		return c.GetOutPkts(), c.GetInPkts()
+	}
+	ate.Traffic().Start(t, flow)
+	tx, rx := teservice.Measure(t, counters, settle, change)
+	ate.Traffic().Stop(t)
+
+	if tx == 0 {
+		t.Fatalf("No packets sent to %s", prefix)
+	}
+	w := gribi.LossWindow(tx, rx, args.PPS(pps))
+	t.Logf("Lost %d of %d packets, a loss window of %v", tx-rx, tx, w)
+	if err := staticbfd.CheckFailover(w, detection, args.Convergence(*failoverTolerance)); err != nil {
+		t.Error(err)
+	}
+	if err := staticbfd.AwaitNextHops(t, dut, prefix, []string{want}, aftTimeout); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestBFDTracking configures a static route through a primary next hop
+// tracked by BFD and a backup next hop, blackholes the link to the primary
+// next hop, which stays up, and verifies that the DUT withdraws the primary
+// next hop and fails the traffic over to the backup within the BFD
+// detection time, and back once the link forwards again.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/config/prefix
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/next-hop
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/preference
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/enable-bfd/config/enabled
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/next-hop-group
+// telemetry_path:/network-instances/network-instance/afts/next-hops/next-hop/state/ip-address
+func TestBFDTracking(t *testing.T) {
+	detection := staticbfd.Require(t)
+	dg := faults.RequireDegrader(t)
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	flow := configureATE(t, ate)
+
+	if err := staticbfd.AwaitNextHops(t, dut, prefix, []string{atePort2.IPv4}, aftTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	trig := &teservice.BFDDown{Degrader: dg, Link: faults.LinkOf(t, dut, ate, "port2")}
+	t.Run("Fail", func(t *testing.T) {
+		checkFailover(t, ate, dut, flow, detection, trig.Fail, atePort3.IPv4)
+	})
+	t.Run("Restore", func(t *testing.T) {
+		// The backup forwards until the BFD session is up again, so the
+		// traffic should not be interrupted longer than by a failure.
+		checkFailover(t, ate, dut, flow, detection, trig.Restore, atePort2.IPv4)
+	})
+}
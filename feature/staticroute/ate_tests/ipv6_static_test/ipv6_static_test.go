@@ -0,0 +1,313 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ipv6_static_test implements RT-1.15: IPv6 Static Routes.
+package ipv6_static_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/featureprofiles/internal/staticbfd"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/config/networkinstance"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1, dut:port2 -> ate:port2
+// and dut:port3 -> ate:port3, over IPv6 only.
+//
+//   - ate:port1 -> dut:port1 subnet 2001:db8::/126
+//   - dut:port2 -> ate:port2 subnet 2001:db8::4/126
+//   - dut:port3 -> ate:port3 subnet 2001:db8::8/126
+const (
+	plen        = 126
+	staticName  = "STATIC"
+	fps         = 1000
+	trafficTime = 10 * time.Second
+	aftTimeout  = 2 * time.Minute
+)
+
+var (
+	dutPort1 = attrs.Attributes{Desc: "dutPort1", IPv6: "2001:db8::1", IPv6Len: plen}
+	atePort1 = attrs.Attributes{Name: "atePort1", IPv6: "2001:db8::2", IPv6Len: plen}
+	dutPort2 = attrs.Attributes{Desc: "dutPort2", IPv6: "2001:db8::5", IPv6Len: plen}
+	atePort2 = attrs.Attributes{Name: "atePort2", IPv6: "2001:db8::6", IPv6Len: plen}
+	dutPort3 = attrs.Attributes{Desc: "dutPort3", IPv6: "2001:db8::9", IPv6Len: plen}
+	atePort3 = attrs.Attributes{Name: "atePort3", IPv6: "2001:db8::a", IPv6Len: plen}
+)
+
+// route is a static route of the DUT and the port of the ATE its traffic
+// is expected on, none for a drop route.
+type route struct {
+	desc   string
+	prefix string
+	// dst is an address of the prefix, the destination of its traffic.
+	dst string
+	// via is the address of the ATE port the prefix resolves to, empty
+	// for a drop route.
+	via  string
+	port string
+	// static returns the static route of the prefix on the DUT.
+	static func(t testing.TB, dut *ondatra.DUTDevice) *telemetry.NetworkInstance_Protocol_Static
+}
+
+// nextHop adds a next hop to the address to the static route, indexed
+// after the others, and returns it.
+func nextHop(s *telemetry.NetworkInstance_Protocol_Static, addr string) *telemetry.NetworkInstance_Protocol_Static_NextHop {
+	nh := s.GetOrCreateNextHop(fmt.Sprint(len(s.NextHop) + 1))
+	nh.NextHop = telemetry.UnionString(addr)
+	return nh
+}
+
+// metricRoute returns the static route to the prefix through ate:port2 and
+// ate:port3 with the metrics.
+func metricRoute(prefix string, port2Metric, port3Metric uint32) *telemetry.NetworkInstance_Protocol_Static {
+	s := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String(prefix)}
+	nextHop(s, atePort2.IPv6).Metric = ygot.Uint32(port2Metric)
+	nextHop(s, atePort3.IPv6).Metric = ygot.Uint32(port3Metric)
+	return s
+}
+
+var routes = []route{{
+	desc:   "next hop",
+	prefix: "2001:db8:100::/64",
+	dst:    "2001:db8:100::1",
+	via:    atePort2.IPv6,
+	port:   "port2",
+	static: func(testing.TB, *ondatra.DUTDevice) *telemetry.NetworkInstance_Protocol_Static {
+		s := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String("2001:db8:100::/64")}
+		nextHop(s, atePort2.IPv6)
+		return s
+	},
+}, {
+	desc:   "interface next hop",
+	prefix: "2001:db8:101::/64",
+	dst:    "2001:db8:101::1",
+	via:    atePort3.IPv6,
+	port:   "port3",
+	static: func(t testing.TB, dut *ondatra.DUTDevice) *telemetry.NetworkInstance_Protocol_Static {
+		s := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String("2001:db8:101::/64")}
+		ref := nextHop(s, atePort3.IPv6).GetOrCreateInterfaceRef()
+		ref.Interface = ygot.String(dut.Port(t, "port3").Name())
+		ref.Subinterface = ygot.Uint32(0)
+		return s
+	},
+}, {
+	desc:   "recursive next hop",
+	prefix: "2001:db8:102::/64",
+	dst:    "2001:db8:102::1",
+	via:    atePort2.IPv6,
+	port:   "port2",
+	static: func(testing.TB, *ondatra.DUTDevice) *telemetry.NetworkInstance_Protocol_Static {
+		s := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String("2001:db8:102::/64")}
+		// Resolved over the route of the next hop case.
+		nextHop(s, "2001:db8:100::1").Recurse = ygot.Bool(true)
+		return s
+	},
+}, {
+	desc:   "drop",
+	prefix: "2001:db8:103::/64",
+	dst:    "2001:db8:103::1",
+	static: func(testing.TB, *ondatra.DUTDevice) *telemetry.NetworkInstance_Protocol_Static {
+		s := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String("2001:db8:103::/64")}
+		s.GetOrCreateNextHop("1").NextHop = telemetry.LocalRouting_LOCAL_DEFINED_NEXT_HOP_DROP
+		return s
+	},
+}, {
+	desc:   "metrics",
+	prefix: "2001:db8:104::/64",
+	dst:    "2001:db8:104::1",
+	via:    atePort3.IPv6,
+	port:   "port3",
+	static: func(testing.TB, *ondatra.DUTDevice) *telemetry.NetworkInstance_Protocol_Static {
+		return metricRoute("2001:db8:104::/64", 20, 10)
+	},
+}}
+
+// staticPath returns the config path of the static routes of the DUT.
+func staticPath(dut *ondatra.DUTDevice) *networkinstance.NetworkInstance_ProtocolPath {
+	return dut.Config().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName)
+}
+
+// configureDUT configures the ports and the static routes of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutPort1}, {"port2", &dutPort2}, {"port3", &dutPort3}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	static := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC,
+		Name:       ygot.String(staticName),
+	}
+	for _, r := range routes {
+		if err := static.AppendStatic(r.static(t, dut)); err != nil {
+			t.Fatalf("Cannot add static route: %v", err)
+		}
+	}
+	fptest.LogYgot(t, "DUT static routes", staticPath(dut), static)
+	staticPath(dut).Replace(t, static)
+}
+
+// configureATE configures the ports of the ATE and returns their
+// interfaces by port ID.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) map[string]*ondatra.Interface {
+	top := ate.Topology().New()
+	intfs := map[string]*ondatra.Interface{
+		"port1": atePort1.AddToATE(top, ate.Port(t, "port1"), &dutPort1),
+		"port2": atePort2.AddToATE(top, ate.Port(t, "port2"), &dutPort2),
+		"port3": atePort3.AddToATE(top, ate.Port(t, "port3"), &dutPort3),
+	}
+	top.Push(t).StartProtocols(t)
+	return intfs
+}
+
+// sendTraffic sends a flow to the destination of each route, received by
+// the ATE port the route is expected to forward it to, and returns the
+// loss of each in percent.  The flow of a drop route is received by all
+// the ports.
+func sendTraffic(t *testing.T, ate *ondatra.ATEDevice, intfs map[string]*ondatra.Interface, routes []route) []float32 {
+	var flows []*ondatra.Flow
+	for _, r := range routes {
+		dsts := []ondatra.Endpoint{intfs["port2"], intfs["port3"]}
+		if r.port != "" {
+			dsts = []ondatra.Endpoint{intfs[r.port]}
+		}
+		flows = append(flows, ate.Traffic().NewFlow(r.desc).
+			WithSrcEndpoints(intfs["port1"]).
+			WithDstEndpoints(dsts...).
+			WithHeaders(ondatra.NewEthernetHeader(), ondatra.NewIPv6Header().WithSrcAddress(atePort1.IPv6).WithDstAddress(r.dst)).
+			WithFrameRateFPS(fps))
+	}
+	ate.Traffic().Start(t, flows...)
+	time.Sleep(trafficTime)
+	ate.Traffic().Stop(t)
+
+	var losses []float32
+	for _, f := range flows {
+		losses = append(losses, ate.Telemetry().Flow(f.Name()).LossPct().Get(t))
+	}
+	return losses
+}
+
+// checkRoutes checks the AFT entries of the routes and their traffic.
+func checkRoutes(t *testing.T, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, intfs map[string]*ondatra.Interface, routes []route) {
+	var prefixes []string
+	for _, r := range routes {
+		if r.via != "" {
+			prefixes = append(prefixes, r.prefix)
+		}
+	}
+	entries := recursion.Await(t, dut, prefixes, aftTimeout)
+	afts := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).Afts()
+	for _, r := range routes {
+		if r.via == "" {
+			// The next hop of a drop route has no address to resolve.
+			if !afts.Ipv6Entry(r.prefix).Lookup(t).IsPresent() {
+				t.Errorf("%s: no AFT entry for %s", r.desc, r.prefix)
+			}
+			continue
+		}
+		e := entries[r.prefix]
+		switch {
+		case e == nil:
+			t.Errorf("%s: no AFT entry for %s", r.desc, r.prefix)
+		case e.Protocol != telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC:
+			t.Errorf("%s: %s installed by %v, want STATIC", r.desc, r.prefix, e.Protocol)
+		case len(e.NextHops) != 1 || e.NextHops[0] != r.via:
+			t.Errorf("%s: %s resolved to %v, want %s", r.desc, r.prefix, e.NextHops, r.via)
+		}
+	}
+
+	for i, loss := range sendTraffic(t, ate, intfs, routes) {
+		switch r := routes[i]; {
+		case r.port == "" && loss < 100:
+			t.Errorf("%s: traffic to %s lost %.2f%%, want 100", r.desc, r.dst, loss)
+		case r.port != "" && loss > 0:
+			t.Errorf("%s: traffic to %s lost %.2f%% on ate:%s, want 0", r.desc, r.dst, loss, r.port)
+		}
+	}
+}
+
+// TestIPv6Static verifies IPv6 static routes through a next hop, an
+// interface and a next hop, a recursive next hop, to a drop next hop, and
+// through next hops of different metrics, in the AFTs and for traffic.
+// The metrics are then swapped, and the recursive route is verified to be
+// removed with the route it resolves over.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/config/prefix
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/next-hop
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/metric
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/recurse
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/interface-ref/config/interface
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/interface-ref/config/subinterface
+// telemetry_path:/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/next-hop-group
+// telemetry_path:/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/origin-protocol
+// telemetry_path:/network-instances/network-instance/afts/next-hops/next-hop/state/ip-address
+func TestIPv6Static(t *testing.T) {
+	r := fptest.Requirements{PortIDs: []string{"port1", "port2", "port3"}}
+	dut := fptest.RequireDUT(t, "dut", r)
+	ate := fptest.RequireATE(t, "ate", r)
+	configureDUT(t, dut)
+	intfs := configureATE(t, ate)
+
+	t.Run("Routes", func(t *testing.T) {
+		checkRoutes(t, dut, ate, intfs, routes)
+	})
+
+	t.Run("MetricsSwapped", func(t *testing.T) {
+		swapped := route{
+			desc:   "metrics swapped",
+			prefix: "2001:db8:104::/64",
+			dst:    "2001:db8:104::1",
+			via:    atePort2.IPv6,
+			port:   "port2",
+		}
+		s := metricRoute(swapped.prefix, 10, 20)
+		staticPath(dut).Static(swapped.prefix).Replace(t, s)
+		if err := staticbfd.AwaitNextHops(t, dut, swapped.prefix, []string{swapped.via}, aftTimeout); err != nil {
+			t.Error(err)
+		}
+		checkRoutes(t, dut, ate, intfs, []route{swapped})
+	})
+
+	t.Run("RecursionBroken", func(t *testing.T) {
+		staticPath(dut).Static(routes[0].prefix).Delete(t)
+		if !recursion.AwaitRemoved(t, dut, routes[2].prefix, aftTimeout) {
+			t.Errorf("%s still installed after %s is deleted", routes[2].prefix, routes[0].prefix)
+		}
+		for i, loss := range sendTraffic(t, ate, intfs, routes[2:3]) {
+			if loss < 100 {
+				t.Errorf("%s: traffic to %s lost %.2f%%, want 100", routes[2+i].desc, routes[2+i].dst, loss)
+			}
+		}
+	})
+}
@@ -0,0 +1,312 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recursive_resolution_test implements RT-1.12: Static and BGP
+// Recursive Resolution.
+package recursive_resolution_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The testbed consists of ate:port1 -> dut:port1 and dut:port2 ->
+// ate:port2.  The DUT has iBGP sessions over IPv4 and IPv6 with
+// ate:port2, which advertises the transit prefixes with itself as the
+// next hop, and the BGP prefixes with next hops only reachable through
+// static routes of the DUT.
+//
+//   - ate:port1 -> dut:port1 subnets 192.0.2.0/30 and 2001:db8::/126
+//   - dut:port2 -> ate:port2 subnets 192.0.2.4/30 and 2001:db8::4/126
+//
+// The static prefixes resolve over the transit prefixes, and the BGP
+// prefixes over the next hop prefixes, which are static routes to
+// ate:port2.
+const (
+	plen4       = 30
+	plen6       = 126
+	as          = 64500
+	staticName  = "STATIC"
+	fps         = 1000
+	trafficTime = 10 * time.Second
+	aftTimeout  = 2 * time.Minute
+)
+
+var (
+	dutSrc = attrs.Attributes{Desc: "DUT to ATE source", IPv4: "192.0.2.1", IPv6: "2001:db8::1", IPv4Len: plen4, IPv6Len: plen6}
+	ateSrc = attrs.Attributes{Name: "port1", IPv4: "192.0.2.2", IPv6: "2001:db8::2", IPv4Len: plen4, IPv6Len: plen6}
+	dutDst = attrs.Attributes{Desc: "DUT to ATE destination", IPv4: "192.0.2.5", IPv6: "2001:db8::5", IPv4Len: plen4, IPv6Len: plen6}
+	ateDst = attrs.Attributes{Name: "port2", IPv4: "192.0.2.6", IPv6: "2001:db8::6", IPv4Len: plen4, IPv6Len: plen6}
+)
+
+// chain is a route of the DUT resolved over another, for one address
+// family.
+type chain struct {
+	desc string
+	hop  recursion.Hop
+	// dst is an address of the prefix of the hop, the destination of its
+	// traffic.
+	dst string
+	// final is the address of ate:port2 the hop resolves to.
+	final string
+	ipv6  bool
+}
+
+var (
+	staticOverBGP = []chain{{
+		desc:  "IPv4 static over BGP",
+		hop:   recursion.Hop{Prefix: "198.51.100.0/24", Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, NextHop: "203.0.113.1", Via: "203.0.113.0/24"},
+		dst:   "198.51.100.1",
+		final: ateDst.IPv4,
+	}, {
+		desc:  "IPv6 static over BGP",
+		hop:   recursion.Hop{Prefix: "2001:db8:200::/64", Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, NextHop: "2001:db8:100::1", Via: "2001:db8:100::/64"},
+		dst:   "2001:db8:200::1",
+		final: ateDst.IPv6,
+		ipv6:  true,
+	}}
+
+	bgpOverStatic = []chain{{
+		desc:  "IPv4 BGP over static",
+		hop:   recursion.Hop{Prefix: "198.18.0.0/24", Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, NextHop: "100.64.0.1", Via: "100.64.0.0/24"},
+		dst:   "198.18.0.1",
+		final: ateDst.IPv4,
+	}, {
+		desc:  "IPv6 BGP over static",
+		hop:   recursion.Hop{Prefix: "2001:db8:300::/64", Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, NextHop: "2001:db8:64::1", Via: "2001:db8:64::/64"},
+		dst:   "2001:db8:300::1",
+		final: ateDst.IPv6,
+		ipv6:  true,
+	}}
+)
+
+// configureDUT configures the ports, the static routes and the iBGP
+// sessions of the DUT.
+func configureDUT(t *testing.T, dut *ondatra.DUTDevice) {
+	d := dut.Config()
+	for _, p := range []struct {
+		port  string
+		attrs *attrs.Attributes
+	}{{"port1", &dutSrc}, {"port2", &dutDst}} {
+		i := p.attrs.NewInterface(dut.Port(t, p.port).Name())
+		d.Interface(i.GetName()).Replace(t, i)
+	}
+
+	ni := *deviations.DefaultNetworkInstance
+	static := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC,
+		Name:       ygot.String(staticName),
+	}
+	for _, c := range staticOverBGP {
+		if err := static.AppendStatic(recursion.Static(c.hop.Prefix, c.hop.NextHop)); err != nil {
+			t.Fatalf("Cannot add static route: %v", err)
+		}
+	}
+	for _, c := range bgpOverStatic {
+		if err := static.AppendStatic(recursion.Static(c.hop.Via, c.final)); err != nil {
+			t.Fatalf("Cannot add static route: %v", err)
+		}
+	}
+	staticPath := d.NetworkInstance(ni).Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName)
+	fptest.LogYgot(t, "DUT static routes", staticPath, static)
+	staticPath.Replace(t, static)
+
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	global := bgp.GetOrCreateGlobal()
+	global.As = ygot.Uint32(as)
+	global.RouterId = ygot.String(dutDst.IPv4)
+	for _, n := range []struct {
+		addr string
+		afi  telemetry.E_BgpTypes_AFI_SAFI_TYPE
+	}{
+		{ateDst.IPv4, telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST},
+		{ateDst.IPv6, telemetry.BgpTypes_AFI_SAFI_TYPE_IPV6_UNICAST},
+	} {
+		global.GetOrCreateAfiSafi(n.afi).Enabled = ygot.Bool(true)
+		nbr := bgp.GetOrCreateNeighbor(n.addr)
+		nbr.PeerAs = ygot.Uint32(as)
+		nbr.Enabled = ygot.Bool(true)
+		nbr.GetOrCreateAfiSafi(n.afi).Enabled = ygot.Bool(true)
+	}
+	bgpPath := d.NetworkInstance(ni).Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	fptest.LogYgot(t, "DUT BGP", bgpPath, bgp)
+	bgpPath.Replace(t, bgp)
+}
+
+// configureATE configures the ATE interfaces and the iBGP sessions of
+// ate:port2, and returns the source and destination interfaces, and the
+// transit networks.
+func configureATE(t *testing.T, ate *ondatra.ATEDevice) (*ondatra.ATETopology, *ondatra.Interface, *ondatra.Interface, []*ondatra.Network) {
+	top := ate.Topology().New()
+	src := ateSrc.AddToATE(top, ate.Port(t, "port1"), &dutSrc)
+	dst := ateDst.AddToATE(top, ate.Port(t, "port2"), &dutDst)
+	dst.BGP().AddPeer().WithPeerAddress(dutDst.IPv4).WithLocalASN(as).WithTypeInternal()
+	dst.BGP().AddPeer().WithPeerAddress(dutDst.IPv6).WithLocalASN(as).WithTypeInternal()
+
+	var transit []*ondatra.Network
+	for _, c := range staticOverBGP {
+		net := dst.AddNetwork(c.hop.Via)
+		if c.ipv6 {
+			net.IPv6().WithAddress(c.hop.Via).WithCount(1)
+		} else {
+			net.IPv4().WithAddress(c.hop.Via).WithCount(1)
+		}
+		net.BGP().WithNextHopAddress(c.final).WithOriginIGP()
+		transit = append(transit, net)
+	}
+	for _, c := range bgpOverStatic {
+		net := dst.AddNetwork(c.hop.Prefix)
+		if c.ipv6 {
+			net.IPv6().WithAddress(c.hop.Prefix).WithCount(1)
+		} else {
+			net.IPv4().WithAddress(c.hop.Prefix).WithCount(1)
+		}
+		net.BGP().WithNextHopAddress(c.hop.NextHop).WithOriginIGP()
+	}
+	top.Push(t).StartProtocols(t)
+	return top, src, dst, transit
+}
+
+// awaitSessions waits for the iBGP sessions to be established.
+func awaitSessions(t *testing.T, dut *ondatra.DUTDevice) {
+	state := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp()
+	for _, addr := range []string{ateDst.IPv4, ateDst.IPv6} {
+		_, ok := state.Neighbor(addr).SessionState().Watch(t, time.Minute, func(val *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+			return val.IsPresent() && val.Val(t) == telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+		}).Await(t)
+		if !ok {
+			t.Fatalf("BGP session with %s is not established", addr)
+		}
+	}
+}
+
+// sendTraffic sends a flow to the destination of each chain, and
+// returns the loss of each in percent.
+func sendTraffic(t *testing.T, ate *ondatra.ATEDevice, src, dst *ondatra.Interface, chains []chain) []float32 {
+	var flows []*ondatra.Flow
+	for _, c := range chains {
+		var ip ondatra.Header
+		if c.ipv6 {
+			ip = ondatra.NewIPv6Header().WithSrcAddress(ateSrc.IPv6).WithDstAddress(c.dst)
+		} else {
+			ip = ondatra.NewIPv4Header().WithSrcAddress(ateSrc.IPv4).WithDstAddress(c.dst)
+		}
+		flows = append(flows, ate.Traffic().NewFlow(c.desc).
+			WithSrcEndpoints(src).
+			WithDstEndpoints(dst).
+			WithHeaders(ondatra.NewEthernetHeader(), ip).
+			WithFrameRateFPS(fps))
+	}
+	ate.Traffic().Start(t, flows...)
+	time.Sleep(trafficTime)
+	ate.Traffic().Stop(t)
+
+	var losses []float32
+	for _, f := range flows {
+		losses = append(losses, ate.Telemetry().Flow(f.Name()).LossPct().Get(t))
+	}
+	return losses
+}
+
+// TestRecursiveResolution verifies that static routes resolve over BGP
+// routes, and BGP routes over static routes, in the AFTs and for traffic,
+// and that they are removed when the routes they resolve over are.
+//
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/next-hop
+// config_path:/network-instances/network-instance/protocols/protocol/static-routes/static/next-hops/next-hop/config/recurse
+// telemetry_path:/network-instances/network-instance/afts/ipv4-unicast/ipv4-entry/state/origin-protocol
+// telemetry_path:/network-instances/network-instance/afts/ipv6-unicast/ipv6-entry/state/origin-protocol
+// telemetry_path:/network-instances/network-instance/afts/next-hop-groups/next-hop-group/next-hops/next-hop/state/index
+// telemetry_path:/network-instances/network-instance/afts/next-hops/next-hop/state/ip-address
+func TestRecursiveResolution(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	ate := ondatra.ATE(t, "ate")
+	configureDUT(t, dut)
+	top, src, dst, transit := configureATE(t, ate)
+	awaitSessions(t, dut)
+
+	chains := append(append([]chain{}, staticOverBGP...), bgpOverStatic...)
+	var prefixes []string
+	for _, c := range chains {
+		prefixes = append(prefixes, c.hop.Prefix, c.hop.Via)
+	}
+	entries := recursion.Await(t, dut, prefixes, aftTimeout)
+
+	t.Run("AFT", func(t *testing.T) {
+		for _, c := range chains {
+			t.Logf("%s: %s via %s resolved to %v", c.desc, c.hop.Prefix, c.hop.Via, entries[c.hop.Prefix])
+			for _, err := range c.hop.Check(entries, c.final) {
+				t.Errorf("%s: %v", c.desc, err)
+			}
+		}
+	})
+
+	t.Run("Traffic", func(t *testing.T) {
+		for i, loss := range sendTraffic(t, ate, src, dst, chains) {
+			if loss > 0 {
+				t.Errorf("%s: traffic to %s lost %.2f%%, want 0", chains[i].desc, chains[i].dst, loss)
+			}
+		}
+	})
+
+	// Breaking the chains, from either end, removes the recursive routes.
+	t.Run("BGPWithdrawn", func(t *testing.T) {
+		for _, net := range transit {
+			net.BGP().WithActive(false)
+		}
+		top.UpdateNetworks(t)
+		for _, c := range staticOverBGP {
+			if !recursion.AwaitRemoved(t, dut, c.hop.Prefix, aftTimeout) {
+				t.Errorf("%s: %s still installed after %s is withdrawn", c.desc, c.hop.Prefix, c.hop.Via)
+			}
+		}
+		for i, loss := range sendTraffic(t, ate, src, dst, staticOverBGP) {
+			if loss < 100 {
+				t.Errorf("%s: traffic to %s lost %.2f%%, want 100", staticOverBGP[i].desc, staticOverBGP[i].dst, loss)
+			}
+		}
+	})
+
+	t.Run("StaticDeleted", func(t *testing.T) {
+		staticPath := dut.Config().NetworkInstance(*deviations.DefaultNetworkInstance).
+			Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, staticName)
+		for _, c := range bgpOverStatic {
+			staticPath.Static(c.hop.Via).Delete(t)
+		}
+		for _, c := range bgpOverStatic {
+			if !recursion.AwaitRemoved(t, dut, c.hop.Prefix, aftTimeout) {
+				t.Errorf("%s: %s still installed after %s is deleted", c.desc, c.hop.Prefix, c.hop.Via)
+			}
+		}
+		for i, loss := range sendTraffic(t, ate, src, dst, bgpOverStatic) {
+			if loss < 100 {
+				t.Errorf("%s: traffic to %s lost %.2f%%, want 100", bgpOverStatic[i].desc, bgpOverStatic[i].dst, loss)
+			}
+		}
+	})
+}
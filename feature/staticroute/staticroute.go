@@ -51,6 +51,47 @@ func (sr *Static) WithRoute(prefix string, nextHops []string) *Static {
 	return sr
 }
 
+// WithInterfaceRoute sets the prefix with a next-hop out of the
+// subinterface of an interface, through the next-hop address if not
+// empty, e.g. a link-local IPv6 address of the neighbor.
+func (sr *Static) WithInterfaceRoute(prefix, intf string, subintf uint32, nextHop string) *Static {
+	static := sr.oc.GetOrCreateStatic(prefix)
+	n := static.GetOrCreateNextHop(strconv.Itoa(len(static.NextHop) + 1))
+	if nextHop != "" {
+		n.NextHop = fpoc.UnionString(nextHop)
+	}
+	ref := n.GetOrCreateInterfaceRef()
+	ref.Interface = ygot.String(intf)
+	ref.Subinterface = ygot.Uint32(subintf)
+	return sr
+}
+
+// WithDropRoute sets the prefix with a next-hop dropping its traffic, a
+// null route.
+func (sr *Static) WithDropRoute(prefix string) *Static {
+	static := sr.oc.GetOrCreateStatic(prefix)
+	n := static.GetOrCreateNextHop(strconv.Itoa(len(static.NextHop) + 1))
+	n.NextHop = fpoc.LocalRouting_LOCAL_DEFINED_NEXT_HOP_DROP
+	return sr
+}
+
+// WithMetric sets the metric of the next-hops set for the prefix so far.
+func (sr *Static) WithMetric(prefix string, metric uint32) *Static {
+	for _, n := range sr.oc.GetOrCreateStatic(prefix).NextHop {
+		n.Metric = ygot.Uint32(metric)
+	}
+	return sr
+}
+
+// WithRecurse sets the next-hops set for the prefix so far to be resolved
+// recursively, through the routes to their addresses.
+func (sr *Static) WithRecurse(prefix string) *Static {
+	for _, n := range sr.oc.GetOrCreateStatic(prefix).NextHop {
+		n.Recurse = ygot.Bool(true)
+	}
+	return sr
+}
+
 // AugmentNetworkInstance implements networkinstance.Feature interface.
 // Augments the provided NI with Static OC.
 func (sr *Static) AugmentNetworkInstance(ni *fpoc.NetworkInstance) error {
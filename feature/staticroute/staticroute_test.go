@@ -166,6 +166,16 @@ func TestAugment_Errors(t *testing.T) {
 		static:        New().WithRoute("192.0.2.1", nil),
 		inNI:          &fpoc.NetworkInstance{},
 		wantErrSubStr: "does not match regular expression pattern",
+	}, {
+		desc:          "IPv6 prefix not in CIDR format",
+		static:        New().WithRoute("2001:db8::1", nil),
+		inNI:          &fpoc.NetworkInstance{},
+		wantErrSubStr: "does not match regular expression pattern",
+	}, {
+		desc:          "IPv6 next-hop not an address",
+		static:        New().WithRoute("2001:db8:1::/64", []string{"2001:db8::g"}),
+		inNI:          &fpoc.NetworkInstance{},
+		wantErrSubStr: "does not match regular expression pattern",
 	}, {
 		desc:   "Same prefix with different next-hop",
 		static: New().WithRoute("192.0.2.1/32", []string{"203.0.113.14"}),
@@ -202,3 +212,102 @@ func TestAugment_Errors(t *testing.T) {
 		})
 	}
 }
+
+// TestNextHops tests the next-hops of the static routes of each address
+// family.
+func TestNextHops(t *testing.T) {
+	tests := []struct {
+		desc   string
+		static *Static
+		prefix string
+		want   map[string]*fpoc.NetworkInstance_Protocol_Static_NextHop
+	}{{
+		desc:   "IPv6 next-hops",
+		static: New().WithRoute("2001:db8:1::/64", []string{"2001:db8::6", "2001:db8::a"}),
+		prefix: "2001:db8:1::/64",
+		want: map[string]*fpoc.NetworkInstance_Protocol_Static_NextHop{
+			"1": {Index: ygot.String("1"), NextHop: fpoc.UnionString("2001:db8::6")},
+			"2": {Index: ygot.String("2"), NextHop: fpoc.UnionString("2001:db8::a")},
+		},
+	}, {
+		desc:   "IPv4 interface next-hop",
+		static: New().WithInterfaceRoute("198.51.100.0/24", "eth1", 0, "192.0.2.6"),
+		prefix: "198.51.100.0/24",
+		want: map[string]*fpoc.NetworkInstance_Protocol_Static_NextHop{
+			"1": {
+				Index:        ygot.String("1"),
+				NextHop:      fpoc.UnionString("192.0.2.6"),
+				InterfaceRef: &fpoc.NetworkInstance_Protocol_Static_NextHop_InterfaceRef{Interface: ygot.String("eth1"), Subinterface: ygot.Uint32(0)},
+			},
+		},
+	}, {
+		desc:   "IPv6 interface next-hop without address",
+		static: New().WithInterfaceRoute("2001:db8:1::/64", "eth1", 0, ""),
+		prefix: "2001:db8:1::/64",
+		want: map[string]*fpoc.NetworkInstance_Protocol_Static_NextHop{
+			"1": {
+				Index:        ygot.String("1"),
+				InterfaceRef: &fpoc.NetworkInstance_Protocol_Static_NextHop_InterfaceRef{Interface: ygot.String("eth1"), Subinterface: ygot.Uint32(0)},
+			},
+		},
+	}, {
+		desc:   "IPv6 interface next-hop with link-local address",
+		static: New().WithInterfaceRoute("2001:db8:1::/64", "eth1", 1, "fe80::1"),
+		prefix: "2001:db8:1::/64",
+		want: map[string]*fpoc.NetworkInstance_Protocol_Static_NextHop{
+			"1": {
+				Index:        ygot.String("1"),
+				NextHop:      fpoc.UnionString("fe80::1"),
+				InterfaceRef: &fpoc.NetworkInstance_Protocol_Static_NextHop_InterfaceRef{Interface: ygot.String("eth1"), Subinterface: ygot.Uint32(1)},
+			},
+		},
+	}, {
+		desc:   "IPv4 drop",
+		static: New().WithDropRoute("198.51.100.0/24"),
+		prefix: "198.51.100.0/24",
+		want: map[string]*fpoc.NetworkInstance_Protocol_Static_NextHop{
+			"1": {Index: ygot.String("1"), NextHop: fpoc.LocalRouting_LOCAL_DEFINED_NEXT_HOP_DROP},
+		},
+	}, {
+		desc:   "IPv6 drop",
+		static: New().WithDropRoute("2001:db8:1::/64"),
+		prefix: "2001:db8:1::/64",
+		want: map[string]*fpoc.NetworkInstance_Protocol_Static_NextHop{
+			"1": {Index: ygot.String("1"), NextHop: fpoc.LocalRouting_LOCAL_DEFINED_NEXT_HOP_DROP},
+		},
+	}, {
+		desc:   "IPv6 recursive next-hop",
+		static: New().WithRoute("2001:db8:2::/64", []string{"2001:db8:1::1"}).WithRecurse("2001:db8:2::/64"),
+		prefix: "2001:db8:2::/64",
+		want: map[string]*fpoc.NetworkInstance_Protocol_Static_NextHop{
+			"1": {Index: ygot.String("1"), NextHop: fpoc.UnionString("2001:db8:1::1"), Recurse: ygot.Bool(true)},
+		},
+	}, {
+		desc: "IPv6 metrics",
+		static: New().
+			WithRoute("2001:db8:1::/64", []string{"2001:db8::6"}).
+			WithMetric("2001:db8:1::/64", 10).
+			WithInterfaceRoute("2001:db8:1::/64", "eth2", 0, "2001:db8::a"),
+		prefix: "2001:db8:1::/64",
+		want: map[string]*fpoc.NetworkInstance_Protocol_Static_NextHop{
+			"1": {Index: ygot.String("1"), NextHop: fpoc.UnionString("2001:db8::6"), Metric: ygot.Uint32(10)},
+			"2": {
+				Index:        ygot.String("2"),
+				NextHop:      fpoc.UnionString("2001:db8::a"),
+				InterfaceRef: &fpoc.NetworkInstance_Protocol_Static_NextHop_InterfaceRef{Interface: ygot.String("eth2"), Subinterface: ygot.Uint32(0)},
+			},
+		},
+	}}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			ni := &fpoc.NetworkInstance{}
+			if err := test.static.AugmentNetworkInstance(ni); err != nil {
+				t.Fatalf("error not expected: %v", err)
+			}
+			got := ni.GetProtocol(fpoc.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, Name).GetStatic(test.prefix).NextHop
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("did not get expected next-hops, diff(-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package baseline_test implements gNMI-1.22: Baseline Configuration.
+package baseline_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/baseline"
+	"github.com/openconfig/featureprofiles/internal/configdiff"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+)
+
+// leftover is the hostname a previous suite left behind.
+const leftover = "baseline-leftover"
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// TestBaseline changes the hostname as a previous suite would, applies the
+// baseline of the vendor of the DUT, and verifies that its configuration
+// is the baseline, and that applying it again changes nothing.
+//
+// config_path:/system/config/hostname
+// config_path:/system/grpc-servers/grpc-server/config/enable
+// config_path:/system/grpc-servers/grpc-server/config/port
+// config_path:/system/grpc-servers/grpc-server/config/services
+// config_path:/network-instances/network-instance/config/type
+// telemetry_path:/system/state/hostname
+func TestBaseline(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	p := baseline.For(dut)
+	t.Logf("Baseline of %v: %+v", dut.Vendor(), p)
+	dut.Config().System().Hostname().Replace(t, leftover)
+
+	t.Run("Apply", func(t *testing.T) {
+		baseline.Apply(t, dut, p)
+		for _, err := range p.Check(configdiff.Take(t, dut)) {
+			t.Error(err)
+		}
+		dut.Telemetry().System().Hostname().Await(t, time.Minute, p.Hostname)
+	})
+
+	t.Run("Reapply", func(t *testing.T) {
+		before := configdiff.Take(t, dut)
+		baseline.Apply(t, dut, p)
+		configdiff.CheckUnchanged(t, dut, before)
+	})
+}
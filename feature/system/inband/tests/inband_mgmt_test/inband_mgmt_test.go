@@ -0,0 +1,110 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inband_mgmt_test
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/mgmtdial"
+	"github.com/openconfig/ondatra"
+)
+
+var (
+	inbandAddress = flag.String("inband_test_address", "", "IPv4 address configured on dut:port1 for in-band management.  It must be the inband_address of the DUT in the binding, and be routed from the test host to dut:port1.  TestInbandManagement is skipped if empty.")
+	inbandPlen    = flag.Uint("inband_test_plen", 24, "prefix length of -inband_test_address")
+	rpcTimeout    = flag.Duration("inband_rpc_timeout", 30*time.Second, "time given to each RPC to the DUT")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// session is a check of a service over a new session.
+type session struct {
+	desc  string
+	check func(ctx context.Context, t *testing.T, dut *ondatra.DUTDevice) error
+}
+
+var sessions = []session{{
+	desc: "gNMI",
+	check: func(ctx context.Context, t *testing.T, dut *ondatra.DUTDevice) error {
+		name, err := mgmtdial.GNMIHostname(ctx, t, dut)
+		if err == nil {
+			t.Logf("gNMI got hostname %q", name)
+		}
+		return err
+	},
+}, {
+	desc: "gRIBI",
+	check: func(ctx context.Context, t *testing.T, dut *ondatra.DUTDevice) error {
+		n, err := mgmtdial.GRIBIEntries(ctx, t, dut, *deviations.DefaultNetworkInstance)
+		if err == nil {
+			t.Logf("gRIBI got %d entries", n)
+		}
+		return err
+	},
+}}
+
+// checkSessions dials new sessions to the DUT and checks the services
+// answer over them.
+func checkSessions(t *testing.T, dut *ondatra.DUTDevice) {
+	for _, s := range sessions {
+		t.Run(s.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+			defer cancel()
+			if err := s.check(ctx, t, dut); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestInbandManagement verifies that the gNMI and gRIBI services of the
+// DUT remain reachable when the sessions of the test move from the
+// management port to an address of a front-panel port, and back.
+//
+// config_path:/interfaces/interface/subinterfaces/subinterface/ipv4/addresses/address/config/ip
+// telemetry_path:/system/state/hostname
+func TestInbandManagement(t *testing.T) {
+	if *inbandAddress == "" {
+		t.Skip("No -inband_test_address to move the sessions to")
+	}
+	dut := ondatra.DUT(t, "dut")
+	p1 := dut.Port(t, "port1")
+	dutInband := attrs.Attributes{
+		Desc:    "In-band management",
+		IPv4:    *inbandAddress,
+		IPv4Len: uint8(*inbandPlen),
+	}
+	dut.Config().Interface(p1.Name()).Replace(t, dutInband.NewInterface(p1.Name()))
+
+	t.Run("Management", func(t *testing.T) {
+		checkSessions(t, dut)
+	})
+	t.Run("Inband", func(t *testing.T) {
+		mgmtdial.UseInband(t, true)
+		checkSessions(t, dut)
+	})
+	t.Run("Management again", func(t *testing.T) {
+		mgmtdial.UseInband(t, false)
+		checkSessions(t, dut)
+	})
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system_logging_test
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/syslog"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/netutil"
+	"github.com/openconfig/ondatra/telemetry"
+	"github.com/openconfig/ygot/ygot"
+)
+
+var (
+	collectorHost = flag.String("syslog_collector_host", "", "Address of the test host as reached by the DUT, where the syslog collector listens.  The test is skipped if empty.")
+	collectorPort = flag.Int("syslog_collector_port", syslog.TLSPort, "Port on which the syslog collector listens with TLS.")
+	eventTimeout  = flag.Duration("syslog_timeout", time.Minute, "Time given to the DUT to send the message of an event.")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// The selector of the remote server, which the messages must match.
+const (
+	facility = telemetry.SystemLogging_SYSLOG_FACILITY_ALL
+	severity = telemetry.SystemLogging_SyslogSeverity_INFORMATIONAL
+)
+
+// startCollector starts a collector with a self-signed certificate for
+// the collector host, which the DUT must trust.
+func startCollector(t *testing.T) *syslog.Collector {
+	if *collectorHost == "" {
+		t.Skip("No -syslog_collector_host for the DUT to send its messages to")
+	}
+	config, certPEM, err := syslog.SelfSigned(*collectorHost)
+	if err != nil {
+		t.Fatalf("Cannot create the certificate of the collector: %v", err)
+	}
+	t.Logf("Certificate of the collector, to be trusted by the DUT:\n%s", certPEM)
+	c, err := syslog.NewCollector(fmt.Sprintf(":%d", *collectorPort), config)
+	if err != nil {
+		t.Fatalf("Cannot start the collector: %v", err)
+	}
+	return c
+}
+
+// TestRemoteServerConfigurability verifies that the remote server paths
+// can be read, updated, and deleted.
+func TestRemoteServerConfigurability(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	const host = "192.0.2.1"
+	config := dut.Config().System().Logging().RemoteServer(host)
+	state := dut.Telemetry().System().Logging().RemoteServer(host)
+
+	config.Replace(t, syslog.RemoteServer(host, syslog.TLSPort, facility, severity))
+	defer config.Delete(t)
+
+	rs := state.Get(t)
+	if got := rs.GetRemotePort(); got != syslog.TLSPort {
+		t.Errorf("Telemetry remote port got %d, want %d", got, syslog.TLSPort)
+	}
+	if got := rs.GetSelector(facility, severity); got == nil {
+		t.Errorf("Telemetry has no selector for %v at %v: %v", facility, severity, rs.Selector)
+	}
+}
+
+// TestSyslogTLS verifies that the DUT sends the messages of the
+// triggered events over TLS, with the expected facility and severity.
+func TestSyslogTLS(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	c := startCollector(t)
+	defer c.Close()
+
+	config := dut.Config().System().Logging().RemoteServer(*collectorHost)
+	config.Replace(t, syslog.RemoteServer(*collectorHost, uint16(*collectorPort), facility, severity))
+	defer config.Delete(t)
+
+	// The DUT has no ports in its testbed, so the interface going down
+	// is a loopback created for the test.
+	lo := netutil.LoopbackInterface(t, dut, 1)
+	for _, tc := range []struct {
+		desc string
+		// trigger causes the event, and returns a substring of its
+		// message.
+		trigger func(t *testing.T) string
+		// facilities are the facilities the message may have, or any if
+		// empty.
+		facilities []telemetry.E_SystemLogging_SYSLOG_FACILITY
+		severity   telemetry.E_SystemLogging_SyslogSeverity
+	}{{
+		desc: "interface down",
+		trigger: func(t *testing.T) string {
+			intf := dut.Config().Interface(lo)
+			intf.Replace(t, &telemetry.Interface{
+				Name:    ygot.String(lo),
+				Type:    telemetry.IETFInterfaces_InterfaceType_softwareLoopback,
+				Enabled: ygot.Bool(true),
+			})
+			t.Cleanup(func() {
+				intf.Delete(t)
+			})
+			dut.Telemetry().Interface(lo).OperStatus().Await(t, *eventTimeout, telemetry.Interface_OperStatus_UP)
+			intf.Enabled().Replace(t, false)
+			return lo
+		},
+		severity: telemetry.SystemLogging_SyslogSeverity_NOTICE,
+	}, {
+		desc: "login",
+		trigger: func(t *testing.T) string {
+			dut.RawAPIs().CLI(t).Close()
+			return ""
+		},
+		facilities: []telemetry.E_SystemLogging_SYSLOG_FACILITY{
+			telemetry.SystemLogging_SYSLOG_FACILITY_AUTH,
+			telemetry.SystemLogging_SYSLOG_FACILITY_AUTHPRIV,
+		},
+		severity: severity,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			skip := len(c.Messages())
+			text := tc.trigger(t)
+			m, err := c.Await(skip, *eventTimeout, func(m *syslog.Message) bool {
+				if !strings.Contains(m.Text, text) {
+					return false
+				}
+				if len(tc.facilities) == 0 {
+					return true
+				}
+				for _, f := range tc.facilities {
+					if m.Facility == f {
+						return true
+					}
+				}
+				return false
+			})
+			if err != nil {
+				t.Fatalf("No message of the %s with facility in %v: %v", tc.desc, tc.facilities, err)
+			}
+			t.Logf("Message of the %s: %+v", tc.desc, m)
+			if !m.AtLeast(tc.severity) {
+				t.Errorf("Message of the %s severity got %v, want %v or more severe", tc.desc, m.Severity, tc.severity)
+			}
+		})
+	}
+
+	for _, m := range c.Messages() {
+		if !m.AtLeast(severity) {
+			t.Errorf("Message %+v severity got %v, want %v or more severe as selected", m, m.Severity, severity)
+		}
+	}
+	for _, err := range c.Errors() {
+		t.Errorf("Collector: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package system_ntp_test
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/ntpsync"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/telemetry"
+	"github.com/openconfig/ygot/ygot"
+)
+
+var (
+	serverHost  = flag.String("ntp_server_host", "", "Address of the test host as reached by the DUT, where the NTP server listens.  TestNtpSync is skipped if empty.")
+	serverPort  = flag.Int("ntp_server_port", ntpsync.Port, "Port on which the NTP server listens.")
+	vrfSource   = flag.String("ntp_vrf_source_address", "", "Address of the DUT in a VRF from which it reaches the NTP server.  The VRF case is skipped if empty.")
+	syncTimeout = flag.Duration("ntp_sync_timeout", 5*time.Minute, "Time given to the DUT to synchronize to the NTP server.")
+	offsetBound = flag.Duration("ntp_offset_bound", 100*time.Millisecond, "Largest offset of the DUT to the NTP server once synchronized.")
+)
+
+// serverStratum is the stratum of the NTP server, by which the DUT
+// reports its association with the server.
+const serverStratum = 3
+
+// TestNtpSync verifies that the DUT synchronizes its clock to an NTP
+// server embedded in the test, reached by default or from a VRF, and
+// reports its association with the server.
+//
+// OpenConfig does not model the network instance of an NTP server, so
+// the VRF is selected by a source address of the DUT in it.
+//
+// config_path:/system/ntp/servers/server/config/association-type
+// config_path:/system/ntp/servers/server/config/iburst
+// config_path:/system/ntp/servers/server/config/prefer
+// config_path:/system/ntp/config/ntp-source-address
+// telemetry_path:/system/ntp/servers/server/state/stratum
+// telemetry_path:/system/ntp/servers/server/state/offset
+// telemetry_path:/system/state/current-datetime
+func TestNtpSync(t *testing.T) {
+	if *serverHost == "" {
+		t.Skip("No -ntp_server_host for the DUT to synchronize to")
+	}
+	s, err := ntpsync.NewServer(fmt.Sprintf(":%d", *serverPort), serverStratum)
+	if err != nil {
+		t.Fatalf("Cannot start the NTP server: %v", err)
+	}
+	defer s.Close()
+
+	dut := ondatra.DUT(t, "dut")
+	config := dut.Config().System().Ntp()
+	state := dut.Telemetry().System().Ntp()
+
+	for _, tc := range []struct {
+		desc   string
+		source string
+	}{
+		{"Default", ""},
+		{"VRF", *vrfSource},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if tc.desc == "VRF" && tc.source == "" {
+				t.Skip("No -ntp_vrf_source_address in a VRF")
+			}
+			ntp := &telemetry.System_Ntp{Enabled: ygot.Bool(true)}
+			if tc.source != "" {
+				ntp.NtpSourceAddress = ygot.String(tc.source)
+			}
+			if err := ntp.AppendServer(ntpsync.ServerConfig(*serverHost, uint16(*serverPort))); err != nil {
+				t.Fatalf("Cannot add the NTP server: %v", err)
+			}
+			config.Replace(t, ntp)
+			defer config.Delete(t)
+
+			_, ok := state.Server(*serverHost).Stratum().Watch(t, *syncTimeout, func(v *telemetry.QualifiedUint8) bool {
+				return v.IsPresent() && v.Val(t) == serverStratum
+			}).Await(t)
+			if !ok {
+				t.Fatalf("DUT did not report an association with the NTP server at stratum %d after %v; requests received: %v", serverStratum, *syncTimeout, s.Requests())
+			}
+			t.Logf("NTP requests received from each client: %v", s.Requests())
+
+			if err := ntpsync.CheckServer(state.Server(*serverHost).Get(t), serverStratum, *offsetBound); err != nil {
+				t.Error(err)
+			}
+			datetime := dut.Telemetry().System().CurrentDatetime().Get(t)
+			if err := ntpsync.CheckClock(datetime, time.Now(), *offsetBound); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
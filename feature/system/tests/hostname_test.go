@@ -20,6 +20,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/openconfig/featureprofiles/internal/baseline"
 	"github.com/openconfig/ondatra"
 )
 
@@ -41,6 +42,8 @@ func TestHostname(t *testing.T) {
 	}
 
 	dut := ondatra.DUT(t, "dut")
+	// The test cases delete the hostname, which the suites run next expect.
+	defer baseline.Apply(t, dut, baseline.For(dut))
 
 	for _, testCase := range testCases {
 		t.Run(testCase.description, func(t *testing.T) {
@@ -0,0 +1,100 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dual_stack_test
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/mgmtdial"
+	"github.com/openconfig/ondatra"
+)
+
+var (
+	transports = flag.String("dual_stack_transports", "tcp4,tcp6", "comma separated transports the services are checked over, the first being the reference the others are compared to.  The DUT name of the binding must resolve to an address of each.")
+	rpcTimeout = flag.Duration("dual_stack_rpc_timeout", 30*time.Second, "time given to each RPC to the DUT")
+	clockSkew  = flag.Duration("dual_stack_clock_skew", time.Minute, "maximum difference between the time of the DUT and the one of the test host")
+)
+
+func TestMain(m *testing.M) {
+	fptest.RunTests(m)
+}
+
+// answers are the answers of the services of the DUT over a transport.
+type answers struct {
+	Hostname     string
+	Models       []string
+	GRIBIEntries int
+}
+
+// collect dials new sessions over the transport and collects the
+// answers of the services.
+func collect(t *testing.T, dut *ondatra.DUTDevice, network string) *answers {
+	mgmtdial.UseTransport(t, network)
+	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	defer cancel()
+	a := &answers{}
+	var err error
+
+	if a.Hostname, err = mgmtdial.GNMIHostname(ctx, t, dut); err != nil {
+		t.Errorf("Over %s: %v", network, err)
+	}
+	if a.Models, err = mgmtdial.GNMIModels(ctx, t, dut); err != nil {
+		t.Errorf("Over %s: %v", network, err)
+	}
+	if now, err := mgmtdial.GNOITime(ctx, t, dut); err != nil {
+		t.Errorf("Over %s: %v", network, err)
+	} else if skew := time.Since(now); skew > *clockSkew || skew < -*clockSkew {
+		t.Errorf("Over %s: gNOI time %v is %v off the test host, want within %v", network, now, skew, *clockSkew)
+	}
+	if a.GRIBIEntries, err = mgmtdial.GRIBIEntries(ctx, t, dut, *deviations.DefaultNetworkInstance); err != nil {
+		t.Errorf("Over %s: %v", network, err)
+	}
+	t.Logf("Over %s: hostname %q, %d models, %d gRIBI entries", network, a.Hostname, len(a.Models), a.GRIBIEntries)
+	return a
+}
+
+// TestDualStackTransport verifies that the gNMI, gNOI and gRIBI services
+// of the DUT answer over IPv4 and IPv6 transports, and that their
+// answers are the same over both.
+//
+// telemetry_path:/system/state/hostname
+func TestDualStackTransport(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	networks := strings.Split(*transports, ",")
+
+	var ref *answers
+	for i, network := range networks {
+		t.Run(network, func(t *testing.T) {
+			got := collect(t, dut, network)
+			if i == 0 {
+				ref = got
+				return
+			}
+			if ref == nil {
+				t.Fatalf("No answers over %s to compare to", networks[0])
+			}
+			if diff := cmp.Diff(ref, got); diff != "" {
+				t.Errorf("Answers over %s differ from %s (-%s +%s):\n%s", network, networks[0], networks[0], network, diff)
+			}
+		})
+	}
+}
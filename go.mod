@@ -12,6 +12,7 @@ require (
 	github.com/openconfig/goyang v1.0.0
 	github.com/openconfig/gribi v0.1.1-0.20220520020624-63905fc23f56
 	github.com/openconfig/gribigo v0.0.0-20220525162038-a471e9b7c03e
+	github.com/openconfig/grpctunnel v0.0.0-20210610163803-fde4a9dc048d
 	github.com/openconfig/ondatra v0.0.0-20220808182300-5528f1927e53
 	github.com/openconfig/testt v0.0.0-20220311054427-efbb1a32ec07
 	github.com/openconfig/ygot v0.23.1
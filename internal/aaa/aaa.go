@@ -0,0 +1,206 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aaa provides TACACS+ and RADIUS servers which a test embeds for
+// a device to authenticate, authorize and account its users against, and
+// helpers to configure AAA on the device.
+//
+// The servers record each request of the device as an Event, so that a
+// test can verify the AAA flows of the users logging in over SSH or
+// gNMI, and of the commands they run.
+package aaa
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Default ports of the servers.
+const (
+	TACACSPort     = 49
+	RADIUSAuthPort = 1812
+	RADIUSAcctPort = 1813
+)
+
+// TACACSServer returns a TACACS+ server of a server group of the device.
+func TACACSServer(address string, port uint16, secret string) *telemetry.System_Aaa_ServerGroup_Server {
+	s := &telemetry.System_Aaa_ServerGroup_Server{Address: ygot.String(address)}
+	tacacs := s.GetOrCreateTacacs()
+	tacacs.Port = ygot.Uint16(port)
+	tacacs.SecretKey = ygot.String(secret)
+	return s
+}
+
+// RADIUSServer returns a RADIUS server of a server group of the device.
+func RADIUSServer(address string, authPort, acctPort uint16, secret string) *telemetry.System_Aaa_ServerGroup_Server {
+	s := &telemetry.System_Aaa_ServerGroup_Server{Address: ygot.String(address)}
+	radius := s.GetOrCreateRadius()
+	radius.AuthPort = ygot.Uint16(authPort)
+	radius.AcctPort = ygot.Uint16(acctPort)
+	radius.SecretKey = ygot.String(secret)
+	return s
+}
+
+// Config returns the AAA configuration of a device using the server
+// group of the servers, falling back to the local users, to
+// authenticate the users, and to account their logins and commands.
+// TACACS+ also authorizes their commands.
+func Config(group string, typ telemetry.E_AaaTypes_AAA_SERVER_TYPE, servers ...*telemetry.System_Aaa_ServerGroup_Server) *telemetry.System_Aaa {
+	a := &telemetry.System_Aaa{}
+	sg := a.GetOrCreateServerGroup(group)
+	sg.Type = typ
+	sg.Server = map[string]*telemetry.System_Aaa_ServerGroup_Server{}
+	for _, s := range servers {
+		sg.Server[s.GetAddress()] = s
+	}
+	a.GetOrCreateAuthentication().AuthenticationMethod = []telemetry.System_Aaa_Authentication_AuthenticationMethod_Union{
+		telemetry.UnionString(group),
+		telemetry.AaaTypes_AAA_METHOD_TYPE_LOCAL,
+	}
+	acct := a.GetOrCreateAccounting()
+	acct.AccountingMethod = []telemetry.System_Aaa_Accounting_AccountingMethod_Union{telemetry.UnionString(group)}
+	for _, e := range []telemetry.E_AaaTypes_AAA_ACCOUNTING_EVENT_TYPE{
+		telemetry.AaaTypes_AAA_ACCOUNTING_EVENT_TYPE_AAA_ACCOUNTING_EVENT_LOGIN,
+		telemetry.AaaTypes_AAA_ACCOUNTING_EVENT_TYPE_AAA_ACCOUNTING_EVENT_COMMAND,
+	} {
+		acct.GetOrCreateEvent(e).Record = telemetry.Event_Record_START_STOP
+	}
+	if typ == telemetry.AaaTypes_AAA_SERVER_TYPE_TACACS {
+		authz := a.GetOrCreateAuthorization()
+		authz.AuthorizationMethod = []telemetry.System_Aaa_Authorization_AuthorizationMethod_Union{
+			telemetry.UnionString(group),
+			telemetry.AaaTypes_AAA_METHOD_TYPE_LOCAL,
+		}
+		authz.GetOrCreateEvent(telemetry.AaaTypes_AAA_AUTHORIZATION_EVENT_TYPE_AAA_AUTHORIZATION_EVENT_COMMAND)
+	}
+	return a
+}
+
+// User is a user known to a server.
+type User struct {
+	Password string
+	// Denied are the prefixes of the commands the user is not authorized
+	// to run.
+	Denied []string
+}
+
+// authorized returns whether the user is authorized to run the command.
+func (u *User) authorized(command string) bool {
+	for _, d := range u.Denied {
+		if strings.HasPrefix(command, d) {
+			return false
+		}
+	}
+	return true
+}
+
+// Kind is the kind of an AAA request.
+type Kind int
+
+// Kinds of AAA requests.
+const (
+	Authentication Kind = iota
+	Authorization
+	Accounting
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Authentication:
+		return "authentication"
+	case Authorization:
+		return "authorization"
+	case Accounting:
+		return "accounting"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Event is an AAA request of the device, and the answer of the server.
+type Event struct {
+	Kind Kind
+	User string
+	// Service is the service the user requested, such as "shell" or
+	// "login".
+	Service string
+	// Command is the command the user ran, for the authorization and the
+	// accounting of commands.
+	Command string
+	// Record is "start", "stop" or "update" for accounting.
+	Record string
+	// Remote is the remote address of the user, as reported by the
+	// device.
+	Remote string
+	// Pass is whether the server accepted the request.
+	Pass bool
+}
+
+func (e *Event) String() string {
+	return fmt.Sprintf("%v user=%q service=%q command=%q record=%q remote=%q pass=%v", e.Kind, e.User, e.Service, e.Command, e.Record, e.Remote, e.Pass)
+}
+
+// eventLog records the events of a server.
+type eventLog struct {
+	mu     sync.Mutex
+	events []*Event
+	errs   []error
+}
+
+func (l *eventLog) add(e *Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+}
+
+func (l *eventLog) addError(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errs = append(l.errs, err)
+}
+
+// Events returns the events recorded so far.
+func (l *eventLog) Events() []*Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]*Event(nil), l.events...)
+}
+
+// Errors returns the errors decoding the requests so far.
+func (l *eventLog) Errors() []error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]error(nil), l.errs...)
+}
+
+// Await waits for an event matching the predicate, recorded after the
+// first skip events.
+func (l *eventLog) Await(skip int, timeout time.Duration, match func(*Event) bool) (*Event, error) {
+	for start := time.Now(); ; time.Sleep(100 * time.Millisecond) {
+		events := l.Events()
+		for i := skip; i < len(events); i++ {
+			if match(events[i]) {
+				return events[i], nil
+			}
+		}
+		if time.Since(start) > timeout {
+			return nil, fmt.Errorf("no matching event among the %d recorded after %v", len(events)-skip, timeout)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aaa
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+const secret = "s3cr3t"
+
+var users = map[string]*User{
+	"admin": {Password: "adminpw"},
+	"ops":   {Password: "opspw", Denied: []string{"configure"}},
+}
+
+func TestConfig(t *testing.T) {
+	a := Config("tacacs", telemetry.AaaTypes_AAA_SERVER_TYPE_TACACS, TACACSServer("192.0.2.1", TACACSPort, secret))
+	s := a.GetServerGroup("tacacs").GetServer("192.0.2.1")
+	if got := s.GetTacacs().GetPort(); got != TACACSPort {
+		t.Errorf("TACACS+ server port got %d, want %d", got, TACACSPort)
+	}
+	if got := len(a.GetAuthentication().AuthenticationMethod); got != 2 {
+		t.Errorf("authentication methods got %d, want 2", got)
+	}
+	if a.GetAuthorization() == nil {
+		t.Errorf("TACACS+ config got no authorization")
+	}
+	a = Config("radius", telemetry.AaaTypes_AAA_SERVER_TYPE_RADIUS, RADIUSServer("192.0.2.1", RADIUSAuthPort, RADIUSAcctPort, secret))
+	if a.GetAuthorization() != nil {
+		t.Errorf("RADIUS config got authorization %v, want none", a.GetAuthorization())
+	}
+	if got := len(a.GetAccounting().Event); got != 2 {
+		t.Errorf("accounting events got %d, want 2", got)
+	}
+}
+
+// tacacsClient is a TACACS+ client of a session over a connection.
+type tacacsClient struct {
+	t       *testing.T
+	conn    net.Conn
+	secret  string
+	session uint32
+	seq     byte
+}
+
+func newTACACSClient(t *testing.T, s *TACACS, secret string, session uint32) *tacacsClient {
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatalf("Cannot connect to the TACACS+ server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &tacacsClient{t: t, conn: conn, secret: secret, session: session, seq: 1}
+}
+
+// exchange sends the request body and returns the reply body.
+func (c *tacacsClient) exchange(typ byte, body []byte) []byte {
+	c.t.Helper()
+	req := &tacacsPacket{version: tacacsVersion, typ: typ, seq: c.seq, session: c.session, body: body}
+	if err := writeTACACS(c.conn, req, c.secret); err != nil {
+		c.t.Fatalf("Cannot send the TACACS+ request: %v", err)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	resp, err := readTACACS(c.conn, c.secret)
+	if err != nil {
+		c.t.Fatalf("Cannot read the TACACS+ reply: %v", err)
+	}
+	if resp.seq != c.seq+1 || resp.session != c.session {
+		c.t.Fatalf("TACACS+ reply got seq %d session %d, want seq %d session %d", resp.seq, resp.session, c.seq+1, c.session)
+	}
+	c.seq += 2
+	return resp.body
+}
+
+func authenStart(typ byte, user, data string) []byte {
+	b := []byte{1, 1, typ, 1, byte(len(user)), 3, 8, byte(len(data))}
+	b = append(b, user...)
+	b = append(b, "tty"...)
+	b = append(b, "10.0.0.1"...)
+	return append(b, data...)
+}
+
+func authenContinue(msg string) []byte {
+	b := make([]byte, 5)
+	binary.BigEndian.PutUint16(b, uint16(len(msg)))
+	return append(b, msg...)
+}
+
+func tacacsRequest(flags []byte, user string, args ...string) []byte {
+	b := append(flags, 6, 1, 1, 1, byte(len(user)), 3, 8, byte(len(args)))
+	for _, a := range args {
+		b = append(b, byte(len(a)))
+	}
+	b = append(b, user...)
+	b = append(b, "tty"...)
+	b = append(b, "10.0.0.1"...)
+	for _, a := range args {
+		b = append(b, a...)
+	}
+	return b
+}
+
+func TestTACACS(t *testing.T) {
+	s, err := NewTACACS("127.0.0.1:0", secret, users)
+	if err != nil {
+		t.Fatalf("NewTACACS() got error: %v", err)
+	}
+	defer s.Close()
+
+	if got := newTACACSClient(t, s, secret, 1).exchange(tacacsAuthen, authenStart(authenTypePAP, "admin", "adminpw"))[0]; got != authenPass {
+		t.Errorf("PAP authentication of admin got status %d, want %d", got, authenPass)
+	}
+	if got := newTACACSClient(t, s, secret, 2).exchange(tacacsAuthen, authenStart(authenTypePAP, "admin", "wrong"))[0]; got != authenFail {
+		t.Errorf("PAP authentication with a wrong password got status %d, want %d", got, authenFail)
+	}
+
+	c := newTACACSClient(t, s, secret, 3)
+	for _, step := range []struct {
+		body []byte
+		want byte
+	}{
+		{authenStart(authenTypeASCII, "", ""), authenGetUser},
+		{authenContinue("ops"), authenGetPass},
+		{authenContinue("opspw"), authenPass},
+	} {
+		if got := c.exchange(tacacsAuthen, step.body)[0]; got != step.want {
+			t.Fatalf("ASCII authentication got status %d, want %d", got, step.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		cmd  []string
+		want byte
+	}{
+		{[]string{"service=shell", "cmd=show", "cmd-arg=version", "cmd-arg=<cr>"}, authorPassAdd},
+		{[]string{"service=shell", "cmd=configure", "cmd-arg=terminal"}, authorFail},
+	} {
+		if got := newTACACSClient(t, s, secret, 4).exchange(tacacsAuthor, tacacsRequest(nil, "ops", tc.cmd...))[0]; got != tc.want {
+			t.Errorf("authorization of %v got status %#x, want %#x", tc.cmd, got, tc.want)
+		}
+	}
+	if got := newTACACSClient(t, s, secret, 5).exchange(tacacsAcct, tacacsRequest([]byte{acctFlagStart}, "ops", "service=shell", "task_id=1"))[4]; got != acctSuccess {
+		t.Errorf("accounting got status %d, want %d", got, acctSuccess)
+	}
+
+	want := []*Event{
+		{Kind: Authentication, User: "admin", Service: "login", Remote: "10.0.0.1", Pass: true},
+		{Kind: Authentication, User: "admin", Service: "login", Remote: "10.0.0.1"},
+		{Kind: Authentication, User: "ops", Service: "login", Remote: "10.0.0.1", Pass: true},
+		{Kind: Authorization, User: "ops", Service: "shell", Command: "show version", Remote: "10.0.0.1", Pass: true},
+		{Kind: Authorization, User: "ops", Service: "shell", Command: "configure terminal", Remote: "10.0.0.1"},
+		{Kind: Accounting, User: "ops", Service: "shell", Record: "start", Remote: "10.0.0.1", Pass: true},
+	}
+	if diff := cmp.Diff(want, s.Events()); diff != "" {
+		t.Errorf("Events() diff (-want +got):\n%s", diff)
+	}
+	if errs := s.Errors(); len(errs) != 0 {
+		t.Errorf("Errors() got %v", errs)
+	}
+}
+
+func TestTACACSWrongSecret(t *testing.T) {
+	s, err := NewTACACS("127.0.0.1:0", secret, users)
+	if err != nil {
+		t.Fatalf("NewTACACS() got error: %v", err)
+	}
+	defer s.Close()
+
+	c := newTACACSClient(t, s, "wrong", 1)
+	req := &tacacsPacket{version: tacacsVersion, typ: tacacsAuthen, seq: 1, session: 1, body: authenStart(authenTypePAP, "admin", "adminpw")}
+	if err := writeTACACS(c.conn, req, c.secret); err != nil {
+		t.Fatalf("Cannot send the TACACS+ request: %v", err)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if resp, err := readTACACS(c.conn, c.secret); err == nil && resp.body[0] == authenPass {
+		t.Errorf("authentication with a wrong secret passed")
+	}
+	if _, err := s.Await(0, time.Second, func(e *Event) bool { return e.Pass }); err == nil {
+		t.Errorf("Await() got a passing event with a wrong secret")
+	}
+}
+
+// radiusRequest returns a request of the code with the attributes,
+// hiding the password if any.
+func radiusRequest(code, id byte, secret string, attrs map[byte][]byte) []byte {
+	authenticator := make([]byte, md5.Size)
+	if code == accessRequest {
+		copy(authenticator, "0123456789abcdef")
+	}
+	var a []byte
+	for _, typ := range []byte{attrUserName, attrUserPassword, attrServiceType, attrCallingStationID, attrAcctStatusType} {
+		v, ok := attrs[typ]
+		if !ok {
+			continue
+		}
+		if typ == attrUserPassword {
+			v = hidePassword(v, secret, authenticator)
+		}
+		a = append(a, typ, byte(len(v)+2))
+		a = append(a, v...)
+	}
+	b := []byte{code, id, 0, 0}
+	binary.BigEndian.PutUint16(b[2:], uint16(radiusHeaderLen+len(a)))
+	b = append(append(b, authenticator...), a...)
+	if code == accountingRequest {
+		sum := md5.Sum(append(append([]byte(nil), b...), secret...))
+		copy(b[4:], sum[:])
+	}
+	return b
+}
+
+func hidePassword(password []byte, secret string, authenticator []byte) []byte {
+	p := append([]byte(nil), password...)
+	for len(p)%md5.Size != 0 || len(p) == 0 {
+		p = append(p, 0)
+	}
+	var hidden []byte
+	prev := authenticator
+	for i := 0; i < len(p); i += md5.Size {
+		b := md5.Sum(append([]byte(secret), prev...))
+		for j := 0; j < md5.Size; j++ {
+			hidden = append(hidden, p[i+j]^b[j])
+		}
+		prev = hidden[i : i+md5.Size]
+	}
+	return hidden
+}
+
+func radiusExchange(t *testing.T, addr net.Addr, req []byte) *radiusPacket {
+	t.Helper()
+	conn, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatalf("Cannot connect to the RADIUS server: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(req); err != nil {
+		t.Fatalf("Cannot send the RADIUS request: %v", err)
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Cannot read the RADIUS response: %v", err)
+	}
+	resp, err := parseRADIUS(buf[:n])
+	if err != nil {
+		t.Fatalf("Cannot parse the RADIUS response: %v", err)
+	}
+	check := append([]byte(nil), resp.raw...)
+	copy(check[4:], req[4:radiusHeaderLen])
+	if sum := md5.Sum(append(check, secret...)); string(sum[:]) != string(resp.authenticator) {
+		t.Errorf("RADIUS response got an invalid authenticator")
+	}
+	return resp
+}
+
+func TestRADIUS(t *testing.T) {
+	s, err := NewRADIUS("127.0.0.1:0", "127.0.0.1:0", secret, users)
+	if err != nil {
+		t.Fatalf("NewRADIUS() got error: %v", err)
+	}
+	defer s.Close()
+
+	login := []byte{0, 0, 0, 1}
+	for _, tc := range []struct {
+		user, password string
+		want           byte
+	}{
+		{"admin", "adminpw", accessAccept},
+		{"admin", "a much longer wrong password", accessReject},
+		{"nobody", "adminpw", accessReject},
+	} {
+		req := radiusRequest(accessRequest, 1, secret, map[byte][]byte{
+			attrUserName:         []byte(tc.user),
+			attrUserPassword:     []byte(tc.password),
+			attrServiceType:      login,
+			attrCallingStationID: []byte("10.0.0.1"),
+		})
+		if got := radiusExchange(t, s.AuthAddr(), req).code; got != tc.want {
+			t.Errorf("Access-Request of %s/%s got code %d, want %d", tc.user, tc.password, got, tc.want)
+		}
+	}
+	req := radiusRequest(accountingRequest, 2, secret, map[byte][]byte{
+		attrUserName:       []byte("admin"),
+		attrAcctStatusType: {0, 0, 0, 2},
+	})
+	if got := radiusExchange(t, s.AcctAddr(), req).code; got != accountingResponse {
+		t.Errorf("Accounting-Request got code %d, want %d", got, accountingResponse)
+	}
+
+	want := []*Event{
+		{Kind: Authentication, User: "admin", Service: "login", Remote: "10.0.0.1", Pass: true},
+		{Kind: Authentication, User: "admin", Service: "login", Remote: "10.0.0.1"},
+		{Kind: Authentication, User: "nobody", Service: "login", Remote: "10.0.0.1"},
+		{Kind: Accounting, User: "admin", Record: "stop", Pass: true},
+	}
+	if diff := cmp.Diff(want, s.Events(), cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("Events() diff (-want +got):\n%s", diff)
+	}
+	if e, err := s.Await(1, time.Second, func(e *Event) bool { return e.Kind == Accounting }); err != nil || e.Record != "stop" {
+		t.Errorf("Await(accounting) got %v, %v, want the stop record", e, err)
+	}
+}
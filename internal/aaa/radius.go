@@ -0,0 +1,254 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aaa
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// RADIUS packets (RFC 2865 and RFC 2866).
+const (
+	radiusHeaderLen = 20
+
+	accessRequest      = 1
+	accessAccept       = 2
+	accessReject       = 3
+	accountingRequest  = 4
+	accountingResponse = 5
+
+	attrUserName         = 1
+	attrUserPassword     = 2
+	attrServiceType      = 6
+	attrCallingStationID = 31
+	attrAcctStatusType   = 40
+)
+
+// serviceTypes are the names of the values of the Service-Type
+// attribute.
+var serviceTypes = map[uint32]string{
+	1: "login",
+	6: "administrative",
+	7: "nas-prompt",
+}
+
+// acctStatusTypes are the records of the values of the Acct-Status-Type
+// attribute.
+var acctStatusTypes = map[uint32]string{
+	1: "start",
+	2: "stop",
+	3: "update",
+}
+
+// RADIUS is a RADIUS server.
+type RADIUS struct {
+	eventLog
+	auth, acct net.PacketConn
+	secret     string
+	users      map[string]*User
+	wg         sync.WaitGroup
+}
+
+// NewRADIUS starts a RADIUS server listening for authentication and
+// accounting on the UDP addresses, which shares the secret with the
+// device and knows the users.
+func NewRADIUS(authAddr, acctAddr, secret string, users map[string]*User) (*RADIUS, error) {
+	auth, err := net.ListenPacket("udp", authAddr)
+	if err != nil {
+		return nil, err
+	}
+	acct, err := net.ListenPacket("udp", acctAddr)
+	if err != nil {
+		auth.Close()
+		return nil, err
+	}
+	s := &RADIUS{auth: auth, acct: acct, secret: secret, users: users}
+	s.wg.Add(2)
+	go s.serve(auth, s.authenticate)
+	go s.serve(acct, s.account)
+	return s, nil
+}
+
+// AuthAddr returns the address the server listens on for
+// authentication.
+func (s *RADIUS) AuthAddr() net.Addr {
+	return s.auth.LocalAddr()
+}
+
+// AcctAddr returns the address the server listens on for accounting.
+func (s *RADIUS) AcctAddr() net.Addr {
+	return s.acct.LocalAddr()
+}
+
+// Close stops the server.
+func (s *RADIUS) Close() error {
+	err := s.auth.Close()
+	if err2 := s.acct.Close(); err == nil {
+		err = err2
+	}
+	s.wg.Wait()
+	return err
+}
+
+// radiusPacket is a RADIUS packet.
+type radiusPacket struct {
+	code, id      byte
+	authenticator []byte
+	attrs         map[byte][]byte
+	raw           []byte
+}
+
+func parseRADIUS(b []byte) (*radiusPacket, error) {
+	if len(b) < radiusHeaderLen {
+		return nil, fmt.Errorf("RADIUS packet of %d bytes too short", len(b))
+	}
+	n := int(binary.BigEndian.Uint16(b[2:]))
+	if n < radiusHeaderLen || n > len(b) {
+		return nil, fmt.Errorf("RADIUS packet length %d invalid for %d bytes", n, len(b))
+	}
+	p := &radiusPacket{
+		code:          b[0],
+		id:            b[1],
+		authenticator: b[4:radiusHeaderLen],
+		attrs:         map[byte][]byte{},
+		raw:           b[:n],
+	}
+	for a := b[radiusHeaderLen:n]; len(a) > 0; {
+		if len(a) < 2 || int(a[1]) < 2 || int(a[1]) > len(a) {
+			return nil, fmt.Errorf("RADIUS attribute truncated")
+		}
+		p.attrs[a[0]] = a[2:a[1]]
+		a = a[a[1]:]
+	}
+	return p, nil
+}
+
+// uint32Attr returns the value of an integer attribute.
+func (p *radiusPacket) uint32Attr(typ byte) (uint32, bool) {
+	v, ok := p.attrs[typ]
+	if !ok || len(v) != 4 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(v), true
+}
+
+// decodePassword returns the User-Password hidden by the secret and the
+// request authenticator (RFC 2865 section 5.2).
+func decodePassword(hidden []byte, secret string, authenticator []byte) ([]byte, error) {
+	if len(hidden) == 0 || len(hidden)%md5.Size != 0 {
+		return nil, fmt.Errorf("RADIUS User-Password of %d bytes invalid", len(hidden))
+	}
+	var password []byte
+	prev := authenticator
+	for i := 0; i < len(hidden); i += md5.Size {
+		b := md5.Sum(append([]byte(secret), prev...))
+		for j := 0; j < md5.Size; j++ {
+			password = append(password, hidden[i+j]^b[j])
+		}
+		prev = hidden[i : i+md5.Size]
+	}
+	return bytes.TrimRight(password, "\x00"), nil
+}
+
+// response returns the response of the code to the request, signed by
+// the Response Authenticator.
+func response(req *radiusPacket, code byte, secret string) []byte {
+	b := make([]byte, radiusHeaderLen)
+	b[0] = code
+	b[1] = req.id
+	binary.BigEndian.PutUint16(b[2:], radiusHeaderLen)
+	copy(b[4:], req.authenticator)
+	sum := md5.Sum(append(append([]byte(nil), b...), secret...))
+	copy(b[4:], sum[:])
+	return b
+}
+
+func (s *RADIUS) serve(conn net.PacketConn, handle func(*radiusPacket) ([]byte, error)) {
+	defer s.wg.Done()
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		req, err := parseRADIUS(append([]byte(nil), buf[:n]...))
+		if err == nil {
+			var resp []byte
+			if resp, err = handle(req); err == nil {
+				_, err = conn.WriteTo(resp, addr)
+			}
+		}
+		if err != nil {
+			s.addError(err)
+		}
+	}
+}
+
+func (s *RADIUS) event(req *radiusPacket, kind Kind) *Event {
+	e := &Event{
+		Kind:   kind,
+		User:   string(req.attrs[attrUserName]),
+		Remote: string(req.attrs[attrCallingStationID]),
+	}
+	if v, ok := req.uint32Attr(attrServiceType); ok {
+		e.Service = serviceTypes[v]
+	}
+	return e
+}
+
+func (s *RADIUS) authenticate(req *radiusPacket) ([]byte, error) {
+	if req.code != accessRequest {
+		return nil, fmt.Errorf("RADIUS code %d on the authentication port", req.code)
+	}
+	password, err := decodePassword(req.attrs[attrUserPassword], s.secret, req.authenticator)
+	if err != nil {
+		return nil, err
+	}
+	e := s.event(req, Authentication)
+	u, ok := s.users[e.User]
+	e.Pass = ok && u.Password == string(password)
+	s.add(e)
+	if !e.Pass {
+		return response(req, accessReject, s.secret), nil
+	}
+	return response(req, accessAccept, s.secret), nil
+}
+
+func (s *RADIUS) account(req *radiusPacket) ([]byte, error) {
+	if req.code != accountingRequest {
+		return nil, fmt.Errorf("RADIUS code %d on the accounting port", req.code)
+	}
+	// The Request Authenticator is signed as a response to a request
+	// with a zero authenticator (RFC 2866 section 3).
+	zeroed := append([]byte(nil), req.raw...)
+	copy(zeroed[4:radiusHeaderLen], make([]byte, md5.Size))
+	if sum := md5.Sum(append(zeroed, s.secret...)); !bytes.Equal(sum[:], req.authenticator) {
+		return nil, fmt.Errorf("RADIUS accounting request of %q has an invalid authenticator", req.attrs[attrUserName])
+	}
+	e := s.event(req, Accounting)
+	status, _ := req.uint32Attr(attrAcctStatusType)
+	e.Record = acctStatusTypes[status]
+	e.Pass = e.Record != ""
+	s.add(e)
+	return response(req, accountingResponse, s.secret), nil
+}
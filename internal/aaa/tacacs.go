@@ -0,0 +1,466 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aaa
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// TACACS+ packets (RFC 8907).
+const (
+	tacacsHeaderLen = 12
+	tacacsVersion   = 0xC0
+
+	tacacsAuthen = 1
+	tacacsAuthor = 2
+	tacacsAcct   = 3
+
+	tacacsUnencrypted   = 0x01
+	tacacsSingleConnect = 0x04
+
+	authenTypeASCII = 1
+	authenTypePAP   = 2
+
+	authenPass    = 1
+	authenFail    = 2
+	authenGetUser = 4
+	authenGetPass = 5
+	authenError   = 7
+
+	authenReplyNoEcho = 0x01
+
+	authorPassAdd = 0x01
+	authorFail    = 0x10
+
+	acctSuccess = 0x01
+	acctError   = 0x02
+
+	acctFlagStart    = 0x02
+	acctFlagStop     = 0x04
+	acctFlagWatchdog = 0x08
+)
+
+// authenServices are the names of the authentication services.
+var authenServices = map[byte]string{
+	0: "none",
+	1: "login",
+	2: "enable",
+	3: "ppp",
+	5: "pt",
+	6: "rcmd",
+	7: "x25",
+	8: "nasi",
+	9: "fwproxy",
+}
+
+// TACACS is a TACACS+ server.
+type TACACS struct {
+	eventLog
+	ln     net.Listener
+	secret string
+	users  map[string]*User
+	wg     sync.WaitGroup
+
+	connsMu sync.Mutex
+	conns   map[net.Conn]bool
+}
+
+// NewTACACS starts a TACACS+ server listening on the TCP address, which
+// shares the secret with the device and knows the users.
+func NewTACACS(addr, secret string, users map[string]*User) (*TACACS, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &TACACS{ln: ln, secret: secret, users: users, conns: map[net.Conn]bool{}}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *TACACS) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Close stops the server, closing the connections of the device.
+func (s *TACACS) Close() error {
+	err := s.ln.Close()
+	s.connsMu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connsMu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *TACACS) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		s.connsMu.Lock()
+		s.conns[conn] = true
+		s.connsMu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.connsMu.Lock()
+				delete(s.conns, conn)
+				s.connsMu.Unlock()
+				conn.Close()
+			}()
+			if err := s.handle(conn); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.addError(err)
+			}
+		}()
+	}
+}
+
+// tacacsPacket is a TACACS+ packet with its body in the clear.
+type tacacsPacket struct {
+	version, typ, seq, flags byte
+	session                  uint32
+	body                     []byte
+}
+
+// tacacsPad returns the pseudo-random pad obfuscating a body of the
+// length, as MD5 hashes chained from the session, secret, version and
+// sequence number.
+func tacacsPad(p *tacacsPacket, secret string, length int) []byte {
+	seed := make([]byte, 4)
+	binary.BigEndian.PutUint32(seed, p.session)
+	seed = append(seed, secret...)
+	seed = append(seed, p.version, p.seq)
+	var pad []byte
+	var prev []byte
+	for len(pad) < length {
+		sum := md5.Sum(append(append([]byte(nil), seed...), prev...))
+		prev = sum[:]
+		pad = append(pad, prev...)
+	}
+	return pad[:length]
+}
+
+// obfuscate obfuscates or clears the body with the secret, unless the
+// packet is unencrypted.
+func (p *tacacsPacket) obfuscate(secret string) {
+	if p.flags&tacacsUnencrypted != 0 {
+		return
+	}
+	for i, b := range tacacsPad(p, secret, len(p.body)) {
+		p.body[i] ^= b
+	}
+}
+
+func readTACACS(r io.Reader, secret string) (*tacacsPacket, error) {
+	hdr := make([]byte, tacacsHeaderLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	if hdr[0]&0xF0 != tacacsVersion {
+		return nil, fmt.Errorf("TACACS+ version %#x unsupported", hdr[0])
+	}
+	n := binary.BigEndian.Uint32(hdr[8:])
+	if n > 1<<16 {
+		return nil, fmt.Errorf("TACACS+ body of %d bytes too long", n)
+	}
+	p := &tacacsPacket{
+		version: hdr[0],
+		typ:     hdr[1],
+		seq:     hdr[2],
+		flags:   hdr[3],
+		session: binary.BigEndian.Uint32(hdr[4:]),
+		body:    make([]byte, n),
+	}
+	if _, err := io.ReadFull(r, p.body); err != nil {
+		return nil, err
+	}
+	p.obfuscate(secret)
+	return p, nil
+}
+
+func writeTACACS(w io.Writer, p *tacacsPacket, secret string) error {
+	body := append([]byte(nil), p.body...)
+	q := *p
+	q.body = body
+	q.obfuscate(secret)
+	hdr := make([]byte, tacacsHeaderLen)
+	copy(hdr, []byte{q.version, q.typ, q.seq, q.flags})
+	binary.BigEndian.PutUint32(hdr[4:], q.session)
+	binary.BigEndian.PutUint32(hdr[8:], uint32(len(body)))
+	_, err := w.Write(append(hdr, body...))
+	return err
+}
+
+// fields splits the data into fields of the lengths, failing if it is
+// too short.
+func fields(data []byte, lens ...int) ([][]byte, []byte, error) {
+	var fs [][]byte
+	for _, n := range lens {
+		if len(data) < n {
+			return nil, nil, fmt.Errorf("TACACS+ body truncated")
+		}
+		fs = append(fs, data[:n])
+		data = data[n:]
+	}
+	return fs, data, nil
+}
+
+// authenSession is the state of an ASCII login across the packets of a
+// session.
+type authenSession struct {
+	event    *Event
+	password bool
+}
+
+func (s *TACACS) handle(conn net.Conn) error {
+	sessions := map[uint32]*authenSession{}
+	for {
+		req, err := readTACACS(conn, s.secret)
+		if err != nil {
+			return err
+		}
+		var body []byte
+		switch req.typ {
+		case tacacsAuthen:
+			body, err = s.authenticate(req, sessions)
+		case tacacsAuthor:
+			body, err = s.authorize(req)
+		case tacacsAcct:
+			body, err = s.account(req)
+		default:
+			err = fmt.Errorf("TACACS+ packet type %d unsupported", req.typ)
+		}
+		if err != nil {
+			return err
+		}
+		resp := &tacacsPacket{
+			version: req.version,
+			typ:     req.typ,
+			seq:     req.seq + 1,
+			flags:   req.flags & (tacacsUnencrypted | tacacsSingleConnect),
+			session: req.session,
+			body:    body,
+		}
+		if err := writeTACACS(conn, resp, s.secret); err != nil {
+			return err
+		}
+	}
+}
+
+func authenReply(status, flags byte, msg string) []byte {
+	b := []byte{status, flags, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(b[2:], uint16(len(msg)))
+	return append(b, msg...)
+}
+
+// check records the authentication of the user with the password.
+func (s *TACACS) check(e *Event, password string) []byte {
+	u, ok := s.users[e.User]
+	e.Pass = ok && u.Password == password
+	s.add(e)
+	if !e.Pass {
+		return authenReply(authenFail, 0, "Authentication failed")
+	}
+	return authenReply(authenPass, 0, "")
+}
+
+func (s *TACACS) authenticate(req *tacacsPacket, sessions map[uint32]*authenSession) ([]byte, error) {
+	if req.seq == 1 {
+		// START: action, priv_lvl, authen_type, authen_service, user_len,
+		// port_len, rem_addr_len, data_len, then the fields.
+		hdr, rest, err := fields(req.body, 8)
+		if err != nil {
+			return nil, err
+		}
+		h := hdr[0]
+		f, _, err := fields(rest, int(h[4]), int(h[5]), int(h[6]), int(h[7]))
+		if err != nil {
+			return nil, err
+		}
+		e := &Event{
+			Kind:    Authentication,
+			User:    string(f[0]),
+			Service: authenServices[h[3]],
+			Remote:  string(f[2]),
+		}
+		switch h[2] {
+		case authenTypePAP:
+			return s.check(e, string(f[3])), nil
+		case authenTypeASCII:
+			sessions[req.session] = &authenSession{event: e}
+			if e.User == "" {
+				return authenReply(authenGetUser, 0, "Username: "), nil
+			}
+			sessions[req.session].password = true
+			return authenReply(authenGetPass, authenReplyNoEcho, "Password: "), nil
+		}
+		return authenReply(authenError, 0, fmt.Sprintf("authentication type %d unsupported", h[2])), nil
+	}
+	// CONTINUE: user_msg_len, data_len, flags, then the fields.
+	hdr, rest, err := fields(req.body, 5)
+	if err != nil {
+		return nil, err
+	}
+	h := hdr[0]
+	f, _, err := fields(rest, int(binary.BigEndian.Uint16(h)), int(binary.BigEndian.Uint16(h[2:])))
+	if err != nil {
+		return nil, err
+	}
+	sess, ok := sessions[req.session]
+	if !ok {
+		return authenReply(authenError, 0, "no authentication in progress"), nil
+	}
+	if !sess.password {
+		sess.event.User = string(f[0])
+		sess.password = true
+		return authenReply(authenGetPass, authenReplyNoEcho, "Password: "), nil
+	}
+	delete(sessions, req.session)
+	return s.check(sess.event, string(f[0])), nil
+}
+
+// request is the fields common to the authorization and accounting
+// requests.
+type request struct {
+	service      string
+	user, remote string
+	args         []string
+}
+
+// parseRequest parses the body of an authorization or accounting
+// request, which starts with the skipped bytes then authen_method,
+// priv_lvl, authen_type, authen_service, user_len, port_len, rem_addr_len
+// and arg_cnt, then the lengths of the arguments, then the fields.
+func parseRequest(body []byte, skip int) (*request, error) {
+	hdr, rest, err := fields(body, skip+8)
+	if err != nil {
+		return nil, err
+	}
+	h := hdr[0][skip:]
+	lens, rest, err := fields(rest, int(h[7]))
+	if err != nil {
+		return nil, err
+	}
+	n := []int{int(h[4]), int(h[5]), int(h[6])}
+	for _, l := range lens[0] {
+		n = append(n, int(l))
+	}
+	f, _, err := fields(rest, n...)
+	if err != nil {
+		return nil, err
+	}
+	r := &request{service: authenServices[h[3]], user: string(f[0]), remote: string(f[2])}
+	for _, a := range f[3:] {
+		r.args = append(r.args, string(a))
+	}
+	return r, nil
+}
+
+// arg returns the value of the argument of the name, either mandatory
+// (name=value) or optional (name*value).
+func (r *request) arg(name string) string {
+	for _, a := range r.args {
+		for _, sep := range []string{"=", "*"} {
+			if strings.HasPrefix(a, name+sep) {
+				return strings.TrimPrefix(a, name+sep)
+			}
+		}
+	}
+	return ""
+}
+
+// command returns the command of the cmd and cmd-arg arguments, without
+// the trailing <cr> of a complete command.
+func (r *request) command() string {
+	var cmd []string
+	for _, a := range r.args {
+		for _, name := range []string{"cmd=", "cmd*", "cmd-arg=", "cmd-arg*"} {
+			if !strings.HasPrefix(a, name) {
+				continue
+			}
+			if v := strings.TrimPrefix(a, name); v != "" && v != "<cr>" {
+				cmd = append(cmd, v)
+			}
+			break
+		}
+	}
+	return strings.Join(cmd, " ")
+}
+
+func (r *request) event(kind Kind) *Event {
+	e := &Event{Kind: kind, User: r.user, Service: r.arg("service"), Command: r.command(), Remote: r.remote}
+	if e.Service == "" {
+		e.Service = r.service
+	}
+	return e
+}
+
+func (s *TACACS) authorize(req *tacacsPacket) ([]byte, error) {
+	r, err := parseRequest(req.body, 0)
+	if err != nil {
+		return nil, err
+	}
+	e := r.event(Authorization)
+	u, ok := s.users[e.User]
+	e.Pass = ok && u.authorized(e.Command)
+	s.add(e)
+	// RESPONSE: status, arg_cnt, server_msg_len, data_len, then the
+	// fields.
+	status := byte(authorPassAdd)
+	if !e.Pass {
+		status = authorFail
+	}
+	return []byte{status, 0, 0, 0, 0, 0}, nil
+}
+
+func (s *TACACS) account(req *tacacsPacket) ([]byte, error) {
+	r, err := parseRequest(req.body, 1)
+	if err != nil {
+		return nil, err
+	}
+	e := r.event(Accounting)
+	switch flags := req.body[0]; {
+	case flags&acctFlagStart != 0:
+		e.Record = "start"
+	case flags&acctFlagStop != 0:
+		e.Record = "stop"
+	case flags&acctFlagWatchdog != 0:
+		e.Record = "update"
+	}
+	e.Pass = e.Record != ""
+	s.add(e)
+	// REPLY: server_msg_len, data_len, status, then the fields.
+	status := byte(acctSuccess)
+	if !e.Pass {
+		status = acctError
+	}
+	return []byte{0, 0, 0, 0, status}, nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package args defines the arguments to tune the traffic, convergence and
+// scale of the featureprofiles tests for a testbed.
+//
+// Unlike deviations, the arguments do not change what a test validates,
+// only how hard it pushes the DUT and how long it waits for it: emulated
+// DUTs, such as the containerized DUTs of a KNE topology, typically forward
+// at a fraction of the rate of hardware, and take longer to converge.  Each
+// test keeps its own defaults, which are scaled by the profile of the
+// target unless the corresponding argument is set.
+//
+// A target is emulated when the arg_emulated argument is set, or when the
+// tests run against a KNE topology.  For example, to run the tests against
+// a hardware testbed with a tenth of their traffic rate and scale, and
+// twice as much time to converge:
+//
+//	go test ./... --arg_traffic_rate_percent=10 --arg_scale_percent=10 \
+//	  --arg_convergence_percent=200
+package args
+
+import (
+	"flag"
+	"time"
+)
+
+// Test arguments.
+var (
+	EmulatedArg = flag.Bool("arg_emulated", false,
+		"The DUTs are emulated, such as containerized DUTs, and the tests use the profile of emulated targets. Implied by --kne-config.")
+
+	TrafficDurationArg = flag.Duration("arg_traffic_duration", 0,
+		"Duration of the traffic sent by the tests; zero uses the default of each test.")
+
+	TrafficRatePercentArg = flag.Float64("arg_traffic_rate_percent", 0,
+		"Percentage of the default traffic rate of each test, in packets per second, to send at; zero uses the profile of the target.")
+
+	LineRatePercentArg = flag.Float64("arg_line_rate_percent", 0,
+		"Percentage of the line rate of the ATE ports to send traffic at, in tests sending at a share of the line rate; zero uses the default of each test.")
+
+	ConvergencePercentArg = flag.Float64("arg_convergence_percent", 0,
+		"Percentage of the default convergence timeouts and outage thresholds of each test; zero uses the profile of the target.")
+
+	ScalePercentArg = flag.Float64("arg_scale_percent", 0,
+		"Percentage of the default scale of each test, such as the number of routes, flows or neighbors; zero uses the profile of the target.")
+)
+
+// Profile is how the defaults of the tests are scaled for a kind of
+// target, in percent.
+type Profile struct {
+	TrafficRatePercent float64
+	ConvergencePercent float64
+	ScalePercent       float64
+}
+
+var (
+	// HardwareProfile runs the tests with their defaults.
+	HardwareProfile = Profile{
+		TrafficRatePercent: 100,
+		ConvergencePercent: 100,
+		ScalePercent:       100,
+	}
+
+	// EmulatedProfile runs the tests with a tenth of their traffic rate
+	// and scale, and three times as much time to converge.
+	EmulatedProfile = Profile{
+		TrafficRatePercent: 10,
+		ConvergencePercent: 300,
+		ScalePercent:       10,
+	}
+)
+
+// IsEmulated returns whether the DUTs are emulated, either because the
+// arg_emulated argument is set or because the tests run against a KNE
+// topology.
+func IsEmulated() bool {
+	if *EmulatedArg {
+		return true
+	}
+	// The flag is defined by topologies/binding, which this package does
+	// not depend on.
+	f := flag.Lookup("kne-config")
+	return f != nil && f.Value.String() != ""
+}
+
+// TargetProfile returns the profile of the target of the tests.
+func TargetProfile() Profile {
+	if IsEmulated() {
+		return EmulatedProfile
+	}
+	return HardwareProfile
+}
+
+// percent returns the percentage given by an argument, or by the profile
+// of the target if the argument is not set.
+func percent(arg *float64, profile float64) float64 {
+	if *arg > 0 {
+		return *arg
+	}
+	return profile
+}
+
+// TrafficDuration returns how long to send traffic for, def unless the
+// arg_traffic_duration argument is set.
+func TrafficDuration(def time.Duration) time.Duration {
+	if *TrafficDurationArg > 0 {
+		return *TrafficDurationArg
+	}
+	return def
+}
+
+// PPS returns the rate to send traffic at, def scaled by the
+// arg_traffic_rate_percent argument or the profile of the target, and at
+// least one packet per second.
+func PPS(def uint64) uint64 {
+	pps := uint64(float64(def) * percent(TrafficRatePercentArg, TargetProfile().TrafficRatePercent) / 100)
+	if pps == 0 {
+		return 1
+	}
+	return pps
+}
+
+// LineRatePercent returns the percentage of the line rate to send traffic
+// at, def unless the arg_line_rate_percent argument is set.
+func LineRatePercent(def float64) float64 {
+	if *LineRatePercentArg > 0 {
+		return *LineRatePercentArg
+	}
+	return def
+}
+
+// Convergence returns a convergence timeout or outage threshold, def
+// scaled by the arg_convergence_percent argument or the profile of the
+// target.
+func Convergence(def time.Duration) time.Duration {
+	return time.Duration(float64(def) * percent(ConvergencePercentArg, TargetProfile().ConvergencePercent) / 100)
+}
+
+// Scale returns a scale count, def scaled by the arg_scale_percent
+// argument or the profile of the target, and at least one.
+func Scale(def int) int {
+	n := int(float64(def) * percent(ScalePercentArg, TargetProfile().ScalePercent) / 100)
+	if n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+// set sets an argument for the duration of a test.
+func set(t *testing.T, arg *float64, v float64) {
+	old := *arg
+	*arg = v
+	t.Cleanup(func() { *arg = old })
+}
+
+// emulate makes the target emulated for the duration of a test.
+func emulate(t *testing.T) {
+	old := *EmulatedArg
+	*EmulatedArg = true
+	t.Cleanup(func() { *EmulatedArg = old })
+}
+
+func TestIsEmulated(t *testing.T) {
+	if IsEmulated() {
+		t.Errorf("IsEmulated() by default got true, want false")
+	}
+	if got, want := TargetProfile(), HardwareProfile; got != want {
+		t.Errorf("TargetProfile() by default got %+v, want %+v", got, want)
+	}
+
+	fs := flag.CommandLine
+	if fs.Lookup("kne-config") == nil {
+		fs.String("kne-config", "", "")
+	}
+	old := fs.Lookup("kne-config").Value.String()
+	if err := fs.Set("kne-config", "topology.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Set("kne-config", old)
+	if !IsEmulated() {
+		t.Errorf("IsEmulated() with --kne-config got false, want true")
+	}
+	if got, want := TargetProfile(), EmulatedProfile; got != want {
+		t.Errorf("TargetProfile() with --kne-config got %+v, want %+v", got, want)
+	}
+}
+
+func TestEmulatedProfile(t *testing.T) {
+	emulate(t)
+	if got, want := PPS(10000), uint64(1000); got != want {
+		t.Errorf("PPS(10000) on an emulated target got %d, want %d", got, want)
+	}
+	if got, want := Convergence(time.Minute), 3*time.Minute; got != want {
+		t.Errorf("Convergence() on an emulated target got %v, want %v", got, want)
+	}
+	if got, want := Scale(1000), 100; got != want {
+		t.Errorf("Scale(1000) on an emulated target got %d, want %d", got, want)
+	}
+	// The arguments take precedence over the profile.
+	set(t, ScalePercentArg, 50)
+	if got, want := Scale(1000), 500; got != want {
+		t.Errorf("Scale(1000) on an emulated target at 50%% got %d, want %d", got, want)
+	}
+}
+
+func TestTrafficDuration(t *testing.T) {
+	if got, want := TrafficDuration(15*time.Second), 15*time.Second; got != want {
+		t.Errorf("TrafficDuration() by default got %v, want %v", got, want)
+	}
+	old := *TrafficDurationArg
+	*TrafficDurationArg = time.Minute
+	defer func() { *TrafficDurationArg = old }()
+	if got, want := TrafficDuration(15*time.Second), time.Minute; got != want {
+		t.Errorf("TrafficDuration() with argument got %v, want %v", got, want)
+	}
+}
+
+func TestPPS(t *testing.T) {
+	for _, c := range []struct {
+		percent float64
+		def     uint64
+		want    uint64
+	}{
+		{100, 10000, 10000},
+		{10, 10000, 1000},
+		{250, 1000, 2500},
+		{1, 10, 1},
+		{0, 10000, 10000},
+	} {
+		set(t, TrafficRatePercentArg, c.percent)
+		if got := PPS(c.def); got != c.want {
+			t.Errorf("PPS(%d) at %g%% got %d, want %d", c.def, c.percent, got, c.want)
+		}
+	}
+}
+
+func TestLineRatePercent(t *testing.T) {
+	if got := LineRatePercent(50); got != 50 {
+		t.Errorf("LineRatePercent(50) by default got %g, want 50", got)
+	}
+	set(t, LineRatePercentArg, 5)
+	if got := LineRatePercent(50); got != 5 {
+		t.Errorf("LineRatePercent(50) with argument got %g, want 5", got)
+	}
+}
+
+func TestConvergence(t *testing.T) {
+	if got, want := Convergence(time.Minute), time.Minute; got != want {
+		t.Errorf("Convergence() by default got %v, want %v", got, want)
+	}
+	set(t, ConvergencePercentArg, 250)
+	if got, want := Convergence(time.Minute), 150*time.Second; got != want {
+		t.Errorf("Convergence() at 250%% got %v, want %v", got, want)
+	}
+}
+
+func TestScale(t *testing.T) {
+	for _, c := range []struct {
+		percent   float64
+		def, want int
+	}{
+		{100, 1000, 1000},
+		{10, 1000, 100},
+		{200, 1000, 2000},
+		{10, 5, 1},
+	} {
+		set(t, ScalePercentArg, c.percent)
+		if got := Scale(c.def); got != c.want {
+			t.Errorf("Scale(%d) at %g%% got %d, want %d", c.def, c.percent, got, c.want)
+		}
+	}
+}
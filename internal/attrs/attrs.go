@@ -41,8 +41,20 @@ type Attributes struct {
 	IPv4Len uint8  // Prefix length for IPv4.
 	IPv6Len uint8  // Prefix length for IPv6.
 	MTU     uint16
+
+	// IPv4Unnumbered is the name of the interface whose IPv4 address is
+	// borrowed, only applied to DUT interfaces.  When set, IPv4 is the
+	// borrowed address, which is not configured on this interface but
+	// is used by the ATE peer as its gateway.
+	IPv4Unnumbered string
+	// IPv6LinkLocal is the IPv6 link-local address.  An interface
+	// without IPv6 is only reachable over IPv6 by this address.
+	IPv6LinkLocal string
 }
 
+// linkLocalLen is the prefix length of the IPv6 link-local addresses.
+const linkLocalLen = 64
+
 // IPv4CIDR constructs the IPv4 CIDR notation with the given prefix
 // length, e.g. "192.0.2.1/30".
 func (a *Attributes) IPv4CIDR() string {
@@ -55,6 +67,12 @@ func (a *Attributes) IPv6CIDR() string {
 	return fmt.Sprintf("%s/%d", a.IPv6, a.IPv6Len)
 }
 
+// IPv6LinkLocalCIDR constructs the IPv6 CIDR notation of the link-local
+// address, e.g. "fe80::1/64".
+func (a *Attributes) IPv6LinkLocalCIDR() string {
+	return fmt.Sprintf("%s/%d", a.IPv6LinkLocal, linkLocalLen)
+}
+
 // ConfigInterface configures an OpenConfig interface with these attributes.
 func (a *Attributes) ConfigInterface(intf *oc.Interface) *oc.Interface {
 	if a.Desc != "" {
@@ -70,7 +88,7 @@ func (a *Attributes) ConfigInterface(intf *oc.Interface) *oc.Interface {
 	}
 
 	s := intf.GetOrCreateSubinterface(0)
-	if a.IPv4 != "" {
+	if a.IPv4 != "" || a.IPv4Unnumbered != "" {
 		s4 := s.GetOrCreateIpv4()
 		if *deviations.InterfaceEnabled {
 			s4.Enabled = ygot.Bool(true)
@@ -78,13 +96,21 @@ func (a *Attributes) ConfigInterface(intf *oc.Interface) *oc.Interface {
 		if a.MTU > 0 {
 			s4.Mtu = ygot.Uint16(a.MTU)
 		}
-		a4 := s4.GetOrCreateAddress(a.IPv4)
-		if a.IPv4Len > 0 {
-			a4.PrefixLength = ygot.Uint8(a.IPv4Len)
+		if a.IPv4Unnumbered != "" {
+			u := s4.GetOrCreateUnnumbered()
+			u.Enabled = ygot.Bool(true)
+			ref := u.GetOrCreateInterfaceRef()
+			ref.Interface = ygot.String(a.IPv4Unnumbered)
+			ref.Subinterface = ygot.Uint32(0)
+		} else {
+			a4 := s4.GetOrCreateAddress(a.IPv4)
+			if a.IPv4Len > 0 {
+				a4.PrefixLength = ygot.Uint8(a.IPv4Len)
+			}
 		}
 	}
 
-	if a.IPv6 != "" {
+	if a.IPv6 != "" || a.IPv6LinkLocal != "" {
 		s6 := s.GetOrCreateIpv6()
 		if a.MTU > 0 {
 			s6.Mtu = ygot.Uint32(uint32(a.MTU))
@@ -92,9 +118,14 @@ func (a *Attributes) ConfigInterface(intf *oc.Interface) *oc.Interface {
 		if *deviations.InterfaceEnabled {
 			s6.Enabled = ygot.Bool(true)
 		}
-		a6 := s6.GetOrCreateAddress(a.IPv6)
-		if a.IPv6Len > 0 {
-			a6.PrefixLength = ygot.Uint8(a.IPv6Len)
+		if a.IPv6 != "" {
+			a6 := s6.GetOrCreateAddress(a.IPv6)
+			if a.IPv6Len > 0 {
+				a6.PrefixLength = ygot.Uint8(a.IPv6Len)
+			}
+		}
+		if a.IPv6LinkLocal != "" {
+			s6.GetOrCreateAddress(a.IPv6LinkLocal).PrefixLength = ygot.Uint8(linkLocalLen)
 		}
 	}
 	return intf
@@ -116,10 +147,15 @@ func (a *Attributes) AddToATE(top *ondatra.ATETopology, ap *ondatra.Port, peer *
 			WithAddress(a.IPv4CIDR()).
 			WithDefaultGateway(peer.IPv4)
 	}
-	if a.IPv6 != "" {
+	switch {
+	case a.IPv6 != "":
 		i.IPv6().
 			WithAddress(a.IPv6CIDR()).
 			WithDefaultGateway(peer.IPv6)
+	case a.IPv6LinkLocal != "":
+		i.IPv6().
+			WithAddress(a.IPv6LinkLocalCIDR()).
+			WithDefaultGateway(peer.IPv6LinkLocal)
 	}
 	return i
 }
@@ -0,0 +1,75 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attrs
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	oc "github.com/openconfig/ondatra/telemetry"
+	"github.com/openconfig/ygot/ygot"
+)
+
+func TestConfigInterfaceUnnumbered(t *testing.T) {
+	a := &Attributes{
+		IPv4:           "192.0.2.1",
+		IPv4Len:        32,
+		IPv4Unnumbered: "Loopback0",
+		IPv6LinkLocal:  "fe80::1",
+	}
+	s := a.NewInterface("Ethernet1").GetSubinterface(0)
+
+	if got := s.GetIpv4().GetAddress(a.IPv4); got != nil {
+		t.Errorf("ConfigInterface() configured the borrowed address %s: %v", a.IPv4, got)
+	}
+	wantRef := &oc.Interface_Subinterface_Ipv4_Unnumbered_InterfaceRef{
+		Interface:    ygot.String("Loopback0"),
+		Subinterface: ygot.Uint32(0),
+	}
+	u := s.GetIpv4().GetUnnumbered()
+	if !u.GetEnabled() {
+		t.Errorf("ConfigInterface() unnumbered enabled got false, want true")
+	}
+	if diff := cmp.Diff(wantRef, u.GetInterfaceRef()); diff != "" {
+		t.Errorf("ConfigInterface() unnumbered interface-ref -want, +got:\n%s", diff)
+	}
+
+	addrs := s.GetIpv6().Address
+	if len(addrs) != 1 {
+		t.Fatalf("ConfigInterface() got %d IPv6 addresses, want 1", len(addrs))
+	}
+	if got := s.GetIpv6().GetAddress("fe80::1").GetPrefixLength(); got != 64 {
+		t.Errorf("ConfigInterface() link-local prefix length got %d, want 64", got)
+	}
+}
+
+func TestConfigInterfaceNumbered(t *testing.T) {
+	a := &Attributes{
+		IPv4:    "192.0.2.1",
+		IPv4Len: 30,
+		IPv6:    "2001:db8::1",
+		IPv6Len: 126,
+	}
+	s := a.NewInterface("Ethernet1").GetSubinterface(0)
+	if got := s.GetIpv4().GetAddress(a.IPv4).GetPrefixLength(); got != 30 {
+		t.Errorf("ConfigInterface() IPv4 prefix length got %d, want 30", got)
+	}
+	if got := s.GetIpv4().GetUnnumbered(); got != nil {
+		t.Errorf("ConfigInterface() configured unnumbered: %v", got)
+	}
+	if got := len(s.GetIpv6().Address); got != 1 {
+		t.Errorf("ConfigInterface() got %d IPv6 addresses, want 1", got)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package baseline applies a canonical starting configuration to a DUT,
+// so that a suite does not depend on the configuration the suites run
+// before it on the same testbed left behind.
+//
+// The baseline configures the hostname of the DUT, the gRPC servers of the
+// template of its vendor and the default network instance.  Suites apply
+// it first:
+//
+//	dut := ondatra.DUT(t, "dut")
+//	baseline.Apply(t, dut, baseline.For(dut))
+package baseline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// GRPCServer is a gRPC server of a baseline.
+type GRPCServer struct {
+	Name     string
+	Port     uint16
+	Services []telemetry.E_SystemGrpc_GRPC_SERVICE
+}
+
+// Params are the parameters of a baseline.
+type Params struct {
+	Hostname               string
+	DefaultNetworkInstance string
+	// GRPCServers are updated rather than replaced, not to disrupt the
+	// gRPC sessions of the test.
+	GRPCServers []GRPCServer
+}
+
+// services are the gRPC services of the templates.
+var services = []telemetry.E_SystemGrpc_GRPC_SERVICE{
+	telemetry.SystemGrpc_GRPC_SERVICE_GNMI,
+	telemetry.SystemGrpc_GRPC_SERVICE_P4RT,
+}
+
+// templates are the gRPC servers of the vendors, on their default ports.
+// The baseline of other vendors leaves the gRPC servers as they are.
+var templates = map[ondatra.Vendor][]GRPCServer{
+	ondatra.ARISTA:  {{Name: "DEFAULT", Port: 6030, Services: services}},
+	ondatra.CISCO:   {{Name: "DEFAULT", Port: 57400, Services: services}},
+	ondatra.JUNIPER: {{Name: "DEFAULT", Port: 32767, Services: services}},
+}
+
+// Template returns the baseline of a vendor, with the hostname and the
+// default network instance of the deviation.
+func Template(v ondatra.Vendor, hostname string) *Params {
+	return &Params{
+		Hostname:               hostname,
+		DefaultNetworkInstance: *deviations.DefaultNetworkInstance,
+		GRPCServers:            templates[v],
+	}
+}
+
+// For returns the baseline of a DUT, with its reserved name as hostname.
+func For(dut *ondatra.DUTDevice) *Params {
+	return Template(dut.Vendor(), dut.Name())
+}
+
+// Config returns the configuration of the baseline.
+func (p *Params) Config() *telemetry.Device {
+	d := &telemetry.Device{}
+	s := d.GetOrCreateSystem()
+	s.Hostname = ygot.String(p.Hostname)
+	for _, g := range p.GRPCServers {
+		gs := s.GetOrCreateGrpcServer(g.Name)
+		gs.Enable = ygot.Bool(true)
+		gs.Port = ygot.Uint16(g.Port)
+		gs.Services = append([]telemetry.E_SystemGrpc_GRPC_SERVICE{}, g.Services...)
+	}
+	ni := d.GetOrCreateNetworkInstance(p.DefaultNetworkInstance)
+	ni.Type = telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_DEFAULT_INSTANCE
+	ni.Enabled = ygot.Bool(true)
+	return d
+}
+
+// Apply applies the baseline to the DUT.
+func Apply(t testing.TB, dut *ondatra.DUTDevice, p *Params) {
+	t.Helper()
+	d := p.Config()
+	c := dut.Config()
+	c.System().Hostname().Replace(t, p.Hostname)
+	for name, gs := range d.GetSystem().GrpcServer {
+		c.System().GrpcServer(name).Update(t, gs)
+	}
+	c.NetworkInstance(p.DefaultNetworkInstance).Update(t, d.GetNetworkInstance(p.DefaultNetworkInstance))
+}
+
+// Check returns the differences of a configuration from the baseline.
+func (p *Params) Check(d *telemetry.Device) []error {
+	var errs []error
+	if got := d.GetSystem().GetHostname(); got != p.Hostname {
+		errs = append(errs, fmt.Errorf("hostname got %q, want %q", got, p.Hostname))
+	}
+	for _, g := range p.GRPCServers {
+		gs := d.GetSystem().GetGrpcServer(g.Name)
+		switch {
+		case gs == nil:
+			errs = append(errs, fmt.Errorf("gRPC server %s missing", g.Name))
+			continue
+		case !gs.GetEnable():
+			errs = append(errs, fmt.Errorf("gRPC server %s not enabled", g.Name))
+		}
+		if gs.GetPort() != g.Port {
+			errs = append(errs, fmt.Errorf("gRPC server %s port got %d, want %d", g.Name, gs.GetPort(), g.Port))
+		}
+		for _, want := range g.Services {
+			if !hasService(gs.Services, want) {
+				errs = append(errs, fmt.Errorf("gRPC server %s misses service %v", g.Name, want))
+			}
+		}
+	}
+	ni := d.GetNetworkInstance(p.DefaultNetworkInstance)
+	switch {
+	case ni == nil:
+		errs = append(errs, fmt.Errorf("network instance %s missing", p.DefaultNetworkInstance))
+	case ni.Type != telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_DEFAULT_INSTANCE:
+		errs = append(errs, fmt.Errorf("network instance %s type got %v, want DEFAULT_INSTANCE", p.DefaultNetworkInstance, ni.Type))
+	}
+	return errs
+}
+
+// hasService returns whether a service is in a list.
+func hasService(services []telemetry.E_SystemGrpc_GRPC_SERVICE, s telemetry.E_SystemGrpc_GRPC_SERVICE) bool {
+	for _, got := range services {
+		if got == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baseline
+
+import (
+	"testing"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestTemplate(t *testing.T) {
+	for _, c := range []struct {
+		vendor      ondatra.Vendor
+		wantServers int
+		wantPort    uint16
+	}{
+		{ondatra.ARISTA, 1, 6030},
+		{ondatra.CISCO, 1, 57400},
+		{ondatra.JUNIPER, 1, 32767},
+		{ondatra.CIENA, 0, 0},
+	} {
+		p := Template(c.vendor, "dut1")
+		if p.Hostname != "dut1" || p.DefaultNetworkInstance != "DEFAULT" {
+			t.Errorf("Template(%v) got hostname %q and network instance %q, want dut1 and DEFAULT", c.vendor, p.Hostname, p.DefaultNetworkInstance)
+		}
+		if len(p.GRPCServers) != c.wantServers {
+			t.Errorf("Template(%v) got %d gRPC servers, want %d", c.vendor, len(p.GRPCServers), c.wantServers)
+			continue
+		}
+		if c.wantServers > 0 && p.GRPCServers[0].Port != c.wantPort {
+			t.Errorf("Template(%v) got port %d, want %d", c.vendor, p.GRPCServers[0].Port, c.wantPort)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p := Template(ondatra.ARISTA, "dut1")
+	for _, c := range []struct {
+		desc     string
+		modify   func(d *telemetry.Device)
+		wantErrs int
+	}{
+		{"baseline", func(d *telemetry.Device) {}, 0},
+		{"hostname", func(d *telemetry.Device) { d.GetSystem().Hostname = ygot.String("other") }, 1},
+		{"gRPC server missing", func(d *telemetry.Device) { d.GetSystem().DeleteGrpcServer("DEFAULT") }, 1},
+		{"gRPC server disabled", func(d *telemetry.Device) { d.GetSystem().GetGrpcServer("DEFAULT").Enable = ygot.Bool(false) }, 1},
+		{"gRPC server port and service", func(d *telemetry.Device) {
+			gs := d.GetSystem().GetGrpcServer("DEFAULT")
+			gs.Port = ygot.Uint16(9339)
+			gs.Services = []telemetry.E_SystemGrpc_GRPC_SERVICE{telemetry.SystemGrpc_GRPC_SERVICE_GNMI}
+		}, 2},
+		{"network instance missing", func(d *telemetry.Device) { d.DeleteNetworkInstance("DEFAULT") }, 1},
+		{"network instance type", func(d *telemetry.Device) {
+			d.GetNetworkInstance("DEFAULT").Type = telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L3VRF
+		}, 1},
+		{"more config", func(d *telemetry.Device) { d.GetOrCreateInterface("eth0").Mtu = ygot.Uint16(9000) }, 0},
+	} {
+		d := p.Config()
+		c.modify(d)
+		if errs := p.Check(d); len(errs) != c.wantErrs {
+			t.Errorf("%s: Check() got errors %v, want %d", c.desc, errs, c.wantErrs)
+		}
+	}
+}
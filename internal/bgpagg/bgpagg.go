@@ -0,0 +1,281 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bgpagg provides helpers to configure BGP route aggregation with
+// local aggregates, and to verify the aggregates and the suppression of
+// their contributing routes in the routes received by the ATE and in the
+// local RIB of the DUT.
+//
+// OpenConfig models the local aggregates and their redistribution into
+// BGP, but neither the summary-only option, which suppresses the
+// advertisement of the contributing routes, nor the as-set option, which
+// gathers the ASes of their AS paths into an AS_SET in the AS path of the
+// aggregate.  These are expected to be configured out of band, see
+// -deviation_bgp_aggregate_options.
+//
+// Usage:
+//
+//	aggs := []*bgpagg.Aggregate{{Prefix: "198.51.100.0/24"}, {Prefix: "203.0.113.0/24", SummaryOnly: true, ASSet: true}}
+//	bgpagg.Require(t, aggs...)
+//	err := bgpagg.Configure(ni, aggs...)
+//	...
+//	want, suppressed, err := bgpagg.Expect(aggs, routes)
+//	got, err := bgpagg.AwaitReceived(t, otg, peer, want, suppressed, timeout)
+package bgpagg
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	otg "github.com/openconfig/ondatra/otg"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+	otgtelemetry "github.com/openconfig/ondatra/telemetry/otg"
+)
+
+// Name is the name of the local aggregate protocol of the DUT.
+const Name = "AGGREGATE"
+
+// Aggregate is a local aggregate of the DUT advertised by BGP.
+type Aggregate struct {
+	Prefix string
+	// SummaryOnly suppresses the advertisement of the routes more
+	// specific than the aggregate.
+	SummaryOnly bool
+	// ASSet makes the AS path of the aggregate an AS_SET of the ASes of
+	// the AS paths of the contributing routes.
+	ASSet bool
+}
+
+func (a *Aggregate) String() string {
+	s := a.Prefix
+	if a.SummaryOnly {
+		s += " summary-only"
+	}
+	if a.ASSet {
+		s += " as-set"
+	}
+	return s
+}
+
+// Contains returns whether the prefix is more specific than the
+// aggregate, contributing to it.
+func (a *Aggregate) Contains(prefix string) (bool, error) {
+	_, agg, err := net.ParseCIDR(a.Prefix)
+	if err != nil {
+		return false, fmt.Errorf("aggregate %q: %w", a.Prefix, err)
+	}
+	_, n, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return false, fmt.Errorf("route %q: %w", prefix, err)
+	}
+	aggLen, _ := agg.Mask.Size()
+	nLen, _ := n.Mask.Size()
+	return agg.Contains(n.IP) && nLen > aggLen && len(agg.IP) == len(n.IP), nil
+}
+
+// Configure adds the aggregates to the local aggregates of the network
+// instance, discarding the traffic without a more specific route, and
+// redistributes the IPv4 local aggregates into BGP.
+func Configure(ni *telemetry.NetworkInstance, aggs ...*Aggregate) error {
+	p := ni.GetOrCreateProtocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_LOCAL_AGGREGATE, Name)
+	for _, a := range aggs {
+		ip, _, err := net.ParseCIDR(a.Prefix)
+		if err != nil || ip.To4() == nil {
+			return fmt.Errorf("aggregate %q is not an IPv4 prefix", a.Prefix)
+		}
+		p.GetOrCreateAggregate(a.Prefix).Discard = ygot.Bool(true)
+	}
+	ni.GetOrCreateTableConnection(
+		telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_LOCAL_AGGREGATE,
+		telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP,
+		telemetry.Types_ADDRESS_FAMILY_IPV4)
+	return nil
+}
+
+// Require skips the test unless the DUT is configured with the options of
+// the aggregates, if any, and logs the aggregates it should be configured
+// with.
+func Require(t testing.TB, aggs ...*Aggregate) {
+	t.Helper()
+	var opts []string
+	for _, a := range aggs {
+		if a.SummaryOnly || a.ASSet {
+			opts = append(opts, a.String())
+		}
+	}
+	if len(opts) == 0 {
+		return
+	}
+	if !*deviations.BGPAggregateOptions {
+		t.Skip("BGP aggregate options are not configured, see -deviation_bgp_aggregate_options")
+	}
+	t.Logf("BGP aggregate options: %s", strings.Join(opts, ", "))
+}
+
+// Expect returns the prefixes a BGP neighbor of the DUT should receive,
+// given the routes of the DUT: the aggregates and the routes not
+// suppressed by a summary-only aggregate, and the suppressed routes.
+func Expect(aggs []*Aggregate, routes []string) (want, suppressed []string, err error) {
+	for _, a := range aggs {
+		want = append(want, a.Prefix)
+	}
+	for _, r := range routes {
+		suppress := false
+		for _, a := range aggs {
+			ok, err := a.Contains(r)
+			if err != nil {
+				return nil, nil, err
+			}
+			suppress = suppress || ok && a.SummaryOnly
+		}
+		if suppress {
+			suppressed = append(suppressed, r)
+		} else {
+			want = append(want, r)
+		}
+	}
+	return want, suppressed, nil
+}
+
+// Check returns an error for each prefix of want missing from the
+// prefixes got, and for each suppressed prefix found in them.
+func Check(got map[string]bool, want, suppressed []string) []error {
+	var errs []error
+	for _, p := range want {
+		if !got[p] {
+			errs = append(errs, fmt.Errorf("%s not received", p))
+		}
+	}
+	for _, p := range suppressed {
+		if got[p] {
+			errs = append(errs, fmt.Errorf("%s received, want it suppressed", p))
+		}
+	}
+	return errs
+}
+
+// ASSet returns the ASes of the AS_SET segments of the AS path of a route
+// received by the ATE, sorted.
+func ASSet(path []*otgtelemetry.BgpPeer_UnicastIpv4Prefix_AsPath) []uint32 {
+	var set []uint32
+	for _, seg := range path {
+		if seg.GetSegmentType() == otgtelemetry.State_SegmentType_AS_SET {
+			set = append(set, seg.AsNumbers...)
+		}
+	}
+	sort.Slice(set, func(i, j int) bool { return set[i] < set[j] })
+	return set
+}
+
+// CheckASSet returns an error unless the AS_SET of the AS path has each
+// AS of want.
+func CheckASSet(path []*otgtelemetry.BgpPeer_UnicastIpv4Prefix_AsPath, want []uint32) error {
+	set := ASSet(path)
+	have := map[uint32]bool{}
+	for _, as := range set {
+		have[as] = true
+	}
+	for _, as := range want {
+		if !have[as] {
+			return fmt.Errorf("AS_SET %v does not have AS %d, want %v", set, as, want)
+		}
+	}
+	return nil
+}
+
+// Received returns the routes received by the ATE, keyed by prefix.
+func Received(routes []*otgtelemetry.BgpPeer_UnicastIpv4Prefix) map[string]*otgtelemetry.BgpPeer_UnicastIpv4Prefix {
+	m := map[string]*otgtelemetry.BgpPeer_UnicastIpv4Prefix{}
+	for _, r := range routes {
+		m[fmt.Sprintf("%s/%d", r.GetAddress(), r.GetPrefixLength())] = r
+	}
+	return m
+}
+
+// prefixes returns the set of the keys of the routes.
+func prefixes(routes map[string]*otgtelemetry.BgpPeer_UnicastIpv4Prefix) map[string]bool {
+	m := map[string]bool{}
+	for p := range routes {
+		m[p] = true
+	}
+	return m
+}
+
+// LocRib returns the prefixes of the IPv4 unicast local RIB of the DUT.
+func LocRib(rib *telemetry.NetworkInstance_Protocol_Bgp_Rib_AfiSafi_Ipv4Unicast_LocRib) map[string]bool {
+	m := map[string]bool{}
+	for _, r := range rib.Route {
+		m[r.GetPrefix()] = true
+	}
+	return m
+}
+
+// pollInterval is how often the routes are read while awaiting them.
+var pollInterval = 5 * time.Second
+
+// await calls read every pollInterval until the prefixes it returns have
+// all the prefixes of want and none of suppressed, and returns the errors
+// of the last check if they do not within the timeout.
+func await(timeout time.Duration, want, suppressed []string, read func() map[string]bool) []error {
+	for deadline := time.Now().Add(timeout); ; time.Sleep(pollInterval) {
+		errs := Check(read(), want, suppressed)
+		if len(errs) == 0 || time.Now().After(deadline) {
+			return errs
+		}
+	}
+}
+
+// AwaitReceived waits for the BGP peer of the ATE to receive the prefixes
+// of want and not the suppressed ones, and returns the routes it received
+// with an error for each prefix wrongly received or not, if it does not
+// within the timeout.
+func AwaitReceived(t testing.TB, otg *otg.OTG, peer string, want, suppressed []string, timeout time.Duration) (map[string]*otgtelemetry.BgpPeer_UnicastIpv4Prefix, []error) {
+	t.Helper()
+	var routes map[string]*otgtelemetry.BgpPeer_UnicastIpv4Prefix
+	errs := await(timeout, want, suppressed, func() map[string]bool {
+		var rs []*otgtelemetry.BgpPeer_UnicastIpv4Prefix
+		for _, v := range otg.Telemetry().BgpPeer(peer).UnicastIpv4PrefixAny().Lookup(t) {
+			if v.IsPresent() {
+				rs = append(rs, v.Val(t))
+			}
+		}
+		routes = Received(rs)
+		return prefixes(routes)
+	})
+	return routes, errs
+}
+
+// AwaitLocRib waits for the IPv4 unicast local RIB of the DUT to have the
+// prefixes, and returns an error for each one missing if it does not
+// within the timeout.
+func AwaitLocRib(t testing.TB, dut *ondatra.DUTDevice, want []string, timeout time.Duration) []error {
+	t.Helper()
+	path := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Rib().
+		AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Ipv4Unicast().LocRib()
+	return await(timeout, want, nil, func() map[string]bool {
+		if v := path.Lookup(t); v.IsPresent() {
+			return LocRib(v.Val(t))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,192 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgpagg
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+	otgtelemetry "github.com/openconfig/ondatra/telemetry/otg"
+)
+
+func TestString(t *testing.T) {
+	for _, c := range []struct {
+		agg  *Aggregate
+		want string
+	}{
+		{&Aggregate{Prefix: "198.51.100.0/24"}, "198.51.100.0/24"},
+		{&Aggregate{Prefix: "198.51.100.0/24", SummaryOnly: true}, "198.51.100.0/24 summary-only"},
+		{&Aggregate{Prefix: "198.51.100.0/24", SummaryOnly: true, ASSet: true}, "198.51.100.0/24 summary-only as-set"},
+	} {
+		if got := c.agg.String(); got != c.want {
+			t.Errorf("String() got %q, want %q", got, c.want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	agg := &Aggregate{Prefix: "198.51.100.0/24"}
+	for _, c := range []struct {
+		prefix  string
+		want    bool
+		wantErr bool
+	}{
+		{prefix: "198.51.100.0/26", want: true},
+		{prefix: "198.51.100.192/26", want: true},
+		{prefix: "198.51.100.7/32", want: true},
+		{prefix: "198.51.100.0/24"},
+		{prefix: "198.51.0.0/16"},
+		{prefix: "203.0.113.0/26"},
+		{prefix: "2001:db8::/64"},
+		{prefix: "198.51.100.0", wantErr: true},
+	} {
+		got, err := agg.Contains(c.prefix)
+		if (err != nil) != c.wantErr {
+			t.Errorf("Contains(%q) got error %v, want error %t", c.prefix, err, c.wantErr)
+		}
+		if got != c.want {
+			t.Errorf("Contains(%q) got %t, want %t", c.prefix, got, c.want)
+		}
+	}
+	if _, err := (&Aggregate{Prefix: "bad"}).Contains("198.51.100.0/26"); err == nil {
+		t.Error("Contains() of an invalid aggregate got no error")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	ni := &telemetry.NetworkInstance{Name: ygot.String("DEFAULT")}
+	aggs := []*Aggregate{{Prefix: "198.51.100.0/24"}, {Prefix: "203.0.113.0/24", SummaryOnly: true}}
+	if err := Configure(ni, aggs...); err != nil {
+		t.Fatalf("Configure() got error %v", err)
+	}
+	p := ni.GetProtocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_LOCAL_AGGREGATE, Name)
+	if p == nil {
+		t.Fatalf("Configure() got protocols %v, want local aggregates %s", ni.Protocol, Name)
+	}
+	for _, a := range aggs {
+		if !p.GetAggregate(a.Prefix).GetDiscard() {
+			t.Errorf("Configure() got aggregate %s %v, want discard", a.Prefix, p.GetAggregate(a.Prefix))
+		}
+	}
+	if tc := ni.GetTableConnection(
+		telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_LOCAL_AGGREGATE,
+		telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP,
+		telemetry.Types_ADDRESS_FAMILY_IPV4); tc == nil {
+		t.Errorf("Configure() got table connections %v, want local aggregates to BGP", ni.TableConnection)
+	}
+	if err := ni.Validate(); err != nil {
+		t.Errorf("Configure() network instance does not validate: %v", err)
+	}
+
+	for _, prefix := range []string{"2001:db8::/32", "198.51.100.0"} {
+		if err := Configure(ni, &Aggregate{Prefix: prefix}); err == nil {
+			t.Errorf("Configure(%q) got no error", prefix)
+		}
+	}
+}
+
+func TestExpect(t *testing.T) {
+	aggs := []*Aggregate{
+		{Prefix: "198.51.100.0/24"},
+		{Prefix: "203.0.113.0/24", SummaryOnly: true, ASSet: true},
+	}
+	routes := []string{"198.51.100.0/26", "198.51.100.64/26", "203.0.113.0/26", "203.0.113.64/26", "192.0.2.128/25"}
+	want, suppressed, err := Expect(aggs, routes)
+	if err != nil {
+		t.Fatalf("Expect() got error %v", err)
+	}
+	wantWant := []string{"198.51.100.0/24", "203.0.113.0/24", "198.51.100.0/26", "198.51.100.64/26", "192.0.2.128/25"}
+	if diff := cmp.Diff(wantWant, want); diff != "" {
+		t.Errorf("Expect() want diff (-want +got):\n%s", diff)
+	}
+	wantSuppressed := []string{"203.0.113.0/26", "203.0.113.64/26"}
+	if diff := cmp.Diff(wantSuppressed, suppressed); diff != "" {
+		t.Errorf("Expect() suppressed diff (-want +got):\n%s", diff)
+	}
+
+	if _, _, err := Expect(aggs, []string{"203.0.113.0"}); err == nil {
+		t.Error("Expect() of an invalid route got no error")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	want := []string{"203.0.113.0/24", "198.51.100.0/26"}
+	suppressed := []string{"203.0.113.0/26"}
+	for _, c := range []struct {
+		desc    string
+		got     map[string]bool
+		wantErr int
+	}{
+		{"expected", map[string]bool{"203.0.113.0/24": true, "198.51.100.0/26": true}, 0},
+		{"missing aggregate", map[string]bool{"198.51.100.0/26": true}, 1},
+		{"not suppressed", map[string]bool{"203.0.113.0/24": true, "198.51.100.0/26": true, "203.0.113.0/26": true}, 1},
+		{"nothing", nil, 2},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			if errs := Check(c.got, want, suppressed); len(errs) != c.wantErr {
+				t.Errorf("Check() got errors %v, want %d", errs, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestASSet(t *testing.T) {
+	path := []*otgtelemetry.BgpPeer_UnicastIpv4Prefix_AsPath{
+		{SegmentType: otgtelemetry.State_SegmentType_AS_SEQUENCE, AsNumbers: []uint32{64496}},
+		{SegmentType: otgtelemetry.State_SegmentType_AS_SET, AsNumbers: []uint32{64498, 64497}},
+	}
+	if diff := cmp.Diff([]uint32{64497, 64498}, ASSet(path)); diff != "" {
+		t.Errorf("ASSet() diff (-want +got):\n%s", diff)
+	}
+	if err := CheckASSet(path, []uint32{64497, 64498}); err != nil {
+		t.Errorf("CheckASSet() got error %v", err)
+	}
+	if err := CheckASSet(path, []uint32{64497, 64499}); err == nil {
+		t.Error("CheckASSet() of a missing AS got no error")
+	}
+	if err := CheckASSet(path[:1], []uint32{64497}); err == nil {
+		t.Error("CheckASSet() without an AS_SET got no error")
+	}
+}
+
+func TestReceived(t *testing.T) {
+	routes := []*otgtelemetry.BgpPeer_UnicastIpv4Prefix{
+		{Address: ygot.String("203.0.113.0"), PrefixLength: ygot.Uint32(24)},
+		{Address: ygot.String("198.51.100.64"), PrefixLength: ygot.Uint32(26)},
+	}
+	got := Received(routes)
+	if len(got) != 2 || got["203.0.113.0/24"] != routes[0] || got["198.51.100.64/26"] != routes[1] {
+		t.Errorf("Received() got %v, want the routes keyed by prefix", got)
+	}
+	if diff := cmp.Diff(map[string]bool{"203.0.113.0/24": true, "198.51.100.64/26": true}, prefixes(got)); diff != "" {
+		t.Errorf("prefixes() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocRib(t *testing.T) {
+	rib := &telemetry.NetworkInstance_Protocol_Bgp_Rib_AfiSafi_Ipv4Unicast_LocRib{}
+	for _, p := range []string{"203.0.113.0/24", "203.0.113.0/26"} {
+		if _, err := rib.NewRoute(p, telemetry.UnionString("192.0.2.2"), 0); err != nil {
+			t.Fatalf("Cannot add route %s: %v", p, err)
+		}
+	}
+	want := map[string]bool{"203.0.113.0/24": true, "203.0.113.0/26": true}
+	if diff := cmp.Diff(want, LocRib(rib)); diff != "" {
+		t.Errorf("LocRib() diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bgpattr provides helpers to manipulate the AS path and MED of
+// BGP routes with routing policies, to read these attributes from the
+// RIBs of the DUT and the ATE, and to predict the resulting best path.
+//
+// The ATE does not report the AS path of the routes it receives, so the
+// AS path is read from the routes advertised by the DUT instead.
+package bgpattr
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/telemetry/networkinstance"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Prepend makes the statement actions prepend asn repeat times to the AS
+// path of the matched routes.
+func Prepend(a *telemetry.RoutingPolicy_PolicyDefinition_Statement_Actions, asn uint32, repeat uint8) {
+	p := a.GetOrCreateBgpActions().GetOrCreateSetAsPathPrepend()
+	p.Asn = ygot.Uint32(asn)
+	p.RepeatN = ygot.Uint8(repeat)
+}
+
+// SetMED makes the statement actions set the MED of the matched routes.
+func SetMED(a *telemetry.RoutingPolicy_PolicyDefinition_Statement_Actions, med uint32) {
+	a.GetOrCreateBgpActions().SetMed = telemetry.UnionUint32(med)
+}
+
+// AdjustMED makes the statement actions add delta to the MED of the
+// matched routes, or subtract it if delta is negative.
+func AdjustMED(a *telemetry.RoutingPolicy_PolicyDefinition_Statement_Actions, delta int32) {
+	a.GetOrCreateBgpActions().SetMed = telemetry.UnionString(fmt.Sprintf("%+d", delta))
+}
+
+// ASPath returns the AS numbers of the AS path segments in order.
+func ASPath(segs []*telemetry.NetworkInstance_Protocol_Bgp_Rib_AttrSet_AsSegment) []uint32 {
+	var path []uint32
+	for _, s := range segs {
+		path = append(path, s.Member...)
+	}
+	return path
+}
+
+// PathLength returns the length of the AS path used by the best path
+// selection, as defined by RFC 4271 section 9.1.2.2: an AS_SET counts
+// as one, and confederation segments are not counted.
+func PathLength(segs []*telemetry.NetworkInstance_Protocol_Bgp_Rib_AttrSet_AsSegment) int {
+	n := 0
+	for _, s := range segs {
+		switch s.Type {
+		case telemetry.BgpTypes_AsPathSegmentType_AS_SET:
+			n++
+		case telemetry.BgpTypes_AsPathSegmentType_AS_CONFED_SEQUENCE, telemetry.BgpTypes_AsPathSegmentType_AS_CONFED_SET:
+		default:
+			n += len(s.Member)
+		}
+	}
+	return n
+}
+
+// CheckPrepended checks that the AS path is the original AS path with
+// the local AS asn prepended repeat times.  The local AS may be prepended
+// once more, since the DUT may or may not include it in the AS path of
+// the routes it advertises.
+func CheckPrepended(path []uint32, asn uint32, repeat int, original []uint32) error {
+	for n := repeat; n <= repeat+1; n++ {
+		if len(path) != n+len(original) {
+			continue
+		}
+		ok := true
+		for i, as := range path {
+			want := asn
+			if i >= n {
+				want = original[i-n]
+			}
+			if as != want {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("AS path got %v, want %v with AS %d prepended %d or %d times", path, original, asn, repeat, repeat+1)
+}
+
+// Path is a BGP path considered by the best path selection.
+type Path struct {
+	Neighbor   string
+	NeighborAS uint32
+	LocalPref  uint32
+	ASPathLen  int
+	Origin     telemetry.E_BgpTypes_BgpOriginAttrType
+	MED        uint32
+}
+
+// Best returns the best of the paths, following the steps of RFC 4271
+// section 9.1.2.2 that depend on the path attributes: highest local
+// preference, shortest AS path, lowest origin, and lowest MED among the
+// paths from the same neighbor AS.  It returns nil when these steps do
+// not select a single path, as the remaining steps depend on the IGP
+// and on the router IDs.
+func Best(paths []*Path) *Path {
+	keep := func(better func(a, b *Path) bool) {
+		var best []*Path
+		for _, p := range paths {
+			switch {
+			case len(best) == 0:
+				best = append(best, p)
+			case better(p, best[0]):
+				best = []*Path{p}
+			case !better(best[0], p):
+				best = append(best, p)
+			}
+		}
+		paths = best
+	}
+	keep(func(a, b *Path) bool { return a.LocalPref > b.LocalPref })
+	keep(func(a, b *Path) bool { return a.ASPathLen < b.ASPathLen })
+	keep(func(a, b *Path) bool { return a.Origin < b.Origin })
+
+	// The MED is only compared between paths from the same neighbor AS,
+	// so a path is removed if another path from its AS has a lower MED.
+	var best []*Path
+	for _, p := range paths {
+		worse := false
+		for _, q := range paths {
+			if q.NeighborAS == p.NeighborAS && q.MED < p.MED {
+				worse = true
+				break
+			}
+		}
+		if !worse {
+			best = append(best, p)
+		}
+	}
+	if len(best) != 1 {
+		return nil
+	}
+	return best[0]
+}
+
+// awaitIndex waits for the attribute index of a route to be present and
+// returns it.
+func awaitIndex(t testing.TB, index interface {
+	Watch(testing.TB, time.Duration, func(*telemetry.QualifiedUint64) bool) *telemetry.Uint64Watcher
+}, timeout time.Duration) (uint64, bool) {
+	t.Helper()
+	v, ok := index.Watch(t, timeout, func(v *telemetry.QualifiedUint64) bool {
+		return v.IsPresent()
+	}).Await(t)
+	if !ok {
+		return 0, false
+	}
+	return v.Val(t), true
+}
+
+// Received are the attributes of a route received by the ATE.
+type Received struct {
+	NextHop   string
+	MED       uint32
+	HasMED    bool
+	LocalPref uint32
+	Origin    telemetry.E_BgpTypes_BgpOriginAttrType
+}
+
+// ReadATE waits for the ATE to receive the prefix from the BGP neighbor,
+// and returns the attributes of the route.  The instance is the name of
+// the ATE port, and protocol the name of the BGP protocol, which is the
+// local AS number of the ATE.
+func ReadATE(t testing.TB, ate *ondatra.ATEDevice, instance, protocol, neighbor, prefix string, timeout time.Duration) (*Received, error) {
+	t.Helper()
+	rib := ate.Telemetry().NetworkInstance(instance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, protocol).Bgp().Rib()
+	ip, _, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("bad prefix %q: %w", prefix, err)
+	}
+	var index uint64
+	var ok bool
+	if ip.To4() != nil {
+		index, ok = awaitIndex(t, rib.AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Ipv4Unicast().
+			Neighbor(neighbor).AdjRibInPre().Route(prefix, 0).AttrIndex(), timeout)
+	} else {
+		index, ok = awaitIndex(t, rib.AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV6_UNICAST).Ipv6Unicast().
+			Neighbor(neighbor).AdjRibInPre().Route(prefix, 0).AttrIndex(), timeout)
+	}
+	if !ok {
+		return nil, fmt.Errorf("ATE did not receive %s from %s within %v", prefix, neighbor, timeout)
+	}
+
+	// The ATE only reports leaves, so they are read one by one.
+	as := rib.AttrSet(index)
+	r := &Received{
+		NextHop: as.NextHop().Get(t),
+		Origin:  as.Origin().Get(t),
+	}
+	if med := as.Med().Lookup(t); med.IsPresent() {
+		r.MED, r.HasMED = med.Val(t), true
+	}
+	if lp := as.LocalPref().Lookup(t); lp.IsPresent() {
+		r.LocalPref = lp.Val(t)
+	}
+	return r, nil
+}
+
+// dutRIB returns the BGP RIB of the DUT.
+func dutRIB(dut *ondatra.DUTDevice) *networkinstance.NetworkInstance_Protocol_Bgp_RibPath {
+	return dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Rib()
+}
+
+// ReadAdvertised waits for the DUT to advertise the prefix to the BGP
+// neighbor, and returns the attributes of the advertised route.
+func ReadAdvertised(t testing.TB, dut *ondatra.DUTDevice, neighbor, prefix string, timeout time.Duration) (*telemetry.NetworkInstance_Protocol_Bgp_Rib_AttrSet, error) {
+	t.Helper()
+	rib := dutRIB(dut)
+	ip, _, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("bad prefix %q: %w", prefix, err)
+	}
+	var index uint64
+	var ok bool
+	if ip.To4() != nil {
+		index, ok = awaitIndex(t, rib.AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Ipv4Unicast().
+			Neighbor(neighbor).AdjRibOutPost().Route(prefix, 0).AttrIndex(), timeout)
+	} else {
+		index, ok = awaitIndex(t, rib.AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV6_UNICAST).Ipv6Unicast().
+			Neighbor(neighbor).AdjRibOutPost().Route(prefix, 0).AttrIndex(), timeout)
+	}
+	if !ok {
+		return nil, fmt.Errorf("DUT did not advertise %s to %s within %v", prefix, neighbor, timeout)
+	}
+	return rib.AttrSet(index).Get(t), nil
+}
+
+// IsBest returns whether the route to the prefix received by the DUT
+// from the BGP neighbor is its best path.
+func IsBest(t testing.TB, dut *ondatra.DUTDevice, neighbor, prefix string) (bool, error) {
+	t.Helper()
+	rib := dutRIB(dut)
+	ip, _, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return false, fmt.Errorf("bad prefix %q: %w", prefix, err)
+	}
+	var best *telemetry.QualifiedBool
+	if ip.To4() != nil {
+		best = rib.AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Ipv4Unicast().
+			Neighbor(neighbor).AdjRibInPost().Route(prefix, 0).BestPath().Lookup(t)
+	} else {
+		best = rib.AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV6_UNICAST).Ipv6Unicast().
+			Neighbor(neighbor).AdjRibInPost().Route(prefix, 0).BestPath().Lookup(t)
+	}
+	if !best.IsPresent() {
+		return false, fmt.Errorf("DUT has no route to %s from %s", prefix, neighbor)
+	}
+	return best.Val(t), nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgpattr
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestActions(t *testing.T) {
+	a := &telemetry.RoutingPolicy_PolicyDefinition_Statement_Actions{}
+	Prepend(a, 64500, 3)
+	SetMED(a, 100)
+	ba := a.GetBgpActions()
+	if got := ba.GetSetAsPathPrepend(); got.GetAsn() != 64500 || got.GetRepeatN() != 3 {
+		t.Errorf("AS path prepend got %d x %d, want 64500 x 3", got.GetAsn(), got.GetRepeatN())
+	}
+	if got, want := ba.SetMed, telemetry.UnionUint32(100); got != want {
+		t.Errorf("Set MED got %v, want %v", got, want)
+	}
+	for _, c := range []struct {
+		delta int32
+		want  telemetry.UnionString
+	}{{10, "+10"}, {-5, "-5"}} {
+		AdjustMED(a, c.delta)
+		if got := ba.SetMed; got != c.want {
+			t.Errorf("AdjustMED(%d) got %v, want %v", c.delta, got, c.want)
+		}
+	}
+}
+
+func TestPathLength(t *testing.T) {
+	segs := []*telemetry.NetworkInstance_Protocol_Bgp_Rib_AttrSet_AsSegment{
+		{Type: telemetry.BgpTypes_AsPathSegmentType_AS_CONFED_SEQUENCE, Member: []uint32{65001, 65002}},
+		{Type: telemetry.BgpTypes_AsPathSegmentType_AS_SEQ, Member: []uint32{64500, 64500, 64501}},
+		{Type: telemetry.BgpTypes_AsPathSegmentType_AS_SET, Member: []uint32{64510, 64511}},
+	}
+	if got, want := ASPath(segs), []uint32{65001, 65002, 64500, 64500, 64501, 64510, 64511}; !cmp.Equal(got, want) {
+		t.Errorf("ASPath got %v, want %v", got, want)
+	}
+	if got := PathLength(segs); got != 4 {
+		t.Errorf("PathLength got %d, want 4", got)
+	}
+}
+
+func TestCheckPrepended(t *testing.T) {
+	original := []uint32{64501}
+	for _, c := range []struct {
+		path    []uint32
+		wantErr bool
+	}{
+		{path: []uint32{64500, 64500, 64500, 64501}},
+		{path: []uint32{64500, 64500, 64500, 64500, 64501}},
+		{path: []uint32{64500, 64500, 64501}, wantErr: true},
+		{path: []uint32{64500, 64500, 64500, 64500, 64500, 64501}, wantErr: true},
+		{path: []uint32{64500, 64500, 64500, 64502}, wantErr: true},
+		{path: []uint32{64500, 64500, 64500}, wantErr: true},
+	} {
+		if err := CheckPrepended(c.path, 64500, 3, original); (err != nil) != c.wantErr {
+			t.Errorf("CheckPrepended(%v) got error %v, want error %v", c.path, err, c.wantErr)
+		}
+	}
+}
+
+func TestBest(t *testing.T) {
+	igp := telemetry.BgpTypes_BgpOriginAttrType_IGP
+	for _, c := range []struct {
+		desc  string
+		paths []*Path
+		want  string
+	}{{
+		desc: "local preference",
+		paths: []*Path{
+			{Neighbor: "a", NeighborAS: 1, LocalPref: 100, ASPathLen: 1, Origin: igp},
+			{Neighbor: "b", NeighborAS: 1, LocalPref: 200, ASPathLen: 5, Origin: igp},
+		},
+		want: "b",
+	}, {
+		desc: "AS path length",
+		paths: []*Path{
+			{Neighbor: "a", NeighborAS: 1, ASPathLen: 4, Origin: igp},
+			{Neighbor: "b", NeighborAS: 1, ASPathLen: 1, Origin: igp, MED: 100},
+		},
+		want: "b",
+	}, {
+		desc: "origin",
+		paths: []*Path{
+			{Neighbor: "a", NeighborAS: 1, ASPathLen: 1, Origin: telemetry.BgpTypes_BgpOriginAttrType_INCOMPLETE},
+			{Neighbor: "b", NeighborAS: 1, ASPathLen: 1, Origin: igp, MED: 100},
+		},
+		want: "b",
+	}, {
+		desc: "MED from same AS",
+		paths: []*Path{
+			{Neighbor: "a", NeighborAS: 1, ASPathLen: 1, Origin: igp, MED: 100},
+			{Neighbor: "b", NeighborAS: 1, ASPathLen: 1, Origin: igp, MED: 50},
+		},
+		want: "b",
+	}, {
+		desc: "MED from different AS",
+		paths: []*Path{
+			{Neighbor: "a", NeighborAS: 1, ASPathLen: 1, Origin: igp, MED: 100},
+			{Neighbor: "b", NeighborAS: 2, ASPathLen: 1, Origin: igp, MED: 50},
+		},
+	}, {
+		desc: "tie",
+		paths: []*Path{
+			{Neighbor: "a", NeighborAS: 1, ASPathLen: 1, Origin: igp},
+			{Neighbor: "b", NeighborAS: 1, ASPathLen: 1, Origin: igp},
+		},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			got := Best(c.paths)
+			switch {
+			case c.want == "" && got != nil:
+				t.Errorf("Best got %s, want none", got.Neighbor)
+			case c.want != "" && got == nil:
+				t.Errorf("Best got none, want %s", c.want)
+			case c.want != "" && got.Neighbor != c.want:
+				t.Errorf("Best got %s, want %s", got.Neighbor, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bgpauth provides helpers to configure the TCP authentication
+// and the TTL security (GTSM, RFC 5082) of BGP sessions, and to verify
+// whether the sessions establish.  Probes, TCP SYNs crafted by the ATE,
+// check at the packet level whether the DUT accepts the connections of a
+// neighbor with a given TTL and no MD5 signature.
+//
+// OpenConfig models the TCP MD5 password of a neighbor and the keychains
+// used by TCP-AO, but neither binding a keychain to a neighbor nor GTSM,
+// so these are expected to be configured out of band.
+//
+// A session rejected by the authentication or by GTSM never completes
+// its TCP connection, so it does not go past the ACTIVE state and no
+// NOTIFICATION is exchanged.
+package bgpauth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/keychain"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/telemetry/networkinstance"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// ConfigureMD5 sets the TCP MD5 password of the neighbor, or removes it
+// if the key is empty.
+func ConfigureMD5(nbr *telemetry.NetworkInstance_Protocol_Bgp_Neighbor, key string) {
+	if key == "" {
+		nbr.AuthPassword = nil
+		return
+	}
+	nbr.AuthPassword = ygot.String(key)
+}
+
+// Keychain returns a keychain with a single key, which is sent and
+// accepted at all times.
+func Keychain(name string, id uint64, algorithm telemetry.E_KeychainTypes_CRYPTO_TYPE, secret string) *telemetry.Keychain {
+	return keychain.New(name, keychain.Key{ID: id, Algorithm: algorithm, Secret: secret})
+}
+
+// MinTTL returns the minimum TTL that GTSM accepts from a peer the given
+// number of hops away.
+func MinTTL(hops uint8) (uint8, error) {
+	if hops == 0 {
+		return 0, fmt.Errorf("GTSM needs at least 1 hop")
+	}
+	return uint8(256 - int(hops)), nil
+}
+
+// Accepts returns whether GTSM configured for the given number of hops
+// accepts the packets received with the TTL.
+func Accepts(hops, ttl uint8) bool {
+	minTTL, err := MinTTL(hops)
+	return err == nil && ttl >= minTTL
+}
+
+// Connected returns whether the session state shows that the TCP
+// connection of the session was established.
+func Connected(state telemetry.E_Bgp_Neighbor_SessionState) bool {
+	switch state {
+	case telemetry.Bgp_Neighbor_SessionState_OPENSENT,
+		telemetry.Bgp_Neighbor_SessionState_OPENCONFIRM,
+		telemetry.Bgp_Neighbor_SessionState_ESTABLISHED:
+		return true
+	}
+	return false
+}
+
+// Reason is the state of a BGP session, and the last NOTIFICATION the
+// DUT received on it.
+type Reason struct {
+	State            telemetry.E_Bgp_Neighbor_SessionState
+	Code             telemetry.E_BgpTypes_BGP_ERROR_CODE
+	Subcode          telemetry.E_BgpTypes_BGP_ERROR_SUBCODE
+	NotificationTime uint64
+}
+
+func (r *Reason) String() string {
+	if r.NotificationTime == 0 {
+		return fmt.Sprintf("%v, no NOTIFICATION received", r.State)
+	}
+	return fmt.Sprintf("%v, last NOTIFICATION received %v/%v", r.State, r.Code, r.Subcode)
+}
+
+// neighbor returns the state of the BGP neighbor of the DUT.
+func neighbor(dut *ondatra.DUTDevice, addr string) *networkinstance.NetworkInstance_Protocol_Bgp_NeighborPath {
+	return dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Neighbor(addr)
+}
+
+// ReadReason returns the state of the session with the BGP neighbor.
+func ReadReason(t testing.TB, dut *ondatra.DUTDevice, addr string) *Reason {
+	t.Helper()
+	nbr := neighbor(dut, addr)
+	r := &Reason{State: nbr.SessionState().Get(t)}
+	rcvd := nbr.Messages().Received()
+	if nt := rcvd.LastNotificationTime().Lookup(t); nt.IsPresent() {
+		r.NotificationTime = nt.Val(t)
+		r.Code = rcvd.LastNotificationErrorCode().Get(t)
+		r.Subcode = rcvd.LastNotificationErrorSubcode().Get(t)
+	}
+	return r
+}
+
+// AwaitEstablished waits for the session with the BGP neighbor to be
+// established.
+func AwaitEstablished(t testing.TB, dut *ondatra.DUTDevice, addr string, timeout time.Duration) error {
+	t.Helper()
+	_, ok := neighbor(dut, addr).SessionState().Watch(t, timeout, func(v *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+		return v.IsPresent() && v.Val(t) == telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+	}).Await(t)
+	if !ok {
+		return fmt.Errorf("BGP session with %s is not established after %v: %v", addr, timeout, ReadReason(t, dut, addr))
+	}
+	return nil
+}
+
+// AwaitDown waits for the TCP connection of the session with the BGP
+// neighbor to be closed, as after changing its authentication.
+func AwaitDown(t testing.TB, dut *ondatra.DUTDevice, addr string, timeout time.Duration) error {
+	t.Helper()
+	_, ok := neighbor(dut, addr).SessionState().Watch(t, timeout, func(v *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+		return v.IsPresent() && !Connected(v.Val(t))
+	}).Await(t)
+	if !ok {
+		return fmt.Errorf("BGP session with %s is still connected after %v: %v", addr, timeout, ReadReason(t, dut, addr))
+	}
+	return nil
+}
+
+// CheckRejected checks that the TCP connection of the session with the
+// BGP neighbor is not established for the duration, as when the
+// authentication or GTSM reject the peer, and returns the final state of
+// the session.
+func CheckRejected(t testing.TB, dut *ondatra.DUTDevice, addr string, duration time.Duration) (*Reason, error) {
+	t.Helper()
+	before := ReadReason(t, dut, addr)
+	v, connected := neighbor(dut, addr).SessionState().Watch(t, duration, func(v *telemetry.QualifiedE_Bgp_Neighbor_SessionState) bool {
+		return v.IsPresent() && Connected(v.Val(t))
+	}).Await(t)
+	after := ReadReason(t, dut, addr)
+	if connected {
+		return after, fmt.Errorf("BGP session with %s got state %v, want no TCP connection", addr, v.Val(t))
+	}
+	if after.NotificationTime != before.NotificationTime {
+		return after, fmt.Errorf("BGP session with %s received NOTIFICATION %v/%v, want no TCP connection", addr, after.Code, after.Subcode)
+	}
+	return after, nil
+}
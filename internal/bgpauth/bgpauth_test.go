@@ -0,0 +1,135 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgpauth
+
+import (
+	"testing"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestConfigureMD5(t *testing.T) {
+	nbr := &telemetry.NetworkInstance_Protocol_Bgp_Neighbor{}
+	ConfigureMD5(nbr, "secret")
+	if got := nbr.GetAuthPassword(); got != "secret" {
+		t.Errorf("ConfigureMD5(secret) got password %q, want %q", got, "secret")
+	}
+	ConfigureMD5(nbr, "")
+	if nbr.AuthPassword != nil {
+		t.Errorf("ConfigureMD5(\"\") got password %q, want none", *nbr.AuthPassword)
+	}
+}
+
+func TestKeychain(t *testing.T) {
+	kc := Keychain("BGP-AO", 1, telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_SHA_1_96, "secret")
+	if got := kc.GetName(); got != "BGP-AO" {
+		t.Errorf("Keychain name got %q, want %q", got, "BGP-AO")
+	}
+	k := kc.GetKey(1)
+	if k == nil {
+		t.Fatalf("Keychain got keys %v, want key 1", kc.Key)
+	}
+	if k.CryptoAlgorithm != telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_SHA_1_96 {
+		t.Errorf("Keychain key algorithm got %v, want HMAC_SHA_1_96", k.CryptoAlgorithm)
+	}
+	if got := k.GetSecretKey(); got != "secret" {
+		t.Errorf("Keychain key secret got %q, want %q", got, "secret")
+	}
+	if !k.GetSendLifetime().GetSendAndReceive() {
+		t.Errorf("Keychain key lifetime got %v, want send-and-receive", k.GetSendLifetime())
+	}
+	if err := kc.Validate(); err != nil {
+		t.Errorf("Keychain does not validate: %v", err)
+	}
+}
+
+func TestMinTTL(t *testing.T) {
+	for _, c := range []struct {
+		hops    uint8
+		want    uint8
+		wantErr bool
+	}{
+		{hops: 0, wantErr: true},
+		{hops: 1, want: 255},
+		{hops: 2, want: 254},
+		{hops: 255, want: 1},
+	} {
+		got, err := MinTTL(c.hops)
+		if (err != nil) != c.wantErr {
+			t.Errorf("MinTTL(%d) got error %v, want error %v", c.hops, err, c.wantErr)
+		}
+		if got != c.want {
+			t.Errorf("MinTTL(%d) got %d, want %d", c.hops, got, c.want)
+		}
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	for _, c := range []struct {
+		hops, ttl uint8
+		want      bool
+	}{
+		{hops: 1, ttl: 255, want: true},
+		{hops: 1, ttl: 254, want: false},
+		{hops: 1, ttl: 64, want: false},
+		{hops: 2, ttl: 254, want: true},
+		{hops: 0, ttl: 255, want: false},
+	} {
+		if got := Accepts(c.hops, c.ttl); got != c.want {
+			t.Errorf("Accepts(%d, %d) got %v, want %v", c.hops, c.ttl, got, c.want)
+		}
+	}
+}
+
+func TestConnected(t *testing.T) {
+	want := map[telemetry.E_Bgp_Neighbor_SessionState]bool{
+		telemetry.Bgp_Neighbor_SessionState_UNSET:       false,
+		telemetry.Bgp_Neighbor_SessionState_IDLE:        false,
+		telemetry.Bgp_Neighbor_SessionState_CONNECT:     false,
+		telemetry.Bgp_Neighbor_SessionState_ACTIVE:      false,
+		telemetry.Bgp_Neighbor_SessionState_OPENSENT:    true,
+		telemetry.Bgp_Neighbor_SessionState_OPENCONFIRM: true,
+		telemetry.Bgp_Neighbor_SessionState_ESTABLISHED: true,
+	}
+	for state, want := range want {
+		if got := Connected(state); got != want {
+			t.Errorf("Connected(%v) got %v, want %v", state, got, want)
+		}
+	}
+}
+
+func TestReasonString(t *testing.T) {
+	for _, c := range []struct {
+		r    *Reason
+		want string
+	}{{
+		r:    &Reason{State: telemetry.Bgp_Neighbor_SessionState_ACTIVE},
+		want: telemetry.Bgp_Neighbor_SessionState_ACTIVE.String() + ", no NOTIFICATION received",
+	}, {
+		r: &Reason{
+			State:            telemetry.Bgp_Neighbor_SessionState_IDLE,
+			Code:             telemetry.BgpTypes_BGP_ERROR_CODE_CEASE,
+			Subcode:          telemetry.BgpTypes_BGP_ERROR_SUBCODE_ADMINISTRATIVE_RESET,
+			NotificationTime: 1,
+		},
+		want: telemetry.Bgp_Neighbor_SessionState_IDLE.String() + ", last NOTIFICATION received " +
+			telemetry.BgpTypes_BGP_ERROR_CODE_CEASE.String() + "/" +
+			telemetry.BgpTypes_BGP_ERROR_SUBCODE_ADMINISTRATIVE_RESET.String(),
+	}} {
+		if got := c.r.String(); got != c.want {
+			t.Errorf("Reason.String() got %q, want %q", got, c.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgpauth
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/capture"
+)
+
+// PortBGP is the TCP port of BGP.
+const PortBGP = 179
+
+// TCP flags of the probes and their replies.
+const (
+	flagSYN = 0x02
+	flagRST = 0x04
+	flagACK = 0x10
+)
+
+// Probe is a TCP SYN sent by the ATE to the BGP port of the DUT from the
+// address of a neighbor, with no BGP running on the ATE, to check at the
+// packet level whether the DUT accepts the connection.  The TTL of the
+// probe is set, unlike that of the BGP packets of the ATE, and it carries
+// no TCP options, so no MD5 signature.
+type Probe struct {
+	Name string
+	// SrcMAC is the MAC address of the ATE port, and DstMAC that of the
+	// DUT port.
+	SrcMAC, DstMAC string
+	// Src is the address of the neighbor, and Dst that of the DUT.
+	Src, Dst string
+	// SrcPort is the TCP port of the probe, telling its replies apart.
+	SrcPort uint16
+	TTL     uint8
+}
+
+// Validate returns an error if the addresses of the probe are invalid or
+// it has no TTL or source port.
+func (p *Probe) Validate() error {
+	for _, mac := range []string{p.SrcMAC, p.DstMAC} {
+		if _, err := net.ParseMAC(mac); err != nil {
+			return fmt.Errorf("probe %s: %w", p.Name, err)
+		}
+	}
+	for _, addr := range []string{p.Src, p.Dst} {
+		if ip := net.ParseIP(addr); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("probe %s: %q is not an IPv4 address", p.Name, addr)
+		}
+	}
+	if p.TTL == 0 || p.SrcPort == 0 {
+		return fmt.Errorf("probe %s: TTL %d and source port %d must not be 0", p.Name, p.TTL, p.SrcPort)
+	}
+	return nil
+}
+
+// AddFlow adds to the OTG configuration the flow of the probe, sent out
+// of the port at pps packets per second for the number of packets.  The
+// probes share their source port, so the DUT sees them as retransmitted
+// SYNs of one connection.
+func (p *Probe) AddFlow(config gosnappi.Config, port string, pps int64, packets int32) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	flow := config.Flows().Add().SetName(p.Name)
+	flow.Metrics().SetEnable(true)
+	flow.TxRx().Port().SetTxName(port)
+	flow.Rate().SetPps(pps)
+	flow.Duration().FixedPackets().SetPackets(packets)
+	eth := flow.Packet().Add().Ethernet()
+	eth.Src().SetValue(p.SrcMAC)
+	eth.Dst().SetValue(p.DstMAC)
+	ip := flow.Packet().Add().Ipv4()
+	ip.Src().SetValue(p.Src)
+	ip.Dst().SetValue(p.Dst)
+	ip.TimeToLive().SetValue(int32(p.TTL))
+	tcp := flow.Packet().Add().Tcp()
+	tcp.SrcPort().SetValue(int32(p.SrcPort))
+	tcp.DstPort().SetValue(PortBGP)
+	tcp.CtlSyn().SetValue(1)
+	return nil
+}
+
+// Replies is the number of replies of the DUT to a probe, by kind.
+type Replies struct {
+	// SYNACK is the number of SYN-ACKs, accepting the connection.
+	SYNACK int
+	// RST is the number of resets, refusing it.
+	RST int
+}
+
+// Accepted returns whether the DUT accepted the connection of the probe.
+// A probe rejected by GTSM or the authentication is dropped silently, or
+// reset.
+func (r Replies) Accepted() bool {
+	return r.SYNACK > 0
+}
+
+// Replies returns the replies of the DUT to the probe among the captured
+// packets.
+func (p *Probe) Replies(pkts []*capture.Packet) Replies {
+	src, dst := net.ParseIP(p.Dst), net.ParseIP(p.Src)
+	var r Replies
+	for _, pkt := range pkts {
+		if pkt.IP == nil || pkt.TCP == nil || !pkt.IP.Src.Equal(src) || !pkt.IP.Dst.Equal(dst) ||
+			pkt.TCP.SrcPort != PortBGP || pkt.TCP.DstPort != p.SrcPort {
+			continue
+		}
+		switch f := pkt.TCP.Flags; {
+		case f&flagRST != 0:
+			r.RST++
+		case f&(flagSYN|flagACK) == flagSYN|flagACK:
+			r.SYNACK++
+		}
+	}
+	return r
+}
+
+// Check returns an error if the DUT accepted the connection of the probe
+// and it should not, or the reverse, according to its replies.
+func (p *Probe) Check(r Replies, accept bool) error {
+	if got := r.Accepted(); got != accept {
+		return fmt.Errorf("probe %s with TTL %d: got connection accepted %t (%d SYN-ACKs, %d RSTs), want %t", p.Name, p.TTL, got, r.SYNACK, r.RST, accept)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgpauth
+
+import (
+	"net"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/capture"
+)
+
+func probe() *Probe {
+	return &Probe{
+		Name:    "gtsm",
+		SrcMAC:  "02:00:01:01:01:01",
+		DstMAC:  "02:00:00:00:00:01",
+		Src:     "192.0.2.2",
+		Dst:     "192.0.2.1",
+		SrcPort: 50000,
+		TTL:     255,
+	}
+}
+
+func TestProbeValidate(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		modify  func(p *Probe)
+		wantErr bool
+	}{
+		{"valid", func(p *Probe) {}, false},
+		{"invalid MAC", func(p *Probe) { p.DstMAC = "02:00" }, true},
+		{"IPv6 source", func(p *Probe) { p.Src = "2001:db8::2" }, true},
+		{"no TTL", func(p *Probe) { p.TTL = 0 }, true},
+		{"no source port", func(p *Probe) { p.SrcPort = 0 }, true},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			p := probe()
+			c.modify(p)
+			if err := p.Validate(); (err != nil) != c.wantErr {
+				t.Errorf("Validate() got error %v, want error %t", err, c.wantErr)
+			}
+		})
+	}
+}
+
+// reply returns a captured TCP packet of the flags, between the addresses
+// and ports.
+func reply(src, dst string, srcPort, dstPort uint16, flags uint8) *capture.Packet {
+	return &capture.Packet{
+		IP:  &capture.IP{Version: 4, Src: net.ParseIP(src), Dst: net.ParseIP(dst), Protocol: capture.ProtocolTCP},
+		TCP: &capture.TCP{SrcPort: srcPort, DstPort: dstPort, Flags: flags},
+	}
+}
+
+func TestProbeReplies(t *testing.T) {
+	p := probe()
+	for _, c := range []struct {
+		desc         string
+		pkts         []*capture.Packet
+		want         Replies
+		wantAccepted bool
+	}{{
+		desc: "none",
+	}, {
+		desc: "syn-acks",
+		pkts: []*capture.Packet{
+			reply("192.0.2.1", "192.0.2.2", PortBGP, 50000, flagSYN|flagACK),
+			reply("192.0.2.1", "192.0.2.2", PortBGP, 50000, flagSYN|flagACK),
+		},
+		want:         Replies{SYNACK: 2},
+		wantAccepted: true,
+	}, {
+		desc: "reset",
+		pkts: []*capture.Packet{
+			reply("192.0.2.1", "192.0.2.2", PortBGP, 50000, flagRST|flagACK),
+		},
+		want: Replies{RST: 1},
+	}, {
+		desc: "other packets",
+		pkts: []*capture.Packet{
+			// The SYN of the DUT connecting to the neighbor itself.
+			reply("192.0.2.1", "192.0.2.2", 60000, PortBGP, flagSYN),
+			// A reply to another probe.
+			reply("192.0.2.1", "192.0.2.2", PortBGP, 50001, flagSYN|flagACK),
+			// A reply from another address.
+			reply("192.0.2.5", "192.0.2.2", PortBGP, 50000, flagSYN|flagACK),
+			// A packet with no TCP header.
+			{IP: &capture.IP{Version: 4, Src: net.ParseIP("192.0.2.1"), Dst: net.ParseIP("192.0.2.2")}},
+			{},
+		},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			got := p.Replies(c.pkts)
+			if got != c.want {
+				t.Errorf("Replies() got %+v, want %+v", got, c.want)
+			}
+			if got.Accepted() != c.wantAccepted {
+				t.Errorf("Accepted() got %t, want %t", got.Accepted(), c.wantAccepted)
+			}
+			if err := p.Check(got, c.wantAccepted); err != nil {
+				t.Errorf("Check(%+v, %t) got error %v", got, c.wantAccepted, err)
+			}
+			if err := p.Check(got, !c.wantAccepted); err == nil {
+				t.Errorf("Check(%+v, %t) got no error", got, !c.wantAccepted)
+			}
+		})
+	}
+}
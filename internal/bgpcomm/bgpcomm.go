@@ -0,0 +1,638 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bgpcomm provides helpers to configure BGP community
+// manipulation in routing policies, and to verify the communities of the
+// routes received by the ATE.
+//
+// Communities are written as in OpenConfig: a standard community is
+// either "AS:value" or a well-known community name such as "NO_EXPORT",
+// an extended community is "route-target:AS:value",
+// "route-origin:AS:value" or "link-bandwidth:AS:bandwidth", and a large
+// community is "global:local1:local2".
+//
+// The ATE only reports the standard communities of the routes it
+// receives, so the extended communities are verified on the routes
+// advertised by the DUT instead.  The OpenConfig model used by this tree
+// has no large community sets or actions, and the ATE can neither send
+// nor report large communities: they are configured out of band on the
+// DUT, and evaluated here to derive the standard communities the DUT
+// tags the routes with.
+package bgpcomm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// wellKnown maps the names of the well-known communities to their
+// values, as defined by RFC 1997 and RFC 3765.
+var wellKnown = map[string]struct {
+	value uint32
+	enum  telemetry.E_BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY
+}{
+	"NO_EXPORT":           {0xFFFFFF01, telemetry.BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY_NO_EXPORT},
+	"NO_ADVERTISE":        {0xFFFFFF02, telemetry.BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY_NO_ADVERTISE},
+	"NO_EXPORT_SUBCONFED": {0xFFFFFF03, telemetry.BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY_NO_EXPORT_SUBCONFED},
+	"NOPEER":              {0xFFFFFF04, telemetry.BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY_NOPEER},
+}
+
+// wellKnownName returns the canonical name of a well-known community,
+// accepting lower case and dashes as written by some implementations.
+func wellKnownName(s string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), "-", "_"))
+}
+
+// parsePair parses "high:low" where high and low are at most bits wide.
+func parsePair(s string, hbits, lbits int) (uint64, uint64, error) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return 0, 0, fmt.Errorf("missing colon in %q", s)
+	}
+	h, err := strconv.ParseUint(s[:i], 10, hbits)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad high order value in %q: %w", s, err)
+	}
+	l, err := strconv.ParseUint(s[i+1:], 10, lbits)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad low order value in %q: %w", s, err)
+	}
+	return h, l, nil
+}
+
+// ParseStandard returns the 32-bit value of a standard community written
+// as "AS:value", as a well-known community name, or as a decimal number.
+func ParseStandard(s string) (uint32, error) {
+	if wk, ok := wellKnown[wellKnownName(s)]; ok {
+		return wk.value, nil
+	}
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, ":") {
+		v, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("bad standard community %q: %w", s, err)
+		}
+		return uint32(v), nil
+	}
+	h, l, err := parsePair(s, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("bad standard community: %w", err)
+	}
+	return uint32(h<<16 | l), nil
+}
+
+// FormatStandard returns a standard community value as "AS:value", or
+// its name if it is a well-known community.
+func FormatStandard(v uint32) string {
+	for name, wk := range wellKnown {
+		if wk.value == v {
+			return name
+		}
+	}
+	return fmt.Sprintf("%d:%d", v>>16, v&0xFFFF)
+}
+
+// bandwidthUnits are the suffixes of bandwidths in bits per second.
+var bandwidthUnits = []struct {
+	suffix string
+	scale  uint64
+}{{"G", 1e9}, {"M", 1e6}, {"K", 1e3}}
+
+// ParseBandwidth parses a bandwidth in bits per second, written as a
+// number optionally followed by K, M or G.
+func ParseBandwidth(s string) (uint64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	scale := uint64(1)
+	for _, u := range bandwidthUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, scale = strings.TrimSuffix(s, u.suffix), u.scale
+			break
+		}
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad bandwidth %q: %w", s, err)
+	}
+	if v > math.MaxUint64/scale {
+		return 0, fmt.Errorf("bandwidth %q out of range", s)
+	}
+	return v * scale, nil
+}
+
+// FormatBandwidth returns a bandwidth in bits per second with the
+// largest suffix that represents it exactly.
+func FormatBandwidth(bps uint64) string {
+	for _, u := range bandwidthUnits {
+		if bps > 0 && bps%u.scale == 0 {
+			return fmt.Sprintf("%d%s", bps/u.scale, u.suffix)
+		}
+	}
+	return strconv.FormatUint(bps, 10)
+}
+
+// LinkBandwidth returns the link bandwidth extended community of a
+// bandwidth in bits per second, which some implementations use to
+// weight BGP multipath.
+func LinkBandwidth(as uint16, bps uint64) string {
+	return fmt.Sprintf("link-bandwidth:%d:%s", as, FormatBandwidth(bps))
+}
+
+// ParseExtended checks an extended community written as
+// "route-target:AS:value" or "route-origin:AS:value", where AS is a
+// 2-byte or 4-byte AS number or an IPv4 address, or as
+// "link-bandwidth:AS:bandwidth", where AS is a 2-byte AS number, and
+// returns it in its canonical form.
+func ParseExtended(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return "", fmt.Errorf("missing type in extended community %q", s)
+	}
+	typ, rest := strings.ToLower(s[:i]), s[i+1:]
+	switch typ {
+	case "route-target", "route-origin":
+	case "link-bandwidth":
+		j := strings.Index(rest, ":")
+		if j < 0 {
+			return "", fmt.Errorf("missing colon in extended community %q", s)
+		}
+		as, err := strconv.ParseUint(rest[:j], 10, 16)
+		if err != nil {
+			return "", fmt.Errorf("bad AS in extended community %q: %w", s, err)
+		}
+		bps, err := ParseBandwidth(rest[j+1:])
+		if err != nil {
+			return "", fmt.Errorf("bad extended community %q: %w", s, err)
+		}
+		return LinkBandwidth(uint16(as), bps), nil
+	default:
+		return "", fmt.Errorf("unsupported extended community type %q in %q", typ, s)
+	}
+	j := strings.LastIndex(rest, ":")
+	if j < 0 {
+		return "", fmt.Errorf("missing colon in extended community %q", s)
+	}
+	admin, local := rest[:j], rest[j+1:]
+	var lbits int
+	if ip := net.ParseIP(admin); ip != nil {
+		if ip.To4() == nil {
+			return "", fmt.Errorf("bad IPv4 administrator in extended community %q", s)
+		}
+		lbits = 16
+	} else {
+		as, err := strconv.ParseUint(admin, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("bad administrator in extended community %q: %w", s, err)
+		}
+		lbits = 32
+		if as > 0xFFFF {
+			lbits = 16
+		}
+	}
+	if _, err := strconv.ParseUint(local, 10, lbits); err != nil {
+		return "", fmt.Errorf("bad local administrator in extended community %q: %w", s, err)
+	}
+	return typ + ":" + rest, nil
+}
+
+// Large is a large community defined by RFC 8092.
+type Large struct {
+	Global, Local1, Local2 uint32
+}
+
+// String returns the large community as "global:local1:local2".
+func (l Large) String() string {
+	return fmt.Sprintf("%d:%d:%d", l.Global, l.Local1, l.Local2)
+}
+
+// ParseLarge parses a large community written as "global:local1:local2".
+func ParseLarge(s string) (Large, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return Large{}, fmt.Errorf("large community %q does not have 3 parts", s)
+	}
+	var vs [3]uint32
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return Large{}, fmt.Errorf("bad large community %q: %w", s, err)
+		}
+		vs[i] = uint32(v)
+	}
+	return Large{vs[0], vs[1], vs[2]}, nil
+}
+
+// LargeSet is a large community set, which is configured out of band
+// since it is not modeled in OpenConfig.
+type LargeSet struct {
+	Name        string
+	Match       telemetry.E_PolicyTypes_MatchSetOptionsType
+	Communities []Large
+}
+
+func (s *LargeSet) String() string {
+	var cs []string
+	for _, c := range s.Communities {
+		cs = append(cs, c.String())
+	}
+	return fmt.Sprintf("%s match %s [%s]", s.Name, s.Match, strings.Join(cs, " "))
+}
+
+// hasLarge returns whether the large community c is in cs.
+func hasLarge(cs []Large, c Large) bool {
+	for _, l := range cs {
+		if l == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns whether a route with the large communities got is
+// matched by the set.
+func (s *LargeSet) Matches(got []Large) (bool, error) {
+	n := 0
+	for _, c := range s.Communities {
+		if hasLarge(got, c) {
+			n++
+		}
+	}
+	switch s.Match {
+	case telemetry.PolicyTypes_MatchSetOptionsType_ANY:
+		return n > 0, nil
+	case telemetry.PolicyTypes_MatchSetOptionsType_ALL:
+		return n == len(s.Communities), nil
+	case telemetry.PolicyTypes_MatchSetOptionsType_INVERT:
+		return n == 0, nil
+	}
+	return false, fmt.Errorf("large community set %s: unsupported match option %v", s.Name, s.Match)
+}
+
+// LargeAction adds, removes or replaces the large communities of the
+// routes, which is configured out of band since it is not modeled in
+// OpenConfig.
+type LargeAction struct {
+	Option      telemetry.E_BgpPolicy_BgpSetCommunityOptionType
+	Communities []Large
+}
+
+func (a *LargeAction) String() string {
+	var cs []string
+	for _, c := range a.Communities {
+		cs = append(cs, c.String())
+	}
+	return fmt.Sprintf("%s large communities [%s]", a.Option, strings.Join(cs, " "))
+}
+
+// Apply returns the large communities of a route with the large
+// communities got after the action.
+func (a *LargeAction) Apply(got []Large) ([]Large, error) {
+	var cs []Large
+	switch a.Option {
+	case telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD:
+		cs = append(cs, got...)
+		for _, c := range a.Communities {
+			if !hasLarge(cs, c) {
+				cs = append(cs, c)
+			}
+		}
+	case telemetry.BgpPolicy_BgpSetCommunityOptionType_REMOVE:
+		for _, c := range got {
+			if !hasLarge(a.Communities, c) {
+				cs = append(cs, c)
+			}
+		}
+	case telemetry.BgpPolicy_BgpSetCommunityOptionType_REPLACE:
+		cs = append(cs, a.Communities...)
+	default:
+		return nil, fmt.Errorf("unsupported large community option %v", a.Option)
+	}
+	return cs, nil
+}
+
+// RequireLarge skips the test unless the DUT is configured out of band
+// with large community sets and actions, and logs the configuration it
+// should have.
+func RequireLarge(t testing.TB, config ...string) {
+	t.Helper()
+	if !*deviations.BGPLargeCommunities {
+		t.Skip("BGP large communities are not configured, see -deviation_bgp_large_communities")
+	}
+	for _, c := range config {
+		t.Logf("BGP large communities: %s", c)
+	}
+}
+
+// standardMember returns the well-known community enum of a standard
+// community, or its canonical "AS:value" form if it is not well-known.
+func standardMember(s string) (telemetry.E_BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY, string, error) {
+	if wk, ok := wellKnown[wellKnownName(s)]; ok {
+		return wk.enum, "", nil
+	}
+	v, err := ParseStandard(s)
+	if err != nil {
+		return 0, "", err
+	}
+	return 0, FormatStandard(v), nil
+}
+
+// ConfigureSet configures a community set of standard communities in
+// the routing policy.
+func ConfigureSet(rp *telemetry.RoutingPolicy, name string, match telemetry.E_PolicyTypes_MatchSetOptionsType, communities ...string) error {
+	cs := rp.GetOrCreateDefinedSets().GetOrCreateBgpDefinedSets().GetOrCreateCommunitySet(name)
+	cs.MatchSetOptions = match
+	cs.CommunityMember = nil
+	for _, c := range communities {
+		wk, str, err := standardMember(c)
+		switch {
+		case err != nil:
+			return fmt.Errorf("community set %s: %w", name, err)
+		case wk != 0:
+			cs.CommunityMember = append(cs.CommunityMember, wk)
+		default:
+			cs.CommunityMember = append(cs.CommunityMember, telemetry.UnionString(str))
+		}
+	}
+	return nil
+}
+
+// ConfigureExtSet configures a community set of extended communities in
+// the routing policy.
+func ConfigureExtSet(rp *telemetry.RoutingPolicy, name string, match telemetry.E_PolicyTypes_MatchSetOptionsType, communities ...string) error {
+	cs := rp.GetOrCreateDefinedSets().GetOrCreateBgpDefinedSets().GetOrCreateExtCommunitySet(name)
+	cs.MatchSetOptions = match
+	cs.ExtCommunityMember = nil
+	for _, c := range communities {
+		ext, err := ParseExtended(c)
+		if err != nil {
+			return fmt.Errorf("extended community set %s: %w", name, err)
+		}
+		cs.ExtCommunityMember = append(cs.ExtCommunityMember, ext)
+	}
+	return nil
+}
+
+// SetCommunities adds, removes or replaces the standard communities of
+// the routes matched by the statement actions.
+func SetCommunities(a *telemetry.RoutingPolicy_PolicyDefinition_Statement_Actions, option telemetry.E_BgpPolicy_BgpSetCommunityOptionType, communities ...string) error {
+	sc := a.GetOrCreateBgpActions().GetOrCreateSetCommunity()
+	sc.Method = telemetry.SetCommunity_Method_INLINE
+	sc.Options = option
+	inline := sc.GetOrCreateInline()
+	inline.Communities = nil
+	for _, c := range communities {
+		wk, str, err := standardMember(c)
+		switch {
+		case err != nil:
+			return err
+		case wk != 0:
+			inline.Communities = append(inline.Communities, wk)
+		default:
+			inline.Communities = append(inline.Communities, telemetry.UnionString(str))
+		}
+	}
+	return nil
+}
+
+// SetExtCommunities adds, removes or replaces the extended communities
+// of the routes matched by the statement actions.
+func SetExtCommunities(a *telemetry.RoutingPolicy_PolicyDefinition_Statement_Actions, option telemetry.E_BgpPolicy_BgpSetCommunityOptionType, communities ...string) error {
+	sc := a.GetOrCreateBgpActions().GetOrCreateSetExtCommunity()
+	sc.Method = telemetry.SetCommunity_Method_INLINE
+	sc.Options = option
+	inline := sc.GetOrCreateInline()
+	inline.Communities = nil
+	for _, c := range communities {
+		ext, err := ParseExtended(c)
+		if err != nil {
+			return err
+		}
+		inline.Communities = append(inline.Communities, telemetry.UnionString(ext))
+	}
+	return nil
+}
+
+// Standard returns the values of the standard communities reported in a
+// BGP RIB.
+func Standard(vals []telemetry.NetworkInstance_Protocol_Bgp_Rib_Community_Community_Union) ([]uint32, error) {
+	var got []uint32
+	for _, val := range vals {
+		switch v := val.(type) {
+		case telemetry.UnionUint32:
+			got = append(got, uint32(v))
+		case telemetry.UnionString:
+			c, err := ParseStandard(string(v))
+			if err != nil {
+				return nil, err
+			}
+			got = append(got, c)
+		case telemetry.E_BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY:
+			c, err := ParseStandard(v.String())
+			if err != nil {
+				return nil, err
+			}
+			got = append(got, c)
+		default:
+			return nil, fmt.Errorf("unsupported community type %T", val)
+		}
+	}
+	return got, nil
+}
+
+// decodeExtended returns the string form of a route target, route
+// origin or link bandwidth extended community in its 8-byte wire format.
+func decodeExtended(b []byte) (string, error) {
+	if len(b) != 8 {
+		return "", fmt.Errorf("extended community %x is %d bytes, want 8", b, len(b))
+	}
+	var typ string
+	switch b[1] {
+	case 0x02:
+		typ = "route-target"
+	case 0x03:
+		typ = "route-origin"
+	case 0x04:
+		if b[0]&^0x40 != 0x00 {
+			return "", fmt.Errorf("unsupported link bandwidth extended community type %#x in %x", b[0], b)
+		}
+		// The bandwidth is an IEEE float of bytes per second.
+		bytesPerSec := math.Float32frombits(binary.BigEndian.Uint32(b[4:]))
+		return LinkBandwidth(binary.BigEndian.Uint16(b[2:]), uint64(math.Round(float64(bytesPerSec)*8))), nil
+	default:
+		return "", fmt.Errorf("unsupported extended community subtype %#x in %x", b[1], b)
+	}
+	switch b[0] &^ 0x40 { // Ignore the non-transitive bit.
+	case 0x00:
+		return fmt.Sprintf("%s:%d:%d", typ, binary.BigEndian.Uint16(b[2:]), binary.BigEndian.Uint32(b[4:])), nil
+	case 0x01:
+		return fmt.Sprintf("%s:%v:%d", typ, net.IP(b[2:6]), binary.BigEndian.Uint16(b[6:])), nil
+	case 0x02:
+		return fmt.Sprintf("%s:%d:%d", typ, binary.BigEndian.Uint32(b[2:]), binary.BigEndian.Uint16(b[6:])), nil
+	}
+	return "", fmt.Errorf("unsupported extended community type %#x in %x", b[0], b)
+}
+
+// Extended returns the extended communities reported in a BGP RIB in
+// their canonical string form.
+func Extended(vals []telemetry.NetworkInstance_Protocol_Bgp_Rib_ExtCommunity_ExtCommunity_Union) ([]string, error) {
+	var got []string
+	for _, val := range vals {
+		var ext string
+		var err error
+		switch v := val.(type) {
+		case telemetry.UnionString:
+			ext, err = ParseExtended(string(v))
+		case telemetry.Binary:
+			ext, err = decodeExtended(v)
+		default:
+			err = fmt.Errorf("unsupported extended community type %T", val)
+		}
+		if err != nil {
+			return nil, err
+		}
+		got = append(got, ext)
+	}
+	return got, nil
+}
+
+// ReadATE waits for the ATE to receive the prefix from the BGP neighbor,
+// and returns the standard communities of the route.  The instance is
+// the name of the ATE port, and protocol the name of the BGP protocol,
+// which is the local AS number of the ATE.
+func ReadATE(t testing.TB, ate *ondatra.ATEDevice, instance, protocol, neighbor, prefix string, timeout time.Duration) ([]uint32, error) {
+	t.Helper()
+	rib := ate.Telemetry().NetworkInstance(instance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, protocol).Bgp().Rib()
+	present := func(v *telemetry.QualifiedUint64) bool { return v.IsPresent() }
+
+	var index *telemetry.QualifiedUint64
+	var ok bool
+	if ip, _, err := net.ParseCIDR(prefix); err != nil {
+		return nil, fmt.Errorf("bad prefix %q: %w", prefix, err)
+	} else if ip.To4() != nil {
+		index, ok = rib.AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Ipv4Unicast().
+			Neighbor(neighbor).AdjRibInPre().Route(prefix, 0).CommunityIndex().
+			Watch(t, timeout, present).Await(t)
+	} else {
+		index, ok = rib.AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV6_UNICAST).Ipv6Unicast().
+			Neighbor(neighbor).AdjRibInPre().Route(prefix, 0).CommunityIndex().
+			Watch(t, timeout, present).Await(t)
+	}
+	if !ok {
+		return nil, fmt.Errorf("ATE did not receive %s from %s within %v", prefix, neighbor, timeout)
+	}
+	return Standard(rib.Community(index.Val(t)).Community().Get(t))
+}
+
+// Check returns an error for each community of present missing from the
+// communities got, and for each community of absent found in them.
+func Check(got []uint32, present, absent []string) []error {
+	have := map[uint32]bool{}
+	for _, c := range got {
+		have[c] = true
+	}
+	var errs []error
+	for _, c := range present {
+		v, err := ParseStandard(c)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !have[v] {
+			errs = append(errs, fmt.Errorf("community %s is missing", c))
+		}
+	}
+	for _, c := range absent {
+		v, err := ParseStandard(c)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if have[v] {
+			errs = append(errs, fmt.Errorf("community %s is unexpectedly present", c))
+		}
+	}
+	return errs
+}
+
+// ReadDUTExtended waits for the DUT to advertise the prefix to the BGP
+// neighbor, and returns the extended communities of the advertised
+// route.
+func ReadDUTExtended(t testing.TB, dut *ondatra.DUTDevice, neighbor, prefix string, timeout time.Duration) ([]string, error) {
+	t.Helper()
+	rib := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Rib()
+	present := func(v *telemetry.QualifiedUint64) bool { return v.IsPresent() }
+
+	var index *telemetry.QualifiedUint64
+	var ok bool
+	if ip, _, err := net.ParseCIDR(prefix); err != nil {
+		return nil, fmt.Errorf("bad prefix %q: %w", prefix, err)
+	} else if ip.To4() != nil {
+		index, ok = rib.AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Ipv4Unicast().
+			Neighbor(neighbor).AdjRibOutPost().Route(prefix, 0).ExtCommunityIndex().
+			Watch(t, timeout, present).Await(t)
+	} else {
+		index, ok = rib.AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV6_UNICAST).Ipv6Unicast().
+			Neighbor(neighbor).AdjRibOutPost().Route(prefix, 0).ExtCommunityIndex().
+			Watch(t, timeout, present).Await(t)
+	}
+	if !ok {
+		return nil, fmt.Errorf("DUT did not advertise %s with extended communities to %s within %v", prefix, neighbor, timeout)
+	}
+	return Extended(rib.ExtCommunity(index.Val(t)).ExtCommunity().Get(t))
+}
+
+// CheckExtended returns an error for each extended community of present
+// missing from the extended communities got, and for each extended
+// community of absent found in them.
+func CheckExtended(got []string, present, absent []string) []error {
+	have := map[string]bool{}
+	for _, c := range got {
+		have[c] = true
+	}
+	var errs []error
+	for _, c := range present {
+		ext, err := ParseExtended(c)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !have[ext] {
+			errs = append(errs, fmt.Errorf("extended community %s is missing", c))
+		}
+	}
+	for _, c := range absent {
+		ext, err := ParseExtended(c)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if have[ext] {
+			errs = append(errs, fmt.Errorf("extended community %s is unexpectedly present", c))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,329 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgpcomm
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestParseStandard(t *testing.T) {
+	for _, c := range []struct {
+		in      string
+		want    uint32
+		wantErr bool
+	}{
+		{in: "65000:100", want: 65000<<16 | 100},
+		{in: "0:0", want: 0},
+		{in: "4259840100", want: 4259840100},
+		{in: "NO_EXPORT", want: 0xFFFFFF01},
+		{in: "no-advertise", want: 0xFFFFFF02},
+		{in: "65536:1", wantErr: true},
+		{in: "1:65536", wantErr: true},
+		{in: "a:b", wantErr: true},
+		{in: "", wantErr: true},
+	} {
+		got, err := ParseStandard(c.in)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ParseStandard(%q) got error %v, want error %v", c.in, err, c.wantErr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseStandard(%q) got %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestFormatStandard(t *testing.T) {
+	for _, c := range []struct {
+		in   uint32
+		want string
+	}{
+		{65000<<16 | 100, "65000:100"},
+		{0xFFFFFF01, "NO_EXPORT"},
+		{0xFFFFFF04, "NOPEER"},
+	} {
+		if got := FormatStandard(c.in); got != c.want {
+			t.Errorf("FormatStandard(%d) got %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseExtended(t *testing.T) {
+	for _, c := range []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "route-target:65000:100", want: "route-target:65000:100"},
+		{in: "Route-Origin:65000:4294967295", want: "route-origin:65000:4294967295"},
+		{in: "route-target:4200000000:100", want: "route-target:4200000000:100"},
+		{in: "route-target:4200000000:65536", wantErr: true},
+		{in: "route-target:192.0.2.1:100", want: "route-target:192.0.2.1:100"},
+		{in: "route-target:192.0.2.1:65536", wantErr: true},
+		{in: "route-target:2001:db8::1:100", wantErr: true},
+		{in: "color:0:100", wantErr: true},
+		{in: "link-bandwidth:64500:10g", want: "link-bandwidth:64500:10G"},
+		{in: "link-bandwidth:64500:2500000", want: "link-bandwidth:64500:2500K"},
+		{in: "link-bandwidth:4200000000:1G", wantErr: true},
+		{in: "link-bandwidth:64500:fast", wantErr: true},
+		{in: "link-bandwidth:64500", wantErr: true},
+		{in: "route-target", wantErr: true},
+	} {
+		got, err := ParseExtended(c.in)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ParseExtended(%q) got error %v, want error %v", c.in, err, c.wantErr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseExtended(%q) got %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestBandwidth(t *testing.T) {
+	for _, c := range []struct {
+		in      string
+		want    uint64
+		str     string
+		wantErr bool
+	}{
+		{in: "10G", want: 10e9, str: "10G"},
+		{in: "1500m", want: 1500e6, str: "1500M"},
+		{in: "100k", want: 100e3, str: "100K"},
+		{in: "1234", want: 1234, str: "1234"},
+		{in: "0", want: 0, str: "0"},
+		{in: "G", wantErr: true},
+		{in: "99999999999999G", wantErr: true},
+	} {
+		got, err := ParseBandwidth(c.in)
+		if (err != nil) != c.wantErr {
+			t.Errorf("ParseBandwidth(%q) got error %v, want error %v", c.in, err, c.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseBandwidth(%q) got %d, want %d", c.in, got, c.want)
+		}
+		if s := FormatBandwidth(got); s != c.str {
+			t.Errorf("FormatBandwidth(%d) got %q, want %q", got, s, c.str)
+		}
+	}
+	if got, want := LinkBandwidth(64500, 25e8), "link-bandwidth:64500:2500M"; got != want {
+		t.Errorf("LinkBandwidth got %q, want %q", got, want)
+	}
+}
+
+func TestParseLarge(t *testing.T) {
+	got, err := ParseLarge("4200000000:1:2")
+	if err != nil {
+		t.Fatalf("ParseLarge got error: %v", err)
+	}
+	if want := (Large{4200000000, 1, 2}); got != want {
+		t.Errorf("ParseLarge got %v, want %v", got, want)
+	}
+	if s := got.String(); s != "4200000000:1:2" {
+		t.Errorf("String got %q, want 4200000000:1:2", s)
+	}
+	for _, in := range []string{"1:2", "1:2:3:4", "1:2:4294967296", "a:b:c"} {
+		if _, err := ParseLarge(in); err == nil {
+			t.Errorf("ParseLarge(%q) got no error, want error", in)
+		}
+	}
+}
+
+func TestLargeSet(t *testing.T) {
+	a, b, c := Large{64500, 1, 1}, Large{64500, 1, 2}, Large{64500, 1, 3}
+	for _, tc := range []struct {
+		match telemetry.E_PolicyTypes_MatchSetOptionsType
+		got   []Large
+		want  bool
+	}{
+		{telemetry.PolicyTypes_MatchSetOptionsType_ANY, []Large{b, c}, true},
+		{telemetry.PolicyTypes_MatchSetOptionsType_ANY, []Large{c}, false},
+		{telemetry.PolicyTypes_MatchSetOptionsType_ALL, []Large{a, b, c}, true},
+		{telemetry.PolicyTypes_MatchSetOptionsType_ALL, []Large{a, c}, false},
+		{telemetry.PolicyTypes_MatchSetOptionsType_INVERT, []Large{c}, true},
+		{telemetry.PolicyTypes_MatchSetOptionsType_INVERT, []Large{a}, false},
+	} {
+		s := &LargeSet{Name: "large", Match: tc.match, Communities: []Large{a, b}}
+		got, err := s.Matches(tc.got)
+		if err != nil {
+			t.Errorf("%v Matches(%v) got error: %v", s, tc.got, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%v Matches(%v) got %v, want %v", s, tc.got, got, tc.want)
+		}
+	}
+	s := &LargeSet{Name: "large", Match: telemetry.PolicyTypes_MatchSetOptionsType_ANY, Communities: []Large{a, b}}
+	if got, want := s.String(), "large match ANY [64500:1:1 64500:1:2]"; got != want {
+		t.Errorf("String got %q, want %q", got, want)
+	}
+	if _, err := (&LargeSet{Name: "unset"}).Matches(nil); err == nil {
+		t.Error("Matches with no match option got no error, want error")
+	}
+}
+
+func TestLargeAction(t *testing.T) {
+	a, b, c := Large{64500, 1, 1}, Large{64500, 1, 2}, Large{64500, 1, 3}
+	for _, tc := range []struct {
+		option telemetry.E_BgpPolicy_BgpSetCommunityOptionType
+		want   []Large
+	}{
+		{telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD, []Large{a, b, c}},
+		{telemetry.BgpPolicy_BgpSetCommunityOptionType_REMOVE, []Large{a}},
+		{telemetry.BgpPolicy_BgpSetCommunityOptionType_REPLACE, []Large{b, c}},
+	} {
+		act := &LargeAction{Option: tc.option, Communities: []Large{b, c}}
+		got, err := act.Apply([]Large{a, b})
+		if err != nil {
+			t.Errorf("%v Apply got error: %v", act, err)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("%v Apply (-want, +got):\n%s", act, diff)
+		}
+	}
+	act := &LargeAction{Option: telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD, Communities: []Large{a}}
+	if got, want := act.String(), "ADD large communities [64500:1:1]"; got != want {
+		t.Errorf("String got %q, want %q", got, want)
+	}
+	if _, err := (&LargeAction{}).Apply(nil); err == nil {
+		t.Error("Apply with no option got no error, want error")
+	}
+}
+
+func TestConfigureSet(t *testing.T) {
+	rp := &telemetry.RoutingPolicy{}
+	if err := ConfigureSet(rp, "std", telemetry.PolicyTypes_MatchSetOptionsType_ANY, "65000:100", "no-export"); err != nil {
+		t.Fatalf("ConfigureSet got error: %v", err)
+	}
+	cs := rp.GetDefinedSets().GetBgpDefinedSets().GetCommunitySet("std")
+	want := []telemetry.RoutingPolicy_DefinedSets_BgpDefinedSets_CommunitySet_CommunityMember_Union{
+		telemetry.UnionString("65000:100"),
+		telemetry.BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY_NO_EXPORT,
+	}
+	if diff := cmp.Diff(want, cs.CommunityMember); diff != "" {
+		t.Errorf("Community members (-want, +got):\n%s", diff)
+	}
+	if cs.MatchSetOptions != telemetry.PolicyTypes_MatchSetOptionsType_ANY {
+		t.Errorf("Match set options got %v, want ANY", cs.MatchSetOptions)
+	}
+	if err := ConfigureSet(rp, "bad", telemetry.PolicyTypes_MatchSetOptionsType_ANY, "65536:1"); err == nil {
+		t.Error("ConfigureSet with a bad community got no error, want error")
+	}
+
+	if err := ConfigureExtSet(rp, "ext", telemetry.PolicyTypes_MatchSetOptionsType_ALL, "Route-Target:65000:1"); err != nil {
+		t.Fatalf("ConfigureExtSet got error: %v", err)
+	}
+	ecs := rp.GetDefinedSets().GetBgpDefinedSets().GetExtCommunitySet("ext")
+	if diff := cmp.Diff([]string{"route-target:65000:1"}, ecs.ExtCommunityMember); diff != "" {
+		t.Errorf("Extended community members (-want, +got):\n%s", diff)
+	}
+}
+
+func TestSetCommunities(t *testing.T) {
+	a := &telemetry.RoutingPolicy_PolicyDefinition_Statement_Actions{}
+	if err := SetCommunities(a, telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD, "65000:1", "NOPEER"); err != nil {
+		t.Fatalf("SetCommunities got error: %v", err)
+	}
+	sc := a.GetBgpActions().GetSetCommunity()
+	if sc.Method != telemetry.SetCommunity_Method_INLINE || sc.Options != telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD {
+		t.Errorf("Set community got method %v and options %v, want INLINE and ADD", sc.Method, sc.Options)
+	}
+	want := []telemetry.RoutingPolicy_PolicyDefinition_Statement_Actions_BgpActions_SetCommunity_Inline_Communities_Union{
+		telemetry.UnionString("65000:1"),
+		telemetry.BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY_NOPEER,
+	}
+	if diff := cmp.Diff(want, sc.GetInline().Communities); diff != "" {
+		t.Errorf("Inline communities (-want, +got):\n%s", diff)
+	}
+
+	if err := SetExtCommunities(a, telemetry.BgpPolicy_BgpSetCommunityOptionType_REMOVE, "route-origin:65000:2"); err != nil {
+		t.Fatalf("SetExtCommunities got error: %v", err)
+	}
+	sec := a.GetBgpActions().GetSetExtCommunity()
+	wantExt := []telemetry.RoutingPolicy_PolicyDefinition_Statement_Actions_BgpActions_SetExtCommunity_Inline_Communities_Union{
+		telemetry.UnionString("route-origin:65000:2"),
+	}
+	if diff := cmp.Diff(wantExt, sec.GetInline().Communities); diff != "" {
+		t.Errorf("Inline extended communities (-want, +got):\n%s", diff)
+	}
+	if err := SetExtCommunities(a, telemetry.BgpPolicy_BgpSetCommunityOptionType_ADD, "65000:2"); err == nil {
+		t.Error("SetExtCommunities with a standard community got no error, want error")
+	}
+}
+
+func TestStandardAndCheck(t *testing.T) {
+	got, err := Standard([]telemetry.NetworkInstance_Protocol_Bgp_Rib_Community_Community_Union{
+		telemetry.UnionString("65000:1"),
+		telemetry.UnionUint32(65000<<16 | 2),
+		telemetry.BgpTypes_BGP_WELL_KNOWN_STD_COMMUNITY_NO_EXPORT,
+	})
+	if err != nil {
+		t.Fatalf("Standard got error: %v", err)
+	}
+	if diff := cmp.Diff([]uint32{65000<<16 | 1, 65000<<16 | 2, 0xFFFFFF01}, got); diff != "" {
+		t.Errorf("Standard (-want, +got):\n%s", diff)
+	}
+
+	if errs := Check(got, []string{"65000:1", "NO_EXPORT"}, []string{"65000:3"}); len(errs) != 0 {
+		t.Errorf("Check got errors %v, want none", errs)
+	}
+	if errs := Check(got, []string{"65000:3"}, []string{"65000:2", "no-export"}); len(errs) != 3 {
+		t.Errorf("Check got %d errors %v, want 3", len(errs), errs)
+	}
+}
+
+func TestExtendedAndCheck(t *testing.T) {
+	got, err := Extended([]telemetry.NetworkInstance_Protocol_Bgp_Rib_ExtCommunity_ExtCommunity_Union{
+		telemetry.UnionString("Route-Target:65000:1"),
+		telemetry.Binary{0x00, 0x02, 0xfd, 0xe8, 0x00, 0x00, 0x00, 0x02},
+		telemetry.Binary{0x01, 0x03, 192, 0, 2, 1, 0x00, 0x03},
+		telemetry.Binary{0x42, 0x02, 0xfa, 0x56, 0xea, 0x00, 0x00, 0x04},
+		// 10G is 1.25e9 bytes per second, 0x4e9502f9 as a float.
+		telemetry.Binary{0x40, 0x04, 0xfb, 0xf4, 0x4e, 0x95, 0x02, 0xf9},
+	})
+	if err != nil {
+		t.Fatalf("Extended got error: %v", err)
+	}
+	want := []string{
+		"route-target:65000:1",
+		"route-target:65000:2",
+		"route-origin:192.0.2.1:3",
+		"route-target:4200000000:4",
+		"link-bandwidth:64500:10G",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Extended (-want, +got):\n%s", diff)
+	}
+	for _, b := range []telemetry.Binary{{0x00, 0x02}, {0x00, 0x0b, 0, 0, 0, 0, 0, 0}, {0x03, 0x02, 0, 0, 0, 0, 0, 0}} {
+		if _, err := Extended([]telemetry.NetworkInstance_Protocol_Bgp_Rib_ExtCommunity_ExtCommunity_Union{b}); err == nil {
+			t.Errorf("Extended(%x) got no error, want error", []byte(b))
+		}
+	}
+
+	if errs := CheckExtended(got, []string{"route-target:65000:1"}, []string{"route-origin:65000:1"}); len(errs) != 0 {
+		t.Errorf("CheckExtended got errors %v, want none", errs)
+	}
+	if errs := CheckExtended(got, []string{"route-origin:65000:1"}, []string{"route-target:65000:2"}); len(errs) != 2 {
+		t.Errorf("CheckExtended got %d errors %v, want 2", len(errs), errs)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bgpdefault provides helpers to send the default route to BGP
+// neighbors, unconditionally or conditioned on a route of the RIB of the
+// DUT, and to verify that the neighbors receive it or not.
+//
+// OpenConfig models sending the default route to a neighbor, and the
+// routing policy matching the condition route, but not conditioning the
+// default route on the policy.  The DUT is expected to be configured out
+// of band to bind the policy to the default route of the neighbors, see
+// -deviation_bgp_conditional_default.
+//
+// Usage:
+//
+//	c := &bgpdefault.Condition{Policy: "CONDITION", PrefixSet: "CONDITION", Prefix: "198.51.100.0/24"}
+//	bgpdefault.Require(t, c)
+//	err := c.Configure(rp)
+//	bgpdefault.Send(nbr, true)
+//	...
+//	err = bgpdefault.AwaitReceived(t, otg, peer, true, timeout)
+package bgpdefault
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	otg "github.com/openconfig/ondatra/otg"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+	otgtelemetry "github.com/openconfig/ondatra/telemetry/otg"
+)
+
+// Prefix is the IPv4 default route.
+const Prefix = "0.0.0.0/0"
+
+// Send sets whether the DUT sends the IPv4 default route to the neighbor.
+func Send(nbr *telemetry.NetworkInstance_Protocol_Bgp_Neighbor, send bool) {
+	af := nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST)
+	af.Enabled = ygot.Bool(true)
+	af.GetOrCreateIpv4Unicast().SendDefaultRoute = ygot.Bool(send)
+}
+
+// Condition is a routing policy accepting only the condition route, on
+// which the default route is conditioned.
+type Condition struct {
+	Policy    string
+	PrefixSet string
+	// Prefix is the condition route, matched exactly.
+	Prefix string
+}
+
+// Validate returns an error if the condition has no names or its prefix
+// is not an IPv4 prefix.
+func (c *Condition) Validate() error {
+	if c.Policy == "" || c.PrefixSet == "" {
+		return errors.New("condition without a policy or prefix set name")
+	}
+	if ip, _, err := net.ParseCIDR(c.Prefix); err != nil || ip.To4() == nil {
+		return fmt.Errorf("condition %s: %q is not an IPv4 prefix", c.Policy, c.Prefix)
+	}
+	return nil
+}
+
+// Configure configures the policy of the condition in the routing policy:
+// a prefix set of the condition route, and a policy accepting the routes
+// of the prefix set.  The policy rejects any other route.
+func (c *Condition) Configure(rp *telemetry.RoutingPolicy) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	ps := rp.GetOrCreateDefinedSets().GetOrCreatePrefixSet(c.PrefixSet)
+	ps.Mode = telemetry.PrefixSet_Mode_IPV4
+	ps.Prefix = nil
+	ps.GetOrCreatePrefix(c.Prefix, "exact")
+
+	pd := rp.GetOrCreatePolicyDefinition(c.Policy)
+	pd.Statement = nil
+	st := pd.GetOrCreateStatement("condition")
+	m := st.GetOrCreateConditions().GetOrCreateMatchPrefixSet()
+	m.PrefixSet = ygot.String(c.PrefixSet)
+	m.MatchSetOptions = telemetry.PolicyTypes_MatchSetOptionsRestrictedType_ANY
+	st.GetOrCreateActions().PolicyResult = telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE
+	return nil
+}
+
+// Require skips the test unless the DUT is configured to condition the
+// default route on the policy, and logs the condition.
+func Require(t testing.TB, c *Condition) {
+	t.Helper()
+	if !*deviations.BGPConditionalDefault {
+		t.Skip("The default route is not conditioned, see -deviation_bgp_conditional_default")
+	}
+	t.Logf("BGP default route conditioned on policy %s, matching %s", c.Policy, c.Prefix)
+}
+
+// HasDefault returns whether the routes received by the ATE have the
+// default route.
+func HasDefault(routes []*otgtelemetry.BgpPeer_UnicastIpv4Prefix) bool {
+	for _, r := range routes {
+		if r.GetAddress() == "0.0.0.0" && r.GetPrefixLength() == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pollInterval is how often the routes are read while awaiting the
+// default route.
+var pollInterval = 5 * time.Second
+
+// AwaitReceived waits for the BGP peer of the ATE to receive the default
+// route, or not, and returns an error if it does not within the timeout.
+func AwaitReceived(t testing.TB, otg *otg.OTG, peer string, want bool, timeout time.Duration) error {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); ; time.Sleep(pollInterval) {
+		var routes []*otgtelemetry.BgpPeer_UnicastIpv4Prefix
+		for _, v := range otg.Telemetry().BgpPeer(peer).UnicastIpv4PrefixAny().Lookup(t) {
+			if v.IsPresent() {
+				routes = append(routes, v.Val(t))
+			}
+		}
+		got := HasDefault(routes)
+		if got == want {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s got default route %t after %v, want %t", peer, got, timeout, want)
+		}
+	}
+}
+
+// AwaitAdvertised waits for the DUT to advertise the default route to the
+// BGP neighbor, or not, as reported by its Adj-RIB-Out, and returns an
+// error if it does not within the timeout.
+func AwaitAdvertised(t testing.TB, dut *ondatra.DUTDevice, neighbor string, want bool, timeout time.Duration) error {
+	t.Helper()
+	path := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Rib().
+		AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST).Ipv4Unicast().
+		Neighbor(neighbor).AdjRibOutPost().Route(Prefix, 0).Prefix()
+	_, ok := path.Watch(t, timeout, func(v *telemetry.QualifiedString) bool {
+		return v.IsPresent() == want
+	}).Await(t)
+	if !ok {
+		return fmt.Errorf("DUT got default route advertised to %s %t after %v, want %t", neighbor, !want, timeout, want)
+	}
+	return nil
+}
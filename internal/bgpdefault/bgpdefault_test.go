@@ -0,0 +1,119 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgpdefault
+
+import (
+	"testing"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+	otgtelemetry "github.com/openconfig/ondatra/telemetry/otg"
+)
+
+func TestSend(t *testing.T) {
+	nbr := &telemetry.NetworkInstance_Protocol_Bgp_Neighbor{NeighborAddress: ygot.String("192.0.2.6")}
+	for _, send := range []bool{true, false} {
+		Send(nbr, send)
+		af := nbr.GetAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST)
+		if !af.GetEnabled() {
+			t.Errorf("Send(%t) got IPv4 unicast disabled", send)
+		}
+		if got := af.GetIpv4Unicast().SendDefaultRoute; got == nil || *got != send {
+			t.Errorf("Send(%t) got send-default-route %v", send, got)
+		}
+	}
+}
+
+func TestConditionValidate(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		cond    *Condition
+		wantErr bool
+	}{
+		{"valid", &Condition{Policy: "P", PrefixSet: "S", Prefix: "198.51.100.0/24"}, false},
+		{"no policy", &Condition{PrefixSet: "S", Prefix: "198.51.100.0/24"}, true},
+		{"no prefix set", &Condition{Policy: "P", Prefix: "198.51.100.0/24"}, true},
+		{"address", &Condition{Policy: "P", PrefixSet: "S", Prefix: "198.51.100.1"}, true},
+		{"IPv6", &Condition{Policy: "P", PrefixSet: "S", Prefix: "2001:db8::/32"}, true},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			if err := c.cond.Validate(); (err != nil) != c.wantErr {
+				t.Errorf("Validate() got error %v, want error %t", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestConditionConfigure(t *testing.T) {
+	c := &Condition{Policy: "CONDITION", PrefixSet: "CONDITION-ROUTE", Prefix: "198.51.100.0/24"}
+	rp := &telemetry.RoutingPolicy{}
+	// Configure replaces a previous condition.
+	old := &Condition{Policy: c.Policy, PrefixSet: c.PrefixSet, Prefix: "203.0.113.0/24"}
+	if err := old.Configure(rp); err != nil {
+		t.Fatalf("Configure() got error %v", err)
+	}
+	if err := c.Configure(rp); err != nil {
+		t.Fatalf("Configure() got error %v", err)
+	}
+
+	ps := rp.GetDefinedSets().GetPrefixSet(c.PrefixSet)
+	if ps.GetMode() != telemetry.PrefixSet_Mode_IPV4 {
+		t.Errorf("Configure() got prefix set mode %v, want IPV4", ps.GetMode())
+	}
+	if len(ps.Prefix) != 1 || ps.GetPrefix(c.Prefix, "exact") == nil {
+		t.Errorf("Configure() got prefixes %v, want %s exact", ps.Prefix, c.Prefix)
+	}
+
+	pd := rp.GetPolicyDefinition(c.Policy)
+	if len(pd.Statement) != 1 {
+		t.Fatalf("Configure() got statements %v, want 1", pd.Statement)
+	}
+	for _, st := range pd.Statement {
+		m := st.GetConditions().GetMatchPrefixSet()
+		if m.GetPrefixSet() != c.PrefixSet || m.GetMatchSetOptions() != telemetry.PolicyTypes_MatchSetOptionsRestrictedType_ANY {
+			t.Errorf("Configure() got match %v, want any of %s", m, c.PrefixSet)
+		}
+		if got := st.GetActions().GetPolicyResult(); got != telemetry.RoutingPolicy_PolicyResultType_ACCEPT_ROUTE {
+			t.Errorf("Configure() got result %v, want ACCEPT_ROUTE", got)
+		}
+	}
+	if err := rp.Validate(); err != nil {
+		t.Errorf("Configure() routing policy does not validate: %v", err)
+	}
+
+	if err := (&Condition{Policy: "P"}).Configure(rp); err == nil {
+		t.Error("Configure() of an invalid condition got no error")
+	}
+}
+
+func TestHasDefault(t *testing.T) {
+	route := func(addr string, plen uint32) *otgtelemetry.BgpPeer_UnicastIpv4Prefix {
+		return &otgtelemetry.BgpPeer_UnicastIpv4Prefix{Address: ygot.String(addr), PrefixLength: ygot.Uint32(plen)}
+	}
+	for _, c := range []struct {
+		desc   string
+		routes []*otgtelemetry.BgpPeer_UnicastIpv4Prefix
+		want   bool
+	}{
+		{"none", nil, false},
+		{"default", []*otgtelemetry.BgpPeer_UnicastIpv4Prefix{route("198.51.100.0", 24), route("0.0.0.0", 0)}, true},
+		{"other routes", []*otgtelemetry.BgpPeer_UnicastIpv4Prefix{route("198.51.100.0", 24), route("0.0.0.0", 8)}, false},
+	} {
+		if got := HasDefault(c.routes); got != c.want {
+			t.Errorf("HasDefault(%s) got %t, want %t", c.desc, got, c.want)
+		}
+	}
+}
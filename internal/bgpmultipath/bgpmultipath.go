@@ -0,0 +1,213 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bgpmultipath provides helpers to configure BGP multipath, and
+// to verify how the DUT spreads the traffic over the paths, with equal
+// cost or weighted by the link bandwidth extended community of the
+// routes.
+//
+// Weighting the paths by link bandwidth is not modeled in OpenConfig, so
+// it must be configured out of band, as given by the
+// deviation_bgp_link_bandwidth flag.
+package bgpmultipath
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Configure sets the maximum number of eBGP and iBGP paths used for
+// multipath, leaving unset the maximums that are zero.  allowMultipleAS
+// lets eBGP multipath use paths from different neighbor ASes.
+func Configure(bgp *telemetry.NetworkInstance_Protocol_Bgp, ebgp, ibgp uint32, allowMultipleAS bool) {
+	mp := bgp.GetOrCreateGlobal().GetOrCreateUseMultiplePaths()
+	mp.Enabled = ygot.Bool(ebgp > 1 || ibgp > 1)
+	if ebgp > 0 {
+		e := mp.GetOrCreateEbgp()
+		e.MaximumPaths = ygot.Uint32(ebgp)
+		e.AllowMultipleAs = ygot.Bool(allowMultipleAS)
+	}
+	if ibgp > 0 {
+		mp.GetOrCreateIbgp().MaximumPaths = ygot.Uint32(ibgp)
+	}
+}
+
+// Weights returns the share of the traffic expected on each path, in
+// proportion to their bandwidths.
+func Weights(bandwidths []uint64) ([]float64, error) {
+	var total float64
+	for _, bw := range bandwidths {
+		total += float64(bw)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("no bandwidth in %v", bandwidths)
+	}
+	weights := make([]float64, len(bandwidths))
+	for i, bw := range bandwidths {
+		weights[i] = float64(bw) / total
+	}
+	return weights, nil
+}
+
+// Equal returns the weights of n paths of equal cost.
+func Equal(n int) []float64 {
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = 1 / float64(n)
+	}
+	return weights
+}
+
+// stray returns whether a count of packets is negligible in the total,
+// such as the packets of the control plane.
+func stray(count, total uint64) bool {
+	return float64(count) <= float64(total)/100
+}
+
+// Check returns an error if the packets received on each path are not
+// distributed according to the weights, within tolerancePct percent of
+// the expected count.  The paths with no weight may only receive stray
+// packets.
+func Check(counts []uint64, weights []float64, tolerancePct float64) error {
+	if len(counts) != len(weights) {
+		return fmt.Errorf("got %d counts for %d weights", len(counts), len(weights))
+	}
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return fmt.Errorf("no packet received")
+	}
+	for i, c := range counts {
+		if weights[i] == 0 {
+			if !stray(c, total) {
+				return fmt.Errorf("path %d with no weight received %d packets of %v", i, c, counts)
+			}
+			continue
+		}
+		want := weights[i] * float64(total)
+		if dev := math.Abs(float64(c)-want) / want * 100; dev > tolerancePct {
+			return fmt.Errorf("path %d received %d packets of %v, deviating %.1f%% from the %.0f expected, want <= %.1f%%", i, c, counts, dev, want, tolerancePct)
+		}
+	}
+	return nil
+}
+
+// CheckSpread returns an error if the packets are not balanced over
+// exactly n of the paths, within tolerancePct percent of the mean.  It
+// is used when it is not known which paths are selected for multipath.
+func CheckSpread(counts []uint64, n int, tolerancePct float64) error {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	var used []uint64
+	for _, c := range counts {
+		if !stray(c, total) {
+			used = append(used, c)
+		}
+	}
+	if len(used) != n {
+		return fmt.Errorf("packets %v spread over %d paths, want %d", counts, len(used), n)
+	}
+	return Check(used, Equal(n), tolerancePct)
+}
+
+// NextHopWeights returns the weight of each next hop address of the
+// prefix in the AFTs.  The next hops with no weight have weight 1.
+func NextHopWeights(afts *telemetry.NetworkInstance_Afts, prefix string) (map[string]uint64, error) {
+	var nhg *uint64
+	if ip, _, err := net.ParseCIDR(prefix); err != nil {
+		return nil, fmt.Errorf("bad prefix %q: %w", prefix, err)
+	} else if ip.To4() != nil {
+		if e := afts.GetIpv4Entry(prefix); e != nil {
+			nhg = e.NextHopGroup
+		}
+	} else if e := afts.GetIpv6Entry(prefix); e != nil {
+		nhg = e.NextHopGroup
+	}
+	if nhg == nil {
+		return nil, fmt.Errorf("no AFT entry with a next hop group for %s", prefix)
+	}
+	g := afts.GetNextHopGroup(*nhg)
+	if g == nil {
+		return nil, fmt.Errorf("no next hop group %d for %s", *nhg, prefix)
+	}
+	weights := map[string]uint64{}
+	for index, nh := range g.NextHop {
+		addr := afts.GetNextHop(index).GetIpAddress()
+		if addr == "" {
+			return nil, fmt.Errorf("no IP address for next hop %d of %s", index, prefix)
+		}
+		weight := nh.GetWeight()
+		if weight == 0 {
+			weight = 1
+		}
+		weights[addr] += weight
+	}
+	return weights, nil
+}
+
+// ReadNextHops reads the AFT entry of the prefix from the DUT, with its
+// next hop group and next hops, and returns the weight of each next hop
+// address.
+func ReadNextHops(t testing.TB, dut *ondatra.DUTDevice, prefix string) (map[string]uint64, error) {
+	t.Helper()
+	path := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).Afts()
+	afts := &telemetry.NetworkInstance_Afts{}
+	var nhg *uint64
+	if ip, _, err := net.ParseCIDR(prefix); err != nil {
+		return nil, fmt.Errorf("bad prefix %q: %w", prefix, err)
+	} else if ip.To4() != nil {
+		e := path.Ipv4Entry(prefix).Lookup(t)
+		if !e.IsPresent() {
+			return nil, fmt.Errorf("no AFT entry for %s", prefix)
+		}
+		if err := afts.AppendIpv4Entry(e.Val(t)); err != nil {
+			return nil, err
+		}
+		nhg = e.Val(t).NextHopGroup
+	} else {
+		e := path.Ipv6Entry(prefix).Lookup(t)
+		if !e.IsPresent() {
+			return nil, fmt.Errorf("no AFT entry for %s", prefix)
+		}
+		if err := afts.AppendIpv6Entry(e.Val(t)); err != nil {
+			return nil, err
+		}
+		nhg = e.Val(t).NextHopGroup
+	}
+	if nhg == nil {
+		return nil, fmt.Errorf("no next hop group for %s", prefix)
+	}
+	g := path.NextHopGroup(*nhg).Get(t)
+	if err := afts.AppendNextHopGroup(g); err != nil {
+		return nil, err
+	}
+	for index := range g.NextHop {
+		if err := afts.AppendNextHop(path.NextHop(index).Get(t)); err != nil {
+			return nil, err
+		}
+	}
+	return NextHopWeights(afts, prefix)
+}
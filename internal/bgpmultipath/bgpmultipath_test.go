@@ -0,0 +1,138 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgpmultipath
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestConfigure(t *testing.T) {
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	Configure(bgp, 4, 0, true)
+	mp := bgp.GetGlobal().GetUseMultiplePaths()
+	if !mp.GetEnabled() {
+		t.Error("Multipath not enabled")
+	}
+	if got := mp.GetEbgp().GetMaximumPaths(); got != 4 {
+		t.Errorf("eBGP maximum paths got %d, want 4", got)
+	}
+	if !mp.GetEbgp().GetAllowMultipleAs() {
+		t.Error("eBGP multiple AS not allowed")
+	}
+	if mp.Ibgp != nil {
+		t.Errorf("iBGP multipath got %v, want unset", mp.Ibgp)
+	}
+
+	Configure(bgp, 1, 1, false)
+	if mp.GetEnabled() {
+		t.Error("Multipath enabled with a single path")
+	}
+	if got := mp.GetIbgp().GetMaximumPaths(); got != 1 {
+		t.Errorf("iBGP maximum paths got %d, want 1", got)
+	}
+}
+
+func TestWeights(t *testing.T) {
+	got, err := Weights([]uint64{1e9, 3e9, 4e9})
+	if err != nil {
+		t.Fatalf("Weights got error: %v", err)
+	}
+	if diff := cmp.Diff([]float64{0.125, 0.375, 0.5}, got); diff != "" {
+		t.Errorf("Weights (-want, +got):\n%s", diff)
+	}
+	if _, err := Weights([]uint64{0, 0}); err == nil {
+		t.Error("Weights with no bandwidth got no error, want error")
+	}
+	if diff := cmp.Diff([]float64{0.25, 0.25, 0.25, 0.25}, Equal(4)); diff != "" {
+		t.Errorf("Equal (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	weights := []float64{0.125, 0.375, 0.5, 0}
+	for _, c := range []struct {
+		desc    string
+		counts  []uint64
+		wantErr bool
+	}{
+		{desc: "exact", counts: []uint64{1250, 3750, 5000, 0}},
+		{desc: "within tolerance", counts: []uint64{1300, 3700, 4950, 50}},
+		{desc: "out of tolerance", counts: []uint64{2000, 3000, 5000, 0}, wantErr: true},
+		{desc: "no weight", counts: []uint64{1250, 3750, 4000, 1000}, wantErr: true},
+		{desc: "no packet", counts: []uint64{0, 0, 0, 0}, wantErr: true},
+		{desc: "wrong length", counts: []uint64{1, 2}, wantErr: true},
+	} {
+		if err := Check(c.counts, weights, 10); (err != nil) != c.wantErr {
+			t.Errorf("Check %s got error %v, want error %v", c.desc, err, c.wantErr)
+		}
+	}
+}
+
+func TestCheckSpread(t *testing.T) {
+	for _, c := range []struct {
+		counts  []uint64
+		n       int
+		wantErr bool
+	}{
+		{counts: []uint64{5000, 3, 5100}, n: 2},
+		{counts: []uint64{3300, 3400, 3300}, n: 3},
+		{counts: []uint64{3300, 3400, 3300}, n: 2, wantErr: true},
+		{counts: []uint64{10000, 0, 0}, n: 1},
+		{counts: []uint64{8000, 2000, 0}, n: 2, wantErr: true},
+	} {
+		if err := CheckSpread(c.counts, c.n, 10); (err != nil) != c.wantErr {
+			t.Errorf("CheckSpread(%v, %d) got error %v, want error %v", c.counts, c.n, err, c.wantErr)
+		}
+	}
+}
+
+func TestNextHopWeights(t *testing.T) {
+	afts := &telemetry.NetworkInstance_Afts{}
+	afts.GetOrCreateIpv4Entry("198.51.100.0/24").NextHopGroup = ygot.Uint64(10)
+	afts.GetOrCreateIpv6Entry("2001:db8:1::/64").NextHopGroup = ygot.Uint64(11)
+	afts.GetOrCreateIpv4Entry("203.0.113.0/24").NextHopGroup = ygot.Uint64(12)
+	g := afts.GetOrCreateNextHopGroup(10)
+	g.GetOrCreateNextHop(1).Weight = ygot.Uint64(1)
+	g.GetOrCreateNextHop(2).Weight = ygot.Uint64(3)
+	afts.GetOrCreateNextHopGroup(11).GetOrCreateNextHop(3)
+	afts.GetOrCreateNextHop(1).IpAddress = ygot.String("192.0.2.6")
+	afts.GetOrCreateNextHop(2).IpAddress = ygot.String("192.0.2.10")
+	afts.GetOrCreateNextHop(3).IpAddress = ygot.String("2001:db8::6")
+
+	got, err := NextHopWeights(afts, "198.51.100.0/24")
+	if err != nil {
+		t.Fatalf("NextHopWeights got error: %v", err)
+	}
+	if diff := cmp.Diff(map[string]uint64{"192.0.2.6": 1, "192.0.2.10": 3}, got); diff != "" {
+		t.Errorf("NextHopWeights IPv4 (-want, +got):\n%s", diff)
+	}
+	got, err = NextHopWeights(afts, "2001:db8:1::/64")
+	if err != nil {
+		t.Fatalf("NextHopWeights got error: %v", err)
+	}
+	if diff := cmp.Diff(map[string]uint64{"2001:db8::6": 1}, got); diff != "" {
+		t.Errorf("NextHopWeights IPv6 (-want, +got):\n%s", diff)
+	}
+	for _, prefix := range []string{"192.0.2.0/30", "203.0.113.0/24", "bad"} {
+		if _, err := NextHopWeights(afts, prefix); err == nil {
+			t.Errorf("NextHopWeights(%s) got no error, want error", prefix)
+		}
+	}
+}
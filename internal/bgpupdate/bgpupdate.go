@@ -0,0 +1,262 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bgpupdate measures how fast the DUT generates BGP UPDATEs when
+// it advertises a large RIB to its peers.  For each peer, it records the
+// time from the establishment of the session to the first UPDATE the DUT
+// sends, and to the last UPDATE, when the DUT has sent all the prefixes.
+//
+// The ATE does not report the BGP messages it receives, so the
+// measurement polls the UPDATE and prefix counters of the DUT neighbors
+// instead, and uses the timestamps of the DUT.  The resolution is
+// therefore the polling interval.
+//
+// Usage:
+//
+//	r := &bgpupdate.Recorder{DUT: dut, Neighbors: peers}
+//	r.Start(t)
+//	// Establish the sessions with the peers.
+//	timings := r.Wait(t, routes)
+//	t.Log(bgpupdate.Summarize(timings))
+package bgpupdate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/telemetry/networkinstance"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Sample is a reading of the counters of a BGP neighbor.
+type Sample struct {
+	// Time is the timestamp of the reading on the DUT.
+	Time time.Time
+	// Updates is the number of UPDATEs sent to the neighbor.
+	Updates uint64
+	// Sent is the number of prefixes sent to the neighbor.
+	Sent uint32
+}
+
+// Timing is how long the DUT took to advertise its routes to a neighbor.
+type Timing struct {
+	Neighbor string
+	// First is the time to the first UPDATE, and Last the time to the
+	// last UPDATE, from the establishment of the session.
+	First, Last time.Duration
+	// Updates is the number of UPDATEs sent up to the last one.
+	Updates uint64
+	// Complete is whether all the prefixes were sent.
+	Complete bool
+}
+
+// Rate returns the number of UPDATEs sent per second between the first
+// and the last UPDATE, or 0 if they fall in the same polling interval.
+func (t *Timing) Rate() float64 {
+	d := t.Last - t.First
+	if d <= 0 {
+		return 0
+	}
+	return float64(t.Updates) / d.Seconds()
+}
+
+func (t *Timing) String() string {
+	if !t.Complete {
+		return fmt.Sprintf("%s: first UPDATE after %v, incomplete after %d UPDATEs", t.Neighbor, t.First, t.Updates)
+	}
+	return fmt.Sprintf("%s: first UPDATE after %v, last after %v, %d UPDATEs (%.0f/s)", t.Neighbor, t.First, t.Last, t.Updates, t.Rate())
+}
+
+// Analyze returns the timing of the neighbor from the samples of its
+// counters, in order, given the establishment time of the session, the
+// UPDATE counter before the session was established, and the number of
+// prefixes the DUT should send.  A neighbor which was not sent any
+// UPDATE has a zero First.
+func Analyze(neighbor string, start time.Time, base uint64, samples []Sample, want uint32) *Timing {
+	tm := &Timing{Neighbor: neighbor}
+	first := false
+	for _, s := range samples {
+		if s.Updates < base {
+			// The counter was reset with the session.
+			base = 0
+		}
+		if !first && s.Updates > base {
+			tm.First = s.Time.Sub(start)
+			first = true
+		}
+		tm.Updates = s.Updates - base
+		if s.Sent >= want {
+			tm.Last = s.Time.Sub(start)
+			tm.Complete = true
+			break
+		}
+	}
+	return tm
+}
+
+// Summary aggregates the timings of all the neighbors.
+type Summary struct {
+	Neighbors int
+	// FirstMin and FirstMax are the shortest and longest time to the
+	// first UPDATE, and LastMax the longest time to the last UPDATE of
+	// the neighbors which were sent all the prefixes.
+	FirstMin, FirstMax, LastMax time.Duration
+	// Incomplete are the neighbors which were not sent all the prefixes.
+	Incomplete []string
+}
+
+// Summarize aggregates the timings.
+func Summarize(timings []*Timing) *Summary {
+	s := &Summary{Neighbors: len(timings)}
+	seen := false
+	for _, tm := range timings {
+		if !tm.Complete {
+			s.Incomplete = append(s.Incomplete, tm.Neighbor)
+			continue
+		}
+		if !seen || tm.First < s.FirstMin {
+			s.FirstMin = tm.First
+		}
+		if tm.First > s.FirstMax {
+			s.FirstMax = tm.First
+		}
+		if tm.Last > s.LastMax {
+			s.LastMax = tm.Last
+		}
+		seen = true
+	}
+	return s
+}
+
+func (s *Summary) String() string {
+	str := fmt.Sprintf("%d neighbors: first UPDATE after %v to %v, last UPDATE after %v", s.Neighbors, s.FirstMin, s.FirstMax, s.LastMax)
+	if len(s.Incomplete) > 0 {
+		str += fmt.Sprintf(", incomplete: %v", s.Incomplete)
+	}
+	return str
+}
+
+// Recorder polls the counters of the BGP neighbors of the DUT while
+// their sessions are established.
+type Recorder struct {
+	DUT       *ondatra.DUTDevice
+	Neighbors []string
+	// AfiSafi is the address family of the prefixes, IPv4 unicast by
+	// default.
+	AfiSafi telemetry.E_BgpTypes_AFI_SAFI_TYPE
+	// Interval is the polling interval, 1s by default.
+	Interval time.Duration
+	// Timeout is how long to wait for all the prefixes to be sent, 10
+	// minutes by default.
+	Timeout time.Duration
+
+	bases map[string]uint64
+}
+
+func (r *Recorder) neighbor(addr string) *networkinstance.NetworkInstance_Protocol_Bgp_NeighborPath {
+	return r.DUT.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Neighbor(addr)
+}
+
+// Start reads the UPDATE counters of the neighbors.  It must be called
+// before their sessions are established.
+func (r *Recorder) Start(t testing.TB) {
+	t.Helper()
+	r.bases = map[string]uint64{}
+	for _, addr := range r.Neighbors {
+		if v := r.neighbor(addr).Messages().Sent().UPDATE().Lookup(t); v.IsPresent() {
+			r.bases[addr] = v.Val(t)
+		}
+	}
+}
+
+// sample reads the counters of the neighbor, and returns whether its
+// session is established.
+func (r *Recorder) sample(t testing.TB, addr string, afiSafi telemetry.E_BgpTypes_AFI_SAFI_TYPE) (Sample, bool) {
+	nbr := r.neighbor(addr)
+	var s Sample
+	if v := nbr.SessionState().Lookup(t); !v.IsPresent() || v.Val(t) != telemetry.Bgp_Neighbor_SessionState_ESTABLISHED {
+		return s, false
+	}
+	if v := nbr.Messages().Sent().UPDATE().Lookup(t); v.IsPresent() {
+		s.Updates = v.Val(t)
+	}
+	sent := nbr.AfiSafi(afiSafi).Prefixes().Sent().Lookup(t)
+	if sent.IsPresent() {
+		s.Sent = sent.Val(t)
+		s.Time = sent.Timestamp
+	} else {
+		s.Time = time.Now()
+	}
+	return s, true
+}
+
+// Wait polls the counters of the neighbors until the DUT sent them the
+// number of prefixes, and returns their timings.
+func (r *Recorder) Wait(t testing.TB, want uint32) []*Timing {
+	t.Helper()
+	if r.bases == nil {
+		t.Fatal("Recorder.Wait called before Recorder.Start")
+	}
+	afiSafi := r.AfiSafi
+	if afiSafi == telemetry.BgpTypes_AFI_SAFI_TYPE_UNSET {
+		afiSafi = telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_UNICAST
+	}
+	interval := r.Interval
+	if interval == 0 {
+		interval = time.Second
+	}
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Minute
+	}
+
+	samples := map[string][]Sample{}
+	done := map[string]bool{}
+	deadline := time.Now().Add(timeout)
+	for len(done) < len(r.Neighbors) && time.Now().Before(deadline) {
+		for _, addr := range r.Neighbors {
+			if done[addr] {
+				continue
+			}
+			s, ok := r.sample(t, addr, afiSafi)
+			if !ok {
+				continue
+			}
+			samples[addr] = append(samples[addr], s)
+			if s.Sent >= want {
+				done[addr] = true
+			}
+		}
+		time.Sleep(interval)
+	}
+
+	var timings []*Timing
+	for _, addr := range r.Neighbors {
+		start := time.Now()
+		if v := r.neighbor(addr).LastEstablished().Lookup(t); v.IsPresent() {
+			start = time.Unix(0, int64(v.Val(t)))
+		} else if len(samples[addr]) > 0 {
+			start = samples[addr][0].Time
+		}
+		tm := Analyze(addr, start, r.bases[addr], samples[addr], want)
+		t.Log(tm)
+		timings = append(timings, tm)
+	}
+	return timings
+}
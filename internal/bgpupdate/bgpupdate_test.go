@@ -0,0 +1,107 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bgpupdate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAnalyze(t *testing.T) {
+	start := time.Unix(1000, 0)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+
+	for _, c := range []struct {
+		desc    string
+		base    uint64
+		samples []Sample
+		want    *Timing
+	}{{
+		desc: "complete",
+		base: 10,
+		samples: []Sample{
+			{Time: at(1), Updates: 10},
+			{Time: at(2), Updates: 15, Sent: 500},
+			{Time: at(3), Updates: 30, Sent: 900},
+			{Time: at(4), Updates: 35, Sent: 1000},
+			{Time: at(5), Updates: 35, Sent: 1000},
+		},
+		want: &Timing{Neighbor: "n", First: 2 * time.Second, Last: 4 * time.Second, Updates: 25, Complete: true},
+	}, {
+		desc: "incomplete",
+		samples: []Sample{
+			{Time: at(1), Updates: 0},
+			{Time: at(3), Updates: 5, Sent: 100},
+		},
+		want: &Timing{Neighbor: "n", First: 3 * time.Second, Updates: 5},
+	}, {
+		desc: "counter reset with the session",
+		base: 100,
+		samples: []Sample{
+			{Time: at(1), Updates: 4, Sent: 400},
+			{Time: at(2), Updates: 10, Sent: 1000},
+		},
+		want: &Timing{Neighbor: "n", First: time.Second, Last: 2 * time.Second, Updates: 10, Complete: true},
+	}, {
+		desc: "no samples",
+		want: &Timing{Neighbor: "n"},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			got := Analyze("n", start, c.base, c.samples, 1000)
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Analyze() -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRate(t *testing.T) {
+	for _, c := range []struct {
+		tm   *Timing
+		want float64
+	}{
+		{&Timing{First: time.Second, Last: 3 * time.Second, Updates: 100}, 50},
+		{&Timing{First: time.Second, Last: time.Second, Updates: 100}, 0},
+	} {
+		if got := c.tm.Rate(); got != c.want {
+			t.Errorf("Rate(%v) got %v, want %v", c.tm, got, c.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	timings := []*Timing{
+		{Neighbor: "a", First: 2 * time.Second, Last: 10 * time.Second, Complete: true},
+		{Neighbor: "b", First: 1 * time.Second, Last: 12 * time.Second, Complete: true},
+		{Neighbor: "c", First: 5 * time.Second},
+		{Neighbor: "d", First: 3 * time.Second, Last: 8 * time.Second, Complete: true},
+	}
+	want := &Summary{
+		Neighbors:  4,
+		FirstMin:   time.Second,
+		FirstMax:   3 * time.Second,
+		LastMax:    12 * time.Second,
+		Incomplete: []string{"c"},
+	}
+	got := Summarize(timings)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Summarize() -want, +got:\n%s", diff)
+	}
+	if got, want := got.String(), "4 neighbors: first UPDATE after 1s to 3s, last UPDATE after 12s, incomplete: [c]"; got != want {
+		t.Errorf("Summary.String() got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package capture captures the packets received by OTG ATE ports and
+// decodes the headers that tests validate.
+//
+// Usage:
+//
+//	config := ate.OTG().NewConfig(t)
+//	...
+//	capture.Enable(config, "port1")
+//	ate.OTG().PushConfig(t, config)
+//	api := fptest.DialOTG(t, ate)
+//	capture.Start(t, api, "port1")
+//	ate.OTG().StartTraffic(t)
+//	...
+//	ate.OTG().StopTraffic(t)
+//	capture.Stop(t, api, "port1")
+//	for _, p := range capture.Fetch(t, api, "port1") {
+//	  if p.ICMP != nil { ... }
+//	}
+package capture
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+)
+
+// Enable adds a capture of the packets received by the ports to the
+// OTG configuration.  It must be pushed before starting the capture.
+func Enable(cfg gosnappi.Config, ports ...string) {
+	cfg.Captures().Add().
+		SetName("capture").
+		SetPortNames(ports).
+		SetFormat(gosnappi.CaptureFormat.PCAP)
+}
+
+// Start starts capturing on the ports.
+func Start(t testing.TB, api gosnappi.GosnappiApi, ports ...string) {
+	t.Helper()
+	setState(t, api, ports, gosnappi.CaptureStateState.START)
+}
+
+// Stop stops capturing on the ports.
+func Stop(t testing.TB, api gosnappi.GosnappiApi, ports ...string) {
+	t.Helper()
+	setState(t, api, ports, gosnappi.CaptureStateState.STOP)
+}
+
+func setState(t testing.TB, api gosnappi.GosnappiApi, ports []string, state gosnappi.CaptureStateStateEnum) {
+	t.Helper()
+	cs := gosnappi.NewCaptureState().SetPortNames(ports).SetState(state)
+	if _, err := api.SetCaptureState(cs); err != nil {
+		t.Fatalf("Cannot set capture state %v on %v: %v", state, ports, err)
+	}
+}
+
+// Fetch returns the packets captured on the port.  Packets that cannot
+// be fully decoded are logged and returned with the headers decoded.
+func Fetch(t testing.TB, api gosnappi.GosnappiApi, port string) []*Packet {
+	t.Helper()
+	b, err := api.GetCapture(gosnappi.NewCaptureRequest().SetPortName(port))
+	if err != nil {
+		t.Fatalf("Cannot fetch the capture of %s: %v", port, err)
+	}
+	frames, err := ReadPCAP(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Cannot read the capture of %s: %v", port, err)
+	}
+	var pkts []*Packet
+	for i, f := range frames {
+		p, err := Decode(f)
+		if err != nil {
+			t.Logf("Capture of %s, frame %d: %v", port, i, err)
+		}
+		pkts = append(pkts, p)
+	}
+	return pkts
+}
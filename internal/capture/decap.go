@@ -0,0 +1,44 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"fmt"
+	"net"
+)
+
+// VerifyDecapsulated returns the number of packets to the inner
+// destination dst forwarded decapsulated by the DUT, and an error for
+// the packets to dst still encapsulated.
+func VerifyDecapsulated(pkts []*Packet, dst string) (int, []error) {
+	dstIP := net.ParseIP(dst)
+	if dstIP == nil {
+		return 0, []error{fmt.Errorf("invalid destination address %q", dst)}
+	}
+	n, encapsulated := 0, 0
+	for _, p := range pkts {
+		switch {
+		case p.IP == nil:
+		case p.Inner != nil && p.Inner.IP != nil && p.Inner.IP.Dst.Equal(dstIP):
+			encapsulated++
+		case p.Inner == nil && p.IP.Dst.Equal(dstIP):
+			n++
+		}
+	}
+	if encapsulated > 0 {
+		return n, []error{fmt.Errorf("%d of %d packets to %s are still encapsulated", encapsulated, n+encapsulated, dst)}
+	}
+	return n, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import "testing"
+
+func TestVerifyDecapsulated(t *testing.T) {
+	udp := []byte{0, 1, 0, 2, 0, 8, 0, 0}
+	decode := func(b []byte) *Packet {
+		p, err := Decode(&Frame{Data: concat(ethernet(EtherTypeIPv4), b)})
+		if err != nil {
+			t.Fatalf("Decode got error: %v", err)
+		}
+		return p
+	}
+	inner := ipv4("198.51.100.1", "198.51.100.129", 0, 63, ProtocolUDP, false, udp)
+	decapsulated := decode(inner)
+	encapsulated := decode(ipv4("192.0.2.2", "203.0.113.100", 0, 64, ProtocolIPv4, false, inner))
+	other := decode(ipv4("198.51.100.1", "198.51.100.2", 0, 63, ProtocolUDP, false, udp))
+
+	cases := []struct {
+		desc     string
+		pkts     []*Packet
+		dst      string
+		want     int
+		wantErrs int
+	}{
+		{"decapsulated", []*Packet{decapsulated, decapsulated, other}, "198.51.100.129", 2, 0},
+		{"encapsulated", []*Packet{decapsulated, encapsulated, encapsulated}, "198.51.100.129", 1, 1},
+		{"other destination", []*Packet{other, encapsulated}, "198.51.100.2", 1, 0},
+		{"none", nil, "198.51.100.129", 0, 0},
+		{"no IP header", []*Packet{{}}, "198.51.100.129", 0, 0},
+		{"invalid destination", []*Packet{decapsulated}, "198.51.100", 0, 1},
+	}
+	for _, c := range cases {
+		got, errs := VerifyDecapsulated(c.pkts, c.dst)
+		if got != c.want || len(errs) != c.wantErrs {
+			t.Errorf("%s: VerifyDecapsulated() got %d, %v, want %d, %d errors", c.desc, got, errs, c.want, c.wantErrs)
+		}
+	}
+}
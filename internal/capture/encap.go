@@ -0,0 +1,180 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// EncapType is a UDP based encapsulation.
+type EncapType int
+
+const (
+	// MPLSInUDP is MPLS-in-UDP, RFC 7510.
+	MPLSInUDP EncapType = iota + 1
+	// GUEv1 is Generic UDP Encapsulation variant 1, which carries the
+	// IP packet directly after the UDP header.
+	GUEv1
+)
+
+func (e EncapType) String() string {
+	switch e {
+	case MPLSInUDP:
+		return "MPLS-in-UDP"
+	case GUEv1:
+		return "GUE variant 1"
+	}
+	return fmt.Sprintf("EncapType(%d)", int(e))
+}
+
+// port returns the UDP destination port of the encapsulation.
+func (e EncapType) port() uint16 {
+	if e == MPLSInUDP {
+		return PortMPLSInUDP
+	}
+	return PortGUE
+}
+
+// Tunnel describes the outer headers a next hop, e.g. programmed via
+// gRIBI, encapsulates packets with.
+type Tunnel struct {
+	Type     EncapType
+	Src, Dst string
+	// Labels is the MPLS label stack of MPLS-in-UDP, outermost first.
+	Labels []uint32
+	// DSCP is the outer DSCP.  When CopyDSCP is set, the outer DSCP is
+	// expected to be copied from the inner header instead.
+	DSCP     uint8
+	CopyDSCP bool
+	// TTL is the outer TTL or hop limit.
+	TTL uint8
+}
+
+// Encapsulate returns the inner IP packet encapsulated in the tunnel
+// headers, with the given UDP source port.  It is used to build the
+// packets expected from, or sent to, the DUT.
+func (tn *Tunnel) Encapsulate(inner []byte, srcPort uint16) ([]byte, error) {
+	src, dst := net.ParseIP(tn.Src), net.ParseIP(tn.Dst)
+	if src == nil || dst == nil || (src.To4() == nil) != (dst.To4() == nil) {
+		return nil, fmt.Errorf("invalid tunnel addresses %q and %q", tn.Src, tn.Dst)
+	}
+	if len(inner) == 0 {
+		return nil, fmt.Errorf("empty inner packet")
+	}
+	dscp := tn.DSCP
+	if tn.CopyDSCP {
+		in := &Packet{}
+		if err := in.decodeIP(inner); err != nil && in.IP == nil {
+			return nil, fmt.Errorf("cannot copy inner DSCP: %w", err)
+		}
+		dscp = in.IP.DSCP
+	}
+
+	var payload []byte
+	if tn.Type == MPLSInUDP {
+		if len(tn.Labels) == 0 {
+			return nil, fmt.Errorf("no MPLS label for %v", tn.Type)
+		}
+		for i, l := range tn.Labels {
+			v := l<<12 | uint32(tn.TTL)
+			if i == len(tn.Labels)-1 {
+				v |= 0x100
+			}
+			var lse [4]byte
+			binary.BigEndian.PutUint32(lse[:], v)
+			payload = append(payload, lse[:]...)
+		}
+	}
+	payload = append(payload, inner...)
+
+	udp := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(udp[0:], srcPort)
+	binary.BigEndian.PutUint16(udp[2:], tn.Type.port())
+	binary.BigEndian.PutUint16(udp[4:], uint16(8+len(payload)))
+	udp = append(udp, payload...)
+
+	if src4 := src.To4(); src4 != nil {
+		ip := make([]byte, 20, 20+len(udp))
+		ip[0] = 0x45
+		ip[1] = dscp << 2
+		binary.BigEndian.PutUint16(ip[2:], uint16(20+len(udp)))
+		ip[8] = tn.TTL
+		ip[9] = ProtocolUDP
+		copy(ip[12:], src4)
+		copy(ip[16:], dst.To4())
+		binary.BigEndian.PutUint16(ip[10:], checksum(ip))
+		return append(ip, udp...), nil
+	}
+	ip := make([]byte, 40, 40+len(udp))
+	binary.BigEndian.PutUint32(ip, 6<<28|uint32(dscp)<<22)
+	binary.BigEndian.PutUint16(ip[4:], uint16(len(udp)))
+	ip[6] = ProtocolUDP
+	ip[7] = tn.TTL
+	copy(ip[8:], src)
+	copy(ip[24:], dst)
+	return append(ip, udp...), nil
+}
+
+// checksum returns the IPv4 header checksum.
+func checksum(hdr []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(hdr); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(hdr[i:]))
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// Verify returns the differences between the outer headers of a
+// captured packet and the tunnel.
+func (tn *Tunnel) Verify(p *Packet) []error {
+	if p.IP == nil || p.UDP == nil || p.Inner == nil || p.Inner.IP == nil {
+		return []error{fmt.Errorf("packet is not %v encapsulated", tn.Type)}
+	}
+	var errs []error
+	if got, want := p.UDP.DstPort, tn.Type.port(); got != want {
+		errs = append(errs, fmt.Errorf("UDP destination port got %d, want %d", got, want))
+	}
+	if !p.IP.Src.Equal(net.ParseIP(tn.Src)) {
+		errs = append(errs, fmt.Errorf("outer source got %v, want %s", p.IP.Src, tn.Src))
+	}
+	if !p.IP.Dst.Equal(net.ParseIP(tn.Dst)) {
+		errs = append(errs, fmt.Errorf("outer destination got %v, want %s", p.IP.Dst, tn.Dst))
+	}
+	want := tn.DSCP
+	if tn.CopyDSCP {
+		want = p.Inner.IP.DSCP
+	}
+	if p.IP.DSCP != want {
+		errs = append(errs, fmt.Errorf("outer DSCP got %d, want %d", p.IP.DSCP, want))
+	}
+	if p.IP.TTL != tn.TTL {
+		errs = append(errs, fmt.Errorf("outer TTL got %d, want %d", p.IP.TTL, tn.TTL))
+	}
+	if tn.Type == MPLSInUDP {
+		var labels []uint32
+		for _, m := range p.MPLS {
+			labels = append(labels, m.Label)
+		}
+		if fmt.Sprint(labels) != fmt.Sprint(tn.Labels) {
+			errs = append(errs, fmt.Errorf("MPLS labels got %v, want %v", labels, tn.Labels))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"testing"
+)
+
+// innerPacket is an IPv4 UDP packet with DSCP 10.
+var innerPacket = ipv4("198.51.100.1", "203.0.113.1", 10<<2, 63, ProtocolUDP, false, []byte{0, 1, 0, 2, 0, 9, 0, 0, 0xff})
+
+func TestTunnelRoundTrip(t *testing.T) {
+	cases := []struct {
+		desc string
+		tn   *Tunnel
+	}{
+		{"mpls-in-udp ipv4", &Tunnel{Type: MPLSInUDP, Src: "192.0.2.1", Dst: "192.0.2.9", Labels: []uint32{100, 200}, DSCP: 46, TTL: 64}},
+		{"mpls-in-udp ipv6", &Tunnel{Type: MPLSInUDP, Src: "2001:db8::1", Dst: "2001:db8::9", Labels: []uint32{100}, TTL: 255}},
+		{"gue ipv4 copy dscp", &Tunnel{Type: GUEv1, Src: "192.0.2.1", Dst: "192.0.2.9", CopyDSCP: true, TTL: 64}},
+		{"gue ipv6", &Tunnel{Type: GUEv1, Src: "2001:db8::1", Dst: "2001:db8::9", DSCP: 8, TTL: 1}},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			b, err := c.tn.Encapsulate(innerPacket, 49152)
+			if err != nil {
+				t.Fatalf("Encapsulate got error: %v", err)
+			}
+			etherType := uint16(EtherTypeIPv4)
+			if b[0]>>4 == 6 {
+				etherType = EtherTypeIPv6
+			}
+			p, err := Decode(&Frame{Data: concat(ethernet(etherType), b)})
+			if err != nil {
+				t.Fatalf("Decode got error: %v", err)
+			}
+			if errs := c.tn.Verify(p); len(errs) > 0 {
+				t.Errorf("Verify got errors: %v", errs)
+			}
+			if got := p.Inner.IP.Dst.String(); got != "203.0.113.1" {
+				t.Errorf("Inner destination got %s, want 203.0.113.1", got)
+			}
+			if got := p.Inner.UDP.DstPort; got != 2 {
+				t.Errorf("Inner UDP destination port got %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestTunnelVerifyMismatch(t *testing.T) {
+	sent := &Tunnel{Type: MPLSInUDP, Src: "192.0.2.1", Dst: "192.0.2.9", Labels: []uint32{100}, DSCP: 46, TTL: 64}
+	b, err := sent.Encapsulate(innerPacket, 49152)
+	if err != nil {
+		t.Fatalf("Encapsulate got error: %v", err)
+	}
+	p, err := Decode(&Frame{Data: concat(ethernet(EtherTypeIPv4), b)})
+	if err != nil {
+		t.Fatalf("Decode got error: %v", err)
+	}
+	cases := []struct {
+		desc    string
+		want    *Tunnel
+		wantErr int
+	}{
+		{"copy dscp", &Tunnel{Type: MPLSInUDP, Src: "192.0.2.1", Dst: "192.0.2.9", Labels: []uint32{100}, CopyDSCP: true, TTL: 64}, 1},
+		{"ttl and labels", &Tunnel{Type: MPLSInUDP, Src: "192.0.2.1", Dst: "192.0.2.9", Labels: []uint32{101}, DSCP: 46, TTL: 1}, 2},
+		{"gue", &Tunnel{Type: GUEv1, Src: "192.0.2.2", Dst: "192.0.2.9", DSCP: 46, TTL: 64}, 2},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			if errs := c.want.Verify(p); len(errs) != c.wantErr {
+				t.Errorf("Verify got errors %v, want %d errors", errs, c.wantErr)
+			}
+		})
+	}
+	if errs := sent.Verify(&Packet{}); len(errs) != 1 {
+		t.Errorf("Verify of an empty packet got errors %v, want 1 error", errs)
+	}
+}
+
+func TestEncapsulateError(t *testing.T) {
+	cases := []struct {
+		desc  string
+		tn    *Tunnel
+		inner []byte
+	}{
+		{"bad address", &Tunnel{Type: GUEv1, Src: "bogus", Dst: "192.0.2.9"}, innerPacket},
+		{"mixed families", &Tunnel{Type: GUEv1, Src: "2001:db8::1", Dst: "192.0.2.9"}, innerPacket},
+		{"no labels", &Tunnel{Type: MPLSInUDP, Src: "192.0.2.1", Dst: "192.0.2.9"}, innerPacket},
+		{"empty inner", &Tunnel{Type: GUEv1, Src: "192.0.2.1", Dst: "192.0.2.9"}, nil},
+		{"bad inner", &Tunnel{Type: GUEv1, Src: "192.0.2.1", Dst: "192.0.2.9", CopyDSCP: true}, []byte{0x45}},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			if _, err := c.tn.Encapsulate(c.inner, 49152); err == nil {
+				t.Errorf("Encapsulate got no error, want error")
+			}
+		})
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	// An IPv4 header with a known checksum.
+	hdr := []byte{0x45, 0x00, 0x00, 0x73, 0x00, 0x00, 0x40, 0x00, 0x40, 0x11, 0x00, 0x00, 0xc0, 0xa8, 0x00, 0x01, 0xc0, 0xa8, 0x00, 0xc7}
+	if got, want := checksum(hdr), uint16(0xb861); got != want {
+		t.Errorf("checksum got %#x, want %#x", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ControlClass is a class of control plane traffic originated by the
+// DUT, identified by its transport protocol and well-known port.
+type ControlClass struct {
+	Name     string
+	Protocol uint8
+	Port     uint16
+}
+
+// Control plane traffic classes.
+var (
+	// ControlBGP is BGP, over TCP port 179.
+	ControlBGP = ControlClass{Name: "bgp", Protocol: ProtocolTCP, Port: 179}
+	// ControlGNMI is gNMI, over TCP port 9339.  It is only visible to
+	// the ATE when the DUT reaches a collector in-band.
+	ControlGNMI = ControlClass{Name: "gnmi", Protocol: ProtocolTCP, Port: 9339}
+	// ControlNTP is NTP, over UDP port 123.
+	ControlNTP = ControlClass{Name: "ntp", Protocol: ProtocolUDP, Port: 123}
+	// ControlDNS is DNS, over UDP port 53.
+	ControlDNS = ControlClass{Name: "dns", Protocol: ProtocolUDP, Port: 53}
+
+	// ControlClasses are all the classes above.
+	ControlClasses = []ControlClass{ControlBGP, ControlGNMI, ControlNTP, ControlDNS}
+)
+
+// Match reports whether the packet is of the class and originated by
+// src.  The port is matched on either side, since the DUT may be the
+// client or the server of the session.
+func (c ControlClass) Match(p *Packet, src net.IP) bool {
+	if p.IP == nil || p.IP.Protocol != c.Protocol || !p.IP.Src.Equal(src) {
+		return false
+	}
+	switch {
+	case p.TCP != nil:
+		return p.TCP.SrcPort == c.Port || p.TCP.DstPort == c.Port
+	case p.UDP != nil:
+		return p.UDP.SrcPort == c.Port || p.UDP.DstPort == c.Port
+	}
+	return false
+}
+
+// MarkingProfile is the DSCP the DUT marks the control plane traffic
+// it originates with, keyed by class name.
+type MarkingProfile map[string]uint8
+
+// ParseMarkingProfile parses a comma separated list of DSCP values
+// keyed by class name, in the form "class=dscp", e.g. "bgp=48,ntp=46".
+func ParseMarkingProfile(s string) (MarkingProfile, error) {
+	mp := MarkingProfile{}
+	if s == "" {
+		return mp, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("marking %q is not of the form class=dscp", item)
+		}
+		dscp, err := strconv.ParseUint(kv[1], 10, 8)
+		if err != nil || dscp > 63 {
+			return nil, fmt.Errorf("marking %q has an invalid DSCP", item)
+		}
+		mp[kv[0]] = uint8(dscp)
+	}
+	return mp, nil
+}
+
+// VerifyMarking returns the number of packets of the class originated
+// by src, and the differences between their DSCP and the one wanted.
+// Packets with the same wrong DSCP are reported by a single error.
+func VerifyMarking(pkts []*Packet, class ControlClass, src string, dscp uint8) (int, []error) {
+	srcIP := net.ParseIP(src)
+	if srcIP == nil {
+		return 0, []error{fmt.Errorf("invalid source address %q", src)}
+	}
+	n := 0
+	wrong := map[uint8]int{}
+	for _, p := range pkts {
+		if !class.Match(p, srcIP) {
+			continue
+		}
+		n++
+		if p.IP.DSCP != dscp {
+			wrong[p.IP.DSCP]++
+		}
+	}
+	var got []int
+	for d := range wrong {
+		got = append(got, int(d))
+	}
+	sort.Ints(got)
+	var errs []error
+	for _, d := range got {
+		errs = append(errs, fmt.Errorf("%d of %d %s packets from %s got DSCP %d, want %d", wrong[uint8(d)], n, class.Name, src, d, dscp))
+	}
+	return n, errs
+}
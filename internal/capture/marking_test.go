@@ -0,0 +1,80 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseMarkingProfile(t *testing.T) {
+	got, err := ParseMarkingProfile("bgp=48,ntp=46,dns=0")
+	if err != nil {
+		t.Fatalf("ParseMarkingProfile got error: %v", err)
+	}
+	want := MarkingProfile{"bgp": 48, "ntp": 46, "dns": 0}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseMarkingProfile got diff (-want +got):\n%s", diff)
+	}
+	for _, s := range []string{"bgp", "=48", "bgp=64", "bgp=cs6"} {
+		if _, err := ParseMarkingProfile(s); err == nil {
+			t.Errorf("ParseMarkingProfile(%q) got no error, want error", s)
+		}
+	}
+}
+
+func TestVerifyMarking(t *testing.T) {
+	dut, ate := "192.0.2.1", "192.0.2.2"
+	pkt := func(src string, dscp, proto uint8, port uint16) *Packet {
+		p := &Packet{IP: &IP{Version: 4, Src: net.ParseIP(src), DSCP: dscp, Protocol: proto}}
+		if proto == ProtocolTCP {
+			p.TCP = &TCP{SrcPort: 50000, DstPort: port}
+		} else {
+			p.UDP = &UDP{SrcPort: port, DstPort: port}
+		}
+		return p
+	}
+	pkts := []*Packet{
+		pkt(dut, 48, ProtocolTCP, 179),
+		pkt(dut, 48, ProtocolTCP, 179),
+		pkt(dut, 0, ProtocolTCP, 179),
+		pkt(ate, 0, ProtocolTCP, 179),
+		pkt(dut, 46, ProtocolUDP, 123),
+		pkt(dut, 0, ProtocolUDP, 179),
+		{Ethernet: &Ethernet{EtherType: 0x0806}},
+	}
+
+	n, errs := VerifyMarking(pkts, ControlBGP, dut, 48)
+	if n != 3 {
+		t.Errorf("VerifyMarking of bgp got %d packets, want 3", n)
+	}
+	if len(errs) != 1 {
+		t.Errorf("VerifyMarking of bgp got errors %v, want 1 error", errs)
+	}
+
+	n, errs = VerifyMarking(pkts, ControlNTP, dut, 46)
+	if n != 1 || len(errs) != 0 {
+		t.Errorf("VerifyMarking of ntp got %d packets and errors %v, want 1 packet and no error", n, errs)
+	}
+
+	if n, _ := VerifyMarking(pkts, ControlDNS, dut, 0); n != 0 {
+		t.Errorf("VerifyMarking of dns got %d packets, want 0", n)
+	}
+	if _, errs := VerifyMarking(pkts, ControlBGP, "dut", 48); len(errs) == 0 {
+		t.Error("VerifyMarking with an invalid source got no error, want error")
+	}
+}
@@ -0,0 +1,321 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// EtherTypes decoded.
+const (
+	EtherTypeIPv4 = 0x0800
+	EtherTypeIPv6 = 0x86dd
+	EtherTypeVLAN = 0x8100
+)
+
+// IP protocol numbers decoded.
+const (
+	ProtocolICMP = 1
+	ProtocolIPv4 = 4
+	ProtocolTCP  = 6
+	ProtocolUDP  = 17
+	ProtocolIPv6 = 41
+	// ProtocolRouting is the IPv6 routing header, which carries the
+	// SRv6 segment routing header, RFC 8754.
+	ProtocolRouting = 43
+	ProtocolICMPv6  = 58
+)
+
+// UDP destination ports of the encapsulations decoded.
+const (
+	// PortMPLSInUDP is the port of MPLS-in-UDP, RFC 7510.
+	PortMPLSInUDP = 6635
+	// PortGUE is the port of Generic UDP Encapsulation.
+	PortGUE = 6080
+	// PortVXLAN is the port of VXLAN, RFC 7348.
+	PortVXLAN = 4789
+)
+
+var errTruncated = errors.New("truncated")
+
+// Packet is a decoded packet.  The layers not present in the packet
+// are nil.
+type Packet struct {
+	Time     time.Time
+	Ethernet *Ethernet
+	IP       *IP
+	ICMP     *ICMP
+	TCP      *TCP
+	UDP      *UDP
+	// SRH is the segment routing header of SRv6 packets.
+	SRH *SRH
+	// MPLS is the MPLS label stack of MPLS-in-UDP packets, outermost
+	// first.
+	MPLS []*MPLS
+	// VXLAN is the VXLAN header of VXLAN packets.
+	VXLAN *VXLAN
+	// Inner is the IP packet encapsulated by MPLS-in-UDP, GUE,
+	// IP-in-IP or SRv6, which has no Ethernet header, or the Ethernet
+	// frame encapsulated by VXLAN.
+	Inner *Packet
+	// Payload is the data following the last decoded header.
+	Payload []byte
+}
+
+// Ethernet is an Ethernet header.
+type Ethernet struct {
+	Src, Dst net.HardwareAddr
+	// VLANs are the VLAN IDs of the 802.1Q tags, outermost first.
+	VLANs     []uint16
+	EtherType uint16
+}
+
+// IP is an IPv4 or IPv6 header.
+type IP struct {
+	Version  int
+	Src, Dst net.IP
+	DSCP     uint8
+	ECN      uint8
+	// TTL is the IPv4 TTL or the IPv6 hop limit.
+	TTL uint8
+	// Protocol is the IPv4 protocol or the IPv6 next header.
+	Protocol     uint8
+	DontFragment bool
+}
+
+// ICMP is an ICMP or ICMPv6 header.
+type ICMP struct {
+	Type, Code uint8
+}
+
+// TCP is a TCP header.
+type TCP struct {
+	SrcPort, DstPort uint16
+	Flags            uint8
+}
+
+// UDP is a UDP header.
+type UDP struct {
+	SrcPort, DstPort uint16
+}
+
+// MPLS is an MPLS label stack entry.
+type MPLS struct {
+	Label uint32
+	TC    uint8
+	// S is the bottom of stack bit.
+	S   bool
+	TTL uint8
+}
+
+// VXLAN is a VXLAN header.
+type VXLAN struct {
+	VNI uint32
+}
+
+// Decode decodes an Ethernet frame.  The headers decoded are returned
+// even if a later one is truncated.
+func Decode(f *Frame) (*Packet, error) {
+	p := &Packet{Time: f.Time}
+	b, err := p.decodeEthernet(f.Data)
+	if err != nil {
+		return p, fmt.Errorf("ethernet: %w", err)
+	}
+	p.Payload = b
+	switch p.Ethernet.EtherType {
+	case EtherTypeIPv4, EtherTypeIPv6:
+		return p, p.decodeIP(b)
+	}
+	return p, nil
+}
+
+func (p *Packet) decodeEthernet(b []byte) ([]byte, error) {
+	if len(b) < 14 {
+		return nil, errTruncated
+	}
+	e := &Ethernet{
+		Dst:       net.HardwareAddr(b[0:6]),
+		Src:       net.HardwareAddr(b[6:12]),
+		EtherType: binary.BigEndian.Uint16(b[12:]),
+	}
+	b = b[14:]
+	for e.EtherType == EtherTypeVLAN {
+		if len(b) < 4 {
+			return nil, errTruncated
+		}
+		e.VLANs = append(e.VLANs, binary.BigEndian.Uint16(b)&0xfff)
+		e.EtherType = binary.BigEndian.Uint16(b[2:])
+		b = b[4:]
+	}
+	p.Ethernet = e
+	return b, nil
+}
+
+// decodeIP decodes an IP packet and the layers it carries.
+func (p *Packet) decodeIP(b []byte) error {
+	var err error
+	if len(b) > 0 && b[0]>>4 == 6 {
+		b, err = p.decodeIPv6(b)
+	} else {
+		b, err = p.decodeIPv4(b)
+	}
+	if err != nil {
+		return fmt.Errorf("ip: %w", err)
+	}
+	p.Payload = b
+	proto := p.IP.Protocol
+	if p.IP.Version == 6 && proto == ProtocolRouting {
+		if b, err = p.decodeSRH(b); err != nil {
+			return fmt.Errorf("srh: %w", err)
+		}
+		p.Payload = b
+		proto = p.SRH.NextHeader
+	}
+	switch proto {
+	case ProtocolICMP, ProtocolICMPv6:
+		if len(b) < 8 {
+			return fmt.Errorf("icmp: %w", errTruncated)
+		}
+		p.ICMP = &ICMP{Type: b[0], Code: b[1]}
+		p.Payload = b[8:]
+	case ProtocolIPv4, ProtocolIPv6:
+		return p.decodeInner(b)
+	case ProtocolTCP:
+		if len(b) < 20 {
+			return fmt.Errorf("tcp: %w", errTruncated)
+		}
+		off := int(b[12]>>4) * 4
+		if off < 20 || len(b) < off {
+			return fmt.Errorf("tcp: %w", errTruncated)
+		}
+		p.TCP = &TCP{
+			SrcPort: binary.BigEndian.Uint16(b),
+			DstPort: binary.BigEndian.Uint16(b[2:]),
+			Flags:   b[13],
+		}
+		p.Payload = b[off:]
+	case ProtocolUDP:
+		if len(b) < 8 {
+			return fmt.Errorf("udp: %w", errTruncated)
+		}
+		p.UDP = &UDP{
+			SrcPort: binary.BigEndian.Uint16(b),
+			DstPort: binary.BigEndian.Uint16(b[2:]),
+		}
+		p.Payload = b[8:]
+		return p.decodeUDPPayload(b[8:])
+	}
+	return nil
+}
+
+// decodeUDPPayload decodes the packets encapsulated in UDP.
+func (p *Packet) decodeUDPPayload(b []byte) error {
+	switch p.UDP.DstPort {
+	case PortMPLSInUDP:
+		for {
+			if len(b) < 4 {
+				return fmt.Errorf("mpls: %w", errTruncated)
+			}
+			v := binary.BigEndian.Uint32(b)
+			m := &MPLS{Label: v >> 12, TC: uint8(v>>9) & 7, S: v&0x100 != 0, TTL: uint8(v)}
+			p.MPLS = append(p.MPLS, m)
+			b = b[4:]
+			if m.S {
+				break
+			}
+		}
+		p.Payload = b
+		return p.decodeInner(b)
+	case PortGUE:
+		// Variant 1 of GUE has no GUE header: the first two bits of the
+		// IP version are 01.
+		if len(b) > 0 && b[0]>>6 == 1 {
+			return p.decodeInner(b)
+		}
+	case PortVXLAN:
+		if len(b) < 8 {
+			return fmt.Errorf("vxlan: %w", errTruncated)
+		}
+		p.VXLAN = &VXLAN{VNI: binary.BigEndian.Uint32(b[4:]) >> 8}
+		p.Payload = b[8:]
+		inner, err := Decode(&Frame{Time: p.Time, Data: b[8:]})
+		p.Inner = inner
+		if err != nil {
+			return fmt.Errorf("inner %w", err)
+		}
+	}
+	return nil
+}
+
+// decodeInner decodes an encapsulated IP packet.
+func (p *Packet) decodeInner(b []byte) error {
+	p.Inner = &Packet{Time: p.Time}
+	if err := p.Inner.decodeIP(b); err != nil {
+		return fmt.Errorf("inner %w", err)
+	}
+	return nil
+}
+
+func (p *Packet) decodeIPv4(b []byte) ([]byte, error) {
+	if len(b) < 20 {
+		return nil, errTruncated
+	}
+	if v := b[0] >> 4; v != 4 {
+		return nil, fmt.Errorf("unexpected IP version %d", v)
+	}
+	ihl := int(b[0]&0xf) * 4
+	if ihl < 20 || len(b) < ihl {
+		return nil, errTruncated
+	}
+	p.IP = &IP{
+		Version:      4,
+		DSCP:         b[1] >> 2,
+		ECN:          b[1] & 3,
+		DontFragment: b[6]&0x40 != 0,
+		TTL:          b[8],
+		Protocol:     b[9],
+		Src:          net.IP(b[12:16]),
+		Dst:          net.IP(b[16:20]),
+	}
+	// Trim the Ethernet padding of short packets.
+	if n := int(binary.BigEndian.Uint16(b[2:])); n >= ihl && n < len(b) {
+		b = b[:n]
+	}
+	return b[ihl:], nil
+}
+
+func (p *Packet) decodeIPv6(b []byte) ([]byte, error) {
+	if len(b) < 40 {
+		return nil, errTruncated
+	}
+	tc := uint8(binary.BigEndian.Uint16(b) >> 4)
+	p.IP = &IP{
+		Version:  6,
+		DSCP:     tc >> 2,
+		ECN:      tc & 3,
+		Protocol: b[6],
+		TTL:      b[7],
+		Src:      net.IP(b[8:24]),
+		Dst:      net.IP(b[24:40]),
+	}
+	if n := 40 + int(binary.BigEndian.Uint16(b[4:])); n < len(b) {
+		b = b[:n]
+	}
+	return b[40:], nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+var (
+	srcMAC = net.HardwareAddr{0x02, 0, 0, 0, 0, 1}
+	dstMAC = net.HardwareAddr{0x02, 0, 0, 0, 0, 2}
+	// ipComparer compares IPv4 addresses in their 4 and 16 byte forms.
+	// It is filtered on the net.IP type, which []byte payloads are
+	// otherwise assignable to.
+	ipComparer = cmp.FilterPath(func(p cmp.Path) bool {
+		return p.Last().Type() == reflect.TypeOf(net.IP(nil))
+	}, cmp.Comparer(func(a, b net.IP) bool { return a.Equal(b) }))
+)
+
+// ethernet returns an Ethernet header with the VLAN tags given.
+func ethernet(etherType uint16, vlans ...uint16) []byte {
+	b := append(append([]byte{}, dstMAC...), srcMAC...)
+	for _, v := range vlans {
+		b = append(b, 0x81, 0x00, byte(v>>8), byte(v))
+	}
+	return append(b, byte(etherType>>8), byte(etherType))
+}
+
+// ipv4 returns an IPv4 header followed by the payload.
+func ipv4(src, dst string, tos, ttl, proto uint8, df bool, payload []byte) []byte {
+	b := make([]byte, 20)
+	b[0] = 0x45
+	b[1] = tos
+	binary.BigEndian.PutUint16(b[2:], uint16(20+len(payload)))
+	if df {
+		b[6] = 0x40
+	}
+	b[8] = ttl
+	b[9] = proto
+	copy(b[12:], net.ParseIP(src).To4())
+	copy(b[16:], net.ParseIP(dst).To4())
+	return append(b, payload...)
+}
+
+// ipv6 returns an IPv6 header followed by the payload.
+func ipv6(src, dst string, tc, hopLimit, next uint8, payload []byte) []byte {
+	b := make([]byte, 40)
+	binary.BigEndian.PutUint32(b, 6<<28|uint32(tc)<<20)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(payload)))
+	b[6] = next
+	b[7] = hopLimit
+	copy(b[8:], net.ParseIP(src))
+	copy(b[24:], net.ParseIP(dst))
+	return append(b, payload...)
+}
+
+// tcp returns a TCP header with the given flags and optLen bytes of
+// options, followed by the payload.
+func tcp(src, dst uint16, flags uint8, optLen int, payload []byte) []byte {
+	b := make([]byte, 20+optLen)
+	binary.BigEndian.PutUint16(b, src)
+	binary.BigEndian.PutUint16(b[2:], dst)
+	b[12] = uint8((20+optLen)/4) << 4
+	b[13] = flags
+	return append(b, payload...)
+}
+
+// udp returns a UDP header followed by the payload.
+func udp(src, dst uint16, payload []byte) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint16(b, src)
+	binary.BigEndian.PutUint16(b[2:], dst)
+	binary.BigEndian.PutUint16(b[4:], uint16(8+len(payload)))
+	return append(b, payload...)
+}
+
+// vxlan returns a VXLAN header of the VNI followed by the payload.
+func vxlan(vni uint32, payload []byte) []byte {
+	b := make([]byte, 8)
+	b[0] = 0x08
+	binary.BigEndian.PutUint32(b[4:], vni<<8)
+	return append(b, payload...)
+}
+
+func concat(bs ...[]byte) []byte {
+	var out []byte
+	for _, b := range bs {
+		out = append(out, b...)
+	}
+	return out
+}
+
+func TestDecode(t *testing.T) {
+	icmp := []byte{11, 0, 0, 0, 0, 0, 0, 0, 0xaa}
+	cases := []struct {
+		desc string
+		data []byte
+		want *Packet
+	}{{
+		desc: "ipv4 icmp padded",
+		data: concat(ethernet(EtherTypeIPv4), ipv4("192.0.2.1", "192.0.2.2", 0xb9, 64, ProtocolICMP, true, icmp), make([]byte, 10)),
+		want: &Packet{
+			Ethernet: &Ethernet{Src: srcMAC, Dst: dstMAC, EtherType: EtherTypeIPv4},
+			IP: &IP{
+				Version:      4,
+				Src:          net.ParseIP("192.0.2.1"),
+				Dst:          net.ParseIP("192.0.2.2"),
+				DSCP:         46,
+				ECN:          1,
+				TTL:          64,
+				Protocol:     ProtocolICMP,
+				DontFragment: true,
+			},
+			ICMP:    &ICMP{Type: 11},
+			Payload: []byte{0xaa},
+		},
+	}, {
+		desc: "vlan ipv6 icmpv6",
+		data: concat(ethernet(EtherTypeIPv6, 10, 20), ipv6("2001:db8::1", "2001:db8::2", 0x2b, 1, ProtocolICMPv6, []byte{3, 0, 0, 0, 0, 0, 0, 0})),
+		want: &Packet{
+			Ethernet: &Ethernet{Src: srcMAC, Dst: dstMAC, VLANs: []uint16{10, 20}, EtherType: EtherTypeIPv6},
+			IP: &IP{
+				Version:  6,
+				Src:      net.ParseIP("2001:db8::1"),
+				Dst:      net.ParseIP("2001:db8::2"),
+				DSCP:     10,
+				ECN:      3,
+				TTL:      1,
+				Protocol: ProtocolICMPv6,
+			},
+			ICMP: &ICMP{Type: 3},
+		},
+	}, {
+		desc: "ipv4 tcp with options",
+		data: concat(ethernet(EtherTypeIPv4), ipv4("192.0.2.1", "192.0.2.2", 0xc0, 1, ProtocolTCP, true, tcp(179, 50000, 0x18, 4, []byte{0xbb}))),
+		want: &Packet{
+			Ethernet: &Ethernet{Src: srcMAC, Dst: dstMAC, EtherType: EtherTypeIPv4},
+			IP: &IP{
+				Version:      4,
+				Src:          net.ParseIP("192.0.2.1"),
+				Dst:          net.ParseIP("192.0.2.2"),
+				DSCP:         48,
+				TTL:          1,
+				Protocol:     ProtocolTCP,
+				DontFragment: true,
+			},
+			TCP:     &TCP{SrcPort: 179, DstPort: 50000, Flags: 0x18},
+			Payload: []byte{0xbb},
+		},
+	}, {
+		desc: "vxlan",
+		data: concat(ethernet(EtherTypeIPv4), ipv4("192.0.2.6", "203.0.113.1", 0, 64, ProtocolUDP, false,
+			udp(49152, PortVXLAN, vxlan(10100, concat(ethernet(EtherTypeIPv4), ipv4("198.51.100.101", "198.51.100.1", 0, 64, ProtocolICMP, false, icmp)))))),
+		want: &Packet{
+			Ethernet: &Ethernet{Src: srcMAC, Dst: dstMAC, EtherType: EtherTypeIPv4},
+			IP: &IP{
+				Version:  4,
+				Src:      net.ParseIP("192.0.2.6"),
+				Dst:      net.ParseIP("203.0.113.1"),
+				TTL:      64,
+				Protocol: ProtocolUDP,
+			},
+			UDP:     &UDP{SrcPort: 49152, DstPort: PortVXLAN},
+			VXLAN:   &VXLAN{VNI: 10100},
+			Payload: concat(ethernet(EtherTypeIPv4), ipv4("198.51.100.101", "198.51.100.1", 0, 64, ProtocolICMP, false, icmp)),
+			Inner: &Packet{
+				Ethernet: &Ethernet{Src: srcMAC, Dst: dstMAC, EtherType: EtherTypeIPv4},
+				IP: &IP{
+					Version:  4,
+					Src:      net.ParseIP("198.51.100.101"),
+					Dst:      net.ParseIP("198.51.100.1"),
+					TTL:      64,
+					Protocol: ProtocolICMP,
+				},
+				ICMP:    &ICMP{Type: 11},
+				Payload: []byte{0xaa},
+			},
+		},
+	}, {
+		desc: "arp",
+		data: concat(ethernet(0x0806), []byte{0, 1}),
+		want: &Packet{
+			Ethernet: &Ethernet{Src: srcMAC, Dst: dstMAC, EtherType: 0x0806},
+			Payload:  []byte{0, 1},
+		},
+	}}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := Decode(&Frame{Data: c.data})
+			if err != nil {
+				t.Fatalf("Decode got error: %v", err)
+			}
+			if diff := cmp.Diff(c.want, got, ipComparer, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Decode got diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	cases := []struct {
+		desc string
+		data []byte
+	}{
+		{"short ethernet", make([]byte, 10)},
+		{"short vlan", ethernet(EtherTypeVLAN)},
+		{"short ipv4", concat(ethernet(EtherTypeIPv4), make([]byte, 10))},
+		{"short ipv6", concat(ethernet(EtherTypeIPv6), []byte{0x60, 0, 0, 0})},
+		{"short tcp", concat(ethernet(EtherTypeIPv4), ipv4("192.0.2.1", "192.0.2.2", 0, 64, ProtocolTCP, false, make([]byte, 12)))},
+		{"short tcp options", concat(ethernet(EtherTypeIPv4), ipv4("192.0.2.1", "192.0.2.2", 0, 64, ProtocolTCP, false, tcp(179, 179, 0x10, 8, nil)[:24]))},
+		{"short icmp", concat(ethernet(EtherTypeIPv4), ipv4("192.0.2.1", "192.0.2.2", 0, 64, ProtocolICMP, false, []byte{3, 1}))},
+		{"short vxlan", concat(ethernet(EtherTypeIPv4), ipv4("192.0.2.6", "203.0.113.1", 0, 64, ProtocolUDP, false, udp(49152, PortVXLAN, []byte{0x08, 0})))},
+		{"short vxlan inner", concat(ethernet(EtherTypeIPv4), ipv4("192.0.2.6", "203.0.113.1", 0, 64, ProtocolUDP, false, udp(49152, PortVXLAN, vxlan(10100, make([]byte, 10)))))},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			if _, err := Decode(&Frame{Data: c.data}); err == nil {
+				t.Errorf("Decode got no error, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Magic numbers of the pcap file format, for microsecond and
+// nanosecond timestamps.
+const (
+	magicMicro = 0xa1b2c3d4
+	magicNano  = 0xa1b23c4d
+)
+
+// linkTypeEthernet is the pcap link type of Ethernet frames.
+const linkTypeEthernet = 1
+
+// Frame is a captured frame.
+type Frame struct {
+	Time time.Time
+	Data []byte
+}
+
+// ReadPCAP reads the Ethernet frames of a capture in the pcap file
+// format.
+func ReadPCAP(r io.Reader) ([]*Frame, error) {
+	var hdr [24]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("cannot read pcap header: %w", err)
+	}
+	var order binary.ByteOrder
+	var nano bool
+	switch {
+	case binary.LittleEndian.Uint32(hdr[:]) == magicMicro:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(hdr[:]) == magicMicro:
+		order = binary.BigEndian
+	case binary.LittleEndian.Uint32(hdr[:]) == magicNano:
+		order, nano = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(hdr[:]) == magicNano:
+		order, nano = binary.BigEndian, true
+	default:
+		return nil, fmt.Errorf("not a pcap file, magic number %#x", hdr[:4])
+	}
+	if lt := order.Uint32(hdr[20:]); lt != linkTypeEthernet {
+		return nil, fmt.Errorf("unsupported pcap link type %d", lt)
+	}
+	snaplen := order.Uint32(hdr[16:])
+
+	var frames []*Frame
+	for {
+		var rec [16]byte
+		if _, err := io.ReadFull(r, rec[:]); err == io.EOF {
+			return frames, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot read record %d header: %w", len(frames), err)
+		}
+		sec, frac := int64(order.Uint32(rec[0:])), int64(order.Uint32(rec[4:]))
+		if !nano {
+			frac *= int64(time.Microsecond)
+		}
+		// The captured length is bounded by the snapshot length, which
+		// guards the allocation against corrupt records.
+		n := order.Uint32(rec[8:])
+		if n > snaplen {
+			return nil, fmt.Errorf("record %d length %d exceeds the snapshot length %d", len(frames), n, snaplen)
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("cannot read record %d data: %w", len(frames), err)
+		}
+		frames = append(frames, &Frame{Time: time.Unix(sec, frac), Data: data})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// pcapFile returns a pcap file with the frames, using the byte order
+// and magic number given.
+func pcapFile(order binary.ByteOrder, magic uint32, frames ...*Frame) []byte {
+	var buf bytes.Buffer
+	hdr := make([]byte, 24)
+	order.PutUint32(hdr[0:], magic)
+	order.PutUint16(hdr[4:], 2)
+	order.PutUint16(hdr[6:], 4)
+	order.PutUint32(hdr[16:], 65535)
+	order.PutUint32(hdr[20:], linkTypeEthernet)
+	buf.Write(hdr)
+	for _, f := range frames {
+		rec := make([]byte, 16)
+		frac := f.Time.Nanosecond()
+		if magic == magicMicro {
+			frac /= int(time.Microsecond)
+		}
+		order.PutUint32(rec[0:], uint32(f.Time.Unix()))
+		order.PutUint32(rec[4:], uint32(frac))
+		order.PutUint32(rec[8:], uint32(len(f.Data)))
+		order.PutUint32(rec[12:], uint32(len(f.Data)))
+		buf.Write(rec)
+		buf.Write(f.Data)
+	}
+	return buf.Bytes()
+}
+
+func TestReadPCAP(t *testing.T) {
+	want := []*Frame{
+		{Time: time.Unix(1660000000, 123456000), Data: []byte{1, 2, 3}},
+		{Time: time.Unix(1660000001, 0), Data: []byte{4, 5}},
+	}
+	cases := []struct {
+		desc  string
+		order binary.ByteOrder
+		magic uint32
+	}{
+		{"little endian micro", binary.LittleEndian, magicMicro},
+		{"big endian micro", binary.BigEndian, magicMicro},
+		{"little endian nano", binary.LittleEndian, magicNano},
+		{"big endian nano", binary.BigEndian, magicNano},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := ReadPCAP(bytes.NewReader(pcapFile(c.order, c.magic, want...)))
+			if err != nil {
+				t.Fatalf("ReadPCAP got error: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ReadPCAP got diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadPCAPError(t *testing.T) {
+	valid := pcapFile(binary.LittleEndian, magicMicro, &Frame{Time: time.Unix(0, 0), Data: []byte{1, 2, 3}})
+	badLink := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(badLink[20:], 101)
+	oversize := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(oversize[32:], 0xffffffff)
+	cases := []struct {
+		desc string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", make([]byte, 24)},
+		{"bad link type", badLink},
+		{"truncated record header", valid[:30]},
+		{"truncated record data", valid[:len(valid)-1]},
+		{"record over snapshot length", oversize},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			if _, err := ReadPCAP(bytes.NewReader(c.data)); err == nil {
+				t.Errorf("ReadPCAP got no error, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// routingTypeSRH is the routing type of the segment routing header.
+const routingTypeSRH = 4
+
+// SRH is an SRv6 segment routing header, RFC 8754.
+type SRH struct {
+	NextHeader   uint8
+	SegmentsLeft uint8
+	LastEntry    uint8
+	Tag          uint16
+	// Segments is the segment list as encoded: the last segment of the
+	// path first.
+	Segments []net.IP
+}
+
+// decodeSRH decodes a segment routing header.
+func (p *Packet) decodeSRH(b []byte) ([]byte, error) {
+	if len(b) < 8 {
+		return nil, errTruncated
+	}
+	n := 8 + 8*int(b[1])
+	if len(b) < n {
+		return nil, errTruncated
+	}
+	if b[2] != routingTypeSRH {
+		return nil, fmt.Errorf("unexpected routing type %d", b[2])
+	}
+	h := &SRH{
+		NextHeader:   b[0],
+		SegmentsLeft: b[3],
+		LastEntry:    b[4],
+		Tag:          binary.BigEndian.Uint16(b[6:]),
+	}
+	if 8+16*(int(h.LastEntry)+1) > n {
+		return nil, fmt.Errorf("last entry %d beyond header length %d", h.LastEntry, n)
+	}
+	for i := 0; i <= int(h.LastEntry); i++ {
+		h.Segments = append(h.Segments, net.IP(b[8+16*i:8+16*(i+1)]))
+	}
+	p.SRH = h
+	return b[n:], nil
+}
+
+// SRv6 describes the SRv6 encapsulation, H.Encaps of RFC 8986, of a
+// headend.
+type SRv6 struct {
+	Src string
+	// Segments are the SIDs of the path, in the order they are visited.
+	Segments []string
+}
+
+// segments parses the segments in SRH order.
+func (s *SRv6) segments() ([]net.IP, error) {
+	if len(s.Segments) == 0 || len(s.Segments) > 256 {
+		return nil, fmt.Errorf("invalid number of segments %d", len(s.Segments))
+	}
+	var ips []net.IP
+	for i := len(s.Segments) - 1; i >= 0; i-- {
+		ip := net.ParseIP(s.Segments[i])
+		if ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("invalid SID %q", s.Segments[i])
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// Encapsulate returns the inner IP packet encapsulated by the headend,
+// with the hop limit, as received by the first segment.
+func (s *SRv6) Encapsulate(inner []byte, hopLimit uint8) ([]byte, error) {
+	src := net.ParseIP(s.Src)
+	if src == nil || src.To4() != nil {
+		return nil, fmt.Errorf("invalid IPv6 source %q", s.Src)
+	}
+	segs, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	if len(inner) == 0 {
+		return nil, fmt.Errorf("empty inner packet")
+	}
+	next := uint8(ProtocolIPv4)
+	if inner[0]>>4 == 6 {
+		next = ProtocolIPv6
+	}
+
+	srh := make([]byte, 8, 8+16*len(segs)+len(inner))
+	srh[0] = next
+	srh[1] = uint8(2 * len(segs))
+	srh[2] = routingTypeSRH
+	srh[3] = uint8(len(segs) - 1)
+	srh[4] = uint8(len(segs) - 1)
+	for _, seg := range segs {
+		srh = append(srh, seg...)
+	}
+	payload := append(srh, inner...)
+
+	ip := make([]byte, 40, 40+len(payload))
+	binary.BigEndian.PutUint32(ip, 6<<28)
+	binary.BigEndian.PutUint16(ip[4:], uint16(len(payload)))
+	ip[6] = ProtocolRouting
+	ip[7] = hopLimit
+	copy(ip[8:], src)
+	// The destination is the first segment of the path, the last of
+	// the segment list.
+	copy(ip[24:], segs[len(segs)-1])
+	return append(ip, payload...), nil
+}
+
+// Verify returns the differences between the SRv6 headers of a captured
+// packet and the encapsulation, after segmentsLeft is decremented to
+// the given value by the segment endpoints on the path.
+func (s *SRv6) Verify(p *Packet, segmentsLeft int) []error {
+	want, err := s.segments()
+	if err != nil {
+		return []error{err}
+	}
+	if p.IP == nil || p.IP.Version != 6 || p.SRH == nil {
+		return []error{fmt.Errorf("packet is not SRv6 encapsulated")}
+	}
+	var errs []error
+	if !p.IP.Src.Equal(net.ParseIP(s.Src)) {
+		errs = append(errs, fmt.Errorf("outer source got %v, want %s", p.IP.Src, s.Src))
+	}
+	if got := int(p.SRH.SegmentsLeft); got != segmentsLeft {
+		errs = append(errs, fmt.Errorf("segments left got %d, want %d", got, segmentsLeft))
+	}
+	if fmt.Sprint(p.SRH.Segments) != fmt.Sprint(want) {
+		errs = append(errs, fmt.Errorf("segment list got %v, want %v", p.SRH.Segments, want))
+	}
+	if segmentsLeft >= 0 && segmentsLeft < len(want) && !p.IP.Dst.Equal(want[segmentsLeft]) {
+		errs = append(errs, fmt.Errorf("outer destination got %v, want active segment %v", p.IP.Dst, want[segmentsLeft]))
+	}
+	if p.Inner == nil || p.Inner.IP == nil {
+		errs = append(errs, fmt.Errorf("no inner IP packet"))
+	}
+	return errs
+}
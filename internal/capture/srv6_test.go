@@ -0,0 +1,106 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"net"
+	"testing"
+)
+
+func TestSRv6RoundTrip(t *testing.T) {
+	s := &SRv6{Src: "2001:db8::1", Segments: []string{"2001:db8:a::100", "2001:db8:b::100", "2001:db8:c::100"}}
+	for _, inner := range [][]byte{innerPacket, ipv6("2001:db8:1::1", "2001:db8:2::1", 0, 64, ProtocolUDP, []byte{0, 1, 0, 2, 0, 9, 0, 0, 0xff})} {
+		b, err := s.Encapsulate(inner, 64)
+		if err != nil {
+			t.Fatalf("Encapsulate got error: %v", err)
+		}
+		p, err := Decode(&Frame{Data: concat(ethernet(EtherTypeIPv6), b)})
+		if err != nil {
+			t.Fatalf("Decode got error: %v", err)
+		}
+		if errs := s.Verify(p, 2); len(errs) > 0 {
+			t.Errorf("Verify got errors: %v", errs)
+		}
+		if got, want := p.IP.Dst.String(), "2001:db8:a::100"; got != want {
+			t.Errorf("Outer destination got %s, want %s", got, want)
+		}
+		if got := p.Inner.UDP.DstPort; got != 2 {
+			t.Errorf("Inner UDP destination port got %d, want 2", got)
+		}
+	}
+}
+
+func TestSRv6VerifyMismatch(t *testing.T) {
+	s := &SRv6{Src: "2001:db8::1", Segments: []string{"2001:db8:a::100", "2001:db8:b::100"}}
+	b, err := s.Encapsulate(innerPacket, 64)
+	if err != nil {
+		t.Fatalf("Encapsulate got error: %v", err)
+	}
+	p, err := Decode(&Frame{Data: concat(ethernet(EtherTypeIPv6), b)})
+	if err != nil {
+		t.Fatalf("Decode got error: %v", err)
+	}
+
+	// After the first segment endpoint, the destination is the second
+	// segment and one segment is left.
+	if errs := s.Verify(p, 0); len(errs) != 2 {
+		t.Errorf("Verify with wrong segments left got errors %v, want 2 errors", errs)
+	}
+	p.SRH.SegmentsLeft = 0
+	p.IP.Dst = net.ParseIP("2001:db8:b::100")
+	if errs := s.Verify(p, 0); len(errs) > 0 {
+		t.Errorf("Verify after the first endpoint got errors: %v", errs)
+	}
+
+	other := &SRv6{Src: "2001:db8::2", Segments: []string{"2001:db8:a::100", "2001:db8:c::100"}}
+	if errs := other.Verify(p, 0); len(errs) != 3 {
+		t.Errorf("Verify of other encapsulation got errors %v, want 3 errors", errs)
+	}
+	if errs := s.Verify(&Packet{}, 0); len(errs) != 1 {
+		t.Errorf("Verify of an empty packet got errors %v, want 1 error", errs)
+	}
+}
+
+func TestSRv6EncapsulateError(t *testing.T) {
+	cases := []struct {
+		desc string
+		s    *SRv6
+	}{
+		{"ipv4 source", &SRv6{Src: "192.0.2.1", Segments: []string{"2001:db8:a::100"}}},
+		{"no segment", &SRv6{Src: "2001:db8::1"}},
+		{"ipv4 segment", &SRv6{Src: "2001:db8::1", Segments: []string{"192.0.2.9"}}},
+	}
+	for _, c := range cases {
+		if _, err := c.s.Encapsulate(innerPacket, 64); err == nil {
+			t.Errorf("Encapsulate %s got no error, want error", c.desc)
+		}
+	}
+}
+
+func TestDecodeSRHError(t *testing.T) {
+	s := &SRv6{Src: "2001:db8::1", Segments: []string{"2001:db8:a::100"}}
+	b, err := s.Encapsulate(innerPacket, 64)
+	if err != nil {
+		t.Fatalf("Encapsulate got error: %v", err)
+	}
+	// Routing type 2, the type 2 routing header of Mobile IPv6.
+	b[40+2] = 2
+	if _, err := Decode(&Frame{Data: concat(ethernet(EtherTypeIPv6), b)}); err == nil {
+		t.Error("Decode of routing type 2 got no error, want error")
+	}
+	if _, err := Decode(&Frame{Data: concat(ethernet(EtherTypeIPv6), b[:50])}); err == nil {
+		t.Error("Decode of truncated SRH got no error, want error")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import "fmt"
+
+// TTLMode is the TTL propagation mode of a tunnel, RFC 3443.
+type TTLMode int
+
+const (
+	// Uniform mode propagates the TTL between the inner and outer
+	// headers, so the tunnel counts as hops of the inner packet.
+	Uniform TTLMode = iota + 1
+	// Pipe mode sets the outer TTL independently of the inner one, and
+	// the tunnel counts as a single hop of the inner packet.
+	Pipe
+)
+
+func (m TTLMode) String() string {
+	switch m {
+	case Uniform:
+		return "uniform"
+	case Pipe:
+		return "pipe"
+	}
+	return fmt.Sprintf("TTLMode(%d)", int(m))
+}
+
+// EncapTTL returns the outer and inner TTL of a packet received with
+// the given TTL and encapsulated by the DUT.  In pipe mode, the outer
+// TTL is set to pipeTTL.  The inner TTL is decremented by the routing
+// lookup in both modes.  It returns an error if the packet expires
+// instead of being encapsulated.
+func EncapTTL(mode TTLMode, ttl, pipeTTL uint8) (outer, inner uint8, err error) {
+	if ttl <= 1 {
+		return 0, 0, fmt.Errorf("packet received with TTL %d expires instead of being encapsulated", ttl)
+	}
+	inner = ttl - 1
+	if mode == Pipe {
+		return pipeTTL, inner, nil
+	}
+	return inner, inner, nil
+}
+
+// DecapTTL returns the TTL of the inner packet decapsulated by the DUT
+// from a packet received with the given outer and inner TTL.  In
+// uniform mode, the decremented outer TTL is copied to the inner
+// header.  It returns an error if the packet expires instead of being
+// forwarded.
+func DecapTTL(mode TTLMode, outer, inner uint8) (uint8, error) {
+	ttl := inner
+	if mode == Uniform {
+		ttl = outer
+	}
+	if ttl <= 1 {
+		return 0, fmt.Errorf("%v mode packet received with outer TTL %d and inner TTL %d expires instead of being forwarded", mode, outer, inner)
+	}
+	return ttl - 1, nil
+}
+
+// VerifyEncapTTL returns the differences between the TTL of a packet
+// encapsulated by the DUT and the ones expected for the mode, when the
+// DUT received the inner packet with the given TTL.
+func VerifyEncapTTL(p *Packet, mode TTLMode, ttl, pipeTTL uint8) []error {
+	if p.IP == nil || p.Inner == nil || p.Inner.IP == nil {
+		return []error{fmt.Errorf("packet is not encapsulated")}
+	}
+	outer, inner, err := EncapTTL(mode, ttl, pipeTTL)
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	if p.IP.TTL != outer {
+		errs = append(errs, fmt.Errorf("%v mode outer TTL got %d, want %d", mode, p.IP.TTL, outer))
+	}
+	if p.Inner.IP.TTL != inner {
+		errs = append(errs, fmt.Errorf("%v mode inner TTL got %d, want %d", mode, p.Inner.IP.TTL, inner))
+	}
+	return errs
+}
+
+// VerifyDecapTTL returns an error if the TTL of a packet decapsulated
+// by the DUT is not the one expected for the mode, when the DUT
+// received the encapsulated packet with the given outer and inner TTL.
+func VerifyDecapTTL(p *Packet, mode TTLMode, outer, inner uint8) error {
+	if p.IP == nil {
+		return fmt.Errorf("packet has no IP header")
+	}
+	want, err := DecapTTL(mode, outer, inner)
+	if err != nil {
+		return err
+	}
+	if p.IP.TTL != want {
+		return fmt.Errorf("%v mode decapsulated TTL got %d, want %d", mode, p.IP.TTL, want)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import "testing"
+
+func TestEncapTTL(t *testing.T) {
+	cases := []struct {
+		mode                 TTLMode
+		ttl, pipeTTL         uint8
+		wantOuter, wantInner uint8
+		wantErr              bool
+	}{
+		{Uniform, 64, 255, 63, 63, false},
+		{Pipe, 64, 255, 255, 63, false},
+		{Pipe, 2, 64, 64, 1, false},
+		{Uniform, 1, 255, 0, 0, true},
+		{Pipe, 0, 255, 0, 0, true},
+	}
+	for _, c := range cases {
+		outer, inner, err := EncapTTL(c.mode, c.ttl, c.pipeTTL)
+		if (err != nil) != c.wantErr {
+			t.Errorf("EncapTTL(%v, %d, %d) got error %v, want error %v", c.mode, c.ttl, c.pipeTTL, err, c.wantErr)
+		}
+		if outer != c.wantOuter || inner != c.wantInner {
+			t.Errorf("EncapTTL(%v, %d, %d) got (%d, %d), want (%d, %d)", c.mode, c.ttl, c.pipeTTL, outer, inner, c.wantOuter, c.wantInner)
+		}
+	}
+}
+
+func TestDecapTTL(t *testing.T) {
+	if got, err := DecapTTL(Uniform, 10, 64); got != 9 || err != nil {
+		t.Errorf("DecapTTL(uniform, 10, 64) got %d, %v, want 9, nil", got, err)
+	}
+	if got, err := DecapTTL(Pipe, 10, 64); got != 63 || err != nil {
+		t.Errorf("DecapTTL(pipe, 10, 64) got %d, %v, want 63, nil", got, err)
+	}
+	if _, err := DecapTTL(Uniform, 0, 64); err == nil {
+		t.Error("DecapTTL(uniform, 0, 64) got no error, want error")
+	}
+	if _, err := DecapTTL(Pipe, 64, 1); err == nil {
+		t.Error("DecapTTL(pipe, 64, 1) got no error, want error")
+	}
+}
+
+func TestVerifyEncapTTL(t *testing.T) {
+	inner := ipv4("198.51.100.1", "203.0.113.1", 0, 63, ProtocolUDP, false, []byte{0, 1, 0, 2, 0, 8, 0, 0})
+	tn := &Tunnel{Type: GUEv1, Src: "192.0.2.1", Dst: "192.0.2.9", TTL: 255}
+	b, err := tn.Encapsulate(inner, 49152)
+	if err != nil {
+		t.Fatalf("Encapsulate got error: %v", err)
+	}
+	p, err := Decode(&Frame{Data: concat(ethernet(EtherTypeIPv4), b)})
+	if err != nil {
+		t.Fatalf("Decode got error: %v", err)
+	}
+	if errs := VerifyEncapTTL(p, Pipe, 64, 255); len(errs) > 0 {
+		t.Errorf("VerifyEncapTTL(pipe) got errors: %v", errs)
+	}
+	if errs := VerifyEncapTTL(p, Uniform, 64, 255); len(errs) != 1 {
+		t.Errorf("VerifyEncapTTL(uniform) got errors %v, want 1 error", errs)
+	}
+	if errs := VerifyEncapTTL(&Packet{}, Pipe, 64, 255); len(errs) != 1 {
+		t.Errorf("VerifyEncapTTL of an empty packet got errors %v, want 1 error", errs)
+	}
+}
+
+func TestVerifyDecapTTL(t *testing.T) {
+	p := &Packet{IP: &IP{TTL: 9}}
+	if err := VerifyDecapTTL(p, Uniform, 10, 64); err != nil {
+		t.Errorf("VerifyDecapTTL(uniform) got error: %v", err)
+	}
+	if err := VerifyDecapTTL(p, Pipe, 10, 64); err == nil {
+		t.Errorf("VerifyDecapTTL(pipe) got no error, want error")
+	}
+	if err := VerifyDecapTTL(&Packet{}, Pipe, 10, 64); err == nil {
+		t.Errorf("VerifyDecapTTL of an empty packet got no error, want error")
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package churn generates route churn for stability tests.  A
+// configurable percentage of the routes is repeatedly withdrawn and
+// restored at a target rate for a duration, while monitors check for
+// traffic loss and process health.
+//
+// Usage:
+//
+//	prefixes, _ := fibscale.Prefixes("198.18.0.0/32", 100000)
+//	g := &churn.Generator{
+//	  Routes:   &churn.GRIBIRoutes{Client: c, Prefixes: prefixes, NetworkInstance: ni, NHGIndex: 1},
+//	  Percent:  10,
+//	  Rate:     1000,
+//	  Duration: 30 * time.Minute,
+//	  Monitors: []churn.Monitor{
+//	    churn.FlowLossBelow(ate, "flow", 0.5),
+//	    churn.ProcessesRunning(dut, "bgp", "rib"),
+//	  },
+//	}
+//	stats := g.Run(t)
+package churn
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/featureprofiles/internal/tolerance"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+)
+
+const timeout = time.Minute
+
+// Routes is a set of routes that can be individually withdrawn and
+// restored.  Routes are identified by their index in the set.
+type Routes interface {
+	// Len returns the number of routes in the set.
+	Len() int
+	// Withdraw withdraws the routes with the given indices.
+	Withdraw(t testing.TB, idx []int)
+	// Restore restores the routes with the given indices.
+	Restore(t testing.TB, idx []int)
+}
+
+// Monitor checks the DUT during churn and returns an error describing
+// any problem found.
+type Monitor func(t testing.TB) error
+
+// Stats summarizes a churn run.
+type Stats struct {
+	// Cycles is the number of withdraw and restore cycles performed.
+	Cycles int
+	// Churned is the total number of route updates sent, counting
+	// withdrawals and restorations separately.
+	Churned int
+	// Failures lists the monitor errors, in the order they occurred.
+	Failures []string
+}
+
+// Generator churns Percent of the Routes at Rate route updates per
+// second for Duration.  Each cycle withdraws a randomly chosen subset
+// of the routes, restores it, and then runs the Monitors.
+type Generator struct {
+	Routes   Routes
+	Percent  float64
+	Rate     float64
+	Duration time.Duration
+	Monitors []Monitor
+	// Seed seeds the choice of routes, so runs can be reproduced.
+	Seed int64
+
+	// sleep is replaced in unit tests.
+	sleep func(time.Duration)
+}
+
+// Run churns the routes and reports every monitor failure as a test
+// error.  All routes are restored before Run returns.
+func (g *Generator) Run(t testing.TB) *Stats {
+	t.Helper()
+	n := int(float64(g.Routes.Len()) * g.Percent / 100)
+	if n == 0 || g.Rate <= 0 {
+		t.Fatalf("Invalid churn: %v%% of %d routes at %v routes/s", g.Percent, g.Routes.Len(), g.Rate)
+	}
+	sleep := g.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	rnd := rand.New(rand.NewSource(g.Seed))
+	// Time to spend on each update of n routes to honor the rate.
+	period := time.Duration(float64(n) / g.Rate * float64(time.Second))
+
+	stats := &Stats{}
+	var elapsed time.Duration
+	for elapsed < g.Duration {
+		idx := rnd.Perm(g.Routes.Len())[:n]
+		for _, update := range []func(testing.TB, []int){g.Routes.Withdraw, g.Routes.Restore} {
+			start := time.Now()
+			update(t, idx)
+			stats.Churned += n
+			d := time.Since(start)
+			if d < period {
+				sleep(period - d)
+				d = period
+			}
+			elapsed += d
+		}
+		stats.Cycles++
+		for _, m := range g.Monitors {
+			if err := m(t); err != nil {
+				msg := fmt.Sprintf("cycle %d: %v", stats.Cycles, err)
+				stats.Failures = append(stats.Failures, msg)
+				t.Errorf("Monitor failed during churn, %s", msg)
+			}
+		}
+	}
+	t.Logf("Churned %d route updates in %d cycles with %d failures", stats.Churned, stats.Cycles, len(stats.Failures))
+	return stats
+}
+
+// GRIBIRoutes churns IPv4 entries programmed through gRIBI, pointing to
+// an existing next hop group.  The client must already be the leader.
+type GRIBIRoutes struct {
+	Client          *gribi.Client
+	Prefixes        []string
+	NetworkInstance string
+	NHGIndex        uint64
+}
+
+// Len returns the number of prefixes.
+func (g *GRIBIRoutes) Len() int {
+	return len(g.Prefixes)
+}
+
+func (g *GRIBIRoutes) entries(idx []int) []fluent.GRIBIEntry {
+	var entries []fluent.GRIBIEntry
+	for _, i := range idx {
+		entries = append(entries, fluent.IPv4Entry().WithPrefix(g.Prefixes[i]).
+			WithNetworkInstance(g.NetworkInstance).
+			WithNextHopGroup(g.NHGIndex))
+	}
+	return entries
+}
+
+// Withdraw deletes the IPv4 entries.
+func (g *GRIBIRoutes) Withdraw(t testing.TB, idx []int) {
+	t.Helper()
+	g.Client.Fluent(t).Modify().DeleteEntry(t, g.entries(idx)...)
+	if err := g.Client.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to delete IPv4 entries: %v", err)
+	}
+}
+
+// Restore adds the IPv4 entries back.
+func (g *GRIBIRoutes) Restore(t testing.TB, idx []int) {
+	t.Helper()
+	g.Client.Fluent(t).Modify().AddEntry(t, g.entries(idx)...)
+	if err := g.Client.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to add IPv4 entries: %v", err)
+	}
+}
+
+// BGPRoutes churns the routes advertised by ATE networks.  Each network
+// is a route block withdrawn and restored as a whole, by toggling its
+// BGP attributes active.
+type BGPRoutes struct {
+	Top      *ondatra.ATETopology
+	Networks []*ondatra.Network
+}
+
+// Len returns the number of networks.
+func (b *BGPRoutes) Len() int {
+	return len(b.Networks)
+}
+
+func (b *BGPRoutes) setActive(t testing.TB, idx []int, active bool) {
+	t.Helper()
+	for _, i := range idx {
+		b.Networks[i].BGP().WithActive(active)
+	}
+	b.Top.UpdateNetworks(t)
+}
+
+// Withdraw stops advertising the networks.
+func (b *BGPRoutes) Withdraw(t testing.TB, idx []int) {
+	b.setActive(t, idx, false)
+}
+
+// Restore advertises the networks again.
+func (b *BGPRoutes) Restore(t testing.TB, idx []int) {
+	b.setActive(t, idx, true)
+}
+
+// FlowLossBelow returns a monitor checking that the loss of an ATE flow
+// does not exceed the given percentage, or the tolerance_max_loss_pct
+// argument if set, as tolerance.TrafficPolicy checks it.
+func FlowLossBelow(ate *ondatra.ATEDevice, flow string, percent float32) Monitor {
+	percent = float32(tolerance.MaxLossPct(float64(percent)))
+	return func(t testing.TB) error {
+		if loss := ate.Telemetry().Flow(flow).LossPct().Get(t); loss > percent {
+			return fmt.Errorf("flow %s loss %.2f%%, want <= %.2f%%", flow, loss, percent)
+		}
+		return nil
+	}
+}
+
+// ProcessesRunning returns a monitor checking that processes with the
+// given names are running on the DUT, as reported by /system/processes.
+func ProcessesRunning(dut *ondatra.DUTDevice, names ...string) Monitor {
+	return func(t testing.TB) error {
+		running := map[string]bool{}
+		for _, name := range dut.Telemetry().System().ProcessAny().Name().Get(t) {
+			running[name] = true
+		}
+		var missing []string
+		for _, name := range names {
+			if !running[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("processes not running: %v", missing)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package churn
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeRoutes records the routes withdrawn and restored.
+type fakeRoutes struct {
+	n         int
+	withdrawn map[int]bool
+	updates   int
+}
+
+func (f *fakeRoutes) Len() int { return f.n }
+
+func (f *fakeRoutes) Withdraw(t testing.TB, idx []int) {
+	for _, i := range idx {
+		f.withdrawn[i] = true
+	}
+	f.updates++
+}
+
+func (f *fakeRoutes) Restore(t testing.TB, idx []int) {
+	for _, i := range idx {
+		delete(f.withdrawn, i)
+	}
+	f.updates++
+}
+
+// recorder captures errors reported through testing.TB.
+type recorder struct {
+	testing.TB
+	errors int
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors++
+}
+
+func TestGenerator(t *testing.T) {
+	routes := &fakeRoutes{n: 100, withdrawn: map[int]bool{}}
+	var slept time.Duration
+	monitorCalls := 0
+	g := &Generator{
+		Routes:   routes,
+		Percent:  10,
+		Rate:     100, // 10 routes take 100ms.
+		Duration: time.Second,
+		Monitors: []Monitor{func(testing.TB) error {
+			monitorCalls++
+			if monitorCalls == 2 {
+				return errors.New("traffic loss")
+			}
+			if len(routes.withdrawn) != 0 {
+				return errors.New("routes left withdrawn")
+			}
+			return nil
+		}},
+		sleep: func(d time.Duration) { slept += d },
+	}
+	rec := &recorder{TB: t}
+	got := g.Run(rec)
+	want := &Stats{Cycles: 5, Churned: 100, Failures: []string{"cycle 2: traffic loss"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Run got unexpected stats, diff(-want,+got):\n%s", diff)
+	}
+	if rec.errors != 1 {
+		t.Errorf("Run reported %d errors, want 1", rec.errors)
+	}
+	if routes.updates != 10 {
+		t.Errorf("Run sent %d updates, want 10", routes.updates)
+	}
+	if slept < 900*time.Millisecond {
+		t.Errorf("Run slept %v, want about 1s to honor the rate", slept)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package components provides helpers for chassis component tests: it
+// finds the power supplies, fans and temperature sensors of the DUT,
+// checks the consistency of their telemetry with the alarms the DUT
+// raises and of the software versions of the supervisors and linecards,
+// and reboots components through gNOI to cause component events.
+package components
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+
+	spb "github.com/openconfig/gnoi/system"
+	tpb "github.com/openconfig/gnoi/types"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// HasType returns whether the component is a hardware component of the
+// type.
+func HasType(c *telemetry.Component, typ telemetry.E_PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT) bool {
+	v, ok := c.GetType().(telemetry.E_PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT)
+	return ok && v == typ
+}
+
+// ByType returns the components of the DUT of the type, sorted by name.
+func ByType(t testing.TB, dut *ondatra.DUTDevice, typ telemetry.E_PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT) []*telemetry.Component {
+	t.Helper()
+	var cs []*telemetry.Component
+	for _, c := range dut.Telemetry().ComponentAny().Get(t) {
+		if HasType(c, typ) {
+			cs = append(cs, c)
+		}
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].GetName() < cs[j].GetName() })
+	return cs
+}
+
+// CheckPowerSupply checks the state of a power supply: it must be
+// active, and not use more than the power allocated to it.
+func CheckPowerSupply(c *telemetry.Component) []error {
+	var errs []error
+	if got := c.GetOperStatus(); got != telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE {
+		errs = append(errs, fmt.Errorf("power supply %s oper-status got %v, want ACTIVE", c.GetName(), got))
+	}
+	if c.UsedPower != nil && c.AllocatedPower != nil && c.GetUsedPower() > c.GetAllocatedPower() {
+		errs = append(errs, fmt.Errorf("power supply %s used-power %d is over allocated-power %d", c.GetName(), c.GetUsedPower(), c.GetAllocatedPower()))
+	}
+	return errs
+}
+
+// CheckFan checks that the speed of a fan is reported and within the
+// bounds in RPM.  A max of 0 means no upper bound.
+func CheckFan(c *telemetry.Component, min, max uint32) []error {
+	fan := c.GetFan()
+	if fan == nil || fan.Speed == nil {
+		return []error{fmt.Errorf("fan %s has no speed", c.GetName())}
+	}
+	if speed := fan.GetSpeed(); speed < min || (max > 0 && speed > max) {
+		return []error{fmt.Errorf("fan %s speed got %d RPM, want %d to %d", c.GetName(), speed, min, max)}
+	}
+	return nil
+}
+
+// CheckTemperature checks the consistency of the temperature of a
+// component: the instant temperature is reported within the min and
+// max, the alarm is raised if and only if the temperature reaches the
+// alarm threshold, and a raised alarm has a severity.
+func CheckTemperature(c *telemetry.Component) []error {
+	temp := c.GetTemperature()
+	if temp == nil || temp.Instant == nil {
+		return []error{fmt.Errorf("sensor %s has no instant temperature", c.GetName())}
+	}
+	var errs []error
+	instant := temp.GetInstant()
+	if temp.Min != nil && instant < temp.GetMin() {
+		errs = append(errs, fmt.Errorf("sensor %s instant temperature %.1f is below min %.1f", c.GetName(), instant, temp.GetMin()))
+	}
+	if temp.Max != nil && instant > temp.GetMax() {
+		errs = append(errs, fmt.Errorf("sensor %s instant temperature %.1f is over max %.1f", c.GetName(), instant, temp.GetMax()))
+	}
+	if temp.AlarmThreshold != nil && temp.AlarmStatus != nil {
+		if want := instant >= float64(temp.GetAlarmThreshold()); temp.GetAlarmStatus() != want {
+			errs = append(errs, fmt.Errorf("sensor %s alarm-status got %t at %.1f degrees, want %t with alarm-threshold %d",
+				c.GetName(), temp.GetAlarmStatus(), instant, want, temp.GetAlarmThreshold()))
+		}
+	}
+	if temp.GetAlarmStatus() && temp.GetAlarmSeverity() == telemetry.AlarmTypes_OPENCONFIG_ALARM_SEVERITY_UNSET {
+		errs = append(errs, fmt.Errorf("sensor %s alarm is raised without a severity", c.GetName()))
+	}
+	return errs
+}
+
+// CheckAlarms checks that the system alarms match the temperature
+// alarms of the components: every raised temperature alarm has a system
+// alarm for the component with the same severity, and every system
+// alarm has a severity.
+func CheckAlarms(cs []*telemetry.Component, alarms []*telemetry.System_Alarm) []error {
+	var errs []error
+	byResource := map[string][]*telemetry.System_Alarm{}
+	for _, a := range alarms {
+		if a.GetSeverity() == telemetry.AlarmTypes_OPENCONFIG_ALARM_SEVERITY_UNSET {
+			errs = append(errs, fmt.Errorf("alarm %s has no severity", a.GetId()))
+		}
+		byResource[a.GetResource()] = append(byResource[a.GetResource()], a)
+	}
+	for _, c := range cs {
+		temp := c.GetTemperature()
+		if !temp.GetAlarmStatus() {
+			continue
+		}
+		found := false
+		for _, a := range byResource[c.GetName()] {
+			if a.GetSeverity() == temp.GetAlarmSeverity() {
+				found = true
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("sensor %s alarm with severity %v has no system alarm", c.GetName(), temp.GetAlarmSeverity()))
+		}
+	}
+	return errs
+}
+
+// Alarms returns the system alarms of the DUT.
+func Alarms(t testing.TB, dut *ondatra.DUTDevice) []*telemetry.System_Alarm {
+	t.Helper()
+	var alarms []*telemetry.System_Alarm
+	for _, a := range dut.Telemetry().System().AlarmAny().Lookup(t) {
+		if a.IsPresent() {
+			alarms = append(alarms, a.Val(t))
+		}
+	}
+	return alarms
+}
+
+// Reboot cold reboots the component through gNOI.  The error is that of
+// the RPC, Unimplemented if the DUT cannot reboot the component alone.
+func Reboot(t testing.TB, dut *ondatra.DUTDevice, name string) error {
+	t.Helper()
+	_, err := dut.RawAPIs().GNOI().Default(t).System().Reboot(context.Background(), &spb.RebootRequest{
+		Method:        spb.RebootMethod_COLD,
+		Subcomponents: []*tpb.Path{{Elem: []*tpb.PathElem{{Name: name}}}},
+		Message:       "featureprofiles component event",
+	})
+	return err
+}
+
+// AwaitOperStatus waits for the oper-status of the component to become
+// the one wanted, and returns whether it did.
+func AwaitOperStatus(t testing.TB, dut *ondatra.DUTDevice, name string, timeout time.Duration, want telemetry.E_PlatformTypes_COMPONENT_OPER_STATUS) bool {
+	t.Helper()
+	_, ok := dut.Telemetry().Component(name).OperStatus().Watch(t, timeout, func(v *telemetry.QualifiedE_PlatformTypes_COMPONENT_OPER_STATUS) bool {
+		return v.IsPresent() && v.Val(t) == want
+	}).Await(t)
+	return ok
+}
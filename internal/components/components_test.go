@@ -0,0 +1,176 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package components
+
+import (
+	"testing"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestHasType(t *testing.T) {
+	fan := &telemetry.Component{Name: ygot.String("Fan1"), Type: telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_FAN}
+	if !HasType(fan, telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_FAN) {
+		t.Errorf("HasType(%s, FAN) got false, want true", fan.GetName())
+	}
+	if HasType(fan, telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_POWER_SUPPLY) {
+		t.Errorf("HasType(%s, POWER_SUPPLY) got true, want false", fan.GetName())
+	}
+	if HasType(&telemetry.Component{Name: ygot.String("none")}, telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_FAN) {
+		t.Errorf("HasType() of a component without a type got true, want false")
+	}
+}
+
+func TestCheckPowerSupply(t *testing.T) {
+	for _, c := range []struct {
+		desc       string
+		status     telemetry.E_PlatformTypes_COMPONENT_OPER_STATUS
+		used, allo *uint32
+		wantErrs   int
+	}{
+		{"active", telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE, ygot.Uint32(500), ygot.Uint32(1000), 0},
+		{"no power reported", telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE, nil, nil, 0},
+		{"disabled", telemetry.PlatformTypes_COMPONENT_OPER_STATUS_DISABLED, nil, nil, 1},
+		{"over allocated", telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE, ygot.Uint32(1500), ygot.Uint32(1000), 1},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			psu := &telemetry.Component{Name: ygot.String("PowerSupply1"), OperStatus: c.status, UsedPower: c.used, AllocatedPower: c.allo}
+			if got := CheckPowerSupply(psu); len(got) != c.wantErrs {
+				t.Errorf("CheckPowerSupply() got errors %v, want %d", got, c.wantErrs)
+			}
+		})
+	}
+}
+
+func TestCheckFan(t *testing.T) {
+	for _, c := range []struct {
+		desc     string
+		fan      *telemetry.Component_Fan
+		min, max uint32
+		wantErr  bool
+	}{
+		{"in bounds", &telemetry.Component_Fan{Speed: ygot.Uint32(5000)}, 1000, 20000, false},
+		{"no upper bound", &telemetry.Component_Fan{Speed: ygot.Uint32(50000)}, 1000, 0, false},
+		{"stopped", &telemetry.Component_Fan{Speed: ygot.Uint32(0)}, 1000, 20000, true},
+		{"too fast", &telemetry.Component_Fan{Speed: ygot.Uint32(25000)}, 1000, 20000, true},
+		{"no speed", &telemetry.Component_Fan{}, 1000, 20000, true},
+		{"no fan state", nil, 1000, 20000, true},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			fan := &telemetry.Component{Name: ygot.String("Fan1"), Fan: c.fan}
+			if got := CheckFan(fan, c.min, c.max); (len(got) > 0) != c.wantErr {
+				t.Errorf("CheckFan() got errors %v, want error %v", got, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTemperature(t *testing.T) {
+	const major = telemetry.AlarmTypes_OPENCONFIG_ALARM_SEVERITY_MAJOR
+
+	for _, c := range []struct {
+		desc     string
+		temp     *telemetry.Component_Temperature
+		wantErrs int
+	}{{
+		desc: "normal",
+		temp: &telemetry.Component_Temperature{
+			Instant: ygot.Float64(45), Min: ygot.Float64(30), Max: ygot.Float64(50),
+			AlarmThreshold: ygot.Uint32(80), AlarmStatus: ygot.Bool(false),
+		},
+	}, {
+		desc: "alarm raised",
+		temp: &telemetry.Component_Temperature{
+			Instant: ygot.Float64(85), AlarmThreshold: ygot.Uint32(80), AlarmStatus: ygot.Bool(true), AlarmSeverity: major,
+		},
+	}, {
+		desc:     "no instant",
+		temp:     &telemetry.Component_Temperature{Max: ygot.Float64(50)},
+		wantErrs: 1,
+	}, {
+		desc:     "no temperature state",
+		wantErrs: 1,
+	}, {
+		desc:     "outside min and max",
+		temp:     &telemetry.Component_Temperature{Instant: ygot.Float64(60), Min: ygot.Float64(30), Max: ygot.Float64(50)},
+		wantErrs: 1,
+	}, {
+		desc: "alarm missing",
+		temp: &telemetry.Component_Temperature{
+			Instant: ygot.Float64(85), AlarmThreshold: ygot.Uint32(80), AlarmStatus: ygot.Bool(false),
+		},
+		wantErrs: 1,
+	}, {
+		desc: "spurious alarm without severity",
+		temp: &telemetry.Component_Temperature{
+			Instant: ygot.Float64(45), AlarmThreshold: ygot.Uint32(80), AlarmStatus: ygot.Bool(true),
+		},
+		wantErrs: 2,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			sensor := &telemetry.Component{Name: ygot.String("TempSensor1"), Temperature: c.temp}
+			if got := CheckTemperature(sensor); len(got) != c.wantErrs {
+				t.Errorf("CheckTemperature() got errors %v, want %d", got, c.wantErrs)
+			}
+		})
+	}
+}
+
+func TestCheckAlarms(t *testing.T) {
+	const (
+		major = telemetry.AlarmTypes_OPENCONFIG_ALARM_SEVERITY_MAJOR
+		minor = telemetry.AlarmTypes_OPENCONFIG_ALARM_SEVERITY_MINOR
+	)
+	sensors := []*telemetry.Component{{
+		Name:        ygot.String("TempSensor1"),
+		Temperature: &telemetry.Component_Temperature{AlarmStatus: ygot.Bool(true), AlarmSeverity: major},
+	}, {
+		Name:        ygot.String("TempSensor2"),
+		Temperature: &telemetry.Component_Temperature{AlarmStatus: ygot.Bool(false)},
+	}, {
+		Name: ygot.String("Fan1"),
+	}}
+
+	for _, c := range []struct {
+		desc     string
+		alarms   []*telemetry.System_Alarm
+		wantErrs int
+	}{{
+		desc:   "matching alarm",
+		alarms: []*telemetry.System_Alarm{{Id: ygot.String("1"), Resource: ygot.String("TempSensor1"), Severity: major}},
+	}, {
+		desc:     "no alarm",
+		wantErrs: 1,
+	}, {
+		desc:     "other severity",
+		alarms:   []*telemetry.System_Alarm{{Id: ygot.String("1"), Resource: ygot.String("TempSensor1"), Severity: minor}},
+		wantErrs: 1,
+	}, {
+		desc: "alarm without severity",
+		alarms: []*telemetry.System_Alarm{
+			{Id: ygot.String("1"), Resource: ygot.String("TempSensor1"), Severity: major},
+			{Id: ygot.String("2"), Resource: ygot.String("Fan1")},
+		},
+		wantErrs: 1,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			if got := CheckAlarms(sensors, c.alarms); len(got) != c.wantErrs {
+				t.Errorf("CheckAlarms() got errors %v, want %d", got, c.wantErrs)
+			}
+		})
+	}
+}
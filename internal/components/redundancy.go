@@ -0,0 +1,75 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package components
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Map returns the components of the DUT keyed by name.
+func Map(t testing.TB, dut *ondatra.DUTDevice) map[string]*telemetry.Component {
+	t.Helper()
+	cs := map[string]*telemetry.Component{}
+	for _, c := range dut.Telemetry().ComponentAny().Get(t) {
+		cs[c.GetName()] = c
+	}
+	return cs
+}
+
+// AncestorOfType returns the closest ancestor of the named component of
+// the type, following the parents, or "" if there is none.
+func AncestorOfType(cs map[string]*telemetry.Component, name string, typ telemetry.E_PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT) string {
+	seen := map[string]bool{}
+	for c := cs[name]; c != nil && !seen[c.GetName()]; c = cs[c.GetParent()] {
+		seen[c.GetName()] = true
+		if c.GetName() != name && HasType(c, typ) {
+			return c.GetName()
+		}
+	}
+	return ""
+}
+
+// Linecard returns the linecard holding the DUT port, or "" if the DUT
+// has no linecard.
+func Linecard(t testing.TB, dut *ondatra.DUTDevice, cs map[string]*telemetry.Component, port *ondatra.Port) string {
+	t.Helper()
+	hw := dut.Telemetry().Interface(port.Name()).HardwarePort().Get(t)
+	return AncestorOfType(cs, hw, telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_LINECARD)
+}
+
+// Active returns the names of the components which are active.
+func Active(cs []*telemetry.Component) []string {
+	var names []string
+	for _, c := range cs {
+		if c.GetOperStatus() == telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE {
+			names = append(names, c.GetName())
+		}
+	}
+	return names
+}
+
+// Outage returns the time the traffic was lost for, from the number of
+// packets lost by a flow at a constant rate in packets per second.
+func Outage(lost, pps uint64) time.Duration {
+	if pps == 0 {
+		return 0
+	}
+	return time.Duration(lost) * time.Second / time.Duration(pps)
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package components
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestAncestorOfType(t *testing.T) {
+	const (
+		chassis  = telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_CHASSIS
+		linecard = telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_LINECARD
+		port     = telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_PORT
+		chip     = telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_INTEGRATED_CIRCUIT
+	)
+	cs := map[string]*telemetry.Component{}
+	add := func(name, parent string, typ telemetry.E_PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT) {
+		c := &telemetry.Component{Name: ygot.String(name), Type: typ}
+		if parent != "" {
+			c.Parent = ygot.String(parent)
+		}
+		cs[name] = c
+	}
+	add("Chassis", "", chassis)
+	add("Linecard1", "Chassis", linecard)
+	add("Chip1/0", "Linecard1", chip)
+	add("Port1/1", "Chip1/0", port)
+	add("Port0", "Chassis", port)
+	add("LoopA", "LoopB", port)
+	add("LoopB", "LoopA", port)
+
+	for _, c := range []struct {
+		name string
+		typ  telemetry.E_PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT
+		want string
+	}{
+		{"Port1/1", linecard, "Linecard1"},
+		{"Port1/1", chassis, "Chassis"},
+		{"Chip1/0", linecard, "Linecard1"},
+		{"Linecard1", linecard, ""},
+		{"Port0", linecard, ""},
+		{"LoopA", linecard, ""},
+		{"Unknown", linecard, ""},
+	} {
+		if got := AncestorOfType(cs, c.name, c.typ); got != c.want {
+			t.Errorf("AncestorOfType(%s, %v) got %q, want %q", c.name, c.typ, got, c.want)
+		}
+	}
+}
+
+func TestActive(t *testing.T) {
+	cs := []*telemetry.Component{
+		{Name: ygot.String("Fabric1"), OperStatus: telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE},
+		{Name: ygot.String("Fabric2"), OperStatus: telemetry.PlatformTypes_COMPONENT_OPER_STATUS_INACTIVE},
+		{Name: ygot.String("Fabric3"), OperStatus: telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE},
+		{Name: ygot.String("Fabric4")},
+	}
+	if diff := cmp.Diff([]string{"Fabric1", "Fabric3"}, Active(cs)); diff != "" {
+		t.Errorf("Active() -want, +got:\n%s", diff)
+	}
+}
+
+func TestOutage(t *testing.T) {
+	for _, c := range []struct {
+		lost, pps uint64
+		want      time.Duration
+	}{
+		{0, 1000, 0},
+		{1000, 1000, time.Second},
+		{250, 1000, 250 * time.Millisecond},
+		{100, 0, 0},
+	} {
+		if got := Outage(c.lost, c.pps); got != c.want {
+			t.Errorf("Outage(%d, %d) got %v, want %v", c.lost, c.pps, got, c.want)
+		}
+	}
+}
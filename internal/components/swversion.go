@@ -0,0 +1,116 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package components
+
+import (
+	"fmt"
+	"sort"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// VersionMismatch is a component running another software version than
+// the one expected.
+type VersionMismatch struct {
+	Component string
+	Type      telemetry.E_PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT
+	// Got is the software version of the component, "" if it reports
+	// none.
+	Got, Want string
+}
+
+func (m *VersionMismatch) Error() string {
+	if m.Got == "" {
+		return fmt.Sprintf("%v %s has no software-version, want %q", m.Type, m.Component, m.Want)
+	}
+	return fmt.Sprintf("%v %s software-version got %q, want %q", m.Type, m.Component, m.Got, m.Want)
+}
+
+// softwareTypes are the types of the components running software of
+// their own, which are upgraded with the DUT.
+var softwareTypes = []telemetry.E_PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT{
+	telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_CONTROLLER_CARD,
+	telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_LINECARD,
+}
+
+// runsSoftware returns the type of the component if it is an active
+// supervisor or linecard, and whether it is one.  Empty slots and
+// components which are powered off run no software.
+func runsSoftware(c *telemetry.Component) (telemetry.E_PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT, bool) {
+	if c.GetEmpty() || c.GetOperStatus() != telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE {
+		return 0, false
+	}
+	for _, typ := range softwareTypes {
+		if HasType(c, typ) {
+			return typ, true
+		}
+	}
+	return 0, false
+}
+
+// SoftwareVersions returns the software versions of the active
+// supervisors and linecards, keyed by component name.  A component
+// reporting no version has the version "".
+func SoftwareVersions(cs []*telemetry.Component) map[string]string {
+	versions := map[string]string{}
+	for _, c := range cs {
+		if _, ok := runsSoftware(c); ok {
+			versions[c.GetName()] = c.GetSoftwareVersion()
+		}
+	}
+	return versions
+}
+
+// MostCommonVersion returns the software version most components run,
+// the lowest one in case of a tie, or "" if no component reports one.
+func MostCommonVersion(versions map[string]string) string {
+	counts := map[string]int{}
+	for _, v := range versions {
+		if v != "" {
+			counts[v]++
+		}
+	}
+	var common string
+	for v, n := range counts {
+		if n > counts[common] || n == counts[common] && v < common {
+			common = v
+		}
+	}
+	return common
+}
+
+// CheckSoftwareVersions checks that the active supervisors and linecards
+// all run the software version wanted, or the same version if want is
+// "", and returns the components which do not, sorted by name.
+func CheckSoftwareVersions(cs []*telemetry.Component, want string) []*VersionMismatch {
+	if want == "" {
+		want = MostCommonVersion(SoftwareVersions(cs))
+	}
+	var mismatches []*VersionMismatch
+	for _, c := range cs {
+		typ, ok := runsSoftware(c)
+		if !ok || c.GetSoftwareVersion() == want && want != "" {
+			continue
+		}
+		mismatches = append(mismatches, &VersionMismatch{
+			Component: c.GetName(),
+			Type:      typ,
+			Got:       c.GetSoftwareVersion(),
+			Want:      want,
+		})
+	}
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Component < mismatches[j].Component })
+	return mismatches
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package components
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+const (
+	supervisor = telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_CONTROLLER_CARD
+	linecard   = telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_LINECARD
+)
+
+// card returns an active component of the type with the software version,
+// none if it is "".
+func card(name string, typ telemetry.E_PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT, version string) *telemetry.Component {
+	c := &telemetry.Component{
+		Name:       ygot.String(name),
+		Type:       typ,
+		OperStatus: telemetry.PlatformTypes_COMPONENT_OPER_STATUS_ACTIVE,
+	}
+	if version != "" {
+		c.SoftwareVersion = ygot.String(version)
+	}
+	return c
+}
+
+func TestSoftwareVersions(t *testing.T) {
+	empty := card("Linecard3", linecard, "")
+	empty.Empty = ygot.Bool(true)
+	off := card("Linecard4", linecard, "1.0")
+	off.OperStatus = telemetry.PlatformTypes_COMPONENT_OPER_STATUS_DISABLED
+	cs := []*telemetry.Component{
+		card("Supervisor1", supervisor, "2.0"),
+		card("Linecard1", linecard, "2.0"),
+		card("Linecard2", linecard, ""),
+		empty,
+		off,
+		card("Fan1", telemetry.PlatformTypes_OPENCONFIG_HARDWARE_COMPONENT_FAN, "1.0"),
+	}
+	want := map[string]string{"Supervisor1": "2.0", "Linecard1": "2.0", "Linecard2": ""}
+	if diff := cmp.Diff(want, SoftwareVersions(cs)); diff != "" {
+		t.Errorf("SoftwareVersions() -want, +got:\n%s", diff)
+	}
+}
+
+func TestMostCommonVersion(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		versions map[string]string
+		want     string
+	}{{
+		desc:     "majority",
+		versions: map[string]string{"a": "2.0", "b": "2.0", "c": "1.0"},
+		want:     "2.0",
+	}, {
+		desc:     "tie",
+		versions: map[string]string{"a": "2.0", "b": "1.0"},
+		want:     "1.0",
+	}, {
+		desc:     "missing versions",
+		versions: map[string]string{"a": "", "b": "", "c": "2.0"},
+		want:     "2.0",
+	}, {
+		desc:     "no version",
+		versions: map[string]string{"a": ""},
+		want:     "",
+	}, {
+		desc: "no component",
+		want: "",
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := MostCommonVersion(tc.versions); got != tc.want {
+				t.Errorf("MostCommonVersion(%v) got %q, want %q", tc.versions, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckSoftwareVersions(t *testing.T) {
+	cs := []*telemetry.Component{
+		card("Supervisor2", supervisor, "2.0"),
+		card("Supervisor1", supervisor, "2.0"),
+		card("Linecard2", linecard, "1.0"),
+		card("Linecard1", linecard, "2.0"),
+		card("Linecard3", linecard, ""),
+	}
+	for _, tc := range []struct {
+		desc string
+		want string
+		cs   []*telemetry.Component
+		// mismatches are the components flagged, with the version they
+		// are expected to run.
+		mismatches []*VersionMismatch
+	}{{
+		desc: "consistent",
+		cs:   cs[:2],
+	}, {
+		desc: "expected version",
+		want: "2.0",
+		cs:   cs[:2],
+	}, {
+		desc: "inconsistent",
+		cs:   cs,
+		mismatches: []*VersionMismatch{
+			{Component: "Linecard2", Type: linecard, Got: "1.0", Want: "2.0"},
+			{Component: "Linecard3", Type: linecard, Want: "2.0"},
+		},
+	}, {
+		desc: "not upgraded",
+		want: "3.0",
+		cs:   cs[:3],
+		mismatches: []*VersionMismatch{
+			{Component: "Linecard2", Type: linecard, Got: "1.0", Want: "3.0"},
+			{Component: "Supervisor1", Type: supervisor, Got: "2.0", Want: "3.0"},
+			{Component: "Supervisor2", Type: supervisor, Got: "2.0", Want: "3.0"},
+		},
+	}, {
+		desc: "no version",
+		cs:   cs[4:],
+		mismatches: []*VersionMismatch{
+			{Component: "Linecard3", Type: linecard},
+		},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if diff := cmp.Diff(tc.mismatches, CheckSoftwareVersions(tc.cs, tc.want)); diff != "" {
+				t.Errorf("CheckSoftwareVersions(%q) -want, +got:\n%s", tc.want, diff)
+			}
+		})
+	}
+}
+
+func TestVersionMismatchError(t *testing.T) {
+	for _, tc := range []struct {
+		m    *VersionMismatch
+		want string
+	}{{
+		m:    &VersionMismatch{Component: "Linecard1", Type: linecard, Got: "1.0", Want: "2.0"},
+		want: `LINECARD Linecard1 software-version got "1.0", want "2.0"`,
+	}, {
+		m:    &VersionMismatch{Component: "Linecard1", Type: linecard, Want: "2.0"},
+		want: `LINECARD Linecard1 has no software-version, want "2.0"`,
+	}} {
+		if got := tc.m.Error(); got != tc.want {
+			t.Errorf("Error() got %q, want %q", got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package configdiff compares snapshots of the full configuration of a
+// DUT, to report the configuration a test left behind or the subtrees a
+// Replace operation touched, as a diff of OpenConfig paths.  It also
+// compares the configuration of a subtree replaced with the one read back,
+// to report the leaves the DUT silently dropped.
+package configdiff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Op is the operation of a change.
+type Op int
+
+const (
+	// Added is a leaf set only after.
+	Added Op = iota
+	// Removed is a leaf set only before.
+	Removed
+	// Modified is a leaf set to another value after.
+	Modified
+)
+
+// Change is the change of a leaf between two snapshots.
+type Change struct {
+	Op     Op
+	Path   string
+	Before string
+	After  string
+}
+
+// String formats a change as a line of a diff.
+func (c Change) String() string {
+	switch c.Op {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, c.After)
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, c.Before)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, c.Before, c.After)
+	}
+}
+
+// Take returns a snapshot of the full configuration of the DUT.
+func Take(t testing.TB, dut *ondatra.DUTDevice) *telemetry.Device {
+	t.Helper()
+	return dut.Config().Get(t)
+}
+
+// valueString formats a typed value of a leaf.
+func valueString(v *gpb.TypedValue) string {
+	switch val := v.GetValue().(type) {
+	case *gpb.TypedValue_StringVal:
+		return fmt.Sprintf("%q", val.StringVal)
+	case *gpb.TypedValue_IntVal:
+		return fmt.Sprint(val.IntVal)
+	case *gpb.TypedValue_UintVal:
+		return fmt.Sprint(val.UintVal)
+	case *gpb.TypedValue_BoolVal:
+		return fmt.Sprint(val.BoolVal)
+	case *gpb.TypedValue_LeaflistVal:
+		var elems []string
+		for _, e := range val.LeaflistVal.GetElement() {
+			elems = append(elems, valueString(e))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	return prototext.MarshalOptions{}.Format(v)
+}
+
+// pathString formats the config path of a path, or describes why it
+// cannot.  The structs of the telemetry package have the state paths of
+// their leaves, and the config ones as shadow paths.
+func pathString(p *gpb.Path) string {
+	c := &gpb.Path{Origin: p.GetOrigin()}
+	for _, e := range p.GetElem() {
+		if e.GetName() == "state" {
+			e = &gpb.PathElem{Name: "config", Key: e.GetKey()}
+		}
+		c.Elem = append(c.Elem, e)
+	}
+	s, err := ygot.PathToString(c)
+	if err != nil {
+		return fmt.Sprintf("<%v: %v>", p, err)
+	}
+	return s
+}
+
+// singlePath diffs the keys of lists once, at their paths in the lists.
+var singlePath = &ygot.DiffPathOpt{MapToSinglePath: true}
+
+// empty returns an empty struct of the type of s.
+func empty(s ygot.GoStruct) ygot.GoStruct {
+	return reflect.New(reflect.TypeOf(s).Elem()).Interface().(ygot.GoStruct)
+}
+
+// leaves returns the values of the leaves of a snapshot by path.
+func leaves(s ygot.GoStruct) (map[string]string, error) {
+	n, err := ygot.Diff(empty(s), s, singlePath)
+	if err != nil {
+		return nil, err
+	}
+	vals := map[string]string{}
+	for _, u := range n.GetUpdate() {
+		vals[pathString(u.GetPath())] = valueString(u.GetVal())
+	}
+	return vals, nil
+}
+
+// Diff returns the changes of the leaves from the snapshot before to the
+// one after, ordered by path.
+func Diff(before, after *telemetry.Device) ([]Change, error) {
+	if before == nil {
+		before = &telemetry.Device{}
+	}
+	if after == nil {
+		after = &telemetry.Device{}
+	}
+	return DiffStructs(before, after)
+}
+
+// DiffStructs is like Diff for two structs of the same type, such as the
+// configuration of a subtree sent to the DUT and the one read back.  The
+// paths of the changes are relative to the structs.
+func DiffStructs(before, after ygot.GoStruct) ([]Change, error) {
+	vals, err := leaves(before)
+	if err != nil {
+		return nil, err
+	}
+	n, err := ygot.Diff(before, after, singlePath)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for _, p := range n.GetDelete() {
+		path := pathString(p)
+		changes = append(changes, Change{Op: Removed, Path: path, Before: vals[path]})
+	}
+	for _, u := range n.GetUpdate() {
+		path := pathString(u.GetPath())
+		c := Change{Op: Added, Path: path, After: valueString(u.GetVal())}
+		if v, ok := vals[path]; ok {
+			c.Op = Modified
+			c.Before = v
+		}
+		changes = append(changes, c)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// under returns whether a path is in a subtree.  A subtree without the
+// keys of a list covers all its entries.
+func under(path, subtree string) bool {
+	subtree = strings.TrimSuffix(subtree, "/")
+	if !strings.HasPrefix(path, subtree) {
+		return false
+	}
+	rest := path[len(subtree):]
+	return rest == "" || rest[0] == '/' || (rest[0] == '[' && !strings.HasSuffix(subtree, "]"))
+}
+
+// Outside returns the changes outside of the subtrees, as the ones a
+// Replace of the subtrees should not have made.
+func Outside(changes []Change, subtrees ...string) []Change {
+	var out []Change
+	for _, c := range changes {
+		inside := false
+		for _, s := range subtrees {
+			if under(c.Path, s) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Format formats changes as a diff, one line per change.
+func Format(changes []Change) string {
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// CheckUnchanged reports an error with the diff if the configuration of
+// the DUT changed since the snapshot before, outside of the subtrees
+// ignored, as the configuration left behind by a test.
+func CheckUnchanged(t testing.TB, dut *ondatra.DUTDevice, before *telemetry.Device, ignored ...string) {
+	t.Helper()
+	changes, err := Diff(before, Take(t, dut))
+	if err != nil {
+		t.Fatalf("Cannot diff the configuration of %s: %v", dut.Name(), err)
+	}
+	if left := Outside(changes, ignored...); len(left) > 0 {
+		t.Errorf("Configuration of %s changed, -before, +after:\n%s", dut.Name(), Format(left))
+	}
+}
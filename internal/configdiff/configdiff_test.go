@@ -0,0 +1,216 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdiff
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// newDevice returns a configuration with an interface and a hostname.
+func newDevice() *telemetry.Device {
+	d := &telemetry.Device{}
+	i := d.GetOrCreateInterface("eth0")
+	i.Description = ygot.String("uplink")
+	i.Mtu = ygot.Uint16(1500)
+	d.GetOrCreateSystem().Hostname = ygot.String("dut")
+	return d
+}
+
+func TestDiff(t *testing.T) {
+	for _, c := range []struct {
+		desc   string
+		modify func(d *telemetry.Device)
+		want   []Change
+	}{{
+		desc:   "unchanged",
+		modify: func(d *telemetry.Device) {},
+	}, {
+		desc:   "added",
+		modify: func(d *telemetry.Device) { d.GetInterface("eth0").Enabled = ygot.Bool(true) },
+		want:   []Change{{Op: Added, Path: "/interfaces/interface[name=eth0]/config/enabled", After: "true"}},
+	}, {
+		desc:   "removed",
+		modify: func(d *telemetry.Device) { d.GetInterface("eth0").Description = nil },
+		want:   []Change{{Op: Removed, Path: "/interfaces/interface[name=eth0]/config/description", Before: `"uplink"`}},
+	}, {
+		desc:   "modified",
+		modify: func(d *telemetry.Device) { d.GetInterface("eth0").Mtu = ygot.Uint16(9000) },
+		want:   []Change{{Op: Modified, Path: "/interfaces/interface[name=eth0]/config/mtu", Before: "1500", After: "9000"}},
+	}, {
+		desc:   "ordered by path",
+		modify: func(d *telemetry.Device) { d.GetSystem().Hostname = ygot.String("dut2"); d.DeleteInterface("eth0") },
+		want: []Change{
+			{Op: Removed, Path: "/interfaces/interface[name=eth0]/config/description", Before: `"uplink"`},
+			{Op: Removed, Path: "/interfaces/interface[name=eth0]/config/mtu", Before: "1500"},
+			{Op: Removed, Path: "/interfaces/interface[name=eth0]/name", Before: `"eth0"`},
+			{Op: Modified, Path: "/system/config/hostname", Before: `"dut"`, After: `"dut2"`},
+		},
+	}} {
+		after := newDevice()
+		c.modify(after)
+		got, err := Diff(newDevice(), after)
+		if err != nil {
+			t.Fatalf("%s: Diff() got error: %v", c.desc, err)
+		}
+		if diff := cmp.Diff(c.want, got); diff != "" {
+			t.Errorf("%s: Diff() -want, +got:\n%s", c.desc, diff)
+		}
+	}
+}
+
+func TestOutside(t *testing.T) {
+	changes := []Change{
+		{Path: "/interfaces/interface[name=eth0]/config/mtu"},
+		{Path: "/interfaces/interface[name=eth1]/config/mtu"},
+		{Path: "/interfaces/interface[name=eth10]/config/mtu"},
+		{Path: "/system/config/hostname"},
+	}
+	for _, c := range []struct {
+		desc     string
+		subtrees []string
+		want     []Change
+	}{
+		{"no subtrees", nil, changes},
+		{"list entry", []string{"/interfaces/interface[name=eth1]"}, []Change{changes[0], changes[2], changes[3]}},
+		{"all list entries", []string{"/interfaces/interface"}, []Change{changes[3]}},
+		{"trailing slash", []string{"/system/"}, changes[:3]},
+		{"name prefix", []string{"/sys"}, changes},
+		{"leaf", []string{"/system/config/hostname", "/interfaces"}, nil},
+	} {
+		if diff := cmp.Diff(c.want, Outside(changes, c.subtrees...)); diff != "" {
+			t.Errorf("%s: Outside() -want, +got:\n%s", c.desc, diff)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	changes := []Change{
+		{Op: Added, Path: "/a", After: "1"},
+		{Op: Removed, Path: "/b", Before: `"x"`},
+		{Op: Modified, Path: "/c", Before: "true", After: "false"},
+	}
+	want := "+ /a: 1\n- /b: \"x\"\n~ /c: true -> false\n"
+	if got := Format(changes); got != want {
+		t.Errorf("Format() got %q, want %q", got, want)
+	}
+}
+
+func TestDiffStructs(t *testing.T) {
+	sent := &telemetry.Interface{
+		Name:        ygot.String("eth0"),
+		Description: ygot.String("uplink"),
+		Mtu:         ygot.Uint16(1500),
+	}
+	readBack := &telemetry.Interface{
+		Name:    ygot.String("eth0"),
+		Mtu:     ygot.Uint16(9000),
+		Enabled: ygot.Bool(true),
+	}
+	got, err := DiffStructs(sent, readBack)
+	if err != nil {
+		t.Fatalf("DiffStructs() got error: %v", err)
+	}
+	want := []Change{
+		{Op: Removed, Path: "/config/description", Before: `"uplink"`},
+		{Op: Added, Path: "/config/enabled", After: "true"},
+		{Op: Modified, Path: "/config/mtu", Before: "1500", After: "9000"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DiffStructs() -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff([]Change{want[0], want[2]}, Dropped(got)); diff != "" {
+		t.Errorf("Dropped() -want, +got:\n%s", diff)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	subtree := &gpb.Path{Elem: []*gpb.PathElem{
+		{Name: "interfaces"},
+		{Name: "interface", Key: map[string]string{"name": "eth0"}},
+	}}
+	want := &telemetry.Interface{
+		Name:        ygot.String("eth0"),
+		Description: ygot.String("uplink"),
+		Mtu:         ygot.Uint16(1500),
+	}
+	for _, c := range []struct {
+		desc string
+		ns   []*gpb.Notification
+	}{{
+		desc: "subtree JSON_IETF",
+		ns: []*gpb.Notification{{
+			Update: []*gpb.Update{{
+				Path: subtree,
+				Val: &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: []byte(`{
+					"openconfig-interfaces:name": "eth0",
+					"openconfig-interfaces:config": {"name": "eth0", "description": "uplink", "mtu": 1500}
+				}`)}},
+			}},
+		}},
+	}, {
+		desc: "leaves with module prefixes",
+		ns: []*gpb.Notification{{
+			Prefix: &gpb.Path{Elem: []*gpb.PathElem{
+				{Name: "openconfig-interfaces:interfaces"},
+				{Name: "interface", Key: map[string]string{"name": "eth0"}},
+			}},
+			Update: []*gpb.Update{{
+				Path: &gpb.Path{Elem: []*gpb.PathElem{{Name: "config"}, {Name: "description"}}},
+				Val:  &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "uplink"}},
+			}, {
+				Path: &gpb.Path{Elem: []*gpb.PathElem{{Name: "config"}, {Name: "mtu"}}},
+				Val:  &gpb.TypedValue{Value: &gpb.TypedValue_UintVal{UintVal: 1500}},
+			}, {
+				Path: &gpb.Path{Elem: []*gpb.PathElem{{Name: "config"}, {Name: "name"}}},
+				Val:  &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "eth0"}},
+			}},
+		}},
+	}} {
+		got := &telemetry.Interface{}
+		if err := Unmarshal(subtree, c.ns, got); err != nil {
+			t.Fatalf("%s: Unmarshal() got error: %v", c.desc, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%s: Unmarshal() -want, +got:\n%s", c.desc, diff)
+		}
+	}
+}
+
+func TestUnmarshalOutside(t *testing.T) {
+	subtree := &gpb.Path{Elem: []*gpb.PathElem{
+		{Name: "interfaces"},
+		{Name: "interface", Key: map[string]string{"name": "eth0"}},
+	}}
+	ns := []*gpb.Notification{{
+		Update: []*gpb.Update{{
+			Path: &gpb.Path{Elem: []*gpb.PathElem{
+				{Name: "interfaces"},
+				{Name: "interface", Key: map[string]string{"name": "eth1"}},
+				{Name: "config"},
+				{Name: "mtu"},
+			}},
+			Val: &gpb.TypedValue{Value: &gpb.TypedValue_UintVal{UintVal: 1500}},
+		}},
+	}}
+	if err := Unmarshal(subtree, ns, &telemetry.Interface{}); err == nil {
+		t.Errorf("Unmarshal() of an update of another interface got no error")
+	}
+}
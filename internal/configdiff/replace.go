@@ -0,0 +1,140 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdiff
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+	"github.com/openconfig/ygot/ytypes"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// stripModule returns a name without the prefix of its module.
+func stripModule(name string) string {
+	return name[strings.LastIndex(name, ":")+1:]
+}
+
+// relative returns the elements of a path below the elements of a subtree,
+// or false if the path is not in the subtree.  The names are compared
+// without the prefixes of their modules.
+func relative(subtree, path []*gpb.PathElem) ([]*gpb.PathElem, bool) {
+	if len(path) < len(subtree) {
+		return nil, false
+	}
+	for i, e := range subtree {
+		if stripModule(e.GetName()) != stripModule(path[i].GetName()) || !reflect.DeepEqual(e.GetKey(), path[i].GetKey()) {
+			return nil, false
+		}
+	}
+	return path[len(subtree):], true
+}
+
+// Unmarshal unmarshals the updates of the notifications of a subtree into
+// dest, a struct of the type of the subtree.  Each update may have the
+// JSON_IETF value of the subtree or of a node below it, or the typed value
+// of a leaf.  The config containers are unmarshaled to the shadow paths of
+// the structs, so structs of the configuration read back compare to the ones
+// sent.
+func Unmarshal(subtree *gpb.Path, ns []*gpb.Notification, dest ygot.GoStruct) error {
+	name := reflect.TypeOf(dest).Elem().Name()
+	schema, ok := telemetry.SchemaTree[name]
+	if !ok {
+		return fmt.Errorf("no schema for %s", name)
+	}
+	for _, n := range ns {
+		for _, u := range n.GetUpdate() {
+			elems := append(append([]*gpb.PathElem{}, n.GetPrefix().GetElem()...), u.GetPath().GetElem()...)
+			rel, ok := relative(subtree.GetElem(), elems)
+			if !ok {
+				return fmt.Errorf("update of %s outside of %s", pathString(&gpb.Path{Elem: elems}), pathString(subtree))
+			}
+			if err := ytypes.SetNode(schema, dest, &gpb.Path{Elem: rel}, u.GetVal(),
+				&ytypes.InitMissingElements{}, &ytypes.PreferShadowPath{}); err != nil {
+				return fmt.Errorf("cannot unmarshal the update of %s: %w", pathString(&gpb.Path{Elem: elems}), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Dropped returns the changes from the configuration of a subtree sent to
+// the DUT to the one read back which dropped or modified leaves sent.  The
+// leaves added, such as defaults, are not dropped.
+func Dropped(changes []Change) []Change {
+	var dropped []Change
+	for _, c := range changes {
+		if c.Op != Added {
+			dropped = append(dropped, c)
+		}
+	}
+	return dropped
+}
+
+// VerifyReplace gets the configuration of the subtree at path back from the
+// DUT after a Replace of it with want, and reports an error with the diff if
+// the DUT silently dropped or modified leaves of want.  The leaves the DUT
+// added are logged.
+//
+// Usage:
+//
+//	dut.Config().Interface(name).Replace(t, i)
+//	configdiff.VerifyReplace(t, dut, dut.Config().Interface(name), i)
+func VerifyReplace(t testing.TB, dut *ondatra.DUTDevice, path ygot.PathStruct, want ygot.GoStruct) {
+	t.Helper()
+	p, _, errs := ygot.ResolvePath(path)
+	if len(errs) > 0 {
+		t.Fatalf("Cannot resolve the path of %T: %v", path, errs)
+	}
+	subtree := pathString(p)
+	resp, err := dut.RawAPIs().GNMI().Default(t).Get(context.Background(), &gpb.GetRequest{
+		Path:     []*gpb.Path{p},
+		Type:     gpb.GetRequest_CONFIG,
+		Encoding: gpb.Encoding_JSON_IETF,
+	})
+	if err != nil {
+		t.Fatalf("Cannot get %s back: %v", subtree, err)
+	}
+	got := empty(want)
+	if err := Unmarshal(p, resp.GetNotification(), got); err != nil {
+		t.Fatalf("Cannot unmarshal %s: %v", subtree, err)
+	}
+	changes, err := DiffStructs(want, got)
+	if err != nil {
+		t.Fatalf("Cannot diff %s: %v", subtree, err)
+	}
+	for i := range changes {
+		changes[i].Path = subtree + changes[i].Path
+	}
+	var added []Change
+	for _, c := range changes {
+		if c.Op == Added {
+			added = append(added, c)
+		}
+	}
+	if len(added) > 0 {
+		t.Logf("%s read back with leaves not sent:\n%s", subtree, Format(added))
+	}
+	if dropped := Dropped(changes); len(dropped) > 0 {
+		t.Errorf("%s read back without leaves sent, -sent, +read back:\n%s", subtree, Format(dropped))
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package contention races the operations of concurrent control planes,
+// such as gNMI configuration and gRIBI programming of the same prefix,
+// against each other and reports the operations which wedge.  It also
+// determines the entries a device forwards a prefix with when both control
+// planes have one.
+package contention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrWedged is the error of an operation which did not complete within the
+// timeout of its race.
+var ErrWedged = errors.New("operation did not complete")
+
+// grace is how long operations have to return once their race times out.
+const grace = time.Second
+
+// Op is an operation raced against others.  Do runs on its own goroutine,
+// so it returns its errors rather than failing the test.
+type Op struct {
+	Name string
+	Do   func(ctx context.Context) error
+}
+
+// Result is the outcome of an operation in a race.
+type Result struct {
+	Op string
+	// Delay is how long after the start of the race the operation started.
+	Delay   time.Duration
+	Elapsed time.Duration
+	Err     error
+}
+
+// String returns a summary of the result for the logs.
+func (r Result) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s started at +%v failed after %v: %v", r.Op, r.Delay, r.Elapsed, r.Err)
+	}
+	return fmt.Sprintf("%s started at +%v completed in %v", r.Op, r.Delay, r.Elapsed)
+}
+
+// Race runs the operations concurrently, each after a random delay of up
+// to stagger, and returns their results in the order of the operations.
+// The context of the operations is canceled after timeout, and those which
+// did not return by then are reported with ErrWedged.
+func Race(ctx context.Context, timeout, stagger time.Duration, rnd *rand.Rand, ops ...Op) []Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The channels are buffered so that wedged operations do not leak
+	// blocked goroutines once they eventually return.
+	done := make([]chan Result, len(ops))
+	for i, op := range ops {
+		var delay time.Duration
+		if stagger > 0 {
+			delay = time.Duration(rnd.Int63n(int64(stagger)))
+		}
+		done[i] = make(chan Result, 1)
+		go func(op Op, delay time.Duration, done chan<- Result) {
+			r := Result{Op: op.Name, Delay: delay}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				r.Err = ctx.Err()
+				done <- r
+				return
+			}
+			start := time.Now()
+			r.Err = op.Do(ctx)
+			r.Elapsed = time.Since(start)
+			done <- r
+		}(op, delay, done[i])
+	}
+
+	results := make([]Result, len(ops))
+	for i, op := range ops {
+		select {
+		case results[i] = <-done[i]:
+		case <-ctx.Done():
+			// Give the operation the chance to return once canceled before
+			// it is considered wedged.
+			select {
+			case results[i] = <-done[i]:
+			case <-time.After(grace):
+				results[i] = Result{Op: op.Name, Elapsed: timeout, Err: ErrWedged}
+			}
+		}
+	}
+	return results
+}
+
+// Errors returns the errors of the results, prefixed with their operation.
+func Errors(results []Result) []error {
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Op, r.Err))
+		}
+	}
+	return errs
+}
+
+// The origin protocols of the entries of a prefix.
+const (
+	Static = "STATIC"
+	GRIBI  = "GRIBI"
+)
+
+// Installed records the control planes which have an entry for a prefix.
+type Installed struct {
+	Static bool
+	GRIBI  bool
+}
+
+// String returns the control planes with an entry, for the names of
+// subtests.
+func (i Installed) String() string {
+	switch {
+	case i.Static && i.GRIBI:
+		return "static and gRIBI"
+	case i.Static:
+		return "static only"
+	case i.GRIBI:
+		return "gRIBI only"
+	}
+	return "none"
+}
+
+// Winner returns the origin protocol of the entry the device forwards the
+// prefix with, Static or GRIBI, or "" if no control plane has one.  Static
+// routes are preferred over gRIBI entries unless preferGRIBI is set.
+func (i Installed) Winner(preferGRIBI bool) string {
+	switch {
+	case i.Static && i.GRIBI && preferGRIBI:
+		return GRIBI
+	case i.Static:
+		return Static
+	case i.GRIBI:
+		return GRIBI
+	}
+	return ""
+}
+
+// Steps returns n successive states of the entries of a prefix, starting
+// from none installed.  Each step changes the entry of at least one control
+// plane, and half of them change both so that they race.
+func Steps(rnd *rand.Rand, n int) []Installed {
+	var steps []Installed
+	var cur Installed
+	for len(steps) < n {
+		switch rnd.Intn(4) {
+		case 0:
+			cur.Static = !cur.Static
+		case 1:
+			cur.GRIBI = !cur.GRIBI
+		default:
+			cur.Static, cur.GRIBI = !cur.Static, !cur.GRIBI
+		}
+		steps = append(steps, cur)
+	}
+	return steps
+}
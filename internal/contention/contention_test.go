@@ -0,0 +1,101 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contention
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestRace(t *testing.T) {
+	errFailed := errors.New("failed")
+	block := make(chan struct{})
+	defer close(block)
+	ops := []Op{
+		{"ok", func(ctx context.Context) error { return nil }},
+		{"failed", func(ctx context.Context) error { return errFailed }},
+		{"canceled", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		{"wedged", func(ctx context.Context) error {
+			<-block
+			return nil
+		}},
+	}
+	results := Race(context.Background(), 50*time.Millisecond, 10*time.Millisecond, rand.New(rand.NewSource(1)), ops...)
+	if len(results) != len(ops) {
+		t.Fatalf("Race() got %d results, want %d", len(results), len(ops))
+	}
+	for i, want := range []error{nil, errFailed, context.DeadlineExceeded, ErrWedged} {
+		r := results[i]
+		if r.Op != ops[i].Name {
+			t.Errorf("Race() result %d is of %q, want %q", i, r.Op, ops[i].Name)
+		}
+		if !errors.Is(r.Err, want) {
+			t.Errorf("Race() %s got error %v, want %v", r.Op, r.Err, want)
+		}
+		if r.Delay >= 10*time.Millisecond {
+			t.Errorf("Race() %s delayed by %v, want < 10ms", r.Op, r.Delay)
+		}
+	}
+	if got := Errors(results); len(got) != 3 {
+		t.Errorf("Errors() got %v, want 3 errors", got)
+	}
+}
+
+func TestWinner(t *testing.T) {
+	for _, c := range []struct {
+		installed   Installed
+		preferGRIBI bool
+		want        string
+	}{
+		{Installed{}, false, ""},
+		{Installed{Static: true}, false, Static},
+		{Installed{GRIBI: true}, false, GRIBI},
+		{Installed{Static: true, GRIBI: true}, false, Static},
+		{Installed{Static: true}, true, Static},
+		{Installed{Static: true, GRIBI: true}, true, GRIBI},
+	} {
+		if got := c.installed.Winner(c.preferGRIBI); got != c.want {
+			t.Errorf("%v: Winner(%v) got %q, want %q", c.installed, c.preferGRIBI, got, c.want)
+		}
+	}
+}
+
+func TestSteps(t *testing.T) {
+	const n = 100
+	steps := Steps(rand.New(rand.NewSource(1)), n)
+	if len(steps) != n {
+		t.Fatalf("Steps() got %d steps, want %d", len(steps), n)
+	}
+	var prev Installed
+	both := 0
+	for i, s := range steps {
+		switch {
+		case s == prev:
+			t.Errorf("Steps() step %d is unchanged: %v", i, s)
+		case s.Static != prev.Static && s.GRIBI != prev.GRIBI:
+			both++
+		}
+		prev = s
+	}
+	if both == 0 || both == n {
+		t.Errorf("Steps() changed both control planes in %d of %d steps, want some", both, n)
+	}
+}
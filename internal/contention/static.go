@@ -0,0 +1,68 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contention
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// staticPath returns the path of the static route of a prefix in a network
+// instance.
+func staticPath(ni, prefix string) (*gpb.Path, error) {
+	return ygot.StringToStructuredPath(fmt.Sprintf(
+		"/network-instances/network-instance[name=%s]/protocols/protocol[identifier=%s][name=%s]/static-routes/static[prefix=%s]",
+		ni, Static, Static, prefix))
+}
+
+// StaticRouteRequest returns the SetRequest replacing the static route of a
+// prefix in a network instance with one through nextHop.  The operations
+// of a race cannot fail the test, so they send raw SetRequests rather than
+// use the config paths of ondatra.
+func StaticRouteRequest(ni, prefix, nextHop string) (*gpb.SetRequest, error) {
+	path, err := staticPath(ni, prefix)
+	if err != nil {
+		return nil, err
+	}
+	s := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String(prefix)}
+	s.GetOrCreateNextHop("0").NextHop = telemetry.UnionString(nextHop)
+	v, err := ygot.ConstructIETFJSON(s, &ygot.RFC7951JSONConfig{AppendModuleName: true})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal the static route of %s: %w", prefix, err)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal the static route of %s: %w", prefix, err)
+	}
+	return &gpb.SetRequest{Replace: []*gpb.Update{{
+		Path: path,
+		Val:  &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: b}},
+	}}}, nil
+}
+
+// DeleteStaticRouteRequest returns the SetRequest deleting the static route
+// of a prefix in a network instance.
+func DeleteStaticRouteRequest(ni, prefix string) (*gpb.SetRequest, error) {
+	path, err := staticPath(ni, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &gpb.SetRequest{Delete: []*gpb.Path{path}}, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contention
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/openconfig/ygot/ygot"
+)
+
+func TestStaticRouteRequest(t *testing.T) {
+	const want = "/network-instances/network-instance[name=DEFAULT]/protocols/protocol[identifier=STATIC][name=STATIC]/static-routes/static[prefix=203.0.113.0/24]"
+	req, err := StaticRouteRequest("DEFAULT", "203.0.113.0/24", "192.0.2.6")
+	if err != nil {
+		t.Fatalf("StaticRouteRequest() got error: %v", err)
+	}
+	if len(req.GetReplace()) != 1 {
+		t.Fatalf("StaticRouteRequest() got replaces %v, want 1", req.GetReplace())
+	}
+	u := req.GetReplace()[0]
+	if got, err := ygot.PathToString(u.GetPath()); err != nil || got != want {
+		t.Errorf("StaticRouteRequest() path got %q (%v), want %q", got, err, want)
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal(u.GetVal().GetJsonIetfVal(), &v); err != nil {
+		t.Fatalf("StaticRouteRequest() value is not JSON: %v", err)
+	}
+	if _, ok := v["openconfig-network-instance:next-hops"]; !ok {
+		t.Errorf("StaticRouteRequest() value got %v, want next hops", v)
+	}
+
+	req, err = DeleteStaticRouteRequest("DEFAULT", "203.0.113.0/24")
+	if err != nil {
+		t.Fatalf("DeleteStaticRouteRequest() got error: %v", err)
+	}
+	if len(req.GetDelete()) != 1 {
+		t.Fatalf("DeleteStaticRouteRequest() got deletes %v, want 1", req.GetDelete())
+	}
+	if got, err := ygot.PathToString(req.GetDelete()[0]); err != nil || got != want {
+		t.Errorf("DeleteStaticRouteRequest() path got %q (%v), want %q", got, err, want)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contention
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+)
+
+// TieBreak is how a device forwards a prefix with a static route and a
+// gRIBI entry of equal preference.
+type TieBreak string
+
+// Tie-breaks between a static route and a gRIBI entry.
+const (
+	// ECMP balances the traffic over the next hops of both entries.
+	ECMP TieBreak = "ecmp"
+	// PreferStatic forwards the traffic with the static route.
+	PreferStatic TieBreak = "static"
+	// PreferGRIBI forwards the traffic with the gRIBI entry.
+	PreferGRIBI TieBreak = "gribi"
+)
+
+// ParseTieBreak parses a tie-break.
+func ParseTieBreak(s string) (TieBreak, error) {
+	switch tb := TieBreak(s); tb {
+	case ECMP, PreferStatic, PreferGRIBI:
+		return tb, nil
+	}
+	return "", fmt.Errorf("unknown tie-break %q, want one of %s, %s or %s", s, ECMP, PreferStatic, PreferGRIBI)
+}
+
+// ConfiguredTieBreak returns the tie-break of the DUT, as given by the
+// deviation_static_gribi_tie_break flag.
+func ConfiguredTieBreak() (TieBreak, error) {
+	tb, err := ParseTieBreak(*deviations.StaticGRIBITieBreak)
+	if err != nil {
+		return "", fmt.Errorf("invalid -deviation_static_gribi_tie_break: %w", err)
+	}
+	return tb, nil
+}
+
+// Forwarders returns the origin protocols of the entries the device
+// forwards the prefix with, Static, GRIBI or both, when they have equal
+// preference.
+func (i Installed) Forwarders(tb TieBreak) []string {
+	switch {
+	case i.Static && i.GRIBI && tb == ECMP:
+		return []string{Static, GRIBI}
+	case i.Static && i.GRIBI && tb == PreferGRIBI:
+		return []string{GRIBI}
+	case i.Static:
+		return []string{Static}
+	case i.GRIBI:
+		return []string{GRIBI}
+	}
+	return nil
+}
+
+// CheckDistribution returns an error if the packets forwarded through the
+// next hops of each origin protocol are not distributed over the
+// forwarders.  Packets are balanced within tolerancePct percent of the
+// mean over several forwarders, and all sent through a single one.
+func CheckDistribution(forwarders []string, counts map[string]uint64, tolerancePct float64) error {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return fmt.Errorf("no packet forwarded, want them through %v", forwarders)
+	}
+	want := map[string]bool{}
+	for _, f := range forwarders {
+		want[f] = true
+		if counts[f] == 0 {
+			return fmt.Errorf("no packet forwarded through the %s entry, got %v", f, counts)
+		}
+	}
+	for p, c := range counts {
+		// Ignore stray packets, such as control plane traffic.
+		if !want[p] && float64(c) > float64(total)/100 {
+			return fmt.Errorf("%d packets forwarded through the %s entry, want them through %v only", c, p, forwarders)
+		}
+	}
+	if len(forwarders) < 2 {
+		return nil
+	}
+	var fwd uint64
+	for _, f := range forwarders {
+		fwd += counts[f]
+	}
+	mean := float64(fwd) / float64(len(forwarders))
+	for _, f := range forwarders {
+		if dev := math.Abs(float64(counts[f])-mean) / mean * 100; dev > tolerancePct {
+			return fmt.Errorf("packets not balanced over %v: got %v, deviating %.1f%% from the mean, want <= %.1f%%", forwarders, counts, dev, tolerancePct)
+		}
+	}
+	return nil
+}
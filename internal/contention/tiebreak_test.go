@@ -0,0 +1,74 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contention
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTieBreak(t *testing.T) {
+	for _, s := range []string{"ecmp", "static", "gribi"} {
+		if got, err := ParseTieBreak(s); err != nil || string(got) != s {
+			t.Errorf("ParseTieBreak(%q) got %q, %v, want %q", s, got, err, s)
+		}
+	}
+	if _, err := ParseTieBreak("bgp"); err == nil {
+		t.Errorf("ParseTieBreak(%q) got no error, want one", "bgp")
+	}
+}
+
+func TestForwarders(t *testing.T) {
+	both := Installed{Static: true, GRIBI: true}
+	for _, c := range []struct {
+		installed Installed
+		tb        TieBreak
+		want      []string
+	}{
+		{Installed{}, ECMP, nil},
+		{Installed{Static: true}, ECMP, []string{Static}},
+		{Installed{GRIBI: true}, PreferStatic, []string{GRIBI}},
+		{both, ECMP, []string{Static, GRIBI}},
+		{both, PreferStatic, []string{Static}},
+		{both, PreferGRIBI, []string{GRIBI}},
+	} {
+		if diff := cmp.Diff(c.want, c.installed.Forwarders(c.tb)); diff != "" {
+			t.Errorf("%v: Forwarders(%s) -want, +got:\n%s", c.installed, c.tb, diff)
+		}
+	}
+}
+
+func TestCheckDistribution(t *testing.T) {
+	both := []string{Static, GRIBI}
+	for _, c := range []struct {
+		desc       string
+		forwarders []string
+		counts     map[string]uint64
+		wantErr    bool
+	}{
+		{"balanced", both, map[string]uint64{Static: 5200, GRIBI: 4800}, false},
+		{"unbalanced", both, map[string]uint64{Static: 7000, GRIBI: 3000}, true},
+		{"one of two unused", both, map[string]uint64{Static: 10000}, true},
+		{"single", []string{GRIBI}, map[string]uint64{Static: 20, GRIBI: 10000}, false},
+		{"wrong single", []string{GRIBI}, map[string]uint64{Static: 10000, GRIBI: 5}, true},
+		{"shared single", []string{Static}, map[string]uint64{Static: 5000, GRIBI: 5000}, true},
+		{"nothing forwarded", []string{Static}, map[string]uint64{}, true},
+	} {
+		if err := CheckDistribution(c.forwarders, c.counts, 10); (err != nil) != c.wantErr {
+			t.Errorf("%s: CheckDistribution() got error %v, want error %v", c.desc, err, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package copp provides helpers for control plane policing (CoPP)
+// tests.  It builds ATE flows for the traffic classes punted to the
+// control plane of the DUT, and reads the counters of the policers
+// protecting it.
+package copp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Target describes where the punted traffic is sent.
+type Target struct {
+	// DUT is the DUT side of the link the traffic is received on.
+	DUT *attrs.Attributes
+	// TransitIPv4 is an address routed through the DUT, used as the
+	// destination of the packets expiring on the DUT.
+	TransitIPv4 string
+}
+
+// Class is a class of traffic punted to the control plane.
+type Class struct {
+	Name    string
+	headers func(tgt *Target) []ondatra.Header
+}
+
+// Traffic classes punted to the control plane.
+var (
+	// BGP is TCP traffic to port 179 of the DUT address.
+	BGP = Class{Name: "bgp", headers: func(tgt *Target) []ondatra.Header {
+		ip := ondatra.NewIPv4Header().WithDstAddress(tgt.DUT.IPv4)
+		return []ondatra.Header{ondatra.NewEthernetHeader(), ip, ondatra.NewTCPHeader().WithDstPort(179)}
+	}}
+
+	// ICMP is ICMP echo requests to the DUT address.
+	ICMP = Class{Name: "icmp", headers: func(tgt *Target) []ondatra.Header {
+		ip := ondatra.NewIPv4Header().WithDstAddress(tgt.DUT.IPv4)
+		icmp := ondatra.NewICMPHeader()
+		icmp.EchoRequest()
+		return []ondatra.Header{ondatra.NewEthernetHeader(), ip, icmp}
+	}}
+
+	// TTL1 is transit traffic with a TTL of 1, which expires on the DUT.
+	TTL1 = Class{Name: "ttl1", headers: func(tgt *Target) []ondatra.Header {
+		ip := ondatra.NewIPv4Header().WithDstAddress(tgt.TransitIPv4).WithTTL(1)
+		return []ondatra.Header{ondatra.NewEthernetHeader(), ip}
+	}}
+
+	// ARP is broadcast ARP frames.
+	ARP = Class{Name: "arp", headers: func(tgt *Target) []ondatra.Header {
+		eth := ondatra.NewEthernetHeader().WithEtherType(0x0806).WithDstAddress("ff:ff:ff:ff:ff:ff")
+		return []ondatra.Header{eth}
+	}}
+
+	// AllClasses are all the traffic classes above.
+	AllClasses = []Class{BGP, ICMP, TTL1, ARP}
+)
+
+// Flow returns a flow of the class sent at fps frames per second from
+// the src interface towards the target.  The flow is named
+// "copp-<class>".
+func (c Class) Flow(ate *ondatra.ATEDevice, src, dst ondatra.Endpoint, tgt *Target, fps uint64) *ondatra.Flow {
+	return ate.Traffic().NewFlow("copp-" + c.Name).
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst).
+		WithHeaders(c.headers(tgt)...).
+		WithFrameRateFPS(fps)
+}
+
+// Policer identifies a policer by the input scheduler it is applied
+// with on a QoS interface, e.g. the interface representing the control
+// plane of the DUT.
+type Policer struct {
+	Interface string
+	Sequence  uint32
+}
+
+// String returns a description of the policer for logging.
+func (p Policer) String() string {
+	return fmt.Sprintf("%s scheduler %d", p.Interface, p.Sequence)
+}
+
+// Counters are the packet counters of a policer.
+type Counters struct {
+	Conforming uint64
+	Exceeding  uint64
+	Violating  uint64
+}
+
+// Sub returns the counters increase since prev.
+func (c Counters) Sub(prev Counters) Counters {
+	return Counters{
+		Conforming: c.Conforming - prev.Conforming,
+		Exceeding:  c.Exceeding - prev.Exceeding,
+		Violating:  c.Violating - prev.Violating,
+	}
+}
+
+// Dropped returns the number of packets above the committed rate.
+func (c Counters) Dropped() uint64 {
+	return c.Exceeding + c.Violating
+}
+
+// Read reads the counters of the policer from the DUT.  Counters the
+// DUT does not report are zero.
+func (p Policer) Read(t testing.TB, dut *ondatra.DUTDevice) Counters {
+	t.Helper()
+	s := dut.Telemetry().Qos().Interface(p.Interface).Input().SchedulerPolicy().Scheduler(p.Sequence)
+	var c Counters
+	if v := s.ConformingPkts().Lookup(t); v.IsPresent() {
+		c.Conforming = v.Val(t)
+	}
+	if v := s.ExceedingPkts().Lookup(t); v.IsPresent() {
+		c.Exceeding = v.Val(t)
+	}
+	if v := s.ViolatingPkts().Lookup(t); v.IsPresent() {
+		c.Violating = v.Val(t)
+	}
+	return c
+}
+
+// BGPEstablished reports whether the BGP session with the neighbor in
+// the default network instance is established.
+func BGPEstablished(t testing.TB, dut *ondatra.DUTDevice, neighbor string) bool {
+	t.Helper()
+	state := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().
+		Neighbor(neighbor).SessionState().Lookup(t)
+	return state.IsPresent() && state.Val(t) == telemetry.Bgp_Neighbor_SessionState_ESTABLISHED
+}
+
+// ParsePolicers parses a comma separated list of policers keyed by
+// traffic class name, in the form "class=interface:sequence", e.g.
+// "bgp=CPU:10,icmp=CPU:20".
+func ParsePolicers(s string) (map[string]Policer, error) {
+	policers := map[string]Policer{}
+	if s == "" {
+		return policers, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		class, policer, ok := cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("policer %q is not of the form class=interface:sequence", item)
+		}
+		i := strings.LastIndex(policer, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("policer %q is not of the form class=interface:sequence", item)
+		}
+		seq, err := strconv.ParseUint(policer[i+1:], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("policer %q has an invalid sequence: %w", item, err)
+		}
+		policers[class] = Policer{Interface: policer[:i], Sequence: uint32(seq)}
+	}
+	return policers, nil
+}
+
+// cut is strings.Cut, which is not available in Go 1.17.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
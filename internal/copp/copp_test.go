@@ -0,0 +1,80 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package copp
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePolicers(t *testing.T) {
+	cases := []struct {
+		desc    string
+		in      string
+		want    map[string]Policer
+		wantErr bool
+	}{{
+		desc: "empty",
+		want: map[string]Policer{},
+	}, {
+		desc: "multiple",
+		in:   "bgp=CPU:10,icmp=Ethernet1/1:20",
+		want: map[string]Policer{
+			"bgp":  {Interface: "CPU", Sequence: 10},
+			"icmp": {Interface: "Ethernet1/1", Sequence: 20},
+		},
+	}, {
+		desc: "interface with colon",
+		in:   "arp=cpu:0:1",
+		want: map[string]Policer{"arp": {Interface: "cpu:0", Sequence: 1}},
+	}, {
+		desc:    "missing class",
+		in:      "CPU:10",
+		wantErr: true,
+	}, {
+		desc:    "missing sequence",
+		in:      "bgp=CPU",
+		wantErr: true,
+	}, {
+		desc:    "invalid sequence",
+		in:      "bgp=CPU:x",
+		wantErr: true,
+	}}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := ParsePolicers(c.in)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("ParsePolicers(%q) got error %v, want error %v", c.in, err, c.wantErr)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("ParsePolicers(%q) got unexpected policers, diff(-want,+got):\n%s", c.in, diff)
+			}
+		})
+	}
+}
+
+func TestCounters(t *testing.T) {
+	prev := Counters{Conforming: 10, Exceeding: 5, Violating: 1}
+	cur := Counters{Conforming: 110, Exceeding: 55, Violating: 11}
+	got := cur.Sub(prev)
+	want := Counters{Conforming: 100, Exceeding: 50, Violating: 10}
+	if got != want {
+		t.Errorf("Sub got %+v, want %+v", got, want)
+	}
+	if got := got.Dropped(); got != 60 {
+		t.Errorf("Dropped got %d, want 60", got)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package daemon kills and restarts the daemons of the DUT through gNOI
+// System.KillProcess.  Daemons are identified by their role, since the
+// names of their processes are vendor specific and are mapped through
+// deviations.
+//
+// Usage:
+//
+//	pid := daemon.Kill(t, dut, daemon.Routing, spb.KillProcessRequest_SIGNAL_TERM, true)
+//	if d, ok := daemon.AwaitRestart(t, dut, daemon.Routing, pid, time.Minute); !ok {
+//	  t.Errorf("Routing daemon not restarted")
+//	}
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+
+	spb "github.com/openconfig/gnoi/system"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Role is the role of a daemon of the DUT.
+type Role int
+
+const (
+	// Routing is the daemon running the routing protocols.
+	Routing Role = iota
+	// GRIBI is the daemon serving gRIBI.
+	GRIBI
+)
+
+// String returns the name of the role.
+func (r Role) String() string {
+	switch r {
+	case Routing:
+		return "routing"
+	case GRIBI:
+		return "gRIBI"
+	}
+	return "unknown"
+}
+
+// Name returns the name of the process of the daemon of the role, from
+// the deviations, or "" if unknown.
+func (r Role) Name() string {
+	switch r {
+	case Routing:
+		return *deviations.RoutingDaemon
+	case GRIBI:
+		return *deviations.GRIBIDaemon
+	}
+	return ""
+}
+
+// PID returns the pid of the process with the name among the processes,
+// and whether there is one.
+func PID(procs []*telemetry.System_Process, name string) (uint64, bool) {
+	for _, p := range procs {
+		if p.GetName() == name && p.Pid != nil {
+			return p.GetPid(), true
+		}
+	}
+	return 0, false
+}
+
+// Lookup returns the pid of the process of the daemon of the role on the
+// DUT, and whether it is running.
+func Lookup(t testing.TB, dut *ondatra.DUTDevice, r Role) (uint64, bool) {
+	t.Helper()
+	var procs []*telemetry.System_Process
+	for _, p := range dut.Telemetry().System().ProcessAny().Lookup(t) {
+		if p.IsPresent() {
+			procs = append(procs, p.Val(t))
+		}
+	}
+	return PID(procs, r.Name())
+}
+
+// Kill sends the signal to the process of the daemon of the role through
+// gNOI, asking the DUT to restart it if restart, and returns the pid it
+// had.  The test is skipped if the name of the process is unknown.
+func Kill(t testing.TB, dut *ondatra.DUTDevice, r Role, signal spb.KillProcessRequest_Signal, restart bool) uint64 {
+	t.Helper()
+	name := r.Name()
+	if name == "" {
+		t.Skipf("Name of the %v daemon unknown, see the deviation flags", r)
+	}
+	pid, ok := Lookup(t, dut, r)
+	if !ok {
+		t.Fatalf("Process %s of the %v daemon is not running", name, r)
+	}
+	t.Logf("Killing process %s (pid %d) of the %v daemon with %v", name, pid, r, signal)
+	_, err := dut.RawAPIs().GNOI().Default(t).System().KillProcess(context.Background(), &spb.KillProcessRequest{
+		Name:    name,
+		Signal:  signal,
+		Restart: restart,
+	})
+	if err != nil {
+		t.Fatalf("Cannot kill process %s: %v", name, err)
+	}
+	return pid
+}
+
+// AwaitRestart waits for the process of the daemon of the role to run
+// again with a pid other than the one it had, and returns how long it
+// took, and whether it did within the timeout.
+func AwaitRestart(t testing.TB, dut *ondatra.DUTDevice, r Role, pid uint64, timeout time.Duration) (time.Duration, bool) {
+	t.Helper()
+	start := time.Now()
+	for {
+		if got, ok := Lookup(t, dut, r); ok && got != pid {
+			return time.Since(start), true
+		}
+		if time.Since(start) > timeout {
+			return time.Since(start), false
+		}
+		time.Sleep(time.Second)
+	}
+}
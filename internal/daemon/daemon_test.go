@@ -0,0 +1,71 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestRoleName(t *testing.T) {
+	defer func(routing, gribi string) {
+		*deviations.RoutingDaemon, *deviations.GRIBIDaemon = routing, gribi
+	}(*deviations.RoutingDaemon, *deviations.GRIBIDaemon)
+	*deviations.RoutingDaemon, *deviations.GRIBIDaemon = "rpd", "grpc"
+
+	for _, c := range []struct {
+		role     Role
+		wantName string
+		wantStr  string
+	}{
+		{Routing, "rpd", "routing"},
+		{GRIBI, "grpc", "gRIBI"},
+		{Role(42), "", "unknown"},
+	} {
+		if got := c.role.Name(); got != c.wantName {
+			t.Errorf("%v.Name() got %q, want %q", c.role, got, c.wantName)
+		}
+		if got := c.role.String(); got != c.wantStr {
+			t.Errorf("String() got %q, want %q", got, c.wantStr)
+		}
+	}
+}
+
+func TestPID(t *testing.T) {
+	procs := []*telemetry.System_Process{
+		{Name: ygot.String("init"), Pid: ygot.Uint64(1)},
+		{Name: ygot.String("rpd"), Pid: ygot.Uint64(1234)},
+		{Name: ygot.String("nopid")},
+	}
+	for _, c := range []struct {
+		name    string
+		wantPID uint64
+		wantOK  bool
+	}{
+		{"rpd", 1234, true},
+		{"init", 1, true},
+		{"nopid", 0, false},
+		{"missing", 0, false},
+	} {
+		pid, ok := PID(procs, c.name)
+		if pid != c.wantPID || ok != c.wantOK {
+			t.Errorf("PID(%q) got (%d, %v), want (%d, %v)", c.name, pid, ok, c.wantPID, c.wantOK)
+		}
+	}
+}
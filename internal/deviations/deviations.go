@@ -56,4 +56,58 @@ var (
 
 	SubInterfacePacketCountersSupported = flag.Bool("deviation_subinterface_packet_counters_supported", true,
 		"Subinterface discard packet counters for ipv4/ipv6 are not always supported. Manually set it to False to skip lookup of discard counters in the test")
+
+	HashFields = flag.String("deviation_hash_fields", "src-ip,dst-ip,protocol,src-port,dst-port",
+		"Comma separated packet fields the device is configured to use in load-balancing hashes, since the hash configuration is not modeled in OpenConfig.  Valid fields are src-ip, dst-ip, protocol, src-port, dst-port, flow-label and mpls-entropy.")
+
+	BGPLinkBandwidth = flag.Bool("deviation_bgp_link_bandwidth", false,
+		"Device is configured out of band to weight BGP multipath by the link bandwidth extended community of the routes, since this is not modeled in OpenConfig.  Set it to true to run the unequal cost multipath tests.")
+
+	BGPTCPAO = flag.Bool("deviation_bgp_tcp_ao", false,
+		"Device is configured out of band to authenticate the BGP sessions with TCP-AO using the keychain configured by the test, since binding a keychain to a BGP neighbor is not modeled in OpenConfig.  Set it to true to run the TCP-AO tests.")
+
+	BGPGTSM = flag.Bool("deviation_bgp_gtsm", false,
+		"Device is configured out of band with GTSM for the BGP sessions, accepting peers 1 hop away, since this is not modeled in OpenConfig.  Set it to true to run the GTSM tests.")
+
+	RoutingDaemon = flag.String("deviation_routing_daemon", "",
+		"Name of the process of the device running the routing protocols, since process names are vendor specific.  Tests killing it through gNOI KillProcess are skipped if it is empty.")
+
+	GRIBIDaemon = flag.String("deviation_gribi_daemon", "",
+		"Name of the process of the device serving gRIBI, since process names are vendor specific.  Tests killing it through gNOI KillProcess are skipped if it is empty.")
+
+	GRIBIPreferredOverStatic = flag.Bool("deviation_gribi_preferred_over_static", false,
+		"Device prefers gRIBI entries over static routes of the same prefix, since the preference between them is not modeled in OpenConfig.  Tests expect the static routes to be preferred unless it is set.")
+
+	GRIBIPreference = flag.Uint("deviation_gribi_preference", 0,
+		"Preference, or administrative distance, of the gRIBI entries of the device, since it is not modeled in OpenConfig.  Tests configuring static routes of equal preference are skipped if it is 0.")
+
+	StaticGRIBITieBreak = flag.String("deviation_static_gribi_tie_break", "ecmp",
+		"How the device forwards a prefix with a static route and a gRIBI entry of equal preference, since the tie-break is not modeled in OpenConfig: ecmp over both, or static or gribi for the entry it prefers.")
+
+	GNMISetMaxBytes = flag.Int("deviation_gnmi_set_max_bytes", 0,
+		"Maximum size in bytes of the gNMI SetRequests the device accepts, when it is below the default gRPC limit of 4MiB, since gNMI does not advertise it.  Large configurations are split into SetRequests of at most this size.")
+
+	LeafListUpdateReplaces = flag.Bool("deviation_leaf_list_update_replaces", false,
+		"Device replaces a leaf-list updated by a gNMI Set with the values of the update, rather than merging them into the leaf-list as a YANG merge does.")
+
+	StaticNAT = flag.Bool("deviation_static_nat", false,
+		"Device is configured out of band with the static NAT mappings logged by the test, translating the source addresses of the traffic from the inside interface to the outside interface and the destination addresses back, since NAT is not modeled in OpenConfig.  Set it to true to run the NAT tests.")
+
+	BFDInterval = flag.Duration("deviation_bfd_interval", 0,
+		"Transmit and receive interval of the BFD sessions of the device to the ATE neighbors, which are configured out of band with this interval on both ends, since neither the BFD timers nor BFD on the ATE are modeled.  Tests of BFD are skipped if it is 0.")
+
+	BFDMultiplier = flag.Uint("deviation_bfd_multiplier", 3,
+		"Detection multiplier of the BFD sessions of the device to the ATE neighbors, configured out of band, see -deviation_bfd_interval.")
+
+	ATELDP = flag.Bool("deviation_ate_ldp", false,
+		"ATE is configured out of band with LDP sessions to the DUT, advertising the label bindings logged by the test, since the ATE API has no LDP.  Set it to true to run the LDP tests.")
+
+	BGPAggregateOptions = flag.Bool("deviation_bgp_aggregate_options", false,
+		"Device is configured out of band with the summary-only and as-set options logged by the test for the BGP advertisement of its local aggregates, since neither is modeled in OpenConfig.  Set it to true to run the tests of these options.")
+
+	BGPConditionalDefault = flag.Bool("deviation_bgp_conditional_default", false,
+		"Device is configured out of band to send the default route to the BGP neighbors with send-default-route only while the routing policy logged by the test matches a route of its RIB, since conditioning the default route on a policy is not modeled in OpenConfig.  Set it to true to run the conditional default route tests.")
+
+	BGPLargeCommunities = flag.Bool("deviation_bgp_large_communities", false,
+		"Device is configured out of band with the large community sets and routing policies logged by the test, since large communities are not modeled in OpenConfig.  Set it to true to run the large community tests.")
 )
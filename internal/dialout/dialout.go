@@ -0,0 +1,227 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dialout provides a gRPC tunnel server which a test embeds for
+// a device to dial out to, and helpers to configure the device to dial
+// out and to subscribe to its gNMI telemetry over the tunnel.
+//
+// The device registers itself as a target of the tunnel server, then
+// the test opens tunnel sessions to the target, over which it dials the
+// gNMI server of the device as if it had dialed in.
+//
+// OpenConfig does not model the tunnel client of a device, so the
+// device is configured by a vendor configuration with the variables of
+// Vars.
+package dialout
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/grpctunnel/tunnel"
+	"github.com/openconfig/ondatra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	tunnelpb "github.com/openconfig/grpctunnel/proto/tunnel"
+)
+
+// Variables of the vendor configuration of the tunnel client of a
+// device, replacing {{ var "tunnel_server" }} and so on.
+const (
+	VarServer = "tunnel_server"
+	VarPort   = "tunnel_port"
+	VarTarget = "tunnel_target"
+)
+
+// Vars returns the variables of the vendor configuration of a device
+// dialing out to the tunnel server at the address and port, as the
+// target.
+func Vars(server string, port int, target string) map[string]string {
+	return map[string]string{
+		VarServer: server,
+		VarPort:   strconv.Itoa(port),
+		VarTarget: target,
+	}
+}
+
+// Configure appends the vendor configuration in the file to the device,
+// for it to dial out to the tunnel server as the target.
+func Configure(t testing.TB, dut *ondatra.DUTDevice, file, server string, port int, target string) {
+	t.Helper()
+	dut.Config().New().WithFile(file).WithVarMap(Vars(server, port, target)).Append(t)
+}
+
+// Collector is a tunnel server, serving the tunnel service over gRPC.
+type Collector struct {
+	lis    net.Listener
+	srv    *grpc.Server
+	tunnel *tunnel.Server
+
+	mu      sync.Mutex
+	targets map[string]tunnel.Target
+}
+
+// NewCollector starts a tunnel server listening on the TCP address,
+// with the options of its gRPC server, such as its credentials.
+func NewCollector(addr string, opts ...grpc.ServerOption) (*Collector, error) {
+	c := &Collector{targets: map[string]tunnel.Target{}}
+	var err error
+	c.tunnel, err = tunnel.NewServer(tunnel.ServerConfig{
+		AddTargetHandler:    c.addTarget,
+		DeleteTargetHandler: c.deleteTarget,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the tunnel server: %w", err)
+	}
+	c.lis, err = net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c.srv = grpc.NewServer(opts...)
+	tunnelpb.RegisterTunnelServer(c.srv, c.tunnel)
+	go c.srv.Serve(c.lis)
+	return c, nil
+}
+
+// Addr returns the address the server listens on.
+func (c *Collector) Addr() net.Addr {
+	return c.lis.Addr()
+}
+
+// Close stops the server, closing the tunnels.
+func (c *Collector) Close() {
+	c.srv.Stop()
+}
+
+func (c *Collector) addTarget(t tunnel.Target) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.targets[t.ID] = t
+	return nil
+}
+
+func (c *Collector) deleteTarget(t tunnel.Target) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.targets, t.ID)
+	return nil
+}
+
+// Targets returns the IDs of the targets registered with the server.
+func (c *Collector) Targets() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id := range c.targets {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// AwaitTarget waits for the target to register as a gNMI target with
+// the server.
+func (c *Collector) AwaitTarget(id string, timeout time.Duration) error {
+	for start := time.Now(); ; time.Sleep(time.Second) {
+		c.mu.Lock()
+		t, ok := c.targets[id]
+		c.mu.Unlock()
+		if ok {
+			if want := tunnelpb.TargetType_GNMI_GNOI.String(); t.Type != want {
+				return fmt.Errorf("target %s registered with type %s, want %s", id, t.Type, want)
+			}
+			return nil
+		}
+		if time.Since(start) > timeout {
+			return fmt.Errorf("target %s did not register after %v; registered: %v", id, timeout, c.Targets())
+		}
+	}
+}
+
+// Dial dials the gNMI server of the target over a new tunnel session.
+// The gNMI server of the target serves TLS, whose certificate is not
+// verified.
+func (c *Collector) Dial(ctx context.Context, id string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	t, ok := c.targets[id]
+	c.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("target %s is not registered", id)
+	}
+	conn, err := tunnel.ServerConn(ctx, c.tunnel, c.Addr().String(), &t)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open a tunnel session to target %s: %w", id, err)
+	}
+	opts = append([]grpc.DialOption{
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return conn, nil
+		}),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: true, // NOLINT
+		})),
+	}, opts...)
+	cc, err := grpc.DialContext(ctx, id, opts...)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot dial target %s over the tunnel: %w", id, err)
+	}
+	return cc, nil
+}
+
+// WithCredentials returns the context with the username and password of
+// the gNMI requests.
+func WithCredentials(ctx context.Context, username, password string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "username", username, "password", password)
+}
+
+// Collect subscribes once to the paths, and returns the notifications
+// received until the sync response.
+func Collect(ctx context.Context, client gpb.GNMIClient, paths ...*gpb.Path) ([]*gpb.Notification, error) {
+	sub, err := client.Subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := &gpb.SubscriptionList{Mode: gpb.SubscriptionList_ONCE, Encoding: gpb.Encoding_JSON_IETF}
+	for _, p := range paths {
+		list.Subscription = append(list.Subscription, &gpb.Subscription{Path: p})
+	}
+	if err := sub.Send(&gpb.SubscribeRequest{Request: &gpb.SubscribeRequest_Subscribe{Subscribe: list}}); err != nil {
+		return nil, err
+	}
+	var notifs []*gpb.Notification
+	for {
+		resp, err := sub.Recv()
+		if err == io.EOF {
+			return notifs, fmt.Errorf("subscription ended before the sync response")
+		}
+		if err != nil {
+			return notifs, err
+		}
+		switch r := resp.Response.(type) {
+		case *gpb.SubscribeResponse_Update:
+			notifs = append(notifs, r.Update)
+		case *gpb.SubscribeResponse_SyncResponse:
+			return notifs, nil
+		}
+	}
+}
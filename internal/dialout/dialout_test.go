@@ -0,0 +1,100 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dialout
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// fakeGNMI answers a subscription with its notifications, then the sync
+// response, if the request carries the username.
+type fakeGNMI struct {
+	gpb.UnimplementedGNMIServer
+	username string
+	notifs   []*gpb.Notification
+}
+
+func (f *fakeGNMI) Subscribe(stream gpb.GNMI_SubscribeServer) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	if got := md.Get("username"); len(got) != 1 || got[0] != f.username {
+		return stream.Send(&gpb.SubscribeResponse{})
+	}
+	for _, n := range f.notifs {
+		if err := stream.Send(&gpb.SubscribeResponse{Response: &gpb.SubscribeResponse_Update{Update: n}}); err != nil {
+			return err
+		}
+	}
+	return stream.Send(&gpb.SubscribeResponse{Response: &gpb.SubscribeResponse_SyncResponse{SyncResponse: true}})
+}
+
+func TestCollect(t *testing.T) {
+	hostname := &gpb.Notification{
+		Update: []*gpb.Update{{
+			Path: &gpb.Path{Elem: []*gpb.PathElem{{Name: "system"}, {Name: "state"}, {Name: "hostname"}}},
+			Val:  &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "dut"}},
+		}},
+	}
+	fake := &fakeGNMI{username: "admin", notifs: []*gpb.Notification{hostname}}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Cannot listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	gpb.RegisterGNMIServer(srv, fake)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Cannot dial the gNMI server: %v", err)
+	}
+	defer conn.Close()
+	client := gpb.NewGNMIClient(conn)
+
+	got, err := Collect(WithCredentials(context.Background(), "admin", "secret"), client, hostname.Update[0].Path)
+	if err != nil {
+		t.Fatalf("Collect() got error: %v", err)
+	}
+	if diff := cmp.Diff([]*gpb.Notification{hostname}, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Collect() diff (-want +got):\n%s", diff)
+	}
+	if _, err := Collect(context.Background(), client, hostname.Update[0].Path); err == nil {
+		t.Errorf("Collect() without credentials got no error")
+	}
+}
+
+func TestVars(t *testing.T) {
+	want := map[string]string{
+		VarServer: "192.0.2.1",
+		VarPort:   "4321",
+		VarTarget: "dut",
+	}
+	if diff := cmp.Diff(want, Vars("192.0.2.1", 4321, "dut")); diff != "" {
+		t.Errorf("Vars() diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dualenc verifies that the telemetry of a DUT has the same values
+// in the JSON_IETF and PROTO encodings.  The values of the paths are
+// collected in JSON_IETF, then PROTO, then JSON_IETF again, decoded into
+// the values of their leaves, and compared: the paths whose values changed
+// between the two JSON_IETF collections, such as counters, are volatile
+// and skipped.
+//
+// The values are compared in a form common to both encodings: numbers as
+// their decimal text, decimal64 values as numbers, binaries in base64,
+// identities without the prefix of their module, and leaf-lists as a JSON
+// list of their elements.
+//
+// Tests verifying telemetry run the check in the dual encoding mode,
+// enabled by the dual_encoding flag:
+//
+//	if *dualenc.Enabled {
+//	  dualenc.Check(t, dut, paths...)
+//	}
+package dualenc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/featureprofiles/internal/gnmicap"
+	"github.com/openconfig/featureprofiles/internal/typecheck"
+	"github.com/openconfig/goyang/pkg/yang"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// Enabled is whether the tests verifying telemetry also verify it in both
+// encodings.
+var Enabled = flag.Bool("dual_encoding", false,
+	"Verify the telemetry of the tests supporting it in both the JSON_IETF and PROTO encodings, where the DUT supports both, and report the values differing between them.")
+
+// collectTimeout is the time given to the DUT to send the values of the
+// paths in an encoding.
+const collectTimeout = 5 * time.Minute
+
+// Missing is the value of a path in an encoding without it.
+const Missing = "<missing>"
+
+// Values are the values of leaves, keyed by path, in the form compared.
+type Values map[string]string
+
+// Discrepancy is a path with different values in JSON_IETF and PROTO.
+type Discrepancy struct {
+	// Path is the path of the value, and SchemaPath the one of its leaf,
+	// without list keys.
+	Path       string
+	SchemaPath string
+	JSON       string
+	PROTO      string
+}
+
+func (d *Discrepancy) Error() string {
+	return fmt.Sprintf("%s: %s in JSON_IETF, %s in PROTO", d.Path, d.JSON, d.PROTO)
+}
+
+// Decoder decodes notifications into values against a schema.
+type Decoder struct {
+	checker *typecheck.Checker
+}
+
+// NewDecoder returns a decoder of the schema of the checker.
+func NewDecoder(checker *typecheck.Checker) *Decoder {
+	return &Decoder{checker: checker}
+}
+
+// find returns the schema entry of a path, or nil if it is not in the
+// schema.
+func (d *Decoder) find(p *gpb.Path) *yang.Entry {
+	e, err := d.checker.Find(p)
+	if err != nil {
+		return nil
+	}
+	return e
+}
+
+// Decode returns the values of the leaves of the notifications.  JSON
+// values of containers and lists are decoded into the values of their
+// leaves.
+func (d *Decoder) Decode(ns []*gpb.Notification) (Values, error) {
+	vs := Values{}
+	for _, n := range ns {
+		for _, u := range n.GetUpdate() {
+			// The names of the elements are compared without the
+			// prefixes of their modules.
+			p := &gpb.Path{}
+			for _, e := range append(append([]*gpb.PathElem{}, n.GetPrefix().GetElem()...), u.GetPath().GetElem()...) {
+				p.Elem = append(p.Elem, &gpb.PathElem{Name: stripModule(e.GetName()), Key: e.GetKey()})
+			}
+			var err error
+			switch v := u.GetVal().GetValue().(type) {
+			case *gpb.TypedValue_JsonIetfVal:
+				err = d.decodeJSON(vs, p, v.JsonIetfVal)
+			case *gpb.TypedValue_JsonVal:
+				err = d.decodeJSON(vs, p, v.JsonVal)
+			default:
+				err = vs.set(p, scalar(d.find(p), u.GetVal()))
+			}
+			if err != nil {
+				return vs, err
+			}
+		}
+	}
+	return vs, nil
+}
+
+// set sets the value of a path.
+func (vs Values) set(p *gpb.Path, v string) error {
+	s, err := ygot.PathToString(p)
+	if err != nil {
+		return err
+	}
+	vs[s] = v
+	return nil
+}
+
+// decodeJSON decodes a JSON value at a path.
+func (d *Decoder) decodeJSON(vs Values, p *gpb.Path, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		path, _ := ygot.PathToString(p)
+		return fmt.Errorf("invalid JSON at %s: %w", path, err)
+	}
+	return d.walk(vs, p, d.find(p), v)
+}
+
+// stripModule returns a name without the prefix of its module.
+func stripModule(name string) string {
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// member returns the member of a JSON object of the name, with or without
+// the prefix of its module.
+func member(obj map[string]interface{}, name string) (interface{}, bool) {
+	for k, v := range obj {
+		if stripModule(k) == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// walk sets the values of the leaves of the JSON value of the entry e at
+// the path.
+func (d *Decoder) walk(vs Values, p *gpb.Path, e *yang.Entry, v interface{}) error {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if e != nil && (e.IsLeaf() || e.IsLeafList()) {
+			break
+		}
+		for k, child := range val {
+			cp := &gpb.Path{Elem: append(append([]*gpb.PathElem{}, p.GetElem()...), &gpb.PathElem{Name: stripModule(k)})}
+			if err := d.walk(vs, cp, d.find(cp), child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		if e == nil || !e.IsList() {
+			break
+		}
+		for i, entry := range val {
+			obj, ok := entry.(map[string]interface{})
+			if !ok {
+				path, _ := ygot.PathToString(p)
+				return fmt.Errorf("entry %d of the list %s is not an object", i, path)
+			}
+			elems := append([]*gpb.PathElem{}, p.GetElem()...)
+			last := &gpb.PathElem{Name: elems[len(elems)-1].GetName(), Key: map[string]string{}}
+			elems[len(elems)-1] = last
+			ep := &gpb.Path{Elem: elems}
+			for _, k := range strings.Fields(e.Key) {
+				kv, ok := member(obj, k)
+				if !ok {
+					path, _ := ygot.PathToString(p)
+					return fmt.Errorf("entry %d of the list %s has no key %s", i, path, k)
+				}
+				kp := &gpb.Path{Elem: append(append([]*gpb.PathElem{}, elems...), &gpb.PathElem{Name: k})}
+				last.Key[k] = jsonScalar(d.find(kp), kv)
+			}
+			if err := d.walk(vs, ep, e, obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return vs.set(p, jsonScalar(e, v))
+}
+
+// scalar returns a typed value of the entry e in the form compared.
+func scalar(e *yang.Entry, v *gpb.TypedValue) string {
+	var s string
+	switch val := v.GetValue().(type) {
+	case *gpb.TypedValue_StringVal:
+		s = val.StringVal
+	case *gpb.TypedValue_IntVal:
+		s = strconv.FormatInt(val.IntVal, 10)
+	case *gpb.TypedValue_UintVal:
+		s = strconv.FormatUint(val.UintVal, 10)
+	case *gpb.TypedValue_BoolVal:
+		s = strconv.FormatBool(val.BoolVal)
+	case *gpb.TypedValue_BytesVal:
+		s = base64.StdEncoding.EncodeToString(val.BytesVal)
+	case *gpb.TypedValue_FloatVal:
+		s = strconv.FormatFloat(float64(val.FloatVal), 'g', -1, 32)
+	case *gpb.TypedValue_DecimalVal:
+		f := float64(val.DecimalVal.GetDigits()) / math.Pow10(int(val.DecimalVal.GetPrecision()))
+		s = strconv.FormatFloat(f, 'g', -1, 64)
+	case *gpb.TypedValue_LeaflistVal:
+		var elems []string
+		for _, elem := range val.LeaflistVal.GetElement() {
+			elems = append(elems, scalar(e, elem))
+		}
+		return leafList(elems)
+	default:
+		s = strings.TrimSpace(fmt.Sprint(v))
+	}
+	return normalize(e, s)
+}
+
+// jsonScalar returns a decoded JSON value of a leaf or leaf-list of the
+// entry e in the form compared.
+func jsonScalar(e *yang.Entry, v interface{}) string {
+	var s string
+	switch val := v.(type) {
+	case string:
+		s = val
+	case json.Number:
+		s = string(val)
+	case bool:
+		s = strconv.FormatBool(val)
+	case []interface{}:
+		var elems []string
+		for _, elem := range val {
+			elems = append(elems, jsonScalar(e, elem))
+		}
+		return leafList(elems)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+	return normalize(e, s)
+}
+
+// leafList returns the elements of a leaf-list in the form compared.
+func leafList(elems []string) string {
+	b, err := json.Marshal(elems)
+	if err != nil {
+		return fmt.Sprint(elems)
+	}
+	return string(b)
+}
+
+// kinds returns the kinds of a type, and of the members of a union.
+func kinds(t *yang.YangType) map[yang.TypeKind]bool {
+	ks := map[yang.TypeKind]bool{}
+	if t == nil {
+		return ks
+	}
+	ks[t.Kind] = true
+	for _, member := range t.Type {
+		for k := range kinds(member) {
+			ks[k] = true
+		}
+	}
+	return ks
+}
+
+// normalize returns a scalar value of the entry e in the form compared:
+// an identity without the prefix of its module, and a decimal64 as a
+// number.  Both encodings are normalized alike, so a union of an identity
+// and a string is stripped of its prefix in both.
+func normalize(e *yang.Entry, v string) string {
+	if e == nil {
+		return v
+	}
+	ks := kinds(e.Type)
+	if ks[yang.Yidentityref] {
+		v = stripModule(v)
+	}
+	if ks[yang.Ydecimal64] {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			v = strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	}
+	return v
+}
+
+// schemaPath returns a path string without its list keys.
+func schemaPath(path string) string {
+	p, err := ygot.StringToStructuredPath(path)
+	if err != nil {
+		return path
+	}
+	var b strings.Builder
+	for _, e := range p.GetElem() {
+		b.WriteString("/")
+		b.WriteString(e.GetName())
+	}
+	return b.String()
+}
+
+// Compare returns the discrepancies between the values collected in
+// JSON_IETF before and after the ones collected in PROTO, sorted by path:
+// the values different in PROTO from the ones in JSON_IETF, and the paths
+// only one encoding has.  The paths with values or presence changed
+// between the JSON_IETF collections are volatile, and skipped.
+func Compare(before, proto, after Values) []*Discrepancy {
+	paths := map[string]bool{}
+	for _, vs := range []Values{before, proto, after} {
+		for p := range vs {
+			paths[p] = true
+		}
+	}
+	var ds []*Discrepancy
+	for p := range paths {
+		b, inBefore := before[p]
+		a, inAfter := after[p]
+		if inBefore != inAfter || b != a {
+			continue
+		}
+		j := Missing
+		if inBefore {
+			j = b
+		}
+		pv, ok := proto[p]
+		if !ok {
+			pv = Missing
+		}
+		if j != pv {
+			ds = append(ds, &Discrepancy{Path: p, SchemaPath: schemaPath(p), JSON: j, PROTO: pv})
+		}
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i].Path < ds[j].Path })
+	return ds
+}
+
+// Summarize counts the discrepancies by schema path, and returns the
+// schema paths in order with the first discrepancy of each.
+func Summarize(ds []*Discrepancy) ([]string, map[string]int, map[string]*Discrepancy) {
+	counts := map[string]int{}
+	first := map[string]*Discrepancy{}
+	for _, d := range ds {
+		if counts[d.SchemaPath] == 0 {
+			first[d.SchemaPath] = d
+		}
+		counts[d.SchemaPath]++
+	}
+	var paths []string
+	for p := range counts {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths, counts, first
+}
+
+// Collect collects the values of the paths of the DUT in JSON_IETF, PROTO
+// and JSON_IETF again, and returns their discrepancies.
+func Collect(t testing.TB, dut *ondatra.DUTDevice, paths ...*gpb.Path) ([]*Discrepancy, error) {
+	t.Helper()
+	checker, err := typecheck.New()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load the schema: %w", err)
+	}
+	d := NewDecoder(checker)
+	client := dut.RawAPIs().GNMI().Default(t)
+	var vs []Values
+	for _, enc := range []gpb.Encoding{gpb.Encoding_JSON_IETF, gpb.Encoding_PROTO, gpb.Encoding_JSON_IETF} {
+		ctx, cancel := context.WithTimeout(context.Background(), args.Convergence(collectTimeout))
+		ns, err := typecheck.CollectEncoding(ctx, client, enc, paths...)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("cannot collect the values in %v: %w", enc, err)
+		}
+		v, err := d.Decode(ns)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode the values in %v: %w", enc, err)
+		}
+		vs = append(vs, v)
+	}
+	t.Logf("Collected %d values in JSON_IETF and %d in PROTO", len(vs[0]), len(vs[1]))
+	return Compare(vs[0], vs[1], vs[2]), nil
+}
+
+// Check collects the values of the paths of the DUT in both encodings,
+// and reports an error per schema path with discrepancies.  The check is
+// skipped if the DUT does not advertise both encodings.
+func Check(t testing.TB, dut *ondatra.DUTDevice, paths ...*gpb.Path) {
+	t.Helper()
+	r := gnmicap.Requirements{Encodings: []gpb.Encoding{gpb.Encoding_JSON_IETF, gpb.Encoding_PROTO}}
+	if msgs := r.Unmet(gnmicap.Get(t, dut)); len(msgs) > 0 {
+		t.Logf("Encodings not compared, as %s does not support both: %s", dut.Name(), strings.Join(msgs, "; "))
+		return
+	}
+	ds, err := Collect(t, dut, paths...)
+	if err != nil {
+		t.Fatalf("Cannot compare the encodings: %v", err)
+	}
+	schemaPaths, counts, first := Summarize(ds)
+	for _, p := range schemaPaths {
+		t.Errorf("%s: %d values differ between JSON_IETF and PROTO, first %v", p, counts[p], first[p])
+	}
+}
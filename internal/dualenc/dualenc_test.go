@@ -0,0 +1,198 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dualenc
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/typecheck"
+	"github.com/openconfig/goyang/pkg/yang"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// newDecoder returns a decoder of a schema of an interface list.
+func newDecoder() *Decoder {
+	root := &yang.Entry{Name: "device", Kind: yang.DirectoryEntry, Dir: map[string]*yang.Entry{}}
+	add := func(parent *yang.Entry, e *yang.Entry) *yang.Entry {
+		e.Parent = parent
+		parent.Dir[e.Name] = e
+		return e
+	}
+	dir := func(parent *yang.Entry, name string) *yang.Entry {
+		return add(parent, &yang.Entry{Name: name, Kind: yang.DirectoryEntry, Dir: map[string]*yang.Entry{}})
+	}
+	leaf := func(parent *yang.Entry, name string, t *yang.YangType) *yang.Entry {
+		return add(parent, &yang.Entry{Name: name, Kind: yang.LeafEntry, Type: t})
+	}
+
+	intf := dir(dir(root, "interfaces"), "interface")
+	intf.ListAttr = &yang.ListAttr{}
+	intf.Key = "name"
+	leaf(intf, "name", &yang.YangType{Kind: yang.Ystring})
+	state := dir(intf, "state")
+	leaf(state, "mtu", &yang.YangType{Kind: yang.Yuint16})
+	leaf(state, "enabled", &yang.YangType{Kind: yang.Ybool})
+	leaf(state, "type", &yang.YangType{Kind: yang.Yidentityref})
+	leaf(state, "temperature", &yang.YangType{Kind: yang.Ydecimal64, FractionDigits: 1})
+	leaf(state, "mac", &yang.YangType{Kind: yang.Ybinary})
+	ips := leaf(state, "ips", &yang.YangType{Kind: yang.Ystring})
+	ips.ListAttr = &yang.ListAttr{}
+	leaf(dir(state, "counters"), "in-pkts", &yang.YangType{Kind: yang.Yuint64})
+	return NewDecoder(typecheck.NewFromRoot(root))
+}
+
+func path(elems ...*gpb.PathElem) *gpb.Path {
+	return &gpb.Path{Elem: elems}
+}
+
+func elem(name string, kv ...string) *gpb.PathElem {
+	e := &gpb.PathElem{Name: name}
+	if len(kv) == 2 {
+		e.Key = map[string]string{kv[0]: kv[1]}
+	}
+	return e
+}
+
+func update(p *gpb.Path, v *gpb.TypedValue) *gpb.Update {
+	return &gpb.Update{Path: p, Val: v}
+}
+
+// protoNotifications are the values of an interface in PROTO.
+var protoNotifications = []*gpb.Notification{{
+	Prefix: path(elem("openconfig-interfaces:interfaces"), elem("interface", "name", "eth0")),
+	Update: []*gpb.Update{
+		update(path(elem("name")), &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "eth0"}}),
+		update(path(elem("state"), elem("mtu")), &gpb.TypedValue{Value: &gpb.TypedValue_UintVal{UintVal: 1500}}),
+		update(path(elem("state"), elem("enabled")), &gpb.TypedValue{Value: &gpb.TypedValue_BoolVal{BoolVal: true}}),
+		update(path(elem("state"), elem("type")), &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "ethernetCsmacd"}}),
+		update(path(elem("state"), elem("temperature")), &gpb.TypedValue{Value: &gpb.TypedValue_DecimalVal{DecimalVal: &gpb.Decimal64{Digits: 425, Precision: 1}}}),
+		update(path(elem("state"), elem("mac")), &gpb.TypedValue{Value: &gpb.TypedValue_BytesVal{BytesVal: []byte{0, 1, 2}}}),
+		update(path(elem("state"), elem("ips")), &gpb.TypedValue{Value: &gpb.TypedValue_LeaflistVal{LeaflistVal: &gpb.ScalarArray{Element: []*gpb.TypedValue{
+			{Value: &gpb.TypedValue_StringVal{StringVal: "192.0.2.1"}},
+			{Value: &gpb.TypedValue_StringVal{StringVal: "192.0.2.2"}},
+		}}}}),
+		update(path(elem("state"), elem("counters"), elem("in-pkts")), &gpb.TypedValue{Value: &gpb.TypedValue_UintVal{UintVal: 10}}),
+	},
+}}
+
+// jsonNotifications are the same values in JSON_IETF, at the interfaces
+// container.
+var jsonNotifications = []*gpb.Notification{{
+	Update: []*gpb.Update{update(path(elem("interfaces")), &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: []byte(`{
+  "openconfig-interfaces:interface": [{
+    "name": "eth0",
+    "state": {
+      "mtu": 1500,
+      "enabled": true,
+      "type": "iana-if-type:ethernetCsmacd",
+      "temperature": "42.50",
+      "mac": "AAEC",
+      "ips": ["192.0.2.1", "192.0.2.2"],
+      "counters": {"in-pkts": "10"}
+    }
+  }]
+}`)}})},
+}}
+
+var wantValues = Values{
+	"/interfaces/interface[name=eth0]/name":                   "eth0",
+	"/interfaces/interface[name=eth0]/state/mtu":              "1500",
+	"/interfaces/interface[name=eth0]/state/enabled":          "true",
+	"/interfaces/interface[name=eth0]/state/type":             "ethernetCsmacd",
+	"/interfaces/interface[name=eth0]/state/temperature":      "42.5",
+	"/interfaces/interface[name=eth0]/state/mac":              "AAEC",
+	"/interfaces/interface[name=eth0]/state/ips":              `["192.0.2.1","192.0.2.2"]`,
+	"/interfaces/interface[name=eth0]/state/counters/in-pkts": "10",
+}
+
+func TestDecode(t *testing.T) {
+	d := newDecoder()
+	for _, c := range []struct {
+		desc string
+		ns   []*gpb.Notification
+	}{
+		{"PROTO", protoNotifications},
+		{"JSON_IETF", jsonNotifications},
+	} {
+		got, err := d.Decode(c.ns)
+		if err != nil {
+			t.Fatalf("%s: Decode() got error: %v", c.desc, err)
+		}
+		if diff := cmp.Diff(wantValues, got); diff != "" {
+			t.Errorf("%s: Decode() -want, +got:\n%s", c.desc, diff)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	d := newDecoder()
+	for _, c := range []struct {
+		desc string
+		json string
+	}{
+		{"invalid JSON", `{"interface": [`},
+		{"entry not an object", `{"interface": ["eth0"]}`},
+		{"entry without key", `{"interface": [{"state": {"mtu": 1500}}]}`},
+	} {
+		ns := []*gpb.Notification{{Update: []*gpb.Update{
+			update(path(elem("interfaces")), &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: []byte(c.json)}}),
+		}}}
+		if _, err := d.Decode(ns); err == nil {
+			t.Errorf("%s: Decode() got no error, want error", c.desc)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	before := Values{"/a": "1", "/b": "x", "/c": "5", "/d": "y", "/e": "z"}
+	after := Values{"/a": "1", "/b": "x", "/c": "6", "/d": "y", "/f": "w"}
+	proto := Values{"/a": "1", "/b": "X", "/c": "5", "/g": "v", "/f": "w"}
+	want := []*Discrepancy{
+		{Path: "/b", SchemaPath: "/b", JSON: "x", PROTO: "X"},
+		{Path: "/d", SchemaPath: "/d", JSON: "y", PROTO: Missing},
+		{Path: "/g", SchemaPath: "/g", JSON: Missing, PROTO: "v"},
+	}
+	if diff := cmp.Diff(want, Compare(before, proto, after)); diff != "" {
+		t.Errorf("Compare() -want, +got:\n%s", diff)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	ds := []*Discrepancy{
+		{Path: "/interfaces/interface[name=eth1]/state/mtu", SchemaPath: "/interfaces/interface/state/mtu"},
+		{Path: "/interfaces/interface[name=eth0]/state/type", SchemaPath: "/interfaces/interface/state/type"},
+		{Path: "/interfaces/interface[name=eth2]/state/mtu", SchemaPath: "/interfaces/interface/state/mtu"},
+	}
+	paths, counts, first := Summarize(ds)
+	if diff := cmp.Diff([]string{"/interfaces/interface/state/mtu", "/interfaces/interface/state/type"}, paths); diff != "" {
+		t.Errorf("Summarize() paths -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]int{"/interfaces/interface/state/mtu": 2, "/interfaces/interface/state/type": 1}, counts); diff != "" {
+		t.Errorf("Summarize() counts -want, +got:\n%s", diff)
+	}
+	if first["/interfaces/interface/state/mtu"] != ds[0] {
+		t.Errorf("Summarize() first got %v, want %v", first["/interfaces/interface/state/mtu"], ds[0])
+	}
+}
+
+func TestDiscrepancyError(t *testing.T) {
+	d := &Discrepancy{Path: "/interfaces/interface[name=eth0]/state/mtu", JSON: "1500", PROTO: Missing}
+	want := "/interfaces/interface[name=eth0]/state/mtu: 1500 in JSON_IETF, <missing> in PROTO"
+	if got := d.Error(); got != want {
+		t.Errorf("Error() got %q, want %q", got, want)
+	}
+}
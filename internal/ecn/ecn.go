@@ -0,0 +1,163 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ecn provides helpers to validate congestion management:
+// oversubscribing an egress queue from the ATE, measuring the ratio of
+// packets marked Congestion Experienced (CE) by the DUT, and reading
+// the WRED drop counters of the queue.
+//
+// The ECN codepoint of the packets received by the ATE is measured
+// using egress tracking on the two ECN bits of the IP header, so no
+// packet capture is needed.
+package ecn
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// ECN codepoints, RFC 3168.
+const (
+	NotECT = 0
+	ECT1   = 1
+	ECT0   = 2
+	CE     = 3
+)
+
+// Bit offsets of the ECN field in untagged Ethernet frames, for egress
+// tracking.  The Ethernet header is 112 bits; the ECN field is the
+// last two bits of the IPv4 TOS octet, and bits 10-11 of the IPv6
+// header.
+const (
+	ipv4Offset = 112 + 14
+	ipv6Offset = 112 + 10
+	ecnWidth   = 2
+)
+
+// Flows returns one flow from each of the src interfaces to dst, whose
+// combined rate is loadPct percent of the line rate of one port.  A
+// load above 100 oversubscribes the egress queue of the DUT towards
+// dst.  The packets are marked ECT(0) and the ATE tracks the ECN field
+// of the packets it receives.
+func Flows(ate *ondatra.ATEDevice, srcs []*ondatra.Interface, dst *ondatra.Interface, ipv6 bool, dscp uint8, loadPct float64) []*ondatra.Flow {
+	var flows []*ondatra.Flow
+	for i, src := range srcs {
+		var ip ondatra.Header
+		offset := uint32(ipv4Offset)
+		if ipv6 {
+			h := ondatra.NewIPv6Header().WithDSCP(dscp).WithECN(ECT0)
+			ip, offset = h, ipv6Offset
+		} else {
+			ip = ondatra.NewIPv4Header().WithDSCP(dscp).WithECN(ECT0)
+		}
+		flows = append(flows, ate.Traffic().NewFlow(fmt.Sprintf("ecn%d", i)).
+			WithSrcEndpoints(src).
+			WithDstEndpoints(dst).
+			WithHeaders(ondatra.NewEthernetHeader(), ip).
+			WithFrameRatePct(loadPct/float64(len(srcs))).
+			WithEgressTrackingEnabled(offset, ecnWidth))
+	}
+	return flows
+}
+
+// Marking is the count of received packets by ECN codepoint.
+type Marking map[uint8]uint64
+
+// Total returns the number of packets counted.
+func (m Marking) Total() uint64 {
+	var total uint64
+	for _, n := range m {
+		total += n
+	}
+	return total
+}
+
+// CERatio returns the ratio of packets marked CE, or 0 if no packet
+// was counted.
+func (m Marking) CERatio() float64 {
+	total := m.Total()
+	if total == 0 {
+		return 0
+	}
+	return float64(m[CE]) / float64(total)
+}
+
+// add adds the count of packets whose egress tracking filter is f.
+func (m Marking) add(filter string, pkts uint64) error {
+	v, err := strconv.ParseUint(filter, 10, 8)
+	if err != nil || v > CE {
+		return fmt.Errorf("unexpected ECN egress tracking filter %q", filter)
+	}
+	m[uint8(v)] += pkts
+	return nil
+}
+
+// Measure returns the ECN marking of the packets the ATE received on
+// the flows.
+func Measure(t testing.TB, ate *ondatra.ATEDevice, flows []*ondatra.Flow) Marking {
+	t.Helper()
+	m := Marking{}
+	for _, f := range flows {
+		for _, et := range ate.Telemetry().Flow(f.Name()).EgressTrackingAny().Get(t) {
+			if err := m.add(et.GetFilter(), et.GetCounters().GetInPkts()); err != nil {
+				t.Errorf("Flow %s: %v", f.Name(), err)
+			}
+		}
+	}
+	return m
+}
+
+// QueueCounters are the counters of an egress queue.
+type QueueCounters struct {
+	TransmitPkts uint64
+	DroppedPkts  uint64
+}
+
+// Sub returns the counters increase since prev.
+func (c QueueCounters) Sub(prev QueueCounters) QueueCounters {
+	return QueueCounters{
+		TransmitPkts: c.TransmitPkts - prev.TransmitPkts,
+		DroppedPkts:  c.DroppedPkts - prev.DroppedPkts,
+	}
+}
+
+// ReadQueue reads the counters of an output queue of a QoS interface.
+func ReadQueue(t testing.TB, dut *ondatra.DUTDevice, intf, queue string) QueueCounters {
+	t.Helper()
+	q := dut.Telemetry().Qos().Interface(intf).Output().Queue(queue).Get(t)
+	return QueueCounters{
+		TransmitPkts: q.GetTransmitPkts(),
+		DroppedPkts:  q.GetDroppedPkts(),
+	}
+}
+
+// WREDProfile returns a queue management profile with uniform WRED
+// between the min and max thresholds, in bytes.  When ecn is true,
+// packets are marked instead of dropped between the thresholds.
+func WREDProfile(name string, min, max uint64, maxDropPct uint8, ecn bool) *telemetry.Qos_QueueManagementProfile {
+	p := &telemetry.Qos_QueueManagementProfile{Name: ygot.String(name)}
+	u := p.GetOrCreateWred().GetOrCreateUniform()
+	u.MinThreshold = ygot.Uint64(min)
+	u.MaxThreshold = ygot.Uint64(max)
+	u.MaxDropProbabilityPercent = ygot.Uint8(maxDropPct)
+	u.EnableEcn = ygot.Bool(ecn)
+	u.Drop = ygot.Bool(!ecn)
+	return p
+}
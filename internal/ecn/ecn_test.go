@@ -0,0 +1,77 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecn
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestMarking(t *testing.T) {
+	m := Marking{}
+	for filter, pkts := range map[string]uint64{"2": 700, "3": 300} {
+		if err := m.add(filter, pkts); err != nil {
+			t.Fatalf("add(%q) got error: %v", filter, err)
+		}
+	}
+	if got, want := m.Total(), uint64(1000); got != want {
+		t.Errorf("Total got %d, want %d", got, want)
+	}
+	if got, want := m.CERatio(), 0.3; got != want {
+		t.Errorf("CERatio got %v, want %v", got, want)
+	}
+	if got := (Marking{}).CERatio(); got != 0 {
+		t.Errorf("CERatio of empty marking got %v, want 0", got)
+	}
+}
+
+func TestMarkingAddError(t *testing.T) {
+	for _, filter := range []string{"", "4", "x"} {
+		if err := (Marking{}).add(filter, 1); err == nil {
+			t.Errorf("add(%q) got no error, want error", filter)
+		}
+	}
+}
+
+func TestQueueCountersSub(t *testing.T) {
+	got := QueueCounters{TransmitPkts: 150, DroppedPkts: 20}.Sub(QueueCounters{TransmitPkts: 50, DroppedPkts: 5})
+	want := QueueCounters{TransmitPkts: 100, DroppedPkts: 15}
+	if got != want {
+		t.Errorf("Sub got %+v, want %+v", got, want)
+	}
+}
+
+func TestWREDProfile(t *testing.T) {
+	got := WREDProfile("ecn", 80000, 2000000, 10, true)
+	want := &telemetry.Qos_QueueManagementProfile{
+		Name: ygot.String("ecn"),
+		Wred: &telemetry.Qos_QueueManagementProfile_Wred{
+			Uniform: &telemetry.Qos_QueueManagementProfile_Wred_Uniform{
+				MinThreshold:              ygot.Uint64(80000),
+				MaxThreshold:              ygot.Uint64(2000000),
+				MaxDropProbabilityPercent: ygot.Uint8(10),
+				EnableEcn:                 ygot.Bool(true),
+				Drop:                      ygot.Bool(false),
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("WREDProfile got unexpected profile, diff(-want,+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ethernet provides helpers to configure the port speed, FEC mode
+// and autonegotiation of an Ethernet interface, and to verify from
+// telemetry that the link comes back up with the configured settings.
+//
+// Changing any of them retrains the link, so the interface goes down
+// before coming back up, or stays down if the ATE port does not support
+// the settings.  DUTs may also reject combinations they do not support,
+// e.g. a FEC mode not defined for the port speed.
+package ethernet
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/testt"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// speeds maps the speeds of the testbed ports to OpenConfig.
+var speeds = map[ondatra.Speed]telemetry.E_IfEthernet_ETHERNET_SPEED{
+	ondatra.Speed1Gb:   telemetry.IfEthernet_ETHERNET_SPEED_SPEED_1GB,
+	ondatra.Speed5Gb:   telemetry.IfEthernet_ETHERNET_SPEED_SPEED_5GB,
+	ondatra.Speed10Gb:  telemetry.IfEthernet_ETHERNET_SPEED_SPEED_10GB,
+	ondatra.Speed100Gb: telemetry.IfEthernet_ETHERNET_SPEED_SPEED_100GB,
+	ondatra.Speed400Gb: telemetry.IfEthernet_ETHERNET_SPEED_SPEED_400GB,
+}
+
+// Speed returns the OpenConfig port speed of a testbed port speed, and
+// whether there is one.
+func Speed(s ondatra.Speed) (telemetry.E_IfEthernet_ETHERNET_SPEED, bool) {
+	speed, ok := speeds[s]
+	return speed, ok
+}
+
+// Settings are the Ethernet settings of an interface.  The zero value of
+// Speed and FEC leaves them to the DUT.
+type Settings struct {
+	Speed         telemetry.E_IfEthernet_ETHERNET_SPEED
+	FEC           telemetry.E_IfEthernet_INTERFACE_FEC
+	AutoNegotiate bool
+}
+
+func (s *Settings) String() string {
+	return fmt.Sprintf("speed %v, FEC %v, autonegotiation %t", s.Speed, s.FEC, s.AutoNegotiate)
+}
+
+// Set sets the settings in the Ethernet configuration of an interface.
+// Without autonegotiation, the duplex mode is set to full.
+func (s *Settings) Set(e *telemetry.Interface_Ethernet) {
+	e.AutoNegotiate = ygot.Bool(s.AutoNegotiate)
+	if !s.AutoNegotiate {
+		e.DuplexMode = telemetry.Ethernet_DuplexMode_FULL
+	}
+	if s.Speed != 0 {
+		e.PortSpeed = s.Speed
+	}
+	if s.FEC != 0 {
+		e.FecMode = s.FEC
+	}
+}
+
+// Configure updates the Ethernet configuration of the interface of the
+// DUT with the settings.
+func Configure(t testing.TB, dut *ondatra.DUTDevice, intf string, s *Settings) {
+	t.Helper()
+	e := &telemetry.Interface_Ethernet{}
+	s.Set(e)
+	dut.Config().Interface(intf).Ethernet().Update(t, e)
+}
+
+// TryConfigure is like Configure, but returns the error of the DUT
+// rejecting the settings rather than failing the test.
+func TryConfigure(t testing.TB, dut *ondatra.DUTDevice, intf string, s *Settings) error {
+	t.Helper()
+	if msg := testt.CaptureFatal(t, func(t testing.TB) {
+		Configure(t, dut, intf, s)
+	}); msg != nil {
+		return fmt.Errorf("interface %s rejected %v: %s", intf, s, *msg)
+	}
+	return nil
+}
+
+// AwaitUp waits for the interface of the DUT to be operationally up,
+// and returns whether it was within the timeout.
+func AwaitUp(t testing.TB, dut *ondatra.DUTDevice, intf string, timeout time.Duration) bool {
+	t.Helper()
+	_, ok := dut.Telemetry().Interface(intf).OperStatus().Watch(t, timeout, func(val *telemetry.QualifiedE_Interface_OperStatus) bool {
+		return val.IsPresent() && val.Val(t) == telemetry.Interface_OperStatus_UP
+	}).Await(t)
+	return ok
+}
+
+// Check checks the Ethernet state of an interface against the settings.
+// With autonegotiation, the negotiated port speed is checked if reported.
+func Check(e *telemetry.Interface_Ethernet, s *Settings) []error {
+	var errs []error
+	if got, want := e.GetAutoNegotiate(), s.AutoNegotiate; got != want {
+		errs = append(errs, fmt.Errorf("auto-negotiate got %t, want %t", got, want))
+	}
+	if s.Speed != 0 {
+		if got, want := e.GetPortSpeed(), s.Speed; got != want {
+			errs = append(errs, fmt.Errorf("port-speed got %v, want %v", got, want))
+		}
+		if got := e.GetNegotiatedPortSpeed(); s.AutoNegotiate && got != 0 && got != s.Speed {
+			errs = append(errs, fmt.Errorf("negotiated-port-speed got %v, want %v", got, s.Speed))
+		}
+	}
+	if s.FEC != 0 {
+		if got, want := e.GetFecMode(), s.FEC; got != want {
+			errs = append(errs, fmt.Errorf("fec-mode got %v, want %v", got, want))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethernet
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestSpeed(t *testing.T) {
+	if got, ok := Speed(ondatra.Speed100Gb); !ok || got != telemetry.IfEthernet_ETHERNET_SPEED_SPEED_100GB {
+		t.Errorf("Speed(100Gb) got %v, %t, want SPEED_100GB", got, ok)
+	}
+	if got, ok := Speed(0); ok {
+		t.Errorf("Speed(unspecified) got %v, want none", got)
+	}
+}
+
+func TestSet(t *testing.T) {
+	for _, c := range []struct {
+		desc string
+		s    *Settings
+		want *telemetry.Interface_Ethernet
+	}{{
+		desc: "forced",
+		s: &Settings{
+			Speed: telemetry.IfEthernet_ETHERNET_SPEED_SPEED_100GB,
+			FEC:   telemetry.IfEthernet_INTERFACE_FEC_FEC_RS528,
+		},
+		want: &telemetry.Interface_Ethernet{
+			AutoNegotiate: ygot.Bool(false),
+			DuplexMode:    telemetry.Ethernet_DuplexMode_FULL,
+			PortSpeed:     telemetry.IfEthernet_ETHERNET_SPEED_SPEED_100GB,
+			FecMode:       telemetry.IfEthernet_INTERFACE_FEC_FEC_RS528,
+		},
+	}, {
+		desc: "autonegotiated",
+		s:    &Settings{AutoNegotiate: true},
+		want: &telemetry.Interface_Ethernet{AutoNegotiate: ygot.Bool(true)},
+	}} {
+		got := &telemetry.Interface_Ethernet{}
+		c.s.Set(got)
+		if diff := cmp.Diff(c.want, got); diff != "" {
+			t.Errorf("%s: Set() -want, +got:\n%s", c.desc, diff)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := &Settings{
+		Speed:         telemetry.IfEthernet_ETHERNET_SPEED_SPEED_100GB,
+		FEC:           telemetry.IfEthernet_INTERFACE_FEC_FEC_RS528,
+		AutoNegotiate: true,
+	}
+	state := func() *telemetry.Interface_Ethernet {
+		return &telemetry.Interface_Ethernet{
+			AutoNegotiate:       ygot.Bool(true),
+			PortSpeed:           telemetry.IfEthernet_ETHERNET_SPEED_SPEED_100GB,
+			NegotiatedPortSpeed: telemetry.IfEthernet_ETHERNET_SPEED_SPEED_100GB,
+			FecMode:             telemetry.IfEthernet_INTERFACE_FEC_FEC_RS528,
+		}
+	}
+	for _, c := range []struct {
+		desc     string
+		modify   func(e *telemetry.Interface_Ethernet)
+		wantErrs int
+	}{
+		{"consistent", func(e *telemetry.Interface_Ethernet) {}, 0},
+		{"negotiated speed not reported", func(e *telemetry.Interface_Ethernet) { e.NegotiatedPortSpeed = 0 }, 0},
+		{"wrong negotiated speed", func(e *telemetry.Interface_Ethernet) {
+			e.NegotiatedPortSpeed = telemetry.IfEthernet_ETHERNET_SPEED_SPEED_10GB
+		}, 1},
+		{"autonegotiation not reported", func(e *telemetry.Interface_Ethernet) { e.AutoNegotiate = nil }, 1},
+		{"wrong speed and FEC", func(e *telemetry.Interface_Ethernet) {
+			e.PortSpeed = telemetry.IfEthernet_ETHERNET_SPEED_SPEED_400GB
+			e.FecMode = telemetry.IfEthernet_INTERFACE_FEC_FEC_DISABLED
+		}, 2},
+	} {
+		e := state()
+		c.modify(e)
+		if got := Check(e, s); len(got) != c.wantErrs {
+			t.Errorf("%s: Check() got errors %v, want %d", c.desc, got, c.wantErrs)
+		}
+	}
+}
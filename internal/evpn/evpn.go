@@ -0,0 +1,392 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package evpn provides helpers for EVPN-VXLAN tests: the configuration of
+// the MAC-VRFs of the DUT and of the L2VPN EVPN address family of its BGP
+// neighbors, remote VTEPs emulated by OTG BGP peers advertising the EVPN
+// routes of their hosts, flows of VXLAN encapsulated frames, and the
+// verification of the EVPN routes the DUT learns and of the VXLAN packets
+// it sends from captures.
+//
+// Usage:
+//
+//	in := &evpn.Instance{Name: "MAC-VRF-100", VNI: 10100, RD: "203.0.113.1:100", RT: "64496:100"}
+//	d.NetworkInstance(in.Name).Replace(t, in.MACVRF(loopback, dut.Port(t, "port1").Name()))
+//	evpn.EnableBGP(bgp, "192.0.2.6")
+//	...
+//	v := &evpn.VTEP{RD: "192.0.2.6:100", MAC: "02:00:0a:00:00:01", IPv4: "198.51.100.101", Hosts: 4}
+//	in.Advertise(peer, "vtep", v)
+//	err := evpn.AwaitMACs(t, dut, v.RD, macs, time.Minute)
+package evpn
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// MaxVNI is the largest VXLAN network identifier, which has 24 bits.
+const MaxVNI = 1<<24 - 1
+
+// Instance is a layer 2 EVPN instance bridging hosts over VXLAN with a
+// VNI.  The DUT and the remote VTEPs each advertise their routes with their
+// route distinguisher, and import the routes of the shared route target.
+type Instance struct {
+	// Name is the name of the MAC-VRF of the DUT.
+	Name string
+	VNI  uint32
+	// RD is the route distinguisher of the DUT.
+	RD string
+	RT string
+}
+
+// kind is the kind of the administrator field of a route distinguisher or
+// route target.
+type kind int
+
+const (
+	kindAS2 kind = iota
+	kindIPv4
+	kindAS4
+)
+
+// parse returns the kind of the route distinguisher or route target of
+// the form administrator:assigned-number, where the administrator is a
+// 2-byte AS number with a 4-byte number, or an IPv4 address or a 4-byte AS
+// number with a 2-byte number.
+func parse(v string) (kind, error) {
+	i := strings.LastIndex(v, ":")
+	if i < 0 {
+		return 0, fmt.Errorf("%q is not of the form administrator:number", v)
+	}
+	admin, num := v[:i], v[i+1:]
+	k := kindIPv4
+	if ip := net.ParseIP(admin); ip == nil || ip.To4() == nil {
+		as, err := strconv.ParseUint(admin, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("%q has an administrator which is neither an IPv4 address nor an AS number", v)
+		}
+		k = kindAS4
+		if as <= 0xffff {
+			k = kindAS2
+		}
+	}
+	bits := 16
+	if k == kindAS2 {
+		bits = 32
+	}
+	if _, err := strconv.ParseUint(num, 10, bits); err != nil {
+		return 0, fmt.Errorf("%q has a number which is not %d bits", v, bits)
+	}
+	return k, nil
+}
+
+// Validate returns an error if the VNI is out of range, or the route
+// distinguisher or route target is invalid.
+func (in *Instance) Validate() error {
+	if in.VNI == 0 || in.VNI > MaxVNI {
+		return fmt.Errorf("instance %s: VNI %d out of [1, %d]", in.Name, in.VNI, MaxVNI)
+	}
+	if _, err := parse(in.RD); err != nil {
+		return fmt.Errorf("instance %s: route distinguisher %v", in.Name, err)
+	}
+	if _, err := parse(in.RT); err != nil {
+		return fmt.Errorf("instance %s: route target %v", in.Name, err)
+	}
+	return nil
+}
+
+// MACVRF returns the MAC-VRF of the instance, bridging the access
+// interfaces of the DUT to the VXLAN tunnels sourced from the address of
+// the source interface.  The VNIs of the VXLAN endpoint are state only, and
+// derived from the VNI of the EVPN instance.
+func (in *Instance) MACVRF(source string, intfs ...string) *telemetry.NetworkInstance {
+	ni := &telemetry.NetworkInstance{
+		Name:    ygot.String(in.Name),
+		Type:    telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L2VSI,
+		Enabled: ygot.Bool(true),
+	}
+	for _, intf := range intfs {
+		i := ni.GetOrCreateInterface(intf)
+		i.Interface = ygot.String(intf)
+		i.Subinterface = ygot.Uint32(0)
+	}
+
+	evi := ni.GetOrCreateEvpn().GetOrCreateEvpnInstance(fmt.Sprint(in.VNI))
+	evi.EncapsulationType = telemetry.NetworkInstanceTypes_ENCAPSULATION_VXLAN
+	evi.ServiceType = telemetry.EvpnTypes_EVPN_TYPE_VLAN_BASED
+	evi.RouteDistinguisher = telemetry.UnionString(in.RD)
+	policy := evi.GetOrCreateImportExportPolicy()
+	policy.ImportRouteTarget = []telemetry.NetworkInstance_Evpn_EvpnInstance_ImportExportPolicy_ImportRouteTarget_Union{telemetry.UnionString(in.RT)}
+	policy.ExportRouteTarget = []telemetry.NetworkInstance_Evpn_EvpnInstance_ImportExportPolicy_ExportRouteTarget_Union{telemetry.UnionString(in.RT)}
+	evi.GetOrCreateVxlan().Vni = ygot.Uint32(in.VNI)
+
+	vxlan := ni.GetOrCreateConnectionPoint("vxlan").GetOrCreateEndpoint("vxlan").GetOrCreateVxlan()
+	vxlan.Enabled = ygot.Bool(true)
+	vxlan.SourceInterface = ygot.String(source)
+	return ni
+}
+
+// EnableBGP enables the L2VPN EVPN address family of BGP, globally and on
+// the neighbors.
+func EnableBGP(bgp *telemetry.NetworkInstance_Protocol_Bgp, neighbors ...string) {
+	bgp.GetOrCreateGlobal().GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_L2VPN_EVPN).Enabled = ygot.Bool(true)
+	for _, addr := range neighbors {
+		bgp.GetOrCreateNeighbor(addr).GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_L2VPN_EVPN).Enabled = ygot.Bool(true)
+	}
+}
+
+// VTEP is a remote VTEP of an instance, emulated by an OTG BGP peer.  Its
+// VXLAN tunnels end at the address of the peer.
+type VTEP struct {
+	// RD is the route distinguisher of the routes of the VTEP.
+	RD string
+	// MAC and IPv4 are the addresses of the first host behind the VTEP,
+	// and Hosts the number of hosts, of consecutive addresses.
+	MAC, IPv4 string
+	Hosts     int
+}
+
+// HostMACs returns the MAC addresses of the hosts behind the VTEP, in
+// lower case.
+func (v *VTEP) HostMACs() ([]string, error) {
+	mac, err := net.ParseMAC(v.MAC)
+	if err != nil || len(mac) != 6 {
+		return nil, fmt.Errorf("VTEP %s: bad MAC address %q", v.RD, v.MAC)
+	}
+	var first uint64
+	for _, b := range mac {
+		first = first<<8 | uint64(b)
+	}
+	if v.Hosts <= 0 || first+uint64(v.Hosts)-1 > 1<<48-1 {
+		return nil, fmt.Errorf("VTEP %s: %d hosts from %s out of the MAC addresses", v.RD, v.Hosts, v.MAC)
+	}
+	var macs []string
+	for i := uint64(0); i < uint64(v.Hosts); i++ {
+		m := first + i
+		macs = append(macs, net.HardwareAddr{byte(m >> 40), byte(m >> 32), byte(m >> 24), byte(m >> 16), byte(m >> 8), byte(m)}.String())
+	}
+	return macs, nil
+}
+
+// otgKinds are the OTG types of the route distinguishers of each kind.
+var otgKinds = map[kind]gosnappi.BgpRouteDistinguisherRdTypeEnum{
+	kindAS2:  gosnappi.BgpRouteDistinguisherRdType.AS_2OCTET,
+	kindIPv4: gosnappi.BgpRouteDistinguisherRdType.IPV4_ADDRESS,
+	kindAS4:  gosnappi.BgpRouteDistinguisherRdType.AS_4OCTET,
+}
+
+// otgRTKinds are the OTG types of the route targets of each kind.
+var otgRTKinds = map[kind]gosnappi.BgpRouteTargetRtTypeEnum{
+	kindAS2:  gosnappi.BgpRouteTargetRtType.AS_2OCTET,
+	kindIPv4: gosnappi.BgpRouteTargetRtType.IPV4_ADDRESS,
+	kindAS4:  gosnappi.BgpRouteTargetRtType.AS_4OCTET,
+}
+
+// Advertise configures the OTG BGP peer to emulate the remote VTEP of the
+// instance, with the EVPN capability and a single-homed ethernet segment
+// advertising the inclusive multicast route of ingress replication, and
+// the MAC/IP routes of the hosts behind the VTEP.  The name must be unique
+// in the OTG configuration.
+func (in *Instance) Advertise(peer gosnappi.BgpV4Peer, name string, v *VTEP) error {
+	if err := in.Validate(); err != nil {
+		return err
+	}
+	rdKind, err := parse(v.RD)
+	if err != nil {
+		return fmt.Errorf("VTEP route distinguisher %v", err)
+	}
+	rtKind, _ := parse(in.RT)
+	if _, err := v.HostMACs(); err != nil {
+		return err
+	}
+	if ip := net.ParseIP(v.IPv4); ip == nil || ip.To4() == nil {
+		return fmt.Errorf("VTEP %s: bad IPv4 address %q", v.RD, v.IPv4)
+	}
+
+	peer.Capability().SetEvpn(true)
+	evi := peer.EvpnEthernetSegments().Add().Evis().Add().EviVxlan()
+	evi.SetReplicationType(gosnappi.BgpV4EviVxlanReplicationType.INGRESS_REPLICATION)
+	evi.RouteDistinguisher().SetRdType(otgKinds[rdKind]).SetRdValue(v.RD)
+	evi.RouteTargetExport().Add().SetRtType(otgRTKinds[rtKind]).SetRtValue(in.RT)
+	evi.RouteTargetImport().Add().SetRtType(otgRTKinds[rtKind]).SetRtValue(in.RT)
+	hosts := evi.BroadcastDomains().Add().CmacIpRange().Add().
+		SetName(name).
+		SetL2Vni(int32(in.VNI))
+	hosts.MacAddresses().SetAddress(v.MAC).SetCount(int32(v.Hosts))
+	hosts.Ipv4Addresses().SetAddress(v.IPv4).SetCount(int32(v.Hosts))
+	return nil
+}
+
+// Frame is an Ethernet frame of an IPv4 packet between two hosts.
+type Frame struct {
+	SrcMAC, DstMAC string
+	SrcIP, DstIP   string
+}
+
+// addFrame adds the headers of the frame to the flow.
+func addFrame(flow gosnappi.Flow, f Frame) {
+	eth := flow.Packet().Add().Ethernet()
+	eth.Src().SetValue(f.SrcMAC)
+	eth.Dst().SetValue(f.DstMAC)
+	ip := flow.Packet().Add().Ipv4()
+	ip.Src().SetValue(f.SrcIP)
+	ip.Dst().SetValue(f.DstIP)
+}
+
+// AddFlow adds to the OTG configuration a flow of the frame, sent from the
+// tx port to the rx port, at pps packets per second for the number of
+// packets.
+func AddFlow(config gosnappi.Config, name, tx, rx string, f Frame, pps int64, packets int32) {
+	flow := config.Flows().Add().SetName(name)
+	flow.Metrics().SetEnable(true)
+	flow.TxRx().Port().SetTxName(tx).SetRxName(rx)
+	flow.Rate().SetPps(pps)
+	flow.Duration().FixedPackets().SetPackets(packets)
+	addFrame(flow, f)
+}
+
+// Tunnel is a VXLAN tunnel between two VTEPs, of a VNI.
+type Tunnel struct {
+	Src, Dst string
+	VNI      uint32
+}
+
+// AddVXLANFlow adds to the OTG configuration a flow of the frame
+// encapsulated in the tunnel, sent from the tx device of the MAC address
+// of the source of the tunnel to the rx devices, at pps packets per second
+// for the number of packets.
+func AddVXLANFlow(config gosnappi.Config, name, tx string, rx []string, mac string, tn Tunnel, f Frame, pps int64, packets int32) {
+	flow := config.Flows().Add().SetName(name)
+	flow.Metrics().SetEnable(true)
+	flow.TxRx().Device().SetTxNames([]string{tx}).SetRxNames(rx)
+	flow.Rate().SetPps(pps)
+	flow.Duration().FixedPackets().SetPackets(packets)
+	flow.Packet().Add().Ethernet().Src().SetValue(mac)
+	ip := flow.Packet().Add().Ipv4()
+	ip.Src().SetValue(tn.Src)
+	ip.Dst().SetValue(tn.Dst)
+	flow.Packet().Add().Udp().DstPort().SetValue(capture.PortVXLAN)
+	flow.Packet().Add().Vxlan().Vni().SetValue(int32(tn.VNI))
+	addFrame(flow, f)
+}
+
+// matches returns whether the packet is the frame.
+func (f Frame) matches(p *capture.Packet) bool {
+	return p != nil && p.Ethernet != nil && p.IP != nil &&
+		strings.EqualFold(p.Ethernet.Src.String(), f.SrcMAC) &&
+		strings.EqualFold(p.Ethernet.Dst.String(), f.DstMAC) &&
+		p.IP.Src.Equal(net.ParseIP(f.SrcIP)) &&
+		p.IP.Dst.Equal(net.ParseIP(f.DstIP))
+}
+
+// Count returns the number of the captured packets which are the frame,
+// not encapsulated.
+func (f Frame) Count(pkts []*capture.Packet) int {
+	n := 0
+	for _, p := range pkts {
+		if f.matches(p) {
+			n++
+		}
+	}
+	return n
+}
+
+// Verify returns the number of the captured packets which are the frame
+// encapsulated in VXLAN, and the differences of their outer headers with
+// the tunnel.
+func (tn Tunnel) Verify(pkts []*capture.Packet, f Frame) (int, []error) {
+	n := 0
+	var errs []error
+	for i, p := range pkts {
+		if p.VXLAN == nil || !f.matches(p.Inner) {
+			continue
+		}
+		n++
+		if !p.IP.Src.Equal(net.ParseIP(tn.Src)) {
+			errs = append(errs, fmt.Errorf("packet %d: outer source got %v, want %s", i, p.IP.Src, tn.Src))
+		}
+		if !p.IP.Dst.Equal(net.ParseIP(tn.Dst)) {
+			errs = append(errs, fmt.Errorf("packet %d: outer destination got %v, want %s", i, p.IP.Dst, tn.Dst))
+		}
+		if p.VXLAN.VNI != tn.VNI {
+			errs = append(errs, fmt.Errorf("packet %d: VNI got %d, want %d", i, p.VXLAN.VNI, tn.VNI))
+		}
+	}
+	return n, errs
+}
+
+// MACs returns the MAC addresses of the MAC/IP routes of the route
+// distinguisher in the BGP EVPN RIB, in lower case, sorted and without
+// duplicates.
+func MACs(rd *telemetry.NetworkInstance_Protocol_Bgp_Rib_AfiSafi_L2VpnEvpn_LocRib_RouteDistinguisher) []string {
+	seen := map[string]bool{}
+	var macs []string
+	for k := range rd.TypeTwoRoute {
+		mac := strings.ToLower(k.MacAddress)
+		if !seen[mac] {
+			seen[mac] = true
+			macs = append(macs, mac)
+		}
+	}
+	sort.Strings(macs)
+	return macs
+}
+
+// pollInterval is how often the BGP EVPN RIB is read while awaiting the
+// MAC/IP routes.
+var pollInterval = 5 * time.Second
+
+// AwaitMACs waits for the BGP EVPN RIB of the DUT to have MAC/IP routes of
+// the route distinguisher for all the MAC addresses, and returns an error
+// with the ones missing if it does not within the timeout.
+func AwaitMACs(t testing.TB, dut *ondatra.DUTDevice, rd string, want []string, timeout time.Duration) error {
+	t.Helper()
+	path := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, "BGP").Bgp().Rib().
+		AfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_L2VPN_EVPN).L2VpnEvpn().LocRib().RouteDistinguisher(rd)
+	var missing []string
+	for deadline := time.Now().Add(timeout); ; time.Sleep(pollInterval) {
+		got := map[string]bool{}
+		if v := path.Lookup(t); v.IsPresent() {
+			for _, mac := range MACs(v.Val(t)) {
+				got[mac] = true
+			}
+		}
+		missing = nil
+		for _, mac := range want {
+			if !got[strings.ToLower(mac)] {
+				missing = append(missing, mac)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("no MAC/IP routes of %s for %v after %v", rd, missing, timeout)
+		}
+	}
+}
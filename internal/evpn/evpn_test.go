@@ -0,0 +1,254 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package evpn
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/capture"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		in      Instance
+		wantErr bool
+	}{{
+		desc: "ipv4 rd",
+		in:   Instance{Name: "a", VNI: 10100, RD: "203.0.113.1:100", RT: "64496:100"},
+	}, {
+		desc: "as2 rd",
+		in:   Instance{Name: "a", VNI: 1, RD: "64496:4294967295", RT: "64496:100"},
+	}, {
+		desc: "as4 rd",
+		in:   Instance{Name: "a", VNI: MaxVNI, RD: "4200000000:100", RT: "4200000000:65535"},
+	}, {
+		desc:    "zero vni",
+		in:      Instance{Name: "a", RD: "203.0.113.1:100", RT: "64496:100"},
+		wantErr: true,
+	}, {
+		desc:    "large vni",
+		in:      Instance{Name: "a", VNI: MaxVNI + 1, RD: "203.0.113.1:100", RT: "64496:100"},
+		wantErr: true,
+	}, {
+		desc:    "no colon",
+		in:      Instance{Name: "a", VNI: 1, RD: "203.0.113.1", RT: "64496:100"},
+		wantErr: true,
+	}, {
+		desc:    "bad administrator",
+		in:      Instance{Name: "a", VNI: 1, RD: "203.0.113.1:100", RT: "foo:100"},
+		wantErr: true,
+	}, {
+		desc:    "ipv6 administrator",
+		in:      Instance{Name: "a", VNI: 1, RD: "2001:db8::1:100", RT: "64496:100"},
+		wantErr: true,
+	}, {
+		desc:    "large ipv4 number",
+		in:      Instance{Name: "a", VNI: 1, RD: "203.0.113.1:65536", RT: "64496:100"},
+		wantErr: true,
+	}, {
+		desc:    "large as4 number",
+		in:      Instance{Name: "a", VNI: 1, RD: "203.0.113.1:100", RT: "4200000000:65536"},
+		wantErr: true,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := tc.in.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	for v, want := range map[string]kind{
+		"64496:100":       kindAS2,
+		"192.0.2.6:100":   kindIPv4,
+		"4200000000:100":  kindAS4,
+		"65535:100000000": kindAS2,
+	} {
+		got, err := parse(v)
+		if err != nil {
+			t.Errorf("parse(%q) got error %v", v, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("parse(%q) got %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestMACVRF(t *testing.T) {
+	in := &Instance{Name: "MAC-VRF-100", VNI: 10100, RD: "203.0.113.1:100", RT: "64496:100"}
+	ni := in.MACVRF("Loopback0", "eth1")
+
+	if got, want := ni.GetType(), telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L2VSI; got != want {
+		t.Errorf("type got %v, want %v", got, want)
+	}
+	if i := ni.GetInterface("eth1"); i.GetInterface() != "eth1" || i.GetSubinterface() != 0 {
+		t.Errorf("interface got %v, want eth1.0", i)
+	}
+	evi := ni.GetEvpn().GetEvpnInstance("10100")
+	if evi == nil {
+		t.Fatalf("EVPN instance 10100 missing")
+	}
+	if got, want := evi.GetEncapsulationType(), telemetry.NetworkInstanceTypes_ENCAPSULATION_VXLAN; got != want {
+		t.Errorf("encapsulation got %v, want %v", got, want)
+	}
+	if got, want := evi.RouteDistinguisher, telemetry.UnionString(in.RD); got != want {
+		t.Errorf("route distinguisher got %v, want %v", got, want)
+	}
+	policy := evi.GetImportExportPolicy()
+	if got := policy.ImportRouteTarget; len(got) != 1 || got[0] != telemetry.UnionString(in.RT) {
+		t.Errorf("import route targets got %v, want [%s]", got, in.RT)
+	}
+	if got := policy.ExportRouteTarget; len(got) != 1 || got[0] != telemetry.UnionString(in.RT) {
+		t.Errorf("export route targets got %v, want [%s]", got, in.RT)
+	}
+	if got := evi.GetVxlan().GetVni(); got != in.VNI {
+		t.Errorf("VNI got %d, want %d", got, in.VNI)
+	}
+	vxlan := ni.GetConnectionPoint("vxlan").GetEndpoint("vxlan").GetVxlan()
+	if got := vxlan.GetSourceInterface(); got != "Loopback0" {
+		t.Errorf("source interface got %q, want Loopback0", got)
+	}
+	if !vxlan.GetEnabled() {
+		t.Errorf("VXLAN endpoint not enabled")
+	}
+}
+
+func TestEnableBGP(t *testing.T) {
+	bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+	EnableBGP(bgp, "192.0.2.6", "192.0.2.10")
+	if !bgp.GetGlobal().GetAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_L2VPN_EVPN).GetEnabled() {
+		t.Errorf("global L2VPN EVPN not enabled")
+	}
+	for _, addr := range []string{"192.0.2.6", "192.0.2.10"} {
+		if !bgp.GetNeighbor(addr).GetAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_L2VPN_EVPN).GetEnabled() {
+			t.Errorf("L2VPN EVPN not enabled on neighbor %s", addr)
+		}
+	}
+}
+
+func TestHostMACs(t *testing.T) {
+	v := &VTEP{RD: "192.0.2.6:100", MAC: "02:00:0A:00:00:FF", Hosts: 3}
+	got, err := v.HostMACs()
+	if err != nil {
+		t.Fatalf("HostMACs() got error %v", err)
+	}
+	want := []string{"02:00:0a:00:00:ff", "02:00:0a:00:01:00", "02:00:0a:00:01:01"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("HostMACs() diff (-want +got):\n%s", diff)
+	}
+
+	for _, v := range []*VTEP{
+		{MAC: "02:00:0a:00:00:01"},
+		{MAC: "foo", Hosts: 1},
+		{MAC: "ff:ff:ff:ff:ff:ff", Hosts: 2},
+	} {
+		if _, err := v.HostMACs(); err == nil {
+			t.Errorf("HostMACs() of %+v got no error", v)
+		}
+	}
+}
+
+func TestMACs(t *testing.T) {
+	rd := &telemetry.NetworkInstance_Protocol_Bgp_Rib_AfiSafi_L2VpnEvpn_LocRib_RouteDistinguisher{}
+	for _, r := range []struct {
+		mac, ip string
+	}{
+		{"02:00:0A:00:00:02", "198.51.100.102"},
+		{"02:00:0a:00:00:01", "198.51.100.101"},
+		{"02:00:0a:00:00:01", ""},
+	} {
+		if _, err := rd.NewTypeTwoRoute(0, r.mac, 48, r.ip, 32); err != nil {
+			t.Fatalf("NewTypeTwoRoute(%s, %s) got error %v", r.mac, r.ip, err)
+		}
+	}
+	want := []string{"02:00:0a:00:00:01", "02:00:0a:00:00:02"}
+	if diff := cmp.Diff(want, MACs(rd)); diff != "" {
+		t.Errorf("MACs() diff (-want +got):\n%s", diff)
+	}
+}
+
+var frame = Frame{
+	SrcMAC: "02:00:01:01:01:01",
+	DstMAC: "02:00:0a:00:00:01",
+	SrcIP:  "198.51.100.1",
+	DstIP:  "198.51.100.101",
+}
+
+// packet returns the captured packet of the frame.
+func packet(f Frame) *capture.Packet {
+	src, _ := net.ParseMAC(f.SrcMAC)
+	dst, _ := net.ParseMAC(f.DstMAC)
+	return &capture.Packet{
+		Time:     time.Unix(0, 0),
+		Ethernet: &capture.Ethernet{Src: src, Dst: dst, EtherType: 0x0800},
+		IP:       &capture.IP{Version: 4, Src: net.ParseIP(f.SrcIP), Dst: net.ParseIP(f.DstIP)},
+	}
+}
+
+// encap returns the captured packet of the frame encapsulated in VXLAN.
+func encap(src, dst string, vni uint32, f Frame) *capture.Packet {
+	return &capture.Packet{
+		IP:    &capture.IP{Version: 4, Src: net.ParseIP(src), Dst: net.ParseIP(dst), Protocol: 17},
+		UDP:   &capture.UDP{SrcPort: 49152, DstPort: capture.PortVXLAN},
+		VXLAN: &capture.VXLAN{VNI: vni},
+		Inner: packet(f),
+	}
+}
+
+func TestCount(t *testing.T) {
+	other := frame
+	other.DstIP = "198.51.100.102"
+	pkts := []*capture.Packet{
+		packet(frame),
+		packet(other),
+		encap("203.0.113.1", "192.0.2.6", 10100, frame),
+		packet(frame),
+		{},
+	}
+	if got, want := frame.Count(pkts), 2; got != want {
+		t.Errorf("Count() got %d, want %d", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tn := Tunnel{Src: "203.0.113.1", Dst: "192.0.2.6", VNI: 10100}
+	other := frame
+	other.SrcMAC = "02:00:01:01:01:02"
+	pkts := []*capture.Packet{
+		encap("203.0.113.1", "192.0.2.6", 10100, frame),
+		packet(frame),
+		encap("203.0.113.1", "192.0.2.6", 10100, other),
+		encap("203.0.113.2", "192.0.2.6", 10101, frame),
+		encap("203.0.113.1", "192.0.2.10", 10100, frame),
+	}
+	n, errs := tn.Verify(pkts, frame)
+	if n != 3 {
+		t.Errorf("Verify() got %d packets, want 3", n)
+	}
+	if len(errs) != 3 {
+		t.Errorf("Verify() got errors %v, want 3", errs)
+	}
+	if n, errs := tn.Verify(pkts[:2], frame); n != 1 || len(errs) != 0 {
+		t.Errorf("Verify() of a valid packet got %d packets and errors %v, want 1 and none", n, errs)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Degradation degrades the traffic of a link without bringing it down:
+// every packet is delayed by the latency, give or take the jitter, and
+// the loss percentage of the packets is dropped.
+type Degradation struct {
+	Latency time.Duration
+	Jitter  time.Duration
+	LossPct float64
+}
+
+func (d Degradation) String() string {
+	return fmt.Sprintf("latency %v ± %v, loss %g%%", d.Latency, d.Jitter, d.LossPct)
+}
+
+// Validate checks that the degradation is consistent.
+func (d Degradation) Validate() error {
+	switch {
+	case d.Latency < 0 || d.Jitter < 0:
+		return fmt.Errorf("invalid degradation %v: negative latency or jitter", d)
+	case d.Jitter > d.Latency:
+		return fmt.Errorf("invalid degradation %v: jitter exceeds the latency", d)
+	case d.LossPct < 0 || d.LossPct > 100:
+		return fmt.Errorf("invalid degradation %v: loss out of [0, 100]", d)
+	}
+	return nil
+}
+
+// Degrader degrades the traffic of links.  The ATE and OTG APIs do not
+// degrade traffic, so degraders are provided by the bindings of the
+// testbeds that support it, such as emulated links with Netem.
+type Degrader interface {
+	// Degrade degrades the traffic of the link in both directions,
+	// replacing any previous degradation.
+	Degrade(ctx context.Context, l Link, d Degradation) error
+	// Restore removes the degradation of the link.
+	Restore(ctx context.Context, l Link) error
+}
+
+var degrader Degrader
+
+// RegisterDegrader registers the degrader of the testbed, typically by
+// the binding.
+func RegisterDegrader(d Degrader) {
+	mu.Lock()
+	defer mu.Unlock()
+	degrader = d
+}
+
+// RequireDegrader returns the registered degrader, and skips the test if
+// the testbed cannot degrade links.
+func RequireDegrader(t testing.TB) Degrader {
+	t.Helper()
+	mu.Lock()
+	defer mu.Unlock()
+	if degrader == nil {
+		t.Skip("The testbed cannot degrade links: no degrader registered by the binding")
+	}
+	return degrader
+}
+
+// Degrade degrades the link with the degrader until Restore is called,
+// or at the latest until the test ends.
+func Degrade(t testing.TB, dg Degrader, l Link, d Degradation) {
+	t.Helper()
+	if err := d.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Degrading %v: %v", l, d)
+	if err := dg.Degrade(context.Background(), l, d); err != nil {
+		t.Fatalf("Cannot degrade %v: %v", l, err)
+	}
+	t.Cleanup(func() {
+		if err := dg.Restore(context.Background(), l); err != nil {
+			t.Errorf("Cannot restore %v: %v", l, err)
+		}
+	})
+}
+
+// Restore removes the degradation of the link.
+func Restore(t testing.TB, dg Degrader, l Link) {
+	t.Helper()
+	t.Logf("Restoring %v", l)
+	if err := dg.Restore(context.Background(), l); err != nil {
+		t.Fatalf("Cannot restore %v: %v", l, err)
+	}
+}
+
+// Netem degrades emulated links with the netem queueing discipline of
+// Linux, by running tc commands on the host interfaces of the links,
+// e.g. on both ends of the veth pairs of a KNE topology.
+type Netem struct {
+	// Interfaces returns the host interfaces of the link to degrade.
+	Interfaces func(l Link) ([]string, error)
+	// Run runs the command of the arguments, e.g. in the pod of the
+	// interface.
+	Run func(ctx context.Context, intf string, args ...string) error
+}
+
+// Degrade replaces the root queueing discipline of the interfaces of the
+// link with netem.
+func (n *Netem) Degrade(ctx context.Context, l Link, d Degradation) error {
+	intfs, err := n.Interfaces(l)
+	if err != nil {
+		return err
+	}
+	for _, intf := range intfs {
+		if err := n.Run(ctx, intf, netemArgs(intf, d)...); err != nil {
+			return fmt.Errorf("cannot degrade interface %s: %w", intf, err)
+		}
+	}
+	return nil
+}
+
+// Restore deletes the root queueing discipline of the interfaces of the
+// link, which restores the default one.
+func (n *Netem) Restore(ctx context.Context, l Link) error {
+	intfs, err := n.Interfaces(l)
+	if err != nil {
+		return err
+	}
+	for _, intf := range intfs {
+		if err := n.Run(ctx, intf, "tc", "qdisc", "del", "dev", intf, "root"); err != nil {
+			return fmt.Errorf("cannot restore interface %s: %w", intf, err)
+		}
+	}
+	return nil
+}
+
+// netemArgs returns the tc command degrading the interface.
+func netemArgs(intf string, d Degradation) []string {
+	args := []string{"tc", "qdisc", "replace", "dev", intf, "root", "netem"}
+	if d.Latency > 0 {
+		args = append(args, "delay", fmt.Sprintf("%dus", d.Latency.Microseconds()))
+		if d.Jitter > 0 {
+			args = append(args, fmt.Sprintf("%dus", d.Jitter.Microseconds()))
+		}
+	}
+	if d.LossPct > 0 {
+		args = append(args, "loss", fmt.Sprintf("%g%%", d.LossPct))
+	}
+	return args
+}
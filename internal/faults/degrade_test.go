@@ -0,0 +1,90 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDegradationValidate(t *testing.T) {
+	for _, c := range []struct {
+		d       Degradation
+		wantErr bool
+	}{
+		{Degradation{}, false},
+		{Degradation{Latency: 50 * time.Millisecond, Jitter: 10 * time.Millisecond, LossPct: 1}, false},
+		{Degradation{LossPct: 100}, false},
+		{Degradation{Latency: -time.Millisecond}, true},
+		{Degradation{Latency: time.Millisecond, Jitter: 2 * time.Millisecond}, true},
+		{Degradation{LossPct: 101}, true},
+	} {
+		if err := c.d.Validate(); (err != nil) != c.wantErr {
+			t.Errorf("Validate(%v) got error %v, want error %v", c.d, err, c.wantErr)
+		}
+	}
+}
+
+func TestNetemArgs(t *testing.T) {
+	for _, c := range []struct {
+		d    Degradation
+		want string
+	}{
+		{Degradation{}, "tc qdisc replace dev eth1 root netem"},
+		{Degradation{Latency: 20 * time.Millisecond}, "tc qdisc replace dev eth1 root netem delay 20000us"},
+		{Degradation{Latency: 20 * time.Millisecond, Jitter: 500 * time.Microsecond, LossPct: 0.5}, "tc qdisc replace dev eth1 root netem delay 20000us 500us loss 0.5%"},
+		{Degradation{LossPct: 100}, "tc qdisc replace dev eth1 root netem loss 100%"},
+	} {
+		if got := strings.Join(netemArgs("eth1", c.d), " "); got != c.want {
+			t.Errorf("netemArgs(%v) got %q, want %q", c.d, got, c.want)
+		}
+	}
+}
+
+func TestNetem(t *testing.T) {
+	var cmds []string
+	n := &Netem{
+		Interfaces: func(Link) ([]string, error) { return []string{"eth1", "eth2"}, nil },
+		Run: func(_ context.Context, intf string, args ...string) error {
+			cmds = append(cmds, intf+": "+strings.Join(args, " "))
+			return nil
+		},
+	}
+	if err := n.Degrade(context.Background(), Link{}, Degradation{LossPct: 1}); err != nil {
+		t.Fatalf("Degrade() got error: %v", err)
+	}
+	if err := n.Restore(context.Background(), Link{}); err != nil {
+		t.Fatalf("Restore() got error: %v", err)
+	}
+	want := []string{
+		"eth1: tc qdisc replace dev eth1 root netem loss 1%",
+		"eth2: tc qdisc replace dev eth2 root netem loss 1%",
+		"eth1: tc qdisc del dev eth1 root",
+		"eth2: tc qdisc del dev eth2 root",
+	}
+	if diff := cmp.Diff(want, cmds); diff != "" {
+		t.Errorf("Netem commands -want, +got:\n%s", diff)
+	}
+
+	n.Run = func(context.Context, string, ...string) error { return errors.New("no such device") }
+	if err := n.Degrade(context.Background(), Link{}, Degradation{}); err == nil {
+		t.Errorf("Degrade() with a failing command got no error, want error")
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package faults injects link faults behind a uniform interface, so that
+// failover tests can run with the kind of fault chosen at runtime:
+//
+//   - ATELaser turns off the laser of the ATE port, through the ATE.
+//   - OTGLink brings down the link of the ATE port, through the OTG API.
+//   - AdminDown disables the DUT port, through its OpenConfig
+//     admin-state.
+//   - Impairment brings down the link through an impairment device
+//     between the DUT and the ATE, provided by the binding with
+//     RegisterImpairer.
+//
+// Usage:
+//
+//	for _, kind := range faults.Selected(faults.ATELaser, faults.AdminDown) {
+//	  inj, err := faults.New(kind, &faults.Devices{DUT: dut, ATE: ate})
+//	  ...
+//	  l := faults.LinkOf(t, dut, ate, "port2")
+//	  faults.Down(t, inj, dut, l, time.Minute)
+//	  ...
+//	  faults.Up(t, inj, dut, l, time.Minute)
+//	}
+//
+// The tests inject their default kinds of faults unless the fault_kind
+// argument is set, e.g. --fault_kind=impairment.
+//
+// Links can also be degraded rather than brought down, with latency,
+// jitter and loss, by the Degrader the binding registers where the
+// testbed supports it, e.g. Netem for emulated links.
+package faults
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// KindArg overrides the kind of fault of the tests.
+var KindArg = flag.String("fault_kind", "",
+	"Kind of link fault injected by the failover tests: ate-laser, otg-link, admin-down or impairment; empty uses the default of each test.")
+
+// Kind is a kind of link fault.
+type Kind string
+
+// Kinds of link faults.
+const (
+	ATELaser   Kind = "ate-laser"
+	OTGLink    Kind = "otg-link"
+	AdminDown  Kind = "admin-down"
+	Impairment Kind = "impairment"
+)
+
+// Link is a link between a DUT port and an ATE port.
+type Link struct {
+	DUT *ondatra.Port
+	ATE *ondatra.Port
+}
+
+func (l Link) String() string {
+	return fmt.Sprintf("%s <-> %s", l.DUT, l.ATE)
+}
+
+// LinkOf returns the link between the DUT and ATE ports of the same ID.
+func LinkOf(t testing.TB, dut *ondatra.DUTDevice, ate *ondatra.ATEDevice, portID string) Link {
+	t.Helper()
+	return Link{DUT: dut.Port(t, portID), ATE: ate.Port(t, portID)}
+}
+
+// Injector brings links down and up.
+type Injector interface {
+	// Kind returns the kind of fault injected.
+	Kind() Kind
+	// TriggerLinkDown brings the link down.  It returns once the fault
+	// is injected, not once the DUT detected it.
+	TriggerLinkDown(t testing.TB, l Link)
+	// TriggerLinkUp removes the fault of TriggerLinkDown.
+	TriggerLinkUp(t testing.TB, l Link)
+}
+
+// Impairer brings links down and up from outside of the DUT and the ATE,
+// e.g. through an optical switch or an impairment device.
+type Impairer interface {
+	SetLink(ctx context.Context, l Link, up bool) error
+}
+
+var (
+	mu       sync.Mutex
+	impairer Impairer
+)
+
+// RegisterImpairer registers the impairer of the testbed, typically by
+// the binding, for the Impairment faults.
+func RegisterImpairer(i Impairer) {
+	mu.Lock()
+	defer mu.Unlock()
+	impairer = i
+}
+
+// registeredImpairer returns the registered impairer, if any.
+func registeredImpairer() Impairer {
+	mu.Lock()
+	defer mu.Unlock()
+	return impairer
+}
+
+// Devices are the devices the injectors act on.  Each kind of fault only
+// needs some of them: ATELaser needs the ATE, OTGLink the OTG API and
+// AdminDown the DUT.
+type Devices struct {
+	DUT *ondatra.DUTDevice
+	ATE *ondatra.ATEDevice
+	OTG gosnappi.GosnappiApi
+}
+
+// New returns the injector of the kind of fault.
+func New(kind Kind, d *Devices) (Injector, error) {
+	switch kind {
+	case ATELaser:
+		if d.ATE == nil {
+			return nil, fmt.Errorf("%s faults need an ATE", kind)
+		}
+		return &ateLaser{ate: d.ATE}, nil
+	case OTGLink:
+		if d.OTG == nil {
+			return nil, fmt.Errorf("%s faults need an OTG API", kind)
+		}
+		return &otgLink{api: d.OTG}, nil
+	case AdminDown:
+		if d.DUT == nil {
+			return nil, fmt.Errorf("%s faults need a DUT", kind)
+		}
+		return &adminDown{dut: d.DUT}, nil
+	case Impairment:
+		i := registeredImpairer()
+		if i == nil {
+			return nil, fmt.Errorf("%s faults need an impairer registered by the binding", kind)
+		}
+		return &impairment{impairer: i}, nil
+	}
+	return nil, fmt.Errorf("unknown kind of fault %q, want one of %v", kind, Kinds())
+}
+
+// Selected returns the kinds of faults a test injects: the one given by
+// the fault_kind argument if set, or its defaults.
+func Selected(defs ...Kind) []Kind {
+	if *KindArg != "" {
+		return []Kind{Kind(*KindArg)}
+	}
+	return defs
+}
+
+// Kinds returns the known kinds of faults.
+func Kinds() []Kind {
+	return []Kind{ATELaser, OTGLink, AdminDown, Impairment}
+}
+
+// Down brings the link down with the injector and awaits the DUT port to
+// be operationally down.
+func Down(t testing.TB, inj Injector, dut *ondatra.DUTDevice, l Link, timeout time.Duration) {
+	t.Helper()
+	t.Logf("Bringing down %v by %s", l, inj.Kind())
+	inj.TriggerLinkDown(t, l)
+	dut.Telemetry().Interface(l.DUT.Name()).OperStatus().Await(t, timeout, telemetry.Interface_OperStatus_DOWN)
+}
+
+// Up brings the link up with the injector and awaits the DUT port to be
+// operationally up.
+func Up(t testing.TB, inj Injector, dut *ondatra.DUTDevice, l Link, timeout time.Duration) {
+	t.Helper()
+	t.Logf("Bringing up %v by %s", l, inj.Kind())
+	inj.TriggerLinkUp(t, l)
+	dut.Telemetry().Interface(l.DUT.Name()).OperStatus().Await(t, timeout, telemetry.Interface_OperStatus_UP)
+}
+
+// ateLaser turns the laser of the ATE port off and on.
+type ateLaser struct {
+	ate *ondatra.ATEDevice
+}
+
+func (a *ateLaser) Kind() Kind { return ATELaser }
+
+func (a *ateLaser) setPort(t testing.TB, l Link, enabled bool) {
+	t.Helper()
+	// The ATE actions are only sent with a *testing.T.
+	tt, ok := t.(*testing.T)
+	if !ok {
+		t.Fatalf("Cannot set the state of the ATE port of %v with a %T", l, t)
+	}
+	a.ate.Actions().NewSetPortState().WithPort(l.ATE).WithEnabled(enabled).Send(tt)
+}
+
+func (a *ateLaser) TriggerLinkDown(t testing.TB, l Link) {
+	t.Helper()
+	a.setPort(t, l, false)
+}
+
+func (a *ateLaser) TriggerLinkUp(t testing.TB, l Link) {
+	t.Helper()
+	a.setPort(t, l, true)
+}
+
+// otgLink sets the link state of the ATE port through the OTG API, where
+// the OTG ports are named after the IDs of the ATE ports.
+type otgLink struct {
+	api gosnappi.GosnappiApi
+}
+
+func (o *otgLink) Kind() Kind { return OTGLink }
+
+func (o *otgLink) setLink(t testing.TB, l Link, state gosnappi.LinkStateStateEnum) {
+	t.Helper()
+	ls := o.api.NewLinkState().SetPortNames([]string{l.ATE.ID()}).SetState(state)
+	warns, err := o.api.SetLinkState(ls)
+	if err != nil {
+		t.Fatalf("Cannot set the OTG link state of %v to %v: %v", l, state, err)
+	}
+	if w := warns.Warnings(); len(w) > 0 {
+		t.Logf("Setting the OTG link state of %v to %v warned: %v", l, state, w)
+	}
+}
+
+func (o *otgLink) TriggerLinkDown(t testing.TB, l Link) {
+	t.Helper()
+	o.setLink(t, l, gosnappi.LinkStateState.DOWN)
+}
+
+func (o *otgLink) TriggerLinkUp(t testing.TB, l Link) {
+	t.Helper()
+	o.setLink(t, l, gosnappi.LinkStateState.UP)
+}
+
+// adminDown disables and enables the DUT port.
+type adminDown struct {
+	dut *ondatra.DUTDevice
+}
+
+func (a *adminDown) Kind() Kind { return AdminDown }
+
+func (a *adminDown) TriggerLinkDown(t testing.TB, l Link) {
+	t.Helper()
+	a.dut.Config().Interface(l.DUT.Name()).Enabled().Replace(t, false)
+}
+
+func (a *adminDown) TriggerLinkUp(t testing.TB, l Link) {
+	t.Helper()
+	a.dut.Config().Interface(l.DUT.Name()).Enabled().Replace(t, true)
+}
+
+// impairment brings the link down and up through the impairer.
+type impairment struct {
+	impairer Impairer
+}
+
+func (i *impairment) Kind() Kind { return Impairment }
+
+func (i *impairment) TriggerLinkDown(t testing.TB, l Link) {
+	t.Helper()
+	if err := i.impairer.SetLink(context.Background(), l, false); err != nil {
+		t.Fatalf("Cannot impair %v: %v", l, err)
+	}
+}
+
+func (i *impairment) TriggerLinkUp(t testing.TB, l Link) {
+	t.Helper()
+	if err := i.impairer.SetLink(context.Background(), l, true); err != nil {
+		t.Fatalf("Cannot restore %v: %v", l, err)
+	}
+}
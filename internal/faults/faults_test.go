@@ -0,0 +1,88 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ondatra"
+)
+
+// fakeImpairer records the link states it is set to.
+type fakeImpairer struct {
+	states []bool
+}
+
+func (f *fakeImpairer) SetLink(_ context.Context, _ Link, up bool) error {
+	f.states = append(f.states, up)
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	defer RegisterImpairer(nil)
+	all := &Devices{DUT: &ondatra.DUTDevice{}, ATE: &ondatra.ATEDevice{}}
+	for _, c := range []struct {
+		kind    Kind
+		devices *Devices
+		wantErr bool
+	}{
+		{ATELaser, all, false},
+		{ATELaser, &Devices{DUT: all.DUT}, true},
+		{AdminDown, all, false},
+		{AdminDown, &Devices{ATE: all.ATE}, true},
+		{OTGLink, all, true},
+		{Impairment, all, true},
+		{"laser", all, true},
+	} {
+		inj, err := New(c.kind, c.devices)
+		if (err != nil) != c.wantErr {
+			t.Errorf("New(%q) got error %v, want error %v", c.kind, err, c.wantErr)
+			continue
+		}
+		if err == nil && inj.Kind() != c.kind {
+			t.Errorf("New(%q) got injector of kind %q", c.kind, inj.Kind())
+		}
+	}
+}
+
+func TestImpairment(t *testing.T) {
+	defer RegisterImpairer(nil)
+	f := &fakeImpairer{}
+	RegisterImpairer(f)
+	inj, err := New(Impairment, &Devices{})
+	if err != nil {
+		t.Fatalf("New(%q) got error: %v", Impairment, err)
+	}
+	inj.TriggerLinkDown(t, Link{})
+	inj.TriggerLinkUp(t, Link{})
+	if len(f.states) != 2 || f.states[0] || !f.states[1] {
+		t.Errorf("Impairer got link states %v, want [false true]", f.states)
+	}
+}
+
+func TestSelected(t *testing.T) {
+	defer func(kind string) { *KindArg = kind }(*KindArg)
+
+	*KindArg = ""
+	if diff := cmp.Diff([]Kind{ATELaser, AdminDown}, Selected(ATELaser, AdminDown)); diff != "" {
+		t.Errorf("Selected() without argument -want, +got:\n%s", diff)
+	}
+	*KindArg = string(Impairment)
+	if diff := cmp.Diff([]Kind{Impairment}, Selected(ATELaser, AdminDown)); diff != "" {
+		t.Errorf("Selected() with argument -want, +got:\n%s", diff)
+	}
+}
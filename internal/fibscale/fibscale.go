@@ -0,0 +1,327 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fibscale provides a framework for FIB and RIB capacity
+// tests.  Prefixes are injected in increasing batches, through gRIBI or
+// BGP from the ATE, until the DUT fails to install them, reports
+// resource exhaustion, or a configured maximum is reached.  The scale
+// achieved is recorded, and the DUT is then checked to still hold the
+// routes it installed and to be otherwise healthy.
+//
+// Usage:
+//
+//	r := &fibscale.Ramp{
+//	  Injector: &fibscale.GRIBIInjector{Client: c, NetworkInstance: ni, NHGIndex: 1},
+//	  Start:    "198.18.0.0/32",
+//	  Step:     10000,
+//	  Max:      2000000,
+//	  Exhausted: fibscale.UtilizationExceeds(dut, "FPC0:NPU0", "fib", 95),
+//	}
+//	res := r.Run(t)
+//	t.Logf("Achieved scale: %d prefixes (%s)", res.Installed, res.Reason)
+package fibscale
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/gribigo/constants"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+
+	spb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+const timeout = 5 * time.Minute
+
+// Prefixes returns count consecutive prefixes of the same length,
+// starting from the prefix start in CIDR notation.
+func Prefixes(start string, count int) ([]string, error) {
+	ip, ipnet, err := net.ParseCIDR(start)
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := ipnet.Mask.Size()
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if !ip.Equal(ipnet.IP) {
+		return nil, fmt.Errorf("prefix %s has host bits set", start)
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	step := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	n := new(big.Int).SetBytes(ip)
+
+	prefixes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		if n.Cmp(limit) >= 0 {
+			return nil, fmt.Errorf("cannot generate %d prefixes from %s: address space exhausted", count, start)
+		}
+		b := n.FillBytes(make([]byte, len(ip)))
+		prefixes = append(prefixes, fmt.Sprintf("%s/%d", net.IP(b), ones))
+		n.Add(n, step)
+	}
+	return prefixes, nil
+}
+
+// Injector installs prefixes on the DUT.
+type Injector interface {
+	// Inject installs the prefixes on the DUT and returns how many of
+	// them were installed.  Prefixes are always consecutive, as
+	// generated by Prefixes.
+	Inject(t testing.TB, prefixes []string) int
+	// Installed returns the total number of prefixes injected so far
+	// that the DUT currently holds.
+	Installed(t testing.TB) int
+}
+
+// Result is the outcome of a ramp.
+type Result struct {
+	// Attempted is the number of prefixes injected.
+	Attempted int
+	// Installed is the number of prefixes the DUT installed.
+	Installed int
+	// Reason describes why the ramp stopped.
+	Reason string
+}
+
+// Ramp injects prefixes in batches of Step, starting from Start, until
+// the Injector fails to install a whole batch, Exhausted reports
+// resource exhaustion, or Max prefixes were injected.
+type Ramp struct {
+	Injector Injector
+	// Start is the first prefix injected, in CIDR notation.
+	Start string
+	// Step is the number of prefixes injected per batch.
+	Step int
+	// Max is the maximum number of prefixes injected.
+	Max int
+	// Exhausted optionally reports whether the DUT signals resource
+	// exhaustion.  It is checked after every batch.
+	Exhausted func(t testing.TB) bool
+	// Healthy optionally checks that the DUT is otherwise healthy once
+	// the limit is reached, e.g. that the routing processes are up.
+	Healthy func(t testing.TB) error
+}
+
+// Run ramps up the number of prefixes and verifies that the DUT
+// behaves gracefully at the limit: every prefix it acknowledged is
+// still installed and the Healthy check passes.
+func (r *Ramp) Run(t testing.TB) *Result {
+	t.Helper()
+	if r.Step <= 0 || r.Max <= 0 {
+		t.Fatalf("Invalid ramp: step %d, max %d", r.Step, r.Max)
+	}
+	prefixes, err := Prefixes(r.Start, r.Max)
+	if err != nil {
+		t.Fatalf("Cannot generate prefixes: %v", err)
+	}
+
+	res := &Result{Reason: fmt.Sprintf("maximum of %d prefixes reached", r.Max)}
+	for res.Attempted < r.Max {
+		end := res.Attempted + r.Step
+		if end > r.Max {
+			end = r.Max
+		}
+		batch := prefixes[res.Attempted:end]
+		installed := r.Injector.Inject(t, batch)
+		res.Attempted = end
+		res.Installed += installed
+		t.Logf("Injected %d prefixes, %d installed so far", res.Attempted, res.Installed)
+
+		if installed < len(batch) {
+			res.Reason = fmt.Sprintf("%d of %d prefixes in the last batch failed to install", len(batch)-installed, len(batch))
+			break
+		}
+		if r.Exhausted != nil && r.Exhausted(t) {
+			res.Reason = "resource exhaustion reported"
+			break
+		}
+	}
+	t.Logf("Achieved scale: %d of %d prefixes installed, stopped because %s", res.Installed, res.Attempted, res.Reason)
+
+	if got := r.Injector.Installed(t); got < res.Installed {
+		t.Errorf("DUT holds %d prefixes after reaching the limit, want at least %d", got, res.Installed)
+	}
+	if r.Healthy != nil {
+		if err := r.Healthy(t); err != nil {
+			t.Errorf("DUT is not healthy after reaching the limit: %v", err)
+		}
+	}
+	return res
+}
+
+// GRIBIInjector installs IPv4 prefixes through gRIBI, pointing to an
+// existing next hop group.  The client must already be the leader.
+type GRIBIInjector struct {
+	Client             *gribi.Client
+	NetworkInstance    string
+	NHGIndex           uint64
+	NHGNetworkInstance string
+
+	installed map[string]bool
+}
+
+// Inject adds an IPv4Entry for each prefix, and counts the entries
+// acknowledged as programmed, in the FIB if the client requested FIB
+// acknowledgements.
+func (g *GRIBIInjector) Inject(t testing.TB, prefixes []string) int {
+	t.Helper()
+	if g.installed == nil {
+		g.installed = map[string]bool{}
+	}
+	want := map[string]bool{}
+	var entries []fluent.GRIBIEntry
+	for _, p := range prefixes {
+		e := fluent.IPv4Entry().WithPrefix(p).
+			WithNetworkInstance(g.NetworkInstance).
+			WithNextHopGroup(g.NHGIndex)
+		if g.NHGNetworkInstance != "" && g.NHGNetworkInstance != g.NetworkInstance {
+			e.WithNextHopGroupNetworkInstance(g.NHGNetworkInstance)
+		}
+		entries = append(entries, e)
+		want[p] = true
+	}
+	fc := g.Client.Fluent(t)
+	fc.Modify().AddEntry(t, entries...)
+	if err := g.Client.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to add IPv4 entries: %v", err)
+	}
+
+	programmed := spb.AFTResult_RIB_PROGRAMMED
+	if g.Client.FibACK {
+		programmed = spb.AFTResult_FIB_PROGRAMMED
+	}
+	count := 0
+	for _, r := range fc.Results(t) {
+		if r.Details == nil || r.Details.Type != constants.Add || !want[r.Details.IPv4Prefix] {
+			continue
+		}
+		if r.ProgrammingResult == programmed && !g.installed[r.Details.IPv4Prefix] {
+			g.installed[r.Details.IPv4Prefix] = true
+			count++
+		}
+	}
+	return count
+}
+
+// Installed returns the number of prefixes acknowledged so far that the
+// DUT still holds, read back with a gRIBI Get of its IPv4 entries.
+func (g *GRIBIInjector) Installed(t testing.TB) int {
+	t.Helper()
+	resp, err := g.Client.Fluent(t).Get().
+		WithNetworkInstance(g.NetworkInstance).
+		WithAFT(fluent.IPv4).
+		Send()
+	if err != nil {
+		t.Fatalf("Cannot get the IPv4 entries of %s: %v", g.NetworkInstance, err)
+	}
+	return held(resp, g.installed)
+}
+
+// held returns how many of the prefixes are IPv4 entries of the Get
+// response.
+func held(resp *spb.GetResponse, prefixes map[string]bool) int {
+	seen := map[string]bool{}
+	for _, e := range resp.GetEntry() {
+		if p := e.GetIpv4().GetPrefix(); prefixes[p] {
+			seen[p] = true
+		}
+	}
+	return len(seen)
+}
+
+// BGPInjector advertises IPv4 or IPv6 prefixes from an ATE BGP peer.
+// Each batch is added as a new network on the ATE interface.  The
+// number of installed prefixes is read from the DUT, typically from
+// the installed prefix count of the BGP neighbor.
+type BGPInjector struct {
+	Top     *ondatra.ATETopology
+	Intf    *ondatra.Interface
+	NextHop string
+	// InstalledCount returns the number of prefixes the DUT installed
+	// from the ATE peer.
+	InstalledCount func(t testing.TB) uint32
+	// Settle is how long to wait for a batch to be installed.
+	Settle time.Duration
+
+	batches int
+	base    int
+}
+
+// Inject advertises the prefixes from the ATE and waits up to Settle
+// for the DUT to install them.
+func (b *BGPInjector) Inject(t testing.TB, prefixes []string) int {
+	t.Helper()
+	if len(prefixes) == 0 {
+		return 0
+	}
+	if b.batches == 0 {
+		b.base = int(b.InstalledCount(t))
+	}
+	before := b.Installed(t)
+
+	nw := b.Intf.AddNetwork(fmt.Sprintf("fibscale%d", b.batches))
+	b.batches++
+	ip := nw.IPv4()
+	if addr, _, err := net.ParseCIDR(prefixes[0]); err != nil {
+		t.Fatalf("Invalid prefix %s: %v", prefixes[0], err)
+	} else if addr.To4() == nil {
+		ip = nw.IPv6()
+	}
+	ip.WithAddress(prefixes[0]).WithCount(uint32(len(prefixes)))
+	nw.BGP().WithNextHopAddress(b.NextHop)
+	b.Top.UpdateNetworks(t)
+
+	settle := b.Settle
+	if settle == 0 {
+		settle = timeout
+	}
+	want := before + len(prefixes)
+	deadline := time.Now().Add(settle)
+	got := b.Installed(t)
+	for got < want && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Second)
+		got = b.Installed(t)
+	}
+	return got - before
+}
+
+// Installed returns the number of prefixes installed since the first
+// batch was injected.
+func (b *BGPInjector) Installed(t testing.TB) int {
+	return int(b.InstalledCount(t)) - b.base
+}
+
+// UtilizationExceeds returns an exhaustion check reporting whether the
+// used share of an integrated circuit resource, such as a FIB table,
+// exceeds the given percentage of its maximum.  The check reports no
+// exhaustion if the DUT does not publish the utilization.
+func UtilizationExceeds(dut *ondatra.DUTDevice, component, resource string, percent float64) func(t testing.TB) bool {
+	return func(t testing.TB) bool {
+		res := dut.Telemetry().Component(component).IntegratedCircuit().Utilization().Resource(resource)
+		used, limit := res.Used().Lookup(t), res.MaxLimit().Lookup(t)
+		if !used.IsPresent() || !limit.IsPresent() || limit.Val(t) == 0 {
+			return false
+		}
+		util := 100 * float64(used.Val(t)) / float64(limit.Val(t))
+		t.Logf("Utilization of %s on %s: %.1f%%", resource, component, util)
+		return util > percent
+	}
+}
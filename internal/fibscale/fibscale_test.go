@@ -0,0 +1,172 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fibscale
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	aftpb "github.com/openconfig/gribi/v1/proto/gribi_aft"
+	spb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+func TestPrefixes(t *testing.T) {
+	cases := []struct {
+		desc  string
+		start string
+		count int
+		want  []string
+	}{{
+		desc:  "ipv4 host routes",
+		start: "198.18.0.254/32",
+		count: 3,
+		want:  []string{"198.18.0.254/32", "198.18.0.255/32", "198.18.1.0/32"},
+	}, {
+		desc:  "ipv4 /24",
+		start: "10.0.255.0/24",
+		count: 2,
+		want:  []string{"10.0.255.0/24", "10.1.0.0/24"},
+	}, {
+		desc:  "ipv6 /64",
+		start: "2001:db8::/64",
+		count: 2,
+		want:  []string{"2001:db8::/64", "2001:db8:0:1::/64"},
+	}}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := Prefixes(c.start, c.count)
+			if err != nil {
+				t.Fatalf("Prefixes(%q, %d) got error: %v", c.start, c.count, err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Prefixes(%q, %d) got unexpected prefixes, diff(-want,+got):\n%s", c.start, c.count, diff)
+			}
+		})
+	}
+}
+
+func TestPrefixesError(t *testing.T) {
+	for _, start := range []string{"10.0.0.1/24", "255.255.255.255/32", "bogus"} {
+		if _, err := Prefixes(start, 2); err == nil {
+			t.Errorf("Prefixes(%q, 2) got no error, want error", start)
+		}
+	}
+}
+
+// fakeInjector installs prefixes until it reaches its capacity.
+type fakeInjector struct {
+	capacity  int
+	installed int
+	lost      int
+}
+
+func (f *fakeInjector) Inject(t testing.TB, prefixes []string) int {
+	n := len(prefixes)
+	if f.installed+n > f.capacity {
+		n = f.capacity - f.installed
+	}
+	f.installed += n
+	return n
+}
+
+func (f *fakeInjector) Installed(t testing.TB) int {
+	return f.installed - f.lost
+}
+
+func TestRamp(t *testing.T) {
+	cases := []struct {
+		desc      string
+		capacity  int
+		exhausted int
+		want      *Result
+	}{{
+		desc:     "maximum reached",
+		capacity: 1000,
+		want:     &Result{Attempted: 25, Installed: 25, Reason: "maximum of 25 prefixes reached"},
+	}, {
+		desc:     "install failure",
+		capacity: 12,
+		want:     &Result{Attempted: 20, Installed: 12, Reason: "8 of 10 prefixes in the last batch failed to install"},
+	}, {
+		desc:      "resource exhaustion",
+		capacity:  1000,
+		exhausted: 10,
+		want:      &Result{Attempted: 10, Installed: 10, Reason: "resource exhaustion reported"},
+	}}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			f := &fakeInjector{capacity: c.capacity}
+			r := &Ramp{
+				Injector: f,
+				Start:    "198.18.0.0/32",
+				Step:     10,
+				Max:      25,
+				Exhausted: func(testing.TB) bool {
+					return c.exhausted > 0 && f.installed >= c.exhausted
+				},
+				Healthy: func(testing.TB) error { return nil },
+			}
+			got := r.Run(t)
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Run got unexpected result, diff(-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// recorder captures errors reported through testing.TB.
+type recorder struct {
+	testing.TB
+	errors int
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors++
+}
+
+func TestRampNotGraceful(t *testing.T) {
+	f := &fakeInjector{capacity: 10, lost: 1}
+	r := &Ramp{
+		Injector: f,
+		Start:    "198.18.0.0/32",
+		Step:     10,
+		Max:      20,
+		Healthy:  func(testing.TB) error { return errors.New("routing process crashed") },
+	}
+	rec := &recorder{TB: t}
+	r.Run(rec)
+	if rec.errors != 2 {
+		t.Errorf("Run reported %d errors, want 2", rec.errors)
+	}
+}
+
+func TestHeld(t *testing.T) {
+	ipv4 := func(prefix string) *spb.AFTEntry {
+		return &spb.AFTEntry{Entry: &spb.AFTEntry_Ipv4{Ipv4: &aftpb.Afts_Ipv4EntryKey{Prefix: prefix}}}
+	}
+	resp := &spb.GetResponse{Entry: []*spb.AFTEntry{
+		ipv4("198.18.0.0/32"),
+		ipv4("198.18.0.1/32"),
+		ipv4("198.18.0.1/32"),
+		ipv4("203.0.113.0/24"),
+		{Entry: &spb.AFTEntry_NextHopGroup{NextHopGroup: &aftpb.Afts_NextHopGroupKey{Id: 1}}},
+	}}
+	prefixes := map[string]bool{"198.18.0.0/32": true, "198.18.0.1/32": true, "198.18.0.2/32": true}
+	if got := held(resp, prefixes); got != 2 {
+		t.Errorf("held() got %d, want 2", got)
+	}
+}
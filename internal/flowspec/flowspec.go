@@ -0,0 +1,332 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flowspec provides helpers for BGP flowspec tests (RFC 8955):
+// encoding the rules advertised by the ATE, predicting which traffic
+// they match, and verifying that the DUT drops, rate-limits, redirects
+// or marks the matching traffic accordingly.
+//
+// The ATE API negotiates the flowspec capabilities of its BGP peers, but
+// cannot advertise flowspec routes yet.  Rules are therefore encoded
+// here as their NLRI and extended communities, and advertised to the
+// DUT by Speaker, a minimal BGP speaker run by the test itself on a
+// multihop session, while the ATE sends the traffic.
+package flowspec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Component types of the flowspec NLRI.
+const (
+	typeDstPrefix = 1
+	typeSrcPrefix = 2
+	typeProtocol  = 3
+	typeDstPort   = 5
+	typeSrcPort   = 6
+	typeDSCP      = 11
+)
+
+// Bits of the numeric operators.
+const (
+	opEnd = 0x80
+	opAnd = 0x40
+	opLt  = 0x04
+	opGt  = 0x02
+	opEq  = 0x01
+)
+
+// Range is an inclusive range of values matched by a rule.
+type Range struct {
+	Min, Max uint16
+}
+
+// Value returns the range matching the single value.
+func Value(v uint16) Range {
+	return Range{v, v}
+}
+
+// Rule is a flowspec rule.  Empty match fields match any packet, and
+// the values of a field match if any of its ranges matches.
+type Rule struct {
+	// Dst and Src are IPv4 prefixes in CIDR notation.
+	Dst, Src  string
+	Protocols []Range
+	DstPorts  []Range
+	SrcPorts  []Range
+	DSCPs     []Range
+	Action    Action
+}
+
+// ActionType is the action applied to the traffic matching a rule.
+type ActionType int
+
+const (
+	// Accept forwards the traffic as is.
+	Accept ActionType = iota
+	// Drop discards the traffic.
+	Drop
+	// RateLimit limits the traffic to a rate.
+	RateLimit
+	// Redirect forwards the traffic in the VRF of a route target.
+	Redirect
+	// Mark sets the DSCP of the traffic.
+	Mark
+)
+
+// Action is the action of a rule, with its parameters.
+type Action struct {
+	Type ActionType
+	// AS is the AS number of the traffic rate and redirect communities.
+	AS uint16
+	// Rate is the rate limit in bytes per second.
+	Rate float32
+	// Target is the value of the redirect route target AS:Target.
+	Target uint32
+	// DSCP is the DSCP set by the marking.
+	DSCP uint8
+}
+
+// ExtCommunity returns the extended community advertising the action,
+// or nil for Accept, which is advertised without one.
+func (a *Action) ExtCommunity() []byte {
+	b := make([]byte, 8)
+	switch a.Type {
+	case Drop, RateLimit:
+		rate := a.Rate
+		if a.Type == Drop {
+			rate = 0
+		}
+		b[0], b[1] = 0x80, 0x06
+		binary.BigEndian.PutUint16(b[2:], a.AS)
+		binary.BigEndian.PutUint32(b[4:], math.Float32bits(rate))
+	case Redirect:
+		b[0], b[1] = 0x80, 0x08
+		binary.BigEndian.PutUint16(b[2:], a.AS)
+		binary.BigEndian.PutUint32(b[4:], a.Target)
+	case Mark:
+		b[0], b[1] = 0x80, 0x09
+		b[7] = a.DSCP & 0x3f
+	default:
+		return nil
+	}
+	return b
+}
+
+// prefix encodes a prefix component.
+func prefix(typ byte, s string) ([]byte, error) {
+	_, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, err
+	}
+	ip := ipnet.IP.To4()
+	if ip == nil {
+		return nil, fmt.Errorf("prefix %s is not IPv4", s)
+	}
+	ones, _ := ipnet.Mask.Size()
+	return append([]byte{typ, byte(ones)}, ip[:(ones+7)/8]...), nil
+}
+
+// numeric encodes a numeric component matching any of the ranges.
+func numeric(typ byte, ranges []Range) []byte {
+	b := []byte{typ}
+	op := func(bits byte, v uint16) {
+		if v > 0xff {
+			b = append(b, bits|0x10, byte(v>>8), byte(v))
+		} else {
+			b = append(b, bits, byte(v))
+		}
+	}
+	for i, r := range ranges {
+		last := byte(0)
+		if i == len(ranges)-1 {
+			last = opEnd
+		}
+		if r.Min == r.Max {
+			op(last|opEq, r.Min)
+			continue
+		}
+		op(opGt|opEq, r.Min)
+		op(last|opAnd|opLt|opEq, r.Max)
+	}
+	return b
+}
+
+// NLRI returns the flowspec NLRI of the rule, with its length.
+func (r *Rule) NLRI() ([]byte, error) {
+	var b []byte
+	for _, p := range []struct {
+		typ byte
+		s   string
+	}{{typeDstPrefix, r.Dst}, {typeSrcPrefix, r.Src}} {
+		if p.s == "" {
+			continue
+		}
+		c, err := prefix(p.typ, p.s)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, c...)
+	}
+	for _, n := range []struct {
+		typ    byte
+		ranges []Range
+	}{
+		{typeProtocol, r.Protocols},
+		{typeDstPort, r.DstPorts},
+		{typeSrcPort, r.SrcPorts},
+		{typeDSCP, r.DSCPs},
+	} {
+		if len(n.ranges) > 0 {
+			b = append(b, numeric(n.typ, n.ranges)...)
+		}
+	}
+	switch {
+	case len(b) == 0:
+		return nil, fmt.Errorf("rule has no match")
+	case len(b) < 240:
+		return append([]byte{byte(len(b))}, b...), nil
+	case len(b) < 4096:
+		return append([]byte{0xf0 | byte(len(b)>>8), byte(len(b))}, b...), nil
+	}
+	return nil, fmt.Errorf("rule is too long: %d bytes", len(b))
+}
+
+// Packet are the fields of a packet matched by the rules.
+type Packet struct {
+	Src, Dst         net.IP
+	Protocol         uint8
+	SrcPort, DstPort uint16
+	DSCP             uint8
+}
+
+// inRanges returns whether the value is in any of the ranges, or if
+// there are none.
+func inRanges(v uint16, ranges []Range) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if v >= r.Min && v <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// inPrefix returns whether the address is in the prefix, or if there is
+// none.
+func inPrefix(ip net.IP, s string) bool {
+	if s == "" {
+		return true
+	}
+	_, ipnet, err := net.ParseCIDR(s)
+	return err == nil && ipnet.Contains(ip)
+}
+
+// Matches returns whether the rule matches the packet.
+func (r *Rule) Matches(p *Packet) bool {
+	return inPrefix(p.Dst, r.Dst) && inPrefix(p.Src, r.Src) &&
+		inRanges(uint16(p.Protocol), r.Protocols) &&
+		inRanges(p.DstPort, r.DstPorts) &&
+		inRanges(p.SrcPort, r.SrcPorts) &&
+		inRanges(uint16(p.DSCP), r.DSCPs)
+}
+
+// ActionFor returns the action the DUT applies to the packet: that of
+// the first rule matching it, or Accept.  The rules must be ordered by
+// precedence.
+func ActionFor(rules []*Rule, p *Packet) *Action {
+	for _, r := range rules {
+		if r.Matches(p) {
+			return &r.Action
+		}
+	}
+	return &Action{Type: Accept}
+}
+
+// Enable enables the IPv4 flowspec address family of a DUT neighbor.
+func Enable(nbr *telemetry.NetworkInstance_Protocol_Bgp_Neighbor) {
+	nbr.GetOrCreateAfiSafi(telemetry.BgpTypes_AFI_SAFI_TYPE_IPV4_FLOWSPEC).Enabled = ygot.Bool(true)
+}
+
+// EnableATE enables the flowspec capabilities of an ATE peer.
+func EnableATE(peer *ondatra.BGPPeer) {
+	peer.Capabilities().WithIPv4UnicastFlowSpecEnabled(true).WithIPv6UnicastFlowSpecEnabled(true)
+}
+
+// Outcome are the counters of an ATE flow over the time it was sent.
+type Outcome struct {
+	TxPkts, RxPkts     uint64
+	TxOctets, RxOctets uint64
+	Duration           time.Duration
+}
+
+// ReadOutcome returns the counters of the ATE flow, which was sent for
+// the duration.
+func ReadOutcome(t testing.TB, ate *ondatra.ATEDevice, flow string, duration time.Duration) *Outcome {
+	t.Helper()
+	c := ate.Telemetry().Flow(flow).Counters()
+	return &Outcome{
+		TxPkts:   c.OutPkts().Get(t),
+		RxPkts:   c.InPkts().Get(t),
+		TxOctets: c.OutOctets().Get(t),
+		RxOctets: c.InOctets().Get(t),
+		Duration: duration,
+	}
+}
+
+// Check checks that the outcome of a flow matches the action, within a
+// tolerance in percent.  The flow must be received on the redirect
+// target for Redirect.  Marking is only checked to deliver the traffic,
+// since the DSCP is not counted.
+func (a *Action) Check(o *Outcome, tolerancePct float64) error {
+	if o.TxPkts == 0 {
+		return fmt.Errorf("no packets sent")
+	}
+	loss := 100 * float64(int64(o.TxPkts)-int64(o.RxPkts)) / float64(o.TxPkts)
+	switch a.Type {
+	case Drop:
+		if loss < 100-tolerancePct {
+			return fmt.Errorf("received %d of %d packets, want dropped", o.RxPkts, o.TxPkts)
+		}
+	case RateLimit:
+		if o.Duration <= 0 {
+			return fmt.Errorf("no duration to compute the rate")
+		}
+		want := float64(a.Rate)
+		if tx := float64(o.TxOctets) / o.Duration.Seconds(); tx < want {
+			want = tx
+		}
+		got := float64(o.RxOctets) / o.Duration.Seconds()
+		if math.Abs(got-want) > want*tolerancePct/100 {
+			return fmt.Errorf("received %.0f bytes/s, want %.0f bytes/s", got, want)
+		}
+	default:
+		if loss > tolerancePct {
+			return fmt.Errorf("received %d of %d packets, want all", o.RxPkts, o.TxPkts)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowspec
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNLRI(t *testing.T) {
+	var many []Range
+	for i := 0; i < 130; i++ {
+		many = append(many, Value(uint16(i)))
+	}
+
+	for _, c := range []struct {
+		desc    string
+		rule    *Rule
+		want    []byte
+		wantLen int
+		wantErr bool
+	}{{
+		desc: "destination and TCP port 25",
+		rule: &Rule{Dst: "192.0.2.0/24", Protocols: []Range{Value(6)}, DstPorts: []Range{Value(25)}},
+		want: []byte{0x0b, 0x01, 0x18, 0xc0, 0x00, 0x02, 0x03, 0x81, 0x06, 0x05, 0x81, 0x19},
+	}, {
+		desc: "source only",
+		rule: &Rule{Src: "10.0.0.0/8"},
+		want: []byte{0x03, 0x02, 0x08, 0x0a},
+	}, {
+		desc: "port range",
+		rule: &Rule{DstPorts: []Range{{1000, 2000}}},
+		want: []byte{0x07, 0x05, 0x13, 0x03, 0xe8, 0xd5, 0x07, 0xd0},
+	}, {
+		desc: "several values",
+		rule: &Rule{SrcPorts: []Range{Value(53), {100, 200}}, DSCPs: []Range{Value(46)}},
+		want: []byte{0x0a, 0x06, 0x01, 0x35, 0x03, 0x64, 0xc5, 0xc8, 0x0b, 0x81, 0x2e},
+	}, {
+		desc:    "long rule",
+		rule:    &Rule{DstPorts: many},
+		wantLen: 261,
+	}, {
+		desc:    "no match",
+		rule:    &Rule{},
+		wantErr: true,
+	}, {
+		desc:    "IPv6 prefix",
+		rule:    &Rule{Dst: "2001:db8::/32"},
+		wantErr: true,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := c.rule.NLRI()
+			if (err != nil) != c.wantErr {
+				t.Fatalf("NLRI() got error %v, want error %v", err, c.wantErr)
+			}
+			if c.wantLen > 0 {
+				if len(got) != c.wantLen+2 || got[0] != 0xf0|byte(c.wantLen>>8) || got[1] != byte(c.wantLen) {
+					t.Errorf("NLRI() got %d bytes starting with %x, want a 2 bytes length of %d", len(got), got[:2], c.wantLen)
+				}
+				return
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("NLRI() -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExtCommunity(t *testing.T) {
+	for _, c := range []struct {
+		action *Action
+		want   []byte
+	}{
+		{&Action{Type: Accept}, nil},
+		{&Action{Type: Drop, AS: 64500, Rate: 1000}, []byte{0x80, 0x06, 0xfb, 0xf4, 0, 0, 0, 0}},
+		{&Action{Type: RateLimit, AS: 64500, Rate: 1000}, []byte{0x80, 0x06, 0xfb, 0xf4, 0x44, 0x7a, 0, 0}},
+		{&Action{Type: Redirect, AS: 64500, Target: 100}, []byte{0x80, 0x08, 0xfb, 0xf4, 0, 0, 0, 0x64}},
+		{&Action{Type: Mark, DSCP: 46}, []byte{0x80, 0x09, 0, 0, 0, 0, 0, 0x2e}},
+	} {
+		if diff := cmp.Diff(c.want, c.action.ExtCommunity()); diff != "" {
+			t.Errorf("ExtCommunity(%+v) -want, +got:\n%s", c.action, diff)
+		}
+	}
+}
+
+func TestActionFor(t *testing.T) {
+	rules := []*Rule{
+		{Dst: "198.51.100.0/24", Protocols: []Range{Value(17)}, DstPorts: []Range{{5000, 5999}}, Action: Action{Type: Drop}},
+		{Dst: "198.51.100.0/24", DSCPs: []Range{Value(10)}, Action: Action{Type: Mark, DSCP: 0}},
+		{Src: "192.0.2.0/24", Action: Action{Type: RateLimit, Rate: 1e6}},
+	}
+	pkt := func(src, dst string, proto uint8, dport uint16, dscp uint8) *Packet {
+		return &Packet{Src: net.ParseIP(src), Dst: net.ParseIP(dst), Protocol: proto, SrcPort: 1024, DstPort: dport, DSCP: dscp}
+	}
+	for _, c := range []struct {
+		desc string
+		p    *Packet
+		want ActionType
+	}{
+		{"first rule", pkt("203.0.113.1", "198.51.100.1", 17, 5500, 10), Drop},
+		{"port out of range", pkt("203.0.113.1", "198.51.100.1", 17, 6000, 10), Mark},
+		{"other protocol", pkt("192.0.2.1", "198.51.100.1", 6, 5500, 0), RateLimit},
+		{"no match", pkt("203.0.113.1", "203.0.113.2", 6, 80, 0), Accept},
+	} {
+		if got := ActionFor(rules, c.p).Type; got != c.want {
+			t.Errorf("ActionFor(%s) got %v, want %v", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	const tolerance = 5
+	for _, c := range []struct {
+		desc    string
+		action  *Action
+		o       *Outcome
+		wantErr bool
+	}{
+		{"dropped", &Action{Type: Drop}, &Outcome{TxPkts: 1000, RxPkts: 0}, false},
+		{"not dropped", &Action{Type: Drop}, &Outcome{TxPkts: 1000, RxPkts: 500}, true},
+		{"accepted", &Action{Type: Accept}, &Outcome{TxPkts: 1000, RxPkts: 999}, false},
+		{"accepted with loss", &Action{Type: Accept}, &Outcome{TxPkts: 1000, RxPkts: 900}, true},
+		{"redirected", &Action{Type: Redirect}, &Outcome{TxPkts: 1000, RxPkts: 1000}, false},
+		{"not redirected", &Action{Type: Redirect}, &Outcome{TxPkts: 1000, RxPkts: 0}, true},
+		{"nothing sent", &Action{Type: Accept}, &Outcome{}, true},
+		{
+			"rate limited",
+			&Action{Type: RateLimit, Rate: 1000},
+			&Outcome{TxPkts: 1000, RxPkts: 100, TxOctets: 100000, RxOctets: 10200, Duration: 10 * time.Second},
+			false,
+		},
+		{
+			"not rate limited",
+			&Action{Type: RateLimit, Rate: 1000},
+			&Outcome{TxPkts: 1000, RxPkts: 1000, TxOctets: 100000, RxOctets: 100000, Duration: 10 * time.Second},
+			true,
+		},
+		{
+			"below the rate",
+			&Action{Type: RateLimit, Rate: 1e6},
+			&Outcome{TxPkts: 1000, RxPkts: 1000, TxOctets: 100000, RxOctets: 100000, Duration: 10 * time.Second},
+			false,
+		},
+		{
+			"rate limited without duration",
+			&Action{Type: RateLimit, Rate: 1000},
+			&Outcome{TxPkts: 1000, RxPkts: 100, TxOctets: 100000, RxOctets: 10000},
+			true,
+		},
+	} {
+		err := c.action.Check(c.o, tolerance)
+		if (err != nil) != c.wantErr {
+			t.Errorf("Check(%s) got error %v, want error %v", c.desc, err, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowspec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// BGP message types.
+const (
+	msgOpen         = 1
+	msgUpdate       = 2
+	msgNotification = 3
+	msgKeepalive    = 4
+)
+
+// BGP path attributes.
+const (
+	attrOrigin       = 1
+	attrASPath       = 2
+	attrMPReach      = 14
+	attrMPUnreach    = 15
+	attrExtCommunity = 16
+)
+
+const (
+	afiIPv4      = 1
+	safiFlowspec = 133
+	headerLen    = 19
+	maxMsgLen    = 4096
+)
+
+// message returns a BGP message of the type with the body.
+func message(typ byte, body []byte) []byte {
+	b := make([]byte, headerLen, headerLen+len(body))
+	for i := 0; i < 16; i++ {
+		b[i] = 0xff
+	}
+	binary.BigEndian.PutUint16(b[16:], uint16(headerLen+len(body)))
+	b[18] = typ
+	return append(b, body...)
+}
+
+// attribute encodes a path attribute, with an extended length if
+// needed.
+func attribute(flags, typ byte, value []byte) []byte {
+	if len(value) > 0xff {
+		b := []byte{flags | 0x10, typ, 0, 0}
+		binary.BigEndian.PutUint16(b[2:], uint16(len(value)))
+		return append(b, value...)
+	}
+	return append([]byte{flags, typ, byte(len(value))}, value...)
+}
+
+// open returns the OPEN message of a speaker with the AS and router
+// ID, offering the IPv4 flowspec address family.
+func open(as, holdTime uint16, routerID net.IP) ([]byte, error) {
+	id := routerID.To4()
+	if id == nil {
+		return nil, fmt.Errorf("router ID %v is not IPv4", routerID)
+	}
+	// Capability 1 is multiprotocol, for AFI 1 and SAFI 133, in an
+	// optional parameter 2.
+	capability := []byte{2, 6, 1, 4, 0, afiIPv4, 0, safiFlowspec}
+	b := []byte{4, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(b[1:], as)
+	binary.BigEndian.PutUint16(b[3:], holdTime)
+	b = append(b, id...)
+	b = append(b, byte(len(capability)))
+	return message(msgOpen, append(b, capability...)), nil
+}
+
+// Update returns the UPDATE message advertising the rule from the AS,
+// with the extended community of its action.
+func Update(r *Rule, as uint16) ([]byte, error) {
+	nlri, err := r.NLRI()
+	if err != nil {
+		return nil, err
+	}
+	// The flowspec NLRI has no next hop.
+	reach := append([]byte{0, afiIPv4, safiFlowspec, 0, 0}, nlri...)
+	asPath := []byte{2, 1, 0, 0}
+	binary.BigEndian.PutUint16(asPath[2:], as)
+
+	attrs := attribute(0x40, attrOrigin, []byte{0})
+	attrs = append(attrs, attribute(0x40, attrASPath, asPath)...)
+	attrs = append(attrs, attribute(0x80, attrMPReach, reach)...)
+	if c := r.Action.ExtCommunity(); c != nil {
+		attrs = append(attrs, attribute(0xc0, attrExtCommunity, c)...)
+	}
+	return updateMessage(attrs)
+}
+
+// Withdraw returns the UPDATE message withdrawing the rule.
+func Withdraw(r *Rule) ([]byte, error) {
+	nlri, err := r.NLRI()
+	if err != nil {
+		return nil, err
+	}
+	unreach := append([]byte{0, afiIPv4, safiFlowspec}, nlri...)
+	return updateMessage(attribute(0x80, attrMPUnreach, unreach))
+}
+
+// updateMessage returns the UPDATE message with the path attributes,
+// and no withdrawn routes nor IPv4 unicast NLRI.
+func updateMessage(attrs []byte) ([]byte, error) {
+	if headerLen+4+len(attrs) > maxMsgLen {
+		return nil, fmt.Errorf("UPDATE is too long: %d bytes", headerLen+4+len(attrs))
+	}
+	b := []byte{0, 0, 0, 0}
+	binary.BigEndian.PutUint16(b[2:], uint16(len(attrs)))
+	return message(msgUpdate, append(b, attrs...)), nil
+}
+
+// readMessage reads a BGP message and returns its type and body.
+func readMessage(r io.Reader) (byte, []byte, error) {
+	hdr := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint16(hdr[16:]))
+	if n < headerLen || n > maxMsgLen {
+		return 0, nil, fmt.Errorf("bad BGP message length %d", n)
+	}
+	body := make([]byte, n-headerLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return hdr[18], body, nil
+}
+
+// Speaker is a minimal BGP speaker advertising flowspec rules to the
+// DUT, as the ATE cannot.  It ignores the routes the DUT advertises.
+type Speaker struct {
+	// AS is the AS number of the speaker.
+	AS uint16
+	// RouterID is the IPv4 router ID of the speaker.
+	RouterID net.IP
+	// HoldTime is the hold time offered by the speaker.
+	HoldTime time.Duration
+
+	conn net.Conn
+	wmu  sync.Mutex
+	done chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// Dial establishes the session of the speaker with the BGP neighbor at
+// the address, as host:port.
+func (s *Speaker) Dial(addr string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return err
+	}
+	if err := s.Start(conn, timeout); err != nil {
+		conn.Close()
+		return err
+	}
+	return nil
+}
+
+// Start establishes the session of the speaker over the connection,
+// exchanging the OPEN and KEEPALIVE messages, then keeps it up until
+// Close.
+func (s *Speaker) Start(conn net.Conn, timeout time.Duration) error {
+	msg, err := open(s.AS, uint16(s.HoldTime/time.Second), s.RouterID)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+	var holdTime uint16
+	for _, want := range []byte{msgOpen, msgKeepalive} {
+		typ, body, err := readMessage(conn)
+		if err != nil {
+			return err
+		}
+		switch {
+		case typ == msgNotification && len(body) >= 2:
+			return fmt.Errorf("BGP neighbor sent NOTIFICATION %d/%d", body[0], body[1])
+		case typ != want:
+			return fmt.Errorf("BGP neighbor sent message type %d, want %d", typ, want)
+		case typ == msgOpen:
+			if len(body) < 10 {
+				return fmt.Errorf("BGP neighbor sent a short OPEN")
+			}
+			holdTime = binary.BigEndian.Uint16(body[3:])
+			if _, err := conn.Write(message(msgKeepalive, nil)); err != nil {
+				return err
+			}
+		}
+	}
+	conn.SetDeadline(time.Time{})
+
+	// The hold time is the lowest of the speaker and the neighbor.
+	if h := uint16(s.HoldTime / time.Second); h < holdTime {
+		holdTime = h
+	}
+	s.conn = conn
+	s.done = make(chan struct{})
+	go s.read()
+	if holdTime > 0 {
+		go s.keepalive(time.Duration(holdTime) * time.Second / 3)
+	}
+	return nil
+}
+
+// read reads the messages of the neighbor until the session is closed,
+// recording a NOTIFICATION as the error of the session.
+func (s *Speaker) read() {
+	for {
+		typ, body, err := readMessage(s.conn)
+		if err == nil && typ == msgNotification && len(body) >= 2 {
+			err = fmt.Errorf("BGP neighbor sent NOTIFICATION %d/%d", body[0], body[1])
+		}
+		if err != nil {
+			s.setErr(err)
+			return
+		}
+	}
+}
+
+// keepalive sends a KEEPALIVE every interval until the session is
+// closed.
+func (s *Speaker) keepalive(interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-tick.C:
+			if err := s.write(message(msgKeepalive, nil)); err != nil {
+				s.setErr(err)
+				return
+			}
+		}
+	}
+}
+
+func (s *Speaker) write(msg []byte) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	_, err := s.conn.Write(msg)
+	return err
+}
+
+func (s *Speaker) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Err returns the error which ended the session, if any.
+func (s *Speaker) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Advertise advertises the rules to the neighbor.
+func (s *Speaker) Advertise(rules []*Rule) error {
+	for _, r := range rules {
+		msg, err := Update(r, s.AS)
+		if err != nil {
+			return err
+		}
+		if err := s.write(msg); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// Withdraw withdraws the rules from the neighbor.
+func (s *Speaker) Withdraw(rules []*Rule) error {
+	for _, r := range rules {
+		msg, err := Withdraw(r)
+		if err != nil {
+			return err
+		}
+		if err := s.write(msg); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// Close closes the session, which withdraws all the rules.
+func (s *Speaker) Close() error {
+	close(s.done)
+	return s.conn.Close()
+}
@@ -0,0 +1,151 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flowspec
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var marker = bytes.Repeat([]byte{0xff}, 16)
+
+func TestUpdate(t *testing.T) {
+	r := &Rule{Dst: "192.0.2.0/24", Action: Action{Type: Drop, AS: 64510}}
+	got, err := Update(r, 64510)
+	if err != nil {
+		t.Fatalf("Update() got error %v", err)
+	}
+	want := append(append([]byte{}, marker...),
+		0x00, 0x3b, msgUpdate,
+		0x00, 0x00, // No withdrawn routes.
+		0x00, 0x24, // Path attributes length.
+		0x40, attrOrigin, 0x01, 0x00,
+		0x40, attrASPath, 0x04, 0x02, 0x01, 0xfb, 0xfe,
+		0x80, attrMPReach, 0x0b, 0x00, 0x01, 0x85, 0x00, 0x00, 0x05, 0x01, 0x18, 0xc0, 0x00, 0x02,
+		0xc0, attrExtCommunity, 0x08, 0x80, 0x06, 0xfb, 0xfe, 0x00, 0x00, 0x00, 0x00,
+	)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Update() -want, +got:\n%s", diff)
+	}
+}
+
+func TestUpdateAccept(t *testing.T) {
+	got, err := Update(&Rule{Dst: "192.0.2.0/24"}, 64510)
+	if err != nil {
+		t.Fatalf("Update() got error %v", err)
+	}
+	if bytes.Contains(got, []byte{0xc0, attrExtCommunity}) {
+		t.Errorf("Update() got %x, want no extended community for Accept", got)
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	got, err := Withdraw(&Rule{Dst: "192.0.2.0/24"})
+	if err != nil {
+		t.Fatalf("Withdraw() got error %v", err)
+	}
+	want := append(append([]byte{}, marker...),
+		0x00, 0x23, msgUpdate,
+		0x00, 0x00,
+		0x00, 0x0c,
+		0x80, attrMPUnreach, 0x09, 0x00, 0x01, 0x85, 0x05, 0x01, 0x18, 0xc0, 0x00, 0x02,
+	)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Withdraw() -want, +got:\n%s", diff)
+	}
+}
+
+// neighbor plays the DUT end of a session: it sends the OPEN it
+// receives on the channel and replies, then exchanges the KEEPALIVE
+// messages and sends the UPDATEs it receives on the channel.
+func neighbor(t *testing.T, conn net.Conn, reply []byte, msgs chan<- []byte) {
+	defer close(msgs)
+	_, body, err := readMessage(conn)
+	if err != nil {
+		t.Errorf("Neighbor cannot read the OPEN: %v", err)
+		return
+	}
+	msgs <- body
+	if _, err := conn.Write(reply); err != nil {
+		t.Errorf("Neighbor cannot reply: %v", err)
+		return
+	}
+	if _, _, err := readMessage(conn); err != nil {
+		return
+	}
+	if _, err := conn.Write(message(msgKeepalive, nil)); err != nil {
+		return
+	}
+	for {
+		typ, body, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		if typ == msgUpdate {
+			msgs <- body
+		}
+	}
+}
+
+func TestSpeaker(t *testing.T) {
+	local, remote := net.Pipe()
+	peerOpen, err := open(64500, 90, net.ParseIP("192.0.2.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := make(chan []byte, 4)
+	go neighbor(t, remote, peerOpen, msgs)
+
+	s := &Speaker{AS: 64510, RouterID: net.ParseIP("192.0.2.2"), HoldTime: 180 * time.Second}
+	if err := s.Start(local, time.Second); err != nil {
+		t.Fatalf("Start() got error %v", err)
+	}
+	wantOpen := []byte{4, 0xfb, 0xfe, 0, 180, 192, 0, 2, 2, 8, 2, 6, 1, 4, 0, 1, 0, 133}
+	if diff := cmp.Diff(wantOpen, <-msgs); diff != "" {
+		t.Errorf("Start() sent OPEN -want, +got:\n%s", diff)
+	}
+
+	rule := &Rule{Dst: "192.0.2.0/24", Action: Action{Type: Drop, AS: 64510}}
+	if err := s.Advertise([]*Rule{rule}); err != nil {
+		t.Fatalf("Advertise() got error %v", err)
+	}
+	update, err := Update(rule, 64510)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(update[headerLen:], <-msgs); diff != "" {
+		t.Errorf("Advertise() sent UPDATE -want, +got:\n%s", diff)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close() got error %v", err)
+	}
+}
+
+func TestSpeakerNotification(t *testing.T) {
+	local, remote := net.Pipe()
+	msgs := make(chan []byte, 4)
+	// The neighbor rejects the OPEN with a bad peer AS.
+	go neighbor(t, remote, message(msgNotification, []byte{2, 2}), msgs)
+
+	s := &Speaker{AS: 64510, RouterID: net.ParseIP("192.0.2.2"), HoldTime: 180 * time.Second}
+	if err := s.Start(local, time.Second); err == nil {
+		t.Errorf("Start() got no error, want NOTIFICATION")
+	}
+	local.Close()
+}
@@ -0,0 +1,46 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fptest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/ondatra"
+)
+
+// DialOTG returns a client for the OTG API of the ATE, for the
+// operations the ondatra OTG wrapper does not provide, such as packet
+// capture.  It requires the tests to be run with RunTests and a
+// binding supporting OTG.
+func DialOTG(t testing.TB, ate *ondatra.ATEDevice) gosnappi.GosnappiApi {
+	t.Helper()
+	resvMu.Lock()
+	resv := reserved
+	resvMu.Unlock()
+	if resv == nil {
+		t.Fatalf("DialOTG: no testbed reserved by fptest.RunTests")
+	}
+	a, ok := resv.ATEs[ate.ID()]
+	if !ok {
+		t.Fatalf("DialOTG: ATE %s not found in the reservation", ate.ID())
+	}
+	api, err := a.DialOTG(context.Background())
+	if err != nil {
+		t.Fatalf("DialOTG: cannot dial the OTG API of %s: %v", ate.Name(), err)
+	}
+	return api
+}
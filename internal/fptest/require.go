@@ -0,0 +1,145 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fptest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/ondatra"
+)
+
+// Requirements are the ports a test needs on a device of the reserved
+// testbed.  The zero value of each field requires nothing.
+type Requirements struct {
+	// PortIDs are the IDs of the ports the test uses, e.g. "port3".
+	PortIDs []string
+	// Ports is the minimum number of ports.
+	Ports int
+	// Speed is the minimum speed of the ports in PortIDs, or of all the
+	// ports of the device if PortIDs is empty.
+	Speed ondatra.Speed
+	// LAGs is the number of aggregate interfaces of LAGMembers ports each
+	// the test forms.  All the members need to be of the same speed.
+	LAGs       int
+	LAGMembers int
+}
+
+// port is the part of an ondatra port checked against the requirements.
+type port struct {
+	id    string
+	speed ondatra.Speed
+}
+
+// unmet returns the requirements the ports of a device do not meet.
+func (r Requirements) unmet(ports []port) []string {
+	var msgs []string
+	if len(ports) < r.Ports {
+		msgs = append(msgs, fmt.Sprintf("%d ports, want at least %d", len(ports), r.Ports))
+	}
+
+	byID := map[string]port{}
+	for _, p := range ports {
+		byID[p.id] = p
+	}
+	checked := ports
+	if len(r.PortIDs) > 0 {
+		checked = nil
+		for _, id := range r.PortIDs {
+			p, ok := byID[id]
+			if !ok {
+				msgs = append(msgs, fmt.Sprintf("no port %s", id))
+				continue
+			}
+			checked = append(checked, p)
+		}
+	}
+	if r.Speed > 0 {
+		for _, p := range checked {
+			if p.speed < r.Speed {
+				msgs = append(msgs, fmt.Sprintf("port %s of %s, want at least %s", p.id, speedString(p.speed), speedString(r.Speed)))
+			}
+		}
+	}
+
+	if want := r.LAGs * r.LAGMembers; want > 0 {
+		bySpeed := map[ondatra.Speed]int{}
+		for _, p := range ports {
+			bySpeed[p.speed]++
+		}
+		members := 0
+		for _, n := range bySpeed {
+			members += n - n%r.LAGMembers
+		}
+		if members < want {
+			msgs = append(msgs, fmt.Sprintf("ports for %d LAGs of %d members of the same speed, want %d", members/r.LAGMembers, r.LAGMembers, r.LAGs))
+		}
+	}
+	return msgs
+}
+
+// speedString returns a speed in Gbps, the unit of ondatra speeds.
+func speedString(s ondatra.Speed) string {
+	if s == 0 {
+		return "unknown speed"
+	}
+	return fmt.Sprintf("%dGbps", s)
+}
+
+// checkPorts skips the test if the ports of dev do not meet r.
+func checkPorts(t testing.TB, dev *ondatra.Device, r Requirements) {
+	t.Helper()
+	var ports []port
+	for _, p := range dev.Ports() {
+		ports = append(ports, port{id: p.ID(), speed: p.Speed()})
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].id < ports[j].id })
+	if msgs := r.unmet(ports); len(msgs) > 0 {
+		t.Skipf("Testbed requirements not met by %s: %s", dev.ID(), strings.Join(msgs, "; "))
+	}
+}
+
+// RequireDUT returns the DUT of the reserved testbed with the given ID,
+// and skips the test if there is none or if its ports do not meet the
+// requirements.  Tests call it in place of ondatra.DUT, so that they are
+// skipped with the requirements not met rather than fail when using a
+// missing port:
+//
+//	dut := fptest.RequireDUT(t, "dut", fptest.Requirements{
+//	  PortIDs: []string{"port1", "port2", "port3"},
+//	})
+func RequireDUT(t testing.TB, id string, r Requirements) *ondatra.DUTDevice {
+	t.Helper()
+	dut, ok := ondatra.DUTs(t)[id]
+	if !ok {
+		t.Skipf("Testbed requirements not met: no DUT %s", id)
+	}
+	checkPorts(t, dut.Device, r)
+	return dut
+}
+
+// RequireATE is like RequireDUT for the ATE of the reserved testbed with
+// the given ID.
+func RequireATE(t testing.TB, id string, r Requirements) *ondatra.ATEDevice {
+	t.Helper()
+	ate, ok := ondatra.ATEs(t)[id]
+	if !ok {
+		t.Skipf("Testbed requirements not met: no ATE %s", id)
+	}
+	checkPorts(t, ate.Device, r)
+	return ate
+}
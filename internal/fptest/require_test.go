@@ -0,0 +1,87 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fptest
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ondatra"
+)
+
+func TestRequirementsUnmet(t *testing.T) {
+	ports := []port{
+		{"port1", ondatra.Speed100Gb},
+		{"port2", ondatra.Speed100Gb},
+		{"port3", ondatra.Speed10Gb},
+		{"port4", ondatra.Speed100Gb},
+		{"port5", ondatra.Speed10Gb},
+	}
+	for _, c := range []struct {
+		desc string
+		r    Requirements
+		want []string
+	}{{
+		desc: "none",
+	}, {
+		desc: "met",
+		r: Requirements{
+			PortIDs:    []string{"port1", "port2"},
+			Ports:      4,
+			Speed:      ondatra.Speed100Gb,
+			LAGs:       1,
+			LAGMembers: 3,
+		},
+	}, {
+		desc: "too few ports",
+		r:    Requirements{Ports: 12},
+		want: []string{"5 ports, want at least 12"},
+	}, {
+		desc: "missing port",
+		r:    Requirements{PortIDs: []string{"port1", "port6"}},
+		want: []string{"no port port6"},
+	}, {
+		desc: "slow port",
+		r:    Requirements{PortIDs: []string{"port1", "port3"}, Speed: ondatra.Speed100Gb},
+		want: []string{"port port3 of 10Gbps, want at least 100Gbps"},
+	}, {
+		desc: "slow ports of the device",
+		r:    Requirements{Speed: ondatra.Speed100Gb},
+		want: []string{
+			"port port3 of 10Gbps, want at least 100Gbps",
+			"port port5 of 10Gbps, want at least 100Gbps",
+		},
+	}, {
+		desc: "LAGs of mixed speeds",
+		r:    Requirements{LAGs: 2, LAGMembers: 2},
+	}, {
+		desc: "too few LAG members of the same speed",
+		r:    Requirements{LAGs: 1, LAGMembers: 4},
+		want: []string{"ports for 0 LAGs of 4 members of the same speed, want 1"},
+	}} {
+		if diff := cmp.Diff(c.want, c.r.unmet(ports)); diff != "" {
+			t.Errorf("%s: unmet() -want, +got:\n%s", c.desc, diff)
+		}
+	}
+}
+
+func TestSpeedString(t *testing.T) {
+	if got, want := speedString(0), "unknown speed"; got != want {
+		t.Errorf("speedString(0) got %q, want %q", got, want)
+	}
+	if got, want := speedString(ondatra.Speed400Gb), "400Gbps"; got != want {
+		t.Errorf("speedString(400) got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fptest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// resourcePaths are the component leaves sampled for CPU and memory
+// utilization.
+var resourcePaths = [][]string{
+	{"cpu", "utilization", "state", "avg"},
+	{"state", "memory", "utilized"},
+}
+
+// ResourceWatcher samples the CPU and memory utilization of a DUT in
+// the background while the test performs an operation.
+//
+// Usage:
+//
+//	w := fptest.WatchResources(t, dut, 5*time.Second)
+//	installRoutes(t) // e.g. 10k gRIBI installs.
+//	samples := w.Stop(t)
+//	if err := fptest.CheckPeakCPU(samples, 80); err != nil {
+//	  t.Error(err)
+//	}
+type ResourceWatcher struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	samples []*ResourceSample
+	err     error
+}
+
+// WatchResources starts sampling the DUT every interval until Stop is
+// called.  The first sample is taken immediately and serves as the
+// baseline.
+func WatchResources(t testing.TB, dut *ondatra.DUTDevice, interval time.Duration) *ResourceWatcher {
+	t.Helper()
+	gnmi := dut.RawAPIs().GNMI().Default(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &ResourceWatcher{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s, err := sampleOnce(ctx, gnmi)
+			w.mu.Lock()
+			if err != nil {
+				if ctx.Err() == nil && w.err == nil {
+					w.err = err
+				}
+			} else {
+				w.samples = append(w.samples, s)
+			}
+			w.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return w
+}
+
+// Stop stops sampling and returns the samples taken.  Errors sampling
+// the DUT are reported as test errors.
+func (w *ResourceWatcher) Stop(t testing.TB) []*ResourceSample {
+	t.Helper()
+	w.cancel()
+	<-w.done
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		t.Errorf("Error sampling resources: %v", w.err)
+	}
+	return w.samples
+}
+
+// sampleOnce subscribes once to the component resource leaves.
+func sampleOnce(ctx context.Context, gnmi gpb.GNMIClient) (*ResourceSample, error) {
+	var subs []*gpb.Subscription
+	for _, leaf := range resourcePaths {
+		elems := []*gpb.PathElem{{Name: "components"}, {Name: "component", Key: map[string]string{"name": "*"}}}
+		for _, name := range leaf {
+			elems = append(elems, &gpb.PathElem{Name: name})
+		}
+		subs = append(subs, &gpb.Subscription{Path: &gpb.Path{Elem: elems}})
+	}
+	sub, err := gnmi.Subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := sub.Send(&gpb.SubscribeRequest{
+		Request: &gpb.SubscribeRequest_Subscribe{
+			Subscribe: &gpb.SubscriptionList{
+				Mode:         gpb.SubscriptionList_ONCE,
+				Encoding:     gpb.Encoding_PROTO,
+				Subscription: subs,
+			},
+		},
+	}); err != nil {
+		return nil, err
+	}
+	var ns []*gpb.Notification
+	for {
+		resp, err := sub.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if resp.GetSyncResponse() {
+			return sampleFromNotifications(time.Now(), ns), nil
+		}
+		if n := resp.GetUpdate(); n != nil {
+			ns = append(ns, n)
+		}
+	}
+}
+
+// sampleFromNotifications builds a sample from the component resource
+// leaves in ns.
+func sampleFromNotifications(ts time.Time, ns []*gpb.Notification) *ResourceSample {
+	s := &ResourceSample{Time: ts, CPU: map[string]uint8{}, Memory: map[string]uint64{}}
+	for _, n := range ns {
+		for _, u := range n.GetUpdate() {
+			path := &gpb.Path{Elem: append(append([]*gpb.PathElem{}, n.GetPrefix().GetElem()...), u.GetPath().GetElem()...)}
+			elems := path.GetElem()
+			if len(elems) == 0 {
+				continue
+			}
+			name := componentName(path)
+			switch elems[len(elems)-1].GetName() {
+			case "avg":
+				s.CPU[name] = uint8(u.GetVal().GetUintVal())
+			case "utilized":
+				s.Memory[name] = u.GetVal().GetUintVal()
+			}
+		}
+	}
+	return s
+}
+
+// CheckPeakCPU returns an error if the CPU utilization of any component
+// exceeded maxPercent in any of the samples.
+func CheckPeakCPU(samples []*ResourceSample, maxPercent uint8) error {
+	peak := map[string]uint8{}
+	for _, s := range samples {
+		for name, v := range s.CPU {
+			if v > peak[name] {
+				peak[name] = v
+			}
+		}
+	}
+	var errs []string
+	for name, v := range peak {
+		if v > maxPercent {
+			errs = append(errs, fmt.Sprintf("%s peaked at %d%%", name, v))
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("cpu utilization above %d%%: %s", maxPercent, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// CheckPeakMemory returns an error if the utilized memory of any
+// component grew by more than growthPercent over the first sample in
+// any of the samples.
+func CheckPeakMemory(samples []*ResourceSample, growthPercent float64) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	var errs []string
+	for name, base := range samples[0].Memory {
+		var peak uint64
+		for _, s := range samples[1:] {
+			if v := s.Memory[name]; v > peak {
+				peak = v
+			}
+		}
+		if base > 0 && float64(peak) > float64(base)*(1+growthPercent/100) {
+			errs = append(errs, fmt.Sprintf("%s peaked at %d from %d", name, peak, base))
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("memory utilization grew above %v%%: %s", growthPercent, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// CompareResources returns an error if, compared to base, the CPU
+// utilization of a component in cur increased by more than cpuPoints
+// percentage points, or its utilized memory grew by more than
+// memoryPercent.  Use it to check that resources return to baseline
+// after an operation, which detects resource leaks.
+func CompareResources(base, cur *ResourceSample, cpuPoints uint8, memoryPercent float64) error {
+	var errs []string
+	for name, v := range cur.CPU {
+		if b, ok := base.CPU[name]; ok && v > b && v-b > cpuPoints {
+			errs = append(errs, fmt.Sprintf("%s cpu %d%% -> %d%%", name, b, v))
+		}
+	}
+	for name, v := range cur.Memory {
+		if b, ok := base.Memory[name]; ok && b > 0 && float64(v) > float64(b)*(1+memoryPercent/100) {
+			errs = append(errs, fmt.Sprintf("%s memory %d -> %d", name, b, v))
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("resource regression: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// AwaitBaseline samples the DUT every 10 seconds until its resources
+// return to base within the CompareResources thresholds, or until
+// timeout.  It returns the last comparison error.
+func AwaitBaseline(t testing.TB, dut *ondatra.DUTDevice, base *ResourceSample, timeout time.Duration, cpuPoints uint8, memoryPercent float64) error {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		err := CompareResources(base, SampleResources(t, dut), cpuPoints, memoryPercent)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(10 * time.Second)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fptest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func TestSampleFromNotifications(t *testing.T) {
+	comp := func(name string) []*gpb.PathElem {
+		return []*gpb.PathElem{{Name: "components"}, {Name: "component", Key: map[string]string{"name": name}}}
+	}
+	leaf := func(names ...string) *gpb.Path {
+		var elems []*gpb.PathElem
+		for _, n := range names {
+			elems = append(elems, &gpb.PathElem{Name: n})
+		}
+		return &gpb.Path{Elem: elems}
+	}
+	uintVal := func(v uint64) *gpb.TypedValue {
+		return &gpb.TypedValue{Value: &gpb.TypedValue_UintVal{UintVal: v}}
+	}
+	ts := time.Unix(1000, 0)
+	ns := []*gpb.Notification{{
+		Prefix: &gpb.Path{Elem: comp("RP0")},
+		Update: []*gpb.Update{
+			{Path: leaf("cpu", "utilization", "state", "avg"), Val: uintVal(42)},
+			{Path: leaf("state", "memory", "utilized"), Val: uintVal(1 << 30)},
+		},
+	}, {
+		Update: []*gpb.Update{
+			{Path: &gpb.Path{Elem: append(comp("LC0"), leaf("cpu", "utilization", "state", "avg").Elem...)}, Val: uintVal(7)},
+		},
+	}}
+	got := sampleFromNotifications(ts, ns)
+	want := &ResourceSample{
+		Time:   ts,
+		CPU:    map[string]uint8{"RP0": 42, "LC0": 7},
+		Memory: map[string]uint64{"RP0": 1 << 30},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("sampleFromNotifications got unexpected sample, diff(-want,+got):\n%s", diff)
+	}
+}
+
+func TestCheckPeakCPU(t *testing.T) {
+	samples := []*ResourceSample{
+		{CPU: map[string]uint8{"RP0": 10, "RP1": 20}},
+		{CPU: map[string]uint8{"RP0": 85, "RP1": 30}},
+		{CPU: map[string]uint8{"RP0": 15, "RP1": 20}},
+	}
+	if err := CheckPeakCPU(samples, 90); err != nil {
+		t.Errorf("CheckPeakCPU(90) got error: %v", err)
+	}
+	if err := CheckPeakCPU(samples, 80); err == nil {
+		t.Error("CheckPeakCPU(80) got no error, want error")
+	}
+}
+
+func TestCheckPeakMemory(t *testing.T) {
+	samples := []*ResourceSample{
+		{Memory: map[string]uint64{"RP0": 1000}},
+		{Memory: map[string]uint64{"RP0": 1400}},
+		{Memory: map[string]uint64{"RP0": 1000}},
+	}
+	if err := CheckPeakMemory(samples, 50); err != nil {
+		t.Errorf("CheckPeakMemory(50) got error: %v", err)
+	}
+	if err := CheckPeakMemory(samples, 30); err == nil {
+		t.Error("CheckPeakMemory(30) got no error, want error")
+	}
+	if err := CheckPeakMemory(nil, 30); err != nil {
+		t.Errorf("CheckPeakMemory(nil) got error: %v", err)
+	}
+}
@@ -15,12 +15,68 @@
 package fptest
 
 import (
+	"context"
+	"os"
+	"sync"
 	"testing"
+	"time"
 
+	log "github.com/golang/glog"
+	"github.com/openconfig/featureprofiles/internal/rundata"
 	"github.com/openconfig/featureprofiles/topologies/binding"
 	"github.com/openconfig/ondatra"
+
+	obinding "github.com/openconfig/ondatra/binding"
+	opb "github.com/openconfig/ondatra/proto"
+)
+
+// rundataTimeout bounds the time spent collecting the run properties.
+const rundataTimeout = time.Minute
+
+// rundataBinding wraps a binding to print the run properties of the
+// DUTs once they are reserved.
+type rundataBinding struct {
+	obinding.Binding
+}
+
+var (
+	// resvMu guards reserved.
+	resvMu sync.Mutex
+	// reserved is the reservation of the running tests, set once the
+	// testbed is reserved.
+	reserved *obinding.Reservation
 )
 
+func (b *rundataBinding) Reserve(ctx context.Context, tb *opb.Testbed, runTime, waitTime time.Duration, partial map[string]string) (*obinding.Reservation, error) {
+	resv, err := b.Binding.Reserve(ctx, tb, runTime, waitTime, partial)
+	if err != nil {
+		return nil, err
+	}
+	resvMu.Lock()
+	reserved = resv
+	resvMu.Unlock()
+	ctx, cancel := context.WithTimeout(ctx, rundataTimeout)
+	defer cancel()
+	props, err := rundata.Properties(ctx, resv)
+	if err != nil {
+		log.Warningf("Incomplete run properties: %v", err)
+	}
+	if err := rundata.Write(os.Stdout, props); err != nil {
+		log.Warningf("Cannot write run properties: %v", err)
+	}
+	return resv, nil
+}
+
+// newBinding creates the featureprofiles binding wrapped to record
+// the run properties.
+func newBinding() (obinding.Binding, error) {
+	b, err := binding.New()
+	if err != nil {
+		return nil, err
+	}
+	return &rundataBinding{Binding: b}, nil
+}
+
 // RunTests initializes the appropriate binding and runs the tests.
 // It should be called from every featureprofiles tests like this:
 //
@@ -31,6 +87,14 @@ import (
 //	func TestMain(m *testing.M) {
 //	  fptest.RunTests(m)
 //	}
+//
+// The vendor, hardware model, software version, hostname and gNMI
+// capabilities of the DUTs, and the deviations in effect, are printed to
+// the test output as run properties after the testbed is reserved.
+// They are printed once, as output of the test package rather than of
+// each test: the testbed is reserved once for all the tests of a
+// package, so the properties hold for every one of them, and
+// tools/compliance_report attributes them to each test of the package.
 func RunTests(m *testing.M) {
-	ondatra.RunTests(m, binding.New)
+	ondatra.RunTests(m, newBinding)
 }
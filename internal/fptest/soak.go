@@ -0,0 +1,183 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fptest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// ResourceSample is the control plane CPU and memory utilization of
+// the components of a DUT at a point in time.
+type ResourceSample struct {
+	Time time.Time
+	// CPU is the average CPU utilization in percent, keyed by component.
+	CPU map[string]uint8
+	// Memory is the utilized memory in bytes, keyed by component.
+	Memory map[string]uint64
+}
+
+// componentName returns the name of the component in a telemetry path.
+func componentName(path *gpb.Path) string {
+	for _, e := range path.GetElem() {
+		if e.GetName() == "component" {
+			return e.GetKey()["name"]
+		}
+	}
+	return ""
+}
+
+// SampleResources samples the CPU and memory utilization of all the
+// components of the DUT that report them.
+func SampleResources(t testing.TB, dut *ondatra.DUTDevice) *ResourceSample {
+	t.Helper()
+	s := &ResourceSample{
+		Time:   time.Now(),
+		CPU:    map[string]uint8{},
+		Memory: map[string]uint64{},
+	}
+	comps := dut.Telemetry().ComponentAny()
+	for _, q := range comps.Cpu().Utilization().Avg().Lookup(t) {
+		if q.IsPresent() {
+			s.CPU[componentName(q.GetPath())] = q.Val(t)
+		}
+	}
+	for _, q := range comps.Memory().Utilized().Lookup(t) {
+		if q.IsPresent() {
+			s.Memory[componentName(q.GetPath())] = q.Val(t)
+		}
+	}
+	return s
+}
+
+// String formats the sample as one line per component.
+func (s *ResourceSample) String() string {
+	names := map[string]bool{}
+	for name := range s.CPU {
+		names[name] = true
+	}
+	for name := range s.Memory {
+		names[name] = true
+	}
+	var lines []string
+	for name := range names {
+		lines = append(lines, fmt.Sprintf("%s: cpu=%d%% memory=%d", name, s.CPU[name], s.Memory[name]))
+	}
+	sort.Strings(lines)
+	return s.Time.Format(time.RFC3339) + "\n" + strings.Join(lines, "\n")
+}
+
+// SoakSnapshot is a telemetry subtree written to the test outputs at
+// every soak checkpoint.
+type SoakSnapshot struct {
+	What string
+	Path ygot.PathStruct
+	Get  func(t testing.TB) ygot.ValidatedGoStruct
+}
+
+// Soak runs the checkpoints of a long-duration soak test.  The test
+// starts its workload, e.g. traffic or route churn, and then calls Run,
+// which every Interval until Duration has elapsed:
+//
+//   - writes the Snapshots to the test outputs;
+//   - samples the CPU and memory utilization of the DUT components;
+//   - calls Checkpoint with the samples collected so far.
+//
+// Run stops early if a checkpoint fails, so a regression does not
+// need to wait for the end of a multi-hour run to be reported.
+//
+// Usage:
+//
+//	s := &fptest.Soak{
+//	  DUT:        dut,
+//	  Duration:   8 * time.Hour,
+//	  Interval:   10 * time.Minute,
+//	  Checkpoint: fptest.ResourceRegression(10, 20),
+//	}
+//	s.Run(t)
+type Soak struct {
+	DUT        *ondatra.DUTDevice
+	Duration   time.Duration
+	Interval   time.Duration
+	Snapshots  []SoakSnapshot
+	Checkpoint func(t testing.TB, samples []*ResourceSample) error
+
+	// Replaced in unit tests.
+	sample func(t testing.TB) *ResourceSample
+	sleep  func(time.Duration)
+}
+
+// Run runs the soak checkpoints and returns the resource samples.  A
+// baseline sample is taken before the first interval.
+func (s *Soak) Run(t testing.TB) []*ResourceSample {
+	t.Helper()
+	if s.Interval <= 0 {
+		t.Fatalf("Invalid soak interval: %v", s.Interval)
+	}
+	sample := s.sample
+	if sample == nil {
+		sample = func(t testing.TB) *ResourceSample { return SampleResources(t, s.DUT) }
+	}
+	sleep := s.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	samples := []*ResourceSample{sample(t)}
+	for elapsed, n := time.Duration(0), 1; elapsed < s.Duration; n++ {
+		d := s.Interval
+		if rest := s.Duration - elapsed; rest < d {
+			d = rest
+		}
+		sleep(d)
+		elapsed += d
+
+		for _, snap := range s.Snapshots {
+			WriteYgot(t, fmt.Sprintf("%s at checkpoint %d", snap.What, n), snap.Path, snap.Get(t))
+		}
+		cur := sample(t)
+		samples = append(samples, cur)
+		if err := WriteOutput(fmt.Sprintf("%s resources at checkpoint %d", t.Name(), n), ".txt", cur.String()); err != nil {
+			t.Logf("Could not write test output: %v", err)
+		}
+		if s.Checkpoint == nil {
+			continue
+		}
+		if err := s.Checkpoint(t, samples); err != nil {
+			t.Errorf("Soak checkpoint %d after %v failed, aborting: %v", n, elapsed, err)
+			break
+		}
+		t.Logf("Soak checkpoint %d after %v passed", n, elapsed)
+	}
+	return samples
+}
+
+// ResourceRegression returns a soak checkpoint that fails when the
+// CPU utilization of a component increased by more than cpuPoints
+// percentage points, or its utilized memory grew by more than
+// memoryPercent, compared to the baseline sample.
+func ResourceRegression(cpuPoints uint8, memoryPercent float64) func(t testing.TB, samples []*ResourceSample) error {
+	return func(t testing.TB, samples []*ResourceSample) error {
+		return CompareResources(samples[0], samples[len(samples)-1], cpuPoints, memoryPercent)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fptest
+
+import (
+	"testing"
+	"time"
+)
+
+// soakRecorder captures errors reported through testing.TB.
+type soakRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *soakRecorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func TestSoak(t *testing.T) {
+	cases := []struct {
+		desc        string
+		memory      []uint64
+		wantSamples int
+		wantErrors  int
+	}{{
+		desc:        "steady",
+		memory:      []uint64{100, 105, 110, 110, 110},
+		wantSamples: 4,
+	}, {
+		desc:        "leak aborts early",
+		memory:      []uint64{100, 110, 150, 200, 250},
+		wantSamples: 3,
+		wantErrors:  1,
+	}}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			n := 0
+			var slept time.Duration
+			s := &Soak{
+				Duration:   25 * time.Minute,
+				Interval:   10 * time.Minute,
+				Checkpoint: ResourceRegression(10, 20),
+				sample: func(testing.TB) *ResourceSample {
+					m := c.memory[n]
+					n++
+					return &ResourceSample{
+						CPU:    map[string]uint8{"RP0": 10},
+						Memory: map[string]uint64{"RP0": m},
+					}
+				},
+				sleep: func(d time.Duration) { slept += d },
+			}
+			rec := &soakRecorder{TB: t}
+			got := s.Run(rec)
+			if len(got) != c.wantSamples {
+				t.Errorf("Run got %d samples, want %d", len(got), c.wantSamples)
+			}
+			if len(rec.errors) != c.wantErrors {
+				t.Errorf("Run reported errors %v, want %d errors", rec.errors, c.wantErrors)
+			}
+			if c.wantErrors == 0 && slept != s.Duration {
+				t.Errorf("Run slept %v, want %v", slept, s.Duration)
+			}
+		})
+	}
+}
+
+func TestCompareResources(t *testing.T) {
+	base := &ResourceSample{
+		CPU:    map[string]uint8{"RP0": 10, "RP1": 50},
+		Memory: map[string]uint64{"RP0": 1000},
+	}
+	cases := []struct {
+		desc    string
+		cur     *ResourceSample
+		wantErr bool
+	}{{
+		desc: "within thresholds",
+		cur: &ResourceSample{
+			CPU:    map[string]uint8{"RP0": 20, "RP1": 5},
+			Memory: map[string]uint64{"RP0": 1200, "LC0": 99999},
+		},
+	}, {
+		desc:    "cpu increase",
+		cur:     &ResourceSample{CPU: map[string]uint8{"RP0": 21}},
+		wantErr: true,
+	}, {
+		desc:    "memory growth",
+		cur:     &ResourceSample{Memory: map[string]uint64{"RP0": 1201}},
+		wantErr: true,
+	}}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			err := CompareResources(base, c.cur, 10, 20)
+			if gotErr := err != nil; gotErr != c.wantErr {
+				t.Errorf("CompareResources got error %v, want error %v", err, c.wantErr)
+			}
+		})
+	}
+}
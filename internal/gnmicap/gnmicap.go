@@ -0,0 +1,206 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gnmicap checks the gNMI capabilities of the DUT against the
+// models, model versions and encodings a test requires, so the test is
+// skipped or fails with the capabilities missing rather than on the
+// first path the DUT does not support.
+//
+// Versions are the semantic versions of the OpenConfig models, e.g.
+// "2.4.1", compared numerically.  The advertised capabilities are also
+// recorded in the run properties by internal/rundata.
+package gnmicap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/ondatra"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// Requirements are the gNMI capabilities a test needs from a DUT.  The
+// zero value of each field requires nothing.
+type Requirements struct {
+	// Models maps the names of the models the test uses to their minimum
+	// version, or "" for any version.
+	Models map[string]string
+	// Encodings are the encodings the test uses.
+	Encodings []gpb.Encoding
+	// GNMIVersion is the minimum version of gNMI.
+	GNMIVersion string
+}
+
+// ParseModels parses a comma separated list of models, each a name
+// optionally followed by "@" and its minimum version, e.g.
+// "openconfig-interfaces@2.4.1,openconfig-acl".
+func ParseModels(s string) (map[string]string, error) {
+	models := map[string]string{}
+	for _, m := range strings.Split(s, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		name, version := m, ""
+		if i := strings.IndexByte(m, '@'); i >= 0 {
+			name, version = m[:i], m[i+1:]
+			if _, err := parseVersion(version); err != nil {
+				return nil, fmt.Errorf("model %s: %w", name, err)
+			}
+		}
+		models[name] = version
+	}
+	return models, nil
+}
+
+// parseVersion parses a semantic version into its numbers.
+func parseVersion(v string) ([]int, error) {
+	s := strings.TrimPrefix(v, "v")
+	if s == "" {
+		return nil, fmt.Errorf("empty version %q", v)
+	}
+	var nums []int
+	for _, f := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", v)
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+// compareVersions returns -1, 0 or 1 if the version a is lower than,
+// equal to or greater than b.  Missing numbers are 0.
+func compareVersions(a, b string) (int, error) {
+	an, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bn, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for len(an) < len(bn) {
+		an = append(an, 0)
+	}
+	for len(bn) < len(an) {
+		bn = append(bn, 0)
+	}
+	for i := range an {
+		switch {
+		case an[i] < bn[i]:
+			return -1, nil
+		case an[i] > bn[i]:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// atLeast returns a message if the version advertised is not at least
+// the minimum one.
+func atLeast(what, got, min string) string {
+	if min == "" {
+		return ""
+	}
+	if got == "" {
+		return fmt.Sprintf("%s without version, want at least %s", what, min)
+	}
+	c, err := compareVersions(got, min)
+	if err != nil {
+		return fmt.Sprintf("%s: %v", what, err)
+	}
+	if c < 0 {
+		return fmt.Sprintf("%s version %s, want at least %s", what, got, min)
+	}
+	return ""
+}
+
+// Unmet returns the requirements the capabilities do not meet.
+func (r Requirements) Unmet(caps *gpb.CapabilityResponse) []string {
+	var msgs []string
+	if msg := atLeast("gNMI", caps.GetGNMIVersion(), r.GNMIVersion); msg != "" {
+		msgs = append(msgs, msg)
+	}
+
+	encodings := map[gpb.Encoding]bool{}
+	for _, e := range caps.GetSupportedEncodings() {
+		encodings[e] = true
+	}
+	for _, e := range r.Encodings {
+		if !encodings[e] {
+			msgs = append(msgs, fmt.Sprintf("no encoding %v", e))
+		}
+	}
+
+	versions := map[string]string{}
+	for _, m := range caps.GetSupportedModels() {
+		versions[m.GetName()] = m.GetVersion()
+	}
+	var names []string
+	for name := range r.Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v, ok := versions[name]
+		if !ok {
+			msgs = append(msgs, fmt.Sprintf("no model %s", name))
+			continue
+		}
+		if msg := atLeast("model "+name, v, r.Models[name]); msg != "" {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+// Get returns the gNMI capabilities of the DUT.
+func Get(t testing.TB, dut *ondatra.DUTDevice) *gpb.CapabilityResponse {
+	t.Helper()
+	caps, err := dut.RawAPIs().GNMI().Default(t).Capabilities(context.Background(), &gpb.CapabilityRequest{})
+	if err != nil {
+		t.Fatalf("gNMI Capabilities of %s failed: %v", dut.Name(), err)
+	}
+	return caps
+}
+
+// SkipUnless skips the test if the gNMI capabilities of the DUT do not
+// meet the requirements, for tests of features the DUT may not support:
+//
+//	gnmicap.SkipUnless(t, dut, gnmicap.Requirements{
+//	  Models:    map[string]string{"openconfig-acl": "1.2.0"},
+//	  Encodings: []gpb.Encoding{gpb.Encoding_JSON_IETF},
+//	})
+func SkipUnless(t testing.TB, dut *ondatra.DUTDevice, r Requirements) {
+	t.Helper()
+	if msgs := r.Unmet(Get(t, dut)); len(msgs) > 0 {
+		t.Skipf("gNMI capabilities of %s do not meet the requirements: %s", dut.Name(), strings.Join(msgs, "; "))
+	}
+}
+
+// Require fails the test if the gNMI capabilities of the DUT do not meet
+// the requirements, for tests of features the DUT must support.
+func Require(t testing.TB, dut *ondatra.DUTDevice, r Requirements) {
+	t.Helper()
+	if msgs := r.Unmet(Get(t, dut)); len(msgs) > 0 {
+		t.Fatalf("gNMI capabilities of %s do not meet the requirements: %s", dut.Name(), strings.Join(msgs, "; "))
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gnmicap
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func TestParseModels(t *testing.T) {
+	got, err := ParseModels("openconfig-interfaces@2.4.1, openconfig-acl,,openconfig-system@v0.10")
+	if err != nil {
+		t.Fatalf("ParseModels() got error: %v", err)
+	}
+	want := map[string]string{
+		"openconfig-interfaces": "2.4.1",
+		"openconfig-acl":        "",
+		"openconfig-system":     "v0.10",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseModels() -want, +got:\n%s", diff)
+	}
+	for _, s := range []string{"openconfig-acl@", "openconfig-acl@1.x", "openconfig-acl@1.-2"} {
+		if _, err := ParseModels(s); err == nil {
+			t.Errorf("ParseModels(%q) got no error, want error", s)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"v1.10.0", "1.9.9", 1},
+		{"0.7.0", "0.8.0", -1},
+		{"2.0.0", "10.0.0", -1},
+	} {
+		got, err := compareVersions(c.a, c.b)
+		if err != nil {
+			t.Errorf("compareVersions(%q, %q) got error: %v", c.a, c.b, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("compareVersions(%q, %q) got %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+	if _, err := compareVersions("1.2.3-beta", "1.2.3"); err == nil {
+		t.Error("compareVersions() of a pre-release got no error, want error")
+	}
+}
+
+func TestUnmet(t *testing.T) {
+	caps := &gpb.CapabilityResponse{
+		GNMIVersion:        "0.7.0",
+		SupportedEncodings: []gpb.Encoding{gpb.Encoding_JSON_IETF, gpb.Encoding_PROTO},
+		SupportedModels: []*gpb.ModelData{
+			{Name: "openconfig-interfaces", Version: "2.4.3"},
+			{Name: "openconfig-acl", Version: "1.2.2"},
+			{Name: "openconfig-system"},
+		},
+	}
+	for _, c := range []struct {
+		desc string
+		r    Requirements
+		want []string
+	}{{
+		desc: "nothing",
+	}, {
+		desc: "met",
+		r: Requirements{
+			Models:      map[string]string{"openconfig-interfaces": "2.4.1", "openconfig-acl": "", "openconfig-system": ""},
+			Encodings:   []gpb.Encoding{gpb.Encoding_JSON_IETF},
+			GNMIVersion: "0.7",
+		},
+	}, {
+		desc: "unmet",
+		r: Requirements{
+			Models:      map[string]string{"openconfig-interfaces": "3.0.0", "openconfig-bfd": "", "openconfig-system": "0.10.0"},
+			Encodings:   []gpb.Encoding{gpb.Encoding_JSON, gpb.Encoding_PROTO},
+			GNMIVersion: "0.8.0",
+		},
+		want: []string{
+			"gNMI version 0.7.0, want at least 0.8.0",
+			"no encoding JSON",
+			"no model openconfig-bfd",
+			"model openconfig-interfaces version 2.4.3, want at least 3.0.0",
+			"model openconfig-system without version, want at least 0.10.0",
+		},
+	}} {
+		if diff := cmp.Diff(c.want, c.r.Unmet(caps)); diff != "" {
+			t.Errorf("%s: Unmet() -want, +got:\n%s", c.desc, diff)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/openconfig/gribigo/chk"
+	"github.com/openconfig/gribigo/constants"
+	"github.com/openconfig/gribigo/fluent"
+)
+
+// defaultRoute is the IPv4 default route, through which the lookup
+// network instance of a DecapLookup falls back to another one.
+const defaultRoute = "0.0.0.0/0"
+
+// AddDecapNH adds a NextHopEntry with a given index within a given network
+// instance, which decapsulates IP-in-IP packets and looks the inner
+// packets up in the lookup network instance.
+func (c *Client) AddDecapNH(t testing.TB, nhIndex uint64, instance, lookupInstance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	c.addNHEntry(t, nhIndex, fluent.NextHopEntry().
+		WithNetworkInstance(instance).
+		WithIndex(nhIndex).
+		WithDecapsulateHeader(fluent.IPinIP).
+		WithNextHopNetworkInstance(lookupInstance), expectedResult)
+}
+
+// AddLookupNH adds a NextHopEntry with a given index within a given network
+// instance, which looks the packets up in the lookup network instance.
+func (c *Client) AddLookupNH(t testing.TB, nhIndex uint64, instance, lookupInstance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	c.addNHEntry(t, nhIndex, fluent.NextHopEntry().
+		WithNetworkInstance(instance).
+		WithIndex(nhIndex).
+		WithNextHopNetworkInstance(lookupInstance), expectedResult)
+}
+
+// addNHEntry adds a NextHopEntry and checks its result.
+func (c *Client) addNHEntry(t testing.TB, nhIndex uint64, nh fluent.GRIBIEntry, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	c.fluentC.Modify().AddEntry(t, nh)
+	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to add NH: %v", err)
+	}
+	chk.HasResult(t, c.fluentC.Results(t),
+		fluent.OperationResult().
+			WithNextHopOperation(nhIndex).
+			WithOperationType(constants.Add).
+			WithProgrammingResult(expectedResult).
+			AsResult(),
+		chk.IgnoreOperationID(),
+	)
+}
+
+// DecapLookup is the decapsulate then lookup pattern: IP-in-IP packets to
+// a prefix are decapsulated, and the inner packets are looked up in the
+// lookup network instance.  If a fallback network instance is set, inner
+// packets matching no prefix of the lookup network instance are looked up
+// in it, through a default route of the lookup network instance to a next
+// hop looking up the fallback network instance.
+type DecapLookup struct {
+	// NetworkInstance is the network instance of the decapsulated prefix,
+	// and of the next hops and next hop groups.
+	NetworkInstance string
+	// Prefix is the IPv4 prefix of the decapsulated outer destinations.
+	Prefix                  string
+	LookupNetworkInstance   string
+	FallbackNetworkInstance string
+	// NH and NHG are the indices of the decapsulating next hop and of its
+	// next hop group.  Those of the fallback are NH+1 and NHG+1.
+	NH  uint64
+	NHG uint64
+}
+
+// Validate returns an error if the pattern cannot be programmed.
+func (d *DecapLookup) Validate() error {
+	switch {
+	case d.NetworkInstance == "" || d.LookupNetworkInstance == "":
+		return fmt.Errorf("network instance and lookup network instance are required")
+	case d.FallbackNetworkInstance == d.LookupNetworkInstance:
+		return fmt.Errorf("fallback network instance %s is the lookup network instance", d.FallbackNetworkInstance)
+	case d.NH == 0 || d.NHG == 0:
+		return fmt.Errorf("next hop and next hop group indices are required")
+	}
+	if _, err := parseIPv4Prefix(d.Prefix); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseIPv4Prefix parses an IPv4 prefix.
+func parseIPv4Prefix(prefix string) (*net.IPNet, error) {
+	ip, ipNet, err := net.ParseCIDR(prefix)
+	if err != nil || ip.To4() == nil {
+		return nil, fmt.Errorf("%q is not an IPv4 prefix", prefix)
+	}
+	return ipNet, nil
+}
+
+// Program adds the entries of the pattern: the decapsulating next hop,
+// its next hop group and the prefix, then, with a fallback network
+// instance, the next hop looking it up, its next hop group and the default
+// route of the lookup network instance.
+func (d *DecapLookup) Program(t testing.TB, c *Client, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	c.AddDecapNH(t, d.NH, d.NetworkInstance, d.LookupNetworkInstance, expectedResult)
+	c.AddNHG(t, d.NHG, map[uint64]uint64{d.NH: 1}, d.NetworkInstance, expectedResult)
+	c.AddIPv4(t, d.Prefix, d.NHG, d.NetworkInstance, "", expectedResult)
+	if d.FallbackNetworkInstance == "" {
+		return
+	}
+	c.AddLookupNH(t, d.NH+1, d.NetworkInstance, d.FallbackNetworkInstance, expectedResult)
+	c.AddNHG(t, d.NHG+1, map[uint64]uint64{d.NH + 1: 1}, d.NetworkInstance, expectedResult)
+	c.AddIPv4(t, defaultRoute, d.NHG+1, d.LookupNetworkInstance, d.NetworkInstance, expectedResult)
+}
+
+// Delete deletes the entries of the pattern in the reverse order of
+// Program.
+func (d *DecapLookup) Delete(t testing.TB, c *Client, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	if d.FallbackNetworkInstance != "" {
+		c.DeleteIPv4(t, defaultRoute, d.LookupNetworkInstance, expectedResult)
+		c.DeleteNHG(t, d.NHG+1, d.NetworkInstance, expectedResult)
+		c.DeleteNH(t, d.NH+1, d.NetworkInstance, expectedResult)
+	}
+	c.DeleteIPv4(t, d.Prefix, d.NetworkInstance, expectedResult)
+	c.DeleteNHG(t, d.NHG, d.NetworkInstance, expectedResult)
+	c.DeleteNH(t, d.NH, d.NetworkInstance, expectedResult)
+}
+
+// Forwarder returns the network instance whose table forwards a
+// decapsulated packet to the inner destination dst, given the prefixes of
+// the lookup network instance, other than the default route of the
+// fallback.  It is the lookup network instance if one of its prefixes
+// contains dst, else the fallback network instance, which is empty if the
+// packet is dropped.
+func (d *DecapLookup) Forwarder(dst string, lookupPrefixes []string) (string, error) {
+	ip := net.ParseIP(dst)
+	if ip == nil || ip.To4() == nil {
+		return "", fmt.Errorf("%q is not an IPv4 address", dst)
+	}
+	for _, p := range lookupPrefixes {
+		ipNet, err := parseIPv4Prefix(p)
+		if err != nil {
+			return "", err
+		}
+		if ipNet.Contains(ip) {
+			return d.LookupNetworkInstance, nil
+		}
+	}
+	return d.FallbackNetworkInstance, nil
+}
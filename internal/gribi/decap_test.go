@@ -0,0 +1,86 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import "testing"
+
+func newDecapLookup() *DecapLookup {
+	return &DecapLookup{
+		NetworkInstance:         defaultNI,
+		Prefix:                  "203.0.113.100/32",
+		LookupNetworkInstance:   vrf,
+		FallbackNetworkInstance: defaultNI,
+		NH:                      1,
+		NHG:                     10,
+	}
+}
+
+func TestDecapLookupValidate(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		modify  func(d *DecapLookup)
+		wantErr bool
+	}{
+		{"valid", func(d *DecapLookup) {}, false},
+		{"no fallback", func(d *DecapLookup) { d.FallbackNetworkInstance = "" }, false},
+		{"no network instance", func(d *DecapLookup) { d.NetworkInstance = "" }, true},
+		{"no lookup network instance", func(d *DecapLookup) { d.LookupNetworkInstance = "" }, true},
+		{"fallback to the lookup network instance", func(d *DecapLookup) { d.FallbackNetworkInstance = vrf }, true},
+		{"IPv6 prefix", func(d *DecapLookup) { d.Prefix = "2001:db8::/64" }, true},
+		{"invalid prefix", func(d *DecapLookup) { d.Prefix = "203.0.113.100" }, true},
+		{"no next hop index", func(d *DecapLookup) { d.NH = 0 }, true},
+		{"no next hop group index", func(d *DecapLookup) { d.NHG = 0 }, true},
+	} {
+		d := newDecapLookup()
+		c.modify(d)
+		if err := d.Validate(); (err != nil) != c.wantErr {
+			t.Errorf("%s: Validate() got error %v, want error %v", c.desc, err, c.wantErr)
+		}
+	}
+}
+
+func TestDecapLookupForwarder(t *testing.T) {
+	lookupPrefixes := []string{"198.51.100.0/25", "203.0.113.0/24"}
+	for _, c := range []struct {
+		desc     string
+		dst      string
+		fallback string
+		want     string
+		wantErr  bool
+	}{
+		{"lookup", "198.51.100.1", defaultNI, vrf, false},
+		{"second lookup prefix", "203.0.113.7", defaultNI, vrf, false},
+		{"fallback", "198.51.100.129", defaultNI, defaultNI, false},
+		{"lookup without fallback", "198.51.100.1", "", vrf, false},
+		{"dropped without fallback", "198.51.100.129", "", "", false},
+		{"IPv6 destination", "2001:db8::1", defaultNI, "", true},
+		{"invalid destination", "198.51.100", defaultNI, "", true},
+	} {
+		d := newDecapLookup()
+		d.FallbackNetworkInstance = c.fallback
+		got, err := d.Forwarder(c.dst, lookupPrefixes)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: Forwarder(%q) got error %v, want error %v", c.desc, c.dst, err, c.wantErr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: Forwarder(%q) got %q, want %q", c.desc, c.dst, got, c.want)
+		}
+	}
+	d := newDecapLookup()
+	if _, err := d.Forwarder("198.51.100.1", []string{"198.51.100.0"}); err == nil {
+		t.Errorf("Forwarder() with an invalid lookup prefix got no error, want error")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+)
+
+// Failover is a leader failover between two clients of the same DUT in
+// SINGLE_PRIMARY redundancy mode: A is the leader that programs the
+// entries, until B takes over the mastership to modify them.
+//
+// Usage:
+//
+//	f := &gribi.Failover{
+//	  A: &gribi.Client{DUT: dut, Persistence: true, InitialElectionIDLow: 10},
+//	  B: &gribi.Client{DUT: dut, Persistence: true, InitialElectionIDLow: 10},
+//	}
+//	defer f.Close(t)
+//	if err := f.Start(t); err != nil {
+//	  t.Fatalf("Could not initialize gRIBI: %v", err)
+//	}
+//	f.A.AddNH(...)
+//	w := gribi.MeasureOutage(t, ate, flow, pps, settle, func() {
+//	  f.Takeover(t)
+//	  f.B.AddNH(...)
+//	})
+type Failover struct {
+	A, B *Client
+}
+
+// Start connects both clients to the DUT, and makes A the leader.
+func (f *Failover) Start(t testing.TB) error {
+	t.Helper()
+	if err := f.A.Start(t); err != nil {
+		return fmt.Errorf("client A: %w", err)
+	}
+	if err := f.B.Start(t); err != nil {
+		return fmt.Errorf("client B: %w", err)
+	}
+	f.A.BecomeLeader(t)
+	return nil
+}
+
+// Takeover makes B the leader, with an election id above that of A.
+func (f *Failover) Takeover(t testing.TB) {
+	t.Helper()
+	f.B.BecomeLeader(t)
+}
+
+// Close closes the connections of both clients.
+func (f *Failover) Close(t testing.TB) {
+	t.Helper()
+	f.A.Close(t)
+	f.B.Close(t)
+}
+
+// MeasureOutage forwards the flow, at a constant rate of pps packets per
+// second, for settle before and after running fn, and returns the
+// duration of the traffic outage during the transition.
+func MeasureOutage(t testing.TB, ate *ondatra.ATEDevice, flow *ondatra.Flow, pps uint64, settle time.Duration, fn func()) time.Duration {
+	t.Helper()
+	ate.Traffic().Start(t, flow)
+	time.Sleep(settle)
+	fn()
+	time.Sleep(settle)
+	ate.Traffic().Stop(t)
+	counters := ate.Telemetry().Flow(flow.Name()).Counters().Get(t)
+	t.Logf("Flow %s sent %d packets and received %d", flow.Name(), counters.GetOutPkts(), counters.GetInPkts())
+	return LossWindow(counters.GetOutPkts(), counters.GetInPkts(), pps)
+}
@@ -140,12 +140,17 @@ func (c *Client) UpdateElectionID(t testing.TB, lowElecID, highElecID uint64) {
 func (c *Client) BecomeLeader(t testing.TB) {
 	t.Helper()
 	t.Logf("Trying to be a master with increasing the election id by one on dut: %s", c.DUT.Name())
-	low, high := c.learnElectionID(t)
+	low, high := nextElectionID(c.learnElectionID(t))
+	c.UpdateElectionID(t, low, high)
+}
+
+// nextElectionID returns the election id following the given one.
+func nextElectionID(low, high uint64) (uint64, uint64) {
 	newLow := low + 1
 	if newLow < low {
 		high++ // Carry to high.
 	}
-	c.UpdateElectionID(t, newLow, high)
+	return newLow, high
 }
 
 // AddNHG adds a NextHopGroupEntry with a given index, and a map of next hop entry indices to the weights,
@@ -170,6 +175,28 @@ func (c *Client) AddNHG(t testing.TB, nhgIndex uint64, nhWeights map[uint64]uint
 	)
 }
 
+// AddNHGWithBackup adds a NextHopGroupEntry with a given index, a map of next hop entry indices to the
+// weights, and the index of its backup next hop group, in a given network instance.
+func (c *Client) AddNHGWithBackup(t testing.TB, nhgIndex uint64, nhWeights map[uint64]uint64, backupNHG uint64, instance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	nhg := fluent.NextHopGroupEntry().WithNetworkInstance(instance).WithID(nhgIndex).WithBackupNHG(backupNHG)
+	for nhIndex, weight := range nhWeights {
+		nhg.AddNextHop(nhIndex, weight)
+	}
+	c.fluentC.Modify().AddEntry(t, nhg)
+	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to add NHG: %v", err)
+	}
+	chk.HasResult(t, c.fluentC.Results(t),
+		fluent.OperationResult().
+			WithNextHopGroupOperation(nhgIndex).
+			WithOperationType(constants.Add).
+			WithProgrammingResult(expectedResult).
+			AsResult(),
+		chk.IgnoreOperationID(),
+	)
+}
+
 // AddNH adds a NextHopEntry with a given index to an address within a given network instance.
 func (c *Client) AddNH(t testing.TB, nhIndex uint64, address, instance string, expectedResult fluent.ProgrammingResult) {
 	t.Helper()
@@ -231,3 +258,41 @@ func (c *Client) DeleteIPv4(t testing.TB, prefix string, instance string, expect
 		chk.IgnoreOperationID(),
 	)
 }
+
+// DeleteNHG deletes a NextHopGroupEntry with a given index within a network instance.
+func (c *Client) DeleteNHG(t testing.TB, nhgIndex uint64, instance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	nhg := fluent.NextHopGroupEntry().WithNetworkInstance(instance).WithID(nhgIndex)
+	c.fluentC.Modify().DeleteEntry(t, nhg)
+	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to delete NHG: %v", err)
+	}
+	chk.HasResult(t, c.fluentC.Results(t),
+		fluent.OperationResult().
+			WithNextHopGroupOperation(nhgIndex).
+			WithOperationType(constants.Delete).
+			WithProgrammingResult(expectedResult).
+			AsResult(),
+		chk.IgnoreOperationID(),
+	)
+}
+
+// DeleteNH deletes a NextHopEntry with a given index within a network instance.
+func (c *Client) DeleteNH(t testing.TB, nhIndex uint64, instance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	c.fluentC.Modify().DeleteEntry(t,
+		fluent.NextHopEntry().
+			WithNetworkInstance(instance).
+			WithIndex(nhIndex))
+	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to delete NH: %v", err)
+	}
+	chk.HasResult(t, c.fluentC.Results(t),
+		fluent.OperationResult().
+			WithNextHopOperation(nhIndex).
+			WithOperationType(constants.Delete).
+			WithProgrammingResult(expectedResult).
+			AsResult(),
+		chk.IgnoreOperationID(),
+	)
+}
@@ -0,0 +1,37 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNextElectionID(t *testing.T) {
+	for _, c := range []struct {
+		low, high         uint64
+		wantLow, wantHigh uint64
+	}{
+		{0, 0, 1, 0},
+		{10, 0, 11, 0},
+		{10, 2, 11, 2},
+		{math.MaxUint64, 0, 0, 1},
+	} {
+		gotLow, gotHigh := nextElectionID(c.low, c.high)
+		if gotLow != c.wantLow || gotHigh != c.wantHigh {
+			t.Errorf("nextElectionID(%d, %d) got (%d, %d), want (%d, %d)", c.low, c.high, gotLow, gotHigh, c.wantLow, c.wantHigh)
+		}
+	}
+}
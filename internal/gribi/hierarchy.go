@@ -0,0 +1,313 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// NextHop is a next hop of a next hop group, with its weight in the group.
+type NextHop struct {
+	Index   uint64
+	Address string
+	Weight  uint64
+}
+
+// Entry is an IPv4 prefix resolved through a next hop group.
+type Entry struct {
+	Prefix   string
+	NHG      uint64
+	NextHops []NextHop
+}
+
+// Hierarchy is the hierarchical pattern shared by the TE profiles: an
+// outer prefix, e.g. a VIP, resolved through a next hop group to next
+// hops whose addresses are in inner prefixes, e.g. those of the backends
+// of the VIP, which are in turn resolved through their own next hop
+// groups.
+//
+// The outer prefix is in its own network instance, e.g. a VRF, while the
+// next hop groups, the next hops and the inner prefixes are all in the
+// network instance of the next hop groups, usually the default one.
+//
+// Usage:
+//
+//	h := &gribi.Hierarchy{
+//	  NetworkInstance:    "VRF-A",
+//	  NHGNetworkInstance: *deviations.DefaultNetworkInstance,
+//	  Outer: gribi.Entry{Prefix: "198.51.100.0/24", NHG: 10, NextHops: []gribi.NextHop{
+//	    {Index: 1, Address: "203.0.113.1", Weight: 1},
+//	  }},
+//	  Inner: []gribi.Entry{{Prefix: "203.0.113.1/32", NHG: 20, NextHops: []gribi.NextHop{
+//	    {Index: 2, Address: "192.0.2.6", Weight: 1},
+//	  }}},
+//	}
+//	if err := h.Validate(); err != nil {
+//	  t.Fatalf("Invalid hierarchy: %v", err)
+//	}
+//	h.Program(t, c, fluent.InstalledInFIB)
+//	defer h.Delete(t, c, fluent.InstalledInFIB)
+type Hierarchy struct {
+	NetworkInstance    string
+	NHGNetworkInstance string
+	Outer              Entry
+	Inner              []Entry
+}
+
+// entries returns the entries of the hierarchy in the order they are
+// programmed, inner first, so that every entry only references entries
+// already programmed.
+func (h *Hierarchy) entries() []Entry {
+	return append(append([]Entry{}, h.Inner...), h.Outer)
+}
+
+// instance returns the network instance of the prefix of the i-th of the
+// entries.
+func (h *Hierarchy) instance(i int) string {
+	if i == len(h.Inner) {
+		return h.NetworkInstance
+	}
+	return h.NHGNetworkInstance
+}
+
+// Validate checks the consistency of the hierarchy: that the next hop
+// group and next hop indices are unique and the weights nonzero, and that
+// each next hop of the outer prefix is resolved by exactly one inner
+// prefix.
+func (h *Hierarchy) Validate() error {
+	if h.NetworkInstance == "" || h.NHGNetworkInstance == "" {
+		return errors.New("network instances must be set")
+	}
+	nhgs := map[uint64]string{}
+	nhs := map[uint64]string{}
+	var inner []*net.IPNet
+	for i, e := range h.entries() {
+		ip, ipNet, err := net.ParseCIDR(e.Prefix)
+		if err != nil || ip.To4() == nil {
+			return fmt.Errorf("prefix %q is not an IPv4 prefix", e.Prefix)
+		}
+		if i < len(h.Inner) {
+			inner = append(inner, ipNet)
+		}
+		if e.NHG == 0 {
+			return fmt.Errorf("%s: next hop group index must be nonzero", e.Prefix)
+		}
+		if p, ok := nhgs[e.NHG]; ok {
+			return fmt.Errorf("%s: next hop group %d already used by %s", e.Prefix, e.NHG, p)
+		}
+		nhgs[e.NHG] = e.Prefix
+		if len(e.NextHops) == 0 {
+			return fmt.Errorf("%s: no next hops", e.Prefix)
+		}
+		for _, nh := range e.NextHops {
+			if nh.Index == 0 || nh.Weight == 0 {
+				return fmt.Errorf("%s: next hop %+v must have a nonzero index and weight", e.Prefix, nh)
+			}
+			if p, ok := nhs[nh.Index]; ok {
+				return fmt.Errorf("%s: next hop %d already used by %s", e.Prefix, nh.Index, p)
+			}
+			nhs[nh.Index] = e.Prefix
+			if ip := net.ParseIP(nh.Address); ip == nil || ip.To4() == nil {
+				return fmt.Errorf("%s: next hop address %q is not an IPv4 address", e.Prefix, nh.Address)
+			}
+		}
+	}
+	for _, nh := range h.Outer.NextHops {
+		var n int
+		for _, ipNet := range inner {
+			if ipNet.Contains(net.ParseIP(nh.Address)) {
+				n++
+			}
+		}
+		if n != 1 {
+			return fmt.Errorf("next hop %s of %s is resolved by %d inner prefixes, want 1", nh.Address, h.Outer.Prefix, n)
+		}
+	}
+	return nil
+}
+
+// Program adds the entries of the hierarchy bottom up: for the inner
+// prefixes and then the outer prefix, the next hops, then the next hop
+// group, then the prefix.  Each operation is awaited, and checked to have
+// the expected result, before the next one referencing it is sent.
+func (h *Hierarchy) Program(t testing.TB, c *Client, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	for i, e := range h.entries() {
+		instance := h.instance(i)
+		weights := map[uint64]uint64{}
+		for _, nh := range e.NextHops {
+			c.AddNH(t, nh.Index, nh.Address, h.NHGNetworkInstance, expectedResult)
+			weights[nh.Index] = nh.Weight
+		}
+		c.AddNHG(t, e.NHG, weights, h.NHGNetworkInstance, expectedResult)
+		c.AddIPv4(t, e.Prefix, e.NHG, instance, h.NHGNetworkInstance, expectedResult)
+	}
+}
+
+// Delete deletes the entries of the hierarchy top down, in the reverse
+// order of Program, so that no entry is deleted while still referenced.
+func (h *Hierarchy) Delete(t testing.TB, c *Client, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	entries := h.entries()
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		instance := h.instance(i)
+		c.DeleteIPv4(t, e.Prefix, instance, expectedResult)
+		c.DeleteNHG(t, e.NHG, h.NHGNetworkInstance, expectedResult)
+		for _, nh := range e.NextHops {
+			c.DeleteNH(t, nh.Index, h.NHGNetworkInstance, expectedResult)
+		}
+	}
+}
+
+// Ref is a next hop group or a next hop of a hierarchy, and the entry
+// referencing it.  Exactly one of NHG and NH is set.
+type Ref struct {
+	NHG uint64
+	NH  uint64
+	By  string
+}
+
+// String returns a description of the reference.
+func (r Ref) String() string {
+	if r.NHG != 0 {
+		return fmt.Sprintf("next hop group %d referenced by %s", r.NHG, r.By)
+	}
+	return fmt.Sprintf("next hop %d referenced by %s", r.NH, r.By)
+}
+
+// Refs returns the next hop groups and next hops of the hierarchy,
+// referenced by the prefixes and the next hop groups respectively, top
+// down: those of the outer prefix first.
+func (h *Hierarchy) Refs() []Ref {
+	var refs []Ref
+	entries := h.entries()
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		refs = append(refs, Ref{NHG: e.NHG, By: e.Prefix})
+		for _, nh := range e.NextHops {
+			refs = append(refs, Ref{NH: nh.Index, By: fmt.Sprintf("next hop group %d", e.NHG)})
+		}
+	}
+	return refs
+}
+
+// DeleteReferenced tries to delete each of the next hop groups and next
+// hops of the programmed hierarchy while they are still referenced, and
+// checks that each delete fails, leaving the hierarchy unchanged.
+func (h *Hierarchy) DeleteReferenced(t testing.TB, c *Client) {
+	t.Helper()
+	for _, r := range h.Refs() {
+		t.Logf("Deleting %v", r)
+		if r.NHG != 0 {
+			c.DeleteNHG(t, r.NHG, h.NHGNetworkInstance, fluent.ProgrammingFailed)
+		} else {
+			c.DeleteNH(t, r.NH, h.NHGNetworkInstance, fluent.ProgrammingFailed)
+		}
+	}
+}
+
+// AFTs returns the AFTs of the network instances of the hierarchy on the
+// DUT, keyed by network instance.  Missing AFTs are returned empty.
+func (h *Hierarchy) AFTs(t testing.TB, dut *ondatra.DUTDevice) map[string]*telemetry.NetworkInstance_Afts {
+	t.Helper()
+	afts := map[string]*telemetry.NetworkInstance_Afts{}
+	for _, ni := range []string{h.NetworkInstance, h.NHGNetworkInstance} {
+		afts[ni] = &telemetry.NetworkInstance_Afts{}
+		if v := dut.Telemetry().NetworkInstance(ni).Afts().Lookup(t); v.IsPresent() {
+			afts[ni] = v.Val(t)
+		}
+	}
+	return afts
+}
+
+// Check verifies that the AFTs are consistent with the hierarchy: that
+// each prefix is installed by gRIBI, resolved through the next hop group
+// it was programmed with, to its next hop addresses with their weights.
+// It returns the inconsistencies found.
+func (h *Hierarchy) Check(afts map[string]*telemetry.NetworkInstance_Afts) []error {
+	nhgAFTs := afts[h.NHGNetworkInstance]
+	if nhgAFTs == nil {
+		return []error{fmt.Errorf("no AFTs for network instance %s", h.NHGNetworkInstance)}
+	}
+	var errs []error
+	for i, e := range h.entries() {
+		instance := h.instance(i)
+		a := afts[instance].GetIpv4Entry(e.Prefix)
+		if a == nil {
+			errs = append(errs, fmt.Errorf("%s: no AFT entry in %s", e.Prefix, instance))
+			continue
+		}
+		if got, want := a.GetOriginProtocol(), telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_GRIBI; got != want {
+			errs = append(errs, fmt.Errorf("%s: origin protocol got %v, want %v", e.Prefix, got, want))
+		}
+		if got := a.GetNextHopGroupNetworkInstance(); got != "" && got != h.NHGNetworkInstance {
+			errs = append(errs, fmt.Errorf("%s: next hop group network instance got %s, want %s", e.Prefix, got, h.NHGNetworkInstance))
+		}
+		g := nhgAFTs.GetNextHopGroup(a.GetNextHopGroup())
+		if g == nil {
+			errs = append(errs, fmt.Errorf("%s: no next hop group %d in %s", e.Prefix, a.GetNextHopGroup(), h.NHGNetworkInstance))
+			continue
+		}
+		if g.ProgrammedId != nil && g.GetProgrammedId() != e.NHG {
+			errs = append(errs, fmt.Errorf("%s: next hop group programmed id got %d, want %d", e.Prefix, g.GetProgrammedId(), e.NHG))
+		}
+		want := map[string]uint64{}
+		for _, nh := range e.NextHops {
+			want[nh.Address] = nh.Weight
+		}
+		got := map[string]uint64{}
+		for index, nh := range g.NextHop {
+			got[nhgAFTs.GetNextHop(index).GetIpAddress()] = nh.GetWeight()
+		}
+		for addr, w := range want {
+			gw, ok := got[addr]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("%s: next hop %s missing", e.Prefix, addr))
+			case gw != 0 && gw != w:
+				// Weights are optional in the AFTs, and only checked if reported.
+				errs = append(errs, fmt.Errorf("%s: next hop %s weight got %d, want %d", e.Prefix, addr, gw, w))
+			}
+		}
+		for addr := range got {
+			if _, ok := want[addr]; !ok {
+				errs = append(errs, fmt.Errorf("%s: unexpected next hop %q", e.Prefix, addr))
+			}
+		}
+	}
+	return errs
+}
+
+// CheckRemoved verifies that none of the prefixes of the hierarchy is
+// left in the AFTs, and returns those that are.
+func (h *Hierarchy) CheckRemoved(afts map[string]*telemetry.NetworkInstance_Afts) []error {
+	var errs []error
+	for i, e := range h.entries() {
+		instance := h.instance(i)
+		if afts[instance].GetIpv4Entry(e.Prefix) != nil {
+			errs = append(errs, fmt.Errorf("%s: still in the AFTs of %s", e.Prefix, instance))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,185 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+const (
+	vrf        = "VRF-A"
+	defaultNI  = "DEFAULT"
+	outerNHG   = 10
+	innerNHG   = 20
+	outerNH    = 1
+	innerNH    = 2
+	innerNH2   = 3
+	vip        = "198.51.100.0/24"
+	vipNH      = "203.0.113.1"
+	backend    = "203.0.113.1/32"
+	backendNH  = "192.0.2.6"
+	backendNH2 = "192.0.2.10"
+)
+
+func newHierarchy() *Hierarchy {
+	return &Hierarchy{
+		NetworkInstance:    vrf,
+		NHGNetworkInstance: defaultNI,
+		Outer:              Entry{Prefix: vip, NHG: outerNHG, NextHops: []NextHop{{outerNH, vipNH, 1}}},
+		Inner: []Entry{{Prefix: backend, NHG: innerNHG, NextHops: []NextHop{
+			{innerNH, backendNH, 1},
+			{innerNH2, backendNH2, 3},
+		}}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		modify  func(h *Hierarchy)
+		wantErr bool
+	}{
+		{"valid", func(h *Hierarchy) {}, false},
+		{"no network instance", func(h *Hierarchy) { h.NetworkInstance = "" }, true},
+		{"IPv6 prefix", func(h *Hierarchy) { h.Outer.Prefix = "2001:db8::/64" }, true},
+		{"duplicate NHG", func(h *Hierarchy) { h.Outer.NHG = innerNHG }, true},
+		{"duplicate NH", func(h *Hierarchy) { h.Outer.NextHops[0].Index = innerNH }, true},
+		{"zero weight", func(h *Hierarchy) { h.Inner[0].NextHops[1].Weight = 0 }, true},
+		{"no next hops", func(h *Hierarchy) { h.Inner[0].NextHops = nil }, true},
+		{"unresolved next hop", func(h *Hierarchy) { h.Outer.NextHops[0].Address = "203.0.113.2" }, true},
+		{"ambiguous next hop", func(h *Hierarchy) {
+			h.Inner = append(h.Inner, Entry{Prefix: "203.0.113.0/24", NHG: 30, NextHops: []NextHop{{4, backendNH, 1}}})
+		}, true},
+	} {
+		h := newHierarchy()
+		c.modify(h)
+		if err := h.Validate(); (err != nil) != c.wantErr {
+			t.Errorf("%s: Validate() got error %v, want error %v", c.desc, err, c.wantErr)
+		}
+	}
+}
+
+// buildAFTs returns the AFTs of the hierarchy as a DUT would report them,
+// with device assigned indices.
+func buildAFTs(h *Hierarchy) map[string]*telemetry.NetworkInstance_Afts {
+	afts := map[string]*telemetry.NetworkInstance_Afts{
+		h.NetworkInstance:    {},
+		h.NHGNetworkInstance: {},
+	}
+	nhgAFTs := afts[h.NHGNetworkInstance]
+	for i, e := range h.entries() {
+		id := uint64(1000 + i)
+		a := afts[h.instance(i)].GetOrCreateIpv4Entry(e.Prefix)
+		a.NextHopGroup = ygot.Uint64(id)
+		a.NextHopGroupNetworkInstance = ygot.String(h.NHGNetworkInstance)
+		a.OriginProtocol = telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_GRIBI
+		g := nhgAFTs.GetOrCreateNextHopGroup(id)
+		g.ProgrammedId = ygot.Uint64(e.NHG)
+		for _, nh := range e.NextHops {
+			index := 100 + nh.Index
+			g.GetOrCreateNextHop(index).Weight = ygot.Uint64(nh.Weight)
+			nhgAFTs.GetOrCreateNextHop(index).IpAddress = ygot.String(nh.Address)
+		}
+	}
+	return afts
+}
+
+func TestCheck(t *testing.T) {
+	for _, c := range []struct {
+		desc     string
+		modify   func(afts map[string]*telemetry.NetworkInstance_Afts)
+		wantErrs int
+	}{
+		{"consistent", func(afts map[string]*telemetry.NetworkInstance_Afts) {}, 0},
+		{"weights not reported", func(afts map[string]*telemetry.NetworkInstance_Afts) {
+			for _, nh := range afts[defaultNI].GetNextHopGroup(1000).NextHop {
+				nh.Weight = nil
+			}
+		}, 0},
+		{"outer missing", func(afts map[string]*telemetry.NetworkInstance_Afts) {
+			afts[vrf].DeleteIpv4Entry(vip)
+		}, 1},
+		{"wrong NHG network instance", func(afts map[string]*telemetry.NetworkInstance_Afts) {
+			afts[vrf].GetIpv4Entry(vip).NextHopGroupNetworkInstance = ygot.String(vrf)
+		}, 1},
+		{"wrong programmed id", func(afts map[string]*telemetry.NetworkInstance_Afts) {
+			afts[defaultNI].GetNextHopGroup(1001).ProgrammedId = ygot.Uint64(innerNHG)
+		}, 1},
+		{"wrong weight", func(afts map[string]*telemetry.NetworkInstance_Afts) {
+			afts[defaultNI].GetNextHopGroup(1000).GetNextHop(100 + innerNH2).Weight = ygot.Uint64(1)
+		}, 1},
+		{"next hop missing", func(afts map[string]*telemetry.NetworkInstance_Afts) {
+			afts[defaultNI].GetNextHopGroup(1000).DeleteNextHop(100 + innerNH2)
+		}, 1},
+		{"unexpected next hop", func(afts map[string]*telemetry.NetworkInstance_Afts) {
+			afts[defaultNI].GetNextHopGroup(1001).GetOrCreateNextHop(100 + innerNH)
+		}, 1},
+		{"not gRIBI", func(afts map[string]*telemetry.NetworkInstance_Afts) {
+			afts[defaultNI].GetIpv4Entry(backend).OriginProtocol = telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC
+		}, 1},
+	} {
+		h := newHierarchy()
+		afts := buildAFTs(h)
+		c.modify(afts)
+		if got := h.Check(afts); len(got) != c.wantErrs {
+			t.Errorf("%s: Check() got errors %v, want %d", c.desc, got, c.wantErrs)
+		}
+	}
+}
+
+func TestCheckRemoved(t *testing.T) {
+	h := newHierarchy()
+	afts := buildAFTs(h)
+	if got := h.CheckRemoved(afts); len(got) != 2 {
+		t.Errorf("CheckRemoved() got errors %v, want 2", got)
+	}
+	afts[vrf].DeleteIpv4Entry(vip)
+	afts[defaultNI].DeleteIpv4Entry(backend)
+	if got := h.CheckRemoved(afts); len(got) != 0 {
+		t.Errorf("CheckRemoved() got errors %v, want none", got)
+	}
+}
+
+func TestRefs(t *testing.T) {
+	want := []Ref{
+		{NHG: outerNHG, By: vip},
+		{NH: outerNH, By: "next hop group 10"},
+		{NHG: innerNHG, By: backend},
+		{NH: innerNH, By: "next hop group 20"},
+		{NH: innerNH2, By: "next hop group 20"},
+	}
+	if diff := cmp.Diff(want, newHierarchy().Refs()); diff != "" {
+		t.Errorf("Refs() -want, +got:\n%s", diff)
+	}
+}
+
+func TestRefString(t *testing.T) {
+	for _, c := range []struct {
+		ref  Ref
+		want string
+	}{
+		{Ref{NHG: outerNHG, By: vip}, "next hop group 10 referenced by 198.51.100.0/24"},
+		{Ref{NH: innerNH, By: "next hop group 20"}, "next hop 2 referenced by next hop group 20"},
+	} {
+		if got := c.ref.String(); got != c.want {
+			t.Errorf("String() got %q, want %q", got, c.want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/openconfig/gribigo/chk"
+	"github.com/openconfig/gribigo/constants"
+	"github.com/openconfig/gribigo/fluent"
+
+	aftpb "github.com/openconfig/gribi/v1/proto/gribi_aft"
+	spb "github.com/openconfig/gribi/v1/proto/service"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+	wpb "github.com/openconfig/ygot/proto/ywrapper"
+)
+
+// Attributes are the attributes a controller sets on an IPv4 entry to
+// correlate it with its own state: the opaque metadata of the entry, and
+// the color of its next hop group.  The DUT stores both as is, and returns
+// them in the Get RPC and in the AFTs.
+type Attributes struct {
+	Prefix   string
+	NHG      uint64
+	Metadata []byte
+	// Color is the color of the next hop group, or 0 for none.
+	Color uint64
+}
+
+// coloredNHG is a next hop group entry with a color, which the fluent
+// builder cannot set.
+type coloredNHG struct {
+	fluent.GRIBIEntry
+	color uint64
+}
+
+// setColor sets the color of the next hop group.
+func setColor(nhg *aftpb.Afts_NextHopGroupKey, color uint64) error {
+	if nhg.GetNextHopGroup() == nil {
+		return fmt.Errorf("entry %d is not a next hop group", nhg.GetId())
+	}
+	nhg.NextHopGroup.Color = &wpb.UintValue{Value: color}
+	return nil
+}
+
+// OpProto implements the fluent.GRIBIEntry interface.
+func (e *coloredNHG) OpProto() (*spb.AFTOperation, error) {
+	op, err := e.GRIBIEntry.OpProto()
+	if err != nil {
+		return nil, err
+	}
+	if err := setColor(op.GetNextHopGroup(), e.color); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// EntryProto implements the fluent.GRIBIEntry interface.
+func (e *coloredNHG) EntryProto() (*spb.AFTEntry, error) {
+	entry, err := e.GRIBIEntry.EntryProto()
+	if err != nil {
+		return nil, err
+	}
+	if err := setColor(entry.GetNextHopGroup(), e.color); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// AddNHGWithColor adds a NextHopGroupEntry with a given index, a map of next hop entry indices to the
+// weights, and a color, in a given network instance.
+func (c *Client) AddNHGWithColor(t testing.TB, nhgIndex uint64, nhWeights map[uint64]uint64, color uint64, instance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	nhg := fluent.NextHopGroupEntry().WithNetworkInstance(instance).WithID(nhgIndex)
+	for nhIndex, weight := range nhWeights {
+		nhg.AddNextHop(nhIndex, weight)
+	}
+	c.fluentC.Modify().AddEntry(t, &coloredNHG{GRIBIEntry: nhg, color: color})
+	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to add NHG: %v", err)
+	}
+	chk.HasResult(t, c.fluentC.Results(t),
+		fluent.OperationResult().
+			WithNextHopGroupOperation(nhgIndex).
+			WithOperationType(constants.Add).
+			WithProgrammingResult(expectedResult).
+			AsResult(),
+		chk.IgnoreOperationID(),
+	)
+}
+
+// AddIPv4WithMetadata adds an IPv4Entry mapping a prefix to a given next hop group index within a given
+// network instance, with opaque metadata.
+func (c *Client) AddIPv4WithMetadata(t testing.TB, prefix string, nhgIndex uint64, instance, nhgInstance string, metadata []byte, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	ipv4Entry := fluent.IPv4Entry().WithPrefix(prefix).
+		WithNetworkInstance(instance).
+		WithNextHopGroup(nhgIndex).
+		WithMetadata(metadata)
+	if nhgInstance != "" && nhgInstance != instance {
+		ipv4Entry.WithNextHopGroupNetworkInstance(nhgInstance)
+	}
+	c.fluentC.Modify().AddEntry(t, ipv4Entry)
+	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to add IPv4: %v", err)
+	}
+	chk.HasResult(t, c.fluentC.Results(t),
+		fluent.OperationResult().
+			WithIPv4Operation(prefix).
+			WithOperationType(constants.Add).
+			WithProgrammingResult(expectedResult).
+			AsResult(),
+		chk.IgnoreOperationID(),
+	)
+}
+
+// sortAttributes sorts the attributes by prefix.
+func sortAttributes(attrs []*Attributes) []*Attributes {
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Prefix < attrs[j].Prefix })
+	return attrs
+}
+
+// GetAttributes returns the attributes of the IPv4 entries of a Get
+// response of all the AFTs, sorted by prefix.
+func GetAttributes(resp *spb.GetResponse) []*Attributes {
+	colors := map[uint64]uint64{}
+	for _, e := range resp.GetEntry() {
+		if nhg := e.GetNextHopGroup(); nhg != nil {
+			colors[nhg.GetId()] = nhg.GetNextHopGroup().GetColor().GetValue()
+		}
+	}
+	var attrs []*Attributes
+	for _, e := range resp.GetEntry() {
+		ipv4 := e.GetIpv4()
+		if ipv4 == nil {
+			continue
+		}
+		nhg := ipv4.GetIpv4Entry().GetNextHopGroup().GetValue()
+		attrs = append(attrs, &Attributes{
+			Prefix:   ipv4.GetPrefix(),
+			NHG:      nhg,
+			Metadata: ipv4.GetIpv4Entry().GetEntryMetadata().GetValue(),
+			Color:    colors[nhg],
+		})
+	}
+	return sortAttributes(attrs)
+}
+
+// AFTAttributes returns the attributes of the IPv4 entries installed by
+// gRIBI in the AFTs, sorted by prefix.  The next hop groups are identified
+// by the index they were programmed with, or 0 when the DUT does not
+// report it.
+func AFTAttributes(afts *telemetry.NetworkInstance_Afts) []*Attributes {
+	var attrs []*Attributes
+	for prefix, e := range afts.Ipv4Entry {
+		if e.GetOriginProtocol() != telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_GRIBI {
+			continue
+		}
+		a := &Attributes{Prefix: prefix, Metadata: e.EntryMetadata}
+		if g := afts.GetNextHopGroup(e.GetNextHopGroup()); g != nil {
+			a.NHG = g.GetProgrammedId()
+			a.Color = g.GetColor()
+		}
+		attrs = append(attrs, a)
+	}
+	return sortAttributes(attrs)
+}
+
+// CheckAttributes checks that the attributes read back from the DUT are
+// those of the entries programmed, and returns the differences found.  The
+// next hop groups read back as 0 are not checked.
+func CheckAttributes(want, got []*Attributes) []error {
+	gotByPrefix := map[string]*Attributes{}
+	for _, a := range got {
+		gotByPrefix[a.Prefix] = a
+	}
+	var errs []error
+	for _, w := range want {
+		g, ok := gotByPrefix[w.Prefix]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: missing", w.Prefix))
+			continue
+		}
+		delete(gotByPrefix, w.Prefix)
+		if g.NHG != 0 && g.NHG != w.NHG {
+			errs = append(errs, fmt.Errorf("%s: next hop group got %d, want %d", w.Prefix, g.NHG, w.NHG))
+		}
+		if !bytes.Equal(g.Metadata, w.Metadata) {
+			errs = append(errs, fmt.Errorf("%s: metadata got %x, want %x", w.Prefix, g.Metadata, w.Metadata))
+		}
+		if g.Color != w.Color {
+			errs = append(errs, fmt.Errorf("%s: next hop group color got %d, want %d", w.Prefix, g.Color, w.Color))
+		}
+	}
+	for _, g := range got {
+		if _, ok := gotByPrefix[g.Prefix]; ok {
+			errs = append(errs, fmt.Errorf("%s: unexpected entry", g.Prefix))
+		}
+	}
+	return errs
+}
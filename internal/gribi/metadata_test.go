@@ -0,0 +1,144 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ygot/ygot"
+
+	aftpb "github.com/openconfig/gribi/v1/proto/gribi_aft"
+	spb "github.com/openconfig/gribi/v1/proto/service"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+	wpb "github.com/openconfig/ygot/proto/ywrapper"
+)
+
+func TestColoredNHG(t *testing.T) {
+	e := &coloredNHG{GRIBIEntry: fluent.NextHopGroupEntry().WithID(11).AddNextHop(1, 1), color: 7}
+	op, err := e.OpProto()
+	if err != nil {
+		t.Fatalf("OpProto() got error %v", err)
+	}
+	if got := op.GetNextHopGroup().GetNextHopGroup().GetColor().GetValue(); got != 7 {
+		t.Errorf("OpProto() color got %d, want 7", got)
+	}
+	entry, err := e.EntryProto()
+	if err != nil {
+		t.Fatalf("EntryProto() got error %v", err)
+	}
+	if got := entry.GetNextHopGroup().GetNextHopGroup().GetColor().GetValue(); got != 7 {
+		t.Errorf("EntryProto() color got %d, want 7", got)
+	}
+
+	notNHG := &coloredNHG{GRIBIEntry: fluent.IPv4Entry().WithPrefix("198.51.100.0/24"), color: 7}
+	if _, err := notNHG.OpProto(); err == nil {
+		t.Errorf("OpProto() of an IPv4 entry got no error")
+	}
+}
+
+func TestGetAttributes(t *testing.T) {
+	ipv4 := func(prefix string, nhg uint64, metadata []byte) *spb.AFTEntry {
+		e := &aftpb.Afts_Ipv4Entry{NextHopGroup: &wpb.UintValue{Value: nhg}}
+		if metadata != nil {
+			e.EntryMetadata = &wpb.BytesValue{Value: metadata}
+		}
+		return &spb.AFTEntry{Entry: &spb.AFTEntry_Ipv4{Ipv4: &aftpb.Afts_Ipv4EntryKey{Prefix: prefix, Ipv4Entry: e}}}
+	}
+	resp := &spb.GetResponse{Entry: []*spb.AFTEntry{
+		ipv4("203.0.113.1/32", 12, nil),
+		{Entry: &spb.AFTEntry_NextHopGroup{NextHopGroup: &aftpb.Afts_NextHopGroupKey{
+			Id:           11,
+			NextHopGroup: &aftpb.Afts_NextHopGroup{Color: &wpb.UintValue{Value: 7}},
+		}}},
+		ipv4("198.51.100.0/24", 11, []byte("ctrl-1")),
+	}}
+	want := []*Attributes{
+		{Prefix: "198.51.100.0/24", NHG: 11, Metadata: []byte("ctrl-1"), Color: 7},
+		{Prefix: "203.0.113.1/32", NHG: 12},
+	}
+	if diff := cmp.Diff(want, GetAttributes(resp)); diff != "" {
+		t.Errorf("GetAttributes() -want, +got:\n%s", diff)
+	}
+}
+
+func TestAFTAttributes(t *testing.T) {
+	afts := &telemetry.NetworkInstance_Afts{}
+	e := afts.GetOrCreateIpv4Entry("198.51.100.0/24")
+	e.OriginProtocol = telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_GRIBI
+	e.NextHopGroup = ygot.Uint64(1001)
+	e.EntryMetadata = telemetry.Binary("ctrl-1")
+	g := afts.GetOrCreateNextHopGroup(1001)
+	g.ProgrammedId = ygot.Uint64(11)
+	g.Color = ygot.Uint64(7)
+	e = afts.GetOrCreateIpv4Entry("203.0.113.1/32")
+	e.OriginProtocol = telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_GRIBI
+	e.NextHopGroup = ygot.Uint64(1002)
+	afts.GetOrCreateNextHopGroup(1002)
+	afts.GetOrCreateIpv4Entry("192.0.2.0/30").OriginProtocol = telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_DIRECTLY_CONNECTED
+
+	want := []*Attributes{
+		{Prefix: "198.51.100.0/24", NHG: 11, Metadata: []byte("ctrl-1"), Color: 7},
+		{Prefix: "203.0.113.1/32"},
+	}
+	if diff := cmp.Diff(want, AFTAttributes(afts)); diff != "" {
+		t.Errorf("AFTAttributes() -want, +got:\n%s", diff)
+	}
+}
+
+func TestCheckAttributes(t *testing.T) {
+	want := []*Attributes{
+		{Prefix: "203.0.113.1/32", NHG: 12},
+		{Prefix: "198.51.100.0/24", NHG: 11, Metadata: []byte("ctrl-1"), Color: 7},
+	}
+	for _, tc := range []struct {
+		desc     string
+		got      []*Attributes
+		wantErrs int
+	}{{
+		desc: "same",
+		got: []*Attributes{
+			{Prefix: "198.51.100.0/24", NHG: 11, Metadata: []byte("ctrl-1"), Color: 7},
+			{Prefix: "203.0.113.1/32", NHG: 12, Metadata: []byte{}},
+		},
+	}, {
+		desc: "next hop groups not reported",
+		got: []*Attributes{
+			{Prefix: "198.51.100.0/24", Metadata: []byte("ctrl-1"), Color: 7},
+			{Prefix: "203.0.113.1/32"},
+		},
+	}, {
+		desc: "differences",
+		got: []*Attributes{
+			{Prefix: "198.51.100.0/24", NHG: 12, Metadata: []byte("ctrl-2")},
+			{Prefix: "203.0.113.1/32", NHG: 12},
+		},
+		wantErrs: 3,
+	}, {
+		desc: "missing and unexpected",
+		got: []*Attributes{
+			{Prefix: "198.51.100.0/24", NHG: 11, Metadata: []byte("ctrl-1"), Color: 7},
+			{Prefix: "203.0.113.2/32", NHG: 12},
+		},
+		wantErrs: 2,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if errs := CheckAttributes(want, tc.got); len(errs) != tc.wantErrs {
+				t.Errorf("CheckAttributes() got errors %v, want %d", errs, tc.wantErrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gribigo/chk"
+	"github.com/openconfig/gribigo/constants"
+	"github.com/openconfig/gribigo/fluent"
+)
+
+// AddIPv4Batch adds IPv4Entries mapping prefixes to a given next hop group index within a given network
+// instance, in a single ModifyRequest.
+func (c *Client) AddIPv4Batch(t testing.TB, prefixes []string, nhgIndex uint64, instance, nhgInstance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	var entries []fluent.GRIBIEntry
+	for _, prefix := range prefixes {
+		e := fluent.IPv4Entry().WithPrefix(prefix).
+			WithNetworkInstance(instance).
+			WithNextHopGroup(nhgIndex)
+		if nhgInstance != "" && nhgInstance != instance {
+			e.WithNextHopGroupNetworkInstance(nhgInstance)
+		}
+		entries = append(entries, e)
+	}
+	c.fluentC.Modify().AddEntry(t, entries...)
+	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to add IPv4 batch: %v", err)
+	}
+	for _, prefix := range prefixes {
+		chk.HasResult(t, c.fluentC.Results(t),
+			fluent.OperationResult().
+				WithIPv4Operation(prefix).
+				WithOperationType(constants.Add).
+				WithProgrammingResult(expectedResult).
+				AsResult(),
+			chk.IgnoreOperationID(),
+		)
+	}
+}
+
+// Migration shifts the traffic to a set of prefixes gracefully from their
+// next hop group to another one, make before break.  The new next hop group
+// must already be installed, and the prefixes are implicitly replaced to
+// point to it in batches.  The traffic to the prefixes is measured around
+// each batch, so that an outage is attributed to the batch causing it.
+//
+// Usage:
+//
+//	m := &gribi.Migration{
+//	  NetworkInstance: ni,
+//	  Prefixes:        prefixes,
+//	  ToNHG:           2,
+//	  BatchSize:       8,
+//	  Settle:          5 * time.Second,
+//	}
+//	ate.Traffic().Start(t, flow)
+//	batches := m.Run(t, c, counters)
+//	ate.Traffic().Stop(t)
+//	for _, err := range gribi.CheckBatches(batches, pps, 50*time.Millisecond) {
+//	  t.Error(err)
+//	}
+type Migration struct {
+	NetworkInstance    string
+	NHGNetworkInstance string
+	Prefixes           []string
+	// ToNHG is the index of the next hop group the prefixes migrate to.
+	ToNHG uint64
+	// BatchSize is the number of prefixes migrated at once, all of them if
+	// it is not positive.
+	BatchSize int
+	// Settle is how long the traffic is measured after each batch.
+	Settle time.Duration
+}
+
+// Counters returns the cumulative packets sent to and received from the
+// prefixes of a migration, typically the counters of an ATE flow.
+type Counters func(t testing.TB) (txPkts, rxPkts uint64)
+
+// Batch is the traffic measured around the migration of a batch of
+// prefixes.
+type Batch struct {
+	Prefixes []string
+	TxPkts   uint64
+	RxPkts   uint64
+}
+
+// Batches returns the batches of prefixes of the migration, in order.
+func (m *Migration) Batches() [][]string {
+	size := m.BatchSize
+	if size <= 0 {
+		size = len(m.Prefixes)
+	}
+	var batches [][]string
+	for start := 0; start < len(m.Prefixes); start += size {
+		end := start + size
+		if end > len(m.Prefixes) {
+			end = len(m.Prefixes)
+		}
+		batches = append(batches, m.Prefixes[start:end])
+	}
+	return batches
+}
+
+// Run migrates the prefixes batch by batch through the client, which must
+// be the leader, while traffic to them is running.  It returns the traffic
+// measured around each batch.
+func (m *Migration) Run(t testing.TB, c *Client, counters Counters) []Batch {
+	t.Helper()
+	return m.run(t, counters, func(prefixes []string) {
+		t.Logf("Migrating %d prefixes from %s to next hop group %d", len(prefixes), prefixes[0], m.ToNHG)
+		c.AddIPv4Batch(t, prefixes, m.ToNHG, m.NetworkInstance, m.NHGNetworkInstance, fluent.InstalledInRIB)
+	})
+}
+
+// run measures the counters around migrate for each batch.
+func (m *Migration) run(t testing.TB, counters Counters, migrate func(prefixes []string)) []Batch {
+	var batches []Batch
+	tx, rx := counters(t)
+	for _, prefixes := range m.Batches() {
+		migrate(prefixes)
+		time.Sleep(m.Settle)
+		nextTx, nextRx := counters(t)
+		batches = append(batches, Batch{Prefixes: prefixes, TxPkts: nextTx - tx, RxPkts: nextRx - rx})
+		tx, rx = nextTx, nextRx
+	}
+	return batches
+}
+
+// CheckBatches checks that the outage of the traffic at a constant rate in
+// packets per second is at most max around each batch, or the
+// tolerance_max_convergence argument if set.
+func CheckBatches(batches []Batch, pps uint64, max time.Duration) []error {
+	var errs []error
+	for i, b := range batches {
+		if err := CheckLossWindow(b.TxPkts, b.RxPkts, pps, max); err != nil {
+			errs = append(errs, fmt.Errorf("batch %d of %d prefixes from %s: %w", i+1, len(b.Prefixes), b.Prefixes[0], err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var migrationPrefixes = []string{"198.51.100.0/32", "198.51.100.1/32", "198.51.100.2/32", "198.51.100.3/32", "198.51.100.4/32"}
+
+func TestBatches(t *testing.T) {
+	for _, c := range []struct {
+		size int
+		want [][]string
+	}{
+		{2, [][]string{migrationPrefixes[:2], migrationPrefixes[2:4], migrationPrefixes[4:]}},
+		{5, [][]string{migrationPrefixes}},
+		{8, [][]string{migrationPrefixes}},
+		{0, [][]string{migrationPrefixes}},
+	} {
+		m := &Migration{Prefixes: migrationPrefixes, BatchSize: c.size}
+		if diff := cmp.Diff(c.want, m.Batches()); diff != "" {
+			t.Errorf("Batches() with size %d -want, +got:\n%s", c.size, diff)
+		}
+	}
+}
+
+func TestMigrationRun(t *testing.T) {
+	m := &Migration{Prefixes: migrationPrefixes, BatchSize: 2}
+	// The flow sends 1000 packets per batch, and the second batch loses 200
+	// of them.
+	var tx, rx uint64
+	counters := func(t testing.TB) (uint64, uint64) { return tx, rx }
+	var migrated [][]string
+	batches := m.run(t, counters, func(prefixes []string) {
+		migrated = append(migrated, prefixes)
+		tx += 1000
+		rx += 1000
+		if len(migrated) == 2 {
+			rx -= 200
+		}
+	})
+	if diff := cmp.Diff(m.Batches(), migrated); diff != "" {
+		t.Errorf("run() migrated -want, +got:\n%s", diff)
+	}
+	want := []Batch{
+		{Prefixes: migrationPrefixes[:2], TxPkts: 1000, RxPkts: 1000},
+		{Prefixes: migrationPrefixes[2:4], TxPkts: 1000, RxPkts: 800},
+		{Prefixes: migrationPrefixes[4:], TxPkts: 1000, RxPkts: 1000},
+	}
+	if diff := cmp.Diff(want, batches); diff != "" {
+		t.Errorf("run() -want, +got:\n%s", diff)
+	}
+	if errs := CheckBatches(batches, 1000, 100*time.Millisecond); len(errs) != 1 {
+		t.Errorf("CheckBatches() got errors %v, want 1", errs)
+	}
+}
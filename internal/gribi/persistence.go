@@ -0,0 +1,130 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/gribigo/fluent"
+	"github.com/openconfig/ondatra"
+
+	spb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+// Persistence verifies that the IPv4 entries a client programmed with
+// PERSISTENCE set to PRESERVE survive the client disconnecting without a
+// flush.
+//
+// Usage:
+//
+//	p := &gribi.Persistence{Client: c, NetworkInstance: ni, Prefixes: prefixes, Window: time.Minute}
+//	p.Disconnect(t)
+//	for _, err := range p.CheckWindow(t, dut) {
+//	  t.Error(err)
+//	}
+//	if err := p.Reconnect(t); err != nil {
+//	  t.Fatalf("Could not reconnect: %v", err)
+//	}
+//	if err := p.CheckGet(t); err != nil {
+//	  t.Error(err)
+//	}
+type Persistence struct {
+	Client          *Client
+	NetworkInstance string
+	Prefixes        []string
+	// Window is how long the entries are checked to remain installed
+	// after the client disconnects.
+	Window time.Duration
+}
+
+// Disconnect closes the connection of the client, without flushing its
+// entries.
+func (p *Persistence) Disconnect(t testing.TB) {
+	t.Helper()
+	if !p.Client.Persistence {
+		t.Fatalf("Client of %s does not preserve its entries", p.Client.DUT.Name())
+	}
+	p.Client.Close(t)
+}
+
+// CheckWindow checks, for the window, that the prefixes remain in the
+// AFTs of the DUT, and returns an error for each prefix found missing.
+func (p *Persistence) CheckWindow(t testing.TB, dut *ondatra.DUTDevice) []error {
+	t.Helper()
+	afts := dut.Telemetry().NetworkInstance(p.NetworkInstance).Afts()
+	missing := map[string]time.Duration{}
+	start := time.Now()
+	for {
+		for _, prefix := range p.Prefixes {
+			if _, ok := missing[prefix]; !ok && !afts.Ipv4Entry(prefix).Lookup(t).IsPresent() {
+				missing[prefix] = time.Since(start)
+			}
+		}
+		if time.Since(start) >= p.Window {
+			break
+		}
+		time.Sleep(5 * time.Second)
+	}
+	var errs []error
+	for _, prefix := range p.Prefixes {
+		if d, ok := missing[prefix]; ok {
+			errs = append(errs, fmt.Errorf("%s missing from the AFTs %v after the client disconnected", prefix, d.Round(time.Second)))
+		}
+	}
+	return errs
+}
+
+// Reconnect connects the client again, and makes it the leader.
+func (p *Persistence) Reconnect(t testing.TB) error {
+	t.Helper()
+	if err := p.Client.Start(t); err != nil {
+		return err
+	}
+	p.Client.BecomeLeader(t)
+	return nil
+}
+
+// CheckGet checks that the IPv4 entries returned by the Get RPC of the
+// client in the network instance are the prefixes.
+func (p *Persistence) CheckGet(t testing.TB) error {
+	t.Helper()
+	resp, err := p.Client.Fluent(t).Get().WithNetworkInstance(p.NetworkInstance).WithAFT(fluent.IPv4).Send()
+	if err != nil {
+		return fmt.Errorf("cannot Get: %w", err)
+	}
+	want := append([]string{}, p.Prefixes...)
+	sort.Strings(want)
+	if diff := cmp.Diff(want, IPv4Prefixes(resp)); diff != "" {
+		return fmt.Errorf("persisted prefixes -want, +got:\n%s", diff)
+	}
+	return nil
+}
+
+// IPv4Prefixes returns the prefixes of the IPv4 entries of a Get
+// response, sorted.
+func IPv4Prefixes(resp *spb.GetResponse) []string {
+	var prefixes []string
+	for _, e := range resp.GetEntry() {
+		if prefix := e.GetIpv4().GetPrefix(); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
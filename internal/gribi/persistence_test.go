@@ -0,0 +1,42 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	aftpb "github.com/openconfig/gribi/v1/proto/gribi_aft"
+	spb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+func TestIPv4Prefixes(t *testing.T) {
+	ipv4 := func(prefix string) *spb.AFTEntry {
+		return &spb.AFTEntry{Entry: &spb.AFTEntry_Ipv4{Ipv4: &aftpb.Afts_Ipv4EntryKey{Prefix: prefix}}}
+	}
+	resp := &spb.GetResponse{Entry: []*spb.AFTEntry{
+		ipv4("203.0.113.1/32"),
+		{Entry: &spb.AFTEntry_NextHopGroup{NextHopGroup: &aftpb.Afts_NextHopGroupKey{Id: 42}}},
+		ipv4("198.51.100.0/24"),
+	}}
+	want := []string{"198.51.100.0/24", "203.0.113.1/32"}
+	if diff := cmp.Diff(want, IPv4Prefixes(resp)); diff != "" {
+		t.Errorf("IPv4Prefixes() -want, +got:\n%s", diff)
+	}
+	if got := IPv4Prefixes(&spb.GetResponse{}); len(got) != 0 {
+		t.Errorf("IPv4Prefixes() of an empty response got %v, want none", got)
+	}
+}
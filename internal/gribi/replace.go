@@ -0,0 +1,100 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/tolerance"
+	"github.com/openconfig/gribigo/chk"
+	"github.com/openconfig/gribigo/constants"
+	"github.com/openconfig/gribigo/fluent"
+)
+
+// An entry that already exists is modified in place either by an ADD
+// operation, an implicit replace, or by a REPLACE operation.  AddIPv4 and
+// AddNHG perform implicit replaces of existing entries, and ReplaceIPv4
+// and ReplaceNHG explicit ones.
+
+// ReplaceIPv4 replaces the next hop group of an existing IPv4Entry within a given network instance.
+func (c *Client) ReplaceIPv4(t testing.TB, prefix string, nhgIndex uint64, instance, nhgInstance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	ipv4Entry := fluent.IPv4Entry().WithPrefix(prefix).
+		WithNetworkInstance(instance).
+		WithNextHopGroup(nhgIndex)
+	if nhgInstance != "" && nhgInstance != instance {
+		ipv4Entry.WithNextHopGroupNetworkInstance(nhgInstance)
+	}
+	c.fluentC.Modify().ReplaceEntry(t, ipv4Entry)
+	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to replace IPv4: %v", err)
+	}
+	chk.HasResult(t, c.fluentC.Results(t),
+		fluent.OperationResult().
+			WithIPv4Operation(prefix).
+			WithOperationType(constants.Replace).
+			WithProgrammingResult(expectedResult).
+			AsResult(),
+		chk.IgnoreOperationID(),
+	)
+}
+
+// ReplaceNHG replaces the next hops and weights of an existing NextHopGroupEntry with a given index,
+// in a given network instance.
+func (c *Client) ReplaceNHG(t testing.TB, nhgIndex uint64, nhWeights map[uint64]uint64, instance string, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	nhg := fluent.NextHopGroupEntry().WithNetworkInstance(instance).WithID(nhgIndex)
+	for nhIndex, weight := range nhWeights {
+		nhg.AddNextHop(nhIndex, weight)
+	}
+	c.fluentC.Modify().ReplaceEntry(t, nhg)
+	if err := c.AwaitTimeout(context.Background(), t, timeout); err != nil {
+		t.Fatalf("Error waiting to replace NHG: %v", err)
+	}
+	chk.HasResult(t, c.fluentC.Results(t),
+		fluent.OperationResult().
+			WithNextHopGroupOperation(nhgIndex).
+			WithOperationType(constants.Replace).
+			WithProgrammingResult(expectedResult).
+			AsResult(),
+		chk.IgnoreOperationID(),
+	)
+}
+
+// LossWindow returns the duration of the outage of a flow at a constant
+// rate in packets per second, given the packets it sent and received.
+func LossWindow(txPkts, rxPkts, pps uint64) time.Duration {
+	if rxPkts >= txPkts || pps == 0 {
+		return 0
+	}
+	return time.Duration(txPkts-rxPkts) * time.Second / time.Duration(pps)
+}
+
+// CheckLossWindow checks that the outage of a flow at a constant rate in
+// packets per second, while its entries are modified, is at most max, or
+// the tolerance_max_convergence argument if set.
+func CheckLossWindow(txPkts, rxPkts, pps uint64, max time.Duration) error {
+	max = tolerance.MaxConvergence(max)
+	if txPkts == 0 {
+		return fmt.Errorf("no packets sent")
+	}
+	if w := LossWindow(txPkts, rxPkts, pps); w > max {
+		return fmt.Errorf("lost %d of %d packets at %d pps, a loss window of %v, want at most %v", txPkts-rxPkts, txPkts, pps, w, max)
+	}
+	return nil
+}
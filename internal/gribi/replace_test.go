@@ -0,0 +1,55 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gribi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLossWindow(t *testing.T) {
+	for _, c := range []struct {
+		tx, rx, pps uint64
+		want        time.Duration
+	}{
+		{10000, 10000, 1000, 0},
+		{10000, 9950, 1000, 50 * time.Millisecond},
+		{10000, 9000, 10000, 100 * time.Millisecond},
+		{10000, 10010, 1000, 0},
+		{10000, 0, 0, 0},
+	} {
+		if got := LossWindow(c.tx, c.rx, c.pps); got != c.want {
+			t.Errorf("LossWindow(%d, %d, %d) got %v, want %v", c.tx, c.rx, c.pps, got, c.want)
+		}
+	}
+}
+
+func TestCheckLossWindow(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		tx, rx  uint64
+		wantErr bool
+	}{
+		{"no loss", 10000, 10000, false},
+		{"within window", 10000, 9950, false},
+		{"at window", 10000, 9900, false},
+		{"beyond window", 10000, 9899, true},
+		{"nothing sent", 0, 0, true},
+	} {
+		if err := CheckLossWindow(c.tx, c.rx, 1000, 100*time.Millisecond); (err != nil) != c.wantErr {
+			t.Errorf("%s: CheckLossWindow() got error %v, want error %v", c.desc, err, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package holdtime provides helpers to configure the hold time of an
+// interface, also known as carrier delay, and to verify from telemetry
+// that it suppresses the flaps of the link shorter than the hold time.
+//
+// The hold-time down delays reporting the interface down after it lost
+// the carrier, and the hold-time up delays reporting it up after the
+// carrier came back.  The ATE flaps the link by disabling and enabling
+// its port, which turns its laser off and on.
+package holdtime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Set sets the hold-time up and down of the interface, in milliseconds.
+func Set(intf *telemetry.Interface, up, down time.Duration) {
+	ht := intf.GetOrCreateHoldTime()
+	ht.Up = ygot.Uint32(uint32(up.Milliseconds()))
+	ht.Down = ygot.Uint32(uint32(down.Milliseconds()))
+}
+
+// Configure replaces the hold time of the interface of the DUT.
+func Configure(t testing.TB, dut *ondatra.DUTDevice, intf string, up, down time.Duration) {
+	t.Helper()
+	i := &telemetry.Interface{}
+	Set(i, up, down)
+	dut.Config().Interface(intf).HoldTime().Replace(t, i.GetHoldTime())
+}
+
+// Check checks that the DUT reports the hold-time up and down of the
+// interface.
+func Check(t testing.TB, dut *ondatra.DUTDevice, intf string, up, down time.Duration) error {
+	t.Helper()
+	ht := dut.Telemetry().Interface(intf).HoldTime().Get(t)
+	if got, want := ht.GetUp(), uint32(up.Milliseconds()); got != want {
+		return fmt.Errorf("interface %s hold-time up got %dms, want %dms", intf, got, want)
+	}
+	if got, want := ht.GetDown(), uint32(down.Milliseconds()); got != want {
+		return fmt.Errorf("interface %s hold-time down got %dms, want %dms", intf, got, want)
+	}
+	return nil
+}
+
+// Flap disables the port of the ATE for the duration, then enables it
+// again.
+func Flap(t *testing.T, ate *ondatra.ATEDevice, port *ondatra.Port, duration time.Duration) {
+	t.Helper()
+	ate.Actions().NewSetPortState().WithPort(port).WithEnabled(false).Send(t)
+	time.Sleep(duration)
+	ate.Actions().NewSetPortState().WithPort(port).WithEnabled(true).Send(t)
+}
+
+// CarrierTransitions returns the number of times the interface of the
+// DUT changed its oper-status, or 0 if it is not reported.
+func CarrierTransitions(t testing.TB, dut *ondatra.DUTDevice, intf string) uint64 {
+	t.Helper()
+	if v := dut.Telemetry().Interface(intf).Counters().CarrierTransitions().Lookup(t); v.IsPresent() {
+		return v.Val(t)
+	}
+	return 0
+}
+
+// Event is a change of the oper-status of an interface.
+type Event struct {
+	Time   time.Time
+	Status telemetry.E_Interface_OperStatus
+}
+
+// Collect starts collecting the oper-status of the interface of the DUT
+// for the duration.  The collection must be awaited after the flaps,
+// and its samples passed to Events.
+func Collect(t testing.TB, dut *ondatra.DUTDevice, intf string, duration time.Duration) *telemetry.CollectionE_Interface_OperStatus {
+	t.Helper()
+	return dut.Telemetry().Interface(intf).OperStatus().Collect(t, duration)
+}
+
+// Events returns the changes of the oper-status in the samples, at the
+// time the test received them.
+func Events(t testing.TB, samples []*telemetry.QualifiedE_Interface_OperStatus) []Event {
+	t.Helper()
+	var events []Event
+	for _, s := range samples {
+		if !s.IsPresent() {
+			continue
+		}
+		status := s.Val(t)
+		if n := len(events); n > 0 && events[n-1].Status == status {
+			continue
+		}
+		events = append(events, Event{Time: s.RecvTimestamp, Status: status})
+	}
+	return events
+}
+
+// CountDown returns the number of times the events went down.
+func CountDown(events []Event) int {
+	var n int
+	for i, e := range events {
+		if i > 0 && e.Status != telemetry.Interface_OperStatus_UP && events[i-1].Status == telemetry.Interface_OperStatus_UP {
+			n++
+		}
+	}
+	return n
+}
+
+// First returns the first event with the status at or after the time.
+func First(events []Event, status telemetry.E_Interface_OperStatus, since time.Time) (Event, bool) {
+	for _, e := range events {
+		if e.Status == status && !e.Time.Before(since) {
+			return e, true
+		}
+	}
+	return Event{}, false
+}
+
+// CheckDelay checks that the interface changed its oper-status after
+// the hold time, give or take the tolerance.
+func CheckDelay(elapsed, hold, tolerance time.Duration) error {
+	lo, hi := hold-tolerance, hold+tolerance
+	if elapsed < lo || elapsed > hi {
+		return fmt.Errorf("interface changed its oper-status after %v, want between %v and %v for a hold time of %v", elapsed, lo, hi, hold)
+	}
+	return nil
+}
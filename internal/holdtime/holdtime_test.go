@@ -0,0 +1,114 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package holdtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+const (
+	up   = telemetry.Interface_OperStatus_UP
+	down = telemetry.Interface_OperStatus_DOWN
+)
+
+func TestSet(t *testing.T) {
+	intf := &telemetry.Interface{}
+	Set(intf, 2*time.Second, 150*time.Millisecond)
+	want := &telemetry.Interface_HoldTime{
+		Up:   ygot.Uint32(2000),
+		Down: ygot.Uint32(150),
+	}
+	if diff := cmp.Diff(want, intf.GetHoldTime()); diff != "" {
+		t.Errorf("Set(2s, 150ms) -want, +got:\n%s", diff)
+	}
+}
+
+func TestCountDown(t *testing.T) {
+	t0 := time.Unix(0, 0)
+	at := func(ms int, status telemetry.E_Interface_OperStatus) Event {
+		return Event{Time: t0.Add(time.Duration(ms) * time.Millisecond), Status: status}
+	}
+	for _, c := range []struct {
+		desc   string
+		events []Event
+		want   int
+	}{
+		{"no events", nil, 0},
+		{"stayed up", []Event{at(0, up)}, 0},
+		{"started down", []Event{at(0, down), at(100, up)}, 0},
+		{"one flap", []Event{at(0, up), at(100, down), at(200, up)}, 1},
+		{"two flaps", []Event{at(0, up), at(100, down), at(200, up), at(300, down), at(400, up)}, 2},
+		{"lower layer down", []Event{at(0, up), at(100, telemetry.Interface_OperStatus_LOWER_LAYER_DOWN)}, 1},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			if got := CountDown(c.events); got != c.want {
+				t.Errorf("CountDown(%v) got %d, want %d", c.events, got, c.want)
+			}
+		})
+	}
+}
+
+func TestFirst(t *testing.T) {
+	t0 := time.Unix(0, 0)
+	events := []Event{
+		{Time: t0, Status: up},
+		{Time: t0.Add(time.Second), Status: down},
+		{Time: t0.Add(2 * time.Second), Status: up},
+	}
+	for _, c := range []struct {
+		desc   string
+		status telemetry.E_Interface_OperStatus
+		since  time.Time
+		want   Event
+		wantOK bool
+	}{
+		{"first up", up, t0, events[0], true},
+		{"up after down", up, t0.Add(time.Millisecond), events[2], true},
+		{"down", down, t0, events[1], true},
+		{"none after", down, t0.Add(1500 * time.Millisecond), Event{}, false},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			got, ok := First(events, c.status, c.since)
+			if ok != c.wantOK || got != c.want {
+				t.Errorf("First(%v, %v) got %v, %v, want %v, %v", c.status, c.since, got, ok, c.want, c.wantOK)
+			}
+		})
+	}
+}
+
+func TestCheckDelay(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		elapsed time.Duration
+		wantErr bool
+	}{
+		{"on time", 2 * time.Second, false},
+		{"within tolerance", 2200 * time.Millisecond, false},
+		{"too early", 1500 * time.Millisecond, true},
+		{"too late", 3 * time.Second, true},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			if err := CheckDelay(c.elapsed, 2*time.Second, 250*time.Millisecond); (err != nil) != c.wantErr {
+				t.Errorf("CheckDelay(%v) got error %v, want error %v", c.elapsed, err, c.wantErr)
+			}
+		})
+	}
+}
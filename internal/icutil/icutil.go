@@ -0,0 +1,167 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package icutil provides helpers for the resource utilization of the
+// integrated circuits of the DUT, e.g. its FIB and TCAM tables: reading
+// it, checking its consistency, correlating it with the number of
+// routes programmed, and detecting threshold crossings.
+package icutil
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/components"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Usage is the utilization of a resource of an integrated circuit.
+// The fields the DUT does not report are 0.
+type Usage struct {
+	Used, Free, MaxLimit, HighWatermark uint64
+}
+
+// FromResource returns the usage of the resource.
+func FromResource(r *telemetry.Component_IntegratedCircuit_Utilization_Resource) Usage {
+	return Usage{
+		Used:          r.GetUsed(),
+		Free:          r.GetFree(),
+		MaxLimit:      r.GetMaxLimit(),
+		HighWatermark: r.GetHighWatermark(),
+	}
+}
+
+// Limit returns the number of entries of the resource: its max limit,
+// or the sum of the used and free entries if the limit is not reported.
+func (u Usage) Limit() uint64 {
+	if u.MaxLimit > 0 {
+		return u.MaxLimit
+	}
+	return u.Used + u.Free
+}
+
+// Percent returns the share of the resource used, in percent, or 0 if
+// the limit is unknown.
+func (u Usage) Percent() float64 {
+	if u.Limit() == 0 {
+		return 0
+	}
+	return 100 * float64(u.Used) / float64(u.Limit())
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%d used of %d (%.1f%%), high watermark %d", u.Used, u.Limit(), u.Percent(), u.HighWatermark)
+}
+
+// Check checks the consistency of the usage: the used and free entries
+// add up to the max limit, and the high watermark is at least the used
+// entries.
+func (u Usage) Check() []error {
+	var errs []error
+	if u.MaxLimit > 0 && u.Free > 0 && u.Used+u.Free != u.MaxLimit {
+		errs = append(errs, fmt.Errorf("used %d and free %d do not add up to max-limit %d", u.Used, u.Free, u.MaxLimit))
+	}
+	if u.Limit() > 0 && u.Used > u.Limit() {
+		errs = append(errs, fmt.Errorf("used %d is over the limit %d", u.Used, u.Limit()))
+	}
+	if u.HighWatermark > 0 && u.HighWatermark < u.Used {
+		errs = append(errs, fmt.Errorf("high-watermark %d is below used %d", u.HighWatermark, u.Used))
+	}
+	return errs
+}
+
+// CheckGrowth checks that the used entries grew by the number of routes
+// programmed between the two usages, times the entries used per route,
+// within a tolerance in percent.
+func CheckGrowth(before, after Usage, routes uint64, perRoute, tolerancePct float64) error {
+	want := float64(routes) * perRoute
+	got := float64(after.Used) - float64(before.Used)
+	if math.Abs(got-want) > want*tolerancePct/100 {
+		return fmt.Errorf("used entries grew by %.0f for %d routes, want %.0f", got, routes, want)
+	}
+	return nil
+}
+
+// Crossings returns the indices of the usages which cross the threshold
+// in percent upwards, from a usage under it.
+func Crossings(usages []Usage, percent float64) []int {
+	var idx []int
+	for i := 1; i < len(usages); i++ {
+		if usages[i-1].Percent() < percent && usages[i].Percent() >= percent {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// Components returns the names of the components of the DUT reporting
+// the utilization of the resource, sorted.
+func Components(t testing.TB, dut *ondatra.DUTDevice, resource string) []string {
+	t.Helper()
+	var names []string
+	for _, c := range dut.Telemetry().ComponentAny().Get(t) {
+		if c.GetIntegratedCircuit().GetUtilization().GetResource(resource) != nil {
+			names = append(names, c.GetName())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Read returns the usage of the resource of the component.
+func Read(t testing.TB, dut *ondatra.DUTDevice, component, resource string) Usage {
+	t.Helper()
+	return FromResource(dut.Telemetry().Component(component).IntegratedCircuit().Utilization().Resource(resource).Get(t))
+}
+
+// Collect starts collecting the usages of the resource of the component
+// streamed by the DUT for the duration.  The collection must be awaited
+// after the routes are programmed, and its samples passed to Usages.
+func Collect(t testing.TB, dut *ondatra.DUTDevice, component, resource string, duration time.Duration) *telemetry.CollectionComponent_IntegratedCircuit_Utilization_Resource {
+	t.Helper()
+	return dut.Telemetry().Component(component).IntegratedCircuit().Utilization().Resource(resource).Collect(t, duration)
+}
+
+// Usages returns the usages in the samples.
+func Usages(t testing.TB, samples []*telemetry.QualifiedComponent_IntegratedCircuit_Utilization_Resource) []Usage {
+	t.Helper()
+	var usages []Usage
+	for _, v := range samples {
+		if v.IsPresent() {
+			usages = append(usages, FromResource(v.Val(t)))
+		}
+	}
+	return usages
+}
+
+// AwaitAlarm polls the system alarms of the DUT until one is raised for
+// the component, and returns it, or nil after the timeout.
+func AwaitAlarm(t testing.TB, dut *ondatra.DUTDevice, component string, timeout time.Duration) *telemetry.System_Alarm {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); ; time.Sleep(5 * time.Second) {
+		for _, a := range components.Alarms(t, dut) {
+			if a.GetResource() == component {
+				return a
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil
+		}
+	}
+}
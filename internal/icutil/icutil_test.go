@@ -0,0 +1,109 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package icutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestFromResource(t *testing.T) {
+	r := &telemetry.Component_IntegratedCircuit_Utilization_Resource{
+		Name:     ygot.String("fib"),
+		Used:     ygot.Uint64(250),
+		Free:     ygot.Uint64(750),
+		MaxLimit: ygot.Uint64(1000),
+	}
+	want := Usage{Used: 250, Free: 750, MaxLimit: 1000}
+	if diff := cmp.Diff(want, FromResource(r)); diff != "" {
+		t.Errorf("FromResource() -want, +got:\n%s", diff)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	for _, c := range []struct {
+		desc  string
+		usage Usage
+		want  float64
+	}{
+		{"max limit", Usage{Used: 250, MaxLimit: 1000}, 25},
+		{"used and free", Usage{Used: 100, Free: 300}, 25},
+		{"nothing reported", Usage{}, 0},
+	} {
+		if got := c.usage.Percent(); got != c.want {
+			t.Errorf("%s: Percent() got %v, want %v", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, c := range []struct {
+		desc     string
+		usage    Usage
+		wantErrs int
+	}{
+		{"consistent", Usage{Used: 250, Free: 750, MaxLimit: 1000, HighWatermark: 300}, 0},
+		{"no free", Usage{Used: 250, MaxLimit: 1000}, 0},
+		{"free mismatch", Usage{Used: 250, Free: 700, MaxLimit: 1000}, 1},
+		{"over the limit", Usage{Used: 1200, MaxLimit: 1000}, 1},
+		{"watermark below used", Usage{Used: 250, Free: 750, MaxLimit: 1000, HighWatermark: 200}, 1},
+	} {
+		if got := c.usage.Check(); len(got) != c.wantErrs {
+			t.Errorf("%s: Check() got errors %v, want %d", c.desc, got, c.wantErrs)
+		}
+	}
+}
+
+func TestCheckGrowth(t *testing.T) {
+	before := Usage{Used: 1000}
+	for _, c := range []struct {
+		desc     string
+		after    Usage
+		routes   uint64
+		perRoute float64
+		wantErr  bool
+	}{
+		{"exact", Usage{Used: 11000}, 10000, 1, false},
+		{"within tolerance", Usage{Used: 10600}, 10000, 1, false},
+		{"two entries per route", Usage{Used: 21000}, 10000, 2, false},
+		{"too few", Usage{Used: 5000}, 10000, 1, true},
+		{"shrunk", Usage{Used: 500}, 10000, 1, true},
+		{"no routes", Usage{Used: 1000}, 0, 1, false},
+	} {
+		if err := CheckGrowth(before, c.after, c.routes, c.perRoute, 5); (err != nil) != c.wantErr {
+			t.Errorf("%s: CheckGrowth() got error %v, want error %v", c.desc, err, c.wantErr)
+		}
+	}
+}
+
+func TestCrossings(t *testing.T) {
+	usages := []Usage{
+		{Used: 700, MaxLimit: 1000},
+		{Used: 850, MaxLimit: 1000},
+		{Used: 900, MaxLimit: 1000},
+		{Used: 500, MaxLimit: 1000},
+		{Used: 950, MaxLimit: 1000},
+	}
+	if diff := cmp.Diff([]int{2, 4}, Crossings(usages, 90)); diff != "" {
+		t.Errorf("Crossings() -want, +got:\n%s", diff)
+	}
+	if got := Crossings(usages, 99); got != nil {
+		t.Errorf("Crossings() got %v, want none", got)
+	}
+}
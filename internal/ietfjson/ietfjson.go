@@ -0,0 +1,53 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ietfjson decodes the raw JSON_IETF values of gNMI (RFC 7951),
+// where the members of an object may be qualified by the name of their
+// module, and where servers may return a list entry as a list of this
+// entry only.
+package ietfjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Member returns the member of a JSON object of the name, with or without
+// the name of its module.
+func Member(obj map[string]json.RawMessage, name string) (json.RawMessage, bool) {
+	if v, ok := obj[name]; ok {
+		return v, true
+	}
+	for k, v := range obj {
+		if i := strings.IndexByte(k, ':'); i >= 0 && k[i+1:] == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Object decodes a JSON object, unwrapping a list of this object only, as
+// servers may return a list entry.
+func Object(raw json.RawMessage) (map[string]json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		return obj, nil
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil || len(entries) != 1 {
+		return nil, fmt.Errorf("%s is not a JSON object", raw)
+	}
+	return Object(entries[0])
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ietfjson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMember(t *testing.T) {
+	obj := map[string]json.RawMessage{
+		"name":                      json.RawMessage(`"eth0"`),
+		"openconfig-interfaces:mtu": json.RawMessage(`1500`),
+	}
+	for _, tc := range []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"name", `"eth0"`, true},
+		{"mtu", `1500`, true},
+		{"openconfig-interfaces:mtu", `1500`, true},
+		{"description", "", false},
+		{"interfaces:mtu", "", false},
+	} {
+		got, ok := Member(obj, tc.name)
+		if ok != tc.wantOK || string(got) != tc.want {
+			t.Errorf("Member(%q) got %s, %t, want %s, %t", tc.name, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestObject(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		raw     string
+		want    map[string]json.RawMessage
+		wantErr bool
+	}{{
+		desc: "object",
+		raw:  `{"name": "eth0"}`,
+		want: map[string]json.RawMessage{"name": json.RawMessage(`"eth0"`)},
+	}, {
+		desc: "list entry",
+		raw:  `[{"name": "eth0"}]`,
+		want: map[string]json.RawMessage{"name": json.RawMessage(`"eth0"`)},
+	}, {
+		desc:    "list",
+		raw:     `[{"name": "eth0"}, {"name": "eth1"}]`,
+		wantErr: true,
+	}, {
+		desc:    "scalar",
+		raw:     `"eth0"`,
+		wantErr: true,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := Object(json.RawMessage(tc.raw))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Object() got error %v, want error %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Object() -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ifcounters provides helpers to validate the accuracy of the
+// interface counters: bursts of an exact number of packets of known
+// sizes sent by the OTG, the counters expected to increment for them on
+// physical, LAG and subinterfaces, and the comparison of the counters
+// read from the DUT with those expected.
+package ifcounters
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/mtu"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Kind is the type of an interface whose counters are checked.
+type Kind int
+
+const (
+	// Physical is an Ethernet port.
+	Physical Kind = iota
+	// LAG is an aggregate interface, whose counters add up those of
+	// its members.
+	LAG
+	// Subinterface is a VLAN tagged subinterface.
+	Subinterface
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Physical:
+		return "physical"
+	case LAG:
+		return "LAG"
+	case Subinterface:
+		return "subinterface"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// FrameOverhead returns the size of the headers and FCS added to an IP
+// packet in the frames on an interface of the kind.
+func (k Kind) FrameOverhead() uint32 {
+	if k == Subinterface {
+		// The 802.1Q tag.
+		return mtu.FrameOverhead + 4
+	}
+	return mtu.FrameOverhead
+}
+
+// Ref refers to the interface of the DUT whose counters are read.  The
+// index is only used for subinterfaces.
+type Ref struct {
+	Kind  Kind
+	Name  string
+	Index uint32
+}
+
+func (r Ref) String() string {
+	if r.Kind == Subinterface {
+		return fmt.Sprintf("%s.%d", r.Name, r.Index)
+	}
+	return r.Name
+}
+
+// ConfigureSubinterface adds to the interface a subinterface matching
+// the VLAN, with the IPv4 address of the attributes.
+func ConfigureSubinterface(i *telemetry.Interface, index uint32, vlan uint16, a *attrs.Attributes) {
+	s := i.GetOrCreateSubinterface(index)
+	s.GetOrCreateVlan().GetOrCreateMatch().GetOrCreateSingleTagged().VlanId = ygot.Uint16(vlan)
+	s4 := s.GetOrCreateIpv4()
+	if *deviations.InterfaceEnabled {
+		s.Enabled = ygot.Bool(true)
+		s4.Enabled = ygot.Bool(true)
+	}
+	s4.GetOrCreateAddress(a.IPv4).PrefixLength = ygot.Uint8(a.IPv4Len)
+}
+
+// NewLAG returns a static aggregate interface with the attributes.
+func NewLAG(aggID string, a *attrs.Attributes) *telemetry.Interface {
+	i := a.NewInterface(aggID)
+	i.Type = telemetry.IETFInterfaces_InterfaceType_ieee8023adLag
+	i.Ethernet = nil
+	i.GetOrCreateAggregation().LagType = telemetry.IfAggregate_AggregationType_STATIC
+	return i
+}
+
+// NewMember returns a member of the aggregate interface.
+func NewMember(name, aggID string) *telemetry.Interface {
+	i := &telemetry.Interface{
+		Name: ygot.String(name),
+		Type: telemetry.IETFInterfaces_InterfaceType_ethernetCsmacd,
+	}
+	if *deviations.InterfaceEnabled {
+		i.Enabled = ygot.Bool(true)
+	}
+	i.GetOrCreateEthernet().AggregateId = ygot.String(aggID)
+	return i
+}
+
+// Burst is a number of IP packets of the same size.
+type Burst struct {
+	Packets    uint64
+	PacketSize uint32
+}
+
+// FrameSize returns the size of the frames of the burst on an interface
+// of the kind.
+func (b Burst) FrameSize(k Kind) uint32 {
+	return b.PacketSize + k.FrameOverhead()
+}
+
+// Counters are the interface counters checked, or their increase.
+type Counters struct {
+	InUnicastPkts  uint64
+	InOctets       uint64
+	InErrors       uint64
+	OutUnicastPkts uint64
+	OutOctets      uint64
+	OutErrors      uint64
+}
+
+// Sub returns the counters increase since prev.
+func (c Counters) Sub(prev Counters) Counters {
+	return Counters{
+		InUnicastPkts:  c.InUnicastPkts - prev.InUnicastPkts,
+		InOctets:       c.InOctets - prev.InOctets,
+		InErrors:       c.InErrors - prev.InErrors,
+		OutUnicastPkts: c.OutUnicastPkts - prev.OutUnicastPkts,
+		OutOctets:      c.OutOctets - prev.OutOctets,
+		OutErrors:      c.OutErrors - prev.OutErrors,
+	}
+}
+
+// FromInterface returns the counters of an interface.
+func FromInterface(cs *telemetry.Interface_Counters) Counters {
+	return Counters{
+		InUnicastPkts:  cs.GetInUnicastPkts(),
+		InOctets:       cs.GetInOctets(),
+		InErrors:       cs.GetInErrors(),
+		OutUnicastPkts: cs.GetOutUnicastPkts(),
+		OutOctets:      cs.GetOutOctets(),
+		OutErrors:      cs.GetOutErrors(),
+	}
+}
+
+// FromSubinterface returns the counters of a subinterface.
+func FromSubinterface(cs *telemetry.Interface_Subinterface_Counters) Counters {
+	return Counters{
+		InUnicastPkts:  cs.GetInUnicastPkts(),
+		InOctets:       cs.GetInOctets(),
+		InErrors:       cs.GetInErrors(),
+		OutUnicastPkts: cs.GetOutUnicastPkts(),
+		OutOctets:      cs.GetOutOctets(),
+		OutErrors:      cs.GetOutErrors(),
+	}
+}
+
+// Get returns all the counters of the interface.
+func Get(t testing.TB, dut *ondatra.DUTDevice, r Ref) Counters {
+	t.Helper()
+	i := dut.Telemetry().Interface(r.Name)
+	if r.Kind == Subinterface {
+		return FromSubinterface(i.Subinterface(r.Index).Counters().Get(t))
+	}
+	return FromInterface(i.Counters().Get(t))
+}
+
+// Read returns the input counters of the ingress interface and the
+// output counters of the egress interface.
+func Read(t testing.TB, dut *ondatra.DUTDevice, ingress, egress Ref) Counters {
+	t.Helper()
+	in, out := Get(t, dut, ingress), Get(t, dut, egress)
+	return Counters{
+		InUnicastPkts:  in.InUnicastPkts,
+		InOctets:       in.InOctets,
+		InErrors:       in.InErrors,
+		OutUnicastPkts: out.OutUnicastPkts,
+		OutOctets:      out.OutOctets,
+		OutErrors:      out.OutErrors,
+	}
+}
+
+// In returns the input counters only.
+func (c Counters) In() Counters {
+	return Counters{InUnicastPkts: c.InUnicastPkts, InOctets: c.InOctets, InErrors: c.InErrors}
+}
+
+// Out returns the output counters only.
+func (c Counters) Out() Counters {
+	return Counters{OutUnicastPkts: c.OutUnicastPkts, OutOctets: c.OutOctets, OutErrors: c.OutErrors}
+}
+
+// Sum returns the sum of the counters.
+func Sum(cs []Counters) Counters {
+	var sum Counters
+	for _, c := range cs {
+		sum.InUnicastPkts += c.InUnicastPkts
+		sum.InOctets += c.InOctets
+		sum.InErrors += c.InErrors
+		sum.OutUnicastPkts += c.OutUnicastPkts
+		sum.OutOctets += c.OutOctets
+		sum.OutErrors += c.OutErrors
+	}
+	return sum
+}
+
+// Expect returns the counters increase expected when the bursts are
+// routed from an ingress to an egress interface of the given kinds.
+func Expect(bursts []Burst, ingress, egress Kind) Counters {
+	var c Counters
+	for _, b := range bursts {
+		c.InUnicastPkts += b.Packets
+		c.InOctets += b.Packets * uint64(b.FrameSize(ingress))
+		c.OutUnicastPkts += b.Packets
+		c.OutOctets += b.Packets * uint64(b.FrameSize(egress))
+	}
+	return c
+}
+
+// Check compares the counters increase with the one expected, and
+// returns the differences found.  The packet and octet counters may
+// exceed those expected by the tolerance in percent, for the control
+// traffic of the DUT, but not be under them; the error counters must
+// match exactly.
+func Check(got, want Counters, tolerancePct float64) []error {
+	var errs []error
+	for _, c := range []struct {
+		name      string
+		got, want uint64
+		exact     bool
+	}{
+		{"in-unicast-pkts", got.InUnicastPkts, want.InUnicastPkts, false},
+		{"in-octets", got.InOctets, want.InOctets, false},
+		{"in-errors", got.InErrors, want.InErrors, true},
+		{"out-unicast-pkts", got.OutUnicastPkts, want.OutUnicastPkts, false},
+		{"out-octets", got.OutOctets, want.OutOctets, false},
+		{"out-errors", got.OutErrors, want.OutErrors, true},
+	} {
+		max := c.want
+		if !c.exact {
+			max += uint64(float64(c.want) * tolerancePct / 100)
+		}
+		if c.got < c.want || c.got > max {
+			errs = append(errs, fmt.Errorf("%s increased by %d, want %d", c.name, c.got, c.want))
+		}
+	}
+	return errs
+}
+
+// CheckRollUp compares the counters increase of a LAG with the sum of
+// those of its members, and returns the differences found.  They may
+// differ by the tolerance in percent either way, since the counters of
+// the LAG and the members are not read at the same time.
+func CheckRollUp(lag Counters, members []Counters, tolerancePct float64) []error {
+	sum := Sum(members)
+	var errs []error
+	for _, c := range []struct {
+		name     string
+		lag, sum uint64
+	}{
+		{"in-unicast-pkts", lag.InUnicastPkts, sum.InUnicastPkts},
+		{"in-octets", lag.InOctets, sum.InOctets},
+		{"in-errors", lag.InErrors, sum.InErrors},
+		{"out-unicast-pkts", lag.OutUnicastPkts, sum.OutUnicastPkts},
+		{"out-octets", lag.OutOctets, sum.OutOctets},
+		{"out-errors", lag.OutErrors, sum.OutErrors},
+	} {
+		if math.Abs(float64(c.lag)-float64(c.sum)) > float64(c.sum)*tolerancePct/100 {
+			errs = append(errs, fmt.Errorf("LAG %s increased by %d, members by %d", c.name, c.lag, c.sum))
+		}
+	}
+	return errs
+}
+
+// udpPort is the destination and first source port of the flows.
+const udpPort = 49152
+
+// Endpoint is an IPv4 endpoint of the OTG.  The VLAN is 0 if the
+// endpoint is untagged.
+type Endpoint struct {
+	Device string // Name of the IPv4 address of the OTG device.
+	MAC    string
+	IPv4   string
+	VLAN   uint16
+}
+
+// AddFlows adds to the OTG configuration one flow per burst, sending
+// exactly its packets from src to dst at the rate in packets per
+// second.  The flows are named after the name and the packet size.
+// The packets are UDP, with source ports cycling over 256 values so
+// that they are spread over the members of a LAG.
+func AddFlows(config gosnappi.Config, name string, src, dst Endpoint, bursts []Burst, pps int64) {
+	kind := Physical
+	if src.VLAN != 0 {
+		kind = Subinterface
+	}
+	for _, b := range bursts {
+		flow := config.Flows().Add().SetName(fmt.Sprintf("%s-%d", name, b.PacketSize))
+		flow.Metrics().SetEnable(true)
+		flow.TxRx().Device().
+			SetTxNames([]string{src.Device}).
+			SetRxNames([]string{dst.Device})
+		flow.Rate().SetPps(pps)
+		flow.Duration().FixedPackets().SetPackets(int32(b.Packets))
+		flow.Size().SetFixed(int32(b.FrameSize(kind)))
+		flow.Packet().Add().Ethernet().Src().SetValue(src.MAC)
+		if src.VLAN != 0 {
+			flow.Packet().Add().Vlan().Id().SetValue(int32(src.VLAN))
+		}
+		ip := flow.Packet().Add().Ipv4()
+		ip.Src().SetValue(src.IPv4)
+		ip.Dst().SetValue(dst.IPv4)
+		udp := flow.Packet().Add().Udp()
+		udp.SrcPort().Increment().SetStart(udpPort).SetStep(1).SetCount(256)
+		udp.DstPort().SetValue(udpPort)
+	}
+}
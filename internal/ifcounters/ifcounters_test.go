@@ -0,0 +1,141 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ifcounters
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestFrameSize(t *testing.T) {
+	b := Burst{Packets: 10, PacketSize: 100}
+	for _, c := range []struct {
+		kind Kind
+		want uint32
+	}{
+		{Physical, 118},
+		{LAG, 118},
+		{Subinterface, 122},
+	} {
+		if got := b.FrameSize(c.kind); got != c.want {
+			t.Errorf("FrameSize(%v) got %d, want %d", c.kind, got, c.want)
+		}
+	}
+}
+
+func TestRefString(t *testing.T) {
+	for _, c := range []struct {
+		ref  Ref
+		want string
+	}{
+		{Ref{Kind: Physical, Name: "Ethernet1"}, "Ethernet1"},
+		{Ref{Kind: LAG, Name: "Port-Channel1"}, "Port-Channel1"},
+		{Ref{Kind: Subinterface, Name: "Ethernet1", Index: 10}, "Ethernet1.10"},
+	} {
+		if got := c.ref.String(); got != c.want {
+			t.Errorf("String() got %q, want %q", got, c.want)
+		}
+	}
+}
+
+func TestFromInterface(t *testing.T) {
+	cs := &telemetry.Interface_Counters{
+		InUnicastPkts:  ygot.Uint64(1),
+		InOctets:       ygot.Uint64(2),
+		InErrors:       ygot.Uint64(3),
+		OutUnicastPkts: ygot.Uint64(4),
+		OutOctets:      ygot.Uint64(5),
+	}
+	want := Counters{InUnicastPkts: 1, InOctets: 2, InErrors: 3, OutUnicastPkts: 4, OutOctets: 5}
+	if diff := cmp.Diff(want, FromInterface(cs)); diff != "" {
+		t.Errorf("FromInterface() -want, +got:\n%s", diff)
+	}
+}
+
+func TestExpect(t *testing.T) {
+	bursts := []Burst{{Packets: 1000, PacketSize: 64}, {Packets: 500, PacketSize: 1000}}
+	want := Counters{
+		InUnicastPkts:  1500,
+		InOctets:       1000*86 + 500*1022,
+		OutUnicastPkts: 1500,
+		OutOctets:      1000*82 + 500*1018,
+	}
+	if diff := cmp.Diff(want, Expect(bursts, Subinterface, LAG)); diff != "" {
+		t.Errorf("Expect() -want, +got:\n%s", diff)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	want := Counters{InUnicastPkts: 1000, InOctets: 100000, OutUnicastPkts: 1000, OutOctets: 100000}
+	for _, c := range []struct {
+		desc     string
+		got      Counters
+		wantErrs int
+	}{
+		{"exact", want, 0},
+		{"within tolerance", Counters{InUnicastPkts: 1010, InOctets: 100500, OutUnicastPkts: 1000, OutOctets: 100000}, 0},
+		{"over tolerance", Counters{InUnicastPkts: 1100, InOctets: 100000, OutUnicastPkts: 1000, OutOctets: 100000}, 1},
+		{"loss", Counters{InUnicastPkts: 1000, InOctets: 100000, OutUnicastPkts: 999, OutOctets: 99900}, 2},
+		{"errors", Counters{InUnicastPkts: 1000, InOctets: 100000, InErrors: 1, OutUnicastPkts: 1000, OutOctets: 100000, OutErrors: 1}, 2},
+	} {
+		if got := Check(c.got, want, 1); len(got) != c.wantErrs {
+			t.Errorf("%s: Check() got errors %v, want %d", c.desc, got, c.wantErrs)
+		}
+	}
+}
+
+func TestSub(t *testing.T) {
+	prev := Counters{InUnicastPkts: 10, InOctets: 1000, OutUnicastPkts: 20, OutOctets: 2000}
+	c := Counters{InUnicastPkts: 15, InOctets: 1500, InErrors: 1, OutUnicastPkts: 30, OutOctets: 3000}
+	want := Counters{InUnicastPkts: 5, InOctets: 500, InErrors: 1, OutUnicastPkts: 10, OutOctets: 1000}
+	if diff := cmp.Diff(want, c.Sub(prev)); diff != "" {
+		t.Errorf("Sub() -want, +got:\n%s", diff)
+	}
+}
+
+func TestInOut(t *testing.T) {
+	c := Counters{InUnicastPkts: 1, InOctets: 2, InErrors: 3, OutUnicastPkts: 4, OutOctets: 5, OutErrors: 6}
+	if diff := cmp.Diff(Counters{InUnicastPkts: 1, InOctets: 2, InErrors: 3}, c.In()); diff != "" {
+		t.Errorf("In() -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff(Counters{OutUnicastPkts: 4, OutOctets: 5, OutErrors: 6}, c.Out()); diff != "" {
+		t.Errorf("Out() -want, +got:\n%s", diff)
+	}
+}
+
+func TestCheckRollUp(t *testing.T) {
+	members := []Counters{
+		{InUnicastPkts: 400, InOctets: 40000, OutUnicastPkts: 700, OutOctets: 70000},
+		{InUnicastPkts: 600, InOctets: 60000, OutUnicastPkts: 300, OutOctets: 30000},
+	}
+	for _, c := range []struct {
+		desc     string
+		lag      Counters
+		wantErrs int
+	}{
+		{"sum", Counters{InUnicastPkts: 1000, InOctets: 100000, OutUnicastPkts: 1000, OutOctets: 100000}, 0},
+		{"within tolerance", Counters{InUnicastPkts: 995, InOctets: 100500, OutUnicastPkts: 1000, OutOctets: 100000}, 0},
+		{"member missing", Counters{InUnicastPkts: 400, InOctets: 40000, OutUnicastPkts: 700, OutOctets: 70000}, 4},
+		{"errors", Counters{InUnicastPkts: 1000, InOctets: 100000, InErrors: 5, OutUnicastPkts: 1000, OutOctets: 100000}, 1},
+	} {
+		if got := CheckRollUp(c.lag, members, 1); len(got) != c.wantErrs {
+			t.Errorf("%s: CheckRollUp() got errors %v, want %d", c.desc, got, c.wantErrs)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ippool allocates non-overlapping test addresses: link
+// subnets, next hop addresses and prefix pools, carved in order out of
+// an address block.  Tests that allocate their addresses from pools
+// instead of writing them out can be composed and scaled without their
+// addresses colliding.
+//
+// Usage:
+//
+//	pool, err := ippool.New("192.0.2.0/24")
+//	...
+//	links, err := pool.Links(30, 3)      // 192.0.2.0/30, 192.0.2.4/30, 192.0.2.8/30
+//	nhs, err := pool.Hosts(2)            // 192.0.2.12, 192.0.2.13
+//	routes, err := pool.Sub(26)          // A pool of 192.0.2.64/26
+package ippool
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// Pool allocates consecutive subnets out of an address block.  Each
+// subnet is aligned on its own size, so that the subnets never overlap
+// whatever their sizes.
+type Pool struct {
+	block *net.IPNet
+	bits  int
+	// next is the first address not allocated yet and end the first
+	// address after the block.
+	next, end *big.Int
+}
+
+// New returns a pool of the address block in CIDR notation.
+func New(block string) (*Pool, error) {
+	ip, ipnet, err := net.ParseCIDR(block)
+	if err != nil {
+		return nil, err
+	}
+	if !ip.Equal(ipnet.IP) {
+		return nil, fmt.Errorf("block %s has host bits set", block)
+	}
+	ones, bits := ipnet.Mask.Size()
+	next := toInt(ipnet.IP)
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	return &Pool{
+		block: ipnet,
+		bits:  bits,
+		next:  next,
+		end:   new(big.Int).Add(next, size),
+	}, nil
+}
+
+// toInt returns an address as an integer.
+func toInt(ip net.IP) *big.Int {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return new(big.Int).SetBytes(ip)
+}
+
+// toIP returns an integer as an address of the given number of bits.
+func toIP(n *big.Int, bits int) net.IP {
+	return net.IP(n.FillBytes(make([]byte, bits/8)))
+}
+
+func (p *Pool) String() string {
+	return p.block.String()
+}
+
+// Subnet allocates the next subnet of the prefix length and returns it
+// in CIDR notation.
+func (p *Pool) Subnet(plen int) (string, error) {
+	if plen < 0 || plen > p.bits {
+		return "", fmt.Errorf("invalid prefix length %d for pool %s", plen, p)
+	}
+	size := new(big.Int).Lsh(big.NewInt(1), uint(p.bits-plen))
+	// Round the next address up to the size of the subnet.
+	start := new(big.Int).Add(p.next, new(big.Int).Sub(size, big.NewInt(1)))
+	start.Div(start, size).Mul(start, size)
+	end := new(big.Int).Add(start, size)
+	if end.Cmp(p.end) > 0 {
+		return "", fmt.Errorf("pool %s exhausted: no /%d subnet left", p, plen)
+	}
+	p.next = end
+	return fmt.Sprintf("%s/%d", toIP(start, p.bits), plen), nil
+}
+
+// Subnets allocates the next count subnets of the prefix length.
+func (p *Pool) Subnets(plen, count int) ([]string, error) {
+	subnets := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		s, err := p.Subnet(plen)
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, s)
+	}
+	return subnets, nil
+}
+
+// Hosts allocates the next count addresses, e.g. for next hops, and
+// returns them without prefix length.
+func (p *Pool) Hosts(count int) ([]string, error) {
+	subnets, err := p.Subnets(p.bits, count)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, len(subnets))
+	for i, s := range subnets {
+		ip, _, _ := net.ParseCIDR(s)
+		hosts[i] = ip.String()
+	}
+	return hosts, nil
+}
+
+// Sub allocates the next subnet of the prefix length and returns it as
+// a pool of its own, e.g. for a set of prefixes advertised together.
+func (p *Pool) Sub(plen int) (*Pool, error) {
+	s, err := p.Subnet(plen)
+	if err != nil {
+		return nil, err
+	}
+	return New(s)
+}
+
+// Link is a point-to-point link subnet between a DUT and an ATE port.
+type Link struct {
+	Subnet string
+	// DUT and ATE are the first and second addresses of the subnet.
+	DUT string
+	ATE string
+	Len uint8
+}
+
+// Links allocates the next count link subnets of the prefix length,
+// which must leave room for two addresses.
+func (p *Pool) Links(plen, count int) ([]*Link, error) {
+	if plen > p.bits-1 {
+		return nil, fmt.Errorf("invalid link prefix length %d for pool %s: no room for two addresses", plen, p)
+	}
+	subnets, err := p.Subnets(plen, count)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]*Link, len(subnets))
+	for i, s := range subnets {
+		if links[i], err = NewLink(s); err != nil {
+			return nil, err
+		}
+	}
+	return links, nil
+}
+
+// NewLink returns the link of the subnet in CIDR notation.
+func NewLink(subnet string) (*Link, error) {
+	_, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := ipnet.Mask.Size()
+	if ones > bits-1 {
+		return nil, fmt.Errorf("link subnet %s has no room for two addresses", subnet)
+	}
+	// RFC 3021 links use both of their addresses.
+	first := 1
+	if ones == bits-1 {
+		first = 0
+	}
+	l := &Link{Subnet: subnet, Len: uint8(ones)}
+	if l.DUT, err = Host(subnet, first); err != nil {
+		return nil, err
+	}
+	if l.ATE, err = Host(subnet, first+1); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Host returns the n-th address of the subnet in CIDR notation, counting
+// from 0 for the subnet address.
+func Host(subnet string, n int) (string, error) {
+	_, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return "", err
+	}
+	ones, bits := ipnet.Mask.Size()
+	if size := bits - ones; n < 0 || size < 63 && int64(n) >= int64(1)<<uint(size) {
+		return "", fmt.Errorf("subnet %s has no address %d", subnet, n)
+	}
+	a := new(big.Int).Add(toInt(ipnet.IP), big.NewInt(int64(n)))
+	return toIP(a, bits).String(), nil
+}
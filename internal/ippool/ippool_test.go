@@ -0,0 +1,173 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ippool
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSubnets(t *testing.T) {
+	p, err := New("192.0.2.0/24")
+	if err != nil {
+		t.Fatalf("New() got error: %v", err)
+	}
+	var got []string
+	for _, plen := range []int{30, 29, 30, 32, 26} {
+		s, err := p.Subnet(plen)
+		if err != nil {
+			t.Fatalf("Subnet(%d) got error: %v", plen, err)
+		}
+		got = append(got, s)
+	}
+	want := []string{"192.0.2.0/30", "192.0.2.8/29", "192.0.2.16/30", "192.0.2.20/32", "192.0.2.64/26"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Subnet() -want, +got:\n%s", diff)
+	}
+	if s, err := p.Subnet(24); err == nil {
+		t.Errorf("Subnet(24) got %s, want error for an exhausted pool", s)
+	}
+	if s, err := p.Subnet(26); err != nil || s != "192.0.2.128/26" {
+		t.Errorf("Subnet(26) got %s, %v, want 192.0.2.128/26", s, err)
+	}
+}
+
+func TestSubnetsIPv6(t *testing.T) {
+	p, err := New("2001:db8::/32")
+	if err != nil {
+		t.Fatalf("New() got error: %v", err)
+	}
+	got, err := p.Subnets(64, 2)
+	if err != nil {
+		t.Fatalf("Subnets() got error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"2001:db8::/64", "2001:db8:0:1::/64"}, got); diff != "" {
+		t.Errorf("Subnets() -want, +got:\n%s", diff)
+	}
+	if s, err := p.Subnet(129); err == nil {
+		t.Errorf("Subnet(129) got %s, want error", s)
+	}
+}
+
+func TestHosts(t *testing.T) {
+	p, err := New("198.51.100.0/30")
+	if err != nil {
+		t.Fatalf("New() got error: %v", err)
+	}
+	got, err := p.Hosts(3)
+	if err != nil {
+		t.Fatalf("Hosts() got error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"198.51.100.0", "198.51.100.1", "198.51.100.2"}, got); diff != "" {
+		t.Errorf("Hosts() -want, +got:\n%s", diff)
+	}
+	if _, err := p.Hosts(2); err == nil {
+		t.Errorf("Hosts(2) got no error, want error for an exhausted pool")
+	}
+}
+
+func TestSub(t *testing.T) {
+	p, err := New("192.0.2.0/24")
+	if err != nil {
+		t.Fatalf("New() got error: %v", err)
+	}
+	links, err := p.Sub(26)
+	if err != nil {
+		t.Fatalf("Sub() got error: %v", err)
+	}
+	routes, err := p.Sub(26)
+	if err != nil {
+		t.Fatalf("Sub() got error: %v", err)
+	}
+	if links.String() != "192.0.2.0/26" || routes.String() != "192.0.2.64/26" {
+		t.Errorf("Sub() got %s and %s, want 192.0.2.0/26 and 192.0.2.64/26", links, routes)
+	}
+	if _, err := links.Subnets(30, 17); err == nil {
+		t.Errorf("Subnets(30, 17) got no error, want error beyond the sub-pool")
+	}
+}
+
+func TestLinks(t *testing.T) {
+	p, err := New("192.0.2.0/24")
+	if err != nil {
+		t.Fatalf("New() got error: %v", err)
+	}
+	got, err := p.Links(30, 2)
+	if err != nil {
+		t.Fatalf("Links() got error: %v", err)
+	}
+	want := []*Link{
+		{Subnet: "192.0.2.0/30", DUT: "192.0.2.1", ATE: "192.0.2.2", Len: 30},
+		{Subnet: "192.0.2.4/30", DUT: "192.0.2.5", ATE: "192.0.2.6", Len: 30},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Links(30) -want, +got:\n%s", diff)
+	}
+	got, err = p.Links(31, 1)
+	if err != nil {
+		t.Fatalf("Links(31) got error: %v", err)
+	}
+	if diff := cmp.Diff([]*Link{{Subnet: "192.0.2.8/31", DUT: "192.0.2.8", ATE: "192.0.2.9", Len: 31}}, got); diff != "" {
+		t.Errorf("Links(31) -want, +got:\n%s", diff)
+	}
+	if _, err := p.Links(32, 1); err == nil {
+		t.Errorf("Links(32) got no error, want error")
+	}
+}
+
+func TestHost(t *testing.T) {
+	for _, c := range []struct {
+		subnet  string
+		n       int
+		want    string
+		wantErr bool
+	}{
+		{"192.0.2.4/30", 1, "192.0.2.5", false},
+		{"192.0.2.4/30", 4, "", true},
+		{"192.0.2.4/32", 0, "192.0.2.4", false},
+		{"2001:db8::/126", 2, "2001:db8::2", false},
+		{"2001:db8::/64", -1, "", true},
+		{"not a subnet", 0, "", true},
+	} {
+		got, err := Host(c.subnet, c.n)
+		if got != c.want || (err != nil) != c.wantErr {
+			t.Errorf("Host(%q, %d) got %q, %v, want %q, error %v", c.subnet, c.n, got, err, c.want, c.wantErr)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, block := range []string{"192.0.2.1/24", "192.0.2.0", ""} {
+		if _, err := New(block); err == nil {
+			t.Errorf("New(%q) got no error, want error", block)
+		}
+	}
+}
+
+func TestNewLink(t *testing.T) {
+	got, err := NewLink("2001:db8::4/126")
+	if err != nil {
+		t.Fatalf("NewLink() got error: %v", err)
+	}
+	if diff := cmp.Diff(&Link{Subnet: "2001:db8::4/126", DUT: "2001:db8::5", ATE: "2001:db8::6", Len: 126}, got); diff != "" {
+		t.Errorf("NewLink() -want, +got:\n%s", diff)
+	}
+	for _, s := range []string{"192.0.2.1/32", "2001:db8::/128", "192.0.2.0"} {
+		if _, err := NewLink(s); err == nil {
+			t.Errorf("NewLink(%q) got no error, want error", s)
+		}
+	}
+}
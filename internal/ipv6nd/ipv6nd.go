@@ -0,0 +1,210 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ipv6nd provides helpers to validate IPv6 Neighbor Discovery
+// (RFC 4861) on the DUT: router advertisement configuration, the
+// neighbor cache state machine of ATE-emulated hosts, and Duplicate
+// Address Detection (RFC 4862).
+//
+// The OpenConfig model used by the tests only has the interval,
+// lifetime and suppress leaves for router advertisements, so the
+// advertised prefixes and the managed and other-config flags cannot be
+// configured or validated through it.
+package ipv6nd
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// RouterAdvertisement is the router advertisement configuration of an
+// interface.
+type RouterAdvertisement struct {
+	// Interval is the interval between unsolicited advertisements in
+	// seconds.  Zero leaves the DUT default.
+	Interval uint32
+	// Lifetime is the router lifetime advertised in seconds.  Zero
+	// leaves the DUT default.
+	Lifetime uint32
+	// Suppress suppresses the advertisements.
+	Suppress bool
+}
+
+// Apply sets the router advertisement configuration of the IPv6
+// subinterface.
+func (ra *RouterAdvertisement) Apply(ipv6 *telemetry.Interface_Subinterface_Ipv6) {
+	c := ipv6.GetOrCreateRouterAdvertisement()
+	if ra.Interval > 0 {
+		c.Interval = ygot.Uint32(ra.Interval)
+	}
+	if ra.Lifetime > 0 {
+		c.Lifetime = ygot.Uint32(ra.Lifetime)
+	}
+	c.Suppress = ygot.Bool(ra.Suppress)
+}
+
+// Diff returns the differences between the router advertisement state
+// reported by the DUT and ra.  Leaves left to the DUT default are not
+// compared.
+func (ra *RouterAdvertisement) Diff(got *telemetry.Interface_Subinterface_Ipv6_RouterAdvertisement) []error {
+	var errs []error
+	if ra.Interval > 0 && got.GetInterval() != ra.Interval {
+		errs = append(errs, fmt.Errorf("interval got %d, want %d", got.GetInterval(), ra.Interval))
+	}
+	if ra.Lifetime > 0 && got.GetLifetime() != ra.Lifetime {
+		errs = append(errs, fmt.Errorf("lifetime got %d, want %d", got.GetLifetime(), ra.Lifetime))
+	}
+	if got.GetSuppress() != ra.Suppress {
+		errs = append(errs, fmt.Errorf("suppress got %v, want %v", got.GetSuppress(), ra.Suppress))
+	}
+	return errs
+}
+
+// Verify checks the router advertisement state of subinterface 0 of
+// the DUT port against ra.
+func (ra *RouterAdvertisement) Verify(t testing.TB, dut *ondatra.DUTDevice, port *ondatra.Port) {
+	t.Helper()
+	got := dut.Telemetry().Interface(port.Name()).Subinterface(0).Ipv6().RouterAdvertisement().Get(t)
+	for _, err := range ra.Diff(got) {
+		t.Errorf("Router advertisement on %s: %v", port.Name(), err)
+	}
+}
+
+// transitions are the neighbor cache state changes allowed by RFC 4861
+// section 7.3.3 and appendix C.
+var transitions = map[telemetry.E_Neighbor_NeighborState][]telemetry.E_Neighbor_NeighborState{
+	telemetry.Neighbor_NeighborState_INCOMPLETE: {telemetry.Neighbor_NeighborState_REACHABLE, telemetry.Neighbor_NeighborState_STALE},
+	telemetry.Neighbor_NeighborState_REACHABLE:  {telemetry.Neighbor_NeighborState_STALE},
+	telemetry.Neighbor_NeighborState_STALE:      {telemetry.Neighbor_NeighborState_REACHABLE, telemetry.Neighbor_NeighborState_DELAY},
+	telemetry.Neighbor_NeighborState_DELAY:      {telemetry.Neighbor_NeighborState_REACHABLE, telemetry.Neighbor_NeighborState_STALE, telemetry.Neighbor_NeighborState_PROBE},
+	telemetry.Neighbor_NeighborState_PROBE:      {telemetry.Neighbor_NeighborState_REACHABLE, telemetry.Neighbor_NeighborState_STALE},
+}
+
+// CheckTransitions returns an error for each change between successive
+// neighbor states that the state machine of RFC 4861 does not allow.
+// Unset states, e.g. while the neighbor is not in the cache, are
+// skipped.
+func CheckTransitions(states []telemetry.E_Neighbor_NeighborState) []error {
+	var errs []error
+	prev := telemetry.Neighbor_NeighborState_UNSET
+	for _, s := range states {
+		if s == telemetry.Neighbor_NeighborState_UNSET {
+			continue
+		}
+		if prev != telemetry.Neighbor_NeighborState_UNSET && s != prev && !allowed(prev, s) {
+			errs = append(errs, fmt.Errorf("neighbor state changed from %v to %v", prev, s))
+		}
+		prev = s
+	}
+	return errs
+}
+
+func allowed(from, to telemetry.E_Neighbor_NeighborState) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectNeighborStates collects the states of a neighbor of
+// subinterface 0 of the DUT port for the duration.
+func CollectNeighborStates(t testing.TB, dut *ondatra.DUTDevice, port *ondatra.Port, ip string, d time.Duration) []telemetry.E_Neighbor_NeighborState {
+	t.Helper()
+	path := dut.Telemetry().Interface(port.Name()).Subinterface(0).Ipv6().Neighbor(ip).NeighborState()
+	var states []telemetry.E_Neighbor_NeighborState
+	for _, v := range path.Collect(t, d).Await(t) {
+		if v.IsPresent() {
+			states = append(states, v.Val(t))
+		}
+	}
+	return states
+}
+
+// AwaitNeighborState waits for a neighbor of subinterface 0 of the DUT
+// port to be in one of the given states, and reports whether it was
+// before the timeout.
+func AwaitNeighborState(t testing.TB, dut *ondatra.DUTDevice, port *ondatra.Port, ip string, timeout time.Duration, states ...telemetry.E_Neighbor_NeighborState) bool {
+	t.Helper()
+	path := dut.Telemetry().Interface(port.Name()).Subinterface(0).Ipv6().Neighbor(ip).NeighborState()
+	_, ok := path.Watch(t, timeout, func(val *telemetry.QualifiedE_Neighbor_NeighborState) bool {
+		if !val.IsPresent() {
+			return false
+		}
+		got := val.Val(t)
+		for _, s := range states {
+			if got == s {
+				return true
+			}
+		}
+		return false
+	}).Await(t)
+	return ok
+}
+
+// AwaitAddressStatus waits for an IPv6 address of subinterface 0 of the
+// DUT port to reach the status, e.g. DUPLICATE when an ATE host uses
+// the same address, and reports whether it did before the timeout.
+func AwaitAddressStatus(t testing.TB, dut *ondatra.DUTDevice, port *ondatra.Port, ip string, timeout time.Duration, status telemetry.E_Address_Status) bool {
+	t.Helper()
+	path := dut.Telemetry().Interface(port.Name()).Subinterface(0).Ipv6().Address(ip).Status()
+	_, ok := path.Watch(t, timeout, func(val *telemetry.QualifiedE_Address_Status) bool {
+		return val.IsPresent() && val.Val(t) == status
+	}).Await(t)
+	return ok
+}
+
+// HostAddresses returns count consecutive IPv6 addresses starting at
+// first.
+func HostAddresses(first string, count int) ([]string, error) {
+	ip := net.ParseIP(first)
+	if ip == nil || ip.To4() != nil {
+		return nil, fmt.Errorf("%q is not an IPv6 address", first)
+	}
+	n := new(big.Int).SetBytes(ip.To16())
+	limit := new(big.Int).Lsh(big.NewInt(1), 128)
+	var addrs []string
+	for i := 0; i < count; i++ {
+		if n.Cmp(limit) >= 0 {
+			return nil, fmt.Errorf("%d addresses from %s overflow the address space", count, first)
+		}
+		addrs = append(addrs, net.IP(n.FillBytes(make([]byte, net.IPv6len))).String())
+		n.Add(n, big.NewInt(1))
+	}
+	return addrs, nil
+}
+
+// AddHosts adds an emulated host to the ATE topology on the port for
+// each address, in a subnet of the given prefix length with the DUT as
+// the default gateway.  The hosts are named "<port>-host<i>".
+func AddHosts(top *ondatra.ATETopology, port *ondatra.Port, addrs []string, plen uint8, gateway string) []*ondatra.Interface {
+	var hosts []*ondatra.Interface
+	for i, addr := range addrs {
+		h := top.AddInterface(fmt.Sprintf("%s-host%d", port.ID(), i)).WithPort(port)
+		h.IPv6().
+			WithAddress(fmt.Sprintf("%s/%d", addr, plen)).
+			WithDefaultGateway(gateway)
+		hosts = append(hosts, h)
+	}
+	return hosts
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipv6nd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestRouterAdvertisementApply(t *testing.T) {
+	ra := &RouterAdvertisement{Interval: 30, Lifetime: 1800}
+	ipv6 := &telemetry.Interface_Subinterface_Ipv6{}
+	ra.Apply(ipv6)
+	want := &telemetry.Interface_Subinterface_Ipv6_RouterAdvertisement{
+		Interval: ygot.Uint32(30),
+		Lifetime: ygot.Uint32(1800),
+		Suppress: ygot.Bool(false),
+	}
+	if diff := cmp.Diff(want, ipv6.GetRouterAdvertisement()); diff != "" {
+		t.Errorf("Apply got diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRouterAdvertisementDiff(t *testing.T) {
+	cases := []struct {
+		desc    string
+		ra      RouterAdvertisement
+		got     *telemetry.Interface_Subinterface_Ipv6_RouterAdvertisement
+		wantErr int
+	}{{
+		desc: "match",
+		ra:   RouterAdvertisement{Interval: 30, Lifetime: 1800},
+		got: &telemetry.Interface_Subinterface_Ipv6_RouterAdvertisement{
+			Interval: ygot.Uint32(30),
+			Lifetime: ygot.Uint32(1800),
+		},
+	}, {
+		desc: "defaults not compared",
+		ra:   RouterAdvertisement{},
+		got: &telemetry.Interface_Subinterface_Ipv6_RouterAdvertisement{
+			Interval: ygot.Uint32(600),
+		},
+	}, {
+		desc: "mismatch",
+		ra:   RouterAdvertisement{Interval: 30, Lifetime: 1800, Suppress: true},
+		got: &telemetry.Interface_Subinterface_Ipv6_RouterAdvertisement{
+			Interval: ygot.Uint32(600),
+			Lifetime: ygot.Uint32(1800),
+		},
+		wantErr: 2,
+	}}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			if errs := c.ra.Diff(c.got); len(errs) != c.wantErr {
+				t.Errorf("Diff got errors %v, want %d errors", errs, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTransitions(t *testing.T) {
+	const (
+		unset      = telemetry.Neighbor_NeighborState_UNSET
+		incomplete = telemetry.Neighbor_NeighborState_INCOMPLETE
+		reachable  = telemetry.Neighbor_NeighborState_REACHABLE
+		stale      = telemetry.Neighbor_NeighborState_STALE
+		delay      = telemetry.Neighbor_NeighborState_DELAY
+		probe      = telemetry.Neighbor_NeighborState_PROBE
+	)
+	cases := []struct {
+		desc    string
+		states  []telemetry.E_Neighbor_NeighborState
+		wantErr int
+	}{
+		{"resolution", []telemetry.E_Neighbor_NeighborState{incomplete, reachable, stale, delay, probe, reachable}, 0},
+		{"repeated", []telemetry.E_Neighbor_NeighborState{reachable, reachable, stale, stale}, 0},
+		{"unset skipped", []telemetry.E_Neighbor_NeighborState{reachable, unset, stale}, 0},
+		{"reachable to probe", []telemetry.E_Neighbor_NeighborState{reachable, probe}, 1},
+		{"back to incomplete", []telemetry.E_Neighbor_NeighborState{incomplete, reachable, incomplete}, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			if errs := CheckTransitions(c.states); len(errs) != c.wantErr {
+				t.Errorf("CheckTransitions(%v) got errors %v, want %d errors", c.states, errs, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestHostAddresses(t *testing.T) {
+	got, err := HostAddresses("2001:db8::fe", 3)
+	if err != nil {
+		t.Fatalf("HostAddresses got error: %v", err)
+	}
+	want := []string{"2001:db8::fe", "2001:db8::ff", "2001:db8::100"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("HostAddresses got diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestHostAddressesError(t *testing.T) {
+	for _, c := range []struct {
+		first string
+		count int
+	}{
+		{"192.0.2.1", 1},
+		{"bogus", 1},
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", 2},
+	} {
+		if _, err := HostAddresses(c.first, c.count); err == nil {
+			t.Errorf("HostAddresses(%q, %d) got no error, want error", c.first, c.count)
+		}
+	}
+}
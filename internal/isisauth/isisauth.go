@@ -0,0 +1,184 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package isisauth provides helpers to configure the authentication and
+// the hello timers of IS-IS, and to verify from telemetry how the
+// adjacencies react to them.
+//
+// IS-IS authenticates its PDUs with HMAC-MD5 (RFC 5304), configured
+// either as a simple key in MD5 mode or as a keychain of HMAC_MD5 keys.
+// The ATE only authenticates its hellos, with a simple key.
+package isisauth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/bgpauth"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/keychain"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/telemetry/networkinstance"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Keychain returns a keychain with a single HMAC-MD5 key.
+func Keychain(name string, id uint64, secret string) *telemetry.Keychain {
+	return bgpauth.Keychain(name, id, telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_MD5, secret)
+}
+
+// HelloMD5 authenticates the hellos of the interface at the level with
+// the key, or disables their authentication if the key is empty.
+func HelloMD5(intf *telemetry.NetworkInstance_Protocol_Isis_Interface, level uint8, key string) {
+	auth := intf.GetOrCreateLevel(level).GetOrCreateHelloAuthentication()
+	auth.Enabled = ygot.Bool(key != "")
+	if key == "" {
+		return
+	}
+	auth.AuthMode = telemetry.IsisTypes_AUTH_MODE_MD5
+	auth.AuthType = telemetry.KeychainTypes_AUTH_TYPE_SIMPLE_KEY
+	auth.AuthPassword = ygot.String(key)
+}
+
+// HelloKeychain authenticates the hellos of the interface at the level
+// with the keychain.
+func HelloKeychain(intf *telemetry.NetworkInstance_Protocol_Isis_Interface, level uint8, name string) {
+	keychain.BindISISHello(intf, level, name)
+}
+
+// LSPMD5 authenticates the LSPs and SNPs of the level with the key, or
+// disables their authentication if the key is empty.
+func LSPMD5(isis *telemetry.NetworkInstance_Protocol_Isis, level uint8, key string) {
+	l := isis.GetOrCreateLevel(level)
+	l.Enabled = ygot.Bool(true)
+	auth := l.GetOrCreateAuthentication()
+	auth.Enabled = ygot.Bool(key != "")
+	if key == "" {
+		return
+	}
+	auth.AuthMode = telemetry.IsisTypes_AUTH_MODE_MD5
+	auth.AuthType = telemetry.KeychainTypes_AUTH_TYPE_SIMPLE_KEY
+	auth.AuthPassword = ygot.String(key)
+}
+
+// HelloTimers sets the hello interval in seconds of the interface at the
+// level, and the multiplier giving the hold time advertised to the
+// neighbors.
+func HelloTimers(intf *telemetry.NetworkInstance_Protocol_Isis_Interface, level uint8, interval uint32, multiplier uint8) {
+	timers := intf.GetOrCreateLevel(level).GetOrCreateTimers()
+	timers.HelloInterval = ygot.Uint32(interval)
+	timers.HelloMultiplier = ygot.Uint8(multiplier)
+}
+
+// CheckHoldExpiry checks that an adjacency went down after the hold time
+// expired, given the time elapsed since the neighbor stopped sending
+// hellos.  The last hello may have been received up to one hello
+// interval before the neighbor stopped, and the tolerance accounts for
+// the latency of the telemetry.
+func CheckHoldExpiry(elapsed, hold, hello, tolerance time.Duration) error {
+	lo, hi := hold-hello-tolerance, hold+tolerance
+	if elapsed < lo || elapsed > hi {
+		return fmt.Errorf("adjacency went down after %v, want between %v and %v for a hold time of %v", elapsed, lo, hi, hold)
+	}
+	return nil
+}
+
+// interfacePath returns the telemetry of the IS-IS interface of the DUT.
+func interfacePath(dut *ondatra.DUTDevice, instance, intf string) *networkinstance.NetworkInstance_Protocol_Isis_InterfacePath {
+	return dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_ISIS, instance).Isis().Interface(intf)
+}
+
+// up returns whether the sample reports an adjacency up.
+func up(t testing.TB, v *telemetry.QualifiedE_IsisTypes_IsisInterfaceAdjState) bool {
+	return v.IsPresent() && v.Val(t) == telemetry.IsisTypes_IsisInterfaceAdjState_UP
+}
+
+// AwaitUp waits for an adjacency of the IS-IS interface of the DUT to be
+// up, and returns when the test received the update.
+func AwaitUp(t testing.TB, dut *ondatra.DUTDevice, instance, intf string, timeout time.Duration) (time.Time, error) {
+	t.Helper()
+	v, ok := interfacePath(dut, instance, intf).LevelAny().AdjacencyAny().AdjacencyState().Watch(t, timeout,
+		func(v *telemetry.QualifiedE_IsisTypes_IsisInterfaceAdjState) bool {
+			return up(t, v)
+		}).Await(t)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no IS-IS adjacency up on %s after %v", intf, timeout)
+	}
+	return v.RecvTimestamp, nil
+}
+
+// WatchDown starts watching for the adjacencies of the IS-IS interface
+// of the DUT to go down or be removed.  The watcher must be awaited
+// after the change triggering it, and reports when the test received
+// the update in the RecvTimestamp of its value.
+func WatchDown(t testing.TB, dut *ondatra.DUTDevice, instance, intf string, timeout time.Duration) *telemetry.E_IsisTypes_IsisInterfaceAdjStateWatcher {
+	t.Helper()
+	return interfacePath(dut, instance, intf).LevelAny().AdjacencyAny().AdjacencyState().Watch(t, timeout,
+		func(v *telemetry.QualifiedE_IsisTypes_IsisInterfaceAdjState) bool {
+			return !up(t, v)
+		})
+}
+
+// CheckNoAdjacency checks that no adjacency of the IS-IS interface of
+// the DUT comes up for the duration.
+func CheckNoAdjacency(t testing.TB, dut *ondatra.DUTDevice, instance, intf string, duration time.Duration) error {
+	t.Helper()
+	if _, ok := interfacePath(dut, instance, intf).LevelAny().AdjacencyAny().AdjacencyState().Watch(t, duration,
+		func(v *telemetry.QualifiedE_IsisTypes_IsisInterfaceAdjState) bool {
+			return up(t, v)
+		}).Await(t); ok {
+		return fmt.Errorf("IS-IS adjacency came up on %s, want none", intf)
+	}
+	return nil
+}
+
+// NeighborSystemID returns the system ID of the neighbor of the IS-IS
+// interface of the DUT at the level.
+func NeighborSystemID(t testing.TB, dut *ondatra.DUTDevice, instance, intf string, level uint8) (string, error) {
+	t.Helper()
+	ids := interfacePath(dut, instance, intf).Level(level).AdjacencyAny().SystemId().Get(t)
+	if len(ids) != 1 {
+		return "", fmt.Errorf("got %d level %d adjacencies on %s, want 1", len(ids), level, intf)
+	}
+	return ids[0], nil
+}
+
+// AuthFails returns the number of hellos with a wrong authentication
+// received on the IS-IS interface of the DUT, or 0 if it is not
+// reported.
+func AuthFails(t testing.TB, dut *ondatra.DUTDevice, instance, intf string) uint32 {
+	t.Helper()
+	if v := interfacePath(dut, instance, intf).CircuitCounters().AuthFails().Lookup(t); v.IsPresent() {
+		return v.Val(t)
+	}
+	return 0
+}
+
+// LSPAuthFails returns the number of LSPs and SNPs with a wrong
+// authentication received at the level of the IS-IS instance of the
+// DUT, or 0 if it is not reported.
+func LSPAuthFails(t testing.TB, dut *ondatra.DUTDevice, instance string, level uint8) uint32 {
+	t.Helper()
+	v := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_ISIS, instance).Isis().
+		Level(level).SystemLevelCounters().AuthFails().Lookup(t)
+	if v.IsPresent() {
+		return v.Val(t)
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isisauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestHelloMD5(t *testing.T) {
+	intf := &telemetry.NetworkInstance_Protocol_Isis_Interface{}
+	HelloMD5(intf, 2, "secret")
+	want := &telemetry.NetworkInstance_Protocol_Isis_Interface_Level_HelloAuthentication{
+		Enabled:      ygot.Bool(true),
+		AuthMode:     telemetry.IsisTypes_AUTH_MODE_MD5,
+		AuthType:     telemetry.KeychainTypes_AUTH_TYPE_SIMPLE_KEY,
+		AuthPassword: ygot.String("secret"),
+	}
+	if diff := cmp.Diff(want, intf.GetLevel(2).GetHelloAuthentication()); diff != "" {
+		t.Errorf("HelloMD5(secret) -want, +got:\n%s", diff)
+	}
+
+	intf = &telemetry.NetworkInstance_Protocol_Isis_Interface{}
+	HelloMD5(intf, 2, "")
+	want = &telemetry.NetworkInstance_Protocol_Isis_Interface_Level_HelloAuthentication{
+		Enabled: ygot.Bool(false),
+	}
+	if diff := cmp.Diff(want, intf.GetLevel(2).GetHelloAuthentication()); diff != "" {
+		t.Errorf("HelloMD5(\"\") -want, +got:\n%s", diff)
+	}
+}
+
+func TestHelloKeychain(t *testing.T) {
+	intf := &telemetry.NetworkInstance_Protocol_Isis_Interface{}
+	HelloKeychain(intf, 1, "ISIS")
+	want := &telemetry.NetworkInstance_Protocol_Isis_Interface_Level_HelloAuthentication{
+		Enabled:  ygot.Bool(true),
+		AuthType: telemetry.KeychainTypes_AUTH_TYPE_KEYCHAIN,
+		Keychain: ygot.String("ISIS"),
+	}
+	if diff := cmp.Diff(want, intf.GetLevel(1).GetHelloAuthentication()); diff != "" {
+		t.Errorf("HelloKeychain() -want, +got:\n%s", diff)
+	}
+}
+
+func TestLSPMD5(t *testing.T) {
+	isis := &telemetry.NetworkInstance_Protocol_Isis{}
+	LSPMD5(isis, 2, "secret")
+	want := &telemetry.NetworkInstance_Protocol_Isis_Level_Authentication{
+		Enabled:      ygot.Bool(true),
+		AuthMode:     telemetry.IsisTypes_AUTH_MODE_MD5,
+		AuthType:     telemetry.KeychainTypes_AUTH_TYPE_SIMPLE_KEY,
+		AuthPassword: ygot.String("secret"),
+	}
+	if !isis.GetLevel(2).GetEnabled() {
+		t.Errorf("LSPMD5() did not enable level 2")
+	}
+	if diff := cmp.Diff(want, isis.GetLevel(2).GetAuthentication()); diff != "" {
+		t.Errorf("LSPMD5(secret) -want, +got:\n%s", diff)
+	}
+}
+
+func TestHelloTimers(t *testing.T) {
+	intf := &telemetry.NetworkInstance_Protocol_Isis_Interface{}
+	HelloTimers(intf, 2, 1, 3)
+	want := &telemetry.NetworkInstance_Protocol_Isis_Interface_Level_Timers{
+		HelloInterval:   ygot.Uint32(1),
+		HelloMultiplier: ygot.Uint8(3),
+	}
+	if diff := cmp.Diff(want, intf.GetLevel(2).GetTimers()); diff != "" {
+		t.Errorf("HelloTimers() -want, +got:\n%s", diff)
+	}
+}
+
+func TestKeychain(t *testing.T) {
+	kc := Keychain("ISIS", 1, "secret")
+	if got := kc.GetKey(1).CryptoAlgorithm; got != telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_MD5 {
+		t.Errorf("Keychain() key algorithm got %v, want HMAC_MD5", got)
+	}
+}
+
+func TestCheckHoldExpiry(t *testing.T) {
+	const (
+		hold      = 3 * time.Second
+		hello     = time.Second
+		tolerance = 2 * time.Second
+	)
+	for _, c := range []struct {
+		elapsed time.Duration
+		wantErr bool
+	}{
+		{elapsed: 3 * time.Second},
+		{elapsed: 2 * time.Second},
+		{elapsed: 5 * time.Second},
+		{elapsed: 0},
+		{elapsed: 6 * time.Second, wantErr: true},
+		{elapsed: 30 * time.Second, wantErr: true},
+	} {
+		err := CheckHoldExpiry(c.elapsed, hold, hello, tolerance)
+		if (err != nil) != c.wantErr {
+			t.Errorf("CheckHoldExpiry(%v) got error %v, want error %v", c.elapsed, err, c.wantErr)
+		}
+	}
+	if err := CheckHoldExpiry(time.Second, 10*time.Second, hello, tolerance); err == nil {
+		t.Errorf("CheckHoldExpiry(1s) with a hold time of 10s got no error, want error")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package isisdrain provides helpers to gracefully drain an IS-IS router
+// or link, by setting the overload bit or the maximum metric, and to
+// verify from the ATE that traffic shifts away from it within a bounded
+// loss.
+//
+// Setting the overload bit (RFC 3787) only stops the other routers from
+// using the DUT as a transit, so the DUT keeps forwarding along its own
+// shortest paths.  Raising the metric of a link on the DUT shifts its
+// own traffic away from that link.
+package isisdrain
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/isislsdb"
+	"github.com/openconfig/featureprofiles/internal/tolerance"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// MaxMetric is the highest wide metric of a link which is still used as
+// a last resort; links advertised with 0xFFFFFF are not used by SPF
+// (RFC 5305).
+const MaxMetric = 0xFFFFFE
+
+// SetOverload sets or clears the overload bit of the IS-IS instance,
+// optionally advertising its links with the maximum metric instead while
+// it is set.
+func SetOverload(isis *telemetry.NetworkInstance_Protocol_Isis, set, advertiseHighMetric bool) {
+	ob := isis.GetOrCreateGlobal().GetOrCreateLspBit().GetOrCreateOverloadBit()
+	ob.SetBit = ygot.Bool(set)
+	ob.AdvertiseHighMetric = ygot.Bool(set && advertiseHighMetric)
+}
+
+// SetMetric sets the IPv4 and IPv6 unicast metric of the interface at
+// the level.
+func SetMetric(intf *telemetry.NetworkInstance_Protocol_Isis_Interface, level uint8, metric uint32) {
+	l := intf.GetOrCreateLevel(level)
+	for _, afi := range []telemetry.E_IsisTypes_AFI_TYPE{telemetry.IsisTypes_AFI_TYPE_IPV4, telemetry.IsisTypes_AFI_TYPE_IPV6} {
+		af := l.GetOrCreateAf(afi, telemetry.IsisTypes_SAFI_TYPE_UNICAST)
+		af.Metric = ygot.Uint32(metric)
+	}
+}
+
+// Drain sets the metric of the interface at the level to MaxMetric.
+func Drain(intf *telemetry.NetworkInstance_Protocol_Isis_Interface, level uint8) {
+	SetMetric(intf, level, MaxMetric)
+}
+
+// CheckHighMetric checks that the LSP advertises all its neighbors with
+// MaxMetric, as it should while overloaded with AdvertiseHighMetric.
+func CheckHighMetric(lsp *isislsdb.LSP) error {
+	if len(lsp.Neighbors) == 0 {
+		return fmt.Errorf("LSP %s has no neighbors", lsp.ID)
+	}
+	for id, metric := range lsp.Neighbors {
+		if metric < MaxMetric {
+			return fmt.Errorf("LSP %s advertises neighbor %s with metric %d, want %d", lsp.ID, id, metric, MaxMetric)
+		}
+	}
+	return nil
+}
+
+// CheckPath checks that the packets received on each path all went to
+// the path, allowing for a tolerance in percent of the total.
+func CheckPath(counts []uint64, path int, tolerancePct float64) error {
+	if path < 0 || path >= len(counts) {
+		return fmt.Errorf("path %d out of range of %d paths", path, len(counts))
+	}
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return fmt.Errorf("no packets received")
+	}
+	if got := 100 * float64(counts[path]) / float64(total); got < 100-tolerancePct {
+		return fmt.Errorf("path %d received %.2f%% of the packets %v, want at least %v%%", path, got, counts, 100-tolerancePct)
+	}
+	return nil
+}
+
+// LossDuration returns how long a flow sent at fps frames per second was
+// interrupted, given the packets it sent and received.
+func LossDuration(tx, rx, fps uint64) time.Duration {
+	if rx >= tx || fps == 0 {
+		return 0
+	}
+	return time.Duration(tx-rx) * time.Second / time.Duration(fps)
+}
+
+// CheckLoss checks that a flow sent at fps frames per second was not
+// interrupted for longer than the bound, or the tolerance_max_convergence
+// argument if set.
+func CheckLoss(tx, rx, fps uint64, bound time.Duration) error {
+	bound = tolerance.MaxConvergence(bound)
+	if tx == 0 {
+		return fmt.Errorf("no packets sent")
+	}
+	if d := LossDuration(tx, rx, fps); d > bound {
+		return fmt.Errorf("lost %d of %d packets, an interruption of %v, want at most %v", tx-rx, tx, d, bound)
+	}
+	return nil
+}
+
+// ReadLoss returns the packets sent and received by the flow on the ATE.
+func ReadLoss(t testing.TB, ate *ondatra.ATEDevice, flow string) (tx, rx uint64) {
+	t.Helper()
+	c := ate.Telemetry().Flow(flow).Counters()
+	return c.OutPkts().Get(t), c.InPkts().Get(t)
+}
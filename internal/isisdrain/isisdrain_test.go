@@ -0,0 +1,147 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isisdrain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/isislsdb"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestSetOverload(t *testing.T) {
+	for _, c := range []struct {
+		desc            string
+		set, highMetric bool
+		want            *telemetry.NetworkInstance_Protocol_Isis_Global_LspBit_OverloadBit
+	}{{
+		desc: "set",
+		set:  true,
+		want: &telemetry.NetworkInstance_Protocol_Isis_Global_LspBit_OverloadBit{
+			SetBit:              ygot.Bool(true),
+			AdvertiseHighMetric: ygot.Bool(false),
+		},
+	}, {
+		desc:       "set with high metric",
+		set:        true,
+		highMetric: true,
+		want: &telemetry.NetworkInstance_Protocol_Isis_Global_LspBit_OverloadBit{
+			SetBit:              ygot.Bool(true),
+			AdvertiseHighMetric: ygot.Bool(true),
+		},
+	}, {
+		desc:       "cleared",
+		highMetric: true,
+		want: &telemetry.NetworkInstance_Protocol_Isis_Global_LspBit_OverloadBit{
+			SetBit:              ygot.Bool(false),
+			AdvertiseHighMetric: ygot.Bool(false),
+		},
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			isis := &telemetry.NetworkInstance_Protocol_Isis{}
+			SetOverload(isis, c.set, c.highMetric)
+			got := isis.GetGlobal().GetLspBit().GetOverloadBit()
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("SetOverload(%v, %v) -want, +got:\n%s", c.set, c.highMetric, diff)
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	intf := &telemetry.NetworkInstance_Protocol_Isis_Interface{}
+	SetMetric(intf, 2, 10)
+	Drain(intf, 2)
+	for _, afi := range []telemetry.E_IsisTypes_AFI_TYPE{telemetry.IsisTypes_AFI_TYPE_IPV4, telemetry.IsisTypes_AFI_TYPE_IPV6} {
+		if got := intf.GetLevel(2).GetAf(afi, telemetry.IsisTypes_SAFI_TYPE_UNICAST).GetMetric(); got != MaxMetric {
+			t.Errorf("Drain() %v metric got %d, want %d", afi, got, MaxMetric)
+		}
+	}
+	if intf.GetLevel(1) != nil {
+		t.Errorf("Drain() at level 2 created level 1")
+	}
+}
+
+func TestCheckHighMetric(t *testing.T) {
+	for _, c := range []struct {
+		desc      string
+		neighbors map[string]uint32
+		wantErr   bool
+	}{
+		{"all high", map[string]uint32{"1920.0000.2002.00": MaxMetric, "1920.0000.2003.00": 0xFFFFFF}, false},
+		{"one low", map[string]uint32{"1920.0000.2002.00": MaxMetric, "1920.0000.2003.00": 10}, true},
+		{"no neighbors", nil, true},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			lsp := &isislsdb.LSP{ID: "1920.0000.2001.00-00", Neighbors: c.neighbors}
+			if err := CheckHighMetric(lsp); (err != nil) != c.wantErr {
+				t.Errorf("CheckHighMetric(%v) got error %v, want error %v", c.neighbors, err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		counts  []uint64
+		path    int
+		wantErr bool
+	}{
+		{"all on path", []uint64{0, 1000}, 1, false},
+		{"within tolerance", []uint64{5, 995}, 1, false},
+		{"on other path", []uint64{1000, 0}, 1, true},
+		{"split", []uint64{500, 500}, 1, true},
+		{"third path", []uint64{0, 800, 200}, 1, true},
+		{"nothing received", []uint64{0, 0}, 1, true},
+		{"out of range", []uint64{0, 1000}, 2, true},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			if err := CheckPath(c.counts, c.path, 1); (err != nil) != c.wantErr {
+				t.Errorf("CheckPath(%v, %d) got error %v, want error %v", c.counts, c.path, err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckLoss(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		tx, rx  uint64
+		want    time.Duration
+		wantErr bool
+	}{
+		{"no loss", 10000, 10000, 0, false},
+		{"within bound", 10000, 9500, 50 * time.Millisecond, false},
+		{"beyond bound", 10000, 8000, 200 * time.Millisecond, true},
+		{"duplicates", 10000, 10002, 0, false},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			if got := LossDuration(c.tx, c.rx, 10000); got != c.want {
+				t.Errorf("LossDuration(%d, %d, 10000) got %v, want %v", c.tx, c.rx, got, c.want)
+			}
+			if err := CheckLoss(c.tx, c.rx, 10000, 100*time.Millisecond); (err != nil) != c.wantErr {
+				t.Errorf("CheckLoss(%d, %d) got error %v, want error %v", c.tx, c.rx, err, c.wantErr)
+			}
+		})
+	}
+	if err := CheckLoss(0, 0, 10000, time.Second); err == nil {
+		t.Errorf("CheckLoss() with no packets sent got no error")
+	}
+}
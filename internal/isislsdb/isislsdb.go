@@ -0,0 +1,414 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package isislsdb decodes the IS-IS link state database reported by
+// the DUT through OpenConfig telemetry, and compares the TLVs of an LSP
+// against what a neighbor, e.g. the ATE, is expected to advertise.  It
+// covers the extended IS reachability (TLV 22), the extended IPv4 and
+// IPv6 reachability (TLVs 135 and 236) with their prefix SIDs, and the
+// router capability (TLV 242) with its segment routing sub-TLVs.
+package isislsdb
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// PrefixSID is a prefix segment identifier.
+type PrefixSID struct {
+	Value     uint32
+	Algorithm uint8
+	// Node is the N flag, set for node SIDs.
+	Node bool
+	// Absolute is the V flag, set when the value is a label rather
+	// than an index into the SRGB.
+	Absolute bool
+}
+
+// Prefix is a reachable IPv4 or IPv6 prefix.
+type Prefix struct {
+	Metric uint32
+	// SID is the prefix SID, nil if none.
+	SID *PrefixSID
+}
+
+// LabelRange is a range of MPLS labels, such as an SRGB descriptor.
+type LabelRange struct {
+	Start, Size uint32
+}
+
+// Capability is the router capability of an LSP.
+type Capability struct {
+	RouterID string
+	// SRGB is the segment routing global block, sorted by start label.
+	SRGB []LabelRange
+	// Algorithms are the segment routing algorithms, 0 being SPF.
+	Algorithms []uint8
+}
+
+// LSP is a decoded LSP.
+type LSP struct {
+	ID         string
+	SystemID   string
+	Pseudonode uint8
+	Fragment   uint8
+	Sequence   uint32
+	// Neighbors maps the neighbor IDs, a system ID followed by the
+	// pseudonode ID, to the lowest metric advertised to them.
+	Neighbors map[string]uint32
+	// Prefixes maps the reachable prefixes in CIDR notation to their
+	// attributes.
+	Prefixes map[string]*Prefix
+	// Capability is nil if the LSP has no router capability TLV.
+	Capability *Capability
+}
+
+// Database is a decoded link state database.
+type Database struct {
+	// LSPs maps the LSP IDs to the LSPs.
+	LSPs map[string]*LSP
+}
+
+// FormatSystemID returns the system ID in the dotted xxxx.xxxx.xxxx
+// form, accepting it with or without dots.  A trailing pseudonode ID,
+// e.g. in neighbor IDs, is kept as a fourth dotted octet.
+func FormatSystemID(id string) (string, error) {
+	hex := strings.ToLower(strings.ReplaceAll(id, ".", ""))
+	if len(hex) != 12 && len(hex) != 14 {
+		return "", fmt.Errorf("invalid system ID %q", id)
+	}
+	for _, c := range hex {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return "", fmt.Errorf("invalid system ID %q", id)
+		}
+	}
+	s := hex[0:4] + "." + hex[4:8] + "." + hex[8:12]
+	if len(hex) == 14 {
+		s += "." + hex[12:]
+	}
+	return s, nil
+}
+
+// parseLSPID parses an LSP ID of the form xxxx.xxxx.xxxx.pp-ff.
+func parseLSPID(id string) (sysID string, pseudonode, fragment uint8, err error) {
+	i := strings.LastIndex(id, "-")
+	if i < 0 {
+		return "", 0, 0, fmt.Errorf("invalid LSP ID %q", id)
+	}
+	nodeID, err := FormatSystemID(id[:i])
+	if err != nil || len(nodeID) != len("xxxx.xxxx.xxxx.pp") {
+		return "", 0, 0, fmt.Errorf("invalid LSP ID %q", id)
+	}
+	if _, err := fmt.Sscanf(nodeID[15:]+" "+id[i+1:], "%x %x", &pseudonode, &fragment); err != nil {
+		return "", 0, 0, fmt.Errorf("invalid LSP ID %q: %w", id, err)
+	}
+	return nodeID[:14], pseudonode, fragment, nil
+}
+
+// normalizePrefix returns the prefix in canonical CIDR notation.
+func normalizePrefix(prefix string) (string, error) {
+	_, n, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return "", err
+	}
+	return n.String(), nil
+}
+
+// prefixSID decodes a prefix SID.
+func prefixSID(value uint32, algorithm uint8, flags []telemetry.E_PrefixSid_Flags) *PrefixSID {
+	sid := &PrefixSID{Value: value, Algorithm: algorithm}
+	for _, f := range flags {
+		switch f {
+		case telemetry.PrefixSid_Flags_NODE:
+			sid.Node = true
+		case telemetry.PrefixSid_Flags_VALUE:
+			sid.Absolute = true
+		}
+	}
+	return sid
+}
+
+// lowestKey returns the lowest of the keys.
+func lowestKey(keys []uint32) uint32 {
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys[0]
+}
+
+// Decode decodes the LSPs reported by the DUT.
+func Decode(lsps []*telemetry.NetworkInstance_Protocol_Isis_Level_Lsp) (*Database, error) {
+	db := &Database{LSPs: map[string]*LSP{}}
+	for _, l := range lsps {
+		sysID, pseudonode, fragment, err := parseLSPID(l.GetLspId())
+		if err != nil {
+			return nil, err
+		}
+		lsp := &LSP{
+			ID:         l.GetLspId(),
+			SystemID:   sysID,
+			Pseudonode: pseudonode,
+			Fragment:   fragment,
+			Sequence:   l.GetSequenceNumber(),
+			Neighbors:  map[string]uint32{},
+			Prefixes:   map[string]*Prefix{},
+		}
+		if err := lsp.decodeTLVs(l.Tlv); err != nil {
+			return nil, fmt.Errorf("LSP %s: %w", lsp.ID, err)
+		}
+		db.LSPs[lsp.ID] = lsp
+	}
+	return db, nil
+}
+
+// decodeTLVs decodes the TLVs of the LSP.
+func (lsp *LSP) decodeTLVs(tlvs map[telemetry.E_IsisLsdbTypes_ISIS_TLV_TYPE]*telemetry.NetworkInstance_Protocol_Isis_Level_Lsp_Tlv) error {
+	if tlv := tlvs[telemetry.IsisLsdbTypes_ISIS_TLV_TYPE_EXTENDED_IS_REACHABILITY]; tlv != nil {
+		for id, n := range tlv.GetExtendedIsReachability().Neighbor {
+			nid, err := FormatSystemID(id)
+			if err != nil {
+				return err
+			}
+			for _, inst := range n.Instance {
+				if m, ok := lsp.Neighbors[nid]; !ok || inst.GetMetric() < m {
+					lsp.Neighbors[nid] = inst.GetMetric()
+				}
+			}
+		}
+	}
+
+	if tlv := tlvs[telemetry.IsisLsdbTypes_ISIS_TLV_TYPE_EXTENDED_IPV4_REACHABILITY]; tlv != nil {
+		for p, r := range tlv.GetExtendedIpv4Reachability().Prefix {
+			prefix, err := normalizePrefix(p)
+			if err != nil {
+				return err
+			}
+			attrs := &Prefix{Metric: r.GetMetric()}
+			if sub := r.Subtlv[telemetry.IsisLsdbTypes_ISIS_SUBTLV_TYPE_IP_REACHABILITY_PREFIX_SID]; sub != nil && len(sub.PrefixSid) > 0 {
+				var keys []uint32
+				for k := range sub.PrefixSid {
+					keys = append(keys, k)
+				}
+				s := sub.PrefixSid[lowestKey(keys)]
+				attrs.SID = prefixSID(s.GetValue(), s.GetAlgorithm(), s.Flags)
+			}
+			lsp.Prefixes[prefix] = attrs
+		}
+	}
+
+	if tlv := tlvs[telemetry.IsisLsdbTypes_ISIS_TLV_TYPE_IPV6_REACHABILITY]; tlv != nil {
+		for p, r := range tlv.GetIpv6Reachability().Prefix {
+			prefix, err := normalizePrefix(p)
+			if err != nil {
+				return err
+			}
+			attrs := &Prefix{Metric: r.GetMetric()}
+			if sub := r.Subtlv[telemetry.IsisLsdbTypes_ISIS_SUBTLV_TYPE_IP_REACHABILITY_PREFIX_SID]; sub != nil && len(sub.PrefixSid) > 0 {
+				var keys []uint32
+				for k := range sub.PrefixSid {
+					keys = append(keys, k)
+				}
+				s := sub.PrefixSid[lowestKey(keys)]
+				attrs.SID = prefixSID(s.GetValue(), s.GetAlgorithm(), s.Flags)
+			}
+			lsp.Prefixes[prefix] = attrs
+		}
+	}
+
+	if tlv := tlvs[telemetry.IsisLsdbTypes_ISIS_TLV_TYPE_ROUTER_CAPABILITY]; tlv != nil {
+		for _, c := range tlv.Capability {
+			if lsp.Capability == nil {
+				lsp.Capability = &Capability{}
+			}
+			lsp.Capability.decode(c)
+		}
+	}
+	return nil
+}
+
+// decode merges a router capability TLV into the capability.
+func (c *Capability) decode(tlv *telemetry.NetworkInstance_Protocol_Isis_Level_Lsp_Tlv_Capability) {
+	if id := tlv.GetRouterId(); id != "" {
+		c.RouterID = id
+	}
+	if sub := tlv.Subtlv[telemetry.IsisLsdbTypes_ISIS_SUBTLV_TYPE_ROUTER_CAPABILITY_SR_CAPABILITY]; sub != nil {
+		for _, d := range sub.GetSegmentRoutingCapability().SrgbDescriptor {
+			// Reserved label values are not valid SRGB starts.
+			if start, ok := d.GetLabel().(telemetry.UnionUint32); ok {
+				c.SRGB = append(c.SRGB, LabelRange{Start: uint32(start), Size: d.GetRange()})
+			}
+		}
+		sort.Slice(c.SRGB, func(i, j int) bool { return c.SRGB[i].Start < c.SRGB[j].Start })
+	}
+	if sub := tlv.Subtlv[telemetry.IsisLsdbTypes_ISIS_SUBTLV_TYPE_ROUTER_CAPABILITY_SR_ALGORITHM]; sub != nil {
+		for _, a := range sub.GetSegmentRoutingAlgorithms().Algorithm {
+			// The enumeration starts at 1 with SPF, which is algorithm 0.
+			if a != telemetry.SegmentRoutingAlgorithms_Algorithm_UNSET {
+				c.Algorithms = append(c.Algorithms, uint8(a-1))
+			}
+		}
+	}
+}
+
+// Read returns the decoded LSDB of the level of the IS-IS instance of
+// the DUT.
+func Read(t testing.TB, dut *ondatra.DUTDevice, instance string, level uint8) *Database {
+	t.Helper()
+	lsps := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).
+		Protocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_ISIS, instance).
+		Isis().Level(level).LspAny().Get(t)
+	db, err := Decode(lsps)
+	if err != nil {
+		t.Fatalf("Cannot decode the level %d LSDB: %v", level, err)
+	}
+	return db
+}
+
+// System returns the non-pseudonode LSP of the system, with the TLVs of
+// all its fragments merged, or nil if there is none.
+func (db *Database) System(sysID string) *LSP {
+	id, err := FormatSystemID(sysID)
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for lspID, l := range db.LSPs {
+		if l.SystemID == id && l.Pseudonode == 0 {
+			ids = append(ids, lspID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Strings(ids)
+	merged := &LSP{
+		ID:        ids[0],
+		SystemID:  id,
+		Sequence:  db.LSPs[ids[0]].Sequence,
+		Neighbors: map[string]uint32{},
+		Prefixes:  map[string]*Prefix{},
+	}
+	for _, lspID := range ids {
+		l := db.LSPs[lspID]
+		for n, m := range l.Neighbors {
+			merged.Neighbors[n] = m
+		}
+		for p, attrs := range l.Prefixes {
+			merged.Prefixes[p] = attrs
+		}
+		if l.Capability != nil && merged.Capability == nil {
+			merged.Capability = l.Capability
+		}
+	}
+	return merged
+}
+
+// Advertisement is what a system is expected to advertise.  Only the
+// neighbors, prefixes and capability fields given are compared, so the
+// LSP may contain more.
+type Advertisement struct {
+	SystemID string
+	// Neighbors maps the neighbor IDs to their metric.
+	Neighbors map[string]uint32
+	// Prefixes maps the prefixes to their attributes.
+	Prefixes map[string]*Prefix
+	// Capability is not compared if nil.  Its router ID is not compared
+	// if empty, and neither are its SRGB and algorithms if nil.
+	Capability *Capability
+}
+
+// Diff returns the differences between the LSP of the system in the
+// database and the advertisement.
+func (db *Database) Diff(want *Advertisement) []error {
+	lsp := db.System(want.SystemID)
+	if lsp == nil {
+		return []error{fmt.Errorf("no LSP from system %s", want.SystemID)}
+	}
+	var errs []error
+	errorf := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("LSP %s: "+format, append([]interface{}{lsp.ID}, args...)...))
+	}
+
+	var nids []string
+	for n := range want.Neighbors {
+		nids = append(nids, n)
+	}
+	sort.Strings(nids)
+	for _, n := range nids {
+		nid, err := FormatSystemID(n)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		switch got, ok := lsp.Neighbors[nid]; {
+		case !ok:
+			errorf("no extended IS reachability to %s", nid)
+		case got != want.Neighbors[n]:
+			errorf("extended IS reachability to %s metric got %d, want %d", nid, got, want.Neighbors[n])
+		}
+	}
+
+	var prefixes []string
+	for p := range want.Prefixes {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	for _, p := range prefixes {
+		prefix, err := normalizePrefix(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		w := want.Prefixes[p]
+		got, ok := lsp.Prefixes[prefix]
+		if !ok {
+			errorf("no reachability to %s", prefix)
+			continue
+		}
+		if got.Metric != w.Metric {
+			errorf("reachability to %s metric got %d, want %d", prefix, got.Metric, w.Metric)
+		}
+		switch {
+		case w.SID == nil:
+		case got.SID == nil:
+			errorf("reachability to %s has no prefix SID, want %+v", prefix, *w.SID)
+		case *got.SID != *w.SID:
+			errorf("reachability to %s prefix SID got %+v, want %+v", prefix, *got.SID, *w.SID)
+		}
+	}
+
+	if w := want.Capability; w != nil {
+		got := lsp.Capability
+		if got == nil {
+			errorf("no router capability")
+			return errs
+		}
+		if w.RouterID != "" && got.RouterID != w.RouterID {
+			errorf("router capability router ID got %q, want %q", got.RouterID, w.RouterID)
+		}
+		if w.SRGB != nil && fmt.Sprint(got.SRGB) != fmt.Sprint(w.SRGB) {
+			errorf("SRGB got %v, want %v", got.SRGB, w.SRGB)
+		}
+		if w.Algorithms != nil && fmt.Sprint(got.Algorithms) != fmt.Sprint(w.Algorithms) {
+			errorf("SR algorithms got %v, want %v", got.Algorithms, w.Algorithms)
+		}
+	}
+	return errs
+}
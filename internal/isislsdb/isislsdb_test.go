@@ -0,0 +1,210 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package isislsdb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestFormatSystemID(t *testing.T) {
+	cases := []struct {
+		id, want string
+	}{
+		{"1920.0000.2001", "1920.0000.2001"},
+		{"19200000200A", "1920.0000.200a"},
+		{"1920.0000.2001.01", "1920.0000.2001.01"},
+	}
+	for _, c := range cases {
+		got, err := FormatSystemID(c.id)
+		if err != nil {
+			t.Errorf("FormatSystemID(%q) got error: %v", c.id, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("FormatSystemID(%q) got %q, want %q", c.id, got, c.want)
+		}
+	}
+	for _, id := range []string{"", "1920.0000", "1920.0000.200g"} {
+		if _, err := FormatSystemID(id); err == nil {
+			t.Errorf("FormatSystemID(%q) got no error, want error", id)
+		}
+	}
+}
+
+func TestParseLSPID(t *testing.T) {
+	sysID, pseudonode, fragment, err := parseLSPID("1920.0000.2001.0a-1f")
+	if err != nil {
+		t.Fatalf("parseLSPID got error: %v", err)
+	}
+	if sysID != "1920.0000.2001" || pseudonode != 0x0a || fragment != 0x1f {
+		t.Errorf("parseLSPID got %q, %d, %d, want %q, 10, 31", sysID, pseudonode, fragment, "1920.0000.2001")
+	}
+	for _, id := range []string{"1920.0000.2001", "1920.0000.2001-00", "1920.0000.2001.00-zz"} {
+		if _, _, _, err := parseLSPID(id); err == nil {
+			t.Errorf("parseLSPID(%q) got no error, want error", id)
+		}
+	}
+}
+
+// ateLSPs returns the fragments of an LSP as reported by the DUT.
+func ateLSPs() []*telemetry.NetworkInstance_Protocol_Isis_Level_Lsp {
+	frag0 := &telemetry.NetworkInstance_Protocol_Isis_Level_Lsp{
+		LspId:          ygot.String("6400.0000.0001.00-00"),
+		SequenceNumber: ygot.Uint32(7),
+	}
+	isr := frag0.GetOrCreateTlv(telemetry.IsisLsdbTypes_ISIS_TLV_TYPE_EXTENDED_IS_REACHABILITY).GetOrCreateExtendedIsReachability()
+	n := isr.GetOrCreateNeighbor("1920.0000.2001.00")
+	n.GetOrCreateInstance(0).Metric = ygot.Uint32(20)
+	n.GetOrCreateInstance(1).Metric = ygot.Uint32(10)
+
+	v4 := frag0.GetOrCreateTlv(telemetry.IsisLsdbTypes_ISIS_TLV_TYPE_EXTENDED_IPV4_REACHABILITY).GetOrCreateExtendedIpv4Reachability()
+	p4 := v4.GetOrCreatePrefix("198.51.100.0/24")
+	p4.Metric = ygot.Uint32(10)
+	sid := p4.GetOrCreateSubtlv(telemetry.IsisLsdbTypes_ISIS_SUBTLV_TYPE_IP_REACHABILITY_PREFIX_SID).GetOrCreatePrefixSid(100)
+	sid.Algorithm = ygot.Uint8(0)
+	sid.Flags = []telemetry.E_PrefixSid_Flags{telemetry.PrefixSid_Flags_NODE}
+
+	rc := frag0.GetOrCreateTlv(telemetry.IsisLsdbTypes_ISIS_TLV_TYPE_ROUTER_CAPABILITY).GetOrCreateCapability(0)
+	rc.RouterId = ygot.String("192.0.2.2")
+	srCap := rc.GetOrCreateSubtlv(telemetry.IsisLsdbTypes_ISIS_SUBTLV_TYPE_ROUTER_CAPABILITY_SR_CAPABILITY).GetOrCreateSegmentRoutingCapability()
+	srgb := srCap.GetOrCreateSrgbDescriptor(16000)
+	srgb.Label = telemetry.UnionUint32(16000)
+	srgb.Range = ygot.Uint32(8000)
+	rc.GetOrCreateSubtlv(telemetry.IsisLsdbTypes_ISIS_SUBTLV_TYPE_ROUTER_CAPABILITY_SR_ALGORITHM).GetOrCreateSegmentRoutingAlgorithms().Algorithm =
+		[]telemetry.E_SegmentRoutingAlgorithms_Algorithm{telemetry.SegmentRoutingAlgorithms_Algorithm_SPF, telemetry.SegmentRoutingAlgorithms_Algorithm_STRICT_SPF}
+
+	frag1 := &telemetry.NetworkInstance_Protocol_Isis_Level_Lsp{
+		LspId:          ygot.String("6400.0000.0001.00-01"),
+		SequenceNumber: ygot.Uint32(3),
+	}
+	v6 := frag1.GetOrCreateTlv(telemetry.IsisLsdbTypes_ISIS_TLV_TYPE_IPV6_REACHABILITY).GetOrCreateIpv6Reachability()
+	v6.GetOrCreatePrefix("2001:db8:1::/64").Metric = ygot.Uint32(10)
+
+	pseudonode := &telemetry.NetworkInstance_Protocol_Isis_Level_Lsp{
+		LspId: ygot.String("6400.0000.0001.01-00"),
+	}
+	pseudonode.GetOrCreateTlv(telemetry.IsisLsdbTypes_ISIS_TLV_TYPE_EXTENDED_IS_REACHABILITY).GetOrCreateExtendedIsReachability().
+		GetOrCreateNeighbor("1920.0000.2002.00").GetOrCreateInstance(0).Metric = ygot.Uint32(0)
+
+	return []*telemetry.NetworkInstance_Protocol_Isis_Level_Lsp{frag0, frag1, pseudonode}
+}
+
+func TestDecode(t *testing.T) {
+	db, err := Decode(ateLSPs())
+	if err != nil {
+		t.Fatalf("Decode got error: %v", err)
+	}
+	want := &LSP{
+		ID:        "6400.0000.0001.00-00",
+		SystemID:  "6400.0000.0001",
+		Sequence:  7,
+		Neighbors: map[string]uint32{"1920.0000.2001.00": 10},
+		Prefixes: map[string]*Prefix{
+			"198.51.100.0/24": {Metric: 10, SID: &PrefixSID{Value: 100, Node: true}},
+			"2001:db8:1::/64": {Metric: 10},
+		},
+		Capability: &Capability{
+			RouterID:   "192.0.2.2",
+			SRGB:       []LabelRange{{Start: 16000, Size: 8000}},
+			Algorithms: []uint8{0, 1},
+		},
+	}
+	if diff := cmp.Diff(want, db.System("640000000001")); diff != "" {
+		t.Errorf("System got diff (-want +got):\n%s", diff)
+	}
+	if got := db.LSPs["6400.0000.0001.01-00"].Pseudonode; got != 1 {
+		t.Errorf("Pseudonode got %d, want 1", got)
+	}
+	if got := db.System("6400.0000.0002"); got != nil {
+		t.Errorf("System of unknown system got %+v, want nil", got)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	lsps := []*telemetry.NetworkInstance_Protocol_Isis_Level_Lsp{{LspId: ygot.String("bogus")}}
+	if _, err := Decode(lsps); err == nil {
+		t.Error("Decode got no error, want error")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	db, err := Decode(ateLSPs())
+	if err != nil {
+		t.Fatalf("Decode got error: %v", err)
+	}
+	cases := []struct {
+		desc string
+		want *Advertisement
+		errs []string
+	}{{
+		desc: "match",
+		want: &Advertisement{
+			SystemID:  "6400.0000.0001",
+			Neighbors: map[string]uint32{"1920.0000.2001.00": 10},
+			Prefixes: map[string]*Prefix{
+				"198.51.100.1/24": {Metric: 10, SID: &PrefixSID{Value: 100, Node: true}},
+				"2001:db8:1::/64": {Metric: 10},
+			},
+			Capability: &Capability{SRGB: []LabelRange{{Start: 16000, Size: 8000}}},
+		},
+	}, {
+		desc: "no lsp",
+		want: &Advertisement{SystemID: "6400.0000.0002"},
+		errs: []string{"no LSP from system"},
+	}, {
+		desc: "mismatches",
+		want: &Advertisement{
+			SystemID: "6400.0000.0001",
+			Neighbors: map[string]uint32{
+				"1920.0000.2001.00": 20,
+				"1920.0000.2003.00": 10,
+			},
+			Prefixes: map[string]*Prefix{
+				"198.51.100.0/24": {Metric: 10, SID: &PrefixSID{Value: 101, Node: true}},
+				"2001:db8:1::/64": {Metric: 10, SID: &PrefixSID{Value: 200}},
+				"2001:db8:2::/64": {Metric: 10},
+			},
+			Capability: &Capability{RouterID: "192.0.2.1", Algorithms: []uint8{0}},
+		},
+		errs: []string{
+			"1920.0000.2001.00 metric got 10, want 20",
+			"no extended IS reachability to 1920.0000.2003.00",
+			"198.51.100.0/24 prefix SID got",
+			"2001:db8:1::/64 has no prefix SID",
+			"no reachability to 2001:db8:2::/64",
+			"router ID got",
+			"SR algorithms got",
+		},
+	}}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			errs := db.Diff(c.want)
+			if len(errs) != len(c.errs) {
+				t.Fatalf("Diff got errors %v, want %d errors", errs, len(c.errs))
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), c.errs[i]) {
+					t.Errorf("Diff error %d got %q, want it to contain %q", i, err, c.errs[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keychain builds OpenConfig keychains whose keys are sent and
+// accepted for bounded lifetimes, to roll the keys of a protocol over in
+// service, and binds them to the protocols.
+//
+// A rollover is in service, or hitless, if the peers always accept the key
+// sent, even with their clocks apart: the key rolled over is accepted for a
+// while after the new key is first sent, and the new key for a while before.
+//
+// IS-IS references keychains in OpenConfig, for its hellos and its LSPs.
+// Binding a keychain to a BGP neighbor for TCP-AO is not modeled, so it is
+// expected to be configured out of band, see -deviation_bgp_tcp_ao.  LDP
+// only models a single MD5 key, so it is configured with the key sent at a
+// given time, and its rollovers are not in service.
+package keychain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Lifetime is the time a key is sent or accepted, from its start included
+// to its end excluded.  A zero start or end is unbounded.
+type Lifetime struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains returns whether the lifetime contains the time.
+func (l Lifetime) Contains(at time.Time) bool {
+	return (l.Start.IsZero() || !at.Before(l.Start)) && (l.End.IsZero() || at.Before(l.End))
+}
+
+// equal returns whether two lifetimes are the same.
+func (l Lifetime) equal(o Lifetime) bool {
+	return l.Start.Equal(o.Start) && l.End.Equal(o.End)
+}
+
+// Key is a key of a keychain, with the lifetimes it is sent and accepted
+// for.  A key with zero lifetimes is sent and accepted at all times.
+type Key struct {
+	ID        uint64
+	Algorithm telemetry.E_KeychainTypes_CRYPTO_TYPE
+	Secret    string
+	Send      Lifetime
+	Receive   Lifetime
+}
+
+// nanos returns a time in nanoseconds since the Unix epoch, as the
+// lifetimes of the keys are configured, or nil if it is zero.
+func nanos(t time.Time) *uint64 {
+	if t.IsZero() {
+		return nil
+	}
+	return ygot.Uint64(uint64(t.UnixNano()))
+}
+
+// New returns a keychain with the keys.
+func New(name string, keys ...Key) *telemetry.Keychain {
+	kc := &telemetry.Keychain{Name: ygot.String(name)}
+	for _, k := range keys {
+		key := kc.GetOrCreateKey(k.ID)
+		key.CryptoAlgorithm = k.Algorithm
+		key.SecretKey = ygot.String(k.Secret)
+		send := key.GetOrCreateSendLifetime()
+		send.StartTime = nanos(k.Send.Start)
+		send.EndTime = nanos(k.Send.End)
+		send.SendAndReceive = ygot.Bool(k.Send.equal(k.Receive))
+		if k.Send.equal(k.Receive) {
+			continue
+		}
+		recv := key.GetOrCreateReceiveLifetime()
+		recv.StartTime = nanos(k.Receive.Start)
+		recv.EndTime = nanos(k.Receive.End)
+	}
+	return kc
+}
+
+// Rollover returns the keys rolling the key from over to the key to at the
+// given time: from is sent until then and to after.  Each is accepted for
+// the overlap on the other side of the rollover, to tolerate clocks apart
+// by as much.
+func Rollover(from, to Key, at time.Time, overlap time.Duration) []Key {
+	from.Send.End = at
+	from.Receive.End = at.Add(overlap)
+	to.Send.Start = at
+	to.Receive.Start = at.Add(-overlap)
+	return []Key{from, to}
+}
+
+// SendKey returns the key sent at the given time: of the keys whose send
+// lifetime contains it, the one which started the latest.
+func SendKey(keys []Key, at time.Time) (Key, bool) {
+	var sent Key
+	found := false
+	for _, k := range keys {
+		if !k.Send.Contains(at) {
+			continue
+		}
+		if !found || k.Send.Start.After(sent.Send.Start) || (k.Send.Start.Equal(sent.Send.Start) && k.ID > sent.ID) {
+			sent, found = k, true
+		}
+	}
+	return sent, found
+}
+
+// CheckHitless checks that peers with the keys accept the key sent at any
+// time from start on, with clocks up to skew apart: a key is sent at all
+// times, and accepted from skew before it is first sent until skew after it
+// is last sent.
+func CheckHitless(keys []Key, start time.Time, skew time.Duration) error {
+	// The key sent only changes at the start or end of a send lifetime.
+	times := []time.Time{start}
+	for _, k := range keys {
+		for _, b := range []time.Time{k.Send.Start, k.Send.End} {
+			if b.After(start) {
+				times = append(times, b)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	for i, from := range times {
+		k, ok := SendKey(keys, from)
+		if !ok {
+			return fmt.Errorf("no key sent at %v", from)
+		}
+		if !k.Receive.Start.IsZero() && k.Receive.Start.After(from.Add(-skew)) {
+			return fmt.Errorf("key %d sent at %v is accepted from %v, want from %v", k.ID, from, k.Receive.Start, from.Add(-skew))
+		}
+		if i == len(times)-1 {
+			if !k.Receive.End.IsZero() {
+				return fmt.Errorf("key %d sent from %v on is accepted until %v", k.ID, from, k.Receive.End)
+			}
+			continue
+		}
+		if to := times[i+1]; !k.Receive.End.IsZero() && k.Receive.End.Before(to.Add(skew)) {
+			return fmt.Errorf("key %d sent until %v is accepted until %v, want until %v", k.ID, to, k.Receive.End, to.Add(skew))
+		}
+	}
+	return nil
+}
+
+// BindISISHello authenticates the hellos of the IS-IS interface at the
+// level with the keychain.
+func BindISISHello(intf *telemetry.NetworkInstance_Protocol_Isis_Interface, level uint8, keychain string) {
+	auth := intf.GetOrCreateLevel(level).GetOrCreateHelloAuthentication()
+	auth.Enabled = ygot.Bool(true)
+	auth.AuthType = telemetry.KeychainTypes_AUTH_TYPE_KEYCHAIN
+	auth.Keychain = ygot.String(keychain)
+}
+
+// BindISIS authenticates the LSPs and SNPs of the IS-IS level with the
+// keychain.
+func BindISIS(isis *telemetry.NetworkInstance_Protocol_Isis, level uint8, keychain string) {
+	l := isis.GetOrCreateLevel(level)
+	l.Enabled = ygot.Bool(true)
+	auth := l.GetOrCreateAuthentication()
+	auth.Enabled = ygot.Bool(true)
+	auth.AuthType = telemetry.KeychainTypes_AUTH_TYPE_KEYCHAIN
+	auth.Keychain = ygot.String(keychain)
+}
+
+// BindLDP authenticates the LDP sessions with the secret of the key sent
+// at the given time, since OpenConfig does not model binding a keychain to
+// LDP.  It returns the key, or an error if none is sent then.
+func BindLDP(ldp *telemetry.NetworkInstance_Mpls_SignalingProtocols_Ldp, keys []Key, at time.Time) (Key, error) {
+	k, ok := SendKey(keys, at)
+	if !ok {
+		return Key{}, fmt.Errorf("no key sent at %v", at)
+	}
+	auth := ldp.GetOrCreateGlobal().GetOrCreateAuthentication()
+	auth.Enable = ygot.Bool(true)
+	auth.AuthenticationKey = ygot.String(k.Secret)
+	return k, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keychain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var (
+	t0      = time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	overlap = time.Minute
+)
+
+func TestLifetimeContains(t *testing.T) {
+	l := Lifetime{Start: t0, End: t0.Add(time.Hour)}
+	for _, c := range []struct {
+		desc string
+		l    Lifetime
+		at   time.Time
+		want bool
+	}{
+		{"before start", l, t0.Add(-time.Second), false},
+		{"at start", l, t0, true},
+		{"within", l, t0.Add(time.Minute), true},
+		{"at end", l, t0.Add(time.Hour), false},
+		{"unbounded start", Lifetime{End: t0}, t0.Add(-24 * time.Hour), true},
+		{"unbounded end", Lifetime{Start: t0}, t0.Add(24 * time.Hour), true},
+		{"unbounded", Lifetime{}, t0, true},
+	} {
+		if got := c.l.Contains(c.at); got != c.want {
+			t.Errorf("%s: Contains(%v) got %t, want %t", c.desc, c.at, got, c.want)
+		}
+	}
+}
+
+// keys returns a key sent and accepted at all times, and another.
+func keys() (Key, Key) {
+	return Key{ID: 1, Algorithm: telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_MD5, Secret: "old"},
+		Key{ID: 2, Algorithm: telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_MD5, Secret: "new"}
+}
+
+func TestNew(t *testing.T) {
+	from, to := keys()
+	kc := New("KC", Rollover(from, to, t0, overlap)...)
+	if err := kc.Validate(); err != nil {
+		t.Errorf("New() does not validate: %v", err)
+	}
+	at := uint64(t0.UnixNano())
+	want := &telemetry.Keychain{
+		Name: ygot.String("KC"),
+		Key: map[uint64]*telemetry.Keychain_Key{
+			1: {
+				KeyId:           ygot.Uint64(1),
+				CryptoAlgorithm: telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_MD5,
+				SecretKey:       ygot.String("old"),
+				SendLifetime: &telemetry.Keychain_Key_SendLifetime{
+					EndTime:        ygot.Uint64(at),
+					SendAndReceive: ygot.Bool(false),
+				},
+				ReceiveLifetime: &telemetry.Keychain_Key_ReceiveLifetime{
+					EndTime: ygot.Uint64(at + uint64(overlap)),
+				},
+			},
+			2: {
+				KeyId:           ygot.Uint64(2),
+				CryptoAlgorithm: telemetry.KeychainTypes_CRYPTO_TYPE_HMAC_MD5,
+				SecretKey:       ygot.String("new"),
+				SendLifetime: &telemetry.Keychain_Key_SendLifetime{
+					StartTime:      ygot.Uint64(at),
+					SendAndReceive: ygot.Bool(false),
+				},
+				ReceiveLifetime: &telemetry.Keychain_Key_ReceiveLifetime{
+					StartTime: ygot.Uint64(at - uint64(overlap)),
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, kc); diff != "" {
+		t.Errorf("New() -want, +got:\n%s", diff)
+	}
+}
+
+func TestNewSendAndReceive(t *testing.T) {
+	from, _ := keys()
+	k := New("KC", from).GetKey(1)
+	if !k.GetSendLifetime().GetSendAndReceive() || k.ReceiveLifetime != nil {
+		t.Errorf("New() of a key of the same lifetimes got send %v, receive %v, want send-and-receive", k.GetSendLifetime(), k.GetReceiveLifetime())
+	}
+}
+
+func TestSendKey(t *testing.T) {
+	from, to := keys()
+	ks := Rollover(from, to, t0, overlap)
+	for _, c := range []struct {
+		desc string
+		keys []Key
+		at   time.Time
+		want uint64
+		ok   bool
+	}{
+		{"before rollover", ks, t0.Add(-time.Second), 1, true},
+		{"at rollover", ks, t0, 2, true},
+		{"after rollover", ks, t0.Add(time.Hour), 2, true},
+		{"overlapping, latest start", []Key{from, {ID: 3, Send: Lifetime{Start: t0}}}, t0, 3, true},
+		{"same start, highest ID", []Key{{ID: 4}, from}, t0, 4, true},
+		{"none", []Key{{ID: 5, Send: Lifetime{Start: t0}}}, t0.Add(-time.Second), 0, false},
+	} {
+		got, ok := SendKey(c.keys, c.at)
+		if ok != c.ok || got.ID != c.want {
+			t.Errorf("%s: SendKey(%v) got key %d, %t, want key %d, %t", c.desc, c.at, got.ID, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestCheckHitless(t *testing.T) {
+	from, to := keys()
+	start := t0.Add(-time.Hour)
+	first := Rollover(from, to, t0, overlap)
+	second := Rollover(first[1], Key{ID: 3, Secret: "third"}, t0.Add(time.Hour), overlap)
+	for _, c := range []struct {
+		desc    string
+		keys    []Key
+		skew    time.Duration
+		wantErr bool
+	}{
+		{"single key", []Key{from}, time.Hour, false},
+		{"rollover within the overlap", Rollover(from, to, t0, overlap), overlap, false},
+		{"rollover beyond the overlap", Rollover(from, to, t0, overlap), 2 * overlap, true},
+		{"rollover without overlap", Rollover(from, to, t0, 0), time.Second, true},
+		{"two rollovers", []Key{first[0], second[0], second[1]}, overlap, false},
+		{"two rollovers beyond the overlap", []Key{first[0], second[0], second[1]}, 2 * time.Hour, true},
+		{"gap", []Key{{ID: 1, Send: Lifetime{End: t0}, Receive: Lifetime{End: t0}}, {ID: 2, Send: Lifetime{Start: t0.Add(time.Second)}}}, 0, true},
+		{"last key expires", []Key{{ID: 1, Send: Lifetime{End: t0}, Receive: Lifetime{End: t0.Add(overlap)}}}, 0, true},
+		{"last key not accepted forever", []Key{{ID: 1, Receive: Lifetime{End: t0}}}, 0, true},
+		{"rolled over before start", Rollover(from, to, start.Add(-time.Hour), overlap), overlap, false},
+	} {
+		err := CheckHitless(c.keys, start, c.skew)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: CheckHitless() got error %v, want error %t", c.desc, err, c.wantErr)
+		}
+	}
+}
+
+func TestBindISIS(t *testing.T) {
+	isis := &telemetry.NetworkInstance_Protocol_Isis{}
+	BindISIS(isis, 2, "KC")
+	intf := isis.GetOrCreateInterface("eth0")
+	BindISISHello(intf, 2, "KC")
+	want := &telemetry.NetworkInstance_Protocol_Isis_Level_Authentication{
+		Enabled:  ygot.Bool(true),
+		AuthType: telemetry.KeychainTypes_AUTH_TYPE_KEYCHAIN,
+		Keychain: ygot.String("KC"),
+	}
+	if diff := cmp.Diff(want, isis.GetLevel(2).GetAuthentication()); diff != "" {
+		t.Errorf("BindISIS() -want, +got:\n%s", diff)
+	}
+	wantHello := &telemetry.NetworkInstance_Protocol_Isis_Interface_Level_HelloAuthentication{
+		Enabled:  ygot.Bool(true),
+		AuthType: telemetry.KeychainTypes_AUTH_TYPE_KEYCHAIN,
+		Keychain: ygot.String("KC"),
+	}
+	if diff := cmp.Diff(wantHello, intf.GetLevel(2).GetHelloAuthentication()); diff != "" {
+		t.Errorf("BindISISHello() -want, +got:\n%s", diff)
+	}
+}
+
+func TestBindLDP(t *testing.T) {
+	from, to := keys()
+	ks := Rollover(from, to, t0, overlap)
+	ldp := &telemetry.NetworkInstance_Mpls_SignalingProtocols_Ldp{}
+	k, err := BindLDP(ldp, ks, t0)
+	if err != nil {
+		t.Fatalf("BindLDP() got error: %v", err)
+	}
+	if k.ID != 2 {
+		t.Errorf("BindLDP() got key %d, want key 2", k.ID)
+	}
+	auth := ldp.GetGlobal().GetAuthentication()
+	if !auth.GetEnable() || auth.GetAuthenticationKey() != "new" {
+		t.Errorf("BindLDP() got authentication enabled %t with key %q, want enabled with %q", auth.GetEnable(), auth.GetAuthenticationKey(), "new")
+	}
+	if _, err := BindLDP(ldp, ks[1:], t0.Add(-time.Hour)); err == nil {
+		t.Errorf("BindLDP() before any key is sent got no error")
+	}
+}
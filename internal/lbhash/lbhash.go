@@ -0,0 +1,229 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lbhash provides helpers to test which packet fields the DUT
+// uses to hash traffic over ECMP or LAG members.  Traffic varying a
+// single field is spread over the members if and only if the field is
+// hashed.
+//
+// The hash configuration is not modeled in OpenConfig, so the fields
+// the DUT is configured with are given by the deviation_hash_fields
+// flag.
+package lbhash
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+)
+
+// Field is a packet field used in load-balancing hashes.
+type Field string
+
+// Fields that can be hashed.
+const (
+	SrcIP       Field = "src-ip"
+	DstIP       Field = "dst-ip"
+	Protocol    Field = "protocol"
+	SrcPort     Field = "src-port"
+	DstPort     Field = "dst-port"
+	FlowLabel   Field = "flow-label"
+	MPLSEntropy Field = "mpls-entropy"
+)
+
+// AllFields are all the fields above.
+var AllFields = []Field{SrcIP, DstIP, Protocol, SrcPort, DstPort, FlowLabel, MPLSEntropy}
+
+// ParseFields parses a comma separated list of fields.
+func ParseFields(s string) (map[Field]bool, error) {
+	valid := map[Field]bool{}
+	for _, f := range AllFields {
+		valid[f] = true
+	}
+	fields := map[Field]bool{}
+	for _, name := range strings.Split(s, ",") {
+		f := Field(strings.TrimSpace(name))
+		if f == "" {
+			continue
+		}
+		if !valid[f] {
+			return nil, fmt.Errorf("unknown hash field %q", f)
+		}
+		fields[f] = true
+	}
+	return fields, nil
+}
+
+// Configured returns the fields the DUT is configured to hash, as given
+// by the deviation_hash_fields flag.
+func Configured() (map[Field]bool, error) {
+	fields, err := ParseFields(*deviations.HashFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -deviation_hash_fields: %w", err)
+	}
+	return fields, nil
+}
+
+// protocols are the IPv4 protocols of the flows varying the protocol.
+var protocols = []int{6, 17, 41, 47, 50, 51, 89, 132}
+
+// Spec specifies the traffic varying one field at a time.
+type Spec struct {
+	Src  ondatra.Endpoint
+	Dsts []ondatra.Endpoint
+	IPv6 bool
+	// SrcIP and DstIP are the addresses of the packets, and the first
+	// of the range of addresses when they are varied.
+	SrcIP, DstIP string
+	// MPLSLabel is the label the DUT load-balances over the members,
+	// under which the entropy label is varied.
+	MPLSLabel uint32
+	// Count is the number of values the field is varied over.
+	Count uint32
+	// FramesPerSecond is the rate of each flow.
+	FramesPerSecond uint64
+}
+
+// Flows returns the flows varying the field.  Protocols are varied
+// with a flow per protocol, and the other fields with a range of
+// values in a single flow.
+func (s *Spec) Flows(ate *ondatra.ATEDevice, field Field) ([]*ondatra.Flow, error) {
+	newFlow := func(name string, headers ...ondatra.Header) *ondatra.Flow {
+		return ate.Traffic().NewFlow(name).
+			WithSrcEndpoints(s.Src).
+			WithDstEndpoints(s.Dsts...).
+			WithHeaders(append([]ondatra.Header{ondatra.NewEthernetHeader()}, headers...)...).
+			WithFrameRateFPS(s.FramesPerSecond)
+	}
+	name := "hash-" + string(field)
+
+	if field == Protocol {
+		if s.IPv6 {
+			return nil, fmt.Errorf("varying the protocol is only supported for IPv4")
+		}
+		var flows []*ondatra.Flow
+		for _, p := range protocols {
+			ip := ondatra.NewIPv4Header().WithSrcAddress(s.SrcIP).WithDstAddress(s.DstIP).WithProtocol(p)
+			flows = append(flows, newFlow(fmt.Sprintf("%s-%d", name, p), ip))
+		}
+		return flows, nil
+	}
+
+	var ip ondatra.Header
+	if s.IPv6 {
+		h := ondatra.NewIPv6Header()
+		switch field {
+		case SrcIP:
+			h.SrcAddressRange().WithMin(s.SrcIP).WithCount(s.Count)
+			h.WithDstAddress(s.DstIP)
+		case DstIP:
+			h.WithSrcAddress(s.SrcIP)
+			h.DstAddressRange().WithMin(s.DstIP).WithCount(s.Count)
+		default:
+			h.WithSrcAddress(s.SrcIP).WithDstAddress(s.DstIP)
+		}
+		if field == FlowLabel {
+			h.FlowLabelRange().WithMin(1).WithCount(s.Count)
+		}
+		ip = h
+	} else {
+		if field == FlowLabel {
+			return nil, fmt.Errorf("the flow label is only supported for IPv6")
+		}
+		h := ondatra.NewIPv4Header()
+		switch field {
+		case SrcIP:
+			h.SrcAddressRange().WithMin(s.SrcIP).WithCount(s.Count)
+			h.WithDstAddress(s.DstIP)
+		case DstIP:
+			h.WithSrcAddress(s.SrcIP)
+			h.DstAddressRange().WithMin(s.DstIP).WithCount(s.Count)
+		default:
+			h.WithSrcAddress(s.SrcIP).WithDstAddress(s.DstIP)
+		}
+		ip = h
+	}
+
+	udp := ondatra.NewUDPHeader().WithSrcPort(49152).WithDstPort(4791)
+	switch field {
+	case SrcPort:
+		udp.SrcPortRange().WithMin(1024).WithCount(s.Count)
+	case DstPort:
+		udp.DstPortRange().WithMin(1024).WithCount(s.Count)
+	}
+
+	if field == MPLSEntropy {
+		if s.MPLSLabel == 0 {
+			return nil, fmt.Errorf("no MPLS label given to vary the entropy label")
+		}
+		// The entropy label indicator (7) precedes the entropy label,
+		// RFC 6790.
+		transport := ondatra.NewMPLSHeader().WithLabel(s.MPLSLabel)
+		eli := ondatra.NewMPLSHeader().WithLabel(7)
+		el := ondatra.NewMPLSHeader()
+		el.LabelRange().WithMin(16).WithCount(s.Count)
+		return []*ondatra.Flow{newFlow(name, transport, eli, el, ip, udp)}, nil
+	}
+	return []*ondatra.Flow{newFlow(name, ip, udp)}, nil
+}
+
+// Check returns an error if the packets received by each member are
+// not distributed as expected.  Traffic varying a hashed field should
+// be balanced within tolerancePct percent of the mean, and traffic
+// varying any other field should all be sent to the same member.
+func Check(field Field, hashed bool, counts []uint64, tolerancePct float64) error {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return fmt.Errorf("no packet received varying %s", field)
+	}
+	if !hashed {
+		var used int
+		for _, c := range counts {
+			// Ignore the members receiving stray packets.
+			if float64(c) > float64(total)/100 {
+				used++
+			}
+		}
+		if used > 1 {
+			return fmt.Errorf("packets varying %s spread over %d members %v although %s is not hashed", field, used, counts, field)
+		}
+		return nil
+	}
+	mean := float64(total) / float64(len(counts))
+	var worst float64
+	for _, c := range counts {
+		worst = math.Max(worst, math.Abs(float64(c)-mean)/mean*100)
+	}
+	if worst > tolerancePct {
+		return fmt.Errorf("packets varying %s not balanced over members %v, deviating up to %.1f%% from the mean, want <= %.1f%%", field, counts, worst, tolerancePct)
+	}
+	return nil
+}
+
+// String returns the fields sorted and comma separated.
+func String(fields map[Field]bool) string {
+	var names []string
+	for f := range fields {
+		names = append(names, string(f))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
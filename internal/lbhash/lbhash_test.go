@@ -0,0 +1,85 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lbhash
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+)
+
+func TestParseFields(t *testing.T) {
+	got, err := ParseFields("src-ip, dst-ip,,flow-label")
+	if err != nil {
+		t.Fatalf("ParseFields got error: %v", err)
+	}
+	want := map[Field]bool{SrcIP: true, DstIP: true, FlowLabel: true}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseFields got diff (-want +got):\n%s", diff)
+	}
+	if _, err := ParseFields("src-ip,ttl"); err == nil {
+		t.Errorf("ParseFields with unknown field got no error, want error")
+	}
+}
+
+func TestConfigured(t *testing.T) {
+	defer func(v string) { *deviations.HashFields = v }(*deviations.HashFields)
+
+	got, err := Configured()
+	if err != nil {
+		t.Fatalf("Configured got error: %v", err)
+	}
+	if want := "dst-ip,dst-port,protocol,src-ip,src-port"; String(got) != want {
+		t.Errorf("Configured got %q by default, want %q", String(got), want)
+	}
+	*deviations.HashFields = "mpls-entropy"
+	got, err = Configured()
+	if err != nil {
+		t.Fatalf("Configured got error: %v", err)
+	}
+	if want := "mpls-entropy"; String(got) != want {
+		t.Errorf("Configured got %q, want %q", String(got), want)
+	}
+	*deviations.HashFields = "bogus"
+	if _, err := Configured(); err == nil {
+		t.Errorf("Configured with invalid flag got no error, want error")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		desc    string
+		hashed  bool
+		counts  []uint64
+		wantErr bool
+	}{
+		{"hashed balanced", true, []uint64{980, 1020, 1000, 1000}, false},
+		{"hashed unbalanced", true, []uint64{2000, 1000, 500, 500}, true},
+		{"hashed polarized", true, []uint64{4000, 0, 0, 0}, true},
+		{"not hashed single member", false, []uint64{0, 4000, 0, 0}, false},
+		{"not hashed stray packets", false, []uint64{2, 4000, 0, 1}, false},
+		{"not hashed spread", false, []uint64{2000, 2000, 0, 0}, true},
+		{"no traffic", true, []uint64{0, 0}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			err := Check(SrcPort, c.hashed, c.counts, 10)
+			if gotErr := err != nil; gotErr != c.wantErr {
+				t.Errorf("Check(%v, %v) got error %v, want error %v", c.hashed, c.counts, err, c.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ldp provides helpers for LDP tests: the configuration of LDP on
+// the interfaces of the DUT, the label bindings advertised by the ATE
+// neighbors, and the verification of these bindings in the AFTs of the DUT.
+//
+// The ATE API has no LDP, so the LDP sessions of the ATE neighbors and the
+// label bindings they advertise are expected to be configured out of band,
+// see -deviation_ate_ldp.  OpenConfig models the LDP sessions but not the
+// label bindings, which are verified in the AFTs instead: the entry of the
+// FEC of a binding pushes its label towards the neighbor, and a local label
+// of the DUT is swapped to it.
+//
+// Usage:
+//
+//	ni := d.GetOrCreateNetworkInstance(*deviations.DefaultNetworkInstance)
+//	ldp.Configure(ni, "192.0.2.5", dut.Port(t, "port2").Name())
+//	b := ldp.Binding{FEC: "198.51.100.0/24", Label: 100100, NextHop: "192.0.2.6"}
+//	ldp.Require(t, b)
+//	local, err := ldp.AwaitBinding(t, dut, b, time.Minute)
+package ldp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Configure enables MPLS and LDP for IPv4 on the interfaces of the network
+// instance, with the LSR ID, and returns the LDP configuration.
+func Configure(ni *telemetry.NetworkInstance, lsrID string, intfs ...string) *telemetry.NetworkInstance_Mpls_SignalingProtocols_Ldp {
+	mpls := ni.GetOrCreateMpls()
+	ldp := mpls.GetOrCreateSignalingProtocols().GetOrCreateLdp()
+	ldp.GetOrCreateGlobal().LsrId = ygot.String(lsrID)
+	for _, intf := range intfs {
+		gi := mpls.GetOrCreateGlobal().GetOrCreateInterface(intf)
+		gi.MplsEnabled = ygot.Bool(true)
+		gi.GetOrCreateInterfaceRef().Interface = ygot.String(intf)
+		gi.GetOrCreateInterfaceRef().Subinterface = ygot.Uint32(0)
+
+		li := ldp.GetOrCreateInterfaceAttributes().GetOrCreateInterface(intf)
+		li.GetOrCreateInterfaceRef().Interface = ygot.String(intf)
+		li.GetOrCreateInterfaceRef().Subinterface = ygot.Uint32(0)
+		li.GetOrCreateAddressFamily(telemetry.MplsLdp_MplsLdpAfi_IPV4).Enabled = ygot.Bool(true)
+	}
+	return ldp
+}
+
+// Labels that may be bound to a FEC, RFC 3032 reserving the labels below
+// 16.
+const (
+	MinLabel = 16
+	MaxLabel = 1<<20 - 1
+)
+
+// Binding is a label binding advertised by an LDP neighbor: the label the
+// neighbor expects for the packets of the FEC, an IPv4 prefix, and the
+// address of the neighbor the DUT forwards them to.
+type Binding struct {
+	FEC     string
+	Label   uint32
+	NextHop string
+}
+
+// String returns the binding in the form FEC:label via next-hop.
+func (b Binding) String() string {
+	return fmt.Sprintf("%s:%d via %s", b.FEC, b.Label, b.NextHop)
+}
+
+// Validate returns an error if the FEC is not an IPv4 prefix, the label
+// is reserved or out of range, or the next hop is not an IPv4 address.
+func (b Binding) Validate() error {
+	if ip, _, err := net.ParseCIDR(b.FEC); err != nil || ip.To4() == nil {
+		return fmt.Errorf("binding %v: FEC is not an IPv4 prefix", b)
+	}
+	if b.Label < MinLabel || b.Label > MaxLabel {
+		return fmt.Errorf("binding %v: label out of [%d, %d]", b, MinLabel, MaxLabel)
+	}
+	if ip := net.ParseIP(b.NextHop); ip == nil || ip.To4() == nil {
+		return fmt.Errorf("binding %v: next hop is not an IPv4 address", b)
+	}
+	return nil
+}
+
+// Require skips the test unless the LDP sessions of the ATE neighbors are
+// configured out of band, fails it if a binding is invalid, and logs the
+// bindings the neighbors are expected to advertise.
+func Require(t testing.TB, bindings ...Binding) {
+	t.Helper()
+	if !*deviations.ATELDP {
+		t.Skip("LDP is not configured on the ATE, see -deviation_ate_ldp")
+	}
+	for _, b := range bindings {
+		if err := b.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("ATE LDP neighbor %s advertises label %d for %s", b.NextHop, b.Label, b.FEC)
+	}
+}
+
+// AwaitSession waits for the LDP session of the DUT to the neighbor of the
+// LSR ID, in label space 0, to be operational.
+func AwaitSession(t testing.TB, dut *ondatra.DUTDevice, lsrID string, timeout time.Duration) error {
+	t.Helper()
+	path := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).Mpls().SignalingProtocols().Ldp().Neighbor(lsrID, 0).SessionState()
+	want := telemetry.MplsLdp_Neighbor_SessionState_OPERATIONAL
+	_, ok := path.Watch(t, timeout, func(v *telemetry.QualifiedE_MplsLdp_Neighbor_SessionState) bool {
+		return v.IsPresent() && v.Val(t) == want
+	}).Await(t)
+	if !ok {
+		return fmt.Errorf("LDP session to %s not %v after %v", lsrID, want, timeout)
+	}
+	return nil
+}
+
+// reserved are the values of the reserved labels of the AFTs.
+var reserved = map[telemetry.E_MplsTypes_MplsLabel_Enum]uint32{
+	telemetry.MplsTypes_MplsLabel_Enum_IPV4_EXPLICIT_NULL:      0,
+	telemetry.MplsTypes_MplsLabel_Enum_ROUTER_ALERT:            1,
+	telemetry.MplsTypes_MplsLabel_Enum_IPV6_EXPLICIT_NULL:      2,
+	telemetry.MplsTypes_MplsLabel_Enum_IMPLICIT_NULL:           3,
+	telemetry.MplsTypes_MplsLabel_Enum_ENTROPY_LABEL_INDICATOR: 7,
+}
+
+// label returns the value of a label of the AFTs, which is false for
+// NO_LABEL.
+func label(v interface{}) (uint32, bool) {
+	switch l := v.(type) {
+	case telemetry.UnionUint32:
+		return uint32(l), true
+	case telemetry.E_MplsTypes_MplsLabel_Enum:
+		r, ok := reserved[l]
+		return r, ok
+	}
+	return 0, false
+}
+
+// nextHops returns the next hops of the next hop group in the AFTs.
+func nextHops(afts *telemetry.NetworkInstance_Afts, nhg uint64) ([]*telemetry.NetworkInstance_Afts_NextHop, error) {
+	g := afts.GetNextHopGroup(nhg)
+	if g == nil {
+		return nil, fmt.Errorf("no next hop group %d", nhg)
+	}
+	var nhs []*telemetry.NetworkInstance_Afts_NextHop
+	for index := range g.NextHop {
+		nh := afts.GetNextHop(index)
+		if nh == nil {
+			return nil, fmt.Errorf("no next hop %d of next hop group %d", index, nhg)
+		}
+		nhs = append(nhs, nh)
+	}
+	return nhs, nil
+}
+
+// Pushed returns the label stacks the next hops of the AFT entry of the
+// IPv4 prefix push, outermost first, keyed by the address of the next hop.
+func Pushed(afts *telemetry.NetworkInstance_Afts, prefix string) (map[string][]uint32, error) {
+	e := afts.GetIpv4Entry(prefix)
+	if e == nil {
+		return nil, fmt.Errorf("no AFT entry for %s", prefix)
+	}
+	if e.NextHopGroup == nil {
+		return nil, fmt.Errorf("no next hop group for %s", prefix)
+	}
+	nhs, err := nextHops(afts, e.GetNextHopGroup())
+	if err != nil {
+		return nil, fmt.Errorf("AFT entry for %s: %w", prefix, err)
+	}
+	stacks := map[string][]uint32{}
+	for _, nh := range nhs {
+		var stack []uint32
+		for _, v := range nh.PushedMplsLabelStack {
+			if l, ok := label(v); ok {
+				stack = append(stack, l)
+			}
+		}
+		stacks[nh.GetIpAddress()] = stack
+	}
+	return stacks, nil
+}
+
+// SwappedTo returns the local labels of the label entries of the AFTs
+// swapped to the label towards the next hop address, sorted.
+func SwappedTo(afts *telemetry.NetworkInstance_Afts, nextHop string, out uint32) []uint32 {
+	var locals []uint32
+	for _, e := range afts.LabelEntry {
+		local, ok := label(e.Label)
+		if !ok || e.NextHopGroup == nil {
+			continue
+		}
+		nhs, err := nextHops(afts, e.GetNextHopGroup())
+		if err != nil {
+			continue
+		}
+		for _, nh := range nhs {
+			if nh.GetIpAddress() != nextHop || len(nh.PushedMplsLabelStack) == 0 {
+				continue
+			}
+			if l, ok := label(nh.PushedMplsLabelStack[0]); ok && l == out {
+				locals = append(locals, local)
+				break
+			}
+		}
+	}
+	sort.Slice(locals, func(i, j int) bool { return locals[i] < locals[j] })
+	return locals
+}
+
+// Check returns the local labels of the DUT swapped to the label of the
+// binding, or an error unless the AFT entry of its FEC pushes its label
+// towards its next hop and a local label is swapped to it.
+func (b Binding) Check(afts *telemetry.NetworkInstance_Afts) ([]uint32, error) {
+	stacks, err := Pushed(afts, b.FEC)
+	if err != nil {
+		return nil, err
+	}
+	stack, ok := stacks[b.NextHop]
+	if !ok {
+		return nil, fmt.Errorf("AFT entry for %s has no next hop %s", b.FEC, b.NextHop)
+	}
+	if len(stack) == 0 || stack[0] != b.Label {
+		return nil, fmt.Errorf("AFT entry for %s pushes %v towards %s, want label %d", b.FEC, stack, b.NextHop, b.Label)
+	}
+	locals := SwappedTo(afts, b.NextHop, b.Label)
+	if len(locals) == 0 {
+		return nil, fmt.Errorf("no label entry swapped to %d towards %s", b.Label, b.NextHop)
+	}
+	return locals, nil
+}
+
+// pollInterval is how often the AFTs are read while awaiting a binding.
+var pollInterval = time.Second
+
+// AwaitBinding waits for the AFTs of the DUT to forward with the label of
+// the binding, see Check, and returns the local labels swapped to it, or
+// an error with the last mismatch if it does not within the timeout.
+func AwaitBinding(t testing.TB, dut *ondatra.DUTDevice, b Binding, timeout time.Duration) ([]uint32, error) {
+	t.Helper()
+	path := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).Afts()
+	var last error
+	for deadline := time.Now().Add(timeout); ; time.Sleep(pollInterval) {
+		afts := path.Lookup(t)
+		if !afts.IsPresent() {
+			last = errors.New("no AFTs")
+		} else {
+			locals, err := b.Check(afts.Val(t))
+			if err == nil {
+				return locals, nil
+			}
+			last = err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("binding %v not forwarded after %v: %w", b, timeout, last)
+		}
+	}
+}
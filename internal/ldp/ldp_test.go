@@ -0,0 +1,174 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestConfigure(t *testing.T) {
+	ni := &telemetry.NetworkInstance{}
+	ldp := Configure(ni, "192.0.2.5", "eth1", "eth2")
+	if got := ldp.GetGlobal().GetLsrId(); got != "192.0.2.5" {
+		t.Errorf("LSR ID got %q, want 192.0.2.5", got)
+	}
+	for _, intf := range []string{"eth1", "eth2"} {
+		if gi := ni.GetMpls().GetGlobal().GetInterface(intf); !gi.GetMplsEnabled() || gi.GetInterfaceRef().GetInterface() != intf {
+			t.Errorf("MPLS interface %s got %+v, want enabled", intf, gi)
+		}
+		af := ldp.GetInterfaceAttributes().GetInterface(intf).GetAddressFamily(telemetry.MplsLdp_MplsLdpAfi_IPV4)
+		if !af.GetEnabled() {
+			t.Errorf("LDP IPv4 of %s got %+v, want enabled", intf, af)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		b       Binding
+		wantErr string
+	}{
+		{"valid", Binding{FEC: "198.51.100.0/24", Label: 100100, NextHop: "192.0.2.6"}, ""},
+		{"IPv6 FEC", Binding{FEC: "2001:db8::/64", Label: 100100, NextHop: "192.0.2.6"}, "FEC"},
+		{"reserved label", Binding{FEC: "198.51.100.0/24", Label: 3, NextHop: "192.0.2.6"}, "label"},
+		{"label too large", Binding{FEC: "198.51.100.0/24", Label: 1 << 20, NextHop: "192.0.2.6"}, "label"},
+		{"bad next hop", Binding{FEC: "198.51.100.0/24", Label: 100100, NextHop: "192.0.2"}, "next hop"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.b.Validate()
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("Validate got error %v, want nil", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("Validate got error %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// buildAFTs returns AFTs where 198.51.100.0/24 pushes the labels towards
+// 192.0.2.6, and local label 24000 is swapped to the first of them.
+func buildAFTs(labels ...telemetry.NetworkInstance_Afts_NextHop_PushedMplsLabelStack_Union) *telemetry.NetworkInstance_Afts {
+	afts := &telemetry.NetworkInstance_Afts{}
+	nh := afts.GetOrCreateNextHop(1)
+	nh.IpAddress = ygot.String("192.0.2.6")
+	nh.PushedMplsLabelStack = labels
+	afts.GetOrCreateNextHopGroup(1).GetOrCreateNextHop(1)
+	afts.GetOrCreateIpv4Entry("198.51.100.0/24").NextHopGroup = ygot.Uint64(1)
+
+	if len(labels) > 0 {
+		swap := afts.GetOrCreateNextHop(2)
+		swap.IpAddress = ygot.String("192.0.2.6")
+		swap.PushedMplsLabelStack = labels[:1]
+		afts.GetOrCreateNextHopGroup(2).GetOrCreateNextHop(2)
+		afts.GetOrCreateLabelEntry(telemetry.UnionUint32(24000)).NextHopGroup = ygot.Uint64(2)
+	}
+	return afts
+}
+
+func TestPushed(t *testing.T) {
+	afts := buildAFTs(telemetry.UnionUint32(100100), telemetry.MplsTypes_MplsLabel_Enum_IMPLICIT_NULL, telemetry.MplsTypes_MplsLabel_Enum_NO_LABEL)
+	got, err := Pushed(afts, "198.51.100.0/24")
+	if err != nil {
+		t.Fatalf("Pushed got error %v", err)
+	}
+	want := map[string][]uint32{"192.0.2.6": {100100, 3}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Pushed (-want, +got):\n%s", diff)
+	}
+	if _, err := Pushed(afts, "203.0.113.0/24"); err == nil {
+		t.Errorf("Pushed of a missing prefix got nil error, want error")
+	}
+}
+
+func TestSwappedTo(t *testing.T) {
+	afts := buildAFTs(telemetry.UnionUint32(100100))
+	afts.GetOrCreateLabelEntry(telemetry.UnionUint32(16)).NextHopGroup = ygot.Uint64(2)
+	afts.GetOrCreateLabelEntry(telemetry.UnionUint32(24001)).NextHopGroup = ygot.Uint64(1)
+	if diff := cmp.Diff([]uint32{16, 24000, 24001}, SwappedTo(afts, "192.0.2.6", 100100)); diff != "" {
+		t.Errorf("SwappedTo (-want, +got):\n%s", diff)
+	}
+	if got := SwappedTo(afts, "192.0.2.10", 100100); len(got) != 0 {
+		t.Errorf("SwappedTo of another next hop got %v, want none", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	b := Binding{FEC: "198.51.100.0/24", Label: 100100, NextHop: "192.0.2.6"}
+	for _, tc := range []struct {
+		desc    string
+		b       Binding
+		afts    *telemetry.NetworkInstance_Afts
+		want    []uint32
+		wantErr string
+	}{{
+		desc: "forwarded",
+		b:    b,
+		afts: buildAFTs(telemetry.UnionUint32(100100)),
+		want: []uint32{24000},
+	}, {
+		desc:    "missing FEC",
+		b:       Binding{FEC: "203.0.113.0/24", Label: 100100, NextHop: "192.0.2.6"},
+		afts:    buildAFTs(telemetry.UnionUint32(100100)),
+		wantErr: "no AFT entry",
+	}, {
+		desc:    "other next hop",
+		b:       Binding{FEC: "198.51.100.0/24", Label: 100100, NextHop: "192.0.2.10"},
+		afts:    buildAFTs(telemetry.UnionUint32(100100)),
+		wantErr: "no next hop",
+	}, {
+		desc:    "unlabeled",
+		b:       b,
+		afts:    buildAFTs(),
+		wantErr: "want label 100100",
+	}, {
+		desc:    "other label",
+		b:       b,
+		afts:    buildAFTs(telemetry.UnionUint32(100200)),
+		wantErr: "want label 100100",
+	}, {
+		desc: "not swapped",
+		b:    b,
+		afts: func() *telemetry.NetworkInstance_Afts {
+			afts := buildAFTs(telemetry.UnionUint32(100100))
+			afts.DeleteLabelEntry(telemetry.UnionUint32(24000))
+			return afts
+		}(),
+		wantErr: "no label entry",
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.b.Check(tc.afts)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Check got error %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check got error %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Check (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package macsec configures MACsec (IEEE 802.1AE) on the links between
+// DUTs, keyed by MKA (IEEE 802.1X-2010) with a pre-shared connectivity
+// association key, and reads the MACsec counters of their interfaces.
+//
+// The MACsec model, openconfig-macsec, is not among the models generated
+// by Ondatra, so the configuration is set and the counters are read with
+// raw gNMI in JSON_IETF.  The MKA policy of an interface takes its key from
+// a keychain: the ID of the key is the connectivity association key name
+// (CKN), and its secret the connectivity association key (CAK).
+//
+// Usage:
+//
+//	c := &macsec.Config{Policy: "MACSEC", Keychain: "MKA", Cipher: macsec.GCMAES256, Key: key, Interfaces: []string{intf}}
+//	req, err := c.SetRequest()
+//	_, err = gnmiClient.Set(ctx, req)
+//	...
+//	before, err := macsec.ReadCounters(ctx, gnmiClient, intf)
+//	sent, received, err := macsec.Ping(ctx, gnoiClient.System(), addr, 10)
+//	after, err := macsec.ReadCounters(ctx, gnmiClient, intf)
+//	encrypted := after.Encrypted - before.Encrypted
+package macsec
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/openconfig/featureprofiles/internal/ietfjson"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	spb "github.com/openconfig/gnoi/system"
+	tpb "github.com/openconfig/gnoi/types"
+)
+
+// Cipher is a MACsec cipher suite, as named by openconfig-macsec.
+type Cipher string
+
+// The cipher suites of IEEE 802.1AE.  The XPN suites have extended packet
+// numbers, for links fast enough to exhaust 32-bit ones between rekeys.
+const (
+	GCMAES128    Cipher = "GCM_AES_128"
+	GCMAES256    Cipher = "GCM_AES_256"
+	GCMAESXPN128 Cipher = "GCM_AES_XPN_128"
+	GCMAESXPN256 Cipher = "GCM_AES_XPN_256"
+)
+
+// KeyLen returns the length in bytes of the keys of the cipher suite, or 0
+// if it is unknown.
+func (c Cipher) KeyLen() int {
+	switch c {
+	case GCMAES128, GCMAESXPN128:
+		return 16
+	case GCMAES256, GCMAESXPN256:
+		return 32
+	}
+	return 0
+}
+
+// MaxCKNLen is the maximum length in bytes of a connectivity association
+// key name.
+const MaxCKNLen = 32
+
+// Key is a pre-shared MKA key, in hexadecimal.
+type Key struct {
+	CKN string
+	CAK string
+}
+
+// Validate returns an error if the key is not hexadecimal, the name is
+// empty or longer than MaxCKNLen bytes, or the key does not have the
+// length of the keys of the cipher suite.
+func (k Key) Validate(c Cipher) error {
+	n := c.KeyLen()
+	if n == 0 {
+		return fmt.Errorf("unknown cipher suite %q", c)
+	}
+	ckn, err := hex.DecodeString(k.CKN)
+	if err != nil || len(ckn) == 0 || len(ckn) > MaxCKNLen {
+		return fmt.Errorf("CKN %q is not 1 to %d hexadecimal bytes", k.CKN, MaxCKNLen)
+	}
+	cak, err := hex.DecodeString(k.CAK)
+	if err != nil || len(cak) != n {
+		return fmt.Errorf("CAK of %s is not %d hexadecimal bytes for %s", k.CKN, n, c)
+	}
+	return nil
+}
+
+// Config is the MACsec configuration of the interfaces of a DUT, secured
+// with a single pre-shared key.
+type Config struct {
+	// Policy and Keychain are the names of the MKA policy and of the
+	// keychain of the key.
+	Policy   string
+	Keychain string
+	Cipher   Cipher
+	Key      Key
+	// KeyServerPriority is the priority of the DUT to be elected the key
+	// server, the lowest winning.
+	KeyServerPriority uint8
+	// ShouldSecure lets the interfaces send and receive unprotected
+	// traffic while MKA is not secured, rather than drop it.
+	ShouldSecure bool
+	Interfaces   []string
+}
+
+// Validate returns an error if the configuration has no names, no
+// interfaces, or an invalid key.
+func (c *Config) Validate() error {
+	if c.Policy == "" || c.Keychain == "" {
+		return errors.New("MACsec configuration without a policy or keychain name")
+	}
+	if len(c.Interfaces) == 0 {
+		return fmt.Errorf("MACsec policy %s without interfaces", c.Policy)
+	}
+	if err := c.Key.Validate(c.Cipher); err != nil {
+		return fmt.Errorf("MACsec policy %s: %w", c.Policy, err)
+	}
+	return nil
+}
+
+// The JSON_IETF of the configuration.  The top-level members are
+// qualified with the name of their module, and the members nested in them
+// inherit it.
+type (
+	keychainJSON struct {
+		Name   string `json:"openconfig-keychain:name"`
+		Config struct {
+			Name string `json:"name"`
+		} `json:"openconfig-keychain:config"`
+		Keys struct {
+			Key []keyJSON `json:"key"`
+		} `json:"openconfig-keychain:keys"`
+	}
+	keyJSON struct {
+		KeyID  string `json:"key-id"`
+		Config struct {
+			KeyID     string `json:"key-id"`
+			SecretKey string `json:"secret-key"`
+		} `json:"config"`
+	}
+	policyJSON struct {
+		Name   string `json:"openconfig-macsec:name"`
+		Config struct {
+			Name              string   `json:"name"`
+			CipherSuites      []Cipher `json:"macsec-cipher-suite"`
+			KeyServerPriority uint8    `json:"key-server-priority"`
+			SecurityPolicy    string   `json:"security-policy"`
+		} `json:"openconfig-macsec:config"`
+	}
+	interfaceJSON struct {
+		Name   string `json:"openconfig-macsec:name"`
+		Config struct {
+			Name   string `json:"name"`
+			Enable bool   `json:"enable"`
+		} `json:"openconfig-macsec:config"`
+		MKA struct {
+			Config struct {
+				Policy   string `json:"mka-policy"`
+				Keychain string `json:"key-chain"`
+			} `json:"config"`
+		} `json:"openconfig-macsec:mka"`
+	}
+)
+
+// update returns the update of the path to the JSON_IETF value of v.
+func update(path string, v interface{}) (*gpb.Update, error) {
+	p, err := ygot.StringToStructuredPath(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &gpb.Update{Path: p, Val: &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: b}}}, nil
+}
+
+// KeychainRequest returns the SetRequest replacing the keychain of the
+// configuration with its key.  It rekeys the interfaces on its own, e.g.
+// to a key of their peer mismatched and back.
+func (c *Config) KeychainRequest() (*gpb.SetRequest, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	kc := keychainJSON{Name: c.Keychain}
+	kc.Config.Name = c.Keychain
+	k := keyJSON{KeyID: c.Key.CKN}
+	k.Config.KeyID = c.Key.CKN
+	k.Config.SecretKey = c.Key.CAK
+	kc.Keys.Key = []keyJSON{k}
+	u, err := update(fmt.Sprintf("/keychains/keychain[name=%s]", c.Keychain), kc)
+	if err != nil {
+		return nil, fmt.Errorf("keychain %s: %w", c.Keychain, err)
+	}
+	return &gpb.SetRequest{Replace: []*gpb.Update{u}}, nil
+}
+
+// SetRequest returns the SetRequest replacing the keychain, the MKA policy
+// and the MACsec configuration of the interfaces.
+func (c *Config) SetRequest() (*gpb.SetRequest, error) {
+	req, err := c.KeychainRequest()
+	if err != nil {
+		return nil, err
+	}
+	p := policyJSON{Name: c.Policy}
+	p.Config.Name = c.Policy
+	p.Config.CipherSuites = []Cipher{c.Cipher}
+	p.Config.KeyServerPriority = c.KeyServerPriority
+	p.Config.SecurityPolicy = "MUST_SECURE"
+	if c.ShouldSecure {
+		p.Config.SecurityPolicy = "SHOULD_SECURE"
+	}
+	u, err := update(fmt.Sprintf("/macsec/mka/policies/policy[name=%s]", c.Policy), p)
+	if err != nil {
+		return nil, fmt.Errorf("MKA policy %s: %w", c.Policy, err)
+	}
+	req.Replace = append(req.Replace, u)
+
+	for _, name := range c.Interfaces {
+		i := interfaceJSON{Name: name}
+		i.Config.Name = name
+		i.Config.Enable = true
+		i.MKA.Config.Policy = c.Policy
+		i.MKA.Config.Keychain = c.Keychain
+		u, err := update(fmt.Sprintf("/macsec/interfaces/interface[name=%s]", name), i)
+		if err != nil {
+			return nil, fmt.Errorf("MACsec interface %s: %w", name, err)
+		}
+		req.Replace = append(req.Replace, u)
+	}
+	return req, nil
+}
+
+// DeleteRequest returns the SetRequest deleting the MACsec configuration
+// of the interfaces, the MKA policy and the keychain.
+func (c *Config) DeleteRequest() (*gpb.SetRequest, error) {
+	paths := []string{
+		fmt.Sprintf("/macsec/mka/policies/policy[name=%s]", c.Policy),
+		fmt.Sprintf("/keychains/keychain[name=%s]", c.Keychain),
+	}
+	for _, name := range c.Interfaces {
+		paths = append([]string{fmt.Sprintf("/macsec/interfaces/interface[name=%s]", name)}, paths...)
+	}
+	req := &gpb.SetRequest{}
+	for _, s := range paths {
+		p, err := ygot.StringToStructuredPath(s)
+		if err != nil {
+			return nil, err
+		}
+		req.Delete = append(req.Delete, p)
+	}
+	return req, nil
+}
+
+// Counters are the MACsec counters of an interface.  The counters of the
+// secure channels are summed over the channels.
+type Counters struct {
+	// Encrypted is the number of packets sent encrypted.
+	Encrypted uint64
+	// Valid and Invalid are the numbers of packets received which passed
+	// and failed the integrity check.
+	Valid   uint64
+	Invalid uint64
+	// TxUntagged and RxUntagged are the numbers of packets sent and
+	// received without a MACsec tag, i.e. unprotected.
+	TxUntagged uint64
+	RxUntagged uint64
+}
+
+// counters returns the counters of the state/counters of a JSON object,
+// keyed by name.  JSON_IETF encodes 64-bit numbers as strings, but both
+// strings and numbers are accepted.
+func counters(raw json.RawMessage) (map[string]uint64, error) {
+	obj, err := ietfjson.Object(raw)
+	if err != nil {
+		return nil, err
+	}
+	state, ok := ietfjson.Member(obj, "state")
+	if !ok {
+		return nil, nil
+	}
+	if obj, err = ietfjson.Object(state); err != nil {
+		return nil, err
+	}
+	cs, ok := ietfjson.Member(obj, "counters")
+	if !ok {
+		return nil, nil
+	}
+	var vals map[string]json.RawMessage
+	if err := json.Unmarshal(cs, &vals); err != nil {
+		return nil, fmt.Errorf("counters %s: %w", cs, err)
+	}
+	m := map[string]uint64{}
+	for k, v := range vals {
+		k = k[strings.IndexByte(k, ':')+1:]
+		s := strings.Trim(string(v), `"`)
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("counter %s: %s is not a number", k, v)
+		}
+		m[k] = n
+	}
+	return m, nil
+}
+
+// channels returns the entries of the list of secure channels of the name,
+// in the container of the same name, e.g. scsa-tx/scsa-tx.
+func channels(intf map[string]json.RawMessage, name string) ([]json.RawMessage, error) {
+	c, ok := ietfjson.Member(intf, name)
+	if !ok {
+		return nil, nil
+	}
+	obj, err := ietfjson.Object(c)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := ietfjson.Member(obj, name)
+	if !ok {
+		return nil, nil
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(l, &entries); err != nil {
+		return nil, fmt.Errorf("%s is not a list: %w", name, err)
+	}
+	return entries, nil
+}
+
+// ParseCounters returns the counters of the JSON state of a MACsec
+// interface, /macsec/interfaces/interface.
+func ParseCounters(raw json.RawMessage) (*Counters, error) {
+	intf, err := ietfjson.Object(raw)
+	if err != nil {
+		return nil, err
+	}
+	c := &Counters{}
+	m, err := counters(raw)
+	if err != nil {
+		return nil, err
+	}
+	c.TxUntagged, c.RxUntagged = m["tx-untagged-pkts"], m["rx-untagged-pkts"]
+
+	tx, err := channels(intf, "scsa-tx")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range tx {
+		m, err := counters(e)
+		if err != nil {
+			return nil, fmt.Errorf("scsa-tx: %w", err)
+		}
+		c.Encrypted += m["sc-encrypted"]
+	}
+	rx, err := channels(intf, "scsa-rx")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range rx {
+		m, err := counters(e)
+		if err != nil {
+			return nil, fmt.Errorf("scsa-rx: %w", err)
+		}
+		c.Valid += m["sc-valid"]
+		c.Invalid += m["sc-invalid"]
+	}
+	return c, nil
+}
+
+// ReadCounters returns the MACsec counters of the interface of the DUT.
+func ReadCounters(ctx context.Context, c gpb.GNMIClient, intf string) (*Counters, error) {
+	p, err := ygot.StringToStructuredPath(fmt.Sprintf("/macsec/interfaces/interface[name=%s]", intf))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Get(ctx, &gpb.GetRequest{
+		Path:     []*gpb.Path{p},
+		Type:     gpb.GetRequest_STATE,
+		Encoding: gpb.Encoding_JSON_IETF,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gNMI Get of the MACsec state of %s: %w", intf, err)
+	}
+	for _, n := range resp.GetNotification() {
+		for _, u := range n.GetUpdate() {
+			if b := u.GetVal().GetJsonIetfVal(); len(b) > 0 {
+				return ParseCounters(b)
+			}
+		}
+	}
+	return nil, fmt.Errorf("no MACsec state of %s", intf)
+}
+
+// Ping pings the IPv4 address from the DUT with gNOI, and returns the
+// numbers of echo requests sent and of replies received.
+func Ping(ctx context.Context, c spb.SystemClient, addr string, count int32) (sent, received int32, err error) {
+	stream, err := c.Ping(ctx, &spb.PingRequest{
+		Destination: addr,
+		Count:       count,
+		L3Protocol:  tpb.L3Protocol_IPV4,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("gNOI ping of %s: %w", addr, err)
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return sent, received, nil
+		}
+		if err != nil {
+			return sent, received, fmt.Errorf("gNOI ping of %s: %w", addr, err)
+		}
+		// The summary is the response with the number sent.
+		if resp.GetSent() > 0 {
+			sent, received = resp.GetSent(), resp.GetReceived()
+		}
+	}
+}
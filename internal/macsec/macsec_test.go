@@ -0,0 +1,269 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package macsec
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+)
+
+const (
+	ckn    = "0102030405060708"
+	cak128 = "00112233445566778899aabbccddeeff"
+	cak256 = "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"
+)
+
+func TestKeyValidate(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		key     Key
+		cipher  Cipher
+		wantErr bool
+	}{{
+		desc:   "128",
+		key:    Key{CKN: ckn, CAK: cak128},
+		cipher: GCMAES128,
+	}, {
+		desc:   "xpn 256",
+		key:    Key{CKN: ckn, CAK: cak256},
+		cipher: GCMAESXPN256,
+	}, {
+		desc:    "unknown cipher",
+		key:     Key{CKN: ckn, CAK: cak128},
+		cipher:  "GCM_AES_512",
+		wantErr: true,
+	}, {
+		desc:    "short cak",
+		key:     Key{CKN: ckn, CAK: cak128},
+		cipher:  GCMAES256,
+		wantErr: true,
+	}, {
+		desc:    "empty ckn",
+		key:     Key{CAK: cak128},
+		cipher:  GCMAES128,
+		wantErr: true,
+	}, {
+		desc:    "long ckn",
+		key:     Key{CKN: cak256 + "00", CAK: cak128},
+		cipher:  GCMAES128,
+		wantErr: true,
+	}, {
+		desc:    "odd ckn",
+		key:     Key{CKN: "123", CAK: cak128},
+		cipher:  GCMAES128,
+		wantErr: true,
+	}, {
+		desc:    "not hexadecimal",
+		key:     Key{CKN: ckn, CAK: "zz112233445566778899aabbccddeeff"},
+		cipher:  GCMAES128,
+		wantErr: true,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := tc.key.Validate(tc.cipher); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	key := Key{CKN: ckn, CAK: cak256}
+	for _, c := range []*Config{
+		{Keychain: "MKA", Cipher: GCMAES256, Key: key, Interfaces: []string{"eth1"}},
+		{Policy: "MACSEC", Cipher: GCMAES256, Key: key, Interfaces: []string{"eth1"}},
+		{Policy: "MACSEC", Keychain: "MKA", Cipher: GCMAES256, Key: key},
+		{Policy: "MACSEC", Keychain: "MKA", Cipher: GCMAES128, Key: key, Interfaces: []string{"eth1"}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate() of %+v got no error", c)
+		}
+	}
+}
+
+// decode returns the JSON value of the update as generic values.
+func decode(t *testing.T, b []byte) map[string]interface{} {
+	t.Helper()
+	var v map[string]interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatalf("Cannot decode %s: %v", b, err)
+	}
+	return v
+}
+
+func TestSetRequest(t *testing.T) {
+	c := &Config{
+		Policy:            "MACSEC",
+		Keychain:          "MKA",
+		Cipher:            GCMAES256,
+		Key:               Key{CKN: ckn, CAK: cak256},
+		KeyServerPriority: 16,
+		Interfaces:        []string{"eth1", "eth2"},
+	}
+	req, err := c.SetRequest()
+	if err != nil {
+		t.Fatalf("SetRequest() got error %v", err)
+	}
+	var paths []string
+	for _, u := range req.GetReplace() {
+		p, err := ygot.PathToString(u.GetPath())
+		if err != nil {
+			t.Fatalf("PathToString(%v) got error %v", u.GetPath(), err)
+		}
+		paths = append(paths, p)
+	}
+	wantPaths := []string{
+		"/keychains/keychain[name=MKA]",
+		"/macsec/mka/policies/policy[name=MACSEC]",
+		"/macsec/interfaces/interface[name=eth1]",
+		"/macsec/interfaces/interface[name=eth2]",
+	}
+	if diff := cmp.Diff(wantPaths, paths); diff != "" {
+		t.Fatalf("SetRequest() paths diff (-want +got):\n%s", diff)
+	}
+
+	wantVals := []map[string]interface{}{{
+		"openconfig-keychain:name":   "MKA",
+		"openconfig-keychain:config": map[string]interface{}{"name": "MKA"},
+		"openconfig-keychain:keys": map[string]interface{}{"key": []interface{}{map[string]interface{}{
+			"key-id": ckn,
+			"config": map[string]interface{}{"key-id": ckn, "secret-key": cak256},
+		}}},
+	}, {
+		"openconfig-macsec:name": "MACSEC",
+		"openconfig-macsec:config": map[string]interface{}{
+			"name":                "MACSEC",
+			"macsec-cipher-suite": []interface{}{"GCM_AES_256"},
+			"key-server-priority": float64(16),
+			"security-policy":     "MUST_SECURE",
+		},
+	}, {
+		"openconfig-macsec:name":   "eth1",
+		"openconfig-macsec:config": map[string]interface{}{"name": "eth1", "enable": true},
+		"openconfig-macsec:mka": map[string]interface{}{
+			"config": map[string]interface{}{"mka-policy": "MACSEC", "key-chain": "MKA"},
+		},
+	}}
+	for i, want := range wantVals {
+		got := decode(t, req.GetReplace()[i].GetVal().GetJsonIetfVal())
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("SetRequest() value of %s diff (-want +got):\n%s", wantPaths[i], diff)
+		}
+	}
+
+	c.ShouldSecure = true
+	if req, err = c.SetRequest(); err != nil {
+		t.Fatalf("SetRequest() got error %v", err)
+	}
+	policy := decode(t, req.GetReplace()[1].GetVal().GetJsonIetfVal())["openconfig-macsec:config"].(map[string]interface{})
+	if got := policy["security-policy"]; got != "SHOULD_SECURE" {
+		t.Errorf("SetRequest() security policy got %v, want SHOULD_SECURE", got)
+	}
+
+	c.Key.CAK = cak128
+	if _, err := c.SetRequest(); err == nil {
+		t.Errorf("SetRequest() with a short CAK got no error")
+	}
+}
+
+func TestKeychainRequest(t *testing.T) {
+	c := &Config{Policy: "MACSEC", Keychain: "MKA", Cipher: GCMAES128, Key: Key{CKN: ckn, CAK: cak128}, Interfaces: []string{"eth1"}}
+	req, err := c.KeychainRequest()
+	if err != nil {
+		t.Fatalf("KeychainRequest() got error %v", err)
+	}
+	if got := len(req.GetReplace()); got != 1 {
+		t.Fatalf("KeychainRequest() got %d replaces, want 1", got)
+	}
+	if p, _ := ygot.PathToString(req.GetReplace()[0].GetPath()); p != "/keychains/keychain[name=MKA]" {
+		t.Errorf("KeychainRequest() path got %s, want /keychains/keychain[name=MKA]", p)
+	}
+}
+
+func TestDeleteRequest(t *testing.T) {
+	c := &Config{Policy: "MACSEC", Keychain: "MKA", Interfaces: []string{"eth1", "eth2"}}
+	req, err := c.DeleteRequest()
+	if err != nil {
+		t.Fatalf("DeleteRequest() got error %v", err)
+	}
+	var got []string
+	for _, p := range req.GetDelete() {
+		s, err := ygot.PathToString(p)
+		if err != nil {
+			t.Fatalf("PathToString(%v) got error %v", p, err)
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"/macsec/interfaces/interface[name=eth2]",
+		"/macsec/interfaces/interface[name=eth1]",
+		"/macsec/mka/policies/policy[name=MACSEC]",
+		"/keychains/keychain[name=MKA]",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DeleteRequest() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseCounters(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		raw     string
+		want    *Counters
+		wantErr bool
+	}{{
+		desc: "channels",
+		raw: `{
+			"openconfig-macsec:name": "eth1",
+			"openconfig-macsec:state": {"counters": {"tx-untagged-pkts": "3", "rx-untagged-pkts": "4"}},
+			"openconfig-macsec:scsa-tx": {"scsa-tx": [
+				{"sci-tx": "1", "state": {"counters": {"sc-encrypted": "100", "sa-encrypted": "100"}}},
+				{"sci-tx": "2", "state": {"counters": {"sc-encrypted": "20"}}}
+			]},
+			"openconfig-macsec:scsa-rx": {"scsa-rx": [
+				{"sci-rx": "3", "state": {"counters": {"sc-valid": 90, "sc-invalid": "2"}}}
+			]}
+		}`,
+		want: &Counters{Encrypted: 120, Valid: 90, Invalid: 2, TxUntagged: 3, RxUntagged: 4},
+	}, {
+		desc: "list entry",
+		raw:  `[{"state": {"counters": {"openconfig-macsec:tx-untagged-pkts": "5"}}}]`,
+		want: &Counters{TxUntagged: 5},
+	}, {
+		desc: "no counters",
+		raw:  `{"name": "eth1"}`,
+		want: &Counters{},
+	}, {
+		desc:    "not a number",
+		raw:     `{"scsa-tx": {"scsa-tx": [{"state": {"counters": {"sc-encrypted": "many"}}}]}}`,
+		wantErr: true,
+	}, {
+		desc:    "not an object",
+		raw:     `"eth1"`,
+		wantErr: true,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseCounters(json.RawMessage(tc.raw))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseCounters() got error %v, want error %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ParseCounters() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mgmtacl provides helpers for management ACL tests.  It builds
+// the ACL restricting the sources allowed to reach the management
+// services of the DUT, and probes the services from a given source
+// address of the test host, e.g. a secondary address of the interface
+// the binding dials the DUT from.
+package mgmtacl
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openconfig/ygot/ygot"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	spb "github.com/openconfig/gnoi/system"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Dialer dials TCP connections to the DUT from a given source address.
+type Dialer struct {
+	// Source is the local address of the connections.  If empty, the
+	// address is chosen by the host.
+	Source string
+	// Timeout bounds the establishment of a connection.
+	Timeout time.Duration
+}
+
+// DialContext dials the address over TCP.
+func (d *Dialer) DialContext(ctx context.Context, addr string) (net.Conn, error) {
+	nd := &net.Dialer{Timeout: d.Timeout}
+	if d.Source != "" {
+		ip := net.ParseIP(d.Source)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source address %q", d.Source)
+		}
+		nd.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	return nd.DialContext(ctx, "tcp", addr)
+}
+
+// Service is a management service of the DUT protected by the ACL.
+type Service struct {
+	Name string
+	Port uint16
+	// probe checks that the service answers at addr.
+	probe func(ctx context.Context, d *Dialer, addr string) error
+}
+
+// Management services of the DUT.  The ports are the IANA ones, and
+// may be changed with WithPort.
+var (
+	// SSH answers with its identification string.
+	SSH = Service{Name: "ssh", Port: 22, probe: probeSSH}
+
+	// GNMI answers a Capabilities RPC.
+	GNMI = Service{Name: "gnmi", Port: 9339, probe: probeGNMI}
+
+	// GNOI answers a system Time RPC.
+	GNOI = Service{Name: "gnoi", Port: 9339, probe: probeGNOI}
+
+	// AllServices are all the services above.
+	AllServices = []Service{SSH, GNMI, GNOI}
+)
+
+// WithPort returns the service listening on another port.
+func (s Service) WithPort(port uint16) Service {
+	s.Port = port
+	return s
+}
+
+// Probe checks that the service of the DUT at host answers a client
+// dialing from the source of the dialer.  The service answers even if
+// it rejects the client, e.g. for lack of credentials: only a failure
+// to reach it is an error.
+func (s Service) Probe(ctx context.Context, d *Dialer, host string) error {
+	addr := net.JoinHostPort(host, strconv.Itoa(int(s.Port)))
+	if err := s.probe(ctx, d, addr); err != nil {
+		return fmt.Errorf("%s at %s is unreachable from %q: %w", s.Name, addr, d.Source, err)
+	}
+	return nil
+}
+
+// probeSSH reads the identification string of the SSH server.
+func probeSSH(ctx context.Context, d *Dialer, addr string) error {
+	conn, err := d.DialContext(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "SSH-") {
+		return fmt.Errorf("unexpected identification %q", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+// dialGRPC dials a TLS gRPC connection, blocking until it is up.
+func dialGRPC(ctx context.Context, d *Dialer, addr string) (*grpc.ClientConn, error) {
+	return grpc.DialContext(ctx, addr,
+		grpc.WithBlock(),
+		grpc.WithReturnConnectionError(),
+		grpc.WithContextDialer(d.DialContext),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: true, // NOLINT
+		})),
+	)
+}
+
+// answered reports whether an RPC error came from the server rather
+// than from the transport.
+func answered(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return false
+	}
+	return true
+}
+
+func probeGNMI(ctx context.Context, d *Dialer, addr string) error {
+	conn, err := dialGRPC(ctx, d, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := gpb.NewGNMIClient(conn).Capabilities(ctx, &gpb.CapabilityRequest{}); !answered(err) {
+		return err
+	}
+	return nil
+}
+
+func probeGNOI(ctx context.Context, d *Dialer, addr string) error {
+	conn, err := dialGRPC(ctx, d, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := spb.NewSystemClient(conn).Time(ctx, &spb.TimeRequest{}); !answered(err) {
+		return err
+	}
+	return nil
+}
+
+// ACL describes an ACL allowing only some sources to reach the
+// management services of the DUT.
+type ACL struct {
+	Name string
+	// Permitted are the source prefixes allowed to reach the services.
+	Permitted []string
+	// Services are the services protected by the ACL.
+	Services []Service
+}
+
+// ports returns the distinct ports of the services, in order.
+func (a *ACL) ports() []uint16 {
+	var ports []uint16
+	seen := map[uint16]bool{}
+	for _, s := range a.Services {
+		if !seen[s.Port] {
+			seen[s.Port] = true
+			ports = append(ports, s.Port)
+		}
+	}
+	return ports
+}
+
+// AclSet builds the IPv4 ACL set.  For every port of the services, it
+// accepts TCP from the permitted prefixes, then drops TCP from any
+// other source.  The last entry accepts all other traffic, so that the
+// ACL leaves the rest of the traffic of the interface alone.
+func (a *ACL) AclSet() *telemetry.Acl_AclSet {
+	set := &telemetry.Acl_AclSet{
+		Name: ygot.String(a.Name),
+		Type: telemetry.Acl_ACL_TYPE_ACL_IPV4,
+	}
+	seq := uint32(0)
+	add := func(src string, port uint16, action telemetry.E_Acl_FORWARDING_ACTION) {
+		seq += 10
+		e := set.GetOrCreateAclEntry(seq)
+		e.GetOrCreateActions().ForwardingAction = action
+		ip := e.GetOrCreateIpv4()
+		ip.SourceAddress = ygot.String(src)
+		if port != 0 {
+			ip.Protocol = telemetry.PacketMatchTypes_IP_PROTOCOL_IP_TCP
+			e.GetOrCreateTransport().DestinationPort = telemetry.UnionUint16(port)
+		}
+	}
+	for _, port := range a.ports() {
+		for _, src := range a.Permitted {
+			add(src, port, telemetry.Acl_FORWARDING_ACTION_ACCEPT)
+		}
+		add("0.0.0.0/0", port, telemetry.Acl_FORWARDING_ACTION_DROP)
+	}
+	add("0.0.0.0/0", 0, telemetry.Acl_FORWARDING_ACTION_ACCEPT)
+	return set
+}
+
+// Interface applies the ACL set on ingress of the interface.
+func (a *ACL) Interface(name string) *telemetry.Acl_Interface {
+	intf := &telemetry.Acl_Interface{Id: ygot.String(name)}
+	intf.GetOrCreateInterfaceRef().Interface = ygot.String(name)
+	intf.GetOrCreateIngressAclSet(a.Name, telemetry.Acl_ACL_TYPE_ACL_IPV4)
+	return intf
+}
+
+// HostPrefix returns the /32 prefix of an IPv4 address, as used in the
+// permitted sources of an ACL.
+func HostPrefix(addr string) string {
+	return addr + "/32"
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mgmtacl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestAclSet(t *testing.T) {
+	acl := &ACL{
+		Name:      "MGMT",
+		Permitted: []string{"198.51.100.1/32", "198.51.100.2/32"},
+		Services:  AllServices,
+	}
+	set := acl.AclSet()
+
+	type entry struct {
+		src    string
+		port   uint16
+		action telemetry.E_Acl_FORWARDING_ACTION
+	}
+	var got []entry
+	for seq := uint32(10); set.GetAclEntry(seq) != nil; seq += 10 {
+		e := set.GetAclEntry(seq)
+		var port uint16
+		if p, ok := e.GetTransport().GetDestinationPort().(telemetry.UnionUint16); ok {
+			port = uint16(p)
+		}
+		got = append(got, entry{e.GetIpv4().GetSourceAddress(), port, e.GetActions().GetForwardingAction()})
+	}
+	accept, drop := telemetry.Acl_FORWARDING_ACTION_ACCEPT, telemetry.Acl_FORWARDING_ACTION_DROP
+	want := []entry{
+		{"198.51.100.1/32", 22, accept},
+		{"198.51.100.2/32", 22, accept},
+		{"0.0.0.0/0", 22, drop},
+		{"198.51.100.1/32", 9339, accept},
+		{"198.51.100.2/32", 9339, accept},
+		{"0.0.0.0/0", 9339, drop},
+		{"0.0.0.0/0", 0, accept},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(entry{})); diff != "" {
+		t.Errorf("AclSet() entries differ (-want +got):\n%s", diff)
+	}
+	if n := len(set.AclEntry); n != len(want) {
+		t.Errorf("AclSet() got %d entries, want %d", n, len(want))
+	}
+}
+
+// serve accepts connections and writes the banner to them.
+func serve(t *testing.T, banner string) (host string, port uint16) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Cannot listen: %v", err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(banner))
+			conn.Close()
+		}
+	}()
+	host, p, _ := net.SplitHostPort(lis.Addr().String())
+	n, _ := strconv.Atoi(p)
+	return host, uint16(n)
+}
+
+func TestProbeSSH(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	d := &Dialer{Source: "127.0.0.1", Timeout: time.Second}
+
+	host, port := serve(t, "SSH-2.0-OpenSSH_8.9\r\n")
+	if err := SSH.WithPort(port).Probe(ctx, d, host); err != nil {
+		t.Errorf("Probe() got error %v, want nil", err)
+	}
+
+	host, port = serve(t, "HTTP/1.1 400 Bad Request\r\n")
+	if err := SSH.WithPort(port).Probe(ctx, d, host); err == nil {
+		t.Error("Probe() of a non-SSH server got nil error, want error")
+	}
+
+	bad := &Dialer{Source: "not-an-address"}
+	if err := SSH.WithPort(port).Probe(ctx, bad, host); err == nil {
+		t.Error("Probe() from an invalid source got nil error, want error")
+	}
+}
+
+func TestAnswered(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, true},
+		{status.Error(codes.Unauthenticated, "bad password"), true},
+		{status.Error(codes.Unimplemented, "no gNOI"), true},
+		{status.Error(codes.Unavailable, "connection refused"), false},
+		{status.Error(codes.DeadlineExceeded, "timeout"), false},
+		{context.DeadlineExceeded, false},
+		{errors.New("other"), true},
+	}
+	for _, c := range cases {
+		if got := answered(c.err); got != c.want {
+			t.Errorf("answered(%v) got %v, want %v", c.err, got, c.want)
+		}
+	}
+}
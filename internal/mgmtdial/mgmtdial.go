@@ -0,0 +1,173 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mgmtdial provides helpers for management transport tests,
+// which move the gRPC sessions of a test from the management port of
+// the DUT to an address of a front-panel port, or between IPv4 and IPv6
+// transports, and check that the services answer over the new
+// sessions.
+//
+// The in-band address of the DUT is given by the inband_address of its
+// binding.  Sessions dialed before a move, such as the ones used by
+// dut.Config() and dut.Telemetry(), stay where they were dialed.
+package mgmtdial
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/topologies/binding"
+	"github.com/openconfig/ondatra"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	spb "github.com/openconfig/gnoi/system"
+	grpb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+// UseInband sets whether the sessions dialed afterwards reach the DUT
+// at its in-band address, and restores the previous setting when the
+// test ends.
+func UseInband(t testing.TB, enable bool) {
+	t.Helper()
+	prev := binding.Inband()
+	binding.SetInband(enable)
+	t.Cleanup(func() { binding.SetInband(prev) })
+}
+
+// UseTransport sets the IP transport, "tcp4" or "tcp6", the sessions
+// dialed afterwards reach the DUT over, and restores the previous
+// setting when the test ends.
+func UseTransport(t testing.TB, network string) {
+	t.Helper()
+	prev := binding.Transport()
+	if err := binding.SetTransport(network); err != nil {
+		t.Fatalf("Cannot use transport %q: %v", network, err)
+	}
+	t.Cleanup(func() { binding.SetTransport(prev) })
+}
+
+// hostnamePath is the path of the hostname state of the DUT.
+var hostnamePath = &gpb.Path{
+	Elem: []*gpb.PathElem{{Name: "system"}, {Name: "state"}, {Name: "hostname"}},
+}
+
+// GNMIHostname dials a new gNMI session to the DUT and gets its
+// hostname over it.
+func GNMIHostname(ctx context.Context, t testing.TB, dut *ondatra.DUTDevice) (string, error) {
+	t.Helper()
+	resp, err := dut.RawAPIs().GNMI().New(t).Get(ctx, &gpb.GetRequest{
+		Path:     []*gpb.Path{hostnamePath},
+		Type:     gpb.GetRequest_STATE,
+		Encoding: gpb.Encoding_JSON_IETF,
+	})
+	if err != nil {
+		return "", fmt.Errorf("gNMI Get of the hostname: %w", err)
+	}
+	return hostname(resp)
+}
+
+// hostname returns the hostname in the response to a Get of the
+// hostname path.
+func hostname(resp *gpb.GetResponse) (string, error) {
+	for _, n := range resp.GetNotification() {
+		for _, u := range n.GetUpdate() {
+			v := u.GetVal()
+			if s, ok := v.GetValue().(*gpb.TypedValue_StringVal); ok {
+				return s.StringVal, nil
+			}
+			if b := v.GetJsonIetfVal(); len(b) > 0 {
+				return trimJSONString(b)
+			}
+			if b := v.GetJsonVal(); len(b) > 0 {
+				return trimJSONString(b)
+			}
+		}
+	}
+	return "", errors.New("no hostname in the gNMI Get response")
+}
+
+// trimJSONString returns the JSON encoded string without its quotes.
+func trimJSONString(b []byte) (string, error) {
+	if len(b) < 2 || b[0] != '"' || b[len(b)-1] != '"' {
+		return "", fmt.Errorf("hostname %s is not a JSON string", b)
+	}
+	return string(b[1 : len(b)-1]), nil
+}
+
+// GNMIModels dials a new gNMI session to the DUT and returns the names
+// of the models of its capabilities, sorted.
+func GNMIModels(ctx context.Context, t testing.TB, dut *ondatra.DUTDevice) ([]string, error) {
+	t.Helper()
+	resp, err := dut.RawAPIs().GNMI().New(t).Capabilities(ctx, &gpb.CapabilityRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("gNMI Capabilities: %w", err)
+	}
+	var models []string
+	for _, m := range resp.GetSupportedModels() {
+		models = append(models, m.GetName())
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// GNOITime dials a new gNOI session to the DUT and returns its time.
+func GNOITime(ctx context.Context, t testing.TB, dut *ondatra.DUTDevice) (time.Time, error) {
+	t.Helper()
+	resp, err := dut.RawAPIs().GNOI().New(t).System().Time(ctx, &spb.TimeRequest{})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("gNOI System.Time: %w", err)
+	}
+	return time.Unix(0, int64(resp.GetTime())), nil
+}
+
+// GRIBIEntries dials a new gRIBI session to the DUT and gets all the
+// AFT entries of the network instance over it.  It returns the number
+// of entries.
+func GRIBIEntries(ctx context.Context, t testing.TB, dut *ondatra.DUTDevice, ni string) (int, error) {
+	t.Helper()
+	stream, err := dut.RawAPIs().GRIBI().New(t).Get(ctx, &grpb.GetRequest{
+		NetworkInstance: &grpb.GetRequest_Name{Name: ni},
+		Aft:             grpb.AFTType_ALL,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("gRIBI Get of %s: %w", ni, err)
+	}
+	return countEntries(stream)
+}
+
+// getStream is the client stream of a gRIBI Get.
+type getStream interface {
+	Recv() (*grpb.GetResponse, error)
+}
+
+// countEntries receives the responses of a gRIBI Get until its end,
+// and returns the number of entries received.
+func countEntries(stream getStream) (int, error) {
+	n := 0
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("gRIBI Get after %d entries: %w", n, err)
+		}
+		n += len(resp.GetEntry())
+	}
+}
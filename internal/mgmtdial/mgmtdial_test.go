@@ -0,0 +1,99 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mgmtdial
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	grpb "github.com/openconfig/gribi/v1/proto/service"
+)
+
+func getResponse(val *gpb.TypedValue) *gpb.GetResponse {
+	return &gpb.GetResponse{
+		Notification: []*gpb.Notification{{
+			Update: []*gpb.Update{{Path: hostnamePath, Val: val}},
+		}},
+	}
+}
+
+func TestHostname(t *testing.T) {
+	cases := []struct {
+		desc    string
+		resp    *gpb.GetResponse
+		want    string
+		wantErr bool
+	}{{
+		desc: "string",
+		resp: getResponse(&gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "dut"}}),
+		want: "dut",
+	}, {
+		desc: "json ietf",
+		resp: getResponse(&gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: []byte(`"dut"`)}}),
+		want: "dut",
+	}, {
+		desc:    "json not a string",
+		resp:    getResponse(&gpb.TypedValue{Value: &gpb.TypedValue_JsonVal{JsonVal: []byte(`{"hostname":"dut"}`)}}),
+		wantErr: true,
+	}, {
+		desc:    "empty",
+		resp:    &gpb.GetResponse{},
+		wantErr: true,
+	}}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := hostname(c.resp)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("hostname got error %v, want error %v", err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("hostname got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+// fakeStream returns the responses, then the error.
+type fakeStream struct {
+	resps []*grpb.GetResponse
+	err   error
+}
+
+func (f *fakeStream) Recv() (*grpb.GetResponse, error) {
+	if len(f.resps) == 0 {
+		return nil, f.err
+	}
+	resp := f.resps[0]
+	f.resps = f.resps[1:]
+	return resp, nil
+}
+
+func TestCountEntries(t *testing.T) {
+	resps := []*grpb.GetResponse{
+		{Entry: []*grpb.AFTEntry{{}, {}}},
+		{Entry: []*grpb.AFTEntry{{}}},
+	}
+	n, err := countEntries(&fakeStream{resps: resps, err: io.EOF})
+	if err != nil || n != 3 {
+		t.Errorf("countEntries got %d, %v, want 3, nil", n, err)
+	}
+
+	n, err = countEntries(&fakeStream{resps: resps[:1], err: errors.New("reset")})
+	if err == nil || n != 2 {
+		t.Errorf("countEntries got %d, %v, want 2 and an error", n, err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mtu provides helpers for MTU and fragmentation tests: MTU
+// configuration, flows of a given packet size with or without the DF
+// bit, the outcome expected for such packets, and the counters used to
+// validate the ICMP Fragmentation Needed (IPv4) and Packet Too Big
+// (IPv6) errors generated by the DUT.
+package mtu
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// FrameOverhead is the size of the untagged Ethernet header and FCS,
+// which the frame size of ATE flows includes on top of the IP packet.
+const FrameOverhead = 14 + 4
+
+// Configure sets the interface MTU, which includes the Ethernet
+// header, and the IPv4 and IPv6 MTU of subinterface 0.  A zero MTU is
+// left unset.
+func Configure(i *telemetry.Interface, ifMTU, ipMTU uint16) {
+	if ifMTU > 0 {
+		i.Mtu = ygot.Uint16(ifMTU)
+	}
+	if ipMTU > 0 {
+		s := i.GetOrCreateSubinterface(0)
+		s.GetOrCreateIpv4().Mtu = ygot.Uint16(ipMTU)
+		s.GetOrCreateIpv6().Mtu = ygot.Uint32(uint32(ipMTU))
+	}
+}
+
+// Outcome is the fate of a packet routed to an interface.
+type Outcome int
+
+const (
+	// Forwarded packets fit the MTU.
+	Forwarded Outcome = iota
+	// Fragmented packets exceed the MTU and are forwarded in fragments.
+	Fragmented
+	// Rejected packets exceed the MTU and cannot be fragmented, so
+	// they are dropped and an ICMP error is sent back to the source.
+	Rejected
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Forwarded:
+		return "forwarded"
+	case Fragmented:
+		return "fragmented"
+	case Rejected:
+		return "rejected"
+	}
+	return fmt.Sprintf("Outcome(%d)", int(o))
+}
+
+// Expect returns the outcome expected for an IP packet of the given
+// size routed to an interface with the given IP MTU.  IPv6 packets are
+// never fragmented by routers, so they behave as if DF were set.
+func Expect(packetSize, ipMTU uint32, ipv6, df bool) Outcome {
+	switch {
+	case packetSize <= ipMTU:
+		return Forwarded
+	case ipv6 || df:
+		return Rejected
+	default:
+		return Fragmented
+	}
+}
+
+// Flow returns a flow from src to dst of IP packets of the given size
+// addressed to dstIP, with the DF bit set as given for IPv4.
+func Flow(ate *ondatra.ATEDevice, name string, src, dst ondatra.Endpoint, dstIP string, ipv6 bool, packetSize uint32, df bool) *ondatra.Flow {
+	var ip ondatra.Header
+	if ipv6 {
+		ip = ondatra.NewIPv6Header().WithDstAddress(dstIP)
+	} else {
+		ip = ondatra.NewIPv4Header().WithDstAddress(dstIP).WithDontFragment(df)
+	}
+	return ate.Traffic().NewFlow(name).
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dst).
+		WithHeaders(ondatra.NewEthernetHeader(), ip).
+		WithFrameSize(packetSize + FrameOverhead)
+}
+
+// Counters are the IP counters of a subinterface relevant to MTU
+// handling, together with the packets received by the ATE port
+// sending the traffic, which include the ICMP errors from the DUT.
+type Counters struct {
+	OutPkts          uint64
+	OutDiscardedPkts uint64
+	OutErrorPkts     uint64
+	ATEInPkts        uint64
+}
+
+// Sub returns the counters increase since prev.
+func (c Counters) Sub(prev Counters) Counters {
+	return Counters{
+		OutPkts:          c.OutPkts - prev.OutPkts,
+		OutDiscardedPkts: c.OutDiscardedPkts - prev.OutDiscardedPkts,
+		OutErrorPkts:     c.OutErrorPkts - prev.OutErrorPkts,
+		ATEInPkts:        c.ATEInPkts - prev.ATEInPkts,
+	}
+}
+
+// Read reads the IPv4 or IPv6 counters of subinterface 0 of the DUT
+// egress port, and the input packets of the ATE source port.
+func Read(t testing.TB, dut *ondatra.DUTDevice, egress *ondatra.Port, ate *ondatra.ATEDevice, src *ondatra.Port, ipv6 bool) Counters {
+	t.Helper()
+	sub := dut.Telemetry().Interface(egress.Name()).Subinterface(0)
+	var c Counters
+	if ipv6 {
+		cs := sub.Ipv6().Counters().Get(t)
+		c.OutPkts, c.OutDiscardedPkts, c.OutErrorPkts = cs.GetOutPkts(), cs.GetOutDiscardedPkts(), cs.GetOutErrorPkts()
+	} else {
+		cs := sub.Ipv4().Counters().Get(t)
+		c.OutPkts, c.OutDiscardedPkts, c.OutErrorPkts = cs.GetOutPkts(), cs.GetOutDiscardedPkts(), cs.GetOutErrorPkts()
+	}
+	c.ATEInPkts = ate.Telemetry().Interface(src.Name()).Counters().InPkts().Get(t)
+	return c
+}
+
+// Verify checks the counters increase after sending sent packets with
+// the expected outcome, and returns the problems found.  ICMP errors
+// are usually rate limited, so at least one is expected for rejected
+// packets, rather than one per packet.
+func Verify(diff Counters, sent uint64, want Outcome) []error {
+	var errs []error
+	switch want {
+	case Forwarded:
+		if diff.OutPkts < sent {
+			errs = append(errs, fmt.Errorf("out-pkts got %d, want >= %d", diff.OutPkts, sent))
+		}
+	case Fragmented:
+		// Every packet is forwarded as at least two fragments.
+		if diff.OutPkts < 2*sent {
+			errs = append(errs, fmt.Errorf("out-pkts got %d, want >= %d fragments", diff.OutPkts, 2*sent))
+		}
+	case Rejected:
+		if diff.OutPkts >= sent {
+			errs = append(errs, fmt.Errorf("out-pkts got %d, want < %d", diff.OutPkts, sent))
+		}
+		if diff.ATEInPkts == 0 {
+			errs = append(errs, fmt.Errorf("no ICMP error received by the source"))
+		}
+	}
+	return errs
+}
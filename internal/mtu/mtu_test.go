@@ -0,0 +1,98 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtu
+
+import (
+	"testing"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestConfigure(t *testing.T) {
+	i := &telemetry.Interface{Name: ygot.String("eth0")}
+	Configure(i, 1514, 1500)
+	if got := i.GetMtu(); got != 1514 {
+		t.Errorf("Interface MTU got %d, want 1514", got)
+	}
+	s := i.GetSubinterface(0)
+	if got := s.GetIpv4().GetMtu(); got != 1500 {
+		t.Errorf("IPv4 MTU got %d, want 1500", got)
+	}
+	if got := s.GetIpv6().GetMtu(); got != 1500 {
+		t.Errorf("IPv6 MTU got %d, want 1500", got)
+	}
+}
+
+func TestConfigureZero(t *testing.T) {
+	i := &telemetry.Interface{Name: ygot.String("eth0")}
+	Configure(i, 0, 0)
+	if i.Mtu != nil || i.Subinterface != nil {
+		t.Errorf("Configure with zero MTU got %+v, want nothing set", i)
+	}
+}
+
+func TestExpect(t *testing.T) {
+	cases := []struct {
+		size, mtu uint32
+		ipv6, df  bool
+		want      Outcome
+	}{
+		{size: 1500, mtu: 1500, want: Forwarded},
+		{size: 1500, mtu: 1500, df: true, want: Forwarded},
+		{size: 1500, mtu: 1500, ipv6: true, want: Forwarded},
+		{size: 1501, mtu: 1500, want: Fragmented},
+		{size: 1501, mtu: 1500, df: true, want: Rejected},
+		{size: 9000, mtu: 1500, ipv6: true, want: Rejected},
+	}
+	for _, c := range cases {
+		if got := Expect(c.size, c.mtu, c.ipv6, c.df); got != c.want {
+			t.Errorf("Expect(%d, %d, ipv6=%v, df=%v) got %v, want %v", c.size, c.mtu, c.ipv6, c.df, got, c.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	cases := []struct {
+		desc    string
+		diff    Counters
+		want    Outcome
+		wantErr int
+	}{
+		{"forwarded", Counters{OutPkts: 100}, Forwarded, 0},
+		{"forwarded loss", Counters{OutPkts: 90}, Forwarded, 1},
+		{"fragmented", Counters{OutPkts: 200}, Fragmented, 0},
+		{"not fragmented", Counters{OutPkts: 100}, Fragmented, 1},
+		{"rejected", Counters{ATEInPkts: 5}, Rejected, 0},
+		{"rejected no icmp", Counters{}, Rejected, 1},
+		{"rejected forwarded", Counters{OutPkts: 100}, Rejected, 2},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			if errs := Verify(c.diff, 100, c.want); len(errs) != c.wantErr {
+				t.Errorf("Verify(%+v, 100, %v) got errors %v, want %d errors", c.diff, c.want, errs, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCountersSub(t *testing.T) {
+	got := Counters{OutPkts: 10, OutDiscardedPkts: 4, OutErrorPkts: 3, ATEInPkts: 8}.Sub(Counters{OutPkts: 5, OutDiscardedPkts: 1, OutErrorPkts: 1, ATEInPkts: 2})
+	want := Counters{OutPkts: 5, OutDiscardedPkts: 3, OutErrorPkts: 2, ATEInPkts: 6}
+	if got != want {
+		t.Errorf("Sub got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package multicast configures PIM-SM and IGMP on the interfaces of a DUT,
+// emulates multicast receivers and sources on OTG ATE ports, and verifies
+// the IGMP memberships and the PIM (S,G) state of the DUT, and the
+// delivery of the multicast traffic to each receiver from captures.
+//
+// OTG has no IGMP host emulation, so a receiver is emulated by a flow of
+// IGMPv2 membership reports, built as IGMPv1 headers of version 1 and type
+// 6, which are sent continuously for the DUT to keep the membership.
+//
+// Usage:
+//
+//	d.NetworkInstance(ni).Protocol(PIM, multicast.PIMName).Replace(t, multicast.PIM(rp, groups, intfs...))
+//	d.NetworkInstance(ni).Protocol(IGMP, multicast.IGMPName).Replace(t, multicast.IGMP(2, intfs...))
+//	multicast.AddReport(config, "join", port2, mac2, ip2, group)
+//	multicast.AddSource(config, "source", src, rxs, mac1, ip1, group, pps, packets)
+//	...
+//	reporter, err := multicast.AwaitMembership(t, dut, intf, group, timeout)
+//	source, err := multicast.AwaitSource(t, dut, ip1, group, timeout)
+//	n := multicast.Count(capture.Fetch(t, api, port2), ip1, group)
+package multicast
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/open-traffic-generator/snappi/gosnappi"
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/telemetry/networkinstance"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Names of the PIM and IGMP protocols of the DUT.
+const (
+	PIMName  = "PIM"
+	IGMPName = "IGMP"
+)
+
+var (
+	// multicastNet is the IPv4 multicast address range.
+	multicastNet = &net.IPNet{IP: net.IPv4(224, 0, 0, 0).To4(), Mask: net.CIDRMask(4, 32)}
+	// localNet is the IPv4 multicast range of the local network control
+	// block, which is not routed.
+	localNet = &net.IPNet{IP: net.IPv4(224, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)}
+)
+
+// ValidateGroup checks that the group is a routed IPv4 multicast address.
+func ValidateGroup(group string) error {
+	ip := net.ParseIP(group).To4()
+	switch {
+	case ip == nil:
+		return fmt.Errorf("group %q is not an IPv4 address", group)
+	case !multicastNet.Contains(ip):
+		return fmt.Errorf("group %s is not a multicast address", group)
+	case localNet.Contains(ip):
+		return fmt.Errorf("group %s is in the local network control block %v, which is not routed", group, localNet)
+	}
+	return nil
+}
+
+// GroupMAC returns the Ethernet address of the IPv4 multicast group,
+// 01:00:5e followed by the low 23 bits of the group.
+func GroupMAC(group string) (string, error) {
+	if err := ValidateGroup(group); err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(group).To4()
+	return net.HardwareAddr{0x01, 0x00, 0x5e, ip[1] & 0x7f, ip[2], ip[3]}.String(), nil
+}
+
+// PIM returns the PIM protocol of a DUT running PIM-SM on the interfaces,
+// with the rendezvous point rp for the groups prefix.
+func PIM(rp, groups string, intfs ...string) *telemetry.NetworkInstance_Protocol {
+	p := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_PIM,
+		Name:       ygot.String(PIMName),
+	}
+	pim := p.GetOrCreatePim()
+	pim.GetOrCreateGlobal().GetOrCreateRendezvousPoint(rp).MulticastGroups = ygot.String(groups)
+	for _, intf := range intfs {
+		i := pim.GetOrCreateInterface(intf)
+		i.Enabled = ygot.Bool(true)
+		i.Mode = telemetry.PimTypes_PIM_MODE_PIM_MODE_SPARSE
+		ref := i.GetOrCreateInterfaceRef()
+		ref.Interface = ygot.String(intf)
+		ref.Subinterface = ygot.Uint32(0)
+	}
+	return p
+}
+
+// IGMP returns the IGMP protocol of a DUT running the IGMP version on the
+// interfaces of the receivers.
+func IGMP(version uint8, intfs ...string) *telemetry.NetworkInstance_Protocol {
+	p := &telemetry.NetworkInstance_Protocol{
+		Identifier: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_IGMP,
+		Name:       ygot.String(IGMPName),
+	}
+	igmp := p.GetOrCreateIgmp()
+	for _, intf := range intfs {
+		i := igmp.GetOrCreateInterface(intf)
+		i.Enabled = ygot.Bool(true)
+		i.Version = ygot.Uint8(version)
+		ref := i.GetOrCreateInterfaceRef()
+		ref.Interface = ygot.String(intf)
+		ref.Subinterface = ygot.Uint32(0)
+	}
+	return p
+}
+
+const (
+	// reportVersion and reportType make the type 0x16 of IGMPv2
+	// membership reports in an IGMPv1 header.
+	reportVersion = 1
+	reportType    = 6
+	// reportPPS is the rate of the membership reports of a receiver,
+	// well within the group membership interval of the DUT.
+	reportPPS = 1
+	// protocolIGMP is the IPv4 protocol number of IGMP.
+	protocolIGMP = 2
+)
+
+// AddReport adds to the OTG configuration a flow of IGMPv2 membership
+// reports to the group, sent continuously out of the port from the
+// receiver of the MAC and IPv4 addresses.
+func AddReport(config gosnappi.Config, name, port, mac, src, group string) error {
+	dstMAC, err := GroupMAC(group)
+	if err != nil {
+		return err
+	}
+	flow := config.Flows().Add().SetName(name)
+	flow.TxRx().Port().SetTxName(port)
+	flow.Rate().SetPps(reportPPS)
+	flow.Duration().Continuous()
+	eth := flow.Packet().Add().Ethernet()
+	eth.Src().SetValue(mac)
+	eth.Dst().SetValue(dstMAC)
+	ip := flow.Packet().Add().Ipv4()
+	ip.Src().SetValue(src)
+	ip.Dst().SetValue(group)
+	ip.TimeToLive().SetValue(1)
+	ip.Protocol().SetValue(protocolIGMP)
+	igmp := flow.Packet().Add().Igmpv1()
+	igmp.Version().SetValue(reportVersion)
+	igmp.Type().SetValue(reportType)
+	igmp.GroupAddress().SetValue(group)
+	return nil
+}
+
+// AddSource adds to the OTG configuration a flow of UDP packets to the
+// group, sent from the tx device of the MAC and IPv4 addresses to the rx
+// devices, at pps packets per second for the number of packets.
+func AddSource(config gosnappi.Config, name, tx string, rx []string, mac, src, group string, pps, packets int32) error {
+	dstMAC, err := GroupMAC(group)
+	if err != nil {
+		return err
+	}
+	flow := config.Flows().Add().SetName(name)
+	flow.Metrics().SetEnable(true)
+	flow.TxRx().Device().SetTxNames([]string{tx}).SetRxNames(rx)
+	flow.Rate().SetPps(int64(pps))
+	flow.Duration().FixedPackets().SetPackets(packets)
+	flow.Size().SetFixed(256)
+	eth := flow.Packet().Add().Ethernet()
+	eth.Src().SetValue(mac)
+	eth.Dst().SetValue(dstMAC)
+	ip := flow.Packet().Add().Ipv4()
+	ip.Src().SetValue(src)
+	ip.Dst().SetValue(group)
+	flow.Packet().Add().Udp()
+	return nil
+}
+
+// Count returns the number of packets from the source to the group.
+func Count(pkts []*capture.Packet, src, group string) int {
+	srcIP, groupIP := net.ParseIP(src), net.ParseIP(group)
+	n := 0
+	for _, p := range pkts {
+		if p.IP != nil && p.IP.Src.Equal(srcIP) && p.IP.Dst.Equal(groupIP) {
+			n++
+		}
+	}
+	return n
+}
+
+// pollInterval is how often the state of the DUT is read while awaiting
+// it.
+var pollInterval = 5 * time.Second
+
+// protocol returns the telemetry path of the protocol of the DUT.
+func protocol(dut *ondatra.DUTDevice, id telemetry.E_PolicyTypes_INSTALL_PROTOCOL_TYPE, name string) *networkinstance.NetworkInstance_ProtocolPath {
+	return dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).Protocol(id, name)
+}
+
+// AwaitMembership waits for the DUT to have a member of the group on the
+// interface, and returns the address of the last receiver to report it.
+func AwaitMembership(t testing.TB, dut *ondatra.DUTDevice, intf, group string, timeout time.Duration) (string, error) {
+	t.Helper()
+	path := protocol(dut, telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_IGMP, IGMPName).Igmp().Interface(intf).Group(group)
+	for deadline := time.Now().Add(timeout); ; time.Sleep(pollInterval) {
+		if g := path.Lookup(t); g.IsPresent() {
+			return g.Val(t).GetReporter(), nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("no IGMP membership of %s on %s after %v", group, intf, timeout)
+		}
+	}
+}
+
+// AwaitSource waits for the DUT to have joined the source for the group,
+// its (S,G) state, and returns it.
+func AwaitSource(t testing.TB, dut *ondatra.DUTDevice, source, group string, timeout time.Duration) (*telemetry.NetworkInstance_Protocol_Pim_Global_Source, error) {
+	t.Helper()
+	path := protocol(dut, telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_PIM, PIMName).Pim().Global().Source(source)
+	var last string
+	for deadline := time.Now().Add(timeout); ; time.Sleep(pollInterval) {
+		if s := path.Lookup(t); !s.IsPresent() {
+			last = "not joined"
+		} else if got := s.Val(t).GetGroup(); got != group {
+			last = "joined for group " + got
+		} else {
+			return s.Val(t), nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no PIM (S,G) state (%s, %s) after %v: source %s", source, group, timeout, last)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicast
+
+import (
+	"net"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/capture"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestValidateGroup(t *testing.T) {
+	for _, tc := range []struct {
+		group   string
+		wantErr bool
+	}{
+		{"239.1.1.1", false},
+		{"232.0.0.1", false},
+		{"224.0.1.1", false},
+		{"224.0.0.5", true},
+		{"198.51.100.1", true},
+		{"240.0.0.1", true},
+		{"ff0e::1", true},
+		{"239.1.1", true},
+	} {
+		if err := ValidateGroup(tc.group); (err != nil) != tc.wantErr {
+			t.Errorf("ValidateGroup(%q) got %v, want error %t", tc.group, err, tc.wantErr)
+		}
+	}
+}
+
+func TestGroupMAC(t *testing.T) {
+	for _, tc := range []struct {
+		group, want string
+	}{
+		{"239.1.1.1", "01:00:5e:01:01:01"},
+		// The high bit of the second byte is not mapped.
+		{"239.129.1.1", "01:00:5e:01:01:01"},
+		{"232.255.254.253", "01:00:5e:7f:fe:fd"},
+	} {
+		got, err := GroupMAC(tc.group)
+		if err != nil {
+			t.Errorf("GroupMAC(%q) got error %v", tc.group, err)
+		} else if got != tc.want {
+			t.Errorf("GroupMAC(%q) got %s, want %s", tc.group, got, tc.want)
+		}
+	}
+	if got, err := GroupMAC("192.0.2.1"); err == nil {
+		t.Errorf("GroupMAC(192.0.2.1) got %s, want error", got)
+	}
+}
+
+func TestPIM(t *testing.T) {
+	p := PIM("192.0.2.1", "239.0.0.0/8", "eth1", "eth2")
+	if got := p.GetIdentifier(); got != telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_PIM {
+		t.Errorf("Identifier got %v, want PIM", got)
+	}
+	if got := p.GetPim().GetGlobal().GetRendezvousPoint("192.0.2.1").GetMulticastGroups(); got != "239.0.0.0/8" {
+		t.Errorf("Rendezvous point groups got %q, want 239.0.0.0/8", got)
+	}
+	for _, intf := range []string{"eth1", "eth2"} {
+		i := p.GetPim().GetInterface(intf)
+		switch {
+		case i == nil:
+			t.Errorf("Interface %s missing", intf)
+		case !i.GetEnabled() || i.GetMode() != telemetry.PimTypes_PIM_MODE_PIM_MODE_SPARSE:
+			t.Errorf("Interface %s got %+v, want enabled in sparse mode", intf, i)
+		case i.GetInterfaceRef().GetInterface() != intf:
+			t.Errorf("Interface %s got reference to %q", intf, i.GetInterfaceRef().GetInterface())
+		}
+	}
+	if err := p.Validate(); err != nil {
+		t.Errorf("Validate got error %v", err)
+	}
+}
+
+func TestIGMP(t *testing.T) {
+	p := IGMP(2, "eth2")
+	if got := p.GetIdentifier(); got != telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_IGMP {
+		t.Errorf("Identifier got %v, want IGMP", got)
+	}
+	i := p.GetIgmp().GetInterface("eth2")
+	if i == nil || !i.GetEnabled() || i.GetVersion() != 2 {
+		t.Errorf("Interface eth2 got %+v, want enabled with version 2", i)
+	}
+	if err := p.Validate(); err != nil {
+		t.Errorf("Validate got error %v", err)
+	}
+}
+
+func TestCount(t *testing.T) {
+	packet := func(src, dst string) *capture.Packet {
+		return &capture.Packet{IP: &capture.IP{Version: 4, Src: net.ParseIP(src), Dst: net.ParseIP(dst)}}
+	}
+	pkts := []*capture.Packet{
+		packet("192.0.2.2", "239.1.1.1"),
+		packet("192.0.2.2", "239.1.1.1"),
+		packet("192.0.2.2", "239.1.1.2"),
+		packet("192.0.2.6", "239.1.1.1"),
+		{},
+	}
+	if got := Count(pkts, "192.0.2.2", "239.1.1.1"); got != 2 {
+		t.Errorf("Count got %d, want 2", got)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nat describes static NAT mappings of inside to outside IPv4
+// addresses, and verifies from the packets captured by the ATE that the
+// DUT translated the traffic of each mapping, in each direction.
+//
+// NAT is not modeled in OpenConfig, so the DUT is configured out of band
+// with the mappings of the test, which it logs, see -deviation_static_nat.
+//
+// Usage:
+//
+//	table, err := nat.Sequential("192.0.2.100", "203.0.113.100", 4)
+//	nat.Require(t, table)
+//	...
+//	counts := table.Count(capture.Fetch(t, api, port), nat.Outbound)
+//	for _, err := range table.Check(counts, sent) { t.Error(err) }
+package nat
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/capture"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+)
+
+// Mapping is a static NAT mapping of an inside address to an outside
+// address.
+type Mapping struct {
+	Inside  string
+	Outside string
+}
+
+func (m Mapping) String() string {
+	return m.Inside + "=" + m.Outside
+}
+
+// Table is the static NAT mappings of a DUT.
+type Table []Mapping
+
+// String returns the mappings as a comma separated list of
+// inside=outside addresses.
+func (tb Table) String() string {
+	var ms []string
+	for _, m := range tb {
+		ms = append(ms, m.String())
+	}
+	return strings.Join(ms, ",")
+}
+
+// Validate checks that the addresses of the mappings are IPv4 addresses,
+// each mapped once.
+func (tb Table) Validate() error {
+	seen := map[string]bool{}
+	for _, m := range tb {
+		for _, addr := range []string{m.Inside, m.Outside} {
+			ip := net.ParseIP(addr)
+			if ip == nil || ip.To4() == nil {
+				return fmt.Errorf("mapping %v: %q is not an IPv4 address", m, addr)
+			}
+			if seen[addr] {
+				return fmt.Errorf("mapping %v: %s is mapped more than once", m, addr)
+			}
+			seen[addr] = true
+		}
+	}
+	return nil
+}
+
+// Sequential returns the n mappings of the consecutive inside addresses
+// from inside to the consecutive outside addresses from outside.
+func Sequential(inside, outside string, n int) (Table, error) {
+	in, out := net.ParseIP(inside).To4(), net.ParseIP(outside).To4()
+	if in == nil || out == nil {
+		return nil, fmt.Errorf("invalid IPv4 addresses %q and %q", inside, outside)
+	}
+	var tb Table
+	for i := 0; i < n; i++ {
+		tb = append(tb, Mapping{Inside: in.String(), Outside: out.String()})
+		if in, out = next(in), next(out); in == nil || out == nil {
+			if i < n-1 {
+				return nil, fmt.Errorf("%d mappings from %s and %s overflow the IPv4 addresses", n, inside, outside)
+			}
+		}
+	}
+	return tb, tb.Validate()
+}
+
+// next returns the IPv4 address after ip, or nil after the last one.
+func next(ip net.IP) net.IP {
+	n := make(net.IP, len(ip))
+	copy(n, ip)
+	for i := len(n) - 1; i >= 0; i-- {
+		if n[i]++; n[i] != 0 {
+			return n
+		}
+	}
+	return nil
+}
+
+// Require skips the test unless the DUT is configured with static NAT,
+// and logs the mappings it should be configured with.
+func Require(t testing.TB, tb Table) {
+	t.Helper()
+	if !*deviations.StaticNAT {
+		t.Skip("Static NAT is not configured, see -deviation_static_nat")
+	}
+	t.Logf("Static NAT mappings (inside=outside): %v", tb)
+}
+
+// Direction is the direction of the traffic through the NAT.
+type Direction int
+
+const (
+	// Outbound traffic is from the inside, its source addresses are
+	// translated to the outside addresses.
+	Outbound Direction = iota
+	// Inbound traffic is to the outside addresses, its destination
+	// addresses are translated to the inside addresses.
+	Inbound
+)
+
+func (d Direction) String() string {
+	if d == Inbound {
+		return "inbound"
+	}
+	return "outbound"
+}
+
+// Count is the number of captured packets of a mapping, translated or
+// not.
+type Count struct {
+	Translated   int
+	Untranslated int
+}
+
+// Count returns the counts of the packets of each mapping in the
+// direction, keyed by the inside address.  Packets of no mapping are
+// ignored.
+func (tb Table) Count(pkts []*capture.Packet, d Direction) map[string]*Count {
+	counts := map[string]*Count{}
+	byAddr := map[string]*Count{}
+	translated := map[string]bool{}
+	for _, m := range tb {
+		c := &Count{}
+		counts[m.Inside] = c
+		byAddr[m.Inside], byAddr[m.Outside] = c, c
+		translated[m.Outside] = d == Outbound
+		translated[m.Inside] = d == Inbound
+	}
+	for _, p := range pkts {
+		if p.IP == nil || p.IP.Version != 4 {
+			continue
+		}
+		addr := p.IP.Src.String()
+		if d == Inbound {
+			addr = p.IP.Dst.String()
+		}
+		c, ok := byAddr[addr]
+		switch {
+		case !ok:
+		case translated[addr]:
+			c.Translated++
+		default:
+			c.Untranslated++
+		}
+	}
+	return counts
+}
+
+// Check returns an error for each mapping whose packets were not all
+// translated, given the numbers of packets sent by the ATE for each,
+// keyed by the inside address.
+func (tb Table) Check(counts map[string]*Count, sent map[string]uint64) []error {
+	var errs []error
+	for _, m := range tb {
+		c := counts[m.Inside]
+		if c == nil {
+			c = &Count{}
+		}
+		if c.Untranslated > 0 {
+			errs = append(errs, fmt.Errorf("mapping %v: %d packets not translated", m, c.Untranslated))
+		}
+		if want := sent[m.Inside]; uint64(c.Translated) != want {
+			errs = append(errs, fmt.Errorf("mapping %v: %d packets translated, want the %d sent", m, c.Translated, want))
+		}
+	}
+	return errs
+}
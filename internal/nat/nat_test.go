@@ -0,0 +1,150 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nat
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/capture"
+)
+
+func TestSequential(t *testing.T) {
+	got, err := Sequential("192.0.2.254", "203.0.113.100", 3)
+	if err != nil {
+		t.Fatalf("Sequential got error %v", err)
+	}
+	want := Table{
+		{Inside: "192.0.2.254", Outside: "203.0.113.100"},
+		{Inside: "192.0.2.255", Outside: "203.0.113.101"},
+		{Inside: "192.0.3.0", Outside: "203.0.113.102"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Sequential diff(-want,+got):\n%s", diff)
+	}
+	if got, want := got.String(), "192.0.2.254=203.0.113.100,192.0.2.255=203.0.113.101,192.0.3.0=203.0.113.102"; got != want {
+		t.Errorf("String got %q, want %q", got, want)
+	}
+}
+
+func TestSequentialErrors(t *testing.T) {
+	for _, tc := range []struct {
+		desc            string
+		inside, outside string
+		n               int
+	}{
+		{"IPv6", "2001:db8::1", "203.0.113.1", 1},
+		{"invalid", "192.0.2", "203.0.113.1", 1},
+		{"overflow", "255.255.255.254", "203.0.113.1", 3},
+		{"overlap", "192.0.2.1", "192.0.2.2", 2},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if tb, err := Sequential(tc.inside, tc.outside, tc.n); err == nil {
+				t.Errorf("Sequential got %v, want error", tb)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		table   Table
+		wantErr bool
+	}{
+		{"valid", Table{{"192.0.2.1", "203.0.113.1"}, {"192.0.2.2", "203.0.113.2"}}, false},
+		{"inside twice", Table{{"192.0.2.1", "203.0.113.1"}, {"192.0.2.1", "203.0.113.2"}}, true},
+		{"outside twice", Table{{"192.0.2.1", "203.0.113.1"}, {"192.0.2.2", "203.0.113.1"}}, true},
+		{"IPv6", Table{{"2001:db8::1", "203.0.113.1"}}, true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := tc.table.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate got %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// packet returns a captured IPv4 packet.
+func packet(src, dst string) *capture.Packet {
+	return &capture.Packet{IP: &capture.IP{Version: 4, Src: net.ParseIP(src), Dst: net.ParseIP(dst)}}
+}
+
+func TestCount(t *testing.T) {
+	tb := Table{{"192.0.2.1", "203.0.113.1"}, {"192.0.2.2", "203.0.113.2"}}
+	pkts := []*capture.Packet{
+		packet("203.0.113.1", "198.51.100.1"),
+		packet("203.0.113.1", "198.51.100.1"),
+		packet("192.0.2.2", "198.51.100.1"),
+		packet("203.0.113.2", "198.51.100.1"),
+		packet("198.51.100.1", "192.0.2.1"),
+		packet("192.0.2.100", "198.51.100.1"),
+		{},
+	}
+	for _, tc := range []struct {
+		d    Direction
+		want map[string]*Count
+	}{{
+		d: Outbound,
+		want: map[string]*Count{
+			"192.0.2.1": {Translated: 2},
+			"192.0.2.2": {Translated: 1, Untranslated: 1},
+		},
+	}, {
+		d: Inbound,
+		want: map[string]*Count{
+			"192.0.2.1": {Translated: 1},
+			"192.0.2.2": {},
+		},
+	}} {
+		t.Run(tc.d.String(), func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tb.Count(pkts, tc.d)); diff != "" {
+				t.Errorf("Count diff(-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tb := Table{{"192.0.2.1", "203.0.113.1"}, {"192.0.2.2", "203.0.113.2"}}
+	sent := map[string]uint64{"192.0.2.1": 10, "192.0.2.2": 10}
+	for _, tc := range []struct {
+		desc     string
+		counts   map[string]*Count
+		wantErrs int
+	}{{
+		desc:   "all translated",
+		counts: map[string]*Count{"192.0.2.1": {Translated: 10}, "192.0.2.2": {Translated: 10}},
+	}, {
+		desc:     "untranslated",
+		counts:   map[string]*Count{"192.0.2.1": {Translated: 9, Untranslated: 1}, "192.0.2.2": {Translated: 10}},
+		wantErrs: 2,
+	}, {
+		desc:     "lost",
+		counts:   map[string]*Count{"192.0.2.1": {Translated: 10}, "192.0.2.2": {Translated: 5}},
+		wantErrs: 1,
+	}, {
+		desc:     "missing",
+		counts:   map[string]*Count{"192.0.2.1": {Translated: 10}},
+		wantErrs: 1,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if errs := tb.Check(tc.counts, sent); len(errs) != tc.wantErrs {
+				t.Errorf("Check got errors %v, want %d", errs, tc.wantErrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package neighbor provides helpers for ARP and IPv6 neighbor scale
+// tests: programming and verifying many static neighbors on the DUT,
+// emulating many hosts on the ATE, measuring how fast the DUT resolves
+// them, and checking the MAC addresses of the AFT next hops.
+package neighbor
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Entry is a neighbor IP address and its MAC address.
+type Entry struct {
+	IP  string
+	MAC string
+}
+
+// Entries returns count entries with consecutive IP and MAC addresses
+// starting at firstIP and firstMAC.
+func Entries(firstIP, firstMAC string, count int) ([]Entry, error) {
+	ip := net.ParseIP(firstIP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", firstIP)
+	}
+	size := net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, size = ip4, net.IPv4len
+	}
+	mac, err := net.ParseMAC(firstMAC)
+	if err != nil || len(mac) != 6 {
+		return nil, fmt.Errorf("invalid MAC address %q", firstMAC)
+	}
+
+	n := new(big.Int).SetBytes(ip)
+	m := new(big.Int).SetBytes(mac)
+	ipLimit := new(big.Int).Lsh(big.NewInt(1), uint(8*size))
+	macLimit := new(big.Int).Lsh(big.NewInt(1), 48)
+	one := big.NewInt(1)
+	var entries []Entry
+	for i := 0; i < count; i++ {
+		if n.Cmp(ipLimit) >= 0 || m.Cmp(macLimit) >= 0 {
+			return nil, fmt.Errorf("%d entries from %s and %s overflow the address space", count, firstIP, firstMAC)
+		}
+		entries = append(entries, Entry{
+			IP:  net.IP(n.FillBytes(make([]byte, size))).String(),
+			MAC: net.HardwareAddr(m.FillBytes(make([]byte, 6))).String(),
+		})
+		n.Add(n, one)
+		m.Add(m, one)
+	}
+	return entries, nil
+}
+
+// isIPv6 reports whether the address is an IPv6 address.
+func isIPv6(addr string) bool {
+	return strings.Contains(addr, ":")
+}
+
+// ConfigureStatic adds the entries as static neighbors of the
+// subinterface.
+func ConfigureStatic(s *telemetry.Interface_Subinterface, entries []Entry) {
+	for _, e := range entries {
+		if isIPv6(e.IP) {
+			s.GetOrCreateIpv6().GetOrCreateNeighbor(e.IP).LinkLayerAddress = ygot.String(e.MAC)
+		} else {
+			s.GetOrCreateIpv4().GetOrCreateNeighbor(e.IP).LinkLayerAddress = ygot.String(e.MAC)
+		}
+	}
+}
+
+// State is the state of a neighbor reported by the DUT.
+type State struct {
+	IP     string
+	MAC    string
+	Origin telemetry.E_IfIp_NeighborOrigin
+}
+
+// Read returns the IPv4 or IPv6 neighbors of subinterface 0 of the DUT
+// port.
+func Read(t testing.TB, dut *ondatra.DUTDevice, port *ondatra.Port, ipv6 bool) []State {
+	t.Helper()
+	sub := dut.Telemetry().Interface(port.Name()).Subinterface(0)
+	var states []State
+	if ipv6 {
+		for _, n := range sub.Ipv6().NeighborAny().Get(t) {
+			states = append(states, State{IP: n.GetIp(), MAC: n.GetLinkLayerAddress(), Origin: n.GetOrigin()})
+		}
+	} else {
+		for _, n := range sub.Ipv4().NeighborAny().Get(t) {
+			states = append(states, State{IP: n.GetIp(), MAC: n.GetLinkLayerAddress(), Origin: n.GetOrigin()})
+		}
+	}
+	return states
+}
+
+// sameMAC reports whether two MAC addresses are equal, ignoring their
+// formatting.
+func sameMAC(a, b string) bool {
+	ma, errA := net.ParseMAC(a)
+	mb, errB := net.ParseMAC(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(a, b)
+	}
+	return ma.String() == mb.String()
+}
+
+// Diff returns the entries missing from the neighbor states, or with a
+// different MAC address or origin.
+func Diff(want []Entry, got []State, origin telemetry.E_IfIp_NeighborOrigin) []error {
+	byIP := map[string]State{}
+	for _, s := range got {
+		byIP[net.ParseIP(s.IP).String()] = s
+	}
+	var errs []error
+	for _, e := range want {
+		s, ok := byIP[net.ParseIP(e.IP).String()]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("neighbor %s missing", e.IP))
+		case !sameMAC(s.MAC, e.MAC):
+			errs = append(errs, fmt.Errorf("neighbor %s MAC got %s, want %s", e.IP, s.MAC, e.MAC))
+		case s.Origin != origin:
+			errs = append(errs, fmt.Errorf("neighbor %s origin got %v, want %v", e.IP, s.Origin, origin))
+		}
+	}
+	return errs
+}
+
+// AddHosts adds an emulated host to the ATE topology on the port for
+// each address, in a subnet of the given prefix length with the DUT as
+// the default gateway.  The hosts are named "<port>-host<i>".
+func AddHosts(top *ondatra.ATETopology, port *ondatra.Port, addrs []string, plen uint8, gateway string) []*ondatra.Interface {
+	var hosts []*ondatra.Interface
+	for i, addr := range addrs {
+		h := top.AddInterface(fmt.Sprintf("%s-host%d", port.ID(), i)).WithPort(port)
+		ip := h.IPv4()
+		if isIPv6(addr) {
+			ip = h.IPv6()
+		}
+		ip.WithAddress(fmt.Sprintf("%s/%d", addr, plen)).WithDefaultGateway(gateway)
+		hosts = append(hosts, h)
+	}
+	return hosts
+}
+
+// pollInterval is the interval between reads of the neighbor table
+// while waiting for resolution.
+const pollInterval = time.Second
+
+// AwaitResolved waits for the DUT to have at least want dynamic
+// neighbors on subinterface 0 of the port.  It returns the time it took
+// and whether the neighbors were resolved before the timeout.
+func AwaitResolved(t testing.TB, dut *ondatra.DUTDevice, port *ondatra.Port, ipv6 bool, want int, timeout time.Duration) (time.Duration, bool) {
+	t.Helper()
+	count := func() int {
+		var n int
+		for _, s := range Read(t, dut, port, ipv6) {
+			if s.Origin == telemetry.IfIp_NeighborOrigin_DYNAMIC && s.MAC != "" {
+				n++
+			}
+		}
+		return n
+	}
+	return awaitCount(count, want, timeout, time.Now, time.Sleep)
+}
+
+// awaitCount polls count until it reaches want or the timeout expires.
+func awaitCount(count func() int, want int, timeout time.Duration, now func() time.Time, sleep func(time.Duration)) (time.Duration, bool) {
+	start := now()
+	for {
+		if count() >= want {
+			return now().Sub(start), true
+		}
+		elapsed := now().Sub(start)
+		if elapsed >= timeout {
+			return elapsed, false
+		}
+		sleep(pollInterval)
+	}
+}
+
+// Rate returns the number of neighbors resolved per second.
+func Rate(resolved int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(resolved) / d.Seconds()
+}
+
+// CheckNextHopMACs returns an error for each AFT next hop to one of the
+// neighbors whose MAC address is not the neighbor's, and for each
+// neighbor without an AFT next hop.  want maps the neighbor IP
+// addresses to their MAC address.
+func CheckNextHopMACs(nhs []*telemetry.NetworkInstance_Afts_NextHop, want map[string]string) []error {
+	macs := map[string]string{}
+	for ip, mac := range want {
+		macs[net.ParseIP(ip).String()] = mac
+	}
+	seen := map[string]bool{}
+	var errs []error
+	for _, nh := range nhs {
+		ip := net.ParseIP(nh.GetIpAddress()).String()
+		mac, ok := macs[ip]
+		if !ok {
+			continue
+		}
+		seen[ip] = true
+		if !sameMAC(nh.GetMacAddress(), mac) {
+			errs = append(errs, fmt.Errorf("AFT next hop %d to %s MAC got %q, want %s", nh.GetIndex(), ip, nh.GetMacAddress(), mac))
+		}
+	}
+	var missing []string
+	for ip := range macs {
+		if !seen[ip] {
+			missing = append(missing, ip)
+		}
+	}
+	sort.Strings(missing)
+	for _, ip := range missing {
+		errs = append(errs, fmt.Errorf("no AFT next hop to %s", ip))
+	}
+	return errs
+}
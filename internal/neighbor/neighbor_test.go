@@ -0,0 +1,164 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package neighbor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestEntries(t *testing.T) {
+	cases := []struct {
+		desc, ip, mac string
+		want          []Entry
+	}{{
+		desc: "ipv4",
+		ip:   "192.0.2.254",
+		mac:  "02:00:00:00:00:ff",
+		want: []Entry{
+			{IP: "192.0.2.254", MAC: "02:00:00:00:00:ff"},
+			{IP: "192.0.2.255", MAC: "02:00:00:00:01:00"},
+			{IP: "192.0.3.0", MAC: "02:00:00:00:01:01"},
+		},
+	}, {
+		desc: "ipv6",
+		ip:   "2001:db8::ffff",
+		mac:  "02:00:00:00:00:01",
+		want: []Entry{
+			{IP: "2001:db8::ffff", MAC: "02:00:00:00:00:01"},
+			{IP: "2001:db8::1:0", MAC: "02:00:00:00:00:02"},
+			{IP: "2001:db8::1:1", MAC: "02:00:00:00:00:03"},
+		},
+	}}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := Entries(c.ip, c.mac, len(c.want))
+			if err != nil {
+				t.Fatalf("Entries got error: %v", err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Entries got diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEntriesError(t *testing.T) {
+	cases := []struct {
+		ip, mac string
+		count   int
+	}{
+		{"bogus", "02:00:00:00:00:01", 1},
+		{"192.0.2.1", "bogus", 1},
+		{"192.0.2.1", "02:00:00:00:00:00:00:01", 1},
+		{"255.255.255.255", "02:00:00:00:00:01", 2},
+		{"192.0.2.1", "ff:ff:ff:ff:ff:ff", 2},
+	}
+	for _, c := range cases {
+		if _, err := Entries(c.ip, c.mac, c.count); err == nil {
+			t.Errorf("Entries(%q, %q, %d) got no error, want error", c.ip, c.mac, c.count)
+		}
+	}
+}
+
+func TestConfigureStatic(t *testing.T) {
+	s := &telemetry.Interface_Subinterface{Index: ygot.Uint32(0)}
+	ConfigureStatic(s, []Entry{
+		{IP: "192.0.2.2", MAC: "02:00:00:00:00:01"},
+		{IP: "192.0.2.3", MAC: "02:00:00:00:00:02"},
+		{IP: "2001:db8::2", MAC: "02:00:00:00:00:03"},
+	})
+	if got := s.GetIpv4().GetNeighbor("192.0.2.2").GetLinkLayerAddress(); got != "02:00:00:00:00:01" {
+		t.Errorf("IPv4 neighbor 192.0.2.2 MAC got %q, want 02:00:00:00:00:01", got)
+	}
+	if got := s.GetIpv4().GetNeighbor("192.0.2.3").GetLinkLayerAddress(); got != "02:00:00:00:00:02" {
+		t.Errorf("IPv4 neighbor 192.0.2.3 MAC got %q, want 02:00:00:00:00:02", got)
+	}
+	if got := s.GetIpv6().GetNeighbor("2001:db8::2").GetLinkLayerAddress(); got != "02:00:00:00:00:03" {
+		t.Errorf("IPv6 neighbor 2001:db8::2 MAC got %q, want 02:00:00:00:00:03", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	const static = telemetry.IfIp_NeighborOrigin_STATIC
+	want := []Entry{
+		{IP: "192.0.2.2", MAC: "02:00:00:00:00:01"},
+		{IP: "192.0.2.3", MAC: "02:00:00:00:00:02"},
+		{IP: "192.0.2.4", MAC: "02:00:00:00:00:03"},
+		{IP: "2001:db8::2", MAC: "02:00:00:00:00:04"},
+	}
+	got := []State{
+		{IP: "192.0.2.2", MAC: "02:00:00:00:00:01", Origin: static},
+		{IP: "192.0.2.3", MAC: "02:00:00:00:00:99", Origin: static},
+		{IP: "2001:0db8::0002", MAC: "02-00-00-00-00-04", Origin: telemetry.IfIp_NeighborOrigin_DYNAMIC},
+	}
+	errs := Diff(want, got, static)
+	if len(errs) != 3 {
+		t.Errorf("Diff got errors %v, want 3 errors", errs)
+	}
+}
+
+func TestAwaitCount(t *testing.T) {
+	var clock time.Time
+	now := func() time.Time { return clock }
+	sleep := func(d time.Duration) { clock = clock.Add(d) }
+	counts := []int{0, 500, 1000}
+	count := func() int {
+		c := counts[0]
+		if len(counts) > 1 {
+			counts = counts[1:]
+		}
+		return c
+	}
+
+	elapsed, ok := awaitCount(count, 1000, time.Minute, now, sleep)
+	if !ok || elapsed != 2*pollInterval {
+		t.Errorf("awaitCount got (%v, %v), want (%v, true)", elapsed, ok, 2*pollInterval)
+	}
+	if got, want := Rate(1000, elapsed), 500.0; got != want {
+		t.Errorf("Rate got %v, want %v", got, want)
+	}
+
+	clock = time.Time{}
+	elapsed, ok = awaitCount(func() int { return 0 }, 1, 5*time.Second, now, sleep)
+	if ok || elapsed != 5*time.Second {
+		t.Errorf("awaitCount got (%v, %v), want (5s, false)", elapsed, ok)
+	}
+	if got := Rate(10, 0); got != 0 {
+		t.Errorf("Rate with no duration got %v, want 0", got)
+	}
+}
+
+func TestCheckNextHopMACs(t *testing.T) {
+	nhs := []*telemetry.NetworkInstance_Afts_NextHop{
+		{Index: ygot.Uint64(1), IpAddress: ygot.String("192.0.2.2"), MacAddress: ygot.String("02:00:00:00:00:01")},
+		{Index: ygot.Uint64(2), IpAddress: ygot.String("192.0.2.3"), MacAddress: ygot.String("02:00:00:00:00:99")},
+		{Index: ygot.Uint64(3), IpAddress: ygot.String("198.51.100.1")},
+	}
+	want := map[string]string{
+		"192.0.2.2": "02:00:00:00:00:01",
+		"192.0.2.3": "02:00:00:00:00:02",
+		"192.0.2.4": "02:00:00:00:00:03",
+	}
+	errs := CheckNextHopMACs(nhs, want)
+	if len(errs) != 2 {
+		t.Errorf("CheckNextHopMACs got errors %v, want 2 errors", errs)
+	}
+}
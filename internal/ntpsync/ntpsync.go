@@ -0,0 +1,247 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ntpsync provides an NTP server which a test embeds for a
+// device to synchronize its clock to, and helpers to configure the
+// device and to verify from telemetry its association with the server
+// and how far its clock is from the one of the test.
+//
+// The server answers the client requests of NTPv3 and NTPv4 (RFC 5905)
+// with the clock of the test host, at a configurable stratum, so that
+// the stratum reported by the device identifies the server.
+package ntpsync
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Port is the NTP port.
+const Port = 123
+
+const (
+	packetLen  = 48
+	modeClient = 3
+	modeServer = 4
+	// ntpEpoch is the offset of the NTP epoch, 1900, to the Unix epoch.
+	ntpEpoch = 2208988800
+)
+
+// ServerConfig returns an NTP server of the device, polled in iburst.
+func ServerConfig(address string, port uint16) *telemetry.System_Ntp_Server {
+	return &telemetry.System_Ntp_Server{
+		Address:         ygot.String(address),
+		Port:            ygot.Uint16(port),
+		AssociationType: telemetry.Server_AssociationType_SERVER,
+		Iburst:          ygot.Bool(true),
+		Prefer:          ygot.Bool(true),
+	}
+}
+
+// toNTP returns the NTP timestamp of the time.
+func toNTP(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpoch)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+// fromNTP returns the time of the NTP timestamp.
+func fromNTP(ts uint64) time.Time {
+	secs := int64(ts>>32) - ntpEpoch
+	nsecs := (ts & 0xFFFFFFFF) * uint64(time.Second) >> 32
+	return time.Unix(secs, int64(nsecs))
+}
+
+// Server is an NTP server.
+type Server struct {
+	conn    net.PacketConn
+	stratum uint8
+	wg      sync.WaitGroup
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+// NewServer starts a server listening on the UDP address, answering at
+// the stratum, from 1 to 15.
+func NewServer(addr string, stratum uint8) (*Server, error) {
+	if stratum < 1 || stratum > 15 {
+		return nil, fmt.Errorf("stratum %d out of range [1, 15]", stratum)
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{conn: conn, stratum: stratum, requests: map[string]int{}}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// Stratum returns the stratum of the server.
+func (s *Server) Stratum() uint8 {
+	return s.stratum
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	err := s.conn.Close()
+	s.wg.Wait()
+	return err
+}
+
+// Requests returns the number of requests answered for each client IP.
+func (s *Server) Requests() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := map[string]int{}
+	for ip, n := range s.requests {
+		r[ip] = n
+	}
+	return r
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		recv := time.Now()
+		if n < packetLen || buf[0]&0x7 != modeClient {
+			continue
+		}
+		if _, err := s.conn.WriteTo(s.reply(buf[:packetLen], recv), addr); err != nil {
+			continue
+		}
+		if u, ok := addr.(*net.UDPAddr); ok {
+			s.mu.Lock()
+			s.requests[u.IP.String()]++
+			s.mu.Unlock()
+		}
+	}
+}
+
+// reply returns the reply to the client request received at the time.
+func (s *Server) reply(req []byte, recv time.Time) []byte {
+	resp := make([]byte, packetLen)
+	version := req[0] >> 3 & 0x7
+	resp[0] = version<<3 | modeServer
+	resp[1] = s.stratum
+	resp[2] = req[2] // Poll interval.
+	resp[3] = 0xEC   // Precision of 2^-20s.
+	// Root delay and dispersion of 1/65536s, and a reference ID of the
+	// loopback for a secondary server (RFC 5905 section 7.3).
+	binary.BigEndian.PutUint32(resp[4:], 1)
+	binary.BigEndian.PutUint32(resp[8:], 1)
+	copy(resp[12:16], net.IPv4(127, 0, 0, 1).To4())
+	binary.BigEndian.PutUint64(resp[16:], toNTP(recv.Add(-time.Minute)))
+	copy(resp[24:32], req[40:48])
+	binary.BigEndian.PutUint64(resp[32:], toNTP(recv))
+	binary.BigEndian.PutUint64(resp[40:], toNTP(time.Now()))
+	return resp
+}
+
+// Response is the response of a server to Query.
+type Response struct {
+	Stratum uint8
+	// Offset is the offset of the clock of the server to the local
+	// clock.
+	Offset time.Duration
+}
+
+// Query queries the server at the UDP address.
+func Query(addr string, timeout time.Duration) (*Response, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	req := make([]byte, packetLen)
+	req[0] = 4<<3 | modeClient
+	sent := time.Now()
+	binary.BigEndian.PutUint64(req[40:], toNTP(sent))
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, packetLen)
+	if _, err := conn.Read(resp); err != nil {
+		return nil, err
+	}
+	got := time.Now()
+	if mode := resp[0] & 0x7; mode != modeServer {
+		return nil, fmt.Errorf("response mode got %d, want %d", mode, modeServer)
+	}
+	if origin := binary.BigEndian.Uint64(resp[24:]); origin != toNTP(sent) {
+		return nil, fmt.Errorf("response origin timestamp got %x, want %x", origin, toNTP(sent))
+	}
+	recv := fromNTP(binary.BigEndian.Uint64(resp[32:]))
+	xmit := fromNTP(binary.BigEndian.Uint64(resp[40:]))
+	return &Response{
+		Stratum: resp[1],
+		Offset:  (recv.Sub(sent) + xmit.Sub(got)) / 2,
+	}, nil
+}
+
+// CheckServer checks that the device reports an association with the
+// server at its stratum, and an offset to it within the bound.
+func CheckServer(s *telemetry.System_Ntp_Server, stratum uint8, bound time.Duration) error {
+	if got := s.GetStratum(); got != stratum {
+		return fmt.Errorf("NTP server %s stratum got %d, want %d", s.GetAddress(), got, stratum)
+	}
+	// The offset is in milliseconds.
+	if got := time.Duration(s.GetOffset()) * time.Millisecond; got > bound {
+		return fmt.Errorf("NTP server %s offset got %v, want at most %v", s.GetAddress(), got, bound)
+	}
+	return nil
+}
+
+// CheckClock checks that the current date and time reported by the
+// device, in RFC 3339, is within the bound of the time.
+func CheckClock(datetime string, now time.Time, bound time.Duration) error {
+	clock, err := time.Parse(time.RFC3339, datetime)
+	if err != nil {
+		return fmt.Errorf("cannot parse the current date and time %q: %w", datetime, err)
+	}
+	d := clock.Sub(now)
+	if d < 0 {
+		d = -d
+	}
+	// The date and time may have a resolution of a second.
+	if d > bound+time.Second {
+		return fmt.Errorf("clock %v is %v away from %v, want at most %v", clock, d, now, bound)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ntpsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestTimestamp(t *testing.T) {
+	for _, want := range []time.Time{
+		time.Unix(0, 0),
+		time.Date(2022, 8, 1, 12, 0, 0, 500000000, time.UTC),
+		time.Date(2036, 2, 7, 6, 28, 15, 0, time.UTC),
+	} {
+		got := fromNTP(toNTP(want))
+		if d := got.Sub(want); d < -time.Nanosecond || d > time.Nanosecond {
+			t.Errorf("fromNTP(toNTP(%v)) got %v", want, got)
+		}
+	}
+	if got, want := toNTP(time.Unix(0, 0)), uint64(ntpEpoch)<<32; got != want {
+		t.Errorf("toNTP(Unix epoch) got %x, want %x", got, want)
+	}
+}
+
+func TestServer(t *testing.T) {
+	if _, err := NewServer("127.0.0.1:0", 16); err == nil {
+		t.Errorf("NewServer() at stratum 16 got no error")
+	}
+	s, err := NewServer("127.0.0.1:0", 3)
+	if err != nil {
+		t.Fatalf("NewServer() got error: %v", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := Query(s.Addr().String(), 5*time.Second)
+		if err != nil {
+			t.Fatalf("Query() got error: %v", err)
+		}
+		if resp.Stratum != 3 {
+			t.Errorf("Query() stratum got %d, want 3", resp.Stratum)
+		}
+		if resp.Offset < -100*time.Millisecond || resp.Offset > 100*time.Millisecond {
+			t.Errorf("Query() offset got %v, want about 0", resp.Offset)
+		}
+	}
+	if got := s.Requests()["127.0.0.1"]; got != 2 {
+		t.Errorf("Requests() got %d from 127.0.0.1, want 2", got)
+	}
+}
+
+func TestCheckServer(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		stratum uint8
+		offset  uint64
+		wantErr bool
+	}{
+		{"synced", 3, 5, false},
+		{"other server", 2, 5, true},
+		{"offset too large", 3, 500, true},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			s := &telemetry.System_Ntp_Server{
+				Address: ygot.String("192.0.2.1"),
+				Stratum: ygot.Uint8(c.stratum),
+				Offset:  ygot.Uint64(c.offset),
+			}
+			if err := CheckServer(s, 3, 100*time.Millisecond); (err != nil) != c.wantErr {
+				t.Errorf("CheckServer(stratum %d, offset %dms) got error %v, want error %v", c.stratum, c.offset, err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckClock(t *testing.T) {
+	now := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		desc     string
+		datetime string
+		wantErr  bool
+	}{
+		{"same", "2022-08-01T12:00:00Z", false},
+		{"other zone", "2022-08-01T14:00:01+02:00", false},
+		{"behind", "2022-08-01T11:59:55Z", true},
+		{"ahead", "2022-08-01T12:00:05Z", true},
+		{"invalid", "Mon Aug  1 12:00:00 2022", true},
+	} {
+		t.Run(c.desc, func(t *testing.T) {
+			if err := CheckClock(c.datetime, now, time.Second); (err != nil) != c.wantErr {
+				t.Errorf("CheckClock(%q) got error %v, want error %v", c.datetime, err, c.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ordering provides helpers to validate how a gNMI server handles
+// leaf-lists and the lists ordered by the user, such as the statements of
+// a routing policy.
+//
+// The configuration is read back with raw gNMI Gets in JSON_IETF, as the
+// ygot structs keep lists in maps and lose their order.  The values are
+// compared as their JSON text, so numbers and strings need no decoding.
+//
+// A replace sets a leaf-list or an ordered list to exactly its values.  An
+// update merges them as a YANG merge does (RFC 7950): the values already
+// present keep their position, and the new ones are appended in order.
+package ordering
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/openconfig/featureprofiles/internal/ietfjson"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// JSON returns the JSON_IETF value of v.
+func JSON(v interface{}) (*gpb.TypedValue, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: b}}, nil
+}
+
+// Get returns the JSON value of the configuration at the path.
+func Get(ctx context.Context, c gpb.GNMIClient, path *gpb.Path) (json.RawMessage, error) {
+	resp, err := c.Get(ctx, &gpb.GetRequest{
+		Path:     []*gpb.Path{path},
+		Type:     gpb.GetRequest_CONFIG,
+		Encoding: gpb.Encoding_JSON_IETF,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gNMI Get of %v: %w", path, err)
+	}
+	for _, n := range resp.GetNotification() {
+		for _, u := range n.GetUpdate() {
+			if b := u.GetVal().GetJsonIetfVal(); len(b) > 0 {
+				return b, nil
+			}
+			if b := u.GetVal().GetJsonVal(); len(b) > 0 {
+				return b, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no JSON value in the gNMI Get response of %v", path)
+}
+
+// lookup returns the value at the path of member names from the JSON
+// value.
+func lookup(raw json.RawMessage, names []string) (json.RawMessage, error) {
+	for i, name := range names {
+		obj, err := ietfjson.Object(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.Join(names[:i], "/"), err)
+		}
+		v, ok := ietfjson.Member(obj, name)
+		if !ok {
+			return nil, fmt.Errorf("no member %s", strings.Join(names[:i+1], "/"))
+		}
+		raw = v
+	}
+	return raw, nil
+}
+
+// LeafList returns the values of the leaf-list at the path of member names
+// from the JSON value, in order, as their JSON text.  A missing leaf-list
+// has no values.
+func LeafList(raw json.RawMessage, names ...string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no leaf-list in %s", raw)
+	}
+	parent, err := lookup(raw, names[:len(names)-1])
+	if err != nil {
+		return nil, err
+	}
+	obj, err := ietfjson.Object(parent)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := ietfjson.Member(obj, names[len(names)-1])
+	if !ok {
+		return nil, nil
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(v, &values); err != nil {
+		return nil, fmt.Errorf("%s is not a leaf-list: %w", strings.Join(names, "/"), err)
+	}
+	var got []string
+	for _, v := range values {
+		got = append(got, string(v))
+	}
+	return got, nil
+}
+
+// Keys returns the values of the key leaf of the entries of the list at
+// the path of member names from the JSON value, in order, as their JSON
+// text.
+func Keys(raw json.RawMessage, key string, names ...string) ([]string, error) {
+	v, err := lookup(raw, names)
+	if err != nil {
+		return nil, err
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(v, &entries); err != nil {
+		return nil, fmt.Errorf("%s is not a list: %w", strings.Join(names, "/"), err)
+	}
+	var keys []string
+	for i, e := range entries {
+		k, err := lookup(e, []string{key})
+		if err != nil {
+			return nil, fmt.Errorf("entry %d of %s: %w", i, strings.Join(names, "/"), err)
+		}
+		keys = append(keys, string(k))
+	}
+	return keys, nil
+}
+
+// Merge returns the values of a leaf-list or ordered list updated with
+// values: the current ones in order, followed by the new ones.
+func Merge(cur, values []string) []string {
+	merged := append([]string{}, cur...)
+	seen := map[string]bool{}
+	for _, v := range cur {
+		seen[v] = true
+	}
+	for _, v := range values {
+		if !seen[v] {
+			merged = append(merged, v)
+			seen[v] = true
+		}
+	}
+	return merged
+}
+
+// Remove returns the values of a leaf-list or ordered list without the
+// removed ones, in order.
+func Remove(cur []string, removed ...string) []string {
+	rm := map[string]bool{}
+	for _, v := range removed {
+		rm[v] = true
+	}
+	var left []string
+	for _, v := range cur {
+		if !rm[v] {
+			left = append(left, v)
+		}
+	}
+	return left
+}
+
+// CheckOrdered returns an error if the values are not the wanted ones in
+// the same order, as for a list or leaf-list ordered by the user.
+func CheckOrdered(got, want []string) error {
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		return fmt.Errorf("values in order -want, +got:\n%s", diff)
+	}
+	return nil
+}
+
+// CheckUnordered returns an error if the values are not the wanted ones in
+// any order, as for a list or leaf-list ordered by the system.
+func CheckUnordered(got, want []string) error {
+	less := func(a, b string) bool { return a < b }
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty(), cmpopts.SortSlices(less)); diff != "" {
+		return fmt.Errorf("values in any order -want, +got:\n%s", diff)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ordering
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+const policy = `{
+  "openconfig-routing-policy:name": "POLICY",
+  "openconfig-routing-policy:config": {"name": "POLICY"},
+  "openconfig-routing-policy:statements": {
+    "statement": [
+      {"name": "zeta", "config": {"name": "zeta"}},
+      {"name": "alpha", "config": {"name": "alpha"}},
+      {"name": "mu", "config": {"name": "mu"}}
+    ]
+  }
+}`
+
+const communitySet = `[{
+  "community-set-name": "COMMUNITIES",
+  "config": {
+    "community-set-name": "COMMUNITIES",
+    "community-member": ["64496:2", "64496:1", 100]
+  }
+}]`
+
+func TestKeys(t *testing.T) {
+	got, err := Keys(json.RawMessage(policy), "name", "statements", "statement")
+	if err != nil {
+		t.Fatalf("Keys() got error: %v", err)
+	}
+	want := []string{`"zeta"`, `"alpha"`, `"mu"`}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Keys() -want, +got:\n%s", diff)
+	}
+
+	for _, c := range []struct {
+		desc  string
+		key   string
+		names []string
+	}{
+		{"missing list", "name", []string{"statements", "entry"}},
+		{"not a list", "name", []string{"config"}},
+		{"missing key", "id", []string{"statements", "statement"}},
+	} {
+		if _, err := Keys(json.RawMessage(policy), c.key, c.names...); err == nil {
+			t.Errorf("%s: Keys() got no error, want error", c.desc)
+		}
+	}
+}
+
+func TestLeafList(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		raw     string
+		names   []string
+		want    []string
+		wantErr bool
+	}{
+		{"values", communitySet, []string{"config", "community-member"}, []string{`"64496:2"`, `"64496:1"`, "100"}, false},
+		{"leaf-list value", `["64496:1"]`, nil, nil, true},
+		{"missing leaf-list", communitySet, []string{"config", "other-member"}, nil, false},
+		{"missing container", communitySet, []string{"state", "community-member"}, nil, true},
+		{"not a leaf-list", communitySet, []string{"config", "community-set-name"}, nil, true},
+	} {
+		got, err := LeafList(json.RawMessage(c.raw), c.names...)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: LeafList() got error %v, want error %v", c.desc, err, c.wantErr)
+		}
+		if diff := cmp.Diff(c.want, got); diff != "" {
+			t.Errorf("%s: LeafList() -want, +got:\n%s", c.desc, diff)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	got := Merge([]string{"zeta", "alpha", "mu"}, []string{"beta", "alpha", "beta", "gamma"})
+	want := []string{"zeta", "alpha", "mu", "beta", "gamma"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Merge() -want, +got:\n%s", diff)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	got := Remove([]string{"zeta", "alpha", "mu"}, "zeta", "beta")
+	want := []string{"alpha", "mu"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Remove() -want, +got:\n%s", diff)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, c := range []struct {
+		desc          string
+		got, want     []string
+		wantOrdered   bool
+		wantUnordered bool
+	}{
+		{"same", []string{"a", "b"}, []string{"a", "b"}, true, true},
+		{"empty", nil, []string{}, true, true},
+		{"reordered", []string{"b", "a"}, []string{"a", "b"}, false, true},
+		{"missing", []string{"a"}, []string{"a", "b"}, false, false},
+		{"extra", []string{"a", "b", "c"}, []string{"a", "b"}, false, false},
+	} {
+		if err := CheckOrdered(c.got, c.want); (err == nil) != c.wantOrdered {
+			t.Errorf("%s: CheckOrdered() got error %v, want match %v", c.desc, err, c.wantOrdered)
+		}
+		if err := CheckUnordered(c.got, c.want); (err == nil) != c.wantUnordered {
+			t.Errorf("%s: CheckUnordered() got error %v, want match %v", c.desc, err, c.wantUnordered)
+		}
+	}
+}
+
+// fakeGNMI is a gNMI client answering Gets with a fixed value.
+type fakeGNMI struct {
+	gpb.GNMIClient
+	val *gpb.TypedValue
+	req *gpb.GetRequest
+}
+
+func (f *fakeGNMI) Get(_ context.Context, req *gpb.GetRequest, _ ...grpc.CallOption) (*gpb.GetResponse, error) {
+	f.req = req
+	return &gpb.GetResponse{Notification: []*gpb.Notification{{
+		Update: []*gpb.Update{{Path: req.GetPath()[0], Val: f.val}},
+	}}}, nil
+}
+
+func TestGet(t *testing.T) {
+	val, err := JSON(map[string]interface{}{"name": "POLICY"})
+	if err != nil {
+		t.Fatalf("JSON() got error: %v", err)
+	}
+	f := &fakeGNMI{val: val}
+	got, err := Get(context.Background(), f, &gpb.Path{})
+	if err != nil {
+		t.Fatalf("Get() got error: %v", err)
+	}
+	if want := `{"name":"POLICY"}`; string(got) != want {
+		t.Errorf("Get() got %s, want %s", got, want)
+	}
+	if f.req.GetType() != gpb.GetRequest_CONFIG || f.req.GetEncoding() != gpb.Encoding_JSON_IETF {
+		t.Errorf("Get() sent %v, want a Get of the CONFIG in JSON_IETF", f.req)
+	}
+
+	f.val = &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "POLICY"}}
+	if _, err := Get(context.Background(), f, &gpb.Path{}); err == nil {
+		t.Error("Get() of a value which is not JSON got no error, want error")
+	}
+}
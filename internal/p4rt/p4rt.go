@@ -0,0 +1,306 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package p4rt provides helpers to punt packets to a P4Runtime controller
+// and to collect them, as a controller of the DUT would.
+//
+// The DUT is a P4Runtime device once its integrated circuit has a node ID
+// and its ports have P4Runtime port IDs, both configured in OpenConfig.
+// The controller then becomes the primary of the device through the
+// stream channel, pushes the P4Info of its pipeline, and installs the
+// ACL entries trapping the packets to punt, which it receives as
+// packet-ins on the stream channel.
+package p4rt
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+	"google.golang.org/protobuf/encoding/prototext"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+	p4infopb "github.com/p4lang/p4runtime/go/p4/config/v1"
+	p4pb "github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+// LLDPEtherType is the EtherType of LLDP frames.
+const LLDPEtherType = 0x88cc
+
+// IsLLDP returns whether an untagged Ethernet frame is an LLDP frame.
+func IsLLDP(frame []byte) bool {
+	return len(frame) >= 14 && binary.BigEndian.Uint16(frame[12:14]) == LLDPEtherType
+}
+
+// CountLLDP returns the number of packet-ins of LLDP frames.
+func CountLLDP(packets []*p4pb.PacketIn) int {
+	var n int
+	for _, p := range packets {
+		if IsLLDP(p.GetPayload()) {
+			n++
+		}
+	}
+	return n
+}
+
+// Configure configures the integrated circuit of the DUT with the node ID,
+// which is the P4Runtime device ID, and its ports with P4Runtime port IDs,
+// keyed by port name.
+func Configure(t testing.TB, dut *ondatra.DUTDevice, node string, nodeID uint64, portIDs map[string]uint32) {
+	t.Helper()
+	ic := &telemetry.Component_IntegratedCircuit{NodeId: ygot.Uint64(nodeID)}
+	dut.Config().Component(node).IntegratedCircuit().Replace(t, ic)
+	for name, id := range portIDs {
+		dut.Config().Interface(name).Id().Replace(t, id)
+	}
+}
+
+// LoadP4Info reads a P4Info in text format.
+func LoadP4Info(path string) (*p4infopb.P4Info, error) {
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read P4Info: %w", err)
+	}
+	info := &p4infopb.P4Info{}
+	if err := prototext.Unmarshal(in, info); err != nil {
+		return nil, fmt.Errorf("cannot parse P4Info %s: %w", path, err)
+	}
+	return info, nil
+}
+
+// Trap is an ACL entry trapping the frames of an EtherType to the
+// controller, named after the tables, match fields and actions of a
+// P4Info.
+type Trap struct {
+	Table      string
+	MatchField string
+	Action     string
+	// Params are the values of the parameters of the action, by name.
+	Params    map[string][]byte
+	EtherType uint16
+	Priority  int32
+}
+
+// Entry returns the table entry of the trap, with the IDs of the P4Info.
+func (tr *Trap) Entry(info *p4infopb.P4Info) (*p4pb.TableEntry, error) {
+	var table *p4infopb.Table
+	for _, tb := range info.GetTables() {
+		if tb.GetPreamble().GetName() == tr.Table {
+			table = tb
+		}
+	}
+	if table == nil {
+		return nil, fmt.Errorf("no table %s in the P4Info", tr.Table)
+	}
+	var field *p4infopb.MatchField
+	for _, f := range table.GetMatchFields() {
+		if f.GetName() == tr.MatchField {
+			field = f
+		}
+	}
+	if field == nil {
+		return nil, fmt.Errorf("no match field %s in table %s", tr.MatchField, tr.Table)
+	}
+	var action *p4infopb.Action
+	for _, a := range info.GetActions() {
+		if a.GetPreamble().GetName() == tr.Action {
+			action = a
+		}
+	}
+	if action == nil {
+		return nil, fmt.Errorf("no action %s in the P4Info", tr.Action)
+	}
+
+	var params []*p4pb.Action_Param
+	for _, p := range action.GetParams() {
+		v, ok := tr.Params[p.GetName()]
+		if !ok {
+			return nil, fmt.Errorf("no value for parameter %s of action %s", p.GetName(), tr.Action)
+		}
+		params = append(params, &p4pb.Action_Param{ParamId: p.GetId(), Value: v})
+	}
+	if len(params) != len(tr.Params) {
+		return nil, fmt.Errorf("action %s has %d parameters, got values for %d", tr.Action, len(params), len(tr.Params))
+	}
+
+	value := make([]byte, 2)
+	binary.BigEndian.PutUint16(value, tr.EtherType)
+	return &p4pb.TableEntry{
+		TableId: table.GetPreamble().GetId(),
+		Match: []*p4pb.FieldMatch{{
+			FieldId: field.GetId(),
+			FieldMatchType: &p4pb.FieldMatch_Ternary_{Ternary: &p4pb.FieldMatch_Ternary{
+				Value: value,
+				Mask:  []byte{0xff, 0xff},
+			}},
+		}},
+		Action: &p4pb.TableAction{Type: &p4pb.TableAction_Action{Action: &p4pb.Action{
+			ActionId: action.GetPreamble().GetId(),
+			Params:   params,
+		}}},
+		Priority: tr.Priority,
+	}, nil
+}
+
+// Client is a P4Runtime controller of a device.
+type Client struct {
+	DeviceID   uint64
+	ElectionID uint64
+
+	c       p4pb.P4RuntimeClient
+	cancel  context.CancelFunc
+	stream  p4pb.P4Runtime_StreamChannelClient
+	packets chan *p4pb.PacketIn
+}
+
+// Connect opens the stream channel to the device of the DUT and becomes
+// its primary controller.  The client receives the packet-ins of the
+// device until it is closed.
+func Connect(t testing.TB, dut *ondatra.DUTDevice, deviceID, electionID uint64) (*Client, error) {
+	t.Helper()
+	c := &Client{
+		DeviceID:   deviceID,
+		ElectionID: electionID,
+		c:          dut.RawAPIs().P4RT(t),
+		packets:    make(chan *p4pb.PacketIn, 1000),
+	}
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	stream, err := c.c.StreamChannel(ctx)
+	if err != nil {
+		c.cancel()
+		return nil, fmt.Errorf("cannot open the stream channel: %w", err)
+	}
+	c.stream = stream
+	if err := stream.Send(&p4pb.StreamMessageRequest{
+		Update: &p4pb.StreamMessageRequest_Arbitration{Arbitration: &p4pb.MasterArbitrationUpdate{
+			DeviceId:   deviceID,
+			ElectionId: c.electionID(),
+		}},
+	}); err != nil {
+		c.cancel()
+		return nil, fmt.Errorf("cannot send the arbitration: %w", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		c.cancel()
+		return nil, fmt.Errorf("no arbitration response: %w", err)
+	}
+	if code := resp.GetArbitration().GetStatus().GetCode(); code != 0 {
+		c.cancel()
+		return nil, fmt.Errorf("not primary: arbitration status %d: %s", code, resp.GetArbitration().GetStatus().GetMessage())
+	}
+	go c.receive()
+	return c, nil
+}
+
+// electionID returns the election ID of the client as a P4Runtime
+// 128-bit integer.
+func (c *Client) electionID() *p4pb.Uint128 {
+	return &p4pb.Uint128{Low: c.ElectionID}
+}
+
+// receive keeps the packet-ins of the stream channel until it is closed,
+// dropping them if they are not collected.
+func (c *Client) receive() {
+	defer close(c.packets)
+	for {
+		resp, err := c.stream.Recv()
+		if err != nil {
+			return
+		}
+		if p := resp.GetPacket(); p != nil {
+			select {
+			case c.packets <- p:
+			default:
+			}
+		}
+	}
+}
+
+// SetPipeline pushes the P4Info of the pipeline of the device.
+func (c *Client) SetPipeline(t testing.TB, info *p4infopb.P4Info) error {
+	t.Helper()
+	_, err := c.c.SetForwardingPipelineConfig(context.Background(), &p4pb.SetForwardingPipelineConfigRequest{
+		DeviceId:   c.DeviceID,
+		ElectionId: c.electionID(),
+		Action:     p4pb.SetForwardingPipelineConfigRequest_VERIFY_AND_COMMIT,
+		Config:     &p4pb.ForwardingPipelineConfig{P4Info: info},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot set the forwarding pipeline: %w", err)
+	}
+	return nil
+}
+
+// Write inserts, modifies or deletes the table entries.
+func (c *Client) Write(t testing.TB, typ p4pb.Update_Type, entries ...*p4pb.TableEntry) error {
+	t.Helper()
+	req := &p4pb.WriteRequest{
+		DeviceId:   c.DeviceID,
+		ElectionId: c.electionID(),
+	}
+	for _, e := range entries {
+		req.Updates = append(req.Updates, &p4pb.Update{
+			Type:   typ,
+			Entity: &p4pb.Entity{Entity: &p4pb.Entity_TableEntry{TableEntry: e}},
+		})
+	}
+	if _, err := c.c.Write(context.Background(), req); err != nil {
+		return fmt.Errorf("cannot %v %d table entries: %w", typ, len(entries), err)
+	}
+	return nil
+}
+
+// Drain discards the packet-ins received so far.
+func (c *Client) Drain() {
+	for {
+		select {
+		case _, ok := <-c.packets:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// PacketIns returns the packet-ins received for the duration.
+func (c *Client) PacketIns(duration time.Duration) []*p4pb.PacketIn {
+	var packets []*p4pb.PacketIn
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	for {
+		select {
+		case p, ok := <-c.packets:
+			if !ok {
+				return packets
+			}
+			packets = append(packets, p)
+		case <-timer.C:
+			return packets
+		}
+	}
+}
+
+// Close closes the stream channel, which gives up being primary.
+func (c *Client) Close() {
+	c.cancel()
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package p4rt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	p4infopb "github.com/p4lang/p4runtime/go/p4/config/v1"
+	p4pb "github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+// frame returns an untagged Ethernet frame of the EtherType.
+func frame(etherType uint16) []byte {
+	f := make([]byte, 64)
+	copy(f, []byte{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e})
+	f[12], f[13] = byte(etherType>>8), byte(etherType)
+	return f
+}
+
+func TestIsLLDP(t *testing.T) {
+	for _, c := range []struct {
+		desc  string
+		frame []byte
+		want  bool
+	}{
+		{"LLDP", frame(LLDPEtherType), true},
+		{"IPv4", frame(0x0800), false},
+		{"truncated", frame(LLDPEtherType)[:13], false},
+	} {
+		if got := IsLLDP(c.frame); got != c.want {
+			t.Errorf("%s: IsLLDP() got %t, want %t", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestCountLLDP(t *testing.T) {
+	packets := []*p4pb.PacketIn{
+		{Payload: frame(LLDPEtherType)},
+		{Payload: frame(0x86dd)},
+		{Payload: frame(LLDPEtherType)},
+		{},
+	}
+	if got := CountLLDP(packets); got != 2 {
+		t.Errorf("CountLLDP() got %d, want 2", got)
+	}
+}
+
+var info = &p4infopb.P4Info{
+	Tables: []*p4infopb.Table{{
+		Preamble: &p4infopb.Preamble{Id: 33554689, Name: "ingress.acl_ingress.acl_ingress_table"},
+		MatchFields: []*p4infopb.MatchField{
+			{Id: 2, Name: "is_ipv4"},
+			{Id: 4, Name: "ether_type"},
+		},
+	}},
+	Actions: []*p4infopb.Action{{
+		Preamble: &p4infopb.Preamble{Id: 16777217, Name: "ingress.acl_ingress.acl_trap"},
+		Params:   []*p4infopb.Action_Param{{Id: 1, Name: "qos_queue"}},
+	}},
+}
+
+func newTrap() *Trap {
+	return &Trap{
+		Table:      "ingress.acl_ingress.acl_ingress_table",
+		MatchField: "ether_type",
+		Action:     "ingress.acl_ingress.acl_trap",
+		Params:     map[string][]byte{"qos_queue": {0x1}},
+		EtherType:  LLDPEtherType,
+		Priority:   1,
+	}
+}
+
+func TestTrapEntry(t *testing.T) {
+	got, err := newTrap().Entry(info)
+	if err != nil {
+		t.Fatalf("Entry() got error %v", err)
+	}
+	want := &p4pb.TableEntry{
+		TableId: 33554689,
+		Match: []*p4pb.FieldMatch{{
+			FieldId: 4,
+			FieldMatchType: &p4pb.FieldMatch_Ternary_{Ternary: &p4pb.FieldMatch_Ternary{
+				Value: []byte{0x88, 0xcc},
+				Mask:  []byte{0xff, 0xff},
+			}},
+		}},
+		Action: &p4pb.TableAction{Type: &p4pb.TableAction_Action{Action: &p4pb.Action{
+			ActionId: 16777217,
+			Params:   []*p4pb.Action_Param{{ParamId: 1, Value: []byte{0x1}}},
+		}}},
+		Priority: 1,
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Entry() -want, +got:\n%s", diff)
+	}
+}
+
+func TestTrapEntryErrors(t *testing.T) {
+	for _, c := range []struct {
+		desc   string
+		modify func(tr *Trap)
+	}{
+		{"no table", func(tr *Trap) { tr.Table = "ingress.acl_egress.acl_egress_table" }},
+		{"no match field", func(tr *Trap) { tr.MatchField = "dst_mac" }},
+		{"no action", func(tr *Trap) { tr.Action = "ingress.acl_ingress.acl_copy" }},
+		{"missing parameter", func(tr *Trap) { tr.Params = nil }},
+		{"extra parameter", func(tr *Trap) { tr.Params["cpu_queue"] = []byte{0x2} }},
+	} {
+		tr := newTrap()
+		c.modify(tr)
+		if _, err := tr.Entry(info); err == nil {
+			t.Errorf("%s: Entry() got no error, want error", c.desc)
+		}
+	}
+}
@@ -0,0 +1,443 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ptp configures the Precision Time Protocol (IEEE 1588) on the
+// DUT, and validates its clock against the grandmaster of the testbed:
+// the grandmaster and clock quality it traces to, its offset from its
+// master, which bounds the phase error, and the drift of that offset over
+// time, which bounds the frequency error left by the syntonization of the
+// clock, by PTP or SyncE.
+//
+// OpenConfig does not model PTP, so the DUT is configured and its data
+// sets are read with raw gNMI in JSON_IETF, with the ietf-ptp model of
+// RFC 8575.  The ATE and OTG APIs have no PTP either, so the grandmaster,
+// an ATE port running PTP or a lab grandmaster, is configured and
+// registered by the binding of the testbeds that have one.
+//
+// Usage:
+//
+//	gm := ptp.RequireGrandmaster(t)
+//	c := &ptp.Config{Instance: 0, Domain: gm.Domain, Interfaces: []string{dut.Port(t, gm.Port).Name()}}
+//	req, err := c.SetRequest()
+//	...
+//	s, err := ptp.AwaitLocked(t, gnmiClient, c, gm, bounds, time.Minute)
+package ptp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/ietfjson"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// Clock classes of ITU-T G.8275.1.
+const (
+	// ClassPRTCLocked is the class of a grandmaster locked to a primary
+	// reference time clock, such as GNSS.
+	ClassPRTCLocked = 6
+	// ClassPRTCHoldover is the class of a grandmaster in holdover within
+	// its specification.
+	ClassPRTCHoldover = 7
+	// ClassDefault is the class of a clock free running, never locked.
+	ClassDefault = 248
+	// ClassSlaveOnly is the class of a clock which cannot be a master.
+	ClassSlaveOnly = 255
+)
+
+// Port states of RFC 8575.
+const (
+	StateMaster = "master"
+	StateSlave  = "slave"
+)
+
+// Grandmaster is the PTP grandmaster of a testbed, connected to a port of
+// the DUT.
+type Grandmaster struct {
+	// Port is the ID of the DUT port connected to the grandmaster.
+	Port string
+	// Identity is the clock identity of the grandmaster, as 8 bytes in
+	// hexadecimal separated by dashes, e.g. 02-00-5e-ff-fe-00-53-01.
+	Identity string
+	// ClockClass is the class the grandmaster advertises when locked.
+	ClockClass uint8
+	Domain     uint8
+}
+
+var (
+	mu          sync.Mutex
+	grandmaster *Grandmaster
+)
+
+// RegisterGrandmaster registers the grandmaster of the testbed, typically
+// by the binding.
+func RegisterGrandmaster(gm *Grandmaster) {
+	mu.Lock()
+	defer mu.Unlock()
+	grandmaster = gm
+}
+
+// RequireGrandmaster returns the registered grandmaster, and skips the
+// test if the testbed has none.
+func RequireGrandmaster(t testing.TB) *Grandmaster {
+	t.Helper()
+	mu.Lock()
+	defer mu.Unlock()
+	if grandmaster == nil {
+		t.Skip("The testbed has no PTP grandmaster: none registered by the binding")
+	}
+	return grandmaster
+}
+
+// Config is the PTP instance of the DUT, a boundary clock with a PTP port
+// on each interface, using the end-to-end delay mechanism.
+type Config struct {
+	Instance   uint32
+	Domain     uint8
+	Interfaces []string
+}
+
+// The JSON_IETF of an instance.  The top-level members are qualified with
+// the name of the module, and the members nested in them inherit it.
+type (
+	instanceJSON struct {
+		Number    uint32     `json:"ietf-ptp:instance-number"`
+		DefaultDS defaultDS  `json:"ietf-ptp:default-ds"`
+		Ports     []portJSON `json:"ietf-ptp:port-ds-list"`
+	}
+	defaultDS struct {
+		Domain    uint8 `json:"domain-number"`
+		SlaveOnly bool  `json:"slave-only"`
+	}
+	portJSON struct {
+		Number         uint16 `json:"port-number"`
+		Interface      string `json:"underlying-interface"`
+		DelayMechanism string `json:"delay-mechanism"`
+	}
+)
+
+// path returns the path of the instance.
+func (c *Config) path() (*gpb.Path, error) {
+	return ygot.StringToStructuredPath(fmt.Sprintf("/ptp/instance-list[instance-number=%d]", c.Instance))
+}
+
+// SetRequest returns the SetRequest replacing the instance.  The ports are
+// numbered from 1 in the order of the interfaces.
+func (c *Config) SetRequest() (*gpb.SetRequest, error) {
+	if len(c.Interfaces) == 0 {
+		return nil, fmt.Errorf("PTP instance %d without interfaces", c.Instance)
+	}
+	in := instanceJSON{Number: c.Instance, DefaultDS: defaultDS{Domain: c.Domain}}
+	for i, intf := range c.Interfaces {
+		in.Ports = append(in.Ports, portJSON{Number: uint16(i + 1), Interface: intf, DelayMechanism: "E2E"})
+	}
+	p, err := c.path()
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	return &gpb.SetRequest{Replace: []*gpb.Update{{
+		Path: p,
+		Val:  &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: b}},
+	}}}, nil
+}
+
+// DeleteRequest returns the SetRequest deleting the instance.
+func (c *Config) DeleteRequest() (*gpb.SetRequest, error) {
+	p, err := c.path()
+	if err != nil {
+		return nil, err
+	}
+	return &gpb.SetRequest{Delete: []*gpb.Path{p}}, nil
+}
+
+// State is the state of a PTP instance of the DUT.
+type State struct {
+	// ClockClass is the class of the clock of the DUT.
+	ClockClass uint8
+	// StepsRemoved is the number of boundary clocks between the DUT and
+	// the grandmaster, counting the grandmaster.
+	StepsRemoved uint16
+	// Offset is the offset of the clock of the DUT from its master.
+	Offset time.Duration
+	// MeanPathDelay is the mean delay of the path to the master.
+	MeanPathDelay time.Duration
+	// Parent and Grandmaster are the clock identities of the master and
+	// of the grandmaster, formatted as Grandmaster.Identity.
+	Parent      string
+	Grandmaster string
+	// GrandmasterClass is the clock class of the grandmaster.
+	GrandmasterClass uint8
+	// PortStates are the states of the PTP ports, keyed by interface.
+	PortStates map[string]string
+}
+
+// lookup returns the value at the path of member names from the JSON
+// value, or nil if it is missing.
+func lookup(raw json.RawMessage, names ...string) (json.RawMessage, error) {
+	for i, name := range names {
+		obj, err := ietfjson.Object(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.Join(names[:i], "/"), err)
+		}
+		v, ok := ietfjson.Member(obj, name)
+		if !ok {
+			return nil, nil
+		}
+		raw = v
+	}
+	return raw, nil
+}
+
+// number decodes an integer of at most max, or any integer if max is 0.
+// JSON_IETF encodes the 64-bit integers as strings.
+func number(raw json.RawMessage, max int64) (int64, error) {
+	if raw == nil {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(strings.Trim(string(raw), `"`), 10, 64)
+	if err != nil || max > 0 && (n < 0 || n > max) {
+		return 0, fmt.Errorf("%s is not a number in range", raw)
+	}
+	return n, nil
+}
+
+// identity decodes a clock identity, 8 bytes of binary encoded in base64.
+func identity(raw json.RawMessage) (string, error) {
+	if raw == nil {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", fmt.Errorf("clock identity %s is not a string", raw)
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) != 8 {
+		return "", fmt.Errorf("clock identity %q is not 8 bytes in base64", s)
+	}
+	var parts []string
+	for _, c := range b {
+		parts = append(parts, hex.EncodeToString([]byte{c}))
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// interval converts a time interval of RFC 8575, in nanoseconds
+// multiplied by 2^16, to a duration.
+func interval(scaled int64) time.Duration {
+	return time.Duration(math.Round(float64(scaled) / (1 << 16)))
+}
+
+// ParseState returns the state of the JSON of an instance,
+// /ptp/instance-list.
+func ParseState(raw json.RawMessage) (*State, error) {
+	s := &State{PortStates: map[string]string{}}
+	for _, f := range []struct {
+		names []string
+		max   int64
+		set   func(int64)
+	}{
+		{[]string{"default-ds", "clock-quality", "clock-class"}, math.MaxUint8, func(n int64) { s.ClockClass = uint8(n) }},
+		{[]string{"current-ds", "steps-removed"}, math.MaxUint16, func(n int64) { s.StepsRemoved = uint16(n) }},
+		{[]string{"current-ds", "offset-from-master"}, 0, func(n int64) { s.Offset = interval(n) }},
+		{[]string{"current-ds", "mean-path-delay"}, 0, func(n int64) { s.MeanPathDelay = interval(n) }},
+		{[]string{"parent-ds", "grandmaster-clock-quality", "clock-class"}, math.MaxUint8, func(n int64) { s.GrandmasterClass = uint8(n) }},
+	} {
+		v, err := lookup(raw, f.names...)
+		if err != nil {
+			return nil, err
+		}
+		n, err := number(v, f.max)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.Join(f.names, "/"), err)
+		}
+		f.set(n)
+	}
+
+	v, err := lookup(raw, "parent-ds", "parent-port-identity", "clock-identity")
+	if err != nil {
+		return nil, err
+	}
+	if s.Parent, err = identity(v); err != nil {
+		return nil, fmt.Errorf("parent-ds: %w", err)
+	}
+	if v, err = lookup(raw, "parent-ds", "grandmaster-identity"); err != nil {
+		return nil, err
+	}
+	if s.Grandmaster, err = identity(v); err != nil {
+		return nil, fmt.Errorf("parent-ds: %w", err)
+	}
+
+	ports, err := lookup(raw, "port-ds-list")
+	if err != nil || ports == nil {
+		return s, err
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(ports, &entries); err != nil {
+		return nil, fmt.Errorf("port-ds-list is not a list: %w", err)
+	}
+	for _, e := range entries {
+		var p struct {
+			Interface string `json:"underlying-interface"`
+			State     string `json:"port-state"`
+		}
+		if err := json.Unmarshal(e, &p); err != nil {
+			return nil, fmt.Errorf("port-ds-list: %w", err)
+		}
+		s.PortStates[p.Interface] = p.State
+	}
+	return s, nil
+}
+
+// ReadState returns the state of the instance of the DUT.
+func ReadState(ctx context.Context, c gpb.GNMIClient, instance uint32) (*State, error) {
+	p, err := (&Config{Instance: instance}).path()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Get(ctx, &gpb.GetRequest{
+		Path:     []*gpb.Path{p},
+		Type:     gpb.GetRequest_ALL,
+		Encoding: gpb.Encoding_JSON_IETF,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gNMI Get of PTP instance %d: %w", instance, err)
+	}
+	for _, n := range resp.GetNotification() {
+		for _, u := range n.GetUpdate() {
+			if b := u.GetVal().GetJsonIetfVal(); len(b) > 0 {
+				return ParseState(b)
+			}
+		}
+	}
+	return nil, fmt.Errorf("no state of PTP instance %d", instance)
+}
+
+// Bounds are the bounds of the state of a clock locked to the
+// grandmaster.
+type Bounds struct {
+	// MaxOffset is the maximum absolute offset from the master.
+	MaxOffset time.Duration
+	// MaxMeanPathDelay is the maximum mean path delay to the master.
+	MaxMeanPathDelay time.Duration
+}
+
+// Check returns the differences of the state of a clock directly
+// connected to the grandmaster through the interface with the state of a
+// clock locked to it, within the bounds.
+func (s *State) Check(gm *Grandmaster, intf string, b Bounds) []error {
+	var errs []error
+	if !strings.EqualFold(s.Grandmaster, gm.Identity) {
+		errs = append(errs, fmt.Errorf("grandmaster got %q, want %q", s.Grandmaster, gm.Identity))
+	}
+	if !strings.EqualFold(s.Parent, gm.Identity) {
+		errs = append(errs, fmt.Errorf("parent got %q, want the grandmaster %q", s.Parent, gm.Identity))
+	}
+	if s.StepsRemoved != 1 {
+		errs = append(errs, fmt.Errorf("steps removed got %d, want 1", s.StepsRemoved))
+	}
+	if s.GrandmasterClass != gm.ClockClass {
+		errs = append(errs, fmt.Errorf("grandmaster clock class got %d, want %d", s.GrandmasterClass, gm.ClockClass))
+	}
+	if s.ClockClass == ClassDefault {
+		errs = append(errs, fmt.Errorf("clock class got %d, the class of a clock never locked", s.ClockClass))
+	}
+	if got := s.PortStates[intf]; got != StateSlave {
+		errs = append(errs, fmt.Errorf("port state of %s got %q, want %q", intf, got, StateSlave))
+	}
+	if s.Offset > b.MaxOffset || s.Offset < -b.MaxOffset {
+		errs = append(errs, fmt.Errorf("offset from master got %v, want within ±%v", s.Offset, b.MaxOffset))
+	}
+	if s.MeanPathDelay < 0 || s.MeanPathDelay > b.MaxMeanPathDelay {
+		errs = append(errs, fmt.Errorf("mean path delay got %v, want within [0, %v]", s.MeanPathDelay, b.MaxMeanPathDelay))
+	}
+	return errs
+}
+
+// Sample is an offset from the master sampled at a time.
+type Sample struct {
+	Time   time.Time
+	Offset time.Duration
+}
+
+// errFewSamples is the error of a drift of less than two distinct times.
+var errFewSamples = errors.New("drift of less than two samples at distinct times")
+
+// Drift returns the drift of the offset of the samples, by linear
+// regression, in parts per billion: the frequency error of the clock
+// from its master.
+func Drift(samples []Sample) (float64, error) {
+	if len(samples) < 2 {
+		return 0, errFewSamples
+	}
+	t0 := samples[0].Time
+	var n, sx, sy, sxx, sxy float64
+	for _, s := range samples {
+		x := s.Time.Sub(t0).Seconds()
+		y := float64(s.Offset.Nanoseconds())
+		n++
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return 0, errFewSamples
+	}
+	// The slope is in nanoseconds per second, i.e. parts per billion.
+	return (n*sxy - sx*sy) / d, nil
+}
+
+// pollInterval is how often the state is read while awaiting a lock.
+var pollInterval = 5 * time.Second
+
+// AwaitLocked waits for the instance of the DUT to lock to the
+// grandmaster through its first interface, and returns its state, or an
+// error with the differences of its last state if it does not within the
+// timeout.
+func AwaitLocked(t testing.TB, c gpb.GNMIClient, cfg *Config, gm *Grandmaster, b Bounds, timeout time.Duration) (*State, error) {
+	t.Helper()
+	if len(cfg.Interfaces) == 0 {
+		return nil, fmt.Errorf("PTP instance %d without interfaces", cfg.Instance)
+	}
+	var errs []error
+	for deadline := time.Now().Add(timeout); ; time.Sleep(pollInterval) {
+		s, err := ReadState(context.Background(), c, cfg.Instance)
+		if err != nil {
+			return nil, err
+		}
+		if errs = s.Check(gm, cfg.Interfaces[0], b); len(errs) == 0 {
+			return s, nil
+		}
+		if time.Now().After(deadline) {
+			return s, fmt.Errorf("PTP instance %d not locked after %v: %v", cfg.Instance, timeout, errs)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptp
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+)
+
+var gm = &Grandmaster{Port: "port1", Identity: "02-00-5e-ff-fe-00-53-01", ClockClass: ClassPRTCLocked, Domain: 24}
+
+func TestRequireGrandmaster(t *testing.T) {
+	RegisterGrandmaster(gm)
+	defer RegisterGrandmaster(nil)
+	if got := RequireGrandmaster(t); got != gm {
+		t.Errorf("RequireGrandmaster() got %v, want %v", got, gm)
+	}
+}
+
+func TestSetRequest(t *testing.T) {
+	c := &Config{Instance: 1, Domain: 24, Interfaces: []string{"eth1", "eth2"}}
+	req, err := c.SetRequest()
+	if err != nil {
+		t.Fatalf("SetRequest() got error %v", err)
+	}
+	if len(req.GetReplace()) != 1 {
+		t.Fatalf("SetRequest() got %d replaces, want 1", len(req.GetReplace()))
+	}
+	u := req.GetReplace()[0]
+	if p, _ := ygot.PathToString(u.GetPath()); p != "/ptp/instance-list[instance-number=1]" {
+		t.Errorf("SetRequest() path got %s, want /ptp/instance-list[instance-number=1]", p)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(u.GetVal().GetJsonIetfVal(), &got); err != nil {
+		t.Fatalf("Cannot decode %s: %v", u.GetVal().GetJsonIetfVal(), err)
+	}
+	want := map[string]interface{}{
+		"ietf-ptp:instance-number": float64(1),
+		"ietf-ptp:default-ds":      map[string]interface{}{"domain-number": float64(24), "slave-only": false},
+		"ietf-ptp:port-ds-list": []interface{}{
+			map[string]interface{}{"port-number": float64(1), "underlying-interface": "eth1", "delay-mechanism": "E2E"},
+			map[string]interface{}{"port-number": float64(2), "underlying-interface": "eth2", "delay-mechanism": "E2E"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SetRequest() diff (-want +got):\n%s", diff)
+	}
+
+	if _, err := (&Config{Instance: 1}).SetRequest(); err == nil {
+		t.Errorf("SetRequest() without interfaces got no error")
+	}
+}
+
+func TestDeleteRequest(t *testing.T) {
+	req, err := (&Config{Instance: 2}).DeleteRequest()
+	if err != nil {
+		t.Fatalf("DeleteRequest() got error %v", err)
+	}
+	if len(req.GetDelete()) != 1 {
+		t.Fatalf("DeleteRequest() got %d deletes, want 1", len(req.GetDelete()))
+	}
+	if p, _ := ygot.PathToString(req.GetDelete()[0]); p != "/ptp/instance-list[instance-number=2]" {
+		t.Errorf("DeleteRequest() path got %s, want /ptp/instance-list[instance-number=2]", p)
+	}
+}
+
+// locked is the JSON of an instance locked to gm through eth1, with an
+// offset of -25ns and a mean path delay of 1.5us.
+const locked = `{
+	"ietf-ptp:instance-number": 1,
+	"ietf-ptp:default-ds": {"domain-number": 24, "clock-quality": {"clock-class": 135}},
+	"ietf-ptp:current-ds": {"steps-removed": 1, "offset-from-master": "-1638400", "mean-path-delay": "98304000"},
+	"ietf-ptp:parent-ds": {
+		"parent-port-identity": {"clock-identity": "AgBe//4AUwE=", "port-number": 3},
+		"grandmaster-identity": "AgBe//4AUwE=",
+		"grandmaster-clock-quality": {"clock-class": 6}
+	},
+	"ietf-ptp:port-ds-list": [
+		{"port-number": 1, "underlying-interface": "eth1", "port-state": "slave"},
+		{"port-number": 2, "underlying-interface": "eth2", "port-state": "master"}
+	]
+}`
+
+func TestParseState(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		raw     string
+		want    *State
+		wantErr bool
+	}{{
+		desc: "locked",
+		raw:  locked,
+		want: &State{
+			ClockClass:       135,
+			StepsRemoved:     1,
+			Offset:           -25 * time.Nanosecond,
+			MeanPathDelay:    1500 * time.Nanosecond,
+			Parent:           "02-00-5e-ff-fe-00-53-01",
+			Grandmaster:      "02-00-5e-ff-fe-00-53-01",
+			GrandmasterClass: ClassPRTCLocked,
+			PortStates:       map[string]string{"eth1": StateSlave, "eth2": StateMaster},
+		},
+	}, {
+		desc: "list entry without data sets",
+		raw:  `[{"instance-number": 1, "default-ds": {"clock-quality": {"clock-class": 248}}}]`,
+		want: &State{ClockClass: ClassDefault, PortStates: map[string]string{}},
+	}, {
+		desc:    "bad clock class",
+		raw:     `{"default-ds": {"clock-quality": {"clock-class": 256}}}`,
+		wantErr: true,
+	}, {
+		desc:    "bad offset",
+		raw:     `{"current-ds": {"offset-from-master": "soon"}}`,
+		wantErr: true,
+	}, {
+		desc:    "short identity",
+		raw:     `{"parent-ds": {"grandmaster-identity": "AgBe"}}`,
+		wantErr: true,
+	}, {
+		desc:    "ports not a list",
+		raw:     `{"port-ds-list": {"port-number": 1}}`,
+		wantErr: true,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseState(json.RawMessage(tc.raw))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseState() got error %v, want error %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ParseState() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	b := Bounds{MaxOffset: 100 * time.Nanosecond, MaxMeanPathDelay: 10 * time.Microsecond}
+	s, err := ParseState(json.RawMessage(locked))
+	if err != nil {
+		t.Fatalf("ParseState() got error %v", err)
+	}
+	if errs := s.Check(gm, "eth1", b); len(errs) != 0 {
+		t.Errorf("Check() of a locked clock got errors %v", errs)
+	}
+	if errs := s.Check(gm, "eth2", b); len(errs) != 1 {
+		t.Errorf("Check() of the master port got errors %v, want 1", errs)
+	}
+
+	s = &State{
+		ClockClass:       ClassDefault,
+		StepsRemoved:     2,
+		Offset:           -200 * time.Nanosecond,
+		MeanPathDelay:    -time.Nanosecond,
+		Parent:           "02-00-5e-ff-fe-00-53-02",
+		Grandmaster:      "02-00-5e-ff-fe-00-53-03",
+		GrandmasterClass: ClassPRTCHoldover,
+	}
+	if errs := s.Check(gm, "eth1", b); len(errs) != 8 {
+		t.Errorf("Check() of an unlocked clock got errors %v, want 8", errs)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	var samples []Sample
+	for i := 0; i < 10; i++ {
+		// An offset growing by 5ns per second, with a little noise.
+		noise := time.Duration(i%2) * time.Nanosecond
+		samples = append(samples, Sample{Time: t0.Add(time.Duration(i) * time.Second), Offset: 10*time.Nanosecond + time.Duration(5*i)*time.Nanosecond + noise})
+	}
+	got, err := Drift(samples)
+	if err != nil {
+		t.Fatalf("Drift() got error %v", err)
+	}
+	if math.Abs(got-5) > 0.2 {
+		t.Errorf("Drift() got %v ppb, want about 5", got)
+	}
+
+	if _, err := Drift(samples[:1]); err == nil {
+		t.Errorf("Drift() of 1 sample got no error")
+	}
+	if _, err := Drift([]Sample{{Time: t0}, {Time: t0, Offset: 1}}); err == nil {
+		t.Errorf("Drift() of samples at the same time got no error")
+	}
+}
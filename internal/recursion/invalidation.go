@@ -0,0 +1,92 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recursion
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Resolution is the state of a prefix whose next hops resolve over another
+// route, the via prefix, and which has a backup next hop group, such as a
+// gRIBI entry to a next hop learned from BGP.
+type Resolution string
+
+// The states of the resolution of a prefix.
+const (
+	// Resolved prefixes forward through their next hops, resolved over
+	// the installed via route.
+	Resolved Resolution = "resolved"
+	// Unresolved prefixes remain installed once the via route is removed,
+	// and forward through their backup next hop group.
+	Unresolved Resolution = "unresolved"
+	// Removed prefixes are not installed.
+	Removed Resolution = "removed"
+)
+
+// State returns the state of the resolution of the IPv4 prefix over the via
+// prefix in the AFTs, or an error if the AFTs are inconsistent with any.
+func State(afts *telemetry.NetworkInstance_Afts, prefix, via string) (Resolution, error) {
+	e := afts.GetIpv4Entry(prefix)
+	if e == nil {
+		return Removed, nil
+	}
+	g := afts.GetNextHopGroup(e.GetNextHopGroup())
+	if g == nil {
+		return "", fmt.Errorf("no next hop group %d for %s", e.GetNextHopGroup(), prefix)
+	}
+	if afts.GetIpv4Entry(via) != nil {
+		return Resolved, nil
+	}
+	if g.BackupNextHopGroup == nil {
+		return "", fmt.Errorf("%s installed without %s nor a backup next hop group", prefix, via)
+	}
+	if b := afts.GetNextHopGroup(g.GetBackupNextHopGroup()); b == nil || len(b.NextHop) == 0 {
+		return "", fmt.Errorf("%s installed without %s, and its backup next hop group %d has no next hops", prefix, via, g.GetBackupNextHopGroup())
+	}
+	return Unresolved, nil
+}
+
+// AwaitState reads the AFTs of the default network instance of the DUT
+// until the resolution of the IPv4 prefix over the via prefix reaches the
+// wanted state, and returns how long it took, or an error with the last
+// state after the timeout.
+func AwaitState(t testing.TB, dut *ondatra.DUTDevice, prefix, via string, want Resolution, timeout time.Duration) (time.Duration, error) {
+	t.Helper()
+	path := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).Afts()
+	start := time.Now()
+	for {
+		afts := &telemetry.NetworkInstance_Afts{}
+		if v := path.Lookup(t); v.IsPresent() {
+			afts = v.Val(t)
+		}
+		got, err := State(afts, prefix, via)
+		switch {
+		case err == nil && got == want:
+			return time.Since(start), nil
+		case time.Since(start) > timeout && err != nil:
+			return 0, fmt.Errorf("resolution of %s over %s not %s after %v: %w", prefix, via, want, timeout, err)
+		case time.Since(start) > timeout:
+			return 0, fmt.Errorf("resolution of %s over %s got %s after %v, want %s", prefix, via, got, timeout, want)
+		}
+		time.Sleep(time.Second)
+	}
+}
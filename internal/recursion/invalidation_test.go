@@ -0,0 +1,68 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recursion
+
+import (
+	"testing"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestState(t *testing.T) {
+	const (
+		prefix = "198.51.100.0/24"
+		via    = "203.0.113.0/30"
+	)
+	// newAFTs returns the AFTs of the prefix through 203.0.113.1 with a
+	// backup through 192.0.2.10, and of the via route.
+	newAFTs := func() *telemetry.NetworkInstance_Afts {
+		afts := buildAFTs(t, map[string][]string{prefix: {"203.0.113.1"}, via: {"192.0.2.6"}}, bgp)
+		afts.GetOrCreateNextHopGroup(20).GetOrCreateNextHop(2000)
+		afts.GetOrCreateNextHop(2000).IpAddress = ygot.String("192.0.2.10")
+		afts.GetNextHopGroup(afts.GetIpv4Entry(prefix).GetNextHopGroup()).BackupNextHopGroup = ygot.Uint64(20)
+		return afts
+	}
+
+	for _, c := range []struct {
+		desc    string
+		modify  func(afts *telemetry.NetworkInstance_Afts)
+		want    Resolution
+		wantErr bool
+	}{
+		{"resolved", func(afts *telemetry.NetworkInstance_Afts) {}, Resolved, false},
+		{"via withdrawn", func(afts *telemetry.NetworkInstance_Afts) { afts.DeleteIpv4Entry(via) }, Unresolved, false},
+		{"removed", func(afts *telemetry.NetworkInstance_Afts) { afts.DeleteIpv4Entry(prefix) }, Removed, false},
+		{"no backup", func(afts *telemetry.NetworkInstance_Afts) {
+			afts.DeleteIpv4Entry(via)
+			afts.GetNextHopGroup(afts.GetIpv4Entry(prefix).GetNextHopGroup()).BackupNextHopGroup = nil
+		}, "", true},
+		{"empty backup", func(afts *telemetry.NetworkInstance_Afts) {
+			afts.DeleteIpv4Entry(via)
+			afts.GetNextHopGroup(20).DeleteNextHop(2000)
+		}, "", true},
+		{"no next hop group", func(afts *telemetry.NetworkInstance_Afts) {
+			afts.DeleteNextHopGroup(afts.GetIpv4Entry(prefix).GetNextHopGroup())
+		}, "", true},
+	} {
+		afts := newAFTs()
+		c.modify(afts)
+		got, err := State(afts, prefix, via)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("%s: State() got %q, %v, want %q, error %v", c.desc, got, err, c.want, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recursion provides helpers for routes whose next hop is not
+// directly connected and is resolved over another route, e.g. static
+// routes over BGP routes and BGP routes over static routes: recursive
+// static route configuration, and the verification of the resolution
+// of the routes in the AFTs, including their fall back to a backup next
+// hop group once the route they resolve over is withdrawn.
+package recursion
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Static returns a static route to the prefix through the next hops,
+// which the DUT may resolve recursively.
+func Static(prefix string, nextHops ...string) *telemetry.NetworkInstance_Protocol_Static {
+	s := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String(prefix)}
+	for i, nh := range nextHops {
+		n := s.GetOrCreateNextHop(fmt.Sprint(i + 1))
+		n.NextHop = telemetry.UnionString(nh)
+		n.Recurse = ygot.Bool(true)
+	}
+	return s
+}
+
+// Entry is an AFT entry of a prefix, with the addresses of the next
+// hops it is resolved to, sorted.
+type Entry struct {
+	Protocol telemetry.E_PolicyTypes_INSTALL_PROTOCOL_TYPE
+	NextHops []string
+}
+
+// Lookup returns the entry of the prefix in the AFTs.
+func Lookup(afts *telemetry.NetworkInstance_Afts, prefix string) (*Entry, error) {
+	var nhg *uint64
+	e := &Entry{}
+	if ip, _, err := net.ParseCIDR(prefix); err != nil {
+		return nil, fmt.Errorf("bad prefix %q: %w", prefix, err)
+	} else if ip.To4() != nil {
+		if a := afts.GetIpv4Entry(prefix); a != nil {
+			nhg, e.Protocol = a.NextHopGroup, a.GetOriginProtocol()
+		}
+	} else if a := afts.GetIpv6Entry(prefix); a != nil {
+		nhg, e.Protocol = a.NextHopGroup, a.GetOriginProtocol()
+	}
+	if nhg == nil {
+		return nil, fmt.Errorf("no AFT entry with a next hop group for %s", prefix)
+	}
+	g := afts.GetNextHopGroup(*nhg)
+	if g == nil {
+		return nil, fmt.Errorf("no next hop group %d for %s", *nhg, prefix)
+	}
+	for index := range g.NextHop {
+		addr := afts.GetNextHop(index).GetIpAddress()
+		if addr == "" {
+			return nil, fmt.Errorf("no IP address for next hop %d of %s", index, prefix)
+		}
+		e.NextHops = append(e.NextHops, addr)
+	}
+	sort.Strings(e.NextHops)
+	return e, nil
+}
+
+// Read reads the AFT entry of the prefix from the DUT, with its next hop
+// group and next hops, and returns it.
+func Read(t testing.TB, dut *ondatra.DUTDevice, prefix string) (*Entry, error) {
+	t.Helper()
+	path := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).Afts()
+	afts := &telemetry.NetworkInstance_Afts{}
+	var nhg *uint64
+	if ip, _, err := net.ParseCIDR(prefix); err != nil {
+		return nil, fmt.Errorf("bad prefix %q: %w", prefix, err)
+	} else if ip.To4() != nil {
+		e := path.Ipv4Entry(prefix).Lookup(t)
+		if !e.IsPresent() {
+			return nil, fmt.Errorf("no AFT entry for %s", prefix)
+		}
+		if err := afts.AppendIpv4Entry(e.Val(t)); err != nil {
+			return nil, err
+		}
+		nhg = e.Val(t).NextHopGroup
+	} else {
+		e := path.Ipv6Entry(prefix).Lookup(t)
+		if !e.IsPresent() {
+			return nil, fmt.Errorf("no AFT entry for %s", prefix)
+		}
+		if err := afts.AppendIpv6Entry(e.Val(t)); err != nil {
+			return nil, err
+		}
+		nhg = e.Val(t).NextHopGroup
+	}
+	if nhg == nil {
+		return nil, fmt.Errorf("no next hop group for %s", prefix)
+	}
+	g := path.NextHopGroup(*nhg).Get(t)
+	if err := afts.AppendNextHopGroup(g); err != nil {
+		return nil, err
+	}
+	for index := range g.NextHop {
+		if err := afts.AppendNextHop(path.NextHop(index).Get(t)); err != nil {
+			return nil, err
+		}
+	}
+	return Lookup(afts, prefix)
+}
+
+// Await reads the AFT entries of the prefixes until they are all
+// installed, and returns them, or fails the test after the timeout.
+func Await(t testing.TB, dut *ondatra.DUTDevice, prefixes []string, timeout time.Duration) map[string]*Entry {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		entries := map[string]*Entry{}
+		var err error
+		for _, p := range prefixes {
+			var e *Entry
+			if e, err = Read(t, dut, p); err != nil {
+				break
+			}
+			entries[p] = e
+		}
+		switch {
+		case err == nil:
+			return entries
+		case time.Now().After(deadline):
+			t.Fatalf("AFT entries not installed after %v: %v", timeout, err)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// Hop is a route installed by a protocol, whose next hop is resolved
+// over another route, the via prefix.
+type Hop struct {
+	Prefix   string
+	Protocol telemetry.E_PolicyTypes_INSTALL_PROTOCOL_TYPE
+	NextHop  string
+	Via      string
+}
+
+// Check checks that the route of the hop is installed by its protocol,
+// and resolved to the same next hops as the via route, including want,
+// and returns the problems found.
+func (h *Hop) Check(entries map[string]*Entry, want string) []error {
+	var errs []error
+	if _, via, err := net.ParseCIDR(h.Via); err != nil || !via.Contains(net.ParseIP(h.NextHop)) {
+		errs = append(errs, fmt.Errorf("next hop %s of %s is not in %s", h.NextHop, h.Prefix, h.Via))
+	}
+	e, v := entries[h.Prefix], entries[h.Via]
+	if e == nil {
+		return append(errs, fmt.Errorf("no AFT entry for %s", h.Prefix))
+	}
+	if v == nil {
+		return append(errs, fmt.Errorf("no AFT entry for %s, resolving %s", h.Via, h.Prefix))
+	}
+	if e.Protocol != h.Protocol {
+		errs = append(errs, fmt.Errorf("%s installed by %v, want %v", h.Prefix, e.Protocol, h.Protocol))
+	}
+	if diff := cmp.Diff(v.NextHops, e.NextHops); diff != "" {
+		errs = append(errs, fmt.Errorf("%s next hops differ from those of %s, -via, +got:\n%s", h.Prefix, h.Via, diff))
+	}
+	if i := sort.SearchStrings(e.NextHops, want); i == len(e.NextHops) || e.NextHops[i] != want {
+		errs = append(errs, fmt.Errorf("%s next hops got %v, want %s", h.Prefix, e.NextHops, want))
+	}
+	return errs
+}
+
+// AwaitRemoved waits for the AFT entry of the prefix to be removed, and
+// returns whether it was within the timeout.
+func AwaitRemoved(t testing.TB, dut *ondatra.DUTDevice, prefix string, timeout time.Duration) bool {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); ; time.Sleep(5 * time.Second) {
+		if _, err := Read(t, dut, prefix); err != nil {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recursion
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+const (
+	static = telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC
+	bgp    = telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP
+)
+
+func TestStatic(t *testing.T) {
+	s := Static("198.51.100.0/24", "203.0.113.1", "203.0.113.2")
+	if got := s.GetPrefix(); got != "198.51.100.0/24" {
+		t.Errorf("Prefix got %q, want 198.51.100.0/24", got)
+	}
+	for _, i := range []string{"1", "2"} {
+		nh := s.GetNextHop(i)
+		if nh == nil || !nh.GetRecurse() {
+			t.Errorf("Next hop %s got %+v, want recursive", i, nh)
+		}
+	}
+	if got := s.GetNextHop("2").NextHop; got != telemetry.UnionString("203.0.113.2") {
+		t.Errorf("Next hop 2 got %v, want 203.0.113.2", got)
+	}
+}
+
+// buildAFTs returns AFTs with the IPv4 and IPv6 prefixes resolved to the
+// next hop addresses.
+func buildAFTs(t *testing.T, routes map[string][]string, protocol telemetry.E_PolicyTypes_INSTALL_PROTOCOL_TYPE) *telemetry.NetworkInstance_Afts {
+	t.Helper()
+	afts := &telemetry.NetworkInstance_Afts{}
+	var index uint64
+	for prefix, addrs := range routes {
+		index++
+		nhg := afts.GetOrCreateNextHopGroup(index)
+		for j, addr := range addrs {
+			nhIndex := index*100 + uint64(j)
+			afts.GetOrCreateNextHop(nhIndex).IpAddress = ygot.String(addr)
+			nhg.GetOrCreateNextHop(nhIndex)
+		}
+		if ip, _, err := net.ParseCIDR(prefix); err != nil {
+			t.Fatalf("Bad prefix %q: %v", prefix, err)
+		} else if ip.To4() != nil {
+			e := afts.GetOrCreateIpv4Entry(prefix)
+			e.NextHopGroup, e.OriginProtocol = ygot.Uint64(index), protocol
+		} else {
+			e := afts.GetOrCreateIpv6Entry(prefix)
+			e.NextHopGroup, e.OriginProtocol = ygot.Uint64(index), protocol
+		}
+	}
+	return afts
+}
+
+func TestLookup(t *testing.T) {
+	afts := buildAFTs(t, map[string][]string{
+		"198.51.100.0/24":   {"192.0.2.6", "192.0.2.10"},
+		"2001:db8:200::/64": {"2001:db8::6"},
+	}, static)
+	afts.GetOrCreateIpv4Entry("203.0.113.0/24")
+
+	for _, c := range []struct {
+		prefix  string
+		want    *Entry
+		wantErr bool
+	}{
+		{"198.51.100.0/24", &Entry{Protocol: static, NextHops: []string{"192.0.2.10", "192.0.2.6"}}, false},
+		{"2001:db8:200::/64", &Entry{Protocol: static, NextHops: []string{"2001:db8::6"}}, false},
+		{"203.0.113.0/24", nil, true},
+		{"192.0.2.0/24", nil, true},
+		{"bad", nil, true},
+	} {
+		got, err := Lookup(afts, c.prefix)
+		if (err != nil) != c.wantErr {
+			t.Errorf("Lookup(%s) got error %v, want error %v", c.prefix, err, c.wantErr)
+		}
+		if diff := cmp.Diff(c.want, got); diff != "" {
+			t.Errorf("Lookup(%s) -want, +got:\n%s", c.prefix, diff)
+		}
+	}
+}
+
+func TestHopCheck(t *testing.T) {
+	entries := map[string]*Entry{
+		"198.51.100.0/24": {Protocol: static, NextHops: []string{"192.0.2.6"}},
+		"203.0.113.0/24":  {Protocol: bgp, NextHops: []string{"192.0.2.6"}},
+		"198.18.0.0/24":   {Protocol: bgp, NextHops: []string{"192.0.2.10"}},
+	}
+	for _, c := range []struct {
+		desc     string
+		hop      Hop
+		wantErrs int
+	}{
+		{"resolved", Hop{"198.51.100.0/24", static, "203.0.113.1", "203.0.113.0/24"}, 0},
+		{"wrong protocol", Hop{"198.51.100.0/24", bgp, "203.0.113.1", "203.0.113.0/24"}, 1},
+		{"next hop not in via", Hop{"198.51.100.0/24", static, "203.0.114.1", "203.0.113.0/24"}, 1},
+		{"different next hops", Hop{"198.18.0.0/24", bgp, "203.0.113.1", "203.0.113.0/24"}, 2},
+		{"not installed", Hop{"192.0.2.128/25", static, "203.0.113.1", "203.0.113.0/24"}, 1},
+	} {
+		if got := c.hop.Check(entries, "192.0.2.6"); len(got) != c.wantErrs {
+			t.Errorf("%s: Check() got errors %v, want %d", c.desc, got, c.wantErrs)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package routepref provides helpers to configure the preferences, also
+// known as administrative distances, of the routes of the static, BGP and
+// IS-IS protocols, and to verify which protocol the DUT selects for a
+// prefix it has routes to from several protocols.
+//
+// The DUT installs the route of the lowest preference in its AFTs.  How it
+// breaks a tie between protocols of equal preference is vendor specific,
+// so tied routes are not expected to select either.
+package routepref
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// MaxBGPDistance is the highest distance of the BGP routes, which are
+// modeled as 8 bit values.
+const MaxBGPDistance = 255
+
+// SetStatic sets the preference of all the next hops of the static route.
+func SetStatic(s *telemetry.NetworkInstance_Protocol_Static, preference uint32) {
+	for _, nh := range s.NextHop {
+		nh.Preference = ygot.Uint32(preference)
+	}
+}
+
+// SetBGP sets the distance of the routes learned from the external and
+// the internal BGP neighbors.
+func SetBGP(bgp *telemetry.NetworkInstance_Protocol_Bgp, external, internal uint32) error {
+	for _, d := range []uint32{external, internal} {
+		if d == 0 || d > MaxBGPDistance {
+			return fmt.Errorf("BGP distance %d out of range [1, %d]", d, MaxBGPDistance)
+		}
+	}
+	rd := bgp.GetOrCreateGlobal().GetOrCreateDefaultRouteDistance()
+	rd.ExternalRouteDistance = ygot.Uint8(uint8(external))
+	rd.InternalRouteDistance = ygot.Uint8(uint8(internal))
+	return nil
+}
+
+// SetISIS sets the preference of the internal and the external routes
+// learned by IS-IS at the level.
+func SetISIS(isis *telemetry.NetworkInstance_Protocol_Isis, level uint8, internal, external uint32) {
+	rp := isis.GetOrCreateLevel(level).GetOrCreateRoutePreference()
+	rp.InternalRoutePreference = ygot.Uint32(internal)
+	rp.ExternalRoutePreference = ygot.Uint32(external)
+}
+
+// Route is a route of a protocol to a prefix through a next hop, with the
+// preference of the protocol.
+type Route struct {
+	Protocol   telemetry.E_PolicyTypes_INSTALL_PROTOCOL_TYPE
+	Preference uint32
+	NextHop    string
+}
+
+func (r *Route) String() string {
+	return fmt.Sprintf("%v route through %s of preference %d", r.Protocol, r.NextHop, r.Preference)
+}
+
+// Select returns the route the DUT is expected to select among the routes
+// to a prefix, of the lowest preference, or an error if there is none or
+// if several routes have the lowest preference.
+func Select(routes ...*Route) (*Route, error) {
+	var best *Route
+	tied := false
+	for _, r := range routes {
+		switch {
+		case best == nil || r.Preference < best.Preference:
+			best, tied = r, false
+		case r.Preference == best.Preference:
+			tied = true
+		}
+	}
+	switch {
+	case best == nil:
+		return nil, fmt.Errorf("no route to select")
+	case tied:
+		return nil, fmt.Errorf("several routes of preference %d, the tie-break is vendor specific", best.Preference)
+	}
+	return best, nil
+}
+
+// pollInterval is how often the AFT entry of a prefix is read while
+// awaiting the route selected.
+var pollInterval = time.Second
+
+// AwaitSelected waits for the AFT entry of the prefix to originate from
+// the protocol of the route and to be resolved to its next hop, and
+// returns an error with the last entry read if it is not within the
+// timeout.
+func AwaitSelected(t testing.TB, dut *ondatra.DUTDevice, prefix string, want *Route, timeout time.Duration) error {
+	t.Helper()
+	var last string
+	for deadline := time.Now().Add(timeout); ; time.Sleep(pollInterval) {
+		e, err := recursion.Read(t, dut, prefix)
+		switch {
+		case err != nil:
+			last = err.Error()
+		case e.Protocol == want.Protocol && cmp.Equal(e.NextHops, []string{want.NextHop}):
+			return nil
+		default:
+			last = fmt.Sprintf("%v entry with next hops %v", e.Protocol, e.NextHops)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("AFT entry of %s not from the %v after %v: %s", prefix, want, timeout, last)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routepref
+
+import (
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/recursion"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestSetStatic(t *testing.T) {
+	s := recursion.Static("198.51.100.0/24", "192.0.2.6", "192.0.2.10")
+	SetStatic(s, 200)
+	for _, index := range []string{"1", "2"} {
+		if got := s.GetNextHop(index).GetPreference(); got != 200 {
+			t.Errorf("Next hop %s preference got %d, want 200", index, got)
+		}
+	}
+}
+
+func TestSetBGP(t *testing.T) {
+	for _, tc := range []struct {
+		desc               string
+		external, internal uint32
+		wantErr            bool
+	}{
+		{"defaults", 20, 200, false},
+		{"maximum", MaxBGPDistance, MaxBGPDistance, false},
+		{"zero", 0, 200, true},
+		{"too high", 20, MaxBGPDistance + 1, true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			bgp := &telemetry.NetworkInstance_Protocol_Bgp{}
+			err := SetBGP(bgp, tc.external, tc.internal)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SetBGP got error %v, want error %t", err, tc.wantErr)
+			}
+			rd := bgp.GetGlobal().GetDefaultRouteDistance()
+			if tc.wantErr {
+				if rd != nil {
+					t.Errorf("SetBGP with error set the distances %+v", rd)
+				}
+				return
+			}
+			if got := rd.GetExternalRouteDistance(); uint32(got) != tc.external {
+				t.Errorf("External distance got %d, want %d", got, tc.external)
+			}
+			if got := rd.GetInternalRouteDistance(); uint32(got) != tc.internal {
+				t.Errorf("Internal distance got %d, want %d", got, tc.internal)
+			}
+		})
+	}
+}
+
+func TestSetISIS(t *testing.T) {
+	isis := &telemetry.NetworkInstance_Protocol_Isis{}
+	SetISIS(isis, 2, 115, 116)
+	rp := isis.GetLevel(2).GetRoutePreference()
+	if got := rp.GetInternalRoutePreference(); got != 115 {
+		t.Errorf("Internal preference got %d, want 115", got)
+	}
+	if got := rp.GetExternalRoutePreference(); got != 116 {
+		t.Errorf("External preference got %d, want 116", got)
+	}
+	if isis.GetLevel(1) != nil {
+		t.Errorf("SetISIS at level 2 created level 1")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	static := &Route{Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, Preference: 1, NextHop: "192.0.2.6"}
+	bgp := &Route{Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_BGP, Preference: 20, NextHop: "192.0.2.10"}
+	isis := &Route{Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_ISIS, Preference: 115, NextHop: "192.0.2.14"}
+	tied := &Route{Protocol: telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, Preference: 20, NextHop: "192.0.2.6"}
+	for _, tc := range []struct {
+		desc    string
+		routes  []*Route
+		want    *Route
+		wantErr bool
+	}{
+		{desc: "static", routes: []*Route{isis, bgp, static}, want: static},
+		{desc: "bgp", routes: []*Route{isis, bgp}, want: bgp},
+		{desc: "single", routes: []*Route{isis}, want: isis},
+		{desc: "tie", routes: []*Route{isis, bgp, tied}, wantErr: true},
+		{desc: "tie broken by lower", routes: []*Route{bgp, tied, static}, want: static},
+		{desc: "none", wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := Select(tc.routes...)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Select got error %v, want error %t", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("Select got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rundata collects the properties of a test run, such as the
+// vendor, hardware model, software version and hostname of the
+// reserved DUTs, the versions of the OpenConfig models and the encodings
+// they advertise, and the deviations in effect, so test results can be
+// attributed to exact builds.
+//
+// The properties are printed to the test output as lines of the form
+//
+//	Property: key=value
+//
+// which are picked up by tools/compliance_report.
+package rundata
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/ondatra/binding"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// dutInfo is the platform information of a single DUT.
+type dutInfo struct {
+	vendor          string
+	model           string
+	softwareVersion string
+	hostname        string
+}
+
+// componentPaths are the component leaves the DUT information is
+// collected from.
+var componentPaths = []string{"type", "mfg-name", "part-no", "description", "software-version"}
+
+// hostnamePath is the path of the hostname the DUT information is
+// collected from.
+var hostnamePath = &gpb.Path{Elem: []*gpb.PathElem{{Name: "system"}, {Name: "state"}, {Name: "hostname"}}}
+
+// fromNotifications extracts the DUT information from the system and
+// component state in ns.  The vendor and model are taken from the
+// CHASSIS component, the software version from the OPERATING_SYSTEM
+// component, and the hostname from the system.
+func fromNotifications(ns []*gpb.Notification) *dutInfo {
+	info := &dutInfo{}
+	comps := map[string]map[string]string{}
+	for _, n := range ns {
+		for _, u := range n.GetUpdate() {
+			elems := append(append([]*gpb.PathElem{}, n.GetPrefix().GetElem()...), u.GetPath().GetElem()...)
+			if len(elems) == 3 && elems[0].GetName() == "system" && elems[2].GetName() == "hostname" {
+				info.hostname = u.GetVal().GetStringVal()
+				continue
+			}
+			if len(elems) != 4 || elems[0].GetName() != "components" || elems[1].GetName() != "component" {
+				continue
+			}
+			name := elems[1].GetKey()["name"]
+			leaf := elems[3].GetName()
+			if comps[name] == nil {
+				comps[name] = map[string]string{}
+			}
+			comps[name][leaf] = u.GetVal().GetStringVal()
+		}
+	}
+
+	names := make([]string, 0, len(comps))
+	for name := range comps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c := comps[name]
+		// Strip the module prefix from the identityref.
+		typ := c["type"][strings.LastIndex(c["type"], ":")+1:]
+		switch typ {
+		case "CHASSIS":
+			if info.vendor == "" {
+				info.vendor = c["mfg-name"]
+			}
+			if info.model == "" {
+				info.model = c["part-no"]
+			}
+			if info.model == "" {
+				info.model = c["description"]
+			}
+		case "OPERATING_SYSTEM":
+			if info.softwareVersion == "" {
+				info.softwareVersion = c["software-version"]
+			}
+		}
+	}
+	return info
+}
+
+// queryDUT subscribes once to the system and component state of the DUT.
+func queryDUT(ctx context.Context, gnmi gpb.GNMIClient) ([]*gpb.Notification, error) {
+	subs := []*gpb.Subscription{{Path: hostnamePath}}
+	for _, leaf := range componentPaths {
+		subs = append(subs, &gpb.Subscription{
+			Path: &gpb.Path{Elem: []*gpb.PathElem{
+				{Name: "components"},
+				{Name: "component", Key: map[string]string{"name": "*"}},
+				{Name: "state"},
+				{Name: leaf},
+			}},
+		})
+	}
+	sub, err := gnmi.Subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := sub.Send(&gpb.SubscribeRequest{
+		Request: &gpb.SubscribeRequest_Subscribe{
+			Subscribe: &gpb.SubscriptionList{
+				Mode:         gpb.SubscriptionList_ONCE,
+				Encoding:     gpb.Encoding_PROTO,
+				Subscription: subs,
+			},
+		},
+	}); err != nil {
+		return nil, err
+	}
+	var ns []*gpb.Notification
+	for {
+		resp, err := sub.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if resp.GetSyncResponse() {
+			return ns, nil
+		}
+		if n := resp.GetUpdate(); n != nil {
+			ns = append(ns, n)
+		}
+	}
+}
+
+// capabilities returns the properties of the gNMI capabilities of a DUT
+// under the given key prefix: the gNMI version, the encodings, and the
+// versions of the OpenConfig models, keyed by model name.
+func capabilities(id string, caps *gpb.CapabilityResponse) map[string]string {
+	props := map[string]string{}
+	if v := caps.GetGNMIVersion(); v != "" {
+		props[id+".gnmi_version"] = v
+	}
+	var encodings []string
+	for _, e := range caps.GetSupportedEncodings() {
+		encodings = append(encodings, e.String())
+	}
+	if len(encodings) > 0 {
+		sort.Strings(encodings)
+		props[id+".encodings"] = strings.Join(encodings, ",")
+	}
+	for _, m := range caps.GetSupportedModels() {
+		if strings.HasPrefix(m.GetName(), "openconfig-") {
+			props[id+".models."+m.GetName()] = m.GetVersion()
+		}
+	}
+	return props
+}
+
+// dutProperties returns the properties of dut under the given key
+// prefix.  Values reported by the DUT take precedence over those
+// requested by the testbed.
+func dutProperties(ctx context.Context, id string, dut binding.DUT) (map[string]string, error) {
+	props := map[string]string{
+		id + ".name":             dut.Name(),
+		id + ".vendor":           dut.Vendor().String(),
+		id + ".model":            dut.HardwareModel(),
+		id + ".software_version": dut.SoftwareVersion(),
+	}
+	gnmi, err := dut.DialGNMI(ctx)
+	if err != nil {
+		return props, fmt.Errorf("cannot dial gNMI of %s: %w", id, err)
+	}
+	// The components are still queried if the capabilities cannot be.
+	caps, capsErr := gnmi.Capabilities(ctx, &gpb.CapabilityRequest{})
+	for k, v := range capabilities(id, caps) {
+		props[k] = v
+	}
+	ns, err := queryDUT(ctx, gnmi)
+	if err != nil {
+		return props, fmt.Errorf("cannot query components of %s: %w", id, err)
+	}
+	info := fromNotifications(ns)
+	for k, v := range map[string]string{
+		id + ".vendor":           info.vendor,
+		id + ".model":            info.model,
+		id + ".software_version": info.softwareVersion,
+		id + ".hostname":         info.hostname,
+	} {
+		if v != "" {
+			props[k] = v
+		}
+	}
+	if capsErr != nil {
+		return props, fmt.Errorf("cannot get the capabilities of %s: %w", id, capsErr)
+	}
+	return props, nil
+}
+
+// deviations returns the deviation and test argument flags set to a
+// non-default value.
+func deviations(fs *flag.FlagSet) map[string]string {
+	props := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		for _, prefix := range []string{"deviation", "arg"} {
+			if !strings.HasPrefix(f.Name, prefix+"_") {
+				continue
+			}
+			if v := f.Value.String(); v != f.DefValue {
+				props[prefix+"."+strings.TrimPrefix(f.Name, prefix+"_")] = v
+			}
+		}
+	})
+	return props
+}
+
+// Properties collects the run properties of the DUTs in resv, keyed
+// by the DUT ID in the testbed, together with the deviations and test
+// arguments in use.
+// Properties that could not be collected are reported in the error,
+// but the ones that were are still returned.
+func Properties(ctx context.Context, resv *binding.Reservation) (map[string]string, error) {
+	props := deviations(flag.CommandLine)
+	var errs []string
+	for id, dut := range resv.DUTs {
+		dp, err := dutProperties(ctx, id, dut)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		for k, v := range dp {
+			props[k] = v
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return props, fmt.Errorf("rundata: %s", strings.Join(errs, "; "))
+	}
+	return props, nil
+}
+
+// Write prints the properties to w, sorted by key.
+func Write(w io.Writer, props map[string]string) error {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "Property: %s=%s\n", k, props[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rundata
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func update(comp, leaf, val string) *gpb.Update {
+	return &gpb.Update{
+		Path: &gpb.Path{Elem: []*gpb.PathElem{
+			{Name: "components"},
+			{Name: "component", Key: map[string]string{"name": comp}},
+			{Name: "state"},
+			{Name: leaf},
+		}},
+		Val: &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: val}},
+	}
+}
+
+func TestFromNotifications(t *testing.T) {
+	ns := []*gpb.Notification{{
+		Update: []*gpb.Update{
+			update("Chassis", "type", "openconfig-platform-types:CHASSIS"),
+			update("Chassis", "mfg-name", "Acme"),
+			update("Chassis", "part-no", "AR-1000"),
+			update("Linecard1", "type", "openconfig-platform-types:LINECARD"),
+			update("Linecard1", "part-no", "LC-10"),
+		},
+	}, {
+		Update: []*gpb.Update{
+			update("OS", "type", "OPERATING_SYSTEM"),
+			update("OS", "software-version", "4.2.0"),
+		},
+	}, {
+		Update: []*gpb.Update{{
+			Path: hostnamePath,
+			Val:  &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "dut1"}},
+		}},
+	}}
+	got := fromNotifications(ns)
+	want := &dutInfo{vendor: "Acme", model: "AR-1000", softwareVersion: "4.2.0", hostname: "dut1"}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(dutInfo{})); diff != "" {
+		t.Errorf("fromNotifications got unexpected info, diff(-want,+got):\n%s", diff)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	caps := &gpb.CapabilityResponse{
+		GNMIVersion:        "0.7.0",
+		SupportedEncodings: []gpb.Encoding{gpb.Encoding_PROTO, gpb.Encoding_JSON_IETF},
+		SupportedModels: []*gpb.ModelData{
+			{Name: "openconfig-interfaces", Organization: "OpenConfig working group", Version: "2.4.3"},
+			{Name: "openconfig-system"},
+			{Name: "acme-interfaces-deviations", Organization: "Acme", Version: "1.0.0"},
+		},
+	}
+	got := capabilities("dut", caps)
+	want := map[string]string{
+		"dut.gnmi_version":                 "0.7.0",
+		"dut.encodings":                    "JSON_IETF,PROTO",
+		"dut.models.openconfig-interfaces": "2.4.3",
+		"dut.models.openconfig-system":     "",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("capabilities got unexpected properties, diff(-want,+got):\n%s", diff)
+	}
+	if got := capabilities("dut", nil); len(got) != 0 {
+		t.Errorf("capabilities of no response got %v, want none", got)
+	}
+}
+
+func TestDeviations(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("deviation_foo", false, "")
+	fs.Bool("deviation_bar", true, "")
+	fs.String("deviation_baz", "DEFAULT", "")
+	fs.Float64("arg_scale_percent", 100, "")
+	fs.Bool("other", false, "")
+	if err := fs.Parse([]string{"-deviation_foo", "-deviation_baz=default", "-arg_scale_percent=10", "-other"}); err != nil {
+		t.Fatal(err)
+	}
+	got := deviations(fs)
+	want := map[string]string{"deviation.foo": "true", "deviation.baz": "default", "arg.scale_percent": "10"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("deviations got unexpected properties, diff(-want,+got):\n%s", diff)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var sb strings.Builder
+	if err := Write(&sb, map[string]string{"dut.vendor": "Acme", "deviation.foo": "true"}); err != nil {
+		t.Fatalf("Write got error: %v", err)
+	}
+	const want = "Property: deviation.foo=true\nProperty: dut.vendor=Acme\n"
+	if got := sb.String(); got != want {
+		t.Errorf("Write got %q, want %q", got, want)
+	}
+}
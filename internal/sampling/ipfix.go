@@ -0,0 +1,293 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// IPFIX messages (RFC 7011) and information elements (RFC 7012 and
+// iana.org/assignments/ipfix).
+const (
+	ipfixVersion   = 10
+	ipfixHeaderLen = 16
+
+	setTemplate        = 2
+	setOptionsTemplate = 3
+	setMinData         = 256
+
+	variableLength = 0xFFFF
+
+	ieOctetDeltaCount        = 1
+	iePacketDeltaCount       = 2
+	ieProtocolIdentifier     = 4
+	ieIPClassOfService       = 5
+	ieSourceIPv4Address      = 8
+	ieIngressInterface       = 10
+	ieDestinationIPv4Address = 12
+	ieEgressInterface        = 14
+	ieSourceIPv6Address      = 27
+	ieDestinationIPv6Address = 28
+	ieSamplingInterval       = 34
+	ieSamplingPacketInterval = 305
+	ieDataLinkFrameSize      = 312
+	ieIPHeaderPacketSection  = 313
+	ieDataLinkFrameSection   = 315
+	ieEnterpriseBit          = 0x8000
+)
+
+// ipfixField is a field of a template.
+type ipfixField struct {
+	id         uint16
+	length     uint16
+	enterprise bool
+}
+
+// templateKey identifies a template of an exporter.
+type templateKey struct {
+	exporter string
+	domain   uint32
+	id       uint16
+}
+
+// domainKey identifies an observation domain of an exporter.
+type domainKey struct {
+	exporter string
+	domain   uint32
+}
+
+// ipfixDecoder decodes IPFIX messages with the templates of the
+// exporters.
+type ipfixDecoder struct {
+	mu        sync.Mutex
+	templates map[templateKey][]ipfixField
+	// rates are the sampling intervals exported in options records.
+	rates map[domainKey]uint32
+}
+
+func newIPFIXDecoder() *ipfixDecoder {
+	return &ipfixDecoder{
+		templates: map[templateKey][]ipfixField{},
+		rates:     map[domainKey]uint32{},
+	}
+}
+
+// decode decodes the data records of the message received from the
+// address, and learns its templates.
+func (d *ipfixDecoder) decode(b []byte, addr net.Addr) ([]*Sample, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(b) < ipfixHeaderLen {
+		return nil, fmt.Errorf("IPFIX message of %d bytes too short", len(b))
+	}
+	n := int(binary.BigEndian.Uint16(b[2:]))
+	if n < ipfixHeaderLen || n > len(b) {
+		return nil, fmt.Errorf("IPFIX message length %d invalid for %d bytes", n, len(b))
+	}
+	exporter := addr.String()
+	if u, ok := addr.(*net.UDPAddr); ok {
+		exporter = u.IP.String()
+	}
+	domain := domainKey{exporter: exporter, domain: binary.BigEndian.Uint32(b[12:])}
+
+	var samples []*Sample
+	var err error
+	for sets := b[ipfixHeaderLen:n]; len(sets) > 0; {
+		if len(sets) < 4 {
+			return samples, fmt.Errorf("IPFIX set header truncated")
+		}
+		id := binary.BigEndian.Uint16(sets)
+		setLen := int(binary.BigEndian.Uint16(sets[2:]))
+		if setLen < 4 || setLen > len(sets) {
+			return samples, fmt.Errorf("IPFIX set length %d invalid for %d bytes", setLen, len(sets))
+		}
+		body := sets[4:setLen]
+		sets = sets[setLen:]
+		switch {
+		case id == setTemplate || id == setOptionsTemplate:
+			if terr := d.learn(domain, body, id == setOptionsTemplate); terr != nil {
+				return samples, terr
+			}
+		case id >= setMinData:
+			fields, ok := d.templates[templateKey{exporter: domain.exporter, domain: domain.domain, id: id}]
+			if !ok {
+				// Keep decoding the other sets, the template may come later.
+				err = fmt.Errorf("IPFIX data set of unknown template %d from %s", id, exporter)
+				continue
+			}
+			s, derr := d.decodeData(domain, fields, body)
+			samples = append(samples, s...)
+			if derr != nil {
+				return samples, derr
+			}
+		}
+	}
+	return samples, err
+}
+
+// learn learns the templates of a template or options template set.
+func (d *ipfixDecoder) learn(domain domainKey, body []byte, options bool) error {
+	hdrLen := 4
+	if options {
+		hdrLen = 6 // With the scope field count.
+	}
+	// A set is padded to less than a template header.
+	for len(body) >= hdrLen {
+		id := binary.BigEndian.Uint16(body)
+		count := int(binary.BigEndian.Uint16(body[2:]))
+		body = body[hdrLen:]
+		key := templateKey{exporter: domain.exporter, domain: domain.domain, id: id}
+		if count == 0 {
+			delete(d.templates, key) // Withdrawal.
+			continue
+		}
+		var fields []ipfixField
+		for i := 0; i < count; i++ {
+			if len(body) < 4 {
+				return fmt.Errorf("IPFIX template %d truncated", id)
+			}
+			raw := binary.BigEndian.Uint16(body)
+			f := ipfixField{
+				id:         raw &^ ieEnterpriseBit,
+				length:     binary.BigEndian.Uint16(body[2:]),
+				enterprise: raw&ieEnterpriseBit != 0,
+			}
+			body = body[4:]
+			if f.enterprise {
+				if len(body) < 4 {
+					return fmt.Errorf("IPFIX template %d truncated", id)
+				}
+				body = body[4:] // Enterprise number.
+			}
+			fields = append(fields, f)
+		}
+		d.templates[key] = fields
+	}
+	return nil
+}
+
+// decodeData decodes the records of a data set of the template fields.
+func (d *ipfixDecoder) decodeData(domain domainKey, fields []ipfixField, body []byte) ([]*Sample, error) {
+	var samples []*Sample
+	for len(body) > 0 {
+		values := map[uint16][]byte{}
+		rest := body
+		for _, f := range fields {
+			n := int(f.length)
+			if f.length == variableLength {
+				if len(rest) < 1 {
+					rest = nil
+					break
+				}
+				n, rest = int(rest[0]), rest[1:]
+				if n == 255 {
+					if len(rest) < 2 {
+						rest = nil
+						break
+					}
+					n, rest = int(binary.BigEndian.Uint16(rest)), rest[2:]
+				}
+			}
+			if len(rest) < n {
+				rest = nil
+				break
+			}
+			if !f.enterprise {
+				values[f.id] = rest[:n]
+			}
+			rest = rest[n:]
+		}
+		if rest == nil {
+			// The rest of the set is padding.
+			break
+		}
+		body = rest
+		s, err := d.sample(domain, values)
+		if err != nil {
+			return samples, err
+		}
+		if s != nil {
+			samples = append(samples, s)
+		}
+	}
+	return samples, nil
+}
+
+// unsigned decodes an unsigned integer of reduced size.
+func unsigned(b []byte) uint64 {
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v
+}
+
+// sample returns the sample of the values of a data record, or nil for
+// an options record, learning the sampling interval it exports.
+func (d *ipfixDecoder) sample(domain domainKey, values map[uint16][]byte) (*Sample, error) {
+	rate, hasRate := values[ieSamplingPacketInterval]
+	if !hasRate {
+		rate, hasRate = values[ieSamplingInterval]
+	}
+	s := &Sample{Format: "ipfix", Agent: domain.exporter, Rate: d.rates[domain], Packets: 1}
+	var err error
+	switch {
+	case values[ieDataLinkFrameSection] != nil:
+		err = decodeFrame(s, values[ieDataLinkFrameSection])
+	case values[ieIPHeaderPacketSection] != nil:
+		err = decodeIP(s, values[ieIPHeaderPacketSection])
+	case values[ieSourceIPv4Address] != nil || values[ieSourceIPv6Address] != nil:
+		for _, ie := range []uint16{ieSourceIPv4Address, ieSourceIPv6Address} {
+			if v, ok := values[ie]; ok {
+				s.Src = net.IP(v)
+			}
+		}
+		for _, ie := range []uint16{ieDestinationIPv4Address, ieDestinationIPv6Address} {
+			if v, ok := values[ie]; ok {
+				s.Dst = net.IP(v)
+			}
+		}
+		s.Protocol = uint8(unsigned(values[ieProtocolIdentifier]))
+		s.DSCP = uint8(unsigned(values[ieIPClassOfService])) >> 2
+	default:
+		if hasRate {
+			d.rates[domain] = uint32(unsigned(rate))
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if hasRate {
+		s.Rate = uint32(unsigned(rate))
+	}
+	if s.Rate == 0 {
+		s.Rate = 1 // Not sampled, or no sampling interval exported yet.
+	}
+	if v, ok := values[iePacketDeltaCount]; ok {
+		s.Packets = unsigned(v)
+	}
+	s.Input = uint32(unsigned(values[ieIngressInterface]))
+	s.Output = uint32(unsigned(values[ieEgressInterface]))
+	if v, ok := values[ieDataLinkFrameSize]; ok {
+		s.Length = uint32(unsigned(v))
+	} else if v, ok := values[ieOctetDeltaCount]; ok && s.Packets > 0 {
+		s.Length = uint32(unsigned(v) / s.Packets)
+	}
+	return s, nil
+}
@@ -0,0 +1,283 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sampling provides a collector of packet samples which a test
+// embeds for a device to export sFlow or IPFIX to, and helpers to
+// configure the sampling of the device and to correlate the samples
+// with the flows of the ATE.
+//
+// The collector decodes the flow samples of sFlow version 5 and the
+// data records of IPFIX (RFC 7011) into a common Sample, with the
+// addresses, protocol and DSCP of the sampled packet and the sampling
+// rate, so that a test can estimate the packets of a flow from its
+// samples.
+package sampling
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Default ports of the collectors.
+const (
+	SflowPort = 6343
+	IPFIXPort = 4739
+)
+
+// Config returns the sFlow configuration of a device exporting to the
+// collector from the source address, if any, sampling the packets
+// received on the interfaces at the rate of 1 in rate.
+func Config(collector string, port uint16, source string, rate uint32, intfs ...string) *telemetry.Sampling_Sflow {
+	s := &telemetry.Sampling_Sflow{
+		Enabled:             ygot.Bool(true),
+		IngressSamplingRate: ygot.Uint32(rate),
+	}
+	c := s.GetOrCreateCollector(collector, port)
+	if source != "" {
+		c.SourceAddress = ygot.String(source)
+	}
+	for _, name := range intfs {
+		s.GetOrCreateInterface(name).Enabled = ygot.Bool(true)
+	}
+	return s
+}
+
+// Sample is a packet sampled by a device.
+type Sample struct {
+	// Format is "sflow" or "ipfix".
+	Format string
+	// Agent is the address of the device which sampled the packet.
+	Agent string
+	// Rate is the sampling rate, of 1 sample in Rate packets.
+	Rate uint32
+	// Input and Output are the ifindexes of the interfaces the packet was
+	// received on and sent out of.
+	Input, Output uint32
+	// Packets is the number of sampled packets the sample stands for.
+	Packets uint64
+	// Length is the length of the sampled frame.
+	Length   uint32
+	Src, Dst net.IP
+	Protocol uint8
+	DSCP     uint8
+}
+
+func (s *Sample) String() string {
+	return fmt.Sprintf("%s from %s 1:%d in=%d out=%d %v -> %v proto=%d dscp=%d len=%d packets=%d", s.Format, s.Agent, s.Rate, s.Input, s.Output, s.Src, s.Dst, s.Protocol, s.DSCP, s.Length, s.Packets)
+}
+
+// Matches returns whether the sample is of a packet from the source to
+// the destination address.
+func (s *Sample) Matches(src, dst string) bool {
+	return s.Src.Equal(net.ParseIP(src)) && s.Dst.Equal(net.ParseIP(dst))
+}
+
+// Estimate returns the number of packets the samples stand for, scaled
+// by their sampling rate.
+func Estimate(samples []*Sample) uint64 {
+	var n uint64
+	for _, s := range samples {
+		n += s.Packets * uint64(s.Rate)
+	}
+	return n
+}
+
+// Filter returns the samples matching the predicate.
+func Filter(samples []*Sample, match func(*Sample) bool) []*Sample {
+	var matched []*Sample
+	for _, s := range samples {
+		if match(s) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// CheckEstimate checks that the number of packets estimated from the
+// samples of a flow is within the tolerance, in percent, of the packets
+// sent by the flow.
+func CheckEstimate(estimate, sent uint64, tolerancePct float64) error {
+	if sent == 0 {
+		return fmt.Errorf("no packets sent")
+	}
+	pct := 100 * (float64(estimate) - float64(sent)) / float64(sent)
+	if pct < -tolerancePct || pct > tolerancePct {
+		return fmt.Errorf("estimated %d packets from the samples, %.1f%% off the %d sent, want within %g%%", estimate, pct, sent, tolerancePct)
+	}
+	return nil
+}
+
+// decodeFrame decodes the addresses, protocol and DSCP of the header of
+// an Ethernet frame into the sample.
+func decodeFrame(s *Sample, frame []byte) error {
+	if len(frame) < 14 {
+		return fmt.Errorf("frame header of %d bytes too short", len(frame))
+	}
+	etherType := binary.BigEndian.Uint16(frame[12:])
+	frame = frame[14:]
+	for etherType == 0x8100 || etherType == 0x88A8 {
+		if len(frame) < 4 {
+			return fmt.Errorf("VLAN tag truncated")
+		}
+		etherType = binary.BigEndian.Uint16(frame[2:])
+		frame = frame[4:]
+	}
+	switch etherType {
+	case 0x0800, 0x86DD:
+		return decodeIP(s, frame)
+	}
+	return fmt.Errorf("ethertype %#04x is not IP", etherType)
+}
+
+// decodeIP decodes the addresses, protocol and DSCP of the header of an
+// IPv4 or IPv6 packet into the sample.
+func decodeIP(s *Sample, packet []byte) error {
+	if len(packet) == 0 {
+		return fmt.Errorf("IP header missing")
+	}
+	switch packet[0] >> 4 {
+	case 4:
+		if len(packet) < 20 {
+			return fmt.Errorf("IPv4 header of %d bytes too short", len(packet))
+		}
+		s.DSCP = packet[1] >> 2
+		s.Protocol = packet[9]
+		s.Src = net.IP(append([]byte(nil), packet[12:16]...))
+		s.Dst = net.IP(append([]byte(nil), packet[16:20]...))
+	case 6:
+		if len(packet) < 40 {
+			return fmt.Errorf("IPv6 header of %d bytes too short", len(packet))
+		}
+		s.DSCP = (packet[0]&0x0F)<<2 | packet[1]>>6
+		s.Protocol = packet[6]
+		s.Src = net.IP(append([]byte(nil), packet[8:24]...))
+		s.Dst = net.IP(append([]byte(nil), packet[24:40]...))
+	default:
+		return fmt.Errorf("IP version %d unsupported", packet[0]>>4)
+	}
+	return nil
+}
+
+// Collector is a collector of sFlow and IPFIX, told apart by the version
+// of their datagrams.
+type Collector struct {
+	conn  net.PacketConn
+	ipfix *ipfixDecoder
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	samples []*Sample
+	errs    []error
+}
+
+// NewCollector starts a collector listening on the UDP address.
+func NewCollector(addr string) (*Collector, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &Collector{conn: conn, ipfix: newIPFIXDecoder()}
+	c.wg.Add(1)
+	go c.serve()
+	return c, nil
+}
+
+// Addr returns the address the collector listens on.
+func (c *Collector) Addr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// Close stops the collector.
+func (c *Collector) Close() error {
+	err := c.conn.Close()
+	c.wg.Wait()
+	return err
+}
+
+// Samples returns the samples collected so far.
+func (c *Collector) Samples() []*Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Sample(nil), c.samples...)
+}
+
+// Errors returns the errors decoding the datagrams so far.
+func (c *Collector) Errors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]error(nil), c.errs...)
+}
+
+// Reset discards the samples and errors collected so far.
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.samples = nil
+	c.errs = nil
+}
+
+// Await waits until the collector collected at least n samples matching
+// the predicate, and returns them.
+func (c *Collector) Await(n int, timeout time.Duration, match func(*Sample) bool) ([]*Sample, error) {
+	for start := time.Now(); ; time.Sleep(100 * time.Millisecond) {
+		matched := Filter(c.Samples(), match)
+		if len(matched) >= n {
+			return matched, nil
+		}
+		if time.Since(start) > timeout {
+			return matched, fmt.Errorf("collected %d matching samples after %v, want at least %d", len(matched), timeout, n)
+		}
+	}
+}
+
+func (c *Collector) serve() {
+	defer c.wg.Done()
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		// The samples may refer to the datagram, so decode a copy of it.
+		samples, err := c.decode(append([]byte(nil), buf[:n]...), addr)
+		c.mu.Lock()
+		c.samples = append(c.samples, samples...)
+		if err != nil {
+			c.errs = append(c.errs, fmt.Errorf("datagram from %v: %w", addr, err))
+		}
+		c.mu.Unlock()
+	}
+}
+
+// decode decodes the datagram received from the address.
+func (c *Collector) decode(b []byte, addr net.Addr) ([]*Sample, error) {
+	switch {
+	case len(b) >= 4 && binary.BigEndian.Uint32(b) == sflowVersion:
+		return decodeSflow(b)
+	case len(b) >= 2 && binary.BigEndian.Uint16(b) == ipfixVersion:
+		return c.ipfix.decode(b, addr)
+	}
+	return nil, fmt.Errorf("neither sFlow version 5 nor IPFIX")
+}
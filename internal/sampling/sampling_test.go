@@ -0,0 +1,262 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// frame returns the header of an Ethernet frame of an IPv4 or IPv6
+// packet, tagged with a VLAN.
+func frame(src, dst string, protocol, dscp uint8) []byte {
+	b := make([]byte, 12)
+	b = append(b, 0x81, 0x00, 0, 10)
+	s, d := net.ParseIP(src), net.ParseIP(dst)
+	if s4 := s.To4(); s4 != nil {
+		b = append(b, 0x08, 0x00)
+		ip := make([]byte, 20)
+		ip[0] = 0x45
+		ip[1] = dscp << 2
+		ip[9] = protocol
+		copy(ip[12:], s4)
+		copy(ip[16:], d.To4())
+		return append(b, ip...)
+	}
+	b = append(b, 0x86, 0xDD)
+	ip := make([]byte, 40)
+	ip[0] = 0x60 | dscp>>2
+	ip[1] = dscp << 6
+	ip[6] = protocol
+	copy(ip[8:], s)
+	copy(ip[24:], d)
+	return append(b, ip...)
+}
+
+func put32(b []byte, vs ...uint32) []byte {
+	for _, v := range vs {
+		b = append(b, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], v)
+	}
+	return b
+}
+
+func put16(b []byte, vs ...uint16) []byte {
+	for _, v := range vs {
+		b = append(b, 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-2:], v)
+	}
+	return b
+}
+
+// opaque returns the data with its tag and length, padded to 4 bytes.
+func opaque(tag uint32, data []byte) []byte {
+	b := put32(nil, tag, uint32(len(data)))
+	b = append(b, data...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// sflowDatagram returns an sFlow datagram of a counter sample, then a
+// flow sample and an expanded flow sample of the frames.
+func sflowDatagram(rate uint32, frames ...[]byte) []byte {
+	b := put32(nil, sflowVersion, 1)
+	b = append(b, 192, 0, 2, 1)
+	b = put32(b, 0, 1, 1000, uint32(len(frames)+1))
+	b = append(b, opaque(2, put32(nil, 1, 1, 0))...)
+	for i, f := range frames {
+		raw := put32(nil, headerEthernet, 1500, 4, uint32(len(f)))
+		raw = append(raw, f...)
+		for len(raw)%4 != 0 {
+			raw = append(raw, 0)
+		}
+		var s []byte
+		if i%2 == 0 {
+			s = put32(nil, uint32(i), 1, rate, 0, 0, 1, 2, 1)
+			s = append(s, opaque(sflowRawHeader, raw)...)
+			b = append(b, opaque(sflowFlowSample, s)...)
+		} else {
+			s = put32(nil, uint32(i), 0, 1, rate, 0, 0, 0, 1, 0, 2, 1)
+			s = append(s, opaque(sflowRawHeader, raw)...)
+			b = append(b, opaque(sflowExpandedFlowSample, s)...)
+		}
+	}
+	return b
+}
+
+func TestDecodeSflow(t *testing.T) {
+	b := sflowDatagram(1000,
+		frame("198.51.100.1", "203.0.113.1", 17, 46),
+		frame("2001:db8::1", "2001:db8:1::1", 6, 10),
+	)
+	got, err := decodeSflow(b)
+	if err != nil {
+		t.Fatalf("decodeSflow() got error: %v", err)
+	}
+	want := []*Sample{{
+		Format: "sflow", Agent: "192.0.2.1", Rate: 1000, Input: 1, Output: 2, Packets: 1, Length: 1500,
+		Src: net.ParseIP("198.51.100.1").To4(), Dst: net.ParseIP("203.0.113.1").To4(), Protocol: 17, DSCP: 46,
+	}, {
+		Format: "sflow", Agent: "192.0.2.1", Rate: 1000, Input: 1, Output: 2, Packets: 1, Length: 1500,
+		Src: net.ParseIP("2001:db8::1"), Dst: net.ParseIP("2001:db8:1::1"), Protocol: 6, DSCP: 10,
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("decodeSflow() diff (-want +got):\n%s", diff)
+	}
+	if _, err := decodeSflow(b[:len(b)-8]); err == nil {
+		t.Errorf("decodeSflow() of a truncated datagram got no error")
+	}
+}
+
+// ipfixMessage returns an IPFIX message of the sets.
+func ipfixMessage(sets ...[]byte) []byte {
+	b := put16(nil, ipfixVersion, 0)
+	b = put32(b, 0, 1, 7)
+	for _, s := range sets {
+		b = append(b, s...)
+	}
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	return b
+}
+
+// ipfixSet returns a set of the ID and body.
+func ipfixSet(id uint16, body []byte) []byte {
+	return append(put16(nil, id, uint16(len(body)+4)), body...)
+}
+
+func TestIPFIX(t *testing.T) {
+	d := newIPFIXDecoder()
+	addr := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}
+
+	// Template 256 of addresses and counters, template 257 of a frame
+	// section of variable length, and an options template 258 of the
+	// sampling interval.
+	templates := ipfixSet(setTemplate, put16(nil,
+		256, 6,
+		ieSourceIPv4Address, 4, ieDestinationIPv4Address, 4, ieProtocolIdentifier, 1,
+		ieIPClassOfService, 1, iePacketDeltaCount, 8, ieIngressInterface, 4,
+		257, 2,
+		ieDataLinkFrameSection, variableLength, ieEnterpriseBit|1, 2, 0, 9,
+	))
+	options := ipfixSet(setOptionsTemplate, put16(nil, 258, 2, 1, 149, 4, ieSamplingPacketInterval, 4))
+
+	if _, err := d.decode(ipfixMessage(ipfixSet(256, make([]byte, 22))), addr); err == nil {
+		t.Errorf("decode() of an unknown template got no error")
+	}
+
+	rec256 := append([]byte{198, 51, 100, 1, 203, 0, 113, 1, 17, 46 << 2}, 0, 0, 0, 0, 0, 0, 0, 3)
+	rec256 = put32(rec256, 5)
+	f := frame("2001:db8::1", "2001:db8:1::1", 6, 10)
+	rec257 := append([]byte{byte(len(f))}, f...)
+	rec257 = append(rec257, 0xAB, 0xCD)
+	data257 := append(append([]byte(nil), rec257...), 0, 0) // Padded.
+
+	got, err := d.decode(ipfixMessage(
+		templates, options,
+		ipfixSet(258, put32(nil, 1, 100)),
+		ipfixSet(256, rec256),
+		ipfixSet(257, data257),
+	), addr)
+	if err != nil {
+		t.Fatalf("decode() got error: %v", err)
+	}
+	want := []*Sample{{
+		Format: "ipfix", Agent: "192.0.2.1", Rate: 100, Input: 5, Packets: 3,
+		Src: net.IP{198, 51, 100, 1}, Dst: net.IP{203, 0, 113, 1}, Protocol: 17, DSCP: 46,
+	}, {
+		Format: "ipfix", Agent: "192.0.2.1", Rate: 100, Packets: 1,
+		Src: net.ParseIP("2001:db8::1"), Dst: net.ParseIP("2001:db8:1::1"), Protocol: 6, DSCP: 10,
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("decode() diff (-want +got):\n%s", diff)
+	}
+	if got := Estimate(got); got != 400 {
+		t.Errorf("Estimate() got %d, want 400", got)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c, err := NewCollector("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("NewCollector() got error: %v", err)
+	}
+	defer c.Close()
+
+	conn, err := net.Dial("udp", c.Addr().String())
+	if err != nil {
+		t.Fatalf("Cannot connect to the collector: %v", err)
+	}
+	defer conn.Close()
+	for _, b := range [][]byte{
+		sflowDatagram(10, frame("198.51.100.1", "203.0.113.1", 17, 0), frame("198.51.100.2", "203.0.113.1", 17, 0)),
+		sflowDatagram(10, frame("198.51.100.1", "203.0.113.1", 17, 0)),
+		[]byte("garbage"),
+	} {
+		if _, err := conn.Write(b); err != nil {
+			t.Fatalf("Cannot send to the collector: %v", err)
+		}
+	}
+	match := func(s *Sample) bool { return s.Matches("198.51.100.1", "203.0.113.1") }
+	samples, err := c.Await(2, 5*time.Second, match)
+	if err != nil {
+		t.Fatalf("Await() got error: %v", err)
+	}
+	if got := Estimate(samples); got != 20 {
+		t.Errorf("Estimate() got %d, want 20", got)
+	}
+	for start := time.Now(); len(c.Errors()) == 0 && time.Since(start) < 5*time.Second; {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := len(c.Errors()); got != 1 {
+		t.Errorf("Errors() got %d, want 1 for the garbage", got)
+	}
+	c.Reset()
+	if _, err := c.Await(1, 200*time.Millisecond, match); err == nil {
+		t.Errorf("Await() after Reset() got no error")
+	}
+}
+
+func TestCheckEstimate(t *testing.T) {
+	for _, c := range []struct {
+		estimate, sent uint64
+		wantErr        bool
+	}{
+		{1000, 1000, false},
+		{1090, 1000, false},
+		{890, 1000, true},
+		{0, 1000, true},
+		{0, 0, true},
+	} {
+		if err := CheckEstimate(c.estimate, c.sent, 10); (err != nil) != c.wantErr {
+			t.Errorf("CheckEstimate(%d, %d) got error %v, want error %v", c.estimate, c.sent, err, c.wantErr)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	s := Config("192.0.2.10", SflowPort, "192.0.2.1", 1000, "eth1", "eth2")
+	if got := s.GetCollector("192.0.2.10", SflowPort).GetSourceAddress(); got != "192.0.2.1" {
+		t.Errorf("Config() collector source address got %q, want 192.0.2.1", got)
+	}
+	if got := len(s.Interface); got != 2 {
+		t.Errorf("Config() got %d interfaces, want 2", got)
+	}
+}
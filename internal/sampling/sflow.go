@@ -0,0 +1,171 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// sFlow version 5 datagrams (sflow.org/sflow_version_5.txt).
+const (
+	sflowVersion = 5
+
+	sflowFlowSample         = 1
+	sflowExpandedFlowSample = 3
+
+	sflowRawHeader   = 1
+	sflowSampledIPv4 = 3
+	sflowSampledIPv6 = 4
+
+	headerEthernet = 1
+	headerIPv4     = 11
+	headerIPv6     = 12
+)
+
+// xdr reads the big-endian fields of sFlow, padded to 4 bytes.
+type xdr struct {
+	b   []byte
+	err error
+}
+
+func (x *xdr) bytes(n int) []byte {
+	padded := (n + 3) &^ 3
+	if x.err != nil || n < 0 || len(x.b) < padded {
+		if x.err == nil {
+			x.err = fmt.Errorf("sFlow datagram truncated")
+		}
+		return make([]byte, n)
+	}
+	b := x.b[:n]
+	x.b = x.b[padded:]
+	return b
+}
+
+func (x *xdr) uint32() uint32 {
+	return binary.BigEndian.Uint32(x.bytes(4))
+}
+
+// opaque returns the data of a tag and length, for the caller to read
+// from.
+func (x *xdr) opaque() (uint32, *xdr) {
+	tag := x.uint32()
+	return tag, &xdr{b: x.bytes(int(x.uint32()))}
+}
+
+// decodeSflow decodes the flow samples of an sFlow datagram, skipping
+// its counter samples.
+func decodeSflow(b []byte) ([]*Sample, error) {
+	x := &xdr{b: b}
+	x.uint32() // Version.
+	var agent net.IP
+	switch typ := x.uint32(); typ {
+	case 1:
+		agent = net.IP(x.bytes(4))
+	case 2:
+		agent = net.IP(x.bytes(16))
+	default:
+		return nil, fmt.Errorf("sFlow agent address type %d unsupported", typ)
+	}
+	x.uint32() // Sub-agent ID.
+	x.uint32() // Sequence number.
+	x.uint32() // Uptime.
+	n := x.uint32()
+	var samples []*Sample
+	for i := uint32(0); i < n && x.err == nil; i++ {
+		tag, data := x.opaque()
+		if tag>>12 != 0 {
+			continue // Enterprise specific.
+		}
+		switch tag & 0xFFF {
+		case sflowFlowSample, sflowExpandedFlowSample:
+			s, err := decodeFlowSample(data, tag&0xFFF == sflowExpandedFlowSample)
+			if err != nil {
+				return samples, err
+			}
+			s.Agent = agent.String()
+			samples = append(samples, s)
+		}
+	}
+	return samples, x.err
+}
+
+// decodeFlowSample decodes a flow sample or an expanded flow sample.
+func decodeFlowSample(x *xdr, expanded bool) (*Sample, error) {
+	s := &Sample{Format: "sflow", Packets: 1}
+	x.uint32() // Sequence number.
+	if expanded {
+		x.uint32() // Source ID type.
+		x.uint32() // Source ID index.
+	} else {
+		x.uint32() // Source ID.
+	}
+	s.Rate = x.uint32()
+	x.uint32() // Sample pool.
+	x.uint32() // Drops.
+	if expanded {
+		x.uint32() // Input format.
+		s.Input = x.uint32()
+		x.uint32() // Output format.
+		s.Output = x.uint32()
+	} else {
+		// The two top bits are the format of the interface.
+		s.Input = x.uint32() & 0x3FFFFFFF
+		s.Output = x.uint32() & 0x3FFFFFFF
+	}
+	n := x.uint32()
+	for i := uint32(0); i < n && x.err == nil; i++ {
+		tag, r := x.opaque()
+		if tag>>12 != 0 {
+			continue
+		}
+		var err error
+		switch tag & 0xFFF {
+		case sflowRawHeader:
+			protocol := r.uint32()
+			s.Length = r.uint32()
+			r.uint32() // Stripped.
+			header := r.bytes(int(r.uint32()))
+			if r.err != nil {
+				return nil, r.err
+			}
+			switch protocol {
+			case headerEthernet:
+				err = decodeFrame(s, header)
+			case headerIPv4, headerIPv6:
+				err = decodeIP(s, header)
+			}
+		case sflowSampledIPv4, sflowSampledIPv6:
+			addrLen := 4
+			if tag&0xFFF == sflowSampledIPv6 {
+				addrLen = 16
+			}
+			s.Length = r.uint32()
+			s.Protocol = uint8(r.uint32())
+			s.Src = net.IP(r.bytes(addrLen))
+			s.Dst = net.IP(r.bytes(addrLen))
+			r.uint32() // Source port.
+			r.uint32() // Destination port.
+			r.uint32() // TCP flags.
+			s.DSCP = uint8(r.uint32()) >> 2
+			err = r.err
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, x.err
+}
@@ -0,0 +1,216 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scenario composes the long operational tests, such as a
+// failover or an upgrade under traffic, from named steps which configure
+// the devices, verify their state, perturb them, measure the effects, and
+// clean up.  Each step runs as a subtest and is timed and logged.  Once a
+// step fails, the steps after it are skipped, since they would run from
+// an unknown state, except for the cleanup steps, and the steps allowed
+// to fail.  The outcome and duration of all the steps are logged as a
+// summary at the end.
+//
+// Usage:
+//
+//	s := scenario.New(tl)
+//	s.Configure("ProgramA", func(t *testing.T) { ... })
+//	s.Perturb("TakeoverB", func(t *testing.T) { ... })
+//	s.Verify("Converged", func(t *testing.T) { ... })
+//	s.Cleanup("Delete", func(t *testing.T) { ... })
+//	s.Run(t)
+package scenario
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/timeline"
+)
+
+// Kind is the kind of a step.
+type Kind string
+
+// Kinds of steps.
+const (
+	Configure Kind = "configure"
+	Verify    Kind = "verify"
+	Perturb   Kind = "perturb"
+	Measure   Kind = "measure"
+	Cleanup   Kind = "cleanup"
+)
+
+// Step is a named step of a scenario.
+type Step struct {
+	Name string
+	Kind Kind
+	Run  func(t *testing.T)
+	// MayFail lets the scenario go on after the step fails, for the steps
+	// whose failure leaves the devices in a known state, e.g. a
+	// verification.
+	MayFail bool
+}
+
+// Outcome is the outcome of a step.
+type Outcome string
+
+// Outcomes of the steps.
+const (
+	Passed  Outcome = "PASS"
+	Failed  Outcome = "FAIL"
+	Skipped Outcome = "SKIP"
+)
+
+// Result is the result of a step.
+type Result struct {
+	Step     *Step
+	Outcome  Outcome
+	Start    time.Time
+	Duration time.Duration
+	// Reason is why the step was skipped.
+	Reason string
+}
+
+// T is the test the steps of a scenario run in, as subtests.  It is
+// implemented by *testing.T.
+type T interface {
+	Helper()
+	Logf(format string, args ...interface{})
+	Run(name string, f func(t *testing.T)) bool
+}
+
+// Scenario is a sequence of steps.
+type Scenario struct {
+	Steps []*Step
+	// tl, if not nil, is marked at the start and the end of each step.
+	tl  *timeline.Timeline
+	now func() time.Time
+}
+
+// New returns an empty scenario, marking its steps on the timeline if it
+// is not nil.
+func New(tl *timeline.Timeline) *Scenario {
+	return &Scenario{tl: tl, now: time.Now}
+}
+
+// Add adds a step to the scenario.
+func (s *Scenario) Add(step *Step) *Scenario {
+	s.Steps = append(s.Steps, step)
+	return s
+}
+
+// Configure adds a step configuring the devices.
+func (s *Scenario) Configure(name string, run func(t *testing.T)) *Scenario {
+	return s.Add(&Step{Name: name, Kind: Configure, Run: run})
+}
+
+// Verify adds a step verifying the state of the devices.  The scenario
+// goes on if it fails.
+func (s *Scenario) Verify(name string, run func(t *testing.T)) *Scenario {
+	return s.Add(&Step{Name: name, Kind: Verify, Run: run, MayFail: true})
+}
+
+// Perturb adds a step perturbing the devices.
+func (s *Scenario) Perturb(name string, run func(t *testing.T)) *Scenario {
+	return s.Add(&Step{Name: name, Kind: Perturb, Run: run})
+}
+
+// Measure adds a step measuring the effects of a perturbation.  The
+// scenario goes on if it fails.
+func (s *Scenario) Measure(name string, run func(t *testing.T)) *Scenario {
+	return s.Add(&Step{Name: name, Kind: Measure, Run: run, MayFail: true})
+}
+
+// Cleanup adds a step cleaning up the devices, which runs even once a
+// previous step failed.
+func (s *Scenario) Cleanup(name string, run func(t *testing.T)) *Scenario {
+	return s.Add(&Step{Name: name, Kind: Cleanup, Run: run})
+}
+
+// mark marks the timeline, if any.
+func (s *Scenario) mark(format string, args ...interface{}) {
+	if s.tl != nil {
+		s.tl.Mark(format, args...)
+	}
+}
+
+// Run runs the steps of the scenario in order as subtests of the test,
+// logs their summary, and returns their results.
+func (s *Scenario) Run(t T) []*Result {
+	t.Helper()
+	var results []*Result
+	var failed *Step
+	for _, step := range s.Steps {
+		r := &Result{Step: step, Start: s.now()}
+		results = append(results, r)
+		if failed != nil && step.Kind != Cleanup {
+			r.Outcome = Skipped
+			r.Reason = fmt.Sprintf("step %s failed", failed.Name)
+			t.Logf("Skipping %s step %s: %s", step.Kind, step.Name, r.Reason)
+			continue
+		}
+		s.mark("Start of %s step %s", step.Kind, step.Name)
+		ok := t.Run(step.Name, step.Run)
+		r.Duration = s.now().Sub(r.Start)
+		r.Outcome = Passed
+		if !ok {
+			r.Outcome = Failed
+			if failed == nil && !step.MayFail {
+				failed = step
+			}
+		}
+		s.mark("End of %s step %s: %s", step.Kind, step.Name, r.Outcome)
+		t.Logf("%s step %s: %s in %v", step.Kind, step.Name, r.Outcome, r.Duration)
+	}
+	t.Logf("Scenario summary:\n%s", Summary(results))
+	return results
+}
+
+// FormatSummary writes the results as a table, one step per line.
+func FormatSummary(w io.Writer, results []*Result) {
+	width := len("STEP")
+	for _, r := range results {
+		if len(r.Step.Name) > width {
+			width = len(r.Step.Name)
+		}
+	}
+	fmt.Fprintf(w, "%-*s  %-9s  %-7s  %s\n", width, "STEP", "KIND", "OUTCOME", "DURATION")
+	for _, r := range results {
+		d := r.Duration.Round(time.Millisecond).String()
+		if r.Outcome == Skipped {
+			d = r.Reason
+		}
+		fmt.Fprintf(w, "%-*s  %-9s  %-7s  %s\n", width, r.Step.Name, r.Step.Kind, r.Outcome, d)
+	}
+}
+
+// Summary returns the results as a table.
+func Summary(results []*Result) string {
+	var b strings.Builder
+	FormatSummary(&b, results)
+	return b.String()
+}
+
+// Failures returns the steps which failed or were skipped.
+func Failures(results []*Result) []*Result {
+	var failures []*Result
+	for _, r := range results {
+		if r.Outcome != Passed {
+			failures = append(failures, r)
+		}
+	}
+	return failures
+}
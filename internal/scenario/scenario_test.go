@@ -0,0 +1,190 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scenario
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/timeline"
+)
+
+// fakeT runs the steps by name, failing those in fail without running
+// them.
+type fakeT struct {
+	fail map[string]bool
+	ran  []string
+	logs []string
+}
+
+func (*fakeT) Helper() {}
+
+func (f *fakeT) Logf(format string, args ...interface{}) {
+	f.logs = append(f.logs, format)
+}
+
+func (f *fakeT) Run(name string, _ func(t *testing.T)) bool {
+	f.ran = append(f.ran, name)
+	return !f.fail[name]
+}
+
+// newScenario returns a scenario whose clock advances by a second at
+// each reading.
+func newScenario(tl *timeline.Timeline) *Scenario {
+	s := New(tl)
+	now := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return s
+}
+
+// build returns a scenario with a step of each kind.
+func build(tl *timeline.Timeline) *Scenario {
+	noop := func(t *testing.T) {}
+	return newScenario(tl).
+		Configure("configure", noop).
+		Perturb("perturb", noop).
+		Measure("measure", noop).
+		Verify("verify", noop).
+		Configure("reconfigure", noop).
+		Cleanup("cleanup", noop)
+}
+
+// outcomes returns the outcome of each step.
+func outcomes(results []*Result) map[string]Outcome {
+	o := map[string]Outcome{}
+	for _, r := range results {
+		o[r.Step.Name] = r.Outcome
+	}
+	return o
+}
+
+func TestRun(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		fail    []string
+		wantRan []string
+		want    map[string]Outcome
+	}{{
+		desc:    "all pass",
+		wantRan: []string{"configure", "perturb", "measure", "verify", "reconfigure", "cleanup"},
+		want: map[string]Outcome{
+			"configure": Passed, "perturb": Passed, "measure": Passed,
+			"verify": Passed, "reconfigure": Passed, "cleanup": Passed,
+		},
+	}, {
+		desc:    "configure fails",
+		fail:    []string{"configure"},
+		wantRan: []string{"configure", "cleanup"},
+		want: map[string]Outcome{
+			"configure": Failed, "perturb": Skipped, "measure": Skipped,
+			"verify": Skipped, "reconfigure": Skipped, "cleanup": Passed,
+		},
+	}, {
+		desc:    "measure and verify may fail",
+		fail:    []string{"measure", "verify"},
+		wantRan: []string{"configure", "perturb", "measure", "verify", "reconfigure", "cleanup"},
+		want: map[string]Outcome{
+			"configure": Passed, "perturb": Passed, "measure": Failed,
+			"verify": Failed, "reconfigure": Passed, "cleanup": Passed,
+		},
+	}, {
+		desc:    "perturb and cleanup fail",
+		fail:    []string{"perturb", "cleanup"},
+		wantRan: []string{"configure", "perturb", "cleanup"},
+		want: map[string]Outcome{
+			"configure": Passed, "perturb": Failed, "measure": Skipped,
+			"verify": Skipped, "reconfigure": Skipped, "cleanup": Failed,
+		},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ft := &fakeT{fail: map[string]bool{}}
+			for _, name := range tc.fail {
+				ft.fail[name] = true
+			}
+			results := build(nil).Run(ft)
+			if diff := cmp.Diff(tc.wantRan, ft.ran); diff != "" {
+				t.Errorf("Run() steps run -want, +got:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.want, outcomes(results)); diff != "" {
+				t.Errorf("Run() outcomes -want, +got:\n%s", diff)
+			}
+			for _, r := range results {
+				if r.Outcome == Skipped && r.Reason == "" {
+					t.Errorf("Run() skipped step %s without a reason", r.Step.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestRunDuration(t *testing.T) {
+	results := build(nil).Run(&fakeT{})
+	for _, r := range results {
+		// The clock is read at the start and the end of each step.
+		if r.Duration != time.Second {
+			t.Errorf("Step %s duration got %v, want 1s", r.Step.Name, r.Duration)
+		}
+	}
+}
+
+func TestRunTimeline(t *testing.T) {
+	tl := timeline.New()
+	newScenario(tl).
+		Configure("configure", func(t *testing.T) {}).
+		Cleanup("cleanup", func(t *testing.T) {}).
+		Run(&fakeT{fail: map[string]bool{"configure": true}})
+	var got []string
+	for _, e := range tl.Events() {
+		got = append(got, e.Text)
+	}
+	want := []string{
+		"Start of configure step configure",
+		"End of configure step configure: FAIL",
+		"Start of cleanup step cleanup",
+		"End of cleanup step cleanup: PASS",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Timeline events -want, +got:\n%s", diff)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	results := build(nil).Run(&fakeT{fail: map[string]bool{"perturb": true}})
+	got := strings.Split(strings.TrimSpace(Summary(results)), "\n")
+	want := []string{
+		"STEP         KIND       OUTCOME  DURATION",
+		"configure    configure  PASS     1s",
+		"perturb      perturb    FAIL     1s",
+		"measure      measure    SKIP     step perturb failed",
+		"verify       verify     SKIP     step perturb failed",
+		"reconfigure  configure  SKIP     step perturb failed",
+		"cleanup      cleanup    PASS     1s",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Summary() -want, +got:\n%s", diff)
+	}
+	var failures []string
+	for _, r := range Failures(results) {
+		failures = append(failures, r.Step.Name)
+	}
+	if diff := cmp.Diff([]string{"perturb", "measure", "verify", "reconfigure"}, failures); diff != "" {
+		t.Errorf("Failures() -want, +got:\n%s", diff)
+	}
+}
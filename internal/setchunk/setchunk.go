@@ -0,0 +1,365 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package setchunk splits gNMI SetRequests too large for a device into
+// SetRequests of bounded size, applied in sequence.  Devices reject the
+// messages larger than their limit with RESOURCE_EXHAUSTED, which large
+// QoS or ACL configurations hit.
+//
+// The deletes, replaces and updates of the request are kept in order, and
+// a replace or update too large on its own is split by the members of its
+// JSON value, recursively, and by the entries of its lists: the first part
+// replaces the node and the others update it, which is equivalent.  The split
+// request is not applied atomically.
+//
+// Usage:
+//
+//	resps, err := setchunk.Set(ctx, gnmiClient, req, setchunk.Limit())
+package setchunk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// DefaultLimit is the default maximum size of the messages received by a
+// gRPC server, 4MiB.
+const DefaultLimit = 4 << 20
+
+// ErrTooLarge is the error of a delete, replace or update which does not
+// fit in a SetRequest of the limit, even split.
+var ErrTooLarge = errors.New("cannot fit in a SetRequest")
+
+// Limit returns the maximum size of the SetRequests of the device: the one
+// declared by the deviation, else DefaultLimit.
+func Limit() int {
+	if *deviations.GNMISetMaxBytes > 0 {
+		return *deviations.GNMISetMaxBytes
+	}
+	return DefaultLimit
+}
+
+// largerThanMax matches the error of gRPC servers receiving a message
+// larger than their limit, e.g. "grpc: received message larger than max
+// (5000000 vs. 4194304)".
+var largerThanMax = regexp.MustCompile(`larger than max \((\d+) vs\. (\d+)\)`)
+
+// LimitFromError returns the limit advertised by the RESOURCE_EXHAUSTED
+// error of a device rejecting a message too large, if any.
+func LimitFromError(err error) (int, bool) {
+	s, ok := status.FromError(err)
+	if !ok || s.Code() != codes.ResourceExhausted {
+		return 0, false
+	}
+	m := largerThanMax.FindStringSubmatch(s.Message())
+	if m == nil {
+		return 0, false
+	}
+	limit, err := strconv.Atoi(m[2])
+	if err != nil {
+		return 0, false
+	}
+	return limit, true
+}
+
+// op is a delete, replace or update of a SetRequest.
+type op struct {
+	del     *gpb.Path
+	replace *gpb.Update
+	update  *gpb.Update
+}
+
+// add adds the operation to the request.
+func (o op) add(req *gpb.SetRequest) {
+	switch {
+	case o.del != nil:
+		req.Delete = append(req.Delete, o.del)
+	case o.replace != nil:
+		req.Replace = append(req.Replace, o.replace)
+	default:
+		req.Update = append(req.Update, o.update)
+	}
+}
+
+// remove removes the operation last added to the request.
+func (o op) remove(req *gpb.SetRequest) {
+	switch {
+	case o.del != nil:
+		req.Delete = req.Delete[:len(req.Delete)-1]
+	case o.replace != nil:
+		req.Replace = req.Replace[:len(req.Replace)-1]
+	default:
+		req.Update = req.Update[:len(req.Update)-1]
+	}
+}
+
+// String returns a description of the operation.
+func (o op) String() string {
+	switch {
+	case o.del != nil:
+		return fmt.Sprintf("delete of %v", o.del)
+	case o.replace != nil:
+		return fmt.Sprintf("replace of %v", o.replace.GetPath())
+	}
+	return fmt.Sprintf("update of %v", o.update.GetPath())
+}
+
+// size returns the size of a request of the operation on its own.
+func (o op) size(prefix *gpb.Path) int {
+	req := &gpb.SetRequest{Prefix: prefix}
+	o.add(req)
+	return proto.Size(req)
+}
+
+// Split returns the SetRequests of at most limit bytes applying the
+// request, in order: its deletes, then its replaces, then its updates, as
+// a single SetRequest applies them.  A request within the limit is
+// returned as is.
+func Split(req *gpb.SetRequest, limit int) ([]*gpb.SetRequest, error) {
+	if proto.Size(req) <= limit {
+		return []*gpb.SetRequest{req}, nil
+	}
+	prefix := req.GetPrefix()
+	var ops []op
+	for _, p := range req.GetDelete() {
+		ops = append(ops, op{del: p})
+	}
+	for _, u := range req.GetReplace() {
+		ops = append(ops, op{replace: u})
+	}
+	for _, u := range req.GetUpdate() {
+		ops = append(ops, op{update: u})
+	}
+
+	var reqs []*gpb.SetRequest
+	var cur *gpb.SetRequest
+	for _, o := range ops {
+		parts := []op{o}
+		if o.size(prefix) > limit {
+			var err error
+			if parts, err = splitOp(o, prefix, limit); err != nil {
+				return nil, err
+			}
+		}
+		for _, p := range parts {
+			if cur != nil {
+				p.add(cur)
+				if proto.Size(cur) <= limit {
+					continue
+				}
+				p.remove(cur)
+			}
+			cur = &gpb.SetRequest{Prefix: prefix}
+			p.add(cur)
+			reqs = append(reqs, cur)
+		}
+	}
+	return reqs, nil
+}
+
+// slack bounds the growth of the length prefixes of the messages nesting
+// a JSON value, as the value grows from its estimated size.
+const slack = 16
+
+// splitOp splits a replace or update too large for a request of the limit
+// by its JSON value.  The first part of a replace replaces the node, and
+// the others update it.
+func splitOp(o op, prefix *gpb.Path, limit int) ([]op, error) {
+	u := o.replace
+	if u == nil {
+		u = o.update
+	}
+	if u == nil {
+		return nil, fmt.Errorf("%v: %w", o, ErrTooLarge)
+	}
+	var raw []byte
+	ietf := false
+	switch v := u.GetVal().GetValue().(type) {
+	case *gpb.TypedValue_JsonIetfVal:
+		raw, ietf = v.JsonIetfVal, true
+	case *gpb.TypedValue_JsonVal:
+		raw = v.JsonVal
+	default:
+		return nil, fmt.Errorf("%v with a value which is not JSON: %w", o, ErrTooLarge)
+	}
+	part := func(b []byte, first bool) op {
+		val := &gpb.TypedValue{Value: &gpb.TypedValue_JsonVal{JsonVal: b}}
+		if ietf {
+			val = &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: b}}
+		}
+		pu := &gpb.Update{Path: u.GetPath(), Val: val}
+		if first && o.replace != nil {
+			return op{replace: pu}
+		}
+		return op{update: pu}
+	}
+
+	// The values are split within the budget left by an empty value.
+	empty := []byte("{}")
+	budget := limit - part(empty, true).size(prefix) + len(empty) - slack
+	vals, err := split(raw, budget)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", o, err)
+	}
+	var parts []op
+	for i, v := range vals {
+		parts = append(parts, part(v, i == 0))
+	}
+	return parts, nil
+}
+
+// split splits a JSON value into values of at most budget bytes which,
+// merged in order, make the value: an object by its members, recursively,
+// and a list by its entries.
+func split(v json.RawMessage, budget int) ([]json.RawMessage, error) {
+	if len(v) <= budget {
+		return []json.RawMessage{v}, nil
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(v, &members); err == nil {
+		return splitObject(members, budget)
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(v, &entries); err == nil {
+		return splitList(entries, budget)
+	}
+	return nil, ErrTooLarge
+}
+
+// splitObject splits the members of a JSON object into objects of at most
+// budget bytes.  The parts of a member too large are in separate objects.
+func splitObject(members map[string]json.RawMessage, budget int) ([]json.RawMessage, error) {
+	var keys []string
+	for k := range members {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []json.RawMessage
+	cur, size := map[string]json.RawMessage{}, 2
+	flush := func() error {
+		if len(cur) == 0 {
+			return nil
+		}
+		b, err := json.Marshal(cur)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, b)
+		cur, size = map[string]json.RawMessage{}, 2
+		return nil
+	}
+	for _, k := range keys {
+		// The name of a member takes its quotes, a colon and a comma.
+		name := len(strconv.Quote(k)) + 2
+		vals, err := split(members[k], budget-2-name)
+		if err != nil {
+			return nil, fmt.Errorf("member %s: %w", k, err)
+		}
+		for i, v := range vals {
+			n := name + len(v)
+			if i > 0 || size+n > budget {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			cur[k], size = v, size+n
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// splitList splits the entries of a JSON list into lists of at most budget
+// bytes.
+func splitList(entries []json.RawMessage, budget int) ([]json.RawMessage, error) {
+	var parts []json.RawMessage
+	var chunk []json.RawMessage
+	size := 2
+	flush := func() error {
+		b, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, b)
+		chunk, size = nil, 2
+		return nil
+	}
+	for i, e := range entries {
+		// An entry takes a comma.
+		n := len(e) + 1
+		if 2+n > budget {
+			return nil, fmt.Errorf("entry %d: %w", i, ErrTooLarge)
+		}
+		if size+n > budget {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		chunk, size = append(chunk, e), size+n
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// Set applies the request to the device in SetRequests of at most limit
+// bytes, in order, and returns their responses.  If the device rejects one
+// as too large and advertises a lower limit in its error, the rest of the
+// request is split again with that limit.
+func Set(ctx context.Context, c gpb.GNMIClient, req *gpb.SetRequest, limit int) ([]*gpb.SetResponse, error) {
+	reqs, err := Split(req, limit)
+	if err != nil {
+		return nil, err
+	}
+	var resps []*gpb.SetResponse
+	for i := 0; i < len(reqs); {
+		resp, err := c.Set(ctx, reqs[i])
+		if err == nil {
+			resps = append(resps, resp)
+			i++
+			continue
+		}
+		lower, ok := LimitFromError(err)
+		if !ok || lower >= limit {
+			return resps, fmt.Errorf("SetRequest %d of %d: %w", i+1, len(reqs), err)
+		}
+		limit = lower
+		rest := reqs[i:]
+		reqs = reqs[:i:i]
+		for _, r := range rest {
+			split, err := Split(r, limit)
+			if err != nil {
+				return resps, fmt.Errorf("cannot split for the limit of %d bytes of the device: %w", limit, err)
+			}
+			reqs = append(reqs, split...)
+		}
+	}
+	return resps, nil
+}
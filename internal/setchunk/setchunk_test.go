@@ -0,0 +1,291 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package setchunk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func path(elems ...string) *gpb.Path {
+	p := &gpb.Path{}
+	for _, e := range elems {
+		p.Elem = append(p.Elem, &gpb.PathElem{Name: e})
+	}
+	return p
+}
+
+func jsonUpdate(t *testing.T, p *gpb.Path, v interface{}) *gpb.Update {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Cannot marshal %v: %v", v, err)
+	}
+	return &gpb.Update{Path: p, Val: &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: b}}}
+}
+
+// aclSet returns the JSON value of an ACL set with n entries.
+func aclSet(n int) map[string]interface{} {
+	var entries []interface{}
+	for i := 1; i <= n; i++ {
+		entries = append(entries, map[string]interface{}{
+			"sequence-id": i,
+			"config":      map[string]interface{}{"sequence-id": i, "description": fmt.Sprintf("entry %d", i)},
+		})
+	}
+	return map[string]interface{}{
+		"name":        "ACL",
+		"type":        "ACL_IPV4",
+		"config":      map[string]interface{}{"name": "ACL", "type": "ACL_IPV4"},
+		"acl-entries": map[string]interface{}{"acl-entry": entries},
+	}
+}
+
+// merge merges the JSON value src into dst, as an update does: the members
+// of objects are merged, and the entries of lists appended.
+func merge(dst, src interface{}) interface{} {
+	switch s := src.(type) {
+	case map[string]interface{}:
+		d, ok := dst.(map[string]interface{})
+		if !ok {
+			d = map[string]interface{}{}
+		}
+		for k, v := range s {
+			d[k] = merge(d[k], v)
+		}
+		return d
+	case []interface{}:
+		d, _ := dst.([]interface{})
+		return append(d, s...)
+	}
+	return src
+}
+
+// apply returns the JSON values of the replaces and updates of the
+// requests merged in order, and whether the first one replaces the node.
+func apply(t *testing.T, reqs []*gpb.SetRequest) (interface{}, bool) {
+	t.Helper()
+	var got interface{}
+	replaced := false
+	for i, req := range reqs {
+		us := req.GetUpdate()
+		if len(req.GetReplace()) > 0 {
+			if i != 0 {
+				t.Errorf("SetRequest %d has a replace, want only the first one", i)
+			}
+			replaced = true
+			us = append(req.GetReplace(), us...)
+		}
+		for _, u := range us {
+			var v interface{}
+			if err := json.Unmarshal(u.GetVal().GetJsonIetfVal(), &v); err != nil {
+				t.Fatalf("Cannot unmarshal the value of SetRequest %d: %v", i, err)
+			}
+			got = merge(got, v)
+		}
+	}
+	return got, replaced
+}
+
+func TestSplitWithinLimit(t *testing.T) {
+	req := &gpb.SetRequest{Update: []*gpb.Update{jsonUpdate(t, path("acl"), aclSet(1))}}
+	got, err := Split(req, DefaultLimit)
+	if err != nil {
+		t.Fatalf("Split() got error: %v", err)
+	}
+	if len(got) != 1 || got[0] != req {
+		t.Errorf("Split() got %v, want the request as is", got)
+	}
+}
+
+func TestSplitOps(t *testing.T) {
+	prefix := path("acl")
+	req := &gpb.SetRequest{Prefix: prefix}
+	var want []string
+	for i := 0; i < 20; i++ {
+		req.Update = append(req.Update, jsonUpdate(t, path(fmt.Sprintf("u%d", i)), aclSet(2)))
+		want = append(want, fmt.Sprintf("update u%d", i))
+	}
+	req.Replace = []*gpb.Update{jsonUpdate(t, path("r"), aclSet(2))}
+	req.Delete = []*gpb.Path{path("d")}
+	want = append([]string{"delete d", "replace r"}, want...)
+	limit := proto.Size(req) / 4
+
+	reqs, err := Split(req, limit)
+	if err != nil {
+		t.Fatalf("Split() got error: %v", err)
+	}
+	if len(reqs) < 4 {
+		t.Errorf("Split() got %d requests, want at least 4", len(reqs))
+	}
+	var got []string
+	for i, r := range reqs {
+		if n := proto.Size(r); n > limit {
+			t.Errorf("SetRequest %d got %d bytes, want at most %d", i, n, limit)
+		}
+		if !proto.Equal(r.GetPrefix(), prefix) {
+			t.Errorf("SetRequest %d got prefix %v, want %v", i, r.GetPrefix(), prefix)
+		}
+		for _, p := range r.GetDelete() {
+			got = append(got, "delete "+p.GetElem()[0].GetName())
+		}
+		for _, u := range r.GetReplace() {
+			got = append(got, "replace "+u.GetPath().GetElem()[0].GetName())
+		}
+		for _, u := range r.GetUpdate() {
+			got = append(got, "update "+u.GetPath().GetElem()[0].GetName())
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Operations of the split requests -want, +got:\n%s", diff)
+	}
+}
+
+func TestSplitValue(t *testing.T) {
+	want := aclSet(500)
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Cannot marshal: %v", err)
+	}
+	var wantJSON interface{}
+	if err := json.Unmarshal(b, &wantJSON); err != nil {
+		t.Fatalf("Cannot unmarshal: %v", err)
+	}
+	for _, replace := range []bool{false, true} {
+		u := jsonUpdate(t, path("acl", "acl-sets", "acl-set"), want)
+		req := &gpb.SetRequest{Update: []*gpb.Update{u}}
+		if replace {
+			req = &gpb.SetRequest{Replace: []*gpb.Update{u}}
+		}
+		limit := proto.Size(req) / 10
+		reqs, err := Split(req, limit)
+		if err != nil {
+			t.Fatalf("Split(replace %v) got error: %v", replace, err)
+		}
+		if len(reqs) < 10 {
+			t.Errorf("Split(replace %v) got %d requests, want at least 10", replace, len(reqs))
+		}
+		for i, r := range reqs {
+			if n := proto.Size(r); n > limit {
+				t.Errorf("Split(replace %v): SetRequest %d got %d bytes, want at most %d", replace, i, n, limit)
+			}
+		}
+		got, replaced := apply(t, reqs)
+		if replaced != replace {
+			t.Errorf("Split(replace %v) replaced the node: %v", replace, replaced)
+		}
+		if diff := cmp.Diff(wantJSON, got); diff != "" {
+			t.Errorf("Split(replace %v) applied -want, +got:\n%s", replace, diff)
+		}
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	big := jsonUpdate(t, path("acl"), aclSet(100))
+	for _, c := range []struct {
+		desc  string
+		req   *gpb.SetRequest
+		limit int
+	}{
+		{"delete", &gpb.SetRequest{Delete: []*gpb.Path{path("a", "b", "c", "d")}}, 4},
+		{"not JSON", &gpb.SetRequest{Update: []*gpb.Update{{
+			Path: path("a"), Val: &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "0123456789"}},
+		}}}, 8},
+		{"JSON string", &gpb.SetRequest{Update: []*gpb.Update{jsonUpdate(t, path("a"), "0123456789abcdef")}}, 16},
+		{"entry too large", &gpb.SetRequest{Update: []*gpb.Update{big}}, 64},
+	} {
+		if _, err := Split(c.req, c.limit); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("%s: Split() got error %v, want %v", c.desc, err, ErrTooLarge)
+		}
+	}
+}
+
+func TestLimitFromError(t *testing.T) {
+	for _, c := range []struct {
+		desc   string
+		err    error
+		want   int
+		wantOK bool
+	}{
+		{"gRPC limit", status.Error(codes.ResourceExhausted, "grpc: received message larger than max (5000000 vs. 4194304)"), 4194304, true},
+		{"no limit", status.Error(codes.ResourceExhausted, "too many requests"), 0, false},
+		{"other code", status.Error(codes.InvalidArgument, "larger than max (5 vs. 4)"), 0, false},
+		{"not a status", errors.New("larger than max (5 vs. 4)"), 0, false},
+	} {
+		got, ok := LimitFromError(c.err)
+		if got != c.want || ok != c.wantOK {
+			t.Errorf("%s: LimitFromError() got %d, %v, want %d, %v", c.desc, got, ok, c.want, c.wantOK)
+		}
+	}
+}
+
+// fakeGNMI is a gNMI client of a device rejecting the SetRequests larger
+// than its limit as gRPC servers do.
+type fakeGNMI struct {
+	gpb.GNMIClient
+	limit int
+	reqs  []*gpb.SetRequest
+}
+
+func (f *fakeGNMI) Set(_ context.Context, req *gpb.SetRequest, _ ...grpc.CallOption) (*gpb.SetResponse, error) {
+	if n := proto.Size(req); n > f.limit {
+		return nil, status.Errorf(codes.ResourceExhausted, "grpc: received message larger than max (%d vs. %d)", n, f.limit)
+	}
+	f.reqs = append(f.reqs, req)
+	return &gpb.SetResponse{}, nil
+}
+
+func TestSet(t *testing.T) {
+	req := &gpb.SetRequest{Replace: []*gpb.Update{jsonUpdate(t, path("acl"), aclSet(500))}}
+	size := proto.Size(req)
+	for _, c := range []struct {
+		desc        string
+		limit       int
+		deviceLimit int
+	}{
+		{"within the limit", size, size},
+		{"split", size / 4, size / 4},
+		{"device advertises a lower limit", size / 2, size / 8},
+	} {
+		f := &fakeGNMI{limit: c.deviceLimit}
+		resps, err := Set(context.Background(), f, req, c.limit)
+		if err != nil {
+			t.Fatalf("%s: Set() got error: %v", c.desc, err)
+		}
+		if len(resps) != len(f.reqs) {
+			t.Errorf("%s: Set() got %d responses, want %d", c.desc, len(resps), len(f.reqs))
+		}
+		want, _ := apply(t, []*gpb.SetRequest{req})
+		got, _ := apply(t, f.reqs)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%s: Set() applied -want, +got:\n%s", c.desc, diff)
+		}
+	}
+	f := &fakeGNMI{limit: 16}
+	if _, err := Set(context.Background(), f, req, size); err == nil {
+		t.Error("Set() to a device with a limit too low got no error, want error")
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package srmpls provides helpers for Segment Routing MPLS tests with
+// IS-IS: configuration builders for the SRGB, prefix SIDs and adjacency
+// SIDs of the DUT and the ATE, computing the labels learned from the
+// IS-IS LSDB, and checking the MPLS forwarding of these labels.
+//
+// Usage:
+//
+//	d := &telemetry.Device{}
+//	ni := d.GetOrCreateNetworkInstance(*deviations.DefaultNetworkInstance)
+//	srgb := &srmpls.Block{Name: "srgb", Start: 16000, End: 23999}
+//	srgb.ConfigureSRGB(ni)
+//	isis := ni.GetOrCreateProtocol(isisType, isisName).GetOrCreateIsis()
+//	srmpls.EnableISIS(isis, srgb.Name)
+//	srmpls.ConfigurePrefixSID(isis, "Loopback0", "192.0.2.255/32", 10)
+package srmpls
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/isislsdb"
+	"github.com/openconfig/featureprofiles/internal/tolerance"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ondatra/ixnet"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Block is a block of MPLS labels reserved for segment routing.
+type Block struct {
+	Name       string
+	Start, End uint32
+}
+
+// Size returns the number of labels in the block.
+func (b *Block) Size() uint32 {
+	return b.End - b.Start + 1
+}
+
+// Label returns the label of the SID index in the block.
+func (b *Block) Label(index uint32) (uint32, error) {
+	if index >= b.Size() {
+		return 0, fmt.Errorf("SID index %d out of block %s of %d labels", index, b.Name, b.Size())
+	}
+	return b.Start + index, nil
+}
+
+// reserve reserves the labels of the block in the network instance.
+func (b *Block) reserve(ni *telemetry.NetworkInstance) {
+	rlb := ni.GetOrCreateMpls().GetOrCreateGlobal().GetOrCreateReservedLabelBlock(b.Name)
+	rlb.LowerBound = telemetry.UnionUint32(b.Start)
+	rlb.UpperBound = telemetry.UnionUint32(b.End)
+}
+
+// ConfigureSRGB reserves the labels of the block and uses them as an
+// MPLS SRGB in the network instance.
+func (b *Block) ConfigureSRGB(ni *telemetry.NetworkInstance) {
+	b.reserve(ni)
+	srgb := ni.GetOrCreateSegmentRouting().GetOrCreateSrgb(b.Name)
+	srgb.DataplaneType = telemetry.SegmentRoutingTypes_SrDataplaneType_MPLS
+	srgb.MplsLabelBlocks = []string{b.Name}
+}
+
+// ConfigureSRLB reserves the labels of the block and uses them as an
+// MPLS SRLB, from which adjacency SIDs are allocated, in the network
+// instance.
+func (b *Block) ConfigureSRLB(ni *telemetry.NetworkInstance) {
+	b.reserve(ni)
+	srlb := ni.GetOrCreateSegmentRouting().GetOrCreateSrlb(b.Name)
+	srlb.DataplaneType = telemetry.SegmentRoutingTypes_SrDataplaneType_MPLS
+	srlb.MplsLabelBlock = ygot.String(b.Name)
+}
+
+// EnableISIS enables segment routing in IS-IS with the SRGB.
+func EnableISIS(isis *telemetry.NetworkInstance_Protocol_Isis, srgb string) {
+	sr := isis.GetOrCreateGlobal().GetOrCreateSegmentRouting()
+	sr.Enabled = ygot.Bool(true)
+	sr.Srgb = ygot.String(srgb)
+}
+
+// af returns the level 2 unicast address family of the IPv4 or IPv6
+// address of an IS-IS interface.
+func af(isis *telemetry.NetworkInstance_Protocol_Isis, intf string, ipv6 bool) *telemetry.NetworkInstance_Protocol_Isis_Interface_Level_Af {
+	afi := telemetry.IsisTypes_AFI_TYPE_IPV4
+	if ipv6 {
+		afi = telemetry.IsisTypes_AFI_TYPE_IPV6
+	}
+	return isis.GetOrCreateInterface(intf).GetOrCreateLevel(2).GetOrCreateAf(afi, telemetry.IsisTypes_SAFI_TYPE_UNICAST)
+}
+
+// isIPv6 reports whether the address or prefix is IPv6.
+func isIPv6(addr string) bool {
+	return strings.Contains(addr, ":")
+}
+
+// ConfigurePrefixSID advertises the prefix of the IS-IS interface,
+// typically a loopback, with the SID index.
+func ConfigurePrefixSID(isis *telemetry.NetworkInstance_Protocol_Isis, intf, prefix string, index uint32) {
+	sid := af(isis, intf, isIPv6(prefix)).GetOrCreateSegmentRouting().GetOrCreatePrefixSid(prefix)
+	sid.SidId = telemetry.UnionUint32(index)
+}
+
+// ConfigureAdjacencySID allocates the static label, from the SRLB, as
+// the adjacency SID of the IS-IS interface to the neighbor address.
+func ConfigureAdjacencySID(isis *telemetry.NetworkInstance_Protocol_Isis, intf, neighbor string, label uint32) {
+	af(isis, intf, isIPv6(neighbor)).GetOrCreateSegmentRouting().
+		GetOrCreateAdjacencySid(neighbor, telemetry.UnionUint32(label))
+}
+
+// ConfigureATE enables segment routing on an ATE IS-IS router, with
+// the SRGB and the node SID index, and returns its configuration for
+// further changes such as adjacency SIDs.
+func ConfigureATE(isis *ixnet.ISIS, srgb *Block, nodeIndex uint32) *ixnet.ISISSegmentRouting {
+	sr := isis.SegmentRouting().WithEnabled(true).WithSIDIndexLabel(nodeIndex).WithFlagNodeSID(true)
+	sr.AddSRGBRange().WithSIDStartLabel(srgb.Start).WithSIDCount(srgb.Size())
+	return sr
+}
+
+// AdvertiseATEPrefix advertises the network of the ATE in IS-IS with
+// the SID index.
+func AdvertiseATEPrefix(net *ondatra.Network, metric, index uint32) {
+	net.ISIS().WithIPReachabilityMetric(metric).
+		WithSIDIndexLabelEnabled(true).WithIPReachabilitySIDIndexLabel(index)
+}
+
+// PrefixLabel returns the label a router uses for a prefix advertised
+// with a SID by the system in the LSDB, given the LSP of the router.
+// The SID index of the prefix is mapped into the router's SRGB, as in
+// RFC 8660, unless the SID is advertised as an absolute label.
+func PrefixLabel(db *isislsdb.Database, router *isislsdb.LSP, system, prefix string) (uint32, error) {
+	lsp := db.System(system)
+	if lsp == nil {
+		return 0, fmt.Errorf("no LSP from system %s", system)
+	}
+	_, n, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return 0, err
+	}
+	attrs, ok := lsp.Prefixes[n.String()]
+	if !ok {
+		return 0, fmt.Errorf("no reachability to %s from system %s", prefix, system)
+	}
+	if attrs.SID == nil {
+		return 0, fmt.Errorf("no prefix SID for %s from system %s", prefix, system)
+	}
+	if attrs.SID.Absolute {
+		return attrs.SID.Value, nil
+	}
+	if router.Capability == nil || len(router.Capability.SRGB) == 0 {
+		return 0, fmt.Errorf("no SRGB advertised by system %s", router.SystemID)
+	}
+	index := attrs.SID.Value
+	for _, r := range router.Capability.SRGB {
+		if index < r.Size {
+			return r.Start + index, nil
+		}
+		index -= r.Size
+	}
+	return 0, fmt.Errorf("SID index %d of %s out of the SRGB %v of system %s", attrs.SID.Value, prefix, router.Capability.SRGB, router.SystemID)
+}
+
+// AwaitLabelEntry waits for the label to be in the MPLS AFT of the DUT,
+// and returns the entry, or nil on timeout.
+func AwaitLabelEntry(t testing.TB, dut *ondatra.DUTDevice, label uint32, timeout time.Duration) *telemetry.NetworkInstance_Afts_LabelEntry {
+	t.Helper()
+	path := dut.Telemetry().NetworkInstance(*deviations.DefaultNetworkInstance).Afts().LabelEntry(telemetry.UnionUint32(label))
+	entry, ok := path.Watch(t, timeout, func(v *telemetry.QualifiedNetworkInstance_Afts_LabelEntry) bool {
+		return v.IsPresent()
+	}).Await(t)
+	if !ok {
+		return nil
+	}
+	return entry.Val(t)
+}
+
+// Flow returns a flow of MPLS packets with the label stack, outermost
+// first, over an IPv4 or IPv6 packet to the destination address.
+func Flow(ate *ondatra.ATEDevice, name string, src ondatra.Endpoint, dsts []ondatra.Endpoint, labels []uint32, dstIP string) *ondatra.Flow {
+	headers := []ondatra.Header{ondatra.NewEthernetHeader()}
+	for _, l := range labels {
+		headers = append(headers, ondatra.NewMPLSHeader().WithLabel(l))
+	}
+	if isIPv6(dstIP) {
+		headers = append(headers, ondatra.NewIPv6Header().WithDstAddress(dstIP))
+	} else {
+		headers = append(headers, ondatra.NewIPv4Header().WithDstAddress(dstIP))
+	}
+	return ate.Traffic().NewFlow(name).
+		WithSrcEndpoints(src).
+		WithDstEndpoints(dsts...).
+		WithHeaders(headers...)
+}
+
+// VerifyFlow returns an error if the flow lost more than maxLossPct
+// percent of its packets, or the tolerance_max_loss_pct argument if set.
+func VerifyFlow(t testing.TB, ate *ondatra.ATEDevice, flow *ondatra.Flow, maxLossPct float64) error {
+	t.Helper()
+	maxLossPct = tolerance.MaxLossPct(maxLossPct)
+	if loss := float64(ate.Telemetry().Flow(flow.Name()).LossPct().Get(t)); loss > maxLossPct {
+		return fmt.Errorf("flow %s lost %.2f%% of its packets, want <= %.2f%%", flow.Name(), loss, maxLossPct)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package srmpls
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/isislsdb"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestBlockLabel(t *testing.T) {
+	b := &Block{Name: "srgb", Start: 16000, End: 16099}
+	if got := b.Size(); got != 100 {
+		t.Errorf("Size got %d, want 100", got)
+	}
+	if got, err := b.Label(99); err != nil || got != 16099 {
+		t.Errorf("Label(99) got %d, %v, want 16099, nil", got, err)
+	}
+	if _, err := b.Label(100); err == nil {
+		t.Error("Label(100) got no error, want error")
+	}
+}
+
+func TestConfigureSRGB(t *testing.T) {
+	ni := &telemetry.NetworkInstance{}
+	srgb := &Block{Name: "srgb", Start: 16000, End: 23999}
+	srgb.ConfigureSRGB(ni)
+	srlb := &Block{Name: "srlb", Start: 15000, End: 15999}
+	srlb.ConfigureSRLB(ni)
+
+	for _, b := range []*Block{srgb, srlb} {
+		rlb := ni.GetMpls().GetGlobal().GetReservedLabelBlock(b.Name)
+		if rlb == nil {
+			t.Fatalf("No reserved label block %s", b.Name)
+		}
+		if rlb.LowerBound != telemetry.UnionUint32(b.Start) || rlb.UpperBound != telemetry.UnionUint32(b.End) {
+			t.Errorf("Reserved label block %s got %v-%v, want %d-%d", b.Name, rlb.LowerBound, rlb.UpperBound, b.Start, b.End)
+		}
+	}
+	if got := ni.GetSegmentRouting().GetSrgb("srgb").MplsLabelBlocks; !cmp.Equal(got, []string{"srgb"}) {
+		t.Errorf("SRGB label blocks got %v, want [srgb]", got)
+	}
+	if got := ni.GetSegmentRouting().GetSrlb("srlb").GetMplsLabelBlock(); got != "srlb" {
+		t.Errorf("SRLB label block got %q, want srlb", got)
+	}
+}
+
+func TestConfigureSIDs(t *testing.T) {
+	isis := &telemetry.NetworkInstance_Protocol_Isis{}
+	EnableISIS(isis, "srgb")
+	ConfigurePrefixSID(isis, "lo0", "192.0.2.255/32", 1)
+	ConfigurePrefixSID(isis, "lo0", "2001:db8::ff/128", 2)
+	ConfigureAdjacencySID(isis, "eth1", "192.0.2.2", 15000)
+
+	if got := isis.GetGlobal().GetSegmentRouting().GetSrgb(); got != "srgb" {
+		t.Errorf("IS-IS SRGB got %q, want srgb", got)
+	}
+	lo := isis.GetInterface("lo0").GetLevel(2)
+	v4 := lo.GetAf(telemetry.IsisTypes_AFI_TYPE_IPV4, telemetry.IsisTypes_SAFI_TYPE_UNICAST).GetSegmentRouting().GetPrefixSid("192.0.2.255/32")
+	if v4 == nil || v4.SidId != telemetry.UnionUint32(1) {
+		t.Errorf("IPv4 prefix SID got %+v, want index 1", v4)
+	}
+	v6 := lo.GetAf(telemetry.IsisTypes_AFI_TYPE_IPV6, telemetry.IsisTypes_SAFI_TYPE_UNICAST).GetSegmentRouting().GetPrefixSid("2001:db8::ff/128")
+	if v6 == nil || v6.SidId != telemetry.UnionUint32(2) {
+		t.Errorf("IPv6 prefix SID got %+v, want index 2", v6)
+	}
+	adj := isis.GetInterface("eth1").GetLevel(2).GetAf(telemetry.IsisTypes_AFI_TYPE_IPV4, telemetry.IsisTypes_SAFI_TYPE_UNICAST).GetSegmentRouting()
+	if adj.GetAdjacencySid("192.0.2.2", telemetry.UnionUint32(15000)) == nil {
+		t.Errorf("No adjacency SID 15000 to 192.0.2.2, got %v", adj.AdjacencySid)
+	}
+}
+
+func TestPrefixLabel(t *testing.T) {
+	ate := &isislsdb.LSP{
+		ID:       "6400.0000.0001.00-00",
+		SystemID: "6400.0000.0001",
+		Prefixes: map[string]*isislsdb.Prefix{
+			"198.51.100.0/24": {Metric: 10, SID: &isislsdb.PrefixSID{Value: 150}},
+			"198.51.101.0/24": {Metric: 10, SID: &isislsdb.PrefixSID{Value: 24000, Absolute: true}},
+			"198.51.102.0/24": {Metric: 10, SID: &isislsdb.PrefixSID{Value: 300}},
+			"198.51.103.0/24": {Metric: 10},
+		},
+	}
+	dut := &isislsdb.LSP{
+		ID:       "1920.0000.2001.00-00",
+		SystemID: "1920.0000.2001",
+		Capability: &isislsdb.Capability{
+			SRGB: []isislsdb.LabelRange{{Start: 16000, Size: 100}, {Start: 20000, Size: 100}},
+		},
+	}
+	db := &isislsdb.Database{LSPs: map[string]*isislsdb.LSP{ate.ID: ate, dut.ID: dut}}
+
+	cases := []struct {
+		prefix  string
+		want    uint32
+		wantErr bool
+	}{
+		{prefix: "198.51.100.0/24", want: 20050},
+		{prefix: "198.51.100.1/24", want: 20050},
+		{prefix: "198.51.101.0/24", want: 24000},
+		{prefix: "198.51.102.0/24", wantErr: true},
+		{prefix: "198.51.103.0/24", wantErr: true},
+		{prefix: "203.0.113.0/24", wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := PrefixLabel(db, dut, ate.SystemID, c.prefix)
+		if (err != nil) != c.wantErr {
+			t.Errorf("PrefixLabel(%s) got error %v, want error %v", c.prefix, err, c.wantErr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("PrefixLabel(%s) got %d, want %d", c.prefix, got, c.want)
+		}
+	}
+	if _, err := PrefixLabel(db, ate, ate.SystemID, "198.51.100.0/24"); err == nil {
+		t.Error("PrefixLabel with a router without SRGB got no error, want error")
+	}
+}
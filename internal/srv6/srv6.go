@@ -0,0 +1,114 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package srv6 provides helpers for SRv6 tests: SRv6 locators and the
+// SIDs of their behaviors.  The SRv6 packets forwarded by the DUT are
+// validated with the capture package.
+//
+// The OpenConfig model used by the tests has no SRv6 locators nor
+// endpoint behaviors, so a locator is configured as an SRGB with the
+// IPv6 dataplane, and the behaviors of its SIDs, such as End and
+// End.DT4, are expected to be configured out of band with the function
+// values given to SID.
+package srv6
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// functionBits is the length of the function of the SIDs, which
+// follows the locator.
+const functionBits = 16
+
+// Behavior is an SRv6 endpoint behavior, RFC 8986.
+type Behavior string
+
+// Behaviors used by the tests.
+const (
+	End    Behavior = "End"
+	EndX   Behavior = "End.X"
+	EndDT4 Behavior = "End.DT4"
+	EndDT6 Behavior = "End.DT6"
+)
+
+// Locator is an SRv6 locator.
+type Locator struct {
+	Name string
+	// Prefix is the locator prefix, e.g. 2001:db8:a::/48.
+	Prefix string
+}
+
+// Configure configures the locator in the network instance.
+func (l *Locator) Configure(ni *telemetry.NetworkInstance) {
+	srgb := ni.GetOrCreateSegmentRouting().GetOrCreateSrgb(l.Name)
+	srgb.DataplaneType = telemetry.SegmentRoutingTypes_SrDataplaneType_IPV6
+	srgb.Ipv6Prefixes = []string{l.Prefix}
+}
+
+// SID returns the SID of the function in the locator: the locator
+// followed by the 16 bits of the function.
+func (l *Locator) SID(function uint16) (string, error) {
+	ip, n, err := net.ParseCIDR(l.Prefix)
+	if err != nil || ip.To4() != nil {
+		return "", fmt.Errorf("invalid IPv6 locator %q", l.Prefix)
+	}
+	plen, _ := n.Mask.Size()
+	if plen+functionBits > 128 {
+		return "", fmt.Errorf("locator %s too long for a %d bits function", l.Prefix, functionBits)
+	}
+	sid := new(big.Int).SetBytes(n.IP.To16())
+	f := new(big.Int).Lsh(big.NewInt(int64(function)), uint(128-plen-functionBits))
+	sid.Or(sid, f)
+	return net.IP(sid.FillBytes(make([]byte, net.IPv6len))).String(), nil
+}
+
+// Contains reports whether the SID is in the locator.
+func (l *Locator) Contains(sid string) bool {
+	_, n, err := net.ParseCIDR(l.Prefix)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(sid)
+	return ip != nil && n.Contains(ip)
+}
+
+// Segment is a segment of an SRv6 path.
+type Segment struct {
+	Behavior Behavior
+	SID      string
+}
+
+// SIDs returns the SIDs of the path, in the order they are visited.
+func SIDs(path []Segment) []string {
+	var sids []string
+	for _, s := range path {
+		sids = append(sids, s.SID)
+	}
+	return sids
+}
+
+// SegmentsLeft returns the segments left of the packets of the path
+// after the endpoint of segment i, counting from 0, processed them.
+// Packets leaving the endpoint of the last segment, which decapsulates
+// them, have no segment left.
+func SegmentsLeft(path []Segment, i int) int {
+	if i >= len(path)-1 {
+		return 0
+	}
+	return len(path) - 2 - i
+}
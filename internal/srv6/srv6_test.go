@@ -0,0 +1,86 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package srv6
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestSID(t *testing.T) {
+	cases := []struct {
+		prefix   string
+		function uint16
+		want     string
+	}{
+		{"2001:db8:a::/48", 0x100, "2001:db8:a:100::"},
+		{"2001:db8:a:b::/64", 0xffff, "2001:db8:a:b:ffff::"},
+		{"2001:db8:a::1/48", 1, "2001:db8:a:1::"},
+		{"2001:db8::/112", 7, "2001:db8::7"},
+	}
+	for _, c := range cases {
+		l := &Locator{Name: "loc", Prefix: c.prefix}
+		got, err := l.SID(c.function)
+		if err != nil {
+			t.Errorf("SID(%#x) of %s got error: %v", c.function, c.prefix, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("SID(%#x) of %s got %s, want %s", c.function, c.prefix, got, c.want)
+		}
+		if !l.Contains(got) {
+			t.Errorf("Locator %s does not contain its SID %s", c.prefix, got)
+		}
+	}
+	for _, prefix := range []string{"192.0.2.0/24", "2001:db8::/120", "bogus"} {
+		l := &Locator{Name: "loc", Prefix: prefix}
+		if _, err := l.SID(1); err == nil {
+			t.Errorf("SID of %s got no error, want error", prefix)
+		}
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	ni := &telemetry.NetworkInstance{}
+	(&Locator{Name: "loc", Prefix: "2001:db8:a::/48"}).Configure(ni)
+	want := &telemetry.NetworkInstance_SegmentRouting_Srgb{
+		LocalId:       ygot.String("loc"),
+		DataplaneType: telemetry.SegmentRoutingTypes_SrDataplaneType_IPV6,
+		Ipv6Prefixes:  []string{"2001:db8:a::/48"},
+	}
+	if diff := cmp.Diff(want, ni.GetSegmentRouting().GetSrgb("loc")); diff != "" {
+		t.Errorf("Configure got diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestSegmentsLeft(t *testing.T) {
+	path := []Segment{
+		{End, "2001:db8:a:100::"},
+		{End, "2001:db8:b:100::"},
+		{EndDT4, "2001:db8:c:200::"},
+	}
+	if got, want := SIDs(path), []string{"2001:db8:a:100::", "2001:db8:b:100::", "2001:db8:c:200::"}; !cmp.Equal(got, want) {
+		t.Errorf("SIDs got %v, want %v", got, want)
+	}
+	for i, want := range []int{1, 0, 0} {
+		if got := SegmentsLeft(path, i); got != want {
+			t.Errorf("SegmentsLeft after segment %d got %d, want %d", i, got, want)
+		}
+	}
+}
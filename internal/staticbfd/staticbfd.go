@@ -0,0 +1,115 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package staticbfd provides helpers for static routes whose next hops are
+// tracked by BFD sessions to ATE neighbors: their configuration, the
+// detection time of the sessions, and the verification that the DUT
+// withdraws a next hop which stops responding within it.
+//
+// OpenConfig models enabling BFD on the next hops of static routes, but not
+// the timers of the BFD sessions, and the ATE API has no BFD, so the
+// sessions of the ATE neighbors are expected to be configured out of band,
+// see -deviation_bfd_interval.  A neighbor stops responding while its link
+// stays up when the degrader of the testbed blackholes the link.
+package staticbfd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/recursion"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// NextHop is a next hop of a static route.  The DUT prefers the next hops
+// of the lowest preference, and a zero preference is the default of the
+// DUT.
+type NextHop struct {
+	Address    string
+	Preference uint32
+	BFD        bool
+}
+
+// Static returns a static route to the prefix through the next hops,
+// indexed from 1 in order.
+func Static(prefix string, nextHops ...NextHop) *telemetry.NetworkInstance_Protocol_Static {
+	s := &telemetry.NetworkInstance_Protocol_Static{Prefix: ygot.String(prefix)}
+	for i, nh := range nextHops {
+		n := s.GetOrCreateNextHop(fmt.Sprint(i + 1))
+		n.NextHop = telemetry.UnionString(nh.Address)
+		if nh.Preference > 0 {
+			n.Preference = ygot.Uint32(nh.Preference)
+		}
+		n.GetOrCreateEnableBfd().Enabled = ygot.Bool(nh.BFD)
+	}
+	return s
+}
+
+// DetectionTime returns the time a BFD session takes to detect that its
+// neighbor stopped responding: the multiplier times the interval.
+func DetectionTime(interval time.Duration, multiplier uint) time.Duration {
+	return interval * time.Duration(multiplier)
+}
+
+// Require skips the test unless BFD sessions to the ATE neighbors are
+// configured out of band, and returns their detection time.
+func Require(t testing.TB) time.Duration {
+	t.Helper()
+	if *deviations.BFDInterval <= 0 || *deviations.BFDMultiplier == 0 {
+		t.Skip("BFD is not configured, see -deviation_bfd_interval and -deviation_bfd_multiplier")
+	}
+	return DetectionTime(*deviations.BFDInterval, *deviations.BFDMultiplier)
+}
+
+// CheckFailover checks that the traffic through a next hop which stopped
+// responding failed over within the detection time of its BFD session,
+// give or take the tolerance, given the loss window of the traffic.
+func CheckFailover(lossWindow, detection, tolerance time.Duration) error {
+	if max := detection + tolerance; lossWindow > max {
+		return fmt.Errorf("loss window of %v, want at most %v for a BFD detection time of %v", lossWindow, max, detection)
+	}
+	return nil
+}
+
+// pollInterval is how often the AFT entry of a prefix is read while
+// awaiting its next hops.
+var pollInterval = time.Second
+
+// AwaitNextHops waits for the AFT entry of the prefix to be resolved to
+// the next hop addresses, sorted, and returns an error with the last entry
+// read if it is not within the timeout.
+func AwaitNextHops(t testing.TB, dut *ondatra.DUTDevice, prefix string, want []string, timeout time.Duration) error {
+	t.Helper()
+	var last string
+	for deadline := time.Now().Add(timeout); ; time.Sleep(pollInterval) {
+		e, err := recursion.Read(t, dut, prefix)
+		switch {
+		case err != nil:
+			last = err.Error()
+		case cmp.Equal(e.NextHops, want):
+			return nil
+		default:
+			last = fmt.Sprintf("next hops %v", e.NextHops)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("AFT entry of %s not resolved to %v after %v: %s", prefix, want, timeout, last)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package staticbfd
+
+import (
+	"testing"
+	"time"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestStatic(t *testing.T) {
+	s := Static("198.51.100.0/24",
+		NextHop{Address: "192.0.2.6", Preference: 10, BFD: true},
+		NextHop{Address: "192.0.2.10"},
+	)
+	if got := s.GetPrefix(); got != "198.51.100.0/24" {
+		t.Errorf("Prefix got %q, want 198.51.100.0/24", got)
+	}
+	for _, tc := range []struct {
+		index      string
+		addr       string
+		preference uint32
+		bfd        bool
+	}{
+		{"1", "192.0.2.6", 10, true},
+		{"2", "192.0.2.10", 0, false},
+	} {
+		nh := s.GetNextHop(tc.index)
+		if nh == nil {
+			t.Fatalf("Next hop %s missing", tc.index)
+		}
+		if got := nh.NextHop; got != telemetry.UnionString(tc.addr) {
+			t.Errorf("Next hop %s got %v, want %s", tc.index, got, tc.addr)
+		}
+		switch {
+		case tc.preference == 0 && nh.Preference != nil:
+			t.Errorf("Next hop %s preference got %d, want unset", tc.index, nh.GetPreference())
+		case nh.GetPreference() != tc.preference:
+			t.Errorf("Next hop %s preference got %d, want %d", tc.index, nh.GetPreference(), tc.preference)
+		}
+		if got := nh.GetEnableBfd().GetEnabled(); got != tc.bfd {
+			t.Errorf("Next hop %s BFD enabled got %t, want %t", tc.index, got, tc.bfd)
+		}
+	}
+}
+
+func TestDetectionTime(t *testing.T) {
+	if got, want := DetectionTime(300*time.Millisecond, 3), 900*time.Millisecond; got != want {
+		t.Errorf("DetectionTime got %v, want %v", got, want)
+	}
+}
+
+func TestCheckFailover(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		window  time.Duration
+		wantErr bool
+	}{
+		{"within detection", 800 * time.Millisecond, false},
+		{"within tolerance", 1100 * time.Millisecond, false},
+		{"beyond tolerance", 1200 * time.Millisecond, true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := CheckFailover(tc.window, 900*time.Millisecond, 200*time.Millisecond)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("CheckFailover got %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
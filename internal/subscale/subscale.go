@@ -0,0 +1,282 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package subscale provides a harness opening many concurrent gNMI
+// Subscribe RPCs to a DUT, sampling sets of paths, and measuring the
+// update throughput and the samples the DUT dropped.
+//
+// Each client subscribes in STREAM mode with SAMPLE subscriptions.  The
+// leaves of its initial sync are the leaves it expects to be sampled
+// once per sample interval for the rest of the run; the samples short of
+// that are counted as dropped.
+package subscale
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// Config is the configuration of a run.
+type Config struct {
+	// Clients is the number of concurrent Subscribe RPCs.
+	Clients int
+	// Paths are the paths each client subscribes to.
+	Paths          []*gpb.Path
+	SampleInterval time.Duration
+	// Duration is how long the clients stream after their initial sync.
+	Duration time.Duration
+}
+
+// ParsePaths parses paths in their string form, e.g.
+// "/interfaces/interface[name=*]/state/counters".
+func ParsePaths(paths []string) ([]*gpb.Path, error) {
+	var ps []*gpb.Path
+	for _, s := range paths {
+		p, err := ygot.StringToStructuredPath(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %q: %w", s, err)
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// request returns the Subscribe request of the configuration.
+func (c *Config) request() *gpb.SubscribeRequest {
+	list := &gpb.SubscriptionList{
+		Mode:     gpb.SubscriptionList_STREAM,
+		Encoding: gpb.Encoding_PROTO,
+	}
+	for _, p := range c.Paths {
+		list.Subscription = append(list.Subscription, &gpb.Subscription{
+			Path:           p,
+			Mode:           gpb.SubscriptionMode_SAMPLE,
+			SampleInterval: uint64(c.SampleInterval.Nanoseconds()),
+		})
+	}
+	return &gpb.SubscribeRequest{Request: &gpb.SubscribeRequest_Subscribe{Subscribe: list}}
+}
+
+// Stats are the statistics of a client.
+type Stats struct {
+	Client int
+	// Notifications and Updates count all the notifications and updates
+	// received, including the initial sync.
+	Notifications uint64
+	Updates       uint64
+	// SyncLatency is how long the initial sync took, if Synced.
+	Synced      bool
+	SyncLatency time.Duration
+	// Err is the error that ended the subscription early, if any.
+	Err error
+
+	start time.Time
+	// samples counts the samples of each leaf of the initial sync after
+	// the sync.
+	samples map[string]uint64
+}
+
+func newStats(client int, start time.Time) *Stats {
+	return &Stats{Client: client, start: start, samples: map[string]uint64{}}
+}
+
+// add accounts for a response received at the time.
+func (s *Stats) add(resp *gpb.SubscribeResponse, at time.Time) {
+	switch r := resp.GetResponse().(type) {
+	case *gpb.SubscribeResponse_SyncResponse:
+		s.Synced = true
+		s.SyncLatency = at.Sub(s.start)
+	case *gpb.SubscribeResponse_Update:
+		s.Notifications++
+		for _, u := range r.Update.GetUpdate() {
+			s.Updates++
+			leaf := leafKey(r.Update.GetPrefix(), u.GetPath())
+			if n, ok := s.samples[leaf]; ok && s.Synced {
+				s.samples[leaf] = n + 1
+			} else if !s.Synced {
+				s.samples[leaf] = 0
+			}
+		}
+	}
+}
+
+// leafKey returns the path of an update, joined with the prefix of its
+// notification, as a string.
+func leafKey(prefix, path *gpb.Path) string {
+	p := &gpb.Path{Origin: prefix.GetOrigin()}
+	p.Elem = append(append(p.Elem, prefix.GetElem()...), path.GetElem()...)
+	s, err := ygot.PathToString(p)
+	if err != nil {
+		return p.String()
+	}
+	return s
+}
+
+// Leaves returns the number of leaves of the initial sync.
+func (s *Stats) Leaves() int {
+	return len(s.samples)
+}
+
+// Missed returns the samples the client did not receive out of the ones
+// expected after the sync.  One sample per leaf is allowed to be missing
+// for the boundaries of the run.
+func (s *Stats) Missed(expected uint64) uint64 {
+	var missed uint64
+	for _, n := range s.samples {
+		if n+1 < expected {
+			missed += expected - 1 - n
+		}
+	}
+	return missed
+}
+
+// Expected returns the number of samples of a leaf expected over the
+// duration.
+func Expected(duration, interval time.Duration) uint64 {
+	if interval <= 0 {
+		return 0
+	}
+	return uint64(duration / interval)
+}
+
+// Summary summarizes the statistics of the clients of a run.
+type Summary struct {
+	Clients  int
+	Failed   int
+	Unsynced int
+	Updates  uint64
+	// UpdatesPerSecond is the update throughput of all the clients over
+	// the duration of the run.
+	UpdatesPerSecond float64
+	// Expected and Missed count the samples after the sync.
+	Expected       uint64
+	Missed         uint64
+	MaxSyncLatency time.Duration
+}
+
+// Summarize summarizes the statistics of the clients of a run of the
+// configuration.
+func Summarize(stats []*Stats, c *Config) *Summary {
+	sum := &Summary{Clients: len(stats)}
+	perLeaf := Expected(c.Duration, c.SampleInterval)
+	for _, s := range stats {
+		if s.Err != nil {
+			sum.Failed++
+		}
+		if !s.Synced {
+			sum.Unsynced++
+			continue
+		}
+		sum.Updates += s.Updates
+		sum.Expected += perLeaf * uint64(s.Leaves())
+		sum.Missed += s.Missed(perLeaf)
+		if s.SyncLatency > sum.MaxSyncLatency {
+			sum.MaxSyncLatency = s.SyncLatency
+		}
+	}
+	if c.Duration > 0 {
+		sum.UpdatesPerSecond = float64(sum.Updates) / c.Duration.Seconds()
+	}
+	return sum
+}
+
+// DropPercent returns the percentage of the expected samples missed.
+func (s *Summary) DropPercent() float64 {
+	if s.Expected == 0 {
+		return 0
+	}
+	return 100 * float64(s.Missed) / float64(s.Expected)
+}
+
+func (s *Summary) String() string {
+	return fmt.Sprintf("%d clients (%d failed, %d not synced): %d updates, %.1f updates/s, %d of %d samples missed (%.2f%%), sync in at most %v",
+		s.Clients, s.Failed, s.Unsynced, s.Updates, s.UpdatesPerSecond, s.Missed, s.Expected, s.DropPercent(), s.MaxSyncLatency)
+}
+
+// Run opens the Subscribe RPCs of the configuration to the DUT, each on
+// its own gNMI client, and returns their statistics once they streamed
+// for the duration.  Each client has the duration plus syncTimeout to
+// complete its initial sync and stream.
+func Run(t testing.TB, dut *ondatra.DUTDevice, c *Config, syncTimeout time.Duration) []*Stats {
+	t.Helper()
+	clients := make([]gpb.GNMIClient, c.Clients)
+	for i := range clients {
+		clients[i] = dut.RawAPIs().GNMI().New(t)
+	}
+
+	stats := make([]*Stats, c.Clients)
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client gpb.GNMIClient) {
+			defer wg.Done()
+			stats[i] = subscribe(client, i, c, syncTimeout)
+		}(i, client)
+	}
+	wg.Wait()
+	return stats
+}
+
+// subscribe subscribes with the client until it streamed for the
+// duration after its sync, or until the sync timed out.
+func subscribe(client gpb.GNMIClient, i int, c *Config, syncTimeout time.Duration) *Stats {
+	start := time.Now()
+	s := newStats(i, start)
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout+c.Duration)
+	defer cancel()
+	sub, err := client.Subscribe(ctx)
+	if err != nil {
+		s.Err = err
+		return s
+	}
+	if err := sub.Send(c.request()); err != nil {
+		s.Err = err
+		return s
+	}
+	var end time.Time
+	for {
+		resp, err := sub.Recv()
+		now := time.Now()
+		switch {
+		case err == io.EOF:
+			s.Err = fmt.Errorf("subscription ended after %v", now.Sub(start))
+			return s
+		case err != nil && ctx.Err() != nil && s.Synced:
+			// Canceled at the end of the run.
+			return s
+		case err != nil:
+			s.Err = err
+			return s
+		}
+		s.add(resp, now)
+		if s.Synced && end.IsZero() {
+			end = now.Add(c.Duration)
+			// Stop at the end of the duration rather than at the
+			// deadline of the context.
+			go func() {
+				time.Sleep(time.Until(end))
+				cancel()
+			}()
+		}
+	}
+}
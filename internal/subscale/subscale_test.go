@@ -0,0 +1,149 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subscale
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// notification returns a response updating the counters of interfaces.
+func notification(intfs ...string) *gpb.SubscribeResponse {
+	n := &gpb.Notification{Prefix: &gpb.Path{Origin: "openconfig", Elem: []*gpb.PathElem{{Name: "interfaces"}}}}
+	for _, i := range intfs {
+		n.Update = append(n.Update, &gpb.Update{Path: &gpb.Path{Elem: []*gpb.PathElem{
+			{Name: "interface", Key: map[string]string{"name": i}},
+			{Name: "state"}, {Name: "counters"}, {Name: "in-pkts"},
+		}}})
+	}
+	return &gpb.SubscribeResponse{Response: &gpb.SubscribeResponse_Update{Update: n}}
+}
+
+var syncResponse = &gpb.SubscribeResponse{Response: &gpb.SubscribeResponse_SyncResponse{SyncResponse: true}}
+
+func TestParsePaths(t *testing.T) {
+	ps, err := ParsePaths([]string{"/interfaces/interface[name=*]/state/counters", "/components/component/state"})
+	if err != nil {
+		t.Fatalf("ParsePaths() got error %v", err)
+	}
+	if got := len(ps); got != 2 {
+		t.Fatalf("ParsePaths() got %d paths, want 2", got)
+	}
+	if got, want := ps[0].GetElem()[1].GetKey()["name"], "*"; got != want {
+		t.Errorf("ParsePaths() got key %q, want %q", got, want)
+	}
+	if _, err := ParsePaths([]string{"/interfaces/interface[name=*"}); err == nil {
+		t.Errorf("ParsePaths() of an invalid path got no error")
+	}
+}
+
+func TestRequest(t *testing.T) {
+	ps, err := ParsePaths([]string{"/interfaces", "/components"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{Paths: ps, SampleInterval: 10 * time.Second}
+	list := c.request().GetSubscribe()
+	if got, want := list.GetMode(), gpb.SubscriptionList_STREAM; got != want {
+		t.Errorf("request() mode got %v, want %v", got, want)
+	}
+	if got := len(list.GetSubscription()); got != 2 {
+		t.Fatalf("request() got %d subscriptions, want 2", got)
+	}
+	for _, s := range list.GetSubscription() {
+		if s.GetMode() != gpb.SubscriptionMode_SAMPLE || s.GetSampleInterval() != 10e9 {
+			t.Errorf("request() subscription got mode %v every %dns, want SAMPLE every 10s", s.GetMode(), s.GetSampleInterval())
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	start := time.Unix(1000, 0)
+	s := newStats(0, start)
+	s.add(notification("eth0", "eth1"), start.Add(time.Second))
+	s.add(syncResponse, start.Add(2*time.Second))
+	for i := 0; i < 10; i++ {
+		s.add(notification("eth0"), start.Add(time.Duration(3+i)*time.Second))
+	}
+	for i := 0; i < 6; i++ {
+		// eth2 was not in the initial sync, so it is not expected.
+		s.add(notification("eth1", "eth2"), start.Add(time.Duration(3+i)*time.Second))
+	}
+
+	if !s.Synced || s.SyncLatency != 2*time.Second {
+		t.Errorf("Stats got synced %t after %v, want synced after 2s", s.Synced, s.SyncLatency)
+	}
+	if got, want := s.Notifications, uint64(17); got != want {
+		t.Errorf("Stats got %d notifications, want %d", got, want)
+	}
+	if got, want := s.Updates, uint64(24); got != want {
+		t.Errorf("Stats got %d updates, want %d", got, want)
+	}
+	if got := s.Leaves(); got != 2 {
+		t.Errorf("Leaves() got %d, want 2", got)
+	}
+	// eth0 got all 10 samples, and eth1 6, one short of 9 being allowed.
+	if got, want := s.Missed(10), uint64(3); got != want {
+		t.Errorf("Missed(10) got %d, want %d", got, want)
+	}
+}
+
+func TestExpected(t *testing.T) {
+	if got := Expected(time.Minute, 10*time.Second); got != 6 {
+		t.Errorf("Expected(1m, 10s) got %d, want 6", got)
+	}
+	if got := Expected(time.Minute, 0); got != 0 {
+		t.Errorf("Expected(1m, 0) got %d, want 0", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	c := &Config{Clients: 3, SampleInterval: 10 * time.Second, Duration: 100 * time.Second}
+	synced := &Stats{
+		Synced:      true,
+		SyncLatency: 3 * time.Second,
+		Updates:     500,
+		samples:     map[string]uint64{"a": 10, "b": 4},
+	}
+	slow := &Stats{
+		Synced:      true,
+		SyncLatency: 8 * time.Second,
+		Updates:     300,
+		samples:     map[string]uint64{"a": 9},
+	}
+	failed := &Stats{Err: errors.New("connection refused")}
+	got := Summarize([]*Stats{synced, slow, failed}, c)
+	want := &Summary{
+		Clients:          3,
+		Failed:           1,
+		Unsynced:         1,
+		Updates:          800,
+		UpdatesPerSecond: 8,
+		Expected:         30,
+		Missed:           5,
+		MaxSyncLatency:   8 * time.Second,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Summarize() -want, +got:\n%s", diff)
+	}
+	if got, want := got.DropPercent(), 100*5/30.0; got != want {
+		t.Errorf("DropPercent() got %g, want %g", got, want)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package swpkg provides helpers to transfer software packages to the DUT
+// through gNOI System.SetPackage, either streaming their contents to the
+// DUT or having the DUT download them from a remote location, and to
+// verify that the DUT rejects a package whose hash does not match.
+//
+// Usage:
+//
+//	p := &swpkg.Package{Filename: "/var/tmp/pkg.bin", Version: "1.2.3"}
+//	hash, err := swpkg.Hash(tpb.HashType_SHA256, contents)
+//	...
+//	reqs, err := p.Requests(contents, hash)
+//	...
+//	if err := swpkg.Send(ctx, dut.RawAPIs().GNOI().Default(t).System(), reqs); err != nil {
+//	  t.Errorf("SetPackage failed: %v", err)
+//	}
+package swpkg
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"net/url"
+
+	commonpb "github.com/openconfig/gnoi/common"
+	spb "github.com/openconfig/gnoi/system"
+	tpb "github.com/openconfig/gnoi/types"
+)
+
+// ChunkSize is the largest chunk of the contents of a package that a
+// SetPackage request carries.
+const ChunkSize = 64 * 1024
+
+// Hash returns the hash of the contents with the method.
+func Hash(method tpb.HashType_HashMethod, contents []byte) (*tpb.HashType, error) {
+	var h hash.Hash
+	switch method {
+	case tpb.HashType_SHA256:
+		h = sha256.New()
+	case tpb.HashType_SHA512:
+		h = sha512.New()
+	case tpb.HashType_MD5:
+		h = md5.New()
+	default:
+		return nil, fmt.Errorf("unsupported hash method %v", method)
+	}
+	h.Write(contents)
+	return &tpb.HashType{Method: method, Hash: h.Sum(nil)}, nil
+}
+
+// Corrupt returns a copy of the hash with all its bits flipped, which the
+// contents it was computed from do not match.
+func Corrupt(h *tpb.HashType) *tpb.HashType {
+	b := make([]byte, len(h.GetHash()))
+	for i, v := range h.GetHash() {
+		b[i] = ^v
+	}
+	return &tpb.HashType{Method: h.GetMethod(), Hash: b}
+}
+
+// Package is a software package, as stored on the DUT.
+type Package struct {
+	// Filename is the path of the package on the DUT.
+	Filename string
+	Version  string
+	// Activate makes the package the one the DUT boots from next.
+	Activate bool
+}
+
+// proto returns the package as a SetPackage request.
+func (p *Package) proto(hash *tpb.HashType) (*spb.Package, error) {
+	if p.Filename == "" {
+		return nil, fmt.Errorf("package without a filename")
+	}
+	if len(hash.GetHash()) == 0 {
+		return nil, fmt.Errorf("package %s without a hash", p.Filename)
+	}
+	return &spb.Package{Filename: p.Filename, Version: p.Version, Activate: p.Activate}, nil
+}
+
+// Requests returns the SetPackage requests transferring the contents of
+// the package to the DUT: the package, its contents in chunks of at most
+// ChunkSize, and the hash.
+func (p *Package) Requests(contents []byte, hash *tpb.HashType) ([]*spb.SetPackageRequest, error) {
+	pkg, err := p.proto(hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("package %s without contents", p.Filename)
+	}
+	reqs := []*spb.SetPackageRequest{{Request: &spb.SetPackageRequest_Package{Package: pkg}}}
+	for start := 0; start < len(contents); start += ChunkSize {
+		end := start + ChunkSize
+		if end > len(contents) {
+			end = len(contents)
+		}
+		reqs = append(reqs, &spb.SetPackageRequest{Request: &spb.SetPackageRequest_Contents{Contents: contents[start:end]}})
+	}
+	return append(reqs, &spb.SetPackageRequest{Request: &spb.SetPackageRequest_Hash{Hash: hash}}), nil
+}
+
+// Remote is a remote location the DUT downloads a package from.
+type Remote struct {
+	// Path is the location in the form gNOI expects: the URL without
+	// its scheme for HTTP and HTTPS, and host:/path for SFTP and SCP.
+	Path               string
+	Protocol           commonpb.RemoteDownload_Protocol
+	Username, Password string
+}
+
+// ParseRemote returns the remote location of a URL with the scheme
+// http, https, sftp or scp, and optionally the credentials.
+func ParseRemote(rawURL string) (*Remote, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("bad URL: %w", err)
+	}
+	if u.Host == "" || u.Path == "" {
+		return nil, fmt.Errorf("URL %s without a host or a path", u.Redacted())
+	}
+	r := &Remote{}
+	switch u.Scheme {
+	case "http", "https":
+		r.Protocol = commonpb.RemoteDownload_HTTP
+		if u.Scheme == "https" {
+			r.Protocol = commonpb.RemoteDownload_HTTPS
+		}
+		r.Path = u.Host + u.EscapedPath()
+		if u.RawQuery != "" {
+			r.Path += "?" + u.RawQuery
+		}
+	case "sftp", "scp":
+		r.Protocol = commonpb.RemoteDownload_SFTP
+		if u.Scheme == "scp" {
+			r.Protocol = commonpb.RemoteDownload_SCP
+		}
+		r.Path = u.Host + ":" + u.Path
+	default:
+		return nil, fmt.Errorf("URL %s with unsupported scheme %q", u.Redacted(), u.Scheme)
+	}
+	if u.User != nil {
+		r.Username = u.User.Username()
+		r.Password, _ = u.User.Password()
+	}
+	return r, nil
+}
+
+func (r *Remote) String() string {
+	return fmt.Sprintf("%v %s", r.Protocol, r.Path)
+}
+
+// RemoteRequests returns the SetPackage requests having the DUT download
+// the package from the remote location: the package, and the hash.
+func (p *Package) RemoteRequests(r *Remote, hash *tpb.HashType) ([]*spb.SetPackageRequest, error) {
+	pkg, err := p.proto(hash)
+	if err != nil {
+		return nil, err
+	}
+	if r.Path == "" {
+		return nil, fmt.Errorf("remote location of package %s without a path", p.Filename)
+	}
+	pkg.RemoteDownload = &commonpb.RemoteDownload{Path: r.Path, Protocol: r.Protocol}
+	if r.Username != "" {
+		pkg.RemoteDownload.Credentials = &tpb.Credentials{
+			Username: r.Username,
+			Password: &tpb.Credentials_Cleartext{Cleartext: r.Password},
+		}
+	}
+	return []*spb.SetPackageRequest{
+		{Request: &spb.SetPackageRequest_Package{Package: pkg}},
+		{Request: &spb.SetPackageRequest_Hash{Hash: hash}},
+	}, nil
+}
+
+// Send sends the requests on a SetPackage stream of the client, and
+// returns the error of the response, which is nil once the DUT stored the
+// package and verified its hash.
+func Send(ctx context.Context, c spb.SystemClient, reqs []*spb.SetPackageRequest) error {
+	stream, err := c.SetPackage(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot start SetPackage: %w", err)
+	}
+	for _, req := range reqs {
+		// Once the DUT rejects the package, sending fails and the reason is
+		// the error of the response.
+		if err := stream.Send(req); err != nil {
+			break
+		}
+	}
+	if _, err := stream.CloseAndRecv(); err != nil {
+		return fmt.Errorf("SetPackage rejected: %w", err)
+	}
+	return nil
+}
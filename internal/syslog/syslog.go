@@ -0,0 +1,328 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package syslog provides helpers to configure the remote syslog servers
+// of a device, and a collector which receives its messages over TLS
+// (RFC 5425) so that a test can verify their facility and severity.
+//
+// OpenConfig does not model the TLS transport of the remote servers, so
+// the device must be provisioned out of band to send to the TLS port and
+// to trust the certificate of the collector.
+package syslog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// TLSPort is the port of syslog over TLS (RFC 5425).
+const TLSPort = 6514
+
+// RemoteServer returns a remote syslog server sending to the port of the
+// host the messages of the facility at the severity or more severe.
+func RemoteServer(host string, port uint16, facility telemetry.E_SystemLogging_SYSLOG_FACILITY, severity telemetry.E_SystemLogging_SyslogSeverity) *telemetry.System_Logging_RemoteServer {
+	rs := &telemetry.System_Logging_RemoteServer{
+		Host:       ygot.String(host),
+		RemotePort: ygot.Uint16(port),
+	}
+	rs.GetOrCreateSelector(facility, severity)
+	return rs
+}
+
+// facilities maps the facility codes of RFC 5424 to OpenConfig.
+var facilities = map[int]telemetry.E_SystemLogging_SYSLOG_FACILITY{
+	0:  telemetry.SystemLogging_SYSLOG_FACILITY_KERNEL,
+	1:  telemetry.SystemLogging_SYSLOG_FACILITY_USER,
+	2:  telemetry.SystemLogging_SYSLOG_FACILITY_MAIL,
+	3:  telemetry.SystemLogging_SYSLOG_FACILITY_SYSTEM_DAEMON,
+	4:  telemetry.SystemLogging_SYSLOG_FACILITY_AUTH,
+	5:  telemetry.SystemLogging_SYSLOG_FACILITY_SYSLOG,
+	10: telemetry.SystemLogging_SYSLOG_FACILITY_AUTHPRIV,
+	12: telemetry.SystemLogging_SYSLOG_FACILITY_NTP,
+	13: telemetry.SystemLogging_SYSLOG_FACILITY_AUDIT,
+	14: telemetry.SystemLogging_SYSLOG_FACILITY_CONSOLE,
+	16: telemetry.SystemLogging_SYSLOG_FACILITY_LOCAL0,
+	17: telemetry.SystemLogging_SYSLOG_FACILITY_LOCAL1,
+	18: telemetry.SystemLogging_SYSLOG_FACILITY_LOCAL2,
+	19: telemetry.SystemLogging_SYSLOG_FACILITY_LOCAL3,
+	20: telemetry.SystemLogging_SYSLOG_FACILITY_LOCAL4,
+	21: telemetry.SystemLogging_SYSLOG_FACILITY_LOCAL5,
+	22: telemetry.SystemLogging_SYSLOG_FACILITY_LOCAL6,
+	23: telemetry.SystemLogging_SYSLOG_FACILITY_LOCAL7,
+}
+
+// Message is a syslog message received by the collector.
+type Message struct {
+	// Facility is UNSET if OpenConfig does not model the facility.
+	Facility telemetry.E_SystemLogging_SYSLOG_FACILITY
+	Severity telemetry.E_SystemLogging_SyslogSeverity
+	// Hostname and AppName are only parsed from RFC 5424 messages.
+	Hostname string
+	AppName  string
+	// Text is the rest of the message after its header.
+	Text string
+}
+
+// AtLeast returns whether the message is at the severity or more severe.
+func (m *Message) AtLeast(severity telemetry.E_SystemLogging_SyslogSeverity) bool {
+	return m.Severity <= severity
+}
+
+// Parse parses an RFC 5424 message, or an RFC 3164 message of which only
+// the priority is parsed.
+func Parse(raw string) (*Message, error) {
+	if !strings.HasPrefix(raw, "<") {
+		return nil, fmt.Errorf("message %q does not start with a priority", raw)
+	}
+	end := strings.IndexByte(raw, '>')
+	if end < 2 || end > 4 {
+		return nil, fmt.Errorf("message %q has no valid priority", raw)
+	}
+	pri, err := strconv.Atoi(raw[1:end])
+	if err != nil || pri > 191 {
+		return nil, fmt.Errorf("message %q has no valid priority", raw)
+	}
+	m := &Message{
+		Facility: facilities[pri/8],
+		// The OpenConfig severities are the codes of RFC 5424 plus one.
+		Severity: telemetry.E_SystemLogging_SyslogSeverity(pri%8 + 1),
+		Text:     raw[end+1:],
+	}
+	if !strings.HasPrefix(m.Text, "1 ") {
+		return m, nil
+	}
+	// VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG],
+	// where the structured data is assumed to have no spaces.
+	f := strings.SplitN(m.Text, " ", 8)
+	if len(f) < 7 {
+		return nil, fmt.Errorf("RFC 5424 message %q has %d header fields, want 7", raw, len(f))
+	}
+	m.Hostname, m.AppName, m.Text = f[2], f[3], ""
+	if len(f) == 8 {
+		m.Text = f[7]
+	}
+	return m, nil
+}
+
+// readFrame reads a message framed by octet counting, as required by
+// RFC 5425, or by a trailing LF, as commonly sent over TCP (RFC 6587).
+func readFrame(r *bufio.Reader) (string, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+	if b[0] < '0' || b[0] > '9' {
+		line, err := r.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	l, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(l, " "))
+	if err != nil {
+		return "", fmt.Errorf("invalid frame length %q: %w", l, err)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// Collector receives syslog messages over TLS, or over plain TCP if it
+// was created without a TLS configuration.
+type Collector struct {
+	ln net.Listener
+	wg sync.WaitGroup
+
+	mu    sync.Mutex
+	msgs  []*Message
+	errs  []error
+	conns map[net.Conn]bool
+}
+
+// NewCollector starts a collector listening on the address.
+func NewCollector(addr string, config *tls.Config) (*Collector, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		ln = tls.NewListener(ln, config)
+	}
+	c := &Collector{ln: ln, conns: map[net.Conn]bool{}}
+	c.wg.Add(1)
+	go c.serve()
+	return c, nil
+}
+
+// Addr returns the address the collector listens on.
+func (c *Collector) Addr() net.Addr {
+	return c.ln.Addr()
+}
+
+// Close stops the collector and closes its connections.
+func (c *Collector) Close() error {
+	err := c.ln.Close()
+	c.mu.Lock()
+	for conn := range c.conns {
+		conn.Close()
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+	return err
+}
+
+func (c *Collector) serve() {
+	defer c.wg.Done()
+	for {
+		conn, err := c.ln.Accept()
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		c.conns[conn] = true
+		c.mu.Unlock()
+		c.wg.Add(1)
+		go c.receive(conn)
+	}
+}
+
+func (c *Collector) receive(conn net.Conn) {
+	defer c.wg.Done()
+	defer func() {
+		c.mu.Lock()
+		delete(c.conns, conn)
+		c.mu.Unlock()
+		conn.Close()
+	}()
+	r := bufio.NewReader(conn)
+	for {
+		raw, err := readFrame(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				c.addError(fmt.Errorf("connection from %v: %w", conn.RemoteAddr(), err))
+			}
+			return
+		}
+		m, err := Parse(raw)
+		if err != nil {
+			c.addError(err)
+			continue
+		}
+		c.mu.Lock()
+		c.msgs = append(c.msgs, m)
+		c.mu.Unlock()
+	}
+}
+
+func (c *Collector) addError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+// Messages returns the messages received so far.
+func (c *Collector) Messages() []*Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Message(nil), c.msgs...)
+}
+
+// Errors returns the errors receiving or parsing messages so far.
+func (c *Collector) Errors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]error(nil), c.errs...)
+}
+
+// Await waits for a message matching the predicate, received after the
+// first skip messages.
+func (c *Collector) Await(skip int, timeout time.Duration, match func(*Message) bool) (*Message, error) {
+	for start := time.Now(); ; time.Sleep(100 * time.Millisecond) {
+		msgs := c.Messages()
+		for i := skip; i < len(msgs); i++ {
+			if match(msgs[i]) {
+				return msgs[i], nil
+			}
+		}
+		if time.Since(start) > timeout {
+			return nil, fmt.Errorf("no matching message among the %d received after %v", len(msgs)-skip, timeout)
+		}
+	}
+}
+
+// SelfSigned returns a TLS configuration with a self-signed certificate
+// for the hosts, which may be names or IP addresses, and the certificate
+// in PEM for the device to trust.
+func SelfSigned(hosts ...string) (*tls.Config, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "syslog collector"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return config, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
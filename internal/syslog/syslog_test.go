@@ -0,0 +1,153 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestRemoteServer(t *testing.T) {
+	got := RemoteServer("192.0.2.1", TLSPort, telemetry.SystemLogging_SYSLOG_FACILITY_ALL, telemetry.SystemLogging_SyslogSeverity_INFORMATIONAL)
+	want := &telemetry.System_Logging_RemoteServer{
+		Host:       ygot.String("192.0.2.1"),
+		RemotePort: ygot.Uint16(6514),
+	}
+	want.GetOrCreateSelector(telemetry.SystemLogging_SYSLOG_FACILITY_ALL, telemetry.SystemLogging_SyslogSeverity_INFORMATIONAL)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RemoteServer() -want, +got:\n%s", diff)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		raw     string
+		want    *Message
+		wantErr bool
+	}{{
+		desc: "RFC 5424",
+		raw:  "<38>1 2022-08-01T12:00:00Z dut sshd 42 - - Accepted publickey for admin",
+		want: &Message{
+			Facility: telemetry.SystemLogging_SYSLOG_FACILITY_AUTH,
+			Severity: telemetry.SystemLogging_SyslogSeverity_INFORMATIONAL,
+			Hostname: "dut",
+			AppName:  "sshd",
+			Text:     "Accepted publickey for admin",
+		},
+	}, {
+		desc: "RFC 3164",
+		raw:  "<189>Aug  1 12:00:00 dut ifmgr: Ethernet1 changed state to down",
+		want: &Message{
+			Facility: telemetry.SystemLogging_SYSLOG_FACILITY_LOCAL7,
+			Severity: telemetry.SystemLogging_SyslogSeverity_NOTICE,
+			Text:     "Aug  1 12:00:00 dut ifmgr: Ethernet1 changed state to down",
+		},
+	}, {
+		desc: "unmodeled facility",
+		raw:  "<72>cron job",
+		want: &Message{
+			Facility: telemetry.SystemLogging_SYSLOG_FACILITY_UNSET,
+			Severity: telemetry.SystemLogging_SyslogSeverity_EMERGENCY,
+			Text:     "cron job",
+		},
+	}, {
+		desc:    "no priority",
+		raw:     "hello",
+		wantErr: true,
+	}, {
+		desc:    "priority out of range",
+		raw:     "<192>hello",
+		wantErr: true,
+	}, {
+		desc:    "truncated RFC 5424 header",
+		raw:     "<38>1 2022-08-01T12:00:00Z dut",
+		wantErr: true,
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			got, err := Parse(c.raw)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("Parse(%q) got error %v, want error %v", c.raw, err, c.wantErr)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("Parse(%q) -want, +got:\n%s", c.raw, diff)
+			}
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	config, certPEM, err := SelfSigned("127.0.0.1")
+	if err != nil {
+		t.Fatalf("SelfSigned() got error: %v", err)
+	}
+	c, err := NewCollector("127.0.0.1:0", config)
+	if err != nil {
+		t.Fatalf("NewCollector() got error: %v", err)
+	}
+	defer c.Close()
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(certPEM) {
+		t.Fatalf("SelfSigned() returned an invalid certificate:\n%s", certPEM)
+	}
+	conn, err := tls.Dial("tcp", c.Addr().String(), &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatalf("Cannot connect to the collector: %v", err)
+	}
+	msgs := []string{
+		"<38>1 2022-08-01T12:00:00Z dut sshd 42 - - Accepted publickey for admin",
+		"<189>1 2022-08-01T12:00:01Z dut ifmgr 7 - - Ethernet1 changed state to down",
+	}
+	for _, m := range msgs {
+		if _, err := fmt.Fprintf(conn, "%d %s", len(m), m); err != nil {
+			t.Fatalf("Cannot send %q: %v", m, err)
+		}
+	}
+	if _, err := fmt.Fprint(conn, "<14>not framed by octet counting\n"); err != nil {
+		t.Fatalf("Cannot send a message framed by LF: %v", err)
+	}
+	conn.Close()
+
+	got, err := c.Await(0, 5*time.Second, func(m *Message) bool {
+		return strings.Contains(m.Text, "state to down")
+	})
+	if err != nil {
+		t.Fatalf("Await() got error: %v", err)
+	}
+	if got.Facility != telemetry.SystemLogging_SYSLOG_FACILITY_LOCAL7 || !got.AtLeast(telemetry.SystemLogging_SyslogSeverity_NOTICE) {
+		t.Errorf("Await() got facility %v severity %v, want LOCAL7 at least NOTICE", got.Facility, got.Severity)
+	}
+	if _, err := c.Await(0, 5*time.Second, func(m *Message) bool {
+		return m.Text == "not framed by octet counting"
+	}); err != nil {
+		t.Errorf("Await() message framed by LF got error: %v", err)
+	}
+	if _, err := c.Await(len(c.Messages()), 200*time.Millisecond, func(*Message) bool { return true }); err == nil {
+		t.Errorf("Await() skipping all the messages got no error")
+	}
+	if errs := c.Errors(); len(errs) != 0 {
+		t.Errorf("Errors() got %v, want none", errs)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package teservice composes a TE service for the failover tests: VIP
+// prefixes in a VRF, programmed by gRIBI through hierarchical entries onto
+// tunnel endpoints, whose next hop groups forward through a primary next
+// hop and fall back to a repair next hop through a backup next hop group.
+// The scale of the service is set by its numbers of VIPs and tunnels.
+//
+// The primary path of a service is failed by a Trigger, bringing its link
+// down or, for BFD triggered switchover, blackholing it while it stays up.
+//
+// Usage:
+//
+//	s, err := (&teservice.Spec{
+//	  NetworkInstance:    vrf,
+//	  NHGNetworkInstance: *deviations.DefaultNetworkInstance,
+//	  VIPs:               args.Scale(1000),
+//	  FirstVIP:           "198.51.100.0/32",
+//	  Tunnels:            16,
+//	  FirstTunnel:        "203.0.113.0/32",
+//	  Primary:            atePort2.IPv4,
+//	  Repair:             atePort3.IPv4,
+//	}).Generate()
+//	...
+//	s.Program(t, c, fluent.InstalledInRIB)
+//	tx, rx := teservice.Measure(t, counters, settle, trigger.Fail)
+package teservice
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/fibscale"
+	"github.com/openconfig/featureprofiles/internal/gribi"
+	"github.com/openconfig/gribigo/fluent"
+)
+
+// Spec is the compact description of a TE service.
+type Spec struct {
+	// NetworkInstance is the VRF of the VIPs.
+	NetworkInstance string
+	// NHGNetworkInstance is the network instance of the tunnel endpoints,
+	// and of all the next hops and next hop groups.
+	NHGNetworkInstance string
+	// VIPs is the number of VIPs, consecutive prefixes from FirstVIP,
+	// spread round robin across the tunnels.
+	VIPs     int
+	FirstVIP string
+	// Tunnels is the number of tunnel endpoints, consecutive /32
+	// addresses from FirstTunnel.
+	Tunnels     int
+	FirstTunnel string
+	// Primary and Repair are the addresses of the next hops of the
+	// primary and repair paths.
+	Primary string
+	Repair  string
+	// FirstIndex is the first index of the next hops and next hop groups
+	// of the service.  Defaults to 1.
+	FirstIndex uint64
+}
+
+// Tunnel is a tunnel endpoint of a service, and the VIPs it carries.
+type Tunnel struct {
+	// Address is the tunnel endpoint, routed by NHG through the primary
+	// next hop, backed up by the repair next hop.
+	Address string
+	NHG     uint64
+	// VIPNH is the next hop of the VIPs to the tunnel endpoint, in the
+	// next hop group VIPNHG.
+	VIPNH  uint64
+	VIPNHG uint64
+	VIPs   []string
+}
+
+// Service is a TE service generated from a spec, with the indices of its
+// entries.
+type Service struct {
+	NetworkInstance    string
+	NHGNetworkInstance string
+	PrimaryNH          uint64
+	RepairNH           uint64
+	RepairNHG          uint64
+	Primary            string
+	Repair             string
+	Tunnels            []*Tunnel
+}
+
+// validate returns an error if the spec is inconsistent.
+func (s *Spec) validate() error {
+	switch {
+	case s.NetworkInstance == "" || s.NHGNetworkInstance == "":
+		return fmt.Errorf("network instance and next hop group network instance are required")
+	case s.Tunnels < 1:
+		return fmt.Errorf("got %d tunnels, want at least 1", s.Tunnels)
+	case s.VIPs < s.Tunnels:
+		return fmt.Errorf("got %d VIPs, want at least one per tunnel, %d", s.VIPs, s.Tunnels)
+	case net.ParseIP(s.Primary) == nil || net.ParseIP(s.Repair) == nil:
+		return fmt.Errorf("invalid primary %q or repair %q next hop", s.Primary, s.Repair)
+	case s.Primary == s.Repair:
+		return fmt.Errorf("primary and repair next hops are both %s", s.Primary)
+	}
+	_, n, err := net.ParseCIDR(s.FirstTunnel)
+	if err != nil {
+		return fmt.Errorf("invalid first tunnel: %w", err)
+	}
+	if ones, bits := n.Mask.Size(); ones != 32 || bits != 32 {
+		return fmt.Errorf("first tunnel %s is not an IPv4 /32", s.FirstTunnel)
+	}
+	return nil
+}
+
+// Generate returns the service of the spec.  The primary and repair next
+// hops and the repair next hop group take the first indices, then the
+// next hop groups of the tunnels, then the next hops and next hop groups
+// of the VIPs of each tunnel.
+func (s *Spec) Generate() (*Service, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	vips, err := fibscale.Prefixes(s.FirstVIP, s.VIPs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate the VIPs: %w", err)
+	}
+	tunnels, err := fibscale.Prefixes(s.FirstTunnel, s.Tunnels)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate the tunnel endpoints: %w", err)
+	}
+	base := s.FirstIndex
+	if base == 0 {
+		base = 1
+	}
+	svc := &Service{
+		NetworkInstance:    s.NetworkInstance,
+		NHGNetworkInstance: s.NHGNetworkInstance,
+		PrimaryNH:          base,
+		RepairNH:           base + 1,
+		RepairNHG:          base,
+		Primary:            s.Primary,
+		Repair:             s.Repair,
+	}
+	n := uint64(s.Tunnels)
+	for i, p := range tunnels {
+		addr, _, _ := net.ParseCIDR(p)
+		svc.Tunnels = append(svc.Tunnels, &Tunnel{
+			Address: addr.String(),
+			NHG:     base + 1 + uint64(i),
+			VIPNH:   base + 2 + uint64(i),
+			VIPNHG:  base + 1 + n + uint64(i),
+		})
+	}
+	for i, vip := range vips {
+		tn := svc.Tunnels[i%s.Tunnels]
+		tn.VIPs = append(tn.VIPs, vip)
+	}
+	return svc, nil
+}
+
+// VIPs returns the VIPs of the service, by tunnel.
+func (s *Service) VIPs() []string {
+	var vips []string
+	for _, tn := range s.Tunnels {
+		vips = append(vips, tn.VIPs...)
+	}
+	return vips
+}
+
+// Program adds the entries of the service bottom up: the primary and
+// repair next hops and the repair next hop group, then for each tunnel,
+// the next hop group of its endpoint with its backup, its endpoint, and
+// the next hop, next hop group and VIPs, in a single batch, referencing
+// it.
+func (s *Service) Program(t testing.TB, c *gribi.Client, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	ni := s.NHGNetworkInstance
+	c.AddNH(t, s.PrimaryNH, s.Primary, ni, expectedResult)
+	c.AddNH(t, s.RepairNH, s.Repair, ni, expectedResult)
+	c.AddNHG(t, s.RepairNHG, map[uint64]uint64{s.RepairNH: 1}, ni, expectedResult)
+	for _, tn := range s.Tunnels {
+		c.AddNHGWithBackup(t, tn.NHG, map[uint64]uint64{s.PrimaryNH: 1}, s.RepairNHG, ni, expectedResult)
+		c.AddIPv4(t, tn.Address+"/32", tn.NHG, ni, "", expectedResult)
+		c.AddNH(t, tn.VIPNH, tn.Address, ni, expectedResult)
+		c.AddNHG(t, tn.VIPNHG, map[uint64]uint64{tn.VIPNH: 1}, ni, expectedResult)
+		c.AddIPv4Batch(t, tn.VIPs, tn.VIPNHG, s.NetworkInstance, ni, expectedResult)
+	}
+}
+
+// Delete deletes the entries of the service top down, in the reverse
+// order of Program.
+func (s *Service) Delete(t testing.TB, c *gribi.Client, expectedResult fluent.ProgrammingResult) {
+	t.Helper()
+	ni := s.NHGNetworkInstance
+	for i := len(s.Tunnels) - 1; i >= 0; i-- {
+		tn := s.Tunnels[i]
+		for _, vip := range tn.VIPs {
+			c.DeleteIPv4(t, vip, s.NetworkInstance, expectedResult)
+		}
+		c.DeleteNHG(t, tn.VIPNHG, ni, expectedResult)
+		c.DeleteNH(t, tn.VIPNH, ni, expectedResult)
+		c.DeleteIPv4(t, tn.Address+"/32", ni, expectedResult)
+		c.DeleteNHG(t, tn.NHG, ni, expectedResult)
+	}
+	c.DeleteNHG(t, s.RepairNHG, ni, expectedResult)
+	c.DeleteNH(t, s.RepairNH, ni, expectedResult)
+	c.DeleteNH(t, s.PrimaryNH, ni, expectedResult)
+}
+
+// Measure reads the counters of the traffic to the service, settles, runs
+// do, settles again and returns the packets sent and received in between,
+// so that the outage caused by do is attributed to it.
+func Measure(t testing.TB, counters gribi.Counters, settle time.Duration, do func(t testing.TB)) (txPkts, rxPkts uint64) {
+	t.Helper()
+	tx0, rx0 := counters(t)
+	time.Sleep(settle)
+	do(t)
+	time.Sleep(settle)
+	tx1, rx1 := counters(t)
+	return tx1 - tx0, rx1 - rx0
+}
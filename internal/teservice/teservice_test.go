@@ -0,0 +1,135 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newSpec() *Spec {
+	return &Spec{
+		NetworkInstance:    "VRF-TE",
+		NHGNetworkInstance: "DEFAULT",
+		VIPs:               5,
+		FirstVIP:           "198.51.100.0/32",
+		Tunnels:            2,
+		FirstTunnel:        "203.0.113.0/32",
+		Primary:            "192.0.2.6",
+		Repair:             "192.0.2.10",
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s, err := newSpec().Generate()
+	if err != nil {
+		t.Fatalf("Generate() got error: %v", err)
+	}
+	want := &Service{
+		NetworkInstance:    "VRF-TE",
+		NHGNetworkInstance: "DEFAULT",
+		PrimaryNH:          1,
+		RepairNH:           2,
+		RepairNHG:          1,
+		Primary:            "192.0.2.6",
+		Repair:             "192.0.2.10",
+		Tunnels: []*Tunnel{{
+			Address: "203.0.113.0",
+			NHG:     2,
+			VIPNH:   3,
+			VIPNHG:  4,
+			VIPs:    []string{"198.51.100.0/32", "198.51.100.2/32", "198.51.100.4/32"},
+		}, {
+			Address: "203.0.113.1",
+			NHG:     3,
+			VIPNH:   4,
+			VIPNHG:  5,
+			VIPs:    []string{"198.51.100.1/32", "198.51.100.3/32"},
+		}},
+	}
+	if diff := cmp.Diff(want, s); diff != "" {
+		t.Errorf("Generate() -want, +got:\n%s", diff)
+	}
+	wantVIPs := []string{"198.51.100.0/32", "198.51.100.2/32", "198.51.100.4/32", "198.51.100.1/32", "198.51.100.3/32"}
+	if diff := cmp.Diff(wantVIPs, s.VIPs()); diff != "" {
+		t.Errorf("VIPs() -want, +got:\n%s", diff)
+	}
+}
+
+func TestGenerateFirstIndex(t *testing.T) {
+	spec := newSpec()
+	spec.FirstIndex = 100
+	s, err := spec.Generate()
+	if err != nil {
+		t.Fatalf("Generate() got error: %v", err)
+	}
+	nhs := map[uint64]bool{s.PrimaryNH: true, s.RepairNH: true}
+	nhgs := map[uint64]bool{s.RepairNHG: true}
+	for _, tn := range s.Tunnels {
+		nhs[tn.VIPNH] = true
+		nhgs[tn.NHG] = true
+		nhgs[tn.VIPNHG] = true
+	}
+	if len(nhs) != 4 || len(nhgs) != 5 {
+		t.Errorf("Generate() got next hops %v and next hop groups %v, want 4 and 5 distinct indices", nhs, nhgs)
+	}
+	for id := range nhgs {
+		if id < 100 {
+			t.Errorf("Next hop group %d is below the first index 100", id)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, c := range []struct {
+		desc   string
+		modify func(s *Spec)
+	}{
+		{"no network instance", func(s *Spec) { s.NetworkInstance = "" }},
+		{"no tunnels", func(s *Spec) { s.Tunnels = 0 }},
+		{"fewer VIPs than tunnels", func(s *Spec) { s.VIPs = 1 }},
+		{"invalid primary", func(s *Spec) { s.Primary = "192.0.2" }},
+		{"same primary and repair", func(s *Spec) { s.Repair = s.Primary }},
+		{"invalid first tunnel", func(s *Spec) { s.FirstTunnel = "203.0.113.0" }},
+		{"first tunnel not a /32", func(s *Spec) { s.FirstTunnel = "203.0.113.0/30" }},
+		{"invalid first VIP", func(s *Spec) { s.FirstVIP = "198.51.100.1/24" }},
+		{"VIPs exhausted", func(s *Spec) { s.FirstVIP = "255.255.255.254/32" }},
+	} {
+		spec := newSpec()
+		c.modify(spec)
+		if _, err := spec.Generate(); err == nil {
+			t.Errorf("%s: Generate() got no error, want error", c.desc)
+		}
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	var tx, rx uint64
+	counters := func(t testing.TB) (uint64, uint64) { return tx, rx }
+	ran := false
+	gotTx, gotRx := Measure(t, counters, time.Millisecond, func(t testing.TB) {
+		ran = true
+		tx += 100
+		rx += 90
+	})
+	if !ran {
+		t.Error("Measure() did not run the trigger")
+	}
+	if gotTx != 100 || gotRx != 90 {
+		t.Errorf("Measure() got %d, %d, want 100, 90", gotTx, gotRx)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teservice
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/faults"
+	"github.com/openconfig/ondatra"
+)
+
+// Trigger fails and restores the primary path of a service.
+type Trigger interface {
+	fmt.Stringer
+	// Fail fails the primary path.  It returns once the failure is
+	// injected, which the DUT may not have detected yet.
+	Fail(t testing.TB)
+	// Restore removes the failure of Fail.
+	Restore(t testing.TB)
+}
+
+// LinkDown fails the primary path by bringing its link down with a fault
+// injector, and awaits the DUT port to be operationally down.
+type LinkDown struct {
+	Injector faults.Injector
+	DUT      *ondatra.DUTDevice
+	Link     faults.Link
+	Timeout  time.Duration
+}
+
+func (l *LinkDown) String() string {
+	return fmt.Sprintf("%s of %v", l.Injector.Kind(), l.Link)
+}
+
+// Fail brings the link down.
+func (l *LinkDown) Fail(t testing.TB) {
+	t.Helper()
+	faults.Down(t, l.Injector, l.DUT, l.Link, l.Timeout)
+}
+
+// Restore brings the link up.
+func (l *LinkDown) Restore(t testing.TB) {
+	t.Helper()
+	faults.Up(t, l.Injector, l.DUT, l.Link, l.Timeout)
+}
+
+// BFDDown fails the primary path by dropping all the traffic of its link,
+// which stays up, so that only a BFD session of the DUT to the primary
+// next hop detects the failure.  The OpenConfig models of this tree have
+// no BFD configuration, so the BFD session is configured out of band,
+// e.g. by the binding.
+type BFDDown struct {
+	Degrader faults.Degrader
+	Link     faults.Link
+}
+
+func (b *BFDDown) String() string {
+	return fmt.Sprintf("BFD blackhole of %v", b.Link)
+}
+
+// blackhole is the degradation of the link of a BFDDown.
+var blackhole = faults.Degradation{LossPct: 100}
+
+// Fail drops all the traffic of the link.  Unlike faults.Degrade, the
+// link is not restored when the test ends, as Fail and Restore usually
+// run in distinct subtests.
+func (b *BFDDown) Fail(t testing.TB) {
+	t.Helper()
+	t.Logf("Blackholing %v", b.Link)
+	if err := b.Degrader.Degrade(context.Background(), b.Link, blackhole); err != nil {
+		t.Fatalf("Cannot blackhole %v: %v", b.Link, err)
+	}
+}
+
+// Restore forwards the traffic of the link again.
+func (b *BFDDown) Restore(t testing.TB) {
+	t.Helper()
+	faults.Restore(t, b.Degrader, b.Link)
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/faults"
+)
+
+// fakeDegrader records the degradation of the links.
+type fakeDegrader struct {
+	degraded map[faults.Link]faults.Degradation
+}
+
+func (f *fakeDegrader) Degrade(_ context.Context, l faults.Link, d faults.Degradation) error {
+	f.degraded[l] = d
+	return nil
+}
+
+func (f *fakeDegrader) Restore(_ context.Context, l faults.Link) error {
+	delete(f.degraded, l)
+	return nil
+}
+
+func TestBFDDown(t *testing.T) {
+	dg := &fakeDegrader{degraded: map[faults.Link]faults.Degradation{}}
+	l := faults.Link{}
+	b := &BFDDown{Degrader: dg, Link: l}
+	b.Fail(t)
+	if got, ok := dg.degraded[l]; !ok || got.LossPct != 100 || got.Latency != 0 {
+		t.Errorf("Fail() degraded the link with %v, want a blackhole", got)
+	}
+	b.Restore(t)
+	if got, ok := dg.degraded[l]; ok {
+		t.Errorf("Restore() left the link degraded with %v", got)
+	}
+}
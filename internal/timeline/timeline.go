@@ -0,0 +1,340 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timeline records the events of a test on a common clock: the
+// steps of the test, the flow statistics of the ATE and the ON_CHANGE
+// telemetry of the DUT, and writes them as a single timeline to the
+// test outputs.  The timestamps of the devices are corrected by the
+// skew of their clocks from the clock of the test, estimated from
+// round trips, so that a convergence measurement can be traced event by
+// event across the devices.
+//
+// Usage:
+//
+//	tl := timeline.New()
+//	defer tl.Write(t)
+//	skew, err := timeline.EstimateGNMISkew(ctx, dut.RawAPIs().GNMI().Default(t), 5)
+//	...
+//	tl.SetSkew(dut.Name(), skew)
+//	stop, err := tl.Watch(ctx, dut.RawAPIs().GNMI().New(t), dut.Name(), paths...)
+//	...
+//	defer stop()
+//	tl.Mark("Withdrawing the primary path")
+//	...
+//	tl.AddFlowStats(t, ate, "flow")
+package timeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// TestSource is the source of the events marked by the test.
+const TestSource = "test"
+
+// Event is an event of the timeline.
+type Event struct {
+	// Time is the time of the event on the clock of the test.
+	Time time.Time
+	// Source is the device of the event, or TestSource.
+	Source string
+	Text   string
+	// DeviceTime is the time of the event on the clock of its device,
+	// if it was timestamped by the device.
+	DeviceTime time.Time
+}
+
+// Skew is the offset of the clock of a device from the clock of the
+// test, within an uncertainty.
+type Skew struct {
+	Offset      time.Duration
+	Uncertainty time.Duration
+}
+
+func (s Skew) String() string {
+	return fmt.Sprintf("%v ± %v", s.Offset, s.Uncertainty)
+}
+
+// Timeline is a timeline of events.  It is safe for concurrent use.
+type Timeline struct {
+	mu     sync.Mutex
+	events []*Event
+	skews  map[string]Skew
+	now    func() time.Time
+}
+
+// New returns an empty timeline.
+func New() *Timeline {
+	return &Timeline{skews: map[string]Skew{}, now: time.Now}
+}
+
+// SetSkew sets the skew of the clock of the source.
+func (tl *Timeline) SetSkew(source string, s Skew) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.skews[source] = s
+}
+
+// add adds an event.
+func (tl *Timeline) add(e *Event) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.events = append(tl.events, e)
+}
+
+// Mark adds an event of the test at the current time.
+func (tl *Timeline) Mark(format string, args ...interface{}) {
+	tl.add(&Event{Time: tl.now(), Source: TestSource, Text: fmt.Sprintf(format, args...)})
+}
+
+// AddDeviceEvent adds an event timestamped by the clock of the source,
+// which is corrected by its skew.
+func (tl *Timeline) AddDeviceEvent(source string, deviceTime time.Time, format string, args ...interface{}) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.events = append(tl.events, &Event{
+		Time:       deviceTime.Add(-tl.skews[source].Offset),
+		Source:     source,
+		Text:       fmt.Sprintf(format, args...),
+		DeviceTime: deviceTime,
+	})
+}
+
+// Events returns the events of the timeline in time order.  Events at
+// the same time keep the order they were added in.
+func (tl *Timeline) Events() []*Event {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	events := append([]*Event(nil), tl.events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events
+}
+
+// Format writes the timeline as text, one event per line with its
+// offset from the first event, followed by the skews of the sources.
+func (tl *Timeline) Format(w io.Writer) {
+	events := tl.Events()
+	if len(events) == 0 {
+		fmt.Fprintln(w, "No events.")
+		return
+	}
+	start := events[0].Time
+	width := len(TestSource)
+	for _, e := range events {
+		if len(e.Source) > width {
+			width = len(e.Source)
+		}
+	}
+	fmt.Fprintf(w, "Timeline starting at %s\n", start.Format(time.RFC3339Nano))
+	for _, e := range events {
+		fmt.Fprintf(w, "%+12.6fs  %-*s  %s\n", e.Time.Sub(start).Seconds(), width, e.Source, e.Text)
+	}
+
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	var sources []string
+	for s := range tl.skews {
+		sources = append(sources, s)
+	}
+	sort.Strings(sources)
+	for _, s := range sources {
+		fmt.Fprintf(w, "Clock of %s: offset %v\n", s, tl.skews[s])
+	}
+}
+
+func (tl *Timeline) String() string {
+	var b strings.Builder
+	tl.Format(&b)
+	return b.String()
+}
+
+// Write writes the timeline to the test outputs.
+func (tl *Timeline) Write(t testing.TB) {
+	t.Helper()
+	if err := fptest.WriteOutput(t.Name()+" timeline", ".txt", tl.String()); err != nil {
+		t.Logf("Could not write the timeline: %v", err)
+	}
+}
+
+// Sample is a round trip to a device: the time a request was sent and
+// its response received on the clock of the test, and the time of the
+// device in its response.
+type Sample struct {
+	Sent     time.Time
+	Device   time.Time
+	Received time.Time
+}
+
+// EstimateSkew estimates the skew of the clock of a device from round
+// trips, assuming the device took its time halfway through each round
+// trip.  It uses the shortest round trip, whose half is the uncertainty.
+func EstimateSkew(samples []Sample) (Skew, error) {
+	var best *Sample
+	for i, s := range samples {
+		if s.Received.Before(s.Sent) {
+			return Skew{}, fmt.Errorf("sample %d received at %v before it was sent at %v", i, s.Received, s.Sent)
+		}
+		if best == nil || s.Received.Sub(s.Sent) < best.Received.Sub(best.Sent) {
+			best = &samples[i]
+		}
+	}
+	if best == nil {
+		return Skew{}, fmt.Errorf("no samples")
+	}
+	half := best.Received.Sub(best.Sent) / 2
+	return Skew{Offset: best.Device.Sub(best.Sent.Add(half)), Uncertainty: half}, nil
+}
+
+// skewPath is the path the device is sampled with, whose value changes
+// every second so that the device cannot cache its timestamp.
+var skewPath = &gpb.Path{Elem: []*gpb.PathElem{{Name: "system"}, {Name: "state"}, {Name: "current-datetime"}}}
+
+// EstimateGNMISkew estimates the skew of the clock of a device from n
+// gNMI Get round trips, with the timestamps of their notifications.
+func EstimateGNMISkew(ctx context.Context, client gpb.GNMIClient, n int) (Skew, error) {
+	var samples []Sample
+	for i := 0; i < n; i++ {
+		sent := time.Now()
+		resp, err := client.Get(ctx, &gpb.GetRequest{
+			Path:     []*gpb.Path{skewPath},
+			Type:     gpb.GetRequest_STATE,
+			Encoding: gpb.Encoding_JSON_IETF,
+		})
+		received := time.Now()
+		if err != nil {
+			return Skew{}, err
+		}
+		ns := resp.GetNotification()
+		if len(ns) == 0 || ns[0].GetTimestamp() == 0 {
+			return Skew{}, fmt.Errorf("no timestamped notification in the response to Get %v", skewPath)
+		}
+		samples = append(samples, Sample{Sent: sent, Device: time.Unix(0, ns[0].GetTimestamp()), Received: received})
+	}
+	return EstimateSkew(samples)
+}
+
+// Watch subscribes to the paths of the source with ON_CHANGE
+// subscriptions, in the background, and adds an event for each update
+// and delete notified after the initial sync.  The returned function
+// ends the subscription.
+func (tl *Timeline) Watch(ctx context.Context, client gpb.GNMIClient, source string, paths ...*gpb.Path) (func(), error) {
+	ctx, cancel := context.WithCancel(ctx)
+	sub, err := client.Subscribe(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	list := &gpb.SubscriptionList{Mode: gpb.SubscriptionList_STREAM, Encoding: gpb.Encoding_PROTO}
+	for _, p := range paths {
+		list.Subscription = append(list.Subscription, &gpb.Subscription{Path: p, Mode: gpb.SubscriptionMode_ON_CHANGE})
+	}
+	if err := sub.Send(&gpb.SubscribeRequest{Request: &gpb.SubscribeRequest_Subscribe{Subscribe: list}}); err != nil {
+		cancel()
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		synced := false
+		for {
+			resp, err := sub.Recv()
+			if err != nil {
+				return
+			}
+			switch r := resp.GetResponse().(type) {
+			case *gpb.SubscribeResponse_SyncResponse:
+				synced = true
+				tl.add(&Event{Time: tl.now(), Source: source, Text: "initial sync of the subscription received"})
+			case *gpb.SubscribeResponse_Update:
+				if synced {
+					tl.AddNotification(source, r.Update)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+// AddNotification adds an event for each update and delete of the
+// notification of the source.
+func (tl *Timeline) AddNotification(source string, n *gpb.Notification) {
+	at := time.Unix(0, n.GetTimestamp())
+	for _, u := range n.GetUpdate() {
+		tl.AddDeviceEvent(source, at, "%s = %s", pathString(n.GetPrefix(), u.GetPath()), valueString(u.GetVal()))
+	}
+	for _, d := range n.GetDelete() {
+		tl.AddDeviceEvent(source, at, "%s deleted", pathString(n.GetPrefix(), d))
+	}
+}
+
+// pathString returns the path joined with the prefix as a string.
+func pathString(prefix, path *gpb.Path) string {
+	p := &gpb.Path{Elem: append(append([]*gpb.PathElem(nil), prefix.GetElem()...), path.GetElem()...)}
+	s, err := ygot.PathToString(p)
+	if err != nil {
+		return p.String()
+	}
+	return s
+}
+
+// valueString returns the value of an update as a string.
+func valueString(v *gpb.TypedValue) string {
+	switch v.GetValue().(type) {
+	case *gpb.TypedValue_StringVal:
+		return v.GetStringVal()
+	case *gpb.TypedValue_IntVal:
+		return fmt.Sprint(v.GetIntVal())
+	case *gpb.TypedValue_UintVal:
+		return fmt.Sprint(v.GetUintVal())
+	case *gpb.TypedValue_BoolVal:
+		return fmt.Sprint(v.GetBoolVal())
+	case *gpb.TypedValue_FloatVal:
+		return fmt.Sprint(v.GetFloatVal())
+	case *gpb.TypedValue_JsonIetfVal:
+		return string(v.GetJsonIetfVal())
+	case *gpb.TypedValue_JsonVal:
+		return string(v.GetJsonVal())
+	}
+	return v.String()
+}
+
+// AddFlowStats adds an event with the counters of each ATE flow, at the
+// time the ATE sampled them.  The ATE is the source of the events.
+func (tl *Timeline) AddFlowStats(t testing.TB, ate *ondatra.ATEDevice, flows ...string) {
+	t.Helper()
+	for _, f := range flows {
+		q := ate.Telemetry().Flow(f).Counters().Lookup(t)
+		if !q.IsPresent() {
+			tl.Mark("no counters for flow %s", f)
+			continue
+		}
+		c := q.Val(t)
+		tl.AddDeviceEvent(ate.Name(), q.GetTimestamp(), "flow %s: %d packets sent, %d received", f, c.GetOutPkts(), c.GetInPkts())
+	}
+}
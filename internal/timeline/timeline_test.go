@@ -0,0 +1,128 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+var t0 = time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+
+func TestEstimateSkew(t *testing.T) {
+	samples := []Sample{
+		// A slow round trip, which is ignored.
+		{Sent: t0, Device: t0.Add(600 * time.Millisecond), Received: t0.Add(200 * time.Millisecond)},
+		// The device clock is 500ms ahead, and the round trip 20ms.
+		{Sent: t0.Add(time.Second), Device: t0.Add(time.Second + 510*time.Millisecond), Received: t0.Add(time.Second + 20*time.Millisecond)},
+	}
+	got, err := EstimateSkew(samples)
+	if err != nil {
+		t.Fatalf("EstimateSkew() got error: %v", err)
+	}
+	if want := (Skew{Offset: 500 * time.Millisecond, Uncertainty: 10 * time.Millisecond}); got != want {
+		t.Errorf("EstimateSkew() got %v, want %v", got, want)
+	}
+
+	if _, err := EstimateSkew(nil); err == nil {
+		t.Errorf("EstimateSkew(nil) got no error, want error")
+	}
+	if _, err := EstimateSkew([]Sample{{Sent: t0.Add(time.Second), Received: t0}}); err == nil {
+		t.Errorf("EstimateSkew() of a sample received before it was sent got no error, want error")
+	}
+}
+
+// newTimeline returns a timeline whose clock returns the times in turn.
+func newTimeline(times ...time.Time) *Timeline {
+	tl := New()
+	tl.now = func() time.Time {
+		now := times[0]
+		times = times[1:]
+		return now
+	}
+	return tl
+}
+
+func TestEvents(t *testing.T) {
+	tl := newTimeline(t0, t0.Add(2*time.Second))
+	tl.SetSkew("dut", Skew{Offset: time.Second})
+	tl.Mark("start")
+	tl.Mark("stop")
+	// On the clock of the test, the device event is at t0 + 1s.
+	tl.AddDeviceEvent("dut", t0.Add(2*time.Second), "route %s withdrawn", "198.51.100.0/24")
+	tl.AddDeviceEvent("ate", t0.Add(2*time.Second), "flow stats")
+
+	var got []string
+	for _, e := range tl.Events() {
+		got = append(got, e.Source+": "+e.Text)
+	}
+	want := []string{"test: start", "dut: route 198.51.100.0/24 withdrawn", "test: stop", "ate: flow stats"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Events() -want, +got:\n%s", diff)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tl := newTimeline(t0)
+	tl.SetSkew("dut", Skew{Offset: 100 * time.Millisecond, Uncertainty: 5 * time.Millisecond})
+	tl.Mark("start")
+	tl.AddDeviceEvent("dut", t0.Add(350*time.Millisecond), "changed")
+	want := `Timeline starting at 2022-08-01T12:00:00Z
+   +0.000000s  test  start
+   +0.250000s  dut   changed
+Clock of dut: offset 100ms ± 5ms
+`
+	if diff := cmp.Diff(want, tl.String()); diff != "" {
+		t.Errorf("String() -want, +got:\n%s", diff)
+	}
+	if got := New().String(); got != "No events.\n" {
+		t.Errorf("String() of an empty timeline got %q, want %q", got, "No events.\n")
+	}
+}
+
+func TestAddNotification(t *testing.T) {
+	tl := New()
+	tl.AddNotification("dut", &gpb.Notification{
+		Timestamp: t0.UnixNano(),
+		Prefix:    &gpb.Path{Elem: []*gpb.PathElem{{Name: "interfaces"}, {Name: "interface", Key: map[string]string{"name": "eth1"}}}},
+		Update: []*gpb.Update{{
+			Path: &gpb.Path{Elem: []*gpb.PathElem{{Name: "state"}, {Name: "oper-status"}}},
+			Val:  &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: "DOWN"}},
+		}, {
+			Path: &gpb.Path{Elem: []*gpb.PathElem{{Name: "state"}, {Name: "mtu"}}},
+			Val:  &gpb.TypedValue{Value: &gpb.TypedValue_UintVal{UintVal: 1500}},
+		}},
+		Delete: []*gpb.Path{{Elem: []*gpb.PathElem{{Name: "state"}, {Name: "description"}}}},
+	})
+	var got []string
+	for _, e := range tl.Events() {
+		if !e.DeviceTime.Equal(t0) {
+			t.Errorf("Event %q got device time %v, want %v", e.Text, e.DeviceTime, t0)
+		}
+		got = append(got, e.Text)
+	}
+	want := []string{
+		"/interfaces/interface[name=eth1]/state/oper-status = DOWN",
+		"/interfaces/interface[name=eth1]/state/mtu = 1500",
+		"/interfaces/interface[name=eth1]/state/description deleted",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("AddNotification() events -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tolerance defines the traffic tolerances of the tests: the
+// loss a flow may suffer, how long it may be interrupted while the DUT
+// converges, and the share of its offered rate it must at least
+// receive.
+//
+// Each test keeps its own default tolerances, which the flags of this
+// package override for a whole run, so that the tolerances of a vendor
+// or platform apply uniformly to all the suites instead of being edited
+// into each test.  For example, to allow a loss of 0.5% and an outage of
+// 200ms to every flow:
+//
+//	go test ./... --tolerance_max_loss_pct=0.5 --tolerance_max_convergence=200ms
+//
+// The traffic helpers of the other packages, such as
+// gribi.CheckLossWindow, apply the overrides to the bounds they are
+// given.
+package tolerance
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openconfig/featureprofiles/internal/args"
+	"github.com/openconfig/ondatra"
+)
+
+// Tolerance arguments.  A negative loss or throughput, or a zero
+// convergence, uses the default of each test.
+var (
+	MaxLossPctArg = flag.Float64("tolerance_max_loss_pct", -1,
+		"Maximum loss of a flow in percent; negative uses the default of each test.")
+
+	MaxConvergenceArg = flag.Duration("tolerance_max_convergence", 0,
+		"Maximum interruption of a flow while the DUT converges; zero uses the default of each test, scaled by arg_convergence_percent.")
+
+	MinThroughputPctArg = flag.Float64("tolerance_min_throughput_pct", -1,
+		"Minimum share of the offered rate of a flow received, in percent; negative uses the default of each test.")
+)
+
+// MaxLossPct returns the maximum loss of a flow in percent, def unless
+// the tolerance_max_loss_pct argument is set.
+func MaxLossPct(def float64) float64 {
+	if *MaxLossPctArg >= 0 {
+		return *MaxLossPctArg
+	}
+	return def
+}
+
+// MaxConvergence returns the maximum interruption of a flow, def unless
+// the tolerance_max_convergence argument is set.  Unlike New, it does
+// not scale def, which callers typically already scaled with
+// args.Convergence.
+func MaxConvergence(def time.Duration) time.Duration {
+	if *MaxConvergenceArg > 0 {
+		return *MaxConvergenceArg
+	}
+	return def
+}
+
+// MinThroughputPct returns the minimum share of the offered rate of a
+// flow received in percent, def unless the tolerance_min_throughput_pct
+// argument is set.
+func MinThroughputPct(def float64) float64 {
+	if *MinThroughputPctArg >= 0 {
+		return *MinThroughputPctArg
+	}
+	return def
+}
+
+// TrafficPolicy bounds the traffic of the flows of a test.  A zero
+// MaxConvergence or MinThroughputPct does not check the interruption or
+// the throughput.
+type TrafficPolicy struct {
+	MaxLossPct       float64
+	MaxConvergence   time.Duration
+	MinThroughputPct float64
+}
+
+// New returns the traffic policy of a test given its defaults,
+// overridden by the tolerance arguments.  The default convergence is
+// scaled by args.Convergence.
+func New(def TrafficPolicy) TrafficPolicy {
+	return TrafficPolicy{
+		MaxLossPct:       MaxLossPct(def.MaxLossPct),
+		MaxConvergence:   MaxConvergence(args.Convergence(def.MaxConvergence)),
+		MinThroughputPct: MinThroughputPct(def.MinThroughputPct),
+	}
+}
+
+func (p TrafficPolicy) String() string {
+	return fmt.Sprintf("loss <= %g%%, convergence <= %v, throughput >= %g%%", p.MaxLossPct, p.MaxConvergence, p.MinThroughputPct)
+}
+
+// Stats are the statistics of a flow sent at a constant rate.
+type Stats struct {
+	Name string
+	// TxPkts and RxPkts are the packets sent and received.
+	TxPkts uint64
+	RxPkts uint64
+	// PPS is the rate of the flow and Duration how long it was sent.
+	PPS      uint64
+	Duration time.Duration
+}
+
+// LossPct returns the loss of the flow in percent.
+func (s *Stats) LossPct() float64 {
+	if s.TxPkts == 0 || s.RxPkts >= s.TxPkts {
+		return 0
+	}
+	return 100 * float64(s.TxPkts-s.RxPkts) / float64(s.TxPkts)
+}
+
+// Outage returns how long the flow was interrupted, given its rate.
+func (s *Stats) Outage() time.Duration {
+	if s.RxPkts >= s.TxPkts || s.PPS == 0 {
+		return 0
+	}
+	return time.Duration(s.TxPkts-s.RxPkts) * time.Second / time.Duration(s.PPS)
+}
+
+// ThroughputPct returns the rate received over the duration as a share
+// of the offered rate, in percent.
+func (s *Stats) ThroughputPct() float64 {
+	if s.PPS == 0 || s.Duration <= 0 {
+		return 0
+	}
+	return 100 * float64(s.RxPkts) / (float64(s.PPS) * s.Duration.Seconds())
+}
+
+// Check checks the statistics of a flow against the policy, and returns
+// an error for each bound exceeded.
+func (p TrafficPolicy) Check(s *Stats) []error {
+	if s.TxPkts == 0 {
+		return []error{fmt.Errorf("flow %s sent no packets", s.Name)}
+	}
+	var errs []error
+	if loss := s.LossPct(); loss > p.MaxLossPct {
+		errs = append(errs, fmt.Errorf("flow %s lost %d of %d packets (%.3f%%), want <= %g%%", s.Name, s.TxPkts-s.RxPkts, s.TxPkts, loss, p.MaxLossPct))
+	}
+	if p.MaxConvergence > 0 {
+		if o := s.Outage(); o > p.MaxConvergence {
+			errs = append(errs, fmt.Errorf("flow %s was interrupted for %v at %d pps, want <= %v", s.Name, o, s.PPS, p.MaxConvergence))
+		}
+	}
+	if p.MinThroughputPct > 0 {
+		if tp := s.ThroughputPct(); tp < p.MinThroughputPct {
+			errs = append(errs, fmt.Errorf("flow %s received %.2f%% of its offered rate of %d pps, want >= %g%%", s.Name, tp, s.PPS, p.MinThroughputPct))
+		}
+	}
+	return errs
+}
+
+// ReadStats returns the statistics of an ATE flow sent at pps packets per
+// second for the duration.
+func ReadStats(t testing.TB, ate *ondatra.ATEDevice, flow string, pps uint64, duration time.Duration) *Stats {
+	t.Helper()
+	c := ate.Telemetry().Flow(flow).Counters()
+	return &Stats{
+		Name:     flow,
+		TxPkts:   c.OutPkts().Get(t),
+		RxPkts:   c.InPkts().Get(t),
+		PPS:      pps,
+		Duration: duration,
+	}
+}
+
+// CheckFlows checks the ATE flows, sent at pps packets per second for
+// the duration, against the policy, and reports an error for each bound
+// exceeded.
+func (p TrafficPolicy) CheckFlows(t testing.TB, ate *ondatra.ATEDevice, pps uint64, duration time.Duration, flows ...*ondatra.Flow) {
+	t.Helper()
+	for _, f := range flows {
+		for _, err := range p.Check(ReadStats(t, ate, f.Name(), pps, duration)) {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tolerance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOverrides(t *testing.T) {
+	defer func(loss, tp float64, conv time.Duration) {
+		*MaxLossPctArg, *MinThroughputPctArg, *MaxConvergenceArg = loss, tp, conv
+	}(*MaxLossPctArg, *MinThroughputPctArg, *MaxConvergenceArg)
+
+	def := TrafficPolicy{MaxLossPct: 1, MaxConvergence: time.Second, MinThroughputPct: 90}
+	if got := New(def); got != def {
+		t.Errorf("New() without arguments got %v, want %v", got, def)
+	}
+
+	*MaxLossPctArg, *MinThroughputPctArg, *MaxConvergenceArg = 0, 50, 200*time.Millisecond
+	want := TrafficPolicy{MaxLossPct: 0, MaxConvergence: 200 * time.Millisecond, MinThroughputPct: 50}
+	if got := New(def); got != want {
+		t.Errorf("New() with arguments got %v, want %v", got, want)
+	}
+	if got := MaxConvergence(5 * time.Second); got != 200*time.Millisecond {
+		t.Errorf("MaxConvergence() got %v, want 200ms", got)
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := &Stats{TxPkts: 10000, RxPkts: 9900, PPS: 1000, Duration: 10 * time.Second}
+	if got := s.LossPct(); got != 1 {
+		t.Errorf("LossPct() got %g, want 1", got)
+	}
+	if got := s.Outage(); got != 100*time.Millisecond {
+		t.Errorf("Outage() got %v, want 100ms", got)
+	}
+	if got := s.ThroughputPct(); got != 99 {
+		t.Errorf("ThroughputPct() got %g, want 99", got)
+	}
+	if got := (&Stats{TxPkts: 10, RxPkts: 12}).Outage(); got != 0 {
+		t.Errorf("Outage() with more packets received got %v, want 0", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p := TrafficPolicy{MaxLossPct: 1, MaxConvergence: 50 * time.Millisecond, MinThroughputPct: 98}
+	for _, c := range []struct {
+		desc     string
+		stats    Stats
+		wantErrs int
+	}{
+		{"no loss", Stats{TxPkts: 10000, RxPkts: 10000, PPS: 1000, Duration: 10 * time.Second}, 0},
+		{"within bounds", Stats{TxPkts: 10000, RxPkts: 9960, PPS: 1000, Duration: 10 * time.Second}, 0},
+		{"outage", Stats{TxPkts: 10000, RxPkts: 9940, PPS: 1000, Duration: 10 * time.Second}, 1},
+		{"loss, outage and throughput", Stats{TxPkts: 10000, RxPkts: 9000, PPS: 1000, Duration: 10 * time.Second}, 3},
+		{"nothing sent", Stats{}, 1},
+	} {
+		if got := p.Check(&c.stats); len(got) != c.wantErrs {
+			t.Errorf("%s: Check() got errors %v, want %d", c.desc, got, c.wantErrs)
+		}
+	}
+
+	loose := TrafficPolicy{MaxLossPct: 20}
+	if got := loose.Check(&Stats{TxPkts: 10000, RxPkts: 9000, PPS: 1000, Duration: 10 * time.Second}); len(got) != 0 {
+		t.Errorf("Check() without convergence or throughput bounds got errors %v, want none", got)
+	}
+}
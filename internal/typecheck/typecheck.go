@@ -0,0 +1,463 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package typecheck checks that the typed values of gNMI telemetry match
+// the types of their leaves in the YANG schema, so that type mismatches of
+// a DUT are reported per leaf rather than surfacing as unmarshal errors of
+// the ygot structs in the tests using them.
+//
+// The checks follow the scalar encoding of gNMI: signed integers are
+// int_val, unsigned integers uint_val, enumerations and identities the
+// string_val of one of their names, decimal64 a decimal_val of the
+// fraction digits of the leaf, or a float_val.  JSON values are checked
+// against the encoding of RFC 7951, where 64-bit integers are strings.
+package typecheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// Mismatch is a value not matching the type of its leaf.
+type Mismatch struct {
+	// Path is the path of the value, and SchemaPath the one of its leaf,
+	// without list keys.
+	Path       string
+	SchemaPath string
+	Type       string
+	Units      string
+	Value      string
+	Reason     string
+}
+
+func (m *Mismatch) Error() string {
+	typ := m.Type
+	if m.Units != "" {
+		typ += " in " + m.Units
+	}
+	return fmt.Sprintf("%s: %s is not a valid %s: %s", m.Path, m.Value, typ, m.Reason)
+}
+
+// Checker checks values against a YANG schema.
+type Checker struct {
+	root *yang.Entry
+}
+
+// New returns a checker of the OpenConfig schema of ondatra.
+func New() (*Checker, error) {
+	tree, err := telemetry.UnzipSchema()
+	if err != nil {
+		return nil, err
+	}
+	root, ok := tree["Device"]
+	if !ok {
+		return nil, fmt.Errorf("no Device in the schema")
+	}
+	return NewFromRoot(root), nil
+}
+
+// NewFromRoot returns a checker of the schema of the root entry.
+func NewFromRoot(root *yang.Entry) *Checker {
+	return &Checker{root: root}
+}
+
+// child returns the child entry of a directory, looking through its
+// choices and cases.
+func child(e *yang.Entry, name string) *yang.Entry {
+	if c, ok := e.Dir[name]; ok {
+		return c
+	}
+	for _, c := range e.Dir {
+		if c.IsChoice() || c.IsCase() {
+			if found := child(c, name); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// Find returns the schema entry of a path.
+func (c *Checker) Find(p *gpb.Path) (*yang.Entry, error) {
+	e := c.root
+	for _, elem := range p.GetElem() {
+		name := elem.GetName()
+		if i := strings.Index(name, ":"); i >= 0 {
+			name = name[i+1:]
+		}
+		next := child(e, name)
+		if next == nil {
+			return nil, fmt.Errorf("no %s in %s", name, e.Path())
+		}
+		e = next
+	}
+	return e, nil
+}
+
+// schemaPath returns a path without its list keys.
+func schemaPath(p *gpb.Path) string {
+	var b strings.Builder
+	for _, elem := range p.GetElem() {
+		b.WriteString("/")
+		b.WriteString(elem.GetName())
+	}
+	return b.String()
+}
+
+// Check checks the values of the updates of a notification, and returns
+// their mismatches.  The updates of paths not in the schema, or not of
+// leaves, are mismatches too.
+func (c *Checker) Check(n *gpb.Notification) []*Mismatch {
+	var ms []*Mismatch
+	for _, u := range n.GetUpdate() {
+		p := &gpb.Path{Origin: n.GetPrefix().GetOrigin()}
+		p.Elem = append(append(p.Elem, n.GetPrefix().GetElem()...), u.GetPath().GetElem()...)
+		path, err := ygot.PathToString(p)
+		if err != nil {
+			path = p.String()
+		}
+		m := &Mismatch{Path: path, SchemaPath: schemaPath(p), Value: valueString(u.GetVal())}
+		e, err := c.Find(p)
+		switch {
+		case err != nil:
+			m.Type = "path"
+			m.Reason = err.Error()
+		case !e.IsLeaf() && !e.IsLeafList():
+			m.Type = "leaf"
+			m.Reason = "not a leaf of the schema"
+		default:
+			m.Type = e.Type.Kind.String()
+			m.Units = e.Units
+			if err := checkValue(e, e.Type, u.GetVal()); err != nil {
+				m.Reason = err.Error()
+			}
+		}
+		if m.Reason != "" {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// valueString returns a value for the messages of mismatches.
+func valueString(v *gpb.TypedValue) string {
+	s := strings.TrimSpace(fmt.Sprint(v))
+	if s == "" {
+		return "<nil>"
+	}
+	return s
+}
+
+// checkValue checks a value against a type of the leaf.  Leaf-lists are
+// checked element by element.
+func checkValue(e *yang.Entry, t *yang.YangType, v *gpb.TypedValue) error {
+	switch val := v.GetValue().(type) {
+	case *gpb.TypedValue_LeaflistVal:
+		if !e.IsLeafList() {
+			return fmt.Errorf("leaf-list value of a leaf")
+		}
+		for _, elem := range val.LeaflistVal.GetElement() {
+			if err := checkValue(e, t, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *gpb.TypedValue_JsonIetfVal:
+		return checkJSON(e, t, val.JsonIetfVal)
+	case *gpb.TypedValue_JsonVal:
+		return checkJSON(e, t, val.JsonVal)
+	}
+	return checkScalar(e, t, v)
+}
+
+// intBounds are the bounds of the integer types.
+var intBounds = map[yang.TypeKind][2]float64{
+	yang.Yint8:   {math.MinInt8, math.MaxInt8},
+	yang.Yint16:  {math.MinInt16, math.MaxInt16},
+	yang.Yint32:  {math.MinInt32, math.MaxInt32},
+	yang.Yint64:  {math.MinInt64, math.MaxInt64},
+	yang.Yuint8:  {0, math.MaxUint8},
+	yang.Yuint16: {0, math.MaxUint16},
+	yang.Yuint32: {0, math.MaxUint32},
+	yang.Yuint64: {0, math.MaxUint64},
+}
+
+// checkScalar checks a scalar value against a type of the leaf.
+func checkScalar(e *yang.Entry, t *yang.YangType, v *gpb.TypedValue) error {
+	switch t.Kind {
+	case yang.Yint8, yang.Yint16, yang.Yint32, yang.Yint64:
+		i, ok := v.GetValue().(*gpb.TypedValue_IntVal)
+		if !ok {
+			return fmt.Errorf("want int_val")
+		}
+		return checkBounds(t.Kind, float64(i.IntVal))
+	case yang.Yuint8, yang.Yuint16, yang.Yuint32, yang.Yuint64:
+		u, ok := v.GetValue().(*gpb.TypedValue_UintVal)
+		if !ok {
+			return fmt.Errorf("want uint_val")
+		}
+		return checkBounds(t.Kind, float64(u.UintVal))
+	case yang.Ybool:
+		if _, ok := v.GetValue().(*gpb.TypedValue_BoolVal); !ok {
+			return fmt.Errorf("want bool_val")
+		}
+	case yang.Ybinary:
+		if _, ok := v.GetValue().(*gpb.TypedValue_BytesVal); !ok {
+			return fmt.Errorf("want bytes_val")
+		}
+	case yang.Ydecimal64:
+		switch d := v.GetValue().(type) {
+		case *gpb.TypedValue_DecimalVal:
+			if got, want := d.DecimalVal.GetPrecision(), uint32(t.FractionDigits); got != want {
+				return fmt.Errorf("decimal_val precision %d, want %d fraction digits", got, want)
+			}
+		case *gpb.TypedValue_FloatVal:
+		default:
+			return fmt.Errorf("want decimal_val or float_val")
+		}
+	case yang.Ystring:
+		if _, ok := v.GetValue().(*gpb.TypedValue_StringVal); !ok {
+			return fmt.Errorf("want string_val")
+		}
+	case yang.Yenum, yang.Yidentityref:
+		s, ok := v.GetValue().(*gpb.TypedValue_StringVal)
+		if !ok {
+			return fmt.Errorf("want the string_val of a name")
+		}
+		return checkName(t, s.StringVal)
+	case yang.Yunion:
+		var reasons []string
+		for _, member := range t.Type {
+			err := checkScalar(e, member, v)
+			if err == nil {
+				return nil
+			}
+			reasons = append(reasons, member.Kind.String()+": "+err.Error())
+		}
+		return fmt.Errorf("no type of the union matches (%s)", strings.Join(reasons, "; "))
+	case yang.Yleafref:
+		if ref := e.Find(leafrefPath(t.Path)); ref != nil && ref.Type != nil {
+			return checkScalar(ref, ref.Type, v)
+		}
+	}
+	return nil
+}
+
+// checkBounds checks that an integer is within the bounds of its type.
+func checkBounds(kind yang.TypeKind, v float64) error {
+	if b := intBounds[kind]; v < b[0] || v > b[1] {
+		return fmt.Errorf("out of the bounds of %v", kind)
+	}
+	return nil
+}
+
+// checkName checks that a name is one of an enumeration or of the
+// identities derived from the base of an identityref.  Identities may be
+// prefixed by their module.
+func checkName(t *yang.YangType, name string) error {
+	if t.Kind == yang.Yenum {
+		if !t.Enum.IsDefined(name) {
+			return fmt.Errorf("not a name of the enumeration")
+		}
+		return nil
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, id := range t.IdentityBase.Values {
+		if id.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("not an identity derived from %s", t.IdentityBase.Name)
+}
+
+var (
+	// predicates matches the predicates of a leafref path, and prefixes
+	// the module prefixes of its names.
+	predicates = regexp.MustCompile(`\[[^\]]*\]`)
+	prefixes   = regexp.MustCompile(`[\w.-]+:`)
+)
+
+// leafrefPath returns a leafref path the schema entries can find, without
+// its predicates and prefixes.
+func leafrefPath(path string) string {
+	return prefixes.ReplaceAllString(predicates.ReplaceAllString(path, ""), "")
+}
+
+// checkJSON checks a JSON value against a type of the leaf, following
+// RFC 7951.
+func checkJSON(e *yang.Entry, t *yang.YangType, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	if e.IsLeafList() {
+		if vs, ok := v.([]interface{}); ok {
+			for _, elem := range vs {
+				if err := checkJSONScalar(e, t, elem); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return checkJSONScalar(e, t, v)
+}
+
+// checkJSONScalar checks a decoded JSON scalar against a type of the
+// leaf, by converting it to the scalar typed value it encodes.
+func checkJSONScalar(e *yang.Entry, t *yang.YangType, v interface{}) error {
+	switch t.Kind {
+	case yang.Yunion:
+		var reasons []string
+		for _, member := range t.Type {
+			err := checkJSONScalar(e, member, v)
+			if err == nil {
+				return nil
+			}
+			reasons = append(reasons, member.Kind.String()+": "+err.Error())
+		}
+		return fmt.Errorf("no type of the union matches (%s)", strings.Join(reasons, "; "))
+	case yang.Yleafref:
+		if ref := e.Find(leafrefPath(t.Path)); ref != nil && ref.Type != nil {
+			return checkJSONScalar(ref, ref.Type, v)
+		}
+		return nil
+	}
+	var tv *gpb.TypedValue
+	switch val := v.(type) {
+	case bool:
+		tv = &gpb.TypedValue{Value: &gpb.TypedValue_BoolVal{BoolVal: val}}
+	case json.Number:
+		tv = numberValue(t, string(val))
+		if tv == nil {
+			return fmt.Errorf("number %s, want %v", val, t.Kind)
+		}
+		switch t.Kind {
+		case yang.Yint64, yang.Yuint64, yang.Ydecimal64:
+			return fmt.Errorf("number %s, want a string for a %v", val, t.Kind)
+		}
+	case string:
+		switch t.Kind {
+		case yang.Yint64, yang.Yuint64, yang.Ydecimal64:
+			if tv = numberValue(t, val); tv == nil {
+				return fmt.Errorf("string %q, want a %v", val, t.Kind)
+			}
+		default:
+			tv = &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: val}}
+		}
+	default:
+		return fmt.Errorf("JSON %T, want a scalar", v)
+	}
+	if t.Kind == yang.Ydecimal64 {
+		// JSON does not carry the precision.
+		return nil
+	}
+	return checkScalar(e, t, tv)
+}
+
+// numberValue returns the typed value of a number of the JSON encoding,
+// or nil if it is not one of the type.
+func numberValue(t *yang.YangType, s string) *gpb.TypedValue {
+	switch t.Kind {
+	case yang.Yint8, yang.Yint16, yang.Yint32, yang.Yint64:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return &gpb.TypedValue{Value: &gpb.TypedValue_IntVal{IntVal: i}}
+		}
+	case yang.Yuint8, yang.Yuint16, yang.Yuint32, yang.Yuint64:
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return &gpb.TypedValue{Value: &gpb.TypedValue_UintVal{UintVal: u}}
+		}
+	case yang.Ydecimal64:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return &gpb.TypedValue{Value: &gpb.TypedValue_FloatVal{FloatVal: float32(f)}}
+		}
+	}
+	return nil
+}
+
+// Summarize counts the mismatches by schema path, and returns the schema
+// paths in order with the first mismatch of each, as the leaves a vendor
+// deviates from the schema for.
+func Summarize(ms []*Mismatch) ([]string, map[string]int, map[string]*Mismatch) {
+	counts := map[string]int{}
+	first := map[string]*Mismatch{}
+	for _, m := range ms {
+		if counts[m.SchemaPath] == 0 {
+			first[m.SchemaPath] = m
+		}
+		counts[m.SchemaPath]++
+	}
+	var paths []string
+	for p := range counts {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths, counts, first
+}
+
+// Collect subscribes once to the paths with PROTO encoding, for the DUT to
+// send typed scalar values, and returns the notifications received until
+// the sync response.
+func Collect(ctx context.Context, client gpb.GNMIClient, paths ...*gpb.Path) ([]*gpb.Notification, error) {
+	return CollectEncoding(ctx, client, gpb.Encoding_PROTO, paths...)
+}
+
+// CollectEncoding is like Collect with the given encoding.
+func CollectEncoding(ctx context.Context, client gpb.GNMIClient, enc gpb.Encoding, paths ...*gpb.Path) ([]*gpb.Notification, error) {
+	sub, err := client.Subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := &gpb.SubscriptionList{Mode: gpb.SubscriptionList_ONCE, Encoding: enc}
+	for _, p := range paths {
+		list.Subscription = append(list.Subscription, &gpb.Subscription{Path: p})
+	}
+	if err := sub.Send(&gpb.SubscribeRequest{Request: &gpb.SubscribeRequest_Subscribe{Subscribe: list}}); err != nil {
+		return nil, err
+	}
+	var notifs []*gpb.Notification
+	for {
+		resp, err := sub.Recv()
+		if err == io.EOF {
+			return notifs, fmt.Errorf("subscription ended before the sync response")
+		}
+		if err != nil {
+			return notifs, err
+		}
+		switch r := resp.Response.(type) {
+		case *gpb.SubscribeResponse_Update:
+			notifs = append(notifs, r.Update)
+		case *gpb.SubscribeResponse_SyncResponse:
+			return notifs, nil
+		}
+	}
+}
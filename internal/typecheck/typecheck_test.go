@@ -0,0 +1,198 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package typecheck
+
+import (
+	"testing"
+
+	"github.com/openconfig/goyang/pkg/yang"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// newRoot returns a schema of an interface list with leaves of the types
+// checked.
+func newRoot() *yang.Entry {
+	root := &yang.Entry{Name: "device", Kind: yang.DirectoryEntry, Dir: map[string]*yang.Entry{}}
+	add := func(parent *yang.Entry, e *yang.Entry) *yang.Entry {
+		e.Parent = parent
+		parent.Dir[e.Name] = e
+		return e
+	}
+	dir := func(parent *yang.Entry, name string) *yang.Entry {
+		return add(parent, &yang.Entry{Name: name, Kind: yang.DirectoryEntry, Dir: map[string]*yang.Entry{}})
+	}
+	leaf := func(parent *yang.Entry, name string, t *yang.YangType) *yang.Entry {
+		return add(parent, &yang.Entry{Name: name, Kind: yang.LeafEntry, Type: t})
+	}
+
+	status := yang.NewEnumType()
+	status.Set("UP", 1)
+	status.Set("DOWN", 2)
+	base := &yang.Identity{Name: "INTERFACE_TYPE"}
+	base.Values = []*yang.Identity{{Name: "ethernetCsmacd"}, {Name: "ieee8023adLag"}}
+
+	intf := dir(dir(root, "interfaces"), "interface")
+	intf.ListAttr = &yang.ListAttr{}
+	leaf(intf, "name", &yang.YangType{Kind: yang.Yleafref, Path: "../state/name"})
+	state := dir(intf, "state")
+	leaf(state, "name", &yang.YangType{Kind: yang.Ystring})
+	leaf(state, "mtu", &yang.YangType{Kind: yang.Yuint16})
+	leaf(state, "enabled", &yang.YangType{Kind: yang.Ybool})
+	leaf(state, "oper-status", &yang.YangType{Kind: yang.Yenum, Enum: status})
+	leaf(state, "type", &yang.YangType{Kind: yang.Yidentityref, IdentityBase: base})
+	leaf(state, "parent", &yang.YangType{Kind: yang.Yleafref, Path: "/oc-if:interfaces/oc-if:interface[oc-if:name=current()/../name]/oc-if:state/oc-if:mtu"})
+	leaf(state, "temperature", &yang.YangType{Kind: yang.Ydecimal64, FractionDigits: 1}).Units = "celsius"
+	leaf(state, "offset", &yang.YangType{Kind: yang.Yint8})
+	leaf(state, "priority", &yang.YangType{Kind: yang.Yunion, Type: []*yang.YangType{
+		{Kind: yang.Yuint8},
+		{Kind: yang.Yenum, Enum: status},
+	}})
+	ips := leaf(state, "ips", &yang.YangType{Kind: yang.Ystring})
+	ips.ListAttr = &yang.ListAttr{}
+	counters := dir(state, "counters")
+	leaf(counters, "in-pkts", &yang.YangType{Kind: yang.Yuint64})
+	choice := add(state, &yang.Entry{Name: "mode", Kind: yang.ChoiceEntry, Dir: map[string]*yang.Entry{}})
+	cas := add(choice, &yang.Entry{Name: "fixed", Kind: yang.CaseEntry, Dir: map[string]*yang.Entry{}})
+	leaf(cas, "speed", &yang.YangType{Kind: yang.Yuint32})
+	return root
+}
+
+func strVal(s string) *gpb.TypedValue {
+	return &gpb.TypedValue{Value: &gpb.TypedValue_StringVal{StringVal: s}}
+}
+func uintVal(u uint64) *gpb.TypedValue {
+	return &gpb.TypedValue{Value: &gpb.TypedValue_UintVal{UintVal: u}}
+}
+func intVal(i int64) *gpb.TypedValue {
+	return &gpb.TypedValue{Value: &gpb.TypedValue_IntVal{IntVal: i}}
+}
+func ietfVal(s string) *gpb.TypedValue {
+	return &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: []byte(s)}}
+}
+
+func TestCheck(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		leaf    string
+		val     *gpb.TypedValue
+		wantErr bool
+	}{
+		{"string", "state/name", strVal("eth0"), false},
+		{"uint as string", "state/mtu", strVal("1500"), true},
+		{"uint", "state/mtu", uintVal(1500), false},
+		{"uint as int", "state/mtu", intVal(1500), true},
+		{"uint out of bounds", "state/mtu", uintVal(70000), true},
+		{"int", "state/offset", intVal(-3), false},
+		{"int out of bounds", "state/offset", intVal(-300), true},
+		{"uint64", "state/counters/in-pkts", uintVal(1 << 40), false},
+		{"bool", "state/enabled", &gpb.TypedValue{Value: &gpb.TypedValue_BoolVal{BoolVal: true}}, false},
+		{"bool as string", "state/enabled", strVal("true"), true},
+		{"enum", "state/oper-status", strVal("UP"), false},
+		{"undefined enum", "state/oper-status", strVal("LOWER_LAYER_DOWN"), true},
+		{"identity", "state/type", strVal("ethernetCsmacd"), false},
+		{"prefixed identity", "state/type", strVal("iana-if-type:ieee8023adLag"), false},
+		{"undefined identity", "state/type", strVal("other"), true},
+		{"decimal", "state/temperature", &gpb.TypedValue{Value: &gpb.TypedValue_DecimalVal{DecimalVal: &gpb.Decimal64{Digits: 425, Precision: 1}}}, false},
+		{"decimal precision", "state/temperature", &gpb.TypedValue{Value: &gpb.TypedValue_DecimalVal{DecimalVal: &gpb.Decimal64{Digits: 4250, Precision: 2}}}, true},
+		{"float", "state/temperature", &gpb.TypedValue{Value: &gpb.TypedValue_FloatVal{FloatVal: 42.5}}, false},
+		{"union uint", "state/priority", uintVal(3), false},
+		{"union enum", "state/priority", strVal("DOWN"), false},
+		{"union neither", "state/priority", strVal("HIGH"), true},
+		{"relative leafref", "name", strVal("eth0"), false},
+		{"relative leafref mismatch", "name", uintVal(0), true},
+		{"absolute leafref", "state/parent", uintVal(9000), false},
+		{"absolute leafref mismatch", "state/parent", strVal("9000"), true},
+		{"choice", "state/speed", uintVal(100000), false},
+		{"leaf-list", "state/ips", &gpb.TypedValue{Value: &gpb.TypedValue_LeaflistVal{LeaflistVal: &gpb.ScalarArray{
+			Element: []*gpb.TypedValue{strVal("192.0.2.1"), strVal("192.0.2.5")},
+		}}}, false},
+		{"leaf-list of a leaf", "state/mtu", &gpb.TypedValue{Value: &gpb.TypedValue_LeaflistVal{LeaflistVal: &gpb.ScalarArray{
+			Element: []*gpb.TypedValue{uintVal(1500)},
+		}}}, true},
+		{"JSON uint", "state/mtu", ietfVal("1500"), false},
+		{"JSON uint as string", "state/mtu", ietfVal(`"1500"`), true},
+		{"JSON uint64", "state/counters/in-pkts", ietfVal(`"1099511627776"`), false},
+		{"JSON uint64 as number", "state/counters/in-pkts", ietfVal("1099511627776"), true},
+		{"JSON decimal", "state/temperature", ietfVal(`"42.5"`), false},
+		{"JSON decimal as number", "state/temperature", ietfVal("42.5"), true},
+		{"JSON union uint", "state/priority", ietfVal("3"), false},
+		{"JSON leafref", "state/parent", ietfVal("9000"), false},
+		{"JSON enum", "state/oper-status", ietfVal(`"UP"`), false},
+		{"JSON leaf-list", "state/ips", ietfVal(`["192.0.2.1"]`), false},
+		{"not a leaf", "state/counters", uintVal(0), true},
+		{"not in the schema", "state/color", strVal("blue"), true},
+	} {
+		p, err := ygot.StringToStructuredPath(c.leaf)
+		if err != nil {
+			t.Fatalf("%s: cannot parse %s: %v", c.desc, c.leaf, err)
+		}
+		n := &gpb.Notification{
+			Prefix: &gpb.Path{Elem: []*gpb.PathElem{{Name: "interfaces"}, {Name: "interface", Key: map[string]string{"name": "eth0"}}}},
+			Update: []*gpb.Update{{Path: p, Val: c.val}},
+		}
+		got := NewFromRoot(newRoot()).Check(n)
+		if (len(got) > 0) != c.wantErr {
+			t.Errorf("%s: Check() got mismatches %v, want mismatch %v", c.desc, got, c.wantErr)
+		}
+	}
+}
+
+func TestFindPrefixes(t *testing.T) {
+	p, err := ygot.StringToStructuredPath("/openconfig-interfaces:interfaces/interface[name=eth0]/state/mtu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewFromRoot(newRoot()).Find(p)
+	if err != nil {
+		t.Fatalf("Find() got error %v", err)
+	}
+	if e.Name != "mtu" {
+		t.Errorf("Find() got %s, want mtu", e.Name)
+	}
+}
+
+func TestMismatchError(t *testing.T) {
+	m := &Mismatch{
+		Path:   "/interfaces/interface[name=eth0]/state/temperature",
+		Type:   "decimal64",
+		Units:  "celsius",
+		Value:  `string_val:"42.5"`,
+		Reason: "want decimal_val or float_val",
+	}
+	want := `/interfaces/interface[name=eth0]/state/temperature: string_val:"42.5" is not a valid decimal64 in celsius: want decimal_val or float_val`
+	if got := m.Error(); got != want {
+		t.Errorf("Error() got %q, want %q", got, want)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	ms := []*Mismatch{
+		{Path: "/a[k=2]/b", SchemaPath: "/a/b"},
+		{Path: "/c", SchemaPath: "/c"},
+		{Path: "/a[k=1]/b", SchemaPath: "/a/b"},
+	}
+	paths, counts, first := Summarize(ms)
+	if len(paths) != 2 || paths[0] != "/a/b" || paths[1] != "/c" {
+		t.Errorf("Summarize() got paths %v, want [/a/b /c]", paths)
+	}
+	if counts["/a/b"] != 2 || counts["/c"] != 1 {
+		t.Errorf("Summarize() got counts %v, want /a/b: 2, /c: 1", counts)
+	}
+	if first["/a/b"] != ms[0] {
+		t.Errorf("Summarize() got first %v, want %v", first["/a/b"], ms[0])
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package urpf enables unicast reverse path forwarding (uRPF, RFC 3704)
+// on the subinterfaces of a DUT, and checks from the traffic of the ATE
+// and the discard counters of the DUT that the packets of spoofed sources
+// are dropped while the legitimate traffic is forwarded.
+//
+// In strict mode, a packet passes the check only if the DUT has a route
+// back to its source through the interface it was received on.  In loose
+// mode, a route back through any interface will do.
+//
+// The urpf containers of openconfig-if-ip are newer than the models
+// generated by Ondatra, so the configuration is set with raw gNMI in
+// JSON_IETF.
+//
+// Usage:
+//
+//	c := &urpf.Config{Interface: intf, Mode: urpf.Strict}
+//	req, err := c.SetRequest()
+//	_, err = gnmiClient.Set(ctx, req)
+//	...
+//	before := urpf.ReadDiscards(t, dut, c)
+//	// Send the flows of the ATE.
+//	after := urpf.ReadDiscards(t, dut, c)
+//	for _, err := range urpf.Check(traffic) { t.Error(err) }
+//	if err := urpf.CheckDiscards(traffic, after-before); err != nil { t.Error(err) }
+package urpf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	gpb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// Mode is a uRPF mode, as named by openconfig-if-ip.
+type Mode string
+
+// The uRPF modes.
+const (
+	// Strict accepts the packets received on the interface of the route
+	// back to their source.
+	Strict Mode = "STRICT"
+	// Loose accepts the packets with a route back to their source through
+	// any interface.
+	Loose Mode = "LOOSE"
+)
+
+// Config is the uRPF configuration of an IPv4 or IPv6 subinterface.
+type Config struct {
+	Interface    string
+	Subinterface uint32
+	IPv6         bool
+	Mode         Mode
+	// AllowDefaultRoute lets a default route pass the check of the
+	// sources with no other route.
+	AllowDefaultRoute bool
+}
+
+// Validate returns an error if the configuration has no interface or an
+// unknown mode.
+func (c *Config) Validate() error {
+	if c.Interface == "" {
+		return errors.New("uRPF configuration without an interface")
+	}
+	if c.Mode != Strict && c.Mode != Loose {
+		return fmt.Errorf("uRPF of %v: unknown mode %q", c, c.Mode)
+	}
+	return nil
+}
+
+func (c *Config) String() string {
+	return fmt.Sprintf("%s.%d %s", c.Interface, c.Subinterface, c.family())
+}
+
+func (c *Config) family() string {
+	if c.IPv6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// path returns the path of the urpf container of the subinterface.
+func (c *Config) path() string {
+	return fmt.Sprintf("/interfaces/interface[name=%s]/subinterfaces/subinterface[index=%d]/%s/urpf", c.Interface, c.Subinterface, c.family())
+}
+
+// configJSON is the JSON_IETF of the urpf/config container.  The
+// top-level members are qualified with the name of their module.
+type configJSON struct {
+	Enabled           bool `json:"openconfig-if-ip:enabled"`
+	Mode              Mode `json:"openconfig-if-ip:mode"`
+	AllowDefaultRoute bool `json:"openconfig-if-ip:allow-default-route"`
+}
+
+// SetRequest returns the SetRequest replacing the uRPF configuration of
+// the subinterface, enabling it in its mode.
+func (c *Config) SetRequest() (*gpb.SetRequest, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	p, err := ygot.StringToStructuredPath(c.path() + "/config")
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(configJSON{Enabled: true, Mode: c.Mode, AllowDefaultRoute: c.AllowDefaultRoute})
+	if err != nil {
+		return nil, err
+	}
+	u := &gpb.Update{Path: p, Val: &gpb.TypedValue{Value: &gpb.TypedValue_JsonIetfVal{JsonIetfVal: b}}}
+	return &gpb.SetRequest{Replace: []*gpb.Update{u}}, nil
+}
+
+// DeleteRequest returns the SetRequest deleting the uRPF configuration of
+// the subinterface, disabling it.
+func (c *Config) DeleteRequest() (*gpb.SetRequest, error) {
+	p, err := ygot.StringToStructuredPath(c.path())
+	if err != nil {
+		return nil, err
+	}
+	return &gpb.SetRequest{Delete: []*gpb.Path{p}}, nil
+}
+
+// ReadDiscards returns the number of packets received and discarded by
+// the subinterface, in its address family.
+func ReadDiscards(t testing.TB, dut *ondatra.DUTDevice, c *Config) uint64 {
+	t.Helper()
+	s := dut.Telemetry().Interface(c.Interface).Subinterface(c.Subinterface)
+	if c.IPv6 {
+		return s.Ipv6().Counters().InDiscardedPkts().Get(t)
+	}
+	return s.Ipv4().Counters().InDiscardedPkts().Get(t)
+}
+
+// Traffic is the packets of a flow of the ATE through the DUT.
+type Traffic struct {
+	Flow string
+	// Spoofed is whether the source of the flow fails the uRPF check of
+	// the DUT.
+	Spoofed  bool
+	Sent     uint64
+	Received uint64
+}
+
+// Check returns an error for each flow which sent nothing, each spoofed
+// flow of which packets were received, and each legitimate flow of which
+// packets were lost.
+func Check(traffic []Traffic) []error {
+	var errs []error
+	for _, tr := range traffic {
+		switch {
+		case tr.Sent == 0:
+			errs = append(errs, fmt.Errorf("flow %s: no packets sent", tr.Flow))
+		case tr.Spoofed && tr.Received > 0:
+			errs = append(errs, fmt.Errorf("flow %s: %d of %d spoofed packets received, want 0", tr.Flow, tr.Received, tr.Sent))
+		case !tr.Spoofed && tr.Received < tr.Sent:
+			errs = append(errs, fmt.Errorf("flow %s: %d of %d packets received, want all", tr.Flow, tr.Received, tr.Sent))
+		}
+	}
+	return errs
+}
+
+// CheckDiscards returns an error if the DUT discarded fewer packets than
+// the spoofed flows sent.  The discards may include other packets, so
+// more are accepted.
+func CheckDiscards(traffic []Traffic, discards uint64) error {
+	var spoofed uint64
+	for _, tr := range traffic {
+		if tr.Spoofed {
+			spoofed += tr.Sent
+		}
+	}
+	if discards < spoofed {
+		return fmt.Errorf("%d packets discarded, want at least the %d spoofed packets sent", discards, spoofed)
+	}
+	return nil
+}
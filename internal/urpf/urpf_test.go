@@ -0,0 +1,193 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package urpf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/ygot/ygot"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		c       *Config
+		wantErr bool
+	}{{
+		desc: "strict",
+		c:    &Config{Interface: "eth1", Mode: Strict},
+	}, {
+		desc: "loose ipv6",
+		c:    &Config{Interface: "eth1", IPv6: true, Mode: Loose},
+	}, {
+		desc:    "no interface",
+		c:       &Config{Mode: Strict},
+		wantErr: true,
+	}, {
+		desc:    "no mode",
+		c:       &Config{Interface: "eth1"},
+		wantErr: true,
+	}, {
+		desc:    "unknown mode",
+		c:       &Config{Interface: "eth1", Mode: "FEASIBLE"},
+		wantErr: true,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := tc.c.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("Validate() got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetRequest(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		c        *Config
+		wantPath string
+		wantVal  map[string]interface{}
+	}{{
+		desc:     "strict ipv4",
+		c:        &Config{Interface: "eth1", Mode: Strict},
+		wantPath: "/interfaces/interface[name=eth1]/subinterfaces/subinterface[index=0]/ipv4/urpf/config",
+		wantVal: map[string]interface{}{
+			"openconfig-if-ip:enabled":             true,
+			"openconfig-if-ip:mode":                "STRICT",
+			"openconfig-if-ip:allow-default-route": false,
+		},
+	}, {
+		desc:     "loose ipv6 with default route",
+		c:        &Config{Interface: "eth2", Subinterface: 10, IPv6: true, Mode: Loose, AllowDefaultRoute: true},
+		wantPath: "/interfaces/interface[name=eth2]/subinterfaces/subinterface[index=10]/ipv6/urpf/config",
+		wantVal: map[string]interface{}{
+			"openconfig-if-ip:enabled":             true,
+			"openconfig-if-ip:mode":                "LOOSE",
+			"openconfig-if-ip:allow-default-route": true,
+		},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			req, err := tc.c.SetRequest()
+			if err != nil {
+				t.Fatalf("SetRequest() got error %v", err)
+			}
+			if n := len(req.GetReplace()); n != 1 {
+				t.Fatalf("SetRequest() got %d replaces, want 1", n)
+			}
+			u := req.GetReplace()[0]
+			p, err := ygot.PathToString(u.GetPath())
+			if err != nil {
+				t.Fatalf("PathToString(%v) got error %v", u.GetPath(), err)
+			}
+			if p != tc.wantPath {
+				t.Errorf("SetRequest() path got %s, want %s", p, tc.wantPath)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(u.GetVal().GetJsonIetfVal(), &got); err != nil {
+				t.Fatalf("Cannot decode %s: %v", u.GetVal().GetJsonIetfVal(), err)
+			}
+			if diff := cmp.Diff(tc.wantVal, got); diff != "" {
+				t.Errorf("SetRequest() value diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := (&Config{Interface: "eth1"}).SetRequest(); err == nil {
+		t.Error("SetRequest() of a configuration without a mode got no error")
+	}
+}
+
+func TestDeleteRequest(t *testing.T) {
+	req, err := (&Config{Interface: "eth1", Mode: Strict}).DeleteRequest()
+	if err != nil {
+		t.Fatalf("DeleteRequest() got error %v", err)
+	}
+	var paths []string
+	for _, d := range req.GetDelete() {
+		p, err := ygot.PathToString(d)
+		if err != nil {
+			t.Fatalf("PathToString(%v) got error %v", d, err)
+		}
+		paths = append(paths, p)
+	}
+	want := []string{"/interfaces/interface[name=eth1]/subinterfaces/subinterface[index=0]/ipv4/urpf"}
+	if diff := cmp.Diff(want, paths); diff != "" {
+		t.Errorf("DeleteRequest() paths diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		traffic []Traffic
+		wantErr int
+	}{{
+		desc: "dropped and forwarded",
+		traffic: []Traffic{
+			{Flow: "legitimate", Sent: 100, Received: 100},
+			{Flow: "spoofed", Spoofed: true, Sent: 100},
+		},
+	}, {
+		desc: "spoofed forwarded",
+		traffic: []Traffic{
+			{Flow: "legitimate", Sent: 100, Received: 100},
+			{Flow: "spoofed", Spoofed: true, Sent: 100, Received: 3},
+		},
+		wantErr: 1,
+	}, {
+		desc: "legitimate lost",
+		traffic: []Traffic{
+			{Flow: "legitimate", Sent: 100, Received: 99},
+			{Flow: "spoofed", Spoofed: true, Sent: 100},
+		},
+		wantErr: 1,
+	}, {
+		desc: "nothing sent",
+		traffic: []Traffic{
+			{Flow: "legitimate"},
+			{Flow: "spoofed", Spoofed: true},
+		},
+		wantErr: 2,
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			errs := Check(tc.traffic)
+			if len(errs) != tc.wantErr {
+				t.Errorf("Check() got errors %v, want %d", errs, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckDiscards(t *testing.T) {
+	traffic := []Traffic{
+		{Flow: "legitimate", Sent: 100, Received: 100},
+		{Flow: "spoofed", Spoofed: true, Sent: 100},
+		{Flow: "unroutable", Spoofed: true, Sent: 50},
+	}
+	for _, tc := range []struct {
+		discards uint64
+		wantErr  bool
+	}{
+		{discards: 150},
+		{discards: 152},
+		{discards: 149, wantErr: true},
+		{discards: 0, wantErr: true},
+	} {
+		if err := CheckDiscards(traffic, tc.discards); (err != nil) != tc.wantErr {
+			t.Errorf("CheckDiscards(%d) got error %v, want error %v", tc.discards, err, tc.wantErr)
+		}
+	}
+}
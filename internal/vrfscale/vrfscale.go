@@ -0,0 +1,352 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vrfscale generates multi-VRF topologies from a compact spec:
+// N VRFs with M VLAN subinterfaces each on a DUT port, their ATE peers,
+// and prefixes routed behind each peer.  It spares VRF scale and policy
+// forwarding tests from writing out every subinterface, network
+// instance, static route and ATE endpoint by hand.
+//
+// Usage:
+//
+//	spec := &vrfscale.Spec{
+//	  VRFs:       args.Scale(16),
+//	  Interfaces: 2,
+//	  Prefixes:   4,
+//	  IPv4Links:  "192.0.2.0/30",
+//	  IPv4Routes: "203.0.113.0/29",
+//	}
+//	tp, err := spec.Generate()
+//	...
+//	tp.Configure(t, dut, dut.Port(t, "port1").Name())
+//	tp.AddToATE(top, ate.Port(t, "port1"))
+package vrfscale
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openconfig/featureprofiles/internal/attrs"
+	"github.com/openconfig/featureprofiles/internal/deviations"
+	"github.com/openconfig/featureprofiles/internal/fibscale"
+	"github.com/openconfig/featureprofiles/internal/fptest"
+	"github.com/openconfig/featureprofiles/internal/ippool"
+	"github.com/openconfig/ondatra"
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+const (
+	// StaticName is the name of the static routing protocol of the VRFs.
+	StaticName = "STATIC"
+	// maxVLAN is the highest VLAN ID usable by a subinterface.
+	maxVLAN = 4094
+)
+
+// Spec is a compact description of a multi-VRF topology.  The links
+// and the routes are allocated consecutively across all the VRFs, in
+// the order of the VRFs and of their interfaces.
+type Spec struct {
+	// VRFs is the number of VRFs, named NamePrefix followed by their
+	// index from 1.
+	VRFs       int
+	NamePrefix string // Defaults to "VRF-".
+	// Interfaces is the number of VLAN subinterfaces of each VRF.
+	Interfaces int
+	// Prefixes is the number of prefixes routed behind each interface,
+	// per address family.
+	Prefixes int
+	// FirstVLAN is the VLAN ID of the first subinterface, and the
+	// others follow it.  Defaults to 10.
+	FirstVLAN uint16
+
+	// IPv4Links and IPv6Links are the first link subnets in CIDR
+	// notation, e.g. "192.0.2.0/30" and "2001:db8::/126".  The DUT takes
+	// the first address of each link and the ATE the second.  At least
+	// one of them is required.
+	IPv4Links string
+	IPv6Links string
+	// IPv4Routes and IPv6Routes are the first prefixes routed behind the
+	// interfaces, required when Prefixes is not zero for the address
+	// family of the links.
+	IPv4Routes string
+	IPv6Routes string
+}
+
+// Endpoint is an interface of a VRF: a VLAN subinterface of the DUT
+// port, indexed by its VLAN ID, and its peer on the ATE port.
+type Endpoint struct {
+	VRF  string
+	VLAN uint16
+	DUT  attrs.Attributes
+	ATE  attrs.Attributes
+	// IPv4Routes and IPv6Routes are the prefixes routed to the ATE peer.
+	IPv4Routes []string
+	IPv6Routes []string
+}
+
+// Subinterface returns the index of the DUT subinterface.
+func (e *Endpoint) Subinterface() uint32 {
+	return uint32(e.VLAN)
+}
+
+// NetworkName returns the name of the ATE network of a route of the
+// endpoint.
+func (e *Endpoint) NetworkName(route string) string {
+	return e.ATE.Name + "-" + route
+}
+
+// VRF is a generated VRF and its interfaces.
+type VRF struct {
+	Name      string
+	Endpoints []*Endpoint
+}
+
+// Topology is a generated multi-VRF topology.
+type Topology struct {
+	VRFs []*VRF
+}
+
+// Endpoints returns the endpoints of all the VRFs.
+func (tp *Topology) Endpoints() []*Endpoint {
+	var es []*Endpoint
+	for _, v := range tp.VRFs {
+		es = append(es, v.Endpoints...)
+	}
+	return es
+}
+
+// validate checks the spec and fills in its defaults.
+func (s *Spec) validate() error {
+	if s.VRFs <= 0 || s.Interfaces <= 0 {
+		return fmt.Errorf("invalid spec: got %d VRFs of %d interfaces, want at least one of each", s.VRFs, s.Interfaces)
+	}
+	if s.Prefixes < 0 {
+		return fmt.Errorf("invalid spec: negative number of prefixes %d", s.Prefixes)
+	}
+	if s.IPv4Links == "" && s.IPv6Links == "" {
+		return fmt.Errorf("invalid spec: no IPv4 or IPv6 links")
+	}
+	if s.Prefixes > 0 && ((s.IPv4Links != "" && s.IPv4Routes == "") || (s.IPv6Links != "" && s.IPv6Routes == "")) {
+		return fmt.Errorf("invalid spec: %d prefixes per interface without routes for every address family of the links", s.Prefixes)
+	}
+	if s.NamePrefix == "" {
+		s.NamePrefix = "VRF-"
+	}
+	if s.FirstVLAN == 0 {
+		s.FirstVLAN = 10
+	}
+	if n := int(s.FirstVLAN) + s.VRFs*s.Interfaces - 1; n > maxVLAN {
+		return fmt.Errorf("invalid spec: %d interfaces from VLAN %d exceed VLAN %d", s.VRFs*s.Interfaces, s.FirstVLAN, maxVLAN)
+	}
+	return nil
+}
+
+// links returns count link subnets from the first one, or empty links
+// if there is no first subnet.
+func links(first string, count int) ([]ippool.Link, error) {
+	ls := make([]ippool.Link, count)
+	if first == "" {
+		return ls, nil
+	}
+	subnets, err := fibscale.Prefixes(first, count)
+	if err != nil {
+		return nil, err
+	}
+	for i, subnet := range subnets {
+		l, err := ippool.NewLink(subnet)
+		if err != nil {
+			return nil, err
+		}
+		ls[i] = *l
+	}
+	return ls, nil
+}
+
+// routes returns count prefixes from the first one, or none if there
+// is no first prefix.
+func routes(first string, count int) ([]string, error) {
+	if first == "" || count == 0 {
+		return nil, nil
+	}
+	return fibscale.Prefixes(first, count)
+}
+
+// Generate generates the topology of the spec.
+func (s *Spec) Generate() (*Topology, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	n := s.VRFs * s.Interfaces
+	v4, err := links(s.IPv4Links, n)
+	if err != nil {
+		return nil, fmt.Errorf("cannot allocate the IPv4 links: %w", err)
+	}
+	v6, err := links(s.IPv6Links, n)
+	if err != nil {
+		return nil, fmt.Errorf("cannot allocate the IPv6 links: %w", err)
+	}
+	r4, err := routes(s.IPv4Routes, n*s.Prefixes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot allocate the IPv4 routes: %w", err)
+	}
+	r6, err := routes(s.IPv6Routes, n*s.Prefixes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot allocate the IPv6 routes: %w", err)
+	}
+
+	tp := &Topology{}
+	for i := 0; i < s.VRFs; i++ {
+		vrf := &VRF{Name: fmt.Sprintf("%s%d", s.NamePrefix, i+1)}
+		for j := 0; j < s.Interfaces; j++ {
+			k := i*s.Interfaces + j
+			e := &Endpoint{VRF: vrf.Name, VLAN: s.FirstVLAN + uint16(k)}
+			e.DUT = attrs.Attributes{
+				Desc:    fmt.Sprintf("%s VLAN %d", vrf.Name, e.VLAN),
+				IPv4:    v4[k].DUT,
+				IPv4Len: v4[k].Len,
+				IPv6:    v6[k].DUT,
+				IPv6Len: v6[k].Len,
+			}
+			e.ATE = attrs.Attributes{
+				Name:    fmt.Sprintf("%s_%d", vrf.Name, e.VLAN),
+				IPv4:    v4[k].ATE,
+				IPv4Len: v4[k].Len,
+				IPv6:    v6[k].ATE,
+				IPv6Len: v6[k].Len,
+			}
+			if r4 != nil {
+				e.IPv4Routes = r4[k*s.Prefixes : (k+1)*s.Prefixes]
+			}
+			if r6 != nil {
+				e.IPv6Routes = r6[k*s.Prefixes : (k+1)*s.Prefixes]
+			}
+			vrf.Endpoints = append(vrf.Endpoints, e)
+		}
+		tp.VRFs = append(tp.VRFs, vrf)
+	}
+	return tp, nil
+}
+
+// ConfigInterface adds the subinterfaces of all the endpoints to the
+// DUT port interface.
+func (tp *Topology) ConfigInterface(i *telemetry.Interface) *telemetry.Interface {
+	i.Type = telemetry.IETFInterfaces_InterfaceType_ethernetCsmacd
+	if *deviations.InterfaceEnabled {
+		i.Enabled = ygot.Bool(true)
+	}
+	for _, e := range tp.Endpoints() {
+		s := i.GetOrCreateSubinterface(e.Subinterface())
+		s.Description = ygot.String(e.DUT.Desc)
+		s.GetOrCreateVlan().GetOrCreateMatch().GetOrCreateSingleTagged().VlanId = ygot.Uint16(e.VLAN)
+		if *deviations.InterfaceEnabled {
+			s.Enabled = ygot.Bool(true)
+		}
+		if e.DUT.IPv4 != "" {
+			s4 := s.GetOrCreateIpv4()
+			if *deviations.InterfaceEnabled {
+				s4.Enabled = ygot.Bool(true)
+			}
+			s4.GetOrCreateAddress(e.DUT.IPv4).PrefixLength = ygot.Uint8(e.DUT.IPv4Len)
+		}
+		if e.DUT.IPv6 != "" {
+			s6 := s.GetOrCreateIpv6()
+			if *deviations.InterfaceEnabled {
+				s6.Enabled = ygot.Bool(true)
+			}
+			s6.GetOrCreateAddress(e.DUT.IPv6).PrefixLength = ygot.Uint8(e.DUT.IPv6Len)
+		}
+	}
+	return i
+}
+
+// NetworkInstances returns the L3VRFs of the topology on the DUT port,
+// with their subinterfaces and the static routes of their prefixes to
+// the ATE peers.
+func (tp *Topology) NetworkInstances(port string) []*telemetry.NetworkInstance {
+	d := &telemetry.Device{}
+	var nis []*telemetry.NetworkInstance
+	for _, v := range tp.VRFs {
+		ni := d.GetOrCreateNetworkInstance(v.Name)
+		ni.Type = telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L3VRF
+		ni.Enabled = ygot.Bool(true)
+		var static *telemetry.NetworkInstance_Protocol
+		for _, e := range v.Endpoints {
+			niIntf := ni.GetOrCreateInterface(fmt.Sprintf("%s.%d", port, e.Subinterface()))
+			niIntf.Interface = ygot.String(port)
+			niIntf.Subinterface = ygot.Uint32(e.Subinterface())
+			if len(e.IPv4Routes)+len(e.IPv6Routes) == 0 {
+				continue
+			}
+			if static == nil {
+				static = ni.GetOrCreateProtocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, StaticName)
+			}
+			for _, r := range e.IPv4Routes {
+				static.GetOrCreateStatic(r).GetOrCreateNextHop("0").NextHop = telemetry.UnionString(e.ATE.IPv4)
+			}
+			for _, r := range e.IPv6Routes {
+				static.GetOrCreateStatic(r).GetOrCreateNextHop("0").NextHop = telemetry.UnionString(e.ATE.IPv6)
+			}
+		}
+		nis = append(nis, ni)
+	}
+	return nis
+}
+
+// Configure replaces the DUT port with its subinterfaces and the VRFs
+// of the topology.
+func (tp *Topology) Configure(t testing.TB, dut *ondatra.DUTDevice, port string) {
+	t.Helper()
+	d := dut.Config()
+	i := tp.ConfigInterface(&telemetry.Interface{Name: ygot.String(port)})
+	fptest.LogYgot(t, "DUT port", d.Interface(port), i)
+	d.Interface(port).Replace(t, i)
+	for _, ni := range tp.NetworkInstances(port) {
+		d.NetworkInstance(ni.GetName()).Replace(t, ni)
+	}
+}
+
+// Unconfigure deletes the VRFs of the topology from the DUT.
+func (tp *Topology) Unconfigure(t testing.TB, dut *ondatra.DUTDevice) {
+	t.Helper()
+	for _, v := range tp.VRFs {
+		dut.Config().NetworkInstance(v.Name).Delete(t)
+	}
+}
+
+// AddToATE adds the ATE peers of all the endpoints to the ATE port, with
+// their routed prefixes as networks, and returns them by the name of
+// their endpoint.
+func (tp *Topology) AddToATE(top *ondatra.ATETopology, ap *ondatra.Port) map[string]*ondatra.Interface {
+	intfs := map[string]*ondatra.Interface{}
+	for _, e := range tp.Endpoints() {
+		i := top.AddInterface(e.ATE.Name).WithPort(ap)
+		i.Ethernet().WithVLANID(e.VLAN)
+		if e.ATE.IPv4 != "" {
+			i.IPv4().WithAddress(e.ATE.IPv4CIDR()).WithDefaultGateway(e.DUT.IPv4)
+		}
+		if e.ATE.IPv6 != "" {
+			i.IPv6().WithAddress(e.ATE.IPv6CIDR()).WithDefaultGateway(e.DUT.IPv6)
+		}
+		for _, r := range e.IPv4Routes {
+			i.AddNetwork(e.NetworkName(r)).IPv4().WithAddress(r).WithCount(1)
+		}
+		for _, r := range e.IPv6Routes {
+			i.AddNetwork(e.NetworkName(r)).IPv6().WithAddress(r).WithCount(1)
+		}
+		intfs[e.ATE.Name] = i
+	}
+	return intfs
+}
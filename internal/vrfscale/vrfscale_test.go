@@ -0,0 +1,149 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vrfscale
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/internal/attrs"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func newSpec() *Spec {
+	return &Spec{
+		VRFs:       2,
+		Interfaces: 2,
+		Prefixes:   2,
+		IPv4Links:  "192.0.2.0/30",
+		IPv6Links:  "2001:db8::/126",
+		IPv4Routes: "203.0.113.0/29",
+		IPv6Routes: "2001:db8:1::/64",
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tp, err := newSpec().Generate()
+	if err != nil {
+		t.Fatalf("Generate() got error: %v", err)
+	}
+	var names []string
+	for _, v := range tp.VRFs {
+		names = append(names, v.Name)
+	}
+	if diff := cmp.Diff([]string{"VRF-1", "VRF-2"}, names); diff != "" {
+		t.Errorf("VRF names -want, +got:\n%s", diff)
+	}
+	es := tp.Endpoints()
+	if len(es) != 4 {
+		t.Fatalf("Endpoints() got %d endpoints, want 4", len(es))
+	}
+	want := &Endpoint{
+		VRF:  "VRF-2",
+		VLAN: 13,
+		DUT: attrs.Attributes{
+			Desc: "VRF-2 VLAN 13", IPv4: "192.0.2.13", IPv4Len: 30, IPv6: "2001:db8::d", IPv6Len: 126,
+		},
+		ATE: attrs.Attributes{
+			Name: "VRF-2_13", IPv4: "192.0.2.14", IPv4Len: 30, IPv6: "2001:db8::e", IPv6Len: 126,
+		},
+		IPv4Routes: []string{"203.0.113.48/29", "203.0.113.56/29"},
+		IPv6Routes: []string{"2001:db8:1:6::/64", "2001:db8:1:7::/64"},
+	}
+	if diff := cmp.Diff(want, es[3]); diff != "" {
+		t.Errorf("Last endpoint -want, +got:\n%s", diff)
+	}
+}
+
+func TestGenerateIPv4Only(t *testing.T) {
+	tp, err := (&Spec{VRFs: 1, Interfaces: 1, IPv4Links: "192.0.2.0/31", NamePrefix: "RED", FirstVLAN: 100}).Generate()
+	if err != nil {
+		t.Fatalf("Generate() got error: %v", err)
+	}
+	e := tp.Endpoints()[0]
+	if e.VRF != "RED1" || e.VLAN != 100 || e.DUT.IPv4 != "192.0.2.0" || e.DUT.IPv6 != "" || e.IPv4Routes != nil {
+		t.Errorf("Endpoint got %+v, want VRF RED1, VLAN 100, DUT 192.0.2.0 and no IPv6 or routes", e)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, c := range []struct {
+		desc   string
+		modify func(s *Spec)
+	}{
+		{"no VRFs", func(s *Spec) { s.VRFs = 0 }},
+		{"no interfaces", func(s *Spec) { s.Interfaces = 0 }},
+		{"no links", func(s *Spec) { s.IPv4Links, s.IPv6Links = "", "" }},
+		{"no IPv6 routes", func(s *Spec) { s.IPv6Routes = "" }},
+		{"too many VLANs", func(s *Spec) { s.FirstVLAN = 4093 }},
+		{"IPv4 links exhausted", func(s *Spec) { s.IPv4Links = "255.255.255.248/30" }},
+		{"link without ATE address", func(s *Spec) { s.IPv4Links = "192.0.2.0/32" }},
+		{"invalid routes", func(s *Spec) { s.IPv4Routes = "203.0.113.1/29" }},
+	} {
+		s := newSpec()
+		c.modify(s)
+		if _, err := s.Generate(); err == nil {
+			t.Errorf("%s: Generate() got no error, want error", c.desc)
+		}
+	}
+}
+
+func TestConfigInterface(t *testing.T) {
+	tp, err := newSpec().Generate()
+	if err != nil {
+		t.Fatalf("Generate() got error: %v", err)
+	}
+	i := tp.ConfigInterface(&telemetry.Interface{})
+	if got := len(i.Subinterface); got != 4 {
+		t.Fatalf("ConfigInterface() got %d subinterfaces, want 4", got)
+	}
+	s := i.GetSubinterface(11)
+	if got := s.GetVlan().GetMatch().GetSingleTagged().GetVlanId(); got != 11 {
+		t.Errorf("Subinterface 11 got VLAN %d, want 11", got)
+	}
+	if got := s.GetIpv4().GetAddress("192.0.2.5").GetPrefixLength(); got != 30 {
+		t.Errorf("Subinterface 11 got IPv4 prefix length %d, want 30", got)
+	}
+	if got := s.GetIpv6().GetAddress("2001:db8::5").GetPrefixLength(); got != 126 {
+		t.Errorf("Subinterface 11 got IPv6 prefix length %d, want 126", got)
+	}
+}
+
+func TestNetworkInstances(t *testing.T) {
+	tp, err := newSpec().Generate()
+	if err != nil {
+		t.Fatalf("Generate() got error: %v", err)
+	}
+	nis := tp.NetworkInstances("port1")
+	if len(nis) != 2 {
+		t.Fatalf("NetworkInstances() got %d network instances, want 2", len(nis))
+	}
+	ni := nis[0]
+	if ni.GetName() != "VRF-1" || ni.GetType() != telemetry.NetworkInstanceTypes_NETWORK_INSTANCE_TYPE_L3VRF {
+		t.Errorf("NetworkInstances() got %s of type %v, want VRF-1 of type L3VRF", ni.GetName(), ni.GetType())
+	}
+	if got := ni.GetInterface("port1.11").GetSubinterface(); got != 11 {
+		t.Errorf("Interface port1.11 got subinterface %d, want 11", got)
+	}
+	static := ni.GetProtocol(telemetry.PolicyTypes_INSTALL_PROTOCOL_TYPE_STATIC, StaticName)
+	if got := len(static.Static); got != 8 {
+		t.Errorf("Static routes got %d, want 8", got)
+	}
+	nh, ok := static.GetStatic("203.0.113.8/29").GetNextHop("0").NextHop.(telemetry.UnionString)
+	if !ok || nh != "192.0.2.2" {
+		t.Errorf("Next hop of 203.0.113.8/29 got %v, want 192.0.2.2", nh)
+	}
+}
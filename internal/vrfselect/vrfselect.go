@@ -0,0 +1,222 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vrfselect builds class based VRF selection policies, which
+// select the network instance forwarding an IPv4 packet by its DSCP, and
+// verifies the network instances that forwarded each DSCP against the
+// policy.  The full DSCP matrix of a policy replaces checking each class
+// by hand.
+//
+// Usage:
+//
+//	p, err := vrfselect.TE("VRF-SELECTION", *deviations.DefaultNetworkInstance, vrfs)
+//	...
+//	pf := p.Config(dut.Port(t, "port1").Name())
+//	dut.Config().NetworkInstance(ni).PolicyForwarding().Replace(t, pf)
+//	...
+//	for _, err := range p.Verify(counts) {
+//	  t.Error(err)
+//	}
+package vrfselect
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/openconfig/ygot/ygot"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+// MaxDSCP is the highest DSCP value.
+const MaxDSCP = 63
+
+// DefaultClass is the name of the class of the DSCPs matching no class of
+// a policy.
+const DefaultClass = "default"
+
+// Class is a traffic class: the DSCPs selecting a network instance.
+type Class struct {
+	Name            string
+	DSCPs           []uint8
+	NetworkInstance string
+}
+
+// Policy is a VRF selection policy.  Its classes are matched in order,
+// and packets matching none are forwarded by the default network
+// instance.
+type Policy struct {
+	Name    string
+	Classes []Class
+	Default string
+}
+
+// dscps returns the DSCPs from first to last.
+func dscps(first, last uint8) []uint8 {
+	var ds []uint8
+	for d := first; d <= last; d++ {
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+// teClasses are the classes of the TE designs, by the DSCPs of their
+// class selectors.  The best effort class selector 0 is left to the
+// default network instance.
+var teClasses = []Class{
+	{Name: "AF1", DSCPs: dscps(8, 15)},
+	{Name: "AF2", DSCPs: dscps(16, 23)},
+	{Name: "AF3", DSCPs: dscps(24, 31)},
+	{Name: "AF4", DSCPs: dscps(32, 39)},
+	{Name: "EF", DSCPs: dscps(40, 47)},
+	{Name: "NC", DSCPs: dscps(48, 63)},
+}
+
+// TE returns the VRF selection policy of the TE designs, which selects one
+// of the vrfs for each of their classes, in order, and the default network
+// instance for best effort traffic.
+func TE(name, defaultNI string, vrfs []string) (*Policy, error) {
+	if len(vrfs) != len(teClasses) {
+		return nil, fmt.Errorf("got %d VRFs, want one for each of the %d classes", len(vrfs), len(teClasses))
+	}
+	p := &Policy{Name: name, Default: defaultNI}
+	for i, c := range teClasses {
+		c.NetworkInstance = vrfs[i]
+		p.Classes = append(p.Classes, c)
+	}
+	return p, nil
+}
+
+// Validate returns an error if the policy cannot be configured.
+func (p *Policy) Validate() error {
+	if p.Name == "" || p.Default == "" {
+		return fmt.Errorf("policy name and default network instance are required")
+	}
+	class := map[uint8]string{}
+	for _, c := range p.Classes {
+		switch {
+		case c.Name == "" || c.Name == DefaultClass:
+			return fmt.Errorf("invalid class name %q", c.Name)
+		case c.NetworkInstance == "":
+			return fmt.Errorf("class %s has no network instance", c.Name)
+		case len(c.DSCPs) == 0:
+			return fmt.Errorf("class %s has no DSCP", c.Name)
+		}
+		for _, d := range c.DSCPs {
+			if d > MaxDSCP {
+				return fmt.Errorf("class %s has invalid DSCP %d", c.Name, d)
+			}
+			if other, ok := class[d]; ok {
+				return fmt.Errorf("DSCP %d is in classes %s and %s", d, other, c.Name)
+			}
+			class[d] = c.Name
+		}
+	}
+	return nil
+}
+
+// Selection is the class of a DSCP and the network instance it selects.
+type Selection struct {
+	DSCP            uint8
+	Class           string
+	NetworkInstance string
+}
+
+// Select returns the selection of a DSCP by the policy.
+func (p *Policy) Select(dscp uint8) Selection {
+	for _, c := range p.Classes {
+		for _, d := range c.DSCPs {
+			if d == dscp {
+				return Selection{DSCP: dscp, Class: c.Name, NetworkInstance: c.NetworkInstance}
+			}
+		}
+	}
+	return Selection{DSCP: dscp, Class: DefaultClass, NetworkInstance: p.Default}
+}
+
+// Matrix returns the selections of all the DSCPs, in order.
+func (p *Policy) Matrix() []Selection {
+	var m []Selection
+	for d := uint8(0); d <= MaxDSCP; d++ {
+		m = append(m, p.Select(d))
+	}
+	return m
+}
+
+// NetworkInstances returns the network instances selected by the policy:
+// those of its classes, in order, then the default one.
+func (p *Policy) NetworkInstances() []string {
+	var nis []string
+	seen := map[string]bool{}
+	for _, c := range p.Classes {
+		if !seen[c.NetworkInstance] {
+			seen[c.NetworkInstance] = true
+			nis = append(nis, c.NetworkInstance)
+		}
+	}
+	if !seen[p.Default] {
+		nis = append(nis, p.Default)
+	}
+	return nis
+}
+
+// Config returns the policy forwarding configuration of the policy,
+// applied to subinterface 0 of the given interfaces.  Each class is a
+// rule, in order, and a last rule matching all packets selects the default
+// network instance.
+func (p *Policy) Config(intfs ...string) *telemetry.NetworkInstance_PolicyForwarding {
+	pf := &telemetry.NetworkInstance_PolicyForwarding{}
+	pol := pf.GetOrCreatePolicy(p.Name)
+	pol.Type = telemetry.Policy_Type_VRF_SELECTION_POLICY
+	for i, c := range p.Classes {
+		r := pol.GetOrCreateRule(uint32(i + 1))
+		r.GetOrCreateIpv4().DscpSet = append([]uint8{}, c.DSCPs...)
+		r.GetOrCreateAction().NetworkInstance = ygot.String(c.NetworkInstance)
+	}
+	pol.GetOrCreateRule(uint32(len(p.Classes) + 1)).GetOrCreateAction().NetworkInstance = ygot.String(p.Default)
+	for _, name := range intfs {
+		i := pf.GetOrCreateInterface(name)
+		i.ApplyVrfSelectionPolicy = ygot.String(p.Name)
+		i.GetOrCreateInterfaceRef().Interface = ygot.String(name)
+		i.GetOrCreateInterfaceRef().Subinterface = ygot.Uint32(0)
+	}
+	return pf
+}
+
+// Verify returns the differences between the network instances which
+// forwarded the packets of each DSCP, given by the number of packets
+// forwarded by each, and the network instance the policy selects.  Each
+// DSCP of the matrix with no packet forwarded is an error.
+func (p *Policy) Verify(counts map[uint8]map[string]uint64) []error {
+	var errs []error
+	for _, s := range p.Matrix() {
+		byNI := counts[s.DSCP]
+		var total uint64
+		var wrong []string
+		for ni, n := range byNI {
+			total += n
+			if ni != s.NetworkInstance && n > 0 {
+				wrong = append(wrong, fmt.Sprintf("%d by %s", n, ni))
+			}
+		}
+		sort.Strings(wrong)
+		switch {
+		case total == 0:
+			errs = append(errs, fmt.Errorf("DSCP %d of class %s: no packet forwarded, want them forwarded by %s", s.DSCP, s.Class, s.NetworkInstance))
+		case len(wrong) > 0:
+			errs = append(errs, fmt.Errorf("DSCP %d of class %s: %d packets forwarded, %v, want all by %s", s.DSCP, s.Class, total, wrong, s.NetworkInstance))
+		}
+	}
+	return errs
+}
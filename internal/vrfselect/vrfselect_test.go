@@ -0,0 +1,169 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vrfselect
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+var vrfs = []string{"VRF-AF1", "VRF-AF2", "VRF-AF3", "VRF-AF4", "VRF-EF", "VRF-NC"}
+
+func newPolicy(t *testing.T) *Policy {
+	t.Helper()
+	p, err := TE("VRF-SELECTION", "DEFAULT", vrfs)
+	if err != nil {
+		t.Fatalf("TE() got error: %v", err)
+	}
+	return p
+}
+
+func TestTE(t *testing.T) {
+	if err := newPolicy(t).Validate(); err != nil {
+		t.Errorf("Validate() of the TE policy got error: %v", err)
+	}
+	if _, err := TE("VRF-SELECTION", "DEFAULT", vrfs[1:]); err == nil {
+		t.Error("TE() with a VRF missing got no error, want error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []struct {
+		desc    string
+		modify  func(p *Policy)
+		wantErr bool
+	}{
+		{"valid", func(p *Policy) {}, false},
+		{"no classes", func(p *Policy) { p.Classes = nil }, false},
+		{"no name", func(p *Policy) { p.Name = "" }, true},
+		{"no default", func(p *Policy) { p.Default = "" }, true},
+		{"no class name", func(p *Policy) { p.Classes[0].Name = "" }, true},
+		{"default class name", func(p *Policy) { p.Classes[0].Name = DefaultClass }, true},
+		{"no network instance", func(p *Policy) { p.Classes[0].NetworkInstance = "" }, true},
+		{"no DSCP", func(p *Policy) { p.Classes[0].DSCPs = nil }, true},
+		{"invalid DSCP", func(p *Policy) { p.Classes[0].DSCPs = []uint8{64} }, true},
+		{"DSCP in two classes", func(p *Policy) { p.Classes[0].DSCPs = []uint8{8, 16} }, true},
+	} {
+		p := newPolicy(t)
+		c.modify(p)
+		if err := p.Validate(); (err != nil) != c.wantErr {
+			t.Errorf("%s: Validate() got error %v, want error %v", c.desc, err, c.wantErr)
+		}
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	m := newPolicy(t).Matrix()
+	if len(m) != MaxDSCP+1 {
+		t.Fatalf("Matrix() got %d selections, want %d", len(m), MaxDSCP+1)
+	}
+	for _, want := range []Selection{
+		{0, DefaultClass, "DEFAULT"},
+		{7, DefaultClass, "DEFAULT"},
+		{8, "AF1", "VRF-AF1"},
+		{26, "AF3", "VRF-AF3"},
+		{46, "EF", "VRF-EF"},
+		{48, "NC", "VRF-NC"},
+		{63, "NC", "VRF-NC"},
+	} {
+		if diff := cmp.Diff(want, m[want.DSCP]); diff != "" {
+			t.Errorf("Selection of DSCP %d -want, +got:\n%s", want.DSCP, diff)
+		}
+	}
+}
+
+func TestNetworkInstances(t *testing.T) {
+	p := newPolicy(t)
+	want := append(append([]string{}, vrfs...), "DEFAULT")
+	if diff := cmp.Diff(want, p.NetworkInstances()); diff != "" {
+		t.Errorf("NetworkInstances() -want, +got:\n%s", diff)
+	}
+	p.Classes[1].NetworkInstance = "VRF-AF1"
+	p.Classes[2].NetworkInstance = "DEFAULT"
+	want = []string{"VRF-AF1", "DEFAULT", "VRF-AF4", "VRF-EF", "VRF-NC"}
+	if diff := cmp.Diff(want, p.NetworkInstances()); diff != "" {
+		t.Errorf("NetworkInstances() with shared network instances -want, +got:\n%s", diff)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	p := &Policy{
+		Name:    "VRF-SELECTION",
+		Classes: []Class{{Name: "AF1", DSCPs: []uint8{8, 10}, NetworkInstance: "VRF-AF1"}},
+		Default: "DEFAULT",
+	}
+	pf := p.Config("port1")
+	pol := pf.GetPolicy("VRF-SELECTION")
+	if pol.GetType() != telemetry.Policy_Type_VRF_SELECTION_POLICY {
+		t.Errorf("Policy type got %v, want VRF_SELECTION_POLICY", pol.GetType())
+	}
+	if len(pol.Rule) != 2 {
+		t.Fatalf("Config() got %d rules, want 2", len(pol.Rule))
+	}
+	if diff := cmp.Diff([]uint8{8, 10}, pol.GetRule(1).GetIpv4().DscpSet); diff != "" {
+		t.Errorf("DSCPs of rule 1 -want, +got:\n%s", diff)
+	}
+	if got := pol.GetRule(1).GetAction().GetNetworkInstance(); got != "VRF-AF1" {
+		t.Errorf("Network instance of rule 1 got %q, want VRF-AF1", got)
+	}
+	if r := pol.GetRule(2); r.Ipv4 != nil || r.GetAction().GetNetworkInstance() != "DEFAULT" {
+		t.Errorf("Rule 2 got %+v, want all packets to DEFAULT", r)
+	}
+	i := pf.GetInterface("port1")
+	if i.GetApplyVrfSelectionPolicy() != "VRF-SELECTION" || i.GetInterfaceRef().GetInterface() != "port1" {
+		t.Errorf("Interface got %+v, want port1 applying VRF-SELECTION", i)
+	}
+}
+
+// counts returns the packets forwarded for each DSCP by the network
+// instance the policy selects.
+func counts(p *Policy, n uint64) map[uint8]map[string]uint64 {
+	c := map[uint8]map[string]uint64{}
+	for _, s := range p.Matrix() {
+		c[s.DSCP] = map[string]uint64{s.NetworkInstance: n}
+	}
+	return c
+}
+
+func TestVerify(t *testing.T) {
+	p := newPolicy(t)
+	for _, c := range []struct {
+		desc     string
+		modify   func(c map[uint8]map[string]uint64)
+		wantErrs int
+	}{
+		{"as selected", func(c map[uint8]map[string]uint64) {}, 0},
+		{"DSCP missing", func(c map[uint8]map[string]uint64) { delete(c, 10) }, 1},
+		{"no packet", func(c map[uint8]map[string]uint64) { c[10]["VRF-AF1"] = 0 }, 1},
+		{"wrong network instance", func(c map[uint8]map[string]uint64) {
+			c[10] = map[string]uint64{"DEFAULT": 100}
+		}, 1},
+		{"some packets leaked", func(c map[uint8]map[string]uint64) { c[0]["VRF-AF1"] = 1 }, 1},
+		{"class to default", func(c map[uint8]map[string]uint64) {
+			for d := uint8(40); d <= 47; d++ {
+				c[d] = map[string]uint64{"DEFAULT": 100}
+			}
+		}, 8},
+	} {
+		cs := counts(p, 100)
+		c.modify(cs)
+		if got := p.Verify(cs); len(got) != c.wantErrs {
+			t.Errorf("%s: Verify() got errors %v, want %d", c.desc, got, c.wantErrs)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// compliance_report builds a per-platform compliance matrix of the
+// feature profile tests from the results of several test runs.
+//
+// Each input file is the output of one run of "go test -json" against
+// a single platform, e.g.
+//
+//	go test -json ./feature/... -args -testbed=... > vendor_a.json
+//	go run ./tools/compliance_report --format=html vendor_a.json vendor_b.json
+//
+// The platform of a run is taken from the properties the tests print
+// (see below), and falls back to the base name of the file.  Runs of
+// different software versions of the same vendor and model are
+// different platforms.  Every top-level test becomes a row of the
+// matrix with one pass, fail or skip cell per platform.  When the same
+// test is run more than once on a platform, the run from the later file
+// wins.
+//
+// Tests may print properties as lines of the form
+//
+//	Property: key=value
+//
+// The following keys are understood: dut.vendor, dut.model and
+// dut.software_version identify the platform, and any key starting
+// with "deviation." records a deviation used by the tests of the
+// package printing it.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/golang/glog"
+)
+
+var (
+	format = flag.String("format", "html", "output format, either html or json")
+	output = flag.String("output", "", "file to write the report to, defaults to stdout")
+)
+
+var propertyRE = regexp.MustCompile(`^\s*Property: ([^=\s]+)=(.*?)\s*$`)
+
+// event is a subset of the JSON events written by test2json.
+type event struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+// Platform identifies the devices a set of runs was executed against.
+type Platform struct {
+	Name            string `json:"name"`
+	Vendor          string `json:"vendor,omitempty"`
+	Model           string `json:"model,omitempty"`
+	SoftwareVersion string `json:"software_version,omitempty"`
+}
+
+// Result is the outcome of a test on one platform.
+type Result struct {
+	Status     string   `json:"status"`
+	Deviations []string `json:"deviations,omitempty"`
+}
+
+// Test is a row of the compliance matrix, keyed by platform name.
+type Test struct {
+	Name    string             `json:"name"`
+	Results map[string]*Result `json:"results"`
+}
+
+// Report is the compliance matrix.
+type Report struct {
+	Platforms []*Platform `json:"platforms"`
+	Tests     []*Test     `json:"tests"`
+}
+
+// testKey identifies a top-level test of a run.
+type testKey struct {
+	Package string
+	Test    string
+}
+
+// run is the parsed content of a single results file.
+type run struct {
+	platform   *Platform
+	deviations map[string][]string // Keyed by package.
+	status     map[testKey]string
+}
+
+// parseRun reads the test2json events of one run.  The name is used
+// as platform name when the run does not carry the dut properties.
+func parseRun(name string, r io.Reader) (*run, error) {
+	props := map[string]string{}
+	pkgDevs := map[string]map[string]string{}
+	status := map[testKey]string{}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 16*1024*1024)
+	for s.Scan() {
+		var e event
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		switch e.Action {
+		case "output":
+			m := propertyRE.FindStringSubmatch(e.Output)
+			switch {
+			case m == nil:
+			case strings.HasPrefix(m[1], "deviation."):
+				if pkgDevs[e.Package] == nil {
+					pkgDevs[e.Package] = map[string]string{}
+				}
+				pkgDevs[e.Package][strings.TrimPrefix(m[1], "deviation.")] = m[2]
+			default:
+				props[m[1]] = m[2]
+			}
+		case "pass", "fail", "skip":
+			if e.Test != "" && !strings.Contains(e.Test, "/") {
+				status[testKey{e.Package, e.Test}] = e.Action
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	p := &Platform{
+		Vendor:          props["dut.vendor"],
+		Model:           props["dut.model"],
+		SoftwareVersion: props["dut.software_version"],
+	}
+	p.Name = strings.TrimSpace(p.Vendor + " " + p.Model)
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	if p.SoftwareVersion != "" {
+		p.Name += " " + p.SoftwareVersion
+	}
+	devs := map[string][]string{}
+	for pkg, kv := range pkgDevs {
+		for k, v := range kv {
+			devs[pkg] = append(devs[pkg], k+"="+v)
+		}
+		sort.Strings(devs[pkg])
+	}
+	return &run{platform: p, deviations: devs, status: status}, nil
+}
+
+// build merges the runs into a report.  Later runs override earlier
+// ones for the same test and platform.
+func build(runs []*run) *Report {
+	rep := &Report{}
+	platforms := map[string]*Platform{}
+	tests := map[string]*Test{}
+	for _, r := range runs {
+		if _, ok := platforms[r.platform.Name]; !ok {
+			rep.Platforms = append(rep.Platforms, r.platform)
+		}
+		platforms[r.platform.Name] = r.platform
+		for k, st := range r.status {
+			name := k.Package + "." + k.Test
+			t, ok := tests[name]
+			if !ok {
+				t = &Test{Name: name, Results: map[string]*Result{}}
+				tests[name] = t
+				rep.Tests = append(rep.Tests, t)
+			}
+			t.Results[r.platform.Name] = &Result{Status: st, Deviations: r.deviations[k.Package]}
+		}
+	}
+	for i, p := range rep.Platforms {
+		rep.Platforms[i] = platforms[p.Name]
+	}
+	sort.Slice(rep.Platforms, func(i, j int) bool { return rep.Platforms[i].Name < rep.Platforms[j].Name })
+	sort.Slice(rep.Tests, func(i, j int) bool { return rep.Tests[i].Name < rep.Tests[j].Name })
+	return rep
+}
+
+var htmlTmpl = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Feature Profiles Compliance</title>
+<style>
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; font-family: sans-serif; font-size: small; }
+.pass { background: #c8e6c9; }
+.fail { background: #ffcdd2; }
+.skip { background: #fff9c4; }
+</style>
+</head>
+<body>
+<table>
+<tr><th>Test</th>{{range .Platforms}}<th>{{.Name}}</th>{{end}}</tr>
+{{- range $t := .Tests}}
+<tr><td>{{$t.Name}}</td>{{range $.Platforms}}{{with index $t.Results .Name}}<td class="{{.Status}}"{{if .Deviations}} title="{{range .Deviations}}{{.}}&#10;{{end}}"{{end}}>{{.Status}}{{if .Deviations}}*{{end}}</td>{{else}}<td></td>{{end}}{{end}}</tr>
+{{- end}}
+</table>
+<p>* run with deviations, hover over the cell for details.</p>
+</body>
+</html>
+`))
+
+func write(w io.Writer, rep *Report) error {
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	case "html":
+		return htmlTmpl.Execute(w, rep)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("At least one results file must be given.")
+	}
+	var runs []*run
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		r, err := parseRun(name, f)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		runs = append(runs, r)
+	}
+	rep := build(runs)
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := write(w, rep); err != nil {
+		log.Fatal(err)
+	}
+}
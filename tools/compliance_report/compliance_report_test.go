@@ -0,0 +1,119 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const vendorARun = `{"Action":"output","Package":"fp/a","Output":"Property: dut.vendor=VENDOR_A\n"}
+{"Action":"output","Package":"fp/a","Output":"Property: dut.model=Model1\n"}
+{"Action":"output","Package":"fp/a","Output":"Property: dut.software_version=1.2.3\n"}
+{"Action":"output","Package":"fp/a","Output":"Property: deviation.interface_enabled=true\n"}
+{"Action":"run","Package":"fp/a","Test":"TestFoo"}
+{"Action":"pass","Package":"fp/a","Test":"TestFoo/sub"}
+{"Action":"pass","Package":"fp/a","Test":"TestFoo"}
+{"Action":"fail","Package":"fp/a","Test":"TestBar"}
+{"Action":"fail","Package":"fp/a"}
+{"Action":"pass","Package":"fp/c","Test":"TestQux"}
+`
+
+const vendorBRun = `{"Action":"skip","Package":"fp/a","Test":"TestFoo"}
+{"Action":"pass","Package":"fp/b","Test":"TestBaz"}
+`
+
+func TestParseRun(t *testing.T) {
+	got, err := parseRun("results/vendor_a.json", strings.NewReader(vendorARun))
+	if err != nil {
+		t.Fatalf("parseRun got error: %v", err)
+	}
+	want := &run{
+		platform:   &Platform{Name: "VENDOR_A Model1 1.2.3", Vendor: "VENDOR_A", Model: "Model1", SoftwareVersion: "1.2.3"},
+		deviations: map[string][]string{"fp/a": {"interface_enabled=true"}},
+		status: map[testKey]string{
+			{"fp/a", "TestFoo"}: "pass",
+			{"fp/a", "TestBar"}: "fail",
+			{"fp/c", "TestQux"}: "pass",
+		},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(run{})); diff != "" {
+		t.Errorf("parseRun got unexpected run, diff(-want,+got):\n%s", diff)
+	}
+}
+
+func TestParseRunError(t *testing.T) {
+	if _, err := parseRun("bad.json", strings.NewReader("not json\n")); err == nil {
+		t.Error("parseRun got no error for malformed input, want error")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	a, err := parseRun("vendor_a.json", strings.NewReader(vendorARun))
+	if err != nil {
+		t.Fatalf("parseRun got error: %v", err)
+	}
+	b, err := parseRun("results/vendor_b.json", strings.NewReader(vendorBRun))
+	if err != nil {
+		t.Fatalf("parseRun got error: %v", err)
+	}
+	got := build([]*run{a, b})
+	want := &Report{
+		Platforms: []*Platform{
+			{Name: "VENDOR_A Model1 1.2.3", Vendor: "VENDOR_A", Model: "Model1", SoftwareVersion: "1.2.3"},
+			{Name: "vendor_b"},
+		},
+		Tests: []*Test{
+			{Name: "fp/a.TestBar", Results: map[string]*Result{
+				"VENDOR_A Model1 1.2.3": {Status: "fail", Deviations: []string{"interface_enabled=true"}},
+			}},
+			{Name: "fp/a.TestFoo", Results: map[string]*Result{
+				"VENDOR_A Model1 1.2.3": {Status: "pass", Deviations: []string{"interface_enabled=true"}},
+				"vendor_b":              {Status: "skip"},
+			}},
+			{Name: "fp/b.TestBaz", Results: map[string]*Result{
+				"vendor_b": {Status: "pass"},
+			}},
+			{Name: "fp/c.TestQux", Results: map[string]*Result{
+				"VENDOR_A Model1 1.2.3": {Status: "pass"},
+			}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("build got unexpected report, diff(-want,+got):\n%s", diff)
+	}
+}
+
+func TestBuildSoftwareVersions(t *testing.T) {
+	v1, err := parseRun("v1.json", strings.NewReader(vendorARun))
+	if err != nil {
+		t.Fatalf("parseRun got error: %v", err)
+	}
+	v2Run := strings.Replace(vendorARun, "1.2.3", "1.2.4", 1)
+	v2, err := parseRun("v2.json", strings.NewReader(v2Run))
+	if err != nil {
+		t.Fatalf("parseRun got error: %v", err)
+	}
+	got := build([]*run{v1, v2})
+	var names []string
+	for _, p := range got.Platforms {
+		names = append(names, p.Name)
+	}
+	if want := []string{"VENDOR_A Model1 1.2.3", "VENDOR_A Model1 1.2.4"}; !cmp.Equal(names, want) {
+		t.Errorf("build got platforms %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocpaths indexes the OpenConfig schema compiled into yang/fpoc.
+//
+// Each schema node is recorded with its full schema path, e.g.
+// /interfaces/interface/state/counters/in-pkts, and with the chain of
+// names used by the generated ygot path structs when paths are
+// compressed, e.g. Interface.Counters.InPkts.  This lets tools map
+// between the paths listed in feature profiles and the API calls made
+// by tests.
+package ocpaths
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/featureprofiles/yang/fpoc"
+	"github.com/openconfig/goyang/pkg/yang"
+	"github.com/openconfig/ygot/util"
+)
+
+// Node describes a single schema node.
+type Node struct {
+	Path   string // Full schema path without module names.
+	Chain  string // Compressed path struct names joined by ".".
+	Config bool   // Whether the node is configuration rather than state.
+	Leaf   bool   // Whether the node is a leaf or leaf-list.
+	Entry  *yang.Entry
+}
+
+// Index provides lookups of schema nodes by path and by compressed chain.
+type Index struct {
+	byPath  map[string]*Node
+	byChain map[string][]*Node
+}
+
+// New builds an Index from the schema compiled into yang/fpoc.
+func New() (*Index, error) {
+	schema, err := fpoc.UnzipSchema()
+	if err != nil {
+		return nil, err
+	}
+	root, ok := schema["Device"]
+	if !ok {
+		return nil, fmt.Errorf("fpoc schema has no Device root")
+	}
+	return FromRoot(root), nil
+}
+
+// FromRoot builds an Index from the schema tree rooted at root.
+func FromRoot(root *yang.Entry) *Index {
+	x := &Index{
+		byPath:  map[string]*Node{},
+		byChain: map[string][]*Node{},
+	}
+	for _, e := range util.Children(root) {
+		x.add(e, nil, nil)
+	}
+	return x
+}
+
+// isSurroundingContainer reports whether e is a container whose only
+// child is a list; such containers are removed when compressing paths.
+func isSurroundingContainer(e *yang.Entry) bool {
+	if !e.IsContainer() || !util.HasOnlyChild(e) {
+		return false
+	}
+	return util.Children(e)[0].IsList()
+}
+
+func (x *Index) add(e *yang.Entry, path, chain []string) {
+	if util.IsChoiceOrCase(e) {
+		for _, c := range util.Children(e) {
+			x.add(c, path, chain)
+		}
+		return
+	}
+	path = append(path[:len(path):len(path)], e.Name)
+	if !util.IsConfigState(e) && !isSurroundingContainer(e) {
+		chain = append(chain[:len(chain):len(chain)], yang.CamelCase(e.Name))
+	}
+	n := &Node{
+		Path:   "/" + strings.Join(path, "/"),
+		Chain:  strings.Join(chain, "."),
+		Config: util.IsConfig(e),
+		Leaf:   e.IsLeaf() || e.IsLeafList(),
+		Entry:  e,
+	}
+	x.byPath[n.Path] = n
+	if n.Chain != "" {
+		x.byChain[n.Chain] = append(x.byChain[n.Chain], n)
+	}
+	for _, c := range util.Children(e) {
+		x.add(c, path, chain)
+	}
+}
+
+// Lookup returns the node at the given schema path, or nil if the
+// path does not exist in the schema.
+func (x *Index) Lookup(path string) *Node {
+	return x.byPath[path]
+}
+
+// Resolve returns the node named by a compressed path struct chain.
+// When the chain names both a configuration and a state node, as is
+// the case for leaves mirrored under config and state containers, the
+// one matching config is returned.  It returns nil if the chain is not
+// known.
+func (x *Index) Resolve(chain string, config bool) *Node {
+	ns := x.byChain[chain]
+	if len(ns) == 0 {
+		return nil
+	}
+	for _, n := range ns {
+		if n.Leaf && n.Config == config {
+			return n
+		}
+	}
+	// Containers and lists have a single node, and state-only leaves
+	// are still reachable through a config path struct for Get.
+	for _, n := range ns {
+		if !n.Leaf {
+			return n
+		}
+	}
+	return ns[0]
+}
+
+// Leaves returns all leaf nodes at or below the given node, sorted by
+// path.  When the node is a container, only leaves whose config-ness
+// matches config are included.
+func (x *Index) Leaves(n *Node, config bool) []*Node {
+	if n.Leaf {
+		return []*Node{n}
+	}
+	var ns []*Node
+	prefix := n.Path + "/"
+	for p, c := range x.byPath {
+		if c.Leaf && c.Config == config && strings.HasPrefix(p, prefix) {
+			ns = append(ns, c)
+		}
+	}
+	sort.Slice(ns, func(i, j int) bool { return ns[i].Path < ns[j].Path })
+	return ns
+}
+
+// Paths returns every schema path in the index, sorted.
+func (x *Index) Paths() []string {
+	ps := make([]string, 0, len(x.byPath))
+	for p := range x.byPath {
+		ps = append(ps, p)
+	}
+	sort.Strings(ps)
+	return ps
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocpaths
+
+import (
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	x, err := New()
+	if err != nil {
+		t.Fatalf("New() got error: %v", err)
+	}
+	cases := []struct {
+		path      string
+		wantChain string
+		wantConf  bool
+		wantLeaf  bool
+	}{
+		{"/interfaces/interface/state/counters/in-pkts", "Interface.Counters.InPkts", false, true},
+		{"/interfaces/interface/config/description", "Interface.Description", true, true},
+		{"/system/config/hostname", "System.Hostname", true, true},
+		{"/interfaces/interface", "Interface", true, false},
+		{"/network-instances/network-instance/protocols/protocol/static-routes/static/config/prefix",
+			"NetworkInstance.Protocol.Static.Prefix", true, true},
+	}
+	for _, c := range cases {
+		n := x.Lookup(c.path)
+		if n == nil {
+			t.Errorf("Lookup(%q) got nil, want node", c.path)
+			continue
+		}
+		if n.Chain != c.wantChain || n.Config != c.wantConf || n.Leaf != c.wantLeaf {
+			t.Errorf("Lookup(%q) got chain=%q config=%v leaf=%v, want chain=%q config=%v leaf=%v",
+				c.path, n.Chain, n.Config, n.Leaf, c.wantChain, c.wantConf, c.wantLeaf)
+		}
+	}
+	if n := x.Lookup("/interfaces/interface/state/no-such-leaf"); n != nil {
+		t.Errorf("Lookup of missing path got %v, want nil", n.Path)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	x, err := New()
+	if err != nil {
+		t.Fatalf("New() got error: %v", err)
+	}
+	cases := []struct {
+		chain  string
+		config bool
+		want   string
+	}{
+		{"Interface.Description", true, "/interfaces/interface/config/description"},
+		{"Interface.Description", false, "/interfaces/interface/state/description"},
+		{"Interface.Counters.InPkts", false, "/interfaces/interface/state/counters/in-pkts"},
+		{"Interface.Counters.InPkts", true, "/interfaces/interface/state/counters/in-pkts"},
+		{"Interface", false, "/interfaces/interface"},
+	}
+	for _, c := range cases {
+		n := x.Resolve(c.chain, c.config)
+		if n == nil {
+			t.Errorf("Resolve(%q, %v) got nil, want %q", c.chain, c.config, c.want)
+			continue
+		}
+		if n.Path != c.want {
+			t.Errorf("Resolve(%q, %v) got %q, want %q", c.chain, c.config, n.Path, c.want)
+		}
+	}
+}
+
+func TestLeaves(t *testing.T) {
+	x, err := New()
+	if err != nil {
+		t.Fatalf("New() got error: %v", err)
+	}
+	counters := x.Lookup("/interfaces/interface/state/counters")
+	if counters == nil {
+		t.Fatal("Lookup of counters container got nil")
+	}
+	found := false
+	for _, n := range x.Leaves(counters, false) {
+		if !n.Leaf || n.Config {
+			t.Errorf("Leaves(counters) got non state leaf %q", n.Path)
+		}
+		if n.Path == "/interfaces/interface/state/counters/in-pkts" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Leaves(counters) did not include in-pkts")
+	}
+	if got := x.Leaves(counters, true); len(got) != 0 {
+		t.Errorf("Leaves(counters, config) got %d leaves, want 0", len(got))
+	}
+}
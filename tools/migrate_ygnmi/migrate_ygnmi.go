@@ -0,0 +1,494 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// migrate_ygnmi rewrites the tests using the legacy Ondatra path structs,
+// dut.Telemetry() and dut.Config(), to the gnmi package of Ondatra and the
+// path structs generated by ygnmi, one file at a time, so the tests can be
+// migrated incrementally rather than all at once.
+//
+// Usage:
+//
+//	go run ./tools/migrate_ygnmi [-l] [-w] <file or directory>...
+//
+// Without -l or -w, the rewritten files are printed.  With -l, only their
+// names are printed, and with -w, they are written in place.
+//
+// The calls rewritten are the ones whose results keep their meaning:
+//
+//	dut.Telemetry().<path>.Get(t)             gnmi.Get(t, dut, ocpath.Root().<path>.State())
+//	dut.Telemetry().<path>Any().<path>.Get(t) gnmi.GetAll(t, dut, ocpath.Root().<path>Any().<path>.State())
+//	dut.Telemetry().<path>.Await(t, d, v)     gnmi.Await(t, dut, ocpath.Root().<path>.State(), d, v)
+//	dut.Config().<path>.Get(t)                gnmi.Get(t, dut, ocpath.Root().<path>.Config())
+//	dut.Config().<path>.Update(t, v)          gnmi.Update(t, dut, ocpath.Root().<path>.Config(), v)
+//	dut.Config().<path>.Replace(t, v)         gnmi.Replace(t, dut, ocpath.Root().<path>.Config(), v)
+//	dut.Config().<path>.Delete(t)             gnmi.Delete(t, dut, ocpath.Root().<path>.Config())
+//
+// Await is only rewritten when its result is unused.  Paths may also be
+// held by local variables, as in p := dut.Telemetry().Interface(name);
+// p.Mtu().Get(t).  The types of the telemetry package are renamed to the
+// ones of the oc package.
+//
+// A file is only rewritten when all its legacy calls are, since the types
+// of the two APIs do not mix.  The calls it cannot rewrite, such as Lookup
+// and Watch whose results and predicates change types, or paths passed to
+// other functions, are reported for manual migration and the file is left
+// unchanged.  Files already importing ocpath are skipped.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/golang/glog"
+)
+
+var (
+	list  = flag.Bool("l", false, "list the files that would be rewritten")
+	write = flag.Bool("w", false, "write the rewritten files in place")
+)
+
+const (
+	telemetryImport = "github.com/openconfig/ondatra/telemetry"
+	ocImport        = "github.com/openconfig/ondatra/gnmi/oc"
+	ocpathImport    = "github.com/openconfig/ondatra/gnmi/oc/ocpath"
+	gnmiImport      = "github.com/openconfig/ondatra/gnmi"
+)
+
+// step is a selector of a call chain, and the call of it if any.
+type step struct {
+	name string
+	call *ast.CallExpr
+}
+
+// flatten walks a selector/call chain such as a.B().C(x).D and returns
+// the root expression and its steps in order.  The root is a call when
+// the chain starts with a call of a function.
+func flatten(e ast.Expr) (ast.Expr, []step) {
+	var steps []step
+	var call *ast.CallExpr
+	for {
+		switch x := e.(type) {
+		case *ast.CallExpr:
+			call = x
+			e = x.Fun
+			continue
+		case *ast.SelectorExpr:
+			steps = append(steps, step{name: x.Sel.Name, call: call})
+			e = x.X
+		case *ast.ParenExpr:
+			e = x.X
+		default:
+			for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+				steps[i], steps[j] = steps[j], steps[i]
+			}
+			if call != nil {
+				return call, steps
+			}
+			return e, steps
+		}
+		call = nil
+	}
+}
+
+// isDevice returns whether an expression may be a device, as an
+// identifier or a field of one.
+func isDevice(e ast.Expr) bool {
+	switch x := e.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		return isDevice(x.X)
+	}
+	return false
+}
+
+// device returns a copy of a device expression without positions, to be
+// printed wherever it is used.
+func device(e ast.Expr) ast.Expr {
+	if sel, ok := e.(*ast.SelectorExpr); ok {
+		return &ast.SelectorExpr{X: device(sel.X), Sel: ast.NewIdent(sel.Sel.Name)}
+	}
+	return ast.NewIdent(e.(*ast.Ident).Name)
+}
+
+// binding is a local variable holding a legacy path.
+type binding struct {
+	dev      ast.Expr
+	config   bool
+	wildcard bool
+}
+
+// legacy is the root of a legacy chain: the device and whether it is a
+// config path, and the index of the first step of the path.
+type legacy struct {
+	dev      ast.Expr
+	config   bool
+	wildcard bool
+	first    int
+}
+
+// migrator rewrites the legacy calls of a file.
+type migrator struct {
+	fset    *token.FileSet
+	vars    map[string]*binding
+	used    map[*ast.Ident]bool
+	stmts   map[*ast.CallExpr]bool
+	changed bool
+	issues  []string
+}
+
+func (m *migrator) report(n ast.Node, format string, args ...interface{}) {
+	m.issues = append(m.issues, fmt.Sprintf("%s: %s", m.fset.Position(n.Pos()), fmt.Sprintf(format, args...)))
+}
+
+// root returns the legacy root of a chain, replacing a Telemetry() or
+// Config() call of a device by ocpath.Root(), or nil if the chain is not a
+// legacy path.
+func (m *migrator) root(e ast.Expr, steps []step) *legacy {
+	if id, ok := e.(*ast.Ident); ok {
+		if b, ok := m.vars[id.Name]; ok {
+			m.used[id] = true
+			return &legacy{dev: b.dev, config: b.config, wildcard: b.wildcard}
+		}
+	}
+	for i, s := range steps {
+		if s.call == nil || len(s.call.Args) > 0 || (s.name != "Telemetry" && s.name != "Config") {
+			continue
+		}
+		sel, ok := s.call.Fun.(*ast.SelectorExpr)
+		if !ok || !isDevice(sel.X) {
+			return nil
+		}
+		l := &legacy{dev: sel.X, config: s.name == "Config", first: i + 1}
+		*s.call = ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent("ocpath"), Sel: ast.NewIdent("Root")}}
+		m.changed = true
+		return l
+	}
+	return nil
+}
+
+// rewrite rewrites the terminal call of a legacy chain.  It returns false
+// if the chain has no terminal call.
+func (m *migrator) rewrite(l *legacy, steps []step) bool {
+	for _, s := range steps[l.first:] {
+		if strings.HasSuffix(s.name, "Any") {
+			l.wildcard = true
+		}
+		if s.call == nil {
+			continue
+		}
+		var fn string
+		switch s.name {
+		case "Get":
+			fn = "Get"
+			if l.wildcard {
+				fn = "GetAll"
+			}
+		case "Await":
+			if l.config || l.wildcard || !m.stmts[s.call] {
+				m.report(s.call, "%s cannot be migrated: its result changes type", s.name)
+				return true
+			}
+			fn = "Await"
+		case "Update", "Replace", "Delete":
+			if !l.config || l.wildcard {
+				m.report(s.call, "%s of a telemetry or wildcard path cannot be migrated", s.name)
+				return true
+			}
+			fn = s.name
+		case "Lookup", "Watch", "Collect", "Batch":
+			m.report(s.call, "%s cannot be migrated: its results or arguments change types", s.name)
+			return true
+		default:
+			continue
+		}
+		if len(s.call.Args) == 0 {
+			m.report(s.call, "%s without arguments", s.name)
+			return true
+		}
+		query := "State"
+		if l.config {
+			query = "Config"
+		}
+		path := s.call.Fun.(*ast.SelectorExpr).X
+		args := []ast.Expr{s.call.Args[0], device(l.dev), &ast.CallExpr{Fun: &ast.SelectorExpr{X: path, Sel: ast.NewIdent(query)}}}
+		*s.call = ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: ast.NewIdent("gnmi"), Sel: ast.NewIdent(fn)},
+			Args: append(args, s.call.Args[1:]...),
+		}
+		m.changed = true
+		return true
+	}
+	return false
+}
+
+// expr rewrites a maximal selector/call chain, and descends into its
+// arguments.
+func (m *migrator) expr(e ast.Expr) {
+	root, steps := flatten(e)
+	// The arguments are saved before the calls are rewritten, not to
+	// rewrite the queries of the calls again.
+	var args []ast.Expr
+	for _, s := range steps {
+		if s.call != nil {
+			args = append(args, s.call.Args...)
+		}
+	}
+	if l := m.root(root, steps); l != nil && !m.rewrite(l, steps) {
+		m.report(e, "legacy path used outside of a call chain")
+	}
+	for _, arg := range args {
+		ast.Inspect(arg, m.inspect)
+	}
+	if c, ok := root.(*ast.CallExpr); ok {
+		for _, arg := range c.Args {
+			ast.Inspect(arg, m.inspect)
+		}
+		ast.Inspect(c.Fun, m.inspect)
+		return
+	}
+	ast.Inspect(root, m.inspect)
+}
+
+// assign binds a local variable to a legacy path.
+func (m *migrator) assign(s *ast.AssignStmt) bool {
+	if len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+		return false
+	}
+	id, ok := s.Lhs[0].(*ast.Ident)
+	if !ok {
+		return false
+	}
+	root, steps := flatten(s.Rhs[0])
+	for _, st := range steps {
+		switch st.name {
+		case "Get", "Lookup", "Watch", "Await", "Collect", "Update", "Replace", "Delete", "Batch":
+			return false
+		}
+	}
+	l := m.root(root, steps)
+	if l == nil {
+		return false
+	}
+	for _, st := range steps[l.first:] {
+		if strings.HasSuffix(st.name, "Any") {
+			l.wildcard = true
+		}
+		if st.call != nil {
+			for _, arg := range st.call.Args {
+				ast.Inspect(arg, m.inspect)
+			}
+		}
+	}
+	m.vars[id.Name] = &binding{dev: l.dev, config: l.config, wildcard: l.wildcard}
+	m.used[id] = true
+	return true
+}
+
+func (m *migrator) inspect(n ast.Node) bool {
+	switch x := n.(type) {
+	case *ast.AssignStmt:
+		if m.assign(x) {
+			return false
+		}
+	case *ast.CallExpr:
+		m.expr(x)
+		return false
+	case *ast.SelectorExpr:
+		m.expr(x)
+		return false
+	}
+	return true
+}
+
+// unused reports the uses of the variables holding legacy paths outside
+// of the chains rewritten.
+func (m *migrator) unused(n ast.Node) bool {
+	switch x := n.(type) {
+	case *ast.SelectorExpr:
+		ast.Inspect(x.X, m.unused)
+		return false
+	case *ast.Ident:
+		if m.vars[x.Name] != nil && !m.used[x] {
+			m.report(x, "legacy path %s used outside of a call chain", x.Name)
+		}
+	}
+	return true
+}
+
+// importPath returns the path of an import.
+func importPath(s *ast.ImportSpec) string {
+	p, err := strconv.Unquote(s.Path.Value)
+	if err != nil {
+		return ""
+	}
+	return p
+}
+
+// migrateFile returns the rewritten source of a file, or nil if it has no
+// legacy calls, and the calls it cannot rewrite.
+func migrateFile(fset *token.FileSet, name string, src interface{}) ([]byte, []string, error) {
+	f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, s := range f.Imports {
+		if importPath(s) == ocpathImport {
+			return nil, nil, nil
+		}
+	}
+	m := &migrator{fset: fset, stmts: map[*ast.CallExpr]bool{}}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if s, ok := n.(*ast.ExprStmt); ok {
+			if c, ok := s.X.(*ast.CallExpr); ok {
+				m.stmts[c] = true
+			}
+		}
+		return true
+	})
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		m.vars = map[string]*binding{}
+		m.used = map[*ast.Ident]bool{}
+		ast.Inspect(fn.Body, m.inspect)
+		ast.Inspect(fn.Body, m.unused)
+	}
+	if len(m.issues) > 0 || !m.changed {
+		return nil, m.issues, nil
+	}
+
+	// Rename the types of the telemetry package to the ones of oc.
+	for _, s := range f.Imports {
+		if importPath(s) != telemetryImport {
+			continue
+		}
+		local := "telemetry"
+		if s.Name != nil {
+			local = s.Name.Name
+		}
+		s.Name = nil
+		s.Path.Value = strconv.Quote(ocImport)
+		ast.Inspect(f, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok && id.Name == local && id.Obj == nil {
+					id.Name = "oc"
+				}
+			}
+			return true
+		})
+	}
+	addImports(f, gnmiImport, ocpathImport)
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, nil, err
+	}
+	// Reparse to sort the imports added.
+	f, err = parser.ParseFile(fset, name, buf.Bytes(), parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	ast.SortImports(fset, f)
+	buf.Reset()
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), nil, nil
+}
+
+// addImports adds imports to the first import declaration of a file.
+func addImports(f *ast.File, paths ...string) {
+	var decl *ast.GenDecl
+	for _, d := range f.Decls {
+		if g, ok := d.(*ast.GenDecl); ok && g.Tok == token.IMPORT {
+			decl = g
+			break
+		}
+	}
+	if decl == nil {
+		decl = &ast.GenDecl{Tok: token.IMPORT}
+		f.Decls = append([]ast.Decl{decl}, f.Decls...)
+	}
+	if !decl.Lparen.IsValid() {
+		decl.Lparen = decl.Pos()
+		decl.Rparen = decl.End()
+	}
+	for _, p := range paths {
+		spec := &ast.ImportSpec{Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(p), ValuePos: decl.Rparen - 1}}
+		decl.Specs = append(decl.Specs, spec)
+		f.Imports = append(f.Imports, spec)
+	}
+}
+
+// goFiles returns the Go files of the arguments, walking directories.
+func goFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		err := filepath.WalkDir(arg, func(path string, e fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !e.IsDir() && strings.HasSuffix(path, ".go") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func main() {
+	flag.Parse()
+	files, err := goFiles(flag.Args())
+	if err != nil {
+		log.Exit(err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		out, issues, err := migrateFile(fset, name, nil)
+		if err != nil {
+			log.Exit(err)
+		}
+		for _, issue := range issues {
+			fmt.Fprintln(os.Stderr, issue)
+		}
+		switch {
+		case out == nil:
+		case *list:
+			fmt.Println(name)
+		case *write:
+			if err := os.WriteFile(name, out, 0644); err != nil {
+				log.Exit(err)
+			}
+		default:
+			os.Stdout.Write(out)
+		}
+	}
+}
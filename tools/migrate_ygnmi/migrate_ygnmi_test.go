@@ -0,0 +1,160 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const legacySrc = `package foo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+
+	telemetry "github.com/openconfig/ondatra/telemetry"
+)
+
+func TestFoo(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	i := &telemetry.Interface{Name: ygot.String("eth0")}
+	dut.Config().Interface("eth0").Replace(t, i)
+	d := dut.Config()
+	d.System().Hostname().Update(t, "foo")
+	if got := dut.Telemetry().Interface("eth0").Counters().InPkts().Get(t); got == 0 {
+		t.Errorf("InPkts() got %d", got)
+	}
+	intf := dut.Telemetry().Interface("eth0")
+	dut.Telemetry().Interface("eth0").OperStatus().Await(t, time.Minute, telemetry.Interface_OperStatus_UP)
+	for _, s := range dut.Telemetry().InterfaceAny().OperStatus().Get(t) {
+		t.Log(s, intf.Mtu().Get(t))
+	}
+	d.Interface("eth0").Delete(t)
+}
+`
+
+const migratedSrc = `package foo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/ondatra"
+
+	"github.com/openconfig/ondatra/gnmi"
+	"github.com/openconfig/ondatra/gnmi/oc"
+	"github.com/openconfig/ondatra/gnmi/oc/ocpath"
+)
+
+func TestFoo(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	i := &oc.Interface{Name: ygot.String("eth0")}
+	gnmi.Replace(t, dut, ocpath.Root().Interface("eth0").Config(), i)
+	d := ocpath.Root()
+	gnmi.Update(t, dut, d.System().Hostname().Config(), "foo")
+	if got := gnmi.Get(t, dut, ocpath.Root().Interface("eth0").Counters().InPkts().State()); got == 0 {
+		t.Errorf("InPkts() got %d", got)
+	}
+	intf := ocpath.Root().Interface("eth0")
+	gnmi.Await(t, dut, ocpath.Root().Interface("eth0").OperStatus().State(), time.Minute, oc.Interface_OperStatus_UP)
+	for _, s := range gnmi.GetAll(t, dut, ocpath.Root().InterfaceAny().OperStatus().State()) {
+		t.Log(s, gnmi.Get(t, dut, intf.Mtu().State()))
+	}
+	gnmi.Delete(t, dut, d.Interface("eth0").Config())
+}
+`
+
+func TestMigrateFile(t *testing.T) {
+	got, issues, err := migrateFile(token.NewFileSet(), "foo_test.go", legacySrc)
+	if err != nil {
+		t.Fatalf("migrateFile() got error: %v", err)
+	}
+	if len(issues) > 0 {
+		t.Fatalf("migrateFile() got issues: %v", issues)
+	}
+	if diff := cmp.Diff(migratedSrc, string(got)); diff != "" {
+		t.Errorf("migrateFile() got unexpected source, diff(-want,+got):\n%s", diff)
+	}
+}
+
+func TestMigrateFileUnchanged(t *testing.T) {
+	for _, c := range []struct {
+		desc      string
+		body      string
+		wantIssue string
+	}{{
+		desc: "no legacy calls",
+		body: `t.Log(dut.Name())`,
+	}, {
+		desc:      "lookup",
+		body:      `dut.Telemetry().System().Hostname().Lookup(t)`,
+		wantIssue: "Lookup cannot be migrated",
+	}, {
+		desc:      "watch",
+		body:      `dut.Telemetry().System().Hostname().Watch(t, time.Minute, func(*telemetry.QualifiedString) bool { return true })`,
+		wantIssue: "Watch cannot be migrated",
+	}, {
+		desc:      "await result used",
+		body:      `v := dut.Telemetry().System().Hostname().Await(t, time.Minute, "foo"); t.Log(v)`,
+		wantIssue: "Await cannot be migrated",
+	}, {
+		desc:      "replace of a telemetry path",
+		body:      `dut.Telemetry().System().Hostname().Replace(t, "foo")`,
+		wantIssue: "Replace of a telemetry or wildcard path",
+	}, {
+		desc:      "path passed to a function",
+		body:      `fptest.LogYgot(t, "system", dut.Config().System(), s)`,
+		wantIssue: "legacy path used outside of a call chain",
+	}, {
+		desc:      "variable passed to a function",
+		body:      `p := dut.Config().System(); fptest.LogYgot(t, "system", p, s)`,
+		wantIssue: "legacy path p used outside of a call chain",
+	}, {
+		desc:      "one call of many",
+		body:      `dut.Telemetry().System().Hostname().Get(t); dut.Telemetry().System().Hostname().Lookup(t)`,
+		wantIssue: "Lookup cannot be migrated",
+	}} {
+		src := "package foo_test\n\nfunc TestFoo(t *testing.T) {\n\t" + c.body + "\n}\n"
+		got, issues, err := migrateFile(token.NewFileSet(), "foo_test.go", src)
+		if err != nil {
+			t.Fatalf("%s: migrateFile() got error: %v", c.desc, err)
+		}
+		if got != nil {
+			t.Errorf("%s: migrateFile() got source %s, want unchanged", c.desc, got)
+		}
+		if c.wantIssue == "" {
+			if len(issues) > 0 {
+				t.Errorf("%s: migrateFile() got issues %v, want none", c.desc, issues)
+			}
+			continue
+		}
+		if len(issues) != 1 || !strings.Contains(issues[0], c.wantIssue) {
+			t.Errorf("%s: migrateFile() got issues %v, want one with %q", c.desc, issues, c.wantIssue)
+		}
+	}
+}
+
+func TestMigrateFileMigrated(t *testing.T) {
+	got, issues, err := migrateFile(token.NewFileSet(), "foo_test.go", migratedSrc)
+	if err != nil || got != nil || len(issues) > 0 {
+		t.Errorf("migrateFile() of a migrated file got %s, %v, %v, want unchanged", got, issues, err)
+	}
+}
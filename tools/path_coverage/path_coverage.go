@@ -0,0 +1,465 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// path_coverage statically analyzes the Go test packages under the
+// feature root, finds the telemetry and config paths they use through
+// the Ondatra path structs, and reports for each feature profile which
+// of the paths it claims are actually exercised by its tests.
+//
+// Usage:
+//
+//	go run ./tools/path_coverage --feature_root=feature/ [--format=csv]
+//
+// Paths are only recognized when the path struct chain starts from a
+// Telemetry() or Config() call, either directly or through a local
+// variable holding such a chain.  Helpers that build paths in other
+// packages are not followed.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/golang/glog"
+	fppb "github.com/openconfig/featureprofiles/proto/feature_go_proto"
+	"github.com/openconfig/featureprofiles/tools/internal/ocpaths"
+	"google.golang.org/protobuf/encoding/prototext"
+)
+
+var (
+	featuresRoot = flag.String("feature_root", "", "root directory of the feature profiles")
+	format       = flag.String("format", "text", "output format, either text or csv")
+)
+
+// use is a schema node referenced by a test, and whether it was
+// referenced through the config or the telemetry path structs.
+type use struct {
+	node   *ocpaths.Node
+	config bool
+}
+
+// pathVar is a local variable bound to a path struct chain.
+type pathVar struct {
+	config bool
+	chain  []string
+	// ocpath is whether the chain starts from ocpath.Root(), whose
+	// queries tell config from telemetry.
+	ocpath bool
+}
+
+// analyzer collects path struct usages from Go source files.
+type analyzer struct {
+	index *ocpaths.Index
+	vars  map[string]pathVar
+	uses  []use
+}
+
+// flatten walks a selector/call chain such as a.B().C(x).D and
+// returns the root expression, the selected names in order, and the
+// arguments of every call along the chain.
+func flatten(e ast.Expr) (root ast.Expr, names []string, args []ast.Expr) {
+	for {
+		switch x := e.(type) {
+		case *ast.CallExpr:
+			args = append(args, x.Args...)
+			e = x.Fun
+		case *ast.SelectorExpr:
+			names = append(names, x.Sel.Name)
+			e = x.X
+		case *ast.ParenExpr:
+			e = x.X
+		default:
+			for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+				names[i], names[j] = names[j], names[i]
+			}
+			return e, names, args
+		}
+	}
+}
+
+// pathChain extracts the path struct names that follow the last
+// Telemetry() or Config() call in names, or ocpath.Root() up to its
+// State() or Config() query, or that follow a variable known to hold a
+// path.  It returns ok=false if names is not a path.
+func (a *analyzer) pathChain(root ast.Expr, names []string) (chain []string, config bool, ok bool) {
+	if id, isIdent := root.(*ast.Ident); isIdent && id.Name == "ocpath" && len(names) > 0 && names[0] == "Root" {
+		chain, config = query(names[1:])
+		return chain, config, true
+	}
+	if id, isIdent := root.(*ast.Ident); isIdent {
+		if v, found := a.vars[id.Name]; found && v.ocpath {
+			chain, config = query(names)
+			return append(v.chain[:len(v.chain):len(v.chain)], chain...), config, true
+		}
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		switch names[i] {
+		case "Telemetry", "Config":
+			return names[i+1:], names[i] == "Config", true
+		}
+	}
+	if id, isIdent := root.(*ast.Ident); isIdent {
+		if v, found := a.vars[id.Name]; found {
+			return append(v.chain[:len(v.chain):len(v.chain)], names...), v.config, true
+		}
+	}
+	return nil, false, false
+}
+
+// query cuts the names of an ocpath chain at its State() or Config()
+// query, and returns whether it is a config query.
+func query(names []string) ([]string, bool) {
+	for i, name := range names {
+		switch name {
+		case "State", "Config":
+			return names[:i], name == "Config"
+		}
+	}
+	return names, false
+}
+
+// resolve finds the deepest schema node named by a prefix of chain.
+// Wildcard path struct names such as InterfaceAny are treated the same
+// as their keyed counterparts.
+func (a *analyzer) resolve(chain []string, config bool) *ocpaths.Node {
+	var (
+		node  *ocpaths.Node
+		names []string
+	)
+	for _, name := range chain {
+		n := a.index.Resolve(strings.Join(append(names, name), "."), config)
+		if n == nil && strings.HasSuffix(name, "Any") {
+			name = strings.TrimSuffix(name, "Any")
+			n = a.index.Resolve(strings.Join(append(names, name), "."), config)
+		}
+		if n == nil {
+			break
+		}
+		names = append(names, name)
+		node = n
+	}
+	return node
+}
+
+// expr records the path usage of a maximal selector/call chain and
+// descends into the call arguments.
+func (a *analyzer) expr(e ast.Expr) {
+	root, names, args := flatten(e)
+	if chain, config, ok := a.pathChain(root, names); ok && len(chain) > 0 {
+		if n := a.resolve(chain, config); n != nil {
+			a.uses = append(a.uses, use{node: n, config: config})
+		}
+	}
+	for _, arg := range args {
+		ast.Inspect(arg, a.inspect)
+	}
+	if root != nil {
+		ast.Inspect(root, a.inspect)
+	}
+}
+
+// assign binds local variables to path chains so that later uses of
+// the variable can be resolved.  Binding a variable is not by itself
+// counted as a use of the path.
+func (a *analyzer) assign(s *ast.AssignStmt) bool {
+	if len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+		return false
+	}
+	id, ok := s.Lhs[0].(*ast.Ident)
+	if !ok {
+		return false
+	}
+	root, names, args := flatten(s.Rhs[0])
+	chain, config, ok := a.pathChain(root, names)
+	if !ok || len(chain) == 0 {
+		return false
+	}
+	// Only bind chains that still name a path, not the result of Get().
+	n := a.resolve(chain, config)
+	if n == nil || n.Chain != strings.Join(trimAny(chain), ".") {
+		return false
+	}
+	v := pathVar{config: config, chain: chain}
+	if rootID, isIdent := root.(*ast.Ident); isIdent {
+		v.ocpath = rootID.Name == "ocpath" || a.vars[rootID.Name].ocpath
+	}
+	a.vars[id.Name] = v
+	for _, arg := range args {
+		ast.Inspect(arg, a.inspect)
+	}
+	return true
+}
+
+// trimAny removes the Any suffix from wildcard path struct names.
+func trimAny(chain []string) []string {
+	var out []string
+	for _, name := range chain {
+		out = append(out, strings.TrimSuffix(name, "Any"))
+	}
+	return out
+}
+
+func (a *analyzer) inspect(n ast.Node) bool {
+	switch x := n.(type) {
+	case *ast.AssignStmt:
+		if a.assign(x) {
+			return false
+		}
+	case *ast.CallExpr:
+		a.expr(x)
+		return false
+	case *ast.SelectorExpr:
+		a.expr(x)
+		return false
+	}
+	return true
+}
+
+// analyzeFile returns the schema nodes used by a single Go source file.
+func analyzeFile(index *ocpaths.Index, fset *token.FileSet, name string, src interface{}) ([]use, error) {
+	f, err := parser.ParseFile(fset, name, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	a := &analyzer{index: index, vars: map[string]pathVar{}}
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			a.vars = map[string]pathVar{}
+			ast.Inspect(fn.Body, a.inspect)
+		}
+	}
+	return a.uses, nil
+}
+
+// profile is a feature profile and the paths it claims.
+type profile struct {
+	name  string
+	dir   string
+	paths map[string]bool
+}
+
+// readProfiles reads every feature.textproto under root.
+func readProfiles(root string) ([]*profile, error) {
+	var ps []*profile
+	err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".textproto") {
+			return nil
+		}
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fp := &fppb.FeatureProfile{}
+		if err := prototext.Unmarshal(bs, fp); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		p := &profile{name: fp.GetId().GetName(), dir: filepath.Dir(path), paths: map[string]bool{}}
+		for _, cp := range fp.GetConfigPath() {
+			p.paths[cp.GetPath()] = true
+		}
+		for _, tp := range fp.GetTelemetryPath() {
+			p.paths[tp.GetPath()] = true
+		}
+		ps = append(ps, p)
+		return nil
+	})
+	return ps, err
+}
+
+// owner returns the profile whose directory most closely encloses dir.
+func owner(profiles []*profile, dir string) *profile {
+	var best *profile
+	for _, p := range profiles {
+		if dir != p.dir && !strings.HasPrefix(dir, p.dir+string(filepath.Separator)) {
+			continue
+		}
+		if best == nil || len(p.dir) > len(best.dir) {
+			best = p
+		}
+	}
+	return best
+}
+
+// coverage maps each schema leaf path to the set of test packages
+// that use it.
+type coverage map[string]map[string]bool
+
+func (c coverage) add(path, pkg string) {
+	if c[path] == nil {
+		c[path] = map[string]bool{}
+	}
+	c[path][pkg] = true
+}
+
+// analyzeTree analyzes the Go packages under root and returns the
+// coverage attributed to each profile by name.  Packages not enclosed
+// by any profile are attributed to the empty profile name.
+func analyzeTree(index *ocpaths.Index, root string, profiles []*profile) (map[string]coverage, error) {
+	fset := token.NewFileSet()
+	result := map[string]coverage{}
+	err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			return nil
+		}
+		uses, err := analyzeFile(index, fset, path, nil)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(path)
+		pkg, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		var name string
+		if p := owner(profiles, dir); p != nil {
+			name = p.name
+		}
+		if result[name] == nil {
+			result[name] = coverage{}
+		}
+		for _, u := range uses {
+			for _, leaf := range index.Leaves(u.node, u.config) {
+				result[name].add(leaf.Path, pkg)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// row is one line of the coverage report.
+type row struct {
+	profile string
+	path    string
+	status  string // "covered", "uncovered" or "unclaimed".
+	tests   []string
+}
+
+// report builds the coverage matrix.  For every profile, each claimed
+// path is reported as covered or uncovered, and any paths used by the
+// profile's tests that the profile does not claim are reported as
+// unclaimed.
+func report(profiles []*profile, cov map[string]coverage) []row {
+	var rows []row
+	for _, p := range profiles {
+		c := cov[p.name]
+		for path := range p.paths {
+			r := row{profile: p.name, path: path, status: "uncovered"}
+			if pkgs := c[path]; len(pkgs) > 0 {
+				r.status = "covered"
+				r.tests = sortedKeys(pkgs)
+			}
+			rows = append(rows, r)
+		}
+		for path, pkgs := range c {
+			if !p.paths[path] {
+				rows = append(rows, row{profile: p.name, path: path, status: "unclaimed", tests: sortedKeys(pkgs)})
+			}
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].profile != rows[j].profile {
+			return rows[i].profile < rows[j].profile
+		}
+		if rows[i].status != rows[j].status {
+			return rows[i].status < rows[j].status
+		}
+		return rows[i].path < rows[j].path
+	})
+	return rows
+}
+
+func sortedKeys(m map[string]bool) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+func writeCSV(w io.Writer, rows []row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"Profile", "Path", "Status", "Tests"}); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := cw.Write([]string{r.profile, r.path, r.status, strings.Join(r.tests, " ")}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeText(w io.Writer, rows []row) {
+	var last string
+	for _, r := range rows {
+		if r.profile != last {
+			fmt.Fprintf(w, "profile: %s\n", r.profile)
+			last = r.profile
+		}
+		fmt.Fprintf(w, "  %-9s %s", r.status, r.path)
+		if len(r.tests) > 0 {
+			fmt.Fprintf(w, " (%s)", strings.Join(r.tests, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *featuresRoot == "" {
+		log.Fatal("feature_root must be set.")
+	}
+	index, err := ocpaths.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	profiles, err := readProfiles(*featuresRoot)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cov, err := analyzeTree(index, *featuresRoot, profiles)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rows := report(profiles, cov)
+	switch *format {
+	case "csv":
+		if err := writeCSV(os.Stdout, rows); err != nil {
+			log.Fatal(err)
+		}
+	case "text":
+		writeText(os.Stdout, rows)
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"go/token"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/tools/internal/ocpaths"
+)
+
+const testSrc = `package foo_test
+
+func TestFoo(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	dut.Config().System().Hostname().Replace(t, "foo")
+	if got := dut.Telemetry().Interface("eth0").Counters().InPkts().Get(t); got == 0 {
+		t.Errorf("%v", dut.Telemetry().System().Hostname().Get(t))
+	}
+	intf := dut.Telemetry().InterfaceAny()
+	intf.OperStatus().Get(t)
+	d := dut.Config()
+	_ = d
+}
+`
+
+func TestAnalyzeFile(t *testing.T) {
+	index, err := ocpaths.New()
+	if err != nil {
+		t.Fatalf("ocpaths.New() got error: %v", err)
+	}
+	uses, err := analyzeFile(index, token.NewFileSet(), "foo_test.go", testSrc)
+	if err != nil {
+		t.Fatalf("analyzeFile got error: %v", err)
+	}
+	var got []string
+	for _, u := range uses {
+		got = append(got, u.node.Path)
+	}
+	sort.Strings(got)
+	want := []string{
+		"/interfaces/interface/state/counters/in-pkts",
+		"/interfaces/interface/state/oper-status",
+		"/system/config/hostname",
+		"/system/state/hostname",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("analyzeFile got unexpected uses, diff(-want,+got):\n%s", diff)
+	}
+}
+
+const ocpathSrc = `package foo_test
+
+func TestFoo(t *testing.T) {
+	dut := ondatra.DUT(t, "dut")
+	gnmi.Replace(t, dut, ocpath.Root().System().Hostname().Config(), "foo")
+	intf := ocpath.Root().Interface("eth0")
+	if got := gnmi.Get(t, dut, intf.Counters().InPkts().State()); got == 0 {
+		t.Errorf("%v", got)
+	}
+}
+`
+
+func TestAnalyzeFileOcpath(t *testing.T) {
+	index, err := ocpaths.New()
+	if err != nil {
+		t.Fatalf("ocpaths.New() got error: %v", err)
+	}
+	uses, err := analyzeFile(index, token.NewFileSet(), "foo_test.go", ocpathSrc)
+	if err != nil {
+		t.Fatalf("analyzeFile got error: %v", err)
+	}
+	var got []string
+	for _, u := range uses {
+		got = append(got, u.node.Path)
+	}
+	sort.Strings(got)
+	want := []string{
+		"/interfaces/interface/state/counters/in-pkts",
+		"/system/config/hostname",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("analyzeFile got unexpected uses, diff(-want,+got):\n%s", diff)
+	}
+}
+
+func TestOwner(t *testing.T) {
+	profiles := []*profile{
+		{name: "bgp", dir: "feature/bgp"},
+		{name: "bgp_policybase", dir: "feature/bgp/policybase"},
+		{name: "system", dir: "feature/system"},
+	}
+	cases := []struct {
+		dir  string
+		want string
+	}{
+		{"feature/bgp/tests/local_bgp_test", "bgp"},
+		{"feature/bgp/policybase/ate_tests/route_installation_test", "bgp_policybase"},
+		{"feature/systemx/tests", ""},
+		{"feature/experimental/gribi", ""},
+	}
+	for _, c := range cases {
+		var got string
+		if p := owner(profiles, c.dir); p != nil {
+			got = p.name
+		}
+		if got != c.want {
+			t.Errorf("owner(%q) got %q, want %q", c.dir, got, c.want)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	profiles := []*profile{{
+		name:  "system",
+		paths: map[string]bool{"/system/config/hostname": true, "/system/state/hostname": true},
+	}}
+	cov := map[string]coverage{
+		"system": {
+			"/system/config/hostname":    {"system/tests": true},
+			"/system/config/domain-name": {"system/tests": true},
+		},
+	}
+	want := []row{
+		{profile: "system", path: "/system/config/hostname", status: "covered", tests: []string{"system/tests"}},
+		{profile: "system", path: "/system/config/domain-name", status: "unclaimed", tests: []string{"system/tests"}},
+		{profile: "system", path: "/system/state/hostname", status: "uncovered"},
+	}
+	got := report(profiles, cov)
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(row{})); diff != "" {
+		t.Errorf("report got unexpected rows, diff(-want,+got):\n%s", diff)
+	}
+}
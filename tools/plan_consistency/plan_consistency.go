@@ -0,0 +1,240 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// plan_consistency checks that every test plan README under the
+// feature root has a Go test implementing it, and that every test
+// directory has a test plan.
+//
+// A test plan is a README.md whose first heading carries a canonical
+// ID, e.g. "# TE-3.6: ACK in the Presence of Other Routes", followed
+// by a "## Procedure" section.  A test directory is any directory
+// below a "tests", "ate_tests", "otg_tests" or "kne_tests" directory
+// that contains Go test functions.  The following are reported:
+//
+//   - plans without any Go test function next to them;
+//   - test directories without a plan, or whose plan has no ID;
+//   - plans without procedure steps;
+//   - the same ID used with different titles.
+//
+// Usage:
+//
+//	go run ./tools/plan_consistency --feature_root=feature/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/golang/glog"
+)
+
+var featuresRoot = flag.String("feature_root", "", "root directory of the feature profiles")
+
+var (
+	idRE   = regexp.MustCompile(`^#\s+([A-Za-z]+-[0-9]+\.[0-9]+):\s*(.*?)\s*$`)
+	stepRE = regexp.MustCompile(`^\s*([*+-]|[0-9]+\.)\s+\S`)
+)
+
+// plan is the parsed content of a test plan README.
+type plan struct {
+	id    string
+	title string
+	steps int
+}
+
+// parsePlan extracts the canonical ID, title and the number of
+// procedure steps from a README.
+func parsePlan(content []byte) *plan {
+	p := &plan{}
+	inProcedure := false
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for s.Scan() {
+		line := s.Text()
+		if p.id == "" && p.title == "" && strings.HasPrefix(line, "# ") {
+			if m := idRE.FindStringSubmatch(line); m != nil {
+				p.id, p.title = m[1], m[2]
+			} else {
+				p.title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			heading := strings.ToLower(strings.TrimSpace(strings.TrimLeft(line, "#")))
+			inProcedure = strings.HasPrefix(heading, "procedure")
+			continue
+		}
+		if inProcedure && stepRE.MatchString(line) {
+			p.steps++
+		}
+	}
+	return p
+}
+
+// testFuncs returns the names of the Go test functions in the files.
+func testFuncs(fset *token.FileSet, files []string) ([]string, error) {
+	var names []string
+	for _, f := range files {
+		af, err := parser.ParseFile(fset, f, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range af.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Name.Name == "TestMain" {
+				continue
+			}
+			if strings.HasPrefix(fn.Name.Name, "Test") {
+				names = append(names, fn.Name.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// isTestDir reports whether dir, relative to the feature root, is
+// below one of the directories conventionally holding tests.
+func isTestDir(dir string) bool {
+	for _, elem := range strings.Split(filepath.ToSlash(dir), "/") {
+		if elem == "tests" || strings.HasSuffix(elem, "_tests") {
+			return true
+		}
+	}
+	return false
+}
+
+// dirInfo collects the plan and tests found in one directory.
+type dirInfo struct {
+	plan  *plan
+	tests []string
+}
+
+// scan collects plans and tests from the tree under root.
+func scan(root string) (map[string]*dirInfo, error) {
+	dirs := map[string]*dirInfo{}
+	get := func(dir string) *dirInfo {
+		if dirs[dir] == nil {
+			dirs[dir] = &dirInfo{}
+		}
+		return dirs[dir]
+	}
+	testFiles := map[string][]string{}
+	err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		switch {
+		case e.Name() == "README.md":
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			get(dir).plan = parsePlan(content)
+		case strings.HasSuffix(e.Name(), "_test.go"):
+			testFiles[dir] = append(testFiles[dir], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	for dir, files := range testFiles {
+		names, err := testFuncs(fset, files)
+		if err != nil {
+			return nil, err
+		}
+		get(dir).tests = names
+	}
+	return dirs, nil
+}
+
+// check returns the inconsistencies found in dirs, sorted.  Directory
+// names are reported relative to root.
+func check(root string, dirs map[string]*dirInfo) []string {
+	var issues []string
+	titles := map[string]map[string][]string{} // id -> title -> dirs
+	for dir, info := range dirs {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			rel = dir
+		}
+		p := info.plan
+		if p != nil && p.id != "" {
+			if titles[p.id] == nil {
+				titles[p.id] = map[string][]string{}
+			}
+			titles[p.id][p.title] = append(titles[p.id][p.title], rel)
+			if len(info.tests) == 0 {
+				issues = append(issues, fmt.Sprintf("%s: plan %s has no tests", rel, p.id))
+			}
+			if p.steps == 0 {
+				issues = append(issues, fmt.Sprintf("%s: plan %s has no procedure steps", rel, p.id))
+			}
+		}
+		if len(info.tests) > 0 && isTestDir(rel) {
+			switch {
+			case p == nil:
+				issues = append(issues, fmt.Sprintf("%s: tests %s have no plan", rel, strings.Join(info.tests, ", ")))
+			case p.id == "":
+				issues = append(issues, fmt.Sprintf("%s: plan %q has no canonical ID", rel, p.title))
+			}
+		}
+	}
+	for id, byTitle := range titles {
+		if len(byTitle) < 2 {
+			continue
+		}
+		var uses []string
+		for title, ds := range byTitle {
+			sort.Strings(ds)
+			uses = append(uses, fmt.Sprintf("%q in %s", title, strings.Join(ds, ", ")))
+		}
+		sort.Strings(uses)
+		issues = append(issues, fmt.Sprintf("%s: used with different titles: %s", id, strings.Join(uses, "; ")))
+	}
+	sort.Strings(issues)
+	return issues
+}
+
+func main() {
+	flag.Parse()
+	if *featuresRoot == "" {
+		log.Fatal("feature_root must be set.")
+	}
+	dirs, err := scan(*featuresRoot)
+	if err != nil {
+		log.Fatal(err)
+	}
+	issues := check(*featuresRoot, dirs)
+	if len(issues) == 0 {
+		return
+	}
+	log.Error("Test plans inconsistent with tests:\n  " + strings.Join(issues, "\n  "))
+	os.Exit(1)
+}
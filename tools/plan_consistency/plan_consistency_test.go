@@ -0,0 +1,101 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePlan(t *testing.T) {
+	const readme = `# TE-3.6: ACK in the Presence of Other Routes
+
+## Summary
+
+Ensure that ACKs are received in the presence of other routes.
+
+## Procedure
+
+*   Connect DUT port-1 to ATE port-1.
+
+*   Establish gRIBI client to the DUT.
+    *   Nested details do not count separately.
+
+1. Send traffic.
+
+## Config parameter coverage
+
+*   /network-instance/name/
+`
+	got := parsePlan([]byte(readme))
+	want := &plan{id: "TE-3.6", title: "ACK in the Presence of Other Routes", steps: 4}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(plan{})); diff != "" {
+		t.Errorf("parsePlan got unexpected plan, diff(-want,+got):\n%s", diff)
+	}
+}
+
+func TestParsePlanNoID(t *testing.T) {
+	got := parsePlan([]byte("# Local BGP Test\n\n## Summary\n\nSomething.\n"))
+	want := &plan{title: "Local BGP Test"}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(plan{})); diff != "" {
+		t.Errorf("parsePlan got unexpected plan, diff(-want,+got):\n%s", diff)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	root := t.TempDir()
+	const testSrc = "package foo_test\n\nfunc TestMain(m *testing.M) {}\n\nfunc TestFoo(t *testing.T) {}\n"
+	const plan = "# RT-1.1: Foo\n\n## Procedure\n\n*   Do foo.\n"
+
+	writeFile(t, filepath.Join(root, "foo/ate_tests/foo_test/README.md"), plan)
+	writeFile(t, filepath.Join(root, "foo/ate_tests/foo_test/foo_test.go"), testSrc)
+	writeFile(t, filepath.Join(root, "foo/otg_tests/foo_test/README.md"), "# RT-1.1: Foo Renamed\n\n## Procedure\n\n*   Do foo.\n")
+	writeFile(t, filepath.Join(root, "foo/otg_tests/foo_test/foo_test.go"), testSrc)
+	writeFile(t, filepath.Join(root, "bar/tests/bar_test/README.md"), "# RT-2.1: Bar\n\n## Summary\n\nNo steps.\n")
+	writeFile(t, filepath.Join(root, "baz/tests/foo_test.go"), testSrc)
+	writeFile(t, filepath.Join(root, "qux/tests/qux_test/README.md"), "# Qux\n")
+	writeFile(t, filepath.Join(root, "qux/tests/qux_test/qux_test.go"), testSrc)
+	// Config library unit tests are not test plans.
+	writeFile(t, filepath.Join(root, "foo/foo_test.go"), testSrc)
+
+	dirs, err := scan(root)
+	if err != nil {
+		t.Fatalf("scan got error: %v", err)
+	}
+	got := check(root, dirs)
+	want := []string{
+		`RT-1.1: used with different titles: "Foo Renamed" in foo/otg_tests/foo_test; "Foo" in foo/ate_tests/foo_test`,
+		`bar/tests/bar_test: plan RT-2.1 has no procedure steps`,
+		`bar/tests/bar_test: plan RT-2.1 has no tests`,
+		`baz/tests: tests TestFoo have no plan`,
+		`qux/tests/qux_test: plan "Qux" has no canonical ID`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("check got unexpected issues, diff(-want,+got):\n%s", diff)
+	}
+}
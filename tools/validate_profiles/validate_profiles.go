@@ -0,0 +1,252 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// validate_profiles checks the config and telemetry paths listed in
+// every feature profile against the OpenConfig schema compiled into
+// yang/fpoc, which is the schema the tests are built against.
+//
+// Unlike validate_paths, it does not need a checkout of the public
+// YANG models, so it can run as a quick presubmit check.  A path is
+// reported when it does not exist in the schema, when it is not a
+// leaf, or when it is listed as config_path but is a state leaf (or
+// vice versa).  For missing paths, the closest schema path is
+// suggested to help spot typos.
+//
+// Usage:
+//
+//	go run ./tools/validate_profiles --feature_root=feature/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/golang/glog"
+	fppb "github.com/openconfig/featureprofiles/proto/feature_go_proto"
+	"github.com/openconfig/featureprofiles/tools/internal/ocpaths"
+	"github.com/protocolbuffers/txtpbfmt/parser"
+	"google.golang.org/protobuf/encoding/prototext"
+)
+
+var (
+	featuresRoot = flag.String("feature_root", "", "root directory of the feature profiles")
+	skipPrefixes = flag.String("skip_prefixes", "",
+		"comma separated list of path prefixes for models not compiled into fpoc")
+)
+
+// finding is a problem with a single path in a profile.
+type finding struct {
+	line   int32
+	path   string
+	detail string
+}
+
+// result holds the findings of a single profile file.
+type result struct {
+	name     string
+	errors   []string
+	findings []finding
+}
+
+// checker validates profile paths against the schema index.
+type checker struct {
+	index  *ocpaths.Index
+	skip   []string
+	leaves []string // Leaf paths used for suggestions, built lazily.
+}
+
+func (c *checker) skipped(path string) bool {
+	for _, p := range c.skip {
+		if p != "" && strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPath returns a description of what is wrong with path listed
+// under the given field, or the empty string if the path is valid.
+func (c *checker) checkPath(field, path string) string {
+	n := c.index.Lookup(path)
+	switch {
+	case n == nil:
+		if s := c.suggest(path); s != "" {
+			return fmt.Sprintf("missing from fpoc schema, did you mean %s", s)
+		}
+		return "missing from fpoc schema"
+	case !n.Leaf:
+		return "not a leaf"
+	case field == "config_path" && !n.Config:
+		return "state leaf erroneously labeled config_path"
+	case field == "telemetry_path" && n.Config:
+		return "config leaf erroneously labeled telemetry_path"
+	}
+	return ""
+}
+
+// suggest returns the leaf path in the schema closest to path by edit
+// distance among paths of the same depth and top-level container, or
+// the empty string if nothing is reasonably close.
+func (c *checker) suggest(path string) string {
+	if c.leaves == nil {
+		for _, p := range c.index.Paths() {
+			if c.index.Lookup(p).Leaf {
+				c.leaves = append(c.leaves, p)
+			}
+		}
+	}
+	elems := strings.Split(path, "/")
+	best, bestDist := "", len(path)/4+1
+	for _, p := range c.leaves {
+		pe := strings.Split(p, "/")
+		if len(pe) != len(elems) || len(pe) < 2 || pe[1] != elems[1] {
+			continue
+		}
+		if d := distance(path, p); d < bestDist {
+			best, bestDist = p, d
+		}
+	}
+	return best
+}
+
+// distance computes the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min(a int, bs ...int) int {
+	for _, b := range bs {
+		if b < a {
+			a = b
+		}
+	}
+	return a
+}
+
+// checkFile validates the profile definition in bs, read from name.
+func (c *checker) checkFile(name string, bs []byte) (*result, error) {
+	r := &result{name: name}
+	if err := prototext.Unmarshal(bs, &fppb.FeatureProfile{}); err != nil {
+		r.errors = append(r.errors, err.Error())
+	}
+	nodes, err := parser.Parse(bs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	for _, n := range nodes {
+		if n.Name != "config_path" && n.Name != "telemetry_path" {
+			continue
+		}
+		for _, child := range n.Children {
+			if child.Name != "path" {
+				continue
+			}
+			for _, v := range child.Values {
+				path, err := strconv.Unquote(v.Value)
+				if err != nil {
+					path = v.Value
+				}
+				if c.skipped(path) {
+					continue
+				}
+				if detail := c.checkPath(n.Name, path); detail != "" {
+					r.findings = append(r.findings, finding{line: child.Start.Line, path: path, detail: detail})
+				}
+			}
+		}
+	}
+	return r, nil
+}
+
+// profileFiles lists the profile definitions under root.
+func profileFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".textproto") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func main() {
+	flag.Parse()
+	if *featuresRoot == "" {
+		log.Fatal("feature_root must be set.")
+	}
+	index, err := ocpaths.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := &checker{index: index, skip: strings.Split(*skipPrefixes, ",")}
+
+	files, err := profileFiles(*featuresRoot)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var msg []string
+	for _, f := range files {
+		bs, err := os.ReadFile(f)
+		if err != nil {
+			log.Fatal(err)
+		}
+		r, err := c.checkFile(f, bs)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(r.errors) == 0 && len(r.findings) == 0 {
+			continue
+		}
+		msg = append(msg, "  file: "+r.name)
+		for _, e := range r.errors {
+			msg = append(msg, "    "+e)
+		}
+		for _, f := range r.findings {
+			msg = append(msg, fmt.Sprintf("    line %d: %s: %s", f.line, f.path, f.detail))
+		}
+	}
+	if len(msg) == 0 {
+		return
+	}
+	log.Error(strings.Join(append([]string{"Feature paths inconsistent with fpoc schema:"}, msg...), "\n"))
+	os.Exit(1)
+}
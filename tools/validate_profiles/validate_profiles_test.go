@@ -0,0 +1,105 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/openconfig/featureprofiles/tools/internal/ocpaths"
+)
+
+const testProfile = `id {
+  name: "system"
+  version: 1
+}
+config_path {
+  path: "/system/config/hostname"
+}
+telemetry_path {
+  path: "/system/state/hostname"
+}
+config_path {
+  path: "/system/state/boot-time"
+}
+telemetry_path {
+  path: "/system/config/domain-name"
+}
+config_path {
+  path: "/system/config/hostnme"
+}
+config_path {
+  path: "/system/clock"
+}
+telemetry_path {
+  path: "/bfd/interfaces/interface/state/id"
+}
+`
+
+func TestCheckFile(t *testing.T) {
+	index, err := ocpaths.New()
+	if err != nil {
+		t.Fatalf("ocpaths.New() got error: %v", err)
+	}
+	c := &checker{index: index, skip: []string{"/bfd"}}
+	got, err := c.checkFile("feature.textproto", []byte(testProfile))
+	if err != nil {
+		t.Fatalf("checkFile got error: %v", err)
+	}
+	if len(got.errors) != 0 {
+		t.Errorf("checkFile got errors %v, want none", got.errors)
+	}
+	want := []finding{
+		{line: 12, path: "/system/state/boot-time", detail: "state leaf erroneously labeled config_path"},
+		{line: 15, path: "/system/config/domain-name", detail: "config leaf erroneously labeled telemetry_path"},
+		{line: 18, path: "/system/config/hostnme", detail: "missing from fpoc schema, did you mean /system/config/hostname"},
+		{line: 21, path: "/system/clock", detail: "not a leaf"},
+	}
+	if diff := cmp.Diff(want, got.findings, cmp.AllowUnexported(finding{})); diff != "" {
+		t.Errorf("checkFile got unexpected findings, diff(-want,+got):\n%s", diff)
+	}
+}
+
+func TestCheckFileSyntaxError(t *testing.T) {
+	index, err := ocpaths.New()
+	if err != nil {
+		t.Fatalf("ocpaths.New() got error: %v", err)
+	}
+	c := &checker{index: index}
+	got, err := c.checkFile("feature.textproto", []byte(`id { nme: "system" }`))
+	if err != nil {
+		t.Fatalf("checkFile got error: %v", err)
+	}
+	if len(got.errors) == 0 {
+		t.Error("checkFile got no errors for unknown field, want error")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"hostname", "hostnme", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, c := range cases {
+		if got := distance(c.a, c.b); got != c.want {
+			t.Errorf("distance(%q, %q) got %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
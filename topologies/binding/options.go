@@ -22,6 +22,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -45,6 +46,57 @@ var (
 	p4rtPort  = flag.Int("p4rt_port", 9559, "default P4RT part")
 )
 
+var inband = flag.Bool("inband", false, "dial the gRPC protocols of the DUTs at their in-band address, when the binding has one")
+
+// SetInband sets whether the gRPC protocols of the DUTs are dialed at
+// their in-band address, overriding the -inband flag.  Only the
+// connections dialed afterwards are affected, which lets a test move
+// its sessions between the management port and a front-panel port.
+func SetInband(enable bool) {
+	*inband = enable
+}
+
+// Inband reports whether the gRPC protocols of the DUTs are dialed at
+// their in-band address.
+func Inband() bool {
+	return *inband
+}
+
+var transport = flag.String("transport", "tcp", "IP transport the DUTs are dialed over: tcp for either IPv4 or IPv6, tcp4 for IPv4 only, or tcp6 for IPv6 only")
+
+// SetTransport sets the IP transport the DUTs are dialed over,
+// overriding the -transport flag.  It is one of "tcp", "tcp4" or
+// "tcp6".  Only the connections dialed afterwards are affected.
+func SetTransport(network string) error {
+	if err := checkTransport(network); err != nil {
+		return err
+	}
+	*transport = network
+	return nil
+}
+
+// Transport returns the IP transport the DUTs are dialed over.
+func Transport() string {
+	return *transport
+}
+
+func checkTransport(network string) error {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return nil
+	}
+	return fmt.Errorf("invalid transport %q, want tcp, tcp4 or tcp6", network)
+}
+
+// dialTransport dials the address over the transport, so that a target
+// with both IPv4 and IPv6 addresses is reached over the one selected.
+func dialTransport(ctx context.Context, addr string) (net.Conn, error) {
+	if err := checkTransport(*transport); err != nil {
+		return nil, err
+	}
+	return (&net.Dialer{}).DialContext(ctx, *transport, addr)
+}
+
 // creds implements the grpc.PerRPCCredentials interface, to be used
 // as a grpc.DialOption in dialGRPC.
 type creds struct {
@@ -87,6 +139,8 @@ func (d *dialer) dialGRPC(ctx context.Context, opts ...grpc.DialOption) (*grpc.C
 		c := &creds{d.Username, d.Password, !d.Insecure}
 		opts = append(opts, grpc.WithPerRPCCredentials(c))
 	}
+	// Prepended so that a dialer given by the caller takes precedence.
+	opts = append([]grpc.DialOption{grpc.WithContextDialer(dialTransport)}, opts...)
 	return grpc.DialContext(ctx, d.Target, opts...)
 }
 
@@ -126,7 +180,10 @@ func (d *dialer) dialSSH() (*ssh.Client, error) {
 		}
 		c.HostKeyCallback = cb
 	}
-	return ssh.Dial("tcp", d.Target, c)
+	if err := checkTransport(*transport); err != nil {
+		return nil, err
+	}
+	return ssh.Dial(*transport, d.Target, c)
 }
 
 // newHTTPClient makes an http.Client using the binding options.
@@ -224,9 +281,19 @@ func (r *resolver) dutDialer(dutName string, port int, optionsFn func(*bindpb.De
 		return dialer{nil}, fmt.Errorf("dut name %q is missing from the binding", dutName)
 	}
 	targetOptions := &bindpb.Options{
-		Target: fmt.Sprintf("%s:%d", dut.Name, port),
+		Target: net.JoinHostPort(dut.Name, strconv.Itoa(port)),
+	}
+	d := merge(targetOptions, r.Options, dut.Options, optionsFn(dut))
+	if *inband && dut.InbandAddress != "" {
+		// Keep the port of the target, which may be overridden by the
+		// options of the protocol.
+		_, targetPort, err := net.SplitHostPort(d.Target)
+		if err != nil {
+			return dialer{nil}, fmt.Errorf("dut %q target %q has no port: %w", dutName, d.Target, err)
+		}
+		d.Target = net.JoinHostPort(dut.InbandAddress, targetPort)
 	}
-	return merge(targetOptions, r.Options, dut.Options, optionsFn(dut)), nil
+	return d, nil
 }
 
 func (r *resolver) gnmi(dutName string) (dialer, error) {
@@ -15,6 +15,8 @@
 package binding
 
 import (
+	"context"
+	"net"
 	"strconv"
 	"testing"
 
@@ -372,3 +374,133 @@ func TestResolver_ByName_Protocols_Error(t *testing.T) {
 		})
 	}
 }
+
+func TestResolver_Inband(t *testing.T) {
+	r := resolver{&bindpb.Binding{
+		Duts: []*bindpb.Device{{
+			Id:            "dut",
+			Name:          "dut.name",
+			InbandAddress: "2001:db8::1",
+			Gribi: &bindpb.Options{
+				Target: "dut.mgmt:9340",
+			},
+		}, {
+			Id:   "mgmtonly",
+			Name: "mgmtonly.name",
+		}},
+	}}
+	defer SetInband(*inband)
+
+	cases := []struct {
+		test   string
+		inband bool
+		fn     func(name string) (dialer, error)
+		name   string
+		want   string
+	}{{
+		test: "gnmi management",
+		fn:   r.gnmi,
+		name: "dut.name",
+		want: "dut.name:" + strconv.Itoa(*gnmiPort),
+	}, {
+		test:   "gnmi inband",
+		inband: true,
+		fn:     r.gnmi,
+		name:   "dut.name",
+		want:   "[2001:db8::1]:" + strconv.Itoa(*gnmiPort),
+	}, {
+		test:   "gribi inband keeps target port",
+		inband: true,
+		fn:     r.gribi,
+		name:   "dut.name",
+		want:   "[2001:db8::1]:9340",
+	}, {
+		test:   "ssh inband stays on management",
+		inband: true,
+		fn:     r.ssh,
+		name:   "dut.name",
+		want:   "dut.name",
+	}, {
+		test:   "gnmi inband without address",
+		inband: true,
+		fn:     r.gnmi,
+		name:   "mgmtonly.name",
+		want:   "mgmtonly.name:" + strconv.Itoa(*gnmiPort),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.test, func(t *testing.T) {
+			SetInband(c.inband)
+			got, err := c.fn(c.name)
+			if err != nil {
+				t.Fatalf("Could not get options: %v", err)
+			}
+			if got.Target != c.want {
+				t.Errorf("Resolve target got %q, want %q", got.Target, c.want)
+			}
+		})
+	}
+}
+
+func TestResolver_IPv6Name(t *testing.T) {
+	r := resolver{&bindpb.Binding{
+		Duts: []*bindpb.Device{{Id: "dut", Name: "2001:db8::1"}},
+	}}
+	got, err := r.gnmi("2001:db8::1")
+	if err != nil {
+		t.Fatalf("Could not get options: %v", err)
+	}
+	if want := "[2001:db8::1]:" + strconv.Itoa(*gnmiPort); got.Target != want {
+		t.Errorf("Resolve target got %q, want %q", got.Target, want)
+	}
+}
+
+func TestSetTransport(t *testing.T) {
+	defer SetTransport(Transport())
+	for _, network := range []string{"tcp", "tcp4", "tcp6"} {
+		if err := SetTransport(network); err != nil {
+			t.Errorf("SetTransport(%q) got error %v, want nil", network, err)
+		}
+		if got := Transport(); got != network {
+			t.Errorf("Transport() got %q, want %q", got, network)
+		}
+	}
+	if err := SetTransport("udp"); err == nil {
+		t.Error("SetTransport(udp) got nil error, want error")
+	}
+	if got := Transport(); got != "tcp6" {
+		t.Errorf("Transport() after an invalid SetTransport got %q, want tcp6", got)
+	}
+}
+
+func TestDialTransport(t *testing.T) {
+	defer SetTransport(Transport())
+	lis, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Cannot listen: %v", err)
+	}
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	ctx := context.Background()
+
+	SetTransport("tcp4")
+	conn, err := dialTransport(ctx, lis.Addr().String())
+	if err != nil {
+		t.Fatalf("dialTransport over tcp4 got error %v, want nil", err)
+	}
+	conn.Close()
+
+	SetTransport("tcp6")
+	if conn, err := dialTransport(ctx, lis.Addr().String()); err == nil {
+		conn.Close()
+		t.Errorf("dialTransport of an IPv4 address over tcp6 got nil error, want error")
+	}
+}